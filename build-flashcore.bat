@@ -45,6 +45,13 @@ if %ERRORLEVEL% NEQ 0 (
 )
 cd ..\..
 
+REM Record the binary's checksum (and signature, if FLASHFLOW_SIGNING_KEY is set)
+python -m cli.core.main services checksum flashcore-service
+if %ERRORLEVEL% NEQ 0 (
+    echo ERROR: Recording the service checksum failed
+    exit /b 1
+)
+
 echo.
 echo ========================================
 echo Build completed successfully!
@@ -0,0 +1,74 @@
+"""
+FlashFlow 'audit' command - Check a project's routes and links
+"""
+
+import click
+import sys
+from pathlib import Path
+
+from core.framework import FlashFlowProject
+from core.route_audit import DEFAULT_MAX_PAGES, audit_crawl, audit_static, write_reports
+from cli.utils.output import get_output
+
+@click.group()
+def audit():
+    """Audit a FlashFlow project"""
+    pass
+
+@audit.command('routes')
+@click.option('--crawl', is_flag=True, help="Also crawl the running dev server's pages for broken links")
+@click.option('--url', default=None, help='Dev server to crawl (default: the running "flashflow serve"); implies --crawl')
+@click.option('--start', multiple=True, help="Path to start crawling from (default '/'; repeatable)")
+@click.option('--max-pages', default=DEFAULT_MAX_PAGES, type=int, help='Stop crawling after this many URLs')
+@click.option('--output', '-o', default=None, help='Directory for routes.json and routes.html (default: .flashflow/reports)')
+@click.option('--strict', is_flag=True, help='Also fail on unreachable pages and unrouted flows')
+@click.pass_context
+def audit_routes(ctx, crawl, url, start, max_pages, output, strict):
+    """Find broken internal links, unreachable pages and flows with no route"""
+    out = get_output()
+    project = FlashFlowProject(ctx.obj.get('project_root') or Path.cwd())
+    if not project.exists():
+        click.echo("❌ Not in a FlashFlow project directory", err=True)
+        sys.exit(1)
+
+    out.echo("🔍 Checking links in .flow files...")
+    if url or crawl:
+        url = url or _running_server(project)
+        out.echo(f"🕸️  Crawling {url}...")
+        try:
+            report = audit_crawl(project, url, max_pages, list(start))
+        except ConnectionError as e:
+            click.echo(f"❌ {str(e)}", err=True)
+            sys.exit(1)
+    else:
+        report = audit_static(project)
+
+    report_dir = Path(output) if output else project.state.ensure() / "reports"
+    json_path, html_path = write_reports(report, report_dir)
+    data = report.to_dict()
+    summary = data['summary']
+
+    for link in data['broken_links']:
+        out.echo(f"❌ {link['href']} linked from {link['found_on']}" + (f" ({link['source']})" if link['source'] else '')
+                 + f": {link['reason']}")
+    for path in data['unreachable']:
+        out.echo(f"⚠️  {path} is not linked from any reachable page")
+    for item in data['unrouted_flows']:
+        out.echo(f"⚠️  {item['file']}: {item['reason']}")
+    if summary['truncated']:
+        out.echo(f"⚠️  Stopped after {max_pages} URLs; raise --max-pages to crawl everything")
+
+    out.echo(f"📊 {summary['pages']} pages, {summary['links']} links checked: {summary['broken']} broken, "
+             f"{summary['unreachable']} unreachable, {summary['unrouted']} unrouted")
+    out.echo(f"📄 Report: {html_path}")
+    out.emit(dict(data, report={'json': str(json_path), 'html': str(html_path)}))
+
+    if summary['broken'] or (strict and (summary['unreachable'] or summary['unrouted'])):
+        sys.exit(1)
+
+def _running_server(project: FlashFlowProject) -> str:
+    running = project.state.read_runtime('serve') or {}
+    host = running.get('host', '127.0.0.1')
+    if host in ('0.0.0.0', '::'):
+        host = '127.0.0.1'
+    return f"http://{host}:{running.get('port', 8000)}"
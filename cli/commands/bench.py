@@ -0,0 +1,167 @@
+"""
+FlashFlow 'bench' command - Performance benchmarks with regression gating
+"""
+
+import click
+import json
+import os
+import sys
+import time
+from datetime import datetime
+from pathlib import Path
+from typing import Callable, Dict, List
+
+from core.framework import FlashFlowProject
+from core.metrics_history import HISTORY_NAME, MetricsHistory, MetricsRetentionError, retention_settings
+from core.state import ProjectState
+
+# Same lookup as test-flashcore.py: prefer a locally built binding
+sys.path.append(str(Path(__file__).parent.parent.parent / "flashcore" / "bindings" / "python"))
+
+MIN_BENCH_SECONDS = 0.2
+
+@click.group()
+def bench():
+    """Run performance benchmarks"""
+    pass
+
+@bench.command('flashcore')
+@click.option('--threshold', '-t', default=15.0, type=float, help='Allowed slowdown versus baseline, in percent')
+@click.option('--save-baseline', is_flag=True, help='Store this run as the new baseline')
+@click.option('--filter', '-f', 'name_filter', default=None, help='Only run benchmarks whose name contains this text')
+@click.pass_context
+def flashcore_bench(ctx, threshold, save_baseline, name_filter):
+    """Benchmark FlashCore vector search and encryption bindings"""
+    try:
+        import flashcore
+    except ImportError as e:
+        click.echo(f"❌ FlashCore bindings not available: {str(e)}")
+        click.echo("Build them with build-flashcore.bat (or 'python setup.py build_ext --inplace' in flashcore/bindings/python)")
+        sys.exit(1)
+
+    state = ProjectState(ctx.obj.get('project_root') or Path.cwd())
+    bench_dir = state.ensure() / "bench"
+    bench_dir.mkdir(exist_ok=True)
+    baseline_path = bench_dir / "flashcore-baseline.json"
+
+    benchmarks = flashcore_benchmarks(flashcore)
+    if name_filter:
+        benchmarks = {name: fn for name, fn in benchmarks.items() if name_filter in name}
+
+    click.echo(f"⏱️  Running {len(benchmarks)} FlashCore benchmarks...")
+    results = {}
+    for name, setup in benchmarks.items():
+        ns_per_op, iterations = run_benchmark(setup)
+        results[name] = {'ns_per_op': ns_per_op, 'iterations': iterations}
+        click.echo(f"   {name:<32} {iterations:>10} ops   {format_ns(ns_per_op):>12}/op")
+
+    report = {
+        'timestamp': datetime.now().isoformat(),
+        'python': sys.version.split()[0],
+        'results': results
+    }
+    run_path = bench_dir / f"flashcore-{datetime.now().strftime('%Y%m%d_%H%M%S')}.json"
+    with open(run_path, 'w') as f:
+        json.dump(report, f, indent=2)
+    prune_runs(state, bench_dir)
+
+    if save_baseline or not baseline_path.exists():
+        with open(baseline_path, 'w') as f:
+            json.dump(report, f, indent=2)
+        click.echo(f"\n📌 Baseline saved to {baseline_path.relative_to(state.root_path)}")
+        return
+
+    with open(baseline_path, 'r') as f:
+        baseline = json.load(f)
+
+    regressions = compare_to_baseline(results, baseline.get('results', {}), threshold)
+    if regressions:
+        click.echo(f"\n❌ {len(regressions)} benchmarks regressed more than {threshold:g}%:")
+        for line in regressions:
+            click.echo(f"   {line}")
+        sys.exit(1)
+
+    click.echo(f"\n✅ No regressions beyond {threshold:g}% (baseline from {baseline.get('timestamp', 'unknown')})")
+
+def prune_runs(state: ProjectState, bench_dir: Path):
+    """Compact run files past the metrics_retention settings (see core/metrics_history.py)"""
+    project = FlashFlowProject(state.root_path)
+    try:
+        settings = retention_settings(project.config if project.config_path.exists() else None)
+        report = MetricsHistory(bench_dir).prune(settings)
+    except MetricsRetentionError as e:
+        click.echo(f"⚠️  Old run files not pruned: {str(e)}")
+        return
+    if report.compacted:
+        click.echo(f"🗜️  Compacted {len(report.compacted)} older run file(s) into {bench_dir.name}/{HISTORY_NAME} "
+                   f"('flashflow metrics history' shows them)")
+
+def flashcore_benchmarks(flashcore) -> Dict[str, Callable[[], Callable[[], None]]]:
+    """Benchmarks keyed by name; each entry builds its fixtures and returns the timed operation"""
+    import numpy as np
+
+    rng = np.random.default_rng(42)
+    benchmarks = {}
+
+    for dim in (128, 512):
+        def add_vector(dim=dim):
+            index = flashcore.HNSWIndex(dim, 1_000_000)
+            vector = rng.random(dim, dtype=np.float32)
+            counter = iter(range(1, 10**9))
+            return lambda: index.add_vector(vector, next(counter))
+        benchmarks[f"AddVector/dim={dim}"] = add_vector
+
+        for k in (1, 10):
+            def search(dim=dim, k=k):
+                index = flashcore.HNSWIndex(dim, 2000)
+                for i in range(1000):
+                    index.add_vector(rng.random(dim, dtype=np.float32), i + 1)
+                query = rng.random(dim, dtype=np.float32)
+                return lambda: index.search(query, k)
+            benchmarks[f"Search/dim={dim}/k={k}"] = search
+
+    for size, label in ((1024, '1KB'), (64 * 1024, '64KB'), (1024 * 1024, '1MB')):
+        def encrypt(size=size):
+            vault = flashcore.AESVault("flashflow_bench_key")
+            payload = os.urandom(size)
+            return lambda: vault.encrypt(payload)
+        benchmarks[f"Encrypt/size={label}"] = encrypt
+
+    return benchmarks
+
+def run_benchmark(setup: Callable[[], Callable[[], None]]) -> tuple:
+    """Time an operation, growing the iteration count until the run is long enough to trust"""
+    operation = setup()
+    operation()  # warm up
+
+    iterations = 1
+    while True:
+        start = time.perf_counter()
+        for _ in range(iterations):
+            operation()
+        elapsed = time.perf_counter() - start
+        if elapsed >= MIN_BENCH_SECONDS or iterations >= 10**7:
+            return elapsed * 1e9 / iterations, iterations
+        # Aim a little past the minimum so the next round is usually the last
+        iterations = max(iterations * 2, int(iterations * MIN_BENCH_SECONDS * 1.2 / max(elapsed, 1e-9)))
+
+def compare_to_baseline(results: Dict, baseline: Dict, threshold: float) -> List[str]:
+    """Describe every benchmark slower than baseline by more than threshold percent"""
+    regressions = []
+    for name, result in results.items():
+        previous = baseline.get(name)
+        if not previous or not previous.get('ns_per_op'):
+            continue
+        change = (result['ns_per_op'] - previous['ns_per_op']) / previous['ns_per_op'] * 100
+        if change > threshold:
+            regressions.append(
+                f"{name}: {format_ns(previous['ns_per_op'])} → {format_ns(result['ns_per_op'])} (+{change:.1f}%)"
+            )
+    return regressions
+
+def format_ns(ns: float) -> str:
+    """Format nanoseconds with a readable unit"""
+    for unit, scale in (('s', 1e9), ('ms', 1e6), ('µs', 1e3)):
+        if ns >= scale:
+            return f"{ns / scale:.2f}{unit}"
+    return f"{ns:.0f}ns"
@@ -0,0 +1,112 @@
+"""
+FlashFlow 'branches' command - Static previews of git branches in dist/branches
+"""
+
+import click
+import sys
+import time
+from pathlib import Path
+
+from core.branch_previews import BranchPreviewError, BranchPreviews, branch_slug
+from core.framework import FlashFlowProject
+from cli.utils.output import get_output
+
+@click.group()
+def branches():
+    """Build, list and clean up previews of git branches (served at /branch/ by 'flashflow serve')"""
+    pass
+
+def _previews(ctx) -> BranchPreviews:
+    project = FlashFlowProject(ctx.obj.get('project_root') or Path.cwd())
+    if not project.exists():
+        click.echo("❌ Not in a FlashFlow project directory", err=True)
+        sys.exit(1)
+    return BranchPreviews(project)
+
+@branches.command('list')
+@click.pass_context
+def branches_list(ctx):
+    """List local branches and their previews"""
+    out = get_output()
+    previews = _previews(ctx)
+    try:
+        heads = previews.branches()
+    except BranchPreviewError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+    records = {record.get('branch'): record for record in previews.records().values()}
+    current = previews.current_branch()
+
+    out.echo(f"🌿 {len(heads)} branch(es), {len(records)} preview(s):")
+    for name in sorted(heads):
+        record = records.get(name)
+        marker = '*' if name == current else ' '
+        if record is None:
+            state = 'not built'
+        else:
+            state = record.get('status', '?')
+            if record.get('commit') and record['commit'] != heads[name]:
+                state += ', outdated'
+            if record.get('built_at'):
+                state += f", built {time.strftime('%Y-%m-%d %H:%M', time.localtime(record['built_at']))}"
+        out.echo(f"  {marker} {name:<30} {state}")
+        if record and record.get('error'):
+            out.echo(f"      {record['error']}")
+    out.echo("   Build one with: flashflow branches build <branch>; serve shows them at /branch/")
+    out.emit({'current': current, 'branches': [dict(records.get(name) or {}, branch=name, head=heads[name])
+                                                for name in sorted(heads)]})
+
+@branches.command('build')
+@click.argument('names', nargs=-1, required=True)
+@click.pass_context
+def branches_build(ctx, names):
+    """Export the pages of one or more branches to dist/branches/<slug>/"""
+    out = get_output()
+    previews = _previews(ctx)
+    results = []
+    for name in names:
+        out.echo(f"🔨 Building {name}...")
+        try:
+            record = previews.build(name)
+        except BranchPreviewError as e:
+            record = {'branch': name, 'slug': branch_slug(name), 'status': 'failed', 'error': str(e)}
+        results.append(record)
+        if record['status'] == 'failed':
+            out.echo(f"   ❌ {record['error']}")
+        else:
+            out.echo(f"   ✅ {len(record.get('pages') or [])} page(s) in {record['seconds']}s → "
+                     f"dist/branches/{record['slug']}/ (/branch/{record['slug']}/)")
+    for slug, reason in previews.clean():
+        out.echo(f"🧹 Removed {slug}: {reason}")
+    out.emit({'builds': results})
+    if any(record['status'] == 'failed' for record in results):
+        sys.exit(1)
+
+@branches.command('clean')
+@click.option('--dry-run', is_flag=True, help='Only list what would be removed')
+@click.option('--all', 'remove_all', is_flag=True, help='Remove every preview, stale or not')
+@click.pass_context
+def branches_clean(ctx, dry_run, remove_all):
+    """Remove previews of deleted branches, unused ones and those beyond max_branches"""
+    out = get_output()
+    previews = _previews(ctx)
+    try:
+        if remove_all:
+            targets = [(slug, 'all previews removed') for slug in previews.records()]
+        else:
+            targets = previews.stale()
+        if not dry_run:
+            if remove_all:
+                for slug, _ in targets:
+                    previews.remove(slug)
+            else:
+                targets = previews.clean()
+    except BranchPreviewError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+
+    if not targets:
+        out.echo("✅ No stale branch previews")
+    for slug, reason in targets:
+        out.echo(f"{'   would remove' if dry_run else '🧹 Removed'} {slug}: {reason}")
+    out.emit({'removed': [{'slug': slug, 'reason': reason} for slug, reason in targets], 'dry_run': dry_run})
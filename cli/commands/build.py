@@ -7,27 +7,54 @@ import os
 import json
 import subprocess
 import sys
+import io
+import shutil
+import hashlib
+import tempfile
+import time
+import contextlib
 from pathlib import Path
+from typing import Any, Callable, Dict, List, Optional, Tuple
 from core.framework import FlashFlowProject, FlashFlowIR
+from core.parser.flow_loader import flow_loader
 from core.parser.parser import FlowParser
+from core.parser.flow_file import INCLUDE_DIRS
+from core.parser.diagnostics import FlowDiagnostic, collect_diagnostics, group_by_file
+from core.content import CONTENT_DIR, ContentError, add_content_pages, content_files
+from core.media import MediaLibrary, MediaError, collect_media_sources, write_manifest
+from core.tracing import configure_tracing, get_tracer
+from cli.utils.go_services import verified_service_binary
+from cli.utils.output import get_output, Output, StepProgress
+from cli.utils.hooks import HookError, report_hook_failure, run_hooks
+from cli.utils.dev_status import record_build
+from cli.utils.notify import EDITORS, BuildNotifier
+from core.profiles import Profile, ProfileError, load_profile, profile_env, project_environ, write_profile
+from core.build_size import BuildSizeHistory, format_size
+from core.static_site import StaticSiteExporter
+from core.build_cache import BuildCache, BuildCacheError, snapshot, written_since
+from core.dependency_graph import GRAPH_FILE, STEP_INPUTS, DependencyGraph, GraphChanges
+from core.guardrails import Guardrails, GuardrailError
+from core.backend_targets import BackendScaffold, BackendTargetError, backend_output, backend_target
+from core.dev_events import DevEventChannel
+from core.file_watch import DEFAULT_POLL_INTERVAL, FileWatchError, create_observer
+from core.settings import SettingsError, resolve_settings
 # Temporarily remove backend generator import to avoid errors
 # from generators.backend.backend import BackendGenerator
 from generators.web.flet_frontend import FletFrontendGenerator
 from generators.mobile.mobile import MobileGenerator
 from generators.desktop.simple_desktop import SimpleDesktopGenerator
 
-def check_go_service_available(service_name):
-    """Check if a Go service executable is available."""
-    service_path = Path(__file__).parent.parent.parent / "go-services" / service_name / f"{service_name}.exe"
-    return service_path.exists()
+def check_go_service_available(service_name, project_root=None):
+    """Check if a Go service executable is available and passes checksum verification."""
+    return verified_service_binary(service_name, warn=click.echo, project_root=project_root) is not None
 
-def run_go_build_service(target, env, watch):
+def run_go_build_service(target, env, watch, extra_env=None):
     """Run the Go build service if available."""
     try:
         # Determine the path to the build service executable
-        build_service_path = Path(__file__).parent.parent.parent / "go-services" / "build-service" / "build-service.exe"
+        build_service_path = verified_service_binary("build-service", project_root=Path.cwd())
         
-        if not build_service_path.exists():
+        if build_service_path is None:
             return False
             
         # Prepare arguments
@@ -40,40 +67,129 @@ def run_go_build_service(target, env, watch):
         env_vars["FLASHFLOW_TARGET"] = target
         env_vars["FLASHFLOW_ENV"] = env
         env_vars["FLASHFLOW_WATCH"] = str(watch).lower()
+        env_vars.update(extra_env or {})
+        get_tracer().inject_env(env_vars)
         
-        # Run the Go build service
-        result = subprocess.run(args, env=env_vars, capture_output=True, text=True)
+        # Run the Go build service (its output streams live with -v)
+        output = get_output()
+        result = output.run(args, env=env_vars)
         
         if result.returncode == 0:
-            click.echo(result.stdout)
+            if result.stdout:
+                output.echo(result.stdout.rstrip())
             return True
         else:
-            click.echo(f"Go build service failed: {result.stderr}")
+            output.echo(f"Go build service failed: {(result.stderr or '').strip() or f'exit code {result.returncode}'}")
             return False
             
     except Exception as e:
-        click.echo(f"Failed to run Go build service: {str(e)}")
+        get_output().echo(f"Failed to run Go build service: {str(e)}")
         return False
 
 @click.command()
-@click.option('--target', '-t', default='all', help='Build target (all, backend, frontend, mobile, ios, android, desktop, windows, macos, linux)')
-@click.option('--env', '-e', default='development', help='Build environment (development, production)')
+@click.option('--target', '-t', default='all', help='Build target (all, backend, frontend, mobile, ios, android, desktop, windows, macos, linux, static)')
+@click.option('--env', '-e', default=None, help='Environment profile from flashflow.json (default: FLASHFLOW_ENV, default_profile, else development)')
 @click.option('--watch', '-w', is_flag=True, help='Watch for file changes and rebuild')
-def build(target, env, watch):
+@click.option('--dry-run', is_flag=True, help='Show what would be generated without writing anything')
+@click.option('--analyze', is_flag=True, help='Show output sizes, largest files and growth since the previous build')
+@click.option('--no-cache', is_flag=True, help='Generate every step even when build_cache has its output')
+@click.option('--full', is_flag=True, help='Build every step and page, not only what changed since the last build')
+@click.option('--notify', 'notify_desktop', is_flag=True, help='With --watch, show a desktop notification when a build fails or is fixed')
+@click.option('--editor', type=click.Choice(EDITORS, case_sensitive=False), default=None,
+              help='With --watch, open the first error of a failed build in this editor')
+@click.option('--poll', is_flag=False, flag_value=str(DEFAULT_POLL_INTERVAL), default=None,
+              help="With --watch, poll for changes every N seconds (default 1), for NFS/SMB/Docker volumes; 'auto' or 'off'")
+def build(target, env, watch, dry_run, analyze, no_cache, full, notify_desktop, editor, poll):
     """Generate application code from .flow files"""
     
+    output = get_output()
+    if watch and output.quiet:
+        raise click.UsageError("--watch cannot be combined with -q")
+    if (notify_desktop or editor) and not watch:
+        raise click.UsageError("--notify and --editor need --watch")
+    if poll is not None and not watch:
+        raise click.UsageError("--poll needs --watch")
+    
+    # With -q everything human-readable is captured and only the report is printed
+    started = time.monotonic()
+    with output.captured() as log:
+        report = run_build(target, env, watch, dry_run, analyze, not no_cache, notify_desktop, editor, poll, full)
+    
+    project = FlashFlowProject(Path.cwd())
+    if not dry_run and project.exists():
+        seconds = time.monotonic() - started
+        if not watch and report['status'] == 'ok':
+            check_build_guardrails(project, report, seconds, output)
+        record_build(project, report, seconds)
+    if log is not None and report['status'] != 'ok':
+        report['log'] = [line for line in log.getvalue().splitlines() if line.strip()]
+    output.emit(report)
+    if output.quiet and report['status'] in ('failed', 'error'):
+        sys.exit(1)
+
+def run_build(target: str, env: str, watch: bool, dry_run: bool, analyze: bool = False, use_cache: bool = True,
+              notify_desktop: bool = False, editor: Optional[str] = None, poll: Optional[str] = None,
+              full: bool = False) -> Dict[str, Any]:
+    """Run the build command, returning the report printed by -q"""
+    
     # Check if we're in a FlashFlow project
     project = FlashFlowProject(Path.cwd())
     if not project.exists():
         click.echo("❌ Not in a FlashFlow project directory")
         click.echo("Run 'flashflow new <project_name>' to create a new project first")
-        return
+        return {'status': 'error', 'error': 'Not in a FlashFlow project directory'}
+    
+    configure_tracing(project, 'flashflow-build')
+    try:
+        profile = load_profile(project, env)
+    except ProfileError as e:
+        click.echo(f"❌ {str(e)}")
+        return {'status': 'error', 'target': target, 'env': env, 'error': str(e)}
+    env = profile.name
+    report = {'status': 'ok', 'target': target, 'env': env}
+    if watch:
+        try:
+            poll = resolve_settings(project, {'watch.poll': poll}, keys=['watch.poll']).get('watch.poll')
+        except SettingsError as e:
+            click.echo(f"❌ {str(e)}")
+            return dict(report, status='error', error=str(e))
+    try:
+        backend = backend_target(project)
+    except BackendTargetError as e:
+        click.echo(f"❌ {str(e)}")
+        return dict(report, status='error', error=str(e))
+    if target in ['all', 'backend']:
+        report['backend'] = backend.name
+    
+    if dry_run:
+        try:
+            report['plan'] = plan_build(project, target, env)
+        except Exception as e:
+            click.echo(f"❌ Build plan failed: {str(e)}")
+            report.update(status='error', error=str(e))
+        return report
     
-    # Try to use Go build service if available for better performance
-    if check_go_service_available("build-service"):
+    missing = profile.missing_keys(project.root_path, project_environ(project))
+    if missing:
+        click.echo(f"⚠️  Keys not set for '{env}': {', '.join(missing)}")
+    
+    # Hooks run once per command; in watch mode a pre_build that writes .flow files would rebuild forever
+    if not run_build_hooks(project, 'pre_build', target, profile, report):
+        return report
+    
+    # Try to use Go build service if available for better performance; it has no static export
+    if target != 'static' and check_go_service_available("build-service", project.root_path):
         click.echo("🚀 Using optimized Go build service for faster builds...")
-        if run_go_build_service(target, env, watch):
-            return
+        if watch and (notify_desktop or editor):
+            click.echo("⚠️  --notify and --editor only apply to the Python watcher; the build service ignores them")
+        service_env = dict(profile_env(project, profile), FLASHFLOW_BACKEND=backend.name)
+        if watch:
+            service_env['FLASHFLOW_WATCH_POLL'] = str(poll)
+        if run_go_build_service(target, env, watch, service_env):
+            report['service'] = 'build-service'
+            record_build_sizes(project, target, env, report, analyze)
+            run_build_hooks(project, 'post_build', target, profile, report)
+            return report
     
     # Fallback to Python implementation
     try:
@@ -83,71 +199,430 @@ def build(target, env, watch):
         
         if watch:
             click.echo("👀 Watch mode enabled - building on file changes...")
-            build_with_watch(project, target, env)
+            build_with_watch(project, target, env, analyze, use_cache,
+                             BuildNotifier(project, notify_desktop, editor), poll, full)
         else:
-            build_once(project, target, env)
+            report.update(build_once(project, target, env, analyze=analyze, use_cache=use_cache, full=full))
+            if report['status'] == 'ok':
+                run_build_hooks(project, 'post_build', target, profile, report)
             
     except Exception as e:
         click.echo(f"❌ Build failed: {str(e)}")
+        report.update(status='error', error=str(e))
+    return report
+
+def check_build_guardrails(project: FlashFlowProject, report: Dict[str, Any], seconds: float, output: Output):
+    """Tag the report with the guardrails the build broke (build_seconds) and warn about them"""
+    try:
+        guardrails = Guardrails.for_project(project)
+    except GuardrailError as e:
+        output.echo(f"⚠️  Guardrails are off: {str(e)}")
+        return
+    if not guardrails:
+        return
+    tagged = guardrails.check({'build_seconds': round(seconds, 2)}, 'build', wait=True)
+    report['violations'] = tagged['violations']
+    for violation in guardrails.violations(tagged):
+        output.echo(f"⚠️  Guardrail {violation['rule']}: {violation['message']}")
+
+def run_build_hooks(project: FlashFlowProject, event: str, target: str, profile: Profile, report: Dict[str, Any]) -> bool:
+    """Run the pre_build or post_build hooks, marking the report failed if one fails"""
+    try:
+        ran = run_hooks(project, event, dict(profile_env(project, profile), FLASHFLOW_TARGET=target))
+    except HookError as e:
+        report_hook_failure(e)
+        report.update(status='failed', hook=e.to_dict())
+        return False
+    if ran:
+        report.setdefault('hooks', []).append(event)
+    return True
+
+def build_once(project: FlashFlowProject, target: str, env: str, output: Optional[Output] = None,
+               analyze: bool = False, use_cache: bool = True, full: bool = False) -> Dict[str, Any]:
+    """Build the project once, returning the status and timing of each step
+
+    Steps and static pages nothing changed for since the last build are left as they are (see
+    core/dependency_graph.py), unless full.
+    """
+    
+    with get_tracer().span("build", attributes={'flashflow.target': target, 'flashflow.env': env}) as span:
+        parser = FlowParser()
+        with get_tracer().span("build.parse"):
+            ir = parse_flow_files(project, parser)
+        if ir is None:
+            span.set_attribute('flashflow.build.skipped', True)
+            # No .flow files is nothing to do; anything else is a diagnostics failure
+            return {'status': 'failed' if project.get_flow_files() else 'skipped', 'steps': []}
+        
+        graph = parser.graph
+        graph.link(ir, project.root_path)
+        graph_path = project.state.path(GRAPH_FILE)
+        # Without dist/ nothing the previous graph describes is there any more
+        previous = None if full or not project.dist_path.exists() else DependencyGraph.load(graph_path)
+        changes = graph.changes_since(previous)
+        print_graph_changes(changes, full)
+        
+        cache = open_build_cache(project) if use_cache else None
+        progress = generate_targets(project, ir, target, env, output, cache, changes, previous)
+        record_built_steps(graph, previous, changes, progress.results, f"{target}/{env}")
+        try:
+            graph.save(graph_path)
+        except OSError as e:
+            click.echo(f"⚠️  Could not save the dependency graph: {str(e)}")
+    
+    report = {'status': 'failed' if progress.failed else 'ok', 'steps': progress.results, 'changes': changes.to_dict()}
+    if cache:
+        report['cache'] = cache.to_dict()
+        print_cache_summary(report['cache'])
+    if progress.failed:
+        click.echo("⚠️  Build finished with errors")
+        return report
+    click.echo("✅ Build completed successfully!")
+    record_build_sizes(project, target, env, report, analyze)
+    return report
+
+def print_graph_changes(changes: GraphChanges, full: bool):
+    if full:
+        click.echo("🧭 Full build (--full)")
+    elif changes.full:
+        click.echo(f"🧭 Full build: {changes.full}")
+    elif changes.nothing:
+        click.echo("🧭 Nothing changed since the last build")
+    else:
+        counts = [f"{len(names)} {label}" for names, label in ((changes.pages, 'page(s)'), (changes.models, 'model(s)'),
+                                                              (changes.endpoints, 'endpoint(s)')) if names]
+        removed = f", {len(changes.removed_pages)} page(s) removed" if changes.removed_pages else ""
+        click.echo(f"🧭 {len(changes.files)} file(s) changed since the last build: rebuilding "
+                   f"{', '.join(counts) or 'nothing'}{removed}")
+
+def record_built_steps(graph: DependencyGraph, previous: Optional[DependencyGraph], changes: GraphChanges,
+                       results: List[Dict[str, Any]], built_for: str):
+    """Note in graph the steps that are up to date with it: those built now, and earlier ones nothing changed for"""
+    if previous is not None:
+        graph.steps.update({name: built for name, built in previous.steps.items() if not changes.affects(name)})
+    for result in results:
+        if result['step'] not in STEP_INPUTS:
+            continue
+        if result['status'] == 'ok':
+            graph.steps[result['step']] = built_for
+        else:
+            graph.steps.pop(result['step'], None)
+
+def open_build_cache(project: FlashFlowProject) -> Optional[BuildCache]:
+    """The build_cache from flashflow.json, or None; a broken setting warns instead of failing the build"""
+    try:
+        return BuildCache.for_project(project)
+    except BuildCacheError as e:
+        click.echo(f"⚠️  Build cache disabled: {str(e)}")
+        return None
+
+def print_cache_summary(stats: Dict[str, Any]):
+    remote = f" ({stats['remote_hits']} downloaded)" if stats['remote_hits'] else ""
+    click.echo(f"🗄️  Build cache {stats['url']}: {stats['hits']} hit(s){remote}, {stats['misses']} miss(es), "
+               f"{stats['uploaded']} uploaded")
+    for error in stats['errors']:
+        click.echo(f"⚠️  Build cache: {error}")
+
+def record_build_sizes(project: FlashFlowProject, target: str, env: str, report: Dict[str, Any], analyze: bool):
+    """Measure dist/, warn about targets that grew a lot, and print the full report with --analyze"""
+    try:
+        sizes = BuildSizeHistory.for_project(project).record(project.dist_path, target, env)
+    except OSError as e:
+        click.echo(f"⚠️  Could not measure build output: {str(e)}")
+        return
+    report['sizes'] = {name: {key: entry[key] for key in ('bytes', 'count', 'delta_bytes')}
+                       for name, entry in sizes['targets'].items()}
+    if analyze:
+        report['sizes_report'] = sizes
+        print_size_report(sizes)
+    for warning in sizes['warnings']:
+        click.echo(click.style(f"⚠️  {warning} since the previous build", fg='yellow'))
 
-def build_once(project: FlashFlowProject, target: str, env: str):
-    """Build the project once"""
+def print_size_report(sizes: Dict[str, Any]):
+    if not sizes['targets']:
+        click.echo("\n📏 Nothing in dist/ to measure")
+        return
+    click.echo("\n📏 Build output sizes" + ("" if sizes['previous_at'] else " (first measured build)"))
+    click.echo(f"   {'Target':<12} {'Size':>10} {'Change':>18} {'Files':>7}")
+    for name, entry in sizes['targets'].items():
+        change = format_size(entry['delta_bytes'], signed=True)
+        if entry['delta_ratio'] is not None and entry['delta_bytes']:
+            change += f" ({entry['delta_ratio'] * 100:+.1f}%)"
+        click.echo(f"   {name:<12} {format_size(entry['bytes']):>10} {change:>18} {entry['count']:>7}")
+    for name, entry in sizes['targets'].items():
+        click.echo(f"\n   📦 {name}: largest files")
+        for item in entry['largest']:
+            click.echo(f"      {format_size(item['bytes']):>10}  {item['path']}{'  (new)' if item.get('new') else ''}")
+        if entry['grown']:
+            click.echo(f"   📈 {name}: grew most since the previous build ({entry['added']} added, {entry['removed']} removed)")
+            for item in entry['grown']:
+                click.echo(f"      {format_size(item['delta_bytes'] if item['delta_bytes'] is not None else item['bytes'], signed=True):>10}  {item['path']}")
+    click.echo("")
+
+def parse_flow_files(project: FlashFlowProject, parser: Optional[FlowParser] = None) -> Optional[FlashFlowIR]:
+    """Parse all .flow files into a fresh IR, returning None when nothing can be built
+
+    parser records what it reads in its dependency graph; pass one to keep it.
+    """
     
     # Parse all .flow files
     click.echo("📖 Parsing .flow files...")
-    parser = FlowParser()
+    parser = parser or FlowParser()
     ir = FlashFlowIR()
     
     flow_files = project.get_flow_files()
     if not flow_files:
         click.echo("⚠️  No .flow files found in src/flows/")
-        return
+        return None
     
     for flow_file in flow_files:
         click.echo(f"   📄 {flow_file.name}")
-        try:
-            parsed_data = parser.parse_file(flow_file)
-            merge_parsed_data_to_ir(ir, parsed_data)
-        except Exception as e:
-            click.echo(f"   ❌ Error parsing {flow_file.name}: {str(e)}")
-            return
     
-    click.echo(f"✅ Parsed {len(flow_files)} .flow files")
+    # Check every file before merging so all problems are reported at once
+    diagnostics = collect_diagnostics(flow_files)
+    if diagnostics:
+        print_diagnostics(diagnostics)
+    if any(d.severity == 'error' for d in diagnostics):
+        return None
+    
+    # Cached and, for many files, parsed in parallel (core/parser/flow_loader.py)
+    documents, stats = flow_loader.load(flow_files)
+    for document in documents:
+        parser.graph.add_document(document)
+        merge_parsed_data_to_ir(ir, document.data)
+    
+    click.echo(f"✅ {stats.summary()}")
+    
+    # Markdown pages in src/content; flow pages keep their routes
+    try:
+        shadowed = add_content_pages(ir, project.root_path)
+    except ContentError as e:
+        file_name = str(e.file.relative_to(project.root_path)) if e.file else CONTENT_DIR
+        print_diagnostics([FlowDiagnostic(file_name, str(e), e.line)])
+        return None
+    content_count = len(content_files(project.root_path))
+    if content_count:
+        click.echo(f"✅ Read {content_count} content pages from {CONTENT_DIR}/")
+    for route in shadowed:
+        click.echo(f"⚠️  {CONTENT_DIR}: {route} is already a flow page; the flow page is kept")
+    return ir
+
+def print_diagnostics(diagnostics: List[FlowDiagnostic]):
+    """Print parse and validation problems grouped by file"""
+    errors = sum(1 for d in diagnostics if d.severity == 'error')
+    warnings = len(diagnostics) - errors
+    
+    click.echo(f"\n{'❌' if errors else '⚠️ '} Found {errors} errors and {warnings} warnings in .flow files:")
+    for file_name, file_diagnostics in group_by_file(diagnostics).items():
+        click.echo(f"\n   📄 {file_name}")
+        for diagnostic in file_diagnostics:
+            color = 'red' if diagnostic.severity == 'error' else 'yellow'
+            click.echo(click.style(f"      {diagnostic.location()}: {diagnostic.severity}: {diagnostic.message}", fg=color))
+            if diagnostic.suggestion:
+                click.echo(f"         💡 {diagnostic.suggestion}")
+    click.echo("")
+
+# The profile step is left out: it is quick and depends on the environment, not just the flows
+CACHED_STEPS = ('backend', 'frontend', 'media', 'mobile', 'desktop', 'static')
+
+def build_steps(project: FlashFlowProject, ir: FlashFlowIR, target: str, env: str,
+                static_routes: Optional[List[str]] = None) -> List[Tuple[str, str, Callable[[], None]]]:
+    """(name, label, generate) for each generator selected by the build target
+
+    static_routes limits the static export to those pages, when the rest of it is up to date.
+    """
+    steps = []
     
-    # Generate code based on target
     if target in ['all', 'backend']:
-        generate_backend(project, ir, env)
+        steps.append(('backend', 'Backend', lambda: generate_backend(project, ir, env)))
     
     if target in ['all', 'frontend']:
-        generate_frontend(project, ir, env)
+        steps.append(('frontend', 'Frontend', lambda: generate_frontend(project, ir, env)))
+        steps.append(('media', 'Media', lambda: generate_media(project, ir)))
     
     if target in ['all', 'mobile', 'ios', 'android']:
-        generate_mobile(project, ir, env, target)
+        steps.append(('mobile', 'Mobile', lambda: generate_mobile(project, ir, env, target)))
     
     if target in ['all', 'desktop', 'windows', 'macos', 'linux']:
-        generate_desktop(project, ir, env, target)
+        steps.append(('desktop', 'Desktop', lambda: generate_desktop(project, ir, env, target)))
     
-    click.echo("✅ Build completed successfully!")
+    # Only on request: the static export leaves out every page that needs the API
+    if target == 'static':
+        steps.append(('static', 'Static site', lambda: generate_static_site(project, ir, env, static_routes)))
+    
+    # The public part of the profile (backend URL, feature flags) for the generated apps to read
+    steps.append(('profile', 'Environment profile', lambda: write_profile(load_profile(project, env), project.dist_path)))
+    
+    return steps
+
+def generate_targets(project: FlashFlowProject, ir: FlashFlowIR, target: str, env: str,
+                     output: Optional[Output] = None, cache: Optional[BuildCache] = None,
+                     changes: Optional[GraphChanges] = None, previous: Optional[DependencyGraph] = None) -> StepProgress:
+    """Run the generators selected by the build target, restoring cached output where the cache has it
+
+    With changes since the previous build's graph, steps built for the same target and env then that nothing
+    changed for are skipped.
+    """
+    
+    def built_before(name: str) -> bool:
+        return bool(previous and previous.steps.get(name) == f"{target}/{env}")
+    
+    def up_to_date(name: str) -> bool:
+        return bool(changes and built_before(name) and not changes.affects(name))
+    
+    tracer = get_tracer()
+    # The static export can write just the changed pages into the one the previous build left
+    static_routes = None
+    if changes and not changes.full and built_before('static'):
+        static_routes = changes.pages + changes.removed_pages
+    steps = build_steps(project, ir, target, env, static_routes)
+    progress = (output or get_output()).steps(len(steps))
+    
+    for name, label, generate in steps:
+        key = cache.key(name, target, env) if cache and name in CACHED_STEPS else None
+        before = None
+        with tracer.span(f"build.generate.{name}", attributes={'flashflow.target': target}) as span:
+            with progress.step(name, label) as result:
+                if up_to_date(name):
+                    result['unchanged'] = True
+                    span.set_attribute('flashflow.build.unchanged', True)
+                    continue
+                hit = cache.restore(key) if key else None
+                if hit:
+                    result['cache'] = hit
+                    span.set_attribute('flashflow.build.cache', hit)
+                else:
+                    if key:
+                        result['cache'] = 'miss'
+                        before = snapshot(project.dist_path)
+                    generate()
+        # Failed steps are not stored, so the next build tries them again
+        if before is not None and result['status'] == 'ok':
+            cache.store(key, written_since(project.dist_path, before))
+    return progress
+
+def plan_build(project: FlashFlowProject, target: str, env: str) -> Optional[Dict[str, Any]]:
+    """Print what a build would change without touching the project"""
+    
+    ir = parse_flow_files(project)
+    if ir is None:
+        return None
+    
+    # Run the real generators against a throwaway copy of the project so the
+    # plan reflects exactly what they would write
+    with tempfile.TemporaryDirectory(prefix="flashflow-plan-") as staging_dir:
+        staging_root = Path(staging_dir)
+        shutil.copy2(project.config_path, staging_root / "flashflow.json")
+        if project.src_path.exists():
+            shutil.copytree(project.src_path, staging_root / "src")
+        
+        staging_project = FlashFlowProject(staging_root)
+        generator_output = io.StringIO()
+        with contextlib.redirect_stdout(generator_output):
+            generate_targets(staging_project, ir, target, env)
+        
+        changes = diff_output_trees(staging_project.dist_path, project.dist_path)
+    
+    migrations = plan_migrations(project, ir) if target in ['all', 'backend'] else []
+    
+    print_build_plan(target, env, changes, migrations)
+    return {'changes': changes, 'migrations': migrations}
+
+def diff_output_trees(planned_dist: Path, current_dist: Path) -> Dict[str, Dict[str, List[str]]]:
+    """Compare generated output with the current dist directory, grouped by output folder"""
+    changes: Dict[str, Dict[str, List[str]]] = {}
+    
+    if not planned_dist.exists():
+        return changes
+    
+    for output_dir in sorted(p for p in planned_dist.iterdir() if p.is_dir()):
+        planned_files = _file_hashes(output_dir)
+        current_files = _file_hashes(current_dist / output_dir.name)
+        
+        created = sorted(f for f in planned_files if f not in current_files)
+        updated = sorted(f for f in planned_files if f in current_files and planned_files[f] != current_files[f])
+        deleted = sorted(f for f in current_files if f not in planned_files)
+        
+        if created or updated or deleted:
+            changes[output_dir.name] = {'create': created, 'update': updated, 'delete': deleted}
+    
+    return changes
+
+def _file_hashes(root: Path) -> Dict[str, str]:
+    """Map every file below root to a content hash"""
+    if not root.exists():
+        return {}
+    
+    hashes = {}
+    for file_path in root.rglob("*"):
+        if file_path.is_file():
+            relative = file_path.relative_to(root).as_posix()
+            hashes[relative] = hashlib.sha256(file_path.read_bytes()).hexdigest()
+    return hashes
+
+def plan_migrations(project: FlashFlowProject, ir: FlashFlowIR) -> List[Dict]:
+    """Determine which database migrations the current models require"""
+    from cli.commands.migrate import get_existing_migrations, analyze_schema_changes
+    
+    migrations_path = project.root_path / "database" / "migrations"
+    return analyze_schema_changes(ir, get_existing_migrations(migrations_path))
+
+def print_build_plan(target: str, env: str, changes: Dict[str, Dict[str, List[str]]], migrations: List[Dict]):
+    """Print a terraform-style summary of planned changes"""
+    click.echo(f"\n📋 Build plan for target '{target}' ({env})")
+    
+    if not changes and not migrations:
+        click.echo("\n✅ No changes. Generated output is up to date.")
+        return
+    
+    symbols = [('create', '+', 'green'), ('update', '~', 'yellow'), ('delete', '-', 'red')]
+    totals = {'create': 0, 'update': 0, 'delete': 0}
+    
+    for output_dir, dir_changes in changes.items():
+        click.echo(f"\n  dist/{output_dir}/")
+        for action, symbol, color in symbols:
+            for file_name in dir_changes[action]:
+                click.echo(click.style(f"    {symbol} {file_name}", fg=color))
+            totals[action] += len(dir_changes[action])
+    
+    if migrations:
+        click.echo("\n  Migrations required:")
+        for change in migrations:
+            click.echo(click.style(f"    + {change['type']}: {change['description']}", fg='green'))
+    
+    click.echo(
+        f"\nPlan: {totals['create']} to create, {totals['update']} to update, "
+        f"{totals['delete']} no longer generated, {len(migrations)} migrations."
+    )
+    click.echo("💡 Nothing was written. Run 'flashflow build' to apply.")
+
+def build_with_watch(project: FlashFlowProject, target: str, env: str, analyze: bool = False, use_cache: bool = True,
+                     notifier: Optional[BuildNotifier] = None, poll: Any = 'auto', full: bool = False):
+    """Build with file watching; poll is the 'watch.poll' setting (core/file_watch.py)
 
-def build_with_watch(project: FlashFlowProject, target: str, env: str):
-    """Build with file watching"""
+    Every rebuild after the first builds only what the changed file affects (core/dependency_graph.py).
+    """
     import time
-    from watchdog.observers import Observer
-    from watchdog.events import FileSystemEventHandler
     
-    class FlowFileHandler(FileSystemEventHandler):
+    class FlowFileHandler:
         def __init__(self, project, target, env):
             self.project = project
             self.target = target
             self.env = env
             self.last_build = 0
+            # Rebuilds run on the watchdog thread, outside the click context
+            self.output = get_output()
+        
+        def dispatch(self, event):
+            if event.event_type == 'modified':
+                self.on_modified(event)
         
         def on_modified(self, event):
             if event.is_directory:
                 return
             
-            # Only rebuild for .flow files
-            if not str(event.src_path).endswith('.flow'):
+            # Only rebuild for .flow files and the Markdown pages of src/content
+            if Path(str(event.src_path)).suffix not in ('.flow', '.md'):
                 return
             
             # Debounce builds (max once per second)
@@ -158,18 +633,33 @@ def build_with_watch(project: FlashFlowProject, target: str, env: str):
             self.last_build = now
             click.echo(f"\n🔄 File changed: {event.src_path}")
             try:
-                build_once(self.project, self.target, self.env)
+                build_and_report(self.project, self.target, self.env, channel, Path(str(event.src_path)).name,
+                                 self.output, analyze, use_cache, notifier)
                 click.echo("👀 Watching for changes... (Ctrl+C to stop)")
             except Exception as e:
                 click.echo(f"❌ Build error: {str(e)}")
     
+    # A running 'flashflow serve' shows the progress and errors of these builds on its pages
+    channel = DevEventChannel(project, 'build --watch')
+    
     # Initial build
-    build_once(project, target, env)
+    build_and_report(project, target, env, channel, None, analyze=analyze, use_cache=use_cache, notifier=notifier,
+                     full=full)
     
-    # Setup file watcher
+    # Setup file watcher; network and VM filesystems need polling. Flows include files from the other
+    # include folders, and the graph knows which pages they are part of
+    watched = [folder for folder in [project.root_path / name for name in INCLUDE_DIRS + (CONTENT_DIR,)] if folder.is_dir()]
     event_handler = FlowFileHandler(project, target, env)
-    observer = Observer()
-    observer.schedule(event_handler, str(project.flows_path), recursive=True)
+    try:
+        observer, polling = create_observer(poll, watched or [project.flows_path])
+    except FileWatchError as e:
+        click.echo(f"❌ {str(e)}")
+        channel.close()
+        return
+    if polling:
+        click.echo(f"👀 Polling for file changes ({polling})")
+    for folder in watched or [project.flows_path]:
+        observer.schedule(event_handler, str(folder), recursive=True)
     observer.start()
     
     click.echo("👀 Watching for changes... (Ctrl+C to stop)")
@@ -182,6 +672,45 @@ def build_with_watch(project: FlashFlowProject, target: str, env: str):
         click.echo("\n🛑 Watch mode stopped")
     
     observer.join()
+    channel.close()
+
+def build_and_report(project: FlashFlowProject, target: str, env: str, channel: DevEventChannel, trigger: Optional[str],
+                     output: Optional[Output] = None, analyze: bool = False, use_cache: bool = True,
+                     notifier: Optional[BuildNotifier] = None, full: bool = False) -> Dict[str, Any]:
+    """build_once, telling the dev server (and the notifier, if any) when it starts and how it ended"""
+    channel.send('build_started', target=target, trigger=trigger)
+    started = time.time()
+    try:
+        report = build_once(project, target, env, output, analyze, use_cache, full)
+    except Exception as e:
+        errors = [{'message': str(e)}]
+        channel.send('build_failed', target=target, seconds=round(time.time() - started, 2), errors=errors)
+        if notifier:
+            notifier.failed(target, errors)
+        raise
+    seconds = round(time.time() - started, 2)
+    if report['status'] == 'failed':
+        errors = build_errors(project, report)
+        channel.send('build_failed', target=target, seconds=seconds, errors=errors)
+        if notifier:
+            notifier.failed(target, errors)
+    else:
+        channel.send('build_finished', target=target, seconds=seconds)
+        if notifier:
+            notifier.finished(target, seconds)
+    return report
+
+def build_errors(project: FlashFlowProject, report: Dict[str, Any]) -> List[Dict[str, Any]]:
+    """What made a build fail: the failed steps, or the .flow diagnostics when it stopped at parsing"""
+    errors = []
+    for step in report.get('steps', []):
+        if step.get('status') == 'failed':
+            reported = [line for line in step.get('messages', []) if line.startswith('❌')]
+            errors.append({'message': f"{step['step']}: {step.get('error') or (reported or ['failed'])[0]}"})
+    if not errors:
+        errors = [diagnostic.to_dict() for diagnostic in collect_diagnostics(project.get_flow_files())
+                  if diagnostic.severity == 'error']
+    return errors
 
 def merge_parsed_data_to_ir(ir: FlashFlowIR, parsed_data: dict):
     """Merge parsed .flow data into the IR"""
@@ -215,8 +744,14 @@ def merge_parsed_data_to_ir(ir: FlashFlowIR, parsed_data: dict):
         ir.set_theme(parsed_data['theme'])
 
 def generate_backend(project: FlashFlowProject, ir: FlashFlowIR, env: str):
-    """Generate backend code"""
-    click.echo("🔧 Generating backend...")
+    """Generate backend code in the language frameworks.backend picks"""
+    target = backend_target(project)
+    click.echo(f"🔧 Generating backend ({target.label})...")
+    
+    if target.files:
+        written = BackendScaffold(project, target).generate(ir)
+        click.echo(f"   ✅ {', '.join(written)} written to {backend_output(project).relative_to(project.root_path)}")
+        return
     
     try:
         # Import backend generator here to avoid import errors
@@ -240,6 +775,45 @@ def generate_frontend(project: FlashFlowProject, ir: FlashFlowIR, env: str):
     click.echo("   ✅ Flet web app generated")
     click.echo("   ✅ Build configuration created")
 
+def generate_media(project: FlashFlowProject, ir: FlashFlowIR):
+    """Copy media used by pages and generate responsive WebP variants"""
+    sources = collect_media_sources(ir.pages)
+    if not sources:
+        return
+    
+    click.echo("🖼️  Generating responsive media...")
+    
+    library = MediaLibrary(project.src_path / "assets", project.state.cache_dir / "media")
+    output_path = project.dist_path / "frontend" / "media"
+    output_path.mkdir(parents=True, exist_ok=True)
+    
+    manifest = {}
+    for src in sources:
+        try:
+            manifest[src] = library.export(src, output_path)
+        except MediaError as e:
+            click.echo(f"   ⚠️  {str(e)}")
+    write_manifest(output_path, manifest)
+    
+    variant_count = sum(len(entry['variants']) for entry in manifest.values())
+    click.echo(f"   ✅ {len(manifest)} assets, {variant_count} WebP variants (dist/frontend/media/manifest.json)")
+
+def generate_static_site(project: FlashFlowProject, ir: FlashFlowIR, env: Optional[str] = None,
+                         routes: Optional[List[str]] = None):
+    """Pre-render the flow pages to static HTML with fingerprinted assets and a sitemap; with routes, only those pages"""
+    click.echo("🗂️  Exporting static site...")
+    
+    # Pages and components behind feature flags follow the build's environment
+    report = StaticSiteExporter(project, ir, features=load_profile(project, env).features).export(routes)
+    for route, reason in report.skipped:
+        click.echo(f"   ⏭️  {route}: {reason}")
+    for warning in report.warnings:
+        click.echo(f"   ⚠️  {warning}")
+    
+    unchanged = f" ({report.unchanged} unchanged)" if report.unchanged else ""
+    click.echo(f"   ✅ {len(report.pages)} pages{unchanged}, {report.assets} assets" + (", sitemap.xml" if report.sitemap else "")
+               + f" (dist/{report.output.relative_to(project.dist_path).as_posix()})")
+
 def generate_mobile(project: FlashFlowProject, ir: FlashFlowIR, env: str, target: str):
     """Generate mobile app code"""
     if target == 'ios':
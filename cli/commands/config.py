@@ -0,0 +1,55 @@
+"""
+FlashFlow 'config' command - Show flashflow.json and the settings commands resolve from it
+"""
+
+import click
+import json
+import sys
+from pathlib import Path
+
+from core.framework import FlashFlowProject
+from core.settings import SettingsError, resolve_settings
+from cli.utils.output import get_output
+
+@click.group()
+def config():
+    """Inspect project configuration"""
+    pass
+
+@config.command('show')
+@click.option('--resolved', is_flag=True, help='Show each setting with the value commands use and where it came from')
+@click.pass_context
+def config_show(ctx, resolved):
+    """Print flashflow.json, or with --resolved the effective settings"""
+    out = get_output()
+    project = _project(ctx)
+
+    if not resolved:
+        try:
+            data = json.loads(project.config_path.read_text(encoding='utf-8'))
+        except (OSError, ValueError) as e:
+            click.echo(f"❌ Cannot read {project.config_path.name}: {str(e)}", err=True)
+            sys.exit(1)
+        out.echo(json.dumps(data, indent=2))
+        out.emit({'config': data})
+        return
+
+    try:
+        settings = resolve_settings(project)
+    except SettingsError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+    out.echo("⚙️  Settings (flag > environment > flashflow.json > default):")
+    width = max(len(key) for key in settings.resolved)
+    value_width = max(len(str(setting.value)) for setting in settings.resolved.values())
+    for key, setting in settings.resolved.items():
+        out.echo(f"   {key.ljust(width)}  {str(setting.value).ljust(value_width)}  ({setting.source})")
+    out.echo("   Flags apply per command, so only environment, flashflow.json and defaults show here")
+    out.emit({'settings': settings.to_dict()})
+
+def _project(ctx) -> FlashFlowProject:
+    project = FlashFlowProject(ctx.obj.get('project_root') or Path.cwd())
+    if not project.exists():
+        click.echo("❌ Not in a FlashFlow project directory", err=True)
+        sys.exit(1)
+    return project
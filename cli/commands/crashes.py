@@ -0,0 +1,99 @@
+"""
+FlashFlow 'crashes' command - Inspect crash reports in .flashflow/crashes
+"""
+
+import click
+import sys
+import time
+from pathlib import Path
+
+from core.crashes import CRASHES_DIR, CrashReportError, list_crashes, load_crash
+from core.framework import FlashFlowProject
+from cli.utils.output import get_output
+
+@click.group()
+def crashes():
+    """List and show crash reports from the CLI, dev server and engine"""
+    pass
+
+@crashes.command('list')
+@click.option('--service', default=None, help='Only reports from this service (cli, dev-server, engine)')
+@click.pass_context
+def crashes_list(ctx, service):
+    """List crash reports, newest first"""
+    out = get_output()
+    project = _project(ctx)
+    found = [crash for crash in list_crashes(project.state) if not service or crash['service'] == service]
+
+    if not found:
+        out.echo(f"✅ No crash reports in {Path(project.state.dir.name) / CRASHES_DIR}")
+    else:
+        out.echo(f"🧯 {len(found)} crash report(s):")
+        width = max(len(crash['id']) for crash in found)
+        for crash in found:
+            exception = crash.get('exception') or {}
+            posted = _posted(crash.get('posted'))
+            out.echo(f"   {crash['id'].ljust(width)}  {_when(crash.get('time'))}  "
+                     f"{exception.get('type')}: {_shorten(exception.get('message', ''))}{posted}")
+        out.echo("   Show one with: flashflow crashes show <id>  (a unique prefix or 'latest' also works)")
+    out.emit({'crashes': found})
+
+@crashes.command('show')
+@click.argument('crash_id', default='latest')
+@click.pass_context
+def crashes_show(ctx, crash_id):
+    """Show one crash report: stack, config snapshot and recent logs"""
+    out = get_output()
+    project = _project(ctx)
+    try:
+        dump = load_crash(project.state, crash_id)
+    except CrashReportError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+
+    exception = dump.get('exception') or {}
+    out.echo(f"🧯 {dump['id']}")
+    out.echo(f"   Service:  {dump.get('service')} (pid {dump.get('pid')})")
+    out.echo(f"   When:     {_when(dump.get('time'))}")
+    out.echo(f"   Command:  {' '.join(dump.get('argv') or [])}")
+    out.echo(f"   Python:   {dump.get('python')} on {dump.get('platform')}")
+    for key, value in (dump.get('context') or {}).items():
+        out.echo(f"   {key.capitalize() + ':':<9} {value}")
+    if dump.get('posted'):
+        out.echo(f"   Posted:   {_posted(dump['posted']).strip()}")
+
+    out.echo(f"\n❌ {exception.get('type')}: {exception.get('message')}")
+    for line in dump.get('stack') or []:
+        out.echo(f"   │ {line}")
+
+    if dump.get('logs'):
+        out.echo(f"\n📜 Last {len(dump['logs'])} log line(s):")
+        for line in dump['logs']:
+            out.echo(f"   {line}")
+    if dump.get('env'):
+        out.echo("\n🌍 Environment:")
+        for name, value in dump['env'].items():
+            out.echo(f"   {name}={value}")
+    out.echo(f"\n⚙️  flashflow.json (secrets redacted) is in {Path(project.state.dir.name) / CRASHES_DIR / (dump['id'] + '.json')}")
+    out.emit(dump)
+
+def _project(ctx) -> FlashFlowProject:
+    project = FlashFlowProject(ctx.obj.get('project_root') or Path.cwd())
+    if not project.exists():
+        click.echo("❌ Not in a FlashFlow project directory", err=True)
+        sys.exit(1)
+    return project
+
+def _when(timestamp) -> str:
+    return time.strftime('%Y-%m-%d %H:%M:%S', time.localtime(timestamp)) if timestamp else '?'
+
+def _shorten(text: str, limit: int = 80) -> str:
+    text = ' '.join(str(text).split())
+    return text if len(text) <= limit else text[:limit - 1] + '…'
+
+def _posted(posted) -> str:
+    if not posted:
+        return ''
+    if posted.get('error'):
+        return f"  (post failed: {posted['error']})"
+    return f"  (posted, HTTP {posted.get('status')})"
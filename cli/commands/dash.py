@@ -0,0 +1,329 @@
+"""
+FlashFlow 'dash' command - Terminal dashboard for the whole dev environment
+
+    ┌ services ─ dev server, engine, OS service, with pid, URL, memory and version (from /__info)
+    ├ build ──── result and steps of the last 'flashflow build'
+    ├ server ─── request rate and latency over the last minute (from /__stats)
+    ├ alerts ─── guardrail breaches of the last hour, when there are any
+    └ logs ───── live tail of .flashflow/logs/*.log
+
+Keys: b rebuild, s start/stop the dev server, r restart it, o/a/e open the
+preview, admin panel or engine, tab next log, q quit. A dev server started
+from the dashboard stops when the dashboard exits.
+"""
+
+import click
+import os
+import subprocess
+import sys
+import time
+import webbrowser
+from pathlib import Path
+from typing import Dict, Any, List, Optional
+
+from core.framework import FlashFlowProject
+from core.settings import SettingsError, resolve_settings
+from cli.utils.dev_status import format_bytes, log_files, snapshot, tail
+from cli.utils.os_service import CLI_ROOT, OSServiceError, read_installed, serve_command, service_manager
+from cli.utils.output import get_output
+
+# Request-rate samples kept for the sparkline
+HISTORY = 60
+SPARK = " ▁▂▃▄▅▆▇█"
+
+@click.command()
+@click.option('--once', is_flag=True, help='Print one snapshot instead of the interactive dashboard')
+@click.option('--interval', default=1.0, type=float, help='Seconds between refreshes')
+@click.option('--host', default=None, help='Host for a dev server started from the dashboard (default: as flashflow serve)')
+@click.option('--port', '-p', default=None, help='Port for a dev server started from the dashboard (default: as flashflow serve)')
+@click.pass_context
+def dash(ctx, once, interval, host, port):
+    """Show services, the last build, request rate and logs in one screen"""
+    out = get_output()
+    project = FlashFlowProject(ctx.obj.get('project_root') or Path.cwd())
+    if not project.exists():
+        click.echo("❌ Not in a FlashFlow project directory", err=True)
+        sys.exit(1)
+    try:
+        settings = resolve_settings(project, {'serve.host': host, 'serve.port': port}, keys=['serve.host', 'serve.port'])
+    except SettingsError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+    host, port = settings.host('serve.host'), settings.port('serve.port')
+
+    if once or out.quiet or not sys.stdout.isatty():
+        data = snapshot(project)
+        for line in snapshot_lines(data):
+            out.echo(line)
+        out.emit(data)
+        return
+
+    try:
+        import curses
+    except ImportError:
+        click.echo("❌ The dashboard needs curses; on Windows: pip install windows-curses", err=True)
+        sys.exit(1)
+
+    dashboard = Dashboard(project, interval, host, port)
+    try:
+        curses.wrapper(dashboard.run)
+    finally:
+        dashboard.shutdown()
+    click.echo("👋 Dashboard closed" + (" (dev server stopped)" if dashboard.stopped_server else ""))
+
+def snapshot_lines(data: Dict[str, Any]) -> List[str]:
+    """Plain-text version of the dashboard panes"""
+    lines = [f"⚡ {data['project']}", "", "Services"]
+    for service in data['services']:
+        state = service.get('state') or ('running' if service['running'] else 'stopped')
+        details = [f"pid {service['pid']}" if service['pid'] else '', service['url'] or '',
+                   format_bytes(service['memory_bytes']) if service['memory_bytes'] else '',
+                   f"v{service['version']}" + (f" ({service['commit']})" if service.get('commit') else '') if service.get('version') else '']
+        lines.append(f"  {'●' if service['running'] else '○'} {service['name']:<14} {state:<10} {'  '.join(d for d in details if d)}")
+
+    lines += ["", "Last build"]
+    lines += ["  " + line for line in build_lines(data['build'])]
+
+    lines += ["", "Server", "  " + server_line(data['server'])]
+    if data.get('alerts'):
+        lines += ["", "Alerts"]
+        lines += ["  " + line for line in alert_lines(data['alerts'])]
+    return lines
+
+def server_line(server: Optional[Dict[str, Any]]) -> str:
+    if not server:
+        return "not running"
+    latency = server.get('latency_ms') or {}
+    p99 = f", p99 {latency['p99']}ms" if latency.get('p99') is not None else ''
+    over = f"  ⚠️  over {', '.join(server['violations'])}" if server.get('violations') else ''
+    return (f"{server['rate_per_second']} req/s over {server['window_seconds']}s, {server['requests']} requests, "
+            f"{server['errors']} 5xx{p99}, memory {format_bytes(server['memory_bytes'])}{over}")
+
+def alert_lines(alerts: List[Dict[str, Any]], limit: int = 5) -> List[str]:
+    """Newest guardrail alerts first"""
+    return [f"⚠️  {_ago(alert.get('time')):<8} {alert.get('source', '?')}: {alert.get('message', alert.get('rule'))}"
+            for alert in reversed(alerts[-limit:])]
+
+def build_lines(build: Optional[Dict[str, Any]]) -> List[str]:
+    if not build:
+        return ["no build yet; press b or run 'flashflow build'"]
+    icon = {'ok': '✅', 'skipped': '⏭️ '}.get(build.get('status'), '❌')
+    ago = _ago(build.get('finished_at'))
+    lines = [f"{icon} {build.get('status')}  {build.get('target', '?')}/{build.get('env', '?')}  "
+             f"{build.get('seconds', 0)}s  {ago}"]
+    for step in build.get('steps', []):
+        cached = f"  ({step['cache']} cache)" if step.get('cache') in ('local', 'remote') else ''
+        lines.append(f"   {step['step']:<10} {step['status']:<8} {step.get('seconds', 0)}s{cached}")
+    if build.get('violations'):
+        lines.append(f"   ⚠️  over guardrail {', '.join(build['violations'])}")
+    if build.get('error'):
+        lines.append(f"   {build['error']}")
+    if build.get('hook'):
+        lines.append(f"   {build['hook']['error']}")
+    return lines
+
+def _ago(timestamp: Optional[float]) -> str:
+    if not timestamp:
+        return ''
+    seconds = int(time.time() - timestamp)
+    if seconds < 60:
+        return f"{seconds}s ago"
+    if seconds < 3600:
+        return f"{seconds // 60}m ago"
+    return f"{seconds // 3600}h ago"
+
+class Dashboard:
+    """curses UI state and the actions bound to keys"""
+
+    def __init__(self, project: FlashFlowProject, interval: float, host: str, port: int):
+        self.project = project
+        self.interval = max(interval, 0.2)
+        self.host = host
+        self.port = port
+        self.data: Dict[str, Any] = {}
+        self.rates: List[float] = []
+        self.log_index = 0
+        self.message = ''
+        self.server: Optional[subprocess.Popen] = None
+        self.builder: Optional[subprocess.Popen] = None
+        self.stopped_server = False
+
+    def run(self, screen):
+        import curses
+        curses.curs_set(0)
+        screen.timeout(100)
+        last_refresh = 0.0
+        while True:
+            if time.monotonic() - last_refresh >= self.interval:
+                self.refresh()
+                last_refresh = time.monotonic()
+            self.draw(screen)
+            key = screen.getch()
+            if key in (ord('q'), ord('Q')):
+                return
+            if key != -1:
+                self.handle(key)
+                last_refresh = 0.0
+
+    def refresh(self):
+        self.data = snapshot(self.project)
+        server = self.data.get('server')
+        self.rates = (self.rates + [server['rate_per_second'] if server else 0.0])[-HISTORY:]
+        if self.builder and self.builder.poll() is not None:
+            self.message = "✅ Rebuild finished" if self.builder.returncode == 0 else f"❌ Rebuild exited with {self.builder.returncode}"
+            self.builder = None
+
+    def handle(self, key: int):
+        actions = {
+            ord('b'): self.rebuild, ord('s'): self.toggle_server, ord('r'): self.restart,
+            ord('o'): lambda: self.open('/preview'), ord('a'): lambda: self.open('/admin/models/'),
+            ord('e'): self.open_engine, 9: self.next_log
+        }
+        action = actions.get(key)
+        if action:
+            action()
+
+    # Actions
+
+    def rebuild(self):
+        if self.builder:
+            self.message = "⏳ A rebuild is already running"
+            return
+        self.builder = self._spawn([sys.executable, '-m', 'cli.core.main', 'build'], "build.log")
+        self.message = "🔨 Rebuilding (logs: build.log)"
+
+    def toggle_server(self):
+        if self.server and self.server.poll() is None:
+            self._stop_server()
+            self.message = "🛑 Dev server stopped"
+        elif self._service('dev server', 'running'):
+            self.message = "⚠️  The dev server was started elsewhere; stop it there"
+        else:
+            self.server = self._spawn(serve_command(self.host, self.port), "serve.log")
+            self.message = f"🚀 Starting dev server on http://{self.host}:{self.port} (logs: serve.log)"
+
+    def restart(self):
+        spec = read_installed(self.project)
+        if spec:
+            try:
+                manager = service_manager(self.project)
+                manager.stop(spec)
+                manager.start(spec)
+                self.message = f"🔄 Restarted {spec.name}"
+            except OSServiceError as e:
+                self.message = f"❌ {str(e)}"
+        elif self.server and self.server.poll() is None:
+            self._stop_server()
+            self.server = self._spawn(serve_command(self.host, self.port), "serve.log")
+            self.message = "🔄 Dev server restarted"
+        else:
+            self.message = "⚠️  Nothing to restart: start the server with s, or install it with 'flashflow service install'"
+
+    def open(self, path: str):
+        url = self._service('dev server', 'url')
+        if not url:
+            self.message = "⚠️  The dev server is not running"
+            return
+        webbrowser.open(url + path)
+        self.message = f"🌐 Opened {url + path}"
+
+    def open_engine(self):
+        url = self._service('engine', 'url')
+        if not url:
+            self.message = "⚠️  The engine is not running (serve --auto-start-engine starts it)"
+            return
+        webbrowser.open(url)
+        self.message = f"🌐 Opened {url}"
+
+    def next_log(self):
+        self.log_index += 1
+
+    def shutdown(self):
+        if self.server and self.server.poll() is None:
+            self._stop_server()
+            self.stopped_server = True
+
+    def _stop_server(self):
+        self.server.terminate()
+        try:
+            self.server.wait(timeout=10)
+        except subprocess.TimeoutExpired:
+            self.server.kill()
+        self.server = None
+
+    def _spawn(self, args: List[str], log_name: str) -> subprocess.Popen:
+        env = os.environ.copy()
+        env['PYTHONPATH'] = os.pathsep.join(filter(None, [str(CLI_ROOT), env.get('PYTHONPATH')]))
+        env['PYTHONUNBUFFERED'] = '1'
+        with open(self.project.state.logs_dir / log_name, 'a') as log_file:
+            return subprocess.Popen(args, cwd=str(self.project.root_path), env=env,
+                                    stdin=subprocess.DEVNULL, stdout=log_file, stderr=subprocess.STDOUT)
+
+    def _service(self, name: str, key: str):
+        for service in self.data.get('services', []):
+            if service['name'] == name:
+                return service.get(key)
+        return None
+
+    # Drawing
+
+    def draw(self, screen):
+        import curses
+        screen.erase()
+        height, width = screen.getmaxyx()
+        row = 0
+
+        def put(text: str, attr=0):
+            nonlocal row
+            if row < height - 1:
+                try:
+                    screen.addnstr(row, 0, text, width - 1, attr)
+                except curses.error:
+                    pass
+            row += 1
+
+        def title(text: str):
+            put(f" {text} ".ljust(width - 1, '─'), curses.A_BOLD)
+
+        put(f" ⚡ FlashFlow dash · {self.data.get('project', self.project.root_path.name)}".ljust(width - 10)
+            + time.strftime('%H:%M:%S'), curses.A_REVERSE)
+        lines = snapshot_lines(self.data) if self.data else []
+        services_end = lines.index("Last build") if "Last build" in lines else 0
+
+        title("Services")
+        for line in lines[3:services_end - 1]:
+            put(line, curses.A_BOLD if '●' in line else curses.A_DIM)
+        title("Last build" + (" (rebuilding…)" if self.builder else ""))
+        for line in build_lines(self.data.get('build'))[:8]:
+            put("  " + line)
+        title("Server")
+        put("  " + server_line(self.data.get('server')) if self.data else "")
+        put("  " + self._sparkline(width - 4))
+        if self.data.get('alerts'):
+            title("Alerts")
+            for line in alert_lines(self.data['alerts'], 3):
+                put("  " + line, curses.A_BOLD)
+
+        logs = log_files(self.project)
+        if logs:
+            current = logs[self.log_index % len(logs)]
+            title(f"Log: {current.name}  ({self.log_index % len(logs) + 1}/{len(logs)}, tab for next)")
+            for line in tail(current, height - row - 2):
+                put("  " + line)
+        else:
+            title("Logs")
+            put("  no logs yet in .flashflow/logs")
+
+        footer = " b rebuild  s start/stop server  r restart  o preview  a admin  e engine  tab log  q quit "
+        try:
+            screen.addnstr(height - 1, 0, (footer + "  " + self.message).ljust(width - 1), width - 1, curses.A_REVERSE)
+        except curses.error:
+            pass
+        screen.refresh()
+
+    def _sparkline(self, width: int) -> str:
+        rates = self.rates[-width:]
+        if not rates:
+            return ""
+        peak = max(rates) or 1.0
+        return "".join(SPARK[round(rate / peak * (len(SPARK) - 1))] for rate in rates) + f"  peak {max(rates)} req/s"
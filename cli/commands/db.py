@@ -0,0 +1,168 @@
+"""
+FlashFlow 'db' command - Export, import and query dev database rows
+
+    flashflow db export|import          rows as JSON or CSV
+    flashflow db console                interactive SQL with history and tab completion
+    flashflow db query "<sql>" [--json] one statement, printed as a table or JSON
+"""
+
+import click
+import sys
+from pathlib import Path
+
+from core.framework import FlashFlowProject
+from core.database import StorageError, create_storage
+from core.sql_console import SqlConsole, format_result, result_json, split_statements
+from core.data_transfer import (
+    FORMATS, STRATEGIES, export_rows, export_tables, format_for, import_rows, parse_rows, to_csv, to_json
+)
+
+@click.group()
+def db():
+    """Inspect the dev database and move its rows between machines"""
+    pass
+
+def _project(ctx) -> FlashFlowProject:
+    project = FlashFlowProject(ctx.obj.get('project_root') or Path.cwd())
+    if not project.exists():
+        click.echo("❌ Not in a FlashFlow project directory", err=True)
+        sys.exit(1)
+    return project
+
+@db.command('export')
+@click.argument('tables', nargs=-1)
+@click.option('--format', '-f', 'fmt', type=click.Choice(FORMATS), default='json', help='Output format')
+@click.option('--output', '-o', default=None, help='File (JSON, or CSV for one table) or directory (one CSV per table); default stdout')
+@click.pass_context
+def export_data(ctx, tables, fmt, output):
+    """Export TABLES (default: every table)"""
+    project = _project(ctx)
+    storage = create_storage(project)
+    try:
+        tables = list(tables) or storage.list_tables()
+        if fmt == 'json':
+            _write(output, to_json(export_tables(storage, tables)))
+            _report(output, f"{len(tables)} table(s)")
+            return
+
+        if len(tables) == 1 and not (output and Path(output).is_dir()):
+            _write(output, to_csv(storage, tables[0], export_rows(storage, tables[0])))
+            _report(output, tables[0])
+            return
+        if not output:
+            click.echo("❌ Exporting several tables as CSV needs --output <directory>", err=True)
+            sys.exit(1)
+        directory = Path(output)
+        directory.mkdir(parents=True, exist_ok=True)
+        for table in tables:
+            (directory / f"{table}.csv").write_text(to_csv(storage, table, export_rows(storage, table)))
+            click.echo(f"📤 {table} -> {directory / f'{table}.csv'}", err=True)
+    except StorageError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+    finally:
+        storage.close()
+
+def _write(output, text: str):
+    if output:
+        Path(output).write_text(text)
+    else:
+        click.echo(text, nl=False)
+
+def _report(output, what: str):
+    if output:
+        click.echo(f"📤 Exported {what} to {output}", err=True)
+
+@db.command('import')
+@click.argument('source', type=click.Path(exists=True))
+@click.option('--table', '-t', default=None, help='Table for a CSV file or a bare list of rows (default: the file name)')
+@click.option('--format', '-f', 'fmt', type=click.Choice(FORMATS), default=None, help='Input format (default: from the file extension)')
+@click.option('--on-conflict', type=click.Choice(STRATEGIES), default='skip', help='What to do with rows whose id already exists')
+@click.option('--no-sync', is_flag=True, help="Do not create missing model tables before importing")
+@click.pass_context
+def import_data(ctx, source, table, fmt, on_conflict, no_sync):
+    """Import rows from SOURCE (a file, or a directory of .json/.csv files)"""
+    project = _project(ctx)
+    source = Path(source)
+    files = sorted(f for f in source.iterdir() if format_for(f.name, '') in FORMATS) if source.is_dir() else [source]
+    if not files:
+        click.echo(f"❌ No .json or .csv files in {source}", err=True)
+        sys.exit(1)
+
+    storage = create_storage(project)
+    try:
+        if not no_sync:
+            # Imports into a fresh checkout should not need the dev server to have run first
+            from cli.devserver.dev_crud import ModelTables
+            ModelTables(project, storage).sync()
+        for path in files:
+            _import_file(storage, path, table, fmt or format_for(path.name), on_conflict)
+    except StorageError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+    finally:
+        storage.close()
+
+def _import_file(storage, path: Path, table, fmt: str, strategy: str):
+    try:
+        # A CSV or a bare list of rows is named after its file unless --table says otherwise
+        tables = parse_rows(path.read_text(encoding='utf-8-sig'), fmt, table, default_table=path.stem)
+    except StorageError as e:
+        raise StorageError(f"{path.name}: {str(e)}")
+    for name, rows in tables.items():
+        result = import_rows(storage, name, rows, strategy)
+        click.echo(f"📥 {name}: {result['inserted']} inserted, {result['updated']} updated, {result['skipped']} skipped")
+
+@db.command('console')
+@click.option('--json', 'as_json', is_flag=True, help='Print results as JSON instead of tables')
+@click.pass_context
+def console(ctx, as_json):
+    """Interactive SQL console on the dev database"""
+    project = _project(ctx)
+    storage = create_storage(project)
+    try:
+        # Fail before the prompt when the database cannot be reached
+        tables = storage.list_tables()
+    except StorageError as e:
+        storage.close()
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+
+    repl = SqlConsole(storage, click.echo, project.state.path('db_history'), 'json' if as_json else 'table')
+    completion = repl.setup_readline()
+    click.echo(f"🗄️  {storage.config.display_dsn() if storage.driver != 'sqlite' else storage.sqlite_path()} "
+               f"({storage.driver}, {len(tables)} table(s))")
+    click.echo("   .help for commands, .quit or Ctrl-D to leave" + ("" if completion else " (no readline: no history or completion)"))
+    try:
+        repl.run()
+    finally:
+        repl.save_history()
+        storage.close()
+
+@db.command('query')
+@click.argument('sql')
+@click.option('--json', 'as_json', is_flag=True, help='Print the rows as a JSON array (statements without rows print their row count)')
+@click.pass_context
+def query(ctx, sql, as_json):
+    """Run SQL (one or more ';'-separated statements) and print the result"""
+    project = _project(ctx)
+    statements = split_statements(sql)
+    if not statements:
+        click.echo("❌ No SQL to run", err=True)
+        sys.exit(1)
+
+    storage = create_storage(project)
+    try:
+        results = [storage.run_sql(statement) for statement in statements]
+    except StorageError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+    finally:
+        storage.close()
+
+    if as_json:
+        # The last statement's result, so 'INSERT ...; SELECT ...' reads back what it wrote
+        click.echo(to_json(result_json(results[-1])), nl=False)
+        return
+    for result in results:
+        click.echo(format_result(result))
@@ -3,14 +3,16 @@ FlashFlow 'deploy' command - Deploy application
 """
 
 import click
+import json
 from pathlib import Path
 from core.framework import FlashFlowProject
+from core.profiles import PROFILE_FILE, Profile, ProfileError, load_profile, project_environ
 
 @click.command()
 @click.option('--all', 'deploy_all', is_flag=True, help='Build, test, and deploy everything')
 @click.option('--edge', is_flag=True, help='Deploy to global edge network')
 @click.option('--vps', is_flag=True, help='Deploy to VPS with FranklinPHP')
-@click.option('--env', '-e', default='production', help='Deployment environment')
+@click.option('--env', '-e', default=None, help='Environment profile from flashflow.json (default: FLASHFLOW_ENV, default_profile, else production)')
 def deploy(deploy_all, edge, vps, env):
     """Deploy FlashFlow application"""
     
@@ -20,6 +22,16 @@ def deploy(deploy_all, edge, vps, env):
         click.echo("❌ Not in a FlashFlow project directory")
         return
     
+    try:
+        profile = load_profile(project, env, default='production')
+    except ProfileError as e:
+        click.echo(f"❌ {str(e)}")
+        return
+    env = profile.name
+    missing = profile.missing_keys(project.root_path, project_environ(project))
+    if missing:
+        click.echo(f"⚠️  Keys not set here for '{env}' (the server needs them): {', '.join(missing)}")
+    
     try:
         if deploy_all:
             click.echo(f"🚀 Deploying {project.config.name} with full pipeline...")
@@ -37,6 +49,15 @@ def deploy(deploy_all, edge, vps, env):
     except Exception as e:
         click.echo(f"❌ Deployment failed: {str(e)}")
 
+def key_check_script(profile: Profile) -> str:
+    """Shell lines warning about env: key references that are unset on the server"""
+    names = [reference[len('env:'):] for reference in profile.keys.values() if reference.startswith('env:')]
+    if not names:
+        return ""
+    return (f"for key in {' '.join(names)}; do\n"
+            f"    if [ -z \"${{!key}}\" ]; then echo \"⚠️  $key is not set; the {profile.name} profile reads it\"; fi\n"
+            f"done\n")
+
 def deploy_full_pipeline(project: FlashFlowProject, env: str, edge: bool):
     """Run full deployment pipeline: build, test, deploy"""
     
@@ -160,6 +181,9 @@ def create_deployment_package(project: FlashFlowProject, env: str) -> str:
             if config_path.exists():
                 zipf.write(config_path, config_file)
         
+        # The profile this package was made for, whatever dist/ was last built with
+        zipf.writestr(PROFILE_FILE, json.dumps(load_profile(project, env).public(), indent=2))
+        
         # Add deployment script
         deploy_script = create_deployment_script(project, env)
         zipf.writestr("deploy.sh", deploy_script)
@@ -183,7 +207,8 @@ def create_deployment_script(project: FlashFlowProject, env: str) -> str:
 # Generated: $(date)
 
 echo "🚀 Deploying {project.config.name} to {env}..."
-
+export FLASHFLOW_ENV={env}
+{key_check_script(load_profile(project, env))}
 # Set permissions
 echo "🔧 Setting permissions..."
 chmod -R 755 dist/
@@ -354,6 +379,8 @@ def create_franklinphp_deployment_package(project: FlashFlowProject, env: str) -
             if config_path.exists():
                 zipf.write(config_path, config_file)
         
+        zipf.writestr(PROFILE_FILE, json.dumps(load_profile(project, env).public(), indent=2))
+        
         # Add FranklinPHP specific files
         backend_path = project.dist_path / "backend"
         franklinphp_files = [
@@ -390,6 +417,7 @@ def create_franklinphp_deployment_script(project: FlashFlowProject, env: str) ->
 # Generated: $(date)
 
 echo "🚀 Deploying {project.config.name} with FranklinPHP..."
+{key_check_script(load_profile(project, env))}
 
 # Check if running as root
 if [ "$EUID" -ne 0 ]; then
@@ -448,6 +476,7 @@ docker run -d \\
     -p 8000:8000 \\
     -v $APP_DIR:/app \\
     -e APP_ENV={env} \\
+    -e FLASHFLOW_ENV={env} \\
     {project.config.name.lower()}-franklinphp
 
 echo "✅ FranklinPHP deployment completed!"
@@ -0,0 +1,46 @@
+"""
+FlashFlow 'generate' command - Scaffold editable code from the flows
+"""
+
+import click
+import sys
+from pathlib import Path
+
+from core.admin_scaffold import URL_PREFIX, AdminScaffold, AdminScaffoldError
+from core.framework import FlashFlowProject
+from core.parser.parser import FlowParser
+from cli.utils.output import get_output
+
+@click.group()
+def generate():
+    """Scaffold files from the flows that you then own and edit"""
+    pass
+
+@generate.command('admin')
+@click.option('--model', '-m', 'models', multiple=True, help='Only this model (repeatable); default is every model')
+@click.option('--force', is_flag=True, help='Also overwrite pages you have edited since they were generated')
+@click.pass_context
+def generate_admin(ctx, models, force):
+    """List, detail and edit screens for every model, served at /admin/models/ by 'flashflow serve'"""
+    out = get_output()
+    project = FlashFlowProject(ctx.obj.get('project_root') or Path.cwd())
+    if not project.exists():
+        click.echo("❌ Not in a FlashFlow project directory", err=True)
+        sys.exit(1)
+
+    scaffold = AdminScaffold(project)
+    try:
+        report = scaffold.generate(FlowParser().parse_project(project.root_path), list(models), force)
+    except AdminScaffoldError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+
+    target = scaffold.target.relative_to(project.root_path)
+    for relative in report['written']:
+        out.echo(f"   ✏️  {target / relative}")
+    for relative in report['kept']:
+        out.echo(f"   ✋ {target / relative} (edited; kept, --force to overwrite)")
+    out.echo(f"✅ Admin: {len(report['written'])} written, {len(report['unchanged'])} unchanged, "
+             f"{len(report['kept'])} kept")
+    out.echo(f"   Open {URL_PREFIX}/ on 'flashflow serve' to manage your models")
+    out.emit(dict(report, directory=str(target)))
@@ -0,0 +1,67 @@
+"""
+FlashFlow 'import' command - Bring existing API descriptions into the flows
+
+    flashflow import openapi <file|url>   models and endpoints from an OpenAPI 3 or Swagger 2.0 spec
+"""
+
+import click
+import sys
+from pathlib import Path
+
+from core.framework import FlashFlowProject
+from core.openapi_import import (
+    STRATEGIES, OpenApiImporter, OpenApiImportError, imported_files, load_spec, project_declarations,
+    project_files
+)
+from cli.utils.output import get_output
+
+@click.group('import')
+def importer():
+    """Turn existing API descriptions into flow declarations"""
+    pass
+
+@importer.command('openapi')
+@click.argument('source')
+@click.option('--prefix', default='', help='Path prefix for every imported endpoint, e.g. /api')
+@click.option('--on-conflict', type=click.Choice(STRATEGIES), default='skip',
+              help="Models the project already declares: keep the project's (skip) or import as <Name>Imported (rename)")
+@click.option('--dry-run', is_flag=True, help='Print the report without writing any flows')
+@click.option('--force', is_flag=True, help='Also overwrite imported flows you have edited since')
+@click.pass_context
+def import_openapi(ctx, source, prefix, on_conflict, dry_run, force):
+    """Models and endpoints from SOURCE, an OpenAPI 3 or Swagger 2.0 spec (JSON or YAML, file or URL)"""
+    out = get_output()
+    project = FlashFlowProject(ctx.obj.get('project_root') or Path.cwd())
+    if not project.exists():
+        click.echo("❌ Not in a FlashFlow project directory", err=True)
+        sys.exit(1)
+
+    try:
+        spec_importer = OpenApiImporter(load_spec(source), source, prefix)
+    except OpenApiImportError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+
+    imported = imported_files(project.flows_path, source)
+    models, routes = project_declarations(project.flows_path, imported)
+    files = spec_importer.plan(models, routes, on_conflict, project_files(project.flows_path, imported))
+    spec_importer.write(project.flows_path, files, force, dry_run)
+    report = spec_importer.report
+
+    out.echo(f"📥 {report.version} spec: {len(report.models)} models, {len(report.endpoints)} endpoints")
+    target = project.flows_path.relative_to(project.root_path)
+    for name in report.files['written']:
+        out.echo(f"   ✏️  {target / name}" + (" (dry run)" if dry_run else ""))
+    for name in report.files['kept']:
+        out.echo(f"   ✋ {target / name} (edited; kept, --force to overwrite)")
+    if report.conflicts:
+        out.echo(f"⚠️  {len(report.conflicts)} conflicts:")
+        for conflict in report.conflicts:
+            out.echo(f"   {conflict['kind']} {conflict['name']} is declared in {conflict['existing']}: {conflict['resolution']}")
+            for difference in conflict.get('differences', []):
+                out.echo(f"      - {difference}")
+    for warning in report.warnings:
+        out.echo(f"   ⚠️  {warning}")
+    out.echo(f"✅ Import: {len(report.files['written'])} written, {len(report.files['unchanged'])} unchanged, "
+             f"{len(report.files['kept'])} kept")
+    out.emit(dict(report.to_dict(), dry_run=dry_run))
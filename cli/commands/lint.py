@@ -0,0 +1,87 @@
+"""
+FlashFlow 'lint' command - Style and best-practice checks for .flow files
+"""
+
+import click
+import json
+import sys
+from pathlib import Path
+
+from core.flow_lint import FlowLinter, LintConfigError, RULES, lint_summary, to_sarif
+from core.framework import FlashFlowProject
+from cli.core import __version__
+from cli.utils.output import get_output
+
+SEVERITY_ICONS = {'error': '❌', 'warning': '⚠️ ', 'info': 'ℹ️ '}
+
+@click.command()
+@click.argument('files', nargs=-1, type=click.Path(exists=True, dir_okay=False))
+@click.option('--format', 'output_format', type=click.Choice(['text', 'json', 'sarif']), default='text',
+              help='text for people, json or sarif (2.1.0) for editors and CI')
+@click.option('--output', '-o', default=None, help='Write the json or sarif result to this file instead of stdout')
+@click.option('--strict', is_flag=True, help='Also fail on warnings')
+@click.option('--show-suppressed', is_flag=True, help="List issues silenced by '# flashflow-lint: disable' comments too")
+@click.option('--rules', 'list_rules', is_flag=True, help='List the rules and their severities, then exit')
+@click.pass_context
+def lint(ctx, files, output_format, output, strict, show_suppressed, list_rules):
+    """Check .flow files (default: all of src/flows) against the lint rules"""
+    out = get_output()
+    project = FlashFlowProject(ctx.obj.get('project_root') or Path.cwd())
+    if not project.exists():
+        click.echo("❌ Not in a FlashFlow project directory", err=True)
+        sys.exit(1)
+
+    try:
+        linter = FlowLinter(project.root_path, project.config.lint)
+    except LintConfigError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+
+    if list_rules:
+        for rule in RULES:
+            out.echo(f"   {rule.id:<18} {linter.settings[rule.id]['severity']:<8} {rule.description}")
+        out.emit({'rules': [dict(id=rule.id, description=rule.description, **linter.settings[rule.id]) for rule in RULES]})
+        return
+
+    flow_files = [Path(file) for file in files] or project.get_flow_files()
+    if not flow_files:
+        click.echo("⚠️  No .flow files found in src/flows/")
+        return
+
+    issues = linter.lint(flow_files)
+    summary = lint_summary(issues)
+    failed = summary['errors'] or (strict and summary['warnings'])
+
+    if output_format != 'text':
+        if output_format == 'sarif':
+            result = to_sarif(issues, linter.settings, __version__)
+        else:
+            result = {'issues': [issue.to_dict() for issue in issues], 'summary': summary}
+        text = json.dumps(result, indent=2)
+        if output:
+            Path(output).write_text(text + '\n', encoding='utf-8')
+            click.echo(f"📄 Wrote {output_format.upper()} to {output}", err=True)
+        else:
+            click.echo(text)
+        sys.exit(1 if failed else 0)
+
+    out.echo(f"🔍 Linting {len(flow_files)} .flow file(s)...")
+    current_file = None
+    for issue in issues:
+        if issue.suppressed and not show_suppressed:
+            continue
+        if issue.file != current_file:
+            current_file = issue.file
+            out.echo(f"\n   📄 {issue.file}")
+        color = None if issue.suppressed else {'error': 'red', 'warning': 'yellow'}.get(issue.severity)
+        line = f"      {issue.location()}: {SEVERITY_ICONS.get(issue.severity, '')} {issue.message} [{issue.rule}]"
+        out.echo(click.style(line + (' (suppressed)' if issue.suppressed else ''), fg=color, dim=issue.suppressed))
+        if issue.suggestion and not issue.suppressed:
+            out.echo(f"         💡 {issue.suggestion}")
+
+    suppressed = f", {summary['suppressed']} suppressed" if summary['suppressed'] else ''
+    icon = '❌' if failed else ('⚠️ ' if summary['warnings'] else '✅')
+    out.echo(f"\n{icon} {summary['errors']} error(s), {summary['warnings']} warning(s), {summary['info']} info{suppressed}")
+    out.emit({'issues': [issue.to_dict() for issue in issues], 'summary': summary})
+    if failed:
+        sys.exit(1)
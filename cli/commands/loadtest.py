@@ -0,0 +1,154 @@
+"""
+FlashFlow 'loadtest' command - HTTP load against the generated endpoints
+"""
+
+import click
+import json
+import sys
+from datetime import datetime
+from pathlib import Path
+
+from core.framework import FlashFlowProject
+from core.load_test import (LoadRunner, LoadTestError, LoadTestOptions, default_targets, model_tables, parse_target,
+                            PERCENTILES)
+from core.parser.parser import FlowParser
+from cli.commands.bench import compare_to_baseline, prune_runs
+from cli.utils.output import VERBOSE, get_output
+
+SUITE = 'loadtest'
+
+@click.command()
+@click.argument('targets', nargs=-1)
+@click.option('--url', '-u', default='http://localhost:8000', help='Server to load (default: the dev server)')
+@click.option('--duration', '-d', type=float, default=10.0, help='Seconds to run')
+@click.option('--concurrency', '-c', type=int, default=10, help='Workers sending requests at once')
+@click.option('--rate', '-r', type=float, default=0.0, help='Requests per second across all workers (default: as fast as possible)')
+@click.option('--payload', '-p', default=None, help='Body template for POST/PUT/PATCH targets: JSON, or @file.json')
+@click.option('--header', '-H', 'headers', multiple=True, help="Extra request header, 'Name: value' (repeatable)")
+@click.option('--timeout', type=float, default=10.0, help='Seconds to wait for each answer')
+@click.option('--threshold', '-t', default=20.0, type=float, help='Allowed slowdown versus baseline, in percent')
+@click.option('--save-baseline', is_flag=True, help='Store this run as the new baseline')
+@click.option('--no-save', is_flag=True, help="Do not store the run in the metrics history")
+@click.pass_context
+def loadtest(ctx, targets, url, duration, concurrency, rate, payload, headers, timeout, threshold, save_baseline, no_save):
+    """Send load to the server's endpoints and report latency percentiles
+
+    TARGETS are model tables ('users'), creates ('POST users') or any
+    '<METHOD> /path'; without them every model's list endpoint is loaded.
+    """
+    out = get_output()
+    project = FlashFlowProject(ctx.obj.get('project_root') or Path.cwd())
+    try:
+        tables = model_tables(FlowParser().parse_project(project.root_path)) if project.exists() else {}
+        body = read_payload(payload)
+        options = LoadTestOptions(url.rstrip('/'), duration, concurrency, rate, timeout, parse_headers(headers))
+        load_targets = [parse_target(spec, tables, body) for spec in targets] or default_targets(tables)
+        runner = LoadRunner(options, load_targets)
+    except LoadTestError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+    except ValueError as e:
+        click.echo(f"❌ Cannot read the flows: {str(e)}", err=True)
+        sys.exit(1)
+
+    pace = f"{rate:g} req/s" if rate else 'as fast as possible'
+    out.echo(f"🔥 Loading {url} for {duration:g}s with {concurrency} worker(s), {pace} (Ctrl+C stops early)")
+    for target in load_targets:
+        out.echo(f"   {target.name}" + (' (with body)' if target.body is not None else ''))
+        if target.body is not None:
+            out.echo(f"      {json.dumps(target.body)}", level=VERBOSE)
+    out.echo('')
+
+    report = runner.run_test(lambda seconds, totals: out.echo(progress_line(seconds, totals)))
+
+    out.echo(f"\n{'Target':<40} {'Requests':>9} {'Errors':>7} {'Req/s':>9} " +
+             ' '.join(f"{'p' + str(pct):>9}" for pct in PERCENTILES) + f" {'Max':>9}")
+    for name, summary in list(report.targets.items()) + [('all', report.total)]:
+        out.echo(summary_line(name, summary))
+    for name, summary in report.targets.items():
+        for failure, count in summary['failures'].items():
+            out.echo(f"⚠️  {name}: {count} × {failure}")
+        bad = {status: count for status, count in summary['statuses'].items() if int(status) >= 400}
+        if bad:
+            out.echo(f"⚠️  {name}: " + ', '.join(f"{count} × {status}" for status, count in sorted(bad.items())))
+    if report.total['requests'] and report.total['errors'] == report.total['requests']:
+        out.echo(f"❌ Every request failed; is the server running at {url}?")
+
+    data = report.to_dict()
+    regressions = []
+    if not no_save and report.total['requests'] and not report.interrupted:
+        regressions = save_run(project, report, data, threshold, save_baseline, out)
+    elif report.interrupted:
+        out.echo("\n⏹️  Stopped early; the run is not saved")
+    data['regressions'] = regressions
+    out.emit(data)
+    if regressions or not report.total['requests'] or report.total['errors'] == report.total['requests']:
+        sys.exit(1)
+
+def save_run(project, report, data, threshold, save_baseline, out):
+    """Store the run next to 'flashflow bench' runs and compare it to the baseline; returns the regressions"""
+    bench_dir = project.state.ensure() / 'bench'
+    bench_dir.mkdir(exist_ok=True)
+    run = {'timestamp': datetime.now().isoformat(), 'python': sys.version.split()[0], 'loadtest': data,
+           'results': report.bench_results()}
+    run_path = bench_dir / f"{SUITE}-{datetime.now().strftime('%Y%m%d_%H%M%S')}.json"
+    with open(run_path, 'w') as f:
+        json.dump(run, f, indent=2)
+    prune_runs(project.state, bench_dir)
+    out.echo(f"\n💾 Saved to {run_path.relative_to(project.root_path)} ('flashflow metrics history --suite {SUITE}' compares runs)")
+
+    baseline_path = bench_dir / f"{SUITE}-baseline.json"
+    if save_baseline or not baseline_path.exists():
+        with open(baseline_path, 'w') as f:
+            json.dump(run, f, indent=2)
+        out.echo(f"📌 Baseline saved to {baseline_path.relative_to(project.root_path)}")
+        return []
+    with open(baseline_path, 'r') as f:
+        baseline = json.load(f)
+    regressions = compare_to_baseline(run['results'], baseline.get('results', {}), threshold)
+    if regressions:
+        out.echo(f"❌ {len(regressions)} results regressed more than {threshold:g}%:")
+        for line in regressions:
+            out.echo(f"   {line}")
+    else:
+        out.echo(f"✅ No regressions beyond {threshold:g}% (baseline from {baseline.get('timestamp', 'unknown')})")
+    return regressions
+
+def read_payload(value):
+    """The --payload body: inline JSON or @file"""
+    if value is None:
+        return None
+    text = value
+    if value.startswith('@'):
+        try:
+            text = Path(value[1:]).read_text(encoding='utf-8')
+        except OSError as e:
+            raise LoadTestError(f"Cannot read {value[1:]}: {str(e)}")
+    try:
+        return json.loads(text)
+    except ValueError as e:
+        raise LoadTestError(f"--payload is not valid JSON: {str(e)}")
+
+def parse_headers(values):
+    headers = {}
+    for value in values:
+        name, separator, content = value.partition(':')
+        if not separator or not name.strip():
+            raise LoadTestError(f"Header '{value}' should look like 'Name: value'")
+        headers[name.strip()] = content.strip()
+    return headers
+
+def format_ms(value) -> str:
+    if value is None:
+        return '-'
+    return f"{value / 1000:.2f}s" if value >= 1000 else f"{value:.1f}ms"
+
+def progress_line(seconds: float, totals) -> str:
+    return (f"   {seconds:5.0f}s  {totals['requests']:>8} req  {totals['rps']:>8.1f}/s  "
+            f"p50 {format_ms(totals['p50_ms']):>8}  p95 {format_ms(totals['p95_ms']):>8}  "
+            f"p99 {format_ms(totals['p99_ms']):>8}  errors {totals['errors']}")
+
+def summary_line(name: str, summary) -> str:
+    return (f"{name[:40]:<40} {summary['requests']:>9} {summary['errors']:>7} {summary['rps']:>9.1f} " +
+            ' '.join(f"{format_ms(summary[f'p{pct}_ms']):>9}" for pct in PERCENTILES) +
+            f" {format_ms(summary['max_ms']):>9}")
@@ -0,0 +1,30 @@
+"""
+FlashFlow 'lsp' command - Language server for .flow files in editors
+"""
+
+import click
+import sys
+from pathlib import Path
+
+from core.language_server import FlowLanguageServer
+from cli.core import __version__
+
+@click.command()
+@click.option('--stdio', is_flag=True, help='Talk over stdin and stdout (the only transport; accepted because editors pass it)')
+@click.option('--log', 'log_path', default=None, type=click.Path(dir_okay=False), help='Also append log messages to this file')
+@click.pass_context
+def lsp(ctx, stdio, log_path):
+    """Run the language server an editor starts for .flow files"""
+    root = ctx.obj.get('project_root') or Path.cwd()
+    reader, writer = sys.stdin.buffer, sys.stdout.buffer
+    # stdout carries the protocol; anything printed by accident goes to the editor's log instead
+    sys.stdout = sys.stderr
+
+    def log(message: str):
+        click.echo(message, err=True)
+        if log_path:
+            with open(log_path, 'a', encoding='utf-8') as log_file:
+                log_file.write(message + '\n')
+
+    server = FlowLanguageServer(Path(root), log, __version__)
+    sys.exit(server.serve(reader, writer))
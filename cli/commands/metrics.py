@@ -0,0 +1,89 @@
+"""
+FlashFlow 'metrics' command - Retention and history of saved benchmark runs
+"""
+
+import click
+import sys
+from pathlib import Path
+
+from core.framework import FlashFlowProject
+from core.metrics_history import MetricsHistory, MetricsRetentionError, retention_settings
+from core.state import ProjectState
+from cli.commands.bench import format_ns
+from cli.utils.output import VERBOSE, get_output
+
+@click.group()
+def metrics():
+    """Prune saved benchmark runs and show their history"""
+    pass
+
+@metrics.command('prune')
+@click.option('--max-files', type=int, default=None, help='Run files kept as JSON per suite')
+@click.option('--max-age-days', type=float, default=None, help='Compact run files older than this')
+@click.option('--max-size-mb', type=float, default=None, help='Compact the oldest run files until the rest fit')
+@click.option('--history-days', type=float, default=None, help='Drop runs older than this from the history too')
+@click.option('--dry-run', is_flag=True, help='Only list what would be compacted')
+@click.pass_context
+def metrics_prune(ctx, max_files, max_age_days, max_size_mb, history_days, dry_run):
+    """Compact run files past the metrics_retention settings into history.sqlite"""
+    out = get_output()
+    history = bench_history(ctx)
+    try:
+        settings = retention_settings(project_config(ctx), max_files=max_files, max_age_days=max_age_days,
+                                      max_size_mb=max_size_mb, history_days=history_days)
+        report = history.prune(settings, dry_run=dry_run)
+    except MetricsRetentionError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+
+    if not report.compacted:
+        out.echo(f"✅ Nothing to compact; {report.kept} run file(s) within retention")
+    else:
+        verb = 'Would compact' if dry_run else 'Compacted'
+        out.echo(f"🗜️  {verb} {len(report.compacted)} run file(s) ({report.freed_bytes / 1024:.1f} KB) "
+                 f"into {history.history_path.name}; {report.kept} kept")
+        for path in report.compacted:
+            out.echo(f"   {path.name}", level=VERBOSE)
+    if report.dropped_runs:
+        out.echo(f"🧹 Dropped {report.dropped_runs} run(s) older than {settings['history_days']:g} days from the history")
+    for path in report.unreadable:
+        out.echo(f"⚠️  {path.name} is not valid JSON; left in place")
+    out.emit(dict(report.to_dict(), dry_run=dry_run, settings=settings))
+
+@metrics.command('history')
+@click.option('--suite', default=None, help='Only runs of this suite, such as flashcore')
+@click.option('--filter', '-f', 'name_filter', default=None, help='Only benchmarks whose name contains this text')
+@click.option('--limit', '-n', type=int, default=10, help='Newest runs to show')
+@click.pass_context
+def metrics_history(ctx, suite, name_filter, limit):
+    """Show benchmark results across runs, compacted or not"""
+    out = get_output()
+    try:
+        runs = bench_history(ctx).history(suite, name_filter, limit)
+    except MetricsRetentionError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+    if not runs:
+        out.echo("No saved benchmark runs; run 'flashflow bench flashcore' first")
+        out.emit({'runs': []})
+        return
+
+    names = sorted({name for run in runs for name in run['results']})
+    out.echo(f"📈 {len(runs)} run(s), oldest first:")
+    for name in names:
+        out.echo(f"\n   {name}")
+        for run in runs:
+            result = run['results'].get(name)
+            if result and result.get('ns_per_op') is not None:
+                where = '' if run['compacted'] else '  (file)'
+                out.echo(f"      {run['started_at'][:19].replace('T', ' ')}  {format_ns(result['ns_per_op']):>12}/op{where}")
+    out.emit({'runs': runs})
+
+def bench_history(ctx) -> MetricsHistory:
+    state = ProjectState(ctx.obj.get('project_root') or Path.cwd())
+    return MetricsHistory(state.dir / 'bench')
+
+def project_config(ctx):
+    """flashflow.json's settings, or None outside a project (the defaults apply then)"""
+    project = FlashFlowProject(ctx.obj.get('project_root') or Path.cwd())
+    return project.config if project.config_path.exists() else None
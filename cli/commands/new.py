@@ -4,14 +4,26 @@ FlashFlow 'new' command - Create new FlashFlow project
 
 import click
 import os
+import sys
 import json
+import tempfile
 from pathlib import Path
 
+from core.framework import FlashFlowProject
+from core.project_templates import TemplateError, TemplateSource, copy_template, fetch, placeholders, resolve
+from cli.utils.hooks import HookError, hook_entries, report_hook_failure, run_hooks
+
+PROJECT_DIRS = [("src",), ("src", "flows"), ("src", "components"), ("src", "tests"), ("src", "models"), ("dist",)]
+
 @click.command()
 @click.argument('project_name')
-@click.option('--template', '-t', default='basic', help='Project template (basic, todo, ecommerce)')
+@click.option('--template', '-t', default='basic',
+              help='basic, todo, ecommerce, a template registry name, a git URL, an archive URL or a directory')
+@click.option('--sha256', default=None, help='Expected SHA-256 of a template archive')
 @click.option('--author', '-a', default='', help='Project author name')
-def new(project_name, template, author):
+@click.option('--yes', '-y', is_flag=True, help="Run the template's post_init hooks without asking")
+@click.option('--no-hooks', is_flag=True, help="Do not run the template's post_init hooks")
+def new(project_name, template, sha256, author, yes, no_hooks):
     """Create a new FlashFlow project"""
     
     # Validate project name
@@ -26,39 +38,29 @@ def new(project_name, template, author):
         click.echo(f"❌ Directory '{project_name}' already exists")
         return
     
+    try:
+        source = resolve(template, sha256)
+    except TemplateError as e:
+        click.echo(f"❌ {str(e)}")
+        return
+    
     try:
         # Create project structure
         click.echo(f"🚀 Creating FlashFlow project: {project_name}")
         
-        # Main directories
-        project_path.mkdir()
-        (project_path / "src").mkdir()
-        (project_path / "src" / "flows").mkdir()
-        (project_path / "src" / "components").mkdir()
-        (project_path / "src" / "tests").mkdir()
-        (project_path / "src" / "models").mkdir()
-        (project_path / "dist").mkdir()
-        
-        # Create flashflow.json config
-        config = {
-            "name": project_name,
-            "version": "0.1.0",
-            "description": f"FlashFlow application: {project_name}",
-            "author": author or "FlashFlow Developer",
-            "frameworks": {
-                "backend": "laravel",
-                "frontend": "react",
-                "mobile": "flet",
-                "database": "sqlite"
-            },
-            "dependencies": []
-        }
-        
-        with open(project_path / "flashflow.json", 'w') as f:
-            json.dump(config, f, indent=2)
-        
-        # Create template files based on template type
-        create_template_files(project_path, template, project_name)
+        if source.kind == 'builtin':
+            # Main directories
+            project_path.mkdir()
+            for parts in PROJECT_DIRS:
+                project_path.joinpath(*parts).mkdir()
+            
+            with open(project_path / "flashflow.json", 'w') as f:
+                json.dump(default_config(project_name, author), f, indent=2)
+            
+            # Create template files based on template type
+            create_template_files(project_path, template, project_name)
+        else:
+            create_from_template(source, project_path, project_name, author)
         
         # Create .env.example
         env_content = """# FlashFlow Environment Configuration
@@ -85,8 +87,9 @@ FACEBOOK_APP_ID=
 FACEBOOK_APP_SECRET=
 """.format(project_name=project_name)
         
-        with open(project_path / ".env.example", 'w') as f:
-            f.write(env_content)
+        if not (project_path / ".env.example").exists():
+            with open(project_path / ".env.example", 'w') as f:
+                f.write(env_content)
         
         # Create README.md
         readme_content = f"""# {project_name}
@@ -108,7 +111,7 @@ A FlashFlow application built with single-syntax full-stack development.
 
 3. Open your browser to:
    - Web App: http://localhost:8000
-   - Admin Panel: http://localhost:8000/admin/cpanel
+   - Admin Panel: http://localhost:8000/admin/models/ (after 'flashflow generate admin')
    - API Docs: http://localhost:8000/api/docs
 
 ## Project Structure
@@ -130,12 +133,22 @@ A FlashFlow application built with single-syntax full-stack development.
 Visit the FlashFlow documentation for detailed guides and examples.
 """
         
-        with open(project_path / "README.md", 'w') as f:
-            f.write(readme_content)
+        if not (project_path / "README.md").exists():
+            with open(project_path / "README.md", 'w') as f:
+                f.write(readme_content)
+        
+        if source.kind != 'builtin' and not run_post_init(project_path, source, yes, no_hooks):
+            click.echo(f"⚠️  The project was created, but its post_init hooks did not finish; see above")
+            return
         
         # Display welcome message after successful creation
         display_welcome_message(project_name, project_path)
         
+    except TemplateError as e:
+        click.echo(f"❌ {str(e)}")
+        if project_path.exists():
+            import shutil
+            shutil.rmtree(project_path)
     except Exception as e:
         click.echo(f"❌ Error creating project: {str(e)}")
         # Cleanup on error
@@ -143,6 +156,84 @@ Visit the FlashFlow documentation for detailed guides and examples.
             import shutil
             shutil.rmtree(project_path)
 
+def default_config(project_name: str, author: str) -> dict:
+    return {
+        "name": project_name,
+        "version": "0.1.0",
+        "description": f"FlashFlow application: {project_name}",
+        "author": author or "FlashFlow Developer",
+        "frameworks": {
+            "backend": "laravel",
+            "frontend": "react",
+            "mobile": "flet",
+            "database": "sqlite"
+        },
+        "dependencies": []
+    }
+
+def create_from_template(source: TemplateSource, project_path: Path, project_name: str, author: str):
+    """Fetch a template, copy it into the project and give flashflow.json the project's name and author"""
+    click.echo(f"📦 Template: {source.label()}" + (f" - {source.description}" if source.description else ""))
+    with tempfile.TemporaryDirectory(prefix="flashflow-template-") as workdir:
+        if source.remote:
+            click.echo("   ⬇️  Downloading...")
+        template_dir = fetch(source, Path(workdir))
+        if source.kind == 'archive':
+            click.echo(f"   🔒 SHA-256 {source.sha256}" + (" verified" if source.verified else " (not pinned; pass --sha256 to verify)"))
+        elif source.verified:
+            click.echo(f"   🔒 Commit {source.ref} verified")
+        project_path.mkdir()
+        copied = copy_template(template_dir, project_path, placeholders(project_name, author or "FlashFlow Developer"))
+    click.echo(f"   ✅ {copied} files copied")
+    
+    config_path = project_path / "flashflow.json"
+    config = default_config(project_name, author)
+    if config_path.exists():
+        try:
+            with open(config_path, 'r') as f:
+                config = dict(json.load(f), name=project_name)
+        except (OSError, ValueError) as e:
+            raise TemplateError(f"The template's flashflow.json is not valid JSON: {e}")
+        if author:
+            config['author'] = author
+    with open(config_path, 'w') as f:
+        json.dump(config, f, indent=2)
+    
+    for parts in PROJECT_DIRS:
+        project_path.joinpath(*parts).mkdir(parents=True, exist_ok=True)
+
+def run_post_init(project_path: Path, source: TemplateSource, yes: bool, no_hooks: bool) -> bool:
+    """Run the template's post_init hooks, asking first since they come from someone else's repository"""
+    project = FlashFlowProject(project_path)
+    try:
+        entries = hook_entries(project, 'post_init')
+    except HookError as e:
+        report_hook_failure(e)
+        return False
+    if not entries:
+        return True
+    
+    click.echo("\n🪝 The template has post_init hooks:")
+    for entry in entries:
+        click.echo(f"   {entry if isinstance(entry, str) else json.dumps(entry)}")
+    if no_hooks:
+        click.echo("   Skipped (--no-hooks).")
+        return True
+    if not yes:
+        if not sys.stdin.isatty():
+            click.echo("   Skipped: not a terminal. Pass --yes to run them.")
+            return True
+        if not click.confirm("   Run them now?", default=source.kind == 'directory'):
+            click.echo("   Skipped.")
+            return True
+    
+    try:
+        run_hooks(project, 'post_init', {'FLASHFLOW_TEMPLATE': source.label()})
+    except HookError as e:
+        report_hook_failure(e)
+        return False
+    return True
+
 def display_welcome_message(project_name: str, project_path: Path):
     """Display a Laravel-style welcome message after project creation"""
     
@@ -0,0 +1,30 @@
+"""
+FlashFlow 'plugins' command - List flashflow-<name> plugin executables
+"""
+
+import click
+
+from cli.utils.output import get_output
+from cli.utils.plugins import PLUGIN_PREFIX, discover_plugins
+
+@click.command()
+@click.pass_context
+def plugins(ctx):
+    """List plugins found in .flashflow/plugins and on PATH"""
+    out = get_output()
+    project_root = ctx.obj.get('project_root')
+    found = discover_plugins(project_root)
+    builtin = set(ctx.parent.command.commands) if ctx.parent else set()
+
+    if not found:
+        out.echo(f"🔌 No plugins found. Put an executable named {PLUGIN_PREFIX}<name> on PATH"
+                 + (" or in .flashflow/plugins" if project_root else ""))
+    else:
+        out.echo(f"🔌 {len(found)} plugin(s):")
+        width = max(len(name) for name in found)
+        for name, plugin in sorted(found.items()):
+            note = "  ⚠️  hidden by the built-in command" if name in builtin else ""
+            out.echo(f"   {name.ljust(width)}  [{plugin.source}] {plugin.path}{note}")
+
+    out.emit({'plugins': [dict(plugin.to_dict(), shadowed=name in builtin)
+                          for name, plugin in sorted(found.items())]})
@@ -12,9 +12,10 @@ sys.path.insert(0, str(Path(__file__).parent.parent / "flashflow-main"))
 
 from services.flet_preview import FletPreviewService
 from core.framework import FlashFlowProject
+from core.settings import SettingsError, resolve_settings
 
 @click.command()
-@click.option('--port', '-p', default=8010, help='Port to serve the preview on')
+@click.option('--port', '-p', default=None, help='Port to serve the preview on (default: FLASHFLOW_PREVIEW_PORT, preview.port in flashflow.json, else 8010)')
 def preview(port):
     """Run Flet live preview service"""
     
@@ -24,6 +25,12 @@ def preview(port):
         click.echo("❌ Not in a FlashFlow project directory")
         return
     
+    try:
+        port = resolve_settings(project, {'preview.port': port}, keys=['preview.port']).port('preview.port')
+    except SettingsError as e:
+        click.echo(f"❌ {str(e)}")
+        sys.exit(1)
+    
     try:
         click.echo(f"📱 Starting Flet Live Preview Service for: {project.config.name}")
         click.echo(f"🌐 Preview available at: http://localhost:{port}")
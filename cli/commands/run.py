@@ -0,0 +1,150 @@
+"""
+FlashFlow 'run' command - Project scripts from flashflow.json
+
+    "scripts": {
+        "lint": "ruff check src",
+        "preseed": "flashflow migrate",
+        "seed": {"command": "python scripts/seed.py", "env": {"SEED_COUNT": "50"}},
+        "e2e": ["flashflow build", "npx playwright test"]
+    }
+
+Like npm, 'pre<name>' and 'post<name>' scripts run around '<name>', and extra
+arguments after the script name are appended to its (last) command.
+"""
+
+import click
+import os
+import shlex
+import subprocess
+import sys
+from pathlib import Path
+from typing import Any, Dict, List, Optional
+
+from core.framework import FlashFlowProject
+from core.profiles import load_dotenv
+from core.tracing import configure_tracing, get_tracer
+from core.vendor import vendor_env
+
+class ScriptError(Exception):
+    """Raised for a script that is missing or malformed in flashflow.json"""
+    pass
+
+@click.command(context_settings={'ignore_unknown_options': True, 'allow_interspersed_args': False})
+@click.argument('script', required=False)
+@click.argument('args', nargs=-1, type=click.UNPROCESSED)
+@click.option('--no-hooks', is_flag=True, help='Skip pre/post scripts')
+@click.pass_context
+def run(ctx, script, args, no_hooks):
+    """Run a script defined in flashflow.json"""
+    project = FlashFlowProject(ctx.obj.get('project_root') or Path.cwd())
+    if not project.exists():
+        click.echo("❌ Not in a FlashFlow project directory")
+        sys.exit(1)
+
+    scripts = project.config.scripts or {}
+    if not script:
+        list_scripts(scripts)
+        return
+
+    if script not in scripts:
+        click.echo(f"❌ Unknown script '{script}'")
+        list_scripts(scripts)
+        sys.exit(1)
+
+    configure_tracing(project, 'flashflow-run')
+
+    plan = [script] if no_hooks else [name for name in (f"pre{script}", script, f"post{script}") if name in scripts]
+    for name in plan:
+        try:
+            exit_code = run_script(project, name, scripts[name], list(args) if name == script else [])
+        except ScriptError as e:
+            click.echo(f"❌ {str(e)}")
+            sys.exit(1)
+        if exit_code != 0:
+            click.echo(f"❌ Script '{name}' failed with exit code {exit_code}")
+            sys.exit(exit_code)
+
+def list_scripts(scripts: Dict[str, Any]):
+    if not scripts:
+        click.echo("No scripts defined. Add a \"scripts\" map to flashflow.json.")
+        return
+    click.echo("📜 Available scripts:")
+    for name, definition in scripts.items():
+        try:
+            summary = " && ".join(normalize_script(name, definition)['commands'])
+        except ScriptError:
+            summary = "(invalid)"
+        click.echo(f"   {name:<20} {summary}")
+
+def normalize_script(name: str, definition: Any) -> Dict[str, Any]:
+    """Accept a command string, a list of commands, or {command, env, cwd}"""
+    env: Dict[str, str] = {}
+    cwd: Optional[str] = None
+    if isinstance(definition, dict):
+        env = {str(key): str(value) for key, value in (definition.get('env') or {}).items()}
+        cwd = definition.get('cwd')
+        definition = definition.get('command')
+
+    if isinstance(definition, str):
+        commands = [definition]
+    elif isinstance(definition, list) and all(isinstance(command, str) for command in definition):
+        commands = list(definition)
+    else:
+        raise ScriptError(f"Script '{name}' must be a command string, a list of commands or an object with 'command'")
+
+    if not commands or not all(command.strip() for command in commands):
+        raise ScriptError(f"Script '{name}' has an empty command")
+    return {'commands': commands, 'env': env, 'cwd': cwd}
+
+def run_script(project: FlashFlowProject, name: str, definition: Any, args: List[str]) -> int:
+    """Run each command of a script in the shell, stopping at the first failure"""
+    script = normalize_script(name, definition)
+    commands = script['commands']
+    if args:
+        commands[-1] = f"{commands[-1]} {quote_args(args)}"
+
+    cwd = project.root_path / script['cwd'] if script['cwd'] else project.root_path
+    env = script_env(project, name)
+    env.update(script['env'])
+
+    with get_tracer().span(f"script {name}", attributes={'flashflow.script': name}) as span:
+        for command in commands:
+            click.echo(f"▶️  {name}: {command}")
+            exit_code = subprocess.call(shell_command(command), cwd=cwd, env=get_tracer().inject_env(dict(env)))
+            if exit_code != 0:
+                span.set_error(f"exit code {exit_code}")
+                return exit_code
+    return 0
+
+def script_env(project: FlashFlowProject, name: str) -> Dict[str, str]:
+    """Process environment plus the project's .env and FlashFlow variables"""
+    env = os.environ.copy()
+    for key, value in load_dotenv(project.root_path / ".env").items():
+        env.setdefault(key, value)
+
+    env['FLASHFLOW_PROJECT_ROOT'] = str(project.root_path)
+    env['FLASHFLOW_PROJECT_NAME'] = project.config.name
+    env['FLASHFLOW_SCRIPT'] = name
+    env['FLASHFLOW_STATE_DIR'] = str(project.state.ensure())
+
+    # Tools vendored with 'flashflow vendor' come first, then locally installed
+    # ones (eslint, playwright, ...) as with npm run
+    vendor_env(project, env)
+    node_bin = project.root_path / "node_modules" / ".bin"
+    if node_bin.is_dir():
+        env['PATH'] = f"{node_bin}{os.pathsep}{env.get('PATH', '')}"
+    return env
+
+def shell_command(command: str) -> List[str]:
+    """Argv running a command through the platform shell (or FLASHFLOW_SCRIPT_SHELL)"""
+    shell = os.environ.get('FLASHFLOW_SCRIPT_SHELL')
+    if os.name == 'nt':
+        shell = shell or os.environ.get('COMSPEC', 'cmd.exe')
+        flag = '/d /s /c' if Path(shell).name.lower() == 'cmd.exe' else '-c'
+        return [shell, *flag.split(), command]
+    return [shell or '/bin/sh', '-c', command]
+
+def quote_args(args: List[str]) -> str:
+    if os.name == 'nt':
+        return subprocess.list2cmdline(args)
+    return " ".join(shlex.quote(arg) for arg in args)
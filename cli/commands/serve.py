@@ -4,48 +4,86 @@ FlashFlow 'serve' command - Run unified development server
 """
 
 import click
+import socket
 import subprocess
 import threading
 import time
 import os
 import sys
+import webbrowser
 from pathlib import Path
-from flask import Flask, render_template_string, jsonify, send_from_directory
-from flask_cors import CORS
+from typing import Any, Iterable, Optional
+from urllib.parse import urlparse
+from flask import render_template_string, send_from_directory
 import flask
 
 # Import Flet preview service
 from services.flet_preview import FletPreviewService
 
+from core.backend_targets import BackendTargetError
 from core.framework import FlashFlowProject
+from core.security_headers import CSP_MODES
+from core.crashes import get_crash_reporter
+from core.file_watch import DEFAULT_POLL_INTERVAL
+from core.html_safety import escape_html
+from core.parser.flow_file import load_flow
+from core.profiles import Profile, ProfileError, load_profile, profile_env, project_environ
+from core.proxy import ProxyConfig, ProxyError
+from core.settings import SettingsError, resolve_settings
+from core.state import StateLockError
+from core.tracing import configure_tracing, get_tracer
+from core.vendor import vendor_env
+from cli.utils.go_services import verified_service_binary
+from cli.utils.tunnel import LocalTunnel, TunnelError, DEFAULT_RELAY, SHARED_PATHS
+from cli.utils.hooks import HookError, report_hook_failure, run_hooks
 import subprocess
 import os
 from pathlib import Path
 
-# Import user activity and notification services
-from src.services.api_endpoints import register_api_endpoints
+from cli.devserver.server import DevServer, with_allowed_hosts, with_host, with_port, with_routes
+from cli.devserver.restart import ServerRestarter
+from cli.devserver.chaos import get_chaos
+from cli.devserver.generated_backend import GeneratedBackend
+from cli.devserver.mailbox import DEFAULT_SMTP_PORT
 
-def check_go_service_available(service_name):
-    """Check if a Go service executable is available."""
-    service_path = Path(__file__).parent.parent.parent / "go-services" / service_name / f"{service_name}.exe"
-    return service_path.exists()
+# Names accepted by --open, besides any path starting with '/'
+OPEN_TARGETS = {
+    'welcome': '/',
+    'dashboard': '/dashboard',
+    'preview': '/preview',
+    'android': '/android',
+    'ios': '/ios',
+    'desktop': '/desktop',
+    'admin': '/admin/models/'
+}
+OPEN_WAIT_SECONDS = 30
 
-def run_go_dev_server(host, port):
+def check_go_service_available(service_name, project_root=None):
+    """Check if a Go service executable is available and passes checksum verification."""
+    return verified_service_binary(service_name, warn=click.echo, project_root=project_root) is not None
+
+def run_go_dev_server(project: FlashFlowProject, host, port, extra_env=None, skip_build=False):
     """Run the Go development server if available."""
     try:
         # Determine the path to the dev server executable
-        dev_server_path = Path(__file__).parent.parent.parent / "go-services" / "dev-server" / "dev-server.exe"
+        dev_server_path = verified_service_binary("dev-server", project_root=project.root_path)
         
-        if not dev_server_path.exists():
+        if dev_server_path is None:
             return False
             
         # Prepare arguments
-        args = [str(dev_server_path), str(Path.cwd())]
+        args = [str(dev_server_path), str(project.root_path)]
         
         # Set environment variables
         env_vars = os.environ.copy()
         env_vars["FLASHFLOW_HOST"] = host
         env_vars["FLASHFLOW_PORT"] = str(port)
+        env_vars["FLASHFLOW_STATE_DIR"] = str(project.state.ensure())
+        if skip_build:
+            # The Go server otherwise builds every platform before it starts serving
+            env_vars["FLASHFLOW_SKIP_BUILD"] = "1"
+        env_vars.update(extra_env or {})
+        get_tracer().inject_env(env_vars)
         
         # Run the Go dev server
         subprocess.run(args, env=env_vars)
@@ -59,45 +97,190 @@ def run_go_dev_server(host, port):
 @click.option('--all', 'serve_all', is_flag=True, help='Serve all components (recommended)')
 @click.option('--backend', is_flag=True, help='Serve backend only')
 @click.option('--frontend', is_flag=True, help='Serve frontend only')
-@click.option('--port', '-p', default=8000, help='Port to serve on')
-@click.option('--host', '-h', default='localhost', help='Host to serve on')
+@click.option('--port', '-p', default=None, help='Port to serve on (default: FLASHFLOW_PORT, serve.port in flashflow.json, else 8000)')
+@click.option('--host', '-h', default=None, help='Host to serve on (default: FLASHFLOW_HOST, serve.host in flashflow.json, else localhost)')
 @click.option('--auto-start-engine', is_flag=True, help='Automatically start FlashFlow Engine')
-def serve(serve_all, backend, frontend, port, host, auto_start_engine):
+@click.option('--share', is_flag=True, help='Expose the server through a public HTTPS tunnel')
+@click.option('--share-relay', default=lambda: os.environ.get('FLASHFLOW_SHARE_RELAY', DEFAULT_RELAY), help='Tunnel relay (localtunnel-compatible) used by --share')
+@click.option('--subdomain', default=None, help='Requested subdomain for the shared URL')
+@click.option('--a11y', is_flag=True, help='Audit rendered pages with axe-core (report at /admin/a11y)')
+@click.option('--smtp-port', default=DEFAULT_SMTP_PORT, type=int, help='Port of the dev SMTP sink feeding /admin/mailbox (0 to disable)')
+@click.option('--strict-schema', is_flag=True, help='Reject /api/data request bodies with fields the model does not declare')
+@click.option('--csp', type=click.Choice(CSP_MODES), default=None, help='Content-Security-Policy mode (default: flashflow.json, else report-only)')
+@click.option('--env', '-e', default=None, help='Environment profile from flashflow.json (default: FLASHFLOW_ENV, default_profile, else development)')
+@click.option('--open', 'open_target', is_flag=False, flag_value='welcome', default=None,
+              help=f"Open the browser once the server is up, at a route or one of: {', '.join(OPEN_TARGETS)} (default: welcome)")
+@click.option('--no-build', is_flag=True, help='Skip the startup build (the Go dev server\'s build of every platform, or the in-process dist/web build)')
+@click.option('--api-workers', default=0, type=click.IntRange(0, 32), help='Serve /api/data from this many worker processes (0: in the server itself)')
+@click.option('--generated-backend', is_flag=True, help="Proxy /api/data to the backend 'flashflow build' generated for frameworks.backend")
+@click.option('--poll', is_flag=False, flag_value=str(DEFAULT_POLL_INTERVAL), default=None,
+              help="Poll for file changes, every N seconds (default 1), for NFS/SMB/Docker volumes; 'auto' or 'off' (default: watch.poll)")
+@click.option('--https-redirect/--no-https-redirect', default=None,
+              help='Redirect plain HTTP that came through a trusted proxy to https:// (default: proxy.https_redirect)')
+@click.pass_context
+def serve(ctx, serve_all, backend, frontend, port, host, auto_start_engine, share, share_relay, subdomain, a11y, smtp_port, strict_schema, csp, env,
+          open_target, no_build, api_workers, generated_backend, poll, https_redirect):
     """Run unified development server"""
     
+    if generated_backend and api_workers:
+        raise click.UsageError("--generated-backend and --api-workers both serve /api/data; pick one")
+    
     # Check if we're in a FlashFlow project
-    project = FlashFlowProject(Path.cwd())
+    project = FlashFlowProject(ctx.obj.get('project_root') or Path.cwd())
     if not project.exists():
         click.echo("❌ Not in a FlashFlow project directory")
         return
     
-    # Try to use Go development server if available for better performance
-    if not backend and not frontend and check_go_service_available("dev-server"):
-        click.echo("🚀 Using optimized Go development server for better performance...")
-        if run_go_dev_server(host, port):
-            return
+    open_path = None
+    if open_target:
+        open_path = open_target if open_target.startswith('/') else OPEN_TARGETS.get(open_target.lower())
+        if open_path is None:
+            click.echo(f"❌ Unknown --open target '{open_target}'. Use a path such as /orders or one of: {', '.join(OPEN_TARGETS)}")
+            sys.exit(1)
+    
+    try:
+        settings = resolve_settings(project, {'serve.host': host, 'serve.port': port, 'watch.poll': poll})
+    except SettingsError as e:
+        click.echo(f"❌ {str(e)}")
+        sys.exit(1)
+    host, port, engine_port = settings.host('serve.host'), settings.port('serve.port'), settings.port('engine.port')
+    poll = settings.get('watch.poll')
+    
+    try:
+        ProxyConfig.for_project(project, https_redirect)
+    except ProxyError as e:
+        click.echo(f"❌ {str(e)}")
+        sys.exit(1)
+    
+    try:
+        profile = load_profile(project, env)
+    except ProfileError as e:
+        click.echo(f"❌ {str(e)}")
+        sys.exit(1)
+    missing = profile.missing_keys(project.root_path, project_environ(project))
+    if missing:
+        click.echo(f"⚠️  Keys not set for '{profile.name}': {', '.join(missing)}")
+    
+    # A restart re-executes this command, so the new configuration must load first
+    restarter = ServerRestarter(check=lambda: load_profile(FlashFlowProject(project.root_path), env))
+    if restarter.restarted_because:
+        click.echo(f"🔄 Dev server restarted ({restarter.restarted_because})")
+    
+    # Only one dev server per project; the OS drops the lock if that server dies
+    serve_lock = project.state.lock('serve')
+    try:
+        serve_lock.acquire()
+    except StateLockError:
+        running = project.state.read_runtime('serve') or {}
+        where = f" on http://{running['host']}:{running['port']}" if running.get('port') else ""
+        click.echo(f"❌ This project is already being served{where} (pid {running.get('pid', 'unknown')})")
+        click.echo("   Stop that server first, or serve another project")
+        sys.exit(1)
+    project.state.write_runtime('serve', {'host': host, 'port': port, 'env': profile.name})
+    configure_tracing(project, 'flashflow-dev-server')
+    
+    tunnel = None
+    restart = False
+    tunnel_hosts = []
+    if share:
+        tunnel = start_share_tunnel(host, port, share_relay, subdomain)
+        restarter.shared = tunnel is not None
+        if tunnel:
+            tunnel_hosts.append(urlparse(tunnel.url).hostname)
+    # A restart keeps the browser tabs it already has
+    if open_path and not restarter.restarted_because:
+        open_when_ready(host, port, open_path)
     
     try:
+        try:
+            run_hooks(project, 'pre_serve', dict(profile_env(project, profile), FLASHFLOW_HOST=host, FLASHFLOW_PORT=str(port)))
+        except HookError as e:
+            report_hook_failure(e)
+            sys.exit(1)
+        
+        # Try to use Go development server if available for better performance
+        if not backend and not frontend and check_go_service_available("dev-server", project.root_path):
+            click.echo("🚀 Using optimized Go development server for better performance...")
+            if run_go_dev_server(project, host, port, dict(profile_env(project, profile), FLASHFLOW_WATCH_POLL=str(poll)), no_build):
+                return
+        
         if serve_all:
             click.echo(f"🚀 Starting FlashFlow unified server for: {project.config.name}")
-            start_unified_server(project, host, port, auto_start_engine)
+            restart = start_unified_server(project, host, port, auto_start_engine, a11y, smtp_port, strict_schema, csp, profile, restarter,
+                                           api_workers, generated_backend, no_build, engine_port, poll, https_redirect,
+                                           tunnel_hosts)
         elif backend:
             click.echo("🔧 Starting backend server only...")
-            start_backend_only(project, host, port)
+            start_backend_only(project, host, port, profile)
         elif frontend:
             click.echo("🎨 Starting frontend server only...")
             start_frontend_only(project, host, port)
         else:
             # Default to unified server
             click.echo(f"🚀 Starting FlashFlow unified server for: {project.config.name}")
-            start_unified_server(project, host, port, auto_start_engine)
+            restart = start_unified_server(project, host, port, auto_start_engine, a11y, smtp_port, strict_schema, csp, profile, restarter,
+                                           api_workers, generated_backend, no_build, engine_port, poll, https_redirect,
+                                           tunnel_hosts)
             
     except KeyboardInterrupt:
         click.echo("\n🛑 Server stopped")
     except Exception as e:
         click.echo(f"❌ Server error: {str(e)}")
+        reporter = get_crash_reporter()
+        report_path = reporter.report(e) if reporter else None
+        if report_path:
+            click.echo(f"🧯 Crash report saved; see: flashflow crashes show {report_path.stem}")
+    finally:
+        if tunnel:
+            tunnel.close()
+        project.state.clear_runtime('serve')
+        serve_lock.release()
+    
+    # Everything is shut down; only the listening socket stays open across exec
+    if restart:
+        restarter.exec_restart()
+
+def open_when_ready(host: str, port: int, path: str):
+    """Open the default browser at path as soon as the server accepts connections"""
+    # A wildcard bind is reachable on localhost; browsers cannot open 0.0.0.0 everywhere
+    browse_host = 'localhost' if host in ('0.0.0.0', '::', '') else host
+    url = f"http://{browse_host}:{port}{path}"
+    
+    def wait_and_open():
+        deadline = time.monotonic() + OPEN_WAIT_SECONDS
+        while time.monotonic() < deadline:
+            try:
+                with socket.create_connection((browse_host, port), timeout=1):
+                    break
+            except OSError:
+                time.sleep(0.25)
+        else:
+            click.echo(f"⚠️  The server did not come up within {OPEN_WAIT_SECONDS}s; not opening {url}")
+            return
+        if not webbrowser.open(url):
+            click.echo(f"⚠️  Could not open a browser; visit {url}")
+    
+    threading.Thread(target=wait_and_open, name="flashflow-open", daemon=True).start()
+
+def start_share_tunnel(host: str, port: int, relay: str, subdomain: str = None):
+    """Open a public tunnel to the dev server and print the shared URL"""
+    # The tunnel connects to the server locally, so a wildcard bind still
+    # needs a concrete address to dial
+    local_host = 'localhost' if host in ('0.0.0.0', '::') else host
+    
+    # Only the app is shared; the dev server's own tools stay on this machine
+    tunnel = LocalTunnel(port, local_host, relay, subdomain, shared=SHARED_PATHS)
+    try:
+        tunnel.open()
+    except TunnelError as e:
+        click.echo(f"⚠️  Could not open share tunnel: {str(e)}")
+        return None
+    
+    click.echo(f"🌍 Public preview URL: {tunnel.share_url}")
+    click.echo("   Anyone with this link can use your app's pages and /api/data (none of the dev tools) - stop the server to revoke it")
+    return tunnel
 
-def start_flashflow_engine(project_root, backend_url="http://localhost:8000"):
+def start_flashflow_engine(project: FlashFlowProject, backend_url="http://localhost:8000", extra_env=None, port: int = 8012):
     """Start the FlashFlow Engine in the background"""
     try:
         # Determine the path to the Flet direct renderer script
@@ -106,264 +289,114 @@ def start_flashflow_engine(project_root, backend_url="http://localhost:8000"):
         if not flet_renderer_path.exists():
             click.echo("⚠️  FlashFlow Engine not found, skipping auto-start")
             return None
-            
-        # Start the FlashFlow Engine in a separate process
-        engine_process = subprocess.Popen([
-            sys.executable, 
-            str(flet_renderer_path), 
-            str(project_root),
-            backend_url
-        ], stdout=subprocess.PIPE, stderr=subprocess.PIPE)
         
-        click.echo("⚡ FlashFlow Engine started automatically on http://localhost:8012")
+        # Engine output goes to the project's state directory instead of an unread pipe
+        log_path = project.state.logs_dir / "engine.log"
+        with open(log_path, 'a') as log_file:
+            engine_process = subprocess.Popen([
+                sys.executable, 
+                str(flet_renderer_path), 
+                str(project.root_path),
+                backend_url
+            ], stdout=log_file, stderr=subprocess.STDOUT, cwd=str(project.root_path),
+               env=get_tracer().inject_env(vendor_env(project, dict(os.environ, **(extra_env or {}), FLASHFLOW_ENGINE_PORT=str(port)))))
+        project.state.write_runtime('engine', {'pid': engine_process.pid, 'port': port, 'log': str(log_path)})
+        
+        click.echo(f"⚡ FlashFlow Engine started automatically on http://localhost:{port}")
+        click.echo(f"   Logs: {log_path.relative_to(project.root_path)}")
         return engine_process
         
     except Exception as e:
         click.echo(f"⚠️  Failed to start FlashFlow Engine: {str(e)}")
         return None
 
-def start_unified_server(project: FlashFlowProject, host: str, port: int, auto_start_engine: bool = True):
-    """Start the unified development server with all routes"""
-    
-    app = Flask(__name__)
-    CORS(app)
+def start_unified_server(project: FlashFlowProject, host: str, port: int, auto_start_engine: bool = True, a11y: bool = False,
+                         smtp_port: int = DEFAULT_SMTP_PORT, strict_schema: bool = False, csp: Optional[str] = None,
+                         profile: Optional[Profile] = None, restarter: Optional[ServerRestarter] = None,
+                         api_workers: int = 0, generated_backend: bool = False, no_build: bool = False,
+                         engine_port: int = 8012, poll: Any = 'auto', https_redirect: Optional[bool] = None,
+                         allowed_hosts: Iterable[str] = ()) -> bool:
+    """Start the unified development server with all routes; returns True when it stopped to restart"""
     
-    # Store project reference
-    app.config['PROJECT'] = project
+    restarter = restarter or ServerRestarter()
+    dev_server = DevServer(project, with_host(host), with_port(port), with_allowed_hosts(*allowed_hosts),
+                           with_routes(setup_unified_routes), profile=profile, restarter=restarter,
+                           strict_schema=strict_schema, csp=csp, a11y=a11y, smtp_port=smtp_port, api_workers=api_workers,
+                           generated_backend=generated_backend, poll=poll, build=not no_build, https_redirect=https_redirect)
+    profile = dev_server.profile
     
     # Automatically start FlashFlow Engine if requested
     engine_process = None
     if auto_start_engine:
-        engine_process = start_flashflow_engine(project.root_path, f"http://{host}:{port}")
+        engine_process = start_flashflow_engine(project, f"http://{host}:{port}", profile_env(project, profile), engine_port)
     
-    # Start file watcher for .flow files
-    file_watcher_thread = None
     try:
-        from watchdog.observers import Observer
-        from watchdog.events import FileSystemEventHandler
-        import threading
+        app = dev_server.create_app()
+        app.config['ENGINE_PORT'] = engine_port
         
-        class FlowFileHandler(FileSystemEventHandler):
-            def __init__(self, app):
-                self.app = app
-                
-            def on_modified(self, event):
-                if event.is_directory:
-                    return
-                    
-                # Check if the file ends with .flow extension
-                if Path(str(event.src_path)).suffix == ".flow":
-                    print(f"🔄 .flow file changed: {event.src_path}")
-                    # Broadcast update to all connected clients
-                    # In a real implementation, this would use WebSockets
-                    pass
-        
-        # Create observer
-        observer = Observer()
-        handler = FlowFileHandler(app)
-        
-        # Watch src/flows directory
-        flows_dir = project.root_path / "src" / "flows"
-        if flows_dir.exists():
-            observer.schedule(handler, str(flows_dir), recursive=False)
-            
-        # Start observer in background thread
-        observer.start()
-        
-        # Store observer reference for cleanup
-        file_watcher_thread = observer
+        click.echo(f"🌐 Unified server starting on http://{host}:{port} (environment: {profile.name})")
+        click.echo("\n📍 Available routes:")
+        click.echo(f"   🏠 Welcome Page:     http://{host}:{port}/")
+        click.echo(f"   📊 Dashboard:        http://{host}:{port}/dashboard")
+        click.echo(f"   👨‍💼 Admin Panel:      http://{host}:{port}/admin/models/")
+        click.echo(f"   👥 Admin Users:      http://{host}:{port}/admin/users")
+        click.echo(f"   🔑 API Keys:         http://{host}:{port}/admin/api-keys")
+        click.echo(f"   🗄️  Admin Database:    http://{host}:{port}/admin/database")
+        click.echo(f"   🧾 Audit Log:        http://{host}:{port}/admin/audit")
+        click.echo(f"   🔌 Dev Data API:     http://{host}:{port}/api/data")
+        if dev_server.worker_pool:
+            click.echo(f"   🧵 API Workers:      http://{host}:{port}/__workers")
+        click.echo(f"   🗃️  Response Cache:   http://{host}:{port}/admin/cache")
+        click.echo(f"   📜 Data API Spec:    http://{host}:{port}/api/data/openapi.json" + (" (strict)" if strict_schema else ""))
+        click.echo(f"   🪝 Webhooks:         http://{host}:{port}/admin/webhooks")
+        click.echo(f"   🔐 Permissions:      http://{host}:{port}/api/permissions")
+        click.echo(f"   🗝️  Vault:            http://{host}:{port}/api/vault")
+        click.echo(f"   🧠 Inference:        http://{host}:{port}/inference/devices")
+        click.echo(f"   🤖 AI Models:        http://{host}:{port}/api/ai")
+        click.echo(f"   🧭 Vector Search:    http://{host}:{port}/vector/indexes")
+        click.echo(f"   📏 Build Size:       http://{host}:{port}/build/size")
+        click.echo(f"   📬 Mailbox:          http://{host}:{port}/admin/mailbox")
+        click.echo(f"   🔔 Notifications:    http://{host}:{port}/admin/notifications")
+        click.echo(f"   🔀 Transforms:       http://{host}:{port}/admin/transforms")
+        click.echo(f"   🔌 Integrations:     http://{host}:{port}/admin/integrations")
+        click.echo(f"   🌿 Branch previews:  http://{host}:{port}/branch/")
+        click.echo(f"   📚 API Docs:         http://{host}:{port}/api/docs")
+        click.echo(f"   🧪 API Tester:       http://{host}:{port}/api/tester")
+        click.echo(f"   📱 Android Preview:  http://{host}:{port}/android")
+        click.echo(f"   🍎 iOS Preview:      http://{host}:{port}/ios")
+        click.echo(f"   🖥️  Desktop Preview:   http://{host}:{port}/desktop")
+        click.echo(f"   🔧 Backend Status:   http://{host}:{port}/backend")
+        click.echo(f"   👁️  Device Farm:       http://{host}:{port}/preview")
+        click.echo(f"   ✏️  Flow Editor:       http://{host}:{port}/edit")
+        click.echo(f"   🔁 Reload Clients:   http://{host}:{port}/__clients")
+        click.echo(f"   🔨 Build Status:     http://{host}:{port}/__build")
+        click.echo(f"   🏗️  Build API:        POST http://{host}:{port}/api/build")
+        click.echo(f"   🔄 Restart:          POST http://{host}:{port}/__restart")
+        click.echo(f"   🌪️  Chaos Mode:       http://{host}:{port}/admin/chaos")
+        click.echo(f"   ⚙️  Settings:         http://{host}:{port}/admin/settings")
+        click.echo(f"   🌍 Profile:          http://{host}:{port}/api/profile")
+        click.echo(f"   🔀 Proxy:            http://{host}:{port}/__proxy")
+        click.echo(f"   🛡️  CSP Reports:      http://{host}:{port}/api/csp-report ({app.config['SECURITY_HEADERS'].mode})")
+        if a11y:
+            click.echo(f"   ♿ A11y Report:      http://{host}:{port}/admin/a11y")
+        if auto_start_engine:
+            click.echo(f"   ⚡ FlashFlow Engine:  http://localhost:{engine_port}")
+        if dev_server.smtp_sink:
+            click.echo(f"   ✉️  SMTP Sink:         localhost:{smtp_port}")
+        tracer = get_tracer()
+        if tracer.enabled:
+            destination = tracer.env_settings.get('OTEL_EXPORTER_OTLP_ENDPOINT') or tracer.env_settings.get('FLASHFLOW_TRACES_DIR')
+            click.echo(f"\n🔭 Tracing as '{tracer.service_name}' → {destination}")
+        if app.config.get('ACCESS_LOG'):
+            click.echo(f"📒 Access log → {app.config['ACCESS_LOG']}")
+        chaos = get_chaos(app)
+        if chaos.enabled:
+            click.echo(f"\n🌪️  Chaos mode is ON with {len(chaos.rules)} rule(s): some requests will be slow or fail (/admin/chaos)")
+        click.echo("\n👀 Server is running... (Ctrl+C to stop)")
         
-    except ImportError:
-        print("⚠️  File watching not available (watchdog not installed)")
-    
-    # Routes
-    setup_unified_routes(app)
-    
-    # Add live preview route with real-time updates (defined after setup_unified_routes to avoid conflicts)
-    @app.route('/preview')
-    def live_preview():
-        """Live preview of .flow files with real-time updates"""
-        project = app.config['PROJECT']
-        
-        # Get flow files data
-        flows_dir = project.root_path / "src" / "flows"
-        flow_data = {}
-        if flows_dir.exists():
-            for flow_file in flows_dir.glob("*.flow"):
-                try:
-                    import yaml
-                    with open(flow_file, 'r') as f:
-                        flow_data[flow_file.name] = yaml.safe_load(f)
-                except Exception as e:
-                    flow_data[flow_file.name] = {"error": str(e)}
-        
-        # Build the flow files list HTML
-        flow_files_html = ""
-        if flow_data:
-            for file_name in flow_data.keys():
-                flow_files_html += f'<li>📄 {file_name}</li>'
-        else:
-            flow_files_html = '<li>No .flow files found</li>'
-        
-        template = f"""
-        <!DOCTYPE html>
-        <html>
-        <head>
-            <title>FlashFlow Live Preview</title>
-            <meta charset="utf-8">
-            <meta name="viewport" content="width=device-width, initial-scale=1">
-            <style>
-                body {{ font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }}
-                .header {{ background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }}
-                .container {{ max-width: 1200px; margin: 0 auto; padding: 2rem; }}
-                .preview-grid {{ display: grid; grid-template-columns: repeat(auto-fit, minmax(300px, 1fr)); gap: 20px; margin: 2rem 0; }}
-                .preview-card {{ background: white; padding: 2rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); text-align: center; }}
-                .preview-card h3 {{ margin-top: 0; color: #3B82F6; }}
-                .preview-placeholder {{ width: 100%; height: 400px; border: 1px solid #ddd; border-radius: 4px; margin: 1rem 0; display: flex; align-items: center; justify-content: center; background: #f8f9fa; }}
-                .status {{ background: #d1fae5; color: #065f46; padding: 1rem; border-radius: 4px; margin: 1rem 0; }}
-                .file-list {{ background: white; padding: 1rem; border-radius: 4px; margin: 1rem 0; }}
-                .file-list li {{ margin: 0.5rem 0; }}
-                .update-indicator {{ display: inline-block; width: 10px; height: 10px; border-radius: 50%; background: #10B981; margin-right: 8px; animation: pulse 2s infinite; }}
-                @keyframes pulse {{
-                    0% {{ opacity: 1; }}
-                    50% {{ opacity: 0.5; }}
-                    100% {{ opacity: 1; }}
-                }}
-                .instructions {{ background: #fef3c7; color: #92400e; padding: 1rem; border-radius: 4px; margin: 1rem 0; }}
-            </style>
-        </head>
-        <body>
-            <div class="header">
-                <h1>👁️ FlashFlow Live Preview</h1>
-                <p>Real-time preview of your .flow files across all platforms</p>
-            </div>
-            <div class="container">
-                <div class="status">
-                    <strong><span class="update-indicator"></span>Live Preview Active</strong> - Changes to .flow files are automatically detected
-                </div>
-                
-                <h2>Platform Previews</h2>
-                <div class="preview-grid">
-                    <div class="preview-card">
-                        <h3>🌐 Web Preview</h3>
-                        <p>Web application preview</p>
-                        <div class="preview-placeholder" id="web-preview">
-                            <div>
-                                <div style="font-size: 3rem; margin-bottom: 1rem;">🌐</div>
-                                <p>Web application preview</p>
-                                <p style="font-size: 0.9rem; color: #666;">Generated from .flow files</p>
-                            </div>
-                        </div>
-                    </div>
-                    <div class="preview-card">
-                        <h3>📱 Mobile Preview</h3>
-                        <p>Mobile application preview</p>
-                        <div class="preview-placeholder" id="mobile-preview">
-                            <div>
-                                <div style="font-size: 3rem; margin-bottom: 1rem;">📱</div>
-                                <p>Mobile application preview</p>
-                                <p style="font-size: 0.9rem; color: #666;">Generated from .flow files</p>
-                            </div>
-                        </div>
-                    </div>
-                    <div class="preview-card">
-                        <h3>🖥️ Desktop Preview</h3>
-                        <p>Desktop application preview</p>
-                        <div class="preview-placeholder" id="desktop-preview">
-                            <div>
-                                <div style="font-size: 3rem; margin-bottom: 1rem;">🖥️</div>
-                                <p>Desktop application preview</p>
-                                <p style="font-size: 0.9rem; color: #666;">Generated from .flow files</p>
-                            </div>
-                        </div>
-                    </div>
-                </div>
-                
-                <h2>Project Information</h2>
-                <div class="file-list">
-                    <h3>.flow Files in Project</h3>
-                    <ul id="flow-files-list">
-                        {flow_files_html}
-                    </ul>
-                </div>
-                
-                <div class="instructions">
-                    <strong>💡 How it works:</strong>
-                    <p>FlashFlow automatically detects changes to your .flow files and updates all platform previews in real-time.</p>
-                    <p>Modify any .flow file in your project's <code>src/flows</code> directory and watch the previews update automatically!</p>
-                    <p><strong>⚡ FlashFlow Engine is running automatically on <a href="http://localhost:8012" target="_blank">http://localhost:8012</a></strong></p>
-                </div>
-                
-                <p><a href="/">← Back to Main Dashboard</a></p>
-            </div>
-            
-            <script>
-                // Simulate real-time updates
-                setInterval(() => {{
-                    // In a real implementation, this would listen for WebSocket updates
-                    const now = new Date();
-                    document.querySelector('.status').innerHTML = 
-                        `<strong><span class="update-indicator"></span>Live Preview Active</strong> - Last checked: ${{now.toLocaleTimeString()}}`;
-                }}, 5000);
-            </script>
-        </body>
-        </html>
-        """
-        
-        return template
-    
-    # Add API endpoint for flow files (defined after setup_unified_routes to avoid conflicts)
-    @app.route('/api/flow-files')
-    def api_flow_files():
-        """API endpoint to get list of flow files"""
-        flows_dir = project.root_path / "src" / "flows"
-        flow_files = []
-        if flows_dir.exists():
-            flow_files = [f.name for f in flows_dir.glob("*.flow")]
-        return {"files": flow_files}
-    
-    # Add API endpoint for preview data (defined after setup_unified_routes to avoid conflicts)
-    @app.route('/api/preview-data')
-    def api_preview_data():
-        """API endpoint to get preview data from flow files"""
-        flows_dir = project.root_path / "src" / "flows"
-        flow_data = {}
-        if flows_dir.exists():
-            for flow_file in flows_dir.glob("*.flow"):
-                try:
-                    import yaml
-                    with open(flow_file, 'r') as f:
-                        flow_data[flow_file.name] = yaml.safe_load(f)
-                except Exception as e:
-                    flow_data[flow_file.name] = {"error": str(e)}
-        return {"flow_files": flow_data}
-    
-    # Register user activity and notification API endpoints
-    register_api_endpoints(app)
-    
-    click.echo(f"🌐 Unified server starting on http://{host}:{port}")
-    click.echo("\n📍 Available routes:")
-    click.echo(f"   🏠 Welcome Page:     http://{host}:{port}/")
-    click.echo(f"   📊 Dashboard:        http://{host}:{port}/dashboard")
-    click.echo(f"   👨‍💼 Admin Panel:      http://{host}:{port}/admin/cpanel")
-    click.echo(f"   📚 API Docs:         http://{host}:{port}/api/docs")
-    click.echo(f"   🧪 API Tester:       http://{host}:{port}/api/tester")
-    click.echo(f"   📱 Android Preview:  http://{host}:{port}/android")
-    click.echo(f"   🍎 iOS Preview:      http://{host}:{port}/ios")
-    click.echo(f"   🖥️  Desktop Preview:   http://{host}:{port}/desktop")
-    click.echo(f"   🔧 Backend Status:   http://{host}:{port}/backend")
-    click.echo(f"   👁️  Live Preview:      http://{host}:{port}/preview")
-    if auto_start_engine:
-        click.echo(f"   ⚡ FlashFlow Engine:  http://localhost:8012")
-    click.echo("\n👀 Server is running... (Ctrl+C to stop)")
-    
-    try:
-        app.run(host=host, port=port, debug=True, use_reloader=False)
+        return dev_server.serve_forever()
     finally:
-        # Clean up file watcher
-        if file_watcher_thread:
-            file_watcher_thread.stop()
-            file_watcher_thread.join()
+        dev_server.stop()
         
         # Clean up FlashFlow Engine process
         if engine_process:
@@ -374,6 +407,7 @@ def start_unified_server(project: FlashFlowProject, host: str, port: int, auto_s
             except subprocess.TimeoutExpired:
                 engine_process.kill()
                 click.echo("\n⚡ FlashFlow Engine force killed")
+            project.state.clear_runtime('engine')
 
 def create_mobile_preview(platform_name, color):
     """Create mobile preview with live content from .flow files"""
@@ -385,9 +419,7 @@ def create_mobile_preview(platform_name, color):
     if flows_dir.exists():
         for flow_file in flows_dir.glob("*.flow"):
             try:
-                import yaml
-                with open(flow_file, 'r') as f:
-                    flow_data[flow_file.name] = yaml.safe_load(f)
+                flow_data[flow_file.name] = load_flow(flow_file, project.root_path).data
             except Exception as e:
                 flow_data[flow_file.name] = {"error": str(e)}
     
@@ -395,7 +427,7 @@ def create_mobile_preview(platform_name, color):
     flow_files_html = ""
     if flow_data:
         for file_name in flow_data.keys():
-            flow_files_html += f'<li>📄 {file_name}</li>'
+            flow_files_html += f'<li>📄 {escape_html(file_name)}</li>'
     else:
         flow_files_html = '<li>No .flow files found</li>'
     
@@ -408,8 +440,8 @@ def create_mobile_preview(platform_name, color):
                 content_str += '...'
             flow_content_html += f'''
                         <div style="margin: 15px 0; padding: 15px; background: #e9ecef; border-radius: 8px;">
-                            <h4>{flow_name}</h4>
-                            <pre style="font-size: 12px; overflow: auto;">{content_str}</pre>
+                            <h4>{escape_html(flow_name)}</h4>
+                            <pre style="font-size: 12px; overflow: auto;">{escape_html(content_str)}</pre>
                         </div>
                         '''
     else:
@@ -419,7 +451,7 @@ def create_mobile_preview(platform_name, color):
     template = f"""<!DOCTYPE html>
 <html>
 <head>
-    <title>{platform_name} Preview - {project.config.name}</title>
+    <title>{platform_name} Preview - {escape_html(project.config.name)}</title>
     <meta charset="utf-8">
     <meta name="viewport" content="width=device-width, initial-scale=1">
     <style>
@@ -555,9 +587,7 @@ def create_desktop_preview():
     if flows_dir.exists():
         for flow_file in flows_dir.glob("*.flow"):
             try:
-                import yaml
-                with open(flow_file, 'r') as f:
-                    flow_data[flow_file.name] = yaml.safe_load(f)
+                flow_data[flow_file.name] = load_flow(flow_file, project.root_path).data
             except Exception as e:
                 flow_data[flow_file.name] = {"error": str(e)}
     
@@ -565,7 +595,7 @@ def create_desktop_preview():
     flow_files_html = ""
     if flow_data:
         for file_name in flow_data.keys():
-            flow_files_html += f'<li>📄 {file_name}</li>'
+            flow_files_html += f'<li>📄 {escape_html(file_name)}</li>'
     else:
         flow_files_html = '<li>No .flow files found</li>'
     
@@ -578,8 +608,8 @@ def create_desktop_preview():
                 content_str += '...'
             flow_content_html += f'''
                         <div style="margin: 15px 0; padding: 15px; background: #4a5568; border-radius: 8px;">
-                            <h3>{flow_name}</h3>
-                            <pre style="font-size: 12px; overflow: auto; background: #2d3748; padding: 10px; border-radius: 4px;">{content_str}</pre>
+                            <h3>{escape_html(flow_name)}</h3>
+                            <pre style="font-size: 12px; overflow: auto; background: #2d3748; padding: 10px; border-radius: 4px;">{escape_html(content_str)}</pre>
                         </div>
                         '''
     else:
@@ -589,7 +619,7 @@ def create_desktop_preview():
     template = f"""<!DOCTYPE html>
 <html>
 <head>
-    <title>Desktop Preview - {project.config.name}</title>
+    <title>Desktop Preview - {escape_html(project.config.name)}</title>
     <meta charset="utf-8">
     <meta name="viewport" content="width=device-width, initial-scale=1">
     <style>
@@ -709,7 +739,7 @@ def create_desktop_preview():
 <body>
     <div class="window">
         <div class="title-bar">
-            <span>Desktop Preview - {project.config.name}</span>
+            <span>Desktop Preview - {escape_html(project.config.name)}</span>
             <div class="window-controls">
                 <div class="control minimize"></div>
                 <div class="control maximize"></div>
@@ -763,6 +793,7 @@ def create_desktop_preview():
         <p>Changes to .flow files are automatically detected and will update this preview</p>
         <p><a href="/preview" style="color: #63b3ed;">👁️ View All Platform Previews</a></p>
     </div>
+    <script src="/desktop/api/bridge.js"></script>
 </body>
 </html>"""
     
@@ -803,7 +834,7 @@ def setup_unified_routes(app):
                 <p class="subtitle">Built with FlashFlow - Single-syntax full-stack development</p>
                 
                 <div class="engine-status">
-                    <p>⚡ FlashFlow Engine is running automatically on <a href="http://localhost:8012" target="_blank">http://localhost:8012</a></p>
+                    <p>⚡ FlashFlow Engine is running automatically on <a href="http://localhost:{{ engine_port }}" target="_blank">http://localhost:{{ engine_port }}</a></p>
                 </div>
                 
                 <div class="grid">
@@ -813,7 +844,7 @@ def setup_unified_routes(app):
                     </div>
                     <div class="card">
                         <h3>👨‍💼 Admin</h3>
-                        <p><a href="/admin/cpanel">Admin Panel</a></p>
+                        <p><a href="/admin/models/">Models</a> | <a href="/admin/users">Users</a></p>
                     </div>
                     <div class="card">
                         <h3>📚 API</h3>
@@ -841,7 +872,7 @@ def setup_unified_routes(app):
         </html>
         """
         
-        return render_template_string(template, project_name=project.config.name)
+        return render_template_string(template, project_name=project.config.name, engine_port=app.config['ENGINE_PORT'])
     
     @app.route('/dashboard')
     def dashboard():
@@ -932,7 +963,7 @@ def setup_unified_routes(app):
             
             <div class="container">
                 <div class="engine-info">
-                    <p>⚡ FlashFlow Engine is running automatically on <a href="http://localhost:8012" target="_blank">http://localhost:8012</a></p>
+                    <p>⚡ FlashFlow Engine is running automatically on <a href="http://localhost:{{ engine_port }}" target="_blank">http://localhost:{{ engine_port }}</a></p>
                 </div>
                 
                 <div class="stats">
@@ -954,7 +985,7 @@ def setup_unified_routes(app):
                     <a href="/">🏠 Home</a>
                     <a href="/preview">👁️ Live Preview</a>
                     <a href="/api/docs">📚 API Docs</a>
-                    <a href="/admin/cpanel">👨‍💼 Admin</a>
+                    <a href="/admin/models/">👨‍💼 Admin</a>
                     <a href="/android">📱 Android</a>
                     <a href="/ios">🍎 iOS</a>
                     <a href="/desktop">🖥️ Desktop</a>
@@ -973,7 +1004,7 @@ def setup_unified_routes(app):
                     <h3>📋 Build Log</h3>
                     <div class="build-log" id="build-log">
                         <div>[INFO] FlashFlow server started</div>
-                        <div>[INFO] FlashFlow Engine started automatically on port 8012</div>
+                        <div>[INFO] FlashFlow Engine started automatically on port {{ engine_port }}</div>
                         <div>[INFO] Watching for .flow file changes...</div>
                     </div>
                 </div>
@@ -1070,7 +1101,7 @@ def setup_unified_routes(app):
         """
         
         project = app.config['PROJECT']
-        return render_template_string(template, project_name=project.config.name)
+        return render_template_string(template, project_name=project.config.name, engine_port=app.config['ENGINE_PORT'])
     
     @app.route('/android')
     def android_preview():
@@ -1129,12 +1160,25 @@ def setup_unified_routes(app):
         </html>
         """
         project = app.config['PROJECT']
-        return render_template_string(template, project_name=project.config.name)
+        return render_template_string(template, project_name=project.config.name, engine_port=app.config['ENGINE_PORT'])
 
-def start_backend_only(project: FlashFlowProject, host: str, port: int):
-    """Start backend server only"""
-    click.echo("🔧 Backend-only mode not yet implemented")
-    click.echo("Use 'flashflow serve --all' for now")
+def start_backend_only(project: FlashFlowProject, host: str, port: int, profile: Optional[Profile] = None):
+    """Run the generated backend alone, in the foreground, until Ctrl+C"""
+    try:
+        backend = GeneratedBackend(project, profile_env(project, profile) if profile else None, host, port)
+        backend.start()
+    except BackendTargetError as e:
+        click.echo(f"❌ {str(e)}")
+        sys.exit(1)
+    click.echo(f"🧩 {backend.target.label} backend on http://{host}:{port}/api/data (log: {backend.log_path})")
+    try:
+        while backend.alive:
+            time.sleep(0.5)
+    finally:
+        backend.stop()
+    if backend.process.returncode:
+        click.echo(f"❌ The backend exited with code {backend.process.returncode}; see {backend.log_path}")
+        sys.exit(1)
 
 def start_frontend_only(project: FlashFlowProject, host: str, port: int):
     """Start frontend server only"""
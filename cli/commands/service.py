@@ -0,0 +1,124 @@
+"""
+FlashFlow 'service' command - Install the dev server as a background OS service
+"""
+
+import click
+import sys
+from pathlib import Path
+
+from core.framework import FlashFlowProject
+from core.settings import SettingsError, resolve_settings
+from cli.utils.os_service import (
+    OSServiceError, build_spec, clear_installed, read_installed, service_manager, write_installed
+)
+
+@click.group()
+def service():
+    """Run the dev server in the background (systemd, launchd or a Windows logon task)"""
+    pass
+
+def _project(ctx) -> FlashFlowProject:
+    project = FlashFlowProject(ctx.obj.get('project_root') or Path.cwd())
+    if not project.exists():
+        click.echo("❌ Not in a FlashFlow project directory")
+        sys.exit(1)
+    return project
+
+def _installed(project: FlashFlowProject):
+    spec = read_installed(project)
+    if spec is None:
+        click.echo("❌ No service installed for this project; run 'flashflow service install' first")
+        sys.exit(1)
+    return spec
+
+@service.command('install')
+@click.option('--name', default=None, help='Service name (default: flashflow-<project name>)')
+@click.option('--host', '-h', default=None, help='Host the dev server listens on (default: FLASHFLOW_HOST, serve.host in flashflow.json, else 127.0.0.1)')
+@click.option('--port', '-p', default=None, help='Port the dev server listens on (default: FLASHFLOW_PORT, serve.port in flashflow.json, else 8000)')
+@click.option('--print', 'print_only', is_flag=True, help='Print the generated unit file without installing it')
+@click.option('--no-start', is_flag=True, help='Register the service without starting it')
+@click.pass_context
+def install_service(ctx, name, host, port, print_only, no_start):
+    """Register the dev server with the OS service manager"""
+    project = _project(ctx)
+    try:
+        settings = resolve_settings(project, {'serve.host': host, 'serve.port': port}, keys=['serve.host', 'serve.port'])
+    except SettingsError as e:
+        click.echo(f"❌ {str(e)}")
+        sys.exit(1)
+    # A unit starting at boot binds IPv4 loopback unless told otherwise; 'localhost' can resolve to ::1 alone
+    host = settings.host('serve.host') if settings.source('serve.host') != 'default' else '127.0.0.1'
+    port = settings.port('serve.port')
+    try:
+        manager = service_manager(project)
+        spec = build_spec(project, manager, name, host, port)
+        if print_only:
+            click.echo(manager.render(spec))
+            return
+
+        existing = read_installed(project)
+        if existing and existing.name != spec.name:
+            click.echo(f"❌ {existing.name} is already installed for this project; uninstall it first")
+            sys.exit(1)
+
+        manager.install(spec)
+        write_installed(project, spec)
+        click.echo(f"✅ Installed {spec.name} ({spec.manager})")
+        click.echo(f"   📄 {spec.unit_path}")
+        click.echo(f"   📜 Logs: {spec.log_path}")
+        if not no_start:
+            manager.start(spec)
+            click.echo(f"🚀 Started on http://{host}:{port}")
+    except OSServiceError as e:
+        click.echo(f"❌ {str(e)}")
+        sys.exit(1)
+
+@service.command('uninstall')
+@click.pass_context
+def uninstall_service(ctx):
+    """Stop the service and remove its registration"""
+    project = _project(ctx)
+    spec = _installed(project)
+    try:
+        service_manager(project).uninstall(spec)
+    except OSServiceError as e:
+        click.echo(f"❌ {str(e)}")
+        sys.exit(1)
+    clear_installed(project)
+    click.echo(f"🗑️  Uninstalled {spec.name}")
+
+@service.command('start')
+@click.pass_context
+def start_service(ctx):
+    """Start the installed service"""
+    _control(ctx, 'start', "🚀 Started")
+
+@service.command('stop')
+@click.pass_context
+def stop_service(ctx):
+    """Stop the installed service"""
+    _control(ctx, 'stop', "🛑 Stopped")
+
+@service.command('status')
+@click.pass_context
+def status_service(ctx):
+    """Show whether the installed service is running"""
+    project = _project(ctx)
+    spec = _installed(project)
+    try:
+        state = service_manager(project).status(spec)
+    except OSServiceError as e:
+        click.echo(f"❌ {str(e)}")
+        sys.exit(1)
+    click.echo(f"{spec.name} ({spec.manager}): {state}")
+    click.echo(f"   📜 Logs: {spec.log_path}")
+
+def _control(ctx, action: str, done: str):
+    project = _project(ctx)
+    spec = _installed(project)
+    try:
+        getattr(service_manager(project), action)(spec)
+    except OSServiceError as e:
+        click.echo(f"❌ {str(e)}")
+        sys.exit(1)
+    click.echo(f"{done} {spec.name}")
@@ -0,0 +1,102 @@
+"""
+FlashFlow 'services' command - Build, verify and release the Go service binaries
+"""
+
+import click
+import sys
+from pathlib import Path
+
+from cli.core import __version__
+from cli.utils.go_release import DEFAULT_TARGETS, RELEASE_DIR, ReleaseError, release_services, write_checksums
+from cli.utils.go_services import (
+    ServiceIntegrityError, build_go_service, find_service_binary, list_services, record_checksum, service_binary, verify_binary
+)
+from cli.utils.plugins import find_project_root
+
+@click.group()
+def services():
+    """Build, checksum, verify and release go-services binaries"""
+    pass
+
+@services.command('build')
+@click.argument('names', nargs=-1)
+def build_services(names):
+    """Build Go services and record their checksums (all services if none are named)"""
+    names = names or list_services()
+    if not names:
+        click.echo("❌ No Go services found")
+        sys.exit(1)
+
+    failed = False
+    for name in names:
+        click.echo(f"🔨 Building {name}...")
+        try:
+            binary = build_go_service(name)
+        except (ServiceIntegrityError, OSError) as e:
+            click.echo(f"❌ {str(e)}")
+            failed = True
+            continue
+        click.echo(f"✅ {binary.name} built and checksummed")
+    if failed:
+        sys.exit(1)
+
+@services.command('checksum')
+@click.argument('names', nargs=-1, required=True)
+def checksum_services(names):
+    """Record checksums (and signatures) for binaries built outside the CLI"""
+    for name in names:
+        try:
+            written = record_checksum(find_service_binary(name, find_project_root(Path.cwd())) or service_binary(name))
+        except ServiceIntegrityError as e:
+            click.echo(f"❌ {str(e)}")
+            sys.exit(1)
+        click.echo(f"🔏 {name}: {', '.join(path.name for path in written)}")
+
+@services.command('verify')
+def verify_services():
+    """Check every built service against its checksum and signature"""
+    problems = 0
+    for name in list_services():
+        binary = find_service_binary(name, find_project_root(Path.cwd()))
+        if binary is None:
+            click.echo(f"   ⚪ {name:<24} not built")
+            continue
+        try:
+            verify_binary(binary)
+            click.echo(f"   ✅ {name:<24} verified")
+        except ServiceIntegrityError as e:
+            problems += 1
+            click.echo(f"   ❌ {name:<24} {str(e)}")
+    if problems:
+        sys.exit(1)
+
+@services.command('release')
+@click.argument('names', nargs=-1)
+@click.option('--target', '-t', 'targets', multiple=True,
+              help=f"<os>/<arch> to build for, repeatable (default: {', '.join(DEFAULT_TARGETS)})")
+@click.option('--version', 'version', default=__version__, show_default=True, help='Version for archive names and /__info')
+@click.option('--output', '-o', type=click.Path(file_okay=False), default=str(RELEASE_DIR), show_default=True)
+def release(names, targets, version, output):
+    """Cross-compile Go services into versioned archives with SHA256SUMS (all services if none are named)"""
+    names = names or list_services()
+    if not names:
+        click.echo("❌ No Go services found")
+        sys.exit(1)
+
+    out_dir = Path(output)
+    try:
+        archives, failures = release_services(list(names), list(targets or DEFAULT_TARGETS), version, out_dir,
+                                              progress=lambda step: click.echo(f"🔨 Building {step}..."))
+        written = write_checksums(archives, out_dir) if archives else []
+    except (ReleaseError, ServiceIntegrityError) as e:
+        click.echo(f"❌ {str(e)}")
+        sys.exit(1)
+
+    for archive in archives:
+        click.echo(f"📦 {archive}")
+    for path in written:
+        click.echo(f"🔏 {path}")
+    for failure in failures:
+        click.echo(f"❌ {failure}")
+    if failures:
+        sys.exit(1)
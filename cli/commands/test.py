@@ -0,0 +1,328 @@
+"""
+FlashFlow 'test' command - End-to-end tests from the flows' 'e2e' sections, API contract checks and the a11y gate
+"""
+
+import click
+import json
+import sys
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+
+import requests
+
+from core.a11y import IMPACT_LEVELS, A11yError, audit_failures, fail_on_level
+from core.contracts import (CONTRACT_DIR, FIXTURES_FILE, SDK_LANGUAGES, SNAPSHOT_FILE, ContractClient, ContractError,
+                            build_contract, compare_contracts, load_contract, load_fixtures, record_fixtures,
+                            replay_fixtures, signature, write_json)
+from core.e2e import DEFAULT_TIMEOUT, Driver, E2eError, E2eRunner, load_scenarios
+from core.framework import FlashFlowProject
+from cli.utils.dev_status import server_url
+from cli.utils.output import get_output
+
+INSTALL_HINT = "pip install playwright && python -m playwright install chromium"
+
+class PlaywrightDriver(Driver):
+    """Headless Chromium through Playwright"""
+
+    def __init__(self, timeout: float, headed: bool = False):
+        try:
+            from playwright.sync_api import Error, sync_playwright
+        except ImportError:
+            raise E2eError(f"End-to-end tests need Playwright: {INSTALL_HINT}")
+        self.timeout_ms = timeout * 1000
+        self.playwright = sync_playwright().start()
+        try:
+            self.browser = self.playwright.chromium.launch(headless=not headed)
+        except Error as e:
+            self.playwright.stop()
+            raise E2eError(f"Cannot start Chromium ({str(e).splitlines()[0]}); install it with: {INSTALL_HINT}")
+        self.context = None
+        self.page = None
+
+    def new_page(self):
+        # Each scenario gets its own cookies and storage
+        if self.context:
+            self.context.close()
+        self.context = self.browser.new_context()
+        self.context.set_default_timeout(self.timeout_ms)
+        self.page = self.context.new_page()
+
+    def goto(self, url: str):
+        response = self.page.goto(url, wait_until='load')
+        return response.status if response else None
+
+    def click(self, selector: str):
+        self.page.click(selector)
+
+    def fill(self, selector: str, value: str):
+        self.page.fill(selector, value)
+
+    def select(self, selector: str, value: str):
+        self.page.select_option(selector, value)
+
+    def check(self, selector: str):
+        self.page.check(selector)
+
+    def submit(self, selector: str):
+        with self.page.expect_navigation(wait_until='load'):
+            self.page.eval_on_selector(selector, "form => form.requestSubmit ? form.requestSubmit() : form.submit()")
+
+    def press(self, key: str):
+        self.page.keyboard.press(key)
+
+    def wait_for(self, selector: str):
+        self.page.wait_for_selector(selector)
+
+    def url(self) -> str:
+        return self.page.url
+
+    def title(self) -> str:
+        return self.page.title()
+
+    def text(self, selector: str = 'body') -> str:
+        element = self.page.query_selector(selector)
+        return element.inner_text() if element else ''
+
+    def visible(self, selector: str) -> bool:
+        return self.page.is_visible(selector)
+
+    def screenshot(self, path: Path):
+        self.page.screenshot(path=str(path), full_page=True)
+
+    def close(self):
+        if self.context:
+            self.context.close()
+        self.browser.close()
+        self.playwright.stop()
+
+@click.group(invoke_without_command=True)
+@click.pass_context
+def test(ctx):
+    """Test the running app; without a subcommand, every e2e scenario"""
+    if ctx.invoked_subcommand is None:
+        ctx.invoke(e2e)
+
+@test.command('e2e')
+@click.argument('names', nargs=-1)
+@click.option('--url', '-u', default=None, help='App to test (default: the running dev server)')
+@click.option('--timeout', default=DEFAULT_TIMEOUT, type=float, help='Seconds each step and expectation may take')
+@click.option('--headed', is_flag=True, help='Show the browser window')
+@click.option('--list', 'list_only', is_flag=True, help='List the scenarios without running them')
+@click.option('--a11y', 'a11y_level', type=click.Choice(IMPACT_LEVELS), default=None,
+              help='Also fail on accessibility violations of this impact or worse on the pages visited')
+@click.pass_context
+def e2e(ctx, names, url, timeout, headed, list_only, a11y_level):
+    """Run the 'e2e' scenarios of the flows in a headless browser
+
+    NAMES pick scenarios by name or flow file (a part of either is enough);
+    without them every scenario runs. Screenshots of failures are written to
+    .flashflow/e2e. With --a11y, the dev server must run with --a11y too: its
+    report is cleared first, and the axe-core violations of the pages the
+    scenarios open are checked afterwards, as 'flashflow test a11y' does.
+    """
+    out = get_output()
+    project = FlashFlowProject(ctx.obj.get('project_root') or Path.cwd())
+    if not project.exists():
+        click.echo("❌ Not in a FlashFlow project directory", err=True)
+        sys.exit(1)
+
+    scenarios, errors = load_scenarios(project)
+    for error in errors:
+        click.echo(f"❌ {error}", err=True)
+    if errors:
+        sys.exit(1)
+    if names:
+        scenarios = [scenario for scenario in scenarios
+                     if any(name.lower() in scenario.name.lower() or name.lower() in scenario.file.lower() for name in names)]
+    if not scenarios:
+        click.echo("❌ No e2e scenarios" + (f" match {' '.join(names)}" if names else " in the flows; add an 'e2e' section to one"),
+                   err=True)
+        sys.exit(1)
+
+    if list_only:
+        for scenario in scenarios:
+            out.echo(f"   {scenario.file}: {scenario.name} ({len(scenario.steps)} steps)")
+        out.emit({'scenarios': [{'file': scenario.file, 'name': scenario.name, 'steps': len(scenario.steps)}
+                                for scenario in scenarios]})
+        return
+
+    url = (url or server_url(project)).rstrip('/')
+    screenshots_dir = project.state.path('e2e', 'report.json').parent
+    if a11y_level:
+        clear_a11y_report(url)
+    try:
+        driver = PlaywrightDriver(timeout, headed)
+    except E2eError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+
+    out.echo(f"🧪 Running {len(scenarios)} e2e scenario(s) against {url}")
+
+    def report(result):
+        if result.passed:
+            out.echo(f"   ✅ {result.scenario.file}: {result.scenario.name} ({result.seconds:.1f}s)")
+        else:
+            out.echo(f"   ❌ {result.scenario.file}: {result.scenario.name}")
+            out.echo(f"      at {result.failed_step}: {result.failure}")
+            if result.screenshot:
+                out.echo(f"      📸 {Path(result.screenshot).relative_to(project.root_path)}")
+
+    try:
+        results = E2eRunner(driver, url, screenshots_dir, timeout).run(scenarios, report)
+    finally:
+        driver.close()
+
+    failed = [result for result in results if not result.passed]
+    data = {'url': url, 'passed': len(results) - len(failed), 'failed': len(failed), 'results': [result.to_dict() for result in results]}
+    out.echo(f"\n{'❌' if failed else '✅'} {data['passed']} passed, {data['failed']} failed")
+    a11y_failures = []
+    if a11y_level:
+        out.echo()
+        _, a11y_failures = check_a11y(out, project, a11y_level)
+        data['a11y'] = {'fail_on': a11y_level, 'failures': a11y_failures}
+    (screenshots_dir / 'report.json').write_text(json.dumps(data, indent=2), encoding='utf-8')
+    out.emit(data)
+    if failed or a11y_failures:
+        sys.exit(1)
+
+def clear_a11y_report(url: str):
+    """Start the dev server's a11y report afresh, so only this run's pages count"""
+    try:
+        response = requests.delete(f"{url}/api/a11y/report", timeout=10)
+    except requests.RequestException as e:
+        click.echo(f"❌ Cannot reach {url}: {str(e)}", err=True)
+        sys.exit(1)
+    if response.status_code == 404:
+        click.echo(f"❌ The dev server at {url} does not audit pages; start it with 'flashflow serve --a11y'", err=True)
+        sys.exit(1)
+    if response.status_code != 200:
+        click.echo(f"❌ Could not clear the a11y report at {url}: {response.status_code} {response.reason}", err=True)
+        sys.exit(1)
+
+@test.command('contract')
+@click.option('--url', '-u', default=None, help='Dev server to check (default: the running one)')
+@click.option('--update', is_flag=True, help=f"Accept the API as it is: rewrite {CONTRACT_DIR}/{SNAPSHOT_FILE}")
+@click.option('--record', is_flag=True, help=f"Record one SDK call per operation into {CONTRACT_DIR}/{FIXTURES_FILE}")
+@click.option('--sdk', 'language', type=click.Choice(SDK_LANGUAGES), default='typescript', show_default=True,
+              help='Language of the SDK signatures shown')
+@click.option('--signatures', is_flag=True, help='Print the SDK signature of every operation')
+@click.pass_context
+def contract(ctx, url, update, record, language, signatures):
+    """Check the dev API against the contract the generated SDKs were built for
+
+    Compares /api/data/openapi.json with the committed snapshot in
+    contracts/api.json and fails on changes that break SDK calls. The
+    recorded calls in contracts/fixtures.json are replayed in a database
+    sandbox and must still get answers the SDKs can decode. Without a
+    snapshot, the first run writes one.
+    """
+    out = get_output()
+    project = FlashFlowProject(ctx.obj.get('project_root') or Path.cwd())
+    if not project.exists():
+        click.echo("❌ Not in a FlashFlow project directory", err=True)
+        sys.exit(1)
+
+    url = (url or server_url(project)).rstrip('/')
+    snapshot_path = project.root_path / CONTRACT_DIR / SNAPSHOT_FILE
+    fixtures_path = project.root_path / CONTRACT_DIR / FIXTURES_FILE
+    client = ContractClient(url)
+    try:
+        current = build_contract(client.spec())
+        snapshot = load_contract(snapshot_path)
+        fixtures = [] if record else load_fixtures(fixtures_path)
+    except ContractError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+
+    operations = current['operations']
+    out.echo(f"📜 {len(operations)} operation(s) at {url}")
+    if signatures:
+        for name, operation in operations.items():
+            out.echo(f"   {signature(name, operation, language)}")
+
+    changes = compare_contracts(snapshot, current) if snapshot else []
+    breaking = [change for change in changes if change.breaking]
+    if changes:
+        icon = ('⚠️ ' if update else '❌') if breaking else '✅'
+        out.echo(f"\n{icon} {len(breaking)} breaking change(s) and "
+                 f"{len(changes) - len(breaking)} addition(s) since {CONTRACT_DIR}/{SNAPSHOT_FILE}:")
+        for name in dict.fromkeys(change.operation for change in changes):
+            before, after = snapshot['operations'].get(name), operations.get(name)
+            out.echo(f"\n   {signature(name, before or after, language)}")
+            for change in changes:
+                if change.operation == name:
+                    out.echo(f"      {'❌' if change.breaking else '➕'} {change.message}")
+            if before and after and signature(name, before, language) != signature(name, after, language):
+                out.echo(f"      now: {signature(name, after, language)}")
+    elif snapshot:
+        out.echo(f"✅ The API matches {CONTRACT_DIR}/{SNAPSHOT_FILE}")
+
+    if update or snapshot is None:
+        write_json(snapshot_path, current)
+        out.echo(f"📝 Wrote {CONTRACT_DIR}/{SNAPSHOT_FILE}; commit it with the SDKs generated from this API")
+        snapshot = current
+
+    failures = []
+    if record or fixtures:
+        try:
+            client.open_sandbox()
+            if record:
+                fixtures, skipped = record_fixtures(current, client)
+                write_json(fixtures_path, fixtures)
+                out.echo(f"🎞️  Recorded {len(fixtures)} fixture(s) in {CONTRACT_DIR}/{FIXTURES_FILE}")
+                for reason in skipped:
+                    out.echo(f"   ⏭️  {reason}")
+            else:
+                failures = replay_fixtures(snapshot, fixtures, client)
+                out.echo(f"\n{'❌' if failures else '✅'} {len(fixtures) - len({f.operation for f in failures})} "
+                         f"of {len(fixtures)} fixture(s) answered as recorded")
+                for failure in failures:
+                    out.echo(f"   ❌ {failure.operation}: {failure.message}")
+        except ContractError as e:
+            click.echo(f"❌ {str(e)}", err=True)
+            sys.exit(1)
+        finally:
+            client.close()
+
+    out.emit({'url': url, 'operations': len(operations), 'breaking': [change.to_dict() for change in breaking],
+              'additions': [change.to_dict() for change in changes if not change.breaking],
+              'fixtures': len(fixtures), 'fixture_failures': [failure.to_dict() for failure in failures]})
+    if (breaking and not update) or failures:
+        sys.exit(1)
+
+@test.command('a11y')
+@click.option('--fail-on', type=click.Choice(IMPACT_LEVELS), default=None,
+              help="Lowest impact that fails (default: a11y.fail_on in flashflow.json, else serious)")
+@click.pass_context
+def a11y(ctx, fail_on):
+    """Fail on the accessibility violations pages reported while served with --a11y
+
+    Reads .flashflow/a11y/report.json, which holds the latest axe-core run
+    of every page opened since the report was last cleared.
+    """
+    out = get_output()
+    project = FlashFlowProject(ctx.obj.get('project_root') or Path.cwd())
+    if not project.exists():
+        click.echo("❌ Not in a FlashFlow project directory", err=True)
+        sys.exit(1)
+    try:
+        level, failures = check_a11y(out, project, fail_on)
+    except A11yError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+    out.emit({'fail_on': level, 'failures': failures})
+    if failures:
+        sys.exit(1)
+
+def check_a11y(out, project: FlashFlowProject, fail_on: Optional[str] = None) -> Tuple[str, List[Dict[str, Any]]]:
+    """The level applied and the recorded violations at or above it, which are printed"""
+    level = fail_on or fail_on_level(project)
+    failures = audit_failures(project, level)
+    if failures:
+        out.echo(f"❌ {len(failures)} accessibility violation(s) at or above '{level}':")
+        for failure in failures:
+            out.echo(f"   {failure['page']}: [{failure['impact']}] {failure['id']} - {failure['help']} "
+                     f"({failure['node_count']} element(s))")
+    else:
+        out.echo(f"✅ No accessibility violations at or above '{level}'")
+    return level, failures
@@ -0,0 +1,95 @@
+"""
+FlashFlow 'vectors' command - Export and import the vector collections of a server
+"""
+
+import click
+import sys
+from pathlib import Path
+from urllib.parse import quote
+
+import requests
+
+from core.framework import FlashFlowProject
+from core.vector_collections import VectorCollectionError, format_for, read_records
+from cli.utils.dev_status import server_url
+from cli.utils.output import get_output
+
+TIMEOUT = 120
+
+@click.group()
+def vectors():
+    """Move vector collections between servers and files (JSONL or Parquet)"""
+    pass
+
+@vectors.command('export')
+@click.argument('collection')
+@click.argument('file', type=click.Path(dir_okay=False, path_type=Path))
+@click.option('--format', 'file_format', type=click.Choice(['jsonl', 'parquet']), default=None, help='File format (default: from the extension)')
+@click.option('--url', '-u', default=None, help='Server holding the collection (default: the running dev server)')
+@click.pass_context
+def vectors_export(ctx, collection, file, file_format, url):
+    """Write a collection's ids, vectors, metadata and texts to FILE"""
+    out = get_output()
+    url = _url(ctx, url)
+    try:
+        file_format = format_for(file.name, file_format)
+    except VectorCollectionError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+
+    response = _request('GET', f"{url}/vector/indexes/{quote(collection, safe='')}/export", params={'format': file_format})
+    file.write_bytes(response.content)
+    count = int(response.headers.get('X-FlashFlow-Vectors', 0))
+    out.echo(f"✅ Exported {count} vector(s) of '{collection}' to {file}")
+    out.emit({'collection': collection, 'file': str(file), 'format': file_format, 'vectors': count})
+
+@vectors.command('import')
+@click.argument('file', type=click.Path(exists=True, dir_okay=False, path_type=Path))
+@click.argument('collection', required=False)
+@click.option('--format', 'file_format', type=click.Choice(['jsonl', 'parquet']), default=None, help='File format (default: from the extension)')
+@click.option('--replace', is_flag=True, help='Empty the collection first instead of upserting by id')
+@click.option('--url', '-u', default=None, help='Server to import into (default: the running dev server)')
+@click.pass_context
+def vectors_import(ctx, file, collection, file_format, replace, url):
+    """Load FILE into COLLECTION (default: the file name without extension)"""
+    out = get_output()
+    url = _url(ctx, url)
+    collection = collection or file.stem
+    try:
+        file_format = format_for(file.name, file_format)
+        # Checked here too, so a bad file is reported with its line before anything is sent
+        records = read_records(file, file_format)
+    except VectorCollectionError as e:
+        click.echo(f"❌ {file}: {str(e)}", err=True)
+        sys.exit(1)
+
+    response = _request('POST', f"{url}/vector/indexes/{quote(collection, safe='')}/import",
+                        params={'format': file_format, 'replace': '1' if replace else '0'}, data=file.read_bytes())
+    result = response.json()
+    out.echo(f"✅ Imported {result['imported']} vector(s) into '{collection}' "
+             f"({result['size'] if result.get('size') is not None else len(records)} in it, {result['dimension']} dimensions)")
+    out.emit(dict(result, collection=collection, file=str(file), format=file_format))
+
+def _url(ctx, url) -> str:
+    if url:
+        return url.rstrip('/')
+    project = FlashFlowProject(ctx.obj.get('project_root') or Path.cwd())
+    if not project.exists():
+        click.echo("❌ Not in a FlashFlow project directory; pass the server with --url", err=True)
+        sys.exit(1)
+    return server_url(project)
+
+def _request(method: str, url: str, **kwargs) -> requests.Response:
+    try:
+        response = requests.request(method, url, timeout=TIMEOUT, **kwargs)
+    except requests.RequestException as e:
+        click.echo(f"❌ Cannot reach {url}: {str(e)}", err=True)
+        sys.exit(1)
+    if response.status_code != 200:
+        try:
+            message = response.json().get('error') or response.text
+        except ValueError:
+            message = response.text.strip() or response.reason
+        click.echo(f"❌ {method} {url} answered {response.status_code}: {message}", err=True)
+        sys.exit(1)
+    return response
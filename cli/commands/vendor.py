@@ -0,0 +1,73 @@
+"""
+FlashFlow 'vendor' command - Pin the tools FlashFlow shells out to under .flashflow/vendor
+"""
+
+import click
+import sys
+from pathlib import Path
+
+from core.framework import FlashFlowProject
+from core.vendor import LOCK_FILE_NAME, VendorConfig, VendorError, install_offline, read_lock, vendor as vendor_tools, vendor_dir, verify_artifacts
+from cli.utils.output import get_output
+
+@click.group(invoke_without_command=True)
+@click.option('--offline', is_flag=True, help=f'Install from already downloaded files, checked against {LOCK_FILE_NAME}')
+@click.pass_context
+def vendor(ctx, offline):
+    """Download Python, npm and prebuilt dependencies for offline builds"""
+    if ctx.invoked_subcommand is not None:
+        return
+
+    out = get_output()
+    project = _project(ctx)
+    try:
+        if offline:
+            lock = install_offline(project, log=out.echo)
+        else:
+            lock = vendor_tools(project, VendorConfig.for_project(project), log=out.echo)
+    except VendorError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+
+    summary = _summary(lock)
+    out.echo(f"✅ Vendored into {vendor_dir(project).relative_to(project.root_path)}: {summary}")
+    if not offline:
+        out.echo(f"📌 Pinned versions and hashes written to {LOCK_FILE_NAME}; commit it with the project")
+        unpinned = [name for name, entry in (lock.get('files') or {}).items() if not entry['pinned']]
+        if unpinned:
+            out.echo(f"⚠️  No sha256 in flashflow.json for {', '.join(unpinned)}; copy it from {LOCK_FILE_NAME} to pin the download")
+    out.emit({'status': 'ok', 'offline': offline, 'lock': lock})
+
+@vendor.command('verify')
+@click.pass_context
+def vendor_verify(ctx):
+    """Check vendored files against the lock"""
+    out = get_output()
+    project = _project(ctx)
+    try:
+        lock = read_lock(project)
+    except VendorError as e:
+        click.echo(f"❌ {str(e)}", err=True)
+        sys.exit(1)
+
+    problems = verify_artifacts(project, lock)
+    for problem in problems:
+        out.echo(f"❌ {problem}")
+    if not problems:
+        out.echo(f"✅ Vendored files match {LOCK_FILE_NAME} ({_summary(lock)})")
+    out.emit({'status': 'failed' if problems else 'ok', 'problems': problems})
+    if problems:
+        sys.exit(1)
+
+def _project(ctx) -> FlashFlowProject:
+    project = FlashFlowProject(ctx.obj.get('project_root') or Path.cwd())
+    if not project.exists():
+        click.echo("❌ Not in a FlashFlow project directory", err=True)
+        sys.exit(1)
+    return project
+
+def _summary(lock) -> str:
+    python = len((lock.get('python') or {}).get('wheels', []))
+    node = len((lock.get('node') or {}).get('resolved', {}))
+    files = len(lock.get('files') or {})
+    return f"{python} Python wheels, {node} npm packages, {files} files"
@@ -4,6 +4,7 @@ FlashFlow CLI - Main entry point
 """
 
 import click
+import json
 import os
 import sys
 from pathlib import Path
@@ -13,21 +14,29 @@ sys.path.insert(0, str(Path(__file__).parent.parent.parent))
 
 try:
     # Updated imports to reflect new structure
-    from cli.commands import new, install, build, serve, test, deploy, migrate, setup, custom, theme, preview
+    from cli.commands import new, install, build, serve, test, deploy, migrate, setup, custom, theme, preview, bench, run, services, service, db, audit, plugins, vendor, dash, crashes, lint, lsp, branches, generate, metrics, loadtest, importer, config, vectors
     from cli.commands.mobile import serve as mobile_serve
     from core.framework import FlashFlowProject
     from cli.core import __version__
+    from cli.utils.output import Output, QUIET, NORMAL
+    from cli.utils.plugins import PluginGroup, find_project_root
+    from core.crashes import get_crash_reporter, install_crash_reporter
 except ImportError as e:
     # Fallback imports for when running from different locations
-    from cli.commands import new, install, build, serve, test, deploy, migrate, setup, custom, theme, preview
+    from cli.commands import new, install, build, serve, test, deploy, migrate, setup, custom, theme, preview, bench, run, services, service, db, audit, plugins, vendor, dash, crashes, lint, lsp, branches, generate, metrics, loadtest, importer, config, vectors
     from cli.commands.mobile import serve as mobile_serve
     from core.framework import FlashFlowProject
     from cli.core import __version__
+    from cli.utils.output import Output, QUIET, NORMAL
+    from cli.utils.plugins import PluginGroup, find_project_root
+    from core.crashes import get_crash_reporter, install_crash_reporter
 
-@click.group()
+@click.group(cls=PluginGroup)
 @click.version_option(__version__)
+@click.option('--quiet', '-q', is_flag=True, help='Print only a JSON result on stdout')
+@click.option('--verbose', '-v', count=True, help='Show child process output (-vv also shows the commands run)')
 @click.pass_context
-def cli(ctx):
+def cli(ctx, quiet, verbose):
     """
     FlashFlow - Single-syntax full-stack framework
     
@@ -36,17 +45,20 @@ def cli(ctx):
     """
     ctx.ensure_object(dict)
     
+    if quiet and verbose:
+        raise click.UsageError("--quiet and --verbose cannot be combined")
+    ctx.obj['output'] = Output(QUIET if quiet else NORMAL + verbose)
+    
     # Find project root
     current_dir = Path.cwd()
-    project_root = None
-    
-    for parent in [current_dir] + list(current_dir.parents):
-        if (parent / "flashflow.json").exists():
-            project_root = parent
-            break
+    project_root = find_project_root(current_dir)
     
     ctx.obj['project_root'] = project_root
     ctx.obj['current_dir'] = current_dir
+    
+    # Unhandled errors inside a project leave a report in .flashflow/crashes
+    if project_root:
+        install_crash_reporter(project_root, 'cli')
 
 # Register commands
 cli.add_command(new.new)
@@ -61,16 +73,43 @@ cli.add_command(custom.custom)
 cli.add_command(theme.theme)
 cli.add_command(mobile_serve.mobile)
 cli.add_command(preview.preview)
+cli.add_command(bench.bench)
+cli.add_command(metrics.metrics)
+cli.add_command(loadtest.loadtest)
+cli.add_command(run.run)
+cli.add_command(services.services)
+cli.add_command(service.service)
+cli.add_command(db.db)
+cli.add_command(audit.audit)
+cli.add_command(plugins.plugins)
+cli.add_command(vendor.vendor)
+cli.add_command(dash.dash)
+cli.add_command(crashes.crashes)
+cli.add_command(lint.lint)
+cli.add_command(lsp.lsp)
+cli.add_command(branches.branches)
+cli.add_command(generate.generate)
+cli.add_command(importer.importer)
+cli.add_command(config.config)
+cli.add_command(vectors.vectors)
 
 def main():
     """Main entry point for the CLI"""
+    obj = {}
     try:
-        cli()
+        cli(obj=obj)
     except KeyboardInterrupt:
         click.echo("\n❌ Operation cancelled by user")
         sys.exit(1)
     except Exception as e:
-        click.echo(f"❌ Error: {str(e)}", err=True)
+        reporter = get_crash_reporter()
+        report_path = reporter.report(e) if reporter else None
+        if obj.get('output') and obj['output'].quiet:
+            click.echo(json.dumps({'status': 'error', 'error': str(e), 'crash_report': report_path.stem if report_path else None}))
+        else:
+            click.echo(f"❌ Error: {str(e)}", err=True)
+            if report_path:
+                click.echo(f"🧯 Crash report saved; see: flashflow crashes show {report_path.stem}", err=True)
         sys.exit(1)
 
 if __name__ == "__main__":
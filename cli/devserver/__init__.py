@@ -0,0 +1,4 @@
+# Development server subsystems for FlashFlow
+
+# Each module exposes a register_*(app) function that adds its routes
+# to the unified Flask server started by 'flashflow serve'
@@ -0,0 +1,245 @@
+"""
+FlashFlow accessibility audit - axe-core injected into pages served with --a11y
+
+Every HTML page outside /admin and /api gets axe-core plus a small script that
+posts its violations to /api/a11y/report whenever the page settles. axe-core
+comes from .flashflow/vendor when it has been vendored, the CDN otherwise.
+"""
+
+import os
+
+from flask import request, jsonify, render_template_string, send_file
+
+from core.a11y import A11yReport, A11yError, AXE_CDN_URL, fail_on_level
+from core.vendor import vendored_axe
+
+EXCLUDED_PREFIXES = ('/admin', '/api', '/media', '/static')
+
+def get_a11y_report(app) -> A11yReport:
+    if 'A11Y_REPORT' not in app.config:
+        app.config['A11Y_REPORT'] = A11yReport.for_project(app.config['PROJECT'])
+    return app.config['A11Y_REPORT']
+
+def audit_script(project) -> str:
+    """The HTML injected into audited pages"""
+    axe_url = os.environ.get('FLASHFLOW_AXE_URL') or ('/api/a11y/axe.min.js' if vendored_axe(project) else AXE_CDN_URL)
+    return A11Y_AUDIT_SCRIPT.replace('__AXE_URL__', axe_url)
+
+def register_a11y(app):
+    """Inject the audit script into rendered pages and register the report API and admin page"""
+    axe_path = vendored_axe(app.config['PROJECT'])
+    script = audit_script(app.config['PROJECT'])
+
+    @app.route('/api/a11y/axe.min.js', methods=['GET'])
+    def a11y_vendored_axe():
+        """axe-core from 'flashflow vendor', so audits work without the CDN"""
+        if axe_path is None:
+            return jsonify({'error': "axe-core is not vendored; run 'flashflow vendor'"}), 404
+        return send_file(axe_path, mimetype='application/javascript')
+
+    @app.after_request
+    def inject_audit_script(response):
+        if (response.mimetype != 'text/html' or response.status_code != 200 or response.is_streamed
+                or request.path.startswith(EXCLUDED_PREFIXES)):
+            return response
+        html = response.get_data(as_text=True)
+        index = html.lower().rfind('</body>')
+        response.set_data(html[:index] + script + html[index:] if index != -1 else html + script)
+        return response
+
+    @app.route('/api/a11y/report', methods=['GET'])
+    def a11y_report():
+        """Aggregated violations for every audited page"""
+        try:
+            fail_on = fail_on_level(app.config['PROJECT'])
+        except A11yError as e:
+            return jsonify({'error': str(e)}), 400
+        return jsonify(get_a11y_report(app).to_dict(fail_on))
+
+    @app.route('/api/a11y/report', methods=['POST'])
+    def a11y_record():
+        """Store the axe-core violations posted by an audited page"""
+        data = request.get_json(silent=True) or {}
+        page = data.get('page')
+        if not isinstance(page, str) or not page.startswith('/'):
+            return jsonify({'error': "'page' must be the audited path"}), 400
+        try:
+            get_a11y_report(app).record(page, data.get('violations'))
+        except A11yError as e:
+            return jsonify({'error': str(e)}), 400
+        return jsonify({'recorded': page}), 201
+
+    @app.route('/api/a11y/report', methods=['DELETE'])
+    def a11y_clear():
+        get_a11y_report(app).clear()
+        return jsonify({'cleared': True})
+
+    @app.route('/admin/a11y')
+    def admin_a11y_page():
+        """Admin page listing violations by page and severity"""
+        project = app.config['PROJECT']
+        return render_template_string(A11Y_ADMIN_TEMPLATE, project_name=project.config.name)
+
+# Runs axe once the page loads and again (debounced) after DOM changes
+A11Y_AUDIT_SCRIPT = """
+<script src="__AXE_URL__"></script>
+<script>
+    (function () {
+        if (!window.axe) return;
+        let timer = null;
+        let running = false;
+
+        async function audit() {
+            if (running) return;
+            running = true;
+            try {
+                const results = await axe.run(document, {
+                    exclude: [['#flashflow-error-overlay'], ['#flashflow-a11y-badge']],
+                    resultTypes: ['violations']
+                });
+                await fetch('/api/a11y/report', {
+                    method: 'POST',
+                    headers: {'Content-Type': 'application/json'},
+                    body: JSON.stringify({page: location.pathname, violations: results.violations})
+                });
+                showBadge(results.violations.length);
+            } catch (e) {
+                console.warn('FlashFlow a11y audit failed', e);
+            } finally {
+                running = false;
+            }
+        }
+
+        function showBadge(count) {
+            let badge = document.getElementById('flashflow-a11y-badge');
+            if (!badge) {
+                badge = document.createElement('a');
+                badge.id = 'flashflow-a11y-badge';
+                badge.href = '/admin/a11y';
+                badge.target = '_blank';
+                badge.style.cssText = 'position:fixed;bottom:12px;right:12px;padding:6px 12px;border-radius:16px;'
+                    + 'font:13px sans-serif;color:#fff;text-decoration:none;z-index:99998;box-shadow:0 2px 6px rgba(0,0,0,0.3)';
+                document.body.appendChild(badge);
+            }
+            badge.style.background = count ? '#b91c1c' : '#15803d';
+            badge.textContent = '♿ ' + (count ? count + ' a11y issue' + (count === 1 ? '' : 's') : 'No a11y issues');
+        }
+
+        function schedule() {
+            clearTimeout(timer);
+            timer = setTimeout(audit, 1500);
+        }
+
+        // Ignore the badge and the diagnostics overlay, or every audit would trigger the next
+        function ours(node) {
+            return node.nodeType === 1 ? !!node.closest('[id^="flashflow-"]') : !!(node.parentElement && ours(node.parentElement));
+        }
+
+        window.addEventListener('load', audit);
+        new MutationObserver(function (mutations) {
+            if (mutations.some(m => !ours(m.target) && (m.type !== 'childList' || ![...m.addedNodes, ...m.removedNodes].every(ours)))) schedule();
+        }).observe(document.documentElement, {childList: true, subtree: true, attributes: true});
+    })();
+</script>
+"""
+
+A11Y_ADMIN_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Accessibility - FlashFlow Admin</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+        .container { max-width: 1200px; margin: 0 auto; padding: 2rem; }
+        .panel { background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); margin-bottom: 1.5rem; }
+        .stats { display: flex; gap: 1rem; flex-wrap: wrap; }
+        .stat { flex: 1; min-width: 120px; text-align: center; padding: 1rem; border-radius: 8px; background: #f3f4f6; }
+        .stat strong { display: block; font-size: 1.8rem; }
+        .impact { display: inline-block; padding: 2px 8px; border-radius: 10px; font-size: 0.8rem; color: white; }
+        .critical { background: #7f1d1d; } .serious { background: #b91c1c; }
+        .moderate { background: #d97706; } .minor { background: #6b7280; }
+        .violation { border-top: 1px solid #e5e7eb; padding: 0.75rem 0; }
+        .violation code { display: block; background: #f3f4f6; padding: 0.3rem 0.5rem; margin: 0.3rem 0; border-radius: 4px; white-space: pre-wrap; word-break: break-all; font-size: 0.8rem; }
+        button { background: #3B82F6; color: white; border: none; padding: 0.4rem 0.9rem; border-radius: 4px; cursor: pointer; }
+        .muted { color: #6b7280; }
+        .failing { color: #b91c1c; font-weight: bold; }
+        #error { color: #b91c1c; font-family: monospace; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>♿ Accessibility</h1>
+        <p>{{ project_name }} · axe-core results from pages opened while serving with --a11y</p>
+    </div>
+    <div class="container">
+        <div class="panel">
+            <div class="stats" id="stats"></div>
+            <p id="gate" class="muted"></p>
+            <p><button id="refresh">Refresh</button> <button id="clear">Clear report</button></p>
+            <div id="error"></div>
+        </div>
+        <div id="pages"></div>
+        <p><a href="/">← Back to Main Dashboard</a></p>
+    </div>
+    <script>
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+
+        async function api(url, options) {
+            const response = await fetch(url, options);
+            const data = await response.json();
+            if (!response.ok) throw new Error(data.error || response.statusText);
+            return data;
+        }
+
+        async function loadReport() {
+            document.getElementById('error').textContent = '';
+            try {
+                const report = await api('/api/a11y/report');
+                document.getElementById('stats').innerHTML =
+                    `<div class="stat"><strong>${report.page_count}</strong>pages</div>`
+                    + ['critical', 'serious', 'moderate', 'minor'].map(level =>
+                        `<div class="stat"><strong>${report.by_impact[level]}</strong><span class="impact ${level}">${level}</span></div>`
+                    ).join('');
+                const gate = document.getElementById('gate');
+                gate.className = report.failing ? 'failing' : 'muted';
+                gate.textContent = report.failing
+                    ? `${report.failing} violation(s) at or above '${report.fail_on}' would fail tests`
+                    : `Nothing at or above '${report.fail_on}'`;
+
+                const pages = Object.entries(report.pages);
+                document.getElementById('pages').innerHTML = pages.map(([page, result]) =>
+                    `<div class="panel"><h3>📄 ${escapeHtml(page)}
+                        <small class="muted">checked ${new Date(result.checked_at * 1000).toLocaleTimeString()}</small></h3>`
+                    + (result.violations.map(v =>
+                        `<div class="violation"><span class="impact ${escapeHtml(v.impact)}">${escapeHtml(v.impact)}</span>
+                            <strong>${escapeHtml(v.help)}</strong> <span class="muted">(${escapeHtml(v.id)}, ${v.node_count} element(s))</span>
+                            ${v.help_url ? `<a href="${escapeHtml(v.help_url)}" target="_blank">rule docs</a>` : ''}`
+                        + v.nodes.map(n => `<code>${escapeHtml(n.target.join(' '))}\n${escapeHtml(n.html)}</code>`).join('')
+                        + '</div>'
+                    ).join('') || '<p class="muted">✅ No violations</p>')
+                    + '</div>'
+                ).join('') || '<div class="panel muted">No pages audited yet. Open a page on this server to audit it.</div>';
+            } catch (e) {
+                document.getElementById('error').textContent = '❌ ' + e.message;
+            }
+        }
+
+        document.getElementById('refresh').onclick = loadReport;
+        document.getElementById('clear').onclick = async () => {
+            await api('/api/a11y/report', {method: 'DELETE'});
+            loadReport();
+        };
+
+        loadReport();
+        setInterval(loadReport, 10000);
+    </script>
+</body>
+</html>
+"""
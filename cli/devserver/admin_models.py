@@ -0,0 +1,106 @@
+"""
+FlashFlow model admin - Serves the screens 'flashflow generate admin' wrote to src/admin
+
+/admin/models/ is the index and /admin/models/<table> manages one model's rows
+through /api/data. Until the admin is generated, both explain how to generate
+it and list the models it would cover. /admin/cpanel, the old admin panel
+address, redirects here.
+"""
+
+import re
+
+from flask import redirect, render_template_string, send_from_directory
+
+from core.admin_scaffold import ADMIN_DIR, ASSETS_DIR, URL_PREFIX, admin_model_specs
+from core.parser.parser import FlowParser
+
+PAGE_NAME = re.compile(r'^[A-Za-z0-9_-]+$')
+
+def register_admin_models(app):
+    """Register /admin/models/ and the redirect from /admin/cpanel"""
+    project = app.config['PROJECT']
+    admin_dir = project.root_path / 'src' / ADMIN_DIR
+
+    def not_generated(table=None):
+        try:
+            specs = admin_model_specs(FlowParser().parse_project(project.root_path))
+        except Exception as e:
+            specs, error = [], str(e)
+        else:
+            error = None
+        return render_template_string(NOT_GENERATED_TEMPLATE, project_name=project.config.name, specs=specs,
+                                      table=table, error=error), 404
+
+    @app.route('/admin/cpanel')
+    def admin_cpanel():
+        return redirect(f"{URL_PREFIX}/")
+
+    @app.route(URL_PREFIX)
+    @app.route(f"{URL_PREFIX}/")
+    def admin_models_index():
+        if not (admin_dir / 'index.html').is_file():
+            return not_generated()
+        return send_from_directory(str(admin_dir), 'index.html', max_age=0)
+
+    @app.route(f"{URL_PREFIX}/{ASSETS_DIR}/<path:filename>")
+    def admin_models_asset(filename):
+        return send_from_directory(str(admin_dir / ASSETS_DIR), filename, max_age=0)
+
+    @app.route(f"{URL_PREFIX}/<table>")
+    def admin_models_page(table):
+        if not PAGE_NAME.match(table) or not (admin_dir / f"{table}.html").is_file():
+            return not_generated(table)
+        return send_from_directory(str(admin_dir), f"{table}.html", max_age=0)
+
+NOT_GENERATED_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Admin - FlashFlow</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+        .container { max-width: 900px; margin: 0 auto; padding: 2rem; }
+        .panel { background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); margin-bottom: 1.5rem; }
+        pre { background: #1f2937; color: #f9fafb; padding: 1rem; border-radius: 4px; }
+        .muted { color: #6b7280; }
+        .error { color: #b91c1c; font-family: monospace; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>🛠️ Admin</h1>
+        <p>{{ project_name }} · management screens for every model</p>
+    </div>
+    <div class="container">
+        <div class="panel">
+            {% if table %}
+            <h2>No admin page for '{{ table }}'</h2>
+            {% else %}
+            <h2>The admin has not been generated yet</h2>
+            {% endif %}
+            <p>Generate list, detail and edit screens for your models with:</p>
+            <pre>flashflow generate admin</pre>
+            <p class="muted">The pages are written to src/admin/, where you can change them; this server picks them up straight away.</p>
+        </div>
+        <div class="panel">
+            <h3>Models in the flows</h3>
+            {% if error %}
+            <p class="error">❌ {{ error }}</p>
+            {% elif specs %}
+            <ul>
+                {% for spec in specs %}
+                <li><strong>{{ spec.model }}</strong> → /admin/models/{{ spec.table }} ({{ spec.fields|length }} fields)</li>
+                {% endfor %}
+            </ul>
+            {% else %}
+            <p class="muted">No models yet; declare one with <code>model:</code> in a .flow file.</p>
+            {% endif %}
+            <p class="muted">Rows can also be browsed table by table at <a href="/admin/database">/admin/database</a>.</p>
+        </div>
+    </div>
+</body>
+</html>
+"""
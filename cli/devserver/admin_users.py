@@ -0,0 +1,262 @@
+"""
+FlashFlow admin users - /admin/users management backed by the dev auth store
+"""
+
+from flask import request, jsonify, render_template_string
+
+from core.framework import FlashFlowProject
+from cli.devserver.audit import audit_admin
+from cli.devserver.auth_store import DevAuthStore, AuthStoreError, ROLES
+
+def get_auth_store(app) -> DevAuthStore:
+    """Return the auth store shared by every dev server subsystem"""
+    if 'AUTH_STORE' not in app.config:
+        project: FlashFlowProject = app.config['PROJECT']
+        app.config['AUTH_STORE'] = DevAuthStore(project.state.path("auth.db"))
+    return app.config['AUTH_STORE']
+
+def register_admin_users(app):
+    """Register the admin users page and its JSON API"""
+
+    @app.route('/admin/users')
+    def admin_users_page():
+        """Admin page for managing dev users"""
+        project = app.config['PROJECT']
+        return render_template_string(ADMIN_USERS_TEMPLATE, project_name=project.config.name, roles=ROLES)
+
+    @app.route('/admin/api/users', methods=['GET'])
+    def admin_list_users():
+        return jsonify({'users': get_auth_store(app).list_users()})
+
+    @app.route('/admin/api/users', methods=['POST'])
+    def admin_create_user():
+        data = request.get_json(silent=True) or {}
+        store = get_auth_store(app)
+        try:
+            user = store.create_user(
+                data.get('email', ''),
+                data.get('password', ''),
+                data.get('name', ''),
+                data.get('role', 'user')
+            )
+        except AuthStoreError as e:
+            return jsonify({'error': str(e)}), 400
+
+        audit_admin(app, 'user.create', 'users', user['email'], new={'name': user['name'], 'role': user['role']})
+        return jsonify({'user': user}), 201
+
+    @app.route('/admin/api/users/<int:user_id>/disable', methods=['POST'])
+    def admin_disable_user(user_id):
+        return _set_disabled(user_id, True)
+
+    @app.route('/admin/api/users/<int:user_id>/enable', methods=['POST'])
+    def admin_enable_user(user_id):
+        return _set_disabled(user_id, False)
+
+    @app.route('/admin/api/users/<int:user_id>/reset-password', methods=['POST'])
+    def admin_reset_password(user_id):
+        data = request.get_json(silent=True) or {}
+        store = get_auth_store(app)
+        try:
+            password = store.reset_password(user_id, data.get('password'))
+        except AuthStoreError as e:
+            return jsonify({'error': str(e)}), _status_for(e)
+
+        user = store.get_user(user_id)
+        audit_admin(app, 'user.reset_password', 'users', user['email'])
+        # Only generated passwords are echoed back; a chosen one is already known to the caller
+        return jsonify({'user': user, 'temporary_password': None if data.get('password') else password})
+
+    @app.route('/admin/api/users/<int:user_id>/role', methods=['PUT', 'POST'])
+    def admin_set_role(user_id):
+        data = request.get_json(silent=True) or {}
+        store = get_auth_store(app)
+        previous = store.get_user(user_id)
+        try:
+            user = store.set_role(user_id, data.get('role', ''))
+        except AuthStoreError as e:
+            return jsonify({'error': str(e)}), _status_for(e)
+
+        audit_admin(app, 'user.set_role', 'users', user['email'], {'role': previous['role']}, {'role': user['role']})
+        return jsonify({'user': user})
+
+    @app.route('/admin/api/users/<int:user_id>/scopes', methods=['PUT', 'POST'])
+    def admin_set_scopes(user_id):
+        data = request.get_json(silent=True) or {}
+        scopes = data.get('scopes') or []
+        if isinstance(scopes, str):
+            scopes = scopes.split(',')
+        store = get_auth_store(app)
+        previous = store.get_user(user_id)
+        try:
+            user = store.set_scopes(user_id, scopes)
+        except AuthStoreError as e:
+            return jsonify({'error': str(e)}), _status_for(e)
+
+        audit_admin(app, 'user.set_scopes', 'users', user['email'], {'scopes': previous['scopes']}, {'scopes': user['scopes']})
+        return jsonify({'user': user})
+
+    def _set_disabled(user_id: int, disabled: bool):
+        store = get_auth_store(app)
+        try:
+            user = store.set_disabled(user_id, disabled)
+        except AuthStoreError as e:
+            return jsonify({'error': str(e)}), _status_for(e)
+
+        audit_admin(app, 'user.disable' if disabled else 'user.enable', 'users', user['email'],
+                    {'disabled': not disabled}, {'disabled': disabled})
+        return jsonify({'user': user})
+
+def _status_for(error: AuthStoreError) -> int:
+    return 404 if 'not found' in str(error) else 400
+
+ADMIN_USERS_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Users - FlashFlow Admin</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+        .container { max-width: 1200px; margin: 0 auto; padding: 2rem; }
+        .panel { background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); margin-bottom: 2rem; }
+        table { width: 100%; border-collapse: collapse; }
+        th, td { text-align: left; padding: 0.6rem; border-bottom: 1px solid #e5e7eb; }
+        input, select { padding: 0.5rem; border: 1px solid #d1d5db; border-radius: 4px; margin-right: 0.5rem; }
+        button { background: #3B82F6; color: white; border: none; padding: 0.5rem 1rem; border-radius: 4px; cursor: pointer; }
+        button.secondary { background: #6b7280; }
+        button.danger { background: #ef4444; }
+        .badge { padding: 2px 8px; border-radius: 10px; font-size: 0.8rem; background: #e0e7ff; color: #3730a3; }
+        .disabled { color: #9ca3af; }
+        #message { margin: 1rem 0; font-family: monospace; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>👥 Users</h1>
+        <p>Manage development accounts for {{ project_name }}</p>
+    </div>
+    <div class="container">
+        <div class="panel">
+            <h3>Create user</h3>
+            <form id="create-form">
+                <input name="email" type="email" placeholder="email" required>
+                <input name="name" placeholder="name">
+                <input name="password" type="password" placeholder="password" required>
+                <select name="role">
+                    {% for role in roles %}<option value="{{ role }}"{% if role == 'user' %} selected{% endif %}>{{ role }}</option>{% endfor %}
+                </select>
+                <button type="submit">Create</button>
+            </form>
+            <div id="message"></div>
+        </div>
+        <div class="panel">
+            <h3>Accounts</h3>
+            <table>
+                <thead><tr><th>ID</th><th>Email</th><th>Name</th><th>Role</th><th>Scopes</th><th>Status</th><th>Actions</th></tr></thead>
+                <tbody id="users"></tbody>
+            </table>
+        </div>
+        <div class="panel">
+            <h3>Audit log <small><a href="/admin/audit">all entries</a></small></h3>
+            <table>
+                <thead><tr><th>When</th><th>Actor</th><th>Action</th><th>User</th><th>Changes</th></tr></thead>
+                <tbody id="audit"></tbody>
+            </table>
+        </div>
+        <p><a href="/">← Back to Main Dashboard</a></p>
+    </div>
+    <script>
+        const roles = {{ roles | tojson }};
+
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+
+        function showMessage(text) {
+            document.getElementById('message').textContent = text;
+        }
+
+        async function api(method, url, body) {
+            const response = await fetch(url, {
+                method: method,
+                headers: {'Content-Type': 'application/json'},
+                body: body ? JSON.stringify(body) : undefined
+            });
+            const data = await response.json();
+            if (!response.ok) throw new Error(data.error || response.statusText);
+            return data;
+        }
+
+        async function load() {
+            const users = (await api('GET', '/admin/api/users')).users;
+            document.getElementById('users').innerHTML = users.map(u => `
+                <tr class="${u.disabled ? 'disabled' : ''}">
+                    <td>${u.id}</td>
+                    <td>${escapeHtml(u.email)}</td>
+                    <td>${escapeHtml(u.name)}</td>
+                    <td><select onchange="setRole(${u.id}, this.value)">
+                        ${roles.map(r => `<option ${r === u.role ? 'selected' : ''}>${r}</option>`).join('')}
+                    </select></td>
+                    <td><input value="${escapeHtml(u.scopes.join(', '))}" placeholder="todos:write" onchange="setScopes(${u.id}, this.value)"></td>
+                    <td><span class="badge">${u.disabled ? 'disabled' : 'active'}</span></td>
+                    <td>
+                        <button class="secondary" onclick="resetPassword(${u.id})">Reset password</button>
+                        <button class="${u.disabled ? '' : 'danger'}" onclick="toggle(${u.id}, ${u.disabled})">${u.disabled ? 'Enable' : 'Disable'}</button>
+                    </td>
+                </tr>`).join('') || '<tr><td colspan="7">No users yet</td></tr>';
+
+            const entries = (await api('GET', '/admin/api/audit?kind=admin&entity=users&limit=50')).entries;
+            document.getElementById('audit').innerHTML = entries.map(e => `
+                <tr>
+                    <td>${escapeHtml(e.created_at)}</td>
+                    <td>${escapeHtml(e.actor)}</td>
+                    <td>${escapeHtml(e.action)}</td>
+                    <td>${escapeHtml(e.entity_id)}</td>
+                    <td><code>${escapeHtml(JSON.stringify(e.new || {}))}</code></td>
+                </tr>`).join('') || '<tr><td colspan="5">No admin actions yet</td></tr>';
+        }
+
+        async function run(action) {
+            try {
+                await action();
+            } catch (e) {
+                showMessage('❌ ' + e.message);
+            }
+            load();
+        }
+
+        function setRole(id, role) {
+            run(async () => { await api('PUT', `/admin/api/users/${id}/role`, {role}); showMessage('✅ Role updated'); });
+        }
+
+        function setScopes(id, scopes) {
+            run(async () => { await api('PUT', `/admin/api/users/${id}/scopes`, {scopes}); showMessage('✅ Scopes updated'); });
+        }
+
+        function toggle(id, disabled) {
+            run(async () => { await api('POST', `/admin/api/users/${id}/${disabled ? 'enable' : 'disable'}`); showMessage('✅ Status updated'); });
+        }
+
+        function resetPassword(id) {
+            run(async () => {
+                const data = await api('POST', `/admin/api/users/${id}/reset-password`);
+                showMessage(`✅ Temporary password for ${data.user.email}: ${data.temporary_password}`);
+            });
+        }
+
+        document.getElementById('create-form').addEventListener('submit', event => {
+            event.preventDefault();
+            const form = Object.fromEntries(new FormData(event.target));
+            run(async () => { await api('POST', '/admin/api/users', form); event.target.reset(); showMessage('✅ User created'); });
+        });
+
+        load();
+    </script>
+</body>
+</html>
+"""
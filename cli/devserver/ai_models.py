@@ -0,0 +1,271 @@
+"""
+FlashFlow dev AI models - /api/ai/<name>/predict for every model under 'ai_models:'
+
+    GET  /api/ai                    declared models, and why any cannot be used
+    POST /api/ai/<name>/predict     {"features": [[0.2, 1.0, 3.5, 0.0]]}
+    GET  /api/ai/embed              the 'embeddings' settings and whether they load
+    POST /api/ai/embed              {"texts": ["red shoes"], "collection": "products", "metadata": [{"price": 40}]}
+
+Declarations are re-read from the flows on each request. A model is loaded on
+its first prediction and again when its .onnx file or its declaration
+changes. /api/ai/embed runs the tokenizer and model from the 'embeddings'
+settings (see core/embeddings.py); with 'collection' the vectors and their
+texts (and any 'metadata' objects) are also upserted into that /vector index.
+Predictions and embeddings stop when the client disconnects or the
+request's X-Request-Timeout passes (see request_context.py). The endpoints
+are described in /api/data/openapi.json.
+"""
+
+import threading
+import time
+from typing import Dict, Any, List, Optional, Tuple
+
+from flask import request, jsonify
+
+from core.ai_models import AiModelError, ModelDeclaration, ModelRunner, load_declarations
+from core.cancellation import Cancelled
+from core.embeddings import Embedder, EmbeddingError
+from core.inference import InferenceConfigError, InferenceOptions
+from core.parser.parser import FlowParser
+from core.validation import SchemaValidationError
+from core.vector_search import VectorSearchError
+from cli.devserver.request_context import cancellable, cancelled_response
+from cli.devserver.vector_search import get_vector_indexes
+
+MAX_TEXTS = 256
+
+class DevModels:
+    """Declarations from the flows and the models loaded so far"""
+
+    def __init__(self, project):
+        self.project = project
+        self.runners: Dict[str, ModelRunner] = {}
+        self._lock = threading.Lock()
+
+    def declarations(self) -> Tuple[Dict[str, ModelDeclaration], Dict[str, str]]:
+        ir = FlowParser().parse_project(self.project.root_path)
+        return load_declarations(getattr(ir, 'ai_models', None) or {})
+
+    def runner(self, declaration: ModelDeclaration) -> ModelRunner:
+        with self._lock:
+            runner = self.runners.get(declaration.name)
+            if runner is None or runner.declaration != declaration or runner.stale():
+                if runner is not None:
+                    runner.close()
+                runner = ModelRunner(declaration, self.project.root_path, InferenceOptions.for_project(self.project))
+                self.runners[declaration.name] = runner
+            return runner
+
+    def status(self, name: str) -> Dict[str, Any]:
+        runner = self.runners.get(name)
+        if runner is None:
+            return {'loaded': False}
+        return {'loaded': True, 'backend': runner.backend, 'providers': runner.info.get('active')}
+
+def get_embedder(app) -> Embedder:
+    """The project's embedder, loaded on first use; flashflow.json changes restart the server"""
+    if 'EMBEDDER' not in app.config:
+        app.config['EMBEDDER'] = Embedder.for_project(app.config['PROJECT'])
+    return app.config['EMBEDDER']
+
+def get_dev_models(app) -> DevModels:
+    if 'AI_MODELS' not in app.config:
+        app.config['AI_MODELS'] = DevModels(app.config['PROJECT'])
+    return app.config['AI_MODELS']
+
+def register_ai_models(app):
+    """Register the /api/ai routes"""
+    models = get_dev_models(app)
+
+    @app.route('/api/ai', methods=['GET'])
+    def ai_models_index():
+        declarations, errors = models.declarations()
+        return jsonify({
+            'models': [dict(declaration.to_dict(), **models.status(name)) for name, declaration in sorted(declarations.items())],
+            'errors': errors
+        })
+
+    @app.route('/api/ai/<name>/predict', methods=['POST'])
+    def ai_model_predict(name):
+        declarations, errors = models.declarations()
+        if name in errors:
+            return jsonify({'error': errors[name]}), 500
+        if name not in declarations:
+            return jsonify({'error': f"No model '{name}' under ai_models: in the flows"}), 404
+        if request.get_data() and request.get_json(silent=True) is None:
+            return jsonify({'error': "The request body is not valid JSON"}), 400
+
+        try:
+            runner = models.runner(declarations[name])
+        except (AiModelError, InferenceConfigError) as e:
+            return jsonify({'error': str(e)}), 500
+        except RuntimeError as e:
+            # onnxruntime is not installed
+            return jsonify({'error': str(e)}), 503
+        except Exception as e:
+            return jsonify({'error': f"Could not load '{name}': {str(e)}"}), 500
+
+        try:
+            with cancellable(app) as cancel:
+                return jsonify(runner.predict(request.get_json(silent=True), cancel))
+        except SchemaValidationError as e:
+            return jsonify(e.to_dict()), 400
+        except Cancelled as e:
+            return cancelled_response(e)
+        except Exception as e:
+            app.logger.error(f"🤖 Inference failed for '{name}': {str(e)}")
+            return jsonify({'error': f"Inference failed: {str(e)}"}), 500
+
+    @app.route('/api/ai/embed', methods=['GET'])
+    def ai_embed_status():
+        try:
+            embedder = get_embedder(app)
+        except (EmbeddingError, InferenceConfigError, RuntimeError) as e:
+            return jsonify({'configured': bool(app.config['PROJECT'].config.embeddings), 'error': str(e)})
+        return jsonify(dict(embedder.to_dict(), configured=True))
+
+    @app.route('/api/ai/embed', methods=['POST'])
+    def ai_embed():
+        body = request.get_json(silent=True)
+        if not isinstance(body, dict):
+            return jsonify({'error': "Send {'text': ...} or {'texts': [...]} as JSON"}), 400
+        texts = [body['text']] if 'text' in body else body.get('texts')
+        if not isinstance(texts, list) or not texts or not all(isinstance(text, str) for text in texts):
+            return jsonify({'error': "'text' must be a string, or 'texts' a non-empty list of strings"}), 400
+        if len(texts) > MAX_TEXTS:
+            return jsonify({'error': f"At most {MAX_TEXTS} texts per request"}), 400
+        ids = body.get('ids')
+        if ids is not None and (not isinstance(ids, list) or len(ids) != len(texts)
+                                or not all(isinstance(item, (str, int)) and not isinstance(item, bool) for item in ids)):
+            return jsonify({'error': "'ids' must be a list with one string or integer id per text"}), 400
+        metadata = body.get('metadata')
+        if metadata is not None and (not isinstance(metadata, list) or len(metadata) != len(texts)
+                                     or not all(item is None or isinstance(item, dict) for item in metadata)):
+            return jsonify({'error': "'metadata' must be a list with one object per text"}), 400
+        collection = body.get('collection')
+        if collection is not None and (not isinstance(collection, str) or not collection):
+            return jsonify({'error': "'collection' must be the name of a vector index"}), 400
+
+        try:
+            embedder = get_embedder(app)
+        except (EmbeddingError, InferenceConfigError) as e:
+            status = 400 if not app.config['PROJECT'].config.embeddings else 500
+            return jsonify({'error': str(e)}), status
+        except RuntimeError as e:
+            # onnxruntime is not installed
+            return jsonify({'error': str(e)}), 503
+
+        started = time.perf_counter()
+        try:
+            with cancellable(app) as cancel:
+                vectors = embedder.embed(texts, cancel)
+        except EmbeddingError as e:
+            return jsonify({'error': str(e)}), 500
+        except Cancelled as e:
+            return cancelled_response(e)
+        except Exception as e:
+            app.logger.error(f"🤖 Embedding failed: {str(e)}")
+            return jsonify({'error': f"Embedding failed: {str(e)}"}), 500
+
+        result = {
+            'model': embedder.settings.model,
+            'dimension': embedder.dimension,
+            'took_ms': round((time.perf_counter() - started) * 1000, 2),
+            'embeddings': vectors
+        }
+        if collection is not None:
+            try:
+                result['collection'] = upsert_embeddings(app, collection, texts, vectors, ids, metadata)
+            except VectorSearchError as e:
+                return jsonify({'error': str(e)}), 400
+        return jsonify(result)
+
+def upsert_embeddings(app, name: str, texts: List[str], vectors: List[List[float]], ids: Optional[List[Any]],
+                      metadata: Optional[List[Optional[Dict[str, Any]]]] = None) -> Dict[str, Any]:
+    """Store vectors and their texts in a /vector index; a text already stored keeps its id"""
+    indexes = get_vector_indexes(app)
+    index = indexes.get_or_create(name, len(vectors[0]))
+    with index.lock:
+        prepared = [index.vector(vector) for vector in vectors]
+        if ids is None:
+            known = {text: vector_id for vector_id, text in index.documents.items()}
+            next_id = max([vector_id for vector_id in index.vectors if isinstance(vector_id, int)] + [-1]) + 1
+            ids = []
+            for text in texts:
+                if text not in known:
+                    known[text] = next_id
+                    next_id += 1
+                ids.append(known[text])
+        for position, (vector, vector_id, text) in enumerate(zip(prepared, ids, texts)):
+            index.add(vector_id, vector, metadata[position] if metadata else None, document=text)
+    indexes.changed(name)
+    return dict(index.to_dict(name), upserted=len(ids), ids=ids)
+
+def add_ai_model_paths(document, declarations: Dict[str, ModelDeclaration]):
+    """Describe each model's predict endpoint in the dev OpenAPI document"""
+    if not declarations:
+        return
+    schemas = document['components']['schemas']
+    error = {'content': {'application/json': {'schema': {'$ref': '#/components/schemas/Error'}}}}
+    invalid = {'description': 'The input does not match the declaration',
+               'content': {'application/json': {'schema': {'$ref': '#/components/schemas/ValidationError'}}}}
+
+    for name, declaration in sorted(declarations.items()):
+        schema_name = f"AiModel_{name}"
+        schemas[f"{schema_name}Input"] = declaration.request_schema()
+        schemas[f"{schema_name}Prediction"] = declaration.response_schema()
+        document['paths'][f"/api/ai/{name}/predict"] = {
+            'post': {
+                'summary': f"Run the '{name}' model",
+                'description': declaration.description or f"ONNX model {declaration.path}",
+                'tags': ['ai_models'],
+                'requestBody': {'required': True, 'content': {'application/json': {
+                    'schema': {'$ref': f"#/components/schemas/{schema_name}Input"}}}},
+                'responses': {
+                    '200': {'description': 'Model outputs', 'content': {'application/json': {
+                        'schema': {'$ref': f"#/components/schemas/{schema_name}Prediction"}}}},
+                    '400': invalid,
+                    '404': dict(error, description='No such model'),
+                    '500': dict(error, description='The model could not be loaded or run'),
+                    '503': dict(error, description='onnxruntime is not installed')
+                }
+            }
+        }
+
+def add_embed_path(document):
+    """Describe /api/ai/embed in the dev OpenAPI document"""
+    error = {'content': {'application/json': {'schema': {'$ref': '#/components/schemas/Error'}}}}
+    vector = {'type': 'array', 'items': {'type': 'number'}}
+    document['paths']['/api/ai/embed'] = {
+        'post': {
+            'summary': "Embed texts with the project's embedding model",
+            'description': "Tokenizes each text and runs the 'embeddings' model from flashflow.json. With 'collection' "
+                           "the vectors and texts are upserted into that vector index; texts already stored keep their id.",
+            'tags': ['ai_models'],
+            'requestBody': {'required': True, 'content': {'application/json': {'schema': {
+                'type': 'object',
+                'properties': {
+                    'text': {'type': 'string'},
+                    'texts': {'type': 'array', 'items': {'type': 'string'}, 'minItems': 1, 'maxItems': MAX_TEXTS},
+                    'ids': {'type': 'array', 'items': {'oneOf': [{'type': 'string'}, {'type': 'integer'}]}},
+                    'collection': {'type': 'string', 'description': 'Vector index to upsert into'},
+                    'metadata': {'type': 'array', 'items': {'type': 'object'}, 'description': 'Returned with search hits'}
+                }
+            }}}},
+            'responses': {
+                '200': {'description': 'One vector per text', 'content': {'application/json': {'schema': {
+                    'type': 'object',
+                    'properties': {
+                        'model': {'type': 'string'},
+                        'dimension': {'type': 'integer'},
+                        'took_ms': {'type': 'number'},
+                        'embeddings': {'type': 'array', 'items': vector},
+                        'collection': {'type': 'object', 'description': 'The index after the upsert, with the ids used'}
+                    }
+                }}}},
+                '400': dict(error, description='Invalid request, or no embeddings settings'),
+                '500': dict(error, description='The tokenizer or model could not be loaded or run'),
+                '503': dict(error, description='onnxruntime is not installed')
+            }
+        }
+    }
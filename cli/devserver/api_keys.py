@@ -0,0 +1,287 @@
+"""
+FlashFlow dev API keys - Enforce 'auth: api_key' and manage keys in /admin/api-keys
+
+Requests to an endpoint declared with 'auth: api_key' (see core/api_keys.py)
+need an active key, sent whole in X-API-Key or as X-API-Key-Id with an
+X-FlashFlow-Signature. Missing or bad credentials get 401, a key without the
+declared scopes 403, and a key over its per-minute limit 429 with
+Retry-After. Answers to keyed requests carry X-RateLimit-Limit and
+X-RateLimit-Remaining. The key then acts as its role and scopes for any
+'permissions:' on the same endpoint.
+"""
+
+from flask import request, jsonify, render_template_string, g
+
+from core.api_keys import (ApiKeyError, ApiKeyRule, ApiKeyStore, RateLimiter, ReplayCache, DEFAULT_RATE_LIMIT,
+                           SIGNATURE_TOLERANCE, verify_signature)
+from core.framework import FlashFlowProject
+from core.permissions import Principal, ROLES
+from cli.devserver.audit import audit_admin
+from cli.devserver.permissions import get_permission_registry
+
+def get_api_key_store(app) -> ApiKeyStore:
+    if 'API_KEY_STORE' not in app.config:
+        project: FlashFlowProject = app.config['PROJECT']
+        app.config['API_KEY_STORE'] = ApiKeyStore(project.state.path('api_keys.db'))
+    return app.config['API_KEY_STORE']
+
+def authenticate_request(store: ApiKeyStore, replays: ReplayCache):
+    """(key, signed) for the current request; raises ApiKeyError for missing or bad credentials"""
+    if request.headers.get('X-API-Key'):
+        key = store.authenticate(request.headers['X-API-Key'])
+        if key is None:
+            raise ApiKeyError("Unknown or revoked API key")
+        return key, False
+
+    key_id, signature = request.headers.get('X-API-Key-Id', ''), request.headers.get('X-FlashFlow-Signature', '')
+    if not key_id or not signature:
+        raise ApiKeyError("API key required: send X-API-Key, or X-API-Key-Id with X-FlashFlow-Signature")
+    secret = store.secret_for(key_id)
+    if secret is None:
+        raise ApiKeyError("Unknown or revoked API key")
+    query = request.query_string.decode('utf-8', 'surrogateescape')
+    target = f"{request.path}?{query}" if query else request.path
+    _, digest = verify_signature(secret, signature, request.method, target, request.get_data(cache=True))
+    if not replays.check_and_add(key_id, digest):
+        raise ApiKeyError("Signature was already used")
+    return store.get_key(key_id), True
+
+def register_api_keys(app):
+    """Install the API key check, ahead of the permission check, and the admin page"""
+    registry = get_permission_registry(app)
+    limiter, replays = RateLimiter(), ReplayCache()
+
+    @app.before_request
+    def enforce_api_keys():
+        registry.reload_if_changed()
+        rules = registry.matching_key_rules(request.method, request.path)
+        if not rules:
+            return None
+
+        store = get_api_key_store(app)
+        try:
+            key, signed = authenticate_request(store, replays)
+        except ApiKeyError as e:
+            return _unauthorized(str(e), rules[0])
+        for rule in rules:
+            reason = rule.requirement.denial(key, signed)
+            if reason is None:
+                continue
+            if rule.requirement.signed and not signed:
+                return _unauthorized(reason, rule)
+            return jsonify({'error': reason, 'path': rule.path, 'required': rule.requirement.to_dict(),
+                            'api_key': key['id']}), 403
+
+        allowed, remaining, retry_after = limiter.hit(key['id'], key['rate_limit'])
+        g.api_key, g.api_key_rate = key['id'], (key['rate_limit'], remaining)
+        if not allowed:
+            response = jsonify({'error': f"Rate limit of {key['rate_limit']} requests per minute exceeded",
+                                'api_key': key['id'], 'retry_after': retry_after})
+            response.status_code = 429
+            response.headers['Retry-After'] = str(retry_after)
+            return response
+        store.record_use(key['id'])
+        g.api_key_principal = Principal(key['role'], key['scopes'], None, 'api_key')
+        return None
+
+    @app.after_request
+    def add_rate_limit_headers(response):
+        limit, remaining = g.get('api_key_rate') or (0, -1)
+        if limit:
+            response.headers['X-RateLimit-Limit'] = str(limit)
+            response.headers['X-RateLimit-Remaining'] = str(max(remaining, 0))
+        return response
+
+    @app.route('/admin/api-keys')
+    def admin_api_keys_page():
+        project = app.config['PROJECT']
+        return render_template_string(ADMIN_API_KEYS_TEMPLATE, project_name=project.config.name, roles=ROLES,
+                                      default_rate_limit=DEFAULT_RATE_LIMIT, tolerance=SIGNATURE_TOLERANCE)
+
+    @app.route('/admin/api/keys', methods=['GET'])
+    def admin_list_api_keys():
+        registry.reload_if_changed()
+        return jsonify({'keys': get_api_key_store(app).list_keys(),
+                        'endpoints': [rule.to_dict() for rule in registry.key_rules]})
+
+    @app.route('/admin/api/keys', methods=['POST'])
+    def admin_create_api_key():
+        data = request.get_json(silent=True) or {}
+        scopes = data.get('scopes') or []
+        if isinstance(scopes, str):
+            scopes = scopes.split(',')
+        try:
+            key, api_key = get_api_key_store(app).create_key(data.get('name', ''), data.get('role') or 'user', scopes,
+                                                             data.get('rate_limit', DEFAULT_RATE_LIMIT))
+        except ApiKeyError as e:
+            return jsonify({'error': str(e)}), 400
+
+        audit_admin(app, 'api_key.create', 'api_key', key['id'],
+                    new={'name': key['name'], 'role': key['role'], 'scopes': key['scopes']})
+        # The only time the secret leaves the store
+        return jsonify({'key': key, 'api_key': api_key, 'secret': api_key.partition('.')[2]}), 201
+
+    @app.route('/admin/api/keys/<key_id>/revoke', methods=['POST'])
+    def admin_revoke_api_key(key_id):
+        try:
+            key = get_api_key_store(app).revoke_key(key_id)
+        except ApiKeyError as e:
+            return jsonify({'error': str(e)}), 404 if 'not found' in str(e) else 400
+
+        limiter.forget(key_id)
+        audit_admin(app, 'api_key.revoke', 'api_key', key_id, {'name': key['name'], 'revoked': False},
+                    {'name': key['name'], 'revoked': True})
+        return jsonify({'key': key})
+
+def _unauthorized(reason: str, rule: ApiKeyRule):
+    response = jsonify({'error': reason, 'path': rule.path, 'required': rule.requirement.to_dict()})
+    response.status_code = 401
+    response.headers['WWW-Authenticate'] = 'ApiKey realm="FlashFlow dev server"'
+    return response
+
+ADMIN_API_KEYS_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>API keys - FlashFlow Admin</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+        .container { max-width: 1200px; margin: 0 auto; padding: 2rem; }
+        .panel { background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); margin-bottom: 2rem; }
+        table { width: 100%; border-collapse: collapse; }
+        th, td { text-align: left; padding: 0.6rem; border-bottom: 1px solid #e5e7eb; }
+        input, select { padding: 0.5rem; border: 1px solid #d1d5db; border-radius: 4px; margin-right: 0.5rem; }
+        button { background: #3B82F6; color: white; border: none; padding: 0.5rem 1rem; border-radius: 4px; cursor: pointer; }
+        button.danger { background: #ef4444; }
+        .badge { padding: 2px 8px; border-radius: 10px; font-size: 0.8rem; background: #e0e7ff; color: #3730a3; }
+        .revoked { color: #9ca3af; }
+        pre { background: #1f2937; color: #e5e7eb; padding: 1rem; border-radius: 6px; overflow-x: auto; }
+        #message { margin: 1rem 0; font-family: monospace; white-space: pre-wrap; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>🔑 API keys</h1>
+        <p>Keys for the 'auth: api_key' endpoints of {{ project_name }}</p>
+    </div>
+    <div class="container">
+        <div class="panel">
+            <h3>Create key</h3>
+            <form id="create-form">
+                <input name="name" placeholder="name, e.g. partner-acme" required>
+                <select name="role">
+                    {% for role in roles %}<option value="{{ role }}"{% if role == 'user' %} selected{% endif %}>{{ role }}</option>{% endfor %}
+                </select>
+                <input name="scopes" placeholder="scopes, e.g. orders:write">
+                <input name="rate_limit" type="number" min="0" value="{{ default_rate_limit }}" title="Requests per minute; 0 for no limit">
+                <button type="submit">Create</button>
+            </form>
+            <div id="message"></div>
+        </div>
+        <div class="panel">
+            <h3>Keys</h3>
+            <table>
+                <thead><tr><th>ID</th><th>Name</th><th>Role</th><th>Scopes</th><th>Limit/min</th><th>Requests</th><th>Last used</th><th>Status</th><th></th></tr></thead>
+                <tbody id="keys"></tbody>
+            </table>
+        </div>
+        <div class="panel">
+            <h3>Protected endpoints</h3>
+            <table>
+                <thead><tr><th>Method</th><th>Path</th><th>Signed only</th><th>Scopes</th><th>Declared in</th></tr></thead>
+                <tbody id="endpoints"></tbody>
+            </table>
+        </div>
+        <div class="panel">
+            <h3>Signing a request</h3>
+            <p>Send the whole key as <code>X-API-Key</code>, or send <code>X-API-Key-Id</code> and sign with the secret.
+               Signatures are valid for {{ tolerance }} seconds and only once.</p>
+<pre>t=$(date +%s)
+body='{"amount": 10}'
+sig=$(printf '%s' "$t.POST./api/partners/orders.$body" | openssl dgst -sha256 -hmac "$SECRET" | cut -d' ' -f2)
+curl -X POST localhost:8000/api/partners/orders -d "$body" \\
+  -H "X-API-Key-Id: $KEY_ID" -H "X-FlashFlow-Signature: t=$t,v1=$sig"</pre>
+        </div>
+        <p><a href="/admin/users">Users</a> · <a href="/">← Back to Main Dashboard</a></p>
+    </div>
+    <script>
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+
+        function showMessage(text) {
+            document.getElementById('message').textContent = text;
+        }
+
+        async function api(method, url, body) {
+            const response = await fetch(url, {
+                method: method,
+                headers: {'Content-Type': 'application/json'},
+                body: body ? JSON.stringify(body) : undefined
+            });
+            const data = await response.json();
+            if (!response.ok) throw new Error(data.error || response.statusText);
+            return data;
+        }
+
+        async function load() {
+            const data = await api('GET', '/admin/api/keys');
+            document.getElementById('keys').innerHTML = data.keys.map(k => `
+                <tr class="${k.status}">
+                    <td><code>${escapeHtml(k.id)}</code></td>
+                    <td>${escapeHtml(k.name)}</td>
+                    <td>${escapeHtml(k.role)}</td>
+                    <td>${escapeHtml(k.scopes.join(', '))}</td>
+                    <td>${k.rate_limit || 'none'}</td>
+                    <td>${k.requests}</td>
+                    <td>${escapeHtml(k.last_used_at || 'never')}</td>
+                    <td><span class="badge">${k.status}</span></td>
+                    <td>${k.status === 'active' ? `<button class="danger" onclick="revoke('${escapeHtml(k.id)}')">Revoke</button>` : ''}</td>
+                </tr>`).join('') || '<tr><td colspan="9">No keys yet</td></tr>';
+
+            document.getElementById('endpoints').innerHTML = data.endpoints.map(e => `
+                <tr>
+                    <td>${escapeHtml(e.methods.join(', ') || 'any')}</td>
+                    <td><code>${escapeHtml(e.path)}</code></td>
+                    <td>${e.auth.signed ? 'yes' : 'no'}</td>
+                    <td>${escapeHtml(e.auth.scopes.join(', '))}</td>
+                    <td>${escapeHtml(e.source)}</td>
+                </tr>`).join('') || "<tr><td colspan=\\"5\\">No endpoint declares 'auth: api_key'</td></tr>";
+        }
+
+        async function run(action) {
+            try {
+                await action();
+            } catch (e) {
+                showMessage('❌ ' + e.message);
+            }
+            load();
+        }
+
+        function revoke(id) {
+            if (!confirm(`Revoke ${id}? Requests with it will get 401.`)) return;
+            run(async () => { await api('POST', `/admin/api/keys/${id}/revoke`); showMessage('✅ Key revoked'); });
+        }
+
+        document.getElementById('create-form').addEventListener('submit', event => {
+            event.preventDefault();
+            const form = Object.fromEntries(new FormData(event.target));
+            form.rate_limit = Number(form.rate_limit);
+            run(async () => {
+                const data = await api('POST', '/admin/api/keys', form);
+                event.target.reset();
+                showMessage(`✅ Key created. Copy it now, it is not shown again:\\n` +
+                            `X-API-Key: ${data.api_key}\\nKey id: ${data.key.id}\\nSecret: ${data.secret}`);
+            });
+        });
+
+        load();
+    </script>
+</body>
+</html>
+"""
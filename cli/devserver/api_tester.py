@@ -0,0 +1,379 @@
+"""
+FlashFlow API tester - /api/tester, with database sandboxes for destructive testing
+
+The tester sends requests to the dev server and shows the status, time,
+headers and body of each answer. With "Sandbox" on, a tester session works
+on its own copy of the dev database (see core/sandboxes.py): creates,
+updates and deletes change the copy, and the shared seed data stays as it
+is for the next demo.
+
+Any /api/ request can ask for a sandbox:
+
+    X-FlashFlow-Sandbox: sbx_...     that sandbox (410 once it has expired); '0' for the shared database
+    ?sandbox=1                       the sandbox of this browser's session, created on first use
+    ?sandbox=sbx_...                 that sandbox
+    ?sandbox=0                       the shared database again, ending the session's sandbox
+
+?sandbox=1 sets a cookie, so the session's later requests stay in the
+sandbox without it. Sandboxed answers carry X-FlashFlow-Sandbox and
+X-FlashFlow-Sandbox-Expires, and are never cached. API workers resolve
+sandboxes themselves; the generated backend cannot, so sandboxed requests
+to it get 409.
+"""
+
+import time
+from datetime import datetime, timezone
+from typing import Optional
+
+from flask import g, has_request_context, jsonify, render_template_string, request
+
+from core.database import Storage
+from core.sandboxes import SANDBOX_HEADER, SandboxError, SandboxStore
+
+SANDBOX_COOKIE = 'flashflow_sandbox'
+SHARED = ('0', 'off', 'false', 'shared')
+# Requests to these paths manage sandboxes rather than run in one
+UNSANDBOXED_PREFIXES = ('/api/tester',)
+EXPIRE_INTERVAL = 60
+
+def get_sandbox_store(app) -> SandboxStore:
+    if 'SANDBOX_STORE' not in app.config:
+        app.config['SANDBOX_STORE'] = SandboxStore(app.config['PROJECT'])
+    return app.config['SANDBOX_STORE']
+
+def current_sandbox_storage(app) -> Optional[Storage]:
+    """The storage of the current request's sandbox, or None outside a sandbox"""
+    if not has_request_context() or not g.get('sandbox'):
+        return None
+    return get_sandbox_store(app).storage(g.sandbox)
+
+def sandbox_requested() -> bool:
+    """Whether the current request asks to run in a sandbox"""
+    requested = request.headers.get(SANDBOX_HEADER) or request.args.get('sandbox')
+    if requested is not None:
+        return requested.strip().lower() not in SHARED
+    return bool(request.cookies.get(SANDBOX_COOKIE))
+
+def register_sandboxes(app):
+    """Run /api/ requests that ask for it in a sandbox; the API workers register this too"""
+    last_expired = [0.0]
+
+    @app.before_request
+    def use_sandbox():
+        if not request.path.startswith('/api/') or request.path.startswith(UNSANDBOXED_PREFIXES):
+            return None
+        if not sandbox_requested():
+            return None
+        store = get_sandbox_store(app)
+        now = time.time()
+        if now - last_expired[0] >= EXPIRE_INTERVAL:
+            last_expired[0] = now
+            store.expire()
+
+        header = request.headers.get(SANDBOX_HEADER)
+        asked = (header or request.args.get('sandbox') or '').strip()
+        cookie = request.cookies.get(SANDBOX_COOKIE)
+        try:
+            if asked and asked != '1':
+                sandbox = store.get(asked)
+                if sandbox is None:
+                    return jsonify({'error': f"Sandbox {asked} has expired or does not exist; start a new one",
+                                    'sandbox': asked}), 410
+            else:
+                # ?sandbox=1, or a session that started with it
+                sandbox = store.get(cookie) if cookie else None
+                if sandbox is None:
+                    sandbox = store.create(label=f"session {request.remote_addr or ''}".strip())
+                    g.sandbox_cookie = True
+        except SandboxError as e:
+            return jsonify({'error': str(e)}), 409
+        g.sandbox = store.touch(sandbox)
+        return None
+
+    @app.after_request
+    def tag_sandbox_response(response):
+        sandbox = g.get('sandbox')
+        if sandbox:
+            response.headers[SANDBOX_HEADER] = sandbox.id
+            response.headers[f"{SANDBOX_HEADER}-Expires"] = _iso(sandbox.expires_at)
+            if g.get('sandbox_cookie'):
+                response.set_cookie(SANDBOX_COOKIE, sandbox.id, path='/api/', samesite='Lax')
+        elif request.args.get('sandbox', '').strip().lower() in SHARED and request.cookies.get(SANDBOX_COOKIE):
+            response.delete_cookie(SANDBOX_COOKIE, path='/api/')
+        return response
+
+def register_api_tester(app):
+    """Register /api/tester and the sandbox API it uses"""
+    project = app.config['PROJECT']
+    register_sandboxes(app)
+
+    @app.route('/api/tester')
+    def api_tester():
+        """Built-in API testing tool"""
+        store = get_sandbox_store(app)
+        return render_template_string(API_TESTER_TEMPLATE, project_name=project.config.name,
+                                      ttl_minutes=int(store.ttl // 60), header=SANDBOX_HEADER)
+
+    @app.route('/api/tester/sandboxes', methods=['GET'])
+    def list_sandboxes():
+        store = get_sandbox_store(app)
+        store.expire()
+        try:
+            source, error = str(store.source_path().relative_to(project.root_path)), None
+        except (SandboxError, ValueError) as e:
+            source, error = None, str(e) if isinstance(e, SandboxError) else None
+        return jsonify({'sandboxes': [_sandbox_dict(sandbox) for sandbox in store.list()], 'source': source,
+                        'available': error is None, 'error': error, 'ttl_minutes': store.ttl / 60,
+                        'max': store.max_sandboxes})
+
+    @app.route('/api/tester/sandboxes', methods=['POST'])
+    def create_sandbox():
+        data = request.get_json(silent=True) or {}
+        try:
+            sandbox = get_sandbox_store(app).create(label=str(data.get('label') or 'API tester'))
+        except SandboxError as e:
+            return jsonify({'error': str(e)}), 409
+        return jsonify({'sandbox': _sandbox_dict(sandbox)}), 201
+
+    @app.route('/api/tester/sandboxes/<sandbox_id>', methods=['DELETE'])
+    def discard_sandbox(sandbox_id):
+        if not get_sandbox_store(app).discard(sandbox_id):
+            return jsonify({'error': f"No sandbox {sandbox_id}"}), 404
+        return jsonify({'discarded': sandbox_id})
+
+def _sandbox_dict(sandbox):
+    return dict(sandbox.to_dict(), expires=_iso(sandbox.expires_at))
+
+def _iso(timestamp: float) -> str:
+    return datetime.fromtimestamp(timestamp, timezone.utc).isoformat(timespec='seconds')
+
+API_TESTER_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>API Tester - {{ project_name }}</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+        .container { max-width: 1200px; margin: 0 auto; padding: 2rem; }
+        .panel { background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); margin-bottom: 2rem; }
+        .request { display: grid; grid-template-columns: 110px 1fr auto; gap: 0.5rem; }
+        input, select, textarea { padding: 0.5rem; border: 1px solid #d1d5db; border-radius: 4px; font-family: inherit; box-sizing: border-box; }
+        textarea { width: 100%; margin-top: 0.5rem; font-family: monospace; }
+        button { background: #3B82F6; color: white; border: none; padding: 0.5rem 1rem; border-radius: 4px; cursor: pointer; }
+        button.secondary { background: #6b7280; }
+        button.danger { background: #ef4444; }
+        .sandbox { display: flex; gap: 1rem; align-items: center; flex-wrap: wrap; padding: 0.75rem 1rem; border-radius: 6px; background: #f3f4f6; margin-bottom: 1rem; }
+        .sandbox.on { background: #ecfdf5; border-left: 4px solid #10b981; }
+        .muted { color: #6b7280; font-size: 0.9rem; }
+        .status { font-weight: 600; }
+        .status.ok { color: #15803d; } .status.error { color: #b91c1c; }
+        pre { background: #1f2937; color: #e5e7eb; padding: 1rem; border-radius: 6px; overflow-x: auto; white-space: pre-wrap; }
+        table { width: 100%; border-collapse: collapse; }
+        th, td { text-align: left; padding: 0.5rem; border-bottom: 1px solid #e5e7eb; font-size: 0.9rem; }
+        #message { margin: 0.5rem 0; font-family: monospace; white-space: pre-wrap; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>🧪 API Tester</h1>
+        <p>Test the API of {{ project_name }}</p>
+    </div>
+    <div class="container">
+        <div class="panel">
+            <div class="sandbox" id="sandbox-bar">
+                <label><input type="checkbox" id="sandbox-toggle"> Sandbox</label>
+                <span class="muted" id="sandbox-status">Requests use the shared dev database</span>
+                <button class="secondary" id="sandbox-reset" hidden>Start over</button>
+            </div>
+            <form id="request-form">
+                <div class="request">
+                    <select id="method">
+                        <option>GET</option><option>POST</option><option>PUT</option><option>PATCH</option><option>DELETE</option>
+                    </select>
+                    <input type="text" id="url" list="endpoints" placeholder="/api/data/todos" value="/api/data">
+                    <button type="submit">Send</button>
+                </div>
+                <datalist id="endpoints"></datalist>
+                <textarea id="headers" rows="2" placeholder="Headers, one per line: X-API-Key: ffk_..."></textarea>
+                <textarea id="body" rows="6" placeholder="Request body (JSON)"></textarea>
+            </form>
+            <div id="message"></div>
+        </div>
+        <div class="panel">
+            <h3>Response <span class="status" id="status"></span> <span class="muted" id="timing"></span></h3>
+            <pre id="response-headers" hidden></pre>
+            <pre id="response">Send a request to see its response</pre>
+        </div>
+        <div class="panel">
+            <h3>Sandboxes</h3>
+            <p class="muted">Each is a copy of <code id="source">the dev database</code>, discarded after
+               {{ ttl_minutes }} minutes without requests. Any /api/ request can use one with
+               <code>{{ header }}: &lt;id&gt;</code> or <code>?sandbox=1</code>.</p>
+            <table>
+                <thead><tr><th>ID</th><th>Label</th><th>Created</th><th>Expires</th><th></th></tr></thead>
+                <tbody id="sandboxes"></tbody>
+            </table>
+        </div>
+        <p><a href="/api/data/openapi.json">OpenAPI</a> · <a href="/">← Back to Main Dashboard</a></p>
+    </div>
+    <script>
+        const HEADER = {{ header|tojson }};
+        // A tester session is this tab: its sandbox lives as long as the tab does
+        let sandbox = JSON.parse(sessionStorage.getItem('flashflow-sandbox') || 'null');
+        const toggle = document.getElementById('sandbox-toggle');
+        toggle.checked = sessionStorage.getItem('flashflow-sandbox-on') === '1';
+
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+
+        function showMessage(text) {
+            document.getElementById('message').textContent = text;
+        }
+
+        async function api(method, url, body) {
+            const response = await fetch(url, {
+                method: method,
+                headers: {'Content-Type': 'application/json'},
+                body: body ? JSON.stringify(body) : undefined
+            });
+            const data = await response.json();
+            if (!response.ok) throw new Error(data.error || response.statusText);
+            return data;
+        }
+
+        function remember(value) {
+            sandbox = value;
+            sessionStorage.setItem('flashflow-sandbox', JSON.stringify(value));
+            showSandbox();
+        }
+
+        function showSandbox() {
+            const on = toggle.checked && sandbox;
+            document.getElementById('sandbox-bar').classList.toggle('on', !!on);
+            document.getElementById('sandbox-reset').hidden = !on;
+            document.getElementById('sandbox-status').textContent = on
+                ? `Requests change sandbox ${sandbox.id}, not the shared data (expires ${new Date(sandbox.expires).toLocaleTimeString()} unless used)`
+                : 'Requests use the shared dev database';
+        }
+
+        async function ensureSandbox() {
+            if (!toggle.checked) return null;
+            if (!sandbox) {
+                remember((await api('POST', '/api/tester/sandboxes', {label: 'API tester'})).sandbox);
+                loadSandboxes();
+            }
+            return sandbox;
+        }
+
+        async function send(retried) {
+            const method = document.getElementById('method').value;
+            const url = document.getElementById('url').value;
+            const body = document.getElementById('body').value;
+            const headers = {'Content-Type': 'application/json'};
+            for (const line of document.getElementById('headers').value.split('\\n')) {
+                const at = line.indexOf(':');
+                if (at > 0) headers[line.slice(0, at).trim()] = line.slice(at + 1).trim();
+            }
+            const current = await ensureSandbox();
+            headers[HEADER] = current ? current.id : '0';
+
+            const started = performance.now();
+            const response = await fetch(url, {method: method, headers: headers,
+                                               body: body && method !== 'GET' ? body : undefined});
+            const text = await response.text();
+            if (response.status === 410 && current && !retried) {
+                // The sandbox expired; start a fresh copy and send again
+                remember(null);
+                showMessage('⌛ The sandbox expired; sent again in a new one');
+                return send(true);
+            }
+            if (current && response.headers.get(HEADER + '-Expires')) {
+                remember(Object.assign({}, current, {expires: response.headers.get(HEADER + '-Expires')}));
+            }
+
+            const status = document.getElementById('status');
+            status.textContent = `${response.status} ${response.statusText}`;
+            status.className = 'status ' + (response.ok ? 'ok' : 'error');
+            document.getElementById('timing').textContent = `${Math.round(performance.now() - started)} ms`;
+            const responseHeaders = [...response.headers.entries()].map(([name, value]) => `${name}: ${value}`).join('\\n');
+            document.getElementById('response-headers').textContent = responseHeaders;
+            document.getElementById('response-headers').hidden = !responseHeaders;
+            let shown = text;
+            try { shown = JSON.stringify(JSON.parse(text), null, 2); } catch (e) { /* not JSON */ }
+            document.getElementById('response').textContent = shown || '(empty body)';
+        }
+
+        async function loadSandboxes() {
+            const data = await api('GET', '/api/tester/sandboxes');
+            if (data.source) document.getElementById('source').textContent = data.source;
+            if (!data.available) {
+                toggle.checked = false;
+                toggle.disabled = true;
+                document.getElementById('sandbox-status').textContent = data.error;
+            }
+            if (sandbox && !data.sandboxes.some(s => s.id === sandbox.id)) remember(null);
+            document.getElementById('sandboxes').innerHTML = data.sandboxes.map(s => `
+                <tr>
+                    <td><code>${escapeHtml(s.id)}</code>${sandbox && s.id === sandbox.id ? ' (this tab)' : ''}</td>
+                    <td>${escapeHtml(s.label)}</td>
+                    <td>${new Date(s.created_at * 1000).toLocaleTimeString()}</td>
+                    <td>${new Date(s.expires_at * 1000).toLocaleTimeString()}</td>
+                    <td><button class="danger" onclick="discard('${escapeHtml(s.id)}')">Discard</button></td>
+                </tr>`).join('') || '<tr><td colspan="5">No sandboxes</td></tr>';
+        }
+
+        async function run(action) {
+            try {
+                await action();
+            } catch (e) {
+                showMessage('❌ ' + e.message);
+            }
+        }
+
+        function discard(id) {
+            run(async () => {
+                await api('DELETE', `/api/tester/sandboxes/${id}`);
+                if (sandbox && sandbox.id === id) remember(null);
+                showMessage(`🗑️ Sandbox ${id} discarded`);
+                loadSandboxes();
+            });
+        }
+
+        async function loadEndpoints() {
+            const data = await api('GET', '/api/data');
+            document.getElementById('endpoints').innerHTML = data.models.map(m => `<option value="${escapeHtml(m.url)}">`).join('');
+        }
+
+        toggle.addEventListener('change', () => {
+            sessionStorage.setItem('flashflow-sandbox-on', toggle.checked ? '1' : '0');
+            showSandbox();
+            if (toggle.checked) run(async () => { await ensureSandbox(); showSandbox(); });
+        });
+        document.getElementById('sandbox-reset').addEventListener('click', () => {
+            run(async () => {
+                if (sandbox) await api('DELETE', `/api/tester/sandboxes/${sandbox.id}`).catch(() => null);
+                remember(null);
+                await ensureSandbox();
+                showMessage('✅ New sandbox with a fresh copy of the dev database');
+                loadSandboxes();
+            });
+        });
+        document.getElementById('request-form').addEventListener('submit', event => {
+            event.preventDefault();
+            showMessage('');
+            run(() => send(false));
+        });
+
+        showSandbox();
+        run(loadSandboxes);
+        run(loadEndpoints);
+    </script>
+</body>
+</html>
+"""
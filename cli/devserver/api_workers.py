@@ -0,0 +1,329 @@
+"""
+FlashFlow API workers - serve the generated /api/data API from separate processes
+
+    flashflow serve --api-workers 4
+
+The dev server starts N worker processes, each running only the generated
+API (dev CRUD with its permissions and request hooks) on a free localhost
+port, and reverse-proxies WORKER_PREFIXES to them in turn. Heavy CRUD or
+load-test traffic then keeps the workers busy while previews, admin pages
+and live reload stay responsive in the main process.
+
+Everything else under /api keeps running in the main process: vector
+indexes, the mailbox, chaos rules and the other subsystems that hold their
+state in memory. Chaos rules and tracing still apply to proxied requests,
+and the trace continues inside the worker. The main server supervises the
+workers: a worker that exits is started again, and all of them stop with
+the server. /__workers lists them.
+
+A worker is started as 'python -m cli.devserver.api_workers <project>'.
+"""
+
+import argparse
+import atexit
+import itertools
+import logging
+import os
+import subprocess
+import sys
+import threading
+import time
+from pathlib import Path
+from typing import Dict, Any, List, Optional
+
+import requests
+from flask import Flask, Response, request, jsonify, g
+
+from core.framework import FlashFlowProject
+from cli.devserver.request_log import REQUEST_ID_HEADER
+from core.tracing import get_tracer
+
+logger = logging.getLogger(__name__)
+
+WORKER_PREFIXES = ('/api/data',)
+STARTUP_TIMEOUT = 15.0
+PROXY_TIMEOUT = 60.0
+# A worker that crashes sooner than this after starting is restarted no faster than this
+RESTART_DELAY = 2.0
+SUPERVISE_INTERVAL = 1.0
+# Headers that belong to one connection and are not forwarded
+HOP_HEADERS = {'connection', 'keep-alive', 'proxy-authenticate', 'proxy-authorization', 'te', 'trailer',
+               'transfer-encoding', 'upgrade', 'content-length', 'content-encoding', 'host'}
+FLASHFLOW_ROOT = Path(__file__).resolve().parent.parent.parent
+
+class ApiWorkerError(Exception):
+    """Raised when an API worker does not start"""
+    pass
+
+class ApiWorker:
+    """One worker process and the port it serves on"""
+
+    def __init__(self, index: int, port_file: Path, log_path: Path):
+        self.index = index
+        self.port_file = port_file
+        self.log_path = log_path
+        self.process: Optional[subprocess.Popen] = None
+        self.port: Optional[int] = None
+        self.started_at = 0.0
+        self.restarts = 0
+        self.requests = 0
+        self.failures = 0
+        # False between a failed proxy attempt and the next start
+        self.available = False
+
+    @property
+    def alive(self) -> bool:
+        return self.process is not None and self.process.poll() is None
+
+    def start(self, command: List[str], env: Dict[str, str], cwd: Path):
+        if self.port_file.exists():
+            self.port_file.unlink()
+        with open(self.log_path, 'a') as log_file:
+            self.process = subprocess.Popen(command + ['--port-file', str(self.port_file)], stdout=log_file,
+                                            stderr=subprocess.STDOUT, cwd=str(cwd), env=env)
+        self.started_at = time.time()
+        self.port = None
+        self.available = False
+
+    def wait_ready(self, timeout: float = STARTUP_TIMEOUT):
+        """Wait for the worker to write the port it listens on"""
+        deadline = time.time() + timeout
+        while time.time() < deadline:
+            if not self.alive:
+                raise ApiWorkerError(f"API worker {self.index} exited with code {self.process.returncode}; see {self.log_path}")
+            if self.port_file.exists():
+                text = self.port_file.read_text().strip()
+                if text.isdigit():
+                    self.port = int(text)
+                    self.available = True
+                    return
+            time.sleep(0.1)
+        raise ApiWorkerError(f"API worker {self.index} did not start within {timeout:.0f}s; see {self.log_path}")
+
+    def stop(self, timeout: float = 5.0):
+        if not self.alive:
+            return
+        self.process.terminate()
+        try:
+            self.process.wait(timeout=timeout)
+        except subprocess.TimeoutExpired:
+            self.process.kill()
+            self.process.wait()
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {
+            'index': self.index,
+            'pid': self.process.pid if self.process else None,
+            'port': self.port,
+            'alive': self.alive,
+            'available': self.available,
+            'uptime_seconds': round(time.time() - self.started_at) if self.alive else 0,
+            'restarts': self.restarts,
+            'requests': self.requests,
+            'failures': self.failures,
+            'log': str(self.log_path)
+        }
+
+class ApiWorkerPool:
+    """Starts the workers, restarts the ones that exit and picks one per request"""
+
+    def __init__(self, project: FlashFlowProject, count: int, env: Optional[Dict[str, str]] = None,
+                 profile_name: Optional[str] = None, strict_schema: bool = False):
+        self.project = project
+        self.env = env or {}
+        self.command = [sys.executable, '-m', 'cli.devserver.api_workers', str(project.root_path)]
+        if profile_name:
+            self.command += ['--env', profile_name]
+        if strict_schema:
+            self.command.append('--strict-schema')
+        self.workers = [ApiWorker(index, project.state.path('run', f"api-worker-{index}.port"),
+                                  project.state.logs_dir / f"api-worker-{index}.log")
+                        for index in range(count)]
+        self._cycle = itertools.cycle(self.workers)
+        self._lock = threading.Lock()
+        self._stopping = threading.Event()
+        self._supervisor: Optional[threading.Thread] = None
+
+    def _environ(self) -> Dict[str, str]:
+        env = dict(os.environ, **self.env)
+        # The worker imports the CLI's modules whether or not it is installed
+        env['PYTHONPATH'] = os.pathsep.join(filter(None, [str(FLASHFLOW_ROOT), env.get('PYTHONPATH')]))
+        return get_tracer().inject_env(env)
+
+    def start(self):
+        """Start every worker and wait until each one listens"""
+        try:
+            for worker in self.workers:
+                worker.start(self.command, self._environ(), self.project.root_path)
+            for worker in self.workers:
+                worker.wait_ready()
+        except Exception:
+            self.stop()
+            raise
+        self._write_runtime()
+        # Never leave workers behind when the server dies before its own cleanup
+        atexit.register(self.stop)
+        self._supervisor = threading.Thread(target=self._supervise, name='api-worker-supervisor', daemon=True)
+        self._supervisor.start()
+
+    def _supervise(self):
+        while not self._stopping.wait(SUPERVISE_INTERVAL):
+            for worker in self.workers:
+                if worker.alive or self._stopping.is_set():
+                    continue
+                if time.time() - worker.started_at < RESTART_DELAY:
+                    continue
+                logger.warning(f"API worker {worker.index} exited with code {worker.process.returncode}; restarting it")
+                try:
+                    worker.start(self.command, self._environ(), self.project.root_path)
+                    worker.restarts += 1
+                    worker.wait_ready()
+                    self._write_runtime()
+                except (ApiWorkerError, OSError) as e:
+                    logger.warning(str(e))
+
+    def _write_runtime(self):
+        self.project.state.write_runtime('api-workers', {
+            'workers': [{'index': worker.index, 'pid': worker.process.pid if worker.process else None,
+                         'port': worker.port} for worker in self.workers]
+        })
+
+    def next_worker(self) -> Optional[ApiWorker]:
+        """The next worker in turn that is up, or None when none is"""
+        with self._lock:
+            for _ in range(len(self.workers)):
+                worker = next(self._cycle)
+                if worker.available and worker.alive:
+                    return worker
+        return None
+
+    def stop(self):
+        self._stopping.set()
+        for worker in self.workers:
+            worker.stop()
+        self.project.state.clear_runtime('api-workers')
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'prefixes': list(WORKER_PREFIXES), 'workers': [worker.to_dict() for worker in self.workers]}
+
+def register_api_workers(app, pool: ApiWorkerPool):
+    """Proxy WORKER_PREFIXES to the pool and register /__workers
+
+    Register right after chaos mode, so chaos still applies but the main
+    process's permission checks and request hooks are left to the worker.
+    """
+
+    @app.before_request
+    def proxy_to_api_worker():
+        if not request.path.startswith(WORKER_PREFIXES):
+            return None
+        # A worker that refuses the connection is skipped and the next one tried
+        for _ in range(len(pool.workers)):
+            worker = pool.next_worker()
+            if worker is None:
+                break
+            try:
+                upstream = forward(worker)
+            except requests.ConnectionError:
+                worker.available = False
+                worker.failures += 1
+                continue
+            except requests.RequestException as e:
+                worker.failures += 1
+                return jsonify({'error': f"API worker {worker.index} failed: {str(e)}"}), 502
+            worker.requests += 1
+            g.api_worker = worker.index
+            return upstream
+        return jsonify({'error': 'No API worker is running; see /__workers'}), 503
+
+    @app.route('/__workers')
+    def api_workers():
+        return jsonify(pool.to_dict())
+
+def forward(worker: ApiWorker) -> Response:
+    """Send the current request to a worker and turn its answer into a Flask response"""
+    response = proxy_request(worker.port)
+    response.headers['X-FlashFlow-Worker'] = str(worker.index)
+    return response
+
+def proxy_request(port: int) -> Response:
+    """Send the current request to a server on a localhost port and turn its answer into a Flask response"""
+    headers = {name: value for name, value in request.headers.items() if name.lower() not in HOP_HEADERS}
+    headers['X-Forwarded-For'] = request.remote_addr or ''
+    headers['X-Forwarded-Host'] = request.host
+    headers['X-Forwarded-Proto'] = request.scheme
+    # The worker adopts the id, so its error bodies and crash reports carry the one the client sees
+    headers[REQUEST_ID_HEADER] = g.get('request_id') or headers.get(REQUEST_ID_HEADER, '')
+    get_tracer().inject_headers(headers)
+    upstream = requests.request(
+        request.method,
+        f"http://127.0.0.1:{port}{request.full_path if request.query_string else request.path}",
+        headers=headers,
+        data=request.get_data(),
+        allow_redirects=False,
+        timeout=PROXY_TIMEOUT
+    )
+    response = Response(upstream.content, status=upstream.status_code)
+    # The raw headers keep repeated ones such as Set-Cookie apart
+    for name, value in upstream.raw.headers.items():
+        if name.lower() not in HOP_HEADERS:
+            response.headers.add(name, value)
+    return response
+
+def create_worker_app(project: FlashFlowProject, profile, strict_schema: bool = False) -> Flask:
+    """The generated API alone, as one worker serves it"""
+    from cli.devserver.api_tester import register_sandboxes
+    from cli.devserver.dev_crud import register_dev_crud
+    from cli.devserver.flow_hooks import register_flow_hooks
+    from cli.devserver.permissions import register_permissions
+    from cli.devserver.request_log import register_request_log
+    from cli.devserver.stats import register_stats
+    from cli.devserver.tracing import register_tracing
+
+    app = Flask(__name__)
+    app.config['PROJECT'] = project
+    app.config['STRICT_SCHEMA'] = strict_schema
+    app.config['PROFILE'] = profile
+    # Every worker must see the others' invalidations
+    app.config['SHARED_RESPONSE_CACHE'] = True
+    register_request_log(app, access_log=False)
+    register_tracing(app)
+    register_stats(app, guardrails=False)
+    register_permissions(app)
+    register_sandboxes(app)
+    register_dev_crud(app)
+    register_flow_hooks(app)
+    return app
+
+def main(argv: Optional[List[str]] = None):
+    """Run one API worker until the dev server terminates it"""
+    from werkzeug.serving import make_server
+
+    from core.profiles import load_profile
+    from core.tracing import configure_tracing
+
+    parser = argparse.ArgumentParser(description='FlashFlow API worker')
+    parser.add_argument('project_root')
+    parser.add_argument('--port-file', required=True)
+    parser.add_argument('--env', default=None)
+    parser.add_argument('--strict-schema', action='store_true')
+    args = parser.parse_args(argv)
+
+    logging.basicConfig(level=logging.INFO, format='%(asctime)s %(levelname)s %(message)s')
+    project = FlashFlowProject(Path(args.project_root))
+    configure_tracing(project, 'flashflow-api-worker')
+    app = create_worker_app(project, load_profile(project, args.env), args.strict_schema)
+    server = make_server('127.0.0.1', 0, app, threaded=True)
+    # Written last, so the dev server only proxies once the port accepts connections
+    port_file = Path(args.port_file)
+    partial = port_file.with_suffix('.tmp')
+    partial.write_text(str(server.server_port))
+    partial.replace(port_file)
+    logger.info(f"API worker {os.getpid()} serving {', '.join(WORKER_PREFIXES)} on 127.0.0.1:{server.server_port}")
+    try:
+        server.serve_forever()
+    except KeyboardInterrupt:
+        pass
+
+if __name__ == '__main__':
+    main()
@@ -0,0 +1,237 @@
+"""
+FlashFlow dev audit - Who changed what, for every /api/data write and admin action
+
+    GET /admin/api/audit                  newest entries; ?actor= &kind= &action= &entity= &entity_id=
+                                          &since= &until= (ISO times) &limit= &before=<id> for older pages
+    GET /admin/api/audit/export           the same filters, oldest first, as ?format=csv (default) or jsonl
+    GET /admin/api/audit/verify           checks the hash chain; 409 when an entry was changed or removed
+    /admin/audit                          viewer with filters and export links
+
+Entries are appended to .flashflow/audit.db (see core/audit_log.py) after
+the write they describe has committed. Writes inside an API tester sandbox
+touch a throwaway copy of the data and are not recorded. A failed append is
+logged as an error; the write it describes has already happened.
+"""
+
+import logging
+from typing import Any, Dict, Optional
+
+from flask import Response, g, jsonify, render_template_string, request
+
+from core.audit_log import AuditEntry, AuditLog, AuditLogError
+from core.framework import FlashFlowProject
+from cli.devserver.api_tester import current_sandbox_storage
+
+logger = logging.getLogger(__name__)
+
+EXPORT_TYPES = {'csv': 'text/csv', 'jsonl': 'application/x-ndjson'}
+
+def get_audit_log(app) -> AuditLog:
+    if 'AUDIT_LOG' not in app.config:
+        project: FlashFlowProject = app.config['PROJECT']
+        app.config['AUDIT_LOG'] = AuditLog(project.state.path("audit.db"))
+    return app.config['AUDIT_LOG']
+
+def current_actor() -> str:
+    """Who performed an admin action (dev server has no admin login)"""
+    return request.headers.get('X-FlashFlow-Actor', 'admin')
+
+def request_actor(app) -> str:
+    """Who the current /api/data request acts as"""
+    if g.get('api_key'):
+        return f"api_key:{g.api_key}"
+    principal = g.get('flashflow_principal')
+    if principal is None:
+        # Endpoints without permission rules are not checked, so nobody resolved the principal yet
+        from cli.devserver.permissions import resolve_principal
+        principal = resolve_principal(app)
+    if principal is not None and principal.email:
+        return principal.email
+    if principal is not None and principal.role:
+        return f"{principal.role} ({principal.source})"
+    return request.headers.get('X-FlashFlow-Actor') or 'anonymous'
+
+def _append(app, entry: AuditEntry):
+    try:
+        get_audit_log(app).record(entry)
+    except Exception as e:
+        logger.error(f"Audit entry not recorded ({entry.kind} {entry.action} {entry.entity} {entry.entity_id}): {str(e)}")
+
+def audit_write(app, action: str, table: str, row_id: Any, old: Optional[Dict[str, Any]], new: Optional[Dict[str, Any]]):
+    """Record a committed create, update or delete of a row"""
+    if current_sandbox_storage(app) is not None:
+        return
+    _append(app, AuditEntry(request_actor(app), 'data', action, table, None if row_id is None else str(row_id), old, new,
+                            f"{request.method} {request.path}"))
+
+def audit_admin(app, action: str, entity: str, entity_id: Any = None, old: Optional[Dict[str, Any]] = None,
+                new: Optional[Dict[str, Any]] = None):
+    """Record an admin action"""
+    _append(app, AuditEntry(current_actor(), 'admin', action, entity, None if entity_id is None else str(entity_id), old, new,
+                            f"{request.method} {request.path}"))
+
+def _query() -> Dict[str, Optional[str]]:
+    return {name: request.args.get(name) for name in ('actor', 'kind', 'action', 'entity', 'entity_id', 'since', 'until')}
+
+def register_audit(app):
+    """Register the audit log API and /admin/audit"""
+
+    @app.route('/admin/api/audit')
+    def admin_audit_entries():
+        try:
+            entries = get_audit_log(app).entries(request.args.get('limit', 100, type=int),
+                                                 request.args.get('before', type=int), **_query())
+        except AuditLogError as e:
+            return jsonify({'error': str(e)}), 400
+        return jsonify({'entries': [entry.to_dict() for entry in entries],
+                        'next_before': entries[-1].id if entries else None})
+
+    @app.route('/admin/api/audit/export')
+    def admin_audit_export():
+        fmt = request.args.get('format', 'csv')
+        try:
+            body = get_audit_log(app).export(fmt, **_query())
+        except AuditLogError as e:
+            return jsonify({'error': str(e)}), 400
+        return Response(body, mimetype=EXPORT_TYPES[fmt],
+                        headers={'Content-Disposition': f'attachment; filename="audit.{fmt}"'})
+
+    @app.route('/admin/api/audit/verify')
+    def admin_audit_verify():
+        checked, problem = get_audit_log(app).verify()
+        return jsonify({'ok': problem is None, 'checked': checked, 'problem': problem}), 200 if problem is None else 409
+
+    @app.route('/admin/audit')
+    def admin_audit_page():
+        project = app.config['PROJECT']
+        return render_template_string(AUDIT_TEMPLATE, project_name=project.config.name)
+
+AUDIT_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Audit Log - FlashFlow Admin</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+        .container { max-width: 1400px; margin: 0 auto; padding: 2rem; }
+        .panel { background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); margin-bottom: 1.5rem; }
+        table { width: 100%; border-collapse: collapse; }
+        th, td { text-align: left; padding: 0.5rem; border-bottom: 1px solid #e5e7eb; font-size: 0.9rem; vertical-align: top; }
+        input, select { padding: 0.4rem; border: 1px solid #d1d5db; border-radius: 4px; margin-right: 0.5rem; }
+        button { background: #3B82F6; color: white; border: none; padding: 0.5rem 1rem; border-radius: 4px; cursor: pointer; }
+        a.button { display: inline-block; background: #6b7280; color: white; padding: 0.45rem 1rem; border-radius: 4px; text-decoration: none; margin-right: 0.5rem; }
+        code { background: #f3f4f6; padding: 0.1rem 0.3rem; border-radius: 4px; }
+        .old { color: #b91c1c; }
+        .new { color: #047857; }
+        .muted { color: #6b7280; }
+        #chain.ok { color: #047857; }
+        #chain.broken { color: #b91c1c; font-weight: bold; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>🧾 Audit Log</h1>
+        <p>{{ project_name }} · Every data write and admin action, append-only</p>
+    </div>
+    <div class="container">
+        <div class="panel">
+            <form id="filters">
+                <select name="kind"><option value="">data and admin</option><option value="data">data</option><option value="admin">admin</option></select>
+                <input name="actor" placeholder="actor">
+                <input name="entity" placeholder="table or entity">
+                <input name="entity_id" placeholder="id" size="6">
+                <input name="action" placeholder="action" size="12">
+                <input name="since" type="datetime-local" title="since (UTC)">
+                <input name="until" type="datetime-local" title="until (UTC)">
+                <button type="submit">Filter</button>
+            </form>
+            <p>
+                <a class="button" id="export-csv" href="/admin/api/audit/export?format=csv">Export CSV</a>
+                <a class="button" id="export-jsonl" href="/admin/api/audit/export?format=jsonl">Export JSON lines</a>
+                <span id="chain" class="muted">checking…</span>
+            </p>
+        </div>
+        <div class="panel">
+            <table>
+                <thead><tr><th>#</th><th>When (UTC)</th><th>Actor</th><th>Action</th><th>Entity</th><th>Changes</th><th>Request</th></tr></thead>
+                <tbody id="entries"></tbody>
+            </table>
+            <p><button id="older" style="display:none">Older entries</button></p>
+        </div>
+        <p><a href="/">← Back to Main Dashboard</a></p>
+    </div>
+    <script>
+        let before = null;
+
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+
+        function value(v) {
+            return v === undefined ? '<span class="muted">—</span>' : '<code>' + escapeHtml(JSON.stringify(v)) + '</code>';
+        }
+
+        function changes(entry) {
+            if (!entry.changed.length) return '<span class="muted">no field changes</span>';
+            return entry.changed.map(name => escapeHtml(name) + ': ' +
+                (entry.old ? '<span class="old">' + value(entry.old[name]) + '</span> → ' : '') +
+                (entry.new ? '<span class="new">' + value(entry.new[name]) + '</span>' : '<span class="old">removed</span>')).join('<br>');
+        }
+
+        function query() {
+            const params = new URLSearchParams();
+            for (const [name, v] of new FormData(document.getElementById('filters'))) {
+                if (v) params.set(name, v);
+            }
+            return params;
+        }
+
+        async function load(more) {
+            const params = query();
+            if (more && before) params.set('before', before);
+            const data = await (await fetch('/admin/api/audit?' + params)).json();
+            const rows = (data.entries || []).map(e => `
+                <tr>
+                    <td>${e.id}</td>
+                    <td>${escapeHtml(e.created_at.replace('T', ' ').slice(0, 19))}</td>
+                    <td>${escapeHtml(e.actor)}</td>
+                    <td>${escapeHtml(e.kind)} <strong>${escapeHtml(e.action)}</strong></td>
+                    <td>${escapeHtml(e.entity)}${e.entity_id ? ' <code>' + escapeHtml(e.entity_id) + '</code>' : ''}</td>
+                    <td>${changes(e)}</td>
+                    <td class="muted">${escapeHtml(e.request)}</td>
+                </tr>`).join('');
+            const body = document.getElementById('entries');
+            body.innerHTML = more ? body.innerHTML + rows
+                : (data.error ? '<tr><td colspan="7">' + escapeHtml(data.error) + '</td></tr>'
+                   : rows || '<tr><td colspan="7" class="muted">Nothing recorded yet</td></tr>');
+            before = data.next_before;
+            document.getElementById('older').style.display = (data.entries || []).length === 100 ? '' : 'none';
+            const exportQuery = params;
+            exportQuery.delete('before');
+            document.getElementById('export-csv').href = '/admin/api/audit/export?format=csv&' + exportQuery;
+            document.getElementById('export-jsonl').href = '/admin/api/audit/export?format=jsonl&' + exportQuery;
+        }
+
+        async function verify() {
+            const data = await (await fetch('/admin/api/audit/verify')).json();
+            const chain = document.getElementById('chain');
+            chain.className = data.ok ? 'ok' : 'broken';
+            chain.textContent = data.ok ? `✓ ${data.checked} entries, hash chain intact` : `✗ ${data.problem}`;
+        }
+
+        document.getElementById('filters').addEventListener('submit', event => {
+            event.preventDefault();
+            load(false);
+        });
+        document.getElementById('older').addEventListener('click', () => load(true));
+        load(false);
+        verify();
+    </script>
+</body>
+</html>
+"""
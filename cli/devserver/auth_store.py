@@ -0,0 +1,171 @@
+"""
+FlashFlow dev auth store - Users and roles for local development
+
+Admin actions on users are recorded in the audit log (cli/devserver/audit.py).
+"""
+
+import hashlib
+import hmac
+import secrets
+import sqlite3
+import threading
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, Any, List, Optional
+
+from core.permissions import ROLES
+
+DEFAULT_ROLE = 'user'
+PASSWORD_ITERATIONS = 120000
+
+class AuthStoreError(ValueError):
+    """Raised for invalid user operations (duplicate email, unknown role, ...)"""
+    pass
+
+def hash_password(password: str) -> str:
+    """Hash a password with PBKDF2-SHA256"""
+    salt = secrets.token_hex(16)
+    digest = hashlib.pbkdf2_hmac('sha256', password.encode('utf-8'), salt.encode('utf-8'), PASSWORD_ITERATIONS)
+    return f"pbkdf2_sha256${PASSWORD_ITERATIONS}${salt}${digest.hex()}"
+
+def verify_password(password: str, password_hash: str) -> bool:
+    """Check a password against a hash produced by hash_password"""
+    try:
+        _, iterations, salt, expected = password_hash.split('$')
+    except ValueError:
+        return False
+    digest = hashlib.pbkdf2_hmac('sha256', password.encode('utf-8'), salt.encode('utf-8'), int(iterations))
+    return hmac.compare_digest(digest.hex(), expected)
+
+class DevAuthStore:
+    """SQLite-backed user store used by the development server"""
+
+    def __init__(self, db_path: Path):
+        self.db_path = Path(db_path)
+        self.db_path.parent.mkdir(parents=True, exist_ok=True)
+        self._lock = threading.Lock()
+        self._init_schema()
+
+    def _connect(self) -> sqlite3.Connection:
+        conn = sqlite3.connect(str(self.db_path))
+        conn.row_factory = sqlite3.Row
+        return conn
+
+    def _init_schema(self):
+        with self._connect() as conn:
+            conn.execute("""
+                CREATE TABLE IF NOT EXISTS users (
+                    id INTEGER PRIMARY KEY AUTOINCREMENT,
+                    email VARCHAR(255) NOT NULL UNIQUE,
+                    name VARCHAR(255) NOT NULL DEFAULT '',
+                    password_hash VARCHAR(255) NOT NULL,
+                    role VARCHAR(50) NOT NULL DEFAULT 'user',
+                    scopes TEXT NOT NULL DEFAULT '',
+                    disabled BOOLEAN NOT NULL DEFAULT 0,
+                    created_at DATETIME NOT NULL,
+                    updated_at DATETIME NOT NULL
+                )
+            """)
+            # Stores created before users had scopes
+            columns = {row['name'] for row in conn.execute("PRAGMA table_info(users)")}
+            if 'scopes' not in columns:
+                conn.execute("ALTER TABLE users ADD COLUMN scopes TEXT NOT NULL DEFAULT ''")
+            conn.commit()
+
+    @staticmethod
+    def _user_to_dict(row: sqlite3.Row) -> Dict[str, Any]:
+        return {
+            'id': row['id'],
+            'email': row['email'],
+            'name': row['name'],
+            'role': row['role'],
+            'scopes': [scope for scope in row['scopes'].split(',') if scope],
+            'disabled': bool(row['disabled']),
+            'created_at': row['created_at'],
+            'updated_at': row['updated_at']
+        }
+
+    def list_users(self) -> List[Dict[str, Any]]:
+        with self._connect() as conn:
+            rows = conn.execute("SELECT * FROM users ORDER BY id").fetchall()
+        return [self._user_to_dict(row) for row in rows]
+
+    def get_user(self, user_id: int) -> Optional[Dict[str, Any]]:
+        with self._connect() as conn:
+            row = conn.execute("SELECT * FROM users WHERE id = ?", (user_id,)).fetchone()
+        return self._user_to_dict(row) if row else None
+
+    def create_user(self, email: str, password: str, name: str = '', role: str = DEFAULT_ROLE) -> Dict[str, Any]:
+        email = (email or '').strip().lower()
+        if not email or '@' not in email:
+            raise AuthStoreError("A valid email is required")
+        if not password or len(password) < 6:
+            raise AuthStoreError("Password must be at least 6 characters")
+        self._check_role(role)
+
+        now = datetime.now().isoformat()
+        with self._lock, self._connect() as conn:
+            try:
+                cursor = conn.execute(
+                    "INSERT INTO users (email, name, password_hash, role, disabled, created_at, updated_at) "
+                    "VALUES (?, ?, ?, ?, 0, ?, ?)",
+                    (email, name or '', hash_password(password), role, now, now)
+                )
+            except sqlite3.IntegrityError:
+                raise AuthStoreError(f"A user with email '{email}' already exists")
+            conn.commit()
+            user_id = cursor.lastrowid
+
+        return self.get_user(user_id)
+
+    def set_disabled(self, user_id: int, disabled: bool) -> Dict[str, Any]:
+        return self._update(user_id, "disabled = ?", (1 if disabled else 0,))
+
+    def set_role(self, user_id: int, role: str) -> Dict[str, Any]:
+        self._check_role(role)
+        return self._update(user_id, "role = ?", (role,))
+
+    def set_scopes(self, user_id: int, scopes: List[str]) -> Dict[str, Any]:
+        """Replace the scopes a user's requests carry, such as todos:write"""
+        cleaned = []
+        for scope in scopes:
+            scope = str(scope).strip()
+            if not scope:
+                continue
+            if ',' in scope or any(char.isspace() for char in scope):
+                raise AuthStoreError(f"Invalid scope '{scope}'; scopes cannot contain commas or spaces")
+            if scope not in cleaned:
+                cleaned.append(scope)
+        return self._update(user_id, "scopes = ?", (','.join(cleaned),))
+
+    def reset_password(self, user_id: int, new_password: Optional[str] = None) -> str:
+        """Set a new password, generating a temporary one when none is given"""
+        password = new_password or secrets.token_urlsafe(9)
+        if len(password) < 6:
+            raise AuthStoreError("Password must be at least 6 characters")
+        self._update(user_id, "password_hash = ?", (hash_password(password),))
+        return password
+
+    def authenticate(self, email: str, password: str) -> Optional[Dict[str, Any]]:
+        """Return the user for valid, enabled credentials"""
+        with self._connect() as conn:
+            row = conn.execute("SELECT * FROM users WHERE email = ?", ((email or '').strip().lower(),)).fetchone()
+        if not row or row['disabled'] or not verify_password(password, row['password_hash']):
+            return None
+        return self._user_to_dict(row)
+
+    def _update(self, user_id: int, assignment: str, params: tuple) -> Dict[str, Any]:
+        with self._lock, self._connect() as conn:
+            cursor = conn.execute(
+                f"UPDATE users SET {assignment}, updated_at = ? WHERE id = ?",
+                params + (datetime.now().isoformat(), user_id)
+            )
+            conn.commit()
+        if cursor.rowcount == 0:
+            raise AuthStoreError(f"User {user_id} not found")
+        return self.get_user(user_id)
+
+    @staticmethod
+    def _check_role(role: str):
+        if role not in ROLES:
+            raise AuthStoreError(f"Unknown role '{role}'. Expected one of: {', '.join(ROLES)}")
@@ -0,0 +1,279 @@
+"""
+FlashFlow dev branch previews - /branch/<slug>/ for static builds of other git branches
+
+    /branch/                     every local branch, its preview and a Build button
+    /branch/<slug>/<path>        a branch's exported pages (see core/branch_previews.py)
+    GET    /api/branches         branches and their previews
+    POST   /api/branches/build   {"branch": "feature/login"}; the build runs in the background
+    POST   /api/branches/clean   remove stale previews
+    DELETE /api/branches/<slug>
+
+Opening a preview whose branch has moved on rebuilds it in the background;
+the old build is served until the new one is ready. Stale previews are
+cleaned up when the server starts and after every build.
+"""
+
+import re
+import threading
+from typing import Dict
+
+import click
+from flask import Response, jsonify, redirect, render_template_string, request, send_from_directory
+
+from core.branch_previews import BranchPreviewError, BranchPreviews
+from core.html_safety import escape_html
+
+PREFIX = '/branch'
+# Exports are made for the site root; these attributes get the preview's prefix in front of root-relative URLs
+ROOT_RELATIVE = re.compile(r'(\s(?:href|src|action|poster)=")/(?!/)')
+SRCSET = re.compile(r'(\ssrcset=")([^"]*)(")')
+MAX_CONCURRENT_BUILDS = 2
+
+def get_branch_previews(app) -> BranchPreviews:
+    if 'BRANCH_PREVIEWS' not in app.config:
+        app.config['BRANCH_PREVIEWS'] = BranchPreviews(app.config['PROJECT'])
+    return app.config['BRANCH_PREVIEWS']
+
+def rebase_html(html: str, base: str) -> str:
+    """Point an exported page's root-relative links and assets at base ('/branch/<slug>')"""
+    html = ROOT_RELATIVE.sub(lambda match: f"{match.group(1)}{base}/", html)
+    return SRCSET.sub(lambda match: match.group(1) + ', '.join(
+        base + candidate.strip() if candidate.strip().startswith('/') and not candidate.strip().startswith('//')
+        else candidate.strip() for candidate in match.group(2).split(',')) + match.group(3), html)
+
+def register_branch_previews(app):
+    """Register /branch/ and the branch preview API"""
+    previews = get_branch_previews(app)
+    building: Dict[str, threading.Thread] = {}
+    slots = threading.BoundedSemaphore(MAX_CONCURRENT_BUILDS)
+    lock = threading.Lock()
+
+    def build_in_background(branch: str) -> bool:
+        """Start a build unless that branch is already being built"""
+        with lock:
+            if branch in building and building[branch].is_alive():
+                return False
+
+            def run():
+                with slots:
+                    record = previews.build(branch)
+                if record['status'] == 'failed':
+                    click.echo(f"❌ Branch preview {branch}: {record['error']}", err=True)
+                else:
+                    click.echo(f"🌿 Branch preview {branch} built: {PREFIX}/{record['slug']}/")
+                clean()
+
+            building[branch] = threading.Thread(target=run, name=f"branch-preview-{branch}", daemon=True)
+            building[branch].start()
+            return True
+
+    def clean():
+        try:
+            for slug, reason in previews.clean():
+                click.echo(f"🧹 Removed branch preview {slug}: {reason}")
+        except BranchPreviewError as e:
+            click.echo(f"⚠️  Branch preview cleanup failed: {str(e)}", err=True)
+
+    previews.reset_interrupted()
+    threading.Thread(target=clean, name='branch-preview-cleanup', daemon=True).start()
+
+    @app.route('/api/branches')
+    def branches_list():
+        try:
+            branches = previews.branches()
+        except BranchPreviewError as e:
+            return jsonify({'error': str(e)}), 400
+        records = previews.records()
+        by_branch = {record.get('branch'): record for record in records.values()}
+        listed = []
+        for name in sorted(branches, key=lambda name: (name not in by_branch, name)):
+            record = dict(by_branch.get(name) or {})
+            record.update(branch=name, head=branches[name], outdated=bool(record.get('commit')) and record['commit'] != branches[name])
+            if record.get('slug') and (previews.output_root / record['slug']).is_dir():
+                record['url'] = f"{PREFIX}/{record['slug']}/"
+            listed.append(record)
+        return jsonify({'current': previews.current_branch(), 'branches': listed,
+                        'max_branches': previews.max_branches, 'max_age_days': previews.max_age / 86400})
+
+    @app.route('/api/branches/build', methods=['POST'])
+    def branches_build():
+        branch = (request.get_json(silent=True) or {}).get('branch')
+        if not branch:
+            return jsonify({'error': "Send {\"branch\": \"<name>\"}"}), 400
+        try:
+            previews.head(str(branch))
+        except BranchPreviewError as e:
+            return jsonify({'error': str(e)}), 404
+        started = build_in_background(str(branch))
+        return jsonify({'branch': branch, 'started': started}), 202
+
+    @app.route('/api/branches/clean', methods=['POST'])
+    def branches_clean():
+        try:
+            removed = previews.clean()
+        except BranchPreviewError as e:
+            return jsonify({'error': str(e)}), 400
+        return jsonify({'removed': [{'slug': slug, 'reason': reason} for slug, reason in removed]})
+
+    @app.route('/api/branches/<slug>', methods=['DELETE'])
+    def branches_remove(slug):
+        if not previews.remove(slug):
+            return jsonify({'error': f"No preview '{slug}'"}), 404
+        return jsonify({'removed': slug})
+
+    @app.route(PREFIX + '/')
+    def branches_index():
+        project = app.config['PROJECT']
+        return render_template_string(BRANCHES_TEMPLATE, project_name=project.config.name)
+
+    @app.route(PREFIX + '/<slug>')
+    def branch_root(slug):
+        return redirect(f"{PREFIX}/{slug}/")
+
+    @app.route(PREFIX + '/<slug>/', defaults={'path': ''})
+    @app.route(PREFIX + '/<slug>/<path:path>')
+    def branch_page(slug, path):
+        record = previews.get(slug)
+        root = previews.output_root / slug
+        if record is None or not root.is_dir():
+            return Response(f"<p>No preview '{escape_html(slug)}' yet. <a href=\"{PREFIX}/\">Branch previews</a></p>",
+                            status=404, mimetype='text/html')
+        previews.touch(slug)
+        try:
+            if record.get('status') != 'building' and previews.head(record['branch']) != record.get('commit'):
+                build_in_background(record['branch'])
+        except BranchPreviewError:
+            pass
+
+        target = (root / path).resolve()
+        if target != root.resolve() and root.resolve() not in target.parents:
+            return Response("Not found", status=404)
+        if target.is_dir():
+            if path and not path.endswith('/'):
+                return redirect(f"{PREFIX}/{slug}/{path}/")
+            path = (path + 'index.html') if path else 'index.html'
+        elif not target.exists() and (root / (path.rstrip('/') + '.html')).exists():
+            path = path.rstrip('/') + '.html'
+        if not (root / path).is_file():
+            not_found = root / '404.html'
+            if not_found.exists():
+                html = rebase_html(not_found.read_text(encoding='utf-8'), f"{PREFIX}/{slug}")
+                return Response(html, status=404, mimetype='text/html')
+            return Response("Not found", status=404)
+        if path.endswith('.html'):
+            html = rebase_html((root / path).read_text(encoding='utf-8'), f"{PREFIX}/{slug}")
+            return Response(html, mimetype='text/html')
+        return send_from_directory(str(root), path)
+
+BRANCHES_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Branch Previews - FlashFlow</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+        .container { max-width: 1100px; margin: 0 auto; padding: 2rem; }
+        .panel { background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); margin-bottom: 1.5rem; }
+        table { width: 100%; border-collapse: collapse; }
+        td, th { text-align: left; padding: 0.6rem; border-bottom: 1px solid #f3f4f6; }
+        code { background: #f3f4f6; padding: 0.1rem 0.3rem; border-radius: 3px; }
+        button { background: #3B82F6; color: white; border: none; padding: 0.4rem 0.9rem; border-radius: 4px; cursor: pointer; }
+        button.danger { background: #dc2626; }
+        .status { display: inline-block; padding: 0.1rem 0.5rem; border-radius: 10px; font-size: 0.8rem; background: #e5e7eb; }
+        .status.ready { background: #d1fae5; color: #065f46; }
+        .status.building { background: #fef3c7; color: #92400e; }
+        .status.failed { background: #fee2e2; color: #991b1b; }
+        .muted { color: #6b7280; font-size: 0.9rem; }
+        #error { color: #b91c1c; font-family: monospace; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>🌿 Branch Previews</h1>
+        <p>{{ project_name }} · static builds of local git branches, side by side</p>
+    </div>
+    <div class="container">
+        <div class="panel">
+            <div id="error"></div>
+            <p class="muted" id="limits"></p>
+            <p><button id="clean" class="danger">Remove stale previews</button></p>
+            <table>
+                <thead><tr><th>Branch</th><th>Preview</th><th>Built</th><th></th></tr></thead>
+                <tbody id="branches"><tr><td colspan="4" class="muted">Loading...</td></tr></tbody>
+            </table>
+            <p><a href="/">← Back to Main Dashboard</a></p>
+        </div>
+    </div>
+    <script>
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+
+        async function api(url, options) {
+            const response = await fetch(url, options);
+            const data = await response.json();
+            if (!response.ok) throw new Error(data.error || response.statusText);
+            return data;
+        }
+
+        function ago(seconds) {
+            if (!seconds) return '';
+            const minutes = Math.round((Date.now() / 1000 - seconds) / 60);
+            return minutes < 60 ? minutes + 'm ago' : minutes < 1440 ? Math.round(minutes / 60) + 'h ago' : Math.round(minutes / 1440) + 'd ago';
+        }
+
+        async function load() {
+            try {
+                const data = await api('/api/branches');
+                document.getElementById('error').textContent = '';
+                document.getElementById('limits').textContent =
+                    `Up to ${data.max_branches} previews; unused ones are removed after ${data.max_age_days} days and when their branch is deleted.`;
+                document.getElementById('branches').innerHTML = data.branches.map(b => `
+                    <tr>
+                        <td><strong>${escapeHtml(b.branch)}</strong>${b.branch === data.current ? ' <span class="muted">(checked out)</span>' : ''}
+                            <br><code>${escapeHtml(b.head.slice(0, 8))}</code></td>
+                        <td>${b.status ? `<span class="status ${escapeHtml(b.status)}">${escapeHtml(b.status)}</span>` : '<span class="muted">not built</span>'}
+                            ${b.url ? ` <a href="${escapeHtml(b.url)}" target="_blank">${escapeHtml(b.url)}</a>` : ''}
+                            ${b.outdated && b.status !== 'building' ? '<br><span class="muted">branch has new commits</span>' : ''}
+                            ${b.error ? '<br><span class="muted">' + escapeHtml(b.error) + '</span>' : ''}</td>
+                        <td class="muted">${b.built_at ? ago(b.built_at) + (b.pages ? ` · ${b.pages.length} page(s)` : '') : ''}</td>
+                        <td>${b.status === 'building' ? '' : `<button data-build="${escapeHtml(b.branch)}">${b.status ? 'Rebuild' : 'Build'}</button>`}
+                            ${b.slug && b.status !== 'building' ? ` <button class="danger" data-remove="${escapeHtml(b.slug)}">Remove</button>` : ''}</td>
+                    </tr>`).join('') || '<tr><td colspan="4" class="muted">No local branches</td></tr>';
+                document.querySelectorAll('[data-build]').forEach(button => button.onclick = () => build(button.dataset.build));
+                document.querySelectorAll('[data-remove]').forEach(button => button.onclick = () => remove(button.dataset.remove));
+            } catch (e) {
+                document.getElementById('error').textContent = '❌ ' + e.message;
+            }
+        }
+
+        async function build(branch) {
+            await api('/api/branches/build', {
+                method: 'POST', headers: {'Content-Type': 'application/json'}, body: JSON.stringify({branch: branch})
+            });
+            load();
+        }
+
+        async function remove(slug) {
+            if (!confirm('Remove this preview?')) return;
+            await api('/api/branches/' + encodeURIComponent(slug), {method: 'DELETE'});
+            load();
+        }
+
+        document.getElementById('clean').onclick = async () => {
+            const data = await api('/api/branches/clean', {method: 'POST'});
+            alert(data.removed.length ? data.removed.map(r => r.slug + ': ' + r.reason).join('\\n') : 'Nothing to remove');
+            load();
+        };
+
+        load();
+        setInterval(load, 3000);
+    </script>
+</body>
+</html>
+"""
@@ -0,0 +1,289 @@
+"""
+FlashFlow build API - Start 'flashflow build' from editors, bots and the flow editor over HTTP
+
+    POST   /api/build              {"target": "frontend", "env": "staging", "clean": false, "full": false}
+                                   -> 202 {"id": "b-3f9a1c2e", "status": "queued", ...}
+    GET    /api/build              recent builds, newest first
+    GET    /api/build/<id>         status, report and log; ?since=<line> for only the newer log lines
+    DELETE /api/build/<id>         cancel a queued or running build
+
+Each build runs 'flashflow build' in a child process in the project
+directory, one at a time in the order they were asked for; the target and
+env are those of the CLI (env defaults to the server's profile). full builds
+every step and page, not only what changed; clean also removes dist/ first
+and skips the build cache. While a build runs, /__build and open pages show
+its progress like that of a 'build --watch'. cli/utils/build_client.py wraps
+these routes for Python callers.
+"""
+
+import os
+import re
+import shutil
+import subprocess
+import sys
+import threading
+import time
+import uuid
+from collections import OrderedDict
+from dataclasses import dataclass, field
+from typing import Any, Dict, List, Optional
+
+import click
+from flask import request, jsonify
+
+from core.build_cache import FLASHFLOW_ROOT
+from core.parser.diagnostics import collect_diagnostics
+from core.profiles import profile_names
+from cli.devserver.audit import audit_admin, current_actor
+from cli.devserver.dev_events import get_dev_events
+from cli.utils.dev_status import last_build
+
+# The targets 'flashflow build --target' takes
+BUILD_TARGETS = ('all', 'backend', 'frontend', 'mobile', 'ios', 'android', 'desktop', 'windows', 'macos', 'linux', 'static')
+ENV_NAME = re.compile(r'^[A-Za-z0-9_-]{1,64}$')
+# Finished builds kept for GET /api/build
+MAX_BUILDS = 50
+MAX_LOG_LINES = 5000
+MAX_QUEUED = 10
+
+@dataclass
+class BuildJob:
+    """One requested build and what became of it"""
+    id: str
+    target: str
+    env: str
+    clean: bool = False
+    full: bool = False
+    requested_by: str = 'admin'
+    status: str = 'queued'
+    created_at: float = field(default_factory=time.time)
+    started_at: Optional[float] = None
+    finished_at: Optional[float] = None
+    exit_code: Optional[int] = None
+    report: Optional[Dict[str, Any]] = None
+    errors: List[Dict[str, Any]] = field(default_factory=list)
+    log: List[str] = field(default_factory=list)
+    log_dropped: int = 0
+
+    @property
+    def done(self) -> bool:
+        return self.status in ('ok', 'failed', 'skipped', 'cancelled', 'error')
+
+    def append_log(self, line: str):
+        self.log.append(line)
+        if len(self.log) > MAX_LOG_LINES:
+            del self.log[0]
+            self.log_dropped += 1
+
+    def to_dict(self, since: Optional[int] = None) -> Dict[str, Any]:
+        data = {'id': self.id, 'target': self.target, 'env': self.env, 'clean': self.clean, 'full': self.full,
+                'requested_by': self.requested_by, 'status': self.status, 'created_at': self.created_at,
+                'started_at': self.started_at, 'finished_at': self.finished_at, 'exit_code': self.exit_code,
+                'seconds': round(self.finished_at - self.started_at, 2) if self.finished_at and self.started_at else None,
+                'errors': self.errors, 'report': self.report, 'log_lines': self.log_dropped + len(self.log)}
+        if since is not None:
+            # Line numbers count from the first line the build printed, dropped ones included
+            start = max(0, since - self.log_dropped)
+            data.update(log=self.log[start:], log_from=self.log_dropped + start)
+        return data
+
+class BuildQueue:
+    """Runs requested builds one after another on a background thread"""
+
+    def __init__(self, app, project):
+        self.app = app
+        self.project = project
+        self.jobs: 'OrderedDict[str, BuildJob]' = OrderedDict()
+        self._queue: List[BuildJob] = []
+        self._process: Optional[subprocess.Popen] = None
+        self._lock = threading.Lock()
+        self._wake = threading.Event()
+        self._worker: Optional[threading.Thread] = None
+
+    def submit(self, job: BuildJob) -> BuildJob:
+        with self._lock:
+            if len(self._queue) >= MAX_QUEUED:
+                raise OverflowError(f"{MAX_QUEUED} builds are already waiting")
+            self.jobs[job.id] = job
+            self._queue.append(job)
+            finished = [key for key, existing in self.jobs.items() if existing.done]
+            for key in finished[:max(0, len(finished) - MAX_BUILDS)]:
+                del self.jobs[key]
+            if self._worker is None:
+                self._worker = threading.Thread(target=self._run, name='flashflow-build-api', daemon=True)
+                self._worker.start()
+        self._wake.set()
+        return job
+
+    def get(self, build_id: str) -> Optional[BuildJob]:
+        with self._lock:
+            return self.jobs.get(build_id)
+
+    def list(self) -> List[BuildJob]:
+        with self._lock:
+            return list(reversed(self.jobs.values()))
+
+    def cancel(self, job: BuildJob) -> bool:
+        """Cancel a build that has not finished; False when it already has"""
+        with self._lock:
+            if job.done:
+                return False
+            if job in self._queue:
+                self._queue.remove(job)
+                job.status, job.finished_at = 'cancelled', time.time()
+                return True
+            job.status = 'cancelled'
+            process = self._process
+        if process is not None and process.poll() is None:
+            process.terminate()
+        return True
+
+    def _run(self):
+        while True:
+            self._wake.wait()
+            with self._lock:
+                if not self._queue:
+                    self._wake.clear()
+                    continue
+                job = self._queue.pop(0)
+                job.status, job.started_at = 'running', time.time()
+            self._build(job)
+
+    def _command(self, job: BuildJob) -> List[str]:
+        command = [sys.executable, '-m', 'cli.core.main', 'build', '--target', job.target, '--env', job.env]
+        if job.full or job.clean:
+            command.append('--full')
+        if job.clean:
+            command.append('--no-cache')
+        return command
+
+    def _environ(self) -> Dict[str, str]:
+        env = dict(os.environ, PYTHONUNBUFFERED='1', CI='1')
+        # The child imports the CLI's modules whether or not it is installed
+        env['PYTHONPATH'] = os.pathsep.join(filter(None, [str(FLASHFLOW_ROOT), env.get('PYTHONPATH')]))
+        return env
+
+    def _build(self, job: BuildJob):
+        events = get_dev_events(self.app)
+        events.dispatch('build_started', {'target': job.target, 'trigger': f"api {job.id}"}, 'api')
+        if job.clean and self.project.dist_path.exists():
+            shutil.rmtree(self.project.dist_path, ignore_errors=True)
+            job.append_log("🧹 Removed dist/")
+        try:
+            process = subprocess.Popen(self._command(job), cwd=self.project.root_path, env=self._environ(),
+                                       stdout=subprocess.PIPE, stderr=subprocess.STDOUT, text=True,
+                                       encoding='utf-8', errors='replace')
+        except OSError as e:
+            self._finish(job, None, [{'message': f"Could not start the build: {e}"}])
+            return
+        with self._lock:
+            self._process = process
+        for line in process.stdout:
+            job.append_log(line.rstrip('\n'))
+        job.exit_code = process.wait()
+        with self._lock:
+            self._process = None
+
+        report = last_build(self.project)
+        if report is None or (report.get('finished_at') or 0) < job.started_at:
+            # The build stopped before recording a report (outside a project, or it crashed)
+            report = None
+        self._finish(job, report, [] if job.status == 'cancelled' else self._errors(job, report))
+
+    def _errors(self, job: BuildJob, report: Optional[Dict[str, Any]]) -> List[Dict[str, Any]]:
+        """The failed steps, else the .flow diagnostics, else the lines the build printed as errors"""
+        errors = []
+        for step in (report or {}).get('steps', []):
+            if step.get('status') == 'failed':
+                reported = [line for line in step.get('messages', []) if line.startswith('❌')]
+                errors.append({'message': f"{step['step']}: {step.get('error') or (reported or ['failed'])[0]}"})
+        if (report or {}).get('error'):
+            errors.append({'message': report['error']})
+        if errors or (report is not None and report.get('status') != 'failed') or (report is None and not job.exit_code):
+            return errors
+        errors = [diagnostic.to_dict() for diagnostic in collect_diagnostics(self.project.get_flow_files())
+                  if diagnostic.severity == 'error']
+        errors = errors or [{'message': line[1:].strip()} for line in job.log if line.startswith('❌')]
+        return errors or [{'message': f"flashflow build exited with code {job.exit_code}"}]
+
+    def _finish(self, job: BuildJob, report: Optional[Dict[str, Any]], errors: List[Dict[str, Any]]):
+        events = get_dev_events(self.app)
+        job.finished_at, job.report, job.errors = time.time(), report, errors
+        seconds = round(job.finished_at - job.started_at, 2)
+        if job.status != 'cancelled':
+            if report is not None:
+                job.status = report.get('status', 'error')
+            else:
+                job.status = 'error' if errors else 'ok'
+        if job.status in ('ok', 'skipped'):
+            events.dispatch('build_finished', {'target': job.target, 'seconds': seconds}, 'api')
+        else:
+            events.dispatch('build_failed', {'target': job.target, 'seconds': seconds,
+                                             'errors': errors or [{'message': f"Build {job.status}"}]}, 'api')
+        click.echo(f"{'✅' if job.status in ('ok', 'skipped') else '❌'} Build {job.id} ({job.target}, {job.env}) "
+                   f"{job.status} in {seconds}s")
+
+def get_build_queue(app) -> BuildQueue:
+    if 'BUILD_QUEUE' not in app.config:
+        app.config['BUILD_QUEUE'] = BuildQueue(app, app.config['PROJECT'])
+    return app.config['BUILD_QUEUE']
+
+def register_build_api(app):
+    """Register /api/build"""
+    project = app.config['PROJECT']
+    queue = get_build_queue(app)
+
+    @app.route('/api/build', methods=['POST'])
+    def api_build_start():
+        body = request.get_json(silent=True)
+        if body is None and not request.get_data():
+            body = {}
+        if not isinstance(body, dict):
+            return jsonify({'error': "Send the build options as a JSON object"}), 400
+        unknown = sorted(set(body) - {'target', 'env', 'clean', 'full'})
+        if unknown:
+            return jsonify({'error': f"Unknown option(s) {', '.join(unknown)}; use target, env, clean and full"}), 400
+        target = body.get('target', 'all')
+        if target not in BUILD_TARGETS:
+            return jsonify({'error': f"Unknown target '{target}'; use {', '.join(BUILD_TARGETS)}"}), 400
+        env = body.get('env') or app.config['PROFILE'].name
+        names = profile_names(project)
+        if not isinstance(env, str) or not ENV_NAME.match(env) or (names and env not in names):
+            known = f"; flashflow.json has {', '.join(names)}" if names else ''
+            return jsonify({'error': f"Unknown env '{env}'{known}"}), 400
+        for flag in ('clean', 'full'):
+            if not isinstance(body.get(flag, False), bool):
+                return jsonify({'error': f"'{flag}' must be true or false"}), 400
+
+        job = BuildJob(f"b-{uuid.uuid4().hex[:8]}", target, env, body.get('clean', False), body.get('full', False),
+                       current_actor())
+        try:
+            queue.submit(job)
+        except OverflowError as e:
+            return jsonify({'error': str(e)}), 429
+        audit_admin(app, 'build.start', 'build', job.id, None, {'target': target, 'env': env,
+                                                                'clean': job.clean, 'full': job.full})
+        response = jsonify(job.to_dict())
+        response.headers['Location'] = f"/api/build/{job.id}"
+        return response, 202
+
+    @app.route('/api/build', methods=['GET'])
+    def api_build_list():
+        return jsonify({'builds': [job.to_dict() for job in queue.list()]})
+
+    @app.route('/api/build/<build_id>', methods=['GET'])
+    def api_build_status(build_id):
+        job = queue.get(build_id)
+        if job is None:
+            return jsonify({'error': f"No build '{build_id}'"}), 404
+        return jsonify(job.to_dict(since=max(0, request.args.get('since', 0, type=int))))
+
+    @app.route('/api/build/<build_id>', methods=['DELETE'])
+    def api_build_cancel(build_id):
+        job = queue.get(build_id)
+        if job is None:
+            return jsonify({'error': f"No build '{build_id}'"}), 404
+        if not queue.cancel(job):
+            return jsonify({'error': f"Build '{build_id}' already {job.status}"}), 409
+        audit_admin(app, 'build.cancel', 'build', job.id)
+        return jsonify(job.to_dict())
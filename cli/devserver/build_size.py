@@ -0,0 +1,153 @@
+"""
+FlashFlow build size report - /build/size shows what each build target weighs
+
+Reads the measurements 'flashflow build' keeps in
+.flashflow/metrics/build-sizes.json: per-target totals over recent builds,
+the largest files of the newest build and the files that grew since the one
+before it.
+"""
+
+from flask import jsonify, render_template_string
+
+from core.build_size import BuildSizeHistory
+
+def register_build_size(app):
+    """Register /build/size and its JSON at /api/build/size"""
+    history = BuildSizeHistory.for_project(app.config['PROJECT'])
+
+    @app.route('/api/build/size')
+    def build_size_data():
+        return jsonify({'latest': history.latest(), 'history': history.totals()})
+
+    @app.route('/build/size')
+    def build_size_page():
+        project = app.config['PROJECT']
+        return render_template_string(BUILD_SIZE_TEMPLATE, project_name=project.config.name)
+
+BUILD_SIZE_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Build Size - FlashFlow</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+        .container { max-width: 1200px; margin: 0 auto; padding: 2rem; }
+        .panel { background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); margin-bottom: 1.5rem; }
+        table { width: 100%; border-collapse: collapse; }
+        th, td { text-align: left; padding: 0.5rem; border-bottom: 1px solid #e5e7eb; font-size: 0.9rem; }
+        td.num, th.num { text-align: right; font-variant-numeric: tabular-nums; }
+        .up { color: #b91c1c; } .down { color: #15803d; }
+        .muted { color: #6b7280; }
+        .warning { background: #fef3c7; border-left: 4px solid #d97706; padding: 0.5rem 1rem; margin-bottom: 0.5rem; }
+        .bar { display: inline-block; height: 0.7rem; background: #667eea; border-radius: 2px; vertical-align: middle; }
+        code { font-size: 0.85rem; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>📏 Build Size</h1>
+        <p>{{ project_name }} · What each target in dist/ weighs, and what changed since the previous build</p>
+    </div>
+    <div class="container">
+        <div id="warnings"></div>
+        <div class="panel">
+            <h3>Targets</h3>
+            <p class="muted" id="built"></p>
+            <table>
+                <thead><tr><th>Target</th><th class="num">Size</th><th class="num">Change</th><th class="num">Files</th><th>Recent builds</th></tr></thead>
+                <tbody id="targets"></tbody>
+            </table>
+        </div>
+        <div id="details"></div>
+        <p><a href="/">← Back to Main Dashboard</a></p>
+    </div>
+    <script>
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+
+        async function api(url, options) {
+            const response = await fetch(url, options);
+            const data = await response.json();
+            if (!response.ok) throw new Error(data.error || response.statusText);
+            return data;
+        }
+
+        function size(bytes, signed) {
+            if (bytes == null) return '–';
+            const sign = signed ? (bytes > 0 ? '+' : bytes < 0 ? '−' : '±') : '';
+            let value = Math.abs(bytes);
+            for (const unit of ['B', 'KB', 'MB', 'GB']) {
+                if (value < 1024 || unit === 'GB') return sign + (unit === 'B' ? value : value.toFixed(1)) + ' ' + unit;
+                value /= 1024;
+            }
+        }
+
+        function change(bytes, ratio) {
+            if (bytes == null) return '<span class="muted">new</span>';
+            const label = size(bytes, true) + (ratio != null && bytes ? ` (${(ratio * 100).toFixed(1)}%)` : '');
+            return `<span class="${bytes > 0 ? 'up' : bytes < 0 ? 'down' : 'muted'}">${label}</span>`;
+        }
+
+        function trend(history, name) {
+            const values = history.map(build => build.targets[name]).filter(value => value != null);
+            const top = Math.max(...values, 1);
+            return values.slice(-15).map(value =>
+                `<span class="bar" title="${size(value)}" style="width:${Math.max(2, Math.round(value / top * 12))}px"></span>`).join(' ');
+        }
+
+        function files(title, items, showDelta) {
+            if (!items.length) return '';
+            return `<h4>${title}</h4><table>` + items.map(item => `<tr>
+                    <td><code>${escapeHtml(item.path)}</code>${item.new ? ' <span class="muted">(new)</span>' : ''}</td>
+                    <td class="num">${size(item.bytes)}</td>
+                    <td class="num">${showDelta || item.delta_bytes ? change(item.delta_bytes, null) : ''}</td>
+                </tr>`).join('') + '</table>';
+        }
+
+        async function load() {
+            const data = await api('/api/build/size');
+            const latest = data.latest;
+            if (!latest || !Object.keys(latest.targets).length) {
+                document.getElementById('built').textContent = "No measured builds yet. Run 'flashflow build'.";
+                return;
+            }
+            document.getElementById('built').textContent = `Last build ${new Date(latest.measured_at * 1000).toLocaleString()}`
+                + ` · target ${latest.target || 'all'} · ${latest.env || ''}`
+                + (latest.previous_at ? ` · compared with ${new Date(latest.previous_at * 1000).toLocaleString()}` : ' · first measured build');
+            document.getElementById('warnings').innerHTML = latest.warnings.map(warning =>
+                `<div class="warning">⚠️ ${escapeHtml(warning)}</div>`).join('');
+            const names = Object.keys(latest.targets);
+            document.getElementById('targets').innerHTML = names.map(name => {
+                const entry = latest.targets[name];
+                return `<tr>
+                    <td><a href="#${escapeHtml(name)}">${escapeHtml(name)}</a></td>
+                    <td class="num">${size(entry.bytes)}</td>
+                    <td class="num">${change(entry.delta_bytes, entry.delta_ratio)}</td>
+                    <td class="num">${entry.count}</td>
+                    <td>${trend(data.history, name)}</td>
+                </tr>`;
+            }).join('');
+            document.getElementById('details').innerHTML = names.map(name => {
+                const entry = latest.targets[name];
+                return `<div class="panel" id="${escapeHtml(name)}">
+                    <h3>📦 ${escapeHtml(name)}</h3>
+                    ${entry.previous_bytes != null ? `<p class="muted">${entry.added} files added, ${entry.removed} removed</p>` : ''}
+                    ${files('Largest files', entry.largest, false)}
+                    ${files('Grew most since the previous build', entry.grown, true)}
+                </div>`;
+            }).join('');
+        }
+
+        load().catch(e => {
+            document.getElementById('built').textContent = '❌ ' + e.message;
+        });
+    </script>
+</body>
+</html>
+"""
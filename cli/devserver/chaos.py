@@ -0,0 +1,274 @@
+"""
+FlashFlow chaos mode - Slow and flaky routes for testing frontends, from /admin/chaos
+
+Matching requests are delayed, answered with an error status, or have their
+connection closed without a response, as the rules in core/chaos.py say.
+Responses touched by chaos mode carry an X-FlashFlow-Chaos header. Admin
+pages, dev tooling and the chaos API itself are never affected, so chaos mode
+can always be switched off again.
+"""
+
+import socket
+import time
+
+from flask import g, request, jsonify, render_template_string, Response
+
+from core.chaos import ChaosConfig, ChaosError
+
+EXEMPT_PREFIXES = ('/admin', '/__', '/api/chaos')
+
+def get_chaos(app) -> ChaosConfig:
+    if 'CHAOS' not in app.config:
+        app.config['CHAOS'] = ChaosConfig.for_project(app.config['PROJECT'])
+    return app.config['CHAOS']
+
+def register_chaos(app):
+    """Apply chaos rules to requests and register /api/chaos and /admin/chaos"""
+    chaos = get_chaos(app)
+
+    @app.before_request
+    def inject_chaos():
+        if request.path.startswith(EXEMPT_PREFIXES):
+            return None
+        decision = chaos.decide(request.method, request.path)
+        if decision is None:
+            return None
+        if decision.delay_ms:
+            time.sleep(decision.delay_ms / 1000)
+        g.chaos = f"rule={decision.rule_id}; delay={decision.delay_ms}ms"
+        if decision.drop:
+            return drop_connection()
+        if decision.error_status:
+            g.chaos += f"; error={decision.error_status}"
+            return jsonify({'error': 'Injected by FlashFlow chaos mode', 'rule': decision.rule_id}), decision.error_status
+        return None
+
+    @app.after_request
+    def mark_chaos(response):
+        if g.get('chaos'):
+            response.headers['X-FlashFlow-Chaos'] = g.chaos
+        return response
+
+    @app.route('/api/chaos', methods=['GET'])
+    def chaos_state():
+        return jsonify(chaos.to_dict())
+
+    @app.route('/api/chaos', methods=['PUT'])
+    def chaos_toggle():
+        """Switch chaos mode on or off; rules are kept either way"""
+        data = request.get_json(silent=True) or {}
+        if not isinstance(data.get('enabled'), bool):
+            return jsonify({'error': "'enabled' must be true or false"}), 400
+        chaos.set_enabled(data['enabled'])
+        app.logger.warning(f"🌪️  Chaos mode {'on' if chaos.enabled else 'off'}")
+        return jsonify(chaos.to_dict())
+
+    @app.route('/api/chaos/rules', methods=['POST'])
+    def chaos_add_rule():
+        try:
+            rule = chaos.add(request.get_json(silent=True))
+        except ChaosError as e:
+            return jsonify({'error': str(e)}), 400
+        return jsonify(rule.to_dict()), 201
+
+    @app.route('/api/chaos/rules/<rule_id>', methods=['PUT'])
+    def chaos_update_rule(rule_id):
+        data = request.get_json(silent=True)
+        if not isinstance(data, dict):
+            return jsonify({'error': "Send the fields to change as a JSON object"}), 400
+        try:
+            rule = chaos.update(rule_id, data)
+        except ChaosError as e:
+            return jsonify({'error': str(e)}), 404 if 'No chaos rule' in str(e) else 400
+        return jsonify(rule.to_dict())
+
+    @app.route('/api/chaos/rules/<rule_id>', methods=['DELETE'])
+    def chaos_delete_rule(rule_id):
+        try:
+            chaos.remove(rule_id)
+        except ChaosError as e:
+            return jsonify({'error': str(e)}), 404
+        return jsonify({'deleted': rule_id})
+
+    @app.route('/api/chaos/routes', methods=['GET'])
+    def chaos_routes():
+        """Routes of this server, as suggestions for rule patterns"""
+        routes = sorted({rule.rule for rule in app.url_map.iter_rules() if not rule.rule.startswith(EXEMPT_PREFIXES)})
+        return jsonify({'routes': routes})
+
+    @app.route('/admin/chaos')
+    def admin_chaos_page():
+        """Admin page to switch chaos mode and edit its rules"""
+        project = app.config['PROJECT']
+        return render_template_string(CHAOS_ADMIN_TEMPLATE, project_name=project.config.name)
+
+def drop_connection() -> Response:
+    """Close the client's connection so it sees a reset instead of a response"""
+    sock = request.environ.get('werkzeug.socket')
+    if sock is not None:
+        try:
+            sock.shutdown(socket.SHUT_RDWR)
+        except OSError:
+            pass
+        return Response(status=499)
+    # Without the socket, promise a body that never comes; clients see a truncated response
+    response = Response(b'', status=200)
+    response.headers['Content-Length'] = '1024'
+    response.headers['Connection'] = 'close'
+    return response
+
+CHAOS_ADMIN_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Chaos Mode - FlashFlow Admin</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+        .container { max-width: 1200px; margin: 0 auto; padding: 2rem; }
+        .panel { background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); margin-bottom: 1.5rem; }
+        table { width: 100%; border-collapse: collapse; }
+        th, td { text-align: left; padding: 0.5rem; border-bottom: 1px solid #e5e7eb; font-size: 0.9rem; }
+        input { padding: 0.35rem; border: 1px solid #d1d5db; border-radius: 4px; width: 100%; box-sizing: border-box; }
+        input[type=checkbox] { width: auto; }
+        form { display: grid; grid-template-columns: 2fr 1fr 1fr 1fr 1fr 1fr 1fr auto; gap: 0.5rem; align-items: end; }
+        label { font-size: 0.8rem; color: #6b7280; }
+        button { background: #3B82F6; color: white; border: none; padding: 0.4rem 0.9rem; border-radius: 4px; cursor: pointer; }
+        button.danger { background: #b91c1c; }
+        .switch { font-size: 1.1rem; font-weight: bold; }
+        .on { color: #b91c1c; } .off { color: #15803d; }
+        .muted { color: #6b7280; }
+        #error { color: #b91c1c; font-family: monospace; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>🌪️ Chaos Mode</h1>
+        <p>{{ project_name }} · Delay, fail or drop requests to test how the frontend copes with a slow or flaky backend</p>
+    </div>
+    <div class="container">
+        <div class="panel">
+            <p class="switch">Chaos mode is <span id="state"></span> <button id="toggle"></button></p>
+            <p class="muted">Rules are tried in order; a request gets the first enabled one whose route and method match.
+                Admin pages, /__ tooling and /api/chaos are never affected.</p>
+            <div id="error"></div>
+        </div>
+        <div class="panel">
+            <h3>Add a rule</h3>
+            <form id="add">
+                <div><label>Route pattern</label><input name="route" list="routes" placeholder="/api/data/*" required></div>
+                <div><label>Methods</label><input name="methods" placeholder="all"></div>
+                <div><label>Delay (ms)</label><input name="delay_ms" type="number" min="0" value="0"></div>
+                <div><label>Jitter (ms)</label><input name="jitter_ms" type="number" min="0" value="0"></div>
+                <div><label>Error rate (%)</label><input name="error_rate" type="number" min="0" max="100" value="0"></div>
+                <div><label>Error status</label><input name="error_status" type="number" min="400" max="599" value="500"></div>
+                <div><label>Drop rate (%)</label><input name="drop_rate" type="number" min="0" max="100" value="0"></div>
+                <button type="submit">Add</button>
+            </form>
+            <datalist id="routes"></datalist>
+        </div>
+        <div class="panel">
+            <h3>Rules</h3>
+            <table>
+                <thead><tr><th>On</th><th>Route</th><th>Methods</th><th>Delay</th><th>Errors</th><th>Drops</th><th>Hits (matched / delayed / errors / dropped)</th><th></th></tr></thead>
+                <tbody id="rules"></tbody>
+            </table>
+        </div>
+        <p><a href="/">← Back to Main Dashboard</a></p>
+    </div>
+    <script>
+        let enabled = false;
+
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+
+        async function api(url, options) {
+            if (options && options.body) options.headers = {'Content-Type': 'application/json'};
+            const response = await fetch(url, options);
+            const data = await response.json();
+            if (!response.ok) throw new Error(data.error || response.statusText);
+            return data;
+        }
+
+        function percent(rate) {
+            return Math.round(rate * 1000) / 10 + '%';
+        }
+
+        async function load() {
+            try {
+                const state = await api('/api/chaos');
+                enabled = state.enabled;
+                const label = document.getElementById('state');
+                label.textContent = enabled ? 'ON' : 'off';
+                label.className = enabled ? 'on' : 'off';
+                document.getElementById('toggle').textContent = enabled ? 'Switch off' : 'Switch on';
+                document.getElementById('rules').innerHTML = state.rules.map(rule => `<tr>
+                        <td><input type="checkbox" data-id="${rule.id}" ${rule.enabled ? 'checked' : ''}></td>
+                        <td><code>${escapeHtml(rule.route)}</code></td>
+                        <td>${escapeHtml(rule.methods.join(', ') || 'all')}</td>
+                        <td>${rule.delay_ms}ms${rule.jitter_ms ? ' + 0–' + rule.jitter_ms + 'ms' : ''}</td>
+                        <td>${percent(rule.error_rate)} → ${rule.error_status}</td>
+                        <td>${percent(rule.drop_rate)}</td>
+                        <td class="muted">${rule.hits.matched} / ${rule.hits.delayed} / ${rule.hits.errors} / ${rule.hits.dropped}</td>
+                        <td><button class="danger" data-delete="${rule.id}">Delete</button></td>
+                    </tr>`).join('') || '<tr><td colspan="8" class="muted">No rules yet</td></tr>';
+            } catch (e) {
+                document.getElementById('error').textContent = '❌ ' + e.message;
+            }
+        }
+
+        async function run(action) {
+            document.getElementById('error').textContent = '';
+            try {
+                await action();
+            } catch (e) {
+                document.getElementById('error').textContent = '❌ ' + e.message;
+            }
+            load();
+        }
+
+        document.getElementById('toggle').onclick = () => run(() =>
+            api('/api/chaos', {method: 'PUT', body: JSON.stringify({enabled: !enabled})}));
+
+        document.getElementById('add').onsubmit = event => {
+            event.preventDefault();
+            const form = new FormData(event.target);
+            const rule = {
+                route: form.get('route'),
+                methods: form.get('methods'),
+                delay_ms: Number(form.get('delay_ms')),
+                jitter_ms: Number(form.get('jitter_ms')),
+                error_rate: Number(form.get('error_rate')) / 100,
+                error_status: Number(form.get('error_status')),
+                drop_rate: Number(form.get('drop_rate')) / 100
+            };
+            run(async () => {
+                await api('/api/chaos/rules', {method: 'POST', body: JSON.stringify(rule)});
+                event.target.reset();
+            });
+        };
+
+        document.getElementById('rules').onclick = event => {
+            const id = event.target.dataset.delete;
+            if (id) run(() => api('/api/chaos/rules/' + id, {method: 'DELETE'}));
+        };
+        document.getElementById('rules').onchange = event => {
+            const id = event.target.dataset.id;
+            if (id) run(() => api('/api/chaos/rules/' + id, {method: 'PUT', body: JSON.stringify({enabled: event.target.checked})}));
+        };
+
+        api('/api/chaos/routes').then(data => {
+            document.getElementById('routes').innerHTML = data.routes.map(route => `<option value="${escapeHtml(route)}">`).join('');
+        }).catch(() => {});
+
+        load();
+        setInterval(load, 5000);
+    </script>
+</body>
+</html>
+"""
@@ -0,0 +1,20 @@
+"""
+FlashFlow dev server crash reports - Unhandled request errors written to .flashflow/crashes
+"""
+
+from flask import request, g, got_request_exception
+
+from core.crashes import install_crash_reporter
+
+def register_crash_reports(app):
+    """Report exceptions that escape route handlers; Flask still answers with its 500 page"""
+    reporter = install_crash_reporter(app.config['PROJECT'].root_path, 'dev-server')
+
+    def on_request_exception(sender, exception, **extra):
+        path = reporter.report(exception, context={'request': f"{request.method} {request.full_path.rstrip('?')}",
+                                                     'request_id': g.get('request_id')})
+        if path:
+            sender.logger.error(f"🧯 Crash report saved: {path.name}")
+
+    # Signal receivers are weakly referenced by default; the closure would be collected right away
+    got_request_exception.connect(on_request_exception, app, weak=False)
@@ -0,0 +1,247 @@
+"""
+FlashFlow database browser - /admin/database view of the configured database
+"""
+
+from flask import request, jsonify, render_template_string, Response
+
+from core.database import StorageError
+from core.data_transfer import FORMATS, STRATEGIES, export_rows, format_for, import_rows, parse_rows, to_csv, to_json
+from cli.devserver.audit import audit_admin
+from cli.devserver.dev_crud import get_storage, storage_error_response
+from cli.devserver.response_cache import invalidate_table
+
+def register_database_browser(app):
+    """Register the admin database browser page and its JSON API"""
+
+    @app.route('/admin/database')
+    def admin_database_page():
+        """Admin page for browsing tables and rows"""
+        project = app.config['PROJECT']
+        return render_template_string(DATABASE_BROWSER_TEMPLATE, project_name=project.config.name)
+
+    @app.route('/admin/api/database')
+    def admin_database_tables():
+        storage = get_storage(app)
+        try:
+            tables = [{'name': table, 'rows': storage.count(table)} for table in storage.list_tables()]
+        except StorageError as e:
+            return storage_error_response(e)
+        return jsonify({'driver': storage.driver, 'dsn': storage.config.display_dsn(), 'tables': tables})
+
+    @app.route('/admin/api/database/<table>')
+    def admin_database_rows(table):
+        storage = get_storage(app)
+        page = max(request.args.get('page', 1, type=int), 1)
+        per_page = min(max(request.args.get('per_page', 25, type=int), 1), 200)
+        try:
+            return jsonify({
+                'table': table,
+                'columns': storage.table_columns(table),
+                'rows': storage.fetch_rows(table, limit=per_page, offset=(page - 1) * per_page),
+                'total': storage.count(table),
+                'page': page,
+                'per_page': per_page
+            })
+        except StorageError as e:
+            return storage_error_response(e)
+
+    @app.route('/admin/api/database/<table>/export')
+    def admin_database_export(table):
+        """Download a table as JSON or CSV"""
+        fmt = request.args.get('format', 'json')
+        if fmt not in FORMATS:
+            return jsonify({'error': f"Unknown format '{fmt}'; use {', '.join(FORMATS)}"}), 400
+        storage = get_storage(app)
+        try:
+            rows = export_rows(storage, table)
+            body = to_csv(storage, table, rows) if fmt == 'csv' else to_json({'tables': {table: rows}})
+        except StorageError as e:
+            return storage_error_response(e)
+        mimetype = 'text/csv' if fmt == 'csv' else 'application/json'
+        return Response(body, mimetype=mimetype,
+                        headers={'Content-Disposition': f'attachment; filename="{table}.{fmt}"'})
+
+    @app.route('/admin/api/database/<table>/import', methods=['POST'])
+    def admin_database_import(table):
+        """Load rows from an uploaded JSON or CSV file (or the raw request body)"""
+        strategy = request.args.get('on_conflict') or request.form.get('on_conflict') or 'skip'
+        if strategy not in STRATEGIES:
+            return jsonify({'error': f"Unknown conflict strategy '{strategy}'; use {', '.join(STRATEGIES)}"}), 400
+
+        upload = request.files.get('file')
+        if upload:
+            text = upload.read().decode('utf-8-sig', errors='replace')
+            fmt = request.args.get('format') or format_for(upload.filename or '')
+        else:
+            text = request.get_data(as_text=True)
+            fmt = request.args.get('format') or ('csv' if request.mimetype == 'text/csv' else 'json')
+        if fmt not in FORMATS:
+            return jsonify({'error': f"Unknown format '{fmt}'; use {', '.join(FORMATS)}"}), 400
+
+        storage = get_storage(app)
+        try:
+            rows = parse_rows(text, fmt, table)[table]
+            result = import_rows(storage, table, rows, strategy)
+        except StorageError as e:
+            return storage_error_response(e)
+        invalidate_table(app, table)
+        # One entry for the import; the rows themselves are in the uploaded file
+        audit_admin(app, 'database.import', table, None, new=dict(result, format=fmt, on_conflict=strategy))
+        return jsonify(result)
+
+DATABASE_BROWSER_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Database - FlashFlow Admin</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+        .container { max-width: 1400px; margin: 0 auto; padding: 2rem; display: grid; grid-template-columns: 240px 1fr; gap: 2rem; }
+        .panel { background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); overflow-x: auto; }
+        .tables a { display: flex; justify-content: space-between; padding: 0.4rem 0.6rem; border-radius: 4px; color: #374151; text-decoration: none; }
+        .tables a.active, .tables a:hover { background: #e0e7ff; }
+        table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+        th, td { text-align: left; padding: 0.5rem; border-bottom: 1px solid #e5e7eb; white-space: nowrap; max-width: 320px; overflow: hidden; text-overflow: ellipsis; }
+        th small { display: block; color: #9ca3af; font-weight: normal; }
+        button { background: #3B82F6; color: white; border: none; padding: 0.4rem 0.9rem; border-radius: 4px; cursor: pointer; }
+        button:disabled { background: #9ca3af; cursor: default; }
+        .muted { color: #6b7280; }
+        .null { color: #9ca3af; font-style: italic; }
+        #error { color: #b91c1c; font-family: monospace; white-space: pre-wrap; }
+        #transfer { display: flex; flex-wrap: wrap; gap: 0.5rem; align-items: center; margin-bottom: 1rem; }
+        #transfer a { color: #3B82F6; }
+        #transfer-result { color: #047857; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>🗄️ Database</h1>
+        <p>{{ project_name }} · <span id="connection" class="muted">connecting…</span></p>
+    </div>
+    <div class="container">
+        <div class="panel tables">
+            <h3>Tables</h3>
+            <div id="tables"></div>
+        </div>
+        <div class="panel">
+            <h3 id="table-title">Select a table</h3>
+            <div id="transfer" hidden>
+                <span>Export:</span>
+                <a id="export-json" href="#">JSON</a>
+                <a id="export-csv" href="#">CSV</a>
+                <span>· Import:</span>
+                <input type="file" id="import-file" accept=".json,.csv">
+                <select id="import-strategy" title="When a row's id already exists">
+                    <option value="skip">skip existing ids</option>
+                    <option value="overwrite">overwrite existing ids</option>
+                    <option value="merge">merge into existing ids</option>
+                </select>
+                <button id="import">Import</button>
+                <span id="transfer-result"></span>
+            </div>
+            <div id="error"></div>
+            <table>
+                <thead id="columns"></thead>
+                <tbody id="rows"></tbody>
+            </table>
+            <p>
+                <button id="prev" disabled>← Previous</button>
+                <span id="page-info" class="muted"></span>
+                <button id="next" disabled>Next →</button>
+            </p>
+            <p><a href="/">← Back to Main Dashboard</a></p>
+        </div>
+    </div>
+    <script>
+        let current = null;
+        let page = 1;
+
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+
+        async function api(url) {
+            const response = await fetch(url);
+            const data = await response.json();
+            if (!response.ok) throw new Error(data.error || response.statusText);
+            return data;
+        }
+
+        async function loadTables() {
+            try {
+                const data = await api('/admin/api/database');
+                document.getElementById('connection').textContent = `${data.driver} · ${data.dsn}`;
+                document.getElementById('tables').innerHTML = data.tables.map(t =>
+                    `<a href="#${escapeHtml(t.name)}" data-table="${escapeHtml(t.name)}">${escapeHtml(t.name)} <span class="muted">${t.rows}</span></a>`
+                ).join('') || '<p class="muted">No tables yet</p>';
+                document.querySelectorAll('[data-table]').forEach(link => {
+                    link.onclick = () => { page = 1; loadRows(link.dataset.table); };
+                });
+                const initial = decodeURIComponent(location.hash.slice(1));
+                if (initial && data.tables.some(t => t.name === initial)) loadRows(initial);
+            } catch (e) {
+                document.getElementById('connection').textContent = 'unavailable';
+                document.getElementById('error').textContent = '❌ ' + e.message;
+            }
+        }
+
+        async function loadRows(table) {
+            current = table;
+            document.querySelectorAll('[data-table]').forEach(link => link.classList.toggle('active', link.dataset.table === table));
+            document.getElementById('error').textContent = '';
+            const base = `/admin/api/database/${encodeURIComponent(table)}`;
+            document.getElementById('transfer').hidden = false;
+            document.getElementById('export-json').href = `${base}/export?format=json`;
+            document.getElementById('export-csv').href = `${base}/export?format=csv`;
+            try {
+                const data = await api(`${base}?page=${page}`);
+                document.getElementById('table-title').textContent = `${table} (${data.total} rows)`;
+                document.getElementById('columns').innerHTML = '<tr>' + data.columns.map(c =>
+                    `<th>${escapeHtml(c.name)}<small>${escapeHtml(c.type)}${c.primary_key ? ' · pk' : ''}</small></th>`
+                ).join('') + '</tr>';
+                document.getElementById('rows').innerHTML = data.rows.map(row => '<tr>' + data.columns.map(c =>
+                    row[c.name] === null ? '<td class="null">null</td>' : `<td title="${escapeHtml(row[c.name])}">${escapeHtml(row[c.name])}</td>`
+                ).join('') + '</tr>').join('') || `<tr><td colspan="${data.columns.length}" class="muted">No rows</td></tr>`;
+
+                const pages = Math.max(1, Math.ceil(data.total / data.per_page));
+                document.getElementById('page-info').textContent = `Page ${data.page} of ${pages}`;
+                document.getElementById('prev').disabled = data.page <= 1;
+                document.getElementById('next').disabled = data.page >= pages;
+            } catch (e) {
+                document.getElementById('error').textContent = '❌ ' + e.message;
+            }
+        }
+
+        document.getElementById('prev').onclick = () => { page--; loadRows(current); };
+        document.getElementById('next').onclick = () => { page++; loadRows(current); };
+
+        document.getElementById('import').onclick = async () => {
+            const file = document.getElementById('import-file').files[0];
+            const result = document.getElementById('transfer-result');
+            if (!file || !current) return;
+            const form = new FormData();
+            form.append('file', file);
+            form.append('on_conflict', document.getElementById('import-strategy').value);
+            result.textContent = 'importing…';
+            try {
+                const response = await fetch(`/admin/api/database/${encodeURIComponent(current)}/import`, {method: 'POST', body: form});
+                const data = await response.json();
+                if (!response.ok) throw new Error(data.error || response.statusText);
+                result.textContent = `✅ ${data.inserted} inserted, ${data.updated} updated, ${data.skipped} skipped`;
+                loadTables();
+            } catch (e) {
+                result.textContent = '';
+                document.getElementById('error').textContent = '❌ ' + e.message;
+            }
+        };
+
+        loadTables();
+    </script>
+</body>
+</html>
+"""
@@ -0,0 +1,246 @@
+"""
+FlashFlow desktop bridge - Simulated desktop capabilities for browser previews
+
+Flows that use native file dialogs, notifications or a tray menu can be
+exercised in the /desktop preview before packaging. Dialog results are
+scriptable so the same flow can be driven through success and cancel paths.
+"""
+
+import json
+import threading
+import time
+from collections import deque
+from pathlib import Path
+from typing import Dict, Any, List, Optional
+
+from flask import request, jsonify, Response
+
+class DesktopBridge:
+    """In-memory desktop capability mock with scripted dialog responses"""
+
+    def __init__(self, script_path: Optional[Path] = None):
+        self.script_path = script_path
+        self._lock = threading.Lock()
+        self.reset()
+
+    def reset(self):
+        """Clear recorded calls and reload the project's mock script"""
+        with self._lock:
+            self.scripts: Dict[str, deque] = {'open_file': deque(), 'save_file': deque()}
+            self.notifications: List[Dict[str, Any]] = []
+            self.tray_menu: List[Dict[str, Any]] = []
+            self.events: List[Dict[str, Any]] = []
+            self.calls: List[Dict[str, Any]] = []
+
+        if self.script_path and self.script_path.exists():
+            with open(self.script_path, 'r') as f:
+                self.load_script(json.load(f))
+
+    def load_script(self, script: Dict[str, List[Dict[str, Any]]]):
+        """Queue dialog responses, e.g. {"open_file": [{"paths": ["a.txt"]}, {"canceled": true}]}"""
+        with self._lock:
+            for capability, responses in script.items():
+                if capability not in self.scripts:
+                    raise ValueError(f"Dialogs that can be scripted: {', '.join(self.scripts)}")
+                self.scripts[capability].extend(responses)
+
+    def open_file(self, options: Dict[str, Any]) -> Dict[str, Any]:
+        scripted = self._next_scripted('open_file', options)
+        if scripted is not None:
+            return {'canceled': scripted.get('canceled', False), 'paths': scripted.get('paths', [])}
+
+        # Unscripted dialogs pick a plausible file so flows keep moving
+        extension = (options.get('filters') or ['txt'])[0].lstrip('.*')
+        count = 2 if options.get('multiple') else 1
+        return {'canceled': False, 'paths': [f"/mock/documents/file{i + 1}.{extension}" for i in range(count)]}
+
+    def save_file(self, options: Dict[str, Any]) -> Dict[str, Any]:
+        scripted = self._next_scripted('save_file', options)
+        if scripted is not None:
+            return {'canceled': scripted.get('canceled', False), 'path': scripted.get('path')}
+
+        default_name = options.get('default_name') or 'untitled.txt'
+        return {'canceled': False, 'path': f"/mock/documents/{default_name}"}
+
+    def notify(self, title: str, body: str = '') -> Dict[str, Any]:
+        with self._lock:
+            notification = {'id': len(self.notifications) + 1, 'title': title, 'body': body, 'timestamp': time.time()}
+            self.notifications.append(notification)
+            self.calls.append({'capability': 'notify', 'options': {'title': title, 'body': body}, 'timestamp': time.time()})
+        self.emit('notification', notification)
+        return notification
+
+    def set_tray_menu(self, items: List[Dict[str, Any]]):
+        with self._lock:
+            self.tray_menu = [{'id': str(item.get('id', item.get('label'))), 'label': item.get('label', '')} for item in items]
+            self.calls.append({'capability': 'tray', 'options': {'items': self.tray_menu}, 'timestamp': time.time()})
+        self.emit('tray_menu', {'items': self.tray_menu})
+
+    def click_tray_item(self, item_id: str) -> Dict[str, Any]:
+        if not any(item['id'] == item_id for item in self.tray_menu):
+            raise KeyError(item_id)
+        return self.emit('tray_click', {'id': item_id})
+
+    def emit(self, event_type: str, payload: Dict[str, Any]) -> Dict[str, Any]:
+        with self._lock:
+            event = {'seq': len(self.events) + 1, 'type': event_type, 'payload': payload, 'timestamp': time.time()}
+            self.events.append(event)
+        return event
+
+    def events_since(self, seq: int) -> List[Dict[str, Any]]:
+        with self._lock:
+            return [event for event in self.events if event['seq'] > seq]
+
+    def state(self) -> Dict[str, Any]:
+        with self._lock:
+            return {
+                'scripted': {name: list(queue) for name, queue in self.scripts.items()},
+                'notifications': list(self.notifications),
+                'tray_menu': list(self.tray_menu),
+                'calls': list(self.calls),
+                'events': len(self.events)
+            }
+
+    def _next_scripted(self, capability: str, options: Dict[str, Any]) -> Optional[Dict[str, Any]]:
+        with self._lock:
+            self.calls.append({'capability': capability, 'options': options, 'timestamp': time.time()})
+            queue = self.scripts[capability]
+            return queue.popleft() if queue else None
+
+def register_desktop_bridge(app):
+    """Register the /desktop/api bridge routes"""
+    project = app.config['PROJECT']
+    bridge = DesktopBridge(project.src_path / "mocks" / "desktop.json")
+    app.config['DESKTOP_BRIDGE'] = bridge
+
+    @app.route('/desktop/api/dialog/open', methods=['POST'])
+    def desktop_open_dialog():
+        return jsonify(bridge.open_file(request.get_json(silent=True) or {}))
+
+    @app.route('/desktop/api/dialog/save', methods=['POST'])
+    def desktop_save_dialog():
+        return jsonify(bridge.save_file(request.get_json(silent=True) or {}))
+
+    @app.route('/desktop/api/notify', methods=['POST'])
+    def desktop_notify():
+        data = request.get_json(silent=True) or {}
+        if not data.get('title'):
+            return jsonify({'error': "'title' is required"}), 400
+        return jsonify(bridge.notify(data['title'], data.get('body', ''))), 201
+
+    @app.route('/desktop/api/notifications')
+    def desktop_notifications():
+        return jsonify({'notifications': bridge.state()['notifications']})
+
+    @app.route('/desktop/api/tray', methods=['GET', 'PUT'])
+    def desktop_tray():
+        if request.method == 'PUT':
+            bridge.set_tray_menu((request.get_json(silent=True) or {}).get('items', []))
+        return jsonify({'items': bridge.state()['tray_menu']})
+
+    @app.route('/desktop/api/tray/<item_id>/click', methods=['POST'])
+    def desktop_tray_click(item_id):
+        try:
+            return jsonify(bridge.click_tray_item(item_id))
+        except KeyError:
+            return jsonify({'error': f"Tray item '{item_id}' not found"}), 404
+
+    @app.route('/desktop/api/events')
+    def desktop_events():
+        return jsonify({'events': bridge.events_since(request.args.get('since', 0, type=int))})
+
+    @app.route('/desktop/api/mock', methods=['GET', 'POST', 'DELETE'])
+    def desktop_mock():
+        if request.method == 'POST':
+            try:
+                bridge.load_script(request.get_json(silent=True) or {})
+            except ValueError as e:
+                return jsonify({'error': str(e)}), 400
+        elif request.method == 'DELETE':
+            bridge.reset()
+        return jsonify(bridge.state())
+
+    @app.route('/desktop/api/bridge.js')
+    def desktop_bridge_script():
+        return Response(BRIDGE_SCRIPT, mimetype='application/javascript')
+
+# Client shim exposing window.flashflowDesktop plus a simulated tray and toasts
+BRIDGE_SCRIPT = """
+(function () {
+    let lastSeq = 0;
+    const trayListeners = [];
+
+    async function call(method, url, body) {
+        const response = await fetch(url, {
+            method: method,
+            headers: {'Content-Type': 'application/json'},
+            body: body ? JSON.stringify(body) : undefined
+        });
+        return response.json();
+    }
+
+    function toast(notification) {
+        const el = document.createElement('div');
+        el.style.cssText = 'position:fixed;right:16px;bottom:' + (16 + document.querySelectorAll('.ff-toast').length * 76) + 'px;'
+            + 'width:280px;background:#1f2937;color:#f9fafb;padding:12px;border-radius:8px;'
+            + 'box-shadow:0 4px 12px rgba(0,0,0,0.3);font-family:sans-serif;z-index:99998';
+        el.className = 'ff-toast';
+        const title = document.createElement('strong');
+        title.textContent = notification.title;
+        const body = document.createElement('div');
+        body.textContent = notification.body;
+        body.style.fontSize = '0.85rem';
+        el.append(title, body);
+        document.body.appendChild(el);
+        setTimeout(() => el.remove(), 5000);
+    }
+
+    async function renderTray() {
+        const items = (await call('GET', '/desktop/api/tray')).items;
+        let tray = document.getElementById('ff-tray');
+        if (!items.length) { if (tray) tray.remove(); return; }
+        if (!tray) {
+            tray = document.createElement('div');
+            tray.id = 'ff-tray';
+            tray.style.cssText = 'position:fixed;right:16px;top:16px;background:#111827;color:#f9fafb;'
+                + 'border-radius:8px;padding:8px;font-family:sans-serif;font-size:0.85rem;z-index:99998';
+            document.body.appendChild(tray);
+        }
+        tray.innerHTML = '<div style="opacity:0.6;margin-bottom:4px">🗔 Tray menu (simulated)</div>';
+        for (const item of items) {
+            const button = document.createElement('button');
+            button.textContent = item.label;
+            button.style.cssText = 'display:block;width:100%;margin:2px 0;background:#374151;color:inherit;border:none;padding:4px 8px;border-radius:4px;cursor:pointer;text-align:left';
+            button.onclick = () => call('POST', '/desktop/api/tray/' + encodeURIComponent(item.id) + '/click');
+            tray.appendChild(button);
+        }
+    }
+
+    async function poll() {
+        try {
+            const events = (await call('GET', '/desktop/api/events?since=' + lastSeq)).events;
+            for (const event of events) {
+                lastSeq = event.seq;
+                if (event.type === 'notification') toast(event.payload);
+                if (event.type === 'tray_menu') renderTray();
+                if (event.type === 'tray_click') trayListeners.forEach(listener => listener(event.payload.id));
+            }
+        } catch (e) {}
+    }
+
+    window.flashflowDesktop = {
+        openFile: (options) => call('POST', '/desktop/api/dialog/open', options || {}),
+        saveFile: (options) => call('POST', '/desktop/api/dialog/save', options || {}),
+        notify: (title, body) => call('POST', '/desktop/api/notify', {title: title, body: body || ''}),
+        setTrayMenu: (items) => call('PUT', '/desktop/api/tray', {items: items}),
+        onTrayClick: (listener) => trayListeners.push(listener)
+    };
+
+    // Skip events that happened before this page loaded
+    call('GET', '/desktop/api/events?since=0').then(data => {
+        if (data.events.length) lastSeq = data.events[data.events.length - 1].seq;
+        renderTray();
+        setInterval(poll, 1000);
+    });
+})();
+"""
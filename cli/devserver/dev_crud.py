@@ -0,0 +1,660 @@
+"""
+FlashFlow dev CRUD - /api/data endpoints for every model declared in .flow files
+
+Rows live in the database configured in flashflow.json (SQLite by default), so
+the same flows can be exercised against Postgres or MySQL before deploying.
+List endpoints take the page/sort/filter grammar from core/query.py, and
+/api/data/openapi.json describes every model's endpoints, plus flow endpoints
+that declare permissions and the predict endpoints of declared AI models. Create and update bodies are validated against the
+same schemas; with 'flashflow serve --strict-schema' fields the model does not
+declare are rejected too. Successful writes fire the flows' webhooks and
+notifications (see cli/devserver/webhooks.py and notifications.py) and are
+recorded in the audit log with the row before and after (see
+cli/devserver/audit.py). With a response_cache block in flashflow.json, list
+and show responses are cached until a write to their table (see
+cli/devserver/response_cache.py). Models' computed fields are added to every
+row returned, and their before_/after_ create, update and delete hooks run
+inside the write's transaction (see core/model_rules.py); a hook that
+rejects a write answers 422.
+
+Rows carry an ETag (a hash of their values) on show, create and update. An
+update or delete sent with If-Match runs only if the row is unchanged, and
+otherwise gets 409 with the row as it is now. POST /api/data/<table>/batch
+applies a list of creates, updates and deletes in one transaction: all of
+them or, if any fails, none.
+"""
+
+import re
+import threading
+from typing import Dict, List, Tuple
+
+from flask import request, jsonify
+
+from core.database import (Storage, StorageError, StorageUnavailable, create_storage, model_field_list, row_etag,
+                           table_name_for)
+from core.framework import FlashFlowProject
+from core.model_rules import ModelRuleError, ModelRules, RuleRejected
+from core.parser.parser import FlowParser
+from core.api_keys import ApiKeyRule, SIGNATURE_TOLERANCE
+from core.permissions import PATH_PARAMETER, AccessRule
+from core.query import MAX_PER_PAGE, QUERY_GRAMMAR, link_header, openapi_list_parameters, parse_list_query
+from core.validation import FieldError, SchemaValidationError, validate_body
+from cli.devserver.permissions import get_permission_registry
+from cli.devserver.ai_models import add_ai_model_paths, add_embed_path, get_dev_models
+from cli.devserver.api_tester import current_sandbox_storage
+from cli.devserver.audit import audit_write
+from cli.devserver.response_cache import cached_get, invalidate_table
+from cli.devserver.notifications import fire_notifications
+from cli.devserver.webhooks import fire_webhooks
+
+# Model field types as OpenAPI schemas; unknown types are strings stored as VARCHAR(255)
+STRING_SCHEMA = {'type': 'string', 'maxLength': 255}
+OPENAPI_TYPES = {
+    'integer': {'type': 'integer'}, 'float': {'type': 'number'}, 'boolean': {'type': 'boolean'},
+    'date': {'type': 'string', 'format': 'date'}, 'datetime': {'type': 'string', 'format': 'date-time'},
+    'timestamp': {'type': 'string', 'format': 'date-time'}, 'json': {}, 'text': {'type': 'string'},
+    'email': dict(STRING_SCHEMA, format='email'), 'url': dict(STRING_SCHEMA, format='uri'),
+    'password': dict(STRING_SCHEMA, format='password'), 'enum': {'type': 'string', 'maxLength': 100}
+}
+BATCH_OPERATIONS = ('create', 'update', 'delete')
+MAX_BATCH_SIZE = 500
+
+def get_storage(app) -> Storage:
+    """Return the storage shared by the dev CRUD API and the admin database browser
+
+    Requests running in a sandbox (see cli/devserver/api_tester.py) get the sandbox's copy instead.
+    """
+    sandbox = current_sandbox_storage(app)
+    if sandbox is not None:
+        return sandbox
+    if 'STORAGE' not in app.config:
+        project: FlashFlowProject = app.config['PROJECT']
+        app.config['STORAGE'] = create_storage(project)
+    return app.config['STORAGE']
+
+def storage_error_response(error: StorageError):
+    if isinstance(error, StorageUnavailable):
+        status = 503
+    elif 'not found' in str(error):
+        status = 404
+    else:
+        status = 400
+    return jsonify({'error': str(error)}), status
+
+def rule_error_response(error):
+    """422 for a write a model hook rejected, 500 for a model whose rules are invalid"""
+    if isinstance(error, RuleRejected):
+        return jsonify(error.to_dict()), 422
+    return jsonify({'error': str(error)}), 500
+
+class RowConflict(Exception):
+    """Raised when If-Match names a version of a row that is no longer current"""
+
+    def __init__(self, table: str, row: dict):
+        super().__init__(f"Row {row['id']} in '{table}' was changed by someone else; reload it and try again")
+        self.row = row
+
+    def to_dict(self):
+        return {'error': str(self), 'current': self.row, 'etag': row_etag(self.row)}
+
+    def response(self):
+        response = jsonify(self.to_dict())
+        response.headers['ETag'] = row_etag(self.row)
+        return response, 409
+
+class BatchError(Exception):
+    """Raised to abandon a batch; carries the response for the operation that failed"""
+
+    def __init__(self, index: int, status: int, body: dict):
+        super().__init__(body.get('error', ''))
+        self.index = index
+        self.status = status
+        self.body = body
+
+def if_match_holds(header, row) -> bool:
+    """Whether an If-Match value (a list of ETags, or *) matches the row"""
+    if header is None:
+        return True
+    header = header.strip()
+    if header == '*':
+        return True
+    etag = row_etag(row)
+    # Weak comparison is fine here: the ETags are only ever compared with each other
+    return any(re.sub(r'^W/', '', tag.strip()) == etag for tag in header.split(','))
+
+def with_etag(response, row):
+    response.headers['ETag'] = row_etag(row)
+    return response
+
+class ModelTables:
+    """Keeps one table per flow model, created on first use"""
+
+    def __init__(self, project: FlashFlowProject, storage: Storage):
+        self.project = project
+        self.storage = storage
+        self.tables = {}
+        self.fields = {}
+        self.rules: Dict[str, ModelRules] = {}
+        self.rule_errors: Dict[str, ModelRuleError] = {}
+        self._signature: Tuple = ()
+        self._lock = threading.Lock()
+
+    def flows_signature(self) -> Tuple:
+        return tuple(sorted((str(path), path.stat().st_mtime) for path in self.project.get_flow_files() if path.exists()))
+
+    def sync(self):
+        """Re-read models from the flows and create any missing tables"""
+        with self._lock:
+            signature = self.flows_signature()
+            ir = FlowParser().parse_project(self.project.root_path)
+            tables, fields, rules, rule_errors = {}, {}, {}, {}
+            for model_name, model_data in ir.models.items():
+                table = table_name_for(model_name)
+                model_data = model_data if isinstance(model_data, dict) else {}
+                fields[table] = model_field_list(model_data.get('fields'))
+                self.storage.ensure_table(table, fields[table])
+                tables[table] = model_name
+                try:
+                    rules[table] = ModelRules.from_model(model_name, model_data, [model_field['name'] for model_field in fields[table]])
+                except ModelRuleError as e:
+                    rule_errors[table] = e
+            self.tables, self.fields, self.rules, self.rule_errors = tables, fields, rules, rule_errors
+            self._signature = signature
+
+    def resolve(self, table: str) -> str:
+        if table not in self.tables or self.flows_signature() != self._signature:
+            # A model may have been added, or its hooks changed, since the last sync
+            self.sync()
+        if table not in self.tables:
+            raise StorageError(f"Table '{table}' not found; no model in the flows maps to it")
+        return table
+
+    def model_rules(self, table: str) -> ModelRules:
+        """A table's computed fields and hooks; raises ModelRuleError when they are declared wrongly"""
+        if table in self.rule_errors:
+            raise self.rule_errors[table]
+        return self.rules[table]
+
+def register_dev_crud(app):
+    """Register the /api/data CRUD routes"""
+    models = ModelTables(app.config['PROJECT'], get_storage(app))
+    strict = app.config.get('STRICT_SCHEMA', False)
+
+    def validated_body(table: str, partial: bool):
+        """The request's JSON object, checked against the model's input schema"""
+        if request.get_data() and request.get_json(silent=True) is None:
+            raise SchemaValidationError(models.tables[table], [FieldError('body', 'json', "is not valid JSON")])
+        return validated_data(table, request.get_json(silent=True), partial)
+
+    def validated_data(table: str, body, partial: bool):
+        rules = models.model_rules(table)
+        computed = [name for name in rules.computed_names if isinstance(body, dict) and name in body]
+        if computed:
+            raise SchemaValidationError(models.tables[table], [FieldError(name, 'computed', "is computed and cannot be set")
+                                                               for name in computed])
+        columns = [column['name'] for column in get_storage(app).table_columns(table)]
+        # Fields a before_create hook sets need not be sent
+        schema = input_schema(models.fields.get(table, []), columns, strict, exempt=rules.assigned('before_create'))
+        validate_body({} if body is None else body, schema, models.tables[table], partial=partial, strict=strict)
+        return body or {}
+
+    def write_row(storage: Storage, table: str, action: str, data, current=None):
+        """Create or update a row with the model's hooks around it, inside the caller's transaction"""
+        rules = models.model_rules(table)
+        data = rules.before(action, data, current)
+        row = storage.insert(table, data) if action == 'create' else storage.update(table, current['id'], data)
+        assigned = rules.after(action, row, current, data)
+        return storage.update(table, row['id'], assigned) if assigned else row
+
+    def delete_row(storage: Storage, table: str, row) -> bool:
+        rules = models.model_rules(table)
+        rules.before('delete', {}, row)
+        deleted = storage.delete(table, row['id'])
+        rules.after('delete', row, row, {})
+        return deleted
+
+    @app.route('/api/data')
+    def dev_crud_index():
+        try:
+            models.sync()
+        except StorageError as e:
+            return storage_error_response(e)
+        storage = get_storage(app)
+        return jsonify({
+            'driver': storage.driver,
+            'models': [{'model': model, 'table': table, 'url': f"/api/data/{table}"} for table, model in sorted(models.tables.items())]
+        })
+
+    @app.route('/api/data/openapi.json')
+    def dev_crud_openapi():
+        """OpenAPI 3 description of the model endpoints, including the list query grammar"""
+        try:
+            models.sync()
+            storage = get_storage(app)
+            columns = {table: [column['name'] for column in storage.table_columns(table)] for table in models.tables}
+        except StorageError as e:
+            return storage_error_response(e)
+        registry = get_permission_registry(app)
+        registry.reload_if_changed()
+        document = openapi_document(app.config['PROJECT'], models, columns, strict)
+        add_endpoint_security(document, registry.rules)
+        add_api_key_security(document, registry.key_rules)
+        add_ai_model_paths(document, get_dev_models(app).declarations()[0])
+        if app.config['PROJECT'].config.embeddings:
+            add_embed_path(document)
+        return jsonify(document)
+
+    @app.route('/api/data/<table>', methods=['GET'])
+    def dev_crud_list(table):
+        try:
+            table = models.resolve(table)
+        except StorageError as e:
+            return storage_error_response(e)
+        return cached_get(app, models.tables[table], table, lambda: list_rows(table))
+
+    def list_rows(table):
+        storage = get_storage(app)
+        try:
+            columns = [column['name'] for column in storage.table_columns(table)]
+            query = parse_list_query(request.args.items(multi=True), columns)
+            total = storage.count(table, filters=query.filters)
+            rows = storage.fetch_rows(table, limit=query.per_page, offset=query.offset, filters=query.filters, sort=query.sort)
+            rules = models.model_rules(table)
+        except StorageError as e:
+            return storage_error_response(e)
+        except ModelRuleError as e:
+            return rule_error_response(e)
+
+        response = jsonify({
+            'data': [rules.compute(row) for row in rows],
+            'total': total,
+            'page': query.page,
+            'per_page': query.per_page,
+            'pages': query.pages(total)
+        })
+        params = {name: value for name, value in request.args.items() if name not in ('page', 'per_page')}
+        response.headers['Link'] = link_header(request.base_url, params, query, total)
+        response.headers['X-Total-Count'] = str(total)
+        return response
+
+    @app.route('/api/data/<table>', methods=['POST'])
+    def dev_crud_create(table):
+        try:
+            table = models.resolve(table)
+            data = validated_body(table, partial=False)
+            storage = get_storage(app)
+            with storage.transaction():
+                row = write_row(storage, table, 'create', data)
+        except SchemaValidationError as e:
+            return jsonify(e.to_dict()), 400
+        except (RuleRejected, ModelRuleError) as e:
+            return rule_error_response(e)
+        except StorageError as e:
+            return storage_error_response(e)
+        invalidate_table(app, table)
+        audit_write(app, 'create', table, row['id'], None, row)
+        fire_webhooks(app, models.tables[table], 'created', row)
+        fire_notifications(app, models.tables[table], 'created', row)
+        return with_etag(jsonify({'data': models.rules[table].compute(row)}), row), 201
+
+    @app.route('/api/data/<table>/<int:row_id>', methods=['GET'])
+    def dev_crud_show(table, row_id):
+        try:
+            table = models.resolve(table)
+        except StorageError as e:
+            return storage_error_response(e)
+        return cached_get(app, models.tables[table], table, lambda: show_row(table, row_id))
+
+    def show_row(table, row_id):
+        try:
+            row = get_storage(app).get(table, row_id)
+            rules = models.model_rules(table)
+        except StorageError as e:
+            return storage_error_response(e)
+        except ModelRuleError as e:
+            return rule_error_response(e)
+        if row is None:
+            return jsonify({'error': f"Row {row_id} not found in '{table}'"}), 404
+        return with_etag(jsonify({'data': rules.compute(row)}), row)
+
+    @app.route('/api/data/<table>/<int:row_id>', methods=['PUT', 'PATCH'])
+    def dev_crud_update(table, row_id):
+        try:
+            table = models.resolve(table)
+            data = validated_body(table, partial=True)
+            storage = get_storage(app)
+            # The check and the write happen under one lock, so two clients cannot both pass it
+            with storage.transaction():
+                current = storage.get(table, row_id, for_update=True)
+                if current is not None and not if_match_holds(request.headers.get('If-Match'), current):
+                    raise RowConflict(table, current)
+                row = write_row(storage, table, 'update', data, current) if current is not None else None
+        except SchemaValidationError as e:
+            return jsonify(e.to_dict()), 400
+        except RowConflict as e:
+            return e.response()
+        except (RuleRejected, ModelRuleError) as e:
+            return rule_error_response(e)
+        except StorageError as e:
+            return storage_error_response(e)
+        if row is None:
+            return jsonify({'error': f"Row {row_id} not found in '{table}'"}), 404
+        invalidate_table(app, table)
+        audit_write(app, 'update', table, row_id, current, row)
+        fire_webhooks(app, models.tables[table], 'updated', row)
+        fire_notifications(app, models.tables[table], 'updated', row)
+        return with_etag(jsonify({'data': models.rules[table].compute(row)}), row)
+
+    @app.route('/api/data/<table>/<int:row_id>', methods=['DELETE'])
+    def dev_crud_delete(table, row_id):
+        try:
+            table = models.resolve(table)
+            storage = get_storage(app)
+            with storage.transaction():
+                # Webhooks get the row as it was
+                row = storage.get(table, row_id, for_update=True)
+                if row is not None and not if_match_holds(request.headers.get('If-Match'), row):
+                    raise RowConflict(table, row)
+                deleted = row is not None and delete_row(storage, table, row)
+        except RowConflict as e:
+            return e.response()
+        except (RuleRejected, ModelRuleError) as e:
+            return rule_error_response(e)
+        except StorageError as e:
+            return storage_error_response(e)
+        if not deleted:
+            return jsonify({'error': f"Row {row_id} not found in '{table}'"}), 404
+        invalidate_table(app, table)
+        audit_write(app, 'delete', table, row_id, row, None)
+        fire_webhooks(app, models.tables[table], 'deleted', row)
+        fire_notifications(app, models.tables[table], 'deleted', row)
+        return '', 204
+
+    @app.route('/api/data/<table>/batch', methods=['POST'])
+    def dev_crud_batch(table):
+        """Apply creates, updates and deletes atomically; the first failure rolls back all of them"""
+        body = request.get_json(silent=True)
+        operations = body.get('operations') if isinstance(body, dict) else body
+        if not isinstance(operations, list) or not operations:
+            return jsonify({'error': "Send {\"operations\": [...]} with at least one create, update or delete"}), 400
+        if len(operations) > MAX_BATCH_SIZE:
+            return jsonify({'error': f"A batch takes at most {MAX_BATCH_SIZE} operations, got {len(operations)}"}), 400
+        try:
+            table = models.resolve(table)
+            storage = get_storage(app)
+            with storage.transaction():
+                results = [apply_operation(storage, table, index, operation) for index, operation in enumerate(operations)]
+        except BatchError as e:
+            return jsonify(dict(e.body, index=e.index, rolled_back=True)), e.status
+        except StorageError as e:
+            response, status = storage_error_response(e)
+            return jsonify(dict(response.get_json(), rolled_back=True)), status
+
+        # Only now that everything is committed
+        invalidate_table(app, table)
+        for result, event, row, previous in results:
+            audit_write(app, result['op'], table, result['id'], previous, None if event == 'deleted' else row)
+            fire_webhooks(app, models.tables[table], event, row)
+            fire_notifications(app, models.tables[table], event, row)
+        return jsonify({'data': [result for result, _, _, _ in results]})
+
+    def apply_operation(storage: Storage, table: str, index: int, operation):
+        """One operation of a batch, as (result, webhook event, webhook row, row before it); raises BatchError to abandon the batch"""
+        if not isinstance(operation, dict) or operation.get('op') not in BATCH_OPERATIONS:
+            raise BatchError(index, 400, {'error': f"Operation {index} needs 'op': one of {', '.join(BATCH_OPERATIONS)}"})
+        op = operation['op']
+        try:
+            rules = models.model_rules(table)
+            if op == 'create':
+                row = write_row(storage, table, 'create', validated_data(table, operation.get('data'), partial=False))
+                return {'op': op, 'id': row['id'], 'data': rules.compute(row), 'etag': row_etag(row)}, 'created', row, None
+
+            row_id = operation.get('id')
+            if isinstance(row_id, bool) or not isinstance(row_id, int):
+                raise BatchError(index, 400, {'error': f"Operation {index} ({op}) needs the integer 'id' of a row"})
+            current = storage.get(table, row_id, for_update=True)
+            if current is None:
+                raise BatchError(index, 404, {'error': f"Row {row_id} not found in '{table}'"})
+            if not if_match_holds(operation.get('if_match'), current):
+                raise RowConflict(table, current)
+            if op == 'update':
+                row = write_row(storage, table, 'update', validated_data(table, operation.get('data'), partial=True), current)
+                return {'op': op, 'id': row_id, 'data': rules.compute(row), 'etag': row_etag(row)}, 'updated', row, current
+            delete_row(storage, table, current)
+            # Webhooks get the row as it was
+            return {'op': op, 'id': row_id, 'deleted': True}, 'deleted', current, current
+        except SchemaValidationError as e:
+            raise BatchError(index, 400, e.to_dict())
+        except RowConflict as e:
+            raise BatchError(index, 409, e.to_dict())
+        except RuleRejected as e:
+            raise BatchError(index, 422, e.to_dict())
+        except ModelRuleError as e:
+            raise BatchError(index, 500, {'error': str(e)})
+
+def field_schema(model_field):
+    """OpenAPI schema for one model field"""
+    schema = dict(OPENAPI_TYPES.get(model_field.get('type', 'string'), STRING_SCHEMA))
+    if isinstance(model_field.get('values'), list):
+        schema['enum'] = list(model_field['values'])
+    if not model_field.get('required'):
+        schema['nullable'] = True
+    return schema
+
+def model_schema(fields, columns):
+    """Row schema from a model's fields plus the columns every table gets"""
+    properties = {'id': {'type': 'integer', 'readOnly': True}}
+    for model_field in fields:
+        name = model_field.get('name') if isinstance(model_field, dict) else None
+        if name and name in columns:
+            properties[name] = field_schema(model_field)
+    for name in ('created_at', 'updated_at'):
+        if name in columns:
+            properties[name] = {'type': 'string', 'format': 'date-time', 'readOnly': True}
+    for name in columns:
+        properties.setdefault(name, {})
+    return {'type': 'object', 'properties': properties}
+
+def input_schema(fields, columns, strict=False, exempt=()):
+    """
+    Request body schema for creating or updating a row: the model's writable
+    fields, with required fields that have no default and are not in exempt
+    (set by hooks). Strict mode closes the schema so columns the model no
+    longer declares are rejected as well.
+    """
+    declared = {model_field['name']: model_field for model_field in fields
+                if isinstance(model_field, dict) and model_field.get('name')}
+    properties = {name: schema for name, schema in model_schema(fields, columns)['properties'].items()
+                  if not (strict and name not in declared and not schema.get('readOnly'))}
+    schema = {'type': 'object', 'properties': properties, 'additionalProperties': not strict}
+    required = [name for name, model_field in declared.items()
+                if name in properties and not properties[name].get('readOnly') and model_field.get('required')
+                and model_field.get('default') is None and not model_field.get('auto') and name not in exempt]
+    if required:
+        schema['required'] = required
+    return schema
+
+def openapi_document(project, models: ModelTables, columns, strict=False):
+    """Paths for each model's list, create, show, update and delete endpoints"""
+    error = {'description': 'Error', 'content': {'application/json': {'schema': {'$ref': '#/components/schemas/Error'}}}}
+    invalid = {'description': 'Invalid request body, or a storage error',
+               'content': {'application/json': {'schema': {'$ref': '#/components/schemas/ValidationError'}}}}
+    schemas = {
+        'Error': {'type': 'object', 'properties': {'error': {'type': 'string'}}},
+        'ValidationError': {'type': 'object', 'properties': {
+            'error': {'type': 'string'},
+            'errors': {'type': 'array', 'items': {'type': 'object', 'properties': {
+                'field': {'type': 'string'}, 'code': {'type': 'string'}, 'message': {'type': 'string'}
+            }}}
+        }},
+        'Conflict': {'type': 'object', 'properties': {
+            'error': {'type': 'string'}, 'current': {'type': 'object'}, 'etag': {'type': 'string'}
+        }}
+    }
+    paths = {}
+    etag = {'ETag': {'schema': {'type': 'string'}, 'description': 'Send it back as If-Match to update only this version'}}
+    if_match = {'name': 'If-Match', 'in': 'header', 'required': False, 'schema': {'type': 'string'},
+                'description': 'The ETag the row was read with; the request fails with 409 if the row has changed since'}
+    conflict = {'description': 'The row changed since it was read (If-Match did not match)', 'headers': etag,
+                'content': {'application/json': {'schema': {'$ref': '#/components/schemas/Conflict'}}}}
+
+    for table, model in sorted(models.tables.items()):
+        fields = models.fields.get(table, [])
+        rules = models.rules.get(table)
+        schemas[model] = model_schema(fields, columns[table])
+        for name, expression in rules.computed if rules else []:
+            schemas[model]['properties'][name] = {'readOnly': True, 'description': f"Computed: {expression}"}
+        schemas[f"{model}Input"] = input_schema(fields, columns[table], strict, rules.assigned('before_create') if rules else ())
+        schemas[f"{model}Update"] = {key: value for key, value in schemas[f"{model}Input"].items() if key != 'required'}
+        row = {'$ref': f"#/components/schemas/{model}"}
+        single = {'headers': etag, 'content': {'application/json': {'schema': {'type': 'object', 'properties': {'data': row}}}}}
+        body = {'required': True, 'content': {'application/json': {'schema': {'$ref': f"#/components/schemas/{model}Input"}}}}
+        update = {'required': True, 'content': {'application/json': {'schema': {'$ref': f"#/components/schemas/{model}Update"}}}}
+        row_id = [{'name': 'row_id', 'in': 'path', 'required': True, 'schema': {'type': 'integer'}}]
+
+        paths[f"/api/data/{table}"] = {
+            'get': {
+                'summary': f"List {model} rows",
+                'description': QUERY_GRAMMAR,
+                'parameters': openapi_list_parameters(columns[table]),
+                'responses': {
+                    '200': {
+                        'description': f"A page of {model} rows",
+                        'headers': {
+                            'Link': {'schema': {'type': 'string'}, 'description': 'first, prev, next and last page URLs'},
+                            'X-Total-Count': {'schema': {'type': 'integer'}}
+                        },
+                        'content': {'application/json': {'schema': {'type': 'object', 'properties': {
+                            'data': {'type': 'array', 'items': row},
+                            'total': {'type': 'integer'},
+                            'page': {'type': 'integer'},
+                            'per_page': {'type': 'integer', 'maximum': MAX_PER_PAGE},
+                            'pages': {'type': 'integer'}
+                        }}}}
+                    },
+                    '400': error
+                }
+            },
+            'post': {'summary': f"Create a {model}", 'requestBody': body,
+                     'responses': {'201': dict(single, description='Created'), '400': invalid}}
+        }
+        paths[f"/api/data/{table}/{{row_id}}"] = {
+            'get': {'summary': f"Get a {model}", 'parameters': row_id,
+                    'responses': {'200': dict(single, description='The row'), '404': error}},
+            'put': {'summary': f"Update a {model}", 'parameters': row_id + [if_match], 'requestBody': update,
+                    'responses': {'200': dict(single, description='Updated'), '400': invalid, '404': error, '409': conflict}},
+            'patch': {'summary': f"Update some fields of a {model}", 'parameters': row_id + [if_match], 'requestBody': update,
+                      'responses': {'200': dict(single, description='Updated'), '400': invalid, '404': error, '409': conflict}},
+            'delete': {'summary': f"Delete a {model}", 'parameters': row_id + [if_match],
+                       'responses': {'204': {'description': 'Deleted'}, '404': error, '409': conflict}}
+        }
+        operation = {'type': 'object', 'required': ['op'], 'properties': {
+            'op': {'type': 'string', 'enum': list(BATCH_OPERATIONS)},
+            'id': {'type': 'integer', 'description': 'The row to update or delete'},
+            'data': {'$ref': f"#/components/schemas/{model}Update", 'description': 'Fields to create or update'},
+            'if_match': {'type': 'string', 'description': 'As the If-Match header of a single update or delete'}
+        }}
+        failed = {'content': {'application/json': {'schema': {'type': 'object', 'properties': {
+            'error': {'type': 'string'}, 'index': {'type': 'integer'}, 'rolled_back': {'type': 'boolean'}
+        }}}}}
+        paths[f"/api/data/{table}/batch"] = {
+            'post': {
+                'summary': f"Create, update and delete {model} rows in one transaction",
+                'description': 'Operations run in order. If one fails nothing is written, and the response '
+                               'names the index of the operation that failed.',
+                'requestBody': {'required': True, 'content': {'application/json': {'schema': {
+                    'type': 'object', 'properties': {'operations': {'type': 'array', 'maxItems': MAX_BATCH_SIZE, 'items': operation}}
+                }}}},
+                'responses': {
+                    '200': {'description': 'Every operation applied', 'content': {'application/json': {'schema': {
+                        'type': 'object', 'properties': {'data': {'type': 'array', 'items': {'type': 'object', 'properties': {
+                            'op': {'type': 'string'}, 'id': {'type': 'integer'}, 'data': row,
+                            'etag': {'type': 'string'}, 'deleted': {'type': 'boolean'}
+                        }}}}
+                    }}}},
+                    '400': dict(failed, description='An invalid operation; nothing was written'),
+                    '404': dict(failed, description='An operation names a row that does not exist; nothing was written'),
+                    '409': dict(failed, description='An if_match did not match; nothing was written')
+                }
+            }
+        }
+
+    return {
+        'openapi': '3.0.3',
+        'info': {'title': f"{project.config.name} dev data API", 'version': project.config.version, 'description': QUERY_GRAMMAR},
+        'paths': paths,
+        'components': {'schemas': schemas}
+    }
+
+# Dev server credentials; roles and scopes themselves are listed per operation
+SECURITY_SCHEMES = {
+    'basicAuth': {'type': 'http', 'scheme': 'basic', 'description': 'A user from /admin/users'},
+    'devRole': {'type': 'apiKey', 'in': 'header', 'name': 'X-FlashFlow-Role',
+                'description': 'Act as a role without logging in; add X-FlashFlow-Scopes for scopes'}
+}
+
+def add_endpoint_security(document, rules: List[AccessRule]):
+    """Describe flow endpoints that declare 'permissions:' and how to authenticate for them"""
+    endpoint_rules = [rule for rule in rules if rule.kind == 'endpoint']
+    if not endpoint_rules:
+        return
+    document['components'].setdefault('securitySchemes', {}).update(SECURITY_SCHEMES)
+    error = {'content': {'application/json': {'schema': {'$ref': '#/components/schemas/Error'}}}}
+
+    for rule in endpoint_rules:
+        path = PATH_PARAMETER.sub(lambda match: '{' + match.group(0).strip('{}:') + '}', rule.path)
+        parameters = [{'name': name, 'in': 'path', 'required': True, 'schema': {'type': 'string'}}
+                      for name in re.findall(r'\{([^}]+)\}', path)]
+        required = rule.permission
+        needs = [f"role {required.role} or higher"] if required.role else []
+        needs += [f"one of roles {', '.join(required.roles)}"] if required.roles else []
+        needs += [f"scopes {', '.join(required.scopes)}"] if required.scopes else []
+        for method in rule.methods or ['get', 'post', 'put', 'patch', 'delete']:
+            document['paths'].setdefault(path, {})[method.lower()] = {
+                'summary': f"Declared in {rule.source}",
+                'description': f"Requires {'; '.join(needs)}.",
+                'parameters': parameters,
+                'security': [{'basicAuth': []}, {'devRole': []}],
+                'x-flashflow-permissions': dict(required.to_dict(), allowed_roles=required.allowed_roles()),
+                'responses': {
+                    '200': {'description': 'OK'},
+                    '401': dict(error, description='Not authenticated'),
+                    '403': dict(error, description='Role or scopes not sufficient')
+                }
+            }
+
+API_KEY_SCHEMES = {
+    'apiKey': {'type': 'apiKey', 'in': 'header', 'name': 'X-API-Key',
+               'description': "A whole key from /admin/api-keys, '<id>.<secret>'"},
+    'signedRequest': {'type': 'apiKey', 'in': 'header', 'name': 'X-FlashFlow-Signature',
+                      'description': "'t=<unix time>,v1=<hex HMAC-SHA256>' of '<t>.<METHOD>.<path?query>.' plus the body, "
+                                     f"keyed with the secret; send X-API-Key-Id too. Valid for {SIGNATURE_TOLERANCE}s, once"}
+}
+
+def add_api_key_security(document, rules: List[ApiKeyRule]):
+    """Describe flow endpoints declared with 'auth: api_key'; merges into what add_endpoint_security wrote"""
+    if not rules:
+        return
+    document['components'].setdefault('securitySchemes', {}).update(API_KEY_SCHEMES)
+    error = {'content': {'application/json': {'schema': {'$ref': '#/components/schemas/Error'}}}}
+
+    for rule in rules:
+        path = PATH_PARAMETER.sub(lambda match: '{' + match.group(0).strip('{}:') + '}', rule.path)
+        required = rule.requirement
+        for method in rule.methods or ['get', 'post', 'put', 'patch', 'delete']:
+            operation = document['paths'].setdefault(path, {}).setdefault(method.lower(), {
+                'summary': f"Declared in {rule.source}",
+                'parameters': [{'name': name, 'in': 'path', 'required': True, 'schema': {'type': 'string'}}
+                               for name in re.findall(r'\{([^}]+)\}', path)],
+                'responses': {'200': {'description': 'OK'}}
+            })
+            needs = ['a signed request' if required.signed else 'an API key']
+            needs += [f"key scopes {', '.join(required.scopes)}"] if required.scopes else []
+            operation['description'] = ' '.join(filter(None, [operation.get('description'), f"Requires {'; '.join(needs)}."]))
+            operation['security'] = [{'signedRequest': []}] if required.signed else [{'apiKey': []}, {'signedRequest': []}]
+            operation['x-flashflow-auth'] = required.to_dict()
+            operation['responses'].update({
+                '401': dict(error, description='Missing, unknown or revoked key, or a bad signature'),
+                '403': dict(error, description='Key lacks a required scope, or its role is not permitted'),
+                '429': dict(error, description="Over the key's requests per minute; see Retry-After")
+            })
@@ -0,0 +1,118 @@
+"""
+FlashFlow dev server events - Build progress and file changes from watchers, pushed to open pages
+
+The server's own .flow watcher and any 'flashflow build --watch' running next
+to it report what they do as typed events (see core/dev_events.py). Pages get:
+
+    reload    a .flow file changed; previews render flows live, so this is a reload
+    build     the build's state changed: building, ok or failed (with its errors)
+
+/__build shows the latest build state and where watchers connect.
+"""
+
+import threading
+import time
+from typing import Any, Dict, List, Optional
+
+import click
+from flask import jsonify
+
+from core.dev_events import DevEventListener
+from cli.devserver.live_reload import get_reload_hub
+
+MAX_ERRORS = 50
+
+class BuildStatus:
+    """What the watchers last reported"""
+
+    def __init__(self):
+        self.state = 'idle'
+        self.target: Optional[str] = None
+        self.trigger: Optional[str] = None
+        self.source: Optional[str] = None
+        self.started_at: Optional[float] = None
+        self.finished_at: Optional[float] = None
+        self.seconds: Optional[float] = None
+        self.errors: List[Dict[str, Any]] = []
+        self.last_change: Optional[Dict[str, Any]] = None
+        self._lock = threading.Lock()
+
+    def apply(self, name: str, data: Dict[str, Any], source: str) -> bool:
+        """Update from an event; whether the build state changed"""
+        with self._lock:
+            if name == 'file_changed':
+                self.last_change = {'file': data.get('file'), 'source': source, 'time': time.time()}
+                return False
+            if name == 'build_started':
+                self.state, self.started_at, self.finished_at, self.seconds = 'building', time.time(), None, None
+                self.trigger, self.errors = data.get('trigger'), []
+            elif name in ('build_finished', 'build_failed'):
+                self.state = 'ok' if name == 'build_finished' else 'failed'
+                self.finished_at, self.seconds = time.time(), data.get('seconds')
+                self.errors = [error for error in data.get('errors') or [] if isinstance(error, dict)][:MAX_ERRORS]
+            else:
+                return False
+            self.target, self.source = data.get('target') or self.target, source
+            return True
+
+    def to_dict(self) -> Dict[str, Any]:
+        with self._lock:
+            return {
+                'state': self.state,
+                'target': self.target,
+                'trigger': self.trigger,
+                'source': self.source,
+                'started_at': self.started_at,
+                'finished_at': self.finished_at,
+                'seconds': self.seconds,
+                'errors': list(self.errors),
+                'last_change': self.last_change
+            }
+
+class DevEvents:
+    """Turns watcher events into build state and page events"""
+
+    def __init__(self, app):
+        self.hub = get_reload_hub(app)
+        self.status = BuildStatus()
+        self.listener: Optional[DevEventListener] = None
+
+    def dispatch(self, name: str, data: Dict[str, Any], source: str):
+        if self.status.apply(name, data, source):
+            self.hub.broadcast('build', self.status.to_dict())
+        if name == 'file_changed':
+            self.hub.broadcast('reload', {'file': data.get('file')})
+
+    def start(self, project):
+        self.listener = DevEventListener(project, self.dispatch)
+        try:
+            self.listener.start()
+        except OSError as e:
+            click.echo(f"⚠️  Watchers cannot report to the dev server: {str(e)}")
+            self.listener = None
+
+    def stop(self):
+        if self.listener:
+            self.listener.stop()
+
+def get_dev_events(app) -> DevEvents:
+    if 'DEV_EVENTS' not in app.config:
+        app.config['DEV_EVENTS'] = DevEvents(app)
+    return app.config['DEV_EVENTS']
+
+def register_dev_events(app, project):
+    """Listen for watcher events and register /__build"""
+    events = get_dev_events(app)
+    events.start(project)
+
+    @app.route('/__build')
+    def build_status():
+        """The latest build state, and where 'flashflow build --watch' connects"""
+        listener = events.listener
+        return jsonify(dict(events.status.to_dict(), listener={
+            'transport': listener.transport if listener else None,
+            'address': listener.address if listener else None,
+            'connections': listener.connections if listener else 0
+        }))
+
+    return events
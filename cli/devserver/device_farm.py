@@ -0,0 +1,707 @@
+"""
+FlashFlow device farm - every preview device side by side at /preview
+
+    /preview                     Android, iOS, tablet and desktop frames of the same page
+    /preview?devices=ios,desktop only some of them
+    /preview#/pricing            open at a route
+    /preview/page/<route>        one flow page as a frame shows it (?__device=ios to emulate a device)
+    /api/preview/pages           the flows' pages and devices
+    /api/preview/devices         device presets; PUT or DELETE /api/preview/devices/<name> edits devices.json
+
+Frames show flow pages rendered the way 'flashflow build -t static' renders
+them, with images from /media, so no build or engine is needed. Following
+a link in one frame moves every frame to that route, unless sync is turned
+off. Reload (or a flow change, through live reload) reloads them all. Each
+frame reports its viewport, the page's width and height, load time and the
+elements that stick out past the viewport, which is usually what breaks a
+layout on a narrow screen.
+
+Each frame emulates its device (see core/device_presets.py): the viewport
+meta the device lays the page out with, navigator.userAgent,
+devicePixelRatio, and for touch devices maxTouchPoints, pointer and hover
+media queries in matchMedia, and touch events fired for mouse input. Scripts
+see the device; CSS media queries still see the browser the farm runs in.
+
+Pages built on live data are rendered without their rows.
+"""
+
+import json
+import re
+from typing import Dict, Any, List, Optional
+
+from flask import Response, render_template_string, jsonify, request
+
+from core.device_presets import BUILTIN_PRESETS, PRESETS_FILE, DevicePreset, DevicePresetError, DevicePresets
+from core.html_safety import escape_html
+from core.media import MediaError
+from core.parser.parser import FlowParser
+from core.social_cards import card_path, cards_available
+from core.static_site import ROUTE_PARAMETER, StaticPage, StaticSiteExporter, uses_live_data
+from cli.devserver.audit import audit_admin
+from cli.devserver.media import get_media_library
+
+PAGE_PREFIX = '/preview/page'
+DEVICE_PARAM = '__device'
+VIEWPORT_META = '<meta name="viewport" content="width=device-width, initial-scale=1">'
+
+class PreviewRenderer(StaticSiteExporter):
+    """Renders single pages for the frames, with assets served by the dev server"""
+
+    def __init__(self, project, ir, library):
+        super().__init__(project, ir)
+        self.library = library
+        pages = [StaticPage(route, data) for route, data in sorted(ir.pages.items()) if isinstance(data, dict)]
+        self._links = self._nav_links(pages)
+        self._pages = {page.url_path for page in pages}
+
+    def _media(self, src: str) -> Dict[str, Any]:
+        try:
+            return self.library.describe(src)
+        except MediaError:
+            return {'src': src}
+
+    def stylesheet(self) -> str:
+        return self._stylesheet()
+
+    def card_url(self, page: StaticPage) -> Optional[str]:
+        # Drawn when a crawler asks for it (cli/devserver/social.py)
+        return card_path(page.route) if self.social_cards and cards_available() else None
+
+    def find(self, path: str) -> Optional[StaticPage]:
+        """The page a URL path shows, matching route parameters such as /orders/{id}"""
+        path = '/' + path.strip('/')
+        for route, data in sorted(self.ir.pages.items()):
+            if isinstance(data, dict) and route_pattern(route).fullmatch(path):
+                return StaticPage(path, data)
+        return None
+
+    def frame(self, page: StaticPage, missing: bool = False, device: Optional[DevicePreset] = None) -> str:
+        html = self.render_page(page, '/preview/site.css')
+        problems = self.compile(page)[1]
+        if problems:
+            # Shown in the frame, so a misspelled component or prop is seen where it would have rendered
+            items = ''.join(f"<li>{escape_html(str(problem))}</li>" for problem in problems)
+            html = html.replace('<main>', f'<main>\n<aside class="flashflow-problems" style="border: 2px solid #dc2626; '
+                                f'border-radius: 8px; padding: 0.5rem 1rem; margin-bottom: 1rem; background: #fef2f2">'
+                                f'<strong>This page has problems</strong><ul>{items}</ul></aside>', 1)
+        if missing:
+            html = html.replace('<head>', '<head>\n    <meta name="flashflow-status" content="404">', 1)
+        if device is not None:
+            html = emulate(html, device)
+        return html.replace('</body>', '<script src="/preview/frame.js"></script>\n</body>', 1)
+
+def emulate(html: str, device: DevicePreset) -> str:
+    """A frame's HTML set up as the device would show it; the emulation script runs before any of the page's own"""
+    html = html.replace(VIEWPORT_META, f'<meta name="viewport" content="{device.viewport_meta}">', 1)
+    # An attribute rather than an inline script, which the dev server's CSP would report
+    return html.replace('<head>', f'<head>\n    <script src="/preview/emulation.js" '
+                                  f'data-device="{escape_html(json.dumps(device.to_dict()))}"></script>', 1)
+
+def route_pattern(route: str):
+    parts = ROUTE_PARAMETER.split('/' + route.strip('/'))
+    return re.compile('[^/]+'.join(re.escape(part) for part in parts) + '/?')
+
+def get_renderer(app) -> PreviewRenderer:
+    """A renderer over the flows as they are now; previews always show the latest edit"""
+    project = app.config['PROJECT']
+    return PreviewRenderer(project, FlowParser().parse_project(project.root_path), get_media_library(app))
+
+def get_device_presets(app) -> DevicePresets:
+    if 'DEVICE_PRESETS' not in app.config:
+        app.config['DEVICE_PRESETS'] = DevicePresets(app.config['PROJECT'].root_path)
+    return app.config['DEVICE_PRESETS']
+
+def selected_devices(presets: Dict[str, DevicePreset], names: Optional[str]) -> Dict[str, Dict[str, Any]]:
+    devices = {name: preset.to_dict() for name, preset in presets.items()}
+    if not names:
+        return devices
+    chosen = {name: devices[name] for name in (part.strip().lower() for part in names.split(',')) if name in devices}
+    return chosen or devices
+
+def register_device_farm(app, injected: Optional[List[str]] = None):
+    """Register /preview, its frames and /api/preview/pages
+
+    injected is HTML appended to the farm page itself (live reload, diagnostics).
+    """
+
+    def presets_or_builtin():
+        """Every preset, or the built-in ones and why when devices.json is broken"""
+        try:
+            return get_device_presets(app).all(), None
+        except DevicePresetError as e:
+            return {name: DevicePreset.from_definition(name, values, source='builtin')
+                    for name, values in BUILTIN_PRESETS.items()}, str(e)
+
+    @app.route('/preview')
+    def device_farm():
+        project = app.config['PROJECT']
+        presets, error = presets_or_builtin()
+        return render_template_string(DEVICE_FARM_TEMPLATE, project_name=project.config.name,
+                                      devices=selected_devices(presets, request.args.get('devices')),
+                                      presets_error=error, injected='\n'.join(injected or []))
+
+    @app.route(PAGE_PREFIX + '/', defaults={'path': ''})
+    @app.route(PAGE_PREFIX + '/<path:path>')
+    def preview_page(path):
+        renderer = get_renderer(app)
+        device = presets_or_builtin()[0].get(request.args.get(DEVICE_PARAM, ''))
+        page = renderer.find(path)
+        if page is None:
+            page = StaticPage('/' + path.strip('/'), {
+                'title': 'No page here',
+                'body': [{'component': 'text', 'content': f"No flow page has the route /{path.strip('/')}."}]
+            })
+            return renderer.frame(page, missing=True, device=device), 404
+        return renderer.frame(page, device=device)
+
+    @app.route('/preview/site.css')
+    def preview_stylesheet():
+        return Response(get_renderer(app).stylesheet(), mimetype='text/css', headers={'Cache-Control': 'no-cache'})
+
+    @app.route('/preview/frame.js')
+    def preview_frame_script():
+        return Response(FRAME_SCRIPT, mimetype='application/javascript', headers={'Cache-Control': 'no-cache'})
+
+    @app.route('/preview/emulation.js')
+    def preview_emulation_script():
+        return Response(EMULATION_SCRIPT, mimetype='application/javascript', headers={'Cache-Control': 'no-cache'})
+
+    @app.route('/preview/device-farm.js')
+    def preview_farm_script():
+        return Response(DEVICE_FARM_SCRIPT, mimetype='application/javascript', headers={'Cache-Control': 'no-cache'})
+
+    @app.route('/api/preview/pages')
+    def preview_pages():
+        ir = FlowParser().parse_project(app.config['PROJECT'].root_path)
+        pages = []
+        for route, data in sorted(ir.pages.items()):
+            if not isinstance(data, dict):
+                continue
+            pages.append({
+                'route': route,
+                'title': data.get('title') or route,
+                'parameters': bool(ROUTE_PARAMETER.search(route)),
+                'live_data': uses_live_data(data.get('body') or [])
+            })
+        return jsonify({'pages': pages, 'devices': {name: preset.to_dict() for name, preset in presets_or_builtin()[0].items()}})
+
+    @app.route('/api/preview/devices')
+    def preview_devices():
+        presets, error = presets_or_builtin()
+        return jsonify({'devices': [preset.to_dict() for preset in presets.values()], 'file': PRESETS_FILE, 'error': error})
+
+    @app.route('/api/preview/devices/<name>', methods=['PUT'])
+    def preview_device_save(name):
+        presets = get_device_presets(app)
+        try:
+            old = presets.get(name)
+            preset = presets.save(name, request.get_json(silent=True))
+        except DevicePresetError as e:
+            return jsonify({'error': str(e)}), 400
+        audit_admin(app, 'device.save', 'device_preset', name, old.definition() if old else None, preset.definition())
+        return jsonify(preset.to_dict())
+
+    @app.route('/api/preview/devices/<name>', methods=['DELETE'])
+    def preview_device_remove(name):
+        presets = get_device_presets(app)
+        try:
+            old = presets.get(name)
+            removed = presets.remove(name)
+        except DevicePresetError as e:
+            return jsonify({'error': str(e)}), 400
+        if not removed:
+            return jsonify({'error': f"'{name}' is not a preset in {PRESETS_FILE}"}), 404
+        audit_admin(app, 'device.remove', 'device_preset', name, old.definition() if old else None, None)
+        return '', 204
+
+# Loaded by every frame: reports metrics and failed requests to the farm and hands link clicks to it
+FRAME_SCRIPT = """
+(function () {
+    var PREFIX = '/preview/page';
+    var DEVICE_PARAM = '__device';
+    var inFarm = window.parent !== window;
+    var started = performance.now();
+    var device = new URLSearchParams(location.search).get(DEVICE_PARAM);
+
+    function routeOf(url) {
+        var path = url.pathname.indexOf(PREFIX) === 0 ? url.pathname.slice(PREFIX.length) || '/' : url.pathname;
+        var params = new URLSearchParams(url.search);
+        params.delete(DEVICE_PARAM);
+        var search = params.toString();
+        return path + (search ? '?' + search : '') + url.hash;
+    }
+
+    function withDevice(route) {
+        if (!device) return route;
+        var hashAt = route.indexOf('#');
+        var path = hashAt >= 0 ? route.slice(0, hashAt) : route;
+        return path + (path.indexOf('?') >= 0 ? '&' : '?') + DEVICE_PARAM + '=' + encodeURIComponent(device)
+            + (hashAt >= 0 ? route.slice(hashAt) : '');
+    }
+
+    function post(message) {
+        message.type = 'flashflow-preview';
+        window.parent.postMessage(message, location.origin);
+    }
+
+    function describe(element) {
+        var name = element.tagName.toLowerCase();
+        if (element.id) return name + '#' + element.id;
+        var classes = (element.getAttribute('class') || '').trim().split(/\\s+/).filter(Boolean);
+        return name + (classes.length ? '.' + classes.slice(0, 2).join('.') : '');
+    }
+
+    function overflowing() {
+        var found = [];
+        var width = document.documentElement.clientWidth;
+        var elements = document.body.getElementsByTagName('*');
+        for (var i = 0; i < elements.length && found.length < 5; i++) {
+            var box = elements[i].getBoundingClientRect();
+            // Only the outermost offender; its children stick out with it
+            if (box.width && box.right > width + 1 && !found.some(function (entry) { return entry.element.contains(elements[i]); })) {
+                found.push({element: elements[i], selector: describe(elements[i]), right: Math.round(box.right)});
+            }
+        }
+        return found.map(function (entry) { return {selector: entry.selector, right: entry.right}; });
+    }
+
+    function report() {
+        if (!inFarm) return;
+        var navigation = performance.getEntriesByType && performance.getEntriesByType('navigation')[0];
+        post({
+            event: 'metrics',
+            route: routeOf(location),
+            title: document.title,
+            status: document.querySelector('meta[name="flashflow-status"]') ? 404 : 200,
+            viewport: [window.innerWidth, window.innerHeight],
+            dpr: window.devicePixelRatio,
+            content: [document.documentElement.scrollWidth, document.documentElement.scrollHeight],
+            load_ms: Math.round(navigation && navigation.duration ? navigation.duration : performance.now() - started),
+            overflow: overflowing()
+        });
+    }
+
+    document.addEventListener('click', function (event) {
+        var link = event.target.closest && event.target.closest('a[href]');
+        if (!link || event.defaultPrevented || event.button !== 0 || event.metaKey || event.ctrlKey || event.shiftKey) return;
+        var url = new URL(link.getAttribute('href'), location.href);
+        if (url.origin !== location.origin || link.getAttribute('href').charAt(0) === '#') return;
+        event.preventDefault();
+        if (inFarm) {
+            post({event: 'navigate', route: routeOf(url)});
+        } else {
+            location.href = PREFIX + withDevice(routeOf(url));
+        }
+    }, true);
+
+    // Failed API calls show up in the farm's error overlay with their request id
+    if (inFarm && window.fetch) {
+        var originalFetch = window.fetch;
+        window.fetch = function (input, init) {
+            return originalFetch.apply(this, arguments).then(function (response) {
+                if (response.status >= 500) {
+                    window.parent.postMessage({
+                        type: 'flashflow-request-error',
+                        method: ((init && init.method) || (input && input.method) || 'GET').toUpperCase(),
+                        url: typeof input === 'string' ? input : (input && input.url) || String(input),
+                        status: response.status,
+                        request_id: response.headers.get('X-Request-ID'),
+                        source: routeOf(location)
+                    }, location.origin);
+                }
+                return response;
+            });
+        };
+    }
+
+    window.addEventListener('load', report);
+    window.addEventListener('resize', report);
+})();
+"""
+
+# Loaded first in an emulating frame: makes scripts see the device, and mouse input arrive as touches on touch devices
+EMULATION_SCRIPT = """
+(function () {
+    var script = document.currentScript;
+    var device = script && JSON.parse(script.getAttribute('data-device') || 'null');
+    if (!device) return;
+    var TRUE_QUERY = '(min-width: 0px)';
+    var FALSE_QUERY = '(max-width: 0px)';
+
+    function define(target, name, value) {
+        try {
+            Object.defineProperty(target, name, {get: function () { return value; }, configurable: true});
+        } catch (e) {}
+    }
+
+    if (device.user_agent) {
+        define(navigator, 'userAgent', device.user_agent);
+        define(navigator, 'appVersion', device.user_agent.replace(/^Mozilla\\//, ''));
+        define(navigator, 'platform', /iPhone|iPad/.test(device.user_agent) ? 'iPhone'
+            : /Android/.test(device.user_agent) ? 'Linux armv8l' : navigator.platform);
+    }
+    define(window, 'devicePixelRatio', device.dpr);
+    define(navigator, 'maxTouchPoints', device.touch ? 5 : 0);
+
+    // Input and resolution features answer as the device would; width and height are already the frame's
+    var originalMatchMedia = window.matchMedia;
+    window.matchMedia = function (query) {
+        var rewritten = String(query)
+            .replace(/\\(\\s*(any-)?pointer\\s*:\\s*(coarse|fine|none)\\s*\\)/gi, function (match, any, value) {
+                return value.toLowerCase() === (device.touch ? 'coarse' : 'fine') ? TRUE_QUERY : FALSE_QUERY;
+            })
+            .replace(/\\(\\s*(any-)?hover\\s*:\\s*(hover|none)\\s*\\)/gi, function (match, any, value) {
+                return value.toLowerCase() === (device.touch ? 'none' : 'hover') ? TRUE_QUERY : FALSE_QUERY;
+            })
+            .replace(/\\(\\s*(-webkit-)?(min-|max-)?(device-pixel-ratio|resolution)\\s*:\\s*([\\d.]+)(dppx|x|dpi)?\\s*\\)/gi,
+                function (match, prefix, bound, feature, number, unit) {
+                    var ratio = parseFloat(number) / (unit && unit.toLowerCase() === 'dpi' ? 96 : 1);
+                    var holds = bound === 'min-' ? device.dpr >= ratio : bound === 'max-' ? device.dpr <= ratio
+                        : Math.abs(device.dpr - ratio) < 0.01;
+                    return holds ? TRUE_QUERY : FALSE_QUERY;
+                });
+        return originalMatchMedia.call(window, rewritten);
+    };
+
+    if (!device.touch) return;
+    if (!('ontouchstart' in window)) window.ontouchstart = null;
+    document.documentElement.style.cursor = 'url("data:image/svg+xml,%3Csvg xmlns=\\'http://www.w3.org/2000/svg\\' width=\\'24\\' height=\\'24\\'%3E%3Ccircle cx=\\'12\\' cy=\\'12\\' r=\\'10\\' fill=\\'rgba(0,0,0,0.25)\\' stroke=\\'white\\'/%3E%3C/svg%3E") 12 12, auto';
+
+    var target = null;
+
+    function touchEvent(type, mouse) {
+        var point = {identifier: 1, target: target, clientX: mouse.clientX, clientY: mouse.clientY, pageX: mouse.pageX,
+                     pageY: mouse.pageY, screenX: mouse.screenX, screenY: mouse.screenY, radiusX: 11.5, radiusY: 11.5, force: 1};
+        var touching = type === 'touchend' ? [] : null;
+        try {
+            var touch = new Touch(point);
+            return new TouchEvent(type, {bubbles: true, cancelable: true, composed: true, touches: touching || [touch],
+                                         targetTouches: touching || [touch], changedTouches: [touch]});
+        } catch (e) {
+            // No Touch constructor (Firefox, Safari on a desktop): a plain event with the same lists
+            var event = new Event(type, {bubbles: true, cancelable: true, composed: true});
+            event.touches = event.targetTouches = touching || [point];
+            event.changedTouches = [point];
+            return event;
+        }
+    }
+
+    document.addEventListener('mousedown', function (event) {
+        if (event.button !== 0) return;
+        target = event.target;
+        if (!target.dispatchEvent(touchEvent('touchstart', event))) event.preventDefault();
+    }, true);
+    document.addEventListener('mousemove', function (event) {
+        if (target) target.dispatchEvent(touchEvent('touchmove', event));
+    }, true);
+    document.addEventListener('mouseup', function (event) {
+        if (!target) return;
+        var touched = target;
+        target = null;
+        touched.dispatchEvent(touchEvent('touchend', event));
+    }, true);
+})();
+"""
+
+DEVICE_FARM_SCRIPT = """
+(function () {
+    var farm = document.getElementById('farm');
+    var devices = JSON.parse(farm.getAttribute('data-devices'));
+    var routeInput = document.getElementById('route');
+    var pageSelect = document.getElementById('pages');
+    var syncBox = document.getElementById('sync');
+    var zoomSelect = document.getElementById('zoom');
+    var frames = {};
+
+    function escapeHtml(text) {
+        var div = document.createElement('div');
+        div.textContent = text == null ? '' : String(text);
+        return div.innerHTML;
+    }
+
+    function currentRoute() {
+        var hash = decodeURIComponent(location.hash.slice(1));
+        return hash.charAt(0) === '/' ? hash : '/';
+    }
+
+    function frameUrl(route, name) {
+        // The device goes in the query, before any #fragment of the route
+        var parts = route.split('#');
+        var url = '/preview/page' + (parts[0] === '/' ? '/' : parts[0]);
+        url += (url.indexOf('?') === -1 ? '?' : '&') + '__device=' + encodeURIComponent(name);
+        return url + (parts.length > 1 ? '#' + parts.slice(1).join('#') : '');
+    }
+
+    function scaleFor(device) {
+        var zoom = zoomSelect.value;
+        if (zoom !== 'fit') return parseFloat(zoom);
+        // Every frame the same height, so pages line up across devices
+        return Math.min(1, 620 / device.height);
+    }
+
+    function layout() {
+        Object.keys(frames).forEach(function (name) {
+            var frame = frames[name];
+            var scale = scaleFor(frame.device);
+            frame.viewport.style.width = Math.round(frame.device.width * scale) + 'px';
+            frame.viewport.style.height = Math.round(frame.device.height * scale) + 'px';
+            frame.iframe.style.transform = 'scale(' + scale + ')';
+            frame.scale.textContent = Math.round(scale * 100) + '%';
+        });
+    }
+
+    function navigate(route, only) {
+        Object.keys(frames).forEach(function (name) {
+            if (only && only !== name) return;
+            frames[name].metrics.innerHTML = '<span class="muted">Loading ' + escapeHtml(route) + '...</span>';
+            frames[name].iframe.src = frameUrl(route, name);
+        });
+        if (!only) {
+            routeInput.value = route;
+            history.replaceState(null, '', '#' + route);
+        }
+    }
+
+    function reloadAll() {
+        Object.keys(frames).forEach(function (name) {
+            try {
+                frames[name].iframe.contentWindow.location.reload();
+            } catch (e) {
+                frames[name].iframe.src = frames[name].iframe.src;
+            }
+        });
+    }
+
+    function showMetrics(frame, data) {
+        var device = frame.device;
+        var overflowX = data.content[0] > data.viewport[0];
+        var rows = [
+            ['Route', escapeHtml(data.route) + (data.status === 404 ? ' <span class="bad">no page</span>' : '')],
+            ['Viewport', data.viewport[0] + ' × ' + data.viewport[1] + ' CSS px'
+                + (device.dpr !== 1 ? ' <span class="muted">(@' + device.dpr + 'x on device)</span>' : '')],
+            ['Page', data.content[0] + ' × ' + data.content[1]
+                + (overflowX ? ' <span class="bad">scrolls sideways</span>' : ' <span class="good">fits</span>')],
+            ['Load', data.load_ms + ' ms']
+        ];
+        var html = '<table>' + rows.map(function (row) {
+            return '<tr><th>' + row[0] + '</th><td>' + row[1] + '</td></tr>';
+        }).join('') + '</table>';
+        if (data.overflow.length) {
+            html += '<div class="bad">Past the edge: ' + data.overflow.map(function (entry) {
+                return '<code>' + escapeHtml(entry.selector) + '</code> (' + entry.right + 'px)';
+            }).join(', ') + '</div>';
+        }
+        frame.metrics.innerHTML = html;
+        frame.card.classList.toggle('overflowing', overflowX);
+    }
+
+    Object.keys(devices).forEach(function (name) {
+        var device = devices[name];
+        var card = document.createElement('div');
+        card.className = 'device';
+        card.innerHTML = '<div class="device-header"><strong>' + device.icon + ' ' + escapeHtml(device.label) + '</strong>'
+            + (device.touch ? ' <span class="badge">touch</span>' : '')
+            + '<span class="muted" title="' + escapeHtml(device.user_agent || 'The browser\\'s own user agent') + '">'
+            + device.width + ' × ' + device.height + ' @' + device.dpr + 'x · <span class="scale"></span></span></div>'
+            + '<div class="viewport"><iframe title="' + escapeHtml(device.label) + ' preview"></iframe></div>'
+            + '<div class="metrics"></div>';
+        farm.appendChild(card);
+        var iframe = card.querySelector('iframe');
+        iframe.style.width = device.width + 'px';
+        iframe.style.height = device.height + 'px';
+        frames[name] = {device: device, card: card, iframe: iframe, viewport: card.querySelector('.viewport'),
+                        metrics: card.querySelector('.metrics'), scale: card.querySelector('.scale')};
+    });
+
+    window.addEventListener('message', function (event) {
+        var data = event.data;
+        if (event.origin !== location.origin || !data || data.type !== 'flashflow-preview') return;
+        var name = Object.keys(frames).filter(function (key) {
+            return frames[key].iframe.contentWindow === event.source;
+        })[0];
+        if (!name) return;
+        if (data.event === 'navigate') {
+            navigate(data.route, syncBox.checked ? null : name);
+        } else if (data.event === 'metrics') {
+            showMetrics(frames[name], data);
+        }
+    });
+
+    document.getElementById('go').onclick = function () {
+        var route = routeInput.value.trim() || '/';
+        navigate(route.charAt(0) === '/' ? route : '/' + route);
+    };
+    routeInput.addEventListener('keydown', function (event) {
+        if (event.key === 'Enter') document.getElementById('go').click();
+    });
+    pageSelect.onchange = function () {
+        if (pageSelect.value) navigate(pageSelect.value);
+        pageSelect.value = '';
+    };
+    document.getElementById('reload').onclick = reloadAll;
+    document.getElementById('devices').onclick = function () {
+        var panel = document.getElementById('presets');
+        panel.hidden = !panel.hidden;
+        if (!panel.hidden) loadPresets();
+    };
+    zoomSelect.onchange = layout;
+    window.addEventListener('hashchange', function () {
+        if (currentRoute() !== routeInput.value) navigate(currentRoute());
+    });
+
+    fetch('/api/preview/pages').then(function (response) { return response.json(); }).then(function (data) {
+        data.pages.forEach(function (page) {
+            var option = document.createElement('option');
+            option.value = page.parameters ? '' : page.route;
+            option.disabled = page.parameters;
+            option.textContent = page.route + ' · ' + page.title + (page.live_data ? ' (live data)' : '')
+                + (page.parameters ? ' (type a concrete URL)' : '');
+            pageSelect.appendChild(option);
+        });
+    });
+
+    // The presets editor: devices.json through /api/preview/devices
+    var presetForm = document.getElementById('preset-form');
+    var presetError = document.getElementById('preset-error');
+
+    function loadPresets() {
+        fetch('/api/preview/devices').then(function (response) { return response.json(); }).then(function (data) {
+            var rows = data.devices.map(function (preset) {
+                var removable = preset.source !== 'builtin';
+                return '<tr><td>' + preset.icon + ' <code>' + escapeHtml(preset.name) + '</code></td>'
+                    + '<td>' + escapeHtml(preset.label) + '</td>'
+                    + '<td>' + preset.width + ' × ' + preset.height + ' @' + preset.dpr + 'x</td>'
+                    + '<td>' + (preset.touch ? 'touch' : '') + (preset.mobile ? ' mobile' : '') + '</td>'
+                    + '<td class="muted">' + escapeHtml(preset.source) + '</td>'
+                    + '<td><button type="button" class="secondary" data-edit="' + escapeHtml(preset.name) + '">Edit</button>'
+                    + (removable ? ' <button type="button" class="danger" data-remove="' + escapeHtml(preset.name) + '">'
+                        + (preset.source === 'override' ? 'Reset' : 'Delete') + '</button>' : '')
+                    + '</td></tr>';
+            });
+            var table = document.getElementById('preset-list');
+            table.innerHTML = rows.join('');
+            table.querySelectorAll('[data-edit]').forEach(function (button) {
+                button.onclick = function () {
+                    var preset = data.devices.filter(function (p) { return p.name === button.getAttribute('data-edit'); })[0];
+                    ['name', 'label', 'width', 'height', 'dpr', 'user_agent'].forEach(function (field) {
+                        presetForm.elements[field].value = preset[field];
+                    });
+                    presetForm.elements.touch.checked = preset.touch;
+                    presetForm.elements.mobile.checked = preset.mobile;
+                };
+            });
+            table.querySelectorAll('[data-remove]').forEach(function (button) {
+                button.onclick = function () {
+                    fetch('/api/preview/devices/' + encodeURIComponent(button.getAttribute('data-remove')), {method: 'DELETE'})
+                        .then(function (response) { return response.ok ? location.reload() : response.json().then(showPresetError); });
+                };
+            });
+            presetError.textContent = data.error || '';
+        });
+    }
+
+    function showPresetError(data) {
+        presetError.textContent = data.error || 'The preset could not be saved';
+    }
+
+    presetForm.addEventListener('submit', function (event) {
+        event.preventDefault();
+        var elements = presetForm.elements;
+        var preset = {label: elements.label.value || elements.name.value, width: parseInt(elements.width.value, 10),
+                      height: parseInt(elements.height.value, 10), dpr: parseFloat(elements.dpr.value || '1'),
+                      user_agent: elements.user_agent.value, touch: elements.touch.checked, mobile: elements.mobile.checked};
+        fetch('/api/preview/devices/' + encodeURIComponent(elements.name.value.trim()), {
+            method: 'PUT', headers: {'Content-Type': 'application/json'}, body: JSON.stringify(preset)
+        }).then(function (response) { return response.ok ? location.reload() : response.json().then(showPresetError); });
+    });
+
+    layout();
+    navigate(currentRoute());
+})();
+"""
+
+DEVICE_FARM_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Device Farm - {{ project_name }}</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+        .toolbar { display: flex; flex-wrap: wrap; gap: 0.75rem; align-items: center; padding: 1rem 2rem; background: white; box-shadow: 0 2px 4px rgba(0,0,0,0.1); position: sticky; top: 0; z-index: 10; }
+        .toolbar input[type=text] { padding: 0.4rem; width: 18rem; font-family: monospace; }
+        button { background: #3B82F6; color: white; border: none; padding: 0.4rem 0.9rem; border-radius: 4px; cursor: pointer; }
+        select { padding: 0.35rem; }
+        #farm { display: flex; flex-wrap: wrap; gap: 1.5rem; align-items: flex-start; padding: 1.5rem 2rem; }
+        .device { background: white; padding: 1rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); border-top: 4px solid #10B981; }
+        .device.overflowing { border-top-color: #ef4444; }
+        .device-header { display: flex; justify-content: space-between; gap: 1rem; margin-bottom: 0.5rem; }
+        .viewport { overflow: hidden; border: 1px solid #d1d5db; border-radius: 6px; background: white; }
+        .viewport iframe { border: none; transform-origin: 0 0; display: block; }
+        .metrics { font-size: 0.8rem; margin-top: 0.5rem; max-width: 100%; }
+        .metrics table { border-collapse: collapse; }
+        .metrics th { text-align: left; padding: 0.1rem 0.75rem 0.1rem 0; color: #6b7280; font-weight: normal; }
+        .muted { color: #6b7280; }
+        .good { color: #166534; }
+        .bad { color: #b91c1c; }
+        .badge { background: #e0e7ff; color: #3730a3; border-radius: 999px; padding: 0 0.5rem; font-size: 0.75rem; }
+        button.secondary { background: #6b7280; }
+        button.danger { background: #ef4444; }
+        .notice { background: #fef2f2; color: #b91c1c; padding: 0.75rem 2rem; }
+        #presets { background: white; margin: 1rem 2rem 0; padding: 1rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); }
+        #presets table { border-collapse: collapse; margin-bottom: 1rem; }
+        #presets td { padding: 0.25rem 0.75rem 0.25rem 0; }
+        #preset-form { display: flex; flex-wrap: wrap; gap: 0.5rem; align-items: center; }
+        #preset-form input[type=text], #preset-form input[type=number] { padding: 0.35rem; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>👁️ Device Farm</h1>
+        <p>{{ project_name }} · The same page on every device; following a link moves them all</p>
+    </div>
+    <div class="toolbar">
+        <input type="text" id="route" value="/" aria-label="Route">
+        <button id="go">Go</button>
+        <select id="pages" aria-label="Pages"><option value="">Pages...</option></select>
+        <button id="reload">🔄 Reload all</button>
+        <button id="devices" class="secondary">📱 Devices</button>
+        <label><input type="checkbox" id="sync" checked> Sync navigation</label>
+        <label>Zoom
+            <select id="zoom">
+                <option value="fit">Fit</option>
+                <option value="0.5">50%</option>
+                <option value="0.75">75%</option>
+                <option value="1">100%</option>
+            </select>
+        </label>
+        <a href="/">← Dashboard</a>
+    </div>
+    {% if presets_error %}
+    <div class="notice">{{ presets_error }} · showing the built-in devices</div>
+    {% endif %}
+    <div id="presets" hidden>
+        <p class="muted">Presets are kept in devices.json at the project root; commit it to share them with the team.
+            A preset named like a built-in one overrides it.</p>
+        <table><tbody id="preset-list"></tbody></table>
+        <form id="preset-form">
+            <input type="text" name="name" placeholder="name, e.g. pixel-8" required pattern="[a-z0-9][a-z0-9_-]{0,31}">
+            <input type="text" name="label" placeholder="Label">
+            <input type="number" name="width" placeholder="Width" min="120" max="7680" required>
+            <input type="number" name="height" placeholder="Height" min="120" max="7680" required>
+            <input type="number" name="dpr" placeholder="DPR" min="0.5" max="5" step="any">
+            <input type="text" name="user_agent" placeholder="User agent (empty: the browser's)" size="40">
+            <label><input type="checkbox" name="touch"> Touch</label>
+            <label><input type="checkbox" name="mobile"> Mobile viewport</label>
+            <button type="submit">Save preset</button>
+        </form>
+        <div id="preset-error" class="bad"></div>
+    </div>
+    <div id="farm" data-devices="{{ devices | tojson | forceescape }}"></div>
+    <script src="/preview/device-farm.js"></script>
+    {{ injected | safe }}
+</body>
+</html>
+"""
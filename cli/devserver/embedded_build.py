@@ -0,0 +1,100 @@
+"""
+FlashFlow embedded build - dist/web when no build service is installed
+
+Without a build service (see cli/utils/go_services.py for where it is looked
+for) the server builds dist/web itself with core/minimal_build.py: once at
+startup and again after every flow or content change, writing only the
+pages the change affects (see core/dependency_graph.py). Builds run on a
+background thread, one at a time; changes during a build start one more
+when it ends. Their progress reaches pages as 'build' events (see
+dev_events.py), and the result is served at /web/.
+"""
+
+import threading
+import time
+from typing import Any, Dict, Optional
+
+import click
+from flask import abort, send_from_directory
+
+from core.minimal_build import MinimalBuildError, MinimalBuilder
+from cli.devserver.dev_events import get_dev_events
+from cli.utils.go_services import verified_service_binary
+
+TARGET = 'web'
+
+class EmbeddedBuild:
+    """Runs the minimal builder for the dev server"""
+
+    def __init__(self, app, project):
+        self.app = app
+        self.builder = MinimalBuilder(project)
+        self.last_report: Optional[Dict[str, Any]] = None
+        self._running = False
+        self._again: Optional[str] = None
+        self._lock = threading.Lock()
+
+    def schedule(self, trigger: Optional[str] = None):
+        """Build on a background thread, or once more after the build that is running"""
+        with self._lock:
+            if self._running:
+                self._again = trigger or 'change'
+                return
+            self._running = True
+        threading.Thread(target=self._loop, args=(trigger,), daemon=True, name='flashflow-embedded-build').start()
+
+    def _loop(self, trigger: Optional[str]):
+        while True:
+            self.run(trigger)
+            with self._lock:
+                if self._again is None:
+                    self._running = False
+                    return
+                trigger, self._again = self._again, None
+
+    def run(self, trigger: Optional[str] = None) -> Dict[str, Any]:
+        events = get_dev_events(self.app)
+        events.dispatch('build_started', {'target': TARGET, 'trigger': trigger}, 'serve')
+        started = time.time()
+        try:
+            report = self.builder.build()
+        except MinimalBuildError as e:
+            report = {'status': 'failed', 'errors': [{'message': str(e)}]}
+        seconds = round(time.time() - started, 2)
+        self.last_report = report
+        if report['status'] == 'failed':
+            events.dispatch('build_failed', {'target': TARGET, 'seconds': seconds, 'errors': report['errors']}, 'serve')
+            click.echo(f"❌ {self.builder.display_path()} build failed: {report['errors'][0].get('message')}")
+        else:
+            events.dispatch('build_finished', {'target': TARGET, 'seconds': seconds}, 'serve')
+            if report['status'] == 'skipped':
+                click.echo(f"ℹ️  {report['reason']}")
+        return report
+
+def get_embedded_build(app) -> Optional[EmbeddedBuild]:
+    """The embedded build, or None when a build service does the building"""
+    return app.config.get('EMBEDDED_BUILD')
+
+def register_embedded_build(app, project, build: bool = True) -> Optional[EmbeddedBuild]:
+    """Build dist/web in process unless a build service is installed; serve it at /web/"""
+    output_path = project.dist_path / TARGET
+
+    @app.route('/web/')
+    @app.route('/web/<path:path>')
+    def web_build(path: str = ''):
+        if path == '' or path.endswith('/'):
+            path += 'index.html'
+        if not (output_path / path).is_file() and (output_path / path / 'index.html').is_file():
+            path += '/index.html'
+        if not output_path.exists():
+            abort(404)
+        return send_from_directory(str(output_path), path)
+
+    if verified_service_binary('build-service', project_root=project.root_path) is not None:
+        return None
+    embedded = EmbeddedBuild(app, project)
+    app.config['EMBEDDED_BUILD'] = embedded
+    click.echo(f"📦 No build service installed; building {embedded.builder.display_path()} in process")
+    if build:
+        embedded.schedule('startup')
+    return embedded
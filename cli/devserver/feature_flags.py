@@ -0,0 +1,177 @@
+"""
+FlashFlow dev server feature flags - /api/features and the /admin/features switches
+
+/api/features answers which flags are on for the profile picked with
+'serve --env' and the user asking: ?user=<id>, else the signed-in user's
+email (see cli/devserver/permissions.py). ?explain=1 adds every flag's rules
+and the one that decided it. /admin/features forces flags on or off for
+this dev server; the overrides are kept in .flashflow/features/overrides.json
+and open pages reload when one changes. flashflow.json changes restart the
+server, which picks up new flags and rules.
+"""
+
+from flask import request, jsonify, render_template_string
+
+from core.feature_flags import FeatureFlagError, FlagOverrides
+from cli.devserver.audit import audit_admin
+from cli.devserver.live_reload import get_reload_hub
+from cli.devserver.permissions import resolve_principal
+
+def get_flag_overrides(app) -> FlagOverrides:
+    if 'FLAG_OVERRIDES' not in app.config:
+        app.config['FLAG_OVERRIDES'] = FlagOverrides.for_project(app.config['PROJECT'])
+    return app.config['FLAG_OVERRIDES']
+
+def request_subject(app):
+    """Who the flags are evaluated for: ?user=, else the signed-in user, else nobody"""
+    if request.args.get('user'):
+        return request.args['user']
+    principal = resolve_principal(app)
+    return principal.email if principal else None
+
+def register_feature_flags(app):
+    """Register /api/features and /admin/features"""
+    overrides = get_flag_overrides(app)
+
+    def flags():
+        return app.config['PROFILE'].flags
+
+    @app.route('/api/features', methods=['GET'])
+    def api_features():
+        subject = request_subject(app)
+        current = overrides.snapshot()
+        data = {'env': flags().env, 'user': subject, 'features': flags().evaluate(subject, current)}
+        if request.args.get('explain') in ('1', 'true'):
+            data['flags'] = flags().explain(subject, current)
+        return jsonify(data)
+
+    @app.route('/api/features/<name>', methods=['PUT'])
+    def api_feature_override(name):
+        """Force a flag on or off in this dev server; {"enabled": null} goes back to its rules"""
+        data = request.get_json(silent=True)
+        if not isinstance(data, dict) or 'enabled' not in data or not isinstance(data['enabled'], (bool, type(None))):
+            return jsonify({'error': "Send {\"enabled\": true}, false, or null to clear the override"}), 400
+        if name not in flags().flags:
+            return jsonify({'error': str(FeatureFlagError(f"Unknown feature flag '{name}'"))}), 404
+        previous = overrides.snapshot().get(name)
+        overrides.set(name, data['enabled'])
+        audit_admin(app, 'flag.override', 'feature_flag', name, {'override': previous}, {'override': data['enabled']})
+        state = 'rules' if data['enabled'] is None else ('on' if data['enabled'] else 'off')
+        app.logger.warning(f"🚩 Feature {name}: {state}")
+        get_reload_hub(app).broadcast('reload', {'file': 'features'})
+        return jsonify({'name': name, 'override': data['enabled'],
+                        'enabled': flags().decide(name, request_subject(app), overrides.snapshot()).enabled})
+
+    @app.route('/admin/features')
+    def admin_features_page():
+        """Admin page listing the flags, with switches and a per-user check"""
+        project = app.config['PROJECT']
+        return render_template_string(FEATURES_ADMIN_TEMPLATE, project_name=project.config.name, env=flags().env)
+
+FEATURES_ADMIN_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Feature Flags - FlashFlow Admin</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+        .container { max-width: 1200px; margin: 0 auto; padding: 2rem; }
+        .panel { background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); margin-bottom: 1.5rem; }
+        table { width: 100%; border-collapse: collapse; }
+        th, td { text-align: left; padding: 0.5rem; border-bottom: 1px solid #e5e7eb; font-size: 0.9rem; vertical-align: top; }
+        input { padding: 0.35rem; border: 1px solid #d1d5db; border-radius: 4px; }
+        select { padding: 0.3rem; border: 1px solid #d1d5db; border-radius: 4px; }
+        .on { color: #15803d; font-weight: bold; } .off { color: #6b7280; font-weight: bold; }
+        .muted { color: #6b7280; }
+        code { background: #f3f4f6; padding: 0 0.25rem; border-radius: 3px; }
+        #error { color: #b91c1c; font-family: monospace; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>🚩 Feature Flags</h1>
+        <p>{{ project_name }} · Environment <strong>{{ env }}</strong> · Flags from "features" in flashflow.json</p>
+    </div>
+    <div class="container">
+        <div class="panel">
+            <label>Evaluate for user <input id="user" placeholder="anyone (no rollouts)"></label>
+            <p class="muted">Rollouts pick users by a stable hash of the flag and the user id, so a user keeps their answer
+                as long as the percentage does not drop. Overrides only apply to this dev server.</p>
+            <div id="error"></div>
+        </div>
+        <div class="panel">
+            <table>
+                <thead><tr><th>Flag</th><th>State</th><th>Decided by</th><th>Rules</th><th>Override</th></tr></thead>
+                <tbody id="flags"></tbody>
+            </table>
+        </div>
+        <p><a href="/">← Back to Main Dashboard</a></p>
+    </div>
+    <script>
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+
+        async function api(url, options) {
+            if (options && options.body) options.headers = {'Content-Type': 'application/json'};
+            const response = await fetch(url, options);
+            const data = await response.json();
+            if (!response.ok) throw new Error(data.error || response.statusText);
+            return data;
+        }
+
+        function rules(flag) {
+            const parts = ['default ' + (flag.default ? 'on' : 'off')];
+            if (flag.rollout != null) parts.push('rollout ' + flag.rollout + '%');
+            for (const [env, rule] of Object.entries(flag.environments)) {
+                parts.push(env + ': ' + ('enabled' in rule ? (rule.enabled ? 'on' : 'off') : rule.rollout + '%'));
+            }
+            if (flag.profile != null) parts.push('profile: ' + (flag.profile ? 'on' : 'off'));
+            return parts.map(part => '<code>' + escapeHtml(part) + '</code>').join(' ');
+        }
+
+        async function load() {
+            const user = document.getElementById('user').value.trim();
+            try {
+                const state = await api('/api/features?explain=1' + (user ? '&user=' + encodeURIComponent(user) : ''));
+                document.getElementById('flags').innerHTML = state.flags.map(flag => `<tr>
+                        <td><strong>${escapeHtml(flag.name)}</strong><br><span class="muted">${escapeHtml(flag.description)}</span></td>
+                        <td class="${flag.enabled ? 'on' : 'off'}">${flag.enabled ? 'ON' : 'off'}</td>
+                        <td class="muted">${escapeHtml(flag.reason)}</td>
+                        <td>${rules(flag)}</td>
+                        <td><select data-name="${escapeHtml(flag.name)}">
+                            <option value="" ${flag.override == null ? 'selected' : ''}>Follow rules</option>
+                            <option value="true" ${flag.override === true ? 'selected' : ''}>Force on</option>
+                            <option value="false" ${flag.override === false ? 'selected' : ''}>Force off</option>
+                        </select></td>
+                    </tr>`).join('') || '<tr><td colspan="5" class="muted">No flags; add a "features" block to flashflow.json</td></tr>';
+            } catch (e) {
+                document.getElementById('error').textContent = '❌ ' + e.message;
+            }
+        }
+
+        document.getElementById('flags').onchange = async event => {
+            const name = event.target.dataset.name;
+            if (!name) return;
+            const value = event.target.value;
+            document.getElementById('error').textContent = '';
+            try {
+                await api('/api/features/' + encodeURIComponent(name),
+                          {method: 'PUT', body: JSON.stringify({enabled: value === '' ? null : value === 'true'})});
+            } catch (e) {
+                document.getElementById('error').textContent = '❌ ' + e.message;
+            }
+            load();
+        };
+        document.getElementById('user').oninput = load;
+
+        load();
+    </script>
+</body>
+</html>
+"""
@@ -0,0 +1,424 @@
+"""
+FlashFlow flow editor - Edit .flow files in the browser at /edit
+
+    /edit                          the editor, on the first flow file
+    /edit?file=todos.flow          on a given file
+    /api/edit/files                the flow files, with their routes and mtimes
+    /api/edit/files/<name>         GET the content; PUT {content, mtime, force} saves it
+    /api/edit/check                POST {file, content}: diagnostics for a draft
+    /api/edit/preview              POST {file, content}: the draft's page as the device farm renders it
+
+YAML on the left is checked as you type with the same diagnostics as
+/api/diagnostics; the right pane renders the unsaved page. Saving writes the
+file atomically into src/flows and reloads open previews the way the file
+watcher does. A save is refused with 409 when the file changed on disk since
+it was loaded (mtime is what the editor last saw, null for a new file), and
+with 422 while the draft has errors, unless force is set.
+"""
+
+import hashlib
+import os
+import re
+from pathlib import Path
+from typing import Any, Dict, List
+
+from flask import Response, jsonify, render_template_string, request
+
+from core.framework import FlashFlowIR, FlashFlowProject
+from core.parser.diagnostics import diagnose_content, diagnostics_report
+from core.parser.flow_file import FlowParseError, load_flow
+from core.static_site import StaticPage
+from cli.devserver.audit import audit_admin
+from cli.devserver.dev_events import get_dev_events
+from cli.devserver.device_farm import PreviewRenderer, get_renderer
+from cli.devserver.media import get_media_library
+
+FLOW_NAME = re.compile(r'^[A-Za-z0-9][A-Za-z0-9_.-]*\.flow$')
+NEW_FLOW = """page:
+  path: /new-page
+  title: New page
+  body:
+    - component: header
+      content: New page
+"""
+
+class FlowEditorError(Exception):
+    """Raised for a flow file name the editor will not open or write"""
+    pass
+
+def flows_dir(project: FlashFlowProject) -> Path:
+    return project.root_path / 'src' / 'flows'
+
+def flow_path(project: FlashFlowProject, name: str) -> Path:
+    """The file for a name from the editor; only plain .flow names directly in src/flows"""
+    if not FLOW_NAME.match(name or '') or '..' in name:
+        raise FlowEditorError(f"'{name}' is not a flow file name; use e.g. 'orders.flow'")
+    return flows_dir(project) / name
+
+def mtime_of(path: Path):
+    try:
+        return path.stat().st_mtime
+    except OSError:
+        return None
+
+def list_flow_files(project: FlashFlowProject) -> List[Dict[str, Any]]:
+    files = []
+    for path in sorted(flows_dir(project).glob('*.flow')):
+        try:
+            route = load_flow(path, project.root_path).route
+        except (FlowParseError, OSError):
+            route = None
+        files.append({'file': path.name, 'route': route, 'mtime': mtime_of(path)})
+    return files
+
+def file_version(path: Path):
+    """A flow file as the audit log records it: its hash and size, not its text"""
+    try:
+        data = path.read_bytes()
+    except OSError:
+        return None
+    return {'sha256': hashlib.sha256(data).hexdigest(), 'bytes': len(data)}
+
+def save_flow(path: Path, content: str):
+    """Write through a temporary file, so the watcher and previews never see half a file"""
+    path.parent.mkdir(parents=True, exist_ok=True)
+    temporary = path.with_name(f".{path.name}.tmp")
+    temporary.write_text(content, encoding='utf-8')
+    os.replace(str(temporary), str(path))
+
+def render_draft(app, path: Path, content: str) -> str:
+    """The draft's page; other pages (for navigation) come from the files on disk"""
+    project = app.config['PROJECT']
+    try:
+        renderer = get_renderer(app)
+    except ValueError:
+        # Another file does not parse; render the draft on its own
+        renderer = PreviewRenderer(project, FlashFlowIR(), get_media_library(app))
+    try:
+        page = load_flow(path, project.root_path, content=content).page
+    except FlowParseError as e:
+        return renderer.frame(_notice('Does not parse', f"{e.problem} (line {e.line})" if e.line else e.problem), missing=True)
+    if page is None:
+        return renderer.frame(_notice(path.name, f"{path.name} declares no page; models and endpoints have no preview."))
+    return renderer.frame(StaticPage(page.path or '/', page.data))
+
+def _notice(title: str, text: str) -> StaticPage:
+    return StaticPage('/', {'title': title, 'body': [{'component': 'text', 'content': text}]})
+
+def register_flow_editor(app):
+    """Register /edit and its /api/edit endpoints"""
+    project: FlashFlowProject = app.config['PROJECT']
+
+    def draft():
+        data = request.get_json(silent=True) or {}
+        return flow_path(project, str(data.get('file', ''))), str(data.get('content', ''))
+
+    @app.errorhandler(FlowEditorError)
+    def flow_editor_error(e):
+        return jsonify({'error': str(e)}), 400
+
+    @app.route('/edit')
+    def flow_editor():
+        return render_template_string(FLOW_EDITOR_TEMPLATE, project_name=project.config.name,
+                                      file=request.args.get('file', ''), new_flow=NEW_FLOW)
+
+    @app.route('/api/edit/files')
+    def flow_editor_files():
+        return jsonify({'files': list_flow_files(project)})
+
+    @app.route('/api/edit/files/<name>', methods=['GET'])
+    def flow_editor_read(name):
+        path = flow_path(project, name)
+        if not path.is_file():
+            return jsonify({'error': f"{name} does not exist"}), 404
+        return jsonify({'file': name, 'content': path.read_text(encoding='utf-8'), 'mtime': mtime_of(path)})
+
+    @app.route('/api/edit/files/<name>', methods=['PUT'])
+    def flow_editor_save(name):
+        path = flow_path(project, name)
+        data = request.get_json(silent=True) or {}
+        content = data.get('content')
+        if not isinstance(content, str):
+            return jsonify({'error': "'content' must be the file's text"}), 400
+
+        on_disk = mtime_of(path)
+        if on_disk != data.get('mtime'):
+            message = (f"{name} already exists" if data.get('mtime') is None
+                       else f"{name} changed on disk since it was opened; reload it or save with force")
+            if on_disk is not None and not data.get('force'):
+                return jsonify({'error': message, 'mtime': on_disk}), 409
+        report = diagnostics_report(diagnose_content(path, content))
+        if report['error_count'] and not data.get('force'):
+            return jsonify({'error': f"{report['error_count']} error(s); fix them or save with force",
+                            'diagnostics': report}), 422
+
+        previous = file_version(path)
+        save_flow(path, content)
+        audit_admin(app, 'flow.save', 'flow', name, previous, dict(file_version(path) or {}, forced=bool(data.get('force'))))
+        get_dev_events(app).dispatch('file_changed', {'file': name, 'path': str(path)}, 'edit')
+        return jsonify({'file': name, 'mtime': mtime_of(path), 'diagnostics': report})
+
+    @app.route('/api/edit/check', methods=['POST'])
+    def flow_editor_check():
+        path, content = draft()
+        try:
+            route = load_flow(path, project.root_path, content=content).route
+        except FlowParseError:
+            route = None
+        return jsonify(dict(diagnostics_report(diagnose_content(path, content)), route=route))
+
+    @app.route('/api/edit/preview', methods=['POST'])
+    def flow_editor_preview():
+        path, content = draft()
+        return Response(render_draft(app, path, content), mimetype='text/html', headers={'Cache-Control': 'no-store'})
+
+# Plain textarea with a line gutter: the editor has to work offline, like the rest of the dev server
+FLOW_EDITOR_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Edit flows - {{ project_name }}</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        * { box-sizing: border-box; }
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; height: 100vh; display: flex; flex-direction: column; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 0.6rem 1.5rem; display: flex; align-items: center; gap: 1rem; }
+        .header h1 { font-size: 1.2rem; margin: 0 1rem 0 0; }
+        .header select, .header button { padding: 0.4rem 0.8rem; border-radius: 4px; border: none; }
+        .header button { background: #3B82F6; color: white; cursor: pointer; }
+        .header button.secondary { background: rgba(255,255,255,0.2); }
+        .header a { color: white; margin-left: auto; }
+        #status { font-size: 0.9rem; opacity: 0.9; }
+        .panes { flex: 1; display: flex; min-height: 0; }
+        .source { flex: 1; display: flex; flex-direction: column; min-width: 0; border-right: 1px solid #d1d5db; }
+        .code { flex: 1; display: flex; min-height: 0; background: #1f2937; }
+        #gutter { width: 3.5rem; padding: 0.75rem 0.5rem; margin: 0; text-align: right; color: #6b7280; background: #111827; overflow: hidden; user-select: none; }
+        #gutter span.error { color: #f87171; font-weight: bold; }
+        #gutter span.warning { color: #fbbf24; }
+        textarea { flex: 1; border: none; resize: none; padding: 0.75rem; background: #1f2937; color: #e5e7eb; outline: none; white-space: pre; tab-size: 2; }
+        #gutter, textarea { font: 13px/1.5 'SFMono-Regular', Consolas, monospace; }
+        #problems { max-height: 30%; overflow: auto; margin: 0; padding: 0.5rem 1rem; list-style: none; background: white; border-top: 1px solid #e5e7eb; font-size: 0.9rem; }
+        #problems li { padding: 0.25rem 0; cursor: pointer; }
+        #problems li.error::before { content: '❌ '; }
+        #problems li.warning::before { content: '⚠️ '; }
+        #problems .suggestion { color: #6b7280; }
+        .preview { flex: 1; display: flex; flex-direction: column; min-width: 0; }
+        .preview .bar { padding: 0.4rem 1rem; background: white; border-bottom: 1px solid #e5e7eb; font-size: 0.9rem; color: #4b5563; }
+        iframe { flex: 1; border: none; background: white; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>✏️ Flow editor</h1>
+        <select id="files"></select>
+        <button type="button" class="secondary" id="new">New file</button>
+        <button type="button" id="save" title="Ctrl+S">Save</button>
+        <button type="button" class="secondary" id="build" title="flashflow build, on the dev server">Build</button>
+        <span id="status"></span>
+        <a href="/preview">Device farm</a>
+    </div>
+    <div class="panes">
+        <div class="source">
+            <div class="code">
+                <pre id="gutter"></pre>
+                <textarea id="editor" spellcheck="false" autocomplete="off"></textarea>
+            </div>
+            <ul id="problems"></ul>
+        </div>
+        <div class="preview">
+            <div class="bar">Preview of the unsaved page: <code id="route">-</code></div>
+            <iframe id="frame" title="Preview"></iframe>
+        </div>
+    </div>
+    <script>
+        const editor = document.getElementById('editor');
+        const state = {file: {{ file | tojson }}, mtime: null, saved: '', timer: null};
+
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+
+        function setStatus(text) {
+            document.getElementById('status').textContent = text;
+        }
+
+        async function api(method, url, body) {
+            const response = await fetch(url, {
+                method: method,
+                headers: {'Content-Type': 'application/json'},
+                body: body ? JSON.stringify(body) : undefined
+            });
+            const data = await response.json();
+            if (!response.ok) {
+                const error = new Error(data.error || response.statusText);
+                error.status = response.status;
+                throw error;
+            }
+            return data;
+        }
+
+        function dirty() {
+            return editor.value !== state.saved;
+        }
+
+        function renderGutter(diagnostics) {
+            const marks = {};
+            for (const d of diagnostics) {
+                if (d.line && marks[d.line] !== 'error') marks[d.line] = d.severity;
+            }
+            const count = editor.value.split('\\n').length;
+            let html = '';
+            for (let line = 1; line <= count; line++) {
+                html += marks[line] ? `<span class="${marks[line]}">${line}</span>\\n` : line + '\\n';
+            }
+            document.getElementById('gutter').innerHTML = html;
+            document.getElementById('gutter').scrollTop = editor.scrollTop;
+        }
+
+        function goToLine(line) {
+            const lines = editor.value.split('\\n');
+            const start = lines.slice(0, line - 1).reduce((total, text) => total + text.length + 1, 0);
+            editor.focus();
+            editor.setSelectionRange(start, start + (lines[line - 1] || '').length);
+            editor.scrollTop = Math.max(0, (line - 5) * 19.5);
+        }
+
+        async function check() {
+            const body = {file: state.file, content: editor.value};
+            const report = await api('POST', '/api/edit/check', body);
+            const diagnostics = report.files[state.file] || [];
+            renderGutter(diagnostics);
+            document.getElementById('route').textContent = report.route || '-';
+            document.getElementById('problems').innerHTML = diagnostics.map(d => `
+                <li class="${d.severity}" onclick="goToLine(${d.line || 1})">
+                    ${d.line ? 'Line ' + d.line + ': ' : ''}${escapeHtml(d.message)}
+                    ${d.suggestion ? '<br><span class="suggestion">💡 ' + escapeHtml(d.suggestion) + '</span>' : ''}
+                </li>`).join('') || '<li>✅ No problems</li>';
+
+            const response = await fetch('/api/edit/preview', {
+                method: 'POST', headers: {'Content-Type': 'application/json'}, body: JSON.stringify(body)
+            });
+            document.getElementById('frame').srcdoc = await response.text();
+            setStatus(dirty() ? '● Unsaved changes' : 'Saved');
+        }
+
+        function scheduleCheck() {
+            clearTimeout(state.timer);
+            renderGutter([]);
+            setStatus(dirty() ? '● Unsaved changes' : 'Saved');
+            state.timer = setTimeout(() => check().catch(e => setStatus('❌ ' + e.message)), 300);
+        }
+
+        async function loadFiles() {
+            const files = (await api('GET', '/api/edit/files')).files;
+            document.getElementById('files').innerHTML = files.map(f =>
+                `<option value="${escapeHtml(f.file)}" ${f.file === state.file ? 'selected' : ''}>${escapeHtml(f.file)}${f.route ? ' (' + escapeHtml(f.route) + ')' : ''}</option>`
+            ).join('');
+            return files;
+        }
+
+        async function openFile(file) {
+            if (dirty() && !confirm('Discard unsaved changes?')) {
+                document.getElementById('files').value = state.file;
+                return;
+            }
+            const data = await api('GET', '/api/edit/files/' + encodeURIComponent(file));
+            Object.assign(state, {file: data.file, mtime: data.mtime, saved: data.content});
+            editor.value = data.content;
+            history.replaceState(null, '', '/edit?file=' + encodeURIComponent(file));
+            await check();
+        }
+
+        async function save(force) {
+            try {
+                const data = await api('PUT', '/api/edit/files/' + encodeURIComponent(state.file),
+                                       {content: editor.value, mtime: state.mtime, force: !!force});
+                Object.assign(state, {mtime: data.mtime, saved: editor.value});
+                setStatus('✅ Saved; previews reloaded');
+                await loadFiles();
+            } catch (e) {
+                if ((e.status === 409 || e.status === 422) && confirm(e.message + '\\n\\nSave anyway?')) return save(true);
+                setStatus('❌ ' + e.message);
+            }
+        }
+
+        editor.addEventListener('input', scheduleCheck);
+        editor.addEventListener('scroll', () => { document.getElementById('gutter').scrollTop = editor.scrollTop; });
+        editor.addEventListener('keydown', event => {
+            if (event.key === 'Tab') {
+                // .flow files are YAML: indent with spaces
+                event.preventDefault();
+                editor.setRangeText('  ', editor.selectionStart, editor.selectionEnd, 'end');
+                scheduleCheck();
+            }
+        });
+        document.addEventListener('keydown', event => {
+            if ((event.ctrlKey || event.metaKey) && event.key === 's') {
+                event.preventDefault();
+                save(false);
+            }
+        });
+        document.getElementById('save').onclick = () => save(false);
+        document.getElementById('build').onclick = async () => {
+            const button = document.getElementById('build');
+            button.disabled = true;
+            try {
+                let build = await api('POST', '/api/build', {target: 'all'});
+                while (['queued', 'running'].includes(build.status)) {
+                    setStatus('🔨 Build ' + build.status + '…');
+                    await new Promise(resolve => setTimeout(resolve, 1000));
+                    build = await api('GET', '/api/build/' + build.id + '?since=' + build.log_lines);
+                }
+                setStatus(build.status === 'ok' || build.status === 'skipped'
+                          ? '✅ Built in ' + build.seconds + 's'
+                          : '❌ Build ' + build.status + (build.errors.length ? ': ' + build.errors[0].message : ''));
+            } catch (e) {
+                setStatus('❌ ' + e.message);
+            } finally {
+                button.disabled = false;
+            }
+        };
+        document.getElementById('files').onchange = event => openFile(event.target.value).catch(e => setStatus('❌ ' + e.message));
+        document.getElementById('new').onclick = () => {
+            const name = prompt('New flow file name', 'page.flow');
+            if (!name || (dirty() && !confirm('Discard unsaved changes?'))) return;
+            Object.assign(state, {file: name.endsWith('.flow') ? name : name + '.flow', mtime: null, saved: ''});
+            editor.value = {{ new_flow | tojson }};
+            scheduleCheck();
+        };
+
+        // Links in the preview open in the device farm
+        window.addEventListener('message', event => {
+            if (event.data && event.data.type === 'flashflow-preview' && event.data.event === 'navigate') {
+                window.open('/preview#' + event.data.route, 'flashflow-preview');
+            }
+        });
+
+        // Pick up changes made outside the editor while there is nothing unsaved here
+        setInterval(async () => {
+            const files = await loadFiles().catch(() => []);
+            const current = files.find(f => f.file === state.file);
+            if (!current || state.mtime === null || current.mtime === state.mtime) return;
+            if (dirty()) {
+                setStatus('⚠️ Changed on disk; saving will ask before overwriting');
+            } else {
+                openFile(state.file);
+            }
+        }, 3000);
+
+        window.addEventListener('beforeunload', event => {
+            if (dirty()) event.preventDefault();
+        });
+
+        loadFiles().then(files => {
+            if (!state.file && files.length) state.file = files[0].file;
+            if (state.file) return openFile(state.file);
+            document.getElementById('new').click();
+        }).catch(e => setStatus('❌ ' + e.message));
+    </script>
+</body>
+</html>
+"""
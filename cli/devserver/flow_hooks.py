@@ -0,0 +1,309 @@
+"""
+FlashFlow request hooks - before/after middleware declared in .flow files
+
+Example:
+
+    hooks:
+      - name: require_json
+        route: /api/*
+        methods: [POST, PUT]
+        when: before
+        expression: "request.headers.get('Content-Type', '').startswith('application/json')"
+        status: 415
+        message: JSON body required
+      - name: audit
+        route: /api/todos*
+        when: after
+        webhook: http://localhost:9000/audit
+        timeout: 2
+        on_error: continue
+
+A hook runs exactly one of 'expression', 'script' or 'webhook'; a script is
+a path inside the project and is refused when it resolves anywhere else. Before-hooks
+reject the request when the expression is falsy or the script/webhook answers
+{"reject": {...}}; after-hooks may add response headers with 'set_headers' or
+by answering {"headers": {...}}.
+"""
+
+import fnmatch
+import json
+import logging
+import os
+import subprocess
+import sys
+from dataclasses import dataclass, field
+from typing import Dict, Any, List, Optional
+
+import requests
+from flask import request, jsonify, g
+
+from core.parser.flow_file import FlowDocument
+from core.parser.parser import extract_hooks
+from core.utils.expressions import evaluate, ExpressionError
+from core.tracing import get_tracer
+from cli.devserver.flow_registry import FlowRegistry, FlowSectionRegistry, get_flow_registry
+
+logger = logging.getLogger(__name__)
+
+ERROR_POLICIES = ['abort', 'continue']
+DEFAULT_TIMEOUT = 5.0
+
+class HookFailure(Exception):
+    """Raised when a hook cannot run or times out"""
+    pass
+
+@dataclass
+class RequestHook:
+    """A single hook declaration from a flow file"""
+    name: str
+    route: str
+    when: str = 'before'
+    methods: List[str] = field(default_factory=list)
+    order: int = 100
+    timeout: float = DEFAULT_TIMEOUT
+    on_error: str = 'abort'
+    expression: Optional[str] = None
+    script: Optional[str] = None
+    webhook: Optional[str] = None
+    set_headers: Dict[str, str] = field(default_factory=dict)
+    status: int = 403
+    message: str = ''
+    source: str = ''
+
+    @classmethod
+    def from_definition(cls, definition: Dict[str, Any], source: str, index: int) -> 'RequestHook':
+        when = str(definition.get('when', 'before')).lower()
+        if when not in ('before', 'after'):
+            raise ValueError(f"hook 'when' must be 'before' or 'after', got '{when}'")
+
+        actions = [key for key in ('expression', 'script', 'webhook') if definition.get(key)]
+        if len(actions) > 1:
+            raise ValueError(f"hook declares {', '.join(actions)}; use only one")
+        if not actions and not definition.get('set_headers'):
+            raise ValueError("hook needs an 'expression', 'script', 'webhook' or 'set_headers'")
+
+        on_error = str(definition.get('on_error', 'abort')).lower()
+        if on_error not in ERROR_POLICIES:
+            raise ValueError(f"hook 'on_error' must be one of {', '.join(ERROR_POLICIES)}")
+
+        methods = definition.get('methods') or []
+        if isinstance(methods, str):
+            methods = [methods]
+
+        return cls(
+            name=str(definition.get('name') or f"{source}#{index + 1}"),
+            route=str(definition.get('route', '*')),
+            when=when,
+            methods=[m.upper() for m in methods],
+            order=int(definition.get('order', 100)),
+            timeout=float(definition.get('timeout', DEFAULT_TIMEOUT)),
+            on_error=on_error,
+            expression=definition.get('expression'),
+            script=definition.get('script'),
+            webhook=definition.get('webhook'),
+            set_headers={str(k): str(v) for k, v in (definition.get('set_headers') or {}).items()},
+            status=int(definition.get('status', 403)),
+            message=str(definition.get('message', '')),
+            source=source
+        )
+
+    def matches(self, method: str, path: str) -> bool:
+        if self.methods and method.upper() not in self.methods:
+            return False
+        return fnmatch.fnmatchcase(path, self.route)
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {key: value for key, value in self.__dict__.items() if value not in (None, [], {}, '')}
+
+class HookEngine(FlowSectionRegistry):
+    """Loads hooks from flow files and runs them around dev server requests"""
+
+    def __init__(self, flows: FlowRegistry):
+        super().__init__(flows)
+        self.hooks: List[RequestHook] = []
+
+    def _load(self, documents: List[FlowDocument]):
+        hooks, errors = [], []
+        for document in documents:
+            for index, definition in enumerate(extract_hooks(document.data)):
+                try:
+                    hooks.append(RequestHook.from_definition(definition, document.file.name, index))
+                except (ValueError, TypeError) as e:
+                    errors.append(f"{document.file.name}: {str(e)}")
+
+        # Stable sort keeps declaration order for hooks with equal 'order'
+        hooks.sort(key=lambda hook: hook.order)
+        self.hooks, self.errors = hooks, errors
+        for error in errors:
+            logger.warning(f"Invalid hook in {error}")
+
+    def matching(self, when: str, method: str, path: str) -> List[RequestHook]:
+        return [hook for hook in self.hooks if hook.when == when and hook.matches(method, path)]
+
+    def run(self, hook: RequestHook, context: Dict[str, Any]) -> Dict[str, Any]:
+        """Run one hook and return its outcome: {'reject': {...}} and/or {'headers': {...}}"""
+        action = 'expression' if hook.expression else 'script' if hook.script else 'webhook' if hook.webhook else 'headers'
+        with get_tracer().span(f"hook {hook.name}", attributes={'flashflow.hook.when': hook.when, 'flashflow.hook.action': action}) as span:
+            outcome = self._run(hook, context)
+            if 'reject' in outcome:
+                span.set_attribute('flashflow.hook.rejected', True)
+            return outcome
+
+    def _run(self, hook: RequestHook, context: Dict[str, Any]) -> Dict[str, Any]:
+        outcome: Dict[str, Any] = {'headers': dict(hook.set_headers)}
+
+        if hook.expression:
+            try:
+                passed = evaluate(hook.expression, context)
+            except ExpressionError as e:
+                raise HookFailure(str(e))
+            if not passed:
+                if hook.when == 'before':
+                    outcome['reject'] = {'status': hook.status, 'message': hook.message or f"Rejected by hook '{hook.name}'"}
+                else:
+                    # A falsy after-hook expression just skips its headers
+                    outcome['headers'] = {}
+            return outcome
+
+        if hook.script:
+            result = self._run_script(hook, context)
+        elif hook.webhook:
+            result = self._call_webhook(hook, context)
+        else:
+            return outcome
+
+        outcome['headers'].update({str(k): str(v) for k, v in (result.get('headers') or {}).items()})
+        if hook.when == 'before' and result.get('reject'):
+            reject = result['reject'] if isinstance(result['reject'], dict) else {}
+            outcome['reject'] = {
+                'status': int(reject.get('status', hook.status)),
+                'message': reject.get('message') or hook.message or f"Rejected by hook '{hook.name}'"
+            }
+        return outcome
+
+    def _run_script(self, hook: RequestHook, context: Dict[str, Any]) -> Dict[str, Any]:
+        root = self.project.root_path.resolve()
+        script_path = (root / hook.script).resolve()
+        # Absolute paths, '..' and symlinks out of the project would run whatever a flow names
+        if root not in script_path.parents:
+            raise HookFailure(f"script {hook.script} is outside the project")
+        if not script_path.exists():
+            raise HookFailure(f"script not found: {hook.script}")
+
+        command = [sys.executable, str(script_path)] if script_path.suffix == '.py' else [str(script_path)]
+        try:
+            completed = subprocess.run(
+                command, input=json.dumps(context), capture_output=True, text=True,
+                timeout=hook.timeout, cwd=str(self.project.root_path),
+                env=get_tracer().inject_env(os.environ.copy())
+            )
+        except subprocess.TimeoutExpired:
+            raise HookFailure(f"script timed out after {hook.timeout}s")
+        except OSError as e:
+            raise HookFailure(f"script could not start: {str(e)}")
+
+        if completed.returncode != 0:
+            raise HookFailure(f"script exited with {completed.returncode}: {completed.stderr.strip()[:500]}")
+        return _parse_result(completed.stdout)
+
+    def _call_webhook(self, hook: RequestHook, context: Dict[str, Any]) -> Dict[str, Any]:
+        try:
+            response = requests.post(hook.webhook, json=context, timeout=hook.timeout,
+                                     headers=get_tracer().inject_headers({}))
+        except requests.Timeout:
+            raise HookFailure(f"webhook timed out after {hook.timeout}s")
+        except requests.RequestException as e:
+            raise HookFailure(f"webhook failed: {str(e)}")
+
+        if response.status_code >= 400:
+            raise HookFailure(f"webhook returned HTTP {response.status_code}")
+        return _parse_result(response.text)
+
+def _parse_result(output: str) -> Dict[str, Any]:
+    """Scripts and webhooks may answer with JSON; anything else means 'no changes'"""
+    output = (output or '').strip()
+    if not output:
+        return {}
+    try:
+        result = json.loads(output)
+    except json.JSONDecodeError:
+        return {}
+    return result if isinstance(result, dict) else {}
+
+def build_request_context() -> Dict[str, Any]:
+    """Snapshot of the current request exposed to hooks"""
+    return {
+        'request': {
+            'method': request.method,
+            'path': request.path,
+            'headers': dict(request.headers),
+            'query': request.args.to_dict(),
+            'json': request.get_json(silent=True)
+        }
+    }
+
+def register_flow_hooks(app):
+    """Install the hook engine as Flask before/after request handlers"""
+    engine = HookEngine(get_flow_registry(app))
+    app.config['HOOK_ENGINE'] = engine
+
+    def handle_failure(hook: RequestHook, error: HookFailure):
+        logger.warning(f"Hook '{hook.name}' failed: {str(error)}")
+        if hook.on_error == 'abort':
+            return jsonify({'error': f"Hook '{hook.name}' failed", 'detail': str(error)}), 502
+        return None
+
+    @app.before_request
+    def run_before_hooks():
+        engine.reload_if_changed()
+        hooks = engine.matching('before', request.method, request.path)
+        g.flow_hook_headers = {}
+        if not hooks:
+            return None
+
+        context = build_request_context()
+        for hook in hooks:
+            try:
+                outcome = engine.run(hook, context)
+            except HookFailure as e:
+                failure = handle_failure(hook, e)
+                if failure:
+                    return failure
+                continue
+
+            g.flow_hook_headers.update(outcome['headers'])
+            if 'reject' in outcome:
+                return jsonify({'error': outcome['reject']['message'], 'hook': hook.name}), outcome['reject']['status']
+        return None
+
+    @app.after_request
+    def run_after_hooks(response):
+        # A request proxied to an API worker already ran its hooks there; a generated backend has none
+        if g.get('api_worker') is not None or g.get('generated_backend'):
+            return response
+        for name, value in getattr(g, 'flow_hook_headers', {}).items():
+            response.headers[name] = value
+
+        hooks = engine.matching('after', request.method, request.path)
+        if not hooks:
+            return response
+
+        context = build_request_context()
+        context['response'] = {'status': response.status_code, 'headers': dict(response.headers)}
+        for hook in hooks:
+            try:
+                outcome = engine.run(hook, context)
+            except HookFailure as e:
+                failure = handle_failure(hook, e)
+                if failure:
+                    return app.make_response(failure)
+                continue
+            for name, value in outcome['headers'].items():
+                response.headers[name] = value
+        return response
+
+    @app.route('/api/hooks')
+    def api_hooks():
+        """List the active request hooks and any declaration errors"""
+        engine.reload_if_changed()
+        return jsonify({'hooks': [hook.to_dict() for hook in engine.hooks], 'errors': engine.errors})
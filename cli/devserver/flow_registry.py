@@ -0,0 +1,121 @@
+"""
+FlashFlow dev flow registry - The flow files every dev server subsystem reads, re-read when they change
+
+Notifications, request hooks, transforms, webhooks and permissions each take
+one section from every flow file. They subclass FlowSectionRegistry, which
+gets the documents from the app's FlowRegistry; that reads them through the
+shared loader (core/parser/flow_loader.py), so a file is parsed once for all
+of them, and again only when it or a file it includes changes.
+
+A file that stops parsing keeps its last version that did, so a typo does not
+switch off its permissions or hooks until it is fixed. The error is logged
+when it first appears; /api/diagnostics reports it too.
+"""
+
+import logging
+import threading
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+
+from core.framework import FlashFlowProject
+from core.parser.flow_file import FlowDocument
+from core.parser.flow_loader import flow_loader
+
+logger = logging.getLogger(__name__)
+
+class FlowRegistry:
+    """The project's flow files as documents, each file's last version that parsed"""
+
+    def __init__(self, project: FlashFlowProject):
+        self.project = project
+        self.documents: List[FlowDocument] = []
+        # File name -> why it does not parse; its last good version (if any) stays in documents
+        self.failures: Dict[str, str] = {}
+        self.generation = 0
+        self._good: Dict[Path, FlowDocument] = {}
+        self._signature: Optional[Tuple] = None
+        self._lock = threading.Lock()
+
+    def refresh(self) -> int:
+        """Re-read what changed; returns a number that changes whenever documents does"""
+        flow_files = sorted(path.resolve() for path in self.project.get_flow_files())
+        if self._current_signature(flow_files) == self._signature:
+            return self.generation
+
+        with self._lock:
+            # Taken before reading: a file saved meanwhile differs from it next time
+            signature = self._current_signature(flow_files)
+            if signature == self._signature:
+                return self.generation
+            good, failures = {}, {}
+            for flow_file in flow_files:
+                try:
+                    good[flow_file] = flow_loader.load_file(flow_file)
+                except (ValueError, OSError) as e:
+                    failures[flow_file.name] = str(e)
+                    if flow_file in self._good:
+                        good[flow_file] = self._good[flow_file]
+                    if self.failures.get(flow_file.name) != failures[flow_file.name]:
+                        kept = "keeping its last version that parsed" if flow_file in good else "its sections are left out"
+                        logger.error(f"{flow_file.name} does not parse, {kept}: {str(e)}")
+            documents = [good[flow_file] for flow_file in flow_files if flow_file in good]
+            # The first read of a file with includes changes the signature without changing anything else
+            changed = _contents(documents) != _contents(self.documents) or failures != self.failures
+            self._good, self.failures, self._signature = good, failures, signature
+            if changed:
+                self.documents = documents
+                self.generation += 1
+            return self.generation
+
+    def _current_signature(self, flow_files: List[Path]) -> Tuple:
+        # Every file with what it includes, so editing a shared include is a change too
+        signature = []
+        for flow_file in flow_files:
+            document = self._good.get(flow_file)
+            for path in [flow_file] + (document.includes if document else []):
+                try:
+                    info = path.stat()
+                except OSError:
+                    signature.append((str(path), None, None))
+                    continue
+                signature.append((str(path), info.st_mtime_ns, info.st_size))
+        return tuple(signature)
+
+def _contents(documents: List[FlowDocument]) -> List[Tuple[Path, Any]]:
+    return [(document.file, document.data) for document in documents]
+
+def get_flow_registry(app) -> FlowRegistry:
+    if 'FLOW_REGISTRY' not in app.config:
+        app.config['FLOW_REGISTRY'] = FlowRegistry(app.config['PROJECT'])
+    return app.config['FLOW_REGISTRY']
+
+class FlowSectionRegistry:
+    """What one subsystem reads from the flows; subclasses build it in _load from the documents"""
+
+    def __init__(self, flows: FlowRegistry):
+        self.flows = flows
+        self.project = flows.project
+        self.errors: List[str] = []
+        self._generation: Optional[int] = None
+        self._lock = threading.Lock()
+
+    def reload_if_changed(self) -> bool:
+        """Rebuild when a flow file was added, removed or modified; True when it did"""
+        generation = self.flows.refresh()
+        if generation == self._generation:
+            return False
+        with self._lock:
+            if generation == self._generation:
+                return False
+            self._load(self.flows.documents)
+            self._generation = generation
+        return True
+
+    def _load(self, documents: List[FlowDocument]):
+        raise NotImplementedError
+
+    @staticmethod
+    def sections(documents: List[FlowDocument], key: str) -> List[Tuple[Any, str]]:
+        """(value, file name) for every document with a top-level key"""
+        return [(document.data[key], document.file.name) for document in documents
+                if isinstance(document.data, dict) and key in document.data]
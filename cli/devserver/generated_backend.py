@@ -0,0 +1,209 @@
+"""
+FlashFlow generated backend - Run the built API and proxy /api/data to it
+
+    flashflow build --target backend
+    flashflow serve --generated-backend
+
+The dev server normally answers /api/data itself. With --generated-backend
+it starts the API that 'flashflow build' generated for frameworks.backend
+(Laravel, Flask or Gin; see core/backend_targets.py) from dist/backend on a
+free localhost port and proxies WORKER_PREFIXES to it the way it proxies to
+API workers, so previews and tools exercise the code that ships. Whatever
+the language, the backend gets HOST, PORT and, with a SQLite database,
+DATABASE_PATH pointing at the dev database, so both see the same rows.
+/__backend shows the process; it is started again when it exits and stops
+with the server. 'flashflow serve --backend' runs it alone, on --port.
+"""
+
+import atexit
+import logging
+import os
+import socket
+import subprocess
+import threading
+import time
+from typing import Any, Dict, List, Optional
+
+import requests
+from flask import request, jsonify, g
+
+from core.backend_targets import BackendTarget, BackendTargetError, backend_output, backend_target
+from core.database import DatabaseConfig, Storage
+from cli.devserver.api_tester import sandbox_requested
+from cli.devserver.api_workers import RESTART_DELAY, SUPERVISE_INTERVAL, WORKER_PREFIXES, proxy_request
+
+logger = logging.getLogger(__name__)
+
+STARTUP_TIMEOUT = 60.0
+# 'go mod tidy' and 'composer install' download dependencies
+PREPARE_TIMEOUT = 600.0
+
+class GeneratedBackend:
+    """The generated API as a child process"""
+
+    def __init__(self, project, env: Optional[Dict[str, str]] = None, host: str = '127.0.0.1', port: int = 0):
+        self.project = project
+        self.target: BackendTarget = backend_target(project)
+        self.output = backend_output(project)
+        self.env = env or {}
+        self.host = host
+        self.port = port
+        self.process: Optional[subprocess.Popen] = None
+        self.log_path = project.state.logs_dir / 'generated-backend.log'
+        self.started_at = 0.0
+        self.restarts = 0
+        self.requests = 0
+        self.failures = 0
+        self._stopping = threading.Event()
+        self._supervisor: Optional[threading.Thread] = None
+
+    @property
+    def alive(self) -> bool:
+        return self.process is not None and self.process.poll() is None
+
+    def check(self):
+        """Raise BackendTargetError unless the backend has been built"""
+        if not (self.output / self.target.entry).exists():
+            raise BackendTargetError(f"No {self.target.label} backend in {self.output}; "
+                                     f"run 'flashflow build --target backend' first")
+
+    def _environ(self) -> Dict[str, str]:
+        env = dict(os.environ, **self.env)
+        env.update(HOST=self.host, PORT=str(self.port), FLASHFLOW_BACKEND=self.target.name)
+        config = DatabaseConfig.from_dict(self.project.config.database)
+        if config.driver == 'sqlite':
+            env['DATABASE_PATH'] = str(Storage(config, self.project.root_path).sqlite_path())
+        return env
+
+    def prepare(self):
+        """Install the backend's dependencies the first time, when its language needs that"""
+        if not self.target.prepare:
+            return
+        marker, command = self.target.prepare
+        if (self.output / marker).exists():
+            return
+        logger.info(f"Running {' '.join(command)} in {self.output}")
+        try:
+            with open(self.log_path, 'a') as log_file:
+                result = subprocess.run(list(command), cwd=str(self.output), stdout=log_file, stderr=subprocess.STDOUT,
+                                        env=self._environ(), timeout=PREPARE_TIMEOUT)
+        except (OSError, subprocess.TimeoutExpired) as e:
+            raise BackendTargetError(f"'{' '.join(command)}' failed: {str(e)}")
+        if result.returncode != 0:
+            raise BackendTargetError(f"'{' '.join(command)}' exited with code {result.returncode}; see {self.log_path}")
+
+    def command(self) -> List[str]:
+        return self.target.run_command(self.host, self.port)
+
+    def start(self):
+        """Start the process and wait until it accepts connections"""
+        self.check()
+        self.project.state.logs_dir.mkdir(parents=True, exist_ok=True)
+        self.prepare()
+        if not self.port:
+            self.port = free_port(self.host)
+        try:
+            with open(self.log_path, 'a') as log_file:
+                self.process = subprocess.Popen(self.command(), cwd=str(self.output), stdout=log_file,
+                                                stderr=subprocess.STDOUT, env=self._environ())
+        except OSError as e:
+            raise BackendTargetError(f"Could not run {' '.join(self.command())}: {str(e)}")
+        self.started_at = time.time()
+        self.wait_ready()
+
+    def wait_ready(self, timeout: float = STARTUP_TIMEOUT):
+        deadline = time.time() + timeout
+        while time.time() < deadline:
+            if not self.alive:
+                raise BackendTargetError(f"The {self.target.label} backend exited with code {self.process.returncode}; "
+                                         f"see {self.log_path}")
+            try:
+                with socket.create_connection((self.host, self.port), timeout=1):
+                    return
+            except OSError:
+                time.sleep(0.2)
+        self._terminate()
+        raise BackendTargetError(f"The {self.target.label} backend did not listen within {timeout:.0f}s; see {self.log_path}")
+
+    def supervise(self):
+        """Restart the process whenever it exits, until stop()"""
+        atexit.register(self.stop)
+        self._supervisor = threading.Thread(target=self._supervise, name='generated-backend-supervisor', daemon=True)
+        self._supervisor.start()
+
+    def _supervise(self):
+        while not self._stopping.wait(SUPERVISE_INTERVAL):
+            if self.alive or self._stopping.is_set() or time.time() - self.started_at < RESTART_DELAY:
+                continue
+            logger.warning(f"The {self.target.label} backend exited with code {self.process.returncode}; restarting it")
+            try:
+                self.restarts += 1
+                self.started_at = time.time()
+                self.start()
+            except BackendTargetError as e:
+                logger.warning(str(e))
+
+    def stop(self):
+        self._stopping.set()
+        self._terminate()
+
+    def _terminate(self, timeout: float = 5.0):
+        if not self.alive:
+            return
+        self.process.terminate()
+        try:
+            self.process.wait(timeout=timeout)
+        except subprocess.TimeoutExpired:
+            self.process.kill()
+            self.process.wait()
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {
+            'backend': self.target.name,
+            'language': self.target.language,
+            'command': self.command() if self.port else None,
+            'directory': str(self.output),
+            'prefixes': list(WORKER_PREFIXES),
+            'pid': self.process.pid if self.process else None,
+            'port': self.port,
+            'alive': self.alive,
+            'uptime_seconds': round(time.time() - self.started_at) if self.alive else 0,
+            'restarts': self.restarts,
+            'requests': self.requests,
+            'failures': self.failures,
+            'log': str(self.log_path)
+        }
+
+def free_port(host: str) -> int:
+    with socket.socket(socket.AF_INET, socket.SOCK_STREAM) as probe:
+        probe.bind((host, 0))
+        return probe.getsockname()[1]
+
+def register_generated_backend(app, backend: GeneratedBackend):
+    """Proxy WORKER_PREFIXES to the generated backend and register /__backend
+
+    Registered where API workers would be, right after chaos mode.
+    """
+
+    @app.before_request
+    def proxy_to_generated_backend():
+        if not request.path.startswith(WORKER_PREFIXES):
+            return None
+        if not backend.alive:
+            return jsonify({'error': f"The {backend.target.label} backend is not running; see /__backend"}), 503
+        if sandbox_requested():
+            return jsonify({'error': f"The {backend.target.label} backend has no sandboxes; "
+                                     f"serve without --generated-backend to test in one"}), 409
+        try:
+            upstream = proxy_request(backend.port)
+        except requests.RequestException as e:
+            backend.failures += 1
+            return jsonify({'error': f"The {backend.target.label} backend failed: {str(e)}"}), 502
+        backend.requests += 1
+        upstream.headers['X-FlashFlow-Backend'] = backend.target.name
+        g.generated_backend = backend.target.name
+        return upstream
+
+    @app.route('/__backend')
+    def generated_backend_status():
+        return jsonify(backend.to_dict())
@@ -0,0 +1,19 @@
+"""
+FlashFlow inference devices - /inference/devices report of ONNX execution providers
+"""
+
+from flask import jsonify
+
+from core.inference import InferenceOptions, InferenceConfigError, device_info
+
+def register_inference(app):
+    """Register the inference device report"""
+
+    @app.route('/inference/devices', methods=['GET'])
+    def inference_devices():
+        """Providers the installed ONNX Runtime supports and the ones sessions will use"""
+        try:
+            options = InferenceOptions.for_project(app.config['PROJECT'])
+        except InferenceConfigError as e:
+            return jsonify({'error': str(e)}), 400
+        return jsonify(device_info(options))
@@ -0,0 +1,340 @@
+"""
+FlashFlow dev integrations - Mock payment, SMS and push providers with a console
+
+    POST /api/_integrations/payments/v1/payment_intents                 create (JSON or Stripe's form encoding)
+    GET  /api/_integrations/payments/v1/payment_intents[/<id>]          list / retrieve
+    POST /api/_integrations/payments/v1/payment_intents/<id>            update
+    POST /api/_integrations/payments/v1/payment_intents/<id>/confirm    also /capture, /cancel
+    POST /api/_integrations/payments/v1/payment_intents/<id>/authenticate  {"approve": true} finishes 3-D Secure
+    POST /api/_integrations/sms/messages                                {"to", "body", "from"}
+    POST /api/_integrations/push/send                                   {"tokens" | "token" | "topic", "title", "body", "data"}
+    GET  /api/_integrations                                             declared integrations and call counts
+    GET  /api/_integrations/calls[/<id>]   DELETE /api/_integrations/calls
+
+Stripe's libraries can be pointed at /api/_integrations/payments as their API
+base. Every call is captured and shown at /admin/integrations; see
+core/integrations.py for the test values that decline, fail or need action.
+"""
+
+from flask import request, jsonify, render_template_string, g
+
+from core.integrations import (INTEGRATIONS, DEFAULT_COUNTRY_CODE, DEFAULT_CURRENCY, FakePayments, FakePush, FakeSms,
+                               IntegrationError, IntegrationStore, nested_form)
+from core.parser.parser import FlowParser
+
+PREFIX = '/api/_integrations'
+PAYMENT_INTENTS = PREFIX + '/payments/v1/payment_intents'
+
+def get_integration_store(app) -> IntegrationStore:
+    if 'INTEGRATIONS' not in app.config:
+        app.config['INTEGRATIONS'] = IntegrationStore(app.config['PROJECT'].state.path('integrations.json'))
+    return app.config['INTEGRATIONS']
+
+def declared_integrations(app):
+    """The 'payments', 'sms' and 'push_notifications' sections of the flows, parsed again when a flow changes"""
+    project = app.config['PROJECT']
+    flow_files = project.get_flow_files()
+    signature = tuple(sorted((str(path), path.stat().st_mtime) for path in flow_files if path.exists()))
+    cached = app.config.get('INTEGRATIONS_DECLARED')
+    if cached and cached[0] == signature:
+        return cached[1]
+    declared = {}
+    try:
+        ir = FlowParser().parse_project(project.root_path)
+    except Exception:
+        ir = None
+    for name, attribute in (('payments', 'payments'), ('sms', 'sms'), ('push', 'push_notifications')):
+        if ir is not None and getattr(ir, attribute, None):
+            declared[name] = getattr(ir, attribute)
+    app.config['INTEGRATIONS_DECLARED'] = (signature, declared)
+    return declared
+
+def register_integrations(app):
+    """Register the mock providers under /api/_integrations and the /admin/integrations console"""
+
+    def params():
+        if request.is_json:
+            data = request.get_json(silent=True)
+            return data if isinstance(data, dict) else {}
+        items = list(request.args.items(multi=True)) if request.method == 'GET' else list(request.form.items(multi=True))
+        return nested_form(items)
+
+    def call(integration: str, operation: str, action, status: int = 200):
+        data = params()
+        try:
+            body = action(data)
+        except IntegrationError as e:
+            status, body = e.status, e.to_dict()
+        get_integration_store(app).record(integration, operation, request.method, request.path, data, status, body,
+                                          g.get('request_id'))
+        return jsonify(body), status
+
+    def payments() -> FakePayments:
+        settings = declared_integrations(app).get('payments') or {}
+        return FakePayments(get_integration_store(app), str(settings.get('currency') or DEFAULT_CURRENCY))
+
+    # Payments
+
+    @app.route(PAYMENT_INTENTS, methods=['POST'])
+    def payment_intent_create():
+        return call('payments', 'payment_intents.create', lambda data: payments().create(data))
+
+    @app.route(PAYMENT_INTENTS, methods=['GET'])
+    def payment_intent_list():
+        return call('payments', 'payment_intents.list',
+                    lambda data: payments().list(max(1, min(int(data.get('limit') or 10), 100))))
+
+    @app.route(PAYMENT_INTENTS + '/<intent_id>', methods=['GET'])
+    def payment_intent_retrieve(intent_id):
+        return call('payments', 'payment_intents.retrieve', lambda data: payments().retrieve(intent_id))
+
+    @app.route(PAYMENT_INTENTS + '/<intent_id>', methods=['POST'])
+    def payment_intent_update(intent_id):
+        return call('payments', 'payment_intents.update', lambda data: payments().update(intent_id, data))
+
+    @app.route(PAYMENT_INTENTS + '/<intent_id>/<action>', methods=['POST'])
+    def payment_intent_action(intent_id, action):
+        if action not in ('confirm', 'capture', 'cancel', 'authenticate'):
+            return jsonify(IntegrationError(f"Unrecognized request URL (POST: {request.path})", 404).to_dict()), 404
+        if action == 'authenticate':
+            return call('payments', 'payment_intents.authenticate',
+                        lambda data: payments().authenticate(intent_id, data.get('approve', True) not in (False, 'false', '0')))
+        return call('payments', f"payment_intents.{action}", lambda data: getattr(payments(), action)(intent_id, data))
+
+    # SMS and push
+
+    @app.route(PREFIX + '/sms/messages', methods=['POST'])
+    def sms_send():
+        settings = declared_integrations(app).get('sms') or {}
+        sms = FakeSms(str(settings.get('default_country_code') or DEFAULT_COUNTRY_CODE))
+        return call('sms', 'messages.create', sms.send, 201)
+
+    @app.route(PREFIX + '/push/send', methods=['POST'])
+    def push_send():
+        return call('push', 'messages.send', FakePush().send)
+
+    # Console
+
+    @app.route(PREFIX)
+    def integrations_status():
+        declared = declared_integrations(app)
+        base = request.host_url.rstrip('/') + PREFIX
+        return jsonify({
+            'declared': declared,
+            'counts': get_integration_store(app).counts(),
+            'endpoints': {'payments': base + '/payments', 'sms': base + '/sms/messages', 'push': base + '/push/send'}
+        })
+
+    @app.route(PREFIX + '/calls', methods=['GET'])
+    def integrations_calls():
+        integration = request.args.get('integration') or None
+        if integration and integration not in INTEGRATIONS:
+            return jsonify({'error': f"Unknown integration '{integration}'; use one of {', '.join(INTEGRATIONS)}"}), 400
+        return jsonify({'calls': get_integration_store(app).calls(integration, int(request.args.get('limit', 200)))})
+
+    @app.route(PREFIX + '/calls', methods=['DELETE'])
+    def integrations_clear():
+        get_integration_store(app).clear()
+        return jsonify({'cleared': True})
+
+    @app.route(PREFIX + '/calls/<call_id>')
+    def integrations_call(call_id):
+        found = get_integration_store(app).get_call(call_id)
+        if found is None:
+            return jsonify({'error': 'Call not found'}), 404
+        return jsonify(found)
+
+    @app.route('/admin/integrations')
+    def admin_integrations_page():
+        """Captured integration calls, with controls for payments waiting on 3-D Secure"""
+        project = app.config['PROJECT']
+        return render_template_string(INTEGRATIONS_TEMPLATE, project_name=project.config.name)
+
+INTEGRATIONS_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Integrations - FlashFlow Admin</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+        .container { max-width: 1400px; margin: 0 auto; padding: 2rem; display: grid; grid-template-columns: 440px 1fr; gap: 2rem; }
+        .panel { background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); margin-bottom: 1.5rem; }
+        .call { display: block; padding: 0.6rem; border-radius: 4px; color: #374151; text-decoration: none; border-bottom: 1px solid #f3f4f6; }
+        .call.active, .call:hover, tr.active { background: #e0e7ff; }
+        .call small { display: block; color: #6b7280; }
+        .tabs button { background: #e5e7eb; color: #374151; }
+        .tabs button.active { background: #3B82F6; color: white; }
+        button { background: #3B82F6; color: white; border: none; padding: 0.4rem 0.9rem; border-radius: 4px; cursor: pointer; }
+        button.danger { background: #dc2626; }
+        button.ok { background: #059669; }
+        pre { white-space: pre-wrap; background: #f3f4f6; padding: 1rem; border-radius: 4px; max-height: 480px; overflow: auto; }
+        table { width: 100%; border-collapse: collapse; }
+        td, th { text-align: left; padding: 0.4rem; border-bottom: 1px solid #f3f4f6; font-size: 0.9rem; }
+        code { background: #f3f4f6; padding: 0.1rem 0.3rem; border-radius: 3px; }
+        .status { display: inline-block; padding: 0.1rem 0.5rem; border-radius: 10px; font-size: 0.8rem; background: #e5e7eb; }
+        .status.good { background: #d1fae5; color: #065f46; }
+        .status.bad { background: #fee2e2; color: #991b1b; }
+        .status.wait { background: #fef3c7; color: #92400e; }
+        .muted { color: #6b7280; }
+        #error { color: #b91c1c; font-family: monospace; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>🔌 Integrations</h1>
+        <p>{{ project_name }} · fake payments, SMS and push · nothing is charged or sent</p>
+    </div>
+    <div class="container">
+        <div>
+            <div class="panel">
+                <p class="tabs">
+                    <button data-filter="" class="active">All</button>
+                    <button data-filter="payments">💳 Payments</button>
+                    <button data-filter="sms">💬 SMS</button>
+                    <button data-filter="push">🔔 Push</button>
+                </p>
+                <p><button id="refresh">Refresh</button> <button id="clear" class="danger">Clear all</button></p>
+                <div id="calls"></div>
+            </div>
+        </div>
+        <div>
+            <div class="panel">
+                <h2>Declared in flows</h2>
+                <div id="declared" class="muted">Loading...</div>
+            </div>
+            <div class="panel">
+                <h2>Payment intents</h2>
+                <div id="intents" class="muted">Loading...</div>
+            </div>
+            <div class="panel">
+                <div id="error"></div>
+                <div id="detail" class="muted">Select a call</div>
+                <p><a href="/">← Back to Main Dashboard</a></p>
+            </div>
+        </div>
+    </div>
+    <script>
+        const INTENTS = '/api/_integrations/payments/v1/payment_intents';
+        const ICONS = {payments: '💳', sms: '💬', push: '🔔'};
+        let filter = '';
+        let current = null;
+        const intents = {};
+
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+
+        async function api(url, options) {
+            const response = await fetch(url, options);
+            const data = await response.json();
+            if (!response.ok && !(data.error && data.error.type)) throw new Error(data.error || response.statusText);
+            return data;
+        }
+
+        function statusClass(status) {
+            if (['succeeded', 'delivered'].includes(status) || (status >= 200 && status < 300)) return 'good';
+            if (['requires_action', 'requires_capture', 'requires_confirmation'].includes(status)) return 'wait';
+            return 'bad';
+        }
+
+        async function loadStatus() {
+            const data = await api('/api/_integrations');
+            const names = {payments: 'Payments', sms: 'SMS', push: 'Push notifications'};
+            document.getElementById('declared').innerHTML = Object.keys(names).map(name =>
+                `<p>${ICONS[name]} <strong>${names[name]}</strong>: ${data.declared[name]
+                    ? 'declared (' + escapeHtml(JSON.stringify(data.declared[name].default_provider || data.declared[name].providers || '')) + ')'
+                    : '<span class="muted">not declared</span>'} · ${data.counts[name]} call(s)<br>
+                    <small><code>${escapeHtml(data.endpoints[name])}</code></small></p>`
+            ).join('');
+        }
+
+        async function loadIntents() {
+            const data = await api(INTENTS + '?limit=20');
+            data.data.forEach(pi => intents[pi.id] = pi);
+            document.getElementById('intents').innerHTML = data.data.length ? '<table><tr><th>Intent</th><th>Amount</th><th>Status</th><th></th></tr>'
+                + data.data.map(pi => `<tr id="${pi.id}"${location.hash === '#' + pi.id ? ' class="active"' : ''}><td><code>${escapeHtml(pi.id)}</code></td>
+                    <td>${(pi.amount / 100).toFixed(2)} ${escapeHtml(pi.currency.toUpperCase())}</td>
+                    <td><span class="status ${statusClass(pi.status)}">${escapeHtml(pi.status)}</span>
+                        ${pi.last_payment_error ? '<br><small class="muted">' + escapeHtml(pi.last_payment_error.message) + '</small>' : ''}</td>
+                    <td>${pi.status === 'requires_action'
+                        ? `<button class="ok" data-approve="${pi.id}">Approve 3-D Secure</button>
+                           <button class="danger" data-fail="${pi.id}">Fail</button>`
+                        : ''}</td></tr>`).join('') + '</table>'
+                : '<p class="muted">No payment intents yet</p>';
+            document.querySelectorAll('[data-approve]').forEach(button => button.onclick = () => authenticate(button.dataset.approve, true));
+            document.querySelectorAll('[data-fail]').forEach(button => button.onclick = () => authenticate(button.dataset.fail, false));
+        }
+
+        // Like a bank's 3-D Secure page: finish the challenge, then go back to the flow's return_url if it gave one
+        async function authenticate(id, approve) {
+            const action = intents[id].next_action;
+            const returnUrl = action && action.redirect_to_url.return_url;
+            await api(`${INTENTS}/${id}/authenticate`, {
+                method: 'POST', headers: {'Content-Type': 'application/json'}, body: JSON.stringify({approve: approve})
+            });
+            if (returnUrl && /^(https?:\/\/|\/)/.test(returnUrl)) {
+                location.href = returnUrl + (returnUrl.includes('?') ? '&' : '?') + 'payment_intent=' + encodeURIComponent(id);
+                return;
+            }
+            refresh();
+        }
+
+        async function loadCalls() {
+            const data = await api('/api/_integrations/calls' + (filter ? '?integration=' + filter : ''));
+            document.getElementById('calls').innerHTML = data.calls.map(c =>
+                `<a href="#${c.id}" class="call${c.id === current ? ' active' : ''}" data-id="${c.id}">
+                    ${ICONS[c.integration]} <strong>${escapeHtml(c.operation)}</strong>
+                    <span class="status ${statusClass(c.status)}">${c.status}</span>
+                    <small>${escapeHtml(c.method + ' ' + c.path)} · ${new Date(c.time * 1000).toLocaleTimeString()}</small></a>`
+            ).join('') || '<p class="muted">No calls yet</p>';
+            document.querySelectorAll('[data-id]').forEach(link => link.onclick = () => showCall(link.dataset.id));
+        }
+
+        async function showCall(id) {
+            current = id;
+            document.querySelectorAll('[data-id]').forEach(link => link.classList.toggle('active', link.dataset.id === id));
+            const c = await api(`/api/_integrations/calls/${id}`);
+            document.getElementById('detail').innerHTML = `
+                <h2>${ICONS[c.integration]} ${escapeHtml(c.operation)} <span class="status ${statusClass(c.status)}">${c.status}</span></h2>
+                <p class="muted">${escapeHtml(c.method + ' ' + c.path)} · ${new Date(c.time * 1000).toLocaleString()}
+                    ${c.request_id ? ' · request <code>' + escapeHtml(c.request_id) + '</code>' : ''}</p>
+                <h3>Request</h3><pre>${escapeHtml(JSON.stringify(c.request, null, 2))}</pre>
+                <h3>Response</h3><pre>${escapeHtml(JSON.stringify(c.response, null, 2))}</pre>`;
+        }
+
+        async function refresh() {
+            try {
+                document.getElementById('error').textContent = '';
+                await Promise.all([loadStatus(), loadIntents(), loadCalls()]);
+            } catch (e) {
+                document.getElementById('error').textContent = '❌ ' + e.message;
+            }
+        }
+
+        document.querySelectorAll('[data-filter]').forEach(button => button.onclick = () => {
+            filter = button.dataset.filter;
+            document.querySelectorAll('[data-filter]').forEach(b => b.classList.toggle('active', b === button));
+            loadCalls();
+        });
+        document.getElementById('refresh').onclick = refresh;
+        document.getElementById('clear').onclick = async () => {
+            if (!confirm('Delete all captured calls and payment intents?')) return;
+            await api('/api/_integrations/calls', {method: 'DELETE'});
+            current = null;
+            document.getElementById('detail').innerHTML = '<span class="muted">Select a call</span>';
+            refresh();
+        };
+
+        refresh();
+        const initial = location.hash.slice(1);
+        if (initial.startsWith('call_')) showCall(initial);
+        setInterval(refresh, 5000);
+    </script>
+</body>
+</html>
+"""
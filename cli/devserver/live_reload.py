@@ -0,0 +1,384 @@
+"""
+FlashFlow live reload - Server-sent reload events for open preview pages
+
+Each browser subscribes to /__reload with a client id and gets its own bounded
+queue. A slow client never blocks the others and is never dropped: when its
+queue is full the oldest pending event is discarded, since a reload makes
+every earlier one redundant. /__clients lists who is connected and how quickly
+reloads reach them.
+
+Event ids carry the id of the server process that sent them. A browser that
+reconnects after a restart sends the previous server's id as Last-Event-ID and
+is sent a reload straight away, since whatever it was showing may be stale.
+
+The stream sends a 'heartbeat' event every HEARTBEAT_SECONDS. The page script
+treats a stream that has been quiet for a few heartbeats as dead, even if the
+socket looks open (a sleeping laptop, a proxy that stopped forwarding), and
+reconnects with exponential backoff. A badge in the corner of the page shows
+whether it is connected, reconnecting or looking at a failed build.
+
+Besides 'reload', pages get 'build' events with the progress and errors of
+builds, which dev_events.py collects from the watchers.
+"""
+
+import itertools
+import json
+import queue
+import threading
+import time
+import uuid
+from dataclasses import dataclass, field
+from typing import Dict, Any, List, Optional
+
+from flask import request, jsonify, Response, stream_with_context
+
+CLIENT_QUEUE_SIZE = 16
+HEARTBEAT_SECONDS = 10.0
+# Reconnect delays after the stream drops, e.g. during a restart: doubled per failed attempt up to the max
+RECONNECT_MS = 500
+RECONNECT_MAX_MS = 10000
+LATENCY_SAMPLES = 500
+
+@dataclass
+class ReloadClient:
+    """One subscribed browser tab"""
+    id: str
+    user_agent: str
+    page: str
+    remote_addr: str
+    connected_at: float = field(default_factory=time.time)
+    queue: 'queue.Queue' = field(default_factory=lambda: queue.Queue(maxsize=CLIENT_QUEUE_SIZE))
+    delivered: int = 0
+    dropped: int = 0
+    last_event_at: Optional[float] = None
+    # Failed attempts the browser made before this connection
+    reconnects: int = 0
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {
+            'id': self.id,
+            'user_agent': self.user_agent,
+            'page': self.page,
+            'remote_addr': self.remote_addr,
+            'connected_at': self.connected_at,
+            'pending': self.queue.qsize(),
+            'delivered': self.delivered,
+            'dropped': self.dropped,
+            'last_event_at': self.last_event_at,
+            'reconnects': self.reconnects
+        }
+
+class ReloadHub:
+    """Fans reload events out to every connected client"""
+
+    def __init__(self):
+        self.server_id = uuid.uuid4().hex[:8]
+        self._clients: Dict[str, ReloadClient] = {}
+        self._lock = threading.Lock()
+        self._event_ids = itertools.count(1)
+        self._latencies: List[float] = []
+        self.broadcasts = 0
+
+    def subscribe(self, client_id: str, user_agent: str, page: str, remote_addr: str, reconnects: int = 0) -> ReloadClient:
+        client = ReloadClient(client_id, user_agent, page, remote_addr, reconnects=reconnects)
+        with self._lock:
+            # A tab that reconnects with the same id replaces its old stream
+            self._clients[client_id] = client
+        return client
+
+    def unsubscribe(self, client: ReloadClient):
+        with self._lock:
+            if self._clients.get(client.id) is client:
+                del self._clients[client.id]
+
+    def broadcast(self, event_type: str, data: Dict[str, Any] = None) -> int:
+        """Queue an event for every client; returns how many clients it was queued for"""
+        event = {'id': next(self._event_ids), 'type': event_type, 'sent_at': time.time(), 'data': data or {}}
+        with self._lock:
+            clients = list(self._clients.values())
+            self.broadcasts += 1
+        for client in clients:
+            while True:
+                try:
+                    client.queue.put_nowait(event)
+                    break
+                except queue.Full:
+                    try:
+                        client.queue.get_nowait()
+                        client.dropped += 1
+                    except queue.Empty:
+                        pass
+        return len(clients)
+
+    def record_delivery(self, client: ReloadClient, event: Dict[str, Any]):
+        now = time.time()
+        client.delivered += 1
+        client.last_event_at = now
+        with self._lock:
+            self._latencies.append((now - event['sent_at']) * 1000)
+            del self._latencies[:-LATENCY_SAMPLES]
+
+    def event_id(self, event: Dict[str, Any]) -> str:
+        return f"{self.server_id}-{event['id']}"
+
+    def from_previous_server(self, last_event_id: str) -> bool:
+        """Whether a reconnecting client last heard from another server process"""
+        return bool(last_event_id) and not last_event_id.startswith(f"{self.server_id}-")
+
+    def clients(self) -> List[ReloadClient]:
+        with self._lock:
+            return sorted(self._clients.values(), key=lambda client: client.connected_at)
+
+    def metrics(self) -> Dict[str, Any]:
+        """Delivery latency (broadcast to write on the client's stream) over recent events"""
+        with self._lock:
+            samples = sorted(self._latencies)
+            broadcasts = self.broadcasts
+        latency = {'count': len(samples), 'avg_ms': None, 'p95_ms': None, 'max_ms': None}
+        if samples:
+            latency.update({
+                'avg_ms': round(sum(samples) / len(samples), 2),
+                'p95_ms': round(samples[min(len(samples) - 1, int(len(samples) * 0.95))], 2),
+                'max_ms': round(samples[-1], 2)
+            })
+        return {'broadcasts': broadcasts, 'delivery_latency': latency}
+
+def get_reload_hub(app) -> ReloadHub:
+    if 'RELOAD_HUB' not in app.config:
+        app.config['RELOAD_HUB'] = ReloadHub()
+    return app.config['RELOAD_HUB']
+
+def register_live_reload(app):
+    """Register the /__reload event stream and the /__clients debug endpoint"""
+    hub = get_reload_hub(app)
+
+    @app.route('/__reload')
+    def reload_stream():
+        client = hub.subscribe(
+            request.args.get('client') or uuid.uuid4().hex[:12],
+            request.headers.get('User-Agent', ''),
+            request.args.get('page', ''),
+            request.remote_addr or '',
+            request.args.get('reconnects', 0, type=int) or 0
+        )
+
+        # The page script opens a new EventSource to reconnect, which cannot set the header, so it passes ?last=
+        restarted = hub.from_previous_server(request.headers.get('Last-Event-ID') or request.args.get('last', ''))
+
+        def stream():
+            # The hello's id is what the browser sends back as Last-Event-ID if the stream drops
+            hello = {'client': client.id, 'server': hub.server_id, 'heartbeat_seconds': HEARTBEAT_SECONDS,
+                     'retry_ms': RECONNECT_MS, 'max_retry_ms': RECONNECT_MAX_MS}
+            yield f"retry: {RECONNECT_MS}\nid: {hub.server_id}-0\nevent: hello\ndata: {json.dumps(hello)}\n\n"
+            if restarted:
+                yield f"id: {hub.server_id}-0\nevent: reload\ndata: {json.dumps({'reason': 'server restarted'})}\n\n"
+            try:
+                while True:
+                    try:
+                        event = client.queue.get(timeout=HEARTBEAT_SECONDS)
+                    except queue.Empty:
+                        # No id, so the browser's Last-Event-ID stays the last real event
+                        yield f"event: heartbeat\ndata: {json.dumps({'at': time.time()})}\n\n"
+                        continue
+                    yield f"id: {hub.event_id(event)}\nevent: {event['type']}\ndata: {json.dumps(event['data'])}\n\n"
+                    hub.record_delivery(client, event)
+            finally:
+                # Runs when the browser goes away and the next write fails
+                hub.unsubscribe(client)
+
+        response = Response(stream_with_context(stream()), mimetype='text/event-stream')
+        response.headers['Cache-Control'] = 'no-cache'
+        response.headers['X-Accel-Buffering'] = 'no'
+        return response
+
+    @app.route('/__clients')
+    def reload_clients():
+        """Connected browsers and reload delivery metrics"""
+        clients = hub.clients()
+        return jsonify({'count': len(clients), 'clients': [client.to_dict() for client in clients], **hub.metrics()})
+
+# Subscribes a page to /__reload; the id survives reloads so /__clients stays readable
+LIVE_RELOAD_SCRIPT = """
+<script>
+    (function () {
+        if (!window.EventSource) return;
+        let id = sessionStorage.getItem('flashflow-client-id');
+        if (!id) {
+            id = Math.random().toString(36).slice(2, 14);
+            sessionStorage.setItem('flashflow-client-id', id);
+        }
+        
+        // Overwritten by the server's hello
+        const timing = {heartbeat_seconds: 10, retry_ms: 500, max_retry_ms: 10000};
+        let source = null;
+        let lastEventId = '';
+        let attempts = 0;
+        let connected = false;
+        let buildFailed = false;
+        let retryAt = 0;
+        let retryTimer = null;
+        let watchdog = null;
+        
+        // Connected, reconnecting or build failed, in the top left corner
+        function showStatus() {
+            let status = document.getElementById('flashflow-reload-status');
+            if (!status) {
+                if (!document.body) return;
+                status = document.createElement('div');
+                status.id = 'flashflow-reload-status';
+                status.setAttribute('role', 'status');
+                status.style.cssText = 'position:fixed;top:12px;left:12px;padding:4px 10px;border-radius:12px;'
+                    + 'font:12px sans-serif;color:#f9fafb;background:rgba(17,24,39,0.85);z-index:99997;cursor:default';
+                status.onclick = function () {
+                    if (!connected) connect();
+                };
+                document.body.appendChild(status);
+            }
+            let dot, text, title;
+            if (!connected) {
+                const seconds = Math.max(0, Math.ceil((retryAt - Date.now()) / 1000));
+                dot = '#fbbf24';
+                text = attempts ? 'Reconnecting' + (seconds ? ' in ' + seconds + 's' : '…') : 'Connecting…';
+                title = attempts ? 'Lost the dev server; attempt ' + attempts + '. Click to retry now.' : '';
+                status.style.cursor = 'pointer';
+            } else if (buildFailed) {
+                dot = '#f87171';
+                text = 'Build failed';
+                title = 'Hot reload is connected; the last build failed';
+                status.style.cursor = 'default';
+            } else {
+                dot = '#34d399';
+                text = 'Live';
+                title = 'Hot reload is connected';
+                status.style.cursor = 'default';
+            }
+            status.replaceChildren();
+            const light = document.createElement('span');
+            light.style.cssText = 'display:inline-block;width:8px;height:8px;border-radius:50%;margin-right:6px;background:' + dot;
+            status.appendChild(light);
+            status.appendChild(document.createTextNode(text));
+            status.title = title;
+        }
+        
+        // A stream that misses a few heartbeats is dead even if the socket looks open
+        function alive() {
+            clearTimeout(watchdog);
+            watchdog = setTimeout(function () {
+                console.warn('FlashFlow: no heartbeat from the dev server, reconnecting');
+                dropped();
+            }, timing.heartbeat_seconds * 2500);
+        }
+        
+        function dropped() {
+            clearTimeout(watchdog);
+            if (source) source.close();
+            source = null;
+            connected = false;
+            attempts += 1;
+            // Exponential backoff with jitter, so tabs do not all reconnect at once after a restart
+            const delay = Math.min(timing.max_retry_ms, timing.retry_ms * Math.pow(2, attempts - 1));
+            retryAt = Date.now() + delay / 2 + Math.random() * delay / 2;
+            clearTimeout(retryTimer);
+            retryTimer = setTimeout(connect, retryAt - Date.now());
+            showStatus();
+        }
+        
+        function track(event) {
+            if (event.lastEventId) lastEventId = event.lastEventId;
+            alive();
+        }
+        
+        function connect() {
+            clearTimeout(retryTimer);
+            if (source) source.close();
+            source = new EventSource('/__reload?client=' + encodeURIComponent(id) + '&page=' + encodeURIComponent(location.pathname)
+                + '&reconnects=' + attempts + (lastEventId ? '&last=' + encodeURIComponent(lastEventId) : ''));
+            source.addEventListener('hello', function (event) {
+                Object.assign(timing, JSON.parse(event.data));
+                connected = true;
+                attempts = 0;
+                track(event);
+                showStatus();
+            });
+            source.addEventListener('heartbeat', track);
+            source.addEventListener('reload', function () {
+                location.reload();
+            });
+            source.addEventListener('build', function (event) {
+                track(event);
+                showBuild(JSON.parse(event.data));
+            });
+            source.onerror = dropped;
+            alive();
+        }
+        
+        // Waiting out a long backoff is pointless once the tab is looked at again or the network is back
+        function retryNow() {
+            if (!connected && !document.hidden) connect();
+        }
+        document.addEventListener('visibilitychange', retryNow);
+        window.addEventListener('online', retryNow);
+        setInterval(function () {
+            if (!connected && attempts) showStatus();
+        }, 1000);
+        
+        // Progress and errors of builds reported by watchers (see dev_events.py)
+        let hideTimer = null;
+        function showBuild(build) {
+            if (build.state !== 'building') {
+                buildFailed = build.state !== 'ok' && build.state !== 'idle';
+                showStatus();
+            }
+            let badge = document.getElementById('flashflow-build-status');
+            clearTimeout(hideTimer);
+            if (build.state === 'idle') {
+                if (badge) badge.remove();
+                return;
+            }
+            if (!badge) {
+                badge = document.createElement('div');
+                badge.id = 'flashflow-build-status';
+                badge.style.cssText = 'position:fixed;top:1rem;right:1rem;max-width:28rem;max-height:60vh;overflow:auto;'
+                    + 'background:rgba(17,24,39,0.95);color:#f9fafb;font-family:monospace;font-size:13px;'
+                    + 'padding:0.6rem 0.9rem;border-radius:6px;z-index:99997';
+                document.body.appendChild(badge);
+            }
+            const target = build.target ? ' ' + build.target : '';
+            badge.replaceChildren();
+            if (build.state === 'building') {
+                badge.style.borderLeft = '4px solid #fbbf24';
+                badge.textContent = '🔨 Building' + target + (build.trigger ? ' (' + build.trigger + ')' : '') + '…';
+            } else if (build.state === 'ok') {
+                badge.style.borderLeft = '4px solid #34d399';
+                badge.textContent = '✅ Built' + target + (build.seconds != null ? ' in ' + build.seconds + 's' : '');
+                hideTimer = setTimeout(function () { badge.remove(); }, 3000);
+            } else {
+                badge.style.borderLeft = '4px solid #f87171';
+                const title = document.createElement('strong');
+                title.textContent = '❌ Build' + target + ' failed';
+                badge.appendChild(title);
+                const list = document.createElement('ul');
+                list.style.margin = '0.4rem 0 0';
+                list.style.paddingLeft = '1.2rem';
+                for (const error of build.errors || []) {
+                    const item = document.createElement('li');
+                    const where = error.file ? error.file + (error.line ? ':' + error.line : '') + ' ' : '';
+                    item.textContent = where + error.message;
+                    list.appendChild(item);
+                }
+                badge.appendChild(list);
+            }
+        }
+        // A page that loads while a build runs, or after one failed, shows it straight away
+        fetch('/__build').then(function (response) {
+            return response.ok ? response.json() : null;
+        }).then(function (build) {
+            if (build && build.state !== 'ok') showBuild(build);
+        }).catch(function () {});
+        
+        connect();
+        if (document.body) showStatus();
+        else document.addEventListener('DOMContentLoaded', showStatus);
+    })();
+</script>
+"""
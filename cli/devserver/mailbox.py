@@ -0,0 +1,403 @@
+"""
+FlashFlow dev mailbox - Capture outgoing email instead of sending it
+
+Flows and backends can deliver mail two ways while developing:
+
+    SMTP    point MAIL_HOST/MAIL_PORT at localhost:1025 (any credentials, no TLS)
+    HTTP    POST /api/_mail {"to": [...], "subject": "...", "html": "...", "text": "..."}
+
+Captured messages are listed at /admin/mailbox and /api/_mail and kept in
+.flashflow/mailbox.json (newest MAX_MESSAGES) so they survive restarts.
+"""
+
+import json
+import socketserver
+import threading
+import time
+import uuid
+from email import message_from_bytes, policy
+from email.utils import getaddresses
+from pathlib import Path
+from typing import Dict, Any, List, Optional
+
+from flask import request, jsonify, render_template_string, Response
+
+DEFAULT_SMTP_PORT = 1025
+MAX_MESSAGES = 200
+MAX_MESSAGE_BYTES = 10 * 1024 * 1024
+
+class MailboxError(Exception):
+    """Raised for a message that cannot be stored"""
+    pass
+
+class Mailbox:
+    """Captured messages, newest first"""
+
+    def __init__(self, path: Path):
+        self.path = Path(path)
+        self._lock = threading.Lock()
+        self._messages: List[Dict[str, Any]] = self._load()
+
+    def _load(self) -> List[Dict[str, Any]]:
+        if not self.path.exists():
+            return []
+        try:
+            with open(self.path, 'r') as f:
+                return json.load(f)
+        except (OSError, json.JSONDecodeError):
+            return []
+
+    def _save(self):
+        partial = self.path.with_suffix('.tmp')
+        with open(partial, 'w') as f:
+            json.dump(self._messages, f)
+        partial.replace(self.path)
+
+    def add(self, sender: str, recipients: List[str], subject: str, text: str = '', html: str = '',
+            headers: Dict[str, str] = None, attachments: List[Dict[str, Any]] = None, source: str = 'api') -> Dict[str, Any]:
+        if not recipients:
+            raise MailboxError("A message needs at least one recipient")
+        message = {
+            'id': uuid.uuid4().hex[:12],
+            'received_at': time.time(),
+            'source': source,
+            'from': sender or '',
+            'to': recipients,
+            'subject': subject or '(no subject)',
+            'text': text or '',
+            'html': html or '',
+            'headers': headers or {},
+            'attachments': attachments or []
+        }
+        with self._lock:
+            self._messages.insert(0, message)
+            del self._messages[MAX_MESSAGES:]
+            self._save()
+        return message
+
+    def add_raw(self, sender: str, recipients: List[str], data: bytes) -> Dict[str, Any]:
+        """Store an RFC 5322 message as received over SMTP"""
+        parsed = message_from_bytes(data, policy=policy.default)
+        text_part = parsed.get_body(preferencelist=('plain',))
+        html_part = parsed.get_body(preferencelist=('html',))
+        attachments = [
+            {'filename': part.get_filename() or 'attachment', 'content_type': part.get_content_type(),
+             'size': len(part.get_payload(decode=True) or b'')}
+            for part in parsed.iter_attachments()
+        ]
+        return self.add(
+            sender or str(parsed.get('From', '')),
+            recipients or [address for _, address in getaddresses(parsed.get_all('To', []))],
+            str(parsed.get('Subject', '')),
+            text=text_part.get_content() if text_part else '',
+            html=html_part.get_content() if html_part else '',
+            headers={key: str(value) for key, value in parsed.items()},
+            attachments=attachments,
+            source='smtp'
+        )
+
+    def list(self) -> List[Dict[str, Any]]:
+        with self._lock:
+            return [
+                {key: message[key] for key in ('id', 'received_at', 'source', 'from', 'to', 'subject')}
+                for message in self._messages
+            ]
+
+    def get(self, message_id: str) -> Optional[Dict[str, Any]]:
+        with self._lock:
+            return next((message for message in self._messages if message['id'] == message_id), None)
+
+    def delete(self, message_id: str) -> bool:
+        with self._lock:
+            before = len(self._messages)
+            self._messages = [message for message in self._messages if message['id'] != message_id]
+            self._save()
+            return len(self._messages) != before
+
+    def clear(self):
+        with self._lock:
+            self._messages = []
+            self._save()
+
+class SMTPSinkHandler(socketserver.StreamRequestHandler):
+    """Just enough SMTP (RFC 5321) to accept mail from frameworks and mail libraries"""
+
+    def reply(self, line: str):
+        self.wfile.write(f"{line}\r\n".encode('utf-8'))
+
+    def handle(self):
+        sender, recipients = '', []
+        self.reply("220 flashflow-mailbox ESMTP ready")
+        while True:
+            line = self.rfile.readline(4096)
+            if not line:
+                return
+            command, _, argument = line.decode('utf-8', 'replace').strip().partition(' ')
+            command = command.upper()
+
+            if command == 'EHLO':
+                self.reply("250-flashflow-mailbox")
+                self.reply(f"250-SIZE {MAX_MESSAGE_BYTES}")
+                self.reply("250-AUTH PLAIN LOGIN")
+                self.reply("250 8BITMIME")
+            elif command == 'HELO':
+                self.reply("250 flashflow-mailbox")
+            elif command == 'MAIL':
+                sender, recipients = _smtp_address(argument), []
+                self.reply("250 OK")
+            elif command == 'RCPT':
+                recipients.append(_smtp_address(argument))
+                self.reply("250 OK")
+            elif command == 'DATA':
+                if not recipients:
+                    self.reply("503 RCPT first")
+                    continue
+                self.reply("354 End data with <CR><LF>.<CR><LF>")
+                data = self._read_data()
+                if data is None:
+                    self.reply("552 Message too large")
+                else:
+                    try:
+                        self.server.mailbox.add_raw(sender, recipients, data)
+                        self.reply("250 OK: captured")
+                    except Exception as e:
+                        self.reply(f"554 Could not store message: {e}")
+                sender, recipients = '', []
+            elif command == 'RSET':
+                sender, recipients = '', []
+                self.reply("250 OK")
+            elif command in ('NOOP', 'VRFY'):
+                self.reply("250 OK")
+            elif command == 'AUTH':
+                # Accept any credentials so apps configured for a real server still work
+                mechanism, _, initial = argument.partition(' ')
+                prompts = {'LOGIN': 2 - bool(initial), 'PLAIN': 0 if initial else 1}.get(mechanism.upper(), 0)
+                for _ in range(prompts):
+                    self.reply("334 ")
+                    self.rfile.readline(4096)
+                self.reply("235 Authentication succeeded")
+            elif command == 'QUIT':
+                self.reply("221 Bye")
+                return
+            else:
+                self.reply("502 Command not implemented")
+
+    def _read_data(self) -> Optional[bytes]:
+        lines, size = [], 0
+        while True:
+            line = self.rfile.readline(65536)
+            if not line or line.rstrip(b'\r\n') == b'.':
+                break
+            if line.startswith(b'..'):
+                line = line[1:]
+            size += len(line)
+            if size <= MAX_MESSAGE_BYTES:
+                lines.append(line)
+        return b''.join(lines) if size <= MAX_MESSAGE_BYTES else None
+
+class SMTPSink(socketserver.ThreadingTCPServer):
+    daemon_threads = True
+    allow_reuse_address = True
+
+    def __init__(self, host: str, port: int, mailbox: Mailbox):
+        super().__init__((host, port), SMTPSinkHandler)
+        self.mailbox = mailbox
+
+def _smtp_address(argument: str) -> str:
+    """Address out of 'FROM:<a@b.c> SIZE=123' / 'TO:<a@b.c>'"""
+    value = argument.partition(':')[2].strip()
+    if value.startswith('<'):
+        value = value[1:value.find('>')] if '>' in value else value[1:]
+    return value.split(' ')[0]
+
+def get_mailbox(app) -> Mailbox:
+    if 'MAILBOX' not in app.config:
+        app.config['MAILBOX'] = Mailbox(app.config['PROJECT'].state.path('mailbox.json'))
+    return app.config['MAILBOX']
+
+def start_smtp_sink(app, host: str = 'localhost', port: int = DEFAULT_SMTP_PORT) -> SMTPSink:
+    """Accept SMTP on a background thread; raises OSError if the port is taken"""
+    sink = SMTPSink(host, port, get_mailbox(app))
+    threading.Thread(target=sink.serve_forever, name='flashflow-smtp', daemon=True).start()
+    return sink
+
+def register_mailbox(app):
+    """Register the /api/_mail capture API and the /admin/mailbox page"""
+
+    @app.route('/api/_mail', methods=['GET'])
+    def mail_list():
+        return jsonify({'messages': get_mailbox(app).list()})
+
+    @app.route('/api/_mail', methods=['POST'])
+    def mail_capture():
+        """Capture a message sent over HTTP"""
+        data = request.get_json(silent=True) or {}
+        recipients = data.get('to') or []
+        if isinstance(recipients, str):
+            recipients = [recipients]
+        try:
+            message = get_mailbox(app).add(
+                data.get('from', ''), [str(address) for address in recipients], data.get('subject', ''),
+                text=data.get('text', ''), html=data.get('html', ''), headers=data.get('headers') or {}
+            )
+        except MailboxError as e:
+            return jsonify({'error': str(e)}), 400
+        return jsonify(message), 201
+
+    @app.route('/api/_mail', methods=['DELETE'])
+    def mail_clear():
+        get_mailbox(app).clear()
+        return jsonify({'cleared': True})
+
+    @app.route('/api/_mail/<message_id>', methods=['GET'])
+    def mail_get(message_id):
+        message = get_mailbox(app).get(message_id)
+        if message is None:
+            return jsonify({'error': 'Message not found'}), 404
+        return jsonify(message)
+
+    @app.route('/api/_mail/<message_id>', methods=['DELETE'])
+    def mail_delete(message_id):
+        if not get_mailbox(app).delete(message_id):
+            return jsonify({'error': 'Message not found'}), 404
+        return jsonify({'deleted': message_id})
+
+    @app.route('/api/_mail/<message_id>/html')
+    def mail_html(message_id):
+        """The HTML body on its own, for the sandboxed preview frame"""
+        message = get_mailbox(app).get(message_id)
+        if message is None:
+            return jsonify({'error': 'Message not found'}), 404
+        response = Response(message['html'] or f"<pre>{_escape(message['text'])}</pre>", mimetype='text/html')
+        response.headers['Content-Security-Policy'] = "script-src 'none'"
+        return response
+
+    @app.route('/admin/mailbox')
+    def admin_mailbox_page():
+        """Admin page listing captured mail with an HTML preview"""
+        project = app.config['PROJECT']
+        smtp_port = app.config.get('SMTP_PORT')
+        return render_template_string(MAILBOX_TEMPLATE, project_name=project.config.name, smtp_port=smtp_port)
+
+def _escape(text: str) -> str:
+    return text.replace('&', '&amp;').replace('<', '&lt;').replace('>', '&gt;')
+
+MAILBOX_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Mailbox - FlashFlow Admin</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+        .container { max-width: 1400px; margin: 0 auto; padding: 2rem; display: grid; grid-template-columns: 380px 1fr; gap: 2rem; }
+        .panel { background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); }
+        .message { display: block; padding: 0.6rem; border-radius: 4px; color: #374151; text-decoration: none; border-bottom: 1px solid #f3f4f6; }
+        .message.active, .message:hover { background: #e0e7ff; }
+        .message small { display: block; color: #6b7280; }
+        .tabs button { background: #e5e7eb; color: #374151; }
+        .tabs button.active { background: #3B82F6; color: white; }
+        button { background: #3B82F6; color: white; border: none; padding: 0.4rem 0.9rem; border-radius: 4px; cursor: pointer; }
+        button.danger { background: #dc2626; }
+        iframe { width: 100%; height: 600px; border: 1px solid #e5e7eb; border-radius: 4px; background: white; }
+        pre { white-space: pre-wrap; background: #f3f4f6; padding: 1rem; border-radius: 4px; max-height: 600px; overflow: auto; }
+        dl { display: grid; grid-template-columns: 80px 1fr; gap: 0.3rem; }
+        dt { color: #6b7280; }
+        .muted { color: #6b7280; }
+        #error { color: #b91c1c; font-family: monospace; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>📬 Mailbox</h1>
+        <p>{{ project_name }} · mail is captured, never sent ·
+            {% if smtp_port %}SMTP on localhost:{{ smtp_port }}{% else %}SMTP sink off{% endif %} · POST /api/_mail</p>
+    </div>
+    <div class="container">
+        <div class="panel">
+            <p><button id="refresh">Refresh</button> <button id="clear" class="danger">Delete all</button></p>
+            <div id="messages"></div>
+        </div>
+        <div class="panel">
+            <div id="error"></div>
+            <div id="detail" class="muted">Select a message</div>
+            <p><a href="/">← Back to Main Dashboard</a></p>
+        </div>
+    </div>
+    <script>
+        let current = null;
+
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+
+        async function api(url, options) {
+            const response = await fetch(url, options);
+            const data = await response.json();
+            if (!response.ok) throw new Error(data.error || response.statusText);
+            return data;
+        }
+
+        async function loadMessages() {
+            try {
+                const data = await api('/api/_mail');
+                document.getElementById('messages').innerHTML = data.messages.map(m =>
+                    `<a href="#${m.id}" class="message${m.id === current ? ' active' : ''}" data-id="${m.id}">
+                        <strong>${escapeHtml(m.subject)}</strong>
+                        <small>To ${escapeHtml(m.to.join(', '))} · ${new Date(m.received_at * 1000).toLocaleString()} · ${m.source}</small></a>`
+                ).join('') || '<p class="muted">No mail yet</p>';
+                document.querySelectorAll('[data-id]').forEach(link => link.onclick = () => showMessage(link.dataset.id));
+            } catch (e) {
+                document.getElementById('error').textContent = '❌ ' + e.message;
+            }
+        }
+
+        async function showMessage(id) {
+            current = id;
+            document.querySelectorAll('[data-id]').forEach(link => link.classList.toggle('active', link.dataset.id === id));
+            try {
+                const m = await api(`/api/_mail/${id}`);
+                document.getElementById('detail').innerHTML = `
+                    <h2>${escapeHtml(m.subject)}</h2>
+                    <dl><dt>From</dt><dd>${escapeHtml(m.from)}</dd><dt>To</dt><dd>${escapeHtml(m.to.join(', '))}</dd>
+                        <dt>Received</dt><dd>${new Date(m.received_at * 1000).toLocaleString()}</dd>
+                        ${m.attachments.length ? `<dt>Files</dt><dd>${m.attachments.map(a => escapeHtml(a.filename) + ' (' + a.size + ' B)').join(', ')}</dd>` : ''}</dl>
+                    <p class="tabs"><button data-tab="html" class="active">HTML</button> <button data-tab="text">Text</button>
+                        <button data-tab="headers">Headers</button> <button id="delete" class="danger">Delete</button></p>
+                    <div id="tab-html"><iframe sandbox src="/api/_mail/${id}/html"></iframe></div>
+                    <div id="tab-text" hidden><pre>${escapeHtml(m.text || '(no text part)')}</pre></div>
+                    <div id="tab-headers" hidden><pre>${escapeHtml(Object.entries(m.headers).map(([k, v]) => k + ': ' + v).join('\\n'))}</pre></div>`;
+                document.querySelectorAll('[data-tab]').forEach(button => button.onclick = () => {
+                    document.querySelectorAll('[data-tab]').forEach(b => b.classList.toggle('active', b === button));
+                    ['html', 'text', 'headers'].forEach(tab => document.getElementById('tab-' + tab).hidden = tab !== button.dataset.tab);
+                });
+                document.getElementById('delete').onclick = async () => {
+                    await api(`/api/_mail/${id}`, {method: 'DELETE'});
+                    current = null;
+                    document.getElementById('detail').innerHTML = '<span class="muted">Select a message</span>';
+                    loadMessages();
+                };
+            } catch (e) {
+                document.getElementById('error').textContent = '❌ ' + e.message;
+            }
+        }
+
+        document.getElementById('refresh').onclick = loadMessages;
+        document.getElementById('clear').onclick = async () => {
+            if (!confirm('Delete all captured mail?')) return;
+            await api('/api/_mail', {method: 'DELETE'});
+            loadMessages();
+        };
+
+        loadMessages();
+        const initial = location.hash.slice(1);
+        if (initial) showMessage(initial);
+        setInterval(loadMessages, 5000);
+    </script>
+</body>
+</html>
+"""
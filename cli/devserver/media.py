@@ -0,0 +1,51 @@
+"""
+FlashFlow media serving - /media assets with responsive WebP variants
+
+Requests carrying the asset fingerprint (?v=...) are cached as immutable, the
+rest revalidate with an ETag, which matches how the built site is served.
+"""
+
+from flask import request, jsonify, send_file, Response
+
+from core.media import MediaLibrary, MediaError
+
+IMMUTABLE_CACHE = 'public, max-age=31536000, immutable'
+
+def get_media_library(app) -> MediaLibrary:
+    if 'MEDIA_LIBRARY' not in app.config:
+        project = app.config['PROJECT']
+        app.config['MEDIA_LIBRARY'] = MediaLibrary(project.src_path / "assets", project.state.cache_dir / "media")
+    return app.config['MEDIA_LIBRARY']
+
+def register_media(app):
+    """Register the /media asset routes and the /api/media description endpoint"""
+
+    @app.route('/media/<path:asset>')
+    def media_asset(asset):
+        library = get_media_library(app)
+        width = request.args.get('w', type=int)
+        try:
+            version = library.fingerprint(asset)
+            etag = f"{version}-{width}" if width else version
+            if request.if_none_match.contains(etag):
+                response = Response(status=304)
+            elif width:
+                response = send_file(library.variant(asset, width), mimetype='image/webp', conditional=False)
+            else:
+                response = send_file(library.resolve(asset), conditional=False)
+        except MediaError as e:
+            status = 404 if 'not found' in str(e) else 400
+            return jsonify({'error': str(e)}), status
+
+        response.set_etag(etag)
+        response.headers['Cache-Control'] = IMMUTABLE_CACHE if request.args.get('v') == version else 'no-cache'
+        return response
+
+    @app.route('/api/media/<path:asset>')
+    def media_description(asset):
+        """srcset, sizes and variant URLs for an asset"""
+        try:
+            return jsonify(get_media_library(app).describe(asset, sizes=request.args.get('sizes')))
+        except MediaError as e:
+            status = 404 if 'not found' in str(e) else 400
+            return jsonify({'error': str(e)}), status
@@ -0,0 +1,417 @@
+"""
+FlashFlow dev notifications - the flows' 'notifications:' sent as web push
+
+    GET    /api/notifications                          declared notifications, errors and subscriptions
+    GET    /api/notifications/vapid-public-key         applicationServerKey for PushManager.subscribe()
+    POST   /api/notifications/subscriptions            a PushSubscription.toJSON() from the browser
+    DELETE /api/notifications/subscriptions/<id>
+    POST   /api/notifications/<name>/send              send one now; {"row": {...}} fills event notifications
+    GET    /api/notifications/sent                     sent messages, newest first
+    DELETE /api/notifications/sent
+
+Creating, updating or deleting a row through /api/data sends the
+notifications whose event matches, and 'every' notifications are sent on
+their schedule while the server runs. /admin/notifications subscribes the
+browser (through the service worker at /__push-sw.js), sends test messages
+and lists what was sent. Each message is also shown on open pages of the
+console, so it can be previewed without a push service. See
+core/notifications.py for the declaration format.
+"""
+
+import logging
+import threading
+import time
+from typing import Any, Dict, List, Optional, Tuple
+
+from flask import request, jsonify, render_template_string, Response
+
+from core.notifications import NotificationDeclaration, NotificationError, PushSender, PushStore, load_declarations
+from core.parser.flow_file import FlowDocument
+from cli.devserver.flow_registry import FlowRegistry, FlowSectionRegistry, get_flow_registry
+from cli.devserver.live_reload import get_reload_hub
+
+logger = logging.getLogger(__name__)
+
+SCHEDULER_TICK = 1.0
+
+class NotificationRegistry(FlowSectionRegistry):
+    """Notification declarations from the flows, re-read when a flow file changes"""
+
+    def __init__(self, flows: FlowRegistry):
+        super().__init__(flows)
+        self.notifications: List[NotificationDeclaration] = []
+        self.store = PushStore.for_project(self.project)
+        self.sender = PushSender(self.store, self.project.config.notifications)
+
+    def _load(self, documents: List[FlowDocument]):
+        self.notifications, self.errors = load_declarations(self.sections(documents, 'notifications'))
+        for error in self.errors:
+            logger.warning(f"Invalid notification in {error}")
+
+    def get(self, name: str) -> NotificationDeclaration:
+        for notification in self.notifications:
+            if notification.name == name:
+                return notification
+        raise NotificationError(f"Notification '{name}' is not declared")
+
+def get_notifications(app) -> NotificationRegistry:
+    if 'NOTIFICATIONS' not in app.config:
+        app.config['NOTIFICATIONS'] = NotificationRegistry(get_flow_registry(app))
+    return app.config['NOTIFICATIONS']
+
+def send_notification(app, notification: NotificationDeclaration, context: Dict[str, Any], trigger: str) -> Optional[Dict[str, Any]]:
+    """Render and send one notification; None when its 'if' is false"""
+    message = notification.message(dict(context, name=notification.name, now=time.strftime('%Y-%m-%d %H:%M:%S')))
+    if message is None:
+        return None
+    entry = get_notifications(app).sender.send(message, trigger)
+    get_reload_hub(app).broadcast('notification', {'id': entry['id'], 'message': message, 'trigger': trigger})
+    return entry
+
+def fire_notifications(app, model: str, action: str, row: Dict[str, Any]) -> int:
+    """Send every notification matching '<model>.<action>' in the background; returns how many matched"""
+    registry = get_notifications(app)
+    registry.reload_if_changed()
+    event = f"{model.lower()}.{action}"
+    matching = [notification for notification in registry.notifications if notification.matches(event)]
+    if matching:
+        threading.Thread(target=_send_all, args=(app, matching, {'row': dict(row), 'event': event}, event), daemon=True,
+                         name='flashflow-notifications').start()
+    return len(matching)
+
+def _send_all(app, notifications: List[NotificationDeclaration], context: Dict[str, Any], trigger: str):
+    for notification in notifications:
+        try:
+            send_notification(app, notification, context, trigger)
+        except NotificationError as e:
+            logger.warning(str(e))
+
+class NotificationScheduler:
+    """Sends the 'every' notifications while the server runs; the first send is one interval after startup"""
+
+    def __init__(self, app):
+        self.app = app
+        self.due: Dict[str, Tuple[float, float]] = {}
+        self._stop = threading.Event()
+
+    def start(self):
+        threading.Thread(target=self._run, daemon=True, name='flashflow-notification-schedule').start()
+
+    def stop(self):
+        self._stop.set()
+
+    def _run(self):
+        while not self._stop.wait(SCHEDULER_TICK):
+            try:
+                self.tick(time.time())
+            except Exception as e:
+                logger.warning(f"Scheduled notifications: {str(e)}")
+
+    def tick(self, now: float) -> List[str]:
+        registry = get_notifications(self.app)
+        registry.reload_if_changed()
+        sent = []
+        scheduled = {notification.name: notification for notification in registry.notifications if notification.every}
+        for name in set(self.due) - set(scheduled):
+            del self.due[name]
+        for name, notification in scheduled.items():
+            every, due_at = self.due.get(name, (None, None))
+            if every != notification.every:
+                # New, or its interval changed: start counting again
+                self.due[name] = (notification.every, now + notification.every)
+                continue
+            if now >= due_at:
+                self.due[name] = (every, now + every)
+                try:
+                    send_notification(self.app, notification, {'event': f"every {int(every)}s"}, 'schedule')
+                    sent.append(name)
+                except NotificationError as e:
+                    logger.warning(str(e))
+        return sent
+
+def register_notifications(app) -> NotificationScheduler:
+    """Register /api/notifications, the service worker and /admin/notifications; start the schedule"""
+    registry = get_notifications(app)
+
+    @app.route('/api/notifications')
+    def notifications_list():
+        registry.reload_if_changed()
+        return jsonify({'notifications': [notification.to_dict() for notification in registry.notifications],
+                        'errors': registry.errors,
+                        'subscriptions': [_public(subscription) for subscription in registry.store.subscriptions()]})
+
+    @app.route('/api/notifications/vapid-public-key')
+    def notifications_public_key():
+        try:
+            return jsonify({'public_key': registry.store.keys().public_key})
+        except NotificationError as e:
+            return jsonify({'error': str(e)}), 503
+
+    @app.route('/api/notifications/subscriptions', methods=['POST'])
+    def notifications_subscribe():
+        try:
+            subscription = registry.store.subscribe(request.get_json(silent=True),
+                                                    request.headers.get('User-Agent', ''))
+        except NotificationError as e:
+            return jsonify({'error': str(e)}), 400
+        return jsonify(_public(subscription)), 201
+
+    @app.route('/api/notifications/subscriptions/<subscription_id>', methods=['DELETE'])
+    def notifications_unsubscribe(subscription_id):
+        if not registry.store.unsubscribe(subscription_id):
+            return jsonify({'error': f"Subscription '{subscription_id}' not found"}), 404
+        return '', 204
+
+    @app.route('/api/notifications/<name>/send', methods=['POST'])
+    def notifications_send(name):
+        registry.reload_if_changed()
+        body = request.get_json(silent=True) or {}
+        if not isinstance(body.get('row', {}), dict):
+            return jsonify({'error': "'row' must be an object"}), 400
+        try:
+            notification = registry.get(name)
+        except NotificationError as e:
+            return jsonify({'error': str(e)}), 404
+        try:
+            entry = send_notification(app, notification, {'row': body.get('row') or {}, 'event': notification.event or 'manual'},
+                                      'manual')
+        except NotificationError as e:
+            return jsonify({'error': str(e)}), 400
+        if entry is None:
+            return jsonify({'error': f"'if' of '{name}' is false for this row; nothing was sent"}), 409
+        return jsonify(entry)
+
+    @app.route('/api/notifications/sent', methods=['GET'])
+    def notifications_sent():
+        return jsonify({'sent': registry.store.sent()})
+
+    @app.route('/api/notifications/sent', methods=['DELETE'])
+    def notifications_clear():
+        registry.store.clear_sent()
+        return '', 204
+
+    @app.route('/__push-sw.js')
+    def notifications_service_worker():
+        response = Response(SERVICE_WORKER, mimetype='application/javascript')
+        response.headers['Service-Worker-Allowed'] = '/'
+        response.headers['Cache-Control'] = 'no-cache'
+        return response
+
+    @app.route('/admin/notifications')
+    def admin_notifications_page():
+        project = app.config['PROJECT']
+        return render_template_string(NOTIFICATIONS_TEMPLATE, project_name=project.config.name)
+
+    scheduler = NotificationScheduler(app)
+    scheduler.start()
+    return scheduler
+
+def _public(subscription: Dict[str, Any]) -> Dict[str, Any]:
+    """A subscription without its keys"""
+    return {key: subscription.get(key) for key in ('id', 'endpoint', 'user_agent', 'created_at')}
+
+SERVICE_WORKER = """
+self.addEventListener('push', event => {
+    let message = {};
+    try { message = event.data ? event.data.json() : {}; } catch (e) { message = {body: event.data.text()}; }
+    event.waitUntil(self.registration.showNotification(message.title || 'FlashFlow', {
+        body: message.body || '', icon: message.icon, tag: message.tag, data: {url: message.url}
+    }));
+});
+self.addEventListener('notificationclick', event => {
+    event.notification.close();
+    const url = event.notification.data && event.notification.data.url;
+    if (url) event.waitUntil(clients.openWindow(url));
+});
+"""
+
+NOTIFICATIONS_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Notifications - FlashFlow Admin</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+        .container { max-width: 1400px; margin: 0 auto; padding: 2rem; display: grid; grid-template-columns: 1fr 1fr; gap: 2rem; }
+        .panel { background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); margin-bottom: 1.5rem; }
+        table { width: 100%; border-collapse: collapse; }
+        th, td { text-align: left; padding: 0.45rem; border-bottom: 1px solid #e5e7eb; font-size: 0.875rem; vertical-align: top; }
+        button { background: #3B82F6; color: white; border: none; padding: 0.4rem 0.9rem; border-radius: 4px; cursor: pointer; }
+        button.secondary { background: #6b7280; }
+        textarea { width: 100%; font-family: monospace; min-height: 4rem; }
+        .status { padding: 0.1rem 0.5rem; border-radius: 999px; font-size: 0.75rem; }
+        .ok { background: #dcfce7; color: #166534; }
+        .failed { background: #fee2e2; color: #991b1b; }
+        .none { background: #e5e7eb; color: #374151; }
+        .muted { color: #6b7280; }
+        #error, .error { color: #b91c1c; font-family: monospace; }
+        #preview { position: fixed; right: 1.5rem; bottom: 1.5rem; width: 340px; }
+        .toast { background: #111827; color: white; padding: 0.9rem 1rem; border-radius: 8px; margin-top: 0.5rem; box-shadow: 0 4px 12px rgba(0,0,0,0.3); }
+        .toast small { color: #9ca3af; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>🔔 Notifications</h1>
+        <p>{{ project_name }} · The flows' 'notifications:', sent as web push to subscribed browsers</p>
+    </div>
+    <div class="container">
+        <div>
+            <div class="panel">
+                <h3>This browser</h3>
+                <p id="subscription" class="muted">Checking...</p>
+                <p><button id="subscribe">Subscribe</button> <button id="unsubscribe" class="secondary">Unsubscribe</button></p>
+                <p class="muted">Push needs a secure origin: open the console on localhost, not on a LAN address.</p>
+            </div>
+            <div class="panel">
+                <h3>Declared</h3>
+                <div id="declared" class="muted">Loading...</div>
+                <p class="muted">Row for event notifications sent from here (JSON):</p>
+                <textarea id="row">{}</textarea>
+            </div>
+        </div>
+        <div>
+            <div class="panel">
+                <div id="error"></div>
+                <p><button id="refresh">Refresh</button> <button id="clear" class="secondary">Clear history</button></p>
+                <table>
+                    <thead><tr><th>Sent</th><th>Notification</th><th>Message</th><th>Delivered</th></tr></thead>
+                    <tbody id="sent"></tbody>
+                </table>
+                <p><a href="/">← Back to Main Dashboard</a></p>
+            </div>
+        </div>
+    </div>
+    <div id="preview"></div>
+    <script>
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+
+        async function api(url, options) {
+            const response = await fetch(url, options);
+            const data = response.status === 204 ? {} : await response.json();
+            if (!response.ok) throw new Error(data.error || response.statusText);
+            return data;
+        }
+
+        function showError(e) {
+            document.getElementById('error').textContent = '❌ ' + e.message;
+        }
+
+        function urlBase64ToUint8Array(text) {
+            const padded = (text + '='.repeat((4 - text.length % 4) % 4)).replace(/-/g, '+').replace(/_/g, '/');
+            return Uint8Array.from(atob(padded), c => c.charCodeAt(0));
+        }
+
+        async function registration() {
+            if (!('serviceWorker' in navigator) || !('PushManager' in window)) {
+                throw new Error('This browser (or a non-secure origin) has no web push');
+            }
+            return navigator.serviceWorker.register('/__push-sw.js', {scope: '/'});
+        }
+
+        async function refreshSubscription() {
+            const element = document.getElementById('subscription');
+            try {
+                const current = await (await registration()).pushManager.getSubscription();
+                element.innerHTML = current ? '✅ Subscribed: <code>' + escapeHtml(new URL(current.endpoint).host) + '</code>'
+                                            : 'Not subscribed';
+            } catch (e) {
+                element.textContent = e.message;
+            }
+        }
+
+        async function subscribe() {
+            const key = await api('/api/notifications/vapid-public-key');
+            if (await Notification.requestPermission() !== 'granted') throw new Error('Notifications are blocked for this site');
+            const reg = await registration();
+            const subscription = await reg.pushManager.subscribe({userVisibleOnly: true, applicationServerKey: urlBase64ToUint8Array(key.public_key)});
+            await api('/api/notifications/subscriptions', {method: 'POST', headers: {'Content-Type': 'application/json'},
+                                                          body: JSON.stringify(subscription.toJSON())});
+            await refreshSubscription();
+            await loadDeclared();
+        }
+
+        async function unsubscribe() {
+            const current = await (await registration()).pushManager.getSubscription();
+            if (current) {
+                const data = await api('/api/notifications');
+                const known = data.subscriptions.find(item => item.endpoint === current.endpoint);
+                if (known) await api('/api/notifications/subscriptions/' + known.id, {method: 'DELETE'});
+                await current.unsubscribe();
+            }
+            await refreshSubscription();
+            await loadDeclared();
+        }
+
+        async function loadDeclared() {
+            const data = await api('/api/notifications');
+            const rows = data.notifications.map(notification => `<tr>
+                    <td><strong>${escapeHtml(notification.name)}</strong><br><span class="muted">${escapeHtml(notification.source)}</span></td>
+                    <td>${notification.event ? '<code>' + escapeHtml(notification.event) + '</code>' : 'every ' + notification.every + 's'}</td>
+                    <td>${escapeHtml(notification.title)}</td>
+                    <td><button data-send="${escapeHtml(notification.name)}">Send</button></td>
+                </tr>`).join('');
+            const errors = data.errors.map(error => `<p class="error">${escapeHtml(error)}</p>`).join('');
+            document.getElementById('declared').innerHTML = (rows ? `<table>${rows}</table>` : 'No notifications declared in the flows') +
+                errors + `<p class="muted">${data.subscriptions.length} subscribed browser(s)</p>`;
+            document.querySelectorAll('[data-send]').forEach(button => button.onclick = () => send(button.dataset.send).catch(showError));
+        }
+
+        async function send(name) {
+            document.getElementById('error').textContent = '';
+            const row = JSON.parse(document.getElementById('row').value || '{}');
+            await api('/api/notifications/' + encodeURIComponent(name) + '/send', {method: 'POST',
+                headers: {'Content-Type': 'application/json'}, body: JSON.stringify({row: row})});
+            await loadSent();
+        }
+
+        async function loadSent() {
+            const data = await api('/api/notifications/sent');
+            document.getElementById('sent').innerHTML = data.sent.map(entry => {
+                const total = entry.results.length;
+                const state = entry.error || (total && !entry.delivered) ? 'failed' : total ? 'ok' : 'none';
+                const details = entry.error ? escapeHtml(entry.error)
+                    : entry.results.map(result => escapeHtml(result.endpoint) + ': ' + (result.ok ? result.status_code : escapeHtml(result.error || result.status_code))).join('<br>');
+                return `<tr>
+                    <td>${new Date(entry.sent_at * 1000).toLocaleTimeString()}<br><span class="muted">${escapeHtml(entry.trigger)}</span></td>
+                    <td>${escapeHtml(entry.notification)}</td>
+                    <td><strong>${escapeHtml(entry.message.title)}</strong><br>${escapeHtml(entry.message.body)}</td>
+                    <td><span class="status ${state}">${total ? entry.delivered + '/' + total : 'no subscribers'}</span><br><span class="muted">${details}</span></td>
+                </tr>`;
+            }).join('') || '<tr><td colspan="4" class="muted">Nothing sent yet</td></tr>';
+        }
+
+        function preview(message) {
+            const toast = document.createElement('div');
+            toast.className = 'toast';
+            toast.innerHTML = `<strong>${escapeHtml(message.title)}</strong><div>${escapeHtml(message.body)}</div>` +
+                (message.url ? `<small>${escapeHtml(message.url)}</small>` : '');
+            document.getElementById('preview').appendChild(toast);
+            setTimeout(() => toast.remove(), 8000);
+        }
+
+        if (window.EventSource) {
+            const source = new EventSource('/__reload?client=notifications-' + Math.random().toString(36).slice(2) + '&page=' + encodeURIComponent(location.pathname));
+            source.addEventListener('notification', event => {
+                preview(JSON.parse(event.data).message);
+                loadSent().catch(showError);
+            });
+        }
+        document.getElementById('subscribe').onclick = () => subscribe().catch(showError);
+        document.getElementById('unsubscribe').onclick = () => unsubscribe().catch(showError);
+        document.getElementById('refresh').onclick = () => loadSent().catch(showError);
+        document.getElementById('clear').onclick = () => api('/api/notifications/sent', {method: 'DELETE'}).then(loadSent).catch(showError);
+        refreshSubscription();
+        loadDeclared().catch(showError);
+        loadSent().catch(showError);
+    </script>
+</body>
+</html>
+"""
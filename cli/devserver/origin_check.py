@@ -0,0 +1,99 @@
+"""
+FlashFlow dev server origin check - pages on other sites cannot change anything
+
+Any page open in the developer's browser can send requests to the dev
+server. CORS keeps it from reading the answers, but not a form POST from
+landing, so requests that change something (every method but GET, HEAD and
+OPTIONS) are refused with 403 when a browser sends them from elsewhere:
+
+- the dev server's own tools (TOOLING_PREFIXES: the editor, builds, the
+  vault, restarts, admin pages, ...) only take them from the dev server's
+  own pages;
+- everything else, such as /api/data and flow endpoints, also from the
+  origins the cors settings allow (core/runtime_settings.py), by default
+  pages on this machine, so a frontend on another local port keeps working.
+
+A browser names the page a request comes from in Origin, or when it leaves
+that out, says whether it is cross-site in Sec-Fetch-Site. Requests with
+neither come from scripts, curl or the SDKs, not from a page, and pass.
+
+The dev server's own origin is taken from the Host header, which a page can
+point at the dev server by DNS rebinding (its own name resolving to this
+machine). So before anything else, every request must name a known host:
+localhost, the bind address, the share tunnel, or an IP address, which
+cannot be rebound. Any other host gets 403.
+"""
+
+import ipaddress
+from typing import Iterable, Optional
+
+from flask import jsonify, request
+
+from core.runtime_settings import cors_allows
+from cli.devserver.runtime_settings import get_runtime_settings
+
+SAFE_METHODS = ('GET', 'HEAD', 'OPTIONS')
+TOOLING_PREFIXES = ('/admin', '/__', '/edit', '/api/edit', '/api/build', '/api/branches', '/api/vault', '/api/chaos',
+                    '/api/features', '/api/permissions', '/api/preview', '/api/tester', '/api/_cache', '/api/_mail',
+                    '/api/notifications', '/api/webhooks', '/api/a11y', '/api/render', '/desktop/api')
+
+LOCAL_HOSTS = ('localhost', '127.0.0.1', '::1')
+
+def host_name(host: str) -> str:
+    """host without its port and brackets, lowercased"""
+    host = host.strip().lower()
+    if host.startswith('['):
+        return host[1:].partition(']')[0]
+    if host.count(':') == 1:
+        host = host.partition(':')[0]
+    return host.rstrip('.')
+
+def host_refusal(host: str, allowed: Iterable[str]) -> Optional[str]:
+    """Why a request for host is refused, or None when the dev server answers to it"""
+    name = host_name(host or '')
+    if name in {host_name(known) for known in allowed if known}:
+        return None
+    try:
+        ipaddress.ip_address(name)
+        return None
+    except ValueError:
+        return f"host {host!r}; the dev server only answers to localhost, its bind address and its share tunnel"
+
+def own_origin() -> str:
+    """The origin the dev server's pages are served from, as the browser sees it"""
+    return request.host_url.rstrip('/').lower()
+
+def origin_refusal(cors) -> Optional[str]:
+    """Why the current request may not change anything, or None when it may"""
+    if request.method in SAFE_METHODS:
+        return None
+    origin = request.headers.get('Origin')
+    if origin is None:
+        if request.headers.get('Sec-Fetch-Site') == 'cross-site':
+            return "a page on another site"
+        return None
+    if origin.lower() == own_origin():
+        return None
+    if request.path.startswith(TOOLING_PREFIXES):
+        return f"{origin}; the dev server's tools only take changes from its own pages"
+    if cors_allows(cors, origin):
+        return None
+    return f"{origin}, which the cors settings in /admin/settings do not allow"
+
+def register_origin_check(app, hosts: Iterable[str] = ()):
+    """Refuse requests for unknown hosts, and changes browsers send from other origins
+
+    hosts are the names the dev server answers to besides LOCAL_HOSTS and IP addresses.
+    Register ahead of every other before_request hook.
+    """
+    settings = get_runtime_settings(app)
+    allowed = LOCAL_HOSTS + tuple(hosts)
+
+    @app.before_request
+    def check_origin():
+        refusal = host_refusal(request.host, allowed)
+        if refusal:
+            return jsonify({'error': f"Refused a request for {refusal}"}), 403
+        refusal = origin_refusal(settings.value('cors'))
+        if refusal:
+            return jsonify({'error': f"Refused a {request.method} from {refusal}"}), 403
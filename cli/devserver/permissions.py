@@ -0,0 +1,235 @@
+"""
+FlashFlow dev permissions - Enforce 'permissions:' from flow pages and endpoints
+
+A request acts as (first match wins):
+
+    Authorization: Basic <email:password>      a user from /admin/users, with the role and scopes set there
+    X-API-Key / a signed request               a key from /admin/api-keys, on 'auth: api_key' endpoints
+    X-FlashFlow-Role (+ X-FlashFlow-Scopes)    impersonation for scripts and tests
+    the 'view as' cookie                        set by the switcher on previews
+
+Anonymous requests to a protected path get 401, others that fall short get
+403 with the requirement that failed. Previews carry a "view as" switcher
+that shows which pages and endpoints the chosen role can reach.
+"""
+
+import base64
+import logging
+from typing import List, Optional
+from urllib.parse import urlencode, parse_qs
+
+from flask import request, jsonify, g
+
+from core.api_keys import ApiKeyRule, collect_api_key_rules
+from core.parser.flow_file import FlowDocument
+from core.permissions import AccessRule, Principal, ROLES, collect_rules
+from cli.devserver.admin_users import get_auth_store
+from cli.devserver.audit import audit_admin
+from cli.devserver.flow_registry import FlowRegistry, FlowSectionRegistry, get_flow_registry
+
+logger = logging.getLogger(__name__)
+
+VIEW_AS_COOKIE = 'flashflow_view_as'
+PREVIEW_PATHS = ('/preview', '/android', '/ios', '/desktop')
+
+class PermissionRegistry(FlowSectionRegistry):
+    """Access rules and API key requirements from every flow file, re-read when the files change"""
+
+    def __init__(self, flows: FlowRegistry):
+        super().__init__(flows)
+        self.rules: List[AccessRule] = []
+        self.key_rules: List[ApiKeyRule] = []
+
+    def _load(self, documents: List[FlowDocument]):
+        rules, key_rules, errors = [], [], []
+        for document in documents:
+            file_rules, file_errors = collect_rules(document.data, document.file.name)
+            file_key_rules, file_key_errors = collect_api_key_rules(document.data, document.file.name)
+            rules.extend(file_rules)
+            key_rules.extend(file_key_rules)
+            errors.extend(file_errors + file_key_errors)
+        self.rules, self.key_rules, self.errors = rules, key_rules, errors
+        for error in errors:
+            logger.warning(f"Invalid permissions or auth in {error}")
+
+    def matching(self, method: str, path: str) -> List[AccessRule]:
+        return [rule for rule in self.rules if rule.matches(method, path)]
+
+    def matching_key_rules(self, method: str, path: str) -> List[ApiKeyRule]:
+        return [rule for rule in self.key_rules if rule.matches(method, path)]
+
+def get_permission_registry(app) -> PermissionRegistry:
+    if 'PERMISSION_REGISTRY' not in app.config:
+        app.config['PERMISSION_REGISTRY'] = PermissionRegistry(get_flow_registry(app))
+    return app.config['PERMISSION_REGISTRY']
+
+def resolve_principal(app) -> Optional[Principal]:
+    """The principal for the current request, or None for bad Basic credentials"""
+    # Set by the API key check on 'auth: api_key' endpoints
+    if g.get('api_key_principal') is not None:
+        return g.api_key_principal
+
+    auth = request.headers.get('Authorization', '')
+    if auth.lower().startswith('basic '):
+        try:
+            email, _, password = base64.b64decode(auth[6:].strip()).decode('utf-8').partition(':')
+        except (ValueError, UnicodeDecodeError):
+            return None
+        user = get_auth_store(app).authenticate(email, password)
+        if not user:
+            return None
+        # A header cannot add scopes to a real user; they are set in /admin/users
+        return Principal(user['role'], list(user.get('scopes') or []), user['email'], 'basic')
+
+    if request.headers.get('X-FlashFlow-Role'):
+        return _impersonate(request.headers['X-FlashFlow-Role'], request.headers.get('X-FlashFlow-Scopes'), 'header')
+
+    view_as = parse_qs(request.cookies.get(VIEW_AS_COOKIE, ''))
+    if view_as.get('role'):
+        return _impersonate(view_as['role'][0], (view_as.get('scopes') or [''])[0], 'view-as')
+    return Principal()
+
+def _impersonate(role: str, scopes: Optional[str], source: str) -> Principal:
+    role = role.strip().lower()
+    # An unknown role acts as anonymous rather than erroring on every request
+    return Principal(role if role in ROLES else None, _scopes(scopes), None, source)
+
+def _scopes(value: Optional[str]) -> List[str]:
+    return [scope.strip() for scope in (value or '').split(',') if scope.strip()]
+
+def register_permissions(app):
+    """Install the permission check and the /api/permissions endpoints"""
+    registry = get_permission_registry(app)
+
+    @app.before_request
+    def enforce_permissions():
+        registry.reload_if_changed()
+        rules = registry.matching(request.method, request.path)
+        if not rules:
+            return None
+
+        principal = resolve_principal(app)
+        if principal is None:
+            return _unauthorized("Invalid credentials")
+        g.flashflow_principal = principal
+
+        for rule in rules:
+            reason = rule.permission.denial(principal)
+            if reason is None:
+                continue
+            if not principal.authenticated:
+                return _unauthorized(reason, rule)
+            return jsonify({'error': reason, 'path': rule.path, 'required': rule.permission.to_dict(),
+                            'principal': principal.to_dict()}), 403
+        return None
+
+    @app.after_request
+    def inject_view_as_switcher(response):
+        if (response.mimetype != 'text/html' or response.status_code != 200 or response.is_streamed
+                or request.path not in PREVIEW_PATHS):
+            return response
+        html = response.get_data(as_text=True)
+        index = html.lower().rfind('</body>')
+        response.set_data(html[:index] + VIEW_AS_SCRIPT + html[index:] if index != -1 else html + VIEW_AS_SCRIPT)
+        return response
+
+    @app.route('/api/permissions', methods=['GET'])
+    def api_permissions():
+        """Declared rules and whether the current principal passes each one"""
+        registry.reload_if_changed()
+        principal = resolve_principal(app) or Principal()
+        rules = []
+        for rule in registry.rules:
+            reason = rule.permission.denial(principal)
+            rules.append(dict(rule.to_dict(), allowed=reason is None, reason=reason))
+        # Rules of a flow that stopped parsing are those of its last version that did
+        stale = [f"{name} does not parse, so its permissions may be out of date" for name in registry.flows.failures]
+        return jsonify({'principal': principal.to_dict(), 'roles': ROLES, 'rules': rules, 'errors': registry.errors + stale})
+
+    @app.route('/api/permissions/view-as', methods=['POST'])
+    def api_view_as():
+        """Set (or with an empty role, clear) the role previews and requests act as"""
+        data = request.get_json(silent=True) or {}
+        role = str(data.get('role') or '').strip().lower()
+        if role and role not in ROLES:
+            return jsonify({'error': f"Unknown role '{role}'; roles are {', '.join(ROLES)}"}), 400
+
+        scopes = data.get('scopes') or []
+        if isinstance(scopes, str):
+            scopes = _scopes(scopes)
+        response = jsonify({'role': role or None, 'scopes': scopes if role else []})
+        audit_admin(app, 'view_as.set' if role else 'view_as.clear', 'view_as',
+                    new={'role': role or None, 'scopes': scopes if role else []})
+        if role:
+            response.set_cookie(VIEW_AS_COOKIE, urlencode({'role': role, 'scopes': ','.join(scopes)}), samesite='Lax')
+        else:
+            response.delete_cookie(VIEW_AS_COOKIE)
+        return response
+
+def _unauthorized(reason: str, rule: AccessRule = None):
+    body = {'error': reason}
+    if rule:
+        body.update(path=rule.path, required=rule.permission.to_dict())
+    response = jsonify(body)
+    response.status_code = 401
+    response.headers['WWW-Authenticate'] = 'Basic realm="FlashFlow dev server"'
+    return response
+
+# Floating role picker; lists what the chosen role can reach
+VIEW_AS_SCRIPT = """
+<div id="flashflow-view-as" style="position:fixed;bottom:12px;left:12px;z-index:99998;font:13px sans-serif;">
+    <button type="button" id="flashflow-view-as-toggle"
+        style="padding:6px 12px;border:none;border-radius:16px;color:#fff;cursor:pointer;box-shadow:0 2px 6px rgba(0,0,0,0.3);background:linear-gradient(135deg, #667eea 0%, #764ba2 100%);">
+        👤 View as: <span id="flashflow-view-as-current">anonymous</span>
+    </button>
+    <div id="flashflow-view-as-panel" hidden
+        style="margin-bottom:8px;position:absolute;bottom:100%;left:0;width:320px;max-height:60vh;overflow:auto;background:#fff;color:#333;border-radius:8px;padding:12px;box-shadow:0 4px 16px rgba(0,0,0,0.25);">
+        <label>Role
+            <select id="flashflow-view-as-role" style="width:100%;margin:4px 0 8px;"></select>
+        </label>
+        <label>Scopes (comma separated)
+            <input id="flashflow-view-as-scopes" style="width:100%;margin:4px 0 8px;box-sizing:border-box;" placeholder="todos:write">
+        </label>
+        <button type="button" id="flashflow-view-as-apply">Apply</button>
+        <ul id="flashflow-view-as-rules" style="list-style:none;padding:0;margin:12px 0 0;"></ul>
+    </div>
+</div>
+<script>
+    (function () {
+        const $ = function (id) { return document.getElementById('flashflow-view-as-' + id); };
+
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+
+        async function load() {
+            const data = await (await fetch('/api/permissions')).json();
+            $('current').textContent = data.principal.role || 'anonymous';
+            $('role').innerHTML = '<option value="">anonymous</option>' + data.roles.map(function (role) {
+                return '<option' + (role === data.principal.role ? ' selected' : '') + '>' + escapeHtml(role) + '</option>';
+            }).join('');
+            $('scopes').value = (data.principal.scopes || []).join(', ');
+            $('rules').innerHTML = data.rules.length ? data.rules.map(function (rule) {
+                return '<li style="padding:4px 0;border-top:1px solid #eee;">' + (rule.allowed ? '✅' : '🚫') + ' '
+                    + '<code>' + escapeHtml(rule.methods.join(',')) + ' ' + escapeHtml(rule.path) + '</code>'
+                    + (rule.allowed ? '' : '<br><small style="color:#b91c1c;">' + escapeHtml(rule.reason) + '</small>') + '</li>';
+            }).join('') : '<li><small>No pages or endpoints declare permissions.</small></li>';
+        }
+
+        $('toggle').addEventListener('click', function () {
+            $('panel').hidden = !$('panel').hidden;
+        });
+        $('apply').addEventListener('click', async function () {
+            await fetch('/api/permissions/view-as', {
+                method: 'POST',
+                headers: {'Content-Type': 'application/json'},
+                body: JSON.stringify({role: $('role').value, scopes: $('scopes').value})
+            });
+            location.reload();
+        });
+        load().catch(function (e) { console.warn('FlashFlow view-as switcher failed', e); });
+    })();
+</script>
+"""
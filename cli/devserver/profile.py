@@ -0,0 +1,61 @@
+"""
+FlashFlow dev server profile - The selected environment profile for pages
+
+Rendered pages get window.FLASHFLOW_ENV (name, backend_url and features of
+the profile picked with 'serve --env') ahead of their own scripts, and
+/api/profile returns the same plus the names of keys that are not set. A
+backend_url on localhost is swapped for the page's own origin when the page
+was opened through a tunnel or proxy, where localhost is the visitor's
+machine.
+"""
+
+import json
+
+from flask import request, jsonify
+
+from core.profiles import Profile, project_environ
+
+# The dev server's own pages don't read the profile
+EXCLUDED_PREFIXES = ('/admin', '/api', '/__')
+
+LOCAL_BACKEND_SCRIPT = (
+    "(function (env) {"
+    " var local = ['localhost', '127.0.0.1', '[::1]'];"
+    " try {"
+    " var url = new URL(env.backend_url);"
+    " if (local.indexOf(url.hostname) !== -1 && local.indexOf(location.hostname) === -1)"
+    " env.backend_url = location.origin + url.pathname.replace(/\\/$/, '');"
+    " } catch (e) {}"
+    " })(window.FLASHFLOW_ENV);"
+)
+
+def profile_script(profile: Profile) -> str:
+    """The HTML injected into pages; '</' is escaped so values cannot close the script
+
+    The same for every request, so the CSP can allow it by hash.
+    """
+    data = json.dumps(profile.public(), sort_keys=True).replace('</', '<\\/')
+    fix = LOCAL_BACKEND_SCRIPT if profile.backend_url else ''
+    return f"<script>window.FLASHFLOW_ENV = {data};{fix}</script>"
+
+def register_profile(app, profile: Profile):
+    """Inject the public profile into rendered pages and register /api/profile"""
+    script = profile_script(profile)
+
+    @app.after_request
+    def inject_profile(response):
+        if (response.mimetype != 'text/html' or response.status_code != 200 or response.is_streamed
+                or request.path.startswith(EXCLUDED_PREFIXES)):
+            return response
+        html = response.get_data(as_text=True)
+        lower = html.lower()
+        head = lower.find('<head')
+        index = lower.find('>', head) + 1 if head != -1 else 0
+        response.set_data(html[:index] + script + html[index:])
+        return response
+
+    @app.route('/api/profile')
+    def api_profile():
+        """The selected profile as pages see it, plus which keys are missing"""
+        project = app.config['PROJECT']
+        return jsonify(dict(profile.public(), missing_keys=profile.missing_keys(project.root_path, project_environ(project))))
@@ -0,0 +1,32 @@
+"""
+FlashFlow dev server proxy - Trusted forwarding headers and the HTTPS redirect
+
+ProxyMiddleware (core/proxy.py) wraps the whole app, outside the
+transforms and any middleware passed to DevServer, so request logs,
+redirects and links all see the client's address and the public
+URL. GET /__proxy shows the settings and what they made of the request
+itself, for checking a tunnel or proxy setup:
+
+    {"trusted": ["127.0.0.1/32", "::1/128"], "https_redirect": false,
+     "request": {"peer": "127.0.0.1", "client": "203.0.113.7", "scheme": "https",
+                 "host": "demo.loca.lt", "prefix": null, "proxies": 1, "url": "https://demo.loca.lt/"}}
+"""
+
+from typing import Optional
+
+from flask import request, jsonify
+
+from core.proxy import ENVIRON_KEY, ProxyConfig, ProxyMiddleware
+
+def register_proxy(app, https_redirect: Optional[bool] = None) -> ProxyConfig:
+    """Register /__proxy and wrap the app; call after every other middleware"""
+    config = ProxyConfig.for_project(app.config['PROJECT'], https_redirect)
+    app.config['PROXY'] = config
+
+    @app.route('/__proxy')
+    def proxy_info():
+        seen = {key: value for key, value in (request.environ.get(ENVIRON_KEY) or {}).items() if key != 'original'}
+        return jsonify(dict(config.to_dict(), request=dict(seen, url=request.url_root)))
+
+    app.wsgi_app = ProxyMiddleware(app.wsgi_app, config)
+    return config
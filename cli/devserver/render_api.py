@@ -0,0 +1,120 @@
+"""
+FlashFlow dev render API - Flow components rendered to HTML over HTTP
+
+    POST /api/render/component                     {"component": {...}} or {"components": [...]}
+    GET  /api/render/uploads/<id>/<filename>       a file sent with a multipart render request
+
+A JSON request is refused with 413 when its Content-Length is over
+render_api.max_body_kb, before any of it is read; a chunked one is read
+until it passes the limit. Components that show uploaded files are sent as
+multipart/form-data (see core/render_api.py), which Werkzeug parses as it
+arrives, spilling file parts to temporary files, so a large upload is
+never held in memory whole.
+
+One component answers {"html", "problems"}; a batch answers
+{"results": [{"id", "html", "problems"}, ...]}. Both name the stylesheet
+the HTML is written for.
+"""
+
+import json
+from pathlib import Path
+from typing import Any, Dict, Optional, Tuple
+
+from flask import jsonify, request, send_file
+
+from core.framework import FlashFlowProject
+from core.render_api import (BodyTooLarge, LengthRequired, RenderApiError, RenderLimits, UploadStore, read_limited,
+                             render_items, safe_filename, substitute_uploads)
+from core.render_context import RenderProblem, compile_components
+from core.static_site import StaticPage
+from cli.devserver.device_farm import get_renderer
+
+STYLESHEET = '/preview/site.css'
+
+def get_upload_store(app) -> UploadStore:
+    if 'RENDER_UPLOADS' not in app.config:
+        project: FlashFlowProject = app.config['PROJECT']
+        app.config['RENDER_UPLOADS'] = UploadStore(project.state.path("render-uploads"))
+    return app.config['RENDER_UPLOADS']
+
+def _read_json(limits: RenderLimits) -> Any:
+    if request.content_length is not None and request.content_length > limits.max_body:
+        raise BodyTooLarge(limits.max_body)
+    raw = read_limited(request.stream, limits.max_body)
+    try:
+        return json.loads(raw.decode('utf-8'))
+    except (UnicodeDecodeError, ValueError):
+        raise RenderApiError("The request body is not valid JSON")
+
+def _read_multipart(app, limits: RenderLimits) -> Tuple[Any, Dict[str, str], Optional[str]]:
+    """The request JSON, the URL of each uploaded part by name, and the upload id"""
+    if request.content_length is None:
+        raise LengthRequired("Send Content-Length with a multipart render request")
+    if request.content_length > limits.max_upload:
+        raise BodyTooLarge(limits.max_upload)
+    if 'request' not in request.form:
+        raise RenderApiError("A multipart render request needs its JSON as the 'request' part")
+    try:
+        data = json.loads(request.form['request'])
+    except ValueError:
+        raise RenderApiError("The 'request' part is not valid JSON")
+    if not request.files:
+        return data, {}, None
+
+    upload_id, directory = get_upload_store(app).create()
+    urls = {}
+    for name, upload in request.files.items():
+        # Named after the part, so two parts with the same file name do not overwrite each other
+        filename = safe_filename(name) + Path(safe_filename(upload.filename or '')).suffix
+        upload.save(str(directory / filename))
+        urls[name] = f"/api/render/uploads/{upload_id}/{filename}"
+    return data, urls, upload_id
+
+def _render(app, items, batch: bool, urls: Dict[str, str]) -> Dict[str, Any]:
+    renderer = get_renderer(app)
+    known = renderer.component_types()
+    context = renderer.context(StaticPage('/', {}))
+    results = []
+    for index, (item_id, component) in enumerate(items):
+        missing = []
+        component = substitute_uploads(component, urls, missing)
+        components, problems = compile_components([component], known, 'components')
+        where = f"components[{index}]" if batch else 'component'
+        for problem in problems:
+            problem.path = where + problem.path[len('components[0]'):]
+        problems += [RenderProblem(where, '', f"no upload named '{name}'") for name in missing]
+        results.append({'id': item_id, 'html': renderer.render_components(components, context),
+                        'problems': [str(problem) for problem in problems]})
+    body = {'results': results} if batch else {'html': results[0]['html'], 'problems': results[0]['problems']}
+    body.update(uploads=urls, stylesheet=STYLESHEET)
+    return body
+
+def register_render_api(app):
+    """Register /api/render/component and the uploads it serves"""
+
+    @app.route('/api/render/component', methods=['POST'])
+    def render_component_api():
+        upload_id = None
+        try:
+            limits = RenderLimits.from_config(app.config['PROJECT'])
+            if request.mimetype == 'multipart/form-data':
+                data, urls, upload_id = _read_multipart(app, limits)
+            else:
+                data, urls = _read_json(limits), {}
+            items, batch = render_items(data, limits.max_batch)
+        except BodyTooLarge as e:
+            return jsonify({'error': str(e), 'limit': e.limit}), 413
+        except LengthRequired as e:
+            return jsonify({'error': str(e)}), 411
+        except RenderApiError as e:
+            if upload_id:
+                get_upload_store(app).discard(upload_id)
+            return jsonify({'error': str(e)}), 400
+        return jsonify(_render(app, items, batch, urls))
+
+    @app.route('/api/render/uploads/<upload_id>/<filename>')
+    def render_upload(upload_id, filename):
+        path = get_upload_store(app).resolve(upload_id, filename)
+        if path is None:
+            return jsonify({'error': 'Upload not found or expired'}), 404
+        return send_file(str(path))
@@ -0,0 +1,139 @@
+"""
+FlashFlow request contexts - Cancelling long FlashCore work once its request goes away
+
+Vector searches, model predictions and embeddings run with a CancelToken
+(core/cancellation.py) for their request, which is cancelled when:
+
+    the client disconnects            the socket is watched while the work runs
+    X-Request-Timeout: <seconds>      the request's deadline passes (at most MAX_TIMEOUT)
+    DELETE /__requests/<id>           someone cancels it by its X-Request-ID
+
+GET /__requests lists the requests that can be cancelled right now. A
+cancelled request answers 499 (client gone or cancelled) or 504 (deadline)
+with the reason as "cancelled".
+"""
+
+import select
+import socket
+import threading
+import time
+import uuid
+from contextlib import contextmanager
+from typing import Any, Dict, Iterator, List, Optional
+
+from flask import g, request, jsonify
+
+from core.cancellation import CancelToken, Cancelled
+from cli.devserver.request_log import current_request_id
+
+TIMEOUT_HEADER = 'X-Request-Timeout'
+MAX_TIMEOUT = 600.0
+# How often the socket and the deadline are looked at while work runs
+POLL_INTERVAL = 0.1
+CLIENT_CLOSED = 'client disconnected'
+
+class RequestContexts:
+    """Tokens of the requests running cancellable work, by request id"""
+
+    def __init__(self):
+        self._lock = threading.Lock()
+        self._active: Dict[str, Dict[str, Any]] = {}
+
+    def start(self, request_id: str, method: str, path: str, timeout: Optional[float]) -> CancelToken:
+        token = CancelToken(timeout)
+        with self._lock:
+            self._active[request_id] = {'token': token, 'method': method, 'path': path, 'started': time.time()}
+        return token
+
+    def finish(self, request_id: str):
+        with self._lock:
+            self._active.pop(request_id, None)
+
+    def cancel(self, request_id: str, reason: str = 'cancelled') -> bool:
+        with self._lock:
+            entry = self._active.get(request_id)
+        if entry is None:
+            return False
+        entry['token'].cancel(reason)
+        return True
+
+    def to_list(self) -> List[Dict[str, Any]]:
+        now = time.time()
+        with self._lock:
+            entries = sorted(self._active.items(), key=lambda item: item[1]['started'])
+        return [{'request_id': request_id, 'method': entry['method'], 'path': entry['path'],
+                 'running_ms': round((now - entry['started']) * 1000, 1),
+                 'remaining_seconds': entry['token'].remaining(), 'cancelled': entry['token'].reason}
+                for request_id, entry in entries]
+
+def get_request_contexts(app) -> RequestContexts:
+    if 'REQUEST_CONTEXTS' not in app.config:
+        app.config['REQUEST_CONTEXTS'] = RequestContexts()
+    return app.config['REQUEST_CONTEXTS']
+
+def client_gone(sock) -> bool:
+    """Whether the peer closed the connection: readable with nothing to read"""
+    try:
+        readable, _, _ = select.select([sock], [], [], 0)
+        return bool(readable) and sock.recv(1, socket.MSG_PEEK) == b''
+    except ValueError:
+        # TLS sockets cannot peek; assume the client is still there
+        return False
+    except OSError:
+        return True
+
+def _watch(sock, token: CancelToken, done: threading.Event):
+    while not done.wait(POLL_INTERVAL):
+        # Looking at the token is what cancels it once the deadline passes
+        if token.cancelled:
+            return
+        if sock is not None and client_gone(sock):
+            token.cancel(CLIENT_CLOSED)
+            return
+
+@contextmanager
+def cancellable(app) -> Iterator[CancelToken]:
+    """The current request's token for the work in the block; read the request body before entering"""
+    contexts = get_request_contexts(app)
+    request_id = current_request_id() or uuid.uuid4().hex
+    token = contexts.start(request_id, request.method, request.path, g.get('request_timeout'))
+    done = threading.Event()
+    threading.Thread(target=_watch, args=(request.environ.get('werkzeug.socket'), token, done),
+                     name=f"request-{request_id[:8]}", daemon=True).start()
+    try:
+        yield token
+    finally:
+        done.set()
+        contexts.finish(request_id)
+
+def cancelled_response(error: Cancelled):
+    return jsonify({'error': str(error), 'cancelled': error.reason}), 504 if error.deadline else 499
+
+def register_request_context(app):
+    """Read X-Request-Timeout and register /__requests"""
+    contexts = get_request_contexts(app)
+
+    @app.before_request
+    def read_request_timeout():
+        value = request.headers.get(TIMEOUT_HEADER)
+        if value is None:
+            return None
+        try:
+            timeout = float(value)
+        except ValueError:
+            timeout = -1.0
+        if not 0 < timeout <= MAX_TIMEOUT:
+            return jsonify({'error': f"{TIMEOUT_HEADER} must be a number of seconds up to {MAX_TIMEOUT:g}"}), 400
+        g.request_timeout = timeout
+        return None
+
+    @app.route('/__requests', methods=['GET'])
+    def running_requests():
+        return jsonify({'requests': contexts.to_list()})
+
+    @app.route('/__requests/<request_id>', methods=['DELETE'])
+    def cancel_request(request_id):
+        if not contexts.cancel(request_id):
+            return jsonify({'error': f"No cancellable request '{request_id}' is running"}), 404
+        app.logger.info(f"🛑 Cancelled request {request_id}")
+        return '', 204
@@ -0,0 +1,134 @@
+"""
+FlashFlow request IDs and access log - X-Request-ID on every dev server request
+
+Every request gets an id: the client's own X-Request-ID when it sends a
+sane one, otherwise a fresh one. It is echoed in the X-Request-ID response
+header, added to JSON error bodies as "request_id", passed on to API
+workers and written with each request to .flashflow/logs/access.log, one
+JSON object per line:
+
+    {"ts": "2026-10-16T09:12:03.412Z", "request_id": "9f2c...", "method": "POST",
+     "path": "/api/data/orders", "status": 500, "duration_ms": 12.4, ...}
+
+The log rotates by size; tune or turn it off in flashflow.json:
+
+    "access_log": {"max_bytes": 5242880, "backups": 5, "enabled": true}
+"""
+
+import json
+import logging
+import re
+import time
+import uuid
+from html import escape
+from logging.handlers import RotatingFileHandler
+from typing import Any, Dict, Optional
+
+from flask import Response, request, jsonify, g
+
+REQUEST_ID_HEADER = 'X-Request-ID'
+# Incoming ids end up in logs and headers, so only plain tokens are adopted
+REQUEST_ID_PATTERN = re.compile(r'[A-Za-z0-9._-]{1,128}')
+LOG_NAME = 'access.log'
+DEFAULT_MAX_BYTES = 5 * 1024 * 1024
+DEFAULT_BACKUPS = 5
+# Polled by every open page and by 'flashflow dash'; they would drown out real traffic
+QUIET_PATHS = ('/__reload', '/__stats', '/__workers', '/__build')
+
+def current_request_id() -> Optional[str]:
+    return g.get('request_id')
+
+def access_logger(project) -> Optional[logging.Logger]:
+    """A logger writing JSON lines to .flashflow/logs/access.log, or None when the access log is off"""
+    settings = project.config.access_log or {}
+    if not settings.get('enabled', True):
+        return None
+    path = project.state.logs_dir / LOG_NAME
+    logger = logging.getLogger(f"flashflow.access.{path}")
+    if not logger.handlers:
+        handler = RotatingFileHandler(path, maxBytes=int(settings.get('max_bytes', DEFAULT_MAX_BYTES)),
+                                      backupCount=int(settings.get('backups', DEFAULT_BACKUPS)), encoding='utf-8')
+        handler.setFormatter(logging.Formatter('%(message)s'))
+        logger.addHandler(handler)
+        logger.setLevel(logging.INFO)
+        # Keep access lines out of the server's console output
+        logger.propagate = False
+    return logger
+
+def access_record(response, duration: float) -> Dict[str, Any]:
+    now = time.time()
+    record = {
+        'ts': time.strftime('%Y-%m-%dT%H:%M:%S', time.gmtime(now)) + f".{int(now % 1 * 1000):03d}Z",
+        'request_id': current_request_id(),
+        'method': request.method,
+        'path': request.path,
+        'query': request.query_string.decode('utf-8', 'replace'),
+        'status': response.status_code,
+        'duration_ms': round(duration * 1000, 1),
+        'bytes': response.calculate_content_length(),
+        'remote_addr': request.remote_addr,
+        'user_agent': request.headers.get('User-Agent', '')
+    }
+    span = g.get('trace_span')
+    if span is not None:
+        record['trace_id'] = span.context.trace_id
+    if g.get('api_worker') is not None:
+        record['worker'] = g.api_worker
+    if g.get('generated_backend'):
+        record['backend'] = g.generated_backend
+    if g.get('api_key'):
+        record['api_key'] = g.api_key
+    return record
+
+def add_request_id(response):
+    """Put the id into a JSON error body ({"error": ...}) so it shows wherever the error is displayed"""
+    if response.status_code < 400 or not response.is_json or response.is_streamed:
+        return
+    body = response.get_json(silent=True)
+    if isinstance(body, dict) and 'error' in body and 'request_id' not in body:
+        body['request_id'] = current_request_id()
+        response.set_data(json.dumps(body))
+
+def register_request_log(app, access_log: bool = True):
+    """Assign request ids and write the access log; register first, so every later hook sees g.request_id
+
+    API workers register it with access_log=False: they adopt the id the
+    main server forwards, and the main server logs the request once.
+    """
+    logger = access_logger(app.config['PROJECT']) if access_log else None
+    app.config['ACCESS_LOG'] = logger.handlers[0].baseFilename if logger else None
+
+    @app.before_request
+    def assign_request_id():
+        incoming = request.headers.get(REQUEST_ID_HEADER, '')
+        g.request_id = incoming if REQUEST_ID_PATTERN.fullmatch(incoming) else uuid.uuid4().hex
+        g.request_started = time.perf_counter()
+        return None
+
+    @app.after_request
+    def log_request(response):
+        request_id = current_request_id()
+        if request_id is None:
+            return response
+        response.headers[REQUEST_ID_HEADER] = request_id
+        add_request_id(response)
+        if logger is not None and not request.path.startswith(QUIET_PATHS):
+            try:
+                logger.info(json.dumps(access_record(response, time.perf_counter() - g.request_started),
+                                       default=str))
+            except Exception as e:
+                app.logger.warning(f"Could not write access log: {e}")
+        return response
+
+    @app.errorhandler(500)
+    def server_error(error):
+        # Flask has already logged the exception and sent got_request_exception (crash reports)
+        cause = getattr(error, 'original_exception', None) or error
+        name = type(cause).__name__
+        if request.path.startswith('/api') or request.accept_mimetypes.best == 'application/json':
+            return jsonify({'error': f"Internal server error: {name}", 'request_id': current_request_id()}), 500
+        return Response(f"<h1>500 Internal Server Error</h1><p>{escape(name)} while handling "
+                        f"{escape(request.method)} {escape(request.path)}.</p>"
+                        f"<p>Request ID: <code>{escape(current_request_id() or '')}</code> "
+                        f"(see .flashflow/logs/{LOG_NAME} and .flashflow/crashes)</p>",
+                        status=500, mimetype='text/html')
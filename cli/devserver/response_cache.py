@@ -0,0 +1,215 @@
+"""
+FlashFlow dev response cache - Cache-Status for /api/data reads, and /admin/cache
+
+List and show endpoints of the generated API go through cached_get(), which
+answers from core/response_cache.py when it can and says so in a Cache-Status
+header (RFC 9211):
+
+    Cache-Status: flashflow; hit; ttl=42
+    Cache-Status: flashflow; fwd=miss; stored; ttl=60
+    Cache-Status: flashflow; fwd=request; stored; ttl=60    (the request sent Cache-Control: no-cache)
+    Cache-Status: flashflow; fwd=bypass                      (the model is not cached)
+
+Writes call invalidate_table(). /admin/cache lists the entries with their
+tags, TTLs and hits, and flushes one entry, a tag or everything.
+"""
+
+import time
+from typing import Callable, Optional
+
+import click
+from flask import Response, g, jsonify, render_template_string, request
+
+from core.response_cache import ResponseCache, ResponseCacheError
+
+CACHE_NAME = 'flashflow'
+# What a cached response keeps; everything else is added again per request
+STORED_HEADERS = ('Content-Type', 'ETag', 'Link', 'X-Total-Count')
+
+def get_response_cache(app) -> Optional[ResponseCache]:
+    """The project's response cache, or None when it is off or misconfigured"""
+    if 'RESPONSE_CACHE' not in app.config:
+        try:
+            cache = ResponseCache.for_project(app.config['PROJECT'], shared=app.config.get('SHARED_RESPONSE_CACHE', False))
+        except ResponseCacheError as e:
+            click.echo(f"⚠️  Response cache is off: {str(e)}")
+            cache = None
+        app.config['RESPONSE_CACHE'] = cache
+    return app.config['RESPONSE_CACHE']
+
+def _cache_key() -> str:
+    # Link headers carry the host, and parameter order should not matter
+    query = '&'.join(f"{name}={value}" for name, value in sorted(request.args.items(multi=True)))
+    return f"{request.host}{request.path}" + (f"?{query}" if query else '')
+
+def cached_get(app, model: str, table: str, build: Callable[[], object]) -> Response:
+    """The response of build() for this GET, from the cache when there is a fresh one"""
+    cache = get_response_cache(app)
+    # A sandbox's rows are its own; the cache holds the shared database's
+    ttl = cache.ttl_for(model) if cache and not g.get('sandbox') else None
+    if ttl is None:
+        response = app.make_response(build())
+        if cache:
+            response.headers['Cache-Status'] = f"{CACHE_NAME}; fwd=bypass"
+        return response
+
+    key = _cache_key()
+    no_cache = 'no-cache' in request.headers.get('Cache-Control', '') or 'no-cache' in request.headers.get('Pragma', '')
+    if not no_cache:
+        entry = cache.get(key)
+        if entry is not None:
+            response = Response(entry.body, status=entry.status)
+            for name, value in entry.headers.items():
+                response.headers[name] = value
+            response.headers['Age'] = str(max(0, int(time.time() - entry.created_at)))
+            response.headers['Cache-Status'] = f"{CACHE_NAME}; hit; ttl={entry.ttl()}"
+            return response
+
+    response = app.make_response(build())
+    forward = 'request' if no_cache else 'miss'
+    if response.status_code == 200:
+        headers = {name: response.headers[name] for name in STORED_HEADERS if name in response.headers}
+        cache.put(key, response.status_code, response.get_data(), headers, [table], ttl)
+        response.headers['Cache-Status'] = f"{CACHE_NAME}; fwd={forward}; stored; ttl={int(ttl)}"
+    else:
+        response.headers['Cache-Status'] = f"{CACHE_NAME}; fwd={forward}"
+    return response
+
+def invalidate_table(app, table: str) -> int:
+    """Drop every cached response read from a table that was just written"""
+    cache = get_response_cache(app)
+    return cache.invalidate(table) if cache else 0
+
+def register_response_cache(app):
+    """Register /admin/cache and its JSON API at /api/_cache"""
+
+    @app.route('/api/_cache', methods=['GET'])
+    def response_cache_entries():
+        cache = get_response_cache(app)
+        if cache is None:
+            return jsonify({'enabled': False, 'entries': []})
+        return jsonify({'enabled': True, 'stats': cache.stats(), 'entries': [entry.to_dict() for entry in cache.entries()]})
+
+    @app.route('/api/_cache', methods=['DELETE'])
+    def response_cache_flush():
+        """Flush everything, the entries of one tag (?tag=) or one entry (?key=)"""
+        cache = get_response_cache(app)
+        if cache is None:
+            return jsonify({'error': "The response cache is off; add response_cache to flashflow.json"}), 404
+        if request.args.get('key'):
+            if not cache.delete(request.args['key']):
+                return jsonify({'error': 'Entry not found'}), 404
+            return jsonify({'removed': 1})
+        if request.args.get('tag'):
+            return jsonify({'removed': cache.invalidate(request.args['tag']), 'tag': request.args['tag']})
+        return jsonify({'removed': cache.clear()})
+
+    @app.route('/admin/cache')
+    def admin_cache_page():
+        project = app.config['PROJECT']
+        return render_template_string(CACHE_TEMPLATE, project_name=project.config.name)
+
+CACHE_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Response Cache - FlashFlow Admin</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+        .container { max-width: 1400px; margin: 0 auto; padding: 2rem; }
+        .panel { background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); margin-bottom: 1.5rem; overflow-x: auto; }
+        .stats { display: flex; gap: 2rem; flex-wrap: wrap; }
+        .stats div strong { display: block; font-size: 1.5rem; }
+        table { width: 100%; border-collapse: collapse; font-size: 0.9rem; }
+        th, td { text-align: left; padding: 0.5rem; border-bottom: 1px solid #e5e7eb; }
+        td.key { font-family: monospace; word-break: break-all; }
+        .tag { background: #e0e7ff; color: #3730a3; border: none; border-radius: 10px; padding: 0.1rem 0.6rem; cursor: pointer; }
+        button { background: #3B82F6; color: white; border: none; padding: 0.4rem 0.9rem; border-radius: 4px; cursor: pointer; }
+        button.danger { background: #dc2626; }
+        .muted { color: #6b7280; }
+        #error { color: #b91c1c; font-family: monospace; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>🗃️ Response Cache</h1>
+        <p>{{ project_name }} · cached GET responses of /api/data · a write to a model flushes its tag</p>
+    </div>
+    <div class="container">
+        <div id="error"></div>
+        <div class="panel" id="summary"><p class="muted">Loading…</p></div>
+        <div class="panel">
+            <p><button id="refresh">Refresh</button> <button id="flush" class="danger">Flush all</button>
+                <span class="muted">Click a tag to flush its entries.</span></p>
+            <table>
+                <thead><tr><th>Request</th><th>Tags</th><th>Size</th><th>TTL</th><th>Hits</th><th></th></tr></thead>
+                <tbody id="entries"></tbody>
+            </table>
+            <p><a href="/">← Back to Main Dashboard</a></p>
+        </div>
+    </div>
+    <script>
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+
+        async function api(url, options) {
+            const response = await fetch(url, options);
+            const data = await response.json();
+            if (!response.ok) throw new Error(data.error || response.statusText);
+            return data;
+        }
+
+        async function flush(params) {
+            try {
+                await api('/api/_cache' + (params ? '?' + new URLSearchParams(params) : ''), {method: 'DELETE'});
+                load();
+            } catch (e) {
+                document.getElementById('error').textContent = '❌ ' + e.message;
+            }
+        }
+
+        async function load() {
+            let data;
+            try {
+                data = await api('/api/_cache');
+            } catch (e) {
+                document.getElementById('error').textContent = '❌ ' + e.message;
+                return;
+            }
+            document.getElementById('error').textContent = '';
+            if (!data.enabled) {
+                document.getElementById('summary').innerHTML = '<h3>The response cache is off</h3><p>Turn it on in flashflow.json:</p>'
+                    + '<pre>"response_cache": {"ttl_seconds": 60}</pre>';
+                document.getElementById('entries').innerHTML = '';
+                return;
+            }
+            const s = data.stats;
+            document.getElementById('summary').innerHTML = '<div class="stats">'
+                + [['Entries', s.entries + ' / ' + s.max_entries], ['Hits', s.hits], ['Misses', s.misses],
+                   ['Hit ratio', s.hit_ratio == null ? '—' : Math.round(s.hit_ratio * 100) + '%'],
+                   ['Invalidated', s.invalidated], ['Backend', s.backend], ['Default TTL', s.ttl_seconds + 's']]
+                    .map(([label, value]) => `<div><strong>${escapeHtml(value)}</strong><span class="muted">${label}</span></div>`).join('')
+                + '</div>';
+            document.getElementById('entries').innerHTML = data.entries.map(e => `
+                <tr><td class="key">${escapeHtml(e.key)}</td>
+                    <td>${e.tags.map(tag => `<button class="tag" data-tag="${escapeHtml(tag)}">${escapeHtml(tag)}</button>`).join(' ')}</td>
+                    <td>${e.size} B</td><td>${e.ttl}s</td><td>${e.hits}</td>
+                    <td><button class="danger" data-key="${escapeHtml(e.key)}">Flush</button></td></tr>`
+            ).join('') || '<tr><td colspan="6" class="muted">Nothing cached yet</td></tr>';
+            document.querySelectorAll('[data-tag]').forEach(button => button.onclick = () => flush({tag: button.dataset.tag}));
+            document.querySelectorAll('[data-key]').forEach(button => button.onclick = () => flush({key: button.dataset.key}));
+        }
+
+        document.getElementById('refresh').onclick = load;
+        document.getElementById('flush').onclick = () => confirm('Flush every cached response?') && flush(null);
+        load();
+    </script>
+</body>
+</html>
+"""
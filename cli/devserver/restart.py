@@ -0,0 +1,168 @@
+"""
+FlashFlow dev server restarts - Restart without refusing connections
+
+A restart (flashflow.json or .env changed, SIGHUP, or POST /__restart) stops
+accepting, lets in-flight requests finish, runs the usual shutdown and then
+re-executes 'flashflow serve' in the same process. The listening socket is
+duplicated and kept open across exec, so the kernel queues new connections
+until the new server accepts them instead of refusing them. The pid, terminal
+and Ctrl+C keep working as before. Browsers reconnect /__reload to the new
+server, which replays a reload to them (see live_reload.py).
+
+Restarts need POSIX fd inheritance; on Windows a change only prints a notice.
+"""
+
+import os
+import signal
+import sys
+import threading
+import time
+from typing import Callable, Optional
+
+import click
+from flask import g, request, jsonify
+from werkzeug.debug import DebuggedApplication
+from werkzeug.serving import make_server
+
+SERVER_FD_ENV = 'FLASHFLOW_SERVER_FD'
+RESTART_REASON_ENV = 'FLASHFLOW_RESTART_REASON'
+RESTART_FILES = ('flashflow.json', '.env')
+# Editors often write a file more than once per save
+RESTART_DEBOUNCE = 0.5
+DRAIN_TIMEOUT = 5.0
+# Event streams stay open for good; they reconnect to the new server instead
+STREAM_PATHS = ('/__reload',)
+LOOPBACK_HOSTS = ('localhost', '127.0.0.1', '::1')
+
+def restart_supported() -> bool:
+    return os.name == 'posix'
+
+class ServerRestarter:
+    """Schedules restarts and hands the listening socket to the re-executed server"""
+
+    def __init__(self, check: Optional[Callable[[], None]] = None):
+        self.check = check
+        self.server = None
+        self.reason: Optional[str] = None
+        self.socket_fd: Optional[int] = None
+        # Set while a share tunnel makes the server public
+        self.shared = False
+        # Set when this process was itself started by a restart
+        self.restarted_because = os.environ.pop(RESTART_REASON_ENV, None)
+        self._inherited_fd = os.environ.pop(SERVER_FD_ENV, None)
+        self._timer: Optional[threading.Timer] = None
+        self._lock = threading.Lock()
+        self._in_flight = 0
+
+    def debugger_allowed(self, host: str) -> bool:
+        """The interactive debugger runs code for whoever reaches it, so only this machine may"""
+        return host.strip('[]').lower() in LOOPBACK_HOSTS and not self.shared
+
+    def make_server(self, app, host: str, port: int):
+        """The threaded dev server, on the inherited socket after a restart; with the debugger on loopback"""
+        fd = int(self._inherited_fd) if self._inherited_fd else None
+        app.debug = True
+        wsgi_app = DebuggedApplication(app, evalex=True) if self.debugger_allowed(host) else app
+        self.server = make_server(host, port, wsgi_app, threaded=True, fd=fd)
+        if fd is not None:
+            # make_server works on a duplicate
+            os.close(fd)
+        return self.server
+
+    def request(self, reason: str) -> bool:
+        """Schedule a restart; requests that arrive together become one"""
+        if not restart_supported():
+            click.echo(f"⚠️  {reason}; restart the dev server to apply it")
+            return False
+        with self._lock:
+            if self.reason:
+                return False
+            if self._timer:
+                self._timer.cancel()
+            self._timer = threading.Timer(RESTART_DEBOUNCE, self._restart, [reason])
+            self._timer.daemon = True
+            self._timer.start()
+        return True
+
+    def _restart(self, reason: str):
+        with self._lock:
+            self._timer = None
+            if self.reason or not self.server:
+                return
+            self.reason = reason
+        if self.check:
+            try:
+                self.check()
+            except Exception as e:
+                click.echo(f"⚠️  Not restarting ({reason}): {str(e)}")
+                click.echo("   Still serving the previous configuration")
+                self.reason = None
+                return
+        click.echo(f"\n🔄 Restarting the dev server ({reason})...")
+        # serve_forever closes its socket on the way out; this copy outlives it and the exec
+        self.socket_fd = os.dup(self.server.socket.fileno())
+        os.set_inheritable(self.socket_fd, True)
+        self.server.shutdown()
+
+    def track(self, app):
+        """Count requests in flight so a restart can wait for them"""
+        @app.before_request
+        def restart_request_started():
+            if not request.path.startswith(STREAM_PATHS):
+                g.restart_tracked = True
+                with self._lock:
+                    self._in_flight += 1
+
+        @app.teardown_request
+        def restart_request_finished(exc=None):
+            # Teardown also runs for requests an earlier before_request answered
+            if g.pop('restart_tracked', False):
+                with self._lock:
+                    self._in_flight -= 1
+
+    def drain(self, timeout: float = DRAIN_TIMEOUT) -> int:
+        """Wait for in-flight requests; returns how many were still running at the timeout"""
+        deadline = time.time() + timeout
+        while self._in_flight > 0 and time.time() < deadline:
+            time.sleep(0.05)
+        return self._in_flight
+
+    def install_signal_handler(self):
+        """SIGHUP restarts, e.g. after a self-update; only possible from the main thread"""
+        if restart_supported() and threading.current_thread() is threading.main_thread():
+            signal.signal(signal.SIGHUP, lambda signum, frame: self.request("SIGHUP"))
+
+    def exec_restart(self):
+        """Replace this process with a fresh 'flashflow serve' that inherits the socket"""
+        os.environ[SERVER_FD_ENV] = str(self.socket_fd)
+        os.environ[RESTART_REASON_ENV] = self.reason
+        sys.stdout.flush()
+        sys.stderr.flush()
+        # orig_argv keeps '-m cli.core.main' style invocations intact
+        args = list(getattr(sys, 'orig_argv', None) or [sys.executable] + sys.argv)
+        os.execv(sys.executable, args)
+
+def register_restart(app, restarter: ServerRestarter):
+    """POST /__restart restarts the dev server the same way a config change does"""
+    restarter.track(app)
+
+    @app.route('/__restart', methods=['POST'])
+    def restart_server():
+        if not restart_supported():
+            return jsonify({'error': 'Restarting in place needs a POSIX system'}), 501
+        scheduled = restarter.request(request.args.get('reason') or "requested via /__restart")
+        return jsonify({'scheduled': scheduled, 'pid': os.getpid()}), 202 if scheduled else 409
+
+class ConfigChangeHandler:
+    """watchdog handler restarting the server when flashflow.json or .env changes"""
+
+    def __init__(self, restarter: ServerRestarter):
+        self.restarter = restarter
+
+    def dispatch(self, event):
+        if event.is_directory or event.event_type not in ('modified', 'created', 'moved'):
+            return
+        path = getattr(event, 'dest_path', None) or event.src_path
+        name = os.path.basename(str(path))
+        if name in RESTART_FILES:
+            self.restarter.request(f"{name} changed")
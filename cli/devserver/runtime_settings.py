@@ -0,0 +1,427 @@
+"""
+FlashFlow dev settings - /admin/settings, the resolved configuration and the settings that apply at once
+
+    GET    /admin/api/settings               resolved settings, every section with its value and default
+    PUT    /admin/api/settings/<section>     {"origins": ["http://localhost:3000"], "credentials": true}
+    DELETE /admin/api/settings/<section>     back to the section's default
+    POST   /admin/api/settings/reset         every section back to its default
+    GET    /admin/api/settings/history       ?section= &limit= newest first
+
+The sections are theme, cors, chaos and features (see core/runtime_settings.py).
+Theme colors are added to every rendered page as --color-<name> variables
+and open pages reload; CORS settings narrow what flask_cors answers, from
+the next request on. Each change is written to the history in
+.flashflow/settings.json and to the audit log.
+"""
+
+from typing import Any, Dict
+
+from flask import request, jsonify, render_template_string
+
+from core.parser.parser import FlowParser
+from core.runtime_settings import (SECTIONS, STORED_SECTIONS, RuntimeSettings, RuntimeSettingsError,
+                                   cors_allows, default, theme_style, validate)
+from core.settings import SettingsError, resolve_settings
+from cli.devserver.audit import audit_admin, current_actor
+from cli.devserver.chaos import get_chaos
+from cli.devserver.feature_flags import get_flag_overrides
+from cli.devserver.live_reload import get_reload_hub
+
+# The dev server's own pages keep their look
+EXCLUDED_PREFIXES = ('/admin', '/api', '/__')
+DESCRIPTIONS = {
+    'theme': 'Colors over the theme in the flows, as --color-<name> CSS variables',
+    'cors': 'Origins allowed to call the dev server from a browser',
+    'chaos': 'Chaos mode; its rules are edited in /admin/chaos',
+    'features': 'Feature flags forced on or off; the rules are in flashflow.json',
+}
+
+def get_runtime_settings(app) -> RuntimeSettings:
+    if 'RUNTIME_SETTINGS' not in app.config:
+        app.config['RUNTIME_SETTINGS'] = RuntimeSettings.for_project(app.config['PROJECT'])
+    return app.config['RUNTIME_SETTINGS']
+
+def register_settings_cors(app):
+    """Narrow CORS answers to the cors settings; register before CORS(app), so this hook runs after flask_cors's"""
+    settings = get_runtime_settings(app)
+
+    @app.after_request
+    def apply_cors_settings(response):
+        cors = settings.value('cors')
+        origin = request.headers.get('Origin')
+        if not cors_allows(cors, origin):
+            for header in [name for name in response.headers.keys() if name.lower().startswith('access-control-')]:
+                del response.headers[header]
+            return response
+        response.headers['Access-Control-Allow-Origin'] = '*' if cors['origins'] == '*' else origin
+        response.vary.add('Origin')
+        if cors['credentials']:
+            response.headers['Access-Control-Allow-Credentials'] = 'true'
+        elif 'Access-Control-Allow-Credentials' in response.headers:
+            del response.headers['Access-Control-Allow-Credentials']
+        if cors['max_age'] is not None and request.method == 'OPTIONS':
+            response.headers['Access-Control-Max-Age'] = str(cors['max_age'])
+        return response
+
+def register_runtime_settings(app):
+    """Register /admin/settings and its API, and add the theme colors to rendered pages"""
+    project = app.config['PROJECT']
+    settings = get_runtime_settings(app)
+    chaos = get_chaos(app)
+    overrides = get_flag_overrides(app)
+
+    def known_flags():
+        return list(app.config['PROFILE'].flags.flags)
+
+    def current(section: str) -> Dict[str, Any]:
+        if section in STORED_SECTIONS:
+            return settings.value(section)
+        if section == 'chaos':
+            return {'enabled': chaos.enabled}
+        return {'overrides': dict(sorted(overrides.snapshot().items()))}
+
+    def apply(section: str, value: Dict[str, Any], action: str):
+        old = current(section)
+        if section in STORED_SECTIONS:
+            settings.set(section, value, current_actor())
+        else:
+            if section == 'chaos':
+                chaos.set_enabled(value['enabled'])
+            else:
+                for name in set(old['overrides']) | set(value['overrides']):
+                    if old['overrides'].get(name) != value['overrides'].get(name):
+                        overrides.set(name, value['overrides'].get(name))
+            settings.record(section, old, value, current_actor())
+        audit_admin(app, action, 'settings', section, old, value)
+        app.logger.warning(f"⚙️  Settings: {section} {'reset' if action == 'settings.reset' else 'changed'}")
+        if section in ('theme', 'features'):
+            get_reload_hub(app).broadcast('reload', {'file': 'settings'})
+
+    def flow_theme() -> Dict[str, Any]:
+        try:
+            theme = FlowParser().parse_project(project.root_path).theme
+        except Exception:
+            return {}
+        return theme if isinstance(theme, dict) else {}
+
+    def section_state(section: str) -> Dict[str, Any]:
+        state = {'value': current(section), 'default': default(section), 'description': DESCRIPTIONS[section]}
+        if section in STORED_SECTIONS:
+            state['source'] = 'settings.json' if settings.stored(section) is not None else 'default'
+        else:
+            state['source'] = '.flashflow/chaos' if section == 'chaos' else '.flashflow/features'
+        if section == 'theme':
+            colors = {str(name): str(color) for name, color in (flow_theme().get('colors') or {}).items()}
+            state['flows'] = colors
+            state['effective'] = dict(colors, **state['value']['colors'])
+        elif section == 'features':
+            state['flags'] = known_flags()
+        return state
+
+    @app.after_request
+    def inject_theme_settings(response):
+        if (response.mimetype != 'text/html' or response.status_code != 200 or response.is_streamed
+                or request.path.startswith(EXCLUDED_PREFIXES)):
+            return response
+        colors = settings.value('theme')['colors']
+        if not colors:
+            return response
+        html = response.get_data(as_text=True)
+        # Last in <head>, so it wins over the page's own stylesheet
+        index = html.lower().find('</head>')
+        response.set_data(html[:index] + theme_style(colors) + html[index:] if index != -1 else theme_style(colors) + html)
+        return response
+
+    @app.route('/admin/api/settings', methods=['GET'])
+    def admin_settings_state():
+        try:
+            resolved, resolve_error = [setting.to_dict() for setting in resolve_settings(project).resolved.values()], None
+        except SettingsError as e:
+            resolved, resolve_error = [], str(e)
+        return jsonify({'env': app.config['PROFILE'].name, 'resolved': resolved, 'resolve_error': resolve_error,
+                        'settings_error': settings.error,
+                        'sections': {section: section_state(section) for section in SECTIONS}})
+
+    @app.route('/admin/api/settings/<section>', methods=['PUT'])
+    def admin_settings_update(section):
+        try:
+            value = validate(section, request.get_json(silent=True), known_flags())
+        except RuntimeSettingsError as e:
+            return jsonify({'error': str(e)}), 404 if section not in SECTIONS else 400
+        apply(section, value, 'settings.update')
+        return jsonify(section_state(section))
+
+    @app.route('/admin/api/settings/<section>', methods=['DELETE'])
+    def admin_settings_reset_section(section):
+        if section not in SECTIONS:
+            return jsonify({'error': f"Unknown settings section '{section}'; use {', '.join(SECTIONS)}"}), 404
+        apply(section, default(section), 'settings.reset')
+        return jsonify(section_state(section))
+
+    @app.route('/admin/api/settings/reset', methods=['POST'])
+    def admin_settings_reset():
+        reset = [section for section in SECTIONS if current(section) != default(section)]
+        for section in reset:
+            apply(section, default(section), 'settings.reset')
+        return jsonify({'reset': reset, 'sections': {section: section_state(section) for section in SECTIONS}})
+
+    @app.route('/admin/api/settings/history', methods=['GET'])
+    def admin_settings_history():
+        section = request.args.get('section') or None
+        if section is not None and section not in SECTIONS:
+            return jsonify({'error': f"Unknown settings section '{section}'; use {', '.join(SECTIONS)}"}), 400
+        limit = max(1, min(request.args.get('limit', 50, type=int), 200))
+        return jsonify({'history': settings.history(section, limit)})
+
+    @app.route('/admin/settings')
+    def admin_settings_page():
+        return render_template_string(SETTINGS_ADMIN_TEMPLATE, project_name=project.config.name,
+                                      env=app.config['PROFILE'].name)
+
+SETTINGS_ADMIN_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Settings - FlashFlow Admin</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+        .container { max-width: 1200px; margin: 0 auto; padding: 2rem; }
+        .panel { background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); margin-bottom: 1.5rem; }
+        .panel h2 { margin-top: 0; font-size: 1.15rem; }
+        table { width: 100%; border-collapse: collapse; }
+        th, td { text-align: left; padding: 0.5rem; border-bottom: 1px solid #e5e7eb; font-size: 0.9rem; vertical-align: top; }
+        input, select, textarea { padding: 0.35rem; border: 1px solid #d1d5db; border-radius: 4px; font: inherit; }
+        textarea { width: 100%; min-height: 4rem; font-family: monospace; }
+        button { padding: 0.4rem 0.9rem; border: none; border-radius: 4px; background: #667eea; color: white; cursor: pointer; }
+        button.secondary { background: #e5e7eb; color: #111827; }
+        button.danger { background: #dc2626; }
+        .actions { margin-top: 1rem; display: flex; gap: 0.5rem; }
+        .swatch { display: inline-block; width: 1rem; height: 1rem; border-radius: 3px; border: 1px solid #d1d5db; vertical-align: middle; }
+        .muted { color: #6b7280; }
+        .source { font-size: 0.8rem; color: #6b7280; }
+        code { background: #f3f4f6; padding: 0 0.25rem; border-radius: 3px; }
+        .error { color: #b91c1c; font-family: monospace; }
+        .grid { display: grid; grid-template-columns: repeat(auto-fit, minmax(480px, 1fr)); gap: 1.5rem; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>⚙️ Settings</h1>
+        <p>{{ project_name }} · Environment <strong>{{ env }}</strong> · Changes apply at once, no restart</p>
+    </div>
+    <div class="container">
+        <div id="error" class="error"></div>
+        <div class="grid">
+            <div class="panel">
+                <h2>🎨 Theme <span class="source" id="theme-source"></span></h2>
+                <p class="muted">Colors set here win over the theme in the flows.</p>
+                <table>
+                    <thead><tr><th>Name</th><th>Flows</th><th>Override</th><th></th></tr></thead>
+                    <tbody id="colors"></tbody>
+                </table>
+                <div class="actions">
+                    <button class="secondary" id="add-color">Add color</button>
+                    <button data-save="theme">Save</button>
+                    <button class="secondary" data-reset="theme">Reset</button>
+                </div>
+            </div>
+            <div class="panel">
+                <h2>🌐 CORS <span class="source" id="cors-source"></span></h2>
+                <label>Allowed origins, one per line (<code>local</code> for this machine, <code>*</code> for any)<textarea id="cors-origins"></textarea></label>
+                <p><label><input type="checkbox" id="cors-credentials"> Allow credentials (cookies, Authorization)</label></p>
+                <label>Preflight max age <input id="cors-max-age" type="number" min="0" max="86400" placeholder="browser default"> s</label>
+                <div class="actions">
+                    <button data-save="cors">Save</button>
+                    <button class="secondary" data-reset="cors">Reset</button>
+                </div>
+            </div>
+            <div class="panel">
+                <h2>🌪️ Chaos mode <span class="source" id="chaos-source"></span></h2>
+                <p><label><input type="checkbox" id="chaos-enabled"> Delay, fail or drop requests matching the chaos rules</label></p>
+                <p class="muted">Rules are edited in <a href="/admin/chaos">/admin/chaos</a>.</p>
+                <div class="actions">
+                    <button data-save="chaos">Save</button>
+                    <button class="secondary" data-reset="chaos">Reset</button>
+                </div>
+            </div>
+            <div class="panel">
+                <h2>🚩 Feature flags <span class="source" id="features-source"></span></h2>
+                <table>
+                    <thead><tr><th>Flag</th><th>Override</th></tr></thead>
+                    <tbody id="flags"></tbody>
+                </table>
+                <p class="muted">Rules and rollouts are in <a href="/admin/features">/admin/features</a>.</p>
+                <div class="actions">
+                    <button data-save="features">Save</button>
+                    <button class="secondary" data-reset="features">Reset</button>
+                </div>
+            </div>
+        </div>
+        <div class="panel">
+            <h2>🧭 Resolved configuration</h2>
+            <p class="muted">Flag &gt; environment &gt; flashflow.json &gt; default. Flags given to the running server are not shown.</p>
+            <div id="resolve-error" class="error"></div>
+            <table>
+                <thead><tr><th>Setting</th><th>Value</th><th>From</th><th>Environment variable</th><th>Default</th></tr></thead>
+                <tbody id="resolved"></tbody>
+            </table>
+        </div>
+        <div class="panel">
+            <h2>🕘 History</h2>
+            <table>
+                <thead><tr><th>When</th><th>Who</th><th>Section</th><th>Before</th><th>After</th></tr></thead>
+                <tbody id="history"></tbody>
+            </table>
+            <div class="actions"><button class="danger" id="reset-all">Reset everything to defaults</button></div>
+        </div>
+        <p><a href="/">← Back to Main Dashboard</a></p>
+    </div>
+    <script>
+        let state = null;
+
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+
+        async function api(url, options) {
+            if (options && options.body) options.headers = {'Content-Type': 'application/json'};
+            const response = await fetch(url, options);
+            const data = await response.json();
+            if (!response.ok) throw new Error(data.error || response.statusText);
+            return data;
+        }
+
+        function showError(e) {
+            document.getElementById('error').textContent = e ? '❌ ' + e.message : '';
+        }
+
+        function colorRow(name, flows, override) {
+            return `<tr>
+                <td><input class="color-name" value="${escapeHtml(name)}" placeholder="primary"></td>
+                <td>${flows ? `<span class="swatch" style="background:${escapeHtml(flows)}"></span> <code>${escapeHtml(flows)}</code>` : '<span class="muted">-</span>'}</td>
+                <td><input class="color-value" value="${escapeHtml(override || '')}" placeholder="${escapeHtml(flows || '#2563eb')}"></td>
+                <td><button class="secondary remove-color">✕</button></td>
+            </tr>`;
+        }
+
+        function render() {
+            const sections = state.sections;
+            for (const name of Object.keys(sections)) {
+                document.getElementById(name + '-source').textContent = '· ' + sections[name].source;
+            }
+            const theme = sections.theme;
+            const names = [...new Set([...Object.keys(theme.flows), ...Object.keys(theme.value.colors)])].sort();
+            document.getElementById('colors').innerHTML = names.map(name => colorRow(name, theme.flows[name], theme.value.colors[name])).join('')
+                || '<tr><td colspan="4" class="muted">No theme colors in the flows; add one</td></tr>';
+
+            const cors = sections.cors.value;
+            document.getElementById('cors-origins').value = typeof cors.origins === 'string' ? cors.origins : cors.origins.join('\\n');
+            document.getElementById('cors-credentials').checked = cors.credentials;
+            document.getElementById('cors-max-age').value = cors.max_age == null ? '' : cors.max_age;
+
+            document.getElementById('chaos-enabled').checked = sections.chaos.value.enabled;
+
+            const overrides = sections.features.value.overrides;
+            document.getElementById('flags').innerHTML = sections.features.flags.map(flag => `<tr>
+                    <td><strong>${escapeHtml(flag)}</strong></td>
+                    <td><select data-flag="${escapeHtml(flag)}">
+                        <option value="" ${overrides[flag] == null ? 'selected' : ''}>Follow rules</option>
+                        <option value="true" ${overrides[flag] === true ? 'selected' : ''}>Force on</option>
+                        <option value="false" ${overrides[flag] === false ? 'selected' : ''}>Force off</option>
+                    </select></td>
+                </tr>`).join('') || '<tr><td colspan="2" class="muted">No flags; add a "features" block to flashflow.json</td></tr>';
+
+            document.getElementById('resolve-error').textContent = state.resolve_error ? '❌ ' + state.resolve_error : '';
+            document.getElementById('resolved').innerHTML = state.resolved.map(setting => `<tr>
+                    <td><strong>${escapeHtml(setting.key)}</strong><br><span class="muted">${escapeHtml(setting.description)}</span></td>
+                    <td><code>${escapeHtml(setting.value)}</code></td>
+                    <td>${escapeHtml(setting.source)}</td>
+                    <td>${setting.env ? '<code>' + escapeHtml(setting.env) + '</code>' : ''}</td>
+                    <td><code>${escapeHtml(setting.default)}</code></td>
+                </tr>`).join('');
+            if (state.settings_error) showError(new Error(state.settings_error));
+        }
+
+        function sectionValue(section) {
+            if (section === 'theme') {
+                const colors = {};
+                for (const row of document.querySelectorAll('#colors tr')) {
+                    const name = row.querySelector('.color-name');
+                    const value = row.querySelector('.color-value');
+                    if (name && value && name.value.trim() && value.value.trim()) colors[name.value.trim()] = value.value.trim();
+                }
+                return {colors};
+            }
+            if (section === 'cors') {
+                const text = document.getElementById('cors-origins').value.trim();
+                const maxAge = document.getElementById('cors-max-age').value.trim();
+                return {origins: text === '*' || text === 'local' || !text ? (text || 'local') : text.split(/\\s+/).filter(Boolean),
+                        credentials: document.getElementById('cors-credentials').checked,
+                        max_age: maxAge === '' ? null : Number(maxAge)};
+            }
+            if (section === 'chaos') return {enabled: document.getElementById('chaos-enabled').checked};
+            const overrides = {};
+            for (const select of document.querySelectorAll('#flags select')) {
+                if (select.value !== '') overrides[select.dataset.flag] = select.value === 'true';
+            }
+            return {overrides};
+        }
+
+        async function loadHistory() {
+            const data = await api('/admin/api/settings/history?limit=50');
+            document.getElementById('history').innerHTML = data.history.map(entry => `<tr>
+                    <td>${escapeHtml(entry.ts)}</td>
+                    <td>${escapeHtml(entry.actor)}</td>
+                    <td>${escapeHtml(entry.section)}</td>
+                    <td><code>${escapeHtml(JSON.stringify(entry.old))}</code></td>
+                    <td><code>${escapeHtml(JSON.stringify(entry.new))}</code></td>
+                </tr>`).join('') || '<tr><td colspan="5" class="muted">No changes yet</td></tr>';
+        }
+
+        async function load() {
+            try {
+                state = await api('/admin/api/settings');
+                render();
+                await loadHistory();
+            } catch (e) {
+                showError(e);
+            }
+        }
+
+        document.body.onclick = async event => {
+            const target = event.target;
+            try {
+                if (target.dataset.save) {
+                    showError(null);
+                    await api('/admin/api/settings/' + target.dataset.save,
+                              {method: 'PUT', body: JSON.stringify(sectionValue(target.dataset.save))});
+                    await load();
+                } else if (target.dataset.reset) {
+                    showError(null);
+                    await api('/admin/api/settings/' + target.dataset.reset, {method: 'DELETE'});
+                    await load();
+                } else if (target.id === 'reset-all') {
+                    if (!confirm('Reset theme, CORS, chaos mode and feature flag overrides to their defaults?')) return;
+                    showError(null);
+                    await api('/admin/api/settings/reset', {method: 'POST'});
+                    await load();
+                } else if (target.id === 'add-color') {
+                    document.getElementById('colors').insertAdjacentHTML('beforeend', colorRow('', '', ''));
+                } else if (target.classList.contains('remove-color')) {
+                    target.closest('tr').remove();
+                }
+            } catch (e) {
+                showError(e);
+            }
+        };
+
+        load();
+    </script>
+</body>
+</html>
+"""
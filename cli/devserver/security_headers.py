@@ -0,0 +1,65 @@
+"""
+FlashFlow dev server security headers - CSP, X-Frame-Options, Referrer-Policy and HSTS
+
+Every response gets the configured headers; HTML pages outside the dev
+server's own tooling also get the Content-Security-Policy (report-only by
+default). Browsers post violations to /api/csp-report, which keeps them for
+GET /api/csp-report and prints each new one once.
+"""
+
+import click
+from typing import List, Optional
+
+from flask import request, jsonify
+
+from core.security_headers import (REPORT_GROUP, REPORT_PATH, CspReport, SecurityHeadersConfig,
+                                   parse_reports, trusted_script_sources)
+
+# The dev server's own pages load CDNs and inline scripts; reports from them would only be noise
+EXEMPT_PREFIXES = ('/admin', '/api', '/__')
+EXEMPT_PATHS = ('/edit',)
+
+def register_security_headers(app, mode: Optional[str] = None, injected: Optional[List[str]] = None):
+    """Add security headers to responses and register the CSP report collector
+
+    injected is HTML the dev server adds to pages (live reload, a11y audit);
+    its scripts are allowed by hash so they don't show up as violations.
+    """
+    config = SecurityHeadersConfig.for_project(app.config['PROJECT'], mode)
+    policy = config.policy(trusted_script_sources(injected or []))
+    csp_header = config.csp_header()
+    report = CspReport()
+    app.config['SECURITY_HEADERS'] = config
+    app.config['CSP_REPORT'] = report
+
+    @app.after_request
+    def add_security_headers(response):
+        # X-Forwarded-Proto only counts from trusted proxies, which core/proxy.py has already applied
+        for name, value in config.headers(request.is_secure).items():
+            response.headers.setdefault(name, value)
+        if (csp_header and response.mimetype == 'text/html' and not request.path.startswith(EXEMPT_PREFIXES)
+                and request.path not in EXEMPT_PATHS):
+            response.headers.setdefault(csp_header, policy)
+            response.headers.setdefault('Reporting-Endpoints', f'{REPORT_GROUP}="{REPORT_PATH}"')
+        return response
+
+    @app.route(REPORT_PATH, methods=['POST'])
+    def csp_report_collect():
+        """Violations posted by browsers (application/csp-report or application/reports+json)"""
+        violations = parse_reports(request.get_json(force=True, silent=True))
+        for violation in violations:
+            if report.record(violation):
+                where = f" ({violation['source']}:{violation['line']})" if violation['source'] else ""
+                kind = "mixed content" if violation['mixed_content'] else violation['directive']
+                click.echo(f"🛡️  CSP {kind}: {violation['blocked']} blocked on {violation['page']}{where}")
+        return '', 204
+
+    @app.route(REPORT_PATH, methods=['GET'])
+    def csp_report_list():
+        """Distinct violations seen since the server started, with the active policy"""
+        return jsonify(dict(report.to_dict(), policy=policy, mode=config.mode))
+
+    @app.route(REPORT_PATH, methods=['DELETE'])
+    def csp_report_clear():
+        report.clear()
+        return jsonify({'cleared': True})
@@ -0,0 +1,593 @@
+"""
+FlashFlow dev server - The unified dev server as a library
+
+'flashflow serve' runs a DevServer; other Python programs and test suites can
+run the same server, and extend it, without going through the CLI:
+
+    from cli.devserver.server import DevServer, with_port, with_routes
+
+    with DevServer(project, with_port(0), with_routes(register_orders_api), watch=False) as server:
+        requests.get(f"{server.url}/api/data/users")
+
+Options are option functions (with_host, with_port, with_allowed_hosts,
+with_routes, with_middleware), applied in order, or the keyword arguments of
+the same names, which are applied first. routes are register_x(app) callables
+like the ones in cli/devserver, called once every built-in route is
+registered, so they add pages and endpoints next to the built-in ones. middleware are WSGI
+middlewares, each called with the application and returning the wrapped one;
+the last one added sees requests first. Port 0 picks a free port, which
+server.port and server.url report once the server listens.
+
+start() serves from a background thread and stop() shuts the server and its
+helpers (API workers, SMTP sink, watchers, dev event socket) down again;
+serve_forever() serves from the calling thread until Ctrl+C or a restart.
+"""
+
+import signal
+import threading
+from pathlib import Path
+from typing import Any, Callable, Dict, Iterable, List, Optional
+
+import click
+from flask import Flask, jsonify
+from flask_cors import CORS
+
+from core.content import content_path
+from core.file_watch import FileWatchError, create_observer
+from core.framework import FlashFlowProject
+from core.parser.diagnostics import collect_diagnostics, diagnostics_report
+from core.parser.flow_file import INCLUDE_DIRS, load_flow
+from core.parser.flow_loader import PARALLEL_THRESHOLD
+from core.parser.parser import FlowParser
+from core.backend_targets import BackendTargetError
+from core.profiles import Profile, profile_env
+from src.services.api_endpoints import register_api_endpoints
+from cli.devserver.a11y import register_a11y, audit_script
+from cli.devserver.admin_models import register_admin_models
+from cli.devserver.admin_users import register_admin_users
+from cli.devserver.ai_models import register_ai_models
+from cli.devserver.api_keys import register_api_keys
+from cli.devserver.api_tester import register_api_tester
+from cli.devserver.api_workers import ApiWorkerError, ApiWorkerPool, register_api_workers
+from cli.devserver.audit import register_audit
+from cli.devserver.branch_previews import register_branch_previews
+from cli.devserver.build_api import register_build_api
+from cli.devserver.build_size import register_build_size
+from cli.devserver.chaos import register_chaos
+from cli.devserver.crashes import register_crash_reports
+from cli.devserver.database_browser import register_database_browser
+from cli.devserver.desktop_bridge import register_desktop_bridge
+from cli.devserver.dev_crud import register_dev_crud
+from cli.devserver.dev_events import register_dev_events, get_dev_events
+from cli.devserver.embedded_build import get_embedded_build, register_embedded_build
+from cli.devserver.device_farm import register_device_farm
+from cli.devserver.feature_flags import register_feature_flags
+from cli.devserver.flow_editor import register_flow_editor
+from cli.devserver.flow_hooks import register_flow_hooks
+from cli.devserver.generated_backend import GeneratedBackend, register_generated_backend
+from cli.devserver.inference import register_inference
+from cli.devserver.integrations import register_integrations
+from cli.devserver.live_reload import register_live_reload, LIVE_RELOAD_SCRIPT
+from cli.devserver.mailbox import register_mailbox, start_smtp_sink, DEFAULT_SMTP_PORT
+from cli.devserver.media import register_media
+from cli.devserver.notifications import register_notifications
+from cli.devserver.origin_check import register_origin_check
+from cli.devserver.permissions import register_permissions
+from cli.devserver.profile import register_profile, profile_script
+from cli.devserver.proxy import register_proxy
+from cli.devserver.render_api import register_render_api
+from cli.devserver.request_context import register_request_context
+from cli.devserver.request_log import register_request_log
+from cli.devserver.response_cache import register_response_cache
+from cli.devserver.restart import ServerRestarter, ConfigChangeHandler, register_restart
+from cli.devserver.runtime_settings import register_runtime_settings, register_settings_cors
+from cli.devserver.security_headers import register_security_headers
+from cli.devserver.service_info import register_service_info
+from cli.devserver.sitemap import register_sitemap
+from cli.devserver.social import register_social
+from cli.devserver.stats import register_stats
+from cli.devserver.tracing import register_tracing
+from cli.devserver.transforms import register_transforms
+from cli.devserver.vault import register_vault
+from cli.devserver.vector_search import register_vector_search
+from cli.devserver.snapshots import register_snapshots, snapshot_on_stop
+from cli.devserver.webhooks import register_webhooks
+
+# Shows a dismissible overlay listing flow errors reported by /api/diagnostics, and failed requests with their ids
+DIAGNOSTICS_OVERLAY_SCRIPT = """
+<script>
+    (function () {
+        let dismissedSignature = null;
+        
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+        
+        async function showDiagnostics() {
+            let report;
+            try {
+                report = await (await fetch('/api/diagnostics')).json();
+            } catch (e) {
+                return;
+            }
+            
+            // Keep a dismissed overlay hidden until the set of errors changes
+            const signature = JSON.stringify(report.files);
+            if (signature === dismissedSignature) return;
+            
+            const existing = document.getElementById('flashflow-error-overlay');
+            if (existing) existing.remove();
+            if (!report.error_count) return;
+            
+            let html = '<div style="display:flex;justify-content:space-between;align-items:center">'
+                + '<h2 style="margin:0">❌ ' + report.error_count + ' error(s) in .flow files</h2>'
+                + '<button id="flashflow-error-dismiss" style="background:none;border:1px solid #fca5a5;color:#fca5a5;padding:4px 10px;border-radius:4px;cursor:pointer">Dismiss</button></div>';
+            for (const [file, items] of Object.entries(report.files)) {
+                html += '<h3 style="color:#fca5a5">📄 ' + escapeHtml(file) + '</h3><ul>';
+                for (const d of items) {
+                    const where = d.line ? ':' + d.line + (d.column ? ':' + d.column : '') : '';
+                    html += '<li style="margin:0.5rem 0"><code>' + escapeHtml(file + where) + '</code> '
+                        + escapeHtml(d.severity) + ': ' + escapeHtml(d.message)
+                        + (d.suggestion ? '<br><span style="color:#fde68a">💡 ' + escapeHtml(d.suggestion) + '</span>' : '')
+                        + '</li>';
+                }
+                html += '</ul>';
+            }
+            
+            const overlay = document.createElement('div');
+            overlay.id = 'flashflow-error-overlay';
+            overlay.style.cssText = 'position:fixed;inset:0;background:rgba(17,24,39,0.95);color:#f9fafb;'
+                + 'font-family:monospace;padding:2rem;overflow:auto;z-index:99999';
+            overlay.innerHTML = html;
+            document.body.appendChild(overlay);
+            document.getElementById('flashflow-error-dismiss').onclick = function () {
+                dismissedSignature = signature;
+                overlay.remove();
+            };
+        }
+        
+        showDiagnostics();
+        setInterval(showDiagnostics, 5000);
+        
+        // Failed requests, with the X-Request-ID to look up in .flashflow/logs/access.log
+        const failedRequests = [];
+        
+        function showRequestError(entry) {
+            failedRequests.unshift(entry);
+            failedRequests.length = Math.min(failedRequests.length, 5);
+            let panel = document.getElementById('flashflow-request-errors');
+            if (!panel) {
+                panel = document.createElement('div');
+                panel.id = 'flashflow-request-errors';
+                panel.style.cssText = 'position:fixed;left:1rem;right:1rem;bottom:1rem;background:rgba(17,24,39,0.95);color:#f9fafb;'
+                    + 'font-family:monospace;font-size:13px;padding:1rem;border-left:4px solid #f87171;border-radius:6px;z-index:99998';
+                document.body.appendChild(panel);
+            }
+            let html = '<div style="display:flex;justify-content:space-between;align-items:center">'
+                + '<strong>⚠️ Failed requests</strong>'
+                + '<button id="flashflow-request-errors-dismiss" style="background:none;border:1px solid #fca5a5;color:#fca5a5;padding:2px 8px;border-radius:4px;cursor:pointer">Dismiss</button></div><ul style="margin:0.5rem 0 0;padding-left:1.2rem">';
+            for (const e of failedRequests) {
+                html += '<li style="margin:0.25rem 0">' + escapeHtml(e.method + ' ' + e.url) + ' → ' + escapeHtml(e.status)
+                    + (e.error ? ': ' + escapeHtml(e.error) : '')
+                    + (e.request_id ? '<br><span style="color:#fde68a">request id <code style="user-select:all">' + escapeHtml(e.request_id) + '</code></span>' : '')
+                    + (e.source ? ' <span style="color:#9ca3af">(' + escapeHtml(e.source) + ')</span>' : '')
+                    + '</li>';
+            }
+            panel.innerHTML = html + '</ul>';
+            document.getElementById('flashflow-request-errors-dismiss').onclick = function () {
+                failedRequests.length = 0;
+                panel.remove();
+            };
+        }
+        
+        const originalFetch = window.fetch;
+        window.fetch = async function (input, init) {
+            const response = await originalFetch.apply(this, arguments);
+            const url = typeof input === 'string' ? input : (input && input.url) || String(input);
+            // The overlay's own polling is left out
+            if (response.status >= 500 && url.indexOf('/api/diagnostics') === -1) {
+                let error = '';
+                try {
+                    const body = await response.clone().json();
+                    error = body.error || '';
+                } catch (e) {}
+                showRequestError({
+                    method: ((init && init.method) || (input && input.method) || 'GET').toUpperCase(),
+                    url: url,
+                    status: response.status,
+                    error: error,
+                    request_id: response.headers.get('X-Request-ID')
+                });
+            }
+            return response;
+        };
+        
+        // Device farm frames report their failed requests here
+        window.addEventListener('message', function (event) {
+            if (event.origin !== location.origin || !event.data || event.data.type !== 'flashflow-request-error') return;
+            showRequestError(event.data);
+        });
+    })();
+</script>
+"""
+
+# Sets something on a DevServer before it builds its app
+DevServerOption = Callable[['DevServer'], None]
+
+def with_host(host: str) -> DevServerOption:
+    """Listen on host ('0.0.0.0' for every interface)"""
+    def apply(server: 'DevServer'):
+        server.host = host
+    return apply
+
+def with_port(port: int) -> DevServerOption:
+    """Listen on port; 0 picks a free one"""
+    def apply(server: 'DevServer'):
+        server.port = port
+    return apply
+
+def with_allowed_hosts(*hosts: str) -> DevServerOption:
+    """Also answer to these host names, such as a share tunnel's; localhost and IP addresses always work"""
+    def apply(server: 'DevServer'):
+        server.allowed_hosts.extend(hosts)
+    return apply
+
+def with_routes(*routes: Callable) -> DevServerOption:
+    """Add register_x(app) callables, called after the built-in routes in the order added"""
+    def apply(server: 'DevServer'):
+        server.routes.extend(routes)
+    return apply
+
+def with_middleware(*middleware: Callable) -> DevServerOption:
+    """Add WSGI middlewares; the last one added sees requests first"""
+    def apply(server: 'DevServer'):
+        server.middleware.extend(middleware)
+    return apply
+
+class DevServer:
+    """The unified dev server: every dev subsystem on one Flask app, plus what it runs next to it"""
+
+    def __init__(self, project: FlashFlowProject, *options: DevServerOption, host: str = 'localhost', port: int = 8000,
+                 profile: Optional[Profile] = None, restarter: Optional[ServerRestarter] = None,
+                 routes: Iterable[Callable] = (), middleware: Iterable[Callable] = (),
+                 strict_schema: bool = False, csp: Optional[str] = None, a11y: bool = False,
+                 smtp_port: int = DEFAULT_SMTP_PORT, api_workers: int = 0, generated_backend: bool = False,
+                 watch: bool = True, poll: Any = 'auto', build: bool = True, https_redirect: Optional[bool] = None,
+                 allowed_hosts: Iterable[str] = ()):
+        self.project = project
+        self.host = host
+        self.port = port
+        self.profile = profile or Profile('development')
+        self.restarter = restarter or ServerRestarter()
+        self.routes: List[Callable] = list(routes)
+        self.middleware: List[Callable] = list(middleware)
+        self.strict_schema = strict_schema
+        self.csp = csp
+        self.a11y = a11y
+        self.smtp_port = smtp_port
+        self.api_workers = api_workers
+        self.generated_backend = generated_backend
+        self.watch = watch
+        self.poll = poll
+        self.build = build
+        self.https_redirect = https_redirect
+        self.allowed_hosts: List[str] = list(allowed_hosts)
+        self.app: Optional[Flask] = None
+        self.server = None
+        self.worker_pool: Optional[ApiWorkerPool] = None
+        self.backend: Optional[GeneratedBackend] = None
+        self.smtp_sink = None
+        self.dev_events = None
+        self.notification_scheduler = None
+        self._observer = None
+        self._thread: Optional[threading.Thread] = None
+        for option in options:
+            option(self)
+
+    @property
+    def url(self) -> str:
+        return f"http://{self.host}:{self.port}"
+
+    def info_config(self) -> Dict[str, Any]:
+        """The settings /__info reports; nothing secret"""
+        return {
+            'project': str(self.project.root_path), 'host': self.host, 'port': self.port, 'profile': self.profile.name,
+            'api_workers': self.api_workers, 'generated_backend': self.generated_backend, 'watch': self.watch,
+            'poll': self.poll, 'strict_schema': self.strict_schema, 'csp': self.csp, 'a11y': self.a11y,
+            'smtp_port': self.smtp_port, 'build': self.build,
+        }
+
+    def capabilities(self) -> List[str]:
+        """What runs next to the app, for /__info"""
+        running = {'api-workers': self.worker_pool, 'generated-backend': self.backend, 'smtp-sink': self.smtp_sink,
+                   'dev-events': self.dev_events, 'watch': self._observer, 'a11y': self.a11y}
+        return [name for name, present in running.items() if present]
+
+    def create_app(self) -> Flask:
+        """Build the app and start what it relies on; later calls return the same app"""
+        if self.app is not None:
+            return self.app
+        project = self.project
+        app = Flask(__name__)
+        app.config['PROJECT'] = project
+        app.config['STRICT_SCHEMA'] = self.strict_schema
+        app.config['PROFILE'] = self.profile
+        # Hooks run last-registered first, so registering this ahead of CORS lets it narrow flask_cors's headers
+        register_settings_cors(app)
+        CORS(app)
+        # Ahead of every other hook, so a refused request changes nothing
+        register_origin_check(app, [self.host] + self.allowed_hosts)
+
+        register_request_log(app)
+        register_request_context(app)
+        register_tracing(app)
+        register_stats(app)
+        register_service_info(app, self.info_config, self.capabilities)
+        register_crash_reports(app)
+        register_restart(app, self.restarter)
+        register_chaos(app)
+
+        # The API that ships, in whichever language frameworks.backend picks
+        if self.generated_backend:
+            backend = GeneratedBackend(project, profile_env(project, self.profile))
+            try:
+                backend.start()
+            except BackendTargetError as e:
+                click.echo(f"⚠️  {str(e)}; serving /api/data from the server itself")
+            else:
+                backend.supervise()
+                self.backend = backend
+                register_generated_backend(app, backend)
+                click.echo(f"🧩 Generated {backend.target.label} backend serving /api/data")
+        # The generated API runs in its own processes, so load on it cannot stall previews
+        elif self.api_workers:
+            pool = ApiWorkerPool(project, self.api_workers, profile_env(project, self.profile), self.profile.name,
+                                 self.strict_schema)
+            try:
+                pool.start()
+            except ApiWorkerError as e:
+                click.echo(f"⚠️  {str(e)}; serving /api/data from the server itself")
+            else:
+                self.worker_pool = pool
+                register_api_workers(app, pool)
+                # Workers cache responses in SQLite; this process must see and flush the same entries
+                app.config['SHARED_RESPONSE_CACHE'] = True
+                click.echo(f"🧵 {self.api_workers} API worker(s) serving /api/data")
+
+        if self.watch:
+            self._observer = watch_project(app, project, self.restarter, self.poll)
+
+        register_flow_api(app)
+
+        # Register user activity and notification API endpoints
+        register_api_endpoints(app)
+
+        # Dev server subsystems
+        register_admin_users(app)
+        register_audit(app)
+        register_admin_models(app)
+        register_api_keys(app)
+        register_permissions(app)
+        register_feature_flags(app)
+        register_runtime_settings(app)
+        register_api_tester(app)
+        register_dev_crud(app)
+        register_database_browser(app)
+        register_response_cache(app)
+        register_desktop_bridge(app)
+        register_flow_hooks(app)
+        register_webhooks(app)
+        register_media(app)
+        register_device_farm(app, [DIAGNOSTICS_OVERLAY_SCRIPT, LIVE_RELOAD_SCRIPT])
+        register_render_api(app)
+        register_sitemap(app)
+        register_social(app)
+        register_flow_editor(app)
+        register_vault(app)
+        register_inference(app)
+        register_ai_models(app)
+        register_vector_search(app)
+        # After the vault and the vector indexes, which it restores
+        register_snapshots(app)
+        register_build_size(app)
+        register_live_reload(app)
+        self.dev_events = register_dev_events(app, project)
+        # Ahead of the embedded build, which then finds the flows parsed
+        preload_flows(app, project)
+        register_embedded_build(app, project, self.build)
+        register_build_api(app)
+        register_mailbox(app)
+        self.notification_scheduler = register_notifications(app)
+        register_integrations(app)
+        register_branch_previews(app)
+
+        for register in self.routes:
+            register(app)
+
+        if self.smtp_port:
+            try:
+                self.smtp_sink = start_smtp_sink(app, 'localhost', self.smtp_port)
+                app.config['SMTP_PORT'] = self.smtp_port
+            except OSError as e:
+                click.echo(f"⚠️  Dev SMTP sink not started on port {self.smtp_port}: {str(e)}")
+        if self.a11y:
+            register_a11y(app)
+        register_profile(app, self.profile)
+        register_security_headers(app, self.csp, [LIVE_RELOAD_SCRIPT, profile_script(self.profile)] +
+                                  ([audit_script(project)] if self.a11y else []))
+
+        # Outside every route and fault injection, so rewritten paths are what gets routed
+        register_transforms(app)
+        for wrap in self.middleware:
+            app.wsgi_app = wrap(app.wsgi_app)
+        # Outermost, so everything inside sees the client and the public URL
+        register_proxy(app, self.https_redirect)
+
+        self.app = app
+        return app
+
+    def listen(self):
+        """Bind the listening socket; with port=0 this is when the port becomes known"""
+        if self.server is None:
+            self.server = self.restarter.make_server(self.create_app(), self.host, self.port)
+            self.port = self.server.server_port
+        return self.server
+
+    def serve_forever(self) -> bool:
+        """Serve from this thread until shut down; returns True when it stopped to restart"""
+        server = self.listen()
+        self.restarter.install_signal_handler()
+        if threading.current_thread() is threading.main_thread():
+            # Stop as Ctrl+C does, so stop() still snapshots; shutdown() waits for the loop, hence the thread
+            signal.signal(signal.SIGTERM, lambda signum, frame: threading.Thread(target=server.shutdown, daemon=True).start())
+        server.serve_forever()
+        if self.restarter.reason:
+            still_running = self.restarter.drain()
+            if still_running:
+                click.echo(f"⚠️  Restarting with {still_running} request(s) still running")
+        return bool(self.restarter.reason)
+
+    def start(self) -> 'DevServer':
+        """Serve from a background thread"""
+        server = self.listen()
+        self._thread = threading.Thread(target=server.serve_forever, name='flashflow-devserver', daemon=True)
+        self._thread.start()
+        return self
+
+    def stop(self):
+        """Stop serving (when start() was used) and shut every helper down"""
+        if self._thread:
+            self.server.shutdown()
+            self._thread.join()
+            self._thread = None
+        if self.server is not None:
+            self.server.server_close()
+        if self.app is not None:
+            snapshot_on_stop(self.app)
+        if self.worker_pool:
+            self.worker_pool.stop()
+        if self.backend:
+            self.backend.stop()
+        if self.smtp_sink:
+            self.smtp_sink.shutdown()
+            self.smtp_sink.server_close()
+        if self.dev_events:
+            self.dev_events.stop()
+        if self.notification_scheduler:
+            self.notification_scheduler.stop()
+        if self._observer:
+            self._observer.stop()
+            self._observer.join()
+
+    def __enter__(self) -> 'DevServer':
+        return self.start()
+
+    def __exit__(self, *exc):
+        self.stop()
+
+def register_flow_api(app):
+    """Register /api/flow-files, /api/preview-data and /api/diagnostics"""
+    project = app.config['PROJECT']
+
+    @app.route('/api/flow-files')
+    def api_flow_files():
+        """API endpoint to get list of flow files"""
+        flows_dir = project.root_path / "src" / "flows"
+        flow_files = []
+        if flows_dir.exists():
+            flow_files = [f.name for f in flows_dir.glob("*.flow")]
+        return {"files": flow_files}
+
+    @app.route('/api/preview-data')
+    def api_preview_data():
+        """API endpoint to get preview data from flow files"""
+        flows_dir = project.root_path / "src" / "flows"
+        flow_data = {}
+        if flows_dir.exists():
+            for flow_file in flows_dir.glob("*.flow"):
+                try:
+                    flow_data[flow_file.name] = load_flow(flow_file, project.root_path).data
+                except Exception as e:
+                    flow_data[flow_file.name] = {"error": str(e)}
+        return {"flow_files": flow_data}
+
+    @app.route('/api/diagnostics')
+    def api_diagnostics():
+        """API endpoint listing every parse and validation error, grouped by file"""
+        return jsonify(diagnostics_report(collect_diagnostics(project.get_flow_files())))
+
+def preload_flows(app, project: FlashFlowProject) -> threading.Thread:
+    """Parse the flows on a background thread, so the server listens at once and the first request finds them cached
+
+    Large projects log their progress; every project logs how long it took.
+    """
+    def progress(done: int, total: int):
+        if total >= PARALLEL_THRESHOLD and done < total and done * 4 // total > (done - 1) * 4 // total:
+            click.echo(f"📚 Parsing flows: {done}/{total}")
+
+    def preload():
+        parser = FlowParser()
+        try:
+            parser.parse_project(project.root_path, progress)
+        except Exception as e:
+            # /api/diagnostics and the overlay report it in detail
+            click.echo(f"⚠️  Flows not parsed at startup: {str(e)}")
+            return
+        if parser.stats and parser.stats.files:
+            app.config['FLOW_LOAD'] = parser.stats
+            click.echo(f"📚 {parser.stats.summary()}")
+
+    thread = threading.Thread(target=preload, daemon=True, name='flashflow-flow-preload')
+    thread.start()
+    return thread
+
+def watch_project(app, project: FlashFlowProject, restarter: ServerRestarter, poll: Any = 'auto'):
+    """Reload clients when a .flow or content file changes and restart on config changes; None when it cannot watch
+
+    poll is the 'watch.poll' setting: native events, or polling where they do not arrive (core/file_watch.py).
+    """
+    flows_dir = project.root_path / "src" / "flows"
+    content_dir = content_path(project.root_path)
+    # Files flows include; a change there rebuilds the pages that include them
+    include_dirs = [project.root_path / folder for folder in INCLUDE_DIRS if folder != 'src/flows']
+    try:
+        observer, polling = create_observer(poll, [flows_dir, content_dir, project.root_path] + include_dirs)
+    except FileWatchError as e:
+        print(f"⚠️  File watching not available: {str(e)}")
+        return None
+    if polling:
+        print(f"👀 Polling for file changes ({polling})")
+
+    class FlowFileHandler:
+        def dispatch(self, event):
+            if event.event_type == 'modified':
+                self.on_modified(event)
+
+        def on_modified(self, event):
+            if event.is_directory:
+                return
+            # .flow files, and the Markdown pages of src/content
+            suffix = Path(str(event.src_path)).suffix
+            if suffix in (".flow", ".md"):
+                print(f"🔄 {suffix} file changed: {event.src_path}")
+                # Reloads every connected client
+                get_dev_events(app).dispatch('file_changed', {'file': Path(str(event.src_path)).name,
+                                                              'path': str(event.src_path)}, 'serve')
+                # Without a build service, dist/web is rebuilt here, as far as the change reaches
+                embedded = get_embedded_build(app)
+                if embedded:
+                    embedded.schedule(Path(str(event.src_path)).name)
+
+    if flows_dir.exists():
+        observer.schedule(FlowFileHandler(), str(flows_dir), recursive=False)
+    if content_dir.exists():
+        observer.schedule(FlowFileHandler(), str(content_dir), recursive=True)
+    for folder in include_dirs:
+        if folder.is_dir():
+            observer.schedule(FlowFileHandler(), str(folder), recursive=True)
+    # flashflow.json and .env changes restart the server
+    observer.schedule(ConfigChangeHandler(restarter), str(project.root_path), recursive=False)
+    observer.start()
+    return observer
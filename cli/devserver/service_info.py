@@ -0,0 +1,34 @@
+"""
+FlashFlow dev server info - /__info for the dev server (see core/service_info.py)
+
+Capabilities are the admin pages registered ('admin/transforms' becomes
+'transforms') plus what the DevServer says it started next to the app
+(API workers, the SMTP sink, file watching, ...), so a client can check
+for a feature instead of probing its routes.
+"""
+
+import time
+from typing import Any, Callable, Dict, List
+
+from flask import jsonify
+
+from cli.core import __version__
+from core.service_info import INFO_PATH, ServiceInfo
+
+def admin_capabilities(app) -> List[str]:
+    """The admin pages of the app, by name"""
+    names = set()
+    for rule in app.url_map.iter_rules():
+        parts = rule.rule.strip('/').split('/')
+        if len(parts) >= 2 and parts[0] == 'admin' and not parts[1].startswith('<'):
+            names.add(parts[1])
+    return sorted(names)
+
+def register_service_info(app, config: Callable[[], Dict[str, Any]], capabilities: Callable[[], List[str]]):
+    """Register /__info; config and capabilities are read on each request, since the port is known only once bound"""
+    started_at = time.time()
+
+    @app.route(INFO_PATH)
+    def service_info():
+        info = ServiceInfo('dev-server', __version__, config(), admin_capabilities(app) + capabilities(), started_at)
+        return jsonify(info.to_dict())
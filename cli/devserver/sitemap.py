@@ -0,0 +1,42 @@
+"""
+FlashFlow dev server sitemap - /sitemap.xml and /robots.txt for the flows as they are now
+
+Both are built from the flow and content pages on every request, the way
+'flashflow build -t static' writes them (core/sitemap.py), with URLs on the
+address the request came in on, so they work through 'serve --share' and
+proxies too. Problems with pages' sitemap settings are listed in a comment
+at the top of sitemap.xml. robots.txt also keeps crawlers out of the dev
+server's own pages, all but the page previews and social cards.
+"""
+
+from flask import Response, request
+
+from core.parser.parser import FlowParser
+from core.sitemap import disallowed_paths, robots_txt, sitemap_entries, sitemap_xml
+from core.social_cards import CARD_PATH
+from cli.devserver.device_farm import PAGE_PREFIX
+
+# The dev server's tooling, which a crawler reaching a shared dev server has no business in
+DEV_DISALLOW = ['/admin/', '/api/', '/__', '/preview', '/edit']
+# Except what a chat app or social network fetches to unfurl a shared page
+DEV_ALLOW = [CARD_PATH + '/', PAGE_PREFIX + '/']
+
+def register_sitemap(app):
+    """Register /sitemap.xml and /robots.txt"""
+    project = app.config['PROJECT']
+
+    @app.route('/sitemap.xml')
+    def sitemap():
+        ir = FlowParser().parse_project(project.root_path)
+        entries, problems = sitemap_entries(ir.pages, app.config['PROFILE'].features)
+        xml = sitemap_xml(request.url_root, entries)
+        if problems:
+            notes = '\n'.join(f"  {problem}".replace('--', '- -') for problem in problems)
+            xml = xml.replace('\n', f"\n<!-- Problems with the pages' sitemap settings:\n{notes}\n-->\n", 1)
+        return Response(xml, mimetype='application/xml', headers={'Cache-Control': 'no-cache'})
+
+    @app.route('/robots.txt')
+    def robots():
+        disallow, _ = disallowed_paths(project.config.static_site or {})
+        text = robots_txt(request.url_root.rstrip('/') + '/sitemap.xml', disallow + DEV_DISALLOW, DEV_ALLOW)
+        return Response(text, mimetype='text/plain', headers={'Cache-Control': 'no-cache'})
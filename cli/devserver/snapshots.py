@@ -0,0 +1,117 @@
+"""
+FlashFlow dev snapshots - Keep in-memory state across dev server restarts
+
+    POST /admin/snapshot                  write the snapshot now
+    POST /admin/restore[?replace=1]       load the last snapshot
+
+A snapshot is .flashflow/snapshot/: snapshot.json, with each vector index's
+dimension, metric and persistence and the vault's key id and sealed count,
+next to one jsonl file per index. The server writes one when it stops,
+including on SIGTERM and before a restart, and restores it when it starts.
+
+At start, indexes persisted on their own (see vector_search.py) are loaded
+first and a snapshot does not overwrite them. /admin/restore keeps existing
+indexes too, unless replace=1 swaps them for the snapshot's.
+"""
+
+import json
+import logging
+import shutil
+from datetime import datetime
+from pathlib import Path
+from typing import Any, Dict, Optional
+
+from flask import request, jsonify
+
+from cli.devserver.audit import audit_admin
+from cli.devserver.vault import restore_vault_metadata, vault_metadata
+from cli.devserver.vector_search import get_vector_indexes
+
+logger = logging.getLogger(__name__)
+
+SNAPSHOT_DIR = 'snapshot'
+SNAPSHOT_FILE = 'snapshot.json'
+
+class SnapshotError(Exception):
+    """Raised when there is no snapshot to restore or it cannot be read"""
+    pass
+
+def snapshot_dir(app) -> Path:
+    return app.config['PROJECT'].state.dir / SNAPSHOT_DIR
+
+def take_snapshot(app) -> Dict[str, Any]:
+    """Write the snapshot; it replaces the previous one only once it is complete"""
+    directory = snapshot_dir(app)
+    partial = directory.with_name(directory.name + '.tmp')
+    shutil.rmtree(partial, ignore_errors=True)
+    partial.mkdir(parents=True)
+    summary = {
+        'created_at': datetime.now().isoformat(),
+        'vectors': get_vector_indexes(app).save(partial),
+        'vault': vault_metadata(app)
+    }
+    (partial / SNAPSHOT_FILE).write_text(json.dumps(summary, indent=2, sort_keys=True), encoding='utf-8')
+
+    previous = directory.with_name(directory.name + '.old')
+    shutil.rmtree(previous, ignore_errors=True)
+    if directory.exists():
+        directory.rename(previous)
+    partial.rename(directory)
+    shutil.rmtree(previous, ignore_errors=True)
+    return summary
+
+def restore_snapshot(app, replace: bool = False) -> Dict[str, Any]:
+    """Load the last snapshot; the names of the indexes it brought back and when it was taken"""
+    directory = snapshot_dir(app)
+    try:
+        summary = json.loads((directory / SNAPSHOT_FILE).read_text(encoding='utf-8'))
+    except FileNotFoundError:
+        raise SnapshotError(f"No snapshot in {directory}")
+    except (OSError, ValueError) as e:
+        raise SnapshotError(f"Cannot read the snapshot in {directory}: {str(e)}")
+    if not isinstance(summary, dict) or not isinstance(summary.get('vectors', {}), dict):
+        raise SnapshotError(f"{directory / SNAPSHOT_FILE} is not a snapshot")
+
+    restored = get_vector_indexes(app).restore(directory, summary.get('vectors') or {}, replace)
+    if isinstance(summary.get('vault'), dict):
+        restore_vault_metadata(app, summary['vault'])
+    return {'created_at': summary.get('created_at'), 'vectors': restored, 'replaced': replace}
+
+def snapshot_on_stop(app) -> Optional[Dict[str, Any]]:
+    """take_snapshot for shutdown: a failure is logged instead of raised"""
+    try:
+        return take_snapshot(app)
+    except (OSError, ValueError) as e:
+        logger.error(f"Could not write the dev server snapshot: {str(e)}")
+        return None
+
+def register_snapshots(app):
+    """Restore the last snapshot and register /admin/snapshot and /admin/restore"""
+    try:
+        restored = restore_snapshot(app)
+        if restored['vectors']:
+            logger.info(f"Restored {len(restored['vectors'])} vector index(es) from the snapshot of {restored['created_at']}")
+    except SnapshotError as e:
+        if snapshot_dir(app).exists():
+            logger.warning(str(e))
+
+    @app.route('/admin/snapshot', methods=['POST'])
+    def admin_snapshot():
+        try:
+            summary = take_snapshot(app)
+        except (OSError, ValueError) as e:
+            return jsonify({'error': f"Could not write the snapshot: {str(e)}"}), 500
+        result = {'created_at': summary['created_at'], 'vectors': sorted(summary['vectors']),
+                  'vault': summary['vault'] is not None}
+        audit_admin(app, 'snapshot.create', 'snapshot', new=result)
+        return jsonify(result)
+
+    @app.route('/admin/restore', methods=['POST'])
+    def admin_restore():
+        replace = request.args.get('replace', '').lower() in ('1', 'true', 'yes')
+        try:
+            result = restore_snapshot(app, replace)
+        except SnapshotError as e:
+            return jsonify({'error': str(e)}), 404 if str(e).startswith('No snapshot') else 500
+        audit_admin(app, 'snapshot.restore', 'snapshot', new=result)
+        return jsonify(result)
@@ -0,0 +1,54 @@
+"""
+FlashFlow dev server social cards - /__og/<route>.png and absolute og: URLs
+
+Pages the dev server renders (the /web/ build and the /preview frames) carry
+Open Graph and Twitter card meta (core/social_cards.py). Their card images
+are drawn here when asked for, from the flows as they are now, and their
+root-relative og:url, og:image and twitter:image are made absolute on the
+address the request came in on, so a link shared through 'serve --share'
+or a proxy unfurls with its title and card.
+"""
+
+import re
+
+from flask import Response, abort, request
+
+from core.social_cards import CARD_PATH, SocialCardError
+from cli.devserver.device_farm import PAGE_PREFIX, get_renderer
+
+SOCIAL_URL = re.compile(r'(<meta (?:property|name)="(og:url|og:image|twitter:image)" content=")(/(?!/)[^"]*)"')
+
+def register_social(app):
+    """Register /__og/<route>.png and make the social meta of rendered pages absolute"""
+
+    @app.route(f'{CARD_PATH}/<path:route>.png')
+    def social_card(route: str):
+        renderer = get_renderer(app)
+        page = renderer.find('/' if route == 'index' else route)
+        if page is None:
+            abort(404)
+        try:
+            png = renderer.card_png(page)
+        except SocialCardError as e:
+            return Response(str(e), status=501, mimetype='text/plain')
+        return Response(png, mimetype='image/png', headers={'Cache-Control': 'no-cache'})
+
+    @app.after_request
+    def absolute_social_urls(response):
+        if (response.status_code != 200 or response.mimetype != 'text/html'
+                or not request.path.startswith(('/web/', PAGE_PREFIX + '/'))):
+            return response
+        # Files from dist/web are streamed; this reads the page to rewrite it
+        response.direct_passthrough = False
+        html = response.get_data(as_text=True)
+        if 'property="og:' not in html:
+            return response
+        # dist/web's pages link its assets from the root they are deployed at, /web/ here
+        base = request.url_root.rstrip('/') + ('/web' if request.path.startswith('/web/') else '')
+
+        def absolute(match):
+            url = request.base_url if match.group(2) == 'og:url' else base + match.group(3)
+            return f'{match.group(1)}{url}"'
+
+        response.set_data(SOCIAL_URL.sub(absolute, html))
+        return response
@@ -0,0 +1,131 @@
+"""
+FlashFlow dev server stats - /__stats request rate, latency and memory for 'flashflow dash'
+
+With limits under "guardrails" in flashflow.json, the server also checks
+itself every SAMPLE_SECONDS (see core/guardrails.py) and prints a warning
+for each breach.
+"""
+
+import math
+import os
+import threading
+import time
+from collections import deque
+from typing import Any, Dict, Optional
+
+import click
+from flask import request, jsonify, g
+
+from core.guardrails import Guardrails, GuardrailError
+from cli.utils.dev_status import process_rss
+
+# Request rate and latency are taken over this many seconds
+WINDOW_SECONDS = 60
+SAMPLE_SECONDS = 10
+# Below this many requests in the window a p99 is just the slowest request, so it is not checked
+MIN_LATENCY_REQUESTS = 20
+
+def percentile(values, fraction: float) -> Optional[float]:
+    if not values:
+        return None
+    ordered = sorted(values)
+    return ordered[max(0, math.ceil(fraction * len(ordered)) - 1)]
+
+def register_stats(app, guardrails: bool = True):
+    """Count requests and register /__stats; the API workers pass guardrails=False and leave checks to the server"""
+    started = time.time()
+    recent = deque()
+    totals = {'requests': 0, 'errors': 0}
+    lock = threading.Lock()
+    last_sample: Dict[str, Any] = {'violations': []}
+
+    def trim(now: float):
+        while recent and recent[0][0] < now - WINDOW_SECONDS:
+            recent.popleft()
+
+    @app.before_request
+    def start_timer():
+        if g.get('request_started') is None:
+            g.request_started = time.perf_counter()
+
+    @app.after_request
+    def count_request(response):
+        # Dev tooling (/__reload, /__stats itself) would drown out real traffic
+        if not request.path.startswith('/__'):
+            now = time.time()
+            duration_ms = (time.perf_counter() - g.request_started) * 1000 if g.get('request_started') else None
+            with lock:
+                recent.append((now, response.status_code, duration_ms))
+                totals['requests'] += 1
+                totals['errors'] += response.status_code >= 500
+                trim(now)
+        return response
+
+    def latency():
+        durations = [duration for _, _, duration in recent if duration is not None]
+        p50, p99 = percentile(durations, 0.5), percentile(durations, 0.99)
+        return {'p50': round(p50, 1) if p50 is not None else None,
+                'p99': round(p99, 1) if p99 is not None else None,
+                'samples': len(durations)}
+
+    def sample() -> Dict[str, Any]:
+        """What the guardrails check: memory, threads and (with enough traffic) p99 latency"""
+        memory = process_rss(os.getpid())
+        with lock:
+            trim(time.time())
+            timings = latency()
+        return {
+            'memory_mb': round(memory / (1024 * 1024), 1) if memory is not None else None,
+            'threads': threading.active_count(),
+            'p99_ms': timings['p99'] if timings['samples'] >= MIN_LATENCY_REQUESTS else None
+        }
+
+    def watch(checks: Guardrails):
+        while True:
+            time.sleep(SAMPLE_SECONDS)
+            try:
+                tagged = checks.check(sample(), 'dev server')
+            except Exception as e:
+                click.echo(f"⚠️  Guardrail check failed: {str(e)}", err=True)
+                continue
+            checks.clear([rule for rule in checks.limits if rule not in tagged['violations']])
+            for rule in tagged['violations']:
+                if rule not in last_sample['violations']:
+                    limit = checks.limits[rule]
+                    click.echo(f"⚠️  Guardrail {rule}: {tagged[rule]:g} is over the limit of {limit:g}", err=True)
+            last_sample.clear()
+            last_sample.update(tagged)
+
+    if guardrails:
+        project = app.config.get('PROJECT')
+        try:
+            checks = Guardrails.for_project(project) if project is not None else None
+        except GuardrailError as e:
+            click.echo(f"⚠️  Guardrails are off: {str(e)}", err=True)
+            checks = None
+        if checks:
+            threading.Thread(target=watch, args=(checks,), name='guardrails', daemon=True).start()
+
+    @app.route('/__stats')
+    def dev_stats():
+        now = time.time()
+        with lock:
+            trim(now)
+            window = min(WINDOW_SECONDS, max(now - started, 1.0))
+            statuses = {}
+            for _, status, _ in recent:
+                key = f"{status // 100}xx"
+                statuses[key] = statuses.get(key, 0) + 1
+            return jsonify({
+                'pid': os.getpid(),
+                'uptime_seconds': round(now - started),
+                'requests': totals['requests'],
+                'errors': totals['errors'],
+                'rate_per_second': round(len(recent) / window, 2),
+                'window_seconds': WINDOW_SECONDS,
+                'statuses': statuses,
+                'latency_ms': latency(),
+                'memory_bytes': process_rss(os.getpid()),
+                'threads': threading.active_count(),
+                'violations': list(last_sample.get('violations', []))
+            })
@@ -0,0 +1,51 @@
+"""
+FlashFlow request tracing - one server span per dev server request
+"""
+
+from flask import request, g
+
+from core.tracing import SpanContext, get_tracer
+
+def register_tracing(app):
+    """Wrap every request in a span; register before other subsystems so their spans nest inside"""
+
+    @app.before_request
+    def start_request_span():
+        tracer = get_tracer()
+        if not tracer.enabled:
+            return None
+        span = tracer.start_span(
+            f"{request.method} {request.url_rule.rule if request.url_rule else request.path}",
+            kind='server',
+            attributes={
+                'http.request.method': request.method,
+                'url.path': request.path,
+                'url.query': request.query_string.decode('utf-8', 'replace'),
+                'user_agent.original': request.headers.get('User-Agent', '')
+            },
+            parent=SpanContext.parse(request.headers.get('traceparent'))
+        )
+        g.trace_span = span
+        g.trace_token = tracer.activate(span)
+        return None
+
+    @app.after_request
+    def record_response(response):
+        span = getattr(g, 'trace_span', None)
+        if span is not None:
+            span.set_attribute('http.response.status_code', response.status_code)
+            if response.status_code >= 500:
+                span.set_error(f"HTTP {response.status_code}")
+            response.headers['traceparent'] = span.context.traceparent()
+        return response
+
+    @app.teardown_request
+    def end_request_span(error=None):
+        span = g.pop('trace_span', None)
+        if span is None:
+            return
+        if error is not None:
+            span.set_error(f"{type(error).__name__}: {error}")
+        tracer = get_tracer()
+        tracer.deactivate(g.pop('trace_token'))
+        tracer.end_span(span)
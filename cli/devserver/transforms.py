@@ -0,0 +1,270 @@
+"""
+FlashFlow dev transforms - Rewrite requests and responses between clients and the dev API
+
+    GET /api/transforms        active rules (with hits), and errors in their declarations
+    /admin/transforms          the same as a page
+
+Rules come from the flows' 'transforms' sections and "transforms" in
+flashflow.json (see core/transforms.py); flow edits apply to the next
+request. TransformMiddleware wraps the whole WSGI app, so paths are
+rewritten before routing and rules apply to generated, proxied and custom
+endpoints alike. Responses it changed carry X-FlashFlow-Transform with the
+rule names. Admin pages, dev tooling and /api/transforms are never
+transformed.
+"""
+
+import io
+import json
+import logging
+from http import HTTPStatus
+from typing import Any, Dict, List, Optional, Tuple
+
+from flask import jsonify, render_template_string
+
+from core.parser.flow_file import FlowDocument
+from core.transforms import TransformRule, load_rules, rename_json
+from cli.devserver.flow_registry import FlowRegistry, FlowSectionRegistry, get_flow_registry
+
+logger = logging.getLogger(__name__)
+
+EXEMPT_PREFIXES = ('/admin', '/__', '/api/transforms')
+TRANSFORM_HEADER = 'X-FlashFlow-Transform'
+
+class TransformRegistry(FlowSectionRegistry):
+    """Rules from flashflow.json and the flows, re-read when a flow file changes"""
+
+    def __init__(self, flows: FlowRegistry):
+        super().__init__(flows)
+        self.rules: List[TransformRule] = []
+
+    def _load(self, documents: List[FlowDocument]):
+        sections = []
+        # flashflow.json changes restart the server, so its rules are read here like the flows'
+        if self.project.config.transforms:
+            sections.append((self.project.config.transforms, 'flashflow.json'))
+        sections.extend(self.sections(documents, 'transforms'))
+        hits = {rule.name: rule.hits for rule in self.rules}
+        self.rules, self.errors = load_rules(sections)
+        for rule in self.rules:
+            rule.hits = hits.get(rule.name, 0)
+        for error in self.errors:
+            logger.warning(f"Invalid transform in {error}")
+
+    def matching(self, method: str, path: str) -> List[TransformRule]:
+        self.reload_if_changed()
+        return [rule for rule in self.rules if rule.matches(method, path)]
+
+def get_transforms(app) -> TransformRegistry:
+    if 'TRANSFORMS' not in app.config:
+        app.config['TRANSFORMS'] = TransformRegistry(get_flow_registry(app))
+    return app.config['TRANSFORMS']
+
+class TransformMiddleware:
+    """WSGI middleware applying the matching rules to each request and its response"""
+
+    def __init__(self, wsgi_app, registry: TransformRegistry):
+        self.wsgi_app = wsgi_app
+        self.registry = registry
+
+    def __call__(self, environ, start_response):
+        path = environ.get('PATH_INFO') or '/'
+        if path.startswith(EXEMPT_PREFIXES):
+            return self.wsgi_app(environ, start_response)
+        try:
+            rules = self.registry.matching(environ.get('REQUEST_METHOD', 'GET'), path)
+        except OSError as e:
+            logger.warning(f"Transforms not applied: {str(e)}")
+            rules = []
+        if not rules:
+            return self.wsgi_app(environ, start_response)
+        names = ', '.join(rule.name for rule in rules)
+        for rule in rules:
+            rule.hits += 1
+
+        stub = next((rule.stub for rule in rules if rule.stub), None)
+        if stub is not None:
+            body = json.dumps(stub.body).encode('utf-8')
+            headers = [('Content-Type', 'application/json'), ('Content-Length', str(len(body))), (TRANSFORM_HEADER, names)]
+            headers += list(stub.headers.items())
+            start_response(f"{stub.status} {_reason(stub.status)}", headers)
+            return [body]
+
+        for rule in rules:
+            environ['PATH_INFO'] = rule.rewrite_path(environ['PATH_INFO'])
+            for header, value in rule.request_headers.items():
+                key = _environ_key(header)
+                if value is None:
+                    environ.pop(key, None)
+                else:
+                    environ[key] = value
+        request_renames = _merged(rule.rename_request for rule in rules)
+        if request_renames:
+            _rename_request_body(environ, request_renames)
+
+        response_renames = _merged(rule.rename_response for rule in rules)
+        response_headers = _merged(rule.response_headers for rule in rules)
+        captured: Dict[str, Any] = {}
+
+        def capture(status, headers, exc_info=None):
+            captured.update(status=status, headers=headers)
+            # The body is renamed once the app has returned it; nothing is sent before that
+            return lambda data: None
+
+        result = self.wsgi_app(environ, capture if response_renames else _with_headers(start_response, response_headers, names))
+        if not response_renames:
+            return result
+        try:
+            body = b''.join(result)
+        finally:
+            if hasattr(result, 'close'):
+                result.close()
+        headers = list(captured.get('headers', []))
+        content_type = next((value for key, value in headers if key.lower() == 'content-type'), '')
+        if 'json' in content_type:
+            body = rename_json(body, response_renames) or body
+            headers = [(key, value) for key, value in headers if key.lower() != 'content-length']
+            headers.append(('Content-Length', str(len(body))))
+        start_response(captured.get('status', '500 INTERNAL SERVER ERROR'), _apply_headers(headers, response_headers, names))
+        return [body]
+
+def _with_headers(start_response, response_headers: Dict[str, Optional[str]], names: str):
+    def wrapped(status, headers, exc_info=None):
+        return start_response(status, _apply_headers(headers, response_headers, names), exc_info)
+    return wrapped
+
+def _apply_headers(headers: List[Tuple[str, str]], changes: Dict[str, Optional[str]], names: str) -> List[Tuple[str, str]]:
+    lowered = {header.lower() for header in changes}
+    result = [(key, value) for key, value in headers if key.lower() not in lowered]
+    result += [(header, value) for header, value in changes.items() if value is not None]
+    return result + [(TRANSFORM_HEADER, names)]
+
+def _merged(mappings) -> Dict[str, Any]:
+    merged: Dict[str, Any] = {}
+    for mapping in mappings:
+        merged.update(mapping)
+    return merged
+
+def _environ_key(header: str) -> str:
+    key = header.upper().replace('-', '_')
+    return key if key in ('CONTENT_TYPE', 'CONTENT_LENGTH') else f"HTTP_{key}"
+
+def _rename_request_body(environ, renames: Dict[str, str]):
+    if 'json' not in environ.get('CONTENT_TYPE', ''):
+        return
+    try:
+        length = int(environ.get('CONTENT_LENGTH') or 0)
+    except ValueError:
+        return
+    body = environ['wsgi.input'].read(length) if length else b''
+    renamed = rename_json(body, renames) if body else None
+    body = renamed if renamed is not None else body
+    environ['wsgi.input'] = io.BytesIO(body)
+    environ['CONTENT_LENGTH'] = str(len(body))
+
+def _reason(status: int) -> str:
+    try:
+        return HTTPStatus(status).phrase
+    except ValueError:
+        return 'Stubbed'
+
+def register_transforms(app):
+    """Wrap the app in TransformMiddleware and register /api/transforms and /admin/transforms
+
+    Call it after every other route and middleware is registered, so the rules see requests first.
+    """
+    registry = get_transforms(app)
+    app.wsgi_app = TransformMiddleware(app.wsgi_app, registry)
+
+    @app.route('/api/transforms')
+    def transforms_list():
+        registry.reload_if_changed()
+        return jsonify({'rules': [rule.to_dict() for rule in registry.rules], 'errors': registry.errors})
+
+    @app.route('/admin/transforms')
+    def admin_transforms_page():
+        project = app.config['PROJECT']
+        return render_template_string(TRANSFORMS_TEMPLATE, project_name=project.config.name)
+
+TRANSFORMS_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Transforms - FlashFlow Admin</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+        .container { max-width: 1200px; margin: 0 auto; padding: 2rem; }
+        .panel { background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); margin-bottom: 1.5rem; }
+        table { width: 100%; border-collapse: collapse; }
+        th, td { text-align: left; padding: 0.5rem; border-bottom: 1px solid #e5e7eb; font-size: 0.9rem; vertical-align: top; }
+        code { background: #f3f4f6; padding: 0.1rem 0.3rem; border-radius: 4px; }
+        .off { color: #9ca3af; }
+        .muted { color: #6b7280; }
+        #errors { color: #b91c1c; font-family: monospace; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>🔀 Transforms</h1>
+        <p>{{ project_name }} · Rules rewriting requests and responses of the dev API</p>
+    </div>
+    <div class="container">
+        <div class="panel">
+            <p class="muted">Declared under <code>transforms</code> in the flows or flashflow.json. Every enabled rule whose
+                route and method match applies; the first stub answers the request. Responses name the rules in
+                <code>X-FlashFlow-Transform</code>.</p>
+            <div id="errors"></div>
+        </div>
+        <div class="panel">
+            <table>
+                <thead><tr><th>Rule</th><th>Route</th><th>Methods</th><th>Does</th><th>Source</th><th>Hits</th></tr></thead>
+                <tbody id="rules"></tbody>
+            </table>
+        </div>
+        <p><a href="/">← Back to Main Dashboard</a></p>
+    </div>
+    <script>
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+
+        function pairs(mapping, arrow) {
+            return Object.entries(mapping).map(([key, value]) =>
+                escapeHtml(key) + arrow + (value === null ? '<em>removed</em>' : escapeHtml(value))).join(', ');
+        }
+
+        function describe(rule) {
+            const parts = [];
+            if (rule.stub) parts.push('stub <code>' + rule.stub.status + '</code> ' + escapeHtml(JSON.stringify(rule.stub.body)));
+            if (rule.rewrite) parts.push('rewrite <code>' + escapeHtml(rule.rewrite.from) + '</code> → <code>' + escapeHtml(rule.rewrite.to) + '</code>');
+            if (Object.keys(rule.request_headers).length) parts.push('request headers: ' + pairs(rule.request_headers, ': '));
+            if (Object.keys(rule.rename_request).length) parts.push('request fields: ' + pairs(rule.rename_request, ' → '));
+            if (Object.keys(rule.rename_response).length) parts.push('response fields: ' + pairs(rule.rename_response, ' → '));
+            if (Object.keys(rule.response_headers).length) parts.push('response headers: ' + pairs(rule.response_headers, ': '));
+            return parts.join('<br>') || '<span class="muted">nothing</span>';
+        }
+
+        async function load() {
+            const data = await (await fetch('/api/transforms')).json();
+            document.getElementById('errors').innerHTML = data.errors.map(escapeHtml).join('<br>');
+            document.getElementById('rules').innerHTML = data.rules.map(rule => `
+                <tr class="${rule.enabled ? '' : 'off'}">
+                    <td><strong>${escapeHtml(rule.name)}</strong>${rule.enabled ? '' : ' (disabled)'}</td>
+                    <td><code>${escapeHtml(rule.route)}</code></td>
+                    <td>${rule.methods.length ? escapeHtml(rule.methods.join(', ')) : 'all'}</td>
+                    <td>${describe(rule)}</td>
+                    <td>${escapeHtml(rule.source)}</td>
+                    <td>${rule.hits}</td>
+                </tr>`).join('') || '<tr><td colspan="6" class="muted">No transforms declared</td></tr>';
+        }
+
+        load();
+        setInterval(load, 3000);
+    </script>
+</body>
+</html>
+"""
@@ -0,0 +1,102 @@
+"""
+FlashFlow dev vault - AES-256-GCM encryption over HTTP
+
+    POST /api/vault/encrypt   {"plaintext": "<base64>", "aad": "<base64>"}    -> envelope
+    POST /api/vault/decrypt   <envelope>                                      -> {"plaintext": "<base64>"}
+    GET  /api/vault           algorithm, key id and where the key came from
+
+The envelope format is described in core/vault.py. Malformed or tampered
+envelopes are answered with 400, and 503 means the 'cryptography' package
+is not installed.
+
+The vault's key id and how many values it has sealed under that key go into
+dev server snapshots (snapshots.py), so the count a key is rotated by
+survives restarts.
+"""
+
+import logging
+from typing import Any, Dict, Optional
+
+from flask import request, jsonify
+
+from core.vault import Vault, VaultError, VaultTamperError, VaultUnavailableError, b64decode, b64encode, load_key
+
+logger = logging.getLogger(__name__)
+
+def get_vault(app) -> Vault:
+    if 'VAULT' not in app.config:
+        project = app.config['PROJECT']
+        key, source = load_key(project.state.path('vault.key'))
+        vault = Vault(key, source)
+        if app.config.get('VAULT_SNAPSHOT'):
+            _apply_metadata(vault, app.config.pop('VAULT_SNAPSHOT'))
+        app.config['VAULT'] = vault
+    return app.config['VAULT']
+
+def vault_metadata(app) -> Optional[Dict[str, Any]]:
+    """The key id and sealed count a snapshot keeps; None when the vault was never used"""
+    vault = app.config.get('VAULT')
+    if vault is None:
+        return app.config.get('VAULT_SNAPSHOT')
+    return {'kid': vault.kid, 'sealed': vault.sealed}
+
+def restore_vault_metadata(app, metadata: Dict[str, Any]):
+    """Carry a snapshot's sealed count over to the vault, if it has the same key"""
+    vault = app.config.get('VAULT')
+    if vault is None:
+        # Applied when the vault is first used, so restoring does not generate a key
+        app.config['VAULT_SNAPSHOT'] = metadata
+    else:
+        _apply_metadata(vault, metadata)
+
+def _apply_metadata(vault: Vault, metadata: Dict[str, Any]):
+    if metadata.get('kid') != vault.kid:
+        logger.warning(f"Vault snapshot is for key {metadata.get('kid')}, not {vault.kid}; its sealed count is ignored")
+        return
+    sealed = metadata.get('sealed')
+    if isinstance(sealed, int) and not isinstance(sealed, bool):
+        # Never counts down: every value sealed under the key used up an IV
+        vault.sealed = max(vault.sealed, sealed)
+
+def register_vault(app):
+    """Register the /api/vault encrypt and decrypt endpoints"""
+
+    def body():
+        data = request.get_json(silent=True)
+        if not isinstance(data, dict):
+            raise VaultError("Send a JSON object")
+        return data
+
+    @app.route('/api/vault')
+    def vault_info():
+        try:
+            return jsonify(get_vault(app).to_dict())
+        except VaultError as e:
+            return jsonify({'error': str(e)}), 500
+
+    @app.route('/api/vault/encrypt', methods=['POST'])
+    def vault_encrypt():
+        try:
+            data = body()
+            unknown = set(data) - {'plaintext', 'aad'}
+            if unknown:
+                raise VaultError(f"Unknown field(s): {', '.join(sorted(unknown))}")
+            plaintext = b64decode(data.get('plaintext'), 'plaintext')
+            aad = b64decode(data['aad'], 'aad') if 'aad' in data else b''
+            return jsonify(get_vault(app).encrypt(plaintext, aad))
+        except VaultUnavailableError as e:
+            return jsonify({'error': str(e)}), 503
+        except VaultError as e:
+            return jsonify({'error': str(e)}), 400
+
+    @app.route('/api/vault/decrypt', methods=['POST'])
+    def vault_decrypt():
+        try:
+            plaintext = get_vault(app).decrypt(body())
+        except VaultUnavailableError as e:
+            return jsonify({'error': str(e)}), 503
+        except VaultTamperError as e:
+            return jsonify({'error': str(e), 'tampered': True}), 400
+        except VaultError as e:
+            return jsonify({'error': str(e)}), 400
+        return jsonify({'plaintext': b64encode(plaintext)})
@@ -0,0 +1,408 @@
+"""
+FlashFlow dev vector search - In-memory indexes for trying queries over HTTP
+
+    POST   /vector/indexes/<name>/vectors   {"vectors": [{"id": 1, "vector": [...]}, ...]}
+    POST   /vector/indexes/<name>/search    {"vector": [...], "k": 5, "radius": 0.8,
+                                             "normalize": "minmax", "ef_search": 200}
+    GET    /vector/indexes
+    PUT    /vector/indexes/<name>           {"dimension": 384, "metric": "cosine", "persist": true}
+    GET    /vector/indexes/<name>
+    DELETE /vector/indexes/<name>
+    GET    /vector/indexes/<name>/export?format=jsonl|parquet
+    POST   /vector/indexes/<name>/import?format=jsonl|parquet[&replace=1]    (the file as the body)
+
+An index (a collection) is created with PUT, which sets its dimension and
+metric, or by its first vectors, which make an l2 index of their dimension.
+Each index has its own dimension and metric: 'l2' ranks by Euclidean
+distance, 'cosine' stores and queries unit-length vectors, so distances are
+between directions. It uses FlashCore's HNSW index when the bindings are
+built, exact search otherwise. An index created with "persist": true is
+written to its own file in .flashflow/vectors after every change and
+loaded again when the server starts; other indexes live until it stops. /api/ai/embed adds to the same indexes and keeps each vector's text,
+which search hits then include as 'document'. Vectors added with a
+'metadata' object get it back in their hits too. A search stops when its
+client disconnects or its X-Request-Timeout passes (see request_context.py).
+
+Export and import move an index, with its texts and metadata, to and from
+the files of core/vector_collections.py; 'flashflow vectors export|import'
+calls them. Importing upserts by id unless replace=1 empties the index first.
+"""
+
+import io
+import json
+import logging
+import math
+import os
+import threading
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+from urllib.parse import quote
+
+from flask import Response, request, jsonify
+
+from core.cancellation import Cancelled
+from core.flashcore_handles import FlashCoreError, FlashCoreHandle, hnsw_index
+from core.vector_collections import (CONTENT_TYPES, VectorCollectionError, VectorRecord, check_dimensions, format_for,
+                                     read_records, write_records)
+from core.vector_search import ExactIndex, SearchOptions, VectorSearchError, index_size, search
+from cli.devserver.request_context import cancellable, cancelled_response
+
+logger = logging.getLogger(__name__)
+
+MAX_ELEMENTS = 100000
+METRICS = ('l2', 'cosine')
+MANIFEST = 'collections.json'
+
+class VectorIndexExistsError(VectorSearchError):
+    """Raised when an index is created under a name already taken with another dimension or metric"""
+    pass
+
+class DevIndex:
+    def __init__(self, dimension: int, metric: str = 'l2', persist: bool = False):
+        self.dimension = dimension
+        self.metric = metric
+        self.persist = persist
+        self.lock = threading.Lock()
+        self.documents: Dict[Any, str] = {}
+        self.metadata: Dict[Any, Dict[str, Any]] = {}
+        # The native index cannot list what it holds, so exports read the vectors from here
+        self.vectors: Dict[Any, List[float]] = {}
+        try:
+            import numpy as np
+            self.index = hnsw_index(dimension, MAX_ELEMENTS)
+            self.backend = 'flashcore-hnsw'
+            self._vector = lambda values: np.asarray(values, dtype=np.float32)
+        except (ImportError, FlashCoreError) as e:
+            if isinstance(e, FlashCoreError):
+                logger.warning(f"Using an exact index instead of FlashCore: {str(e)}")
+            self.index = ExactIndex(dimension, MAX_ELEMENTS)
+            self.backend = 'exact'
+            self._vector = lambda values: values
+
+    def vector(self, values):
+        if not isinstance(values, list) or not all(isinstance(value, (int, float)) and not isinstance(value, bool) for value in values):
+            raise VectorSearchError("A vector must be a list of numbers")
+        if len(values) != self.dimension:
+            raise VectorSearchError(f"Vector has {len(values)} dimensions, the index has {self.dimension}")
+        if self.metric == 'cosine':
+            norm = math.sqrt(sum(value * value for value in values))
+            if norm == 0:
+                raise VectorSearchError("A vector of zeros has no direction to compare by cosine")
+            values = [value / norm for value in values]
+        return self._vector(values)
+
+    def add(self, vector_id, vector, metadata: Optional[Dict[str, Any]] = None, document: Optional[str] = None):
+        """Add or replace one vector checked by vector(); the caller holds the lock"""
+        self.index.add_vector(vector, vector_id)
+        self.vectors[vector_id] = [float(value) for value in vector]
+        if document is None:
+            self.documents.pop(vector_id, None)
+        else:
+            self.documents[vector_id] = document
+        self.set_metadata(vector_id, metadata)
+
+    def records(self) -> List[VectorRecord]:
+        return [VectorRecord(vector_id, vector, self.metadata.get(vector_id), self.documents.get(vector_id))
+                for vector_id, vector in self.vectors.items()]
+
+    def set_metadata(self, vector_id, metadata: Optional[Dict[str, Any]]):
+        if metadata:
+            self.metadata[vector_id] = metadata
+        else:
+            self.metadata.pop(vector_id, None)
+
+    def close(self):
+        if isinstance(self.index, FlashCoreHandle):
+            self.index.close()
+
+    def to_dict(self, name: str):
+        return {'name': name, 'dimension': self.dimension, 'metric': self.metric, 'persist': self.persist,
+                'size': index_size(self.index), 'backend': self.backend}
+
+class DevIndexes:
+    """The server's named indexes, with the persisted ones kept in directory"""
+
+    def __init__(self, directory: Optional[Path] = None):
+        self.indexes: Dict[str, DevIndex] = {}
+        self.directory = directory
+        self.lock = threading.Lock()
+
+    def get(self, name: str) -> Optional[DevIndex]:
+        return self.indexes.get(name)
+
+    def create(self, name: str, dimension: Any, metric: Any = 'l2', persist: bool = False) -> Tuple[DevIndex, bool]:
+        """name's index, created when missing; True when it was. An existing one must have the same dimension and metric"""
+        if isinstance(dimension, bool) or not isinstance(dimension, int) or dimension <= 0:
+            raise VectorSearchError("'dimension' must be a positive integer")
+        if metric not in METRICS:
+            raise VectorSearchError(f"Unknown metric {metric!r}. Use one of: {', '.join(METRICS)}")
+        with self.lock:
+            index = self.indexes.get(name)
+            created = index is None
+            if created:
+                index = self.indexes[name] = DevIndex(dimension, metric)
+            elif (index.dimension, index.metric) != (dimension, metric):
+                raise VectorIndexExistsError(f"Vector index '{name}' already exists with {index.dimension} dimensions "
+                                        f"and the {index.metric} metric")
+            index.persist = bool(persist)
+        self.changed(name)
+        return index, created
+
+    def get_or_create(self, name: str, dimension: int) -> DevIndex:
+        with self.lock:
+            if name not in self.indexes:
+                self.indexes[name] = DevIndex(dimension)
+            return self.indexes[name]
+
+    def replace(self, name: str, dimension: int) -> DevIndex:
+        """A new empty index in place of name's, with its metric and persistence"""
+        with self.lock:
+            previous = self.indexes.get(name)
+            self.indexes[name] = DevIndex(dimension, previous.metric if previous else 'l2',
+                                          previous.persist if previous else False)
+        if previous is not None:
+            with previous.lock:
+                previous.close()
+        return self.indexes[name]
+
+    def drop(self, name: str) -> bool:
+        with self.lock:
+            index = self.indexes.pop(name, None)
+        if index is None:
+            return False
+        with index.lock:
+            index.close()
+        self._forget(name)
+        return True
+
+    def changed(self, name: str):
+        """Write a persisted index to its file after a change; drop the file of one that no longer is"""
+        index = self.indexes.get(name)
+        if index is None or not self.directory:
+            return
+        if not index.persist:
+            self._forget(name)
+            return
+        settings = _write_index(name, index, self.directory)
+        with self.lock:
+            manifest = self._manifest()
+            manifest[name] = settings
+            self._write_manifest(manifest)
+
+    def load(self):
+        """Bring back the persisted indexes; one whose file cannot be read is logged and skipped"""
+        if not self.directory:
+            return
+        for name, settings in self._manifest().items():
+            try:
+                index = _read_index(self.directory, settings, persist=True)
+            except (KeyError, TypeError, ValueError) as e:
+                logger.warning(f"Could not load vector index '{name}' from {self.directory}: {str(e)}")
+                continue
+            with self.lock:
+                self.indexes[name] = index
+
+    def save(self, directory: Path) -> Dict[str, Dict[str, Any]]:
+        """Write every index, persisted or not, to directory; the settings to restore them with"""
+        with self.lock:
+            indexes = dict(self.indexes)
+        return {name: dict(_write_index(name, index, directory), persist=index.persist)
+                for name, index in sorted(indexes.items())}
+
+    def restore(self, directory: Path, saved: Dict[str, Dict[str, Any]], replace: bool = False) -> List[str]:
+        """Bring back indexes written by save; without replace, one already here is kept. The names restored"""
+        restored = []
+        for name, settings in saved.items():
+            if not replace and name in self.indexes:
+                continue
+            try:
+                index = _read_index(directory, settings, bool(settings.get('persist')))
+            except (KeyError, TypeError, ValueError) as e:
+                logger.warning(f"Could not restore vector index '{name}' from {directory}: {str(e)}")
+                continue
+            with self.lock:
+                previous = self.indexes.get(name)
+                self.indexes[name] = index
+            if previous is not None:
+                with previous.lock:
+                    previous.close()
+            self.changed(name)
+            restored.append(name)
+        return restored
+
+    def _forget(self, name: str):
+        if not self.directory:
+            return
+        with self.lock:
+            manifest = self._manifest()
+            settings = manifest.pop(name, None)
+            if settings is None:
+                return
+            self._write_manifest(manifest)
+        try:
+            (self.directory / settings['file']).unlink()
+        except (OSError, KeyError):
+            pass
+
+    def _manifest(self) -> Dict[str, Dict[str, Any]]:
+        try:
+            manifest = json.loads((self.directory / MANIFEST).read_text(encoding='utf-8'))
+        except (OSError, ValueError):
+            return {}
+        return manifest if isinstance(manifest, dict) else {}
+
+    def _write_manifest(self, manifest: Dict[str, Dict[str, Any]]):
+        self.directory.mkdir(parents=True, exist_ok=True)
+        path = self.directory / MANIFEST
+        temporary = path.with_name(path.name + '.tmp')
+        temporary.write_text(json.dumps(manifest, indent=2, sort_keys=True), encoding='utf-8')
+        os.replace(str(temporary), str(path))
+
+def _file_name(name: str) -> str:
+    # Index names come from URLs; quoting keeps them to one file inside the directory
+    return quote(name, safe='') + '.jsonl'
+
+def _write_index(name: str, index: DevIndex, directory: Path) -> Dict[str, Any]:
+    """Write index to its file in directory, replacing the file in one step; its manifest entry"""
+    directory.mkdir(parents=True, exist_ok=True)
+    path = directory / _file_name(name)
+    temporary = path.with_name(path.name + '.tmp')
+    with index.lock:
+        write_records(index.records(), temporary, 'jsonl')
+        os.replace(str(temporary), str(path))
+        return {'dimension': index.dimension, 'metric': index.metric, 'file': path.name}
+
+def _read_index(directory: Path, settings: Dict[str, Any], persist: bool) -> DevIndex:
+    """The index a manifest entry describes; raises ValueError (or KeyError, TypeError) for a bad one"""
+    records = read_records(directory / settings['file'], 'jsonl')
+    index = DevIndex(int(settings['dimension']), settings.get('metric', 'l2'), persist=persist)
+    with index.lock:
+        check_dimensions(records, index.dimension)
+        for record in records:
+            index.add(record.id, index.vector(record.vector), record.metadata, record.document)
+    return index
+
+def get_vector_indexes(app) -> DevIndexes:
+    if 'VECTOR_INDEXES' not in app.config:
+        app.config['VECTOR_INDEXES'] = DevIndexes(app.config['PROJECT'].state.dir / 'vectors')
+    return app.config['VECTOR_INDEXES']
+
+def register_vector_search(app):
+    """Register the /vector routes"""
+    indexes = get_vector_indexes(app)
+    indexes.load()
+
+    @app.route('/vector/indexes', methods=['GET'])
+    def vector_indexes():
+        return jsonify({'indexes': [index.to_dict(name) for name, index in sorted(indexes.indexes.items())]})
+
+    @app.route('/vector/indexes/<name>', methods=['PUT'])
+    def vector_create(name):
+        body = request.get_json(silent=True) or {}
+        try:
+            index, created = indexes.create(name, body.get('dimension'), body.get('metric', 'l2'), bool(body.get('persist')))
+        except VectorIndexExistsError as e:
+            return jsonify({'error': str(e)}), 409
+        except VectorSearchError as e:
+            return jsonify({'error': str(e)}), 400
+        return jsonify(index.to_dict(name)), 201 if created else 200
+
+    @app.route('/vector/indexes/<name>', methods=['GET'])
+    def vector_index(name):
+        index = indexes.get(name)
+        if index is None:
+            return jsonify({'error': f"Vector index '{name}' not found"}), 404
+        return jsonify(index.to_dict(name))
+
+    @app.route('/vector/indexes/<name>/vectors', methods=['POST'])
+    def vector_add(name):
+        body = request.get_json(silent=True) or {}
+        vectors = body.get('vectors')
+        if not isinstance(vectors, list) or not vectors or not all(isinstance(item, dict) and 'id' in item for item in vectors):
+            return jsonify({'error': "Send {'vectors': [{'id': ..., 'vector': [...]}, ...]}"}), 400
+        if not all(isinstance(item.get('metadata', {}), dict) for item in vectors):
+            return jsonify({'error': "'metadata' must be a JSON object"}), 400
+
+        index = indexes.get(name)
+        if index is None:
+            first = vectors[0].get('vector')
+            if not isinstance(first, list) or not first:
+                return jsonify({'error': "A vector must be a non-empty list of numbers"}), 400
+            index = indexes.get_or_create(name, len(first))
+        try:
+            with index.lock:
+                # Check everything before adding anything, so a bad item leaves the index unchanged
+                prepared = [(index.vector(item.get('vector')), item['id'], item.get('metadata')) for item in vectors]
+                for vector, vector_id, metadata in prepared:
+                    index.add(vector_id, vector, metadata)
+        except VectorSearchError as e:
+            return jsonify({'error': str(e)}), 400
+        indexes.changed(name)
+        return jsonify(dict(index.to_dict(name), added=len(prepared)))
+
+    @app.route('/vector/indexes/<name>/search', methods=['POST'])
+    def vector_search(name):
+        index = indexes.get(name)
+        if index is None:
+            return jsonify({'error': f"Vector index '{name}' not found"}), 404
+        body = dict(request.get_json(silent=True) or {})
+        try:
+            query = index.vector(body.pop('vector', None))
+            options = SearchOptions.from_dict(body)
+            with cancellable(app) as cancel, index.lock:
+                result = search(index.index, query, options, cancel)
+                for hit in result['results']:
+                    if hit['id'] in index.documents:
+                        hit['document'] = index.documents[hit['id']]
+                    if hit['id'] in index.metadata:
+                        hit['metadata'] = index.metadata[hit['id']]
+        except VectorSearchError as e:
+            return jsonify({'error': str(e)}), 400
+        except Cancelled as e:
+            return cancelled_response(e)
+        return jsonify(dict(result, index=name, backend=index.backend))
+
+    @app.route('/vector/indexes/<name>', methods=['DELETE'])
+    def vector_drop(name):
+        if not indexes.drop(name):
+            return jsonify({'error': f"Vector index '{name}' not found"}), 404
+        return '', 204
+
+    @app.route('/vector/indexes/<name>/export', methods=['GET'])
+    def vector_export(name):
+        index = indexes.get(name)
+        if index is None:
+            return jsonify({'error': f"Vector index '{name}' not found"}), 404
+        stream = io.BytesIO()
+        try:
+            file_format = format_for(None, request.args.get('format', 'jsonl'))
+            with index.lock:
+                count = write_records(index.records(), stream, file_format)
+        except VectorCollectionError as e:
+            return jsonify({'error': str(e)}), 400
+        response = Response(stream.getvalue(), mimetype=CONTENT_TYPES[file_format])
+        response.headers['Content-Disposition'] = f'attachment; filename="{name}.{file_format}"'
+        response.headers['X-FlashFlow-Vectors'] = str(count)
+        return response
+
+    @app.route('/vector/indexes/<name>/import', methods=['POST'])
+    def vector_import(name):
+        try:
+            records = read_records(request.get_data(), format_for(None, request.args.get('format', 'jsonl')))
+        except VectorCollectionError as e:
+            return jsonify({'error': str(e)}), 400
+        if not records:
+            return jsonify({'error': 'The file holds no vectors'}), 400
+        dimension = len(records[0].vector)
+        replace = request.args.get('replace', '').lower() in ('1', 'true', 'yes')
+        index = indexes.replace(name, dimension) if replace else indexes.get_or_create(name, dimension)
+        try:
+            with index.lock:
+                check_dimensions(records, index.dimension)
+                prepared = [(index.vector(record.vector), record) for record in records]
+                for vector, record in prepared:
+                    index.add(record.id, vector, record.metadata, record.document)
+        except (VectorCollectionError, VectorSearchError) as e:
+            return jsonify({'error': str(e)}), 400
+        indexes.changed(name)
+        return jsonify(dict(index.to_dict(name), imported=len(records), replaced=replace))
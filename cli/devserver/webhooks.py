@@ -0,0 +1,289 @@
+"""
+FlashFlow dev webhooks - fire the flows' 'webhooks:' on /api/data changes
+
+    GET  /api/webhooks                                   declared webhooks and declaration errors
+    GET  /api/webhooks/deliveries                        recent deliveries, newest first (?status=failed)
+    GET  /api/webhooks/deliveries/<id>                   one delivery with its payload and attempts
+    POST /api/webhooks/deliveries/<id>/redeliver         send the same payload again as a new delivery
+
+Creating, updating or deleting a row through /api/data fires the webhooks
+whose event matches '<model>.<created|updated|deleted>'. /admin/webhooks
+shows the delivery history and redelivers with one click. See
+core/webhooks.py for the declaration format, signing and retries.
+"""
+
+import logging
+from typing import Dict, Any, List
+
+from flask import request, jsonify, render_template_string
+
+from core.parser.flow_file import FlowDocument
+from core.parser.parser import extract_webhooks
+from core.webhooks import (DeliveryStore, WebhookDeclaration, WebhookDispatcher, WebhookError, event_payload,
+                           load_declarations)
+from cli.devserver.flow_registry import FlowRegistry, FlowSectionRegistry, get_flow_registry
+
+logger = logging.getLogger(__name__)
+
+DELIVERY_STATUSES = ('pending', 'retrying', 'delivered', 'failed')
+
+class WebhookRegistry(FlowSectionRegistry):
+    """Webhook declarations from the flows, re-read when a flow file changes"""
+
+    def __init__(self, flows: FlowRegistry):
+        super().__init__(flows)
+        self.webhooks: List[WebhookDeclaration] = []
+        self.dispatcher = WebhookDispatcher(DeliveryStore.for_project(self.project))
+
+    def reload_if_changed(self) -> bool:
+        changed = super().reload_if_changed()
+        # Deliveries left pending by a server that has since stopped
+        self.dispatcher.adopt(self.webhooks)
+        return changed
+
+    def _load(self, documents: List[FlowDocument]):
+        definitions = [(definition, document.file.name, index) for document in documents
+                       for index, definition in enumerate(extract_webhooks(document.data))]
+        self.webhooks, self.errors = load_declarations(definitions)
+        for error in self.errors:
+            logger.warning(f"Invalid webhook in {error}")
+
+    def get(self, name: str) -> WebhookDeclaration:
+        for webhook in self.webhooks:
+            if webhook.name == name:
+                return webhook
+        raise WebhookError(f"Webhook '{name}' is no longer declared")
+
+def get_webhook_registry(app) -> WebhookRegistry:
+    if 'WEBHOOKS' not in app.config:
+        app.config['WEBHOOKS'] = WebhookRegistry(get_flow_registry(app))
+    return app.config['WEBHOOKS']
+
+def fire_webhooks(app, model: str, action: str, row: Dict[str, Any]) -> int:
+    """Queue a delivery to every webhook matching '<model>.<action>'; returns how many"""
+    registry = get_webhook_registry(app)
+    registry.reload_if_changed()
+    payload = event_payload(model, action, row)
+    matching = [webhook for webhook in registry.webhooks if webhook.matches(payload['event'])]
+    for webhook in matching:
+        registry.dispatcher.enqueue(webhook, payload)
+    return len(matching)
+
+def register_webhooks(app):
+    """Register /api/webhooks and the /admin/webhooks page"""
+    registry = get_webhook_registry(app)
+
+    @app.route('/api/webhooks')
+    def webhooks_list():
+        registry.reload_if_changed()
+        return jsonify({'webhooks': [webhook.to_dict() for webhook in registry.webhooks], 'errors': registry.errors})
+
+    @app.route('/api/webhooks/deliveries')
+    def webhook_deliveries():
+        registry.reload_if_changed()
+        status = request.args.get('status')
+        deliveries = registry.dispatcher.store.list()
+        if status:
+            deliveries = [delivery for delivery in deliveries if delivery.get('status') == status]
+        # The list leaves out payloads and response bodies; they are in the single delivery
+        return jsonify({'deliveries': [_summary(delivery) for delivery in deliveries]})
+
+    @app.route('/api/webhooks/deliveries/<delivery_id>')
+    def webhook_delivery(delivery_id):
+        try:
+            return jsonify(registry.dispatcher.store.get(delivery_id))
+        except WebhookError as e:
+            return jsonify({'error': str(e)}), 404
+
+    @app.route('/api/webhooks/deliveries/<delivery_id>/redeliver', methods=['POST'])
+    def webhook_redeliver(delivery_id):
+        registry.reload_if_changed()
+        try:
+            original = registry.dispatcher.store.get(delivery_id)
+        except WebhookError as e:
+            return jsonify({'error': str(e)}), 404
+        try:
+            webhook = registry.get(original['webhook'])
+        except WebhookError as e:
+            return jsonify({'error': str(e)}), 409
+        delivery = registry.dispatcher.enqueue(webhook, original['payload'], redelivery_of=original['id'])
+        return jsonify(_summary(delivery)), 202
+
+    @app.route('/admin/webhooks')
+    def admin_webhooks_page():
+        project = app.config['PROJECT']
+        return render_template_string(WEBHOOKS_TEMPLATE, project_name=project.config.name, statuses=DELIVERY_STATUSES)
+
+def _summary(delivery: Dict[str, Any]) -> Dict[str, Any]:
+    attempts = delivery.get('attempts', [])
+    last = attempts[-1] if attempts else {}
+    return {
+        'id': delivery['id'],
+        'webhook': delivery.get('webhook'),
+        'event': delivery.get('event'),
+        'url': delivery.get('url'),
+        'status': delivery.get('status'),
+        'attempts': len(attempts),
+        'max_attempts': delivery.get('max_attempts'),
+        'last_status_code': last.get('status_code'),
+        'last_error': last.get('error'),
+        'created_at': delivery.get('created_at'),
+        'next_attempt_at': delivery.get('next_attempt_at'),
+        'redelivery_of': delivery.get('redelivery_of')
+    }
+
+WEBHOOKS_TEMPLATE = """
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Webhooks - FlashFlow Admin</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }
+        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+        .container { max-width: 1400px; margin: 0 auto; padding: 2rem; display: grid; grid-template-columns: 1fr 1fr; gap: 2rem; }
+        .panel { background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); margin-bottom: 1.5rem; }
+        table { width: 100%; border-collapse: collapse; }
+        th, td { text-align: left; padding: 0.45rem; border-bottom: 1px solid #e5e7eb; font-size: 0.875rem; }
+        tr.delivery { cursor: pointer; }
+        tr.delivery:hover, tr.delivery.active { background: #e0e7ff; }
+        .status { padding: 0.1rem 0.5rem; border-radius: 999px; font-size: 0.75rem; }
+        .delivered { background: #dcfce7; color: #166534; }
+        .failed { background: #fee2e2; color: #991b1b; }
+        .retrying, .pending { background: #fef3c7; color: #92400e; }
+        button { background: #3B82F6; color: white; border: none; padding: 0.4rem 0.9rem; border-radius: 4px; cursor: pointer; }
+        select { padding: 0.35rem; }
+        pre { white-space: pre-wrap; background: #f3f4f6; padding: 1rem; border-radius: 4px; max-height: 360px; overflow: auto; }
+        .muted { color: #6b7280; }
+        #error, .error { color: #b91c1c; font-family: monospace; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>🪝 Webhooks</h1>
+        <p>{{ project_name }} · Model changes made through /api/data, delivered to the flows' 'webhooks:'</p>
+    </div>
+    <div class="container">
+        <div>
+            <div class="panel">
+                <h3>Declared</h3>
+                <div id="declared" class="muted">Loading...</div>
+            </div>
+            <div class="panel">
+                <p>
+                    <select id="status">
+                        <option value="">All deliveries</option>
+                        {% for status in statuses %}<option value="{{ status }}">{{ status }}</option>{% endfor %}
+                    </select>
+                    <button id="refresh">Refresh</button>
+                </p>
+                <table>
+                    <thead><tr><th>Event</th><th>Webhook</th><th>Status</th><th>Attempts</th><th>Created</th></tr></thead>
+                    <tbody id="deliveries"></tbody>
+                </table>
+            </div>
+        </div>
+        <div class="panel">
+            <div id="error"></div>
+            <div id="detail" class="muted">Select a delivery</div>
+            <p><a href="/">← Back to Main Dashboard</a></p>
+        </div>
+    </div>
+    <script>
+        let current = null;
+
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text == null ? '' : String(text);
+            return div.innerHTML;
+        }
+
+        async function api(url, options) {
+            const response = await fetch(url, options);
+            const data = await response.json();
+            if (!response.ok) throw new Error(data.error || response.statusText);
+            return data;
+        }
+
+        function when(seconds) {
+            return seconds ? new Date(seconds * 1000).toLocaleString() : '–';
+        }
+
+        async function loadDeclared() {
+            const data = await api('/api/webhooks');
+            const rows = data.webhooks.map(webhook => `<tr>
+                    <td><strong>${escapeHtml(webhook.name)}</strong><br><span class="muted">${escapeHtml(webhook.source)}</span></td>
+                    <td><code>${escapeHtml(webhook.event)}</code></td>
+                    <td>${escapeHtml(webhook.url)}</td>
+                    <td>${webhook.signed ? '🔏 signed' : '<span class="muted">unsigned</span>'}</td>
+                </tr>`).join('');
+            const errors = data.errors.map(error => `<p class="error">${escapeHtml(error)}</p>`).join('');
+            document.getElementById('declared').innerHTML = (rows ? `<table>${rows}</table>` : 'No webhooks declared in the flows') + errors;
+        }
+
+        async function loadDeliveries() {
+            const status = document.getElementById('status').value;
+            const data = await api('/api/webhooks/deliveries' + (status ? '?status=' + encodeURIComponent(status) : ''));
+            document.getElementById('deliveries').innerHTML = data.deliveries.map(delivery => `
+                <tr class="delivery ${delivery.id === current ? 'active' : ''}" data-id="${delivery.id}">
+                    <td><code>${escapeHtml(delivery.event)}</code>${delivery.redelivery_of ? ' <span class="muted">↻</span>' : ''}</td>
+                    <td>${escapeHtml(delivery.webhook)}</td>
+                    <td><span class="status ${delivery.status}">${delivery.status}</span></td>
+                    <td>${delivery.attempts}/${delivery.max_attempts}</td>
+                    <td>${when(delivery.created_at)}</td>
+                </tr>`).join('') || '<tr><td colspan="5" class="muted">No deliveries yet</td></tr>';
+            document.querySelectorAll('tr.delivery').forEach(row => row.onclick = () => show(row.dataset.id));
+        }
+
+        async function show(id) {
+            current = id;
+            document.getElementById('error').textContent = '';
+            const delivery = await api('/api/webhooks/deliveries/' + id);
+            const attempts = delivery.attempts.map((attempt, index) => `<tr>
+                    <td>#${index + 1}</td>
+                    <td>${when(attempt.at)}</td>
+                    <td>${attempt.status_code || '–'}</td>
+                    <td>${attempt.duration_ms} ms</td>
+                    <td>${attempt.error ? escapeHtml(attempt.error) : '✅'}</td>
+                </tr>`).join('');
+            const last = delivery.attempts[delivery.attempts.length - 1];
+            document.getElementById('detail').innerHTML = `
+                <h3><code>${escapeHtml(delivery.event)}</code> → ${escapeHtml(delivery.webhook)}</h3>
+                <p><span class="status ${delivery.status}">${delivery.status}</span>
+                    ${delivery.next_attempt_at ? ' · next attempt ' + when(delivery.next_attempt_at) : ''}
+                    ${delivery.redelivery_of ? ' · redelivery of ' + escapeHtml(delivery.redelivery_of) : ''}</p>
+                <p class="muted">${escapeHtml(delivery.url)}</p>
+                <p><button id="redeliver">Redeliver</button></p>
+                <h4>Attempts</h4>
+                ${attempts ? `<table>${attempts}</table>` : '<p class="muted">Not attempted yet</p>'}
+                ${last && last.response ? `<h4>Last response</h4><pre>${escapeHtml(last.response)}</pre>` : ''}
+                <h4>Payload</h4>
+                <pre>${escapeHtml(JSON.stringify(delivery.payload, null, 2))}</pre>`;
+            document.getElementById('redeliver').onclick = () => redeliver(delivery.id);
+            loadDeliveries();
+        }
+
+        async function redeliver(id) {
+            try {
+                const delivery = await api('/api/webhooks/deliveries/' + id + '/redeliver', {method: 'POST'});
+                // Give the first attempt a moment before showing it
+                setTimeout(() => show(delivery.id).catch(showError), 800);
+            } catch (e) {
+                showError(e);
+            }
+        }
+
+        function showError(e) {
+            document.getElementById('error').textContent = '❌ ' + e.message;
+        }
+
+        document.getElementById('refresh').onclick = () => loadDeliveries().catch(showError);
+        document.getElementById('status').onchange = () => loadDeliveries().catch(showError);
+        loadDeclared().catch(showError);
+        loadDeliveries().catch(showError);
+        setInterval(() => loadDeliveries().catch(showError), 5000);
+    </script>
+</body>
+</html>
+"""
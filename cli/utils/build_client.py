@@ -0,0 +1,96 @@
+"""
+FlashFlow build client - Trigger and follow dev server builds from Python
+
+    from cli.utils.build_client import BuildClient
+
+    client = BuildClient()                       # the project's running dev server
+    build = client.start(target='frontend', env='staging', clean=True)
+    result = client.wait(build['id'], on_log=print)
+    if result['status'] != 'ok':
+        print(result['errors'])
+
+A thin wrapper over the dev server's /api/build routes
+(cli/devserver/build_api.py) for editor plugins, CI bots and scripts that
+would otherwise shell out to 'flashflow build'.
+"""
+
+import time
+from pathlib import Path
+from typing import Any, Callable, Dict, List, Optional
+
+import requests
+
+from core.framework import FlashFlowProject
+from cli.utils.dev_status import server_url
+
+TIMEOUT = 10
+POLL_INTERVAL = 0.5
+FINISHED = ('ok', 'failed', 'skipped', 'cancelled', 'error')
+
+class BuildClientError(Exception):
+    """Raised when the dev server cannot be reached or refuses a request"""
+
+    def __init__(self, message: str, status: Optional[int] = None):
+        super().__init__(message)
+        self.status = status
+
+class BuildClient:
+    """Builds on a running dev server, by default the one serving the current project"""
+
+    def __init__(self, url: Optional[str] = None, project_root: Optional[Path] = None, actor: Optional[str] = None):
+        if url is None:
+            project = FlashFlowProject(project_root or Path.cwd())
+            if not project.exists():
+                raise BuildClientError("Not in a FlashFlow project directory; pass the dev server's url")
+            url = server_url(project)
+        self.url = url.rstrip('/')
+        self.headers = {'X-FlashFlow-Actor': actor} if actor else {}
+
+    def _request(self, method: str, path: str, **kwargs) -> Dict[str, Any]:
+        url = f"{self.url}{path}"
+        try:
+            response = requests.request(method, url, timeout=TIMEOUT, headers=self.headers, **kwargs)
+        except requests.RequestException as e:
+            raise BuildClientError(f"Cannot reach {url}: {e}")
+        try:
+            data = response.json()
+        except ValueError:
+            data = {'error': response.text.strip() or response.reason}
+        if response.status_code >= 400:
+            raise BuildClientError(f"{method} {path} answered {response.status_code}: {data.get('error')}",
+                                   response.status_code)
+        return data
+
+    def start(self, target: str = 'all', env: Optional[str] = None, clean: bool = False, full: bool = False) -> Dict[str, Any]:
+        """Queue a build; returns it with its id and status 'queued'"""
+        body: Dict[str, Any] = {'target': target, 'clean': clean, 'full': full}
+        if env:
+            body['env'] = env
+        return self._request('POST', '/api/build', json=body)
+
+    def status(self, build_id: str, since: int = 0) -> Dict[str, Any]:
+        """The build with the log lines from line `since` on"""
+        return self._request('GET', f"/api/build/{build_id}", params={'since': since})
+
+    def builds(self) -> List[Dict[str, Any]]:
+        return self._request('GET', '/api/build')['builds']
+
+    def cancel(self, build_id: str) -> Dict[str, Any]:
+        return self._request('DELETE', f"/api/build/{build_id}")
+
+    def wait(self, build_id: str, timeout: Optional[float] = None,
+             on_log: Optional[Callable[[str], None]] = None) -> Dict[str, Any]:
+        """Poll until the build finishes, handing each new log line to on_log; raises TimeoutError"""
+        deadline = time.monotonic() + timeout if timeout is not None else None
+        since = 0
+        while True:
+            build = self.status(build_id, since)
+            for line in build.get('log', []):
+                if on_log:
+                    on_log(line)
+            since = build.get('log_lines', since)
+            if build['status'] in FINISHED:
+                return build
+            if deadline is not None and time.monotonic() >= deadline:
+                raise TimeoutError(f"Build {build_id} still {build['status']} after {timeout}s")
+            time.sleep(POLL_INTERVAL)
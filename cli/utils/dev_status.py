@@ -0,0 +1,191 @@
+"""
+FlashFlow dev status - One snapshot of a project's dev environment
+
+Gathers what 'flashflow dash' shows from the places the other commands
+already write to: runtime records in .flashflow/run, the last build report in
+.flashflow/metrics/build.json, guardrail alerts in .flashflow/metrics/alerts.json,
+logs in .flashflow/logs, the installed OS service, and the running
+services' /__info and the dev server's /__stats endpoints.
+"""
+
+import json
+import os
+import sys
+import time
+from pathlib import Path
+from typing import Dict, Any, List, Optional
+
+import requests
+
+from core.framework import FlashFlowProject
+from core.guardrails import recent_alerts
+from core.service_info import fetch_info, same_project
+
+BUILD_RECORD = "build.json"
+STATS_TIMEOUT = 1.0
+# Guardrail alerts older than this are left off the dashboard
+ALERT_WINDOW = 3600
+
+def pid_running(pid: Optional[int]) -> bool:
+    if not pid:
+        return False
+    if os.name == 'nt':
+        import ctypes
+        # PROCESS_QUERY_LIMITED_INFORMATION; os.kill(pid, 0) would terminate the process on Windows
+        handle = ctypes.windll.kernel32.OpenProcess(0x1000, False, int(pid))
+        if not handle:
+            return False
+        exit_code = ctypes.c_ulong()
+        ctypes.windll.kernel32.GetExitCodeProcess(handle, ctypes.byref(exit_code))
+        ctypes.windll.kernel32.CloseHandle(handle)
+        return exit_code.value == 259  # STILL_ACTIVE
+    try:
+        os.kill(int(pid), 0)
+    except ProcessLookupError:
+        return False
+    except PermissionError:
+        return True
+    return True
+
+def process_rss(pid: int) -> Optional[int]:
+    """Resident memory of a process in bytes, when the platform lets us read it"""
+    try:
+        import psutil
+        return psutil.Process(pid).memory_info().rss
+    except ImportError:
+        pass
+    except Exception:
+        return None
+    status = Path(f"/proc/{pid}/status")
+    if status.exists():
+        for line in status.read_text().splitlines():
+            if line.startswith('VmRSS:'):
+                return int(line.split()[1]) * 1024
+    if pid == os.getpid() and sys.platform != 'win32':
+        import resource
+        peak = resource.getrusage(resource.RUSAGE_SELF).ru_maxrss
+        # ru_maxrss is bytes on macOS and kilobytes elsewhere; a peak, but better than nothing
+        return peak if sys.platform == 'darwin' else peak * 1024
+    return None
+
+def record_build(project: FlashFlowProject, report: Dict[str, Any], seconds: float):
+    """Keep the last build's report for 'flashflow dash'"""
+    record = dict(report, finished_at=time.time(), seconds=round(seconds, 2))
+    record.pop('log', None)
+    with open(project.state.path('metrics', BUILD_RECORD), 'w') as f:
+        json.dump(record, f, indent=2)
+
+def last_build(project: FlashFlowProject) -> Optional[Dict[str, Any]]:
+    path = project.state.dir / 'metrics' / BUILD_RECORD
+    try:
+        return json.loads(path.read_text())
+    except (OSError, json.JSONDecodeError):
+        return None
+
+def server_url(project: FlashFlowProject) -> str:
+    running = project.state.read_runtime('serve') or {}
+    host = running.get('host', '127.0.0.1')
+    if host in ('0.0.0.0', '::', 'localhost'):
+        host = '127.0.0.1'
+    return f"http://{host}:{running.get('port', 8000)}"
+
+def server_stats(url: str) -> Optional[Dict[str, Any]]:
+    try:
+        response = requests.get(f"{url}/__stats", timeout=STATS_TIMEOUT)
+        return response.json() if response.status_code == 200 else None
+    except (requests.RequestException, ValueError):
+        return None
+
+def services(project: FlashFlowProject) -> List[Dict[str, Any]]:
+    """The dev server, the engine and the installed OS service, running or not
+
+    A service counts as running when its process is alive and, where it
+    answers /__info, says it serves this project; the URL, version and
+    capabilities come from its /__info rather than from the port it was
+    started with.
+    """
+    found = []
+    serve = project.state.read_runtime('serve') or {}
+    # Checking the pid rather than taking the serve lock, which would race a starting server
+    running = pid_running(serve.get('pid'))
+    url = server_url(project)
+    info = fetch_info(url) if running else None
+    found.append(dict({
+        'name': 'dev server', 'running': running, 'pid': serve.get('pid') if running else None,
+        'url': url if running else None, 'memory_bytes': None
+    }, **_info_fields(project, info)))
+
+    engine = project.state.read_runtime('engine') or {}
+    engine_running = pid_running(engine.get('pid'))
+    info = fetch_info(engine['info_url']) if engine_running and engine.get('info_url') else None
+    served = info['config'].get('port') if same_project(info, project.root_path) else None
+    engine_port = served or engine.get('port', 8012)
+    found.append(dict({
+        'name': 'engine', 'running': engine_running, 'pid': engine.get('pid') if engine_running else None,
+        'url': f"http://localhost:{engine_port}" if engine_running else None,
+        'memory_bytes': process_rss(engine['pid']) if engine_running else None
+    }, **_info_fields(project, info)))
+
+    from cli.utils.os_service import OSServiceError, read_installed, service_manager
+    spec = read_installed(project)
+    if spec:
+        try:
+            state = service_manager(project).status(spec)
+        except OSServiceError as e:
+            state = f"unknown ({e})"
+        found.append({'name': f"os service {spec.name}", 'running': state == 'running', 'state': state,
+                      'pid': None, 'url': None, 'memory_bytes': None})
+    return found
+
+def _info_fields(project: FlashFlowProject, info: Optional[Dict[str, Any]]) -> Dict[str, Any]:
+    """What a service's /__info adds to its entry; a service of another project is flagged instead"""
+    if not info:
+        return {'info': None}
+    if not same_project(info, project.root_path):
+        served = (info.get('config') or {}).get('project', 'another project')
+        return {'info': info, 'state': f"port taken by {served}"}
+    return {'info': info, 'version': info.get('version'), 'commit': info.get('commit'),
+            'started_at': info.get('started_at'), 'capabilities': info.get('capabilities', [])}
+
+def log_files(project: FlashFlowProject) -> List[Path]:
+    logs_dir = project.state.dir / 'logs'
+    if not logs_dir.is_dir():
+        return []
+    return sorted(logs_dir.glob("*.log"), key=lambda path: path.stat().st_mtime, reverse=True)
+
+def tail(path: Path, lines: int, max_bytes: int = 64 * 1024) -> List[str]:
+    """Last lines of a file without reading all of it"""
+    try:
+        with open(path, 'rb') as f:
+            f.seek(0, os.SEEK_END)
+            size = f.tell()
+            f.seek(max(0, size - max_bytes))
+            data = f.read().decode('utf-8', errors='replace')
+    except OSError:
+        return []
+    return data.splitlines()[-lines:] if lines > 0 else []
+
+def snapshot(project: FlashFlowProject) -> Dict[str, Any]:
+    """Everything the dashboard shows, as JSON-friendly data"""
+    found = services(project)
+    # Stats of a server serving another project would be someone else's numbers
+    stats = server_stats(found[0]['url']) if found[0]['running'] and not found[0].get('state') else None
+    if stats:
+        found[0]['memory_bytes'] = stats.get('memory_bytes')
+    return {
+        'project': project.config.name,
+        'services': found,
+        'server': stats,
+        'build': last_build(project),
+        'alerts': recent_alerts(project, since=time.time() - ALERT_WINDOW),
+        'logs': [str(path.relative_to(project.root_path)) for path in log_files(project)]
+    }
+
+def format_bytes(count: Optional[int]) -> str:
+    if count is None:
+        return '-'
+    for unit in ('B', 'KB', 'MB', 'GB'):
+        if count < 1024 or unit == 'GB':
+            return f"{count:.0f} {unit}" if unit == 'B' else f"{count:.1f} {unit}"
+        count /= 1024
+    return '-'
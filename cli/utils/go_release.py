@@ -0,0 +1,159 @@
+"""
+FlashFlow Go releases - Cross-compiled, versioned archives of go-services/*
+
+Each service is built for every target with CGO off, so no cross C
+toolchain is needed:
+
+    linux/amd64  linux/arm64  darwin/universal  windows/amd64
+
+darwin/universal is darwin/amd64 and darwin/arm64 joined with lipo, which
+comes with the Xcode command line tools; elsewhere name darwin/amd64 and
+darwin/arm64 instead. Any GOOS/GOARCH pair 'go tool dist list' knows can
+be named as a target.
+
+The version, commit and build time are set with -ldflags on these package
+main variables, which a service reports at /__info (core/service_info.py):
+
+    var version, commit, buildDate string
+
+Archives are written as flashflow-<service>_<version>_<os>_<arch>.tar.gz
+(.zip for Windows). Each holds the binary as flashflow-<service>, the name
+the CLI looks for on PATH, with its .sha256 record and, when
+FLASHFLOW_SIGNING_KEY is set, its .sig, so an unpacked binary passes
+'flashflow services verify'. SHA256SUMS lists every archive and is signed
+the same way.
+"""
+
+import os
+import shutil
+import subprocess
+import tarfile
+import tempfile
+import zipfile
+from datetime import datetime, timezone
+from pathlib import Path
+from typing import Callable, List, Optional, Tuple
+
+from cli.utils.go_services import GO_SERVICES_DIR, ServiceIntegrityError, file_sha256, record_checksum, sign_digest
+from core.service_info import source_commit
+
+DEFAULT_TARGETS = ('linux/amd64', 'linux/arm64', 'darwin/universal', 'windows/amd64')
+UNIVERSAL_ARCHES = ('amd64', 'arm64')
+CHECKSUMS_FILE = 'SHA256SUMS'
+RELEASE_DIR = Path('dist') / 'release'
+
+class ReleaseError(Exception):
+    """Raised when a release target cannot be built or packaged"""
+    pass
+
+def parse_target(spec: str) -> Tuple[str, str]:
+    """'linux/arm64' as ('linux', 'arm64')"""
+    goos, _, goarch = spec.strip().lower().partition('/')
+    if not goos or not goarch or '/' in goarch:
+        raise ReleaseError(f"Target '{spec}' must be <os>/<arch>, e.g. linux/arm64")
+    if goarch == 'universal' and goos != 'darwin':
+        raise ReleaseError(f"Only darwin has a universal target, not '{spec}'")
+    return goos, goarch
+
+def ldflags(version: str, commit: Optional[str], built_at: str) -> str:
+    flags = ['-s', '-w', f"-X main.version={version}", f"-X main.buildDate={built_at}"]
+    if commit:
+        flags.append(f"-X main.commit={commit}")
+    return ' '.join(flags)
+
+def binary_name(service_name: str, goos: str) -> str:
+    return f"flashflow-{service_name}" + ('.exe' if goos == 'windows' else '')
+
+def archive_name(service_name: str, version: str, goos: str, goarch: str) -> str:
+    extension = 'zip' if goos == 'windows' else 'tar.gz'
+    return f"flashflow-{service_name}_{version}_{goos}_{goarch}.{extension}"
+
+def cross_build(service_name: str, goos: str, goarch: str, output: Path, flags: str):
+    """go build one service for one GOOS/GOARCH"""
+    service_dir = GO_SERVICES_DIR / service_name
+    if not (service_dir / "go.mod").exists() and not list(service_dir.glob("*.go")):
+        raise ReleaseError(f"No Go sources in {service_dir}")
+    env = dict(os.environ, GOOS=goos, GOARCH=goarch, CGO_ENABLED='0')
+    result = subprocess.run(['go', 'build', '-trimpath', '-ldflags', flags, '-o', str(output), '.'],
+                            cwd=service_dir, env=env, capture_output=True, text=True)
+    if result.returncode != 0:
+        raise ReleaseError(f"go build failed for {service_name} ({goos}/{goarch}):\n{result.stderr.strip()}")
+
+def build_target(service_name: str, goos: str, goarch: str, output: Path, flags: str):
+    """Build a binary for a target, joining the darwin architectures for darwin/universal"""
+    if goarch != 'universal':
+        cross_build(service_name, goos, goarch, output, flags)
+        return
+    lipo = shutil.which('lipo')
+    if lipo is None:
+        raise ReleaseError("darwin/universal needs lipo (Xcode command line tools); "
+                           "build darwin/amd64 and darwin/arm64 instead")
+    parts = []
+    for arch in UNIVERSAL_ARCHES:
+        part = output.with_name(f"{output.name}-{arch}")
+        cross_build(service_name, goos, arch, part, flags)
+        parts.append(part)
+    result = subprocess.run([lipo, '-create', '-output', str(output)] + [str(part) for part in parts],
+                            capture_output=True, text=True)
+    for part in parts:
+        part.unlink()
+    if result.returncode != 0:
+        raise ReleaseError(f"lipo failed for {service_name}:\n{result.stderr.strip()}")
+
+def package(staging: Path, archive: Path):
+    """Archive every file in staging under a folder named like the archive"""
+    folder = archive.name[:-len('.tar.gz')] if archive.name.endswith('.tar.gz') else archive.stem
+    files = sorted(path for path in staging.iterdir() if path.is_file())
+    if archive.suffix == '.zip':
+        with zipfile.ZipFile(archive, 'w', zipfile.ZIP_DEFLATED) as zf:
+            for path in files:
+                zf.write(path, f"{folder}/{path.name}")
+        return
+    with tarfile.open(archive, 'w:gz') as tf:
+        for path in files:
+            info = tf.gettarinfo(str(path), f"{folder}/{path.name}")
+            info.uid = info.gid = 0
+            info.uname = info.gname = ''
+            with open(path, 'rb') as f:
+                tf.addfile(info, f)
+
+def write_checksums(archives: List[Path], out_dir: Path) -> List[Path]:
+    """SHA256SUMS for the archives (sha256sum format), and its signature when a signing key is set"""
+    sums = out_dir / CHECKSUMS_FILE
+    sums.write_text(''.join(f"{file_sha256(archive)}  {archive.name}\n" for archive in sorted(archives)))
+    written = [sums]
+    signature = sign_digest(sums, file_sha256(sums))
+    if signature is not None:
+        written.append(signature)
+    return written
+
+def release_services(names: List[str], targets: List[str], version: str, out_dir: Path = RELEASE_DIR,
+                     progress: Optional[Callable[[str], None]] = None) -> Tuple[List[Path], List[str]]:
+    """Build and package every service for every target; (archives written, failures)"""
+    if shutil.which('go') is None:
+        raise ReleaseError("Go is not installed or not on PATH")
+    parsed = [parse_target(target) for target in targets]
+    flags = ldflags(version, source_commit(), datetime.now(timezone.utc).strftime('%Y-%m-%dT%H:%M:%SZ'))
+    out_dir.mkdir(parents=True, exist_ok=True)
+    license_file = GO_SERVICES_DIR.parent / 'LICENSE'
+
+    archives, failures = [], []
+    for name in names:
+        for goos, goarch in parsed:
+            if progress:
+                progress(f"{name} {goos}/{goarch}")
+            with tempfile.TemporaryDirectory(prefix='flashflow-release-') as tmp:
+                staging = Path(tmp)
+                binary = staging / binary_name(name, goos)
+                try:
+                    build_target(name, goos, goarch, binary, flags)
+                    record_checksum(binary)
+                except (ReleaseError, ServiceIntegrityError) as e:
+                    failures.append(str(e))
+                    continue
+                if license_file.exists():
+                    shutil.copy2(license_file, staging / license_file.name)
+                archive = out_dir / archive_name(name, version, goos, goarch)
+                package(staging, archive)
+                archives.append(archive)
+    return archives, failures
@@ -0,0 +1,164 @@
+"""
+FlashFlow Go services - Locate, checksum and verify go-services/* binaries
+
+A service is looked for in the project's .flashflow/ports/ (binaries built or
+installed for that project), then in go-services/<name>/ of this checkout, then
+on PATH as flashflow-<name> or <name>, so an installed CLI finds services
+without a source tree next to it.
+
+Every built service binary gets a '<binary>.sha256' record (sha256sum format)
+and, when FLASHFLOW_SIGNING_KEY points at an Ed25519 private key (PEM), a
+'<binary>.sig' signature. Before the CLI runs a binary it checks the record,
+refuses binaries older than their Go sources, and requires a valid signature
+when FLASHFLOW_VERIFY_KEY points at the matching public key. A binary that
+fails verification is treated as unavailable, so commands fall back to the
+Python implementation instead of running it.
+"""
+
+import hashlib
+import os
+import shutil
+import subprocess
+from pathlib import Path
+from typing import List, Optional
+
+GO_SERVICES_DIR = Path(__file__).parent.parent.parent / "go-services"
+PORTS_DIR = Path(".flashflow") / "ports"
+CHECKSUM_SUFFIX = ".sha256"
+SIGNATURE_SUFFIX = ".sig"
+
+class ServiceIntegrityError(Exception):
+    """Raised when a service binary is missing, stale, modified or unsigned"""
+    pass
+
+def service_binary(service_name: str) -> Path:
+    return GO_SERVICES_DIR / service_name / f"{service_name}.exe"
+
+def service_candidates(service_name: str, project_root: Optional[Path] = None) -> List[Path]:
+    """Where a service binary may be, in the order they are tried"""
+    candidates = []
+    if project_root is not None:
+        ports = Path(project_root) / PORTS_DIR
+        candidates += [ports / f"{service_name}.exe", ports / service_name,
+                       ports / service_name / f"{service_name}.exe", ports / service_name / service_name]
+    candidates.append(service_binary(service_name))
+    for command in (f"flashflow-{service_name}", service_name):
+        found = shutil.which(command)
+        if found:
+            candidates.append(Path(found))
+    return candidates
+
+def find_service_binary(service_name: str, project_root: Optional[Path] = None) -> Optional[Path]:
+    """The first service binary that exists, verified or not"""
+    return next((path for path in service_candidates(service_name, project_root) if path.is_file()), None)
+
+def file_sha256(path: Path) -> str:
+    digest = hashlib.sha256()
+    with open(path, 'rb') as f:
+        for chunk in iter(lambda: f.read(1024 * 1024), b''):
+            digest.update(chunk)
+    return digest.hexdigest()
+
+def _checksum_path(binary: Path) -> Path:
+    return binary.with_name(binary.name + CHECKSUM_SUFFIX)
+
+def _signature_path(binary: Path) -> Path:
+    return binary.with_name(binary.name + SIGNATURE_SUFFIX)
+
+def _load_key(env_name: str, private: bool):
+    key_path = os.environ.get(env_name)
+    if not key_path:
+        return None
+    try:
+        from cryptography.hazmat.primitives import serialization
+    except ImportError:
+        raise ServiceIntegrityError(f"{env_name} is set but signing needs the cryptography package: pip install cryptography")
+    try:
+        data = Path(key_path).read_bytes()
+        if private:
+            return serialization.load_pem_private_key(data, password=None)
+        return serialization.load_pem_public_key(data)
+    except (OSError, ValueError) as e:
+        raise ServiceIntegrityError(f"Cannot load {env_name} ({key_path}): {e}")
+
+def record_checksum(binary: Path) -> List[Path]:
+    """Write the checksum (and signature, if a signing key is configured) next to a binary"""
+    if not binary.exists():
+        raise ServiceIntegrityError(f"{binary} does not exist")
+    checksum = file_sha256(binary)
+    written = [_checksum_path(binary)]
+    written[0].write_text(f"{checksum}  {binary.name}\n")
+    signature = sign_digest(binary, checksum)
+    if signature is not None:
+        written.append(signature)
+    return written
+
+def sign_digest(path: Path, checksum: str) -> Optional[Path]:
+    """Write '<path>.sig', a signature of a SHA-256 hex digest, when FLASHFLOW_SIGNING_KEY is set"""
+    signing_key = _load_key('FLASHFLOW_SIGNING_KEY', private=True)
+    if signing_key is None:
+        return None
+    _signature_path(path).write_bytes(signing_key.sign(bytes.fromhex(checksum)))
+    return _signature_path(path)
+
+def verify_binary(binary: Path):
+    """Raise ServiceIntegrityError unless the binary matches its recorded checksum (and signature)"""
+    checksum_path = _checksum_path(binary)
+    if not binary.exists():
+        raise ServiceIntegrityError(f"{binary} does not exist")
+    if not checksum_path.exists():
+        raise ServiceIntegrityError(f"{binary.name} has no {checksum_path.name}; rebuild it with 'flashflow services build' or record it with 'flashflow services checksum'")
+
+    # Sources edited after the build mean the binary no longer matches the code; installed binaries have none beside them
+    built_at = checksum_path.stat().st_mtime
+    has_sources = (binary.parent / "go.mod").exists() or any(binary.parent.glob("*.go"))
+    newer = [source for source in binary.parent.rglob("*.go") if source.stat().st_mtime > built_at] if has_sources else []
+    if newer:
+        raise ServiceIntegrityError(f"{binary.name} is older than {newer[0].relative_to(binary.parent)}; rebuild it with 'flashflow services build'")
+
+    recorded = checksum_path.read_text().split()
+    expected = recorded[0] if recorded else ''
+    actual = file_sha256(binary)
+    if actual != expected:
+        raise ServiceIntegrityError(f"{binary.name} does not match {checksum_path.name} (expected {expected[:12]}…, got {actual[:12]}…)")
+
+    verify_key = _load_key('FLASHFLOW_VERIFY_KEY', private=False)
+    if verify_key is not None:
+        signature_path = _signature_path(binary)
+        if not signature_path.exists():
+            raise ServiceIntegrityError(f"{binary.name} is not signed and FLASHFLOW_VERIFY_KEY requires a signature")
+        try:
+            from cryptography.exceptions import InvalidSignature
+            verify_key.verify(signature_path.read_bytes(), bytes.fromhex(actual))
+        except InvalidSignature:
+            raise ServiceIntegrityError(f"{binary.name} has an invalid signature")
+
+def verified_service_binary(service_name: str, warn=None, project_root: Optional[Path] = None) -> Optional[Path]:
+    """Path of a service binary that passed verification, or None (after warning) if it did not"""
+    binary = find_service_binary(service_name, project_root)
+    if binary is None:
+        return None
+    try:
+        verify_binary(binary)
+    except ServiceIntegrityError as e:
+        if warn:
+            warn(f"⚠️  Not running {service_name}: {e}")
+        return None
+    return binary
+
+def build_go_service(service_name: str) -> Path:
+    """go build a service and record its checksum"""
+    service_dir = GO_SERVICES_DIR / service_name
+    if not (service_dir / "go.mod").exists() and not list(service_dir.glob("*.go")):
+        raise ServiceIntegrityError(f"No Go sources in {service_dir}")
+    binary = service_binary(service_name)
+    result = subprocess.run(['go', 'build', '-o', binary.name, '.'], cwd=service_dir, capture_output=True, text=True)
+    if result.returncode != 0:
+        raise ServiceIntegrityError(f"go build failed for {service_name}:\n{result.stderr.strip()}")
+    record_checksum(binary)
+    return binary
+
+def list_services() -> List[str]:
+    if not GO_SERVICES_DIR.exists():
+        return []
+    return sorted(path.name for path in GO_SERVICES_DIR.iterdir() if path.is_dir())
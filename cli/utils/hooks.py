@@ -0,0 +1,111 @@
+"""
+FlashFlow lifecycle hooks - Commands from flashflow.json run around build and serve
+
+    "hooks": {
+        "pre_build": ["python scripts/codegen.py", "flashflow run sync-assets"],
+        "post_build": [{"command": "./scripts/notify.sh", "env": {"CHANNEL": "builds"}}],
+        "pre_serve": ["docker compose up -d db"],
+        "post_init": ["npm install"]
+    }
+
+Each entry takes the same forms as a script in "scripts" (a command, a list
+of commands or {command, env, cwd}) and gets the same environment as
+'flashflow run', plus FLASHFLOW_HOOK and the event's details (FLASHFLOW_TARGET
+and FLASHFLOW_ENV for builds, FLASHFLOW_HOST and FLASHFLOW_PORT for serve).
+post_init runs once, when 'flashflow new' creates a project from a template
+that declares it, with FLASHFLOW_TEMPLATE set to where the template came from.
+Hooks run in order and the first non-zero exit stops the build or serve.
+Their output is captured and only shown when they fail, unless -v is given.
+"""
+
+import subprocess
+from typing import Any, Dict, List, Optional
+
+import click
+
+from core.framework import FlashFlowProject
+from core.tracing import get_tracer
+from cli.commands.run import ScriptError, normalize_script, script_env, shell_command
+from cli.utils.output import Output, get_output
+
+HOOK_EVENTS = ['pre_build', 'post_build', 'pre_serve', 'post_init']
+# Lines of a failed hook's output shown on the terminal; the -q report has all of it
+FAILURE_TAIL_LINES = 40
+
+class HookError(Exception):
+    """Raised when a hook is malformed or exits non-zero"""
+
+    def __init__(self, event: str, message: str, command: Optional[str] = None,
+                 exit_code: Optional[int] = None, output: str = ''):
+        super().__init__(message)
+        self.event = event
+        self.command = command
+        self.exit_code = exit_code
+        self.output = output
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'event': self.event, 'command': self.command, 'exit_code': self.exit_code,
+                'error': str(self), 'output': self.output.splitlines()}
+
+def hook_entries(project: FlashFlowProject, event: str) -> List[Any]:
+    """The configured entries for one event, in order"""
+    hooks = project.config.hooks or {}
+    if not isinstance(hooks, dict):
+        raise HookError(event, "\"hooks\" in flashflow.json must be an object")
+    unknown = sorted(set(hooks) - set(HOOK_EVENTS))
+    if unknown:
+        raise HookError(event, f"Unknown hook(s) {', '.join(unknown)} in flashflow.json. Supported: {', '.join(HOOK_EVENTS)}")
+    entries = hooks.get(event) or []
+    return entries if isinstance(entries, list) else [entries]
+
+def run_hooks(project: FlashFlowProject, event: str, extra_env: Optional[Dict[str, str]] = None,
+              output: Optional[Output] = None) -> int:
+    """Run every hook for an event; returns how many commands ran, raises HookError on the first failure"""
+    output = output or get_output()
+    entries = hook_entries(project, event)
+    ran = 0
+
+    with get_tracer().span(f"hook {event}", attributes={'flashflow.hook': event}) as span:
+        for position, entry in enumerate(entries, 1):
+            try:
+                hook = normalize_script(f"{event}[{position}]", entry)
+            except ScriptError as e:
+                raise HookError(event, str(e))
+
+            env = script_env(project, event)
+            env['FLASHFLOW_HOOK'] = event
+            env.update(extra_env or {})
+            env.update(hook['env'])
+            cwd = project.root_path / hook['cwd'] if hook['cwd'] else project.root_path
+
+            for command in hook['commands']:
+                output.echo(f"🪝 {event}: {command}")
+                result = _run(command, cwd, get_tracer().inject_env(env), output)
+                ran += 1
+                if result.returncode != 0:
+                    span.set_error(f"exit code {result.returncode}")
+                    raise HookError(event, f"{event} hook failed with exit code {result.returncode}: {command}",
+                                    command, result.returncode, result.stdout or '')
+    return ran
+
+def report_hook_failure(error: HookError):
+    """Print a failed hook and the end of its output"""
+    click.echo(f"❌ {str(error)}")
+    lines = error.output.rstrip().splitlines()
+    if not lines:
+        return
+    if len(lines) > FAILURE_TAIL_LINES:
+        click.echo(f"   ... {len(lines) - FAILURE_TAIL_LINES} earlier lines not shown")
+    for line in lines[-FAILURE_TAIL_LINES:]:
+        click.echo(f"   │ {line}")
+
+def _run(command: str, cwd, env: Dict[str, str], output: Output) -> subprocess.CompletedProcess:
+    args = shell_command(command)
+    try:
+        if output.verbose:
+            return output.run(args, cwd=cwd, env=env)
+        # One stream so the failure report shows stdout and stderr in the order they were written
+        return subprocess.run(args, cwd=cwd, env=env, stdout=subprocess.PIPE, stderr=subprocess.STDOUT,
+                              text=True, errors='replace')
+    except OSError as e:
+        return subprocess.CompletedProcess(args, 127, stdout=str(e))
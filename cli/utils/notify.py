@@ -0,0 +1,159 @@
+"""
+FlashFlow build notifications - Desktop notifications and editor jumps for watched builds
+
+'flashflow build --watch --notify' shows an OS notification when a build
+fails, and when the next one succeeds again:
+
+    macOS    osascript (Notification Center)
+    Linux    notify-send (libnotify)
+    Windows  a PowerShell toast
+
+'--editor vscode' (or idea, pycharm, webstorm, ...) opens the file of the
+first error at its line through the editor's URL scheme. The same error is
+not opened twice in a row, so saving a file that still fails does not pull
+focus again. Both are best effort: without a notifier or an editor the
+build carries on and says why once.
+"""
+
+import os
+import shutil
+import subprocess
+import sys
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+from urllib.parse import quote
+
+import click
+
+APP_NAME = 'FlashFlow'
+# Editors opened with <scheme>://file/<path>:<line>:<column>
+VSCODE_SCHEMES = {'vscode': 'vscode', 'code': 'vscode', 'vscode-insiders': 'vscode-insiders', 'vscodium': 'vscodium',
+                  'cursor': 'cursor', 'windsurf': 'windsurf'}
+# JetBrains IDEs opened with <scheme>://open?file=<path>&line=<line>
+JETBRAINS_SCHEMES = {'idea': 'idea', 'intellij': 'idea', 'pycharm': 'pycharm', 'webstorm': 'webstorm',
+                     'phpstorm': 'phpstorm', 'goland': 'goland', 'rider': 'rider', 'clion': 'clion',
+                     'rubymine': 'rubymine', 'fleet': 'fleet'}
+EDITORS = sorted(set(VSCODE_SCHEMES) | set(JETBRAINS_SCHEMES))
+
+# Text shown in a toast; title and message come from the environment so nothing needs escaping
+WINDOWS_TOAST = """
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName('text')
+$text.Item(0).AppendChild($template.CreateTextNode($env:FLASHFLOW_TITLE)) | Out-Null
+$text.Item(1).AppendChild($template.CreateTextNode($env:FLASHFLOW_MESSAGE)) | Out-Null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($template)
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('FlashFlow').Show($toast)
+"""
+
+def notifier_command(title: str, message: str, failed: bool) -> Optional[Tuple[List[str], Dict[str, str]]]:
+    """The command and extra environment that show a notification here, or None without a notifier"""
+    if sys.platform == 'darwin' and shutil.which('osascript'):
+        script = 'display notification (system attribute "FLASHFLOW_MESSAGE") with title (system attribute "FLASHFLOW_TITLE")'
+        if failed:
+            script += ' sound name "Basso"'
+        return ['osascript', '-e', script], {'FLASHFLOW_TITLE': title, 'FLASHFLOW_MESSAGE': message}
+    if os.name == 'nt':
+        powershell = shutil.which('powershell') or shutil.which('pwsh')
+        if powershell:
+            return ([powershell, '-NoProfile', '-NonInteractive', '-Command', WINDOWS_TOAST],
+                    {'FLASHFLOW_TITLE': title, 'FLASHFLOW_MESSAGE': message})
+        return None
+    if shutil.which('notify-send'):
+        return (['notify-send', f'--app-name={APP_NAME}', '--urgency', 'critical' if failed else 'normal',
+                 '--icon', 'dialog-error' if failed else 'dialog-information', title, message], {})
+    return None
+
+def notify(title: str, message: str, failed: bool = False) -> bool:
+    """Show a desktop notification without waiting for it; False when there is no way to"""
+    command = notifier_command(title, message, failed)
+    if command is None:
+        return False
+    args, env = command
+    try:
+        subprocess.Popen(args, env=dict(os.environ, **env), stdin=subprocess.DEVNULL,
+                         stdout=subprocess.DEVNULL, stderr=subprocess.DEVNULL)
+    except OSError:
+        return False
+    return True
+
+def editor_url(editor: str, path: Path, line: Optional[int] = None, column: Optional[int] = None) -> str:
+    """The URL that opens path at line in an editor"""
+    editor = editor.lower()
+    path = Path(path).resolve()
+    if editor in VSCODE_SCHEMES:
+        location = path.as_posix() + (f":{line}" if line else '') + (f":{column}" if line and column else '')
+        # Windows paths become /C:/...; the scheme wants a leading slash
+        if not location.startswith('/'):
+            location = '/' + location
+        return f"{VSCODE_SCHEMES[editor]}://file{quote(location, safe='/:')}"
+    if editor in JETBRAINS_SCHEMES:
+        url = f"{JETBRAINS_SCHEMES[editor]}://open?file={quote(str(path), safe='')}"
+        if line:
+            url += f"&line={line}"
+        if line and column:
+            url += f"&column={column}"
+        return url
+    raise click.BadParameter(f"Unknown editor '{editor}'; use one of {', '.join(EDITORS)}")
+
+def open_in_editor(editor: str, path: Path, line: Optional[int] = None, column: Optional[int] = None) -> bool:
+    """Hand the editor URL to the OS; False when nothing could open it"""
+    return click.launch(editor_url(editor, path, line, column)) == 0
+
+class BuildNotifier:
+    """Remembers the last outcome of a watched build so only changes are announced"""
+
+    def __init__(self, project, notify_desktop: bool = False, editor: Optional[str] = None):
+        self.project = project
+        self.notify_desktop = notify_desktop
+        self.editor = editor
+        self.failing = False
+        self._opened: Optional[Tuple[str, Optional[int]]] = None
+        self._warned = set()
+
+    def finished(self, target: str, seconds: float):
+        """A build succeeded; announced when it fixes a failing one"""
+        was_failing, self.failing = self.failing, False
+        self._opened = None
+        if self.notify_desktop and was_failing:
+            self._notify(f"✅ {self.project.config.name}: build fixed", f"{target} built in {seconds:g}s", failed=False)
+
+    def failed(self, target: str, errors: List[Dict[str, Any]]):
+        """A build failed; notify, and open the first error that names a file"""
+        self.failing = True
+        first = errors[0] if errors else {'message': 'Build failed'}
+        where = first.get('file') or ''
+        if where and first.get('line'):
+            where += f":{first['line']}"
+        if self.notify_desktop:
+            more = f" (+{len(errors) - 1} more)" if len(errors) > 1 else ''
+            self._notify(f"❌ {self.project.config.name}: {target} build failed",
+                         (f"{where}: " if where else '') + str(first.get('message', '')) + more, failed=True)
+        located = next((error for error in errors if error.get('file')), None)
+        if self.editor and located:
+            self._open(located)
+
+    def _notify(self, title: str, message: str, failed: bool):
+        if not notify(title, message, failed) and 'notify' not in self._warned:
+            self._warned.add('notify')
+            hint = 'install libnotify (notify-send)' if sys.platform.startswith('linux') else 'no notifier found'
+            click.echo(f"⚠️  Desktop notifications are not available: {hint}")
+
+    def _open(self, error: Dict[str, Any]):
+        path = self.resolve(error['file'])
+        if path is None:
+            return
+        location = (str(path), error.get('line'))
+        if location == self._opened:
+            return
+        self._opened = location
+        if not open_in_editor(self.editor, path, error.get('line'), error.get('column')) and 'editor' not in self._warned:
+            self._warned.add('editor')
+            click.echo(f"⚠️  Could not open {self.editor}; is its URL handler installed?")
+
+    def resolve(self, name: str) -> Optional[Path]:
+        """The file a diagnostic names: relative to the project, or a flow in src/flows"""
+        for candidate in (self.project.root_path / name, self.project.flows_path / name):
+            if candidate.is_file():
+                return candidate
+        return None
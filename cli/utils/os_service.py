@@ -0,0 +1,290 @@
+"""
+FlashFlow OS service - Run the dev server in the background under the OS service manager
+
+    Linux     systemd user unit   ~/.config/systemd/user/<name>.service
+    macOS     launchd agent       ~/Library/LaunchAgents/dev.flashflow.<name>.plist
+    Windows   scheduled task      runs at logon through .flashflow/service/<name>.cmd
+
+Services run as the current user, so no root or administrator rights are
+needed. Output goes to .flashflow/logs/service.log either way. Windows gets a
+logon task rather than a Service Control Manager service, since the SCM only
+runs binaries that implement its control protocol.
+"""
+
+import json
+import os
+import platform
+import re
+import shlex
+import subprocess
+import sys
+from dataclasses import dataclass, asdict
+from pathlib import Path
+from typing import Dict, Any, List, Optional
+from xml.sax.saxutils import escape
+
+from core.framework import FlashFlowProject
+
+CLI_ROOT = Path(__file__).parent.parent.parent
+RECORD_NAME = "service.json"
+LOG_NAME = "service.log"
+
+class OSServiceError(Exception):
+    """Raised when the service manager is unavailable or rejects a command"""
+    pass
+
+@dataclass
+class ServiceSpec:
+    """What to run and where"""
+    name: str
+    project_root: str
+    command: List[str]
+    log_path: str
+    manager: str = ''
+    unit_path: str = ''
+
+    def to_dict(self) -> Dict[str, Any]:
+        return asdict(self)
+
+def default_service_name(project: FlashFlowProject) -> str:
+    slug = re.sub(r'[^a-z0-9]+', '-', project.config.name.lower()).strip('-')
+    return f"flashflow-{slug or 'app'}"
+
+def serve_command(host: str, port: int) -> List[str]:
+    """The dev server command line, using this Python and this CLI checkout"""
+    return [sys.executable, '-m', 'cli.core.main', 'serve', '--all', '--host', host, '--port', str(port)]
+
+class ServiceManager:
+    """One OS service manager; subclasses know its file format and commands"""
+    manager = ''
+
+    def __init__(self, project: FlashFlowProject):
+        self.project = project
+
+    def unit_path(self, name: str) -> Path:
+        raise NotImplementedError
+
+    def render(self, spec: ServiceSpec) -> str:
+        raise NotImplementedError
+
+    def install(self, spec: ServiceSpec):
+        path = Path(spec.unit_path)
+        path.parent.mkdir(parents=True, exist_ok=True)
+        path.write_text(self.render(spec))
+        self.after_install(spec)
+
+    def after_install(self, spec: ServiceSpec):
+        pass
+
+    def uninstall(self, spec: ServiceSpec):
+        path = Path(spec.unit_path)
+        if path.exists():
+            path.unlink()
+
+    def start(self, spec: ServiceSpec):
+        raise NotImplementedError
+
+    def stop(self, spec: ServiceSpec):
+        raise NotImplementedError
+
+    def status(self, spec: ServiceSpec) -> str:
+        raise NotImplementedError
+
+    def environment(self) -> Dict[str, str]:
+        # Services start with an almost empty environment; keep what the CLI needs
+        env = {'PYTHONPATH': str(CLI_ROOT), 'PYTHONUNBUFFERED': '1'}
+        for name in ('PATH', 'HOME', 'LANG'):
+            if os.environ.get(name):
+                env[name] = os.environ[name]
+        env.update({name: value for name, value in os.environ.items() if name.startswith(('FLASHFLOW_', 'OTEL_'))})
+        return env
+
+def _run(args: List[str], check: bool = True) -> subprocess.CompletedProcess:
+    try:
+        result = subprocess.run(args, capture_output=True, text=True)
+    except FileNotFoundError:
+        raise OSServiceError(f"'{args[0]}' was not found; is this system's service manager available?")
+    if check and result.returncode != 0:
+        raise OSServiceError(f"{' '.join(args)} failed: {(result.stderr or result.stdout).strip()}")
+    return result
+
+class SystemdManager(ServiceManager):
+    manager = 'systemd'
+
+    def unit_path(self, name: str) -> Path:
+        config_home = Path(os.environ.get('XDG_CONFIG_HOME') or Path.home() / '.config')
+        return config_home / 'systemd' / 'user' / f"{name}.service"
+
+    def render(self, spec: ServiceSpec) -> str:
+        # '%' starts a systemd specifier, so it is doubled everywhere
+        environment = '\n'.join(f'Environment="{name}={_systemd_escape(value)}"' for name, value in sorted(self.environment().items()))
+        return f"""[Unit]
+Description=FlashFlow dev server ({spec.name})
+After=network.target
+
+[Service]
+Type=simple
+WorkingDirectory={spec.project_root}
+ExecStart={_systemd_escape(' '.join(shlex.quote(part) for part in spec.command))}
+{environment}
+Restart=on-failure
+RestartSec=5
+StandardOutput=append:{spec.log_path}
+StandardError=append:{spec.log_path}
+
+[Install]
+WantedBy=default.target
+"""
+
+    def after_install(self, spec: ServiceSpec):
+        _run(['systemctl', '--user', 'daemon-reload'])
+        _run(['systemctl', '--user', 'enable', spec.name])
+
+    def uninstall(self, spec: ServiceSpec):
+        _run(['systemctl', '--user', 'disable', '--now', spec.name], check=False)
+        super().uninstall(spec)
+        _run(['systemctl', '--user', 'daemon-reload'], check=False)
+
+    def start(self, spec: ServiceSpec):
+        _run(['systemctl', '--user', 'start', spec.name])
+
+    def stop(self, spec: ServiceSpec):
+        _run(['systemctl', '--user', 'stop', spec.name])
+
+    def status(self, spec: ServiceSpec) -> str:
+        return _run(['systemctl', '--user', 'is-active', spec.name], check=False).stdout.strip() or 'unknown'
+
+def _systemd_escape(value: str) -> str:
+    return value.replace('%', '%%').replace('"', '\\"')
+
+class LaunchdManager(ServiceManager):
+    manager = 'launchd'
+
+    def label(self, name: str) -> str:
+        return f"dev.flashflow.{name}"
+
+    def unit_path(self, name: str) -> Path:
+        return Path.home() / 'Library' / 'LaunchAgents' / f"{self.label(name)}.plist"
+
+    def render(self, spec: ServiceSpec) -> str:
+        arguments = '\n'.join(f"        <string>{escape(part)}</string>" for part in spec.command)
+        environment = '\n'.join(f"        <key>{escape(name)}</key>\n        <string>{escape(value)}</string>"
+                                for name, value in sorted(self.environment().items()))
+        return f"""<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+    <key>Label</key>
+    <string>{escape(self.label(spec.name))}</string>
+    <key>ProgramArguments</key>
+    <array>
+{arguments}
+    </array>
+    <key>WorkingDirectory</key>
+    <string>{escape(spec.project_root)}</string>
+    <key>EnvironmentVariables</key>
+    <dict>
+{environment}
+    </dict>
+    <key>RunAtLoad</key>
+    <true/>
+    <key>KeepAlive</key>
+    <dict>
+        <key>SuccessfulExit</key>
+        <false/>
+    </dict>
+    <key>StandardOutPath</key>
+    <string>{escape(spec.log_path)}</string>
+    <key>StandardErrorPath</key>
+    <string>{escape(spec.log_path)}</string>
+</dict>
+</plist>
+"""
+
+    def after_install(self, spec: ServiceSpec):
+        _run(['launchctl', 'load', '-w', spec.unit_path])
+
+    def uninstall(self, spec: ServiceSpec):
+        _run(['launchctl', 'unload', '-w', spec.unit_path], check=False)
+        super().uninstall(spec)
+
+    def start(self, spec: ServiceSpec):
+        _run(['launchctl', 'start', self.label(spec.name)])
+
+    def stop(self, spec: ServiceSpec):
+        _run(['launchctl', 'stop', self.label(spec.name)])
+
+    def status(self, spec: ServiceSpec) -> str:
+        result = _run(['launchctl', 'list', self.label(spec.name)], check=False)
+        if result.returncode != 0:
+            return 'not loaded'
+        return 'active' if re.search(r'"PID"\s*=\s*\d+', result.stdout) else 'inactive'
+
+class ScheduledTaskManager(ServiceManager):
+    manager = 'schtasks'
+
+    def unit_path(self, name: str) -> Path:
+        return self.project.state.path('service', f"{name}.cmd")
+
+    def render(self, spec: ServiceSpec) -> str:
+        environment = '\r\n'.join(f'set "{name}={value}"' for name, value in sorted(self.environment().items()))
+        return (f"@echo off\r\n{environment}\r\ncd /d \"{spec.project_root}\"\r\n"
+                f"{subprocess.list2cmdline(spec.command)} >> \"{spec.log_path}\" 2>&1\r\n")
+
+    def after_install(self, spec: ServiceSpec):
+        _run(['schtasks', '/Create', '/F', '/TN', spec.name, '/SC', 'ONLOGON', '/RL', 'LIMITED',
+              '/TR', f'cmd /c "{spec.unit_path}"'])
+
+    def uninstall(self, spec: ServiceSpec):
+        _run(['schtasks', '/End', '/TN', spec.name], check=False)
+        _run(['schtasks', '/Delete', '/F', '/TN', spec.name], check=False)
+        super().uninstall(spec)
+
+    def start(self, spec: ServiceSpec):
+        _run(['schtasks', '/Run', '/TN', spec.name])
+
+    def stop(self, spec: ServiceSpec):
+        _run(['schtasks', '/End', '/TN', spec.name])
+
+    def status(self, spec: ServiceSpec) -> str:
+        result = _run(['schtasks', '/Query', '/TN', spec.name, '/FO', 'LIST'], check=False)
+        if result.returncode != 0:
+            return 'not installed'
+        match = re.search(r'^Status:\s*(.+)$', result.stdout, re.MULTILINE)
+        return match.group(1).strip().lower() if match else 'unknown'
+
+MANAGERS = {'Linux': SystemdManager, 'Darwin': LaunchdManager, 'Windows': ScheduledTaskManager}
+
+def service_manager(project: FlashFlowProject) -> ServiceManager:
+    system = platform.system()
+    if system not in MANAGERS:
+        raise OSServiceError(f"Background services are not supported on {system}")
+    return MANAGERS[system](project)
+
+def build_spec(project: FlashFlowProject, manager: ServiceManager, name: Optional[str], host: str, port: int) -> ServiceSpec:
+    name = name or default_service_name(project)
+    return ServiceSpec(
+        name=name,
+        project_root=str(project.root_path.resolve()),
+        command=serve_command(host, port),
+        log_path=str(project.state.logs_dir.resolve() / LOG_NAME),
+        manager=manager.manager,
+        unit_path=str(manager.unit_path(name))
+    )
+
+def read_installed(project: FlashFlowProject) -> Optional[ServiceSpec]:
+    record_path = project.state.dir / RECORD_NAME
+    if not record_path.exists():
+        return None
+    try:
+        return ServiceSpec(**json.loads(record_path.read_text()))
+    except (OSError, ValueError, TypeError):
+        return None
+
+def write_installed(project: FlashFlowProject, spec: ServiceSpec):
+    project.state.path(RECORD_NAME).write_text(json.dumps(spec.to_dict(), indent=2))
+
+def clear_installed(project: FlashFlowProject):
+    record_path = project.state.dir / RECORD_NAME
+    if record_path.exists():
+        record_path.unlink()
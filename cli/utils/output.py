@@ -0,0 +1,194 @@
+"""
+FlashFlow CLI output - Verbosity levels, progress display and JSON results
+
+    flashflow -q build      one JSON document on stdout, nothing else
+    flashflow build         step progress (a spinner on a terminal)
+    flashflow -v build      generator and child-process output as it happens
+    flashflow -vv build     also the commands being run and their environment
+
+Spinners are only drawn when stderr is a terminal and CI is not set, so CI
+logs get one plain line per step instead of carriage-return noise.
+"""
+
+import contextlib
+import io
+import itertools
+import json
+import os
+import shlex
+import subprocess
+import sys
+import threading
+import time
+from typing import Any, Dict, List, Optional
+
+import click
+
+QUIET = -1
+NORMAL = 0
+VERBOSE = 1
+DEBUG = 2
+
+SPINNER_FRAMES = "⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏"
+SPINNER_INTERVAL = 0.08
+# Lines from a collapsed step that are still worth showing
+NOTABLE_MARKERS = ('❌', '⚠️')
+
+def is_interactive(stream=None) -> bool:
+    """True when progress can be redrawn in place on the given stream"""
+    stream = stream or sys.stderr
+    if os.environ.get('CI', '').lower() not in ('', '0', 'false'):
+        return False
+    if os.environ.get('TERM') == 'dumb':
+        return False
+    try:
+        return stream.isatty()
+    except (AttributeError, ValueError):
+        return False
+
+class Output:
+    """Where a command's human and machine output goes for one invocation"""
+
+    def __init__(self, level: int = NORMAL, interactive: Optional[bool] = None):
+        self.level = level
+        self.interactive = is_interactive() if interactive is None else interactive
+
+    @property
+    def quiet(self) -> bool:
+        return self.level <= QUIET
+
+    @property
+    def verbose(self) -> bool:
+        return self.level >= VERBOSE
+
+    def echo(self, message: str = '', level: int = NORMAL, **kwargs):
+        """Human output shown at the given verbosity and above"""
+        if self.level >= level:
+            click.echo(message, **kwargs)
+
+    def debug(self, message: str):
+        self.echo(click.style(message, dim=True), level=DEBUG, err=True)
+
+    def emit(self, data: Dict[str, Any]):
+        """The machine-readable result; printed only with -q"""
+        if self.quiet:
+            click.echo(json.dumps(data, default=str))
+
+    @contextlib.contextmanager
+    def captured(self):
+        """Keep stray human output off stdout while -q is in effect"""
+        if not self.quiet:
+            yield None
+            return
+        buffer = io.StringIO()
+        with contextlib.redirect_stdout(buffer):
+            yield buffer
+
+    def steps(self, total: int) -> 'StepProgress':
+        return StepProgress(self, total)
+
+    def run(self, args: List[str], **kwargs) -> subprocess.CompletedProcess:
+        """Run a child process, streaming its output with -v and capturing it otherwise"""
+        env = kwargs.get('env')
+        self.debug(f"$ {' '.join(shlex.quote(str(arg)) for arg in args)}")
+        if env is not None and self.level >= DEBUG:
+            for name in sorted(env):
+                if name.startswith('FLASHFLOW_') and os.environ.get(name) != env[name]:
+                    self.debug(f"  {name}={env[name]}")
+
+        if self.verbose:
+            # Inherit stdio so the child's own progress output works
+            return subprocess.run(args, **kwargs)
+        return subprocess.run(args, capture_output=True, text=True, **kwargs)
+
+class StepProgress:
+    """Numbered build steps with timing, collapsed to one line each unless -v"""
+
+    def __init__(self, output: Output, total: int):
+        self.output = output
+        self.total = total
+        self.results: List[Dict[str, Any]] = []
+
+    @property
+    def failed(self) -> bool:
+        return any(result['status'] == 'failed' for result in self.results)
+
+    @contextlib.contextmanager
+    def step(self, name: str, label: str):
+        output = self.output
+        prefix = f"[{len(self.results) + 1}/{self.total}]"
+        result = {'step': name, 'status': 'ok', 'seconds': 0.0}
+        spinner = None
+        buffer = io.StringIO()
+
+        if output.verbose:
+            output.echo(f"{prefix} {label}")
+            capture = contextlib.nullcontext()
+        else:
+            capture = contextlib.redirect_stdout(buffer)
+            if output.interactive and not output.quiet:
+                spinner = _Spinner(f"{prefix} {label}")
+                spinner.start()
+
+        started = time.monotonic()
+        try:
+            with capture:
+                yield result
+        except Exception as e:
+            result['status'] = 'failed'
+            result['error'] = str(e)
+            raise
+        finally:
+            result['seconds'] = round(time.monotonic() - started, 2)
+            if spinner:
+                spinner.stop()
+            self.results.append(result)
+
+            notable = [line.strip() for line in buffer.getvalue().splitlines() if line.strip().startswith(NOTABLE_MARKERS)]
+            if notable:
+                result['messages'] = notable
+            # Generators that report their own errors instead of raising still fail the step
+            if any(line.startswith('❌') for line in notable):
+                result['status'] = 'failed'
+            if not output.verbose:
+                mark = '❌' if result['status'] == 'failed' else '✅'
+                cached = f", {result['cache']} cache" if result.get('cache') in ('local', 'remote') else ''
+                if result.get('unchanged'):
+                    cached = ', unchanged'
+                output.echo(f"{mark} {prefix} {label} ({result['seconds']:.1f}s{cached})")
+                for line in notable:
+                    output.echo(f"   {line}")
+                if 'error' in result:
+                    output.echo(f"   {result['error']}")
+
+class _Spinner:
+    """Redraws one status line on stderr until stopped"""
+
+    def __init__(self, text: str):
+        self.text = text
+        self._stop = threading.Event()
+        self._thread = threading.Thread(target=self._spin, daemon=True)
+
+    def start(self):
+        self._thread.start()
+
+    def stop(self):
+        self._stop.set()
+        self._thread.join()
+        click.echo("\r\033[K", nl=False, err=True)
+
+    def _spin(self):
+        started = time.monotonic()
+        for frame in itertools.cycle(SPINNER_FRAMES):
+            click.echo(f"\r\033[K{frame} {self.text} {time.monotonic() - started:.1f}s", nl=False, err=True)
+            if self._stop.wait(SPINNER_INTERVAL):
+                return
+
+def get_output() -> Output:
+    """The Output chosen by the global -q/-v flags, or the default outside a command"""
+    ctx = click.get_current_context(silent=True)
+    if ctx is not None:
+        root = ctx.find_root()
+        if isinstance(root.obj, dict) and 'output' in root.obj:
+            return root.obj['output']
+    return Output()
@@ -0,0 +1,164 @@
+"""
+FlashFlow CLI plugins - Third-party commands shipped as flashflow-<name> executables
+
+    flashflow lint-flows --fix      runs      flashflow-lint-flows --fix
+
+Plugins are looked up in the project's .flashflow/plugins directory first and
+then on PATH; a built-in command always wins over a plugin with the same name.
+Arguments after the plugin name are passed through untouched, along with:
+
+    FLASHFLOW_PROJECT_ROOT      project directory (unset outside a project)
+    FLASHFLOW_STATE_DIR         the project's .flashflow directory
+    FLASHFLOW_HOST / _PORT      the dev server, from the running 'flashflow serve'
+    FLASHFLOW_URL               or its defaults when it is not running
+    FLASHFLOW_ENGINE_PORT       the Flet engine
+    FLASHFLOW_CLI               command line for calling back into this CLI
+    FLASHFLOW_VERBOSITY         -1 with -q, 0 by default, 1 or 2 with -v / -vv
+    FLASHFLOW_PLUGIN            the plugin's name
+"""
+
+import os
+import shlex
+import subprocess
+import sys
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Dict, Any, List, Optional, Tuple
+
+import click
+
+PLUGIN_PREFIX = "flashflow-"
+PLUGIN_DIR = "plugins"
+DEFAULT_DEV_PORT = 8000
+DEFAULT_ENGINE_PORT = 8012
+CLI_ROOT = Path(__file__).parent.parent.parent
+
+@dataclass
+class Plugin:
+    """An executable providing one CLI command"""
+    name: str
+    path: Path
+    source: str
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'name': self.name, 'path': str(self.path), 'source': self.source}
+
+def find_project_root(start: Path) -> Optional[Path]:
+    """Nearest directory at or above 'start' holding a flashflow.json"""
+    for parent in [start] + list(start.parents):
+        if (parent / "flashflow.json").exists():
+            return parent
+    return None
+
+def plugin_dirs(project_root: Optional[Path]) -> List[Tuple[Path, str]]:
+    dirs = []
+    if project_root:
+        dirs.append((project_root / ".flashflow" / PLUGIN_DIR, 'project'))
+    for entry in os.environ.get('PATH', '').split(os.pathsep):
+        if entry:
+            dirs.append((Path(entry), 'path'))
+    return dirs
+
+def plugin_name(file_name: str) -> Optional[str]:
+    """Command name for an executable's file name, or None if it is not a plugin"""
+    if not file_name.startswith(PLUGIN_PREFIX):
+        return None
+    name = file_name[len(PLUGIN_PREFIX):]
+    if os.name == 'nt':
+        extensions = [ext.lower() for ext in os.environ.get('PATHEXT', '.COM;.EXE;.BAT;.CMD').split(';') if ext]
+        stem, ext = os.path.splitext(name)
+        if ext.lower() not in extensions:
+            return None
+        name = stem
+    return name or None
+
+def _is_executable(path: Path) -> bool:
+    return path.is_file() and (os.name == 'nt' or os.access(path, os.X_OK))
+
+def discover_plugins(project_root: Optional[Path]) -> Dict[str, Plugin]:
+    """Plugins by command name; the first directory that provides a name wins"""
+    plugins: Dict[str, Plugin] = {}
+    for directory, source in plugin_dirs(project_root):
+        try:
+            entries = sorted(directory.iterdir())
+        except OSError:
+            continue
+        for entry in entries:
+            name = plugin_name(entry.name)
+            if name and name not in plugins and _is_executable(entry):
+                plugins[name] = Plugin(name, entry, source)
+    return plugins
+
+def plugin_env(plugin: Plugin, project_root: Optional[Path], verbosity: int = 0) -> Dict[str, str]:
+    """Environment for a plugin process"""
+    env = os.environ.copy()
+    env['FLASHFLOW_PLUGIN'] = plugin.name
+    env['FLASHFLOW_VERBOSITY'] = str(verbosity)
+    env['FLASHFLOW_CLI'] = shlex.join([sys.executable, '-m', 'cli.core.main'])
+    # The CLI checkout must be importable for FLASHFLOW_CLI to work from any directory
+    env['PYTHONPATH'] = os.pathsep.join(filter(None, [str(CLI_ROOT), env.get('PYTHONPATH')]))
+
+    host, port, engine_port = '127.0.0.1', DEFAULT_DEV_PORT, DEFAULT_ENGINE_PORT
+    if project_root:
+        from core.framework import FlashFlowProject
+        from cli.commands.run import script_env
+
+        project = FlashFlowProject(project_root)
+        env.update({key: value for key, value in script_env(project, plugin.name).items()
+                    if key.startswith('FLASHFLOW_') and key != 'FLASHFLOW_SCRIPT'})
+        serve = project.state.read_runtime('serve') or {}
+        engine = project.state.read_runtime('engine') or {}
+        host = serve.get('host', host)
+        port = serve.get('port', port)
+        engine_port = engine.get('port', engine_port)
+
+    if host in ('0.0.0.0', '::'):
+        host = '127.0.0.1'
+    env['FLASHFLOW_HOST'] = host
+    env['FLASHFLOW_PORT'] = str(port)
+    env['FLASHFLOW_URL'] = f"http://{host}:{port}"
+    env['FLASHFLOW_ENGINE_PORT'] = str(engine_port)
+    return env
+
+def run_plugin(plugin: Plugin, args: List[str], project_root: Optional[Path], verbosity: int = 0) -> int:
+    """Run a plugin in the current directory; returns its exit code"""
+    try:
+        return subprocess.call([str(plugin.path), *args], env=plugin_env(plugin, project_root, verbosity))
+    except OSError as e:
+        click.echo(f"❌ Could not run plugin {plugin.path}: {str(e)}", err=True)
+        return 1
+
+def plugin_command(plugin: Plugin) -> click.Command:
+    """Click command that forwards everything to a plugin"""
+
+    @click.pass_context
+    def invoke(ctx, args):
+        obj = ctx.obj or {}
+        output = obj.get('output')
+        project_root = obj.get('project_root') or find_project_root(Path.cwd())
+        ctx.exit(run_plugin(plugin, list(args), project_root, output.level if output else 0))
+
+    return click.Command(
+        plugin.name,
+        callback=invoke,
+        params=[click.Argument(['args'], nargs=-1, type=click.UNPROCESSED)],
+        context_settings={'ignore_unknown_options': True, 'allow_interspersed_args': False},
+        add_help_option=False,  # --help goes to the plugin
+        short_help=f"[plugin] {plugin.path}"
+    )
+
+class PluginGroup(click.Group):
+    """Command group that falls back to flashflow-<name> plugins for unknown commands"""
+
+    def _plugins(self) -> Dict[str, Plugin]:
+        return discover_plugins(find_project_root(Path.cwd()))
+
+    def list_commands(self, ctx) -> List[str]:
+        return sorted(set(super().list_commands(ctx)) | set(self._plugins()))
+
+    def get_command(self, ctx, name: str) -> Optional[click.Command]:
+        command = super().get_command(ctx, name)
+        if command is not None:
+            return command
+        plugin = self._plugins().get(name)
+        return plugin_command(plugin) if plugin else None
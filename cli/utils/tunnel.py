@@ -0,0 +1,307 @@
+"""
+FlashFlow tunnel client - Share the dev server through a public HTTPS URL
+
+Implements the localtunnel client protocol so previews can be opened from
+devices outside the LAN. Works with the public localtunnel.me relay or any
+self-hosted localtunnel-compatible server.
+
+Anyone can reach the public URL, so the tunnel does not pass connections
+through as they are. It reads each request and lets it through only when:
+
+- it is for one of the shared paths (serve passes SHARED_PATHS: the app's
+  pages and /api/data) or PUBLIC_PATHS; everything else, the dev server's
+  tools included, answers 403 through the tunnel whatever the token. A
+  shared path ending in '/' covers everything under it, any other only
+  itself.
+- it carries the share token, which the printed link has as
+  ?flashflow_share=<token>; the first request with it sets a cookie, so the
+  pages it links to work without it. Link previews fetch PUBLIC_PATHS
+  (social cards, robots.txt) without one.
+- it does not ask for the interactive debugger (a __debugger__ parameter),
+  which only this machine may reach.
+
+The relay's forwarding headers are dropped, since anyone on the internet
+can send them; the tunnel sets X-Forwarded-Proto and X-Forwarded-Host from
+its own public URL, so the dev server's absolute URLs point at it. Each
+relay connection carries one request ('Connection: close' to the dev
+server), so every request is checked.
+"""
+
+import hmac
+import json
+import posixpath
+import secrets
+import socket
+import threading
+import time
+import urllib.request
+from http.cookies import SimpleCookie
+from typing import Iterable, List, Optional, Set, Tuple
+from urllib.parse import parse_qs, unquote, urlparse
+
+DEFAULT_RELAY = "https://localtunnel.me"
+SHARE_PARAM = 'flashflow_share'
+SHARE_COOKIE = 'flashflow_share'
+SHARE_HEADER = 'X-FlashFlow-Share'
+# Werkzeug's debugger answers this parameter on any path
+DEBUGGER_PARAM = '__debugger__'
+# What link previews fetch without the token
+PUBLIC_PATHS = ('/__og/', '/robots.txt', '/favicon.ico')
+# What serve --share lets visitors reach: the app's pages, their assets and /api/data
+SHARED_PATHS = ('/', '/dashboard', '/android', '/ios', '/desktop', '/preview', '/preview/', '/web/', '/media/',
+                '/api/data/', '/api/flow-files', '/api/preview-data', '/sitemap.xml', '/__reload', '/__push-sw.js')
+MAX_HEAD = 65536
+WAIT_SECONDS = 1.0
+BODY_TIMEOUT = 30
+DROPPED_HEADERS = ('connection', 'keep-alive', 'proxy-connection', 'forwarded', 'x-real-ip', SHARE_HEADER.lower())
+
+class TunnelError(Exception):
+    """Raised when a tunnel cannot be opened"""
+    pass
+
+class LocalTunnel:
+    """Outbound tunnel that forwards relay connections to the local dev server"""
+
+    def __init__(self, local_port: int, local_host: str = "localhost",
+                 relay: str = DEFAULT_RELAY, subdomain: Optional[str] = None,
+                 token: Optional[str] = None, shared: Iterable[str] = ()):
+        self.local_port = local_port
+        self.local_host = local_host
+        self.relay = relay.rstrip('/')
+        self.subdomain = subdomain
+        self.url: Optional[str] = None
+        self.token = token or secrets.token_urlsafe(18)
+        self.shared = PUBLIC_PATHS + tuple(shared)
+
+        self._remote_host: Optional[str] = None
+        self._remote_port: Optional[int] = None
+        self._max_conn = 1
+        self._running = False
+        self._threads: List[threading.Thread] = []
+        self._sockets: Set[socket.socket] = set()
+        self._lock = threading.Lock()
+
+    @property
+    def share_url(self) -> Optional[str]:
+        """The public URL with the share token, which is what to hand out"""
+        return f"{self.url}/?{SHARE_PARAM}={self.token}" if self.url else None
+
+    def open(self) -> str:
+        """Register with the relay and start forwarding, returning the public URL"""
+        info = self._request_tunnel()
+
+        self.url = info['url'].rstrip('/')
+        self._remote_host = urlparse(self.relay).hostname
+        self._remote_port = int(info['port'])
+        self._max_conn = max(1, int(info.get('max_conn_count') or 1))
+        self._running = True
+
+        for _ in range(self._max_conn):
+            thread = threading.Thread(target=self._connection_loop, name='flashflow-tunnel', daemon=True)
+            thread.start()
+            self._threads.append(thread)
+
+        return self.url
+
+    def close(self, timeout: float = 5.0):
+        """Stop forwarding: close every relay and local connection and wait for the threads"""
+        self._running = False
+        with self._lock:
+            sockets = list(self._sockets)
+        for sock in sockets:
+            self._close(sock)
+        deadline = time.monotonic() + timeout
+        for thread in self._threads:
+            thread.join(max(0.0, deadline - time.monotonic()))
+        self._threads = [thread for thread in self._threads if thread.is_alive()]
+
+    def _request_tunnel(self) -> dict:
+        """Ask the relay to allocate a tunnel"""
+        endpoint = f"{self.relay}/{self.subdomain}" if self.subdomain else f"{self.relay}/?new"
+
+        try:
+            with urllib.request.urlopen(endpoint, timeout=10) as response:
+                info = json.loads(response.read().decode('utf-8'))
+        except Exception as e:
+            raise TunnelError(f"Could not reach tunnel relay {self.relay}: {str(e)}")
+
+        if 'url' not in info or 'port' not in info:
+            raise TunnelError(info.get('message', f"Unexpected response from tunnel relay: {info}"))
+
+        return info
+
+    def _connect(self, host: str, port: int) -> socket.socket:
+        sock = socket.create_connection((host, port), timeout=10)
+        with self._lock:
+            self._sockets.add(sock)
+        if not self._running:
+            self._close(sock)
+            raise OSError("tunnel closed")
+        return sock
+
+    def _close(self, sock: socket.socket):
+        with self._lock:
+            self._sockets.discard(sock)
+        try:
+            # shutdown wakes a thread blocked in recv on it; close alone does not
+            sock.shutdown(socket.SHUT_RDWR)
+        except OSError:
+            pass
+        sock.close()
+
+    def _connection_loop(self):
+        """Keep one relay connection open, reconnecting when it drops"""
+        backoff = 1
+
+        while self._running:
+            try:
+                remote = self._connect(self._remote_host, self._remote_port)
+            except OSError:
+                if not self._running:
+                    break
+                time.sleep(backoff)
+                backoff = min(backoff * 2, 30)
+                continue
+            backoff = 1
+            try:
+                self._serve(remote)
+            except OSError:
+                pass
+            finally:
+                self._close(remote)
+
+    def _read_head(self, remote: socket.socket) -> Optional[bytes]:
+        """Bytes up to and past the end of the request head; None when the relay hung up or the tunnel closed"""
+        data = b''
+        # The relay only sends data once a visitor makes a request; wake now and then to notice close()
+        remote.settimeout(WAIT_SECONDS)
+        while b'\r\n\r\n' not in data:
+            try:
+                chunk = remote.recv(65536)
+            except socket.timeout:
+                if not self._running:
+                    return None
+                if data:
+                    remote.settimeout(BODY_TIMEOUT)
+                continue
+            if not chunk:
+                return None
+            data += chunk
+            if len(data) > MAX_HEAD and b'\r\n\r\n' not in data[:MAX_HEAD]:
+                remote.sendall(_answer(431, 'Request Header Fields Too Large', "Request head too large"))
+                return None
+        remote.settimeout(BODY_TIMEOUT)
+        return data
+
+    def _serve(self, remote: socket.socket):
+        """Check one request from the relay and forward it to the dev server"""
+        data = self._read_head(remote)
+        if data is None:
+            return
+        head, rest = data.split(b'\r\n\r\n', 1)
+        lines = head.decode('latin-1').split('\r\n')
+        try:
+            method, target, version = lines[0].split(' ')
+        except ValueError:
+            remote.sendall(_answer(400, 'Bad Request', "Malformed request line"))
+            return
+        headers = [tuple(part.strip() for part in line.split(':', 1)) for line in lines[1:] if ':' in line]
+        names = {name.lower(): value for name, value in headers}
+
+        refusal, set_cookie = self.gate(target, names)
+        if refusal:
+            remote.sendall(_answer(*refusal))
+            return
+        if 'transfer-encoding' in names:
+            # Browsers never send chunked requests; reading one to its end is more than a preview needs
+            remote.sendall(_answer(411, 'Length Required', "Send the body with a Content-Length"))
+            return
+        length = names.get('content-length', '0')
+        if not length.isdigit():
+            remote.sendall(_answer(400, 'Bad Request', "Invalid Content-Length"))
+            return
+        length = int(length)
+
+        host = urlparse(self.url).netloc
+        kept = [f"{name}: {value}" for name, value in headers
+                if name.lower() not in DROPPED_HEADERS and not name.lower().startswith('x-forwarded-')]
+        kept += ["Connection: close", "X-Forwarded-Proto: https", f"X-Forwarded-Host: {host}"]
+        forwarded = f"{method} {target} {version}\r\n".encode('latin-1') + '\r\n'.join(kept).encode('latin-1') + b'\r\n\r\n'
+
+        local = self._connect(self.local_host, self.local_port)
+        try:
+            # Anything past the body would be a second request, which has to come in on its own connection
+            body = rest[:length]
+            local.sendall(forwarded + body)
+            remaining = length - len(body)
+            while remaining > 0:
+                chunk = remote.recv(min(65536, remaining))
+                if not chunk:
+                    return
+                local.sendall(chunk)
+                remaining -= len(chunk)
+            self._relay_response(local, remote, self._cookie() if set_cookie else None)
+        finally:
+            self._close(local)
+
+    def _relay_response(self, local: socket.socket, remote: socket.socket, cookie: Optional[str]):
+        """Copy the dev server's answer to the relay until the dev server closes the connection"""
+        local.settimeout(None)
+        pending = b''
+        while True:
+            chunk = local.recv(65536)
+            if not chunk:
+                if pending:
+                    remote.sendall(pending)
+                return
+            if cookie is not None:
+                pending += chunk
+                if b'\r\n\r\n' not in pending:
+                    continue
+                head, rest = pending.split(b'\r\n\r\n', 1)
+                chunk = head + f"\r\nSet-Cookie: {cookie}".encode('latin-1') + b'\r\n\r\n' + rest
+                cookie, pending = None, b''
+            remote.sendall(chunk)
+
+    def _cookie(self) -> str:
+        return f"{SHARE_COOKIE}={self.token}; Path=/; HttpOnly; Secure; SameSite=Lax"
+
+    def gate(self, target: str, headers: dict) -> Tuple[Optional[Tuple[int, str, str]], bool]:
+        """(status, reason, message) to answer instead of forwarding, and whether to set the share cookie
+
+        headers has lowercase names.
+        """
+        path, _, query = target.partition('?')
+        if not path.startswith('/'):
+            return (400, 'Bad Request', "Only paths are forwarded"), False
+        # As the dev server will route it: '%61dmin', '//admin' and '/x/../admin' are all /admin
+        path = '/' + posixpath.normpath(unquote(path).replace('\\', '/')).lstrip('/')
+        if DEBUGGER_PARAM in unquote(query):
+            return (403, 'Forbidden', "Not shared: the debugger only works on the machine running the dev server"), False
+        if not _under(path, self.shared):
+            return (403, 'Forbidden', "Not shared: only the app's pages are; the dev server's tools only work on the machine running it"), False
+        if _under(path, PUBLIC_PATHS):
+            return None, False
+
+        from_query = (parse_qs(query).get(SHARE_PARAM) or [''])[0]
+        cookies = SimpleCookie()
+        try:
+            cookies.load(headers.get('cookie', ''))
+        except Exception:
+            pass
+        offered = [from_query, headers.get(SHARE_HEADER.lower(), ''),
+                   cookies[SHARE_COOKIE].value if SHARE_COOKIE in cookies else '']
+        for index, token in enumerate(offered):
+            if token and hmac.compare_digest(token.encode(), self.token.encode()):
+                return None, index == 0
+        return (401, 'Unauthorized', "This preview needs its share link; ask for the full URL, with ?flashflow_share=..."), False
+
+def _under(path: str, paths: Tuple[str, ...]) -> bool:
+    """Whether path is one of paths, or below one of them that ends in '/' ('/' itself only matches the root)"""
+    return any(path == entry or path == entry.rstrip('/') or (entry != '/' and entry.endswith('/') and path.startswith(entry))
+               for entry in paths)
+
+def _answer(status: int, reason: str, message: str) -> bytes:
+    body = (message + '\n').encode('utf-8')
+    return (f"HTTP/1.1 {status} {reason}\r\nContent-Type: text/plain; charset=utf-8\r\n"
+            f"Content-Length: {len(body)}\r\nConnection: close\r\n\r\n").encode('latin-1') + body
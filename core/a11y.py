@@ -0,0 +1,138 @@
+"""
+FlashFlow accessibility audit - Aggregates axe-core results from rendered pages
+
+    "a11y": {
+        "fail_on": "serious"     # minor, moderate, serious or critical
+    }
+
+Pages served with 'flashflow serve --a11y' report their violations here; the
+report is kept in .flashflow/a11y/report.json so it survives restarts and can
+gate test runs: 'flashflow test a11y' fails when audit_failures() finds
+violations at or above fail_on (or its --fail-on).
+"""
+
+import json
+import threading
+import time
+from pathlib import Path
+from typing import Dict, Any, List, Optional
+
+IMPACT_LEVELS = ['minor', 'moderate', 'serious', 'critical']
+DEFAULT_FAIL_ON = 'serious'
+AXE_CDN_URL = 'https://cdnjs.cloudflare.com/ajax/libs/axe-core/4.10.2/axe.min.js'
+MAX_NODES_PER_VIOLATION = 20
+
+class A11yError(Exception):
+    """Raised for an invalid severity or a malformed axe result"""
+    pass
+
+def impact_rank(impact: Optional[str]) -> int:
+    """Position of an axe impact in IMPACT_LEVELS; unknown impacts rank lowest"""
+    return IMPACT_LEVELS.index(impact) if impact in IMPACT_LEVELS else -1
+
+def check_level(level: str, setting: str = 'a11y.fail_on') -> str:
+    """level lowercased if it is an axe impact; A11yError naming setting otherwise"""
+    level = str(level).lower()
+    if level not in IMPACT_LEVELS:
+        raise A11yError(f"{setting} must be one of {', '.join(IMPACT_LEVELS)}, got '{level}'")
+    return level
+
+def fail_on_level(project) -> str:
+    """Severity from flashflow.json at or above which violations fail"""
+    settings = project.config.a11y or {}
+    return check_level(settings.get('fail_on', DEFAULT_FAIL_ON))
+
+class A11yReport:
+    """Latest axe-core violations per page, persisted as JSON"""
+
+    def __init__(self, path: Path):
+        self.path = Path(path)
+        self._lock = threading.Lock()
+        self._pages: Dict[str, Dict[str, Any]] = self._load()
+
+    @classmethod
+    def for_project(cls, project) -> 'A11yReport':
+        return cls(project.state.path('a11y', 'report.json'))
+
+    def _load(self) -> Dict[str, Dict[str, Any]]:
+        if not self.path.exists():
+            return {}
+        try:
+            with open(self.path, 'r') as f:
+                return json.load(f).get('pages', {})
+        except (OSError, json.JSONDecodeError, AttributeError):
+            return {}
+
+    def _save(self):
+        self.path.parent.mkdir(parents=True, exist_ok=True)
+        partial = self.path.with_suffix('.tmp')
+        with open(partial, 'w') as f:
+            json.dump({'pages': self._pages}, f, indent=2)
+        partial.replace(self.path)
+
+    def record(self, page: str, violations: List[Dict[str, Any]]):
+        """Replace a page's violations with a fresh axe run"""
+        if not isinstance(violations, list):
+            raise A11yError("'violations' must be the list from axe.run()")
+        entries = []
+        for violation in violations:
+            if not isinstance(violation, dict) or 'id' not in violation:
+                raise A11yError("Each violation needs an axe rule 'id'")
+            nodes = violation.get('nodes') or []
+            entries.append({
+                'id': violation['id'],
+                'impact': violation.get('impact') or 'minor',
+                'help': violation.get('help', ''),
+                'description': violation.get('description', ''),
+                'help_url': violation.get('helpUrl', ''),
+                'node_count': len(nodes),
+                'nodes': [
+                    {'target': node.get('target', []), 'html': node.get('html', ''), 'summary': node.get('failureSummary', '')}
+                    for node in nodes[:MAX_NODES_PER_VIOLATION] if isinstance(node, dict)
+                ]
+            })
+        entries.sort(key=lambda entry: -impact_rank(entry['impact']))
+
+        with self._lock:
+            self._pages[page] = {'checked_at': time.time(), 'violations': entries}
+            self._save()
+
+    def clear(self):
+        with self._lock:
+            self._pages = {}
+            self._save()
+
+    def failures(self, fail_on: str) -> List[Dict[str, Any]]:
+        """Violations at or above a severity, with the page they were found on"""
+        threshold = impact_rank(fail_on)
+        with self._lock:
+            pages = dict(self._pages)
+        return [
+            dict(violation, page=page)
+            for page, result in sorted(pages.items())
+            for violation in result['violations']
+            if impact_rank(violation['impact']) >= threshold
+        ]
+
+    def to_dict(self, fail_on: str = DEFAULT_FAIL_ON) -> Dict[str, Any]:
+        """JSON-serialisable report used by /api/a11y/report"""
+        by_impact = {level: 0 for level in IMPACT_LEVELS}
+        with self._lock:
+            pages = {page: dict(result) for page, result in self._pages.items()}
+        for result in pages.values():
+            for violation in result['violations']:
+                if violation['impact'] in by_impact:
+                    by_impact[violation['impact']] += 1
+        return {
+            'fail_on': fail_on,
+            'page_count': len(pages),
+            'violation_count': sum(by_impact.values()),
+            'by_impact': by_impact,
+            'failing': len(self.failures(fail_on)),
+            'pages': pages
+        }
+
+def audit_failures(project, fail_on: Optional[str] = None) -> List[Dict[str, Any]]:
+    """Recorded violations that should fail a test run: at or above fail_on, else the project's a11y.fail_on"""
+    level = check_level(fail_on, 'fail_on') if fail_on else fail_on_level(project)
+    return A11yReport.for_project(project).failures(level)
@@ -0,0 +1,676 @@
+"""
+FlashFlow admin scaffold - Generated management screens for every flow model
+
+'flashflow generate admin' writes one page per model into src/admin/, which
+the dev server serves at /admin/models/<table>:
+
+    src/admin/index.html            every model with its row count
+    src/admin/<table>.html          list, detail and edit screens of one model
+    src/admin/_assets/admin.js      the screens, working against /api/data
+    src/admin/_assets/admin.css
+
+Each model page embeds what the screens need to know about the model: its
+fields, how to edit each one, which fields to search and list, and its
+relations ('user_id: integer references User.id' becomes a picker of users,
+and a user's detail screen links to their rows in other models).
+
+The files are yours to edit. Running the generator again rewrites only files
+that still match what it last wrote (recorded in src/admin/.generated.json);
+files you changed are kept unless you pass --force.
+"""
+
+import hashlib
+import json
+from typing import Any, Dict, List, Optional
+
+from core.database import model_field_list, table_name_for
+from core.html_safety import escape_html
+
+ADMIN_DIR = 'admin'
+ASSETS_DIR = '_assets'
+MANIFEST_NAME = '.generated.json'
+URL_PREFIX = '/admin/models'
+LIST_COLUMNS = 6
+# The field shown for a row wherever another model points at it
+DISPLAY_CANDIDATES = ('name', 'title', 'label', 'username', 'email', 'slug', 'code')
+SEARCHABLE_TYPES = {'string', 'text', 'email', 'url', 'enum'}
+# Too long or too sensitive for a table column
+UNLISTED_TYPES = {'text', 'json', 'password'}
+WIDGETS = {
+    'boolean': 'checkbox', 'integer': 'number', 'float': 'number', 'decimal': 'number', 'date': 'date',
+    'datetime': 'datetime-local', 'timestamp': 'datetime-local', 'text': 'textarea', 'json': 'json',
+    'email': 'email', 'url': 'url', 'password': 'password'
+}
+
+class AdminScaffoldError(Exception):
+    """Raised when there is nothing to generate or a model is unknown"""
+    pass
+
+def field_label(name: str) -> str:
+    return name.replace('_', ' ').strip().capitalize() or name
+
+def admin_model_specs(ir) -> List[Dict[str, Any]]:
+    """What the admin screens need to know about each model, sorted by model name"""
+    fields_by_model = {name: model_field_list(data.get('fields')) for name, data in ir.models.items()
+                       if isinstance(data, dict)}
+    specs = {}
+    for model, fields in fields_by_model.items():
+        spec_fields = []
+        for model_field in fields:
+            name = str(model_field['name'])
+            if name in ('id', 'created_at', 'updated_at'):
+                continue
+            field_type = str(model_field.get('type') or 'string')
+            spec = {'name': name, 'label': field_label(name), 'type': field_type,
+                    'required': bool(model_field.get('required')) and model_field.get('default') is None
+                                and not model_field.get('auto'),
+                    'widget': WIDGETS.get(field_type, 'text')}
+            if isinstance(model_field.get('values'), list):
+                spec.update(widget='select', values=[str(value) for value in model_field['values']])
+            if model_field.get('default') is not None:
+                spec['default'] = model_field['default']
+            target = _reference(model_field.get('references'), fields_by_model)
+            if target:
+                spec.update(widget='relation', references=target)
+            spec_fields.append(spec)
+        specs[model] = {
+            'model': model,
+            'table': table_name_for(model),
+            'label': field_label(table_name_for(model)),
+            'fields': spec_fields,
+            'display': _display_field(spec_fields),
+            'columns': ['id'] + [spec['name'] for spec in spec_fields if spec['type'] not in UNLISTED_TYPES][:LIST_COLUMNS],
+            'search': [spec['name'] for spec in spec_fields
+                       if spec['type'] in SEARCHABLE_TYPES or spec['widget'] == 'select'],
+            'incoming': []
+        }
+    # Rows of other models that point at this one, for the detail screen
+    for model, spec in specs.items():
+        for spec_field in spec['fields']:
+            target = spec_field.get('references')
+            if target:
+                specs[target['model']]['incoming'].append({'model': model, 'table': spec['table'],
+                                                           'label': spec['label'], 'field': spec_field['name']})
+    for model, spec in specs.items():
+        for spec_field in spec['fields']:
+            if spec_field.get('references'):
+                spec_field['references']['display'] = specs[spec_field['references']['model']]['display']
+    return [specs[model] for model in sorted(specs)]
+
+def _reference(value: Any, models: Dict[str, List[Dict[str, Any]]]) -> Optional[Dict[str, str]]:
+    """'User.id' (or 'User') as the referenced model and field, if the model exists"""
+    if not value:
+        return None
+    model, _, target_field = str(value).partition('.')
+    if model not in models:
+        return None
+    return {'model': model, 'table': table_name_for(model), 'field': target_field or 'id'}
+
+def _display_field(fields: List[Dict[str, Any]]) -> str:
+    names = [spec['name'] for spec in fields]
+    for candidate in DISPLAY_CANDIDATES:
+        if candidate in names:
+            return candidate
+    for spec in fields:
+        if spec['type'] in ('string', 'email') and spec['widget'] != 'relation':
+            return spec['name']
+    return 'id'
+
+class AdminScaffold:
+    """Writes the admin pages of a project, keeping files that were edited by hand"""
+
+    def __init__(self, project):
+        self.project = project
+        self.target = project.root_path / 'src' / ADMIN_DIR
+
+    def generate(self, ir, models: Optional[List[str]] = None, force: bool = False) -> Dict[str, List[str]]:
+        """Write the pages; returns the relative paths written, unchanged and kept (edited since generated)"""
+        specs = admin_model_specs(ir)
+        if not specs:
+            raise AdminScaffoldError("No models in the flows; declare a model: before generating the admin")
+        selected = specs
+        if models:
+            known = {spec['model'].lower(): spec for spec in specs}
+            known.update({spec['table']: spec for spec in specs})
+            unknown = [name for name in models if name.lower() not in known]
+            if unknown:
+                raise AdminScaffoldError(f"Unknown model(s) {', '.join(unknown)}; models are "
+                                         f"{', '.join(spec['model'] for spec in specs)}")
+            selected = [known[name.lower()] for name in models]
+
+        files = {
+            f"{ASSETS_DIR}/admin.js": ADMIN_JS,
+            f"{ASSETS_DIR}/admin.css": ADMIN_CSS,
+            'index.html': self.render_index(specs),
+        }
+        for spec in selected:
+            files[f"{spec['table']}.html"] = self.render_model(spec, specs)
+
+        manifest = self._manifest()
+        report = {'written': [], 'unchanged': [], 'kept': []}
+        for relative, content in files.items():
+            path = self.target / relative
+            digest = _digest(content)
+            if path.exists():
+                current = _digest(path.read_text(encoding='utf-8'))
+                if current == digest:
+                    manifest[relative] = digest
+                    report['unchanged'].append(relative)
+                    continue
+                if current != manifest.get(relative) and not force:
+                    report['kept'].append(relative)
+                    continue
+            path.parent.mkdir(parents=True, exist_ok=True)
+            path.write_text(content, encoding='utf-8')
+            manifest[relative] = digest
+            report['written'].append(relative)
+        (self.target / MANIFEST_NAME).write_text(json.dumps(manifest, indent=2, sort_keys=True))
+        return report
+
+    def _manifest(self) -> Dict[str, str]:
+        try:
+            manifest = json.loads((self.target / MANIFEST_NAME).read_text())
+        except (OSError, json.JSONDecodeError):
+            return {}
+        return manifest if isinstance(manifest, dict) else {}
+
+    def render_index(self, specs: List[Dict[str, Any]]) -> str:
+        rows = ''.join(
+            f'\n            <tr><td><a href="{URL_PREFIX}/{escape_html(spec["table"])}">{escape_html(spec["model"])}</a></td>'
+            f'<td><code>{escape_html(spec["table"])}</code></td><td>{len(spec["fields"])}</td>'
+            f'<td data-count="{escape_html(spec["table"])}">…</td></tr>'
+            for spec in specs)
+        body = f"""
+        <h2>Models</h2>
+        <table class="rows">
+            <thead><tr><th>Model</th><th>Table</th><th>Fields</th><th>Rows</th></tr></thead>
+            <tbody>{rows}
+            </tbody>
+        </table>"""
+        return self._page('Admin', specs, body, None)
+
+    def render_model(self, spec: Dict[str, Any], specs: List[Dict[str, Any]]) -> str:
+        # '</' would end the script element early
+        data = json.dumps(spec, indent=2).replace('</', '<\\/')
+        body = f"""
+        <div id="admin-view"><p class="muted">Loading…</p></div>
+        <script type="application/json" id="admin-model">
+{data}
+        </script>"""
+        return self._page(spec['label'], specs, body, spec['table'])
+
+    def _page(self, title: str, specs: List[Dict[str, Any]], body: str, current: Optional[str]) -> str:
+        name = escape_html(self.project.config.name)
+        links = ''.join(
+            f'\n            <a href="{URL_PREFIX}/{escape_html(spec["table"])}"{ACTIVE if spec["table"] == current else ""}>'
+            f'{escape_html(spec["label"])}</a>'
+            for spec in specs)
+        return f"""<!DOCTYPE html>
+<!-- Generated by 'flashflow generate admin'; edit freely, edited files are kept when it runs again -->
+<html>
+<head>
+    <title>{escape_html(title)} - {name} admin</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <link rel="stylesheet" href="{URL_PREFIX}/{ASSETS_DIR}/admin.css">
+</head>
+<body>
+    <div class="header">
+        <h1>🛠️ <a href="{URL_PREFIX}/">{name} admin</a></h1>
+        <nav>{links}
+        </nav>
+    </div>
+    <div class="container">
+        <p id="admin-error" class="error" hidden></p>{body}
+    </div>
+    <script src="{URL_PREFIX}/{ASSETS_DIR}/admin.js"></script>
+</body>
+</html>
+"""
+
+ACTIVE = ' class="active"'
+
+def _digest(content: str) -> str:
+    return hashlib.sha256(content.encode('utf-8')).hexdigest()
+
+ADMIN_CSS = """/* Generated by 'flashflow generate admin' */
+body { font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; color: #1f2937; }
+a { color: #4f46e5; }
+.header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }
+.header h1 { margin: 0 0 0.5rem; font-size: 1.4rem; }
+.header h1 a { color: white; text-decoration: none; }
+.header nav a { color: #e0e7ff; margin-right: 1rem; text-decoration: none; }
+.header nav a.active { color: white; font-weight: 600; border-bottom: 2px solid white; }
+.container { max-width: 1400px; margin: 0 auto; padding: 2rem; }
+.panel { background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); margin-bottom: 1.5rem; }
+.toolbar { display: flex; gap: 0.5rem; align-items: center; flex-wrap: wrap; margin-bottom: 1rem; }
+.toolbar .spacer { flex: 1; }
+table.rows { width: 100%; border-collapse: collapse; background: white; }
+table.rows th, table.rows td { text-align: left; padding: 0.5rem 0.6rem; border-bottom: 1px solid #e5e7eb; }
+table.rows th a { color: inherit; text-decoration: none; }
+table.rows tbody tr:hover { background: #eef2ff; cursor: pointer; }
+button, .button { background: #4f46e5; color: white; border: none; padding: 0.4rem 0.9rem; border-radius: 4px; cursor: pointer; text-decoration: none; font-size: 0.9rem; }
+button.secondary, .button.secondary { background: #e5e7eb; color: #374151; }
+button.danger { background: #dc2626; }
+button:disabled { opacity: 0.5; cursor: default; }
+input, select, textarea { font: inherit; padding: 0.35rem 0.5rem; border: 1px solid #d1d5db; border-radius: 4px; }
+textarea { width: 100%; min-height: 6rem; box-sizing: border-box; }
+form.edit { display: grid; grid-template-columns: 200px 1fr; gap: 0.8rem 1rem; align-items: start; }
+form.edit label { padding-top: 0.4rem; font-weight: 600; }
+form.edit .required::after { content: ' *'; color: #dc2626; }
+form.edit .field-error { color: #b91c1c; font-size: 0.85rem; margin-top: 0.2rem; }
+form.edit .picker { display: flex; gap: 0.4rem; }
+form.edit .actions { grid-column: 2; display: flex; gap: 0.5rem; }
+dl.detail { display: grid; grid-template-columns: 200px 1fr; gap: 0.5rem 1rem; }
+dl.detail dt { color: #6b7280; }
+dl.detail dd { margin: 0; white-space: pre-wrap; word-break: break-word; }
+.muted { color: #6b7280; }
+.error { color: #b91c1c; font-family: monospace; }
+.pager { display: flex; gap: 0.5rem; align-items: center; margin-top: 1rem; }
+"""
+
+ADMIN_JS = r"""// Generated by 'flashflow generate admin': list, detail and edit screens over /api/data
+(function () {
+    const PREFIX = '/admin/models';
+    const PER_PAGE = 25;
+    const PICKER_SIZE = 50;
+    const specElement = document.getElementById('admin-model');
+
+    function escapeHtml(text) {
+        const div = document.createElement('div');
+        div.textContent = text == null ? '' : String(text);
+        return div.innerHTML;
+    }
+
+    async function api(url, options) {
+        const response = await fetch(url, options);
+        const data = response.status === 204 ? {} : await response.json();
+        if (!response.ok) {
+            const error = new Error(data.error || response.statusText);
+            error.fields = data.errors || [];
+            throw error;
+        }
+        return data;
+    }
+
+    function showError(message) {
+        const element = document.getElementById('admin-error');
+        element.textContent = message ? '❌ ' + message : '';
+        element.hidden = !message;
+    }
+
+    if (!specElement) {
+        // The index: row counts of every model
+        document.querySelectorAll('[data-count]').forEach(async function (cell) {
+            try {
+                const page = await api('/api/data/' + encodeURIComponent(cell.dataset.count) + '?per_page=1');
+                cell.textContent = page.total;
+            } catch (e) {
+                cell.textContent = '?';
+                showError(e.message);
+            }
+        });
+        return;
+    }
+
+    const model = JSON.parse(specElement.textContent);
+    const base = '/api/data/' + encodeURIComponent(model.table);
+    const view = document.getElementById('admin-view');
+    const fieldsByName = Object.fromEntries(model.fields.map(function (field) { return [field.name, field]; }));
+    // Display values of related rows, by table then id
+    const related = {};
+
+    function route() {
+        const hash = location.hash.replace(/^#\/?/, '');
+        const [path, query] = hash.split('?');
+        const parts = path.split('/').filter(Boolean);
+        return {parts: parts, params: new URLSearchParams(query || '')};
+    }
+
+    function go(path, params) {
+        const query = params && params.toString();
+        location.hash = '#/' + path + (query ? '?' + query : '');
+    }
+
+    async function relatedLabels(field, ids) {
+        const target = field.references;
+        const cache = related[target.table] = related[target.table] || {};
+        const missing = ids.filter(function (id) { return id != null && id !== '' && !(id in cache); });
+        if (missing.length && target.display !== 'id') {
+            try {
+                const page = await api('/api/data/' + encodeURIComponent(target.table) + '?per_page=' + PICKER_SIZE
+                    + '&filter[' + target.field + '][in]=' + encodeURIComponent(missing.join(',')));
+                for (const row of page.data) cache[row[target.field]] = row[target.display];
+            } catch (e) {
+                // The raw ids are shown instead
+            }
+        }
+        return cache;
+    }
+
+    function displayValue(field, value, labels) {
+        if (value == null || value === '') return '<span class="muted">—</span>';
+        if (!field) return escapeHtml(value);
+        if (field.widget === 'relation') {
+            const label = labels && labels[value] != null ? labels[value] + ' (#' + value + ')' : '#' + value;
+            return '<a href="' + PREFIX + '/' + encodeURIComponent(field.references.table) + '#/' + encodeURIComponent(value) + '">'
+                + escapeHtml(label) + '</a>';
+        }
+        if (field.widget === 'checkbox') return value ? '✅' : '—';
+        if (field.widget === 'password') return '••••••';
+        if (field.widget === 'json') return '<code>' + escapeHtml(typeof value === 'string' ? value : JSON.stringify(value)) + '</code>';
+        if (field.widget === 'url') return '<a href="' + escapeHtml(/^https?:/i.test(value) ? value : '#') + '" rel="noopener">' + escapeHtml(value) + '</a>';
+        return escapeHtml(value);
+    }
+
+    async function labelsFor(rows, names) {
+        const labels = {};
+        for (const name of names) {
+            const field = fieldsByName[name];
+            if (field && field.widget === 'relation') {
+                labels[name] = await relatedLabels(field, rows.map(function (row) { return row[name]; }));
+            }
+        }
+        return labels;
+    }
+
+    // List
+
+    async function showList(params) {
+        const page = Number(params.get('page') || 1);
+        const sort = params.get('sort') || '-id';
+        const search = params.get('q') || '';
+        const searchField = params.get('in') || model.search[0] || '';
+        const query = new URLSearchParams({page: page, per_page: PER_PAGE, sort: sort});
+        if (search && searchField) query.set('filter[' + searchField + '][like]', search);
+        // Any other hash parameter is an equality filter, e.g. #/?user_id=3 from a user's detail screen
+        for (const [name, value] of params) {
+            if (!['page', 'sort', 'q', 'in'].includes(name)) query.set('filter[' + name + ']', value);
+        }
+
+        let result;
+        try {
+            result = await api(base + '?' + query.toString());
+        } catch (e) {
+            showError(e.message);
+            return;
+        }
+        showError('');
+        const labels = await labelsFor(result.data, model.columns);
+        const filters = [...params].filter(function ([name]) { return !['page', 'sort', 'q', 'in'].includes(name); });
+
+        view.innerHTML = '<div class="toolbar">'
+            + (model.search.length ? '<form id="search"><input name="q" type="search" placeholder="Search" value="' + escapeHtml(search) + '"> in '
+                + '<select name="in">' + model.search.map(function (name) {
+                    return '<option value="' + escapeHtml(name) + '"' + (name === searchField ? ' selected' : '') + '>'
+                        + escapeHtml(fieldsByName[name].label) + '</option>';
+                }).join('') + '</select> <button>Search</button></form>' : '')
+            + filters.map(function ([name, value]) {
+                return '<span class="muted">' + escapeHtml(name) + ' = ' + escapeHtml(value) + '</span>';
+            }).join(' ')
+            + (filters.length || search ? ' <a href="#/">Clear</a>' : '')
+            + '<span class="spacer"></span><span class="muted">' + result.total + ' row(s)</span>'
+            + '<a class="button" href="#/new' + (filters.length ? '?' + new URLSearchParams(filters).toString() : '') + '">New '
+            + escapeHtml(model.model) + '</a></div>'
+            + '<table class="rows"><thead><tr>' + model.columns.map(function (name) {
+                const active = sort.replace('-', '') === name;
+                const next = active && !sort.startsWith('-') ? '-' + name : name;
+                return '<th><a href="#" data-sort="' + escapeHtml(next) + '">' + escapeHtml(fieldsByName[name] ? fieldsByName[name].label : name)
+                    + (active ? (sort.startsWith('-') ? ' ▼' : ' ▲') : '') + '</a></th>';
+            }).join('') + '</tr></thead><tbody>'
+            + (result.data.map(function (row) {
+                return '<tr data-id="' + escapeHtml(row.id) + '">' + model.columns.map(function (name) {
+                    return '<td>' + displayValue(fieldsByName[name], row[name], labels[name]) + '</td>';
+                }).join('') + '</tr>';
+            }).join('') || '<tr><td colspan="' + model.columns.length + '" class="muted">No rows</td></tr>')
+            + '</tbody></table>'
+            + '<div class="pager"><button class="secondary" id="prev"' + (page <= 1 ? ' disabled' : '') + '>‹ Previous</button>'
+            + '<span class="muted">Page ' + result.page + ' of ' + result.pages + '</span>'
+            + '<button class="secondary" id="next"' + (page >= result.pages ? ' disabled' : '') + '>Next ›</button></div>';
+
+        function withParams(changes) {
+            const next = new URLSearchParams(params);
+            for (const [name, value] of Object.entries(changes)) {
+                if (value) next.set(name, value); else next.delete(name);
+            }
+            return next;
+        }
+        const searchForm = document.getElementById('search');
+        if (searchForm) searchForm.onsubmit = function (event) {
+            event.preventDefault();
+            go('', withParams({q: searchForm.q.value.trim(), in: searchForm.in.value, page: ''}));
+        };
+        view.querySelectorAll('[data-sort]').forEach(function (link) {
+            link.onclick = function (event) {
+                event.preventDefault();
+                go('', withParams({sort: link.dataset.sort, page: ''}));
+            };
+        });
+        view.querySelectorAll('tr[data-id]').forEach(function (row) {
+            row.onclick = function (event) {
+                if (event.target.closest('a')) return;
+                go(row.dataset.id);
+            };
+        });
+        document.getElementById('prev').onclick = function () { go('', withParams({page: String(page - 1)})); };
+        document.getElementById('next').onclick = function () { go('', withParams({page: String(page + 1)})); };
+    }
+
+    // Detail
+
+    async function showDetail(id) {
+        let row;
+        try {
+            row = (await api(base + '/' + encodeURIComponent(id))).data;
+        } catch (e) {
+            showError(e.message);
+            view.innerHTML = '<p><a href="#/">‹ All ' + escapeHtml(model.label.toLowerCase()) + '</a></p>';
+            return;
+        }
+        showError('');
+        const names = model.fields.map(function (field) { return field.name; });
+        const labels = await labelsFor([row], names);
+        view.innerHTML = '<p><a href="#/">‹ All ' + escapeHtml(model.label.toLowerCase()) + '</a></p>'
+            + '<div class="panel"><div class="toolbar"><h2>' + escapeHtml(model.model) + ' #' + escapeHtml(row.id)
+            + (model.display !== 'id' && row[model.display] != null ? ' · ' + escapeHtml(row[model.display]) : '') + '</h2>'
+            + '<span class="spacer"></span><a class="button" href="#/' + encodeURIComponent(row.id) + '/edit">Edit</a>'
+            + '<button class="danger" id="delete">Delete</button></div>'
+            + '<dl class="detail">' + model.fields.map(function (field) {
+                return '<dt>' + escapeHtml(field.label) + '</dt><dd>' + displayValue(field, row[field.name], labels[field.name]) + '</dd>';
+            }).join('')
+            + ['created_at', 'updated_at'].filter(function (name) { return name in row; }).map(function (name) {
+                return '<dt>' + escapeHtml(name.replace('_', ' ')) + '</dt><dd class="muted">' + escapeHtml(row[name]) + '</dd>';
+            }).join('') + '</dl></div>'
+            + (model.incoming.length ? '<div class="panel"><h3>Related</h3><ul>' + model.incoming.map(function (link) {
+                return '<li><a href="' + PREFIX + '/' + encodeURIComponent(link.table) + '#/?' + encodeURIComponent(link.field) + '='
+                    + encodeURIComponent(row.id) + '">' + escapeHtml(link.label) + '</a> <span class="muted">by ' + escapeHtml(link.field) + '</span></li>';
+            }).join('') + '</ul></div>' : '');
+        document.getElementById('delete').onclick = async function () {
+            if (!confirm('Delete ' + model.model + ' #' + row.id + '?')) return;
+            try {
+                await api(base + '/' + encodeURIComponent(row.id), {method: 'DELETE'});
+                go('');
+            } catch (e) {
+                showError(e.message);
+            }
+        };
+    }
+
+    // Create and edit
+
+    function inputFor(field, value) {
+        const name = escapeHtml(field.name);
+        const required = field.required && field.widget !== 'checkbox' && field.widget !== 'password' ? ' required' : '';
+        if (field.widget === 'select') {
+            return '<select name="' + name + '" id="field-' + name + '"' + required + '><option value=""></option>'
+                + field.values.map(function (option) {
+                    return '<option' + (String(value) === option ? ' selected' : '') + '>' + escapeHtml(option) + '</option>';
+                }).join('') + '</select>';
+        }
+        if (field.widget === 'relation') {
+            return '<div class="picker"><input type="search" data-picker="' + name + '" placeholder="Search '
+                + escapeHtml(field.references.model) + '"><select name="' + name + '" id="field-' + name + '"' + required + '>'
+                + '<option value=""></option>' + (value != null && value !== '' ? '<option value="' + escapeHtml(value) + '" selected>#'
+                + escapeHtml(value) + '</option>' : '') + '</select></div>';
+        }
+        if (field.widget === 'checkbox') {
+            return '<input type="checkbox" name="' + name + '" id="field-' + name + '"' + (value ? ' checked' : '') + '>';
+        }
+        if (field.widget === 'textarea' || field.widget === 'json') {
+            const text = field.widget === 'json' && value != null && typeof value !== 'string' ? JSON.stringify(value, null, 2) : value;
+            return '<textarea name="' + name + '" id="field-' + name + '"' + required + '>' + escapeHtml(text == null ? '' : text) + '</textarea>';
+        }
+        let inputValue = value == null ? '' : String(value);
+        if (field.widget === 'datetime-local') inputValue = inputValue.replace(' ', 'T').slice(0, 16);
+        if (field.widget === 'date') inputValue = inputValue.slice(0, 10);
+        if (field.widget === 'password') inputValue = '';
+        const step = field.type === 'integer' ? ' step="1"' : field.widget === 'number' ? ' step="any"' : '';
+        return '<input type="' + field.widget + '" name="' + name + '" id="field-' + name + '" value="' + escapeHtml(inputValue) + '"'
+            + step + required + (field.widget === 'password' && value != null ? ' placeholder="Unchanged"' : '') + '>';
+    }
+
+    async function fillPicker(field, select, search) {
+        const target = field.references;
+        const query = new URLSearchParams({per_page: PICKER_SIZE, sort: target.display});
+        if (search && target.display !== 'id') query.set('filter[' + target.display + '][like]', search);
+        let rows = [];
+        try {
+            rows = (await api('/api/data/' + encodeURIComponent(target.table) + '?' + query.toString())).data;
+        } catch (e) {
+            showError(e.message);
+            return;
+        }
+        const current = select.value;
+        select.innerHTML = '<option value=""></option>' + rows.map(function (row) {
+            const key = row[target.field];
+            const label = target.display === 'id' ? '#' + key : row[target.display] + ' (#' + key + ')';
+            return '<option value="' + escapeHtml(key) + '"' + (String(key) === current ? ' selected' : '') + '>' + escapeHtml(label) + '</option>';
+        }).join('');
+        if (current && !rows.some(function (row) { return String(row[target.field]) === current; })) {
+            select.insertAdjacentHTML('afterbegin', '<option value="' + escapeHtml(current) + '" selected>#' + escapeHtml(current) + '</option>');
+        }
+    }
+
+    function readForm(form, creating) {
+        const body = {};
+        const errors = {};
+        for (const field of model.fields) {
+            const input = form.elements[field.name];
+            if (!input) continue;
+            if (field.widget === 'checkbox') {
+                body[field.name] = input.checked;
+                continue;
+            }
+            const raw = input.value.trim();
+            if (raw === '') {
+                // An empty password keeps the current one
+                if (field.widget === 'password') continue;
+                if (field.required) errors[field.name] = 'is required';
+                else if (!creating) body[field.name] = null;
+                continue;
+            }
+            if (field.type === 'integer' || (field.widget === 'relation' && /^-?\d+$/.test(raw))) {
+                if (!/^-?\d+$/.test(raw)) errors[field.name] = 'must be a whole number';
+                else body[field.name] = Number(raw);
+            } else if (field.type === 'float') {
+                if (isNaN(Number(raw))) errors[field.name] = 'must be a number';
+                else body[field.name] = Number(raw);
+            } else if (field.widget === 'json') {
+                try {
+                    body[field.name] = JSON.parse(raw);
+                } catch (e) {
+                    errors[field.name] = 'must be valid JSON';
+                }
+            } else if (field.widget === 'datetime-local') {
+                body[field.name] = raw.length === 16 ? raw + ':00' : raw;
+            } else {
+                body[field.name] = raw;
+            }
+        }
+        return {body: body, errors: errors};
+    }
+
+    function showFieldErrors(form, errors) {
+        form.querySelectorAll('.field-error').forEach(function (element) { element.textContent = ''; });
+        for (const [name, message] of Object.entries(errors)) {
+            const element = form.querySelector('[data-error-for="' + CSS.escape(name) + '"]');
+            if (element) element.textContent = message;
+            else showError(name + ' ' + message);
+        }
+    }
+
+    async function showForm(id) {
+        const creating = id == null;
+        let row = {};
+        if (!creating) {
+            try {
+                row = (await api(base + '/' + encodeURIComponent(id))).data;
+            } catch (e) {
+                showError(e.message);
+                return;
+            }
+        } else {
+            for (const field of model.fields) {
+                if (field.default != null) row[field.name] = field.widget === 'checkbox' ? ['true', '1', true].includes(field.default) : field.default;
+            }
+            // Coming from a related row's detail screen
+            for (const [name, value] of route().params) row[name] = value;
+        }
+        showError('');
+        view.innerHTML = '<p><a href="' + (creating ? '#/' : '#/' + encodeURIComponent(id)) + '">‹ Back</a></p>'
+            + '<div class="panel"><h2>' + (creating ? 'New ' + escapeHtml(model.model) : 'Edit ' + escapeHtml(model.model) + ' #' + escapeHtml(id)) + '</h2>'
+            + '<form class="edit" novalidate>' + model.fields.map(function (field) {
+                return '<label for="field-' + escapeHtml(field.name) + '"' + (field.required ? ' class="required"' : '') + '>'
+                    + escapeHtml(field.label) + '</label><div>' + inputFor(field, row[field.name])
+                    + '<div class="field-error" data-error-for="' + escapeHtml(field.name) + '"></div></div>';
+            }).join('')
+            + '<div class="actions"><button>' + (creating ? 'Create' : 'Save') + '</button>'
+            + '<a class="button secondary" href="' + (creating ? '#/' : '#/' + encodeURIComponent(id)) + '">Cancel</a></div></form></div>';
+
+        const form = view.querySelector('form');
+        for (const field of model.fields) {
+            if (field.widget !== 'relation') continue;
+            const select = form.elements[field.name];
+            const search = form.querySelector('[data-picker="' + CSS.escape(field.name) + '"]');
+            fillPicker(field, select, '');
+            let timer = null;
+            search.oninput = function () {
+                clearTimeout(timer);
+                timer = setTimeout(function () { fillPicker(field, select, search.value.trim()); }, 250);
+            };
+        }
+        form.onsubmit = async function (event) {
+            event.preventDefault();
+            const result = readForm(form, creating);
+            showFieldErrors(form, result.errors);
+            if (Object.keys(result.errors).length) return;
+            try {
+                const saved = await api(creating ? base : base + '/' + encodeURIComponent(id), {
+                    method: creating ? 'POST' : 'PATCH',
+                    headers: {'Content-Type': 'application/json'},
+                    body: JSON.stringify(result.body)
+                });
+                go(String(saved.data.id));
+            } catch (e) {
+                // The API checks the same schema and reports per field
+                const fieldErrors = {};
+                for (const error of e.fields || []) fieldErrors[error.field] = error.message;
+                showFieldErrors(form, fieldErrors);
+                showError(e.fields && e.fields.length ? '' : e.message);
+            }
+        };
+    }
+
+    function render() {
+        const current = route();
+        const [first, second] = current.parts;
+        if (!first) showList(current.params);
+        else if (first === 'new') showForm(null);
+        else if (second === 'edit') showForm(first);
+        else showDetail(first);
+    }
+
+    window.addEventListener('hashchange', render);
+    render();
+})();
+"""
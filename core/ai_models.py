@@ -0,0 +1,278 @@
+"""
+FlashFlow AI models - Running the ONNX models declared under 'ai_models:' in flows
+
+    ai_models:
+      churn:
+        type: onnx                      # the only supported type
+        path: models/churn.onnx         # relative to the project root
+        description: Chance a customer leaves this month
+        inputs:
+          features:                     # field of the JSON request body
+            tensor: input               # model input name (default: the field name)
+            dtype: float32              # float32, float64, int32, int64, bool or string
+            shape: [1, 4]               # null or -1 for any size
+        outputs:
+          probability:                  # field of the JSON response
+            tensor: output
+            shape: [1, 1]
+
+Request fields are nested lists matching the declared shape (a plain value
+for shape []). Without 'outputs' every model output is returned under its
+tensor name. Models with one float32 input and one fixed-shape output run on
+the FlashCore ONNX runtime when its bindings are built; everything else runs
+on onnxruntime with the project's 'inference' settings (see inference.py).
+predict(body, cancel=token) stops with Cancelled when the token is.
+"""
+
+import logging
+import time
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Dict, Any, List, Optional, Tuple
+
+from core.cancellation import CancelToken
+from core.flashcore_handles import FlashCoreError, onnx_runtime
+from core.inference import InferenceOptions, create_session, run_session
+from core.validation import FieldError, SchemaValidationError, validate_body
+
+logger = logging.getLogger(__name__)
+
+MODEL_TYPES = ('onnx',)
+# dtype -> (numpy type, JSON schema of one element)
+DTYPES = {
+    'float32': ('float32', {'type': 'number'}),
+    'float64': ('float64', {'type': 'number'}),
+    'int32': ('int32', {'type': 'integer'}),
+    'int64': ('int64', {'type': 'integer'}),
+    'bool': ('bool', {'type': 'boolean'}),
+    'string': ('object', {'type': 'string'})
+}
+
+class AiModelError(Exception):
+    """Raised for an invalid ai_models declaration or a model that cannot be loaded"""
+    pass
+
+@dataclass
+class TensorSpec:
+    """One request or response field and the model tensor behind it"""
+    field: str
+    tensor: str
+    dtype: str = 'float32'
+    shape: Optional[List[Optional[int]]] = None
+    description: str = ''
+
+    @classmethod
+    def from_dict(cls, where: str, field_name: str, data: Any) -> 'TensorSpec':
+        data = {} if data is None else data
+        if not isinstance(data, dict):
+            raise AiModelError(f"{where} must be a mapping (tensor, dtype, shape)")
+        dtype = str(data.get('dtype', 'float32')).lower()
+        if dtype not in DTYPES:
+            raise AiModelError(f"{where}.dtype must be one of {', '.join(DTYPES)}, got '{dtype}'")
+        shape = data.get('shape')
+        if shape is not None:
+            if not isinstance(shape, list) or not all(size is None or (isinstance(size, int) and size >= -1) for size in shape):
+                raise AiModelError(f"{where}.shape must be a list of sizes (null or -1 for any size)")
+            shape = [None if size in (None, -1) else size for size in shape]
+        return cls(field_name, str(data.get('tensor') or field_name), dtype, shape, str(data.get('description', '')))
+
+    def element_count(self) -> Optional[int]:
+        """Number of values in the tensor, when every dimension is fixed"""
+        if self.shape is None or None in self.shape:
+            return None
+        count = 1
+        for size in self.shape:
+            count *= size
+        return count
+
+    def schema(self) -> Dict[str, Any]:
+        """Nested arrays down to the declared rank; any depth when the shape is not declared"""
+        leaf = dict(DTYPES[self.dtype][1])
+        if self.shape is None:
+            return {'description': self.description or f"{self.dtype} tensor '{self.tensor}' as nested lists"}
+        schema = leaf
+        for size in reversed(self.shape):
+            schema = {'type': 'array', 'items': schema}
+            if size is not None:
+                schema.update(minItems=size, maxItems=size)
+        schema['description'] = self.description or f"{self.dtype} tensor '{self.tensor}', shape {self.shape_label()}"
+        return schema
+
+    def shape_label(self) -> str:
+        return '[' + ', '.join('?' if size is None else str(size) for size in self.shape or []) + ']'
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'field': self.field, 'tensor': self.tensor, 'dtype': self.dtype, 'shape': self.shape, 'description': self.description}
+
+@dataclass
+class ModelDeclaration:
+    """An 'ai_models:' entry from the flows"""
+    name: str
+    path: str
+    inputs: List[TensorSpec]
+    outputs: List[TensorSpec] = field(default_factory=list)
+    description: str = ''
+
+    @classmethod
+    def from_dict(cls, name: str, data: Any) -> 'ModelDeclaration':
+        where = f"ai_models.{name}"
+        if not isinstance(data, dict):
+            raise AiModelError(f"{where} must be a mapping with 'path' and 'inputs'")
+        kind = str(data.get('type', 'onnx')).lower()
+        if kind not in MODEL_TYPES:
+            raise AiModelError(f"{where}.type '{kind}' is not supported; use {', '.join(MODEL_TYPES)}")
+        if not isinstance(data.get('path'), str) or not data['path']:
+            raise AiModelError(f"{where}.path must be the .onnx file, relative to the project root")
+        inputs, outputs = data.get('inputs'), data.get('outputs') or {}
+        if not isinstance(inputs, dict) or not inputs:
+            raise AiModelError(f"{where}.inputs must map request fields to model inputs")
+        if not isinstance(outputs, dict):
+            raise AiModelError(f"{where}.outputs must map response fields to model outputs")
+        return cls(
+            name=name,
+            path=data['path'],
+            inputs=[TensorSpec.from_dict(f"{where}.inputs.{key}", str(key), value) for key, value in inputs.items()],
+            outputs=[TensorSpec.from_dict(f"{where}.outputs.{key}", str(key), value) for key, value in outputs.items()],
+            description=str(data.get('description', ''))
+        )
+
+    def request_schema(self) -> Dict[str, Any]:
+        return {
+            'type': 'object',
+            'properties': {spec.field: spec.schema() for spec in self.inputs},
+            'required': [spec.field for spec in self.inputs],
+            'additionalProperties': False
+        }
+
+    def response_schema(self) -> Dict[str, Any]:
+        outputs = {spec.field: spec.schema() for spec in self.outputs}
+        return {'type': 'object', 'properties': {
+            'model': {'type': 'string'},
+            'backend': {'type': 'string', 'enum': ['flashcore', 'onnxruntime']},
+            'took_ms': {'type': 'number'},
+            'outputs': {'type': 'object', 'properties': outputs, 'additionalProperties': not self.outputs}
+        }}
+
+    def prepare(self, body: Any) -> Dict[str, Any]:
+        """Validate a request body and turn it into the model's input tensors"""
+        import numpy as np
+        subject = f"input for model '{self.name}'"
+        validate_body(body, self.request_schema(), subject, strict=True)
+        feeds, errors = {}, []
+        for spec in self.inputs:
+            try:
+                array = np.asarray(body[spec.field], dtype=DTYPES[spec.dtype][0])
+            except (TypeError, ValueError):
+                errors.append(FieldError(spec.field, 'shape', "must be nested lists of equal length"))
+                continue
+            if spec.shape is not None and (array.ndim != len(spec.shape) or any(
+                    expected is not None and actual != expected for actual, expected in zip(array.shape, spec.shape))):
+                errors.append(FieldError(spec.field, 'shape', f"has shape {list(array.shape)}, expected {spec.shape_label()}"))
+                continue
+            feeds[spec.tensor] = array
+        if errors:
+            raise SchemaValidationError(subject, errors)
+        return feeds
+
+    def source_path(self, root_path: Path) -> Path:
+        path = Path(self.path).expanduser()
+        return path if path.is_absolute() else Path(root_path) / path
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {
+            'name': self.name,
+            'path': self.path,
+            'description': self.description,
+            'inputs': [spec.to_dict() for spec in self.inputs],
+            'outputs': [spec.to_dict() for spec in self.outputs],
+            'url': f"/api/ai/{self.name}/predict"
+        }
+
+def load_declarations(ai_models: Any) -> Tuple[Dict[str, ModelDeclaration], Dict[str, str]]:
+    """Valid declarations by name, and the error for each invalid one"""
+    declarations, errors = {}, {}
+    if not isinstance(ai_models, dict):
+        return declarations, ({'ai_models': "must map model names to declarations"} if ai_models else {})
+    for name, data in ai_models.items():
+        try:
+            declarations[str(name)] = ModelDeclaration.from_dict(str(name), data)
+        except AiModelError as e:
+            errors[str(name)] = str(e)
+    return declarations, errors
+
+class ModelRunner:
+    """A loaded model; FlashCore when the declaration fits its single-tensor API, onnxruntime otherwise"""
+
+    def __init__(self, declaration: ModelDeclaration, root_path: Path, options: InferenceOptions):
+        self.declaration = declaration
+        path = declaration.source_path(root_path)
+        if not path.exists():
+            raise AiModelError(f"Model file {path} for '{declaration.name}' does not exist")
+        self.path = path
+        self.mtime = path.stat().st_mtime
+        self.session = None
+        self.flashcore = self._flashcore_runtime(options)
+        if self.flashcore is not None:
+            self.backend, self.info = 'flashcore', {'active': options.preference()}
+        else:
+            self.backend = 'onnxruntime'
+            self.session, self.info = create_session(str(path), options)
+            self._check_tensor_names()
+
+    def _flashcore_runtime(self, options: InferenceOptions):
+        inputs, outputs = self.declaration.inputs, self.declaration.outputs
+        if not (len(inputs) == 1 and inputs[0].dtype == 'float32' and len(outputs) == 1
+                and outputs[0].dtype == 'float32' and outputs[0].element_count()):
+            return None
+        try:
+            return onnx_runtime(str(self.path), **options.binding_kwargs())
+        except ImportError:
+            return None
+        except FlashCoreError as e:
+            logger.warning(f"'{self.declaration.name}' falls back to onnxruntime: {str(e)}")
+            return None
+
+    def _check_tensor_names(self):
+        """Catch declarations naming tensors the model does not have, with the names it does have"""
+        for kind, specs, actual in (('input', self.declaration.inputs, self.session.get_inputs()),
+                                    ('output', self.declaration.outputs, self.session.get_outputs())):
+            names = [tensor.name for tensor in actual]
+            for spec in specs:
+                if spec.tensor not in names:
+                    raise AiModelError(f"'{self.declaration.name}' has no {kind} tensor '{spec.tensor}'; "
+                                       f"the model's {kind}s are: {', '.join(names)}")
+
+    def stale(self) -> bool:
+        try:
+            return self.path.stat().st_mtime != self.mtime
+        except OSError:
+            return True
+
+    def close(self):
+        """Release the FlashCore runtime; onnxruntime sessions go with the runner"""
+        if self.flashcore is not None:
+            self.flashcore.close()
+
+    def predict(self, body: Any, cancel: Optional[CancelToken] = None) -> Dict[str, Any]:
+        """Run the model on a request body; raises SchemaValidationError for bad input, Cancelled when cancel is"""
+        import numpy as np
+        feeds = self.declaration.prepare(body)
+        started = time.perf_counter()
+        if self.flashcore is not None:
+            spec = self.declaration.outputs[0]
+            flat = np.ascontiguousarray(next(iter(feeds.values())), dtype=np.float32).ravel()
+            result = np.asarray(self.flashcore.cancellable(cancel, 'run_inference', flat, spec.element_count()),
+                                dtype=np.float32)
+            outputs = {spec.field: result.reshape(spec.shape).tolist()}
+        else:
+            specs = self.declaration.outputs
+            names = [spec.tensor for spec in specs] or None
+            results = run_session(self.session, names, feeds, cancel)
+            fields = [spec.field for spec in specs] or [tensor.name for tensor in self.session.get_outputs()]
+            outputs = {name: np.asarray(value).tolist() for name, value in zip(fields, results)}
+        return {
+            'model': self.declaration.name,
+            'backend': self.backend,
+            'took_ms': round((time.perf_counter() - started) * 1000, 2),
+            'outputs': outputs
+        }
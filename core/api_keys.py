@@ -0,0 +1,335 @@
+"""
+FlashFlow API keys - Keys, request signatures and per-key rate limits for flow endpoints
+
+    endpoint:
+      path: /api/partners/orders
+      method: POST
+      auth: api_key                 # the key, or a request signed with it
+
+    endpoint:
+      path: /api/partners/payouts
+      method: POST
+      auth:
+        type: api_key
+        signed: true                # only signed requests
+        scopes: [payouts:write]     # the key must hold every listed scope
+
+Keys are created in /admin/api-keys and look like '<id>.<secret>'. Send the
+whole key:
+
+    X-API-Key: ffk_3f9a1c2b7d4e.Jx0...
+
+or keep the secret out of the request and sign it, the way webhooks.py
+signs deliveries:
+
+    X-API-Key-Id: ffk_3f9a1c2b7d4e
+    X-FlashFlow-Signature: t=<unix time>,v1=<hex HMAC-SHA256 of "<t>.<METHOD>.<path?query>." + body>
+
+A signature more than SIGNATURE_TOLERANCE seconds off, or one already
+used, is refused. Each key has a role and scopes, which 'permissions:' on
+the same endpoint checks, and its own limit of requests per minute.
+"""
+
+import hashlib
+import hmac
+import json
+import secrets
+import sqlite3
+import threading
+import time
+from collections import deque
+from dataclasses import dataclass, field
+from datetime import datetime
+from pathlib import Path
+from typing import Any, Deque, Dict, List, Optional, Tuple
+
+from core.permissions import ROLES, path_pattern
+
+KEY_PREFIX = 'ffk_'
+AUTH_TYPES = ('api_key',)
+SIGNATURE_TOLERANCE = 300
+DEFAULT_RATE_LIMIT = 60
+RATE_WINDOW = 60.0
+
+class ApiKeyError(ValueError):
+    """Raised for a malformed 'auth' declaration, key operation or request signature"""
+    pass
+
+@dataclass
+class ApiKeyRequirement:
+    """What a key needs to call an endpoint"""
+    signed: bool = False
+    scopes: List[str] = field(default_factory=list)
+
+    @classmethod
+    def from_definition(cls, definition: Any) -> 'ApiKeyRequirement':
+        if isinstance(definition, str):
+            definition = {'type': definition}
+        if not isinstance(definition, dict):
+            raise ApiKeyError("'auth' must be 'api_key' or a mapping with type, signed and scopes")
+
+        unknown = set(definition) - {'type', 'signed', 'scopes'}
+        if unknown:
+            raise ApiKeyError(f"Unknown auth key(s): {', '.join(sorted(unknown))}; use type, signed or scopes")
+        kind = str(definition.get('type', '')).lower()
+        if kind not in AUTH_TYPES:
+            raise ApiKeyError(f"Unknown auth type '{kind}'; use {', '.join(AUTH_TYPES)}")
+        if not isinstance(definition.get('signed', False), bool):
+            raise ApiKeyError("'signed' must be true or false")
+        scopes = definition.get('scopes') or []
+        if isinstance(scopes, str):
+            scopes = [scopes]
+        if not isinstance(scopes, list) or not all(isinstance(scope, str) for scope in scopes):
+            raise ApiKeyError("'scopes' must be a list of names")
+        return cls(definition.get('signed', False), list(scopes))
+
+    def denial(self, key: Dict[str, Any], signed: bool) -> Optional[str]:
+        """Why the key is refused, or None when it is allowed"""
+        if self.signed and not signed:
+            return "Requires a signed request (X-API-Key-Id and X-FlashFlow-Signature)"
+        missing = [scope for scope in self.scopes if not _key_has_scope(key, scope)]
+        if missing:
+            return f"API key is missing scope(s): {', '.join(missing)}"
+        return None
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'type': 'api_key', 'signed': self.signed, 'scopes': self.scopes}
+
+@dataclass
+class ApiKeyRule:
+    """An 'auth: api_key' requirement on an endpoint path"""
+    path: str
+    methods: List[str]
+    requirement: ApiKeyRequirement
+    source: str = ''
+
+    def __post_init__(self):
+        self._pattern = path_pattern(self.path)
+
+    def matches(self, method: str, path: str) -> bool:
+        if self.methods and method.upper() not in self.methods:
+            return False
+        return bool(self._pattern.match(path))
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'path': self.path, 'methods': self.methods, 'auth': self.requirement.to_dict(), 'source': self.source}
+
+def collect_api_key_rules(parsed_data: Dict[str, Any], source: str) -> Tuple[List[ApiKeyRule], List[str]]:
+    """Endpoints one parsed flow file puts behind API keys, plus declaration errors"""
+    rules, errors = [], []
+    if not isinstance(parsed_data, dict):
+        return rules, errors
+
+    endpoints = parsed_data.get('endpoint')
+    if isinstance(endpoints, dict):
+        endpoints = [endpoints]
+    for endpoint in endpoints if isinstance(endpoints, list) else []:
+        if not isinstance(endpoint, dict) or endpoint.get('auth') is None:
+            continue
+        method = str(endpoint.get('method', '')).upper()
+        try:
+            rules.append(ApiKeyRule(str(endpoint.get('path', '/')), [method] if method else [],
+                                    ApiKeyRequirement.from_definition(endpoint['auth']), source))
+        except ApiKeyError as e:
+            errors.append(f"{source}: endpoint {method} {endpoint.get('path', '?')}: {e}")
+    return rules, errors
+
+def _key_has_scope(key: Dict[str, Any], scope: str) -> bool:
+    return key['role'] == 'admin' or '*' in key['scopes'] or scope in key['scopes']
+
+def string_to_sign(timestamp: int, method: str, target: str, body: bytes) -> bytes:
+    """What a request signature covers; target is the path plus '?query' when there is one
+
+    A query that is not UTF-8 arrives decoded with surrogateescape and is signed as the bytes sent.
+    """
+    return f"{timestamp}.{method.upper()}.{target}.".encode('utf-8', 'surrogateescape') + (body or b'')
+
+def sign_request(secret: str, method: str, target: str, body: bytes = b'', timestamp: Optional[int] = None) -> str:
+    """The X-FlashFlow-Signature value for a request"""
+    timestamp = int(time.time()) if timestamp is None else timestamp
+    digest = hmac.new(secret.encode('utf-8'), string_to_sign(timestamp, method, target, body), hashlib.sha256).hexdigest()
+    return f"t={timestamp},v1={digest}"
+
+def parse_signature(header: str) -> Tuple[int, str]:
+    parts = dict(part.strip().partition('=')[::2] for part in (header or '').split(','))
+    try:
+        timestamp = int(parts.get('t', ''))
+    except ValueError:
+        raise ApiKeyError("X-FlashFlow-Signature must look like 't=<unix time>,v1=<hex digest>'")
+    if not parts.get('v1'):
+        raise ApiKeyError("X-FlashFlow-Signature must look like 't=<unix time>,v1=<hex digest>'")
+    return timestamp, parts['v1']
+
+class ReplayCache:
+    """Signatures seen within the tolerance window"""
+
+    def __init__(self, window: float = SIGNATURE_TOLERANCE):
+        self.window = window
+        self._seen: Dict[Tuple[str, str], float] = {}
+        self._lock = threading.Lock()
+
+    def check_and_add(self, key_id: str, digest: str, now: Optional[float] = None) -> bool:
+        """False when the signature was already used"""
+        now = time.time() if now is None else now
+        with self._lock:
+            for entry in [entry for entry, expires in self._seen.items() if expires < now]:
+                del self._seen[entry]
+            if (key_id, digest) in self._seen:
+                return False
+            # Covers a timestamp up to the tolerance in the future, too
+            self._seen[(key_id, digest)] = now + 2 * self.window
+            return True
+
+def verify_signature(secret: str, header: str, method: str, target: str, body: bytes,
+                     now: Optional[float] = None) -> Tuple[int, str]:
+    """The signature's timestamp and digest; raises ApiKeyError when it does not match or is stale"""
+    timestamp, digest = parse_signature(header)
+    now = time.time() if now is None else now
+    if abs(now - timestamp) > SIGNATURE_TOLERANCE:
+        raise ApiKeyError(f"Signature timestamp is more than {SIGNATURE_TOLERANCE}s from the server's clock")
+    expected = sign_request(secret, method, target, body, timestamp).partition(',v1=')[2]
+    # Headers can carry any byte; compare_digest only takes ASCII strings
+    if not hmac.compare_digest(expected.encode('utf-8'), digest.encode('utf-8')):
+        raise ApiKeyError("Signature does not match the request")
+    return timestamp, digest
+
+class RateLimiter:
+    """Requests per key over a sliding minute"""
+
+    def __init__(self, window: float = RATE_WINDOW):
+        self.window = window
+        self._hits: Dict[str, Deque[float]] = {}
+        self._lock = threading.Lock()
+
+    def hit(self, key_id: str, limit: int, now: Optional[float] = None) -> Tuple[bool, int, int]:
+        """(allowed, requests left in the window, seconds until the next one is allowed)"""
+        if limit <= 0:
+            return True, -1, 0
+        now = time.monotonic() if now is None else now
+        with self._lock:
+            hits = self._hits.setdefault(key_id, deque())
+            while hits and hits[0] <= now - self.window:
+                hits.popleft()
+            if len(hits) >= limit:
+                return False, 0, max(1, int(hits[0] + self.window - now + 0.999))
+            hits.append(now)
+            return True, limit - len(hits), 0
+
+    def forget(self, key_id: str):
+        with self._lock:
+            self._hits.pop(key_id, None)
+
+class ApiKeyStore:
+    """SQLite store of API keys; secrets are kept so signatures can be checked"""
+
+    def __init__(self, db_path: Path):
+        self.db_path = Path(db_path)
+        self.db_path.parent.mkdir(parents=True, exist_ok=True)
+        self._lock = threading.Lock()
+        self._init_schema()
+
+    def _connect(self) -> sqlite3.Connection:
+        conn = sqlite3.connect(str(self.db_path))
+        conn.row_factory = sqlite3.Row
+        return conn
+
+    def _init_schema(self):
+        with self._connect() as conn:
+            conn.execute("""
+                CREATE TABLE IF NOT EXISTS api_keys (
+                    id VARCHAR(32) PRIMARY KEY,
+                    name VARCHAR(255) NOT NULL,
+                    secret VARCHAR(64) NOT NULL,
+                    role VARCHAR(50) NOT NULL DEFAULT 'user',
+                    scopes TEXT NOT NULL DEFAULT '[]',
+                    rate_limit INTEGER NOT NULL DEFAULT 60,
+                    requests INTEGER NOT NULL DEFAULT 0,
+                    created_at DATETIME NOT NULL,
+                    last_used_at DATETIME,
+                    revoked_at DATETIME
+                )
+            """)
+            conn.commit()
+
+    @staticmethod
+    def _key_to_dict(row: sqlite3.Row) -> Dict[str, Any]:
+        return {
+            'id': row['id'],
+            'name': row['name'],
+            'role': row['role'],
+            'scopes': json.loads(row['scopes'] or '[]'),
+            'rate_limit': row['rate_limit'],
+            'requests': row['requests'],
+            'created_at': row['created_at'],
+            'last_used_at': row['last_used_at'],
+            'revoked_at': row['revoked_at'],
+            'status': 'revoked' if row['revoked_at'] else 'active'
+        }
+
+    def list_keys(self) -> List[Dict[str, Any]]:
+        with self._connect() as conn:
+            rows = conn.execute("SELECT * FROM api_keys ORDER BY created_at, id").fetchall()
+        return [self._key_to_dict(row) for row in rows]
+
+    def get_key(self, key_id: str) -> Optional[Dict[str, Any]]:
+        with self._connect() as conn:
+            row = conn.execute("SELECT * FROM api_keys WHERE id = ?", (key_id,)).fetchone()
+        return self._key_to_dict(row) if row else None
+
+    def create_key(self, name: str, role: str = 'user', scopes: Optional[List[str]] = None,
+                   rate_limit: int = DEFAULT_RATE_LIMIT) -> Tuple[Dict[str, Any], str]:
+        """The new key and its full '<id>.<secret>' value, which is not shown again"""
+        name = (name or '').strip()
+        if not name:
+            raise ApiKeyError("An API key needs a name")
+        if role not in ROLES:
+            raise ApiKeyError(f"Unknown role '{role}'. Expected one of: {', '.join(ROLES)}")
+        scopes = [scope.strip() for scope in scopes or [] if scope.strip()]
+        try:
+            rate_limit = int(rate_limit)
+        except (TypeError, ValueError):
+            raise ApiKeyError("rate_limit must be a number of requests per minute")
+        if rate_limit < 0:
+            raise ApiKeyError("rate_limit must be 0 (no limit) or more requests per minute")
+
+        key_id, secret = KEY_PREFIX + secrets.token_hex(6), secrets.token_urlsafe(32)
+        with self._lock, self._connect() as conn:
+            conn.execute(
+                "INSERT INTO api_keys (id, name, secret, role, scopes, rate_limit, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+                (key_id, name, secret, role, json.dumps(scopes), rate_limit, datetime.now().isoformat())
+            )
+            conn.commit()
+        return self.get_key(key_id), f"{key_id}.{secret}"
+
+    def revoke_key(self, key_id: str) -> Dict[str, Any]:
+        with self._lock, self._connect() as conn:
+            cursor = conn.execute("UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL",
+                                  (datetime.now().isoformat(), key_id))
+            conn.commit()
+        key = self.get_key(key_id)
+        if key is None:
+            raise ApiKeyError(f"API key {key_id} not found")
+        if cursor.rowcount == 0:
+            raise ApiKeyError(f"API key {key_id} is already revoked")
+        return key
+
+    def secret_for(self, key_id: str) -> Optional[str]:
+        """The secret of an active key"""
+        with self._connect() as conn:
+            row = conn.execute("SELECT secret FROM api_keys WHERE id = ? AND revoked_at IS NULL",
+                               (key_id,)).fetchone()
+        return row['secret'] if row else None
+
+    def authenticate(self, api_key: str) -> Optional[Dict[str, Any]]:
+        """The active key a full '<id>.<secret>' value belongs to"""
+        key_id, _, secret = (api_key or '').strip().partition('.')
+        expected = self.secret_for(key_id)
+        if expected is None or not hmac.compare_digest(expected.encode('utf-8'), secret.encode('utf-8')):
+            return None
+        return self.get_key(key_id)
+
+    def record_use(self, key_id: str):
+        with self._lock, self._connect() as conn:
+            conn.execute("UPDATE api_keys SET requests = requests + 1, last_used_at = ? WHERE id = ?",
+                         (datetime.now().isoformat(), key_id))
+            conn.commit()
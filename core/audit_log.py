@@ -0,0 +1,249 @@
+"""
+FlashFlow audit log - Append-only record of data writes and admin actions
+
+Each entry says who did what, when and to which row or object, with the
+values before and after:
+
+    actor       the user's email, an API key ('api_key:<id>'), a role acted as
+                ('editor (view-as)'), or X-FlashFlow-Actor for admin actions
+    kind        'data' for writes through /api/data, 'admin' for admin actions
+    action      'create', 'update', 'delete' for data; 'user.set_role', 'flag.override', ... for admin
+    entity      the table or the kind of object ('users', 'api_key', 'flow')
+    entity_id   the row id or the object's name
+    old / new   values before and after; fields named like secrets are redacted
+
+Entries live in an SQLite table that refuses UPDATE and DELETE. Every entry
+also stores a SHA-256 hash over its values and the previous entry's hash,
+so verify() finds an entry that was changed or removed behind the
+triggers' back, for example by editing the file directly.
+"""
+
+import csv
+import hashlib
+import io
+import json
+import sqlite3
+import threading
+from dataclasses import dataclass, field
+from datetime import datetime, timezone
+from pathlib import Path
+from typing import Any, Dict, Iterator, List, Optional, Tuple
+
+from core.crashes import redact
+
+KINDS = ('data', 'admin')
+EXPORT_FORMATS = ('csv', 'jsonl')
+MAX_LIMIT = 1000
+GENESIS_HASH = '0' * 64
+FILTERS = ('actor', 'kind', 'action', 'entity', 'entity_id')
+CSV_COLUMNS = ['id', 'created_at', 'actor', 'kind', 'action', 'entity', 'entity_id', 'changed', 'old', 'new', 'request', 'hash']
+
+class AuditLogError(Exception):
+    """Raised for invalid audit queries and exports"""
+    pass
+
+def changed_fields(old: Optional[Dict[str, Any]], new: Optional[Dict[str, Any]]) -> List[str]:
+    """Fields whose value differs between old and new, a field missing on one side included"""
+    old, new = old or {}, new or {}
+    return sorted(name for name in set(old) | set(new) if old.get(name) != new.get(name) or (name in old) != (name in new))
+
+@dataclass
+class AuditEntry:
+    """One recorded write or admin action"""
+    actor: str
+    kind: str
+    action: str
+    entity: str
+    entity_id: Optional[str] = None
+    old: Optional[Dict[str, Any]] = None
+    new: Optional[Dict[str, Any]] = None
+    request: Optional[str] = None
+    created_at: str = field(default_factory=lambda: datetime.now(timezone.utc).isoformat())
+    id: Optional[int] = None
+    hash: Optional[str] = None
+
+    @property
+    def changed(self) -> List[str]:
+        return changed_fields(self.old, self.new)
+
+    def digest(self, previous: str) -> str:
+        payload = json.dumps([previous, self.created_at, self.actor, self.kind, self.action, self.entity, self.entity_id,
+                              self.old, self.new, self.request], sort_keys=True, default=str)
+        return hashlib.sha256(payload.encode('utf-8')).hexdigest()
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {
+            'id': self.id, 'created_at': self.created_at, 'actor': self.actor, 'kind': self.kind, 'action': self.action,
+            'entity': self.entity, 'entity_id': self.entity_id, 'changed': self.changed, 'old': self.old, 'new': self.new,
+            'request': self.request, 'hash': self.hash,
+        }
+
+class AuditLog:
+    """The audit table in its own SQLite file, shared by every process of a dev server"""
+
+    def __init__(self, db_path: Path):
+        self.db_path = Path(db_path)
+        self.db_path.parent.mkdir(parents=True, exist_ok=True)
+        self._lock = threading.Lock()
+        self._init_schema()
+
+    def _connect(self) -> sqlite3.Connection:
+        # API workers append from their own processes; wait for their writes instead of failing
+        conn = sqlite3.connect(str(self.db_path), timeout=10, isolation_level=None)
+        conn.row_factory = sqlite3.Row
+        return conn
+
+    def _init_schema(self):
+        conn = self._connect()
+        try:
+            conn.executescript("""
+                CREATE TABLE IF NOT EXISTS audit_log (
+                    id INTEGER PRIMARY KEY AUTOINCREMENT,
+                    created_at TEXT NOT NULL,
+                    actor VARCHAR(255) NOT NULL,
+                    kind VARCHAR(10) NOT NULL,
+                    action VARCHAR(100) NOT NULL,
+                    entity VARCHAR(255) NOT NULL,
+                    entity_id VARCHAR(255),
+                    old_values TEXT,
+                    new_values TEXT,
+                    request TEXT,
+                    prev_hash CHAR(64) NOT NULL,
+                    hash CHAR(64) NOT NULL
+                );
+                CREATE INDEX IF NOT EXISTS audit_log_entity ON audit_log (entity, entity_id);
+                CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+                BEGIN SELECT RAISE(ABORT, 'audit_log is append-only'); END;
+                CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+                BEGIN SELECT RAISE(ABORT, 'audit_log is append-only'); END;
+            """)
+        finally:
+            conn.close()
+
+    def record(self, entry: AuditEntry) -> AuditEntry:
+        """Append an entry; its id and hash are set on it"""
+        if entry.kind not in KINDS:
+            raise AuditLogError(f"Unknown audit kind '{entry.kind}'; use {', '.join(KINDS)}")
+        # Stored as JSON; hashing what comes back from it lets verify() recompute the same hash
+        entry.old = _normalized(redact('', entry.old)) if entry.old is not None else None
+        entry.new = _normalized(redact('', entry.new)) if entry.new is not None else None
+        with self._lock:
+            conn = self._connect()
+            try:
+                # The write lock is taken before reading the last hash, so two processes cannot chain onto the same entry
+                conn.execute("BEGIN IMMEDIATE")
+                last = conn.execute("SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1").fetchone()
+                previous = last['hash'] if last else GENESIS_HASH
+                entry.hash = entry.digest(previous)
+                cursor = conn.execute(
+                    "INSERT INTO audit_log (created_at, actor, kind, action, entity, entity_id, old_values, new_values, "
+                    "request, prev_hash, hash) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+                    (entry.created_at, entry.actor, entry.kind, entry.action, entry.entity, entry.entity_id,
+                     _dump(entry.old), _dump(entry.new), entry.request, previous, entry.hash))
+                conn.execute("COMMIT")
+            except BaseException:
+                if conn.in_transaction:
+                    conn.execute("ROLLBACK")
+                raise
+            finally:
+                conn.close()
+        entry.id = cursor.lastrowid
+        return entry
+
+    def entries(self, limit: int = 100, before: Optional[int] = None, since: Optional[str] = None,
+                until: Optional[str] = None, **filters: Optional[str]) -> List[AuditEntry]:
+        """Newest entries first, matching every filter given (see FILTERS); since and until are ISO timestamps"""
+        if not 1 <= limit <= MAX_LIMIT:
+            raise AuditLogError(f"limit must be from 1 to {MAX_LIMIT}")
+        where, params = self._where(before, since, until, filters)
+        conn = self._connect()
+        try:
+            rows = conn.execute(f"SELECT * FROM audit_log{where} ORDER BY id DESC LIMIT ?", params + [limit]).fetchall()
+        finally:
+            conn.close()
+        return [_entry(row) for row in rows]
+
+    def iter_entries(self, since: Optional[str] = None, until: Optional[str] = None,
+                     **filters: Optional[str]) -> Iterator[AuditEntry]:
+        """Every matching entry, oldest first"""
+        where, params = self._where(None, since, until, filters)
+        conn = self._connect()
+        try:
+            for row in conn.execute(f"SELECT * FROM audit_log{where} ORDER BY id", params):
+                yield _entry(row)
+        finally:
+            conn.close()
+
+    def export(self, fmt: str, **query: Optional[str]) -> str:
+        """Matching entries, oldest first, as CSV or JSON lines"""
+        if fmt not in EXPORT_FORMATS:
+            raise AuditLogError(f"Unknown export format '{fmt}'; use {', '.join(EXPORT_FORMATS)}")
+        entries = self.iter_entries(**query)
+        if fmt == 'jsonl':
+            return ''.join(json.dumps(entry.to_dict(), default=str) + '\n' for entry in entries)
+        output = io.StringIO()
+        writer = csv.DictWriter(output, fieldnames=CSV_COLUMNS)
+        writer.writeheader()
+        for entry in entries:
+            row = entry.to_dict()
+            row.update(changed=' '.join(row['changed']), old=_dump(row['old']), new=_dump(row['new']))
+            writer.writerow(row)
+        return output.getvalue()
+
+    def verify(self) -> Tuple[int, Optional[str]]:
+        """(entries checked, the first problem found or None)"""
+        previous, checked = GENESIS_HASH, 0
+        conn = self._connect()
+        try:
+            for row in conn.execute("SELECT * FROM audit_log ORDER BY id"):
+                entry = _entry(row)
+                if row['prev_hash'] != previous:
+                    return checked, f"entry {entry.id} does not follow the one before it; an entry was removed"
+                if entry.digest(previous) != entry.hash:
+                    return checked, f"entry {entry.id} was changed after it was recorded"
+                previous, checked = entry.hash, checked + 1
+        finally:
+            conn.close()
+        return checked, None
+
+    @staticmethod
+    def _where(before: Optional[int], since: Optional[str], until: Optional[str],
+               filters: Dict[str, Optional[str]]) -> Tuple[str, List[Any]]:
+        unknown = sorted(set(filters) - set(FILTERS))
+        if unknown:
+            raise AuditLogError(f"Unknown audit filter(s) {', '.join(unknown)}; use {', '.join(FILTERS)}")
+        clauses, params = [], []
+        for name, value in filters.items():
+            if value:
+                clauses.append(f"{name} = ?")
+                params.append(str(value))
+        for name, value, operator in (('since', since, '>='), ('until', until, '<=')):
+            if value:
+                clauses.append(f"created_at {operator} ?")
+                params.append(_timestamp(name, value))
+        if before is not None:
+            clauses.append("id < ?")
+            params.append(int(before))
+        return (' WHERE ' + ' AND '.join(clauses) if clauses else ''), params
+
+def _timestamp(name: str, value: str) -> str:
+    """An ISO date or time as stored (UTC); a naive one is taken as UTC"""
+    try:
+        moment = datetime.fromisoformat(value.replace('Z', '+00:00'))
+    except ValueError:
+        raise AuditLogError(f"'{name}' must be an ISO date or time, e.g. 2026-01-31 or 2026-01-31T09:00:00Z")
+    if moment.tzinfo is None:
+        moment = moment.replace(tzinfo=timezone.utc)
+    return moment.astimezone(timezone.utc).isoformat()
+
+def _dump(values: Optional[Dict[str, Any]]) -> Optional[str]:
+    return None if values is None else json.dumps(values, sort_keys=True, default=str)
+
+def _normalized(values: Dict[str, Any]) -> Dict[str, Any]:
+    return json.loads(_dump(values))
+
+def _entry(row: sqlite3.Row) -> AuditEntry:
+    return AuditEntry(row['actor'], row['kind'], row['action'], row['entity'], row['entity_id'],
+                      json.loads(row['old_values']) if row['old_values'] else None,
+                      json.loads(row['new_values']) if row['new_values'] else None,
+                      row['request'], row['created_at'], row['id'], row['hash'])
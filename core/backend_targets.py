@@ -0,0 +1,779 @@
+"""
+FlashFlow backend targets - The language the generated API is written in
+
+flashflow.json picks it with frameworks.backend:
+
+    "frameworks": {"backend": "gin", ...}
+
+    laravel   PHP, the Laravel app from generators/backend (the default)
+    flask     Python, dist/backend/app.py: one Flask file over SQLite
+    gin       Go, dist/backend/main.go: a Gin server over SQLite
+
+'php', 'python' and 'go' name the same targets. Whatever the language, the
+generated API answers the dev server's /api/data routes (see
+cli/devserver/dev_crud.py) with the same bodies, so the frontends work the
+same against each, and 'flashflow serve --generated-backend' can put any of
+them behind the dev server. The Flask and Gin apps read their models from
+models.json, written next to them, listen on $HOST:$PORT and keep rows in
+$DATABASE_PATH.
+"""
+
+import json
+import re
+import sys
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+
+from core.database import FIELD_TYPES, model_field_list, table_name_for
+
+IDENTIFIER = re.compile(r'^[A-Za-z_][A-Za-z0-9_]*$')
+# Columns every table has; a model field with one of these names is left out
+RESERVED_COLUMNS = ('id', 'created_at', 'updated_at')
+OUTPUT_DIR = 'backend'
+MODELS_FILE = 'models.json'
+# gofmt lines up the values of the generated type map
+GO_KEY_WIDTH = max(len(name) for name in FIELD_TYPES['sqlite']) + 1
+
+class BackendTargetError(Exception):
+    """Raised for an unknown frameworks.backend or a backend that has not been generated"""
+    pass
+
+@dataclass(frozen=True)
+class BackendTarget:
+    """One frameworks.backend choice and how to run what it generates"""
+    name: str
+    language: str
+    # The file whose presence means the backend has been generated
+    entry: str
+    # '{host}', '{port}' and '{python}' are filled in by run_command
+    run: Tuple[str, ...]
+    # (marker, command): the command runs in the output folder while the marker is missing
+    prepare: Optional[Tuple[str, Tuple[str, ...]]] = None
+    # Files the scaffold writes; empty for targets with their own generator
+    files: Tuple[str, ...] = ()
+
+    @property
+    def label(self) -> str:
+        return f"{self.name} ({self.language})"
+
+    def run_command(self, host: str, port: int) -> List[str]:
+        return [part.format(host=host, port=port, python=sys.executable) for part in self.run]
+
+BACKENDS = {
+    'laravel': BackendTarget('laravel', 'php', 'artisan', ('php', 'artisan', 'serve', '--host={host}', '--port={port}'),
+                             prepare=('vendor', ('composer', 'install'))),
+    'flask': BackendTarget('flask', 'python', 'app.py', ('{python}', 'app.py'),
+                           files=('app.py', 'requirements.txt', MODELS_FILE)),
+    'gin': BackendTarget('gin', 'go', 'main.go', ('go', 'run', '.'), prepare=('go.sum', ('go', 'mod', 'tidy')),
+                         files=('main.go', 'go.mod', MODELS_FILE))
+}
+BACKEND_ALIASES = {'php': 'laravel', 'python': 'flask', 'go': 'gin'}
+
+def resolve_backend(name: Optional[str]) -> BackendTarget:
+    key = str(name or 'laravel').strip().lower()
+    key = BACKEND_ALIASES.get(key, key)
+    if key not in BACKENDS:
+        raise BackendTargetError(f"Unknown frameworks.backend '{name}'. Supported: {', '.join(BACKENDS)} "
+                                 f"(or {', '.join(BACKEND_ALIASES)})")
+    return BACKENDS[key]
+
+def backend_target(project) -> BackendTarget:
+    return resolve_backend((project.config.frameworks or {}).get('backend'))
+
+def backend_output(project) -> Path:
+    return project.dist_path / OUTPUT_DIR
+
+def backend_models(ir) -> List[Dict[str, Any]]:
+    """The flow models as models.json lists them: model, table and the fields that become columns"""
+    models = []
+    for name in sorted(ir.models):
+        data = ir.models[name]
+        fields = []
+        for model_field in model_field_list(data.get('fields')) if isinstance(data, dict) else []:
+            field_name = str(model_field['name'])
+            if field_name in RESERVED_COLUMNS or not IDENTIFIER.match(field_name):
+                continue
+            fields.append({'name': field_name, 'type': str(model_field.get('type', 'string')),
+                           'required': bool(model_field.get('required'))})
+        models.append({'model': name, 'table': table_name_for(name), 'fields': fields})
+    return models
+
+class BackendScaffold:
+    """Writes the Flask or Gin API into dist/backend"""
+
+    def __init__(self, project, target: BackendTarget):
+        if not target.files:
+            raise BackendTargetError(f"The {target.name} backend has its own generator")
+        self.project = project
+        self.target = target
+        self.output = backend_output(project)
+
+    def render(self, ir) -> Dict[str, str]:
+        models = {'name': self.project.config.name, 'backend': self.target.name, 'models': backend_models(ir)}
+        files = {MODELS_FILE: json.dumps(models, indent=2) + '\n'}
+        if self.target.name == 'flask':
+            files['app.py'] = FLASK_APP.replace('__NAME__', self.project.config.name)
+            files['requirements.txt'] = 'flask>=2.3\n'
+        else:
+            files['main.go'] = GIN_MAIN.replace('__NAME__', self.project.config.name)
+            files['go.mod'] = GIN_GO_MOD
+        return files
+
+    def generate(self, ir) -> List[str]:
+        """Write the files, dropping the other scaffold's; returns the written names"""
+        self.output.mkdir(parents=True, exist_ok=True)
+        for other in BACKENDS.values():
+            for name in set(other.files) - set(self.target.files):
+                stale = self.output / name
+                if stale.is_file():
+                    stale.unlink()
+        files = self.render(ir)
+        for name, content in files.items():
+            (self.output / name).write_text(content, encoding='utf-8')
+        return sorted(files)
+
+FLASK_APP = '''"""
+__NAME__ API - /api/data for the models in the flows
+
+Generated by 'flashflow build --target backend' (frameworks.backend: flask);
+a build overwrites this file. The models come from models.json.
+
+    pip install -r requirements.txt
+    PORT=8001 DATABASE_PATH=app.sqlite python app.py
+"""
+
+import json
+import math
+import os
+import sqlite3
+from pathlib import Path
+
+from flask import Flask, g, jsonify, request
+
+HERE = Path(__file__).resolve().parent
+SPEC = json.loads((HERE / 'models.json').read_text(encoding='utf-8'))
+MODELS = {model['table']: model for model in SPEC['models']}
+SQL_TYPES = ''' + repr(FIELD_TYPES['sqlite']) + '''
+DATABASE_PATH = os.environ.get('DATABASE_PATH') or str(HERE / 'database.sqlite')
+DEFAULT_PER_PAGE = 20
+MAX_PER_PAGE = 100
+
+app = Flask(__name__)
+
+def db():
+    if 'db' not in g:
+        g.db = sqlite3.connect(DATABASE_PATH)
+        g.db.row_factory = sqlite3.Row
+    return g.db
+
+@app.teardown_appcontext
+def close_db(_):
+    connection = g.pop('db', None)
+    if connection is not None:
+        connection.close()
+
+def create_tables():
+    connection = sqlite3.connect(DATABASE_PATH)
+    for table, model in MODELS.items():
+        columns = ['id INTEGER PRIMARY KEY AUTOINCREMENT']
+        columns += [f'"{field["name"]}" {SQL_TYPES.get(field["type"], "VARCHAR(255)")}' for field in model['fields']]
+        columns += ['created_at DATETIME DEFAULT CURRENT_TIMESTAMP', 'updated_at DATETIME DEFAULT CURRENT_TIMESTAMP']
+        connection.execute(f'CREATE TABLE IF NOT EXISTS "{table}" ({", ".join(columns)})')
+    connection.commit()
+    connection.close()
+
+def columns(model):
+    return ['id'] + [field['name'] for field in model['fields']] + ['created_at', 'updated_at']
+
+def to_json(model, row):
+    data = dict(row)
+    for field in model['fields']:
+        value = data.get(field['name'])
+        if value is None:
+            continue
+        if field['type'] == 'boolean':
+            data[field['name']] = bool(value)
+        elif field['type'] == 'json':
+            try:
+                data[field['name']] = json.loads(value)
+            except (TypeError, ValueError):
+                pass
+    return data
+
+def writable(model, body):
+    data = {}
+    for field in model['fields']:
+        if field['name'] in body:
+            value = body[field['name']]
+            data[field['name']] = json.dumps(value) if isinstance(value, (dict, list)) else value
+    return data
+
+def find(table, row_id):
+    row = db().execute(f'SELECT * FROM "{table}" WHERE id = ?', (row_id,)).fetchone()
+    return None if row is None else to_json(MODELS[table], row)
+
+def model_or_404(table):
+    if table not in MODELS:
+        return None, (jsonify({'error': f"Table '{table}' not found; no model in the flows maps to it"}), 404)
+    return MODELS[table], None
+
+def json_body():
+    body = request.get_json(silent=True)
+    if body is None and not request.get_data():
+        body = {}
+    if not isinstance(body, dict):
+        return None, (jsonify({'error': 'The body must be a JSON object'}), 400)
+    return body, None
+
+@app.route('/health')
+def health():
+    return jsonify({'status': 'ok', 'backend': 'flask'})
+
+@app.route('/api/data')
+def index():
+    return jsonify({'driver': 'sqlite', 'models': [{'model': model['model'], 'table': table, 'url': f"/api/data/{table}"}
+                                                  for table, model in sorted(MODELS.items())]})
+
+@app.route('/api/data/<table>', methods=['GET'])
+def list_rows(table):
+    model, error = model_or_404(table)
+    if error:
+        return error
+    names = columns(model)
+    try:
+        page = max(1, int(request.args.get('page', 1)))
+        per_page = min(MAX_PER_PAGE, max(1, int(request.args.get('per_page', DEFAULT_PER_PAGE))))
+    except ValueError:
+        return jsonify({'error': 'page and per_page must be whole numbers'}), 400
+    sort = request.args.get('sort', 'id')
+    if sort.lstrip('-') not in names:
+        return jsonify({'error': f"Cannot sort by '{sort.lstrip('-')}'; use one of: {', '.join(names)}"}), 400
+    filters = [(name, value) for name, value in request.args.items() if name in names]
+    where = ' AND '.join(f'"{name}" = ?' for name, _ in filters)
+    where = f' WHERE {where}' if where else ''
+    params = tuple(value for _, value in filters)
+    total = db().execute(f'SELECT COUNT(*) FROM "{table}"{where}', params).fetchone()[0]
+    order = f'"{sort.lstrip("-")}" {"DESC" if sort.startswith("-") else "ASC"}'
+    rows = db().execute(f'SELECT * FROM "{table}"{where} ORDER BY {order} LIMIT ? OFFSET ?',
+                        params + (per_page, (page - 1) * per_page)).fetchall()
+    response = jsonify({'data': [to_json(model, row) for row in rows], 'total': total, 'page': page,
+                        'per_page': per_page, 'pages': math.ceil(total / per_page)})
+    response.headers['X-Total-Count'] = str(total)
+    return response
+
+@app.route('/api/data/<table>', methods=['POST'])
+def create_row(table):
+    model, error = model_or_404(table)
+    if error:
+        return error
+    body, error = json_body()
+    if error:
+        return error
+    missing = [field['name'] for field in model['fields'] if field['required'] and body.get(field['name']) is None]
+    if missing:
+        return jsonify({'error': f"Missing required field(s): {', '.join(missing)}"}), 400
+    data = writable(model, body)
+    if data:
+        names = ', '.join(f'"{name}"' for name in data)
+        cursor = db().execute(f'INSERT INTO "{table}" ({names}) VALUES ({", ".join("?" for _ in data)})',
+                              tuple(data.values()))
+    else:
+        cursor = db().execute(f'INSERT INTO "{table}" DEFAULT VALUES')
+    db().commit()
+    return jsonify({'data': find(table, cursor.lastrowid)}), 201
+
+@app.route('/api/data/<table>/<int:row_id>', methods=['GET'])
+def show_row(table, row_id):
+    _, error = model_or_404(table)
+    if error:
+        return error
+    row = find(table, row_id)
+    if row is None:
+        return jsonify({'error': f"Row {row_id} not found in '{table}'"}), 404
+    return jsonify({'data': row})
+
+@app.route('/api/data/<table>/<int:row_id>', methods=['PUT', 'PATCH'])
+def update_row(table, row_id):
+    model, error = model_or_404(table)
+    if error:
+        return error
+    body, error = json_body()
+    if error:
+        return error
+    if find(table, row_id) is None:
+        return jsonify({'error': f"Row {row_id} not found in '{table}'"}), 404
+    data = writable(model, body)
+    assignments = ''.join(f'"{name}" = ?, ' for name in data)
+    db().execute(f'UPDATE "{table}" SET {assignments}updated_at = CURRENT_TIMESTAMP WHERE id = ?',
+                 tuple(data.values()) + (row_id,))
+    db().commit()
+    return jsonify({'data': find(table, row_id)})
+
+@app.route('/api/data/<table>/<int:row_id>', methods=['DELETE'])
+def delete_row(table, row_id):
+    _, error = model_or_404(table)
+    if error:
+        return error
+    if db().execute(f'DELETE FROM "{table}" WHERE id = ?', (row_id,)).rowcount == 0:
+        return jsonify({'error': f"Row {row_id} not found in '{table}'"}), 404
+    db().commit()
+    return '', 204
+
+create_tables()
+
+if __name__ == '__main__':
+    app.run(host=os.environ.get('HOST', '127.0.0.1'), port=int(os.environ.get('PORT', 8000)))
+'''
+
+GIN_GO_MOD = '''module flashflow-backend
+
+go 1.21
+
+require (
+	github.com/gin-gonic/gin v1.9.1
+	modernc.org/sqlite v1.29.5
+)
+'''
+
+GIN_MAIN = '''// __NAME__ API - /api/data for the models in the flows
+//
+// Generated by 'flashflow build --target backend' (frameworks.backend: gin);
+// a build overwrites this file. The models come from models.json.
+//
+//	go mod tidy
+//	PORT=8001 DATABASE_PATH=app.sqlite go run .
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	_ "modernc.org/sqlite"
+)
+
+type Field struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Required bool   `json:"required"`
+}
+
+type Model struct {
+	Model  string  `json:"model"`
+	Table  string  `json:"table"`
+	Fields []Field `json:"fields"`
+}
+
+type Spec struct {
+	Name   string  `json:"name"`
+	Models []Model `json:"models"`
+}
+
+var sqlTypes = map[string]string{
+''' + ''.join(f'\t"{name}":{" " * (GO_KEY_WIDTH - len(name))}"{sql}",\n' for name, sql in FIELD_TYPES['sqlite'].items()) + '''}
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+type api struct {
+	db     *sql.DB
+	models map[string]Model
+}
+
+func main() {
+	data, err := os.ReadFile("models.json")
+	if err != nil {
+		log.Fatalf("reading models.json: %v", err)
+	}
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		log.Fatalf("parsing models.json: %v", err)
+	}
+	path := os.Getenv("DATABASE_PATH")
+	if path == "" {
+		path = "database.sqlite"
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		log.Fatalf("opening %s: %v", path, err)
+	}
+	// SQLite takes one writer at a time
+	db.SetMaxOpenConns(1)
+
+	a := &api{db: db, models: map[string]Model{}}
+	for _, model := range spec.Models {
+		a.models[model.Table] = model
+		if err := a.createTable(model); err != nil {
+			log.Fatalf("creating %s: %v", model.Table, err)
+		}
+	}
+
+	r := gin.Default()
+	r.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok", "backend": "gin"})
+	})
+	r.GET("/api/data", a.index)
+	r.GET("/api/data/:table", a.list)
+	r.POST("/api/data/:table", a.create)
+	r.GET("/api/data/:table/:id", a.show)
+	r.PUT("/api/data/:table/:id", a.update)
+	r.PATCH("/api/data/:table/:id", a.update)
+	r.DELETE("/api/data/:table/:id", a.remove)
+
+	host, port := os.Getenv("HOST"), os.Getenv("PORT")
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if port == "" {
+		port = "8000"
+	}
+	log.Fatal(r.Run(host + ":" + port))
+}
+
+func (a *api) createTable(model Model) error {
+	columns := []string{"id INTEGER PRIMARY KEY AUTOINCREMENT"}
+	for _, field := range model.Fields {
+		sqlType, ok := sqlTypes[field.Type]
+		if !ok {
+			sqlType = "VARCHAR(255)"
+		}
+		columns = append(columns, fmt.Sprintf("%q %s", field.Name, sqlType))
+	}
+	columns = append(columns, "created_at DATETIME DEFAULT CURRENT_TIMESTAMP", "updated_at DATETIME DEFAULT CURRENT_TIMESTAMP")
+	_, err := a.db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %q (%s)", model.Table, strings.Join(columns, ", ")))
+	return err
+}
+
+func columnNames(model Model) []string {
+	names := []string{"id"}
+	for _, field := range model.Fields {
+		names = append(names, field.Name)
+	}
+	return append(names, "created_at", "updated_at")
+}
+
+func contains(names []string, name string) bool {
+	for _, candidate := range names {
+		if candidate == name {
+			return true
+		}
+	}
+	return false
+}
+
+func fail(c *gin.Context, status int, message string) {
+	c.JSON(status, gin.H{"error": message})
+}
+
+func (a *api) model(c *gin.Context) (Model, bool) {
+	table := c.Param("table")
+	model, ok := a.models[table]
+	if !ok {
+		fail(c, http.StatusNotFound, fmt.Sprintf("Table '%s' not found; no model in the flows maps to it", table))
+	}
+	return model, ok
+}
+
+func rowID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		fail(c, http.StatusNotFound, "Not found")
+		return 0, false
+	}
+	return id, true
+}
+
+func body(c *gin.Context) (map[string]any, bool) {
+	raw, err := c.GetRawData()
+	data := map[string]any{}
+	if err == nil && len(raw) > 0 {
+		err = json.Unmarshal(raw, &data)
+	}
+	if err != nil {
+		fail(c, http.StatusBadRequest, "The body must be a JSON object")
+		return nil, false
+	}
+	return data, true
+}
+
+// writable keeps the model's fields, with JSON values stored as text
+func writable(model Model, data map[string]any) ([]string, []any) {
+	var names []string
+	var values []any
+	for _, field := range model.Fields {
+		value, ok := data[field.Name]
+		if !ok {
+			continue
+		}
+		switch value.(type) {
+		case map[string]any, []any:
+			encoded, _ := json.Marshal(value)
+			value = string(encoded)
+		}
+		names = append(names, field.Name)
+		values = append(values, value)
+	}
+	return names, values
+}
+
+func scanRows(rows *sql.Rows, model Model) ([]map[string]any, error) {
+	types := map[string]string{}
+	for _, field := range model.Fields {
+		types[field.Name] = field.Type
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := []map[string]any{}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := map[string]any{}
+		for i, name := range columns {
+			value := values[i]
+			if raw, ok := value.([]byte); ok {
+				value = string(raw)
+			}
+			switch types[name] {
+			case "boolean":
+				if number, ok := value.(int64); ok {
+					value = number != 0
+				}
+			case "json":
+				if text, ok := value.(string); ok {
+					var decoded any
+					if json.Unmarshal([]byte(text), &decoded) == nil {
+						value = decoded
+					}
+				}
+			}
+			row[name] = value
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+func (a *api) find(model Model, id int64) (map[string]any, error) {
+	rows, err := a.db.Query(fmt.Sprintf("SELECT * FROM %q WHERE id = ?", model.Table), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	found, err := scanRows(rows, model)
+	if err != nil || len(found) == 0 {
+		return nil, err
+	}
+	return found[0], nil
+}
+
+func (a *api) index(c *gin.Context) {
+	tables := make([]string, 0, len(a.models))
+	for table := range a.models {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	models := []gin.H{}
+	for _, table := range tables {
+		models = append(models, gin.H{"model": a.models[table].Model, "table": table, "url": "/api/data/" + table})
+	}
+	c.JSON(http.StatusOK, gin.H{"driver": "sqlite", "models": models})
+}
+
+func (a *api) list(c *gin.Context) {
+	model, ok := a.model(c)
+	if !ok {
+		return
+	}
+	names := columnNames(model)
+	page, err1 := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, err2 := strconv.Atoi(c.DefaultQuery("per_page", strconv.Itoa(defaultPerPage)))
+	if err1 != nil || err2 != nil {
+		fail(c, http.StatusBadRequest, "page and per_page must be whole numbers")
+		return
+	}
+	page = max(page, 1)
+	perPage = min(max(perPage, 1), maxPerPage)
+	order := c.DefaultQuery("sort", "id")
+	column := strings.TrimPrefix(order, "-")
+	if !contains(names, column) {
+		fail(c, http.StatusBadRequest, fmt.Sprintf("Cannot sort by '%s'; use one of: %s", column, strings.Join(names, ", ")))
+		return
+	}
+	var conditions []string
+	var params []any
+	for name, values := range c.Request.URL.Query() {
+		if contains(names, name) {
+			conditions = append(conditions, fmt.Sprintf("%q = ?", name))
+			params = append(params, values[0])
+		}
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+	var total int
+	if err := a.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %q%s", model.Table, where), params...).Scan(&total); err != nil {
+		fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	direction := "ASC"
+	if strings.HasPrefix(order, "-") {
+		direction = "DESC"
+	}
+	query := fmt.Sprintf("SELECT * FROM %q%s ORDER BY %q %s LIMIT ? OFFSET ?", model.Table, where, column, direction)
+	rows, err := a.db.Query(query, append(params, perPage, (page-1)*perPage)...)
+	if err != nil {
+		fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+	data, err := scanRows(rows, model)
+	if err != nil {
+		fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.JSON(http.StatusOK, gin.H{"data": data, "total": total, "page": page, "per_page": perPage,
+		"pages": int(math.Ceil(float64(total) / float64(perPage)))})
+}
+
+func (a *api) create(c *gin.Context) {
+	model, ok := a.model(c)
+	if !ok {
+		return
+	}
+	data, ok := body(c)
+	if !ok {
+		return
+	}
+	var missing []string
+	for _, field := range model.Fields {
+		if field.Required && data[field.Name] == nil {
+			missing = append(missing, field.Name)
+		}
+	}
+	if len(missing) > 0 {
+		fail(c, http.StatusBadRequest, "Missing required field(s): "+strings.Join(missing, ", "))
+		return
+	}
+	names, values := writable(model, data)
+	query := fmt.Sprintf("INSERT INTO %q DEFAULT VALUES", model.Table)
+	if len(names) > 0 {
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			quoted[i] = fmt.Sprintf("%q", name)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ")
+		query = fmt.Sprintf("INSERT INTO %q (%s) VALUES (%s)", model.Table, strings.Join(quoted, ", "), placeholders)
+	}
+	result, err := a.db.Exec(query, values...)
+	if err != nil {
+		fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	id, _ := result.LastInsertId()
+	row, err := a.find(model, id)
+	if err != nil {
+		fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": row})
+}
+
+func (a *api) show(c *gin.Context) {
+	model, ok := a.model(c)
+	if !ok {
+		return
+	}
+	id, ok := rowID(c)
+	if !ok {
+		return
+	}
+	row, err := a.find(model, id)
+	if err != nil {
+		fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if row == nil {
+		fail(c, http.StatusNotFound, fmt.Sprintf("Row %d not found in '%s'", id, model.Table))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": row})
+}
+
+func (a *api) update(c *gin.Context) {
+	model, ok := a.model(c)
+	if !ok {
+		return
+	}
+	id, ok := rowID(c)
+	if !ok {
+		return
+	}
+	data, ok := body(c)
+	if !ok {
+		return
+	}
+	names, values := writable(model, data)
+	assignments := ""
+	for _, name := range names {
+		assignments += fmt.Sprintf("%q = ?, ", name)
+	}
+	query := fmt.Sprintf("UPDATE %q SET %supdated_at = CURRENT_TIMESTAMP WHERE id = ?", model.Table, assignments)
+	result, err := a.db.Exec(query, append(values, id)...)
+	if err != nil {
+		fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if changed, _ := result.RowsAffected(); changed == 0 {
+		fail(c, http.StatusNotFound, fmt.Sprintf("Row %d not found in '%s'", id, model.Table))
+		return
+	}
+	row, err := a.find(model, id)
+	if err != nil {
+		fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": row})
+}
+
+func (a *api) remove(c *gin.Context) {
+	model, ok := a.model(c)
+	if !ok {
+		return
+	}
+	id, ok := rowID(c)
+	if !ok {
+		return
+	}
+	result, err := a.db.Exec(fmt.Sprintf("DELETE FROM %q WHERE id = ?", model.Table), id)
+	if err != nil {
+		fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		fail(c, http.StatusNotFound, fmt.Sprintf("Row %d not found in '%s'", id, model.Table))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+'''
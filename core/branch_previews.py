@@ -0,0 +1,247 @@
+"""
+FlashFlow branch previews - Static builds of several git branches, side by side
+
+A branch preview is the branch's flow pages exported the way 'flashflow build
+-t static' exports them, into dist/branches/<slug>/. The working tree is never
+touched: the branch's files are taken with 'git archive' into a scratch
+directory, so any number of branches can be built while you keep working.
+The dev server serves each one at /branch/<slug>/ and lists them at /branch/.
+
+Previews that are no longer useful are removed by clean(): those of deleted
+branches, those not built or opened for max_age_days, and the least recently
+used beyond max_branches:
+
+    "branch_previews": {"max_age_days": 14, "max_branches": 10}
+
+What was built from which commit is recorded in .flashflow/branches.json.
+"""
+
+import hashlib
+import io
+import json
+import os
+import re
+import shutil
+import subprocess
+import tarfile
+import threading
+import time
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+
+from core.framework import FlashFlowProject
+from core.parser.parser import FlowParser
+from core.static_site import StaticSiteExporter
+
+RECORD_NAME = 'branches.json'
+DEFAULT_MAX_AGE_DAYS = 14
+DEFAULT_MAX_BRANCHES = 10
+# Opening a preview refreshes its last_viewed at most this often, to keep page views from rewriting the record
+VIEW_RESOLUTION = 60
+
+class BranchPreviewError(Exception):
+    """Raised when a branch cannot be found or built"""
+    pass
+
+def branch_slug(branch: str) -> str:
+    """A URL- and file-safe name; branches like 'feature/login' get a hash suffix so slugs never collide"""
+    slug = re.sub(r'[^A-Za-z0-9._-]+', '-', branch).strip('-.') or 'branch'
+    if slug != branch:
+        slug += '-' + hashlib.sha1(branch.encode('utf-8')).hexdigest()[:6]
+    return slug
+
+class BranchPreviews:
+    """Builds, records and cleans up the branch previews of one project"""
+
+    def __init__(self, project: FlashFlowProject):
+        self.project = project
+        self.output_root = project.dist_path / 'branches'
+        settings = getattr(project.config, 'branch_previews', None) or {}
+        self.max_age = float(settings.get('max_age_days', DEFAULT_MAX_AGE_DAYS)) * 86400
+        self.max_branches = int(settings.get('max_branches', DEFAULT_MAX_BRANCHES))
+        self._lock = threading.RLock()
+
+    # Git
+
+    def git(self, *args: str, binary: bool = False, cwd: Optional[Path] = None):
+        try:
+            result = subprocess.run(['git', '-C', str(cwd or self.project.root_path), *args], stdout=subprocess.PIPE,
+                                    stderr=subprocess.PIPE, env=dict(os.environ, GIT_TERMINAL_PROMPT='0'))
+        except FileNotFoundError:
+            raise BranchPreviewError("git is not installed; branch previews need it")
+        if result.returncode != 0:
+            message = result.stderr.decode('utf-8', 'replace').strip() or f"exit code {result.returncode}"
+            raise BranchPreviewError(f"git {args[0]} failed: {message}")
+        return result.stdout if binary else result.stdout.decode('utf-8', 'replace')
+
+    def branches(self) -> Dict[str, str]:
+        """Local branches and the commit each points at"""
+        found = {}
+        for line in self.git('for-each-ref', '--format=%(refname:short) %(objectname)', 'refs/heads').splitlines():
+            name, _, commit = line.rpartition(' ')
+            if name:
+                found[name] = commit
+        return found
+
+    def current_branch(self) -> Optional[str]:
+        try:
+            name = self.git('rev-parse', '--abbrev-ref', 'HEAD').strip()
+        except BranchPreviewError:
+            return None
+        return None if name == 'HEAD' else name
+
+    def head(self, branch: str) -> str:
+        commit = self.branches().get(branch)
+        if commit is None:
+            raise BranchPreviewError(f"No local branch '{branch}'")
+        return commit
+
+    # Records
+
+    def records(self) -> Dict[str, Dict[str, Any]]:
+        """Previews by slug; a build whose process is gone is reported as interrupted"""
+        try:
+            records = json.loads((self.project.state.dir / RECORD_NAME).read_text())
+        except (OSError, json.JSONDecodeError):
+            return {}
+        for record in records.values():
+            if record.get('status') == 'building' and not _process_running(record.get('pid')):
+                record.update(status='failed', error="The build was interrupted")
+        return records
+
+    def _save(self, records: Dict[str, Dict[str, Any]]):
+        path = self.project.state.path(RECORD_NAME)
+        temporary = path.with_suffix('.tmp')
+        temporary.write_text(json.dumps(records, indent=2))
+        temporary.replace(path)
+
+    def _update(self, slug: str, **changes) -> Dict[str, Any]:
+        with self._lock:
+            records = self.records()
+            record = records.setdefault(slug, {'slug': slug})
+            record.update(changes)
+            self._save(records)
+            return dict(record)
+
+    def reset_interrupted(self):
+        """Fail builds this process had started; for a dev server that re-executed itself under the same pid"""
+        with self._lock:
+            records = self.records()
+            for record in records.values():
+                if record.get('status') == 'building' and record.get('pid') == os.getpid():
+                    record.update(status='failed', error="The build was interrupted", pid=None)
+            self._save(records)
+
+    def get(self, slug: str) -> Optional[Dict[str, Any]]:
+        return self.records().get(slug)
+
+    def touch(self, slug: str):
+        """Note that someone opened the preview, which keeps it from going stale"""
+        record = self.get(slug)
+        if record and time.time() - record.get('last_viewed', 0) > VIEW_RESOLUTION:
+            self._update(slug, last_viewed=time.time())
+
+    # Building
+
+    def build(self, branch: str) -> Dict[str, Any]:
+        """Export the branch's pages to dist/branches/<slug>/; the record says whether it worked"""
+        commit = self.head(branch)
+        slug = branch_slug(branch)
+        now = time.time()
+        previous = self.get(slug) or {}
+        self._update(slug, branch=branch, status='building', commit=commit, pid=os.getpid(), started_at=now,
+                     last_viewed=previous.get('last_viewed', now), error=None)
+        scratch = self.project.state.path('cache', 'branches', slug)
+        staging = self.output_root / f".{slug}.tmp"
+        try:
+            shutil.rmtree(scratch, ignore_errors=True)
+            self._checkout(commit, scratch)
+            branch_project = FlashFlowProject(scratch)
+            if not branch_project.exists():
+                raise BranchPreviewError(f"'{branch}' has no flashflow.json for this project")
+            report = StaticSiteExporter(branch_project, FlowParser().parse_project(scratch), staging).export()
+            target = self.output_root / slug
+            with self._lock:
+                shutil.rmtree(target, ignore_errors=True)
+                staging.replace(target)
+            return self._update(slug, status='ready', built_at=time.time(), seconds=round(time.time() - now, 2),
+                                pages=report.pages, skipped=[route for route, _ in report.skipped],
+                                warnings=report.warnings, pid=None)
+        except Exception as e:
+            shutil.rmtree(staging, ignore_errors=True)
+            return self._update(slug, status='failed', error=str(e), pid=None)
+        finally:
+            shutil.rmtree(scratch, ignore_errors=True)
+
+    def _checkout(self, commit: str, target: Path):
+        """The project's files at a commit, from wherever the project sits in the repository"""
+        prefix = self.git('rev-parse', '--show-prefix').strip().rstrip('/')
+        # From the top level, since 'git archive' in a subdirectory only takes that subdirectory of the tree
+        top_level = Path(self.git('rev-parse', '--show-toplevel').strip())
+        archive = self.git('archive', '--format=tar', f"{commit}:{prefix}" if prefix else commit, binary=True, cwd=top_level)
+        target.mkdir(parents=True, exist_ok=True)
+        with tarfile.open(fileobj=io.BytesIO(archive)) as tar:
+            if hasattr(tarfile, 'data_filter'):
+                tar.extractall(target, filter='data')
+            else:
+                tar.extractall(target)
+
+    # Cleanup
+
+    def stale(self) -> List[Tuple[str, str]]:
+        """(slug, reason) for every preview clean() would remove"""
+        records = self.records()
+        branches = self.branches()
+        now = time.time()
+        found = []
+        for slug, record in records.items():
+            if record.get('status') == 'building':
+                continue
+            if record.get('branch') not in branches:
+                found.append((slug, f"branch '{record.get('branch')}' was deleted"))
+            elif now - max(record.get('last_viewed', 0), record.get('built_at', 0)) > self.max_age:
+                found.append((slug, f"not built or opened for {self.max_age / 86400:g} days"))
+        removed = {slug for slug, _ in found}
+        remaining = sorted((record for slug, record in records.items() if slug not in removed),
+                           key=lambda record: max(record.get('last_viewed', 0), record.get('built_at', 0)), reverse=True)
+        for record in remaining[self.max_branches:]:
+            if record.get('status') != 'building':
+                found.append((record['slug'], f"more than {self.max_branches} previews; least recently used"))
+        return found
+
+    def remove(self, slug: str) -> bool:
+        with self._lock:
+            records = self.records()
+            removed = records.pop(slug, None) is not None
+            self._save(records)
+        target = self.output_root / slug
+        if target.exists():
+            shutil.rmtree(target)
+            removed = True
+        return removed
+
+    def clean(self) -> List[Tuple[str, str]]:
+        """Remove stale previews, and output directories no record knows about"""
+        removed = self.stale()
+        for slug, _ in removed:
+            self.remove(slug)
+        known = set(self.records())
+        if self.output_root.is_dir():
+            for directory in self.output_root.iterdir():
+                if directory.is_dir() and directory.name not in known and not directory.name.startswith('.'):
+                    shutil.rmtree(directory)
+                    removed.append((directory.name, "no record of it"))
+        return removed
+
+def _process_running(pid: Optional[int]) -> bool:
+    if not pid:
+        return False
+    if pid == os.getpid():
+        return True
+    try:
+        os.kill(pid, 0)
+    except ProcessLookupError:
+        return False
+    except (PermissionError, OSError):
+        return True
+    return True
@@ -0,0 +1,332 @@
+"""
+FlashFlow build cache - Share generated output between machines
+
+With a "build_cache" block in flashflow.json, every build step's output is
+stored under a key made from what the step reads (the flow files, the
+files they can include and src/content, flashflow.json, the assets for media and static
+steps, the target and profile, and the FlashFlow generators themselves).
+A later build anywhere with the same inputs downloads the output instead of
+generating it:
+
+    "build_cache": {"url": "https://cache.example.com/flashflow", "headers": {"Authorization": "Bearer ${CACHE_TOKEN}"}}
+    "build_cache": {"url": "s3://ci-cache/flashflow", "region": "eu-west-1"}
+    "build_cache": {"url": "s3://cache/flashflow", "endpoint": "https://minio.internal:9000", "push": false}
+
+HTTP caches get GET and PUT on <url>/<key>.tar.gz, which is what nginx
+with WebDAV, bazel-remote and most artifact servers accept; a 404 is a
+miss. S3 and compatible stores such as MinIO or R2 are signed with the
+AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN of the
+environment; grant s3:ListBucket too, or S3 answers a miss with 403.
+"push": false only reads, e.g. on developer machines while CI fills it.
+FLASHFLOW_BUILD_CACHE overrides the url, and 'off' disables the cache.
+
+Downloads are kept in .flashflow/cache/build, so a repeated build does not
+fetch them again. A cache that cannot be reached is reported and the build
+carries on generating.
+"""
+
+import datetime
+import hashlib
+import hmac
+import io
+import os
+import re
+import sys
+import tarfile
+import urllib.error
+import urllib.request
+from pathlib import Path
+from typing import Dict, Any, Iterable, List, Optional, Tuple
+from urllib.parse import quote, urlparse
+
+from core.content import CONTENT_DIR
+from core.parser.flow_file import INCLUDE_DIRS
+
+# Bumped when the archive layout changes, so old entries are not restored
+CACHE_FORMAT = 1
+REQUEST_TIMEOUT = 60
+LOCAL_LIMIT = 200
+ENV_VAR = 'FLASHFLOW_BUILD_CACHE'
+# Steps whose output depends on src/assets as well as the flows
+ASSET_STEPS = ('media', 'static')
+FLASHFLOW_ROOT = Path(__file__).resolve().parent.parent
+GENERATOR_DIRS = ('core', 'generators')
+
+class BuildCacheError(Exception):
+    """Raised for invalid build_cache settings, or a cache that cannot be read or written"""
+    pass
+
+def _expand(value: Any) -> str:
+    return re.sub(r"\$\{(\w+)\}", lambda match: os.environ.get(match.group(1), ''), str(value))
+
+class HttpCache:
+    """GET and PUT of <url>/<key>.tar.gz"""
+
+    def __init__(self, url: str, headers: Optional[Dict[str, str]] = None):
+        self.url = url.rstrip('/')
+        self.headers = {name: _expand(value) for name, value in (headers or {}).items()}
+
+    def describe(self) -> str:
+        return self.url
+
+    def _request(self, method: str, key: str, data: Optional[bytes] = None) -> urllib.request.Request:
+        request = urllib.request.Request(f"{self.url}/{key}.tar.gz", data=data, method=method, headers=dict(self.headers))
+        if data is not None:
+            request.add_header('Content-Type', 'application/gzip')
+        return request
+
+    def get(self, key: str) -> Optional[bytes]:
+        try:
+            with urllib.request.urlopen(self._request('GET', key), timeout=REQUEST_TIMEOUT) as response:
+                return response.read()
+        except urllib.error.HTTPError as e:
+            if e.code == 404:
+                return None
+            raise BuildCacheError(f"GET {self.url}/{key}.tar.gz answered {e.code}")
+        except OSError as e:
+            raise BuildCacheError(f"Could not reach {self.url}: {getattr(e, 'reason', e)}")
+
+    def put(self, key: str, data: bytes):
+        try:
+            with urllib.request.urlopen(self._request('PUT', key, data), timeout=REQUEST_TIMEOUT):
+                pass
+        except urllib.error.HTTPError as e:
+            raise BuildCacheError(f"PUT {self.url}/{key}.tar.gz answered {e.code}")
+        except OSError as e:
+            raise BuildCacheError(f"Could not reach {self.url}: {getattr(e, 'reason', e)}")
+
+class S3Cache(HttpCache):
+    """Objects <prefix>/<key>.tar.gz in a bucket, signed with AWS Signature Version 4"""
+
+    def __init__(self, bucket: str, prefix: str, region: str, endpoint: Optional[str] = None):
+        self.bucket = bucket
+        self.prefix = prefix.strip('/')
+        self.region = region
+        self.access_key = os.environ.get('AWS_ACCESS_KEY_ID', '')
+        self.secret_key = os.environ.get('AWS_SECRET_ACCESS_KEY', '')
+        self.session_token = os.environ.get('AWS_SESSION_TOKEN')
+        if not self.access_key or not self.secret_key:
+            raise BuildCacheError("An s3:// build cache needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+        if endpoint:
+            # Path-style addressing, which MinIO and other S3-compatible stores expect
+            super().__init__(f"{endpoint.rstrip('/')}/{bucket}")
+        else:
+            super().__init__(f"https://{bucket}.s3.{region}.amazonaws.com")
+
+    def describe(self) -> str:
+        return f"s3://{self.bucket}/{self.prefix}" if self.prefix else f"s3://{self.bucket}"
+
+    def _request(self, method: str, key: str, data: Optional[bytes] = None) -> urllib.request.Request:
+        name = f"{self.prefix}/{key}.tar.gz" if self.prefix else f"{key}.tar.gz"
+        url = f"{self.url}/{quote(name)}"
+        parsed = urlparse(url)
+        now = datetime.datetime.utcnow()
+        amz_date = now.strftime('%Y%m%dT%H%M%SZ')
+        date = now.strftime('%Y%m%d')
+        payload_hash = hashlib.sha256(data or b'').hexdigest()
+
+        headers = {'host': parsed.netloc, 'x-amz-content-sha256': payload_hash, 'x-amz-date': amz_date}
+        if self.session_token:
+            headers['x-amz-security-token'] = self.session_token
+        signed_headers = ';'.join(sorted(headers))
+        canonical_request = '\n'.join([method, parsed.path, '',
+                                       ''.join(f"{name}:{headers[name]}\n" for name in sorted(headers)),
+                                       signed_headers, payload_hash])
+        scope = f"{date}/{self.region}/s3/aws4_request"
+        string_to_sign = '\n'.join(['AWS4-HMAC-SHA256', amz_date, scope,
+                                    hashlib.sha256(canonical_request.encode('utf-8')).hexdigest()])
+        signing_key = ('AWS4' + self.secret_key).encode('utf-8')
+        for part in (date, self.region, 's3', 'aws4_request'):
+            signing_key = hmac.new(signing_key, part.encode('utf-8'), hashlib.sha256).digest()
+        signature = hmac.new(signing_key, string_to_sign.encode('utf-8'), hashlib.sha256).hexdigest()
+
+        headers['Authorization'] = (f"AWS4-HMAC-SHA256 Credential={self.access_key}/{scope}, "
+                                    f"SignedHeaders={signed_headers}, Signature={signature}")
+        del headers['host']
+        request = urllib.request.Request(url, data=data, method=method, headers=headers)
+        if data is not None:
+            request.add_header('Content-Type', 'application/gzip')
+        return request
+
+def backend_from_settings(settings: Dict[str, Any]):
+    url = _expand(settings.get('url') or '')
+    if url.startswith('s3://'):
+        parsed = urlparse(url)
+        region = settings.get('region') or os.environ.get('AWS_REGION') or os.environ.get('AWS_DEFAULT_REGION') or 'us-east-1'
+        return S3Cache(parsed.netloc, parsed.path, region, _expand(settings['endpoint']) if settings.get('endpoint') else None)
+    if url.startswith(('http://', 'https://')):
+        headers = settings.get('headers') or {}
+        if not isinstance(headers, dict):
+            raise BuildCacheError("build_cache.headers must be an object")
+        return HttpCache(url, headers)
+    raise BuildCacheError(f"build_cache.url must be an http(s):// or s3:// URL, not '{url}'")
+
+def _tree_digest(paths: Iterable[Path], root: Path, digest):
+    for path in sorted(paths):
+        if path.is_file() and '__pycache__' not in path.parts:
+            digest.update(path.relative_to(root).as_posix().encode('utf-8') + b'\0')
+            digest.update(hashlib.sha256(path.read_bytes()).digest())
+
+_generator_fingerprint: Optional[str] = None
+
+def generator_fingerprint() -> str:
+    """A hash of the FlashFlow code that generates output, so upgrading FlashFlow invalidates the cache"""
+    global _generator_fingerprint
+    if _generator_fingerprint is None:
+        digest = hashlib.sha256(f"{CACHE_FORMAT}:{sys.version_info[:2]}".encode('utf-8'))
+        for name in GENERATOR_DIRS:
+            _tree_digest((FLASHFLOW_ROOT / name).rglob('*.py'), FLASHFLOW_ROOT, digest)
+        _generator_fingerprint = digest.hexdigest()
+    return _generator_fingerprint
+
+class BuildCache:
+    """Restores and stores the output of build steps"""
+
+    def __init__(self, project, backend, push: bool = True):
+        self.project = project
+        self.backend = backend
+        self.push = push
+        self.local_dir = project.state.cache_dir / 'build'
+        self.stats = {'url': backend.describe(), 'hits': 0, 'remote_hits': 0, 'misses': 0,
+                      'uploaded': 0, 'errors': []}
+        self._inputs: Optional[str] = None
+        self._assets: Optional[str] = None
+        self._remote_ok = True
+
+    @classmethod
+    def for_project(cls, project) -> Optional['BuildCache']:
+        """The configured cache, or None when there is none (or FLASHFLOW_BUILD_CACHE=off)"""
+        settings = dict(getattr(project.config, 'build_cache', None) or {})
+        override = os.environ.get(ENV_VAR)
+        if override:
+            if override.lower() in ('off', '0', 'false'):
+                return None
+            settings['url'] = override
+        if not settings.get('url'):
+            return None
+        return cls(project, backend_from_settings(settings), push=settings.get('push', True) is not False)
+
+    def key(self, step: str, target: str, env: str) -> str:
+        if self._inputs is None:
+            digest = hashlib.sha256(generator_fingerprint().encode('utf-8'))
+            if self.project.config_path.exists():
+                digest.update(self.project.config_path.read_bytes())
+            # Flows can include files from the other include folders too, and src/content holds pages
+            for folder in INCLUDE_DIRS + (CONTENT_DIR,):
+                _tree_digest((self.project.root_path / folder).rglob('*'), self.project.root_path, digest)
+            self._inputs = digest.hexdigest()
+        parts = [self._inputs, step, target, env]
+        if step in ASSET_STEPS:
+            if self._assets is None:
+                digest = hashlib.sha256()
+                _tree_digest((self.project.src_path / 'assets').rglob('*'), self.project.root_path, digest)
+                self._assets = digest.hexdigest()
+            parts.append(self._assets)
+        return f"{step}-" + hashlib.sha256('\n'.join(parts).encode('utf-8')).hexdigest()[:40]
+
+    # Restoring
+
+    def restore(self, key: str) -> Optional[str]:
+        """Unpack a step's cached output into dist/; returns 'local' or 'remote' on a hit, None on a miss"""
+        local = self.local_dir / f"{key}.tar.gz"
+        source = None
+        if local.exists():
+            data = local.read_bytes()
+            source = 'local'
+        else:
+            _, data = self._remote(lambda: self.backend.get(key))
+            if data is not None:
+                source = 'remote'
+        if data is None:
+            self.stats['misses'] += 1
+            return None
+        try:
+            unpack(data, self.project.dist_path)
+        except BuildCacheError as e:
+            self.stats['errors'].append(f"{key}: {str(e)}")
+            local.unlink(missing_ok=True)
+            self.stats['misses'] += 1
+            return None
+        if source == 'remote':
+            self._keep(local, data)
+            self.stats['remote_hits'] += 1
+        self.stats['hits'] += 1
+        return source
+
+    # Storing
+
+    def store(self, key: str, files: List[Path]):
+        """Pack the files a step wrote and keep them locally and, with push, remotely"""
+        data = pack(files, self.project.dist_path)
+        self._keep(self.local_dir / f"{key}.tar.gz", data)
+        if self.push and self._remote(lambda: self.backend.put(key, data))[0]:
+            self.stats['uploaded'] += 1
+
+    def _remote(self, call) -> Tuple[bool, Any]:
+        """(succeeded, result) of a backend call; after one failure the remote is skipped for the rest of the build"""
+        if not self._remote_ok:
+            return False, None
+        try:
+            return True, call()
+        except BuildCacheError as e:
+            self.stats['errors'].append(str(e))
+            self._remote_ok = False
+            return False, None
+
+    def _keep(self, path: Path, data: bytes):
+        self.local_dir.mkdir(parents=True, exist_ok=True)
+        partial = path.with_suffix('.tmp')
+        partial.write_bytes(data)
+        partial.replace(path)
+        entries = sorted(self.local_dir.glob('*.tar.gz'), key=lambda entry: entry.stat().st_mtime)
+        for old in entries[:-LOCAL_LIMIT]:
+            old.unlink()
+
+    def to_dict(self) -> Dict[str, Any]:
+        return dict(self.stats, errors=list(self.stats['errors']))
+
+def snapshot(root: Path) -> Dict[str, Tuple[int, int]]:
+    """(mtime, size) of every file under root, to find what a step wrote"""
+    if not root.exists():
+        return {}
+    result = {}
+    for path in root.rglob('*'):
+        if path.is_file():
+            stat = path.stat()
+            result[path.relative_to(root).as_posix()] = (stat.st_mtime_ns, stat.st_size)
+    return result
+
+def written_since(root: Path, before: Dict[str, Tuple[int, int]]) -> List[Path]:
+    return [root / name for name, stat in sorted(snapshot(root).items()) if before.get(name) != stat]
+
+def pack(files: List[Path], root: Path) -> bytes:
+    buffer = io.BytesIO()
+    with tarfile.open(fileobj=buffer, mode='w:gz') as archive:
+        for path in files:
+            info = archive.gettarinfo(str(path), arcname=path.relative_to(root).as_posix())
+            # Deterministic archives: the same output uploads the same bytes
+            info.mtime, info.uid, info.gid, info.uname, info.gname = 0, 0, 0, '', ''
+            with open(path, 'rb') as f:
+                archive.addfile(info, f)
+    return buffer.getvalue()
+
+def unpack(data: bytes, root: Path):
+    root = Path(root)
+    try:
+        with tarfile.open(fileobj=io.BytesIO(data), mode='r:gz') as archive:
+            members = archive.getmembers()
+            resolved_root = root.resolve()
+            for member in members:
+                if not member.isfile():
+                    raise BuildCacheError(f"Cache entry contains {member.name}, which is not a regular file")
+                destination = (root / member.name).resolve()
+                if resolved_root not in destination.parents:
+                    raise BuildCacheError(f"Cache entry {member.name} would be written outside dist/")
+            for member in members:
+                destination = root / member.name
+                destination.parent.mkdir(parents=True, exist_ok=True)
+                with archive.extractfile(member) as source, open(destination, 'wb') as target:
+                    target.write(source.read())
+                os.chmod(destination, member.mode & 0o777 or 0o644)
+    except (tarfile.TarError, OSError, EOFError) as e:
+        raise BuildCacheError(f"Unreadable cache entry: {e}")
@@ -0,0 +1,146 @@
+"""
+FlashFlow build sizes - What each build target weighs, and how that changed
+
+After a build every folder under dist/ (backend, frontend, mobile, ...) is
+measured. The last HISTORY_LIMIT measurements are kept in
+.flashflow/metrics/build-sizes.json; the newest one also keeps every file's
+size, as does the one before it, so a report can say which files grew.
+"""
+
+import json
+import threading
+import time
+from pathlib import Path
+from typing import Dict, Any, List, Optional
+
+HISTORY_LIMIT = 30
+LARGEST_FILES = 10
+# A target growing by more than this share of its size gets a warning
+GROWTH_WARNING = 0.10
+
+def measure(dist_path: Path) -> Dict[str, Any]:
+    """Sizes of every file below each dist/<target>/ folder"""
+    targets = {}
+    dist_path = Path(dist_path)
+    if dist_path.exists():
+        for target_dir in sorted(path for path in dist_path.iterdir() if path.is_dir()):
+            files = {}
+            for file_path in target_dir.rglob('*'):
+                if file_path.is_file():
+                    files[file_path.relative_to(target_dir).as_posix()] = file_path.stat().st_size
+            targets[target_dir.name] = {'bytes': sum(files.values()), 'count': len(files), 'files': files}
+    return {'measured_at': time.time(), 'targets': targets}
+
+def compare(current: Dict[str, Any], previous: Optional[Dict[str, Any]]) -> Dict[str, Any]:
+    """Per-target totals, largest files and growth against the previous measurement"""
+    report = {'measured_at': current['measured_at'], 'previous_at': previous['measured_at'] if previous else None,
+              'targets': {}, 'warnings': []}
+    before_targets = (previous or {}).get('targets', {})
+    for name, target in current['targets'].items():
+        before = before_targets.get(name)
+        # Builds older than the previous one only kept their totals
+        before_files = before.get('files') if before else None
+        files = target['files']
+        entry = {
+            'bytes': target['bytes'],
+            'count': target['count'],
+            'previous_bytes': before['bytes'] if before else None,
+            'delta_bytes': target['bytes'] - before['bytes'] if before else None,
+            'delta_ratio': _ratio(target['bytes'], before['bytes']) if before else None,
+            'largest': [_file_entry(path, size, before_files)
+                        for path, size in sorted(files.items(), key=lambda item: -item[1])[:LARGEST_FILES]],
+            'grown': [],
+            'added': 0,
+            'removed': 0
+        }
+        if before_files is not None:
+            deltas = [(path, size - before_files.get(path, 0)) for path, size in files.items()]
+            entry['grown'] = [_file_entry(path, files[path], before_files)
+                              for path, delta in sorted(deltas, key=lambda item: -item[1])[:LARGEST_FILES] if delta > 0]
+            entry['added'] = len(set(files) - set(before_files))
+            entry['removed'] = len(set(before_files) - set(files))
+        if entry['delta_ratio'] is not None and entry['delta_ratio'] > GROWTH_WARNING:
+            report['warnings'].append(f"{name} grew by {entry['delta_ratio'] * 100:.0f}% "
+                                      f"({format_size(before['bytes'])} → {format_size(target['bytes'])})")
+        report['targets'][name] = entry
+    return report
+
+def _file_entry(path: str, size: int, before_files: Optional[Dict[str, int]]) -> Dict[str, Any]:
+    entry = {'path': path, 'bytes': size}
+    if before_files is not None:
+        entry['delta_bytes'] = size - before_files[path] if path in before_files else None
+        entry['new'] = path not in before_files
+    return entry
+
+def _ratio(now: int, before: int) -> Optional[float]:
+    if not before:
+        return None
+    return round((now - before) / before, 4)
+
+def format_size(count: Optional[int], signed: bool = False) -> str:
+    if count is None:
+        return '-'
+    sign = ('+' if count > 0 else '-' if count < 0 else '±') if signed else ('-' if count < 0 else '')
+    value = abs(count)
+    for unit in ('B', 'KB', 'MB', 'GB'):
+        if value < 1024 or unit == 'GB':
+            return f"{sign}{value:.0f} {unit}" if unit == 'B' else f"{sign}{value:.1f} {unit}"
+        value /= 1024
+    return '-'
+
+class BuildSizeHistory:
+    """Measurements of past builds, newest last"""
+
+    def __init__(self, path: Path):
+        self.path = Path(path)
+        self._lock = threading.Lock()
+
+    @classmethod
+    def for_project(cls, project) -> 'BuildSizeHistory':
+        return cls(project.state.path('metrics', 'build-sizes.json'))
+
+    def load(self) -> List[Dict[str, Any]]:
+        if not self.path.exists():
+            return []
+        try:
+            with open(self.path, 'r') as f:
+                builds = json.load(f)
+            return builds if isinstance(builds, list) else []
+        except (OSError, json.JSONDecodeError):
+            return []
+
+    def _save(self, builds: List[Dict[str, Any]]):
+        self.path.parent.mkdir(parents=True, exist_ok=True)
+        partial = self.path.with_suffix('.tmp')
+        with open(partial, 'w') as f:
+            json.dump(builds, f)
+        partial.replace(self.path)
+
+    def record(self, dist_path: Path, target: str, env: str) -> Dict[str, Any]:
+        """Measure dist/ after a build and return the comparison with the build before"""
+        current = dict(measure(dist_path), target=target, env=env)
+        with self._lock:
+            builds = self.load()
+            previous = builds[-1] if builds else None
+            report = dict(compare(current, previous), target=target, env=env)
+            # Only the two newest builds keep per-file sizes; older ones keep their totals
+            for build in builds[:-1]:
+                for entry in build.get('targets', {}).values():
+                    entry.pop('files', None)
+            builds.append(current)
+            self._save(builds[-HISTORY_LIMIT:])
+        return report
+
+    def latest(self) -> Optional[Dict[str, Any]]:
+        """The newest build compared with the one before it"""
+        builds = self.load()
+        if not builds:
+            return None
+        return dict(compare(builds[-1], builds[-2] if len(builds) > 1 else None),
+                    target=builds[-1].get('target'), env=builds[-1].get('env'))
+
+    def totals(self) -> List[Dict[str, Any]]:
+        """Per-target totals of every kept build, oldest first"""
+        return [{'measured_at': build['measured_at'], 'target': build.get('target'), 'env': build.get('env'),
+                 'targets': {name: entry['bytes'] for name, entry in build.get('targets', {}).items()}}
+                for build in self.load()]
@@ -0,0 +1,106 @@
+"""
+FlashFlow cancellation - Stopping long FlashCore and ONNX calls nobody waits for any more
+
+A CancelToken is handed down from whoever started the work (the dev server
+gives each request one) to the calls doing it:
+
+    token = CancelToken(timeout=5)
+    search(index, query, options, cancel=token)
+    runner.predict(body, cancel=token)
+    embedder.embed(texts, cancel=token)
+
+The work stops with Cancelled at the next point it checks the token:
+between chunks of an exact search, between radius rounds and embedding
+batches, and inside a native call when the binding can be told to stop
+(FlashCore bindings built with a should_cancel callback, onnxruntime via
+RunOptions.terminate). Calls that cannot be interrupted run to the end and
+their result is dropped.
+
+A token is cancelled by cancel(reason), or by its deadline passing, which
+is noticed the next time anyone looks at it.
+"""
+
+import threading
+import time
+from typing import Callable, List, Optional
+
+from core.flashcore_handles import FlashCoreError
+
+DEADLINE_EXCEEDED = 'deadline exceeded'
+
+class Cancelled(FlashCoreError):
+    """Raised when work stops because its token was cancelled"""
+
+    def __init__(self, reason: str):
+        super().__init__(f"Cancelled: {reason}")
+        self.reason = reason
+
+    @property
+    def deadline(self) -> bool:
+        return self.reason == DEADLINE_EXCEEDED
+
+class CancelToken:
+    """Cancelled once, by cancel() or its deadline; callbacks run when that happens"""
+
+    def __init__(self, timeout: Optional[float] = None):
+        self.deadline = time.monotonic() + timeout if timeout is not None else None
+        self.reason: Optional[str] = None
+        self._event = threading.Event()
+        self._lock = threading.Lock()
+        self._callbacks: List[Callable[[], None]] = []
+
+    @property
+    def cancelled(self) -> bool:
+        if not self._event.is_set() and self.deadline is not None and time.monotonic() >= self.deadline:
+            self.cancel(DEADLINE_EXCEEDED)
+        return self._event.is_set()
+
+    def cancel(self, reason: str = 'cancelled'):
+        """Cancel the token; only the first reason is kept"""
+        with self._lock:
+            if self._event.is_set():
+                return
+            self.reason = reason
+            self._event.set()
+            callbacks, self._callbacks = self._callbacks, []
+        for callback in callbacks:
+            try:
+                callback()
+            except Exception:
+                pass
+
+    def check(self):
+        """Raise Cancelled when the token is cancelled"""
+        if self.cancelled:
+            raise Cancelled(self.reason)
+
+    def remaining(self) -> Optional[float]:
+        """Seconds to the deadline, None without one"""
+        return None if self.deadline is None else max(0.0, self.deadline - time.monotonic())
+
+    def on_cancel(self, callback: Callable[[], None]) -> Callable[[], None]:
+        """Run callback when the token is cancelled (now, if it already is); returns a function removing it"""
+        with self._lock:
+            if not self._event.is_set():
+                self._callbacks.append(callback)
+                return lambda: self._remove(callback)
+        callback()
+        return lambda: None
+
+    def _remove(self, callback: Callable[[], None]):
+        with self._lock:
+            if callback in self._callbacks:
+                self._callbacks.remove(callback)
+
+    def wait(self, timeout: Optional[float] = None) -> bool:
+        """Block until cancelled or timeout; True when cancelled"""
+        remaining = self.remaining()
+        if remaining is not None:
+            timeout = remaining if timeout is None else min(timeout, remaining)
+        self._event.wait(timeout)
+        return self.cancelled
+
+def check(cancel: Optional[CancelToken]):
+    """token.check() for code that takes an optional token"""
+    if cancel is not None:
+        cancel.check()
@@ -0,0 +1,215 @@
+"""
+FlashFlow chaos mode - Latency and faults injected into dev server routes
+
+Rules are edited from /admin/chaos and kept in .flashflow/chaos/rules.json, so
+they survive restarts:
+
+    {
+        "route": "/api/data/*",      # fnmatch-style pattern
+        "methods": ["GET"],          # empty for every method
+        "delay_ms": 800,             # added to each matching request
+        "jitter_ms": 400,            # plus up to this much more, at random
+        "error_rate": 0.1,           # share of requests answered with error_status
+        "error_status": 503,
+        "drop_rate": 0.05            # share of connections closed without a response
+    }
+
+A request gets the first enabled rule that matches it, and only while chaos
+mode itself is switched on.
+"""
+
+import fnmatch
+import json
+import random
+import threading
+import uuid
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Dict, Any, List, Optional
+
+HTTP_METHODS = ['GET', 'POST', 'PUT', 'PATCH', 'DELETE', 'HEAD', 'OPTIONS']
+MAX_DELAY_MS = 120000
+
+class ChaosError(Exception):
+    """Raised for an invalid chaos rule"""
+    pass
+
+@dataclass
+class ChaosDecision:
+    """What chaos mode does to one request"""
+    rule_id: str
+    delay_ms: int = 0
+    error_status: Optional[int] = None
+    drop: bool = False
+
+@dataclass
+class ChaosRule:
+    """Faults for the requests matching one route pattern"""
+    route: str
+    methods: List[str] = field(default_factory=list)
+    delay_ms: int = 0
+    jitter_ms: int = 0
+    error_rate: float = 0.0
+    error_status: int = 500
+    drop_rate: float = 0.0
+    enabled: bool = True
+    id: str = field(default_factory=lambda: uuid.uuid4().hex[:8])
+    # Not persisted; what the rule has done since the server started
+    hits: Dict[str, int] = field(default_factory=lambda: {'matched': 0, 'delayed': 0, 'errors': 0, 'dropped': 0})
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any], rule_id: Optional[str] = None) -> 'ChaosRule':
+        if not isinstance(data, dict):
+            raise ChaosError("A chaos rule must be a JSON object")
+        route = data.get('route')
+        if not isinstance(route, str) or not route.startswith('/'):
+            raise ChaosError("'route' must be a path pattern starting with '/', e.g. /api/data/*")
+
+        methods = data.get('methods') or []
+        if isinstance(methods, str):
+            methods = methods.replace(',', ' ').split()
+        methods = [str(method).upper() for method in methods]
+        unknown = [method for method in methods if method not in HTTP_METHODS]
+        if unknown:
+            raise ChaosError(f"Unknown method(s) {', '.join(unknown)}; use {', '.join(HTTP_METHODS)}")
+
+        rule = cls(
+            route=route,
+            methods=methods,
+            delay_ms=_number(data, 'delay_ms', 0, 0, MAX_DELAY_MS, int),
+            jitter_ms=_number(data, 'jitter_ms', 0, 0, MAX_DELAY_MS, int),
+            error_rate=_number(data, 'error_rate', 0.0, 0.0, 1.0, float),
+            error_status=_number(data, 'error_status', 500, 400, 599, int),
+            drop_rate=_number(data, 'drop_rate', 0.0, 0.0, 1.0, float),
+            enabled=bool(data.get('enabled', True))
+        )
+        if rule.error_rate + rule.drop_rate > 1.0:
+            raise ChaosError("error_rate and drop_rate together cannot exceed 1")
+        if rule_id:
+            rule.id = rule_id
+        return rule
+
+    def matches(self, method: str, path: str) -> bool:
+        return (self.enabled and (not self.methods or method.upper() in self.methods)
+                and fnmatch.fnmatchcase(path, self.route))
+
+    def decide(self, rng: random.Random) -> ChaosDecision:
+        """Roll the dice for one request; an error and a drop never happen together"""
+        decision = ChaosDecision(self.id, self.delay_ms + (rng.randint(0, self.jitter_ms) if self.jitter_ms else 0))
+        roll = rng.random()
+        if roll < self.drop_rate:
+            decision.drop = True
+        elif roll < self.drop_rate + self.error_rate:
+            decision.error_status = self.error_status
+        return decision
+
+    def to_dict(self, with_hits: bool = True) -> Dict[str, Any]:
+        data = {
+            'id': self.id,
+            'route': self.route,
+            'methods': list(self.methods),
+            'delay_ms': self.delay_ms,
+            'jitter_ms': self.jitter_ms,
+            'error_rate': self.error_rate,
+            'error_status': self.error_status,
+            'drop_rate': self.drop_rate,
+            'enabled': self.enabled
+        }
+        if with_hits:
+            data['hits'] = dict(self.hits)
+        return data
+
+class ChaosConfig:
+    """Chaos mode switch and rules, persisted as JSON"""
+
+    def __init__(self, path: Path, seed: Optional[int] = None):
+        self.path = Path(path)
+        self._lock = threading.Lock()
+        self._rng = random.Random(seed)
+        self.enabled, self.rules = self._load()
+
+    @classmethod
+    def for_project(cls, project) -> 'ChaosConfig':
+        return cls(project.state.path('chaos', 'rules.json'))
+
+    def _load(self):
+        if not self.path.exists():
+            return False, []
+        try:
+            with open(self.path, 'r') as f:
+                data = json.load(f)
+            return bool(data.get('enabled')), [ChaosRule.from_dict(rule, rule.get('id')) for rule in data.get('rules', [])]
+        except (OSError, json.JSONDecodeError, AttributeError, ChaosError):
+            return False, []
+
+    def _save(self):
+        self.path.parent.mkdir(parents=True, exist_ok=True)
+        partial = self.path.with_suffix('.tmp')
+        with open(partial, 'w') as f:
+            json.dump({'enabled': self.enabled, 'rules': [rule.to_dict(with_hits=False) for rule in self.rules]}, f, indent=2)
+        partial.replace(self.path)
+
+    def set_enabled(self, enabled: bool):
+        with self._lock:
+            self.enabled = bool(enabled)
+            self._save()
+
+    def add(self, data: Dict[str, Any]) -> ChaosRule:
+        rule = ChaosRule.from_dict(data)
+        with self._lock:
+            self.rules.append(rule)
+            self._save()
+        return rule
+
+    def update(self, rule_id: str, data: Dict[str, Any]) -> ChaosRule:
+        """Change some fields of a rule; its counters carry over"""
+        with self._lock:
+            index = self._index(rule_id)
+            current = self.rules[index]
+            rule = ChaosRule.from_dict(dict(current.to_dict(with_hits=False), **(data or {})), rule_id)
+            rule.hits = current.hits
+            self.rules[index] = rule
+            self._save()
+        return rule
+
+    def remove(self, rule_id: str):
+        with self._lock:
+            del self.rules[self._index(rule_id)]
+            self._save()
+
+    def _index(self, rule_id: str) -> int:
+        for index, rule in enumerate(self.rules):
+            if rule.id == rule_id:
+                return index
+        raise ChaosError(f"No chaos rule with id '{rule_id}'")
+
+    def decide(self, method: str, path: str) -> Optional[ChaosDecision]:
+        """What to do to a request, or None to leave it alone"""
+        with self._lock:
+            if not self.enabled:
+                return None
+            rule = next((rule for rule in self.rules if rule.matches(method, path)), None)
+            if rule is None:
+                return None
+            decision = rule.decide(self._rng)
+            rule.hits['matched'] += 1
+            rule.hits['delayed'] += decision.delay_ms > 0
+            rule.hits['errors'] += decision.error_status is not None
+            rule.hits['dropped'] += decision.drop
+        return decision
+
+    def to_dict(self) -> Dict[str, Any]:
+        with self._lock:
+            return {'enabled': self.enabled, 'rules': [rule.to_dict() for rule in self.rules]}
+
+def _number(data: Dict[str, Any], key: str, default, low, high, kind):
+    value = data.get(key, default)
+    if value in (None, ''):
+        value = default
+    try:
+        value = kind(value)
+    except (TypeError, ValueError):
+        raise ChaosError(f"'{key}' must be a number")
+    if not low <= value <= high:
+        raise ChaosError(f"'{key}' must be between {low} and {high}")
+    return value
@@ -0,0 +1,145 @@
+"""
+FlashFlow content pages - Markdown files in src/content served as pages
+
+Every .md file under src/content becomes a routed page whose body is one
+'markdown' component, so the dev server, the static export and the direct
+renderer show it like any flow page. Frontmatter sets what a flow's page
+section would:
+
+    ---
+    title: Getting started          # default: the '# Heading' the file opens with
+    path: /docs/start               # default: the file's path, index.md being its folder
+    description: Install and run your first app
+    nav_title: Start                # and nav: false, nav_order: 10
+    static: {exclude: true}         # as for flow pages (core/static_site.py)
+    sitemap: {priority: 0.8}        # and noindex: true (core/sitemap.py)
+    og: {image: docs-card.png}      # and twitter: {...} (core/social_cards.py)
+    feature: new_docs               # only while the flag is on (core/feature_flags.py)
+    draft: true                     # leave the page out
+    ---
+
+    # Getting started
+    ...
+
+src/content/docs/start.md is served at /docs/start, src/content/index.md at /.
+A page title taken from the heading is removed from the body, since page
+layouts print the title themselves. When a flow page has the same route,
+the flow page wins.
+
+A flow page can also show Markdown through the component itself:
+
+    - component: markdown
+      content: |
+        Some **formatted** text
+    - component: markdown
+      src: docs/changelog.md        # relative to src/content; its frontmatter is dropped
+"""
+
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Any, Dict, List, Optional
+
+from core.markdown import MarkdownError, first_heading, split_frontmatter
+
+CONTENT_DIR = 'src/content'
+PAGE_KEYS = ('description', 'nav', 'nav_title', 'nav_order', 'static', 'sitemap', 'noindex', 'og', 'twitter', 'feature')
+
+class ContentError(Exception):
+    """Raised for a content file that cannot be read or has invalid frontmatter"""
+
+    def __init__(self, message: str, file: Optional[Path] = None, line: Optional[int] = None):
+        super().__init__(message)
+        self.file = file
+        self.line = line
+
+@dataclass
+class ContentPage:
+    file: Path
+    route: str
+    title: str
+    markdown: str
+    frontmatter: Dict[str, Any] = field(default_factory=dict)
+
+    @property
+    def draft(self) -> bool:
+        return bool(self.frontmatter.get('draft'))
+
+    def page_data(self, root: Path) -> Dict[str, Any]:
+        """The page as a flow's page section would declare it"""
+        data = {key: self.frontmatter[key] for key in PAGE_KEYS if key in self.frontmatter}
+        data.update({'path': self.route, 'title': self.title,
+                     'body': [{'component': 'markdown', 'content': self.markdown}],
+                     'source': self.file.relative_to(root).as_posix()})
+        return data
+
+def content_path(root: Path) -> Path:
+    return Path(root) / CONTENT_DIR
+
+def content_files(root: Path) -> List[Path]:
+    folder = content_path(root)
+    if not folder.is_dir():
+        return []
+    return sorted(path for path in folder.rglob('*.md') if path.is_file() and not path.name.startswith(('.', '_')))
+
+def route_for(root: Path, file: Path) -> str:
+    """The route of a content file without a 'path' in its frontmatter"""
+    parts = list(Path(file).relative_to(content_path(root)).with_suffix('').parts)
+    if parts and parts[-1].lower() in ('index', 'readme'):
+        parts.pop()
+    return '/' + '/'.join(parts)
+
+def read_content_page(root: Path, file: Path) -> ContentPage:
+    file = Path(file)
+    try:
+        text = file.read_text(encoding='utf-8')
+    except (OSError, UnicodeDecodeError) as e:
+        raise ContentError(f"Cannot read {file.name}: {str(e)}", file)
+    try:
+        frontmatter, markdown, _ = split_frontmatter(text)
+    except MarkdownError as e:
+        raise ContentError(str(e), file, e.line)
+
+    route = frontmatter.get('path')
+    if route is None:
+        route = route_for(root, file)
+    elif not isinstance(route, str) or not route.startswith('/'):
+        raise ContentError(f"path must start with '/', got {route!r}", file, 2)
+
+    title = frontmatter.get('title')
+    heading = first_heading(markdown)
+    if heading:
+        # Layouts print the page title, so the heading would show twice
+        if title is None:
+            title = heading[0]
+        if str(title) == heading[0]:
+            markdown = heading[1]
+    if title is None:
+        title = file.stem.replace('-', ' ').replace('_', ' ').capitalize()
+    return ContentPage(file, route, str(title), markdown.strip('\n') + '\n', frontmatter)
+
+def load_content_pages(root: Path) -> List[ContentPage]:
+    """Every page in src/content, drafts left out"""
+    pages = [read_content_page(root, file) for file in content_files(root)]
+    return [page for page in pages if not page.draft]
+
+def add_content_pages(ir, root: Path) -> List[str]:
+    """Add the content pages to the IR, returning the routes left to flow pages"""
+    root = Path(root)
+    shadowed = []
+    for page in load_content_pages(root):
+        if page.route in ir.pages:
+            shadowed.append(page.route)
+            continue
+        ir.add_page(page.route, page.page_data(root))
+    return shadowed
+
+def read_markdown_source(root: Path, src: str) -> str:
+    """The Markdown of a file a 'markdown' component points at with src, frontmatter dropped"""
+    folder = content_path(root).resolve()
+    file = (folder / str(src)).resolve()
+    if folder not in file.parents or not file.is_file():
+        raise ContentError(f"Markdown file '{src}' not found in {CONTENT_DIR}")
+    try:
+        return split_frontmatter(file.read_text(encoding='utf-8'))[1]
+    except (OSError, UnicodeDecodeError, MarkdownError) as e:
+        raise ContentError(f"Cannot read {src}: {str(e)}", file)
@@ -0,0 +1,519 @@
+"""
+FlashFlow API contracts - Catching dev API changes that break the SDKs generated from it
+
+A contract is what a client SDK generated from /api/data/openapi.json
+sees: one operation per method and path, with its parameters, request body
+and success response as plain shapes, under the names SDK generators give
+them (operationId, else method and path: GET /api/data/users/{row_id} is
+getDataUsersByRowId). 'flashflow test contract' builds it from the running
+dev server and compares it with contracts/api.json, the snapshot committed
+next to the SDKs:
+
+- a removed operation, parameter or response field, a changed type, a
+  request field or parameter that became required, and a request enum
+  value dropped or a response enum value added break existing SDK calls
+- new operations and optional fields are additions, which do not
+
+Each change is shown with the operation's TypeScript or Dart SDK signature
+(see signature()), before and after.
+
+Fixtures (contracts/fixtures.json) are recorded SDK calls: one request per
+operation and the answer it got. They are replayed against the server in
+order and must still get the status they got and fit the response shape of
+the snapshot. Rows a create fixture makes are what later fixtures' path ids
+point at, so the replay does not depend on the rows in the database.
+Recording and replaying both run in a throwaway database sandbox (see
+core/sandboxes.py), so writes leave the dev data alone.
+"""
+
+import json
+import re
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+
+import requests
+
+from core.sandboxes import SANDBOX_HEADER
+
+CONTRACT_DIR = 'contracts'
+SNAPSHOT_FILE = 'api.json'
+FIXTURES_FILE = 'fixtures.json'
+CONTRACT_FORMAT = 1
+SPEC_PATH = '/api/data/openapi.json'
+SDK_LANGUAGES = ('typescript', 'dart')
+METHODS = ('get', 'post', 'put', 'patch', 'delete')
+# Creates first, so the reads, updates and deletes after them have a row to work on
+METHOD_ORDER = {'post': 0, 'get': 1, 'put': 2, 'patch': 3, 'delete': 4}
+REQUEST_TIMEOUT = 15
+PATH_PARAMETER = re.compile(r"\{([^}]+)\}")
+# Sample values for request bodies and required parameters, by format and type
+SAMPLE_FORMATS = {'email': 'contract@example.test', 'uri': 'https://example.test/', 'date': '2024-01-01',
+                  'date-time': '2024-01-01T00:00:00Z', 'password': 'Contract-Test-1!'}
+SAMPLE_TYPES = {'string': 'contract', 'integer': 1, 'number': 1.5, 'boolean': True}
+TYPESCRIPT_TYPES = {'string': 'string', 'integer': 'number', 'number': 'number', 'boolean': 'boolean', 'any': 'unknown'}
+DART_TYPES = {'string': 'String', 'integer': 'int', 'number': 'double', 'boolean': 'bool', 'any': 'dynamic'}
+
+class ContractError(Exception):
+    """Raised when the API description, a snapshot or the fixtures cannot be used"""
+    pass
+
+@dataclass
+class ContractChange:
+    """One difference between the snapshot and the API as it is now"""
+    operation: str
+    message: str
+    breaking: bool
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'operation': self.operation, 'message': self.message, 'breaking': self.breaking}
+
+# Contracts from OpenAPI
+
+def _words(text: str) -> List[str]:
+    return [word for word in re.split(r"[^A-Za-z0-9]+", text) if word]
+
+def camel(text: str) -> str:
+    words = _words(text)
+    return (words[0][:1].lower() + words[0][1:] + ''.join(word.capitalize() for word in words[1:])) if words else text
+
+def operation_name(method: str, path: str, operation: Dict[str, Any]) -> str:
+    if isinstance(operation.get('operationId'), str) and operation['operationId']:
+        return operation['operationId']
+    words = []
+    for segment in path.strip('/').split('/'):
+        if not segment or segment == 'api':
+            continue
+        parameter = PATH_PARAMETER.fullmatch(segment)
+        words += ['By'] + _words(parameter.group(1)) if parameter else _words(segment)
+    return method.lower() + ''.join(word[:1].upper() + word[1:] for word in words)
+
+def shape_of(schema: Any, spec: Dict[str, Any], seen: Tuple[str, ...] = ()) -> Dict[str, Any]:
+    """A JSON schema as the shape an SDK types it with; $refs are inlined and keep their name"""
+    if not isinstance(schema, dict):
+        return {'type': 'any'}
+    ref = schema.get('$ref')
+    if isinstance(ref, str):
+        name = ref.rsplit('/', 1)[-1]
+        if name in seen:
+            # A schema containing itself; the name is all an SDK needs there
+            return {'type': 'object', 'name': name}
+        target = ((spec.get('components') or {}).get('schemas') or {}).get(name)
+        if target is None:
+            raise ContractError(f"{ref} is not in the API description")
+        return dict(shape_of(target, spec, seen + (name,)), name=name)
+    kind = schema.get('type')
+    if kind == 'array' or 'items' in schema:
+        return {'type': 'array', 'items': shape_of(schema.get('items'), spec, seen)}
+    if kind == 'object' or 'properties' in schema:
+        properties = schema.get('properties') or {}
+        shape: Dict[str, Any] = {'type': 'object', 'properties': {name: shape_of(value, spec, seen)
+                                                                  for name, value in sorted(properties.items())}}
+        required = sorted(name for name in schema.get('required') or [] if name in properties)
+        if required:
+            shape['required'] = required
+        return shape
+    if kind not in ('string', 'integer', 'number', 'boolean'):
+        return {'type': 'any'}
+    shape = {'type': kind}
+    if schema.get('format'):
+        shape['format'] = schema['format']
+    if isinstance(schema.get('enum'), list):
+        shape['enum'] = schema['enum']
+    return shape
+
+def _json_content(holder: Dict[str, Any]) -> Optional[Dict[str, Any]]:
+    content = holder.get('content') or {}
+    media = content.get('application/json') or next(iter(content.values()), None)
+    return media if isinstance(media, dict) else None
+
+def build_contract(spec: Dict[str, Any]) -> Dict[str, Any]:
+    """The SDK-facing contract of an OpenAPI document"""
+    if not isinstance(spec, dict) or not isinstance(spec.get('paths'), dict):
+        raise ContractError("The API description has no paths")
+    operations: Dict[str, Dict[str, Any]] = {}
+    for path, item in sorted(spec['paths'].items()):
+        if not isinstance(item, dict):
+            continue
+        for method in METHODS:
+            operation = item.get(method)
+            if not isinstance(operation, dict):
+                continue
+            params = {}
+            for parameter in (item.get('parameters') or []) + (operation.get('parameters') or []):
+                if not isinstance(parameter, dict) or not parameter.get('name'):
+                    continue
+                where = parameter.get('in', 'query')
+                params[parameter['name']] = {'in': where, 'required': bool(parameter.get('required')) or where == 'path',
+                                             'schema': shape_of(parameter.get('schema'), spec)}
+            body = None
+            if isinstance(operation.get('requestBody'), dict):
+                media = _json_content(operation['requestBody'])
+                body = {'required': bool(operation['requestBody'].get('required')),
+                        'schema': shape_of(media.get('schema') if media else None, spec)}
+            responses = operation.get('responses') or {}
+            status = min((code for code in responses if str(code).startswith('2')), default=None, key=str)
+            media = _json_content(responses[status]) if status is not None and isinstance(responses[status], dict) else None
+            name = operation_name(method, path, operation)
+            if name in operations:
+                name = f"{name}{method.capitalize()}"
+            operations[name] = {'method': method.upper(), 'path': path, 'params': params, 'body': body,
+                                'status': int(status) if status is not None and str(status).isdigit() else None,
+                                'response': shape_of(media.get('schema'), spec) if media and 'schema' in media else None}
+    return {'format': CONTRACT_FORMAT, 'operations': operations}
+
+def load_contract(path: Path) -> Optional[Dict[str, Any]]:
+    """The snapshot at path, or None when there is none yet"""
+    if not path.exists():
+        return None
+    try:
+        contract = json.loads(path.read_text(encoding='utf-8'))
+    except (OSError, ValueError) as e:
+        raise ContractError(f"Cannot read {path.name}: {str(e)}")
+    if not isinstance(contract, dict) or not isinstance(contract.get('operations'), dict):
+        raise ContractError(f"{path.name} is not a contract snapshot; write a new one with --update")
+    if contract.get('format') != CONTRACT_FORMAT:
+        raise ContractError(f"{path.name} was written by another FlashFlow version; write a new one with --update")
+    return contract
+
+def write_json(path: Path, data: Any):
+    path.parent.mkdir(parents=True, exist_ok=True)
+    # Sorted and indented, so the committed files diff well
+    path.write_text(json.dumps(data, indent=2, sort_keys=True) + '\n', encoding='utf-8')
+
+# Comparing contracts
+
+def compare_contracts(old: Dict[str, Any], new: Dict[str, Any]) -> List[ContractChange]:
+    """How new differs from old, breaking changes and additions, in operation order"""
+    changes = []
+    old_operations, new_operations = old['operations'], new['operations']
+    for name in sorted(set(old_operations) | set(new_operations)):
+        if name not in new_operations:
+            before = old_operations[name]
+            changes.append(ContractChange(name, f"{before['method']} {before['path']} was removed", True))
+        elif name not in old_operations:
+            after = new_operations[name]
+            changes.append(ContractChange(name, f"{after['method']} {after['path']} is new", False))
+        else:
+            changes += [ContractChange(name, message, breaking)
+                        for message, breaking in _compare_operations(old_operations[name], new_operations[name])]
+    return changes
+
+def _compare_operations(old: Dict[str, Any], new: Dict[str, Any]) -> List[Tuple[str, bool]]:
+    changes = []
+    if (old['method'], old['path']) != (new['method'], new['path']):
+        changes.append((f"moved from {old['method']} {old['path']} to {new['method']} {new['path']}", True))
+    for name, before in old['params'].items():
+        after = new['params'].get(name)
+        if after is None:
+            changes.append((f"parameter {name} was removed", True))
+            continue
+        if after['in'] != before['in']:
+            changes.append((f"parameter {name} moved from the {before['in']} to the {after['in']}", True))
+        if after['required'] and not before['required']:
+            changes.append((f"parameter {name} is now required", True))
+        changes += _compare_shapes(before['schema'], after['schema'], f"parameter {name}", True)
+    for name, after in new['params'].items():
+        if name not in old['params']:
+            changes.append((f"parameter {name} is new" + (" and required" if after['required'] else ''), after['required']))
+
+    if old['body'] and not new['body']:
+        changes.append(("no longer takes a request body", True))
+    elif new['body'] and not old['body']:
+        changes.append(("takes a request body" + (", which is required" if new['body']['required'] else ''),
+                        new['body']['required']))
+    elif old['body'] and new['body']:
+        if new['body']['required'] and not old['body']['required']:
+            changes.append(("the request body is now required", True))
+        changes += _compare_shapes(old['body']['schema'], new['body']['schema'], 'body', True)
+
+    if old['status'] != new['status']:
+        changes.append((f"answers {new['status']} instead of {old['status']}", True))
+    if old['response'] and not new['response']:
+        changes.append(("no longer describes its response", True))
+    elif old['response'] and new['response']:
+        changes += _compare_shapes(old['response'], new['response'], 'response', False)
+    return changes
+
+def _compare_shapes(old: Dict[str, Any], new: Dict[str, Any], where: str, request: bool) -> List[Tuple[str, bool]]:
+    """Differences between two shapes; request says which side of the call sends the value"""
+    old_type, new_type = old.get('type'), new.get('type')
+    if 'any' in (old_type, new_type):
+        return []
+    if old_type != new_type:
+        # A field that took integers takes any number, and one that returned numbers may return integers
+        widened = (old_type, new_type) == (('integer', 'number') if request else ('number', 'integer'))
+        return [(f"{where} changed from {old_type} to {new_type}", not widened)]
+
+    changes = []
+    if old_type == 'array':
+        return _compare_shapes(old.get('items') or {}, new.get('items') or {}, f"{where}[]", request)
+    if old_type == 'object':
+        old_properties, new_properties = old.get('properties') or {}, new.get('properties') or {}
+        old_required, new_required = set(old.get('required') or []), set(new.get('required') or [])
+        for name, before in old_properties.items():
+            if name not in new_properties:
+                changes.append((f"{where}.{name} was removed" if request else f"{where}.{name} is no longer returned", True))
+                continue
+            if request and name in new_required and name not in old_required:
+                changes.append((f"{where}.{name} is now required", True))
+            changes += _compare_shapes(before, new_properties[name], f"{where}.{name}", request)
+        for name in new_properties:
+            if name not in old_properties:
+                required = request and name in new_required
+                changes.append((f"{where}.{name} is new" + (" and required" if required else ''), required))
+        return changes
+
+    # Requests may only send values the server takes, and responses only hold values the SDK knows
+    sent, accepted = (old.get('enum'), new.get('enum')) if request else (new.get('enum'), old.get('enum'))
+    if isinstance(accepted, list):
+        if not isinstance(sent, list):
+            listed = ', '.join(json.dumps(value) for value in accepted)
+            changes.append((f"{where} is now limited to {listed}" if request else f"{where} is no longer limited to {listed}", True))
+        else:
+            extra = ', '.join(json.dumps(value) for value in sent if value not in accepted)
+            if extra:
+                changes.append((f"{where} no longer takes {extra}" if request else f"{where} may now be {extra}", True))
+    return changes
+
+# SDK signatures
+
+def type_name(shape: Optional[Dict[str, Any]], language: str) -> str:
+    """The type an SDK in language gives shape"""
+    shape = shape or {'type': 'any'}
+    kind = shape.get('type', 'any')
+    if kind == 'object' and shape.get('name'):
+        return shape['name']
+    if language == 'dart':
+        if kind == 'array':
+            return f"List<{type_name(shape.get('items'), language)}>"
+        if kind == 'object':
+            return 'Map<String, dynamic>'
+        return DART_TYPES.get(kind, 'dynamic')
+    if kind == 'array':
+        inner = type_name(shape.get('items'), language)
+        return f"({inner})[]" if '|' in inner else f"{inner}[]"
+    if kind == 'object':
+        properties = shape.get('properties') or {}
+        if not properties:
+            return 'Record<string, unknown>'
+        required = set(shape.get('required') or [])
+        return '{ ' + '; '.join(f"{name}{'' if name in required else '?'}: {type_name(value, language)}"
+                                for name, value in properties.items()) + ' }'
+    if isinstance(shape.get('enum'), list) and shape['enum']:
+        return ' | '.join(json.dumps(value) for value in shape['enum'])
+    return TYPESCRIPT_TYPES.get(kind, 'unknown')
+
+def signature(name: str, operation: Dict[str, Any], language: str = 'typescript') -> str:
+    """The operation's method in a generated SDK: path parameters first, then the body, then the rest as options"""
+    path_params = [(key, value) for key, value in operation['params'].items() if value['in'] == 'path']
+    options = [(key, value) for key, value in operation['params'].items() if value['in'] != 'path']
+    body = operation.get('body')
+    response = type_name(operation['response'], language) if operation.get('response') else 'void'
+    if language == 'dart':
+        positional = [f"{type_name(value['schema'], language)} {camel(key)}" for key, value in path_params]
+        named = [f"required {type_name(value['schema'], language)} {camel(key)}" if value['required'] else
+                 f"{type_name(value['schema'], language)}? {camel(key)}" for key, value in options]
+        if body and body['required']:
+            positional.append(f"{type_name(body['schema'], language)} body")
+        elif body:
+            named.append(f"{type_name(body['schema'], language)}? body")
+        arguments = positional + (['{' + ', '.join(named) + '}'] if named else [])
+        return f"Future<{response}> {name}({', '.join(arguments)})"
+
+    arguments = [f"{camel(key)}: {type_name(value['schema'], language)}" for key, value in path_params]
+    if body:
+        arguments.append(f"body{'' if body['required'] else '?'}: {type_name(body['schema'], language)}")
+    if options:
+        fields = '; '.join(f"{camel(key)}{'' if value['required'] else '?'}: {type_name(value['schema'], language)}"
+                           for key, value in options)
+        arguments.append(f"options{'' if any(value['required'] for _, value in options) else '?'}: {{ {fields} }}")
+    return f"{name}({', '.join(arguments)}): Promise<{response}>"
+
+# Fixtures
+
+def sample(shape: Optional[Dict[str, Any]]) -> Any:
+    """A value fitting shape, for a recorded request"""
+    shape = shape or {'type': 'any'}
+    kind = shape.get('type')
+    if isinstance(shape.get('enum'), list) and shape['enum']:
+        return shape['enum'][0]
+    if kind == 'object':
+        return {name: sample(value) for name, value in (shape.get('properties') or {}).items()
+                if value.get('type') != 'any'}
+    if kind == 'array':
+        return []
+    if kind == 'string':
+        return SAMPLE_FORMATS.get(shape.get('format'), SAMPLE_TYPES['string'])
+    return SAMPLE_TYPES.get(kind)
+
+def fit_problems(shape: Optional[Dict[str, Any]], value: Any, where: str = 'response') -> List[str]:
+    """Where value does not have the shape an SDK would decode it with"""
+    if shape is None or value is None or shape.get('type') in (None, 'any'):
+        return []
+    kind = shape['type']
+    if kind == 'object':
+        if not isinstance(value, dict):
+            return [f"{where} is {type(value).__name__}, not an object"]
+        problems = []
+        for name, field_shape in (shape.get('properties') or {}).items():
+            if name not in value:
+                problems.append(f"{where}.{name} is missing")
+            else:
+                problems += fit_problems(field_shape, value[name], f"{where}.{name}")
+        return problems
+    if kind == 'array':
+        if not isinstance(value, list):
+            return [f"{where} is {type(value).__name__}, not an array"]
+        return [problem for index, item in enumerate(value) for problem in fit_problems(shape.get('items'), item, f"{where}[{index}]")]
+    expected = {'string': (str,), 'integer': (int,), 'number': (int, float), 'boolean': (bool,)}.get(kind, ())
+    if not isinstance(value, expected) or (kind != 'boolean' and isinstance(value, bool)):
+        return [f"{where} is {json.dumps(value)}, not {'a' if kind != 'integer' else 'an'} {kind}"]
+    if isinstance(shape.get('enum'), list) and value not in shape['enum']:
+        return [f"{where} is {json.dumps(value)}, not one of {', '.join(json.dumps(item) for item in shape['enum'])}"]
+    return []
+
+def _collection(path: str) -> str:
+    return path.split('{', 1)[0].rstrip('/') or '/'
+
+def _created_id(response: Any) -> Any:
+    data = response.get('data') if isinstance(response, dict) else None
+    return data.get('id') if isinstance(data, dict) else None
+
+class ContractClient:
+    """The dev server, called the way an SDK would, inside one database sandbox"""
+
+    def __init__(self, url: str, timeout: float = REQUEST_TIMEOUT):
+        self.url = url.rstrip('/')
+        self.timeout = timeout
+        self.session = requests.Session()
+        self.sandbox: Optional[str] = None
+
+    def spec(self) -> Dict[str, Any]:
+        try:
+            response = self.session.get(self.url + SPEC_PATH, timeout=self.timeout)
+            if response.status_code != 200:
+                raise ContractError(f"{self.url}{SPEC_PATH} answered {response.status_code}")
+            return response.json()
+        except requests.RequestException as e:
+            raise ContractError(f"Cannot reach the dev server at {self.url}: {str(e)}; start it with 'flashflow serve'")
+        except ValueError:
+            raise ContractError(f"{self.url}{SPEC_PATH} is not JSON")
+
+    def open_sandbox(self):
+        """Send every later call to a new sandbox, so fixtures cannot change the dev data"""
+        try:
+            response = self.session.post(f"{self.url}/api/tester/sandboxes", json={'label': 'contract test'}, timeout=self.timeout)
+            body = response.json()
+        except (requests.RequestException, ValueError) as e:
+            raise ContractError(f"Cannot start a database sandbox: {str(e)}")
+        if response.status_code != 201:
+            raise ContractError(f"Fixtures run in a database sandbox, which the server refused: {body.get('error')}")
+        self.sandbox = body['sandbox']['id']
+        self.session.headers[SANDBOX_HEADER] = self.sandbox
+
+    def close(self):
+        if self.sandbox:
+            try:
+                self.session.delete(f"{self.url}/api/tester/sandboxes/{self.sandbox}", timeout=self.timeout)
+            except requests.RequestException:
+                # It expires on its own
+                pass
+            self.sandbox = None
+            self.session.headers.pop(SANDBOX_HEADER, None)
+
+    def call(self, method: str, path: str, query: Dict[str, Any], body: Any) -> Tuple[int, Any]:
+        try:
+            response = self.session.request(method, self.url + path, params=query or None,
+                                            json=body if body is not None else None, timeout=self.timeout)
+        except requests.RequestException as e:
+            raise ContractError(f"{method} {path} failed: {str(e)}")
+        try:
+            return response.status_code, response.json() if response.content else None
+        except ValueError:
+            return response.status_code, None
+
+def _fill_path(operation: Dict[str, Any], params: Dict[str, Any], created: Dict[str, Any]) -> Tuple[Optional[str], Optional[str]]:
+    """The operation's path with its parameters filled in, or None and what is missing"""
+    path = operation['path']
+    for name, value in params.items():
+        if isinstance(value, dict) and 'from' in value:
+            if value['from'] not in created:
+                return None, f"needs the row {value['from']} creates, which it did not"
+            value = created[value['from']]
+        path = path.replace('{' + name + '}', str(value))
+    return path, None
+
+def record_fixtures(contract: Dict[str, Any], client: ContractClient) -> Tuple[List[Dict[str, Any]], List[str]]:
+    """Call every operation once, in a sandbox; the calls that worked, and why the others were left out"""
+    fixtures, skipped = [], []
+    created_by: Dict[str, str] = {}
+    created: Dict[str, Any] = {}
+    operations = contract['operations']
+    ordered = sorted(operations, key=lambda name: (_collection(operations[name]['path']), '{' in operations[name]['path'],
+                                                   METHOD_ORDER.get(operations[name]['method'].lower(), 9), name))
+    for name in ordered:
+        operation = operations[name]
+        params: Dict[str, Any] = {}
+        missing = None
+        for key, value in operation['params'].items():
+            if value['in'] == 'path':
+                source = created_by.get(_collection(operation['path']))
+                if source is None:
+                    missing = f"needs a {key}, and no create fixture makes one"
+                    break
+                params[key] = {'from': source}
+        if missing:
+            skipped.append(f"{name}: {missing}")
+            continue
+        query = {key: sample(value['schema']) for key, value in operation['params'].items()
+                 if value['in'] == 'query' and value['required']}
+        body = sample(operation['body']['schema']) if operation.get('body') else None
+        path, _ = _fill_path(operation, params, created)
+        status, response = client.call(operation['method'], path, query, body)
+        if not 200 <= status < 300:
+            error = response.get('error') if isinstance(response, dict) else None
+            skipped.append(f"{name}: answered {status}" + (f" ({error})" if error else ''))
+            continue
+        if operation['method'] == 'POST' and not params and _created_id(response) is not None:
+            created_by[_collection(operation['path'])] = name
+            created[name] = _created_id(response)
+        fixtures.append({'operation': name, 'params': params, 'query': query, 'body': body,
+                         'status': status, 'response': response})
+    return fixtures, skipped
+
+def load_fixtures(path: Path) -> List[Dict[str, Any]]:
+    if not path.exists():
+        return []
+    try:
+        fixtures = json.loads(path.read_text(encoding='utf-8'))
+    except (OSError, ValueError) as e:
+        raise ContractError(f"Cannot read {path.name}: {str(e)}")
+    if not isinstance(fixtures, list) or not all(isinstance(fixture, dict) and 'operation' in fixture for fixture in fixtures):
+        raise ContractError(f"{path.name} is not a list of fixtures; record them again with --record")
+    return fixtures
+
+def replay_fixtures(contract: Dict[str, Any], fixtures: List[Dict[str, Any]], client: ContractClient) -> List[ContractChange]:
+    """Send each fixture's request; what came back that an SDK built from contract could not handle"""
+    failures = []
+    created: Dict[str, Any] = {}
+    for fixture in fixtures:
+        name = fixture['operation']
+        operation = contract['operations'].get(name)
+        if operation is None:
+            failures.append(ContractChange(name, "is recorded but not in the contract; record the fixtures again", True))
+            continue
+        path, missing = _fill_path(operation, fixture.get('params') or {}, created)
+        if path is None:
+            failures.append(ContractChange(name, missing, True))
+            continue
+        status, response = client.call(operation['method'], path, fixture.get('query') or {}, fixture.get('body'))
+        if status != fixture.get('status'):
+            error = response.get('error') if isinstance(response, dict) else None
+            failures.append(ContractChange(name, f"answered {status} where it answered {fixture.get('status')}"
+                                           + (f": {error}" if error else ''), True))
+            continue
+        failures += [ContractChange(name, problem, True) for problem in fit_problems(operation['response'], response)]
+        if operation['method'] == 'POST' and _created_id(response) is not None:
+            created[name] = _created_id(response)
+    return failures
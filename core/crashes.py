@@ -0,0 +1,241 @@
+"""
+FlashFlow crash reports - Structured dumps of unhandled errors in .flashflow/crashes
+
+    "crash_reports": {
+        "endpoint": "https://crashes.example.com/flashflow",   # optional; FLASHFLOW_CRASH_ENDPOINT overrides
+        "headers": {"Authorization": "Bearer ..."}
+    }
+
+The CLI, the dev server (including errors in request handlers and background
+threads) and the engine write one JSON file per crash with the stack, a
+redacted snapshot of flashflow.json and FLASHFLOW_* settings, and the last
+log lines of the crashing process. Native faults (e.g. in FlashCore) can't be
+caught in Python; faulthandler appends their stacks to crashes/fatal-<service>.log.
+When an endpoint is configured each dump is also POSTed there as JSON.
+"""
+
+import faulthandler
+import json
+import logging
+import os
+import platform
+import re
+import sys
+import threading
+import time
+import traceback
+import uuid
+from collections import deque
+from pathlib import Path
+from typing import Any, Dict, List, Optional
+
+from core.state import ProjectState
+
+CRASHES_DIR = "crashes"
+# Newest dumps kept; older ones are deleted as new ones are written
+MAX_CRASHES = 50
+LOG_LINES = 100
+POST_TIMEOUT = 5
+SECRET_PATTERN = re.compile(r'pass(word)?|secret|token|key|credential|auth', re.I)
+REDACTED = '[redacted]'
+
+class CrashReportError(Exception):
+    """Raised when a crash report cannot be found or read"""
+    pass
+
+class RecentLogHandler(logging.Handler):
+    """Keeps the last log lines of this process for the next crash dump"""
+
+    def __init__(self, capacity: int = LOG_LINES):
+        super().__init__()
+        self.lines = deque(maxlen=capacity)
+        self.setFormatter(logging.Formatter('%(asctime)s %(levelname)s %(name)s: %(message)s'))
+
+    def emit(self, record):
+        try:
+            self.lines.append(self.format(record))
+        except Exception:
+            pass
+
+class CrashReporter:
+    """Writes (and optionally posts) crash dumps for one service of a project"""
+
+    def __init__(self, project_root: Path, service: str, log_file: Optional[Path] = None):
+        self.state = ProjectState(project_root)
+        self.root_path = Path(project_root)
+        self.service = service
+        self.log_file = log_file
+        self.recent_logs = RecentLogHandler()
+        self._fatal_file = None
+
+    def install(self):
+        """Report uncaught exceptions in the main and background threads, and native faults"""
+        logging.getLogger().addHandler(self.recent_logs)
+
+        previous_hook = sys.excepthook
+        def excepthook(exc_type, exc, tb):
+            if not issubclass(exc_type, KeyboardInterrupt):
+                self.report(exc, context={'thread': threading.current_thread().name})
+            previous_hook(exc_type, exc, tb)
+        sys.excepthook = excepthook
+
+        previous_thread_hook = threading.excepthook
+        def thread_excepthook(args):
+            if args.exc_type is not SystemExit:
+                self.report(args.exc_value, context={'thread': args.thread.name if args.thread else None})
+            previous_thread_hook(args)
+        threading.excepthook = thread_excepthook
+
+        self._enable_faulthandler()
+        return self
+
+    def rename(self, service: str, log_file: Optional[Path] = None):
+        """Attribute later crashes to another service, e.g. once 'flashflow serve' starts the dev server"""
+        self.service = service
+        self.log_file = log_file or self.log_file
+        self._enable_faulthandler()
+
+    def _enable_faulthandler(self):
+        try:
+            fatal_file = open(self.state.path(CRASHES_DIR, f"fatal-{self.service}.log"), 'a')
+        except OSError:
+            return
+        faulthandler.enable(file=fatal_file, all_threads=True)
+        if self._fatal_file:
+            self._fatal_file.close()
+        self._fatal_file = fatal_file
+
+    def report(self, exc: BaseException, context: Optional[Dict[str, Any]] = None) -> Optional[Path]:
+        """Write a dump for an exception; never raises, since it runs while something else already failed"""
+        try:
+            dump = self.build(exc, context)
+            path = self.state.path(CRASHES_DIR, f"{dump['id']}.json")
+            with open(path, 'w') as f:
+                json.dump(dump, f, indent=2, default=str)
+            prune(self.state)
+            endpoint = crash_endpoint(self.root_path)
+            if endpoint:
+                dump['posted'] = post_crash(dump, endpoint['url'], endpoint.get('headers'))
+                with open(path, 'w') as f:
+                    json.dump(dump, f, indent=2, default=str)
+            return path
+        except Exception as e:
+            print(f"⚠️  Could not write crash report: {e}", file=sys.stderr)
+            return None
+
+    def build(self, exc: BaseException, context: Optional[Dict[str, Any]] = None) -> Dict[str, Any]:
+        now = time.time()
+        stack = traceback.format_exception(type(exc), exc, exc.__traceback__)
+        return {
+            'id': f"{time.strftime('%Y%m%d-%H%M%S', time.localtime(now))}-{self.service}-{uuid.uuid4().hex[:6]}",
+            'service': self.service,
+            'time': now,
+            'pid': os.getpid(),
+            'argv': sys.argv,
+            'python': sys.version.split()[0],
+            'platform': platform.platform(),
+            'exception': {'type': type(exc).__name__, 'message': str(exc)},
+            'stack': ''.join(stack).rstrip().splitlines(),
+            'context': context or {},
+            'config': config_snapshot(self.root_path),
+            'env': {name: redact(name, value) for name, value in sorted(os.environ.items())
+                    if name.startswith(('FLASHFLOW_', 'OTEL_'))},
+            'logs': list(self.recent_logs.lines) or tail_lines(self.log_file, LOG_LINES),
+            'posted': None
+        }
+
+def redact(name: str, value: Any) -> Any:
+    if isinstance(value, dict):
+        return {key: redact(key, item) for key, item in value.items()}
+    if isinstance(value, list):
+        return [redact(name, item) for item in value]
+    return REDACTED if SECRET_PATTERN.search(str(name)) and value not in (None, '') else value
+
+def config_snapshot(root_path: Path) -> Any:
+    """flashflow.json with secrets removed; read raw, since parsing it may be what failed"""
+    try:
+        return redact('', json.loads((Path(root_path) / "flashflow.json").read_text()))
+    except (OSError, ValueError) as e:
+        return {'error': f"flashflow.json unreadable: {e}"}
+
+def crash_endpoint(root_path: Path) -> Optional[Dict[str, Any]]:
+    url = os.environ.get('FLASHFLOW_CRASH_ENDPOINT')
+    try:
+        settings = json.loads((Path(root_path) / "flashflow.json").read_text()).get('crash_reports') or {}
+    except (OSError, ValueError, AttributeError):
+        settings = {}
+    url = url or settings.get('endpoint')
+    return {'url': url, 'headers': settings.get('headers') or {}} if url else None
+
+def post_crash(dump: Dict[str, Any], url: str, headers: Optional[Dict[str, str]] = None) -> Dict[str, Any]:
+    try:
+        import requests
+        response = requests.post(url, json=dump, headers=headers or {}, timeout=POST_TIMEOUT)
+        return {'url': url, 'status': response.status_code}
+    except Exception as e:
+        return {'url': url, 'error': str(e)}
+
+def tail_lines(path: Optional[Path], count: int) -> List[str]:
+    if not path:
+        return []
+    try:
+        with open(path, 'rb') as f:
+            f.seek(0, os.SEEK_END)
+            f.seek(max(0, f.tell() - 64 * 1024))
+            return f.read().decode('utf-8', errors='replace').splitlines()[-count:]
+    except OSError:
+        return []
+
+def prune(state: ProjectState, keep: int = MAX_CRASHES):
+    for path in crash_files(state)[keep:]:
+        try:
+            path.unlink()
+        except OSError:
+            pass
+
+def crash_files(state: ProjectState) -> List[Path]:
+    """Dumps, newest first"""
+    crashes_dir = state.dir / CRASHES_DIR
+    if not crashes_dir.is_dir():
+        return []
+    return sorted(crashes_dir.glob("*.json"), key=lambda path: path.stat().st_mtime, reverse=True)
+
+def list_crashes(state: ProjectState) -> List[Dict[str, Any]]:
+    summaries = []
+    for path in crash_files(state):
+        try:
+            dump = json.loads(path.read_text())
+        except (OSError, ValueError):
+            continue
+        summaries.append({key: dump.get(key) for key in ('id', 'service', 'time', 'pid', 'exception', 'posted')})
+    return summaries
+
+def load_crash(state: ProjectState, crash_id: str) -> Dict[str, Any]:
+    """A dump by id or unique id prefix; 'latest' is the newest"""
+    files = crash_files(state)
+    if crash_id == 'latest':
+        matches = files[:1]
+    else:
+        matches = [path for path in files if path.stem == crash_id] or [path for path in files if path.stem.startswith(crash_id)]
+    if not matches:
+        raise CrashReportError(f"No crash report '{crash_id}' in {state.dir / CRASHES_DIR}")
+    if len(matches) > 1:
+        raise CrashReportError(f"'{crash_id}' matches {len(matches)} crash reports; use more of the id")
+    try:
+        return json.loads(matches[0].read_text())
+    except (OSError, ValueError) as e:
+        raise CrashReportError(f"Cannot read {matches[0].name}: {e}")
+
+_reporter: Optional[CrashReporter] = None
+
+def install_crash_reporter(project_root: Path, service: str, log_file: Optional[Path] = None) -> CrashReporter:
+    """Install the process-wide reporter once; later calls only rename its service"""
+    global _reporter
+    if _reporter is None:
+        _reporter = CrashReporter(project_root, service, log_file).install()
+    elif _reporter.service != service:
+        _reporter.rename(service, log_file)
+    return _reporter
+
+def get_crash_reporter() -> Optional[CrashReporter]:
+    return _reporter
@@ -0,0 +1,266 @@
+"""
+FlashFlow data transfer - JSON and CSV import/export for dev database tables
+
+An export carries every column of every row. On import, values are coerced to
+the column's type (CSV cells all arrive as text) and rows whose id already
+exists are resolved with a conflict strategy:
+
+    skip        keep the stored row
+    overwrite   replace the stored row; columns the import leaves out become NULL
+    merge       update only the columns the import provides
+
+Empty CSV cells import as NULL. Rows without an id are always inserted.
+Imports are all-or-nothing per table: every row is checked before anything is
+written, and the writes share one transaction.
+"""
+
+import csv
+import io
+import json
+from typing import Dict, Any, List, Optional
+
+from core.database import Storage, StorageError
+
+FORMATS = ['json', 'csv']
+STRATEGIES = ['skip', 'overwrite', 'merge']
+TIMESTAMP_COLUMNS = ('created_at', 'updated_at')
+EXPORT_BATCH = 1000
+MAX_REPORTED_ERRORS = 20
+
+TRUE_VALUES = {'true', 't', '1', 'yes', 'y', 'on'}
+FALSE_VALUES = {'false', 'f', '0', 'no', 'n', 'off'}
+
+class DataTransferError(StorageError):
+    """Raised for unreadable files, unknown columns and values that do not fit a column"""
+    pass
+
+def format_for(filename: str, default: str = 'json') -> str:
+    """Transfer format implied by a file name"""
+    suffix = filename.rsplit('.', 1)[-1].lower() if '.' in filename else ''
+    return suffix if suffix in FORMATS else default
+
+# Export
+
+def export_rows(storage: Storage, table: str) -> List[Dict[str, Any]]:
+    """Every row of a table in id order"""
+    rows, offset = [], 0
+    while True:
+        batch = storage.fetch_rows(table, limit=EXPORT_BATCH, offset=offset)
+        rows.extend(batch)
+        if len(batch) < EXPORT_BATCH:
+            return rows
+        offset += EXPORT_BATCH
+
+def export_tables(storage: Storage, tables: Optional[List[str]] = None) -> Dict[str, Any]:
+    """JSON document with the rows of several tables (all of them by default)"""
+    tables = tables or storage.list_tables()
+    return {'tables': {table: export_rows(storage, table) for table in tables}}
+
+def to_json(data: Any) -> str:
+    return json.dumps(data, indent=2, default=str) + "\n"
+
+def to_csv(storage: Storage, table: str, rows: List[Dict[str, Any]]) -> str:
+    """CSV with a header row; NULL is written as an empty cell"""
+    columns = [column['name'] for column in storage.table_columns(table)]
+    output = io.StringIO()
+    writer = csv.writer(output)
+    writer.writerow(columns)
+    for row in rows:
+        writer.writerow([_csv_cell(row.get(name)) for name in columns])
+    return output.getvalue()
+
+def _csv_cell(value: Any) -> Any:
+    if value is None:
+        return ''
+    if isinstance(value, (dict, list)):
+        return json.dumps(value)
+    return value
+
+# Import
+
+def parse_rows(text: str, fmt: str, table: Optional[str] = None,
+               default_table: Optional[str] = None) -> Dict[str, List[Dict[str, Any]]]:
+    """Rows per table from an export; 'table' selects one, 'default_table' names a CSV or bare list of rows"""
+    if fmt == 'csv':
+        table = table or default_table
+        if not table:
+            raise DataTransferError("A CSV import needs a table name")
+        reader = csv.DictReader(io.StringIO(text.lstrip('\ufeff')))
+        # Empty cells are how exports write NULL
+        return {table: [{name: value if value != '' else None for name, value in row.items()} for row in reader]}
+
+    try:
+        data = json.loads(text)
+    except ValueError as e:
+        raise DataTransferError(f"Invalid JSON: {str(e)}")
+
+    if isinstance(data, dict) and isinstance(data.get('tables'), dict):
+        tables = data['tables']
+        if table:
+            if table not in tables:
+                raise DataTransferError(f"The file has no rows for table '{table}'")
+            tables = {table: tables[table]}
+    elif isinstance(data, dict) and isinstance(data.get('rows'), list) and (table or default_table):
+        tables = {table or default_table: data['rows']}
+    elif isinstance(data, list) and (table or default_table):
+        tables = {table or default_table: data}
+    else:
+        raise DataTransferError("Expected {\"tables\": {...}}, or a list of rows together with a table name")
+
+    for name, rows in tables.items():
+        if not isinstance(rows, list) or not all(isinstance(row, dict) for row in rows):
+            raise DataTransferError(f"Rows for '{name}' must be a list of objects")
+    return tables
+
+def column_kind(sql_type: str) -> str:
+    """Coercion kind for a column's SQL type"""
+    sql_type = (sql_type or '').lower()
+    if 'bool' in sql_type or sql_type == 'tinyint(1)':
+        return 'boolean'
+    if 'int' in sql_type:
+        return 'integer'
+    if any(name in sql_type for name in ('real', 'double', 'float', 'numeric', 'decimal')):
+        return 'float'
+    if 'json' in sql_type:
+        return 'json'
+    return 'text'
+
+def coerce(value: Any, kind: str) -> Any:
+    """Convert an imported value for a column; raises ValueError when it does not fit"""
+    if value is None:
+        return None
+    if isinstance(value, str) and value == '' and kind != 'text':
+        return None
+
+    if kind == 'integer':
+        if isinstance(value, bool):
+            return int(value)
+        if isinstance(value, float):
+            if not value.is_integer():
+                raise ValueError(f"{value!r} is not a whole number")
+            return int(value)
+        try:
+            return int(str(value).strip())
+        except ValueError:
+            raise ValueError(f"{value!r} is not an integer")
+    if kind == 'float':
+        if isinstance(value, bool) or not isinstance(value, (int, float, str)):
+            raise ValueError(f"{value!r} is not a number")
+        try:
+            return float(value)
+        except ValueError:
+            raise ValueError(f"{value!r} is not a number")
+    if kind == 'boolean':
+        if isinstance(value, bool):
+            return value
+        text = str(value).strip().lower()
+        if text in TRUE_VALUES:
+            return True
+        if text in FALSE_VALUES:
+            return False
+        raise ValueError(f"{value!r} is not a boolean")
+    if kind == 'json':
+        if isinstance(value, str):
+            try:
+                json.loads(value)
+            except ValueError:
+                raise ValueError(f"{value!r} is not valid JSON")
+            return value
+        return json.dumps(value)
+
+    if isinstance(value, (dict, list)):
+        return json.dumps(value)
+    if isinstance(value, bool):
+        return 'true' if value else 'false'
+    return value if isinstance(value, str) else str(value)
+
+def prepare_rows(storage: Storage, table: str, rows: List[Dict[str, Any]]) -> List[Dict[str, Any]]:
+    """Coerce every row against the table's columns, reporting all problems at once"""
+    kinds = {column['name']: column_kind(column['type']) for column in storage.table_columns(table)}
+    prepared, errors = [], []
+    for number, row in enumerate(rows, start=1):
+        unknown = sorted(set(row) - set(kinds))
+        if unknown:
+            errors.append(f"row {number}: unknown column(s) {', '.join(unknown)}")
+            continue
+        values = {}
+        for name, value in row.items():
+            try:
+                values[name] = coerce(value, kinds[name])
+            except (TypeError, ValueError) as e:
+                errors.append(f"row {number}: {name}: {str(e)}")
+        prepared.append(values)
+
+    if errors:
+        more = f"\n  ... and {len(errors) - MAX_REPORTED_ERRORS} more" if len(errors) > MAX_REPORTED_ERRORS else ""
+        raise DataTransferError(f"Cannot import into '{table}':\n  " + "\n  ".join(errors[:MAX_REPORTED_ERRORS]) + more)
+    return prepared
+
+def import_rows(storage: Storage, table: str, rows: List[Dict[str, Any]], strategy: str = 'skip') -> Dict[str, Any]:
+    """Write rows into an existing table; returns counts of inserted, updated and skipped rows"""
+    if strategy not in STRATEGIES:
+        raise DataTransferError(f"Unknown conflict strategy '{strategy}'; use {', '.join(STRATEGIES)}")
+    prepared = prepare_rows(storage, table, rows)
+    columns = [column['name'] for column in storage.table_columns(table)]
+    quoted_table = storage.quote(table)
+    result = {'table': table, 'inserted': 0, 'updated': 0, 'skipped': 0}
+
+    with storage.connection() as connection:
+        cursor = connection.cursor()
+        try:
+            existing = _existing_ids(storage, cursor, table, [row['id'] for row in prepared if row.get('id') is not None])
+            explicit_ids = False
+            for row in prepared:
+                row_id = row.get('id')
+                if row_id is None:
+                    row.pop('id', None)
+                elif row_id in existing:
+                    if strategy == 'skip':
+                        result['skipped'] += 1
+                        continue
+                    if strategy == 'overwrite':
+                        values = {name: row.get(name) for name in columns if name != 'id' and (name in row or name not in TIMESTAMP_COLUMNS)}
+                    else:
+                        values = {name: value for name, value in row.items() if name != 'id'}
+                    if values:
+                        assignments = ', '.join(f"{storage.quote(name)} = {storage.placeholder}" for name in values)
+                        if 'updated_at' in columns and 'updated_at' not in values:
+                            assignments += ", updated_at = CURRENT_TIMESTAMP"
+                        cursor.execute(f"UPDATE {quoted_table} SET {assignments} WHERE id = {storage.placeholder}",
+                                       tuple(values.values()) + (row_id,))
+                    result['updated'] += 1
+                    continue
+                else:
+                    explicit_ids = True
+                    existing.add(row_id)  # a repeated id later in the file is a conflict too
+
+                names = ', '.join(storage.quote(name) for name in row)
+                if row:
+                    placeholders = ', '.join([storage.placeholder] * len(row))
+                    cursor.execute(f"INSERT INTO {quoted_table} ({names}) VALUES ({placeholders})", tuple(row.values()))
+                else:
+                    cursor.execute(f"INSERT INTO {quoted_table} () VALUES ()" if storage.driver == 'mysql'
+                                   else f"INSERT INTO {quoted_table} DEFAULT VALUES")
+                result['inserted'] += 1
+
+            if explicit_ids and storage.driver == 'postgres':
+                # Explicit ids do not advance the SERIAL sequence; move it past them
+                cursor.execute(f"SELECT setval(pg_get_serial_sequence(%s, 'id'), COALESCE(MAX(id), 1)) FROM {quoted_table}",
+                               (table,))
+        except StorageError:
+            raise
+        except Exception as e:
+            # Constraint violations and the like; the transaction is rolled back
+            raise DataTransferError(f"Import into '{table}' failed: {str(e).strip()}")
+        finally:
+            cursor.close()
+    return result
+
+def _existing_ids(storage: Storage, cursor, table: str, ids: List[Any]) -> set:
+    found = set()
+    for start in range(0, len(ids), 500):
+        chunk = ids[start:start + 500]
+        cursor.execute(f"SELECT id FROM {storage.quote(table)} WHERE id IN ({', '.join([storage.placeholder] * len(chunk))})",
+                       tuple(chunk))
+        found.update(row[0] for row in cursor.fetchall())
+    return found
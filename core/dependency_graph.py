@@ -0,0 +1,295 @@
+"""
+FlashFlow dependency graph - What each page, model and endpoint is built from
+
+The flow parser records, for every file it reads, the files it includes
+and the pages, models and endpoints it declares; once the IR is complete
+the graph links pages to the models and endpoints their components name:
+
+    page:/orders ──► file:src/flows/orders.flow ──► file:src/layouts/main.flow
+                 ├─► file:src/custom/order-card.flow
+                 ├─► model:Order ──► file:src/flows/models.flow
+                 └─► endpoint:/api/orders ──► model:Order
+
+Files are recorded with a hash of their content. Comparing the graph of a
+build with the previous one (kept in .flashflow/graph.json) gives the files
+that changed, and everything that depends on them is what needs building
+again: a change to an included component rebuilds the pages that include
+it, a model change the backend and the pages that show the model. A page
+whose title or nav settings changed also rebuilds the pages with
+'links: auto' navigation.
+
+Some changes affect everything: flashflow.json, the FlashFlow generators,
+and files with sections other than page, model and endpoint (theme,
+authentication, translations, ...).
+"""
+
+import hashlib
+import json
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Any, Dict, Iterator, List, Optional, Set
+
+from core.build_cache import generator_fingerprint
+from core.flow_lint import endpoints_of, model_mentions, route_key, strings
+from core.parser.flow_file import FlowDocument, find_project_root, parse_flow_text
+
+GRAPH_FILE = 'graph.json'
+# Bumped when the node or edge layout changes, so an old graph means a full build
+GRAPH_FORMAT = 1
+# Sections that belong to one page, model or endpoint; any other section can affect every target
+LOCAL_SECTIONS = ('page', 'model', 'endpoint', 'include')
+NAV_KEYS = ('path', 'title', 'nav', 'nav_title', 'nav_order')
+NAV_COMPONENTS = ('navbar', 'sidebar')
+# _declares_global's answers by file hash, so linking does not parse every file a second time
+_GLOBAL_BY_HASH: Dict[Optional[str], bool] = {}
+
+# The kinds of node each build step is generated from; steps left out (profile) always run
+STEP_INPUTS = {
+    'backend': ('model', 'endpoint'),
+    'frontend': ('page', 'model', 'endpoint'),
+    'media': ('page',),
+    'mobile': ('page', 'model', 'endpoint'),
+    'desktop': ('page', 'model', 'endpoint'),
+    'static': ('page',),
+}
+
+def node_kind(node: str) -> str:
+    return node.partition(':')[0]
+
+def node_name(node: str) -> str:
+    return node.partition(':')[2]
+
+def _file_hash(path: Path) -> Optional[str]:
+    try:
+        return hashlib.sha256(path.read_bytes()).hexdigest()
+    except OSError:
+        return None
+
+def _components(components: Any) -> Iterator[Dict[str, Any]]:
+    """Every component of a body, nested ones included"""
+    for component in components if isinstance(components, list) else []:
+        if not isinstance(component, dict):
+            continue
+        yield component
+        for key in ('children', 'footer', 'body'):
+            yield from _components(component.get(key))
+        for tab in component.get('tabs') or []:
+            if isinstance(tab, dict):
+                yield from _components(tab.get('children'))
+
+@dataclass
+class GraphChanges:
+    """What changed between two builds' graphs"""
+    full: Optional[str] = None
+    files: List[str] = field(default_factory=list)
+    pages: List[str] = field(default_factory=list)
+    models: List[str] = field(default_factory=list)
+    endpoints: List[str] = field(default_factory=list)
+    # Pages the previous build had and this one does not
+    removed_pages: List[str] = field(default_factory=list)
+
+    @property
+    def nothing(self) -> bool:
+        return not self.full and not (self.pages or self.models or self.endpoints or self.removed_pages)
+
+    def affects(self, step: str) -> bool:
+        """Whether a build step has to run again"""
+        if self.full or step not in STEP_INPUTS:
+            return True
+        kinds = STEP_INPUTS[step]
+        return bool(('page' in kinds and (self.pages or self.removed_pages)) or ('model' in kinds and self.models)
+                    or ('endpoint' in kinds and self.endpoints))
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'full': self.full, 'files': self.files, 'pages': self.pages, 'models': self.models,
+                'endpoints': self.endpoints, 'removed_pages': self.removed_pages}
+
+class DependencyGraph:
+    """Nodes (file:, page:, model:, endpoint:) and the nodes each depends on"""
+
+    def __init__(self, root: Optional[Path] = None):
+        self.root = Path(root) if root else None
+        self.fingerprint: Optional[str] = None
+        self.nodes: Dict[str, Dict[str, Any]] = {}
+        self.edges: Dict[str, Set[str]] = {}
+        # Built steps still up to date with this graph, with the '<target>/<env>' they were built for
+        self.steps: Dict[str, str] = {}
+
+    # Recording
+
+    def _file_node(self, path: Path) -> str:
+        path = Path(path).resolve()
+        if self.root is None:
+            self.root = find_project_root(path)
+        try:
+            name = path.relative_to(self.root.resolve()).as_posix()
+        except ValueError:
+            name = path.as_posix()
+        node = f"file:{name}"
+        self.nodes.setdefault(node, {'path': str(path)})
+        return node
+
+    def depend(self, node: str, *dependencies: str):
+        self.nodes.setdefault(node, {})
+        self.edges.setdefault(node, set()).update(dependencies)
+
+    def add_document(self, document: FlowDocument):
+        """A parsed .flow file: what it includes and what it declares"""
+        file_node = self._file_node(document.file)
+        self.depend(file_node, *(self._file_node(path) for path in document.includes))
+        data = document.data if isinstance(document.data, dict) else {}
+        page = data.get('page')
+        if isinstance(page, dict):
+            self.depend(f"page:{page.get('path', '/')}", file_node)
+        for model in document.models:
+            self.depend(f"model:{model.name}", file_node)
+        for endpoint, _ in endpoints_of(data):
+            self.depend(f"endpoint:{endpoint.get('path', '/api/unknown')}", file_node)
+
+    def link(self, ir, project_root: Optional[Path] = None):
+        """Add what only the whole IR shows: the models and endpoints pages use, and pages with no flow file"""
+        if project_root is not None:
+            self.root = Path(project_root)
+        models = {name: model_mentions(name) for name in ir.models}
+        endpoints = {route_key(path): path for path in ir.endpoints}
+
+        def mentioned(data: Any) -> Set[str]:
+            found = set()
+            for text in strings(data):
+                for name, pattern in models.items():
+                    if pattern.fullmatch(text):
+                        found.add(f"model:{name}")
+                if text.startswith('/api/'):
+                    path = endpoints.get(route_key(text.split('?')[0]))
+                    if path is not None:
+                        found.add(f"endpoint:{path}")
+            return found
+
+        for name, model in ir.models.items():
+            self.depend(f"model:{name}", *(node for node in mentioned(model) if node != f"model:{name}"))
+        for path, endpoint in ir.endpoints.items():
+            self.depend(f"endpoint:{path}", *(node for node in mentioned(endpoint) if node != f"endpoint:{path}"))
+        for route, page in ir.pages.items():
+            if not isinstance(page, dict):
+                continue
+            node = f"page:{route}"
+            body = page.get('body')
+            self.depend(node, *mentioned(body))
+            if page.get('source') and self.root is not None:
+                # A content page, or a 'markdown' component reading a file of src/content
+                self.depend(node, self._file_node(self.root / str(page['source'])))
+            for component in _components(body):
+                if component.get('component') == 'markdown' and component.get('src') and self.root is not None:
+                    self.depend(node, self._file_node(self.root / 'src' / 'content' / str(component['src'])))
+            auto_nav = any(component.get('component') in NAV_COMPONENTS and component.get('links') == 'auto'
+                           for component in _components(body))
+            self.nodes[node].update(nav=json.dumps({key: page.get(key) for key in NAV_KEYS}, sort_keys=True, default=str),
+                                    auto_nav=auto_nav)
+
+        for node, data in self.nodes.items():
+            if node_kind(node) == 'file' and 'path' in data:
+                path = Path(data.pop('path'))
+                data['hash'] = _file_hash(path)
+                if data['hash'] not in _GLOBAL_BY_HASH:
+                    _GLOBAL_BY_HASH[data['hash']] = self._declares_global(path)
+                data['global'] = _GLOBAL_BY_HASH[data['hash']]
+        config = (self.root / 'flashflow.json') if self.root else None
+        digest = hashlib.sha256(f"{GRAPH_FORMAT}:{generator_fingerprint()}".encode('utf-8'))
+        if config is not None and config.exists():
+            digest.update(config.read_bytes())
+        self.fingerprint = digest.hexdigest()
+
+    @staticmethod
+    def _declares_global(path: Path) -> bool:
+        if path.suffix != '.flow':
+            return False
+        try:
+            data = parse_flow_text(path.read_text(encoding='utf-8'))
+        except (OSError, UnicodeDecodeError, ValueError):
+            return True
+        if not isinstance(data, dict) or isinstance(data.get('model'), str):
+            # 'model: Name' keeps the model's sections beside it
+            return False
+        return any(key not in LOCAL_SECTIONS and not str(key).startswith('model ') for key in data)
+
+    # Queries
+
+    def of_kind(self, kind: str) -> Set[str]:
+        return {node for node in self.nodes if node_kind(node) == kind}
+
+    def dependents(self, nodes: Set[str]) -> Set[str]:
+        """The nodes, and every node that depends on them directly or through others"""
+        reverse: Dict[str, Set[str]] = {}
+        for node, dependencies in self.edges.items():
+            for dependency in dependencies:
+                reverse.setdefault(dependency, set()).add(node)
+        found, queue = set(nodes), list(nodes)
+        while queue:
+            for dependent in reverse.get(queue.pop(), ()):
+                if dependent not in found:
+                    found.add(dependent)
+                    queue.append(dependent)
+        return found
+
+    def changes_since(self, previous: Optional['DependencyGraph']) -> GraphChanges:
+        """What needs building again since the build that recorded previous"""
+        if previous is None:
+            return GraphChanges(full='no previous build')
+        if previous.fingerprint != self.fingerprint:
+            return GraphChanges(full='flashflow.json or FlashFlow changed')
+
+        files = sorted(node for node in set(self.nodes) | set(previous.nodes) if node_kind(node) == 'file'
+                       and self.nodes.get(node, {}).get('hash') != previous.nodes.get(node, {}).get('hash'))
+        changes = GraphChanges(files=[node_name(node) for node in files])
+        for node in files:
+            if self.nodes.get(node, {}).get('global') or previous.nodes.get(node, {}).get('global'):
+                changes.full = f"{node_name(node)} has sections every target uses"
+                return changes
+
+        dirty = self.dependents(set(files)) | previous.dependents(set(files))
+        current, before = self.of_kind('page'), previous.of_kind('page')
+        renamed = {node for node in current | before
+                   if self.nodes.get(node, {}).get('nav') != previous.nodes.get(node, {}).get('nav')}
+        if renamed:
+            dirty |= {node for node in current if self.nodes[node].get('auto_nav')}
+        # Pages added or removed
+        dirty |= current ^ before
+
+        changes.pages = sorted(node_name(node) for node in dirty & current)
+        changes.removed_pages = sorted(node_name(node) for node in before - current)
+        for kind, names in (('model', changes.models), ('endpoint', changes.endpoints)):
+            names.extend(sorted(node_name(node) for node in dirty & (self.of_kind(kind) | previous.of_kind(kind))))
+        return changes
+
+    # Persistence
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'format': GRAPH_FORMAT, 'fingerprint': self.fingerprint, 'steps': dict(sorted(self.steps.items())),
+                'nodes': dict(sorted(self.nodes.items())),
+                'edges': {node: sorted(dependencies) for node, dependencies in sorted(self.edges.items()) if dependencies}}
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> 'DependencyGraph':
+        graph = cls()
+        graph.fingerprint = data.get('fingerprint')
+        graph.steps = dict(data.get('steps') or {})
+        graph.nodes = {node: dict(value) for node, value in (data.get('nodes') or {}).items()}
+        graph.edges = {node: set(dependencies) for node, dependencies in (data.get('edges') or {}).items()}
+        return graph
+
+    @classmethod
+    def load(cls, path: Path) -> Optional['DependencyGraph']:
+        """The graph saved at path; None when there is none or it is unreadable or of another format"""
+        try:
+            data = json.loads(Path(path).read_text(encoding='utf-8'))
+        except (OSError, ValueError):
+            return None
+        if not isinstance(data, dict) or data.get('format') != GRAPH_FORMAT:
+            return None
+        return cls.from_dict(data)
+
+    def save(self, path: Path):
+        path = Path(path)
+        temporary = path.with_suffix('.tmp')
+        temporary.write_text(json.dumps(self.to_dict(), indent=2), encoding='utf-8')
+        temporary.replace(path)
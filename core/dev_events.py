@@ -0,0 +1,247 @@
+"""
+FlashFlow dev events - Typed notifications from file watchers and builds to the dev server
+
+'flashflow serve' listens on a local socket for the lifetime of the server:
+a unix socket at .flashflow/run/events.sock where there are unix sockets, and
+a TCP port on 127.0.0.1 elsewhere (or when the socket path would be too long).
+Where to connect, and the token to connect with, are in .flashflow/run/events.json.
+
+A connection stays open and carries frames, each a fixed header and a JSON body:
+
+    magic 'FE' (2 bytes) | version (1) | event type (1) | body length (4, big endian) | body
+
+The first frame must be a hello with the token. The event types:
+
+    hello           {token, source, pid}
+    file_changed    {file, path}
+    build_started   {target, trigger}
+    build_finished  {target, seconds}
+    build_failed    {target, seconds, errors: [{file, line, column, message}]}
+
+A listener skips types it does not know, so newer senders still work with older
+servers. Senders never need a server: without one, send() returns False.
+"""
+
+import hmac
+import json
+import os
+import secrets
+import select
+import socket
+import struct
+import threading
+from pathlib import Path
+from typing import Any, BinaryIO, Callable, Dict, Optional, Set, Tuple
+
+MAGIC = b'FE'
+VERSION = 1
+HEADER = struct.Struct('>2sBBI')
+MAX_BODY = 1024 * 1024
+HELLO_TIMEOUT = 5.0
+RUNTIME_NAME = 'events'
+SOCKET_NAME = 'events.sock'
+# sun_path is 108 bytes on Linux and 104 on macOS
+MAX_SOCKET_PATH = 100
+
+EVENT_TYPES = {
+    'hello': 1,
+    'file_changed': 2,
+    'build_started': 3,
+    'build_finished': 4,
+    'build_failed': 5,
+}
+EVENT_NAMES = {code: name for name, code in EVENT_TYPES.items()}
+
+class DevEventError(Exception):
+    """Raised for a malformed frame or a connection that did not authenticate"""
+    pass
+
+def encode_event(name: str, data: Optional[Dict[str, Any]] = None) -> bytes:
+    body = json.dumps(data or {}, default=str).encode('utf-8')
+    if len(body) > MAX_BODY:
+        raise DevEventError(f"{name} event is {len(body)} bytes; the limit is {MAX_BODY}")
+    return HEADER.pack(MAGIC, VERSION, EVENT_TYPES[name], len(body)) + body
+
+def read_event(stream: BinaryIO) -> Optional[Tuple[Optional[str], Dict[str, Any]]]:
+    """The next (name, data) from a stream, None at the end of it; name is None for an unknown type"""
+    header = stream.read(HEADER.size)
+    if not header:
+        return None
+    if len(header) < HEADER.size:
+        raise DevEventError("Connection closed in the middle of a frame")
+    magic, version, code, length = HEADER.unpack(header)
+    if magic != MAGIC or version != VERSION:
+        raise DevEventError(f"Not a dev event frame (magic {magic!r}, version {version})")
+    if length > MAX_BODY:
+        raise DevEventError(f"Frame of {length} bytes is over the limit of {MAX_BODY}")
+    body = stream.read(length)
+    if len(body) < length:
+        raise DevEventError("Connection closed in the middle of a frame")
+    try:
+        data = json.loads(body.decode('utf-8')) if body else {}
+    except (UnicodeDecodeError, json.JSONDecodeError) as e:
+        raise DevEventError(f"Frame body is not JSON: {e}")
+    return EVENT_NAMES.get(code), data if isinstance(data, dict) else {}
+
+class DevEventListener:
+    """The dev server's end: accepts senders and hands their events to handler(name, data, source)"""
+
+    def __init__(self, project, handler: Callable[[str, Dict[str, Any], str], None]):
+        self.project = project
+        self.handler = handler
+        self.token = secrets.token_hex(16)
+        self.transport: Optional[str] = None
+        self.address: Optional[str] = None
+        self._connections: Set[socket.socket] = set()
+        self._server: Optional[socket.socket] = None
+        self._socket_path: Optional[Path] = None
+        self._lock = threading.Lock()
+
+    def start(self):
+        socket_path = (self.project.state.run_dir / SOCKET_NAME).resolve()
+        if hasattr(socket, 'AF_UNIX') and len(str(socket_path)) < MAX_SOCKET_PATH:
+            # Left behind by a server that did not shut down cleanly
+            socket_path.unlink(missing_ok=True)
+            server = socket.socket(socket.AF_UNIX, socket.SOCK_STREAM)
+            server.bind(str(socket_path))
+            os.chmod(socket_path, 0o600)
+            self._socket_path = socket_path
+            self.transport, self.address = 'unix', str(socket_path)
+            record = {'transport': 'unix', 'path': str(socket_path)}
+        else:
+            server = socket.socket(socket.AF_INET, socket.SOCK_STREAM)
+            server.bind(('127.0.0.1', 0))
+            port = server.getsockname()[1]
+            self.transport, self.address = 'tcp', f"127.0.0.1:{port}"
+            record = {'transport': 'tcp', 'host': '127.0.0.1', 'port': port}
+        server.listen(8)
+        self._server = server
+        self.project.state.write_runtime(RUNTIME_NAME, dict(record, token=self.token))
+        threading.Thread(target=self._accept, name='dev-events', daemon=True).start()
+
+    def stop(self):
+        server, self._server = self._server, None
+        if server is None:
+            return
+        try:
+            # shutdown() wakes the accept() of the listening thread on Linux; close() alone may not
+            server.shutdown(socket.SHUT_RDWR)
+        except OSError:
+            pass
+        server.close()
+        # Senders notice and connect to the next server, e.g. after a restart
+        with self._lock:
+            connections = list(self._connections)
+        for connection in connections:
+            try:
+                connection.shutdown(socket.SHUT_RDWR)
+            except OSError:
+                pass
+        if self._socket_path:
+            self._socket_path.unlink(missing_ok=True)
+        self.project.state.clear_runtime(RUNTIME_NAME)
+
+    def _accept(self):
+        while self._server is not None:
+            try:
+                connection, _ = self._server.accept()
+            except OSError:
+                return
+            threading.Thread(target=self._serve, args=(connection,), name='dev-events-connection',
+                             daemon=True).start()
+
+    @property
+    def connections(self) -> int:
+        with self._lock:
+            return len(self._connections)
+
+    def _serve(self, connection: socket.socket):
+        with self._lock:
+            self._connections.add(connection)
+        try:
+            with connection, connection.makefile('rb') as stream:
+                connection.settimeout(HELLO_TIMEOUT)
+                hello = read_event(stream)
+                if hello is None or hello[0] != 'hello' or \
+                        not hmac.compare_digest(str(hello[1].get('token', '')), self.token):
+                    return
+                source = str(hello[1].get('source') or 'unknown')
+                connection.settimeout(None)
+                while True:
+                    event = read_event(stream)
+                    if event is None:
+                        return
+                    name, data = event
+                    if name is not None and name != 'hello':
+                        self.handler(name, data, source)
+        except (OSError, DevEventError):
+            return
+        finally:
+            with self._lock:
+                self._connections.discard(connection)
+
+class DevEventChannel:
+    """A sender's end: connects to the running dev server on first use and again after it restarts"""
+
+    def __init__(self, project, source: str):
+        self.project = project
+        self.source = source
+        self._socket: Optional[socket.socket] = None
+        self._lock = threading.Lock()
+
+    def send(self, name: str, **data) -> bool:
+        """Whether a dev server got the event"""
+        frame = encode_event(name, data)
+        with self._lock:
+            # A second attempt covers a server that restarted since the last event
+            for _ in range(2):
+                if self._socket is not None and self._peer_closed():
+                    self._close()
+                if self._socket is None and not self._connect():
+                    return False
+                try:
+                    self._socket.sendall(frame)
+                    return True
+                except OSError:
+                    self._close()
+            return False
+
+    def close(self):
+        with self._lock:
+            self._close()
+
+    def _connect(self) -> bool:
+        record = self.project.state.read_runtime(RUNTIME_NAME)
+        if not record:
+            return False
+        connection = None
+        try:
+            if record.get('transport') == 'unix':
+                connection = socket.socket(socket.AF_UNIX, socket.SOCK_STREAM)
+                connection.settimeout(HELLO_TIMEOUT)
+                connection.connect(record['path'])
+            else:
+                connection = socket.create_connection((record['host'], record['port']), timeout=HELLO_TIMEOUT)
+            connection.sendall(encode_event('hello', {'token': record.get('token'), 'source': self.source,
+                                                      'pid': os.getpid()}))
+        except (OSError, KeyError, AttributeError):
+            if connection is not None:
+                connection.close()
+            return False
+        self._socket = connection
+        return True
+
+    def _peer_closed(self) -> bool:
+        # The server never writes, so a readable socket means it went away; writing first would seem to work
+        try:
+            return bool(select.select([self._socket], [], [], 0)[0])
+        except (OSError, ValueError):
+            return True
+
+    def _close(self):
+        if self._socket is not None:
+            try:
+                self._socket.close()
+            except OSError:
+                pass
+            self._socket = None
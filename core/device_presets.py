@@ -0,0 +1,165 @@
+"""
+FlashFlow device presets - The devices /preview emulates, shared by a team
+
+Built-in presets cover Android, iOS, tablet and desktop. A project adds its
+own, or changes a built-in one, in devices.json next to flashflow.json,
+which is meant to be committed:
+
+    {
+      "pixel-8": {
+        "label": "Pixel 8",
+        "width": 412, "height": 915,           # CSS pixels
+        "dpr": 2.625,                          # window.devicePixelRatio
+        "user_agent": "Mozilla/5.0 (Linux; Android 14; Pixel 8) ...",
+        "touch": true,                         # mouse input arrives as touch events
+        "mobile": true                         # honours the page's viewport meta
+      }
+    }
+
+Fields left out keep the built-in preset's value when the name is a
+built-in one, otherwise the defaults below. /preview edits the file through
+/api/preview/devices.
+"""
+
+import json
+import re
+import threading
+from dataclasses import asdict, dataclass
+from pathlib import Path
+from typing import Any, Dict, Optional
+
+PRESETS_FILE = 'devices.json'
+PRESET_NAME = re.compile(r'^[a-z0-9][a-z0-9_-]{0,31}$')
+PRESET_FIELDS = ('label', 'icon', 'width', 'height', 'dpr', 'user_agent', 'touch', 'mobile')
+MIN_SIZE, MAX_SIZE = 120, 7680
+MIN_DPR, MAX_DPR = 0.5, 5.0
+
+ANDROID_UA = ('Mozilla/5.0 (Linux; Android 14; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) '
+              'Chrome/124.0.0.0 Mobile Safari/537.36')
+IOS_UA = ('Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) '
+          'Version/17.4 Mobile/15E148 Safari/604.1')
+TABLET_UA = ('Mozilla/5.0 (iPad; CPU OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) '
+             'Version/17.4 Mobile/15E148 Safari/604.1')
+
+BUILTIN_PRESETS = {
+    'android': {'label': 'Android', 'icon': '🤖', 'width': 412, 'height': 915, 'dpr': 2.625,
+                'user_agent': ANDROID_UA, 'touch': True, 'mobile': True},
+    'ios': {'label': 'iOS', 'icon': '🍎', 'width': 390, 'height': 844, 'dpr': 3,
+            'user_agent': IOS_UA, 'touch': True, 'mobile': True},
+    'tablet': {'label': 'Tablet', 'icon': '📲', 'width': 820, 'height': 1180, 'dpr': 2,
+               'user_agent': TABLET_UA, 'touch': True, 'mobile': True},
+    'desktop': {'label': 'Desktop', 'icon': '🖥️', 'width': 1440, 'height': 900, 'dpr': 1,
+                'user_agent': '', 'touch': False, 'mobile': False},
+}
+
+class DevicePresetError(Exception):
+    """Raised for an invalid preset or devices.json"""
+    pass
+
+@dataclass
+class DevicePreset:
+    """A device the preview frames emulate"""
+    name: str
+    label: str
+    width: int
+    height: int
+    dpr: float = 1.0
+    user_agent: str = ''
+    touch: bool = False
+    mobile: bool = False
+    icon: str = '📱'
+    source: str = 'builtin'
+
+    @classmethod
+    def from_definition(cls, name: str, definition: Any, base: Optional[Dict[str, Any]] = None,
+                        source: str = 'project') -> 'DevicePreset':
+        if not PRESET_NAME.match(name):
+            raise DevicePresetError(f"Device name '{name}' must be lowercase letters, digits, '-' or '_' (at most 32)")
+        if not isinstance(definition, dict):
+            raise DevicePresetError(f"Device '{name}' must be an object of {', '.join(PRESET_FIELDS)}")
+        unknown = sorted(set(definition) - set(PRESET_FIELDS))
+        if unknown:
+            raise DevicePresetError(f"Device '{name}' has unknown field(s) {', '.join(unknown)}; use {', '.join(PRESET_FIELDS)}")
+        values = dict(base or {}, **definition)
+        if 'width' not in values or 'height' not in values:
+            raise DevicePresetError(f"Device '{name}' needs a width and a height")
+        try:
+            width, height, dpr = int(values['width']), int(values['height']), float(values.get('dpr', 1))
+        except (TypeError, ValueError):
+            raise DevicePresetError(f"Device '{name}': width, height and dpr must be numbers")
+        if not (MIN_SIZE <= width <= MAX_SIZE and MIN_SIZE <= height <= MAX_SIZE):
+            raise DevicePresetError(f"Device '{name}': width and height must be from {MIN_SIZE} to {MAX_SIZE} CSS pixels")
+        if not MIN_DPR <= dpr <= MAX_DPR:
+            raise DevicePresetError(f"Device '{name}': dpr must be from {MIN_DPR:g} to {MAX_DPR:g}")
+        for flag in ('touch', 'mobile'):
+            if not isinstance(values.get(flag, False), bool):
+                raise DevicePresetError(f"Device '{name}': {flag} must be true or false")
+        return cls(name, str(values.get('label') or name), width, height, dpr, str(values.get('user_agent') or ''),
+                   values.get('touch', False), values.get('mobile', False), str(values.get('icon') or '📱'), source)
+
+    @property
+    def viewport_meta(self) -> str:
+        """The viewport the device lays the page out at: the page's own on mobile, the window on desktop"""
+        return 'width=device-width, initial-scale=1' if self.mobile else f"width={self.width}"
+
+    def definition(self) -> Dict[str, Any]:
+        """The preset as written to devices.json"""
+        return {key: value for key, value in asdict(self).items() if key in PRESET_FIELDS}
+
+    def to_dict(self) -> Dict[str, Any]:
+        return asdict(self)
+
+class DevicePresets:
+    """Built-in presets with the project's devices.json over them"""
+
+    def __init__(self, project_root: Path):
+        self.path = Path(project_root) / PRESETS_FILE
+        self._lock = threading.Lock()
+
+    def _read(self) -> Dict[str, Any]:
+        if not self.path.exists():
+            return {}
+        try:
+            data = json.loads(self.path.read_text(encoding='utf-8'))
+        except (OSError, ValueError) as e:
+            raise DevicePresetError(f"{PRESETS_FILE} cannot be read: {e}")
+        if not isinstance(data, dict):
+            raise DevicePresetError(f"{PRESETS_FILE} must map device names to presets")
+        return data
+
+    def _write(self, data: Dict[str, Any]):
+        temporary = self.path.with_suffix('.tmp')
+        temporary.write_text(json.dumps(dict(sorted(data.items())), indent=2, ensure_ascii=False) + '\n', encoding='utf-8')
+        temporary.replace(self.path)
+
+    def all(self) -> Dict[str, DevicePreset]:
+        """Every preset by name, built-in ones first"""
+        presets = {name: DevicePreset.from_definition(name, values, source='builtin')
+                   for name, values in BUILTIN_PRESETS.items()}
+        for name, definition in self._read().items():
+            presets[name] = DevicePreset.from_definition(name, definition, BUILTIN_PRESETS.get(name),
+                                                         'override' if name in BUILTIN_PRESETS else 'project')
+        return presets
+
+    def get(self, name: str) -> Optional[DevicePreset]:
+        return self.all().get(name)
+
+    def save(self, name: str, definition: Any) -> DevicePreset:
+        """Add or replace a project preset; a built-in name overrides that preset"""
+        preset = DevicePreset.from_definition(name, definition, BUILTIN_PRESETS.get(name),
+                                              'override' if name in BUILTIN_PRESETS else 'project')
+        with self._lock:
+            data = self._read()
+            data[name] = preset.definition()
+            self._write(data)
+        return preset
+
+    def remove(self, name: str) -> bool:
+        """Remove a project preset (a built-in one it overrode comes back); False when there is none"""
+        with self._lock:
+            data = self._read()
+            if name not in data:
+                return False
+            del data[name]
+            self._write(data)
+        return True
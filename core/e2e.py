@@ -0,0 +1,326 @@
+"""
+FlashFlow end-to-end tests - Scenarios from the flows, run in a headless browser
+
+A flow file declares scenarios in an 'e2e' section. They start on the
+file's page unless their first step visits somewhere else:
+
+    page:
+      path: /register
+      ...
+    e2e:
+      - name: Sign up
+        steps:
+          - fill: {"#email": "e2e-{{ run }}@example.test", "#password": "Secret-{{ run }}"}
+          - submit: "form"
+          - expect_url: /dashboard
+          - expect_text: Welcome
+      - name: Unknown page
+        steps:
+          - visit: {path: /nowhere, status: 404}
+
+Steps (selectors are CSS):
+
+    visit: /path                  open a page; fails on status 400 and up
+    visit: {path: /x, status: 404}    ... expecting that status instead
+    click: selector
+    fill: {selector: value, ...}  type into inputs
+    select: {selector: value}     choose an <option>
+    check: selector               tick a checkbox
+    submit: selector              submit a form as a click on its submit button would
+    press: Enter                  a key, on the focused element
+    wait: 500                     milliseconds, or a selector to wait for
+    expect_url: /dashboard        the path (and query, if given); * matches anything
+    expect_title: Orders
+    expect_text: Welcome          somewhere on the page, or {selector: text}
+    expect_no_text: Error
+    expect_visible: selector
+    expect_hidden: selector
+    screenshot: name              .flashflow/e2e/<name>.png
+
+Strings may hold '{{ run }}' (a short id of this run) and the other
+expressions of load test bodies (core/load_test.py), so values stay unique
+across runs. A failing scenario leaves a screenshot of the page it failed
+on next to them. The browser is Playwright's Chromium (see
+cli/commands/test.py); the runner only talks to the Driver interface.
+"""
+
+import fnmatch
+import random
+import re
+import time
+import uuid
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+from urllib.parse import urlsplit
+
+from core.load_test import LoadTestError, render_template
+from core.parser.flow_file import FlowParseError, load_flow
+
+ACTIONS = ('visit', 'click', 'fill', 'select', 'check', 'submit', 'press', 'wait', 'expect_url', 'expect_title',
+           'expect_text', 'expect_no_text', 'expect_visible', 'expect_hidden', 'screenshot')
+DEFAULT_TIMEOUT = 10.0
+
+class E2eError(Exception):
+    """Raised for an invalid 'e2e' section or a browser that cannot be started"""
+    pass
+
+class StepFailed(AssertionError):
+    """Raised by a step whose action or expectation did not hold"""
+    pass
+
+@dataclass
+class Step:
+    action: str
+    value: Any
+
+    def describe(self) -> str:
+        if isinstance(self.value, dict):
+            return f"{self.action}: " + ', '.join(f"{key}={value}" for key, value in self.value.items())
+        return f"{self.action}: {self.value}"
+
+@dataclass
+class Scenario:
+    name: str
+    file: str
+    steps: List[Step]
+    # The file's page, opened first unless the scenario starts with a visit
+    start: Optional[str] = None
+
+    @property
+    def slug(self) -> str:
+        return re.sub(r'[^a-z0-9]+', '-', f"{Path(self.file).stem} {self.name}".lower()).strip('-')
+
+@dataclass
+class ScenarioResult:
+    scenario: Scenario
+    passed: bool
+    seconds: float
+    steps_run: int = 0
+    failure: Optional[str] = None
+    failed_step: Optional[str] = None
+    screenshot: Optional[str] = None
+    screenshots: List[str] = field(default_factory=list)
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'name': self.scenario.name, 'file': self.scenario.file, 'passed': self.passed,
+                'seconds': round(self.seconds, 3), 'steps_run': self.steps_run, 'steps': len(self.scenario.steps),
+                'failure': self.failure, 'failed_step': self.failed_step, 'screenshot': self.screenshot,
+                'screenshots': self.screenshots}
+
+def parse_scenarios(data: Any, file: str, start: Optional[str] = None) -> List[Scenario]:
+    """The scenarios of an 'e2e' section; raises E2eError naming the first problem"""
+    if not isinstance(data, list):
+        raise E2eError(f"{file}: 'e2e' must be a list of scenarios with a 'name' and 'steps'")
+    scenarios, names = [], set()
+    for position, item in enumerate(data, 1):
+        if not isinstance(item, dict) or not isinstance(item.get('steps'), list) or not item['steps']:
+            raise E2eError(f"{file}: e2e scenario {position} needs a list of 'steps'")
+        name = str(item.get('name') or f"scenario {position}")
+        if name in names:
+            raise E2eError(f"{file}: two e2e scenarios are named '{name}'")
+        names.add(name)
+        steps = [_step(step, f"{file}: '{name}' step {number}") for number, step in enumerate(item['steps'], 1)]
+        scenarios.append(Scenario(name, file, steps, start))
+    return scenarios
+
+def _step(data: Any, where: str) -> Step:
+    if not isinstance(data, dict) or len(data) != 1:
+        raise E2eError(f"{where} must be one 'action: value', e.g. 'click: button'")
+    action, value = next(iter(data.items()))
+    if action not in ACTIONS:
+        raise E2eError(f"{where}: unknown action '{action}'. Use one of: {', '.join(ACTIONS)}")
+    if action in ('fill', 'select') and (not isinstance(value, dict) or not value):
+        raise E2eError(f"{where}: '{action}' takes a mapping of selector to value")
+    if action == 'visit' and isinstance(value, dict) and not value.get('path'):
+        raise E2eError(f"{where}: 'visit' needs a path")
+    if action == 'wait' and (isinstance(value, bool) or not isinstance(value, (int, float, str))):
+        raise E2eError(f"{where}: 'wait' takes milliseconds or a selector")
+    if value is None or value == '':
+        raise E2eError(f"{where}: '{action}' needs a value")
+    return Step(action, value)
+
+def load_scenarios(project) -> Tuple[List[Scenario], List[str]]:
+    """Every scenario in the project's flows, and the problems of files whose 'e2e' section is invalid"""
+    scenarios, errors = [], []
+    for flow_file in sorted(project.get_flow_files()):
+        try:
+            document = load_flow(flow_file, project.root_path)
+        except FlowParseError as e:
+            if 'e2e:' in flow_file.read_text(encoding='utf-8', errors='replace'):
+                errors.append(f"{flow_file.name}: {str(e)}")
+            continue
+        if not isinstance(document.data, dict) or 'e2e' not in document.data:
+            continue
+        try:
+            scenarios.extend(parse_scenarios(document.data['e2e'], flow_file.name, document.route))
+        except E2eError as e:
+            errors.append(str(e))
+    return scenarios, errors
+
+class Driver:
+    """What the runner needs from a browser; see PlaywrightDriver in cli/commands/test.py"""
+
+    def new_page(self):
+        raise NotImplementedError
+
+    def goto(self, url: str) -> Optional[int]:
+        raise NotImplementedError
+
+    def click(self, selector: str):
+        raise NotImplementedError
+
+    def fill(self, selector: str, value: str):
+        raise NotImplementedError
+
+    def select(self, selector: str, value: str):
+        raise NotImplementedError
+
+    def check(self, selector: str):
+        raise NotImplementedError
+
+    def submit(self, selector: str):
+        raise NotImplementedError
+
+    def press(self, key: str):
+        raise NotImplementedError
+
+    def wait_for(self, selector: str):
+        raise NotImplementedError
+
+    def url(self) -> str:
+        raise NotImplementedError
+
+    def title(self) -> str:
+        raise NotImplementedError
+
+    def text(self, selector: str = 'body') -> str:
+        raise NotImplementedError
+
+    def visible(self, selector: str) -> bool:
+        raise NotImplementedError
+
+    def screenshot(self, path: Path):
+        raise NotImplementedError
+
+    def close(self):
+        pass
+
+class E2eRunner:
+    """Runs scenarios one after another, each on a fresh page"""
+
+    def __init__(self, driver: Driver, base_url: str, screenshots_dir: Path, timeout: float = DEFAULT_TIMEOUT):
+        self.driver = driver
+        self.base_url = base_url.rstrip('/')
+        self.screenshots_dir = Path(screenshots_dir)
+        self.timeout = timeout
+        self.context = {'run': uuid.uuid4().hex[:8], 'random': random.random()}
+
+    def run(self, scenarios: List[Scenario], on_result=None) -> List[ScenarioResult]:
+        results = []
+        for scenario in scenarios:
+            result = self.run_scenario(scenario)
+            results.append(result)
+            if on_result:
+                on_result(result)
+        return results
+
+    def run_scenario(self, scenario: Scenario) -> ScenarioResult:
+        started = time.time()
+        result = ScenarioResult(scenario, False, 0.0)
+        self.driver.new_page()
+        opening = Step('visit', scenario.start) if scenario.start and scenario.steps[0].action != 'visit' else None
+        step = None
+        try:
+            for step in ([opening] if opening else []) + scenario.steps:
+                self.run_step(step, scenario, result)
+                if step is not opening:
+                    result.steps_run += 1
+            result.passed = True
+        except (StepFailed, E2eError) as e:
+            result.failure = str(e)
+        except Exception as e:
+            # Browser errors (timeouts, missing elements) fail the scenario, not the run
+            result.failure = f"{type(e).__name__}: {str(e).splitlines()[0] if str(e) else ''}"
+        if not result.passed:
+            result.failed_step = step.describe() if step else None
+            result.screenshot = self._screenshot(f"{scenario.slug}-failed")
+        result.seconds = time.time() - started
+        return result
+
+    def run_step(self, step: Step, scenario: Scenario, result: ScenarioResult):
+        try:
+            value = render_template(step.value, dict(self.context, scenario=scenario.name))
+        except LoadTestError as e:
+            raise E2eError(str(e))
+        driver = self.driver
+        if step.action == 'visit':
+            path, expected = (value.get('path'), value.get('status')) if isinstance(value, dict) else (value, None)
+            status = driver.goto(self.url(str(path)))
+            if expected is not None and status != int(expected):
+                raise StepFailed(f"{path} answered {status}, expected {expected}")
+            if expected is None and status is not None and status >= 400:
+                raise StepFailed(f"{path} answered {status}")
+        elif step.action == 'click':
+            driver.click(str(value))
+        elif step.action in ('fill', 'select'):
+            for selector, text in value.items():
+                getattr(driver, step.action)(str(selector), str(text))
+        elif step.action == 'check':
+            driver.check(str(value))
+        elif step.action == 'submit':
+            driver.submit(str(value))
+        elif step.action == 'press':
+            driver.press(str(value))
+        elif step.action == 'wait':
+            if isinstance(value, (int, float)):
+                time.sleep(min(float(value), self.timeout * 1000) / 1000)
+            else:
+                driver.wait_for(str(value))
+        elif step.action == 'expect_url':
+            self._eventually(lambda: url_matches(driver.url(), str(value)),
+                             lambda: f"Expected to be at {value}, but the page is {driver.url()}")
+        elif step.action == 'expect_title':
+            self._eventually(lambda: driver.title() == str(value),
+                             lambda: f"Expected the title '{value}', got '{driver.title()}'")
+        elif step.action in ('expect_text', 'expect_no_text'):
+            selector, text = next(iter(value.items())) if isinstance(value, dict) else ('body', value)
+            wanted = step.action == 'expect_text'
+            self._eventually(lambda: (str(text) in driver.text(str(selector))) == wanted,
+                             lambda: f"Expected {'' if wanted else 'no '}'{text}' in {selector}")
+        elif step.action in ('expect_visible', 'expect_hidden'):
+            wanted = step.action == 'expect_visible'
+            self._eventually(lambda: driver.visible(str(value)) == wanted,
+                             lambda: f"Expected {value} to be {'visible' if wanted else 'hidden'}")
+        elif step.action == 'screenshot':
+            result.screenshots.append(self._screenshot(re.sub(r'[^A-Za-z0-9_.-]+', '-', str(value))))
+
+    def url(self, path: str) -> str:
+        return path if re.match(r'^https?://', path) else f"{self.base_url}/{path.lstrip('/')}"
+
+    def _eventually(self, check, message):
+        """Pages update after the step that changed them, so expectations retry until the timeout"""
+        deadline = time.time() + self.timeout
+        while True:
+            if check():
+                return
+            if time.time() >= deadline:
+                raise StepFailed(message())
+            time.sleep(0.1)
+
+    def _screenshot(self, name: str) -> Optional[str]:
+        self.screenshots_dir.mkdir(parents=True, exist_ok=True)
+        path = self.screenshots_dir / f"{name}.png"
+        try:
+            self.driver.screenshot(path)
+        except Exception:
+            return None
+        return str(path)
+
+def url_matches(url: str, expected: str) -> bool:
+    """expected is a path, with a query when it matters, and '*' for any characters"""
+    parts = urlsplit(url)
+    actual = parts.path + (f"?{parts.query}" if parts.query and '?' in expected else '')
+    if re.match(r'^https?://', expected):
+        actual = url
+    return fnmatch.fnmatchcase(actual.rstrip('/') or '/', expected.rstrip('/') or '/')
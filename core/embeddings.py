@@ -0,0 +1,252 @@
+"""
+FlashFlow embeddings - Text to vectors with a tokenizer and an ONNX embedding model
+
+Configure in flashflow.json:
+
+    "embeddings": {
+        "model": "models/all-MiniLM-L6-v2.onnx",
+        "tokenizer": "models/vocab.txt",     # WordPiece vocab.txt, or a tokenizer.json (needs 'tokenizers')
+        "max_length": 256,                   # tokens per text, including [CLS] and [SEP]
+        "lowercase": true,                   # vocab.txt only; tokenizer.json knows its own
+        "pooling": "mean",                   # mean, cls, or none when the model already pools
+        "normalize": true,                   # unit-length vectors
+        "batch_size": 32
+    }
+
+Paths are relative to the project root. The model gets input_ids,
+attention_mask and token_type_ids, whichever of them it declares.
+"""
+
+import unicodedata
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Dict, Any, List, Optional
+
+from core.cancellation import CancelToken
+from core.inference import InferenceOptions, create_session, run_session
+
+POOLING = ('mean', 'cls', 'none')
+TOKENIZER_INPUTS = ('input_ids', 'attention_mask', 'token_type_ids')
+# Words longer than this become [UNK], as in BERT's reference tokenizer
+MAX_WORD_CHARS = 100
+
+class EmbeddingError(Exception):
+    """Raised for invalid 'embeddings' settings or a model/tokenizer that cannot be used"""
+    pass
+
+@dataclass
+class EmbeddingSettings:
+    model: str
+    tokenizer: str
+    max_length: int = 256
+    lowercase: bool = True
+    pooling: str = 'mean'
+    normalize: bool = True
+    batch_size: int = 32
+
+    @classmethod
+    def from_dict(cls, data: Optional[Dict[str, Any]]) -> 'EmbeddingSettings':
+        if not data:
+            raise EmbeddingError("No 'embeddings' settings in flashflow.json; set at least 'model' and 'tokenizer'")
+        if not isinstance(data, dict):
+            raise EmbeddingError("'embeddings' in flashflow.json must be an object")
+        unknown = set(data) - {'model', 'tokenizer', 'max_length', 'lowercase', 'pooling', 'normalize', 'batch_size'}
+        if unknown:
+            raise EmbeddingError(f"Unknown embeddings setting(s): {', '.join(sorted(unknown))}")
+        for key in ('model', 'tokenizer'):
+            if not isinstance(data.get(key), str) or not data[key]:
+                raise EmbeddingError(f"embeddings.{key} must be a path relative to the project root")
+        pooling = str(data.get('pooling', 'mean')).lower()
+        if pooling not in POOLING:
+            raise EmbeddingError(f"embeddings.pooling must be one of {', '.join(POOLING)}")
+        return cls(
+            model=data['model'],
+            tokenizer=data['tokenizer'],
+            max_length=_positive(data.get('max_length', 256), 'max_length', minimum=3),
+            lowercase=bool(data.get('lowercase', True)),
+            pooling=pooling,
+            normalize=bool(data.get('normalize', True)),
+            batch_size=_positive(data.get('batch_size', 32), 'batch_size')
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'model': self.model, 'tokenizer': self.tokenizer, 'max_length': self.max_length, 'lowercase': self.lowercase,
+                'pooling': self.pooling, 'normalize': self.normalize, 'batch_size': self.batch_size}
+
+def _positive(value: Any, name: str, minimum: int = 1) -> int:
+    if isinstance(value, bool) or not isinstance(value, int) or value < minimum:
+        raise EmbeddingError(f"embeddings.{name} must be a whole number of at least {minimum}")
+    return value
+
+class WordPieceTokenizer:
+    """BERT-style tokenization from a vocab.txt: basic splitting, then greedy longest-match word pieces"""
+
+    kind = 'wordpiece'
+
+    def __init__(self, vocab_path: Path, max_length: int, lowercase: bool = True):
+        with open(vocab_path, 'r', encoding='utf-8') as f:
+            self.vocab = {line.rstrip('\n'): index for index, line in enumerate(f) if line.rstrip('\n')}
+        for token in ('[CLS]', '[SEP]', '[UNK]'):
+            if token not in self.vocab:
+                raise EmbeddingError(f"{vocab_path} has no {token} token; is it a WordPiece vocab.txt?")
+        self.max_length = max_length
+        self.lowercase = lowercase
+        self.pad_id = self.vocab.get('[PAD]', 0)
+
+    def encode_batch(self, texts: List[str]) -> Dict[str, List[List[int]]]:
+        rows = [self._ids(text) for text in texts]
+        width = max(len(row) for row in rows)
+        return {
+            'input_ids': [row + [self.pad_id] * (width - len(row)) for row in rows],
+            'attention_mask': [[1] * len(row) + [0] * (width - len(row)) for row in rows],
+            'token_type_ids': [[0] * width for _ in rows]
+        }
+
+    def _ids(self, text: str) -> List[int]:
+        pieces = []
+        for word in self._words(text):
+            pieces.extend(self._pieces(word))
+            if len(pieces) >= self.max_length - 2:
+                break
+        ids = [self.vocab[piece] for piece in pieces[:self.max_length - 2]]
+        return [self.vocab['[CLS]']] + ids + [self.vocab['[SEP]']]
+
+    def _words(self, text: str) -> List[str]:
+        text = ''.join(' ' if unicodedata.category(char).startswith('Z') or char in '\t\n\r' else char
+                       for char in text if char != '\ufffd' and not _is_control(char))
+        if self.lowercase:
+            text = ''.join(char for char in unicodedata.normalize('NFD', text.lower()) if unicodedata.category(char) != 'Mn')
+        words = []
+        for chunk in text.split():
+            word = ''
+            for char in chunk:
+                # Punctuation and CJK characters are words of their own
+                if _is_punctuation(char) or _is_cjk(char):
+                    if word:
+                        words.append(word)
+                    words.append(char)
+                    word = ''
+                else:
+                    word += char
+            if word:
+                words.append(word)
+        return words
+
+    def _pieces(self, word: str) -> List[str]:
+        if len(word) > MAX_WORD_CHARS:
+            return ['[UNK]']
+        pieces, start = [], 0
+        while start < len(word):
+            end = len(word)
+            while end > start:
+                piece = word[start:end] if start == 0 else '##' + word[start:end]
+                if piece in self.vocab:
+                    break
+                end -= 1
+            if end == start:
+                return ['[UNK]']
+            pieces.append(piece)
+            start = end
+        return pieces
+
+class HuggingFaceTokenizer:
+    """A tokenizer.json, through the 'tokenizers' package"""
+
+    kind = 'tokenizer.json'
+
+    def __init__(self, path: Path, max_length: int):
+        try:
+            from tokenizers import Tokenizer
+        except ImportError:
+            raise EmbeddingError("tokenizer.json files need the 'tokenizers' package: pip install tokenizers "
+                                 "(or point embeddings.tokenizer at a WordPiece vocab.txt)")
+        self.tokenizer = Tokenizer.from_file(str(path))
+        self.tokenizer.enable_truncation(max_length)
+        self.tokenizer.enable_padding()
+
+    def encode_batch(self, texts: List[str]) -> Dict[str, List[List[int]]]:
+        encodings = self.tokenizer.encode_batch(texts)
+        return {
+            'input_ids': [encoding.ids for encoding in encodings],
+            'attention_mask': [encoding.attention_mask for encoding in encodings],
+            'token_type_ids': [encoding.type_ids for encoding in encodings]
+        }
+
+def load_tokenizer(path: Path, settings: EmbeddingSettings):
+    if not path.exists():
+        raise EmbeddingError(f"Tokenizer file {path} does not exist")
+    if path.suffix == '.json':
+        return HuggingFaceTokenizer(path, settings.max_length)
+    return WordPieceTokenizer(path, settings.max_length, settings.lowercase)
+
+class Embedder:
+    """Tokenizer plus ONNX model, turning texts into vectors"""
+
+    def __init__(self, settings: EmbeddingSettings, root_path: Path, options: Optional[InferenceOptions] = None):
+        self.settings = settings
+        root_path = Path(root_path)
+        self.tokenizer = load_tokenizer(root_path / settings.tokenizer, settings)
+        model_path = root_path / settings.model
+        if not model_path.exists():
+            raise EmbeddingError(f"Embedding model {model_path} does not exist")
+        self.session, self.info = create_session(str(model_path), options or InferenceOptions())
+        self.inputs = [tensor.name for tensor in self.session.get_inputs()]
+        missing = [name for name in self.inputs if name not in TOKENIZER_INPUTS]
+        if missing:
+            raise EmbeddingError(f"The embedding model needs input(s) {', '.join(missing)}; "
+                                 f"only {', '.join(TOKENIZER_INPUTS)} come from the tokenizer")
+        self.dimension: Optional[int] = None
+
+    @classmethod
+    def for_project(cls, project) -> 'Embedder':
+        return cls(EmbeddingSettings.from_dict(project.config.embeddings), project.root_path,
+                   InferenceOptions.for_project(project))
+
+    def embed(self, texts: List[str], cancel: Optional[CancelToken] = None) -> List[List[float]]:
+        """One vector per text, in order; raises Cancelled when cancel is cancelled between or during batches"""
+        vectors = []
+        for start in range(0, len(texts), self.settings.batch_size):
+            vectors.extend(self._embed_batch(texts[start:start + self.settings.batch_size], cancel))
+        if vectors:
+            self.dimension = len(vectors[0])
+        return vectors
+
+    def _embed_batch(self, texts: List[str], cancel: Optional[CancelToken] = None) -> List[List[float]]:
+        import numpy as np
+        encoded = self.tokenizer.encode_batch(texts)
+        feeds = {name: np.asarray(encoded[name], dtype=np.int64) for name in self.inputs}
+        output = np.asarray(run_session(self.session, None, feeds, cancel)[0], dtype=np.float32)
+
+        if output.ndim == 3:
+            # [batch, tokens, hidden]
+            if self.settings.pooling == 'cls':
+                output = output[:, 0]
+            elif self.settings.pooling == 'mean':
+                mask = np.asarray(encoded['attention_mask'], dtype=np.float32)[:, :, None]
+                output = (output * mask).sum(axis=1) / np.clip(mask.sum(axis=1), 1e-9, None)
+            else:
+                raise EmbeddingError("The model returns one vector per token; set embeddings.pooling to mean or cls")
+        elif output.ndim != 2:
+            raise EmbeddingError(f"Unexpected embedding output with shape {list(output.shape)}")
+
+        if self.settings.normalize:
+            output = output / np.clip(np.linalg.norm(output, axis=1, keepdims=True), 1e-12, None)
+        return output.tolist()
+
+    def to_dict(self) -> Dict[str, Any]:
+        return dict(self.settings.to_dict(), tokenizer_kind=self.tokenizer.kind, inputs=self.inputs,
+                    dimension=self.dimension, providers=self.info.get('active'))
+
+def _is_control(char: str) -> bool:
+    return char not in '\t\n\r' and unicodedata.category(char) in ('Cc', 'Cf')
+
+def _is_punctuation(char: str) -> bool:
+    code = ord(char)
+    if 33 <= code <= 47 or 58 <= code <= 64 or 91 <= code <= 96 or 123 <= code <= 126:
+        return True
+    return unicodedata.category(char).startswith('P')
+
+def _is_cjk(char: str) -> bool:
+    code = ord(char)
+    return (0x4E00 <= code <= 0x9FFF or 0x3400 <= code <= 0x4DBF or 0x20000 <= code <= 0x2A6DF
+            or 0xF900 <= code <= 0xFAFF or 0x2F800 <= code <= 0x2FA1F)
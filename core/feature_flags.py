@@ -0,0 +1,234 @@
+"""
+FlashFlow feature flags - Project flags with per-environment and percentage rollout rules
+
+Flags are declared once in flashflow.json:
+
+    "features": {
+        "dark_mode": true,                          # just a default
+        "new_checkout": {
+            "description": "One-page checkout",
+            "default": false,
+            "rollout": 25,                          # % of users, picked by a stable hash of flag and user
+            "environments": {
+                "development": true,
+                "staging": {"rollout": 50},
+                "production": false
+            }
+        }
+    }
+
+For one user in one environment a flag is decided by the first of:
+
+    1. an override set from /admin/features on the dev server
+    2. the profile's own "features" (see core/profiles.py)
+    3. the flag's rule for the environment: true/false or {"rollout": N}
+    4. the flag's rollout
+    5. the flag's default (false unless set)
+
+A rollout needs a user to hash, so without one it falls back to the default;
+100 turns the flag on for everyone. The same user always lands in the same
+bucket, and raising the percentage only ever adds users.
+
+Pages and components are shown or hidden with a 'feature' key, checked by
+feature_visible; a leading '!' inverts it and a list needs every entry:
+
+    - component: hero
+      feature: new_checkout
+    - component: text
+      feature: [new_checkout, "!dark_mode"]
+      content: "Checkout is {{ 'on' if features.new_checkout else 'off' }}"
+"""
+
+import hashlib
+import json
+import threading
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+
+FEATURE_KEY = 'feature'
+FLAG_FIELDS = ('description', 'default', 'rollout', 'environments')
+ENVIRONMENT_FIELDS = ('enabled', 'rollout')
+
+class FeatureFlagError(Exception):
+    """Raised for an invalid 'features' block or an unknown flag"""
+    pass
+
+@dataclass
+class FlagDecision:
+    """Whether a flag is on for one user, and which rule said so"""
+    enabled: bool
+    reason: str
+
+@dataclass
+class FeatureFlag:
+    """One flag from flashflow.json"""
+    name: str
+    description: str = ''
+    default: bool = False
+    rollout: Optional[float] = None
+    environments: Dict[str, Dict[str, Any]] = field(default_factory=dict)
+
+    @classmethod
+    def from_config(cls, name: str, data: Any) -> 'FeatureFlag':
+        where = f"features.{name}"
+        if isinstance(data, bool):
+            return cls(name, default=data)
+        if not isinstance(data, dict):
+            raise FeatureFlagError(f"{where} must be true, false or an object")
+        unknown = sorted(set(data) - set(FLAG_FIELDS))
+        if unknown:
+            raise FeatureFlagError(f"Unknown field(s) {', '.join(unknown)} in {where}. Supported: {', '.join(FLAG_FIELDS)}")
+        if not isinstance(data.get('default', False), bool):
+            raise FeatureFlagError(f"{where}.default must be true or false")
+        environments = data.get('environments') or {}
+        if not isinstance(environments, dict):
+            raise FeatureFlagError(f"{where}.environments must be an object of environment name → rule")
+        return cls(name, str(data.get('description') or ''), data.get('default', False),
+                   _percentage(data.get('rollout'), f"{where}.rollout"),
+                   {env: _environment_rule(rule, f"{where}.environments.{env}") for env, rule in environments.items()})
+
+    def decide(self, env: str, subject: Optional[str] = None) -> FlagDecision:
+        """Steps 3 to 5 of the order in the module docstring"""
+        rule = self.environments.get(env)
+        if rule and 'enabled' in rule:
+            return FlagDecision(rule['enabled'], f"environment {env}")
+        rollout, where = (rule['rollout'], f"environment {env} rollout") if rule else (self.rollout, 'rollout')
+        if rollout is not None:
+            if rollout >= 100:
+                return FlagDecision(True, f"{where} {rollout:g}%")
+            if subject:
+                return FlagDecision(bucket(self.name, subject) < rollout, f"{where} {rollout:g}%")
+            return FlagDecision(self.default, f"default (no user for the {rollout:g}% {where})")
+        return FlagDecision(self.default, 'default')
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'name': self.name, 'description': self.description, 'default': self.default,
+                'rollout': self.rollout, 'environments': dict(self.environments)}
+
+def bucket(name: str, subject: str) -> float:
+    """Where a user falls for a flag, from 0 up to 100, always the same for the pair"""
+    digest = hashlib.sha256(f"{name}:{subject}".encode('utf-8')).hexdigest()
+    return int(digest[:8], 16) % 10000 / 100
+
+class FeatureFlags:
+    """The flags of a project, as one environment sees them"""
+
+    def __init__(self, flags: Dict[str, FeatureFlag], env: str, profile_features: Optional[Dict[str, bool]] = None):
+        self.env = env
+        self.profile_features = dict(profile_features or {})
+        self.flags = dict(flags)
+        # A flag only a profile mentions is still a flag, off everywhere else
+        for name in self.profile_features:
+            self.flags.setdefault(name, FeatureFlag(name))
+
+    @classmethod
+    def from_config(cls, config: Any, env: str, profile_features: Optional[Dict[str, bool]] = None) -> 'FeatureFlags':
+        if config is None:
+            config = {}
+        if not isinstance(config, dict):
+            raise FeatureFlagError("\"features\" in flashflow.json must be an object of flag name → rule")
+        return cls({name: FeatureFlag.from_config(name, data) for name, data in config.items()}, env, profile_features)
+
+    def decide(self, name: str, subject: Optional[str] = None,
+               overrides: Optional[Dict[str, bool]] = None) -> FlagDecision:
+        if name not in self.flags:
+            raise FeatureFlagError(f"Unknown feature flag '{name}'")
+        if overrides and name in overrides:
+            return FlagDecision(overrides[name], 'override')
+        if name in self.profile_features:
+            return FlagDecision(self.profile_features[name], f"profile {self.env}")
+        return self.flags[name].decide(self.env, subject)
+
+    def evaluate(self, subject: Optional[str] = None, overrides: Optional[Dict[str, bool]] = None) -> Dict[str, bool]:
+        """Every flag → on or off for a user (or nobody in particular)"""
+        return {name: self.decide(name, subject, overrides).enabled for name in sorted(self.flags)}
+
+    def explain(self, subject: Optional[str] = None, overrides: Optional[Dict[str, bool]] = None) -> List[Dict[str, Any]]:
+        """evaluate, with each flag's rules and the one that decided it"""
+        flags = []
+        for name in sorted(self.flags):
+            decision = self.decide(name, subject, overrides)
+            flags.append(dict(self.flags[name].to_dict(), enabled=decision.enabled, reason=decision.reason,
+                              profile=self.profile_features.get(name),
+                              override=(overrides or {}).get(name)))
+        return flags
+
+class FlagOverrides:
+    """Flags switched from /admin/features, persisted as JSON so they survive restarts"""
+
+    def __init__(self, path: Path):
+        self.path = Path(path)
+        self._lock = threading.Lock()
+        self.values = self._load()
+
+    @classmethod
+    def for_project(cls, project) -> 'FlagOverrides':
+        return cls(project.state.path('features', 'overrides.json'))
+
+    def _load(self) -> Dict[str, bool]:
+        try:
+            with open(self.path, 'r') as f:
+                data = json.load(f)
+        except (OSError, ValueError):
+            return {}
+        return {str(name): value for name, value in data.items() if isinstance(value, bool)} if isinstance(data, dict) else {}
+
+    def set(self, name: str, enabled: Optional[bool]):
+        """Force a flag on or off; None goes back to its rules"""
+        with self._lock:
+            if enabled is None:
+                self.values.pop(name, None)
+            else:
+                self.values[name] = bool(enabled)
+            self.path.parent.mkdir(parents=True, exist_ok=True)
+            partial = self.path.with_suffix('.tmp')
+            with open(partial, 'w') as f:
+                json.dump(self.values, f, indent=2, sort_keys=True)
+            partial.replace(self.path)
+
+    def snapshot(self) -> Dict[str, bool]:
+        with self._lock:
+            return dict(self.values)
+
+def feature_requirements(item: Any) -> List[Tuple[str, bool]]:
+    """(flag, wanted state) pairs from a page's or component's 'feature' key"""
+    if not isinstance(item, dict) or item.get(FEATURE_KEY) in (None, '', []):
+        return []
+    value = item[FEATURE_KEY]
+    entries = value if isinstance(value, list) else [value]
+    requirements = []
+    for entry in entries:
+        entry = str(entry).strip()
+        wanted = not entry.startswith('!')
+        requirements.append((entry.lstrip('!').strip(), wanted))
+    return requirements
+
+def feature_visible(item: Any, features: Dict[str, bool]) -> bool:
+    """Whether a page or component passes its 'feature' key; unknown flags count as off"""
+    return all(bool(features.get(name)) == wanted for name, wanted in feature_requirements(item))
+
+def _percentage(value: Any, where: str) -> Optional[float]:
+    if value is None:
+        return None
+    if isinstance(value, bool) or not isinstance(value, (int, float)) or not 0 <= value <= 100:
+        raise FeatureFlagError(f"{where} must be a percentage from 0 to 100, got {value!r}")
+    return float(value)
+
+def _environment_rule(rule: Any, where: str) -> Dict[str, Any]:
+    if isinstance(rule, bool):
+        return {'enabled': rule}
+    if not isinstance(rule, dict):
+        raise FeatureFlagError(f"{where} must be true, false or {{\"rollout\": N}}")
+    unknown = sorted(set(rule) - set(ENVIRONMENT_FIELDS))
+    if unknown:
+        raise FeatureFlagError(f"Unknown field(s) {', '.join(unknown)} in {where}. Supported: {', '.join(ENVIRONMENT_FIELDS)}")
+    if 'enabled' in rule:
+        if not isinstance(rule['enabled'], bool):
+            raise FeatureFlagError(f"{where}.enabled must be true or false")
+        if rule.get('rollout') is not None:
+            raise FeatureFlagError(f"{where} sets both enabled and rollout; use one")
+        return {'enabled': rule['enabled']}
+    if rule.get('rollout') is None:
+        raise FeatureFlagError(f"{where} needs enabled or rollout")
+    return {'rollout': _percentage(rule['rollout'], f"{where}.rollout")}
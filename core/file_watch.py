@@ -0,0 +1,205 @@
+"""
+FlashFlow file watching - watchdog observers with a polling fallback
+
+Native file events (inotify, FSEvents, ReadDirectoryChangesW) never arrive
+for files changed on NFS or SMB shares, or through many Docker and VM
+volume mounts. There a PollingWatcher looks at the watched directories
+every interval instead. A file whose size or mtime changed is hashed, and
+only a new hash (or a new or removed file) is reported, so touching a file
+or a filesystem with coarse mtimes does not set off rebuilds.
+
+The 'watch.poll' setting (core/settings.py) picks the watcher:
+
+    auto        poll when a watched directory is on a network or VM filesystem,
+                or watchdog is not installed; native events otherwise (default)
+    off         native events only
+    <seconds>   always poll, this often (0.1-60)
+
+    flashflow serve --poll            flashflow build --watch --poll=2.5
+    FLASHFLOW_WATCH_POLL=off          "watch": {"poll": 1.5}
+
+Polling hands handlers the same kind of events watchdog does ('created',
+'modified', 'deleted' with src_path), so watchdog handlers work with both.
+"""
+
+import hashlib
+import logging
+import os
+import threading
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+
+logger = logging.getLogger(__name__)
+
+DEFAULT_POLL_INTERVAL = 1.0
+MIN_POLL_INTERVAL = 0.1
+MAX_POLL_INTERVAL = 60.0
+# Filesystems (as named in /proc/mounts) that do not deliver inotify events for changes made elsewhere
+NETWORK_FILESYSTEMS = {'nfs', 'nfs4', 'cifs', 'smb3', 'smbfs', 'afs', '9p', 'virtiofs', 'vboxsf', 'vmhgfs',
+                       'fuse.vmhgfs-fuse', 'fuse.sshfs', 'fuse.grpcfuse', 'fakeowner', 'drvfs', 'fuse.osxfs'}
+HASH_CHUNK = 1024 * 1024
+
+class FileWatchError(Exception):
+    """Raised when no watcher can be made for the requested mode"""
+    pass
+
+@dataclass
+class FileEvent:
+    """What a watchdog handler is given, for changes found by polling"""
+    event_type: str
+    src_path: str
+    is_directory: bool = False
+    is_synthetic: bool = False
+
+def filesystem_type(path: Path, mounts_file: str = '/proc/mounts') -> Optional[str]:
+    """The type of the filesystem holding path, from the mount table; None where there is none (macOS, Windows)"""
+    try:
+        lines = Path(mounts_file).read_text(encoding='utf-8', errors='replace').splitlines()
+    except OSError:
+        return None
+    target = os.path.realpath(str(path))
+    best, best_type = '', None
+    for line in lines:
+        parts = line.split()
+        if len(parts) < 3:
+            continue
+        # Spaces in mount points are written as \040
+        mount_point = parts[1].replace('\\040', ' ')
+        inside = target == mount_point or target.startswith(mount_point.rstrip('/') + '/')
+        if inside and len(mount_point) >= len(best):
+            best, best_type = mount_point, parts[2]
+    return best_type
+
+def needs_polling(path: Path) -> Optional[str]:
+    """Why native events cannot be trusted for path, or None"""
+    fs_type = filesystem_type(path)
+    if fs_type and fs_type.lower() in NETWORK_FILESYSTEMS:
+        return f"{path} is on {fs_type}, which does not report changes made elsewhere"
+    return None
+
+def parse_poll(value: Any) -> Any:
+    """'auto', 'off' or an interval in seconds; ValueError for anything else"""
+    if value is True:
+        return DEFAULT_POLL_INTERVAL
+    if value is False:
+        return 'off'
+    text = str(value).strip().lower()
+    if text in ('auto', 'off'):
+        return text
+    try:
+        seconds = float(text)
+    except ValueError:
+        raise ValueError("is not 'auto', 'off' or a polling interval in seconds")
+    if not MIN_POLL_INTERVAL <= seconds <= MAX_POLL_INTERVAL:
+        raise ValueError(f"is not a polling interval from {MIN_POLL_INTERVAL:g} to {MAX_POLL_INTERVAL:g} seconds")
+    return seconds
+
+class PollingWatcher:
+    """A watchdog Observer stand-in that scans the scheduled directories every interval"""
+
+    def __init__(self, interval: float = DEFAULT_POLL_INTERVAL):
+        self.interval = interval
+        self.watches: List[Tuple[Any, Path, bool]] = []
+        self._snapshots: Dict[int, Dict[str, Tuple[int, int, str]]] = {}
+        self._stop = threading.Event()
+        self._thread: Optional[threading.Thread] = None
+
+    def schedule(self, handler, path, recursive: bool = False):
+        self.watches.append((handler, Path(path), recursive))
+        # What is there now is the baseline; only later changes are events
+        self._snapshots[len(self.watches) - 1] = self._scan(Path(path), recursive, {})
+
+    def start(self):
+        self._thread = threading.Thread(target=self._run, daemon=True, name='flashflow-poll-watcher')
+        self._thread.start()
+
+    def stop(self):
+        self._stop.set()
+
+    def join(self, timeout: Optional[float] = None):
+        if self._thread:
+            self._thread.join(timeout)
+
+    def is_alive(self) -> bool:
+        return bool(self._thread and self._thread.is_alive())
+
+    def _run(self):
+        while not self._stop.wait(self.interval):
+            self.poll()
+
+    def poll(self) -> List[FileEvent]:
+        """Scan once and dispatch what changed; returns the events"""
+        dispatched = []
+        for index, (handler, path, recursive) in enumerate(self.watches):
+            previous = self._snapshots.get(index, {})
+            current = self._scan(path, recursive, previous)
+            events = []
+            for name in sorted(current.keys() - previous.keys()):
+                # inotify reports a new file as created, then modified by its write
+                events += [FileEvent('created', name), FileEvent('modified', name)]
+            for name in sorted(current.keys() & previous.keys()):
+                if current[name][2] != previous[name][2]:
+                    events.append(FileEvent('modified', name))
+            for name in sorted(previous.keys() - current.keys()):
+                events.append(FileEvent('deleted', name))
+            self._snapshots[index] = current
+            for event in events:
+                try:
+                    handler.dispatch(event)
+                except Exception as e:
+                    logger.warning(f"File watcher handler failed on {event.src_path}: {str(e)}")
+            dispatched += events
+        return dispatched
+
+    def _scan(self, path: Path, recursive: bool, previous: Dict[str, Tuple[int, int, str]]) -> Dict[str, Tuple[int, int, str]]:
+        """(size, mtime, content hash) of every file; hashes are reused while size and mtime stay the same"""
+        snapshot = {}
+        try:
+            entries = path.rglob('*') if recursive else path.iterdir()
+            for entry in entries:
+                try:
+                    stat = entry.stat()
+                    if not entry.is_file():
+                        continue
+                except OSError:
+                    continue
+                name = str(entry)
+                known = previous.get(name)
+                if known and known[0] == stat.st_size and known[1] == stat.st_mtime_ns:
+                    snapshot[name] = known
+                    continue
+                digest = _hash_file(entry)
+                if digest is not None:
+                    snapshot[name] = (stat.st_size, stat.st_mtime_ns, digest)
+        except OSError:
+            # The directory is gone or unreadable for now; its files count as removed until it is back
+            pass
+        return snapshot
+
+def _hash_file(path: Path) -> Optional[str]:
+    digest = hashlib.sha256()
+    try:
+        with open(path, 'rb') as f:
+            for chunk in iter(lambda: f.read(HASH_CHUNK), b''):
+                digest.update(chunk)
+    except OSError:
+        return None
+    return digest.hexdigest()
+
+def create_observer(poll: Any, paths: List[Path]) -> Tuple[Any, Optional[str]]:
+    """A watcher for 'watch.poll' and the directories it will watch, and why it polls (None: native events)"""
+    if isinstance(poll, (int, float)) and not isinstance(poll, bool):
+        return PollingWatcher(float(poll)), f"polling every {float(poll):g}s as configured"
+    try:
+        from watchdog.observers import Observer
+    except ImportError:
+        if poll == 'off':
+            raise FileWatchError("File watching needs watchdog (pip install watchdog), or polling (--poll)")
+        return PollingWatcher(), "watchdog is not installed"
+    if poll == 'auto':
+        for path in paths:
+            reason = needs_polling(path)
+            if reason:
+                return PollingWatcher(), reason
+    return Observer(), None
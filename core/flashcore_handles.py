@@ -0,0 +1,169 @@
+"""
+FlashFlow FlashCore handles - Checked constructors and guarded calls for the native bindings
+
+libflashcore hands back null objects when it cannot load a model or
+allocate an index, and the bindings pass them through; the first call on
+one then crashes the process. These constructors turn that into a
+FlashCoreError at creation:
+
+    runtime = onnx_runtime(path, **options.binding_kwargs())
+    index = hnsw_index(128, 10000)
+    vault = aes_vault(key)
+
+Every call on the returned handle checks the native object is still there
+(and still valid, when the binding can say), so a closed or failed handle
+raises instead of crashing. A handle dropped without close() is counted by
+its finalizer; FLASHFLOW_STRICT_HANDLES=1 logs each one, which makes leaks
+show up in test runs. ImportError still means the bindings are not built.
+
+cancellable() makes a call that stops when a CancelToken (see
+core/cancellation.py) is cancelled: bindings built with cancellation take
+a should_cancel callable that libflashcore polls between chunks of work;
+with older bindings the call runs to the end and its result is dropped.
+"""
+
+import logging
+import os
+import threading
+import weakref
+from typing import Any, Dict
+
+logger = logging.getLogger(__name__)
+
+STRICT_ENV = 'FLASHFLOW_STRICT_HANDLES'
+
+_leaks: Dict[str, int] = {}
+_leaks_lock = threading.Lock()
+
+class FlashCoreError(Exception):
+    """Raised when libflashcore cannot create an object, or one is used after it failed or closed"""
+    pass
+
+class FlashCoreHandle:
+    """A native FlashCore object that refuses calls once closed or invalid"""
+
+    def __init__(self, kind: str, native: Any):
+        if native is None:
+            raise FlashCoreError(f"libflashcore returned no {kind}")
+        self._kind = kind
+        self._native = native
+        self.native()
+        self._finalizer = weakref.finalize(self, _leaked, type(native).__name__, kind)
+
+    @property
+    def kind(self) -> str:
+        return self._kind
+
+    @property
+    def closed(self) -> bool:
+        return self._native is None
+
+    def native(self) -> Any:
+        """The binding object, after the same checks a call gets"""
+        native = self._native
+        if native is None:
+            raise FlashCoreError(f"The {self._kind} is closed")
+        is_valid = getattr(native, 'is_valid', None)
+        if callable(is_valid) and not is_valid():
+            raise FlashCoreError(f"The {self._kind} has no native handle; libflashcore failed to create it")
+        return native
+
+    def __getattr__(self, name: str):
+        # Only reached for names the handle itself does not have, i.e. binding methods
+        if name.startswith('_'):
+            raise AttributeError(name)
+        attribute = getattr(self.native(), name)
+        if not callable(attribute):
+            return attribute
+
+        def call(*args, **kwargs):
+            return getattr(self.native(), name)(*args, **kwargs)
+        return call
+
+    @property
+    def supports_cancel(self) -> bool:
+        """Whether the binding polls a should_cancel callback during long calls"""
+        return bool(getattr(self._native, 'supports_cancel', False))
+
+    def cancellable(self, cancel, name: str, *args, **kwargs):
+        """Call a binding method that stops with Cancelled once cancel (a CancelToken or None) is cancelled"""
+        if cancel is None:
+            return getattr(self.native(), name)(*args, **kwargs)
+        from core.cancellation import Cancelled
+        cancel.check()
+        method = getattr(self.native(), name)
+        if self.supports_cancel:
+            kwargs['should_cancel'] = lambda: cancel.cancelled
+        try:
+            result = method(*args, **kwargs)
+        except (RuntimeError, FlashCoreError):
+            if cancel.cancelled:
+                # libflashcore gave up because should_cancel said so
+                raise Cancelled(cancel.reason)
+            raise
+        cancel.check()
+        return result
+
+    def close(self):
+        """Release the native object; later calls raise FlashCoreError"""
+        native, self._native = self._native, None
+        self._finalizer.detach()
+        release = getattr(native, 'close', None)
+        if callable(release):
+            release()
+
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc):
+        self.close()
+
+    def __repr__(self):
+        return f"<FlashCoreHandle {self._kind}{' closed' if self.closed else ''}>"
+
+def _leaked(binding: str, kind: str):
+    with _leaks_lock:
+        _leaks[binding] = _leaks.get(binding, 0) + 1
+    if os.environ.get(STRICT_ENV, '').lower() in ('1', 'true', 'yes'):
+        logger.warning(f"FlashCore {kind} was garbage collected without close()")
+
+def leaked_handles() -> Dict[str, int]:
+    """Handles collected without close(), by binding class, since the last reset"""
+    with _leaks_lock:
+        return dict(_leaks)
+
+def reset_leaked_handles():
+    with _leaks_lock:
+        _leaks.clear()
+
+def _create(kind: str, constructor, *args, **kwargs) -> FlashCoreHandle:
+    try:
+        native = constructor(*args, **kwargs)
+    except (RuntimeError, MemoryError, OSError, ValueError) as e:
+        raise FlashCoreError(f"libflashcore could not create the {kind}: {str(e)}")
+    return FlashCoreHandle(kind, native)
+
+def onnx_runtime(model_path: str, **options) -> FlashCoreHandle:
+    """An ONNXRuntime for a model; options are InferenceOptions.binding_kwargs()"""
+    import flashcore
+    kind = f"ONNX runtime for {model_path}" if model_path else 'ONNX runtime'
+    try:
+        return _create(kind, flashcore.ONNXRuntime, model_path, **options)
+    except TypeError:
+        if not options:
+            raise
+        # Bindings built before constructor options only take the model path
+        return _create(kind, flashcore.ONNXRuntime, model_path)
+
+def hnsw_index(dimension: int, max_elements: int) -> FlashCoreHandle:
+    import flashcore
+    if dimension <= 0 or max_elements <= 0:
+        raise FlashCoreError(f"An HNSW index needs a positive dimension and size, got {dimension} and {max_elements}")
+    return _create(f"HNSW index ({dimension} dimensions, {max_elements} elements)",
+                   flashcore.HNSWIndex, dimension, max_elements)
+
+def aes_vault(key: str) -> FlashCoreHandle:
+    import flashcore
+    if not key:
+        raise FlashCoreError("An AES vault needs a key")
+    return _create('AES vault', flashcore.AESVault, key)
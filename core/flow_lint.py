@@ -0,0 +1,402 @@
+"""
+FlashFlow flow linter - Style and best-practice rules for .flow files
+
+    unused-model      a model no page, endpoint or other model mentions
+    page-title        a page without a 'title'
+    required-props    a component missing a prop it cannot render without
+    prop-types        a prop of the wrong kind, such as a 'level' that is not a number
+    max-depth         components nested deeper than 'max' levels (default 5)
+    duplicate-route   two pages, or two endpoints with one method, on the same path
+    sitemap-settings  a page's sitemap or noindex that core/sitemap.py cannot use
+    social-settings   a page's og or twitter that core/social_cards.py cannot use
+
+Rules are tuned under 'lint' in flashflow.json; a severity of "off" turns
+one off, and other settings sit next to the severity:
+
+    "lint": {"rules": {"unused-model": "off", "max-depth": {"severity": "error", "max": 4}}}
+
+Comments silence rules in a file. 'disable' applies to its own line, or,
+alone on a line, to the line after it; either way it covers the block that
+starts there. Without rule names every rule is silenced:
+
+    page:  # flashflow-lint: disable page-title
+    # flashflow-lint: disable-next-line max-depth
+    # flashflow-lint: disable-file unused-model
+
+Results can be written as SARIF 2.1.0 for editors and CI code scanning.
+"""
+
+import re
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Any, Dict, Iterator, List, Optional, Set, Tuple
+
+import yaml
+
+from core.parser.diagnostics import FlowDiagnostic, suggest_for_yaml_problem
+from core.parser.flow_file import FlowParseError, load_flow
+from core.permissions import PATH_PARAMETER
+from core.render_context import KIND_NAMES, PROP_TYPES, prop_problems
+from core.sitemap import page_seo
+from core.social_cards import page_social
+
+SEVERITIES = ('error', 'warning', 'info', 'off')
+SARIF_LEVELS = {'error': 'error', 'warning': 'warning', 'info': 'note'}
+SARIF_SCHEMA = 'https://json.schemastore.org/sarif-2.1.0.json'
+DEFAULT_MAX_DEPTH = 5
+DISABLE_COMMENT = re.compile(r'#\s*flashflow-lint:\s*(disable-file|disable-next-line|disable)\b([^#]*)')
+# Keys whose lists hold nested components
+CHILD_KEYS = ('children', 'footer')
+
+@dataclass
+class LintRule:
+    id: str
+    description: str
+    severity: str
+
+RULES = [
+    LintRule('unused-model', "Models should be used by a page, an endpoint or another model", 'warning'),
+    LintRule('page-title', "Pages should have a title", 'warning'),
+    LintRule('required-props', "Components need the props they render", 'error'),
+    LintRule('prop-types', "Component props have the kind their renderers expect", 'warning'),
+    LintRule('max-depth', "Component nesting should stay shallow", 'warning'),
+    LintRule('duplicate-route', "Each page route and endpoint is defined once", 'error'),
+    LintRule('sitemap-settings', "Sitemap priority, changefreq, paths and noindex have valid values", 'warning'),
+    LintRule('social-settings', "Open Graph and Twitter card settings have valid values", 'warning'),
+]
+RULES_BY_ID = {rule.id: rule for rule in RULES}
+
+# Props a component cannot do without; a tuple means any one of them
+REQUIRED_PROPS = {
+    'button': [('text', 'label')],
+    'primary_button': [('text', 'label')],
+    'headline': ['text'],
+    'text': [('content', 'text')],
+    'rich_text': ['html'],
+    'header': [('content', 'text')],
+    'hero': ['title'],
+    'form': [('fields', 'model')],
+    'input': [('name', 'label')],
+    'tabs': ['tabs'],
+    'features': ['items'],
+    'modal': [('title', 'trigger')],
+}
+
+class LintConfigError(ValueError):
+    """Raised for an unknown rule or severity under 'lint' in flashflow.json"""
+    pass
+
+@dataclass
+class LintIssue(FlowDiagnostic):
+    """A rule violation; 'file' is relative to the project root"""
+    rule: str = ''
+    suppressed: bool = False
+
+@dataclass
+class FlowSource:
+    """One parsed file with the line each YAML key or list item starts on"""
+    file: str
+    content: str
+    data: Any
+    lines: Dict[Tuple, int]
+
+    def line(self, path: Tuple) -> Optional[int]:
+        # The closest enclosing node that has a line
+        while path:
+            if path in self.lines:
+                return self.lines[path]
+            path = path[:-1]
+        return None
+
+def node_lines(node, path: Tuple = (), lines: Optional[Dict[Tuple, int]] = None) -> Dict[Tuple, int]:
+    """Map key paths like ('page', 'body', 0) to their 1-based line"""
+    lines = {} if lines is None else lines
+    if isinstance(node, yaml.MappingNode):
+        for key, value in node.value:
+            if isinstance(key, yaml.ScalarNode):
+                child = path + (key.value,)
+                lines[child] = key.start_mark.line + 1
+                node_lines(value, child, lines)
+    elif isinstance(node, yaml.SequenceNode):
+        for index, item in enumerate(node.value):
+            lines[path + (index,)] = item.start_mark.line + 1
+            node_lines(item, path + (index,), lines)
+    return lines
+
+def load_source(flow_file: Path, root: Path, content: Optional[str] = None) -> Tuple[Optional[FlowSource], Optional[LintIssue]]:
+    """The parsed file, or the parse error as an issue; content replaces what is on disk (an editor's unsaved text)"""
+    name = relative_name(flow_file, root)
+    if content is None:
+        try:
+            content = flow_file.read_text(encoding='utf-8')
+        except OSError as e:
+            return None, LintIssue(name, f"Cannot read file: {str(e)}", rule='parse')
+    try:
+        data = load_flow(flow_file, root, content=content).data
+    except FlowParseError as e:
+        return None, LintIssue(name, e.problem, e.line, e.column, 'error', suggest_for_yaml_problem(e.problem), rule='parse')
+    try:
+        lines = node_lines(yaml.compose(content, Loader=yaml.SafeLoader))
+    except yaml.YAMLError:
+        lines = {}
+    return FlowSource(name, content, data, lines), None
+
+def relative_name(path: Path, root: Path) -> str:
+    try:
+        return path.resolve().relative_to(root.resolve()).as_posix()
+    except ValueError:
+        return path.as_posix()
+
+def rule_settings(config: Optional[Dict[str, Any]]) -> Dict[str, Dict[str, Any]]:
+    """Each rule's settings from the 'lint' block, with 'severity' always filled in"""
+    configured = ((config or {}).get('rules') or {})
+    unknown = set(configured) - set(RULES_BY_ID)
+    if unknown:
+        raise LintConfigError(f"Unknown lint rule(s): {', '.join(sorted(unknown))}; known: {', '.join(RULES_BY_ID)}")
+    settings = {}
+    for rule in RULES:
+        value = configured.get(rule.id, {})
+        value = {'severity': value} if isinstance(value, str) else dict(value or {})
+        value.setdefault('severity', rule.severity)
+        if value['severity'] not in SEVERITIES:
+            raise LintConfigError(f"Lint rule '{rule.id}': severity must be one of {', '.join(SEVERITIES)}")
+        settings[rule.id] = value
+    return settings
+
+def components(items: Any, path: Tuple, depth: int = 1) -> Iterator[Tuple[Dict[str, Any], Tuple, int]]:
+    """(component, path, depth) for a component list and everything nested in it"""
+    for index, item in enumerate(items if isinstance(items, list) else []):
+        if not isinstance(item, dict):
+            continue
+        item_path = path + (index,)
+        if 'component' in item:
+            component = item
+        elif len(item) == 1 and isinstance(next(iter(item.values())), dict):
+            # '- primary_button: {...}'
+            name, component = next(iter(item.items()))
+            component = dict(component, component=name)
+            item_path += (name,)
+        else:
+            continue
+        yield component, item_path, depth
+        for key in CHILD_KEYS:
+            yield from components(component.get(key), item_path + (key,), depth + 1)
+        tabs = component.get('tabs')
+        for tab_index, tab in enumerate(tabs if isinstance(tabs, list) else []):
+            if isinstance(tab, dict):
+                yield from components(tab.get('children'), item_path + ('tabs', tab_index, 'children'), depth + 1)
+
+def route_key(path: str) -> str:
+    """Routes that match the same URLs compare equal: '/users/{id}' and '/users/:user_id'"""
+    return PATH_PARAMETER.sub(':param', str(path).rstrip('/') or '/')
+
+def endpoints_of(data: Dict[str, Any]) -> List[Tuple[Dict[str, Any], Tuple]]:
+    endpoints = data.get('endpoint')
+    if isinstance(endpoints, dict):
+        return [(endpoints, ('endpoint',))]
+    if isinstance(endpoints, list):
+        return [(endpoint, ('endpoint', index)) for index, endpoint in enumerate(endpoints) if isinstance(endpoint, dict)]
+    return []
+
+def strings(value: Any) -> Iterator[str]:
+    """Every key and string value in nested data"""
+    if isinstance(value, dict):
+        for key, item in value.items():
+            yield str(key)
+            yield from strings(item)
+    elif isinstance(value, list):
+        for item in value:
+            yield from strings(item)
+    elif isinstance(value, str):
+        yield value
+
+def model_mentions(name: str) -> re.Pattern:
+    """User, user, users, UserProfile -> user_profile(s)"""
+    snake = re.sub(r'(?<!^)(?=[A-Z])', '_', name).lower()
+    names = {re.escape(name), re.escape(snake), re.escape(snake) + 's', re.escape(name.lower()) + 's'}
+    return re.compile(rf"(?<![A-Za-z0-9_])({'|'.join(sorted(names))})(?![A-Za-z0-9_])", re.I)
+
+class FlowLinter:
+    """Runs the rules over a set of flow files"""
+
+    def __init__(self, root: Path, config: Optional[Dict[str, Any]] = None):
+        self.root = Path(root)
+        self.settings = rule_settings(config)
+
+    def lint(self, flow_files: List[Path], contents: Optional[Dict[Path, str]] = None) -> List[LintIssue]:
+        issues: List[LintIssue] = []
+        sources: List[FlowSource] = []
+        for flow_file in sorted(flow_files):
+            source, error = load_source(flow_file, self.root, (contents or {}).get(flow_file))
+            if error:
+                issues.append(error)
+            elif isinstance(source.data, dict):
+                sources.append(source)
+
+        found: List[Tuple[FlowSource, LintIssue, Tuple]] = []
+
+        def report(source: FlowSource, rule: str, path: Tuple, message: str, suggestion: str = None):
+            severity = self.settings[rule]['severity']
+            if severity != 'off':
+                issue = LintIssue(source.file, message, source.line(path), None, severity, suggestion, rule=rule)
+                found.append((source, issue, path))
+
+        self.check_unused_models(sources, report)
+        self.check_duplicate_routes(sources, report)
+        for source in sources:
+            self.check_page(source, report)
+
+        for source, issue, path in found:
+            issue.suppressed = is_suppressed(source, issue.rule, path, issue.line)
+            issues.append(issue)
+        issues.sort(key=lambda issue: (issue.file, issue.line or 0, issue.rule))
+        return issues
+
+    def check_page(self, source: FlowSource, report):
+        page = source.data.get('page')
+        if not isinstance(page, dict):
+            return
+        label = f"Page '{page.get('path', '?')}'"
+        if not page.get('title'):
+            report(source, 'page-title', ('page',), f"{label} has no title",
+                   "Add 'title: ...'; it names the browser tab, the sitemap entry and the page heading")
+        for problem in page_seo(page)[1]:
+            key = 'noindex' if problem.startswith('noindex') else 'sitemap'
+            report(source, 'sitemap-settings', ('page', key if key in page else 'static'), f"{label}: {problem}",
+                   "Until it is fixed the setting is ignored in sitemap.xml and the static export")
+        for problem in page_social(page)[1]:
+            key = problem.split(' ', 1)[0]
+            report(source, 'social-settings', ('page', key), f"{label}: {problem}",
+                   "Until it is fixed the setting is ignored in the page's social meta")
+
+        max_depth = int(self.settings['max-depth'].get('max', DEFAULT_MAX_DEPTH))
+        for component, path, depth in components(page.get('body'), ('page', 'body')):
+            component_type = str(component.get('component', '')).lower()
+            for required in REQUIRED_PROPS.get(component_type, []):
+                options = required if isinstance(required, tuple) else (required,)
+                if not any(component.get(option) not in (None, '', []) for option in options):
+                    report(source, 'required-props', path,
+                           f"{label}: '{component_type}' needs {' or '.join(repr(option) for option in options)}",
+                           f"Add '{options[0]}: ...' to the {component_type}")
+            for prop, message in prop_problems(component):
+                report(source, 'prop-types', path + (prop,), f"{label}: {message} on '{component_type}'",
+                       f"Use {KIND_NAMES[PROP_TYPES[component_type][prop]]} for '{prop}'; until then its default is used")
+            if depth == max_depth + 1:
+                # Reported once, at the first level too deep; what is inside it is part of the same problem
+                report(source, 'max-depth', path,
+                       f"{label}: '{component_type}' is nested {depth} levels deep (max {max_depth})",
+                       "Flatten the layout or move the inner part into its own component")
+
+    def check_unused_models(self, sources: List[FlowSource], report):
+        models = []
+        for source in sources:
+            model = source.data.get('model')
+            if isinstance(model, dict) and model.get('name'):
+                models.append((source, str(model['name'])))
+        for source, name in models:
+            pattern = model_mentions(name)
+            used = False
+            for other in sources:
+                for key, value in other.data.items():
+                    if key == 'model' and other is source:
+                        continue
+                    if key == 'model' and isinstance(value, dict):
+                        # Another model uses this one through its fields (references User.id, ...)
+                        value = value.get('fields')
+                    if any(pattern.search(text) for text in strings(value)):
+                        used = True
+                        break
+                if used:
+                    break
+            if not used:
+                report(source, 'unused-model', ('model', 'name'), f"Model '{name}' is not used by any page, endpoint or model",
+                       "Show it on a page, expose it through an endpoint, or remove it")
+
+    def check_duplicate_routes(self, sources: List[FlowSource], report):
+        seen: Dict[str, Tuple[FlowSource, Tuple]] = {}
+        for source in sources:
+            routes = []
+            page = source.data.get('page')
+            if isinstance(page, dict) and page.get('path'):
+                routes.append((f"page {route_key(page['path'])}", f"Page route '{page['path']}'", ('page', 'path')))
+            for endpoint, path in endpoints_of(source.data):
+                if endpoint.get('path'):
+                    method = str(endpoint.get('method', 'GET')).upper()
+                    routes.append((f"{method} {route_key(endpoint['path'])}", f"Endpoint {method} {endpoint['path']}", path))
+            for key, label, path in routes:
+                if key in seen:
+                    first, first_path = seen[key]
+                    where = f"{first.file}:{first.line(first_path)}" if first.line(first_path) else first.file
+                    report(source, 'duplicate-route', path, f"{label} is already defined in {where}",
+                           "Give one of them a different path")
+                else:
+                    seen[key] = (source, path)
+
+def disable_scopes(source: FlowSource) -> Iterator[Tuple[Optional[int], Optional[Set[str]]]]:
+    """(line or None for the whole file, rule ids or None for every rule) per disable comment"""
+    lines = source.content.split('\n')
+    for number, text in enumerate(lines, 1):
+        match = DISABLE_COMMENT.search(text)
+        if not match:
+            continue
+        kind, names = match.group(1), match.group(2)
+        rules = {name for name in re.split(r'[\s,]+', names.strip()) if name} or None
+        if kind == 'disable-file':
+            yield None, rules
+            continue
+        target = number
+        if kind == 'disable-next-line' or not text[:match.start()].strip():
+            target = next((later for later in range(number + 1, len(lines) + 1)
+                           if lines[later - 1].strip() and not lines[later - 1].lstrip().startswith('#')), None)
+        if target is not None:
+            yield target, rules
+
+def is_suppressed(source: FlowSource, rule: str, path: Tuple, line: Optional[int]) -> bool:
+    for target, rules in disable_scopes(source):
+        if rules is not None and rule not in rules:
+            continue
+        if target is None or target == line:
+            return True
+        # A comment on a block's first line covers the whole block
+        if any(source.lines.get(path[:length]) == target for length in range(1, len(path) + 1)):
+            return True
+    return False
+
+def lint_summary(issues: List[LintIssue]) -> Dict[str, int]:
+    active = [issue for issue in issues if not issue.suppressed]
+    return {
+        'errors': sum(1 for issue in active if issue.severity == 'error'),
+        'warnings': sum(1 for issue in active if issue.severity == 'warning'),
+        'info': sum(1 for issue in active if issue.severity == 'info'),
+        'suppressed': len(issues) - len(active)
+    }
+
+def to_sarif(issues: List[LintIssue], settings: Dict[str, Dict[str, Any]], version: str = '') -> Dict[str, Any]:
+    """A SARIF 2.1.0 log; suppressed issues are kept, marked as suppressed in source"""
+    rules = [{
+        'id': rule.id,
+        'shortDescription': {'text': rule.description},
+        'defaultConfiguration': {'level': SARIF_LEVELS.get(settings[rule.id]['severity'], 'none')}
+    } for rule in RULES]
+    results = []
+    for issue in issues:
+        location: Dict[str, Any] = {'artifactLocation': {'uri': issue.file, 'uriBaseId': '%SRCROOT%'}}
+        if issue.line:
+            location['region'] = {'startLine': issue.line}
+            if issue.column:
+                location['region']['startColumn'] = issue.column
+        result = {
+            'ruleId': issue.rule,
+            'level': SARIF_LEVELS.get(issue.severity, 'warning'),
+            'message': {'text': issue.message + (f" ({issue.suggestion})" if issue.suggestion else '')},
+            'locations': [{'physicalLocation': location}]
+        }
+        if issue.rule in RULES_BY_ID:
+            result['ruleIndex'] = list(RULES_BY_ID).index(issue.rule)
+        if issue.suppressed:
+            result['suppressions'] = [{'kind': 'inSource'}]
+        results.append(result)
+    driver = {'name': 'flashflow-lint', 'informationUri': 'https://docs.flashflow.dev', 'rules': rules}
+    if version:
+        driver['version'] = version
+    return {'$schema': SARIF_SCHEMA, 'version': '2.1.0', 'runs': [{'tool': {'driver': driver}, 'results': results}]}
@@ -6,7 +6,12 @@ import json
 import os
 from pathlib import Path
 from typing import Dict, List, Optional, Any
-from dataclasses import dataclass
+from dataclasses import dataclass, fields
+
+from core.state import ProjectState
+
+# Written to flashflow.json even when empty
+ALWAYS_SAVED = ('name', 'version', 'description', 'author', 'frameworks', 'dependencies')
 
 @dataclass
 class FlashFlowConfig:
@@ -17,6 +22,36 @@ class FlashFlowConfig:
     author: str = ""
     frameworks: Optional[Dict[str, str]] = None
     dependencies: Optional[List[str]] = None
+    database: Optional[Dict[str, Any]] = None
+    tracing: Optional[Dict[str, Any]] = None
+    scripts: Optional[Dict[str, Any]] = None
+    a11y: Optional[Dict[str, Any]] = None
+    inference: Optional[Dict[str, Any]] = None
+    hooks: Optional[Dict[str, Any]] = None
+    vendor: Optional[Dict[str, Any]] = None
+    security_headers: Optional[Dict[str, Any]] = None
+    crash_reports: Optional[Dict[str, Any]] = None
+    profiles: Optional[Dict[str, Any]] = None
+    default_profile: Optional[str] = None
+    embeddings: Optional[Dict[str, Any]] = None
+    static_site: Optional[Dict[str, Any]] = None
+    build_cache: Optional[Dict[str, Any]] = None
+    access_log: Optional[Dict[str, Any]] = None
+    lint: Optional[Dict[str, Any]] = None
+    guardrails: Optional[Dict[str, Any]] = None
+    branch_previews: Optional[Dict[str, Any]] = None
+    response_cache: Optional[Dict[str, Any]] = None
+    metrics_retention: Optional[Dict[str, Any]] = None
+    features: Optional[Dict[str, Any]] = None
+    sandboxes: Optional[Dict[str, Any]] = None
+    serve: Optional[Dict[str, Any]] = None
+    engine: Optional[Dict[str, Any]] = None
+    preview: Optional[Dict[str, Any]] = None
+    notifications: Optional[Dict[str, Any]] = None
+    watch: Optional[Dict[str, Any]] = None
+    transforms: Optional[Dict[str, Any]] = None
+    render_api: Optional[Dict[str, Any]] = None
+    proxy: Optional[Dict[str, Any]] = None
     
     def __post_init__(self):
         if self.frameworks is None:
@@ -38,6 +73,7 @@ class FlashFlowProject:
         self.src_path = self.root_path / "src"
         self.flows_path = self.src_path / "flows"
         self.dist_path = self.root_path / "dist"
+        self.state = ProjectState(self.root_path)
         
         self._config: Optional[FlashFlowConfig] = None
     
@@ -64,14 +100,13 @@ class FlashFlowProject:
         if self._config is None:
             return
         
-        config_dict = {
-            "name": self._config.name,
-            "version": self._config.version,
-            "description": self._config.description,
-            "author": self._config.author,
-            "frameworks": self._config.frameworks,
-            "dependencies": self._config.dependencies
-        }
+        # Every field, so a section added to FlashFlowConfig survives a save without touching this;
+        # sections left unset stay out of the file
+        config_dict = {}
+        for config_field in fields(FlashFlowConfig):
+            value = getattr(self._config, config_field.name)
+            if config_field.name in ALWAYS_SAVED or value is not None:
+                config_dict[config_field.name] = value
         
         with open(self.config_path, 'w') as f:
             json.dump(config_dict, f, indent=2)
@@ -145,6 +180,12 @@ class FlashFlowIR:
         self.vector_databases: Dict[str, Any] = {}
         self.webrtc_streams: Dict[str, Any] = {}
         self.lazy_imports: Dict[str, Any] = {}
+        
+        # Request hooks declared in flows
+        self.hooks: List[Dict[str, Any]] = []
+        
+        # Webhooks fired on model changes
+        self.webhooks: List[Dict[str, Any]] = []
     
     def add_model(self, name: str, definition: Dict):
         """Add a model definition to the IR"""
@@ -158,6 +199,14 @@ class FlashFlowIR:
         """Add an API endpoint definition to the IR"""
         self.endpoints[path] = definition
     
+    def add_hook(self, definition: Dict):
+        """Add a request hook definition to the IR"""
+        self.hooks.append(definition)
+    
+    def add_webhook(self, definition: Dict):
+        """Add a webhook definition to the IR"""
+        self.webhooks.append(definition)
+    
     def set_auth(self, auth_config: Dict):
         """Set authentication configuration"""
         self.auth = auth_config
@@ -192,5 +241,7 @@ class FlashFlowIR:
             "ai_models": self.ai_models,
             "vector_databases": self.vector_databases,
             "webrtc_streams": self.webrtc_streams,
-            "lazy_imports": self.lazy_imports
+            "lazy_imports": self.lazy_imports,
+            "hooks": self.hooks,
+            "webhooks": self.webhooks
         }
\ No newline at end of file
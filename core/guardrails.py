@@ -0,0 +1,179 @@
+"""
+FlashFlow guardrails - Resource limits for the dev server and builds, with alerts
+
+Limits live in flashflow.json; a limit that is left out is not checked:
+
+    "guardrails": {
+      "memory_mb": 512,            # dev server resident memory
+      "threads": 200,              # dev server threads
+      "p99_ms": 750,               # 99th percentile request latency over the last minute
+      "build_seconds": 120,        # wall time of 'flashflow build'
+      "webhook": "https://hooks.example.com/flashflow",   # optional; ${NAME} is read from the environment
+      "cooldown_seconds": 300
+    }
+
+The dev server checks a sample of itself every few seconds and each build is
+checked when it finishes. A sample is tagged with the rules it breaks
+("violations"). Each breach is logged to .flashflow/logs/guardrails.log, kept
+in .flashflow/metrics/alerts.json for 'flashflow dash', and POSTed to the
+webhook as JSON. While a rule stays over its limit it alerts again only once
+per cooldown.
+"""
+
+import json
+import logging
+import os
+import threading
+import time
+from dataclasses import dataclass
+from logging.handlers import RotatingFileHandler
+from typing import Any, Dict, List, Optional
+
+import requests
+
+LOG_NAME = 'guardrails.log'
+ALERTS_RECORD = 'alerts.json'
+MAX_ALERTS = 100
+DEFAULT_COOLDOWN = 300.0
+WEBHOOK_TIMEOUT = 5.0
+USER_AGENT = 'FlashFlow-Guardrails/1.0'
+
+class GuardrailError(Exception):
+    """Raised for invalid guardrails settings in flashflow.json"""
+    pass
+
+@dataclass(frozen=True)
+class GuardrailRule:
+    id: str
+    description: str
+    unit: str
+
+RULES = (
+    GuardrailRule('memory_mb', 'dev server memory', 'MB'),
+    GuardrailRule('threads', 'dev server threads', ''),
+    GuardrailRule('p99_ms', 'p99 request latency', 'ms'),
+    GuardrailRule('build_seconds', 'build time', 's'),
+)
+RULE_IDS = tuple(rule.id for rule in RULES)
+
+def guardrail_settings(config) -> Dict[str, Any]:
+    """Validated limits, webhook and cooldown from the 'guardrails' block"""
+    raw = getattr(config, 'guardrails', None) or {}
+    if not isinstance(raw, dict):
+        raise GuardrailError("guardrails must be an object")
+    unknown = set(raw) - set(RULE_IDS) - {'webhook', 'cooldown_seconds'}
+    if unknown:
+        raise GuardrailError(f"Unknown guardrails setting(s) {', '.join(sorted(unknown))}; "
+                             f"limits are {', '.join(RULE_IDS)}")
+    limits = {}
+    for rule_id in RULE_IDS:
+        if raw.get(rule_id) is None:
+            continue
+        value = raw[rule_id]
+        if isinstance(value, bool) or not isinstance(value, (int, float)) or value <= 0:
+            raise GuardrailError(f"guardrails.{rule_id} must be a positive number, got {value!r}")
+        limits[rule_id] = value
+    webhook = os.path.expandvars(str(raw['webhook'])) if raw.get('webhook') else None
+    if webhook and not webhook.startswith(('http://', 'https://')):
+        raise GuardrailError(f"guardrails.webhook must be an http(s) URL, got '{webhook}'")
+    cooldown = raw.get('cooldown_seconds', DEFAULT_COOLDOWN)
+    if isinstance(cooldown, bool) or not isinstance(cooldown, (int, float)) or cooldown < 0:
+        raise GuardrailError(f"guardrails.cooldown_seconds must be a number of seconds, got {cooldown!r}")
+    return {'limits': limits, 'webhook': webhook, 'cooldown_seconds': float(cooldown)}
+
+def guardrail_logger(project) -> logging.Logger:
+    path = project.state.logs_dir / LOG_NAME
+    logger = logging.getLogger(f"flashflow.guardrails.{path}")
+    if not logger.handlers:
+        handler = RotatingFileHandler(path, maxBytes=1024 * 1024, backupCount=2, encoding='utf-8')
+        handler.setFormatter(logging.Formatter('%(asctime)s %(levelname)s %(message)s'))
+        logger.addHandler(handler)
+        logger.setLevel(logging.INFO)
+        logger.propagate = False
+    return logger
+
+def recent_alerts(project, since: Optional[float] = None) -> List[Dict[str, Any]]:
+    """Alerts from .flashflow/metrics/alerts.json, oldest first"""
+    try:
+        alerts = json.loads((project.state.dir / 'metrics' / ALERTS_RECORD).read_text())
+    except (OSError, json.JSONDecodeError):
+        return []
+    return [alert for alert in alerts if since is None or alert.get('time', 0) >= since]
+
+class Guardrails:
+    """Checks samples against the configured limits and raises alerts for breaches"""
+
+    def __init__(self, project, settings: Dict[str, Any]):
+        self.project = project
+        self.limits: Dict[str, float] = settings['limits']
+        self.webhook: Optional[str] = settings['webhook']
+        self.cooldown: float = settings['cooldown_seconds']
+        self._last_alert: Dict[str, float] = {}
+        self._lock = threading.Lock()
+
+    @classmethod
+    def for_project(cls, project) -> Optional['Guardrails']:
+        """The project's guardrails, or None when no limit is set"""
+        settings = guardrail_settings(project.config)
+        return cls(project, settings) if settings['limits'] else None
+
+    def violations(self, sample: Dict[str, Any]) -> List[Dict[str, Any]]:
+        found = []
+        for rule in RULES:
+            limit, value = self.limits.get(rule.id), sample.get(rule.id)
+            if limit is None or value is None or value <= limit:
+                continue
+            found.append({
+                'rule': rule.id, 'value': value, 'limit': limit,
+                'message': f"{rule.description} {value:g}{rule.unit}, over the limit of {limit:g}{rule.unit}"
+            })
+        return found
+
+    def check(self, sample: Dict[str, Any], source: str, wait: bool = False) -> Dict[str, Any]:
+        """The sample tagged with the rules it breaks; alerts for breaches not already alerted within the cooldown.
+        With wait, webhook deliveries finish before returning (for short-lived processes such as a build)."""
+        found = self.violations(sample)
+        tagged = dict(sample, violations=[violation['rule'] for violation in found])
+        now = time.time()
+        senders = []
+        for violation in found:
+            with self._lock:
+                if now - self._last_alert.get(violation['rule'], float('-inf')) < self.cooldown:
+                    continue
+                self._last_alert[violation['rule']] = now
+            alert = dict(violation, source=source, time=now, sample=tagged)
+            guardrail_logger(self.project).warning(f"[{source}] {violation['message']} ({violation['rule']})")
+            self._record(alert)
+            if self.webhook:
+                sender = threading.Thread(target=self._notify, args=(alert,), name='guardrail-webhook', daemon=True)
+                sender.start()
+                senders.append(sender)
+        if wait:
+            for sender in senders:
+                sender.join(WEBHOOK_TIMEOUT + 1)
+        return tagged
+
+    def clear(self, rule_ids):
+        """Forget the cooldown of rules that are back under their limit, so a new breach alerts at once"""
+        with self._lock:
+            for rule_id in rule_ids:
+                self._last_alert.pop(rule_id, None)
+
+    def _record(self, alert: Dict[str, Any]):
+        path = self.project.state.path('metrics', ALERTS_RECORD)
+        with self._lock:
+            alerts = (recent_alerts(self.project) + [alert])[-MAX_ALERTS:]
+            temporary = path.with_suffix('.tmp')
+            temporary.write_text(json.dumps(alerts, indent=2))
+            temporary.replace(path)
+
+    def _notify(self, alert: Dict[str, Any]):
+        payload = {'event': 'guardrail.violated', 'project': self.project.config.name,
+                   **{key: alert[key] for key in ('rule', 'value', 'limit', 'message', 'source', 'time')}}
+        try:
+            response = requests.post(self.webhook, json=payload, timeout=WEBHOOK_TIMEOUT,
+                                     headers={'User-Agent': USER_AGENT})
+            if response.status_code >= 300:
+                guardrail_logger(self.project).error(f"Webhook answered {response.status_code} for {alert['rule']}")
+        except requests.RequestException as e:
+            guardrail_logger(self.project).error(f"Webhook failed for {alert['rule']}: {e}")
@@ -0,0 +1,134 @@
+"""
+FlashFlow HTML safety - Escaping and sanitizing flow content for rendered pages
+
+Flow content reaches HTML in three places, and each needs its own treatment:
+
+    text and attribute values   escape_html(): &, <, >, " and ' become entities
+    href, src, action           safe_url(): only relative, http(s), mailto and tel
+                                URLs (and data: images for src) pass; anything else,
+                                such as javascript:, becomes UNSAFE_URL
+    rich_text 'html'            sanitize_html(): an allowlist of formatting tags and
+                                attributes; scripts, styles, event handlers and
+                                embedded documents are dropped with their content
+
+Renderers must run every flow value through one of these; none of them is
+allowed to put flow content into markup as it is.
+"""
+
+import html
+import re
+from html.parser import HTMLParser
+from typing import Any, List, Optional, Tuple
+
+# What a rejected URL becomes: a link that goes nowhere
+UNSAFE_URL = '#'
+SAFE_SCHEMES = ('http', 'https', 'mailto', 'tel')
+SAFE_DATA_IMAGE = re.compile(r'data:image/(png|gif|jpeg|webp|avif);base64,[A-Za-z0-9+/=\s]*', re.IGNORECASE)
+# Browsers ignore these inside a scheme ('java\tscript:'), so they are ignored before checking it
+URL_IGNORED = re.compile(r'[\x00-\x20\x7f]+')
+URL_SCHEME = re.compile(r'^([a-zA-Z][a-zA-Z0-9+.-]*):')
+
+RICH_TEXT_TAGS = {'p', 'br', 'hr', 'strong', 'b', 'em', 'i', 'u', 's', 'small', 'sub', 'sup', 'mark', 'span',
+                  'a', 'ul', 'ol', 'li', 'blockquote', 'code', 'pre', 'h2', 'h3', 'h4', 'h5', 'h6'}
+VOID_TAGS = {'br', 'hr'}
+RICH_TEXT_ATTRIBUTES = {'a': {'href', 'title'}, 'ol': {'start'}, '*': {'title', 'lang', 'dir'}}
+# Opening one of these ends an unfinished one of the same kind inside the same scope, as in '<li>one<li>two'
+SIBLING_SCOPES = {'li': {'ul', 'ol'}, 'p': {'blockquote', 'li'}}
+# Tags dropped together with everything inside them
+DROPPED_WITH_CONTENT = {'script', 'style', 'iframe', 'object', 'embed', 'template', 'noscript', 'svg', 'math',
+                        'textarea', 'select', 'title', 'head'}
+
+def escape_html(value: Any) -> str:
+    """Text or a quoted attribute value; None is empty"""
+    return html.escape('' if value is None else str(value), quote=True)
+
+def safe_url(value: Any, images: bool = False) -> str:
+    """The URL if following it cannot run script, otherwise UNSAFE_URL; not escaped, so pass it to escape_html"""
+    url = '' if value is None else str(value).strip()
+    if not url:
+        return UNSAFE_URL
+    # Entities are decoded by the browser before the scheme is read ('javascript&#58;')
+    normalized = URL_IGNORED.sub('', html.unescape(url))
+    scheme = URL_SCHEME.match(normalized)
+    if scheme is None:
+        # Relative: '/pricing', 'logo.png', '#faq', '?page=2'; '//host' is protocol-relative http(s)
+        return url if ':' not in re.split(r'[/?#]', normalized, 1)[0] else UNSAFE_URL
+    if scheme.group(1).lower() in SAFE_SCHEMES:
+        return url
+    if images and SAFE_DATA_IMAGE.fullmatch(normalized):
+        return url
+    return UNSAFE_URL
+
+class RichTextSanitizer(HTMLParser):
+    """Rebuilds markup from allowed tags and attributes only, with every text node escaped"""
+
+    def __init__(self):
+        super().__init__(convert_charrefs=True)
+        self.parts: List[str] = []
+        self.open_tags: List[str] = []
+        self.dropping: List[str] = []
+
+    def handle_starttag(self, tag: str, attrs: List[Tuple[str, Optional[str]]]):
+        if self.dropping:
+            if tag == self.dropping[-1] or tag in DROPPED_WITH_CONTENT:
+                self.dropping.append(tag)
+            return
+        if tag in DROPPED_WITH_CONTENT:
+            self.dropping.append(tag)
+            return
+        if tag not in RICH_TEXT_TAGS:
+            return
+        scope = SIBLING_SCOPES.get(tag)
+        if scope is not None:
+            for open_tag in reversed(self.open_tags):
+                if open_tag in scope:
+                    break
+                if open_tag == tag:
+                    self.handle_endtag(tag)
+                    break
+        allowed = RICH_TEXT_ATTRIBUTES.get(tag, set()) | RICH_TEXT_ATTRIBUTES['*']
+        kept = []
+        for name, value in attrs:
+            if name not in allowed or value is None:
+                continue
+            if name == 'href':
+                value = safe_url(value)
+            elif name == 'start' and not value.isdigit():
+                continue
+            kept.append(f' {name}="{escape_html(value)}"')
+        if tag == 'a':
+            kept.append(' rel="nofollow noopener noreferrer"')
+        self.parts.append(f"<{tag}{''.join(kept)}>")
+        if tag not in VOID_TAGS:
+            self.open_tags.append(tag)
+
+    # HTML has no self-closing tags: browsers read '<script/>' and '<b/>' as opening ones
+    handle_startendtag = handle_starttag
+
+    def handle_endtag(self, tag: str):
+        if self.dropping:
+            if tag == self.dropping[-1]:
+                self.dropping.pop()
+            return
+        if tag not in self.open_tags:
+            return
+        # Close whatever was left open inside it, so the output stays well nested
+        while self.open_tags:
+            current = self.open_tags.pop()
+            self.parts.append(f"</{current}>")
+            if current == tag:
+                break
+
+    def handle_data(self, data: str):
+        if not self.dropping:
+            self.parts.append(escape_html(data))
+
+    def result(self) -> str:
+        self.close()
+        return ''.join(self.parts) + ''.join(f"</{tag}>" for tag in reversed(self.open_tags))
+
+def sanitize_html(markup: Any) -> str:
+    """Allowlisted formatting from untrusted markup, safe to put into a page as it is"""
+    sanitizer = RichTextSanitizer()
+    sanitizer.feed('' if markup is None else str(markup))
+    return sanitizer.result()
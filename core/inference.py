@@ -0,0 +1,231 @@
+"""
+FlashFlow inference - ONNX Runtime threading and execution provider selection
+
+Configure in flashflow.json:
+
+    "inference": {
+        "threads": 4,                                  # intra-op threads; 0 lets the runtime decide
+        "inter_op_threads": 0,
+        "providers": ["cuda", "directml", "coreml", "cpu"],
+        "device_id": 0
+    }
+
+Providers are tried in the listed order, and ones the installed runtime does
+not ship are skipped, so the same config works on every machine. CPU always
+comes last: when a GPU provider is present but cannot start (no driver, no
+device), the session is created again on CPU alone.
+FLASHFLOW_INFERENCE_PROVIDERS (comma separated) and FLASHFLOW_INFERENCE_THREADS
+override the config.
+
+run_session() runs a session that a CancelToken can stop mid-run, by
+setting RunOptions.terminate when the token is cancelled.
+"""
+
+import logging
+import os
+from dataclasses import dataclass, field
+from typing import Dict, Any, List, Optional, Tuple
+
+from core.cancellation import CancelToken, Cancelled
+
+logger = logging.getLogger(__name__)
+
+# Short names used in flashflow.json -> ONNX Runtime provider names
+PROVIDERS = {
+    'cpu': 'CPUExecutionProvider',
+    'cuda': 'CUDAExecutionProvider',
+    'tensorrt': 'TensorrtExecutionProvider',
+    'rocm': 'ROCMExecutionProvider',
+    'directml': 'DmlExecutionProvider',
+    'coreml': 'CoreMLExecutionProvider',
+    'openvino': 'OpenVINOExecutionProvider'
+}
+PROVIDER_KINDS = {
+    'cpu': 'cpu', 'cuda': 'gpu', 'tensorrt': 'gpu', 'rocm': 'gpu', 'directml': 'gpu',
+    'coreml': 'accelerator', 'openvino': 'accelerator'
+}
+DEVICE_ID_PROVIDERS = ('cuda', 'tensorrt', 'rocm', 'directml')
+
+class InferenceConfigError(ValueError):
+    """Raised for a malformed 'inference' block"""
+    pass
+
+@dataclass
+class InferenceOptions:
+    """Session settings for ONNX models"""
+    threads: int = 0
+    inter_op_threads: int = 0
+    providers: List[str] = field(default_factory=lambda: ['cpu'])
+    device_id: int = 0
+
+    @classmethod
+    def from_dict(cls, data: Optional[Dict[str, Any]]) -> 'InferenceOptions':
+        data = dict(data or {})
+        unknown = set(data) - {'threads', 'inter_op_threads', 'providers', 'device_id'}
+        if unknown:
+            raise InferenceConfigError(f"Unknown inference setting(s): {', '.join(sorted(unknown))}")
+
+        if os.environ.get('FLASHFLOW_INFERENCE_PROVIDERS'):
+            data['providers'] = os.environ['FLASHFLOW_INFERENCE_PROVIDERS'].split(',')
+        if os.environ.get('FLASHFLOW_INFERENCE_THREADS'):
+            data['threads'] = os.environ['FLASHFLOW_INFERENCE_THREADS']
+
+        providers = data.get('providers', ['cpu'])
+        if isinstance(providers, str):
+            providers = [providers]
+        if not isinstance(providers, list):
+            raise InferenceConfigError("'providers' must be a list of provider names")
+        providers = [str(name).strip().lower() for name in providers if str(name).strip()]
+        for name in providers:
+            if name not in PROVIDERS:
+                raise InferenceConfigError(f"Unknown execution provider '{name}'; use {', '.join(PROVIDERS)}")
+
+        return cls(
+            threads=_non_negative(data.get('threads', 0), 'threads'),
+            inter_op_threads=_non_negative(data.get('inter_op_threads', 0), 'inter_op_threads'),
+            providers=providers or ['cpu'],
+            device_id=_non_negative(data.get('device_id', 0), 'device_id')
+        )
+
+    @classmethod
+    def for_project(cls, project) -> 'InferenceOptions':
+        return cls.from_dict(project.config.inference)
+
+    def preference(self) -> List[str]:
+        """Configured providers in order, with CPU as the final fallback"""
+        return [name for name in self.providers if name != 'cpu'] + ['cpu']
+
+    def binding_kwargs(self) -> Dict[str, Any]:
+        """Constructor options for the FlashCore ONNXRuntime binding"""
+        return {
+            'intra_op_threads': self.threads,
+            'inter_op_threads': self.inter_op_threads,
+            'providers': [PROVIDERS[name] for name in self.preference()],
+            'device_id': self.device_id
+        }
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'threads': self.threads, 'inter_op_threads': self.inter_op_threads,
+                'providers': self.providers, 'device_id': self.device_id}
+
+def _non_negative(value: Any, name: str) -> int:
+    try:
+        number = int(value)
+    except (TypeError, ValueError):
+        raise InferenceConfigError(f"'{name}' must be a whole number")
+    if number < 0:
+        raise InferenceConfigError(f"'{name}' must not be negative")
+    return number
+
+def load_runtime():
+    """The onnxruntime module, or None when it is not installed"""
+    try:
+        import onnxruntime
+    except ImportError:
+        return None
+    return onnxruntime
+
+def short_name(provider: str) -> str:
+    for name, full_name in PROVIDERS.items():
+        if full_name == provider:
+            return name
+    return provider
+
+def available_providers(runtime=None) -> List[str]:
+    """Short names of the providers the installed runtime was built with"""
+    runtime = runtime or load_runtime()
+    if runtime is None:
+        return []
+    return [short_name(provider) for provider in runtime.get_available_providers()]
+
+def select_providers(options: InferenceOptions, available: List[str]) -> Tuple[List[str], List[str]]:
+    """(providers to use, configured providers the runtime lacks)"""
+    selected, skipped = [], []
+    for name in options.preference():
+        if name == 'cpu' or name in available:
+            selected.append(name)
+        else:
+            skipped.append(name)
+    return selected, skipped
+
+def session_options(runtime, options: InferenceOptions):
+    settings = runtime.SessionOptions()
+    if options.threads:
+        settings.intra_op_num_threads = options.threads
+    if options.inter_op_threads:
+        settings.inter_op_num_threads = options.inter_op_threads
+    return settings
+
+def provider_arguments(names: List[str], options: InferenceOptions) -> List[Any]:
+    return [(PROVIDERS[name], {'device_id': options.device_id}) if name in DEVICE_ID_PROVIDERS else PROVIDERS[name]
+            for name in names]
+
+def create_session(model_path: str, options: InferenceOptions):
+    """An InferenceSession on the best configured provider, falling back to CPU; returns (session, info)"""
+    runtime = load_runtime()
+    if runtime is None:
+        raise RuntimeError("ONNX models need onnxruntime: pip install onnxruntime (or onnxruntime-gpu / onnxruntime-directml)")
+
+    selected, skipped = select_providers(options, available_providers(runtime))
+    info = {'requested': options.preference(), 'skipped': skipped, 'fallback': None}
+    try:
+        session = runtime.InferenceSession(model_path, sess_options=session_options(runtime, options),
+                                           providers=provider_arguments(selected, options))
+    except Exception as e:
+        if selected == ['cpu']:
+            raise
+        logger.warning(f"Could not start {', '.join(selected[:-1])} for {model_path}; using CPU: {e}")
+        info['fallback'] = str(e)
+        session = runtime.InferenceSession(model_path, sess_options=session_options(runtime, options),
+                                           providers=provider_arguments(['cpu'], options))
+    info['active'] = [short_name(provider) for provider in session.get_providers()]
+    return session, info
+
+def run_session(session, names: Optional[List[str]], feeds: Dict[str, Any], cancel: Optional[CancelToken] = None):
+    """session.run, stopped with Cancelled when cancel is cancelled before or during the run"""
+    if cancel is None:
+        return session.run(names, feeds)
+    cancel.check()
+    runtime = load_runtime()
+    if runtime is None or not hasattr(runtime, 'RunOptions'):
+        result = session.run(names, feeds)
+        cancel.check()
+        return result
+    run_options = runtime.RunOptions()
+    remove = cancel.on_cancel(lambda: setattr(run_options, 'terminate', True))
+    try:
+        return session.run(names, feeds, run_options)
+    except Exception:
+        if cancel.cancelled:
+            # onnxruntime reports a terminated run as a failure
+            raise Cancelled(cancel.reason)
+        raise
+    finally:
+        remove()
+
+def device_info(options: InferenceOptions) -> Dict[str, Any]:
+    """What the loaded runtime supports and what a session would use"""
+    runtime = load_runtime()
+    available = available_providers(runtime)
+    selected, skipped = select_providers(options, available)
+    return {
+        'runtime': {
+            'name': 'onnxruntime',
+            'version': getattr(runtime, '__version__', None),
+            'device': runtime.get_device() if runtime else None
+        } if runtime else None,
+        'providers': [
+            {'name': name, 'provider': PROVIDERS.get(name, name), 'kind': PROVIDER_KINDS.get(name, 'other'),
+             'configured': name in options.preference(), 'selected': name in selected}
+            for name in available
+        ],
+        'selected': selected if runtime else [],
+        'skipped': skipped,
+        'fallback': 'cpu' if runtime and selected == ['cpu'] and len(options.preference()) > 1 else None,
+        'threads': {
+            'intra_op': options.threads or None,
+            'inter_op': options.inter_op_threads or None,
+            'cpu_count': os.cpu_count()
+        },
+        'config': options.to_dict()
+    }
@@ -0,0 +1,470 @@
+"""
+FlashFlow mock integrations - Fake payment, SMS and push providers for development
+
+Flows that declare 'payments:', 'sms:' or 'push_notifications:' talk to
+these instead of Stripe, Twilio or Firebase while developing. Nothing
+leaves the machine, no account is needed, and every call is captured.
+
+    payments   Stripe-like PaymentIntents: create, confirm, capture, cancel
+    sms        Twilio-like messages
+    push       FCM-like sends to device tokens or a topic
+
+Outcomes are chosen with the providers' usual test values:
+
+    4242424242424242 / pm_card_visa                          succeeds
+    4000000000000002 / pm_card_chargeDeclined                declined (card_declined)
+    4000000000009995 / pm_card_chargeDeclinedInsufficientFunds   declined (insufficient_funds)
+    4000000000000069 / pm_card_chargeDeclinedExpiredCard     declined (expired_card)
+    4000002500003155 / pm_card_authenticationRequired        requires_action until approved or failed
+    +15005550001                                             invalid 'to' number (SMS)
+    +15005550009                                             undelivered (SMS)
+    a push token starting with 'invalid'                     unregistered device
+
+Calls and payment intents are kept in .flashflow/integrations.json (the
+newest MAX_CALLS calls), so they survive restarts.
+"""
+
+import json
+import math
+import re
+import threading
+import time
+import uuid
+from pathlib import Path
+from typing import Any, Dict, List, Optional
+
+INTEGRATIONS = ('payments', 'sms', 'push')
+MAX_CALLS = 500
+MAX_SMS_LENGTH = 1600
+MAX_PUSH_TOKENS = 500
+DEFAULT_CURRENCY = 'usd'
+DEFAULT_COUNTRY_CODE = '+1'
+
+# Test card numbers and the decline each one causes (None succeeds)
+TEST_CARDS = {
+    '4242424242424242': None,
+    '5555555555554444': None,
+    '4000000000000002': 'card_declined',
+    '4000000000009995': 'insufficient_funds',
+    '4000000000000069': 'expired_card',
+    '4000002500003155': 'authentication_required',
+}
+TEST_PAYMENT_METHODS = {
+    'pm_card_visa': '4242424242424242',
+    'pm_card_mastercard': '5555555555554444',
+    'pm_card_chargeDeclined': '4000000000000002',
+    'pm_card_chargeDeclinedInsufficientFunds': '4000000000009995',
+    'pm_card_chargeDeclinedExpiredCard': '4000000000000069',
+    'pm_card_authenticationRequired': '4000002500003155',
+}
+DECLINE_MESSAGES = {
+    'card_declined': "Your card was declined.",
+    'insufficient_funds': "Your card has insufficient funds.",
+    'expired_card': "Your card has expired.",
+}
+INVALID_SMS_NUMBER = '+15005550001'
+UNDELIVERABLE_SMS_NUMBER = '+15005550009'
+PHONE_NUMBER = re.compile(r'^\+[1-9]\d{6,14}$')
+
+class IntegrationError(Exception):
+    """A request the fake provider refuses, answered the way the real API would"""
+
+    def __init__(self, message: str, status: int = 400, code: Optional[str] = None, param: Optional[str] = None,
+                 error_type: str = 'invalid_request_error', extra: Optional[Dict[str, Any]] = None):
+        super().__init__(message)
+        self.status = status
+        self.code = code
+        self.param = param
+        self.error_type = error_type
+        self.extra = extra or {}
+
+    def to_dict(self) -> Dict[str, Any]:
+        error = {'type': self.error_type, 'message': str(self)}
+        if self.code:
+            error['code'] = self.code
+        if self.param:
+            error['param'] = self.param
+        error.update(self.extra)
+        return {'error': error}
+
+def new_id(prefix: str, length: int = 24) -> str:
+    return prefix + uuid.uuid4().hex[:length]
+
+def nested_form(items) -> Dict[str, Any]:
+    """Stripe-style form fields ('metadata[order]=7', 'expand[]=x') as nested values"""
+    result: Dict[str, Any] = {}
+    for key, value in items:
+        match = re.match(r'^([^\[]+)((?:\[[^\]]*\])*)$', key)
+        if not match:
+            continue
+        parts = [match.group(1)] + re.findall(r'\[([^\]]*)\]', match.group(2))
+        target = result
+        for index, part in enumerate(parts):
+            last = index == len(parts) - 1
+            if last:
+                if part == '':
+                    continue
+                target[part] = value
+            elif parts[index + 1] == '':
+                target = target.setdefault(part, [])
+                target.append(value)
+                break
+            else:
+                target = target.setdefault(part, {})
+    return result
+
+def as_bool(value: Any) -> bool:
+    return value is True or str(value).lower() in ('true', '1', 'yes')
+
+def luhn_valid(number: str) -> bool:
+    digits = [int(char) for char in number]
+    checksum = sum(digits[-1::-2]) + sum(sum(divmod(2 * digit, 10)) for digit in digits[-2::-2])
+    return checksum % 10 == 0
+
+class IntegrationStore:
+    """Captured calls, newest first, and the fake provider's objects"""
+
+    def __init__(self, path: Path):
+        self.path = Path(path)
+        self.lock = threading.RLock()
+        data = self._load()
+        self._calls: List[Dict[str, Any]] = data.get('calls', [])
+        self.payment_intents: Dict[str, Dict[str, Any]] = data.get('payment_intents', {})
+
+    def _load(self) -> Dict[str, Any]:
+        if not self.path.exists():
+            return {}
+        try:
+            with open(self.path, 'r') as f:
+                data = json.load(f)
+            return data if isinstance(data, dict) else {}
+        except (OSError, json.JSONDecodeError):
+            return {}
+
+    def save(self):
+        with self.lock:
+            partial = self.path.with_suffix('.tmp')
+            with open(partial, 'w') as f:
+                json.dump({'calls': self._calls, 'payment_intents': self.payment_intents}, f)
+            partial.replace(self.path)
+
+    def record(self, integration: str, operation: str, method: str, path: str, request: Any, status: int,
+               response: Any, request_id: Optional[str] = None) -> Dict[str, Any]:
+        call = {
+            'id': new_id('call_', 12),
+            'time': time.time(),
+            'integration': integration,
+            'operation': operation,
+            'method': method,
+            'path': path,
+            'status': status,
+            'request': request,
+            'response': response,
+            'request_id': request_id
+        }
+        with self.lock:
+            self._calls.insert(0, call)
+            del self._calls[MAX_CALLS:]
+            self.save()
+        return call
+
+    def calls(self, integration: Optional[str] = None, limit: int = MAX_CALLS) -> List[Dict[str, Any]]:
+        with self.lock:
+            calls = [call for call in self._calls if integration in (None, call['integration'])]
+        return [{key: call[key] for key in ('id', 'time', 'integration', 'operation', 'method', 'path', 'status', 'request_id')}
+                for call in calls[:limit]]
+
+    def get_call(self, call_id: str) -> Optional[Dict[str, Any]]:
+        with self.lock:
+            return next((call for call in self._calls if call['id'] == call_id), None)
+
+    def counts(self) -> Dict[str, int]:
+        with self.lock:
+            return {integration: sum(1 for call in self._calls if call['integration'] == integration)
+                    for integration in INTEGRATIONS}
+
+    def clear(self):
+        with self.lock:
+            self._calls = []
+            self.payment_intents = {}
+            self.save()
+
+class FakePayments:
+    """PaymentIntents with Stripe's states: requires_payment_method -> requires_confirmation ->
+    (requires_action) -> requires_capture -> succeeded, or canceled"""
+
+    def __init__(self, store: IntegrationStore, currency: str = DEFAULT_CURRENCY):
+        self.store = store
+        self.currency = currency.lower()
+
+    def _get(self, intent_id: str) -> Dict[str, Any]:
+        intent = self.store.payment_intents.get(intent_id)
+        if intent is None:
+            raise IntegrationError(f"No such payment_intent: '{intent_id}'", 404, 'resource_missing', 'intent')
+        return intent
+
+    def _save(self, intent: Dict[str, Any]) -> Dict[str, Any]:
+        self.store.payment_intents[intent['id']] = intent
+        self.store.save()
+        return dict(intent)
+
+    def _state(self, intent: Dict[str, Any], allowed, action: str):
+        if intent['status'] not in allowed:
+            raise IntegrationError(
+                f"You cannot {action} this PaymentIntent because it has a status of {intent['status']}.",
+                400, 'payment_intent_unexpected_state', extra={'payment_intent': dict(intent)})
+
+    @staticmethod
+    def _amount(value: Any, param: str = 'amount') -> int:
+        try:
+            amount = int(value)
+        except (TypeError, ValueError):
+            raise IntegrationError(f"Invalid integer: {value}", param=param, code='parameter_invalid_integer')
+        if amount < 1:
+            raise IntegrationError("Amount must be at least 1 (in the currency's smallest unit)", param=param,
+                                   code='amount_too_small')
+        return amount
+
+    @staticmethod
+    def card_for(payment_method: str) -> str:
+        """The card number behind a test payment method or a raw number; unknown pm_ ids behave like a good card"""
+        if payment_method in TEST_PAYMENT_METHODS:
+            return TEST_PAYMENT_METHODS[payment_method]
+        number = re.sub(r'[\s-]', '', str(payment_method))
+        if number.isdigit():
+            if not 12 <= len(number) <= 19 or not luhn_valid(number):
+                raise IntegrationError("Your card number is incorrect.", 402, 'incorrect_number', 'payment_method',
+                                       'card_error')
+            return number
+        return '4242424242424242'
+
+    def create(self, params: Dict[str, Any]) -> Dict[str, Any]:
+        if 'amount' not in params:
+            raise IntegrationError("Missing required param: amount.", param='amount', code='parameter_missing')
+        intent_id = new_id('pi_')
+        capture_method = params.get('capture_method', 'automatic')
+        if capture_method not in ('automatic', 'manual'):
+            raise IntegrationError("capture_method must be automatic or manual", param='capture_method')
+        intent = {
+            'id': intent_id,
+            'object': 'payment_intent',
+            'amount': self._amount(params['amount']),
+            'amount_capturable': 0,
+            'amount_received': 0,
+            'currency': str(params.get('currency') or self.currency).lower(),
+            'capture_method': capture_method,
+            'client_secret': f"{intent_id}_secret_{uuid.uuid4().hex[:24]}",
+            'created': int(time.time()),
+            'customer': params.get('customer'),
+            'description': params.get('description'),
+            'metadata': params.get('metadata') or {},
+            'payment_method': params.get('payment_method'),
+            'status': 'requires_confirmation' if params.get('payment_method') else 'requires_payment_method',
+            'last_payment_error': None,
+            'next_action': None,
+            'canceled_at': None,
+            'cancellation_reason': None,
+            'livemode': False
+        }
+        with self.store.lock:
+            self._save(intent)
+            if as_bool(params.get('confirm')):
+                return self.confirm(intent_id, {'return_url': params.get('return_url')})
+            return dict(intent)
+
+    def retrieve(self, intent_id: str) -> Dict[str, Any]:
+        with self.store.lock:
+            return dict(self._get(intent_id))
+
+    def list(self, limit: int = 10) -> Dict[str, Any]:
+        with self.store.lock:
+            intents = sorted(self.store.payment_intents.values(), key=lambda intent: intent['created'], reverse=True)
+        return {'object': 'list', 'url': '/v1/payment_intents', 'has_more': len(intents) > limit,
+                'data': [dict(intent) for intent in intents[:limit]]}
+
+    def update(self, intent_id: str, params: Dict[str, Any]) -> Dict[str, Any]:
+        with self.store.lock:
+            intent = self._get(intent_id)
+            self._state(intent, ('requires_payment_method', 'requires_confirmation', 'requires_action'), 'update')
+            if 'amount' in params:
+                intent['amount'] = self._amount(params['amount'])
+            for key in ('currency', 'description', 'customer'):
+                if key in params:
+                    intent[key] = str(params[key]).lower() if key == 'currency' else params[key]
+            if 'metadata' in params:
+                intent['metadata'] = dict(intent['metadata'], **(params['metadata'] or {}))
+            if params.get('payment_method'):
+                intent['payment_method'] = params['payment_method']
+                intent['status'] = 'requires_confirmation'
+            return self._save(intent)
+
+    def confirm(self, intent_id: str, params: Dict[str, Any]) -> Dict[str, Any]:
+        with self.store.lock:
+            intent = self._get(intent_id)
+            self._state(intent, ('requires_payment_method', 'requires_confirmation'), 'confirm')
+            payment_method = params.get('payment_method') or intent['payment_method']
+            if not payment_method:
+                raise IntegrationError("You cannot confirm this PaymentIntent because it's missing a payment method.",
+                                       400, 'payment_intent_unexpected_state', extra={'payment_intent': dict(intent)})
+            intent['payment_method'] = payment_method
+            intent['last_payment_error'] = None
+            outcome = TEST_CARDS.get(self.card_for(payment_method))
+            if outcome == 'authentication_required':
+                intent['status'] = 'requires_action'
+                intent['next_action'] = {
+                    'type': 'redirect_to_url',
+                    'redirect_to_url': {'url': f"/admin/integrations#{intent_id}", 'return_url': params.get('return_url')}
+                }
+                return self._save(intent)
+            if outcome:
+                return self._decline(intent, outcome)
+            return self._authorized(intent)
+
+    def authenticate(self, intent_id: str, approve: bool) -> Dict[str, Any]:
+        """Finish the fake 3-D Secure step of an intent in requires_action"""
+        with self.store.lock:
+            intent = self._get(intent_id)
+            self._state(intent, ('requires_action',), 'authenticate')
+            intent['next_action'] = None
+            if approve:
+                return self._authorized(intent)
+            intent['status'] = 'requires_payment_method'
+            intent['last_payment_error'] = {
+                'type': 'card_error', 'code': 'payment_intent_authentication_failure',
+                'message': "The provided payment method has failed authentication.",
+                'payment_method': intent['payment_method']
+            }
+            return self._save(intent)
+
+    def _authorized(self, intent: Dict[str, Any]) -> Dict[str, Any]:
+        if intent['capture_method'] == 'manual':
+            intent['status'] = 'requires_capture'
+            intent['amount_capturable'] = intent['amount']
+        else:
+            intent['status'] = 'succeeded'
+            intent['amount_received'] = intent['amount']
+        return self._save(intent)
+
+    def _decline(self, intent: Dict[str, Any], code: str) -> Dict[str, Any]:
+        intent['status'] = 'requires_payment_method'
+        intent['last_payment_error'] = {
+            'type': 'card_error', 'code': 'card_declined', 'decline_code': code,
+            'message': DECLINE_MESSAGES.get(code, "Your card was declined."), 'payment_method': intent['payment_method']
+        }
+        saved = self._save(intent)
+        raise IntegrationError(saved['last_payment_error']['message'], 402, 'card_declined', error_type='card_error',
+                               extra={'decline_code': code, 'payment_intent': saved})
+
+    def capture(self, intent_id: str, params: Dict[str, Any]) -> Dict[str, Any]:
+        with self.store.lock:
+            intent = self._get(intent_id)
+            self._state(intent, ('requires_capture',), 'capture')
+            amount = self._amount(params['amount_to_capture'], 'amount_to_capture') \
+                if 'amount_to_capture' in params else intent['amount_capturable']
+            if amount > intent['amount_capturable']:
+                raise IntegrationError("amount_to_capture must be at most the capturable amount",
+                                       param='amount_to_capture', code='amount_too_large')
+            intent['amount_received'] = amount
+            intent['amount_capturable'] = 0
+            intent['status'] = 'succeeded'
+            return self._save(intent)
+
+    def cancel(self, intent_id: str, params: Dict[str, Any]) -> Dict[str, Any]:
+        with self.store.lock:
+            intent = self._get(intent_id)
+            self._state(intent, ('requires_payment_method', 'requires_confirmation', 'requires_action',
+                                 'requires_capture'), 'cancel')
+            intent['status'] = 'canceled'
+            intent['amount_capturable'] = 0
+            intent['next_action'] = None
+            intent['canceled_at'] = int(time.time())
+            intent['cancellation_reason'] = params.get('cancellation_reason') or 'requested_by_customer'
+            return self._save(intent)
+
+def normalize_phone(number: Any, country_code: str = DEFAULT_COUNTRY_CODE) -> str:
+    """'+44 7700 900123' stays international; '(555) 010-0199' gets the default country code"""
+    text = str(number or '').strip()
+    digits = re.sub(r'\D', '', text)
+    if text.startswith('+'):
+        return '+' + digits
+    if text.startswith('00'):
+        return '+' + digits[2:]
+    return country_code + digits if digits else ''
+
+def sms_segments(body: str) -> int:
+    """Messages are split into 160 (GSM) or 70 (Unicode) character parts, less a header once there are several"""
+    single, multi = (160, 153) if all(ord(char) < 128 for char in body) else (70, 67)
+    return 1 if len(body) <= single else math.ceil(len(body) / multi)
+
+class FakeSms:
+    """Twilio-like message sending"""
+
+    def __init__(self, country_code: str = DEFAULT_COUNTRY_CODE, sender: str = '+15005550006'):
+        self.country_code = country_code
+        self.sender = sender
+
+    def send(self, params: Dict[str, Any]) -> Dict[str, Any]:
+        # Twilio's form fields are capitalised; JSON clients tend not to
+        to = params.get('to') or params.get('To')
+        body = params.get('body') if 'body' in params else params.get('Body')
+        sender = params.get('from') or params.get('From') or self.sender
+        if not to:
+            raise IntegrationError("A 'to' phone number is required", param='to', code='21604')
+        number = normalize_phone(to, self.country_code)
+        if number == INVALID_SMS_NUMBER or not PHONE_NUMBER.match(number):
+            raise IntegrationError(f"The 'To' number {to} is not a valid phone number.", param='to', code='21211')
+        if not body:
+            raise IntegrationError("Message body is required.", param='body', code='21602')
+        if len(str(body)) > MAX_SMS_LENGTH:
+            raise IntegrationError(f"The message body exceeds the {MAX_SMS_LENGTH} character limit.", param='body',
+                                   code='21617')
+        undelivered = number == UNDELIVERABLE_SMS_NUMBER
+        return {
+            'sid': 'SM' + uuid.uuid4().hex,
+            'to': number,
+            'from': str(sender),
+            'body': str(body),
+            'num_segments': str(sms_segments(str(body))),
+            'status': 'undelivered' if undelivered else 'delivered',
+            'error_code': 30006 if undelivered else None,
+            'error_message': "Landline or unreachable carrier" if undelivered else None,
+            'date_created': time.strftime('%a, %d %b %Y %H:%M:%S +0000', time.gmtime())
+        }
+
+class FakePush:
+    """FCM-like sends; each device token gets its own result"""
+
+    def send(self, params: Dict[str, Any]) -> Dict[str, Any]:
+        tokens = params.get('tokens') or ([params['token']] if params.get('token') else [])
+        topic = params.get('topic')
+        notification = params.get('notification') if isinstance(params.get('notification'), dict) else params
+        title, body = notification.get('title'), notification.get('body')
+        if not tokens and not topic:
+            raise IntegrationError("Send to a 'token', a list of 'tokens' or a 'topic'", param='tokens')
+        if not isinstance(tokens, list) or len(tokens) > MAX_PUSH_TOKENS:
+            raise IntegrationError(f"'tokens' must be a list of at most {MAX_PUSH_TOKENS} device tokens", param='tokens')
+        if not title and not body and not params.get('data'):
+            raise IntegrationError("A push needs a 'title', a 'body' or 'data'", param='notification')
+        if params.get('data') is not None and not isinstance(params['data'], dict):
+            raise IntegrationError("'data' must be an object of string values", param='data')
+
+        results = []
+        for token in tokens:
+            if str(token).startswith('invalid'):
+                results.append({'token': token, 'error': {'code': 'UNREGISTERED',
+                                                          'message': "The device token is not registered"}})
+            else:
+                results.append({'token': token, 'message_id': new_id('projects/dev/messages/', 16)})
+        response = {
+            'id': new_id('push_', 16),
+            'title': title,
+            'body': body,
+            'data': params.get('data') or {},
+            'success_count': sum(1 for result in results if 'message_id' in result),
+            'failure_count': sum(1 for result in results if 'error' in result),
+            'results': results
+        }
+        if topic:
+            response['topic'] = topic
+            response['topic_message_id'] = new_id('projects/dev/messages/', 16)
+        return response
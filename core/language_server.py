@@ -0,0 +1,660 @@
+"""
+FlashFlow language server - Language Server Protocol for .flow files
+
+    flashflow lsp
+
+Editors start it as a child process and speak JSON-RPC to it over stdin and
+stdout. It offers
+
+    diagnostics   parse and validation errors (the ones 'flashflow build' stops on) and lint issues
+    completion    sections, component types, a component's props, model names and fields, page routes
+    hover         what a component renders and which props it needs, a model's fields, a route's page
+    definition    model names to their model, routes to their page, include/layout files to the file
+
+Open documents are synced in full and checked as typed, before they are
+saved; everything else is read from src/flows.
+"""
+
+import json
+import re
+import sys
+import traceback
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Any, BinaryIO, Callable, Dict, List, Optional, Tuple
+from urllib.parse import unquote, urlparse
+from urllib.request import url2pathname
+
+from core.flow_lint import FlowLinter, LintConfigError, REQUIRED_PROPS, load_source
+from core.framework import FlashFlowProject
+from core.parser.diagnostics import validate_flow_data
+from core.parser.flow_file import FlowParseError, load_flow, resolve_include
+from core.route_audit import route_pattern
+
+# JSON-RPC error codes
+PARSE_ERROR = -32700
+METHOD_NOT_FOUND = -32601
+INTERNAL_ERROR = -32603
+SERVER_NOT_INITIALIZED = -32002
+
+SEVERITY = {'error': 1, 'warning': 2, 'info': 3}
+KIND_FIELD, KIND_CLASS, KIND_PROPERTY, KIND_KEYWORD, KIND_REFERENCE, KIND_STRUCT = 5, 7, 10, 14, 18, 22
+
+SECTIONS = {
+    'page': "A screen: its `path`, `title` and the components in its `body`",
+    'model': "A database table: `name`, `fields`, `computed` fields and `hooks`; gets CRUD pages and an API",
+    'endpoint': "A custom API route with a `path`, `method` and handler",
+    'authentication': "Login and registration settings",
+    'theme': "Colors, fonts and other design tokens",
+    'ai_models': "ONNX models served at /api/ai/<name>/predict",
+    'hooks': "Code run before and after data API requests",
+    'webhooks': "Outgoing HTTP calls when records change",
+    'notifications': "Web push messages sent on record changes or a schedule",
+    'transforms': "Dev server rules rewriting paths, headers and JSON fields, or stubbing responses",
+    'jobs': "Background jobs",
+    'schedules': "Jobs run on a schedule",
+    'email': "Outgoing email settings",
+    'file_storage': "Where uploads are stored",
+    'search': "Full text search over models",
+    'analytics': "Page and event tracking",
+    'push_notifications': "Push notification settings",
+    'e2e': "End-to-end scenarios run by `flashflow test e2e`",
+}
+PAGE_KEYS = {
+    'path': "The route, e.g. `/orders/{id}`",
+    'title': "Browser tab, sitemap and page heading",
+    'body': "The page's components, top to bottom",
+    'state': "Initial values for `{{ state.x }}` expressions",
+    'permissions': "Who may open the page: `{role: editor}`",
+    'static': "`false` leaves the page out of the static export",
+    'sitemap': "`{priority, changefreq, paths}` for sitemap.xml, or `false` to leave the page out",
+    'noindex': "`true` asks search engines not to index the page",
+    'og': "`{title, description, image, image_alt, type}` for shared link previews, or `false` for none",
+    'twitter': "`{card, site, creator}` for Twitter/X link previews",
+    'feature': "Only while this feature flag is on; `!flag` for while it is off",
+}
+COMMON_PROPS = {
+    'visible_on': "Platforms that show the component: web, ios, android, desktop",
+    'feature': "Feature flag(s) from flashflow.json the component needs; `!flag` inverts",
+    'on_click': "Actions: `set`, `call` or `navigate`",
+}
+COMPONENTS: Dict[str, Dict[str, Any]] = {
+    'header': {'description': "A section heading", 'props': {'content': "The heading text"}},
+    'headline': {'description': "A heading of a given level", 'props': {'text': "The heading text", 'level': "1-5"}},
+    'text': {'description': "A paragraph", 'props': {'content': "The text"}},
+    'rich_text': {'description': "Formatted text from HTML; scripts, styles and event handlers are removed",
+                  'props': {'html': "Markup using p, a, strong, em, lists, headings, code and blockquote"}},
+    'hero': {'description': "A large intro banner with a call to action",
+             'props': {'title': "Headline", 'subtitle': "Line under the title", 'cta': "`{text, link}` button"}},
+    'card': {'description': "A boxed group of content",
+             'props': {'title': "Card title", 'content': "Body text", 'children': "Nested components"}},
+    'features': {'description': "A grid of feature blurbs", 'props': {'items': "List of `{title, description}`"}},
+    'button': {'description': "A button that links or runs actions",
+               'props': {'text': "Label", 'link': "Page to open", 'on_click': "Actions when pressed"}},
+    'primary_button': {'description': "The main call-to-action button",
+                       'props': {'text': "Label", 'link': "Page to open", 'on_click': "Actions when pressed"}},
+    'input': {'description': "A single text input",
+              'props': {'name': "Field name", 'label': "Label shown", 'value': "Initial value", 'disabled': "Read-only"}},
+    'form': {'description': "A form posting to an endpoint or a model",
+             'props': {'fields': "List of fields (`name`, `label`, `type`)", 'model': "Model the form creates",
+                       'action': "Endpoint to post to", 'submit': "Submit button label", 'on_submit': "Actions"}},
+    'image': {'description': "A responsive image from src/assets or a URL",
+              'props': {'src': "Image file or URL", 'alt': "Text for screen readers", 'width': "Pixels",
+                        'height': "Pixels", 'sizes': "The `sizes` attribute"}},
+    'video': {'description': "A video player",
+              'props': {'src': "Video file or URL", 'poster': "Still shown before playing", 'controls': "Show controls",
+                        'autoplay': "Start playing", 'muted': "No sound", 'loop': "Repeat"}},
+    'gallery': {'description': "A grid of images", 'props': {'images': "Image paths or `{src, alt}`", 'columns': "Per row"}},
+    'navbar': {'description': "Top navigation", 'props': {'title': "Brand text", 'links': "`auto` or a list of links"}},
+    'sidebar': {'description': "Side navigation beside the content",
+                'props': {'title': "Heading", 'links': "`auto` or a list of links", 'children': "Main content"}},
+    'tabs': {'description': "Tabbed sections", 'props': {'tabs': "List of `{label, children}`", 'selected': "Open tab"}},
+    'modal': {'description': "A dialog opened by a trigger",
+              'props': {'title': "Dialog title", 'trigger': "Button text", 'children': "Dialog content",
+                        'footer': "Buttons at the bottom"}},
+    'list': {'description': "Records of a model, live from the API", 'props': {'model': "Model to list", 'fields': "Columns"}},
+    'table': {'description': "A table of records", 'props': {'model': "Model to show", 'columns': "Columns"}},
+    'data_table': {'description': "A sortable, paginated table", 'props': {'model': "Model to show", 'columns': "Columns"}},
+    'search': {'description': "A search box over a model", 'props': {'model': "Model to search"}},
+    'chart': {'description': "A chart of model data", 'props': {'model': "Data model", 'type': "line, bar or pie"}},
+}
+LINK_KEYS = ('link', 'href', 'to', 'navigate', 'redirect', 'redirect_after', 'back')
+FILE_KEYS = ('include', 'layout', 'extends')
+COMPONENT_LIST_KEYS = ('body', 'children', 'footer')
+
+class LspError(Exception):
+    """Raised by a handler to answer a request with a JSON-RPC error"""
+
+    def __init__(self, code: int, message: str):
+        super().__init__(message)
+        self.code = code
+
+# Framing
+
+def read_message(stream: BinaryIO) -> Optional[Dict[str, Any]]:
+    """One Content-Length framed message; None at end of input"""
+    length = None
+    while True:
+        line = stream.readline()
+        if not line:
+            return None
+        line = line.strip()
+        if not line:
+            break
+        name, _, value = line.decode('ascii', 'replace').partition(':')
+        if name.strip().lower() == 'content-length':
+            length = int(value.strip())
+    if length is None:
+        raise LspError(PARSE_ERROR, "Message without Content-Length")
+    body = stream.read(length)
+    if len(body) < length:
+        return None
+    try:
+        return json.loads(body.decode('utf-8'))
+    except (UnicodeDecodeError, json.JSONDecodeError) as e:
+        raise LspError(PARSE_ERROR, f"Invalid JSON: {e}")
+
+def write_message(stream: BinaryIO, message: Dict[str, Any]):
+    body = json.dumps(message, separators=(',', ':')).encode('utf-8')
+    stream.write(f"Content-Length: {len(body)}\r\n\r\n".encode('ascii') + body)
+    stream.flush()
+
+# Positions: LSP counts UTF-16 code units unless the client agrees to code points
+
+def uri_to_path(uri: str) -> Path:
+    parsed = urlparse(uri)
+    return Path(url2pathname(unquote(parsed.path)))
+
+def path_to_uri(path: Path) -> str:
+    return Path(path).resolve().as_uri()
+
+def to_client(text: str, index: int, utf16: bool) -> int:
+    return len(text[:index].encode('utf-16-le')) // 2 if utf16 else index
+
+def from_client(text: str, character: int, utf16: bool) -> int:
+    if not utf16:
+        return min(character, len(text))
+    units = 0
+    for index, char in enumerate(text):
+        if units >= character:
+            return index
+        units += 2 if ord(char) > 0xFFFF else 1
+    return len(text)
+
+# The project's models and pages
+
+@dataclass
+class Symbol:
+    name: str
+    file: Path
+    line: int
+    detail: str = ''
+    fields: Dict[str, Tuple[str, int]] = field(default_factory=dict)
+
+@dataclass
+class FlowIndex:
+    models: Dict[str, Symbol] = field(default_factory=dict)
+    pages: Dict[str, Symbol] = field(default_factory=dict)
+
+    def model(self, name: str) -> Optional[Symbol]:
+        if name in self.models:
+            return self.models[name]
+        lowered = {model.lower(): symbol for model, symbol in self.models.items()}
+        return lowered.get(name.lower())
+
+    def page_for(self, route: str) -> Optional[Symbol]:
+        route = route.split('?')[0].split('#')[0]
+        if route in self.pages:
+            return self.pages[route]
+        return next((page for path, page in self.pages.items() if route_pattern(path).match(route)), None)
+
+def model_fields(fields: Any, lines, base: Tuple) -> Dict[str, Tuple[str, int]]:
+    """name -> (type, line), for fields written as a mapping or as a list of {name, type}"""
+    found = {}
+    if isinstance(fields, dict):
+        for name, spec in fields.items():
+            found[str(name)] = (str(spec).split()[0] if spec else 'string', lines(base + (name,)) or 0)
+    elif isinstance(fields, list):
+        for index, item in enumerate(fields):
+            if isinstance(item, dict) and item.get('name'):
+                found[str(item['name'])] = (str(item.get('type', 'string')), lines(base + (index,)) or 0)
+    return found
+
+class FlowWorkspace:
+    """Open documents over the files on disk"""
+
+    def __init__(self, root: Path):
+        self.root = Path(root)
+        self.documents: Dict[Path, str] = {}
+        self._index: Optional[FlowIndex] = None
+
+    def open(self, path: Path, text: str):
+        self.documents[path] = text
+        self._index = None
+
+    def close(self, path: Path):
+        self.documents.pop(path, None)
+        self._index = None
+
+    def changed_on_disk(self):
+        self._index = None
+
+    def text(self, path: Path) -> str:
+        if path in self.documents:
+            return self.documents[path]
+        try:
+            return path.read_text(encoding='utf-8')
+        except OSError:
+            return ''
+
+    def flow_files(self) -> List[Path]:
+        flows = self.root / 'src' / 'flows'
+        files = {path.resolve() for path in flows.glob('*.flow')} if flows.is_dir() else set()
+        files.update(path for path in self.documents if path.suffix == '.flow')
+        return sorted(files)
+
+    def lint_config(self) -> Optional[Dict[str, Any]]:
+        try:
+            return FlashFlowProject(self.root).config.lint
+        except Exception:
+            return None
+
+    def index(self) -> FlowIndex:
+        if self._index is not None:
+            return self._index
+        index = FlowIndex()
+        for path in self.flow_files():
+            source, _ = load_source(path, self.root, self.documents.get(path))
+            if source is None or not isinstance(source.data, dict):
+                continue
+            model = source.data.get('model')
+            if isinstance(model, dict) and model.get('name'):
+                name = str(model['name'])
+                fields = model_fields(model.get('fields'), source.line, ('model', 'fields'))
+                index.models[name] = Symbol(name, path, source.line(('model', 'name')) or 1,
+                                            f"{len(fields)} field(s)", fields)
+            page = source.data.get('page')
+            if isinstance(page, dict) and page.get('path'):
+                route = str(page['path'])
+                index.pages[route] = Symbol(route, path, source.line(('page', 'path')) or 1, str(page.get('title') or ''))
+        self._index = index
+        return index
+
+# Where the cursor is
+
+def indent_of(line: str) -> int:
+    return len(line) - len(line.lstrip(' '))
+
+def key_of(line: str) -> Tuple[Optional[str], int, str]:
+    """(key, column of the key, value text) for 'key: value' and '- key: value' lines"""
+    match = re.match(r'^(\s*(?:-\s+)?)([A-Za-z_][\w-]*)\s*:(.*)$', line)
+    if not match:
+        return None, indent_of(line), ''
+    return match.group(2), len(match.group(1)), match.group(3).strip()
+
+def enclosing(lines: List[str], number: int, column: int, item: bool = False) -> Tuple[Optional[str], Optional[str]]:
+    """(parent key, component type) of the mapping a key at this column belongs to
+
+    item means the line starts a new '- ' list item, whose parent is the key holding the list.
+    """
+    component_type = None
+    if item:
+        column = indent_of(lines[number])
+    for previous in range(number - 1, -1, -1):
+        text = lines[previous]
+        if not text.strip() or text.lstrip().startswith('#'):
+            continue
+        key, key_column, value = key_of(text)
+        dash = text.lstrip().startswith('-')
+        if item:
+            # The list's key sits left of the dashes, or level with them ('body:' then '- x' unindented)
+            if indent_of(text) < column or (indent_of(text) == column and not dash):
+                return key, component_type
+            continue
+        if key_column == column:
+            if key == 'component':
+                component_type = value.strip('\'"')
+            if dash:
+                # The first key of a list item; the parent holds the list
+                item, column = True, indent_of(text)
+            continue
+        if key_column < column:
+            if component_type is None and key in COMPONENTS and not value:
+                # '- hero:' with its props below
+                component_type = key
+            return key, component_type
+    return None, component_type
+
+def word_at(text: str, index: int, pattern: str = r'[\w]') -> Tuple[str, int, int]:
+    start = index
+    while start > 0 and re.match(pattern, text[start - 1]):
+        start -= 1
+    end = index
+    while end < len(text) and re.match(pattern, text[end]):
+        end += 1
+    return text[start:end], start, end
+
+class FlowLanguageServer:
+    """Dispatches LSP requests and notifications for one editor session"""
+
+    def __init__(self, root: Path, log: Callable[[str], None] = None, version: str = ''):
+        self.workspace = FlowWorkspace(root)
+        self.version = version
+        self.log = log or (lambda message: print(message, file=sys.stderr))
+        self.utf16 = True
+        self.initialized = False
+        self.running = True
+        self.exit_code = 1
+        self._writer: Optional[BinaryIO] = None
+        self._published: set = set()
+        self.handlers: Dict[str, Callable[[Dict[str, Any]], Any]] = {
+            'initialize': self.initialize,
+            'initialized': lambda params: None,
+            'shutdown': self.shutdown,
+            'exit': self.exit,
+            'textDocument/didOpen': self.did_open,
+            'textDocument/didChange': self.did_change,
+            'textDocument/didSave': self.did_save,
+            'textDocument/didClose': self.did_close,
+            'workspace/didChangeWatchedFiles': self.did_change_watched_files,
+            'textDocument/completion': self.completion,
+            'textDocument/hover': self.hover,
+            'textDocument/definition': self.definition,
+        }
+
+    # Transport
+
+    def serve(self, reader: BinaryIO, writer: BinaryIO) -> int:
+        """Handle messages until 'exit' or end of input; returns the process exit code"""
+        self._writer = writer
+        while self.running:
+            try:
+                message = read_message(reader)
+            except LspError as e:
+                self.send({'jsonrpc': '2.0', 'id': None, 'error': {'code': e.code, 'message': str(e)}})
+                continue
+            if message is None:
+                break
+            self.handle(message)
+        return self.exit_code
+
+    def send(self, message: Dict[str, Any]):
+        if self._writer is not None:
+            write_message(self._writer, message)
+
+    def notify(self, method: str, params: Dict[str, Any]):
+        self.send({'jsonrpc': '2.0', 'method': method, 'params': params})
+
+    def handle(self, message: Dict[str, Any]):
+        method = message.get('method')
+        request_id = message.get('id')
+        is_request = 'id' in message
+        if method is None:
+            # A response to something we sent; the server sends no requests
+            return
+        handler = self.handlers.get(method)
+        try:
+            if not self.initialized and method not in ('initialize', 'exit') and is_request:
+                raise LspError(SERVER_NOT_INITIALIZED, "Send 'initialize' first")
+            if handler is None:
+                if is_request and not method.startswith('$/'):
+                    raise LspError(METHOD_NOT_FOUND, f"Unsupported method: {method}")
+                return
+            result = handler(message.get('params') or {})
+        except LspError as e:
+            if is_request:
+                self.send({'jsonrpc': '2.0', 'id': request_id, 'error': {'code': e.code, 'message': str(e)}})
+            return
+        except Exception as e:
+            self.log(f"{method} failed:\n{traceback.format_exc()}")
+            if is_request:
+                self.send({'jsonrpc': '2.0', 'id': request_id,
+                           'error': {'code': INTERNAL_ERROR, 'message': f"{type(e).__name__}: {e}"}})
+            return
+        if is_request:
+            self.send({'jsonrpc': '2.0', 'id': request_id, 'result': result})
+
+    # Lifecycle
+
+    def initialize(self, params: Dict[str, Any]) -> Dict[str, Any]:
+        folders = params.get('workspaceFolders') or []
+        root_uri = params.get('rootUri') or (folders[0]['uri'] if folders else None)
+        if root_uri:
+            self.workspace = FlowWorkspace(uri_to_path(root_uri))
+        elif params.get('rootPath'):
+            self.workspace = FlowWorkspace(Path(params['rootPath']))
+        encodings = ((params.get('capabilities') or {}).get('general') or {}).get('positionEncodings') or []
+        self.utf16 = 'utf-32' not in encodings
+        self.initialized = True
+        self.log(f"FlashFlow language server for {self.workspace.root}")
+        return {
+            'capabilities': {
+                'positionEncoding': 'utf-16' if self.utf16 else 'utf-32',
+                'textDocumentSync': {'openClose': True, 'change': 1, 'save': {'includeText': False}},
+                'completionProvider': {'triggerCharacters': ['.', ':', ' ', '/', '-']},
+                'hoverProvider': True,
+                'definitionProvider': True,
+            },
+            'serverInfo': {'name': 'flashflow', 'version': self.version}
+        }
+
+    def shutdown(self, params) -> None:
+        self.exit_code = 0
+        return None
+
+    def exit(self, params) -> None:
+        self.running = False
+
+    # Documents
+
+    def did_open(self, params: Dict[str, Any]):
+        document = params['textDocument']
+        self.workspace.open(uri_to_path(document['uri']).resolve(), document.get('text', ''))
+        self.publish_all()
+
+    def did_change(self, params: Dict[str, Any]):
+        changes = params.get('contentChanges') or []
+        if changes:
+            # Full sync: the last change holds the whole text
+            self.workspace.open(uri_to_path(params['textDocument']['uri']).resolve(), changes[-1].get('text', ''))
+            self.publish_all()
+
+    def did_save(self, params: Dict[str, Any]):
+        self.workspace.changed_on_disk()
+        self.publish_all()
+
+    def did_close(self, params: Dict[str, Any]):
+        path = uri_to_path(params['textDocument']['uri']).resolve()
+        self.workspace.close(path)
+        self.notify('textDocument/publishDiagnostics', {'uri': path_to_uri(path), 'diagnostics': []})
+        self._published.discard(path)
+        self.publish_all()
+
+    def did_change_watched_files(self, params: Dict[str, Any]):
+        self.workspace.changed_on_disk()
+        self.publish_all()
+
+    # Diagnostics
+
+    def publish_all(self):
+        """Lint rules look across files (unused models, duplicate routes), so every open document is checked again"""
+        workspace = self.workspace
+        try:
+            linter = FlowLinter(workspace.root, workspace.lint_config())
+        except LintConfigError as e:
+            self.log(f"{e}; using the default lint rules")
+            linter = FlowLinter(workspace.root)
+        open_flows = [path for path in workspace.documents if path.suffix == '.flow']
+        issues = linter.lint(workspace.flow_files(), dict(workspace.documents)) if open_flows else []
+        for path in open_flows:
+            self.notify('textDocument/publishDiagnostics',
+                        {'uri': path_to_uri(path), 'diagnostics': self.diagnostics(path, issues)})
+            self._published.add(path)
+
+    def diagnostics(self, path: Path, lint_issues) -> List[Dict[str, Any]]:
+        text = self.workspace.text(path)
+        lines = text.split('\n')
+        results = []
+        try:
+            data = load_flow(path, self.workspace.root, content=text).data
+        except FlowParseError as e:
+            return [self.diagnostic(lines, e.line, e.column, 'error', e.problem, None, 'parse')]
+        for problem in validate_flow_data(path.name, text, data):
+            results.append(self.diagnostic(lines, problem.line, problem.column, problem.severity, problem.message,
+                                           problem.suggestion, None))
+        relative = self.relative(path)
+        for issue in lint_issues:
+            if issue.file == relative and not issue.suppressed and issue.rule != 'parse':
+                results.append(self.diagnostic(lines, issue.line, issue.column, issue.severity, issue.message,
+                                               issue.suggestion, issue.rule))
+        return results
+
+    def relative(self, path: Path) -> str:
+        try:
+            return path.resolve().relative_to(self.workspace.root.resolve()).as_posix()
+        except ValueError:
+            return path.as_posix()
+
+    def diagnostic(self, lines: List[str], line: Optional[int], column: Optional[int], severity: str, message: str,
+                   suggestion: Optional[str], code: Optional[str]) -> Dict[str, Any]:
+        number = min(max((line or 1) - 1, 0), max(len(lines) - 1, 0))
+        text = lines[number] if lines else ''
+        start = (column - 1) if column else indent_of(text)
+        diagnostic = {
+            'range': {'start': {'line': number, 'character': to_client(text, min(start, len(text)), self.utf16)},
+                      'end': {'line': number, 'character': to_client(text, len(text.rstrip()), self.utf16)}},
+            'severity': SEVERITY.get(severity, 2),
+            'source': 'flashflow',
+            'message': message + (f"\n💡 {suggestion}" if suggestion else '')
+        }
+        if code:
+            diagnostic['code'] = code
+        return diagnostic
+
+    # Completion, hover and definition
+
+    def position(self, params: Dict[str, Any]) -> Tuple[Path, List[str], int, int]:
+        path = uri_to_path(params['textDocument']['uri']).resolve()
+        lines = self.workspace.text(path).split('\n')
+        number = params['position']['line']
+        text = lines[number] if number < len(lines) else ''
+        return path, lines, number, from_client(text, params['position']['character'], self.utf16)
+
+    def completion(self, params: Dict[str, Any]) -> Dict[str, Any]:
+        path, lines, number, index = self.position(params)
+        text = lines[number] if number < len(lines) else ''
+        before = text[:index]
+        flow_index = self.workspace.index()
+        items: List[Dict[str, Any]] = []
+
+        def add(label: str, kind: int, detail: str = '', documentation: str = '', start: Optional[int] = None):
+            item = {'label': label, 'kind': kind}
+            if detail:
+                item['detail'] = detail
+            if documentation:
+                item['documentation'] = {'kind': 'markdown', 'value': documentation}
+            if start is not None:
+                item['textEdit'] = {'newText': label, 'range': {
+                    'start': {'line': number, 'character': to_client(text, start, self.utf16)},
+                    'end': {'line': number, 'character': to_client(text, index, self.utf16)}}}
+            items.append(item)
+
+        link = re.search(rf"\b({'|'.join(LINK_KEYS)})\s*:\s*['\"]?(\S*)$", before)
+        field_access = re.search(r'\b([A-Za-z_]\w*)\.(\w*)$', before)
+        key, column, _ = key_of(before + 'x:') if re.match(r'^\s*(-\s+)?[\w-]*$', before) else (None, 0, '')
+
+        if link:
+            for route, page in sorted(flow_index.pages.items()):
+                add(route, KIND_REFERENCE, page.detail, f"Page in `{self.relative(page.file)}`", index - len(link.group(2)))
+        elif field_access and flow_index.model(field_access.group(1)):
+            model = flow_index.model(field_access.group(1))
+            for name, (field_type, _) in model.fields.items():
+                add(name, KIND_FIELD, field_type)
+        elif re.search(r'(\bmodel\s*:|\breferences)\s+\w*$', before):
+            for name, model in sorted(flow_index.models.items()):
+                add(name, KIND_STRUCT, model.detail, self.model_docs(model))
+        elif re.search(r'\bcomponent\s*:\s*\w*$', before):
+            for name in sorted(COMPONENTS):
+                add(name, KIND_CLASS, COMPONENTS[name]['description'], self.component_docs(name))
+        elif key is not None and column == 0 and not before.lstrip().startswith('-'):
+            for name, description in SECTIONS.items():
+                add(name, KIND_KEYWORD, description)
+        elif key is not None:
+            item = before.lstrip().startswith('-')
+            parent, component_type = enclosing(lines, number, column, item)
+            if item and parent in COMPONENT_LIST_KEYS:
+                add('component', KIND_PROPERTY, "The component type")
+                for name in sorted(COMPONENTS):
+                    add(name, KIND_CLASS, COMPONENTS[name]['description'], self.component_docs(name))
+            elif component_type in COMPONENTS:
+                props = dict(COMPONENTS[component_type]['props'], **COMMON_PROPS)
+                for name, description in props.items():
+                    add(name, KIND_PROPERTY, description)
+            elif parent == 'page':
+                for name, description in PAGE_KEYS.items():
+                    add(name, KIND_PROPERTY, description)
+        return {'isIncomplete': False, 'items': items}
+
+    def hover(self, params: Dict[str, Any]) -> Optional[Dict[str, Any]]:
+        path, lines, number, index = self.position(params)
+        text = lines[number] if number < len(lines) else ''
+        flow_index = self.workspace.index()
+        word, start, end = word_at(text, index)
+        token, token_start, token_end = word_at(text, index, r'[^\s\'",\[\]{}]')
+        contents = None
+
+        if token.startswith('/') and flow_index.page_for(token):
+            page = flow_index.page_for(token)
+            contents = f"**Page** `{page.name}`" + (f" — {page.detail}" if page.detail else '') + \
+                       f"\n\nDefined in `{self.relative(page.file)}:{page.line}`"
+            start, end = token_start, token_end
+        elif word in COMPONENTS and re.search(rf"(component\s*:\s*['\"]?{word}\b|^\s*-\s*{word}\s*:)", text):
+            contents = self.component_docs(word)
+        elif word and flow_index.model(word):
+            contents = self.model_docs(flow_index.model(word))
+        elif word in SECTIONS and indent_of(text) == 0:
+            contents = f"**{word}**\n\n{SECTIONS[word]}"
+        if not contents:
+            return None
+        return {'contents': {'kind': 'markdown', 'value': contents},
+                'range': {'start': {'line': number, 'character': to_client(text, start, self.utf16)},
+                          'end': {'line': number, 'character': to_client(text, end, self.utf16)}}}
+
+    def definition(self, params: Dict[str, Any]) -> Optional[List[Dict[str, Any]]]:
+        path, lines, number, index = self.position(params)
+        text = lines[number] if number < len(lines) else ''
+        flow_index = self.workspace.index()
+        key, _, value = key_of(text)
+
+        if key in FILE_KEYS and value:
+            target = self.resolve_file(path, value.strip('\'"'))
+            return [self.location(target, 1)] if target else None
+        token = word_at(text, index, r'[^\s\'",\[\]{}]')[0]
+        if token.startswith('/') and flow_index.page_for(token):
+            page = flow_index.page_for(token)
+            return [self.location(page.file, page.line)]
+        word = word_at(text, index)[0]
+        model = flow_index.model(word) if word else None
+        if model:
+            return [self.location(model.file, model.line)]
+        return None
+
+    def resolve_file(self, current: Path, name: str) -> Optional[Path]:
+        # Looked up the way the build resolves includes
+        return resolve_include(current, name, self.workspace.root)
+
+    def location(self, path: Path, line: int) -> Dict[str, Any]:
+        position = {'line': max(line - 1, 0), 'character': 0}
+        return {'uri': path_to_uri(path), 'range': {'start': position, 'end': position}}
+
+    def component_docs(self, name: str) -> str:
+        component = COMPONENTS[name]
+        required = {option for entry in REQUIRED_PROPS.get(name, [])
+                    for option in (entry if isinstance(entry, tuple) else (entry,))}
+        props = '\n'.join(f"- `{prop}`{' (required)' if prop in required else ''}: {description}"
+                          for prop, description in component['props'].items())
+        return f"**{name}**\n\n{component['description']}\n\n{props}"
+
+    def model_docs(self, model: Symbol) -> str:
+        fields = '\n'.join(f"- `{name}`: {field_type}" for name, (field_type, _) in model.fields.items())
+        return f"**Model** `{model.name}`\n\n{fields or '_no fields_'}\n\nDefined in `{self.relative(model.file)}:{model.line}`"
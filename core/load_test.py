@@ -0,0 +1,348 @@
+"""
+FlashFlow load test - HTTP load against the endpoints generated from the flows
+
+'flashflow loadtest' sends requests to a running server ('flashflow serve' or a
+deployed backend) from a pool of workers and reports latency percentiles as
+it goes. A target is one of:
+
+    users                       GET /api/data/users, the list endpoint of a model's table
+    POST users                  creates, with a body made from the model's fields
+    GET /api/data/users/1       any method and path
+
+With no targets every model's list endpoint is used. Targets share the load
+in turn. Bodies are templates whose strings may hold '{{ ... }}' expressions
+(same rules as flow hooks) over:
+
+    n        the request's sequence number in the run, from 1
+    worker   the worker sending it, from 0
+    run      a short id of this run, so unique fields stay unique across runs
+    random   a float in [0, 1)
+
+A model field 'email' becomes "load-{{ run }}-{{ n }}@example.test", an
+integer "{{ n }}", and so on (see FIELD_TEMPLATES); --payload replaces the
+generated body. With a rate the workers share a fixed schedule of
+rate requests per second, otherwise each sends as fast as answers come back.
+Answers with status 400 and up and connection failures count as errors.
+"""
+
+import http.client
+import json
+import math
+import random
+import re
+import threading
+import time
+import uuid
+from dataclasses import dataclass, field
+from typing import Any, Callable, Dict, List, Optional, Tuple
+from urllib.parse import urlsplit
+
+from core.database import model_field_list, table_name_for
+from core.utils.expressions import ExpressionError, evaluate
+
+TEMPLATE_PATTERN = re.compile(r"\{\{\s*(.+?)\s*\}\}")
+TARGET_PATTERN = re.compile(r"^(?:(?P<method>[A-Za-z]+)\s+)?(?P<path>\S+)$")
+METHODS = ('GET', 'POST', 'PUT', 'PATCH', 'DELETE', 'HEAD')
+PERCENTILES = (50, 90, 95, 99)
+MAX_SAMPLES = 200000
+# Bodies for creates, by model field type; other types get the string template
+FIELD_TEMPLATES = {
+    'string': "load-{{ run }}-{{ n }}",
+    'text': "Load test row {{ n }} of run {{ run }}",
+    'email': "load-{{ run }}-{{ n }}@example.test",
+    'url': "https://example.test/{{ run }}/{{ n }}",
+    'password': "Load-{{ run }}-{{ n }}!",
+    'integer': "{{ n }}",
+    'float': "{{ n + random }}",
+    'boolean': "{{ n % 2 == 0 }}",
+    'date': "2024-01-01",
+    'datetime': "2024-01-01T00:00:00Z",
+    'timestamp': "2024-01-01T00:00:00Z",
+    'json': {'load_test': "{{ run }}", 'n': "{{ n }}"},
+}
+
+class LoadTestError(Exception):
+    """Raised for a target, payload or URL the load test cannot use"""
+    pass
+
+@dataclass
+class LoadTarget:
+    """One endpoint under load"""
+    method: str
+    path: str
+    body: Any = None
+
+    @property
+    def name(self) -> str:
+        return f"{self.method} {self.path}"
+
+@dataclass
+class LoadTestOptions:
+    url: str = 'http://localhost:8000'
+    duration: float = 10.0
+    concurrency: int = 10
+    rate: float = 0.0
+    timeout: float = 10.0
+    headers: Dict[str, str] = field(default_factory=dict)
+
+    def validate(self):
+        parts = urlsplit(self.url)
+        if parts.scheme not in ('http', 'https') or not parts.hostname:
+            raise LoadTestError(f"'{self.url}' is not an http(s) URL")
+        if self.duration <= 0:
+            raise LoadTestError("The duration must be more than 0 seconds")
+        if self.concurrency < 1:
+            raise LoadTestError("The concurrency must be at least 1")
+        if self.rate < 0:
+            raise LoadTestError("The rate cannot be negative")
+
+def render_template(value: Any, context: Dict[str, Any]) -> Any:
+    """Fill '{{ expr }}' in strings, lists and mappings; a lone placeholder keeps its type"""
+    if isinstance(value, str):
+        if '{{' not in value:
+            return value
+        try:
+            whole = TEMPLATE_PATTERN.fullmatch(value.strip())
+            if whole:
+                return evaluate(whole.group(1), context)
+            return TEMPLATE_PATTERN.sub(lambda match: str(evaluate(match.group(1), context)), value)
+        except ExpressionError as e:
+            raise LoadTestError(f"{value}: {str(e)}")
+    if isinstance(value, list):
+        return [render_template(item, context) for item in value]
+    if isinstance(value, dict):
+        return {key: render_template(item, context) for key, item in value.items()}
+    return value
+
+def payload_template(fields: List[Dict[str, Any]]) -> Dict[str, Any]:
+    """A create body for a model, from its fields; generated and auto fields are left to the server"""
+    body = {}
+    for model_field in fields:
+        name = model_field.get('name')
+        if not name or name == 'id' or model_field.get('auto'):
+            continue
+        if isinstance(model_field.get('values'), list) and model_field['values']:
+            body[name] = model_field['values'][0]
+        elif model_field.get('references'):
+            # Rows the load test did not create; the first one usually exists
+            body[name] = 1
+        else:
+            body[name] = FIELD_TEMPLATES.get(str(model_field.get('type', 'string')), FIELD_TEMPLATES['string'])
+    return body
+
+def model_tables(ir) -> Dict[str, List[Dict[str, Any]]]:
+    """Table name → fields, for every model in the flows"""
+    return {table_name_for(name): model_field_list(data.get('fields')) if isinstance(data, dict) else []
+            for name, data in ir.models.items()}
+
+def parse_target(spec: str, tables: Dict[str, List[Dict[str, Any]]], payload: Any = None) -> LoadTarget:
+    """A target from the command line; see the module docstring"""
+    match = TARGET_PATTERN.match(spec.strip())
+    if not match:
+        raise LoadTestError(f"'{spec}' is not a target; use a model table, 'POST <table>' or '<METHOD> /path'")
+    method = (match.group('method') or 'GET').upper()
+    if method not in METHODS:
+        raise LoadTestError(f"Unknown method '{method}'; use {', '.join(METHODS)}")
+    path = match.group('path')
+    if path.startswith('/'):
+        return LoadTarget(method, path, payload if method in ('POST', 'PUT', 'PATCH') else None)
+
+    table = path if path in tables else table_name_for(path)
+    if table not in tables:
+        known = ', '.join(sorted(tables)) or 'none'
+        raise LoadTestError(f"No model in the flows uses table '{path}' (tables: {known})")
+    if method == 'POST':
+        return LoadTarget(method, f"/api/data/{table}", payload if payload is not None else payload_template(tables[table]))
+    if method != 'GET':
+        raise LoadTestError(f"'{spec}': give {method} a path, such as {method} /api/data/{table}/1")
+    return LoadTarget(method, f"/api/data/{table}")
+
+def default_targets(tables: Dict[str, List[Dict[str, Any]]]) -> List[LoadTarget]:
+    return [LoadTarget('GET', f"/api/data/{table}") for table in sorted(tables)]
+
+def percentile(ordered: List[float], pct: float) -> Optional[float]:
+    """Nearest-rank percentile of an ascending list"""
+    if not ordered:
+        return None
+    rank = max(1, math.ceil(pct / 100 * len(ordered)))
+    return ordered[min(rank, len(ordered)) - 1]
+
+class TargetStats:
+    """Latencies and outcomes of one target, kept under the runner's lock"""
+
+    def __init__(self):
+        self.latencies: List[float] = []
+        self.requests = 0
+        self.errors = 0
+        self.statuses: Dict[str, int] = {}
+        self.failures: Dict[str, int] = {}
+        self.bytes = 0
+
+    def add(self, seconds: float, status: Optional[int], size: int = 0, failure: Optional[str] = None):
+        self.requests += 1
+        self.bytes += size
+        if len(self.latencies) < MAX_SAMPLES:
+            self.latencies.append(seconds)
+        else:
+            # Reservoir sampling keeps the percentiles honest on long runs
+            slot = random.randrange(self.requests)
+            if slot < MAX_SAMPLES:
+                self.latencies[slot] = seconds
+        if status is None:
+            self.errors += 1
+            self.failures[failure or 'error'] = self.failures.get(failure or 'error', 0) + 1
+            return
+        self.statuses[str(status)] = self.statuses.get(str(status), 0) + 1
+        if status >= 400:
+            self.errors += 1
+
+    def summary(self, seconds: float) -> Dict[str, Any]:
+        ordered = sorted(self.latencies)
+        data = {'requests': self.requests, 'errors': self.errors,
+                'error_rate': round(self.errors / self.requests, 4) if self.requests else 0.0,
+                'rps': round(self.requests / seconds, 2) if seconds else 0.0,
+                'statuses': dict(self.statuses), 'failures': dict(self.failures), 'bytes': self.bytes,
+                'mean_ms': round(sum(ordered) / len(ordered) * 1000, 3) if ordered else None,
+                'max_ms': round(ordered[-1] * 1000, 3) if ordered else None}
+        for pct in PERCENTILES:
+            value = percentile(ordered, pct)
+            data[f"p{pct}_ms"] = round(value * 1000, 3) if value is not None else None
+        return data
+
+@dataclass
+class LoadTestReport:
+    """A finished run"""
+    options: LoadTestOptions
+    run: str
+    seconds: float
+    targets: Dict[str, Dict[str, Any]]
+    total: Dict[str, Any]
+    interrupted: bool = False
+
+    def bench_results(self) -> Dict[str, Dict[str, Any]]:
+        """Results in the shape of 'flashflow bench' runs, so the metrics history can compare them"""
+        results = {}
+        for name, summary in list(self.targets.items()) + [('all', self.total)]:
+            for pct in (50, 95, 99):
+                if summary.get(f"p{pct}_ms") is not None:
+                    results[f"{name} p{pct}"] = {'ns_per_op': summary[f"p{pct}_ms"] * 1e6, 'iterations': summary['requests']}
+            if summary.get('rps'):
+                # Time per request at the throughput reached: lower is better, like every other result
+                results[f"{name} throughput"] = {'ns_per_op': 1e9 / summary['rps'], 'iterations': summary['requests']}
+        return results
+
+    def to_dict(self) -> Dict[str, Any]:
+        options = self.options
+        return {'url': options.url, 'run': self.run, 'duration': options.duration, 'concurrency': options.concurrency,
+                'rate': options.rate or None, 'seconds': round(self.seconds, 3), 'interrupted': self.interrupted,
+                'targets': self.targets, 'total': self.total}
+
+class LoadRunner:
+    """Workers sending requests to the targets until the duration is up"""
+
+    def __init__(self, options: LoadTestOptions, targets: List[LoadTarget]):
+        options.validate()
+        if not targets:
+            raise LoadTestError("Nothing to load: the flows declare no models; name a target such as 'GET /api/health'")
+        self.options = options
+        self.targets = targets
+        self.run = uuid.uuid4().hex[:6]
+        parts = urlsplit(options.url)
+        self._scheme, self._host, self._port = parts.scheme, parts.hostname, parts.port
+        self._prefix = parts.path.rstrip('/')
+        self._lock = threading.Lock()
+        self._stats = {target.name: TargetStats() for target in targets}
+        self._total = TargetStats()
+        self._sequence = 0
+        self._stop = threading.Event()
+        self._started = 0.0
+
+    def _next(self) -> Optional[Tuple[int, float]]:
+        """The next request's number and when to send it, or None once the run is over"""
+        with self._lock:
+            self._sequence += 1
+            number = self._sequence
+        due = self._started + (number - 1) / self.options.rate if self.options.rate else time.monotonic()
+        if due >= self._started + self.options.duration or self._stop.is_set():
+            return None
+        return number, due
+
+    def _connection(self):
+        cls = http.client.HTTPSConnection if self._scheme == 'https' else http.client.HTTPConnection
+        return cls(self._host, self._port, timeout=self.options.timeout)
+
+    def _work(self, worker: int):
+        connection = self._connection()
+        try:
+            while True:
+                slot = self._next()
+                if slot is None:
+                    return
+                number, due = slot
+                delay = due - time.monotonic()
+                if delay > 0 and self._stop.wait(delay):
+                    return
+                target = self.targets[(number - 1) % len(self.targets)]
+                connection = self._send(connection, target, number, worker)
+        finally:
+            connection.close()
+
+    def _send(self, connection, target: LoadTarget, number: int, worker: int):
+        headers = {'User-Agent': 'flashflow-loadtest', 'Accept': 'application/json'}
+        headers.update(self.options.headers)
+        body = None
+        if target.body is not None:
+            context = {'n': number, 'worker': worker, 'run': self.run, 'random': random.random()}
+            body = json.dumps(render_template(target.body, context)).encode('utf-8')
+            headers['Content-Type'] = 'application/json'
+        started = time.perf_counter()
+        status, size, failure = None, 0, None
+        try:
+            connection.request(target.method, self._prefix + target.path, body=body, headers=headers)
+            response = connection.getresponse()
+            size = len(response.read())
+            status = response.status
+            if response.getheader('Connection', '').lower() == 'close':
+                connection.close()
+        except (OSError, http.client.HTTPException) as e:
+            failure = type(e).__name__
+            connection.close()
+            connection = self._connection()
+        elapsed = time.perf_counter() - started
+        with self._lock:
+            self._stats[target.name].add(elapsed, status, size, failure)
+            self._total.add(elapsed, status, size, failure)
+        return connection
+
+    def progress(self) -> Dict[str, Any]:
+        """Totals so far, for live output"""
+        with self._lock:
+            return self._total.summary(max(time.monotonic() - self._started, 1e-9))
+
+    def run_test(self, on_progress: Optional[Callable[[float, Dict[str, Any]], None]] = None,
+                 interval: float = 1.0) -> LoadTestReport:
+        """Run for the duration (Ctrl+C ends it early), calling on_progress every interval seconds"""
+        self._started = time.monotonic()
+        workers = [threading.Thread(target=self._work, args=(index,), name=f"loadtest-{index}", daemon=True)
+                   for index in range(self.options.concurrency)]
+        for worker in workers:
+            worker.start()
+        interrupted = False
+        try:
+            next_report = self._started + interval
+            while any(worker.is_alive() for worker in workers):
+                for worker in workers:
+                    worker.join(timeout=max(0.0, min(next_report - time.monotonic(), 0.1)))
+                if on_progress and time.monotonic() >= next_report:
+                    on_progress(time.monotonic() - self._started, self.progress())
+                    next_report += interval
+        except KeyboardInterrupt:
+            interrupted = True
+            self._stop.set()
+            for worker in workers:
+                worker.join(timeout=self.options.timeout)
+        seconds = time.monotonic() - self._started
+        with self._lock:
+            targets = {name: stats.summary(seconds) for name, stats in self._stats.items()}
+            total = self._total.summary(seconds)
+        return LoadTestReport(self.options, self.run, seconds, targets, total, interrupted)
@@ -0,0 +1,326 @@
+"""
+FlashFlow Markdown - Markdown to safe HTML, on the server
+
+Used by the 'markdown' component and the pages in src/content (see
+core/content.py). It covers what docs and blog posts use:
+
+    # Headings (with ids for #anchors)      **bold**, *italic*, ~~struck~~, `code`
+    paragraphs and hard line breaks         [links](/docs "title"), ![images](/media/a.png), <https://autolinks>
+    - bullet and 1. numbered lists, nested  > quotes
+    ```python fenced code                   | GitHub | tables |
+    ---  horizontal rules
+
+Raw HTML is not passed through: it is shown as text, like everything else the
+Markdown contains, and link and image URLs go through the same checks as flow
+links (core/html_safety.py). Fenced code with a language is highlighted with
+Pygments when it is installed; HIGHLIGHT_CSS holds the matching styles.
+"""
+
+import re
+from typing import Any, Dict, List, Optional, Tuple
+
+import yaml
+
+from core.html_safety import escape_html, safe_url
+
+try:
+    from pygments import highlight
+    from pygments.formatters import HtmlFormatter
+    from pygments.lexers import get_lexer_by_name
+    from pygments.util import ClassNotFound
+    HIGHLIGHT_CSS = HtmlFormatter(style='default').get_style_defs('.highlight')
+    PYGMENTS_AVAILABLE = True
+except ImportError:
+    HIGHLIGHT_CSS = ''
+    PYGMENTS_AVAILABLE = False
+
+FRONTMATTER = re.compile(r'\A---[ \t]*\r?\n(.*?)^(?:---|\.\.\.)[ \t]*(?:\r?\n|\Z)', re.DOTALL | re.MULTILINE)
+FENCE = re.compile(r'^( {0,3})(`{3,}|~{3,})[ \t]*([^`\s]*)[^`]*$')
+HEADING = re.compile(r'^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$')
+RULE = re.compile(r'^ {0,3}([-*_])(?:[ \t]*\1){2,}[ \t]*$')
+QUOTE = re.compile(r'^ {0,3}> ?(.*)$')
+LIST_ITEM = re.compile(r'^( *)([-*+]|\d{1,9}[.)])(?:[ \t]+(.*))?$')
+TABLE_DELIMITER = re.compile(r'^ *\|?(?: *:?-+:? *\|)*(?: *:?-+:? *)\|? *$')
+
+# Inline syntax, matched on the raw text; everything between matches is escaped
+CODE_SPAN = re.compile(r'(`+)(.+?)\1', re.DOTALL)
+URL = r'<?((?:[^\s()<>]|\([^\s()<>]*\))*)>?'
+IMAGE = re.compile(r'!\[([^\]]*)\]\(\s*' + URL + r'(?:\s+"([^"]*)")?\s*\)')
+LINK = re.compile(r'\[((?:[^\[\]]|\[[^\]]*\])*)\]\(\s*' + URL + r'(?:\s+"([^"]*)")?\s*\)')
+AUTOLINK = re.compile(r'<((?:https?|mailto):[^\s<>]+)>')
+ESCAPED = re.compile(r'\\([\\`*_{}\[\]()#+\-.!|~<>])')
+STRONG = re.compile(r'(\*\*|__)(?=\S)(.+?)(?<=\S)\1')
+EMPHASIS = re.compile(r'(?<![\w*])\*(?=\S)(.+?)(?<=\S)\*(?!\*)|(?<![\w_])_(?=\S)(.+?)(?<=\S)_(?![\w_])')
+STRIKE = re.compile(r'~~(?=\S)(.+?)(?<=\S)~~')
+HARD_BREAK = re.compile(r'(?: {2,}|\\)\n')
+# Input cannot contain them: render() replaces NUL with U+FFFD, as CommonMark does
+PLACEHOLDER = '\x00{}\x00'
+SAVED = re.compile('\x00(\\d+)\x00')
+
+class MarkdownError(ValueError):
+    """Raised for frontmatter that is not a YAML mapping"""
+
+    def __init__(self, message: str, line: Optional[int] = None):
+        super().__init__(message)
+        self.line = line
+
+def split_frontmatter(text: str) -> Tuple[Dict[str, Any], str, int]:
+    """The YAML frontmatter between '---' lines, the Markdown after it, and the line the Markdown starts on"""
+    match = FRONTMATTER.match(text)
+    if not match:
+        return {}, text, 1
+    try:
+        data = yaml.safe_load(match.group(1))
+    except yaml.YAMLError as e:
+        mark = getattr(e, 'problem_mark', None)
+        raise MarkdownError(f"Invalid frontmatter: {getattr(e, 'problem', None) or str(e)}",
+                            mark.line + 2 if mark is not None else 1)
+    if data is not None and not isinstance(data, dict):
+        raise MarkdownError("Frontmatter must be a mapping of keys such as title and path", 2)
+    return data or {}, text[match.end():], match.group(0).count('\n') + 1
+
+def first_heading(text: str) -> Optional[Tuple[str, str]]:
+    """The text of a '# Title' that opens the Markdown, and the Markdown without it"""
+    lines = text.split('\n')
+    for index, line in enumerate(lines):
+        if not line.strip():
+            continue
+        match = HEADING.match(line)
+        if match and len(match.group(1)) == 1 and match.group(2):
+            return match.group(2).strip(), '\n'.join(lines[index + 1:])
+        return None
+    return None
+
+def slugify(text: str) -> str:
+    slug = re.sub(r'[^\w\- ]', '', text.lower()).strip().replace(' ', '-')
+    return re.sub(r'-{2,}', '-', slug) or 'section'
+
+def render_markdown(text: Any) -> str:
+    """HTML for Markdown text; safe to put in a page as it is"""
+    return MarkdownRenderer().render(str(text or ''))
+
+class MarkdownRenderer:
+    """One document's rendering; keeps heading ids unique within it"""
+
+    def __init__(self):
+        self._ids: Dict[str, int] = {}
+
+    def render(self, text: str) -> str:
+        lines = text.replace('\x00', '\ufffd').replace('\r\n', '\n').replace('\t', '    ').split('\n')
+        return '\n'.join(self._blocks(lines))
+
+    # Blocks
+
+    def _blocks(self, lines: List[str]) -> List[str]:
+        html: List[str] = []
+        index = 0
+        while index < len(lines):
+            line = lines[index]
+            if not line.strip():
+                index += 1
+                continue
+
+            fence = FENCE.match(line)
+            if fence:
+                indent, marker, language = fence.groups()
+                code = []
+                index += 1
+                while index < len(lines) and not re.match(rf'^ {{0,3}}{re.escape(marker[0])}{{{len(marker)},}}[ \t]*$', lines[index]):
+                    code.append(lines[index][len(indent):] if lines[index].startswith(indent) else lines[index].lstrip())
+                    index += 1
+                html.append(self._code('\n'.join(code), language))
+                index += 1
+                continue
+
+            heading = HEADING.match(line)
+            if heading:
+                level, content = len(heading.group(1)), (heading.group(2) or '').strip()
+                html.append(f'<h{level} id="{self._id(content)}">{self._inline(content)}</h{level}>')
+                index += 1
+                continue
+
+            if RULE.match(line):
+                html.append('<hr>')
+                index += 1
+                continue
+
+            if QUOTE.match(line):
+                quoted = []
+                while index < len(lines) and lines[index].strip() and (QUOTE.match(lines[index]) or quoted):
+                    match = QUOTE.match(lines[index])
+                    quoted.append(match.group(1) if match else lines[index])
+                    index += 1
+                html.append('<blockquote>\n' + '\n'.join(self._blocks(quoted)) + '\n</blockquote>')
+                continue
+
+            item = LIST_ITEM.match(line)
+            if item and not (RULE.match(line)):
+                index = self._list(lines, index, html)
+                continue
+
+            if '|' in line and index + 1 < len(lines) and TABLE_DELIMITER.match(lines[index + 1]) and '-' in lines[index + 1]:
+                index = self._table(lines, index, html)
+                continue
+
+            paragraph = []
+            while index < len(lines) and lines[index].strip() and not self._starts_block(lines[index]):
+                paragraph.append(lines[index].strip() if not lines[index].endswith('  ') else lines[index].lstrip())
+                index += 1
+            html.append(f"<p>{self._inline(chr(10).join(paragraph))}</p>")
+        return html
+
+    def _starts_block(self, line: str) -> bool:
+        return bool(FENCE.match(line) or HEADING.match(line) or RULE.match(line) or QUOTE.match(line) or
+                    LIST_ITEM.match(line))
+
+    def _list(self, lines: List[str], index: int, html: List[str]) -> int:
+        first = LIST_ITEM.match(lines[index])
+        indent = len(first.group(1))
+        ordered = first.group(2)[0].isdigit()
+        items: List[List[str]] = []
+        loose = False
+        while index < len(lines):
+            line = lines[index]
+            match = LIST_ITEM.match(line)
+            if match and len(match.group(1)) == indent and match.group(2)[0].isdigit() == ordered:
+                content_indent = len(line) - len(line.lstrip()) + len(match.group(2)) + 1
+                items.append([match.group(3) or ''])
+                index += 1
+                continue
+            if not line.strip():
+                # A blank line ends the list unless the next line goes on with it
+                following = next((lines[n] for n in range(index + 1, len(lines)) if lines[n].strip()), None)
+                if following is None:
+                    break
+                next_item = LIST_ITEM.match(following)
+                continues = len(following) - len(following.lstrip()) >= content_indent
+                same_list = next_item and len(next_item.group(1)) == indent and next_item.group(2)[0].isdigit() == ordered
+                if not continues and not same_list:
+                    break
+                loose = loose or not continues
+                items[-1].append('')
+                index += 1
+                continue
+            if len(line) - len(line.lstrip()) >= min(content_indent, indent + 2):
+                items[-1].append(line[min(content_indent, len(line) - len(line.lstrip())):])
+                index += 1
+                continue
+            if items[-1][-1].strip() and not self._starts_block(line):
+                # A lazy continuation of the item's paragraph
+                items[-1].append(line.strip())
+                index += 1
+                continue
+            break
+
+        tag = 'ol' if ordered else 'ul'
+        start = int(first.group(2)[:-1]) if ordered else 1
+        rendered = []
+        for item in items:
+            blocks = self._blocks(item)
+            if not loose and blocks and blocks[0].startswith('<p>'):
+                blocks[0] = blocks[0][3:-4]
+            rendered.append('<li>' + '\n'.join(blocks) + '</li>')
+        attributes = f' start="{start}"' if ordered and start != 1 else ''
+        html.append(f'<{tag}{attributes}>\n' + '\n'.join(rendered) + f'\n</{tag}>')
+        return index
+
+    def _table(self, lines: List[str], index: int, html: List[str]) -> int:
+        def cells(row: str) -> List[str]:
+            row = row.strip()
+            if row.startswith('|'):
+                row = row[1:]
+            if row.endswith('|') and not row.endswith('\\|'):
+                row = row[:-1]
+            return [cell.strip().replace('\\|', '|') for cell in re.split(r'(?<!\\)\|', row)]
+
+        header = cells(lines[index])
+        aligns = []
+        for cell in cells(lines[index + 1]):
+            left, right = cell.startswith(':'), cell.endswith(':')
+            aligns.append('center' if left and right else 'right' if right else 'left' if left else None)
+        index += 2
+
+        def row_html(row: List[str], tag: str) -> str:
+            parts = []
+            for column in range(len(header)):
+                value = row[column] if column < len(row) else ''
+                align = aligns[column] if column < len(aligns) and aligns[column] else None
+                style = f' style="text-align: {align}"' if align else ''
+                parts.append(f'<{tag}{style}>{self._inline(value)}</{tag}>')
+            return '<tr>' + ''.join(parts) + '</tr>'
+
+        body = []
+        while index < len(lines) and lines[index].strip() and '|' in lines[index]:
+            body.append(row_html(cells(lines[index]), 'td'))
+            index += 1
+        html.append('<table>\n<thead>' + row_html(header, 'th') + '</thead>\n<tbody>\n' + '\n'.join(body) +
+                    '\n</tbody>\n</table>')
+        return index
+
+    def _code(self, code: str, language: str) -> str:
+        language = language.strip().lower()
+        css_class = f' class="language-{escape_html(language)}"' if language else ''
+        if language and PYGMENTS_AVAILABLE:
+            try:
+                lexer = get_lexer_by_name(language)
+            except ClassNotFound:
+                lexer = None
+            if lexer is not None:
+                highlighted = highlight(code, lexer, HtmlFormatter(nowrap=True)).rstrip('\n')
+                return f'<pre class="highlight"><code{css_class}>{highlighted}</code></pre>'
+        return f'<pre><code{css_class}>{escape_html(code)}</code></pre>'
+
+    def _id(self, text: str) -> str:
+        slug = slugify(re.sub(r'[`*_~\[\]]|\(.*?\)', '', text))
+        count = self._ids.get(slug, 0)
+        self._ids[slug] = count + 1
+        return escape_html(slug if count == 0 else f"{slug}-{count}")
+
+    # Inline
+
+    def _inline(self, text: str) -> str:
+        saved: List[str] = []
+        html = self._inline_markup(text, saved)
+
+        def restore(match, below: int) -> str:
+            # Markup only holds placeholders kept before it, so this ends; anything else is dropped
+            index = int(match.group(1))
+            if index >= below:
+                return ''
+            return SAVED.sub(lambda inner: restore(inner, index), saved[index])
+
+        return SAVED.sub(lambda match: restore(match, len(saved)), html)
+
+    def _inline_markup(self, text: str, saved: List[str]) -> str:
+        """text as HTML with placeholders for the markup kept in saved, which nested labels share"""
+        def keep(markup: str) -> str:
+            saved.append(markup)
+            return PLACEHOLDER.format(len(saved) - 1)
+
+        def code_span(match):
+            return keep(f"<code>{escape_html(match.group(2).strip() if match.group(2).strip() else match.group(2))}</code>")
+
+        def image(match):
+            alt, src, title = match.groups()
+            title_attribute = f' title="{escape_html(title)}"' if title else ''
+            return keep(f'<img src="{escape_html(safe_url(src, images=True))}" alt="{escape_html(alt)}"{title_attribute} loading="lazy">')
+
+        def link(match):
+            label, href, title = match.groups()
+            title_attribute = f' title="{escape_html(title)}"' if title else ''
+            return keep(f'<a href="{escape_html(safe_url(href))}"{title_attribute}>{self._inline_markup(label, saved)}</a>')
+
+        def autolink(match):
+            return keep(f'<a href="{escape_html(safe_url(match.group(1)))}">{escape_html(match.group(1))}</a>')
+
+        text = CODE_SPAN.sub(code_span, text)
+        text = ESCAPED.sub(lambda match: keep(escape_html(match.group(1))), text)
+        text = IMAGE.sub(image, text)
+        text = LINK.sub(link, text)
+        text = AUTOLINK.sub(autolink, text)
+        text = HARD_BREAK.sub(lambda match: keep('<br>') + '\n', text)
+
+        html = escape_html(text)
+        html = STRONG.sub(r'<strong>\2</strong>', html)
+        html = EMPHASIS.sub(lambda match: f"<em>{match.group(1) or match.group(2)}</em>", html)
+        return STRIKE.sub(r'<del>\1</del>', html)
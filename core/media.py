@@ -0,0 +1,198 @@
+"""
+FlashFlow media - Responsive variants for image, video and gallery components
+
+    - component: image
+      src: team.jpg                  # relative to src/assets
+      alt: The team
+      width: 800
+      sizes: "(max-width: 800px) 100vw, 800px"
+    - component: video
+      src: intro.mp4
+      poster: intro.jpg
+    - component: gallery
+      columns: 3
+      images: [a.jpg, {src: b.jpg, alt: Second}]
+
+Raster images get resized WebP variants (never upscaled) and a srcset. Variant
+file names carry a content fingerprint so they can be cached forever.
+"""
+
+import hashlib
+import json
+import shutil
+import threading
+from pathlib import Path
+from typing import Dict, Any, Iterable, List, Optional, Tuple
+
+MEDIA_COMPONENTS = ['image', 'video', 'gallery']
+VARIANT_WIDTHS = [320, 640, 960, 1280, 1920]
+WEBP_QUALITY = 80
+RESIZABLE_SUFFIXES = {'.jpg', '.jpeg', '.png', '.webp', '.gif', '.bmp', '.tif', '.tiff'}
+
+class MediaError(Exception):
+    """Raised when an asset is missing, outside the assets directory or cannot be processed"""
+    pass
+
+class MediaLibrary:
+    """Resolves assets and produces cached WebP variants"""
+
+    def __init__(self, assets_path: Path, cache_path: Path, widths: List[int] = None):
+        self.assets_path = Path(assets_path)
+        self.cache_path = Path(cache_path)
+        self.widths = sorted(widths or VARIANT_WIDTHS)
+        self._fingerprints: Dict[Tuple[str, float, int], str] = {}
+        self._lock = threading.Lock()
+
+    def resolve(self, src: str) -> Path:
+        """Path of an asset, refusing anything outside the assets directory"""
+        assets_root = self.assets_path.resolve()
+        path = (assets_root / src.lstrip('/')).resolve()
+        if assets_root not in path.parents:
+            raise MediaError(f"Asset '{src}' is outside {self.assets_path.name}/")
+        if not path.is_file():
+            raise MediaError(f"Asset '{src}' not found in {self.assets_path}")
+        return path
+
+    def fingerprint(self, src: str) -> str:
+        path = self.resolve(src)
+        stat = path.stat()
+        key = (str(path), stat.st_mtime, stat.st_size)
+        if key not in self._fingerprints:
+            self._fingerprints[key] = hashlib.sha256(path.read_bytes()).hexdigest()[:12]
+        return self._fingerprints[key]
+
+    def is_resizable(self, src: str) -> bool:
+        return Path(src).suffix.lower() in RESIZABLE_SUFFIXES
+
+    def dimensions(self, src: str) -> Tuple[int, int]:
+        Image = _pillow()
+        with Image.open(self.resolve(src)) as image:
+            return image.size
+
+    def variant_widths(self, src: str) -> List[int]:
+        """Configured widths below the original, plus the original width itself"""
+        original_width, _ = self.dimensions(src)
+        widths = [width for width in self.widths if width < original_width]
+        if not widths or original_width <= self.widths[-1]:
+            widths.append(original_width)
+        return widths
+
+    def variant(self, src: str, width: int) -> Path:
+        """WebP variant of an image at (at most) the given width, generated on first use"""
+        if not self.is_resizable(src):
+            raise MediaError(f"'{src}' is not a resizable image")
+
+        # Snap arbitrary requested widths to a generated one so the cache stays small
+        available = self.variant_widths(src)
+        width = next((w for w in available if w >= width), available[-1])
+
+        target = self.cache_path / self.fingerprint(src) / f"{Path(src).stem}-{width}.webp"
+        if target.exists():
+            return target
+
+        with self._lock:
+            if target.exists():
+                return target
+            Image = _pillow()
+            target.parent.mkdir(parents=True, exist_ok=True)
+            with Image.open(self.resolve(src)) as image:
+                if image.mode not in ('RGB', 'RGBA'):
+                    image = image.convert('RGBA' if 'transparency' in image.info else 'RGB')
+                if image.width > width:
+                    height = round(image.height * width / image.width)
+                    image = image.resize((width, height), Image.LANCZOS)
+                partial = target.with_suffix('.tmp')
+                image.save(partial, 'WEBP', quality=WEBP_QUALITY, method=4)
+                partial.replace(target)
+        return target
+
+    def describe(self, src: str, url_prefix: str = '/media', sizes: Optional[str] = None) -> Dict[str, Any]:
+        """URLs, srcset and intrinsic size for an asset as served by the dev server"""
+        version = self.fingerprint(src)
+        url = f"{url_prefix}/{src.lstrip('/')}"
+        description: Dict[str, Any] = {'src': f"{url}?v={version}", 'srcset': '', 'sizes': sizes or '', 'variants': []}
+
+        if not self.is_resizable(src):
+            return description
+
+        try:
+            description['width'], description['height'] = self.dimensions(src)
+            widths = self.variant_widths(src)
+        except MediaError as e:
+            # Without Pillow the original still works, just without variants
+            description['warning'] = str(e)
+            return description
+
+        description['variants'] = [{'width': width, 'url': f"{url}?w={width}&v={version}"} for width in widths]
+        description['srcset'] = ", ".join(f"{variant['url']} {variant['width']}w" for variant in description['variants'])
+        description['sizes'] = sizes or f"(max-width: {widths[-1]}px) 100vw, {widths[-1]}px"
+        return description
+
+    def export(self, src: str, output_path: Path, url_prefix: str = 'media') -> Dict[str, Any]:
+        """Copy an asset and its variants into a build directory under fingerprinted names"""
+        version = self.fingerprint(src)
+        source = self.resolve(src)
+        stem, suffix = Path(src).stem, Path(src).suffix
+        relative_dir = Path(src).parent
+
+        original_name = (relative_dir / f"{stem}.{version}{suffix}").as_posix()
+        (output_path / original_name).parent.mkdir(parents=True, exist_ok=True)
+        shutil.copy2(source, output_path / original_name)
+        description: Dict[str, Any] = {'src': f"{url_prefix}/{original_name}", 'srcset': '', 'variants': []}
+
+        if not self.is_resizable(src):
+            return description
+
+        description['width'], description['height'] = self.dimensions(src)
+        for width in self.variant_widths(src):
+            variant_name = (relative_dir / f"{stem}.{version}-{width}.webp").as_posix()
+            shutil.copy2(self.variant(src, width), output_path / variant_name)
+            description['variants'].append({'width': width, 'url': f"{url_prefix}/{variant_name}"})
+
+        description['srcset'] = ", ".join(f"{variant['url']} {variant['width']}w" for variant in description['variants'])
+        largest = description['variants'][-1]['width']
+        description['sizes'] = f"(max-width: {largest}px) 100vw, {largest}px"
+        return description
+
+def _pillow():
+    try:
+        from PIL import Image
+    except ImportError:
+        raise MediaError("Responsive image variants need Pillow: pip install pillow")
+    return Image
+
+def component_media_sources(component: Dict[str, Any]) -> List[str]:
+    """Local asset paths referenced by a media component"""
+    component_type = str(component.get('component', '')).lower()
+    sources = []
+    if component_type in ('image', 'video'):
+        sources += [component.get('src'), component.get('poster')]
+    elif component_type == 'gallery':
+        for item in component.get('images') or []:
+            sources.append(item.get('src') if isinstance(item, dict) else item)
+    return [src for src in sources if isinstance(src, str) and src and not _is_remote(src)]
+
+def collect_media_sources(pages: Dict[str, Dict[str, Any]]) -> List[str]:
+    """Every local asset used by media components in the IR pages, including nested children"""
+    sources: List[str] = []
+
+    def walk(components: Iterable[Any]):
+        for component in components or []:
+            if not isinstance(component, dict):
+                continue
+            for src in component_media_sources(component):
+                if src not in sources:
+                    sources.append(src)
+            walk(component.get('children') or [])
+
+    for page in pages.values():
+        if isinstance(page, dict):
+            walk(page.get('body') or [])
+    return sources
+
+def write_manifest(output_path: Path, descriptions: Dict[str, Dict[str, Any]]):
+    with open(output_path / "manifest.json", 'w') as f:
+        json.dump(descriptions, f, indent=2)
+
+def _is_remote(src: str) -> bool:
+    return src.startswith(('http://', 'https://', '//', 'data:'))
@@ -0,0 +1,231 @@
+"""
+FlashFlow metrics history - Retention and compaction of saved benchmark runs
+
+'flashflow bench' saves every run as .flashflow/bench/<suite>-<YYYYMMDD_HHMMSS>.json.
+Left alone those files pile up, so after each run (and on 'flashflow metrics
+prune') the ones past the retention settings are compacted into one SQLite
+history, .flashflow/bench/history.sqlite, and deleted:
+
+    "metrics_retention": {
+      "max_files": 20,        # newest run files kept as JSON per suite
+      "max_age_days": 30,     # run files older than this are compacted
+      "max_size_mb": 10,      # and the oldest, until the JSON files fit
+      "history_days": 365     # runs dropped from the history too after this long; null keeps them
+    }
+
+Compacted runs keep every result, so 'flashflow metrics history' still shows
+trends across them. Baselines (<suite>-baseline.json) are never touched.
+"""
+
+import json
+import re
+import sqlite3
+import time
+from dataclasses import dataclass, field
+from datetime import datetime
+from pathlib import Path
+from typing import Any, Dict, Iterable, List, Optional
+
+HISTORY_NAME = 'history.sqlite'
+RUN_FILE = re.compile(r'^(?P<suite>[\w.-]+?)-(?P<stamp>\d{8}_\d{6})\.json$')
+DEFAULT_RETENTION = {'max_files': 20, 'max_age_days': 30, 'max_size_mb': 10, 'history_days': 365}
+
+class MetricsRetentionError(Exception):
+    """Raised for invalid metrics_retention settings or an unreadable history"""
+    pass
+
+@dataclass
+class RunFile:
+    path: Path
+    suite: str
+    started: datetime
+    size: int
+
+    @property
+    def age_days(self) -> float:
+        return (datetime.now() - self.started).total_seconds() / 86400
+
+@dataclass
+class PruneReport:
+    """What a prune did, or would do with dry_run"""
+    compacted: List[Path] = field(default_factory=list)
+    kept: int = 0
+    dropped_runs: int = 0
+    freed_bytes: int = 0
+    unreadable: List[Path] = field(default_factory=list)
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'compacted': [path.name for path in self.compacted], 'kept': self.kept,
+                'dropped_runs': self.dropped_runs, 'freed_bytes': self.freed_bytes,
+                'unreadable': [path.name for path in self.unreadable]}
+
+def retention_settings(config=None, **overrides) -> Dict[str, Optional[float]]:
+    """Validated settings: the defaults, then flashflow.json, then overrides that are not None"""
+    raw = getattr(config, 'metrics_retention', None) if config is not None else None
+    if raw is not None and not isinstance(raw, dict):
+        raise MetricsRetentionError("metrics_retention must be an object")
+    settings = dict(DEFAULT_RETENTION, **(raw or {}))
+    settings.update({key: value for key, value in overrides.items() if value is not None})
+    unknown = sorted(set(settings) - set(DEFAULT_RETENTION))
+    if unknown:
+        raise MetricsRetentionError(f"Unknown metrics_retention settings: {', '.join(unknown)}")
+    for key, value in settings.items():
+        if value is None:
+            continue
+        if isinstance(value, bool) or not isinstance(value, (int, float)) or value < 0:
+            raise MetricsRetentionError(f"metrics_retention.{key} must be a number of at least 0 or null, got {value!r}")
+    return settings
+
+class MetricsHistory:
+    """The run files in a bench folder and the history they are compacted into"""
+
+    def __init__(self, folder: Path):
+        self.folder = Path(folder)
+        self.history_path = self.folder / HISTORY_NAME
+
+    def run_files(self, suite: Optional[str] = None) -> List[RunFile]:
+        """Saved runs, oldest first"""
+        runs = []
+        if not self.folder.is_dir():
+            return runs
+        for path in self.folder.glob('*.json'):
+            match = RUN_FILE.match(path.name)
+            if not match or (suite and match.group('suite') != suite):
+                continue
+            started = datetime.strptime(match.group('stamp'), '%Y%m%d_%H%M%S')
+            runs.append(RunFile(path, match.group('suite'), started, path.stat().st_size))
+        return sorted(runs, key=lambda run: (run.started, run.path.name))
+
+    def _connect(self) -> sqlite3.Connection:
+        self.folder.mkdir(parents=True, exist_ok=True)
+        try:
+            connection = sqlite3.connect(str(self.history_path))
+            connection.executescript("""
+                CREATE TABLE IF NOT EXISTS runs (
+                    id INTEGER PRIMARY KEY AUTOINCREMENT, suite TEXT NOT NULL, started_at TEXT NOT NULL,
+                    file TEXT NOT NULL UNIQUE, python TEXT, report TEXT NOT NULL
+                );
+                CREATE TABLE IF NOT EXISTS results (
+                    run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE, name TEXT NOT NULL,
+                    ns_per_op REAL, iterations INTEGER, PRIMARY KEY (run_id, name)
+                );
+                CREATE INDEX IF NOT EXISTS results_by_name ON results (name);
+            """)
+            connection.execute('PRAGMA foreign_keys = ON')
+        except sqlite3.DatabaseError as e:
+            raise MetricsRetentionError(f"Cannot open {self.history_path.name}: {str(e)}")
+        return connection
+
+    def select_for_compaction(self, settings: Dict[str, Optional[float]]) -> List[RunFile]:
+        """The run files past max_files, max_age_days or max_size_mb, oldest first"""
+        selected: Dict[Path, RunFile] = {}
+        suites: Dict[str, List[RunFile]] = {}
+        for run in self.run_files():
+            suites.setdefault(run.suite, []).append(run)
+        for runs in suites.values():
+            if settings['max_files'] is not None:
+                for run in runs[:max(0, len(runs) - int(settings['max_files']))]:
+                    selected[run.path] = run
+            if settings['max_age_days'] is not None:
+                for run in runs:
+                    if run.age_days > settings['max_age_days']:
+                        selected[run.path] = run
+        if settings['max_size_mb'] is not None:
+            remaining = [run for run in self.run_files() if run.path not in selected]
+            total = sum(run.size for run in remaining)
+            limit = settings['max_size_mb'] * 1024 * 1024
+            for run in remaining:
+                if total <= limit:
+                    break
+                selected[run.path] = run
+                total -= run.size
+        return sorted(selected.values(), key=lambda run: (run.started, run.path.name))
+
+    def compact(self, runs: Iterable[RunFile], report: Optional[PruneReport] = None) -> PruneReport:
+        """Copy runs into the history, then delete their files"""
+        report = report or PruneReport()
+        runs = list(runs)
+        if not runs:
+            return report
+        connection = self._connect()
+        try:
+            for run in runs:
+                try:
+                    data = json.loads(run.path.read_text(encoding='utf-8'))
+                except (OSError, ValueError):
+                    report.unreadable.append(run.path)
+                    continue
+                with connection:
+                    # Re-running a prune that stopped half way must not add a run twice
+                    connection.execute("DELETE FROM runs WHERE file = ?", (run.path.name,))
+                    run_id = connection.execute(
+                        "INSERT INTO runs (suite, started_at, file, python, report) VALUES (?, ?, ?, ?, ?)",
+                        (run.suite, data.get('timestamp') or run.started.isoformat(), run.path.name,
+                         data.get('python'), json.dumps({key: value for key, value in data.items() if key != 'results'}))
+                    ).lastrowid
+                    for name, result in (data.get('results') or {}).items():
+                        result = result if isinstance(result, dict) else {}
+                        connection.execute("INSERT INTO results (run_id, name, ns_per_op, iterations) VALUES (?, ?, ?, ?)",
+                                           (run_id, name, result.get('ns_per_op'), result.get('iterations')))
+                run.path.unlink()
+                report.compacted.append(run.path)
+                report.freed_bytes += run.size
+        finally:
+            connection.close()
+        return report
+
+    def drop_history_before(self, days: float) -> int:
+        """Remove runs older than days from the history; returns how many went"""
+        if not self.history_path.exists():
+            return 0
+        cutoff = datetime.fromtimestamp(time.time() - days * 86400).isoformat()
+        connection = self._connect()
+        try:
+            with connection:
+                removed = connection.execute("DELETE FROM runs WHERE started_at < ?", (cutoff,)).rowcount
+            if removed:
+                connection.execute('VACUUM')
+            return removed
+        finally:
+            connection.close()
+
+    def prune(self, settings: Dict[str, Optional[float]], dry_run: bool = False) -> PruneReport:
+        """Compact the run files past retention and drop expired history"""
+        selected = self.select_for_compaction(settings)
+        report = PruneReport(kept=len(self.run_files()) - len(selected))
+        if dry_run:
+            report.compacted = [run.path for run in selected]
+            report.freed_bytes = sum(run.size for run in selected)
+            return report
+        self.compact(selected, report)
+        if settings['history_days'] is not None:
+            report.dropped_runs = self.drop_history_before(settings['history_days'])
+        return report
+
+    def history(self, suite: Optional[str] = None, name_filter: Optional[str] = None,
+                limit: Optional[int] = None) -> List[Dict[str, Any]]:
+        """Runs from the history and the remaining files, oldest first, as {'started_at', 'file', 'results'}"""
+        runs = []
+        if self.history_path.exists():
+            connection = self._connect()
+            try:
+                query = "SELECT id, suite, started_at, file FROM runs" + (" WHERE suite = ?" if suite else "")
+                for run_id, run_suite, started_at, file in connection.execute(query, (suite,) if suite else ()).fetchall():
+                    results = {name: {'ns_per_op': ns, 'iterations': iterations} for name, ns, iterations in connection.execute(
+                        "SELECT name, ns_per_op, iterations FROM results WHERE run_id = ?", (run_id,))}
+                    runs.append({'suite': run_suite, 'started_at': started_at, 'file': file, 'compacted': True,
+                                 'results': results})
+            finally:
+                connection.close()
+        for run in self.run_files(suite):
+            try:
+                data = json.loads(run.path.read_text(encoding='utf-8'))
+            except (OSError, ValueError):
+                continue
+            runs.append({'suite': run.suite, 'started_at': data.get('timestamp') or run.started.isoformat(),
+                         'file': run.path.name, 'compacted': False, 'results': data.get('results') or {}})
+        if name_filter:
+            for run in runs:
+                run['results'] = {name: result for name, result in run['results'].items() if name_filter in name}
+        runs.sort(key=lambda run: run['started_at'])
+        return runs[-limit:] if limit else runs
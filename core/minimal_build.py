@@ -0,0 +1,109 @@
+"""
+FlashFlow minimal build - dist/web from the flows alone, without the build service
+
+When no build service is installed, 'flashflow serve' still gets a web build:
+the flows are checked and parsed in process, every routed page is rendered to
+plain HTML the way 'flashflow build -t static' does, and the parsed pages,
+models and endpoints are written next to them as app.json:
+
+    dist/web/index.html, dist/web/<route>/index.html    pages
+    dist/web/assets/                                    stylesheet and media
+    dist/web/app.json                                   pages, models and endpoints
+    dist/web/build.json                                 who built it, when, and what was left out
+
+build.json marks the folder as this builder's. A dist/web without it holds a
+full build, which is left alone.
+
+After the first build the builder compares each build's dependency graph
+(core/dependency_graph.py) with the last one and writes only the pages the
+change affects into dist/web, with app.json and build.json, rather than
+rendering the whole site again.
+"""
+
+import json
+import shutil
+import time
+from pathlib import Path
+from typing import Any, Dict, Optional
+
+from core.dependency_graph import DependencyGraph
+from core.parser.diagnostics import collect_diagnostics
+from core.parser.parser import FlowParser
+from core.static_site import StaticExportError, StaticSiteExporter
+
+MARKER = 'build.json'
+BUILDER = 'embedded'
+
+class MinimalBuildError(Exception):
+    """Raised when dist/web cannot be written"""
+    pass
+
+class MinimalBuilder:
+    """Builds dist/web in process"""
+
+    def __init__(self, project, output_path: Optional[Path] = None):
+        self.project = project
+        self.output_path = Path(output_path or project.dist_path / 'web')
+        # The graph of the last build this builder wrote; kept in memory, so a restarted server builds everything once
+        self.graph: Optional[DependencyGraph] = None
+
+    def owns_output(self) -> bool:
+        """Whether dist/web is missing or was written by this builder"""
+        if not self.output_path.exists():
+            return True
+        try:
+            return json.loads((self.output_path / MARKER).read_text(encoding='utf-8')).get('builder') == BUILDER
+        except (OSError, ValueError, AttributeError):
+            return False
+
+    def display_path(self) -> str:
+        try:
+            return self.output_path.relative_to(self.project.root_path).as_posix()
+        except ValueError:
+            return str(self.output_path)
+
+    def build(self) -> Dict[str, Any]:
+        """Check, parse and render the flows; the report says 'ok', 'failed' (with errors) or 'skipped'"""
+        output = self.display_path()
+        report: Dict[str, Any] = {'status': 'ok', 'builder': BUILDER, 'output': output}
+        if not self.owns_output():
+            return dict(report, status='skipped', reason=f"{output} holds a full build; 'flashflow build' refreshes it")
+
+        flow_files = self.project.get_flow_files()
+        errors = [diagnostic.to_dict() for diagnostic in collect_diagnostics(flow_files) if diagnostic.severity == 'error']
+        if errors:
+            return dict(report, status='failed', errors=errors)
+
+        started = time.time()
+        parser = FlowParser()
+        ir = parser.parse_project(self.project.root_path)
+        previous = self.graph if (self.output_path / MARKER).exists() else None
+        changes = parser.graph.changes_since(previous)
+        report['changes'] = changes.to_dict()
+        if not changes.full:
+            # Only what changed, written in place: a page at a time, which the server can serve between writes
+            target, routes = self.output_path, changes.pages + changes.removed_pages
+        else:
+            # Written beside dist/web and swapped in, so the server never serves half a build
+            target, routes = self.output_path.with_name(f".{self.output_path.name}.tmp"), None
+        try:
+            exported = StaticSiteExporter(self.project, ir, target).export(routes)
+            app_data = {'name': self.project.config.name, 'pages': ir.pages, 'models': ir.models, 'endpoints': ir.endpoints}
+            (target / 'app.json').write_text(json.dumps(app_data, indent=2, default=str), encoding='utf-8')
+            report.update(pages=exported.pages, unchanged=exported.unchanged,
+                          skipped=[{'route': route, 'reason': reason} for route, reason in exported.skipped],
+                          models=len(ir.models), endpoints=len(ir.endpoints), built_at=started,
+                          seconds=round(time.time() - started, 2))
+            (target / MARKER).write_text(json.dumps(report, indent=2), encoding='utf-8')
+            if target != self.output_path:
+                if self.output_path.exists():
+                    shutil.rmtree(self.output_path)
+                target.replace(self.output_path)
+        except (OSError, StaticExportError) as e:
+            if target != self.output_path:
+                shutil.rmtree(target, ignore_errors=True)
+            # The next build cannot trust what is in dist/web
+            self.graph = None
+            raise MinimalBuildError(f"Cannot write {output}: {str(e)}")
+        self.graph = parser.graph
+        return report
@@ -0,0 +1,190 @@
+"""
+FlashFlow model rules - Computed fields and lifecycle hooks declared on models
+
+    model:
+      name: OrderLine
+      fields:
+        quantity: integer required
+        unit_price: float required
+        discount: float default:0
+        total: float
+        status: string enum:open,paid,cancelled default:open
+      computed:
+        subtotal: quantity * unit_price                  # not stored; added to every row the API returns
+        label: "str(quantity) + ' x ' + str(unit_price)"
+      hooks:
+        before_create:
+          - require: quantity > 0
+            message: Quantity must be at least 1
+          - set: {total: "quantity * unit_price - (discount or 0)"}
+        before_update:
+          - require: "not (old.status == 'paid' and status == 'cancelled')"
+            message: Paid orders cannot be cancelled
+          - set: {total: "quantity * unit_price - (discount or 0)"}
+
+Expressions use the safe evaluator in core/utils/expressions.py. They see
+the row's fields by name (every declared field, None when unset), plus:
+
+    old       the row before an update or delete (None on create)
+    changes   the fields the request sent
+    now       the current UTC time, ISO 8601
+
+Computed fields are evaluated in declaration order, so one may use those
+above it; one that fails for a row (None * 2) is None for that row. They
+cannot be written, filtered or sorted on.
+
+Hooks are before_ or after_ create, update or delete. Each is a list of
+steps run in order: 'set' assigns stored fields, 'require' rejects the
+write with 'message' when it is falsy. Before-hooks see and change the
+incoming values; after-hooks see the row as written (id and timestamps
+included) and their 'set' writes back to it. All of them run in the
+write's transaction, so a rejection, even by an after-hook, leaves nothing
+written.
+"""
+
+from dataclasses import dataclass, field
+from datetime import datetime, timezone
+from typing import Any, Dict, List, Optional, Tuple
+
+from core.utils.expressions import ExpressionError, compile_expression, evaluate
+
+HOOK_EVENTS = tuple(f"{when}_{action}" for when in ('before', 'after') for action in ('create', 'update', 'delete'))
+STEP_ACTIONS = ('set', 'require')
+# Columns every table has, which hooks cannot assign
+RESERVED_FIELDS = ('id', 'created_at', 'updated_at')
+
+class ModelRuleError(Exception):
+    """Raised for an invalid computed field or hook declaration"""
+    pass
+
+class RuleRejected(Exception):
+    """Raised when a hook refuses a write"""
+
+    def __init__(self, model: str, event: str, message: str):
+        super().__init__(message)
+        self.model = model
+        self.event = event
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'error': str(self), 'model': self.model, 'hook': self.event}
+
+@dataclass
+class HookStep:
+    """One 'set' or 'require' step of a hook"""
+    assignments: List[Tuple[str, str]] = field(default_factory=list)
+    require: Optional[str] = None
+    message: str = ''
+
+@dataclass
+class ModelRules:
+    """A model's computed fields and hooks"""
+    model: str
+    fields: List[str] = field(default_factory=list)
+    computed: List[Tuple[str, str]] = field(default_factory=list)
+    hooks: Dict[str, List[HookStep]] = field(default_factory=dict)
+
+    @classmethod
+    def from_model(cls, model: str, model_data: Dict[str, Any], fields: List[str]) -> 'ModelRules':
+        rules = cls(model, list(fields))
+        computed = model_data.get('computed') or {}
+        if not isinstance(computed, dict):
+            raise ModelRuleError(f"{model}: 'computed' must map field names to expressions")
+        for name, expression in computed.items():
+            name = str(name)
+            if name in fields or name in RESERVED_FIELDS:
+                raise ModelRuleError(f"{model}: computed field '{name}' has the name of a stored field")
+            rules.computed.append((name, _expression(model, f"computed field '{name}'", expression)))
+
+        hooks = model_data.get('hooks') or {}
+        if not isinstance(hooks, dict):
+            raise ModelRuleError(f"{model}: 'hooks' must map {', '.join(HOOK_EVENTS)} to lists of steps")
+        for event, steps in hooks.items():
+            if event not in HOOK_EVENTS:
+                raise ModelRuleError(f"{model}: unknown hook '{event}'; use {', '.join(HOOK_EVENTS)}")
+            if isinstance(steps, dict):
+                steps = [steps]
+            if not isinstance(steps, list):
+                raise ModelRuleError(f"{model}: hook '{event}' must be a list of 'set' and 'require' steps")
+            rules.hooks[event] = [rules._step(event, index, step) for index, step in enumerate(steps)]
+        return rules
+
+    def _step(self, event: str, index: int, step: Any) -> HookStep:
+        label = f"{event}[{index}]"
+        actions = [key for key in STEP_ACTIONS if isinstance(step, dict) and key in step]
+        if len(actions) != 1:
+            raise ModelRuleError(f"{self.model}: {label} needs exactly one of 'set' or 'require'")
+        if actions == ['require']:
+            return HookStep(require=_expression(self.model, label, step['require']),
+                            message=str(step.get('message') or f"{self.model} {event.replace('_', ' ')} rule failed"))
+
+        if event == 'after_delete':
+            raise ModelRuleError(f"{self.model}: {label} cannot 'set' fields of a deleted row")
+        if not isinstance(step['set'], dict) or not step['set']:
+            raise ModelRuleError(f"{self.model}: {label} 'set' must map fields to expressions")
+        assignments = []
+        for name, expression in step['set'].items():
+            name = str(name)
+            if name not in self.fields:
+                raise ModelRuleError(f"{self.model}: {label} sets '{name}', which is not a stored field of the model")
+            assignments.append((name, _expression(self.model, f"{label} '{name}'", expression)))
+        return HookStep(assignments=assignments)
+
+    @property
+    def computed_names(self) -> List[str]:
+        return [name for name, _ in self.computed]
+
+    def assigned(self, event: str) -> List[str]:
+        """Fields a hook sets, which requests need not send"""
+        return [name for step in self.hooks.get(event, []) for name, _ in step.assignments]
+
+    def compute(self, row: Optional[Dict[str, Any]]) -> Optional[Dict[str, Any]]:
+        """The row with its computed fields added"""
+        if row is None or not self.computed:
+            return row
+        result = dict(row)
+        for name, expression in self.computed:
+            try:
+                result[name] = evaluate(expression, dict(result))
+            except ExpressionError:
+                result[name] = None
+        return result
+
+    def before(self, action: str, data: Dict[str, Any], old: Optional[Dict[str, Any]] = None) -> Dict[str, Any]:
+        """The values to write once the before_<action> hook ran; raises RuleRejected"""
+        return dict(data, **self._run(f"before_{action}", dict(old or {}, **data), old, data))
+
+    def after(self, action: str, row: Dict[str, Any], old: Optional[Dict[str, Any]], data: Dict[str, Any]) -> Dict[str, Any]:
+        """Fields the after_<action> hook sets on the written row; raises RuleRejected"""
+        return self._run(f"after_{action}", dict(row), old, data)
+
+    def _run(self, event: str, values: Dict[str, Any], old: Optional[Dict[str, Any]], changes: Dict[str, Any]) -> Dict[str, Any]:
+        assigned: Dict[str, Any] = {}
+        steps = self.hooks.get(event, [])
+        if not steps:
+            return assigned
+        context = dict({name: None for name in self.fields}, **values)
+        context.update(old=old, changes=dict(changes), now=datetime.now(timezone.utc).isoformat())
+        for step in steps:
+            if step.require is not None:
+                if not self._evaluate(event, step.require, context):
+                    raise RuleRejected(self.model, event, step.message)
+                continue
+            for name, expression in step.assignments:
+                context[name] = assigned[name] = self._evaluate(event, expression, context)
+        return assigned
+
+    def _evaluate(self, event: str, expression: str, context: Dict[str, Any]) -> Any:
+        try:
+            return evaluate(expression, context)
+        except ExpressionError as e:
+            raise RuleRejected(self.model, event, f"{self.model} {event} could not evaluate '{expression}': {e}")
+
+def _expression(model: str, label: str, expression: Any) -> str:
+    if isinstance(expression, bool) or not isinstance(expression, (str, int, float)):
+        raise ModelRuleError(f"{model}: {label} must be an expression")
+    source = str(expression)
+    try:
+        compile_expression(source)
+    except ExpressionError as e:
+        raise ModelRuleError(f"{model}: {label}: {e}")
+    return source
@@ -0,0 +1,340 @@
+"""
+FlashFlow notifications - Web push messages declared in flows
+
+    notifications:
+      order_shipped:
+        event: order.updated              # <model>.<created|updated|deleted>; patterns as for webhooks
+        if: "row['status'] == 'shipped'"  # optional expression over the row
+        title: "Order {{ row['id'] }} shipped"
+        body: "It is on its way"
+        url: "/orders/{{ row['id'] }}"    # opened when the notification is clicked
+        icon: /static/icon.png
+        tag: "order-{{ row['id'] }}"      # replaces an earlier notification with the same tag
+      daily_digest:
+        every: 1d                         # a schedule instead of an event: 30s, 15m, 2h, 1d
+        title: Your daily digest
+        body: "Sent at {{ now }}"
+
+Strings may hold '{{ ... }}' expressions over 'row' (event notifications),
+'event', 'name' and 'now'. A notification goes to every browser subscribed
+to the project's web push: the VAPID keys are made on first use and kept in
+.flashflow/push/vapid.json with the subscriptions next to them, and sent
+messages (the newest SENT_LIMIT) are kept in sent.json with each browser's
+result. Push services answering 404 or 410 have dropped the subscription,
+so it is removed.
+
+Payloads are encrypted as aes128gcm (RFC 8291) and requests signed with
+VAPID (RFC 8292), which needs the 'cryptography' package. Settings in
+flashflow.json:
+
+    "notifications": {"subject": "mailto:you@example.com", "ttl": 3600}
+"""
+
+import base64
+import fnmatch
+import json
+import os
+import re
+import threading
+import time
+import uuid
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+from urllib.parse import urlsplit
+
+import requests
+
+from core.load_test import LoadTestError, render_template
+from core.utils.expressions import ExpressionError, evaluate
+from core.webhooks import EVENT_ACTIONS
+
+SENT_LIMIT = 200
+DEFAULT_SUBJECT = 'mailto:flashflow-dev@example.com'
+DEFAULT_TTL = 3600
+# aes128gcm record size; one record holds the whole payload
+RECORD_SIZE = 4096
+MAX_PAYLOAD = 3800
+PUSH_TIMEOUT = 10.0
+INTERVAL = re.compile(r'^(\d+)\s*(s|m|h|d)$')
+INTERVAL_SECONDS = {'s': 1, 'm': 60, 'h': 3600, 'd': 86400}
+MIN_INTERVAL = 10
+MESSAGE_FIELDS = ('title', 'body', 'url', 'icon', 'tag')
+
+class NotificationError(Exception):
+    """Raised for an invalid notification declaration or subscription, or push that cannot be sent"""
+    pass
+
+@dataclass
+class NotificationDeclaration:
+    """One 'notifications:' entry from a flow file"""
+    name: str
+    title: str
+    event: Optional[str] = None
+    every: Optional[float] = None
+    condition: Optional[str] = None
+    body: str = ''
+    url: Optional[str] = None
+    icon: Optional[str] = None
+    tag: Optional[str] = None
+    source: str = ''
+
+    @classmethod
+    def from_definition(cls, name: str, definition: Dict[str, Any], source: str) -> 'NotificationDeclaration':
+        if not isinstance(definition, dict):
+            raise NotificationError(f"notification '{name}' must be a mapping")
+        event = str(definition.get('event') or '').strip().lower() or None
+        every = definition.get('every')
+        if bool(event) == (every is not None):
+            raise NotificationError(f"notification '{name}' needs either an 'event' or an 'every' schedule")
+        if event:
+            model, _, action = event.rpartition('.')
+            if not model or not (action in EVENT_ACTIONS or any(char in action for char in '*?[')):
+                raise NotificationError(f"notification '{name}': 'event' must look like <model>.<{'|'.join(EVENT_ACTIONS)}>, "
+                                        f"got '{event}'")
+        seconds = parse_interval(every, name) if every is not None else None
+        if not definition.get('title'):
+            raise NotificationError(f"notification '{name}' needs a 'title'")
+        return cls(name, str(definition['title']), event, seconds, str(definition['if']) if definition.get('if') else None,
+                   str(definition.get('body') or ''), definition.get('url'), definition.get('icon'), definition.get('tag'),
+                   source)
+
+    def matches(self, event: str) -> bool:
+        return bool(self.event) and fnmatch.fnmatchcase(event, self.event)
+
+    def message(self, context: Dict[str, Any]) -> Optional[Dict[str, Any]]:
+        """The rendered message, or None when 'if' is false for this context"""
+        try:
+            if self.condition and not evaluate(self.condition, context):
+                return None
+            values = {key: getattr(self, key) for key in MESSAGE_FIELDS if getattr(self, key)}
+            message = {key: str(value) for key, value in render_template(values, context).items()}
+        except (ExpressionError, LoadTestError) as e:
+            raise NotificationError(f"notification '{self.name}': {str(e)}")
+        message['notification'] = self.name
+        return message
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'name': self.name, 'event': self.event, 'every': self.every, 'if': self.condition, 'title': self.title,
+                'body': self.body, 'url': self.url, 'source': self.source}
+
+def parse_interval(value: Any, name: str) -> float:
+    match = INTERVAL.match(str(value).strip().lower())
+    if not match:
+        raise NotificationError(f"notification '{name}': 'every' must look like 30s, 15m, 2h or 1d, got '{value}'")
+    seconds = int(match.group(1)) * INTERVAL_SECONDS[match.group(2)]
+    if seconds < MIN_INTERVAL:
+        raise NotificationError(f"notification '{name}': 'every' must be at least {MIN_INTERVAL}s")
+    return float(seconds)
+
+def load_declarations(sections: List[Tuple[Any, str]]) -> Tuple[List[NotificationDeclaration], List[str]]:
+    """Valid declarations from (section, flow file) pairs, and an error for each invalid one"""
+    declarations, errors, names = [], [], set()
+    for section, source in sections:
+        if not isinstance(section, dict):
+            errors.append(f"{source}: 'notifications' must map names to notifications")
+            continue
+        for name, definition in section.items():
+            try:
+                if str(name) in names:
+                    raise NotificationError(f"notification '{name}' is declared twice")
+                declarations.append(NotificationDeclaration.from_definition(str(name), definition, source))
+                names.add(str(name))
+            except NotificationError as e:
+                errors.append(f"{source}: {str(e)}")
+    return declarations, errors
+
+def b64url(data: bytes) -> str:
+    return base64.urlsafe_b64encode(data).rstrip(b'=').decode('ascii')
+
+def b64url_decode(text: str) -> bytes:
+    return base64.urlsafe_b64decode(str(text) + '=' * (-len(str(text)) % 4))
+
+def _crypto():
+    try:
+        from cryptography.hazmat.primitives import hashes, serialization
+        from cryptography.hazmat.primitives.asymmetric import ec
+        from cryptography.hazmat.primitives.asymmetric.utils import decode_dss_signature
+        from cryptography.hazmat.primitives.ciphers.aead import AESGCM
+        from cryptography.hazmat.primitives.kdf.hkdf import HKDF
+    except ImportError:
+        raise NotificationError("Web push needs the 'cryptography' package (pip install cryptography)")
+    return hashes, serialization, ec, decode_dss_signature, AESGCM, HKDF
+
+class VapidKeys:
+    """The P-256 key pair that identifies this project's server to push services"""
+
+    def __init__(self, private_key):
+        _, serialization, _, _, _, _ = _crypto()
+        self.private_key = private_key
+        self.public_key = b64url(private_key.public_key().public_bytes(serialization.Encoding.X962,
+                                                                       serialization.PublicFormat.UncompressedPoint))
+
+    @classmethod
+    def load_or_create(cls, path: Path) -> 'VapidKeys':
+        _, serialization, ec, _, _, _ = _crypto()
+        if path.exists():
+            try:
+                data = json.loads(path.read_text(encoding='utf-8'))
+                return cls(serialization.load_pem_private_key(data['private_key'].encode('ascii'), password=None))
+            except (OSError, ValueError, KeyError) as e:
+                raise NotificationError(f"Cannot read {path}: {str(e)}; delete it to make new keys "
+                                        "(browsers then need to subscribe again)")
+        keys = cls(ec.generate_private_key(ec.SECP256R1()))
+        pem = keys.private_key.private_bytes(serialization.Encoding.PEM, serialization.PrivateFormat.PKCS8,
+                                             serialization.NoEncryption()).decode('ascii')
+        path.parent.mkdir(parents=True, exist_ok=True)
+        path.write_text(json.dumps({'private_key': pem, 'public_key': keys.public_key}, indent=2), encoding='utf-8')
+        os.chmod(path, 0o600)
+        return keys
+
+    def authorization(self, endpoint: str, subject: str, now: Optional[float] = None) -> str:
+        """The VAPID Authorization header for a push service endpoint (RFC 8292)"""
+        hashes, _, ec, decode_dss_signature, _, _ = _crypto()
+        parts = urlsplit(endpoint)
+        claims = {'aud': f"{parts.scheme}://{parts.netloc}", 'exp': int((now or time.time()) + 12 * 3600), 'sub': subject}
+        signing_input = (b64url(json.dumps({'typ': 'JWT', 'alg': 'ES256'}, separators=(',', ':')).encode()) + '.' +
+                         b64url(json.dumps(claims, separators=(',', ':')).encode()))
+        r, s = decode_dss_signature(self.private_key.sign(signing_input.encode('ascii'), ec.ECDSA(hashes.SHA256())))
+        token = signing_input + '.' + b64url(r.to_bytes(32, 'big') + s.to_bytes(32, 'big'))
+        return f"vapid t={token}, k={self.public_key}"
+
+def encrypt_payload(payload: bytes, p256dh: str, auth: str) -> bytes:
+    """payload encrypted for one subscription, as an aes128gcm body (RFC 8291)"""
+    hashes, serialization, ec, _, AESGCM, HKDF = _crypto()
+    if len(payload) > MAX_PAYLOAD:
+        raise NotificationError(f"The message is {len(payload)} bytes; push payloads must stay under {MAX_PAYLOAD}")
+    try:
+        receiver_bytes = b64url_decode(p256dh)
+        receiver = ec.EllipticCurvePublicKey.from_encoded_point(ec.SECP256R1(), receiver_bytes)
+        auth_secret = b64url_decode(auth)
+    except ValueError:
+        raise NotificationError("The subscription's keys are not valid P-256 and auth keys")
+    sender = ec.generate_private_key(ec.SECP256R1())
+    sender_bytes = sender.public_key().public_bytes(serialization.Encoding.X962, serialization.PublicFormat.UncompressedPoint)
+    shared = sender.exchange(ec.ECDH(), receiver)
+    ikm = HKDF(hashes.SHA256(), 32, auth_secret, b'WebPush: info\x00' + receiver_bytes + sender_bytes).derive(shared)
+    salt = os.urandom(16)
+    key = HKDF(hashes.SHA256(), 16, salt, b'Content-Encoding: aes128gcm\x00').derive(ikm)
+    nonce = HKDF(hashes.SHA256(), 12, salt, b'Content-Encoding: nonce\x00').derive(ikm)
+    # 0x02 marks the last (here the only) record
+    ciphertext = AESGCM(key).encrypt(nonce, payload + b'\x02', None)
+    return salt + RECORD_SIZE.to_bytes(4, 'big') + bytes([len(sender_bytes)]) + sender_bytes + ciphertext
+
+def check_subscription(data: Any) -> Dict[str, Any]:
+    """A browser's PushSubscription.toJSON(), checked"""
+    if not isinstance(data, dict) or not str(data.get('endpoint') or '').startswith('https://'):
+        raise NotificationError("A subscription needs the https 'endpoint' of a push service")
+    keys = data.get('keys')
+    if not isinstance(keys, dict) or not keys.get('p256dh') or not keys.get('auth'):
+        raise NotificationError("A subscription needs 'keys' with 'p256dh' and 'auth'")
+    return {'endpoint': data['endpoint'], 'keys': {'p256dh': str(keys['p256dh']), 'auth': str(keys['auth'])}}
+
+class PushStore:
+    """Keys, subscriptions and sent messages in .flashflow/push"""
+
+    def __init__(self, directory: Path):
+        self.directory = Path(directory)
+        self._lock = threading.Lock()
+        self._keys: Optional[VapidKeys] = None
+
+    @classmethod
+    def for_project(cls, project) -> 'PushStore':
+        return cls(project.state.path('push', 'subscriptions.json').parent)
+
+    def keys(self) -> VapidKeys:
+        with self._lock:
+            if self._keys is None:
+                self._keys = VapidKeys.load_or_create(self.directory / 'vapid.json')
+            return self._keys
+
+    def _read(self, name: str) -> List[Dict[str, Any]]:
+        try:
+            data = json.loads((self.directory / name).read_text(encoding='utf-8'))
+        except (OSError, ValueError):
+            return []
+        return data if isinstance(data, list) else []
+
+    def _write(self, name: str, items: List[Dict[str, Any]]):
+        path = self.directory / name
+        path.parent.mkdir(parents=True, exist_ok=True)
+        partial = path.with_suffix('.tmp')
+        partial.write_text(json.dumps(items, indent=2), encoding='utf-8')
+        partial.replace(path)
+
+    def subscriptions(self) -> List[Dict[str, Any]]:
+        return self._read('subscriptions.json')
+
+    def subscribe(self, data: Any, user_agent: str = '') -> Dict[str, Any]:
+        subscription = dict(check_subscription(data), id=uuid.uuid4().hex[:12], user_agent=user_agent[:200],
+                            created_at=time.time())
+        with self._lock:
+            # A browser subscribing again keeps one entry per endpoint
+            kept = [item for item in self._read('subscriptions.json') if item.get('endpoint') != subscription['endpoint']]
+            self._write('subscriptions.json', kept + [subscription])
+        return subscription
+
+    def unsubscribe(self, subscription_id: Optional[str] = None, endpoint: Optional[str] = None) -> bool:
+        with self._lock:
+            items = self._read('subscriptions.json')
+            kept = [item for item in items if item.get('id') != subscription_id and item.get('endpoint') != endpoint]
+            self._write('subscriptions.json', kept)
+        return len(kept) != len(items)
+
+    def sent(self) -> List[Dict[str, Any]]:
+        return self._read('sent.json')
+
+    def record(self, entry: Dict[str, Any]):
+        with self._lock:
+            self._write('sent.json', ([entry] + self._read('sent.json'))[:SENT_LIMIT])
+
+    def clear_sent(self):
+        with self._lock:
+            self._write('sent.json', [])
+
+class PushSender:
+    """Sends rendered messages to every subscription and records what each push service answered"""
+
+    def __init__(self, store: PushStore, settings: Optional[Dict[str, Any]] = None):
+        settings = settings or {}
+        self.store = store
+        self.subject = str(settings.get('subject') or DEFAULT_SUBJECT)
+        self.ttl = int(settings.get('ttl', DEFAULT_TTL))
+
+    def send(self, message: Dict[str, Any], trigger: str) -> Dict[str, Any]:
+        entry = {'id': uuid.uuid4().hex[:16], 'notification': message.get('notification'), 'trigger': trigger,
+                 'message': message, 'sent_at': time.time(), 'results': []}
+        subscriptions = self.store.subscriptions()
+        if subscriptions:
+            try:
+                keys = self.store.keys()
+                payload = json.dumps(message).encode('utf-8')
+                for subscription in subscriptions:
+                    entry['results'].append(self._push(keys, subscription, payload))
+            except NotificationError as e:
+                entry['error'] = str(e)
+        entry['delivered'] = sum(1 for result in entry['results'] if result.get('ok'))
+        self.store.record(entry)
+        return entry
+
+    def _push(self, keys: VapidKeys, subscription: Dict[str, Any], payload: bytes) -> Dict[str, Any]:
+        result = {'subscription': subscription.get('id'), 'endpoint': urlsplit(subscription['endpoint']).netloc}
+        try:
+            body = encrypt_payload(payload, subscription['keys']['p256dh'], subscription['keys']['auth'])
+            response = requests.post(subscription['endpoint'], data=body, timeout=PUSH_TIMEOUT, headers={
+                'Authorization': keys.authorization(subscription['endpoint'], self.subject),
+                'Content-Encoding': 'aes128gcm',
+                'Content-Type': 'application/octet-stream',
+                'TTL': str(self.ttl),
+            })
+        except NotificationError as e:
+            return dict(result, ok=False, error=str(e))
+        except requests.RequestException as e:
+            return dict(result, ok=False, error=f"Cannot reach the push service: {str(e)}")
+        result.update(status_code=response.status_code, ok=200 <= response.status_code < 300)
+        if not result['ok']:
+            result['error'] = response.text[:300]
+        if response.status_code in (404, 410):
+            self.store.unsubscribe(subscription.get('id'))
+            result['removed'] = True
+        return result
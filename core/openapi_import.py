@@ -0,0 +1,468 @@
+"""
+FlashFlow OpenAPI import - Flow models and endpoints from an existing OpenAPI or Swagger spec
+
+    flashflow import openapi petstore.yaml
+    flashflow import openapi https://api.example.com/openapi.json --dry-run
+
+OpenAPI 3.x and Swagger 2.0 specs, as JSON or YAML, are read. Object
+schemas (components.schemas, or definitions) become models, one
+src/flows/<model>.flow each. Their properties become fields the way
+/api/data/openapi.json describes fields, read backwards:
+
+    integer -> integer           string + format date / date-time -> date / datetime
+    number  -> float             string + format email / uri / password -> email / url / password
+    boolean -> boolean           string + enum -> enum with values; maxLength over 255 -> text
+    arrays, objects, $ref to another model, oneOf / anyOf -> json
+
+id, created_at and updated_at are left out, since every table has them.
+Each path and method becomes an endpoint, in one src/flows/<tag>-api.flow
+per tag (the first path segment after /api for untagged operations), with
+operationId as handler and the request and response models named. Security
+requirements become 'auth: api_key' for API key schemes and 'permissions:'
+(role user, with OAuth scopes) for HTTP, OAuth 2 and OpenID Connect.
+
+A model or endpoint the project already declares is a conflict. It is
+reported, with how the fields differ, and the project's own declaration is
+kept; on_conflict='rename' imports such models as <Name>Imported instead.
+Each written file starts with a comment recording its source and a digest
+of what was written, so importing again rewrites files nobody has edited
+and keeps the rest unless forced.
+"""
+
+import hashlib
+import json
+import re
+import urllib.error
+import urllib.request
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+
+import yaml
+
+from core.flow_lint import endpoints_of, route_key
+from core.parser.flow_file import FlowParseError, load_flow
+
+METHODS = ('get', 'post', 'put', 'patch', 'delete')
+STRATEGIES = ('skip', 'rename')
+RENAME_SUFFIX = 'Imported'
+# Columns every table gets; a spec's own copies of them are not fields
+AUTOMATIC_FIELDS = ('id', 'created_at', 'updated_at')
+STRING_FORMATS = {'date': 'date', 'date-time': 'datetime', 'email': 'email', 'uri': 'url', 'url': 'url',
+                  'password': 'password'}
+MAX_STRING_LENGTH = 255
+HEADER = re.compile(r"^# Imported by 'flashflow import openapi' from (?P<source>.+?) \(sha256:(?P<digest>[0-9a-f]{64})\)\n")
+FETCH_TIMEOUT = 30
+
+class OpenApiImportError(Exception):
+    """Raised when a spec cannot be read or is not OpenAPI 3 or Swagger 2"""
+    pass
+
+@dataclass
+class ImportReport:
+    """What an import did, and why it left things out"""
+    source: str
+    version: str
+    models: List[Dict[str, Any]] = field(default_factory=list)
+    endpoints: List[Dict[str, Any]] = field(default_factory=list)
+    conflicts: List[Dict[str, Any]] = field(default_factory=list)
+    warnings: List[str] = field(default_factory=list)
+    files: Dict[str, List[str]] = field(default_factory=lambda: {'written': [], 'unchanged': [], 'kept': []})
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'source': self.source, 'version': self.version, 'models': self.models, 'endpoints': self.endpoints,
+                'conflicts': self.conflicts, 'warnings': self.warnings, 'files': self.files}
+
+def load_spec(source: str) -> Dict[str, Any]:
+    """The spec from a file or an http(s) URL"""
+    if re.match(r'^https?://', source):
+        try:
+            with urllib.request.urlopen(source, timeout=FETCH_TIMEOUT) as response:
+                text = response.read().decode('utf-8')
+        except (urllib.error.URLError, OSError, UnicodeDecodeError) as e:
+            raise OpenApiImportError(f"Cannot fetch {source}: {str(e)}")
+    else:
+        try:
+            text = Path(source).read_text(encoding='utf-8')
+        except (OSError, UnicodeDecodeError) as e:
+            raise OpenApiImportError(f"Cannot read {source}: {str(e)}")
+    try:
+        # JSON is YAML, so one parser reads both
+        spec = yaml.safe_load(text)
+    except yaml.YAMLError as e:
+        raise OpenApiImportError(f"{source} is neither JSON nor YAML: {str(e)}")
+    if not isinstance(spec, dict) or not (str(spec.get('openapi', '')).startswith('3') or str(spec.get('swagger')) == '2.0'):
+        raise OpenApiImportError(f"{source} is not an OpenAPI 3 or Swagger 2.0 spec (no 'openapi: 3.x' or 'swagger: \"2.0\"')")
+    if not isinstance(spec.get('paths', {}), dict):
+        raise OpenApiImportError(f"{source}: 'paths' must be a mapping")
+    return spec
+
+def model_name(name: str) -> str:
+    """A schema name as a model name: Pet, pet_owner -> PetOwner, api.v1.Order -> Order"""
+    last = re.split(r'[./]', name)[-1]
+    parts = [part for part in re.split(r'[^A-Za-z0-9]+', last) if part]
+    name = ''.join(part[:1].upper() + part[1:] for part in parts) or 'Model'
+    return name if name[0].isalpha() else 'Model' + name
+
+def file_stem(name: str) -> str:
+    """PetOwner -> pet-owner, 'Store Orders' -> store-orders"""
+    snake = re.sub(r'(?<=[a-z0-9])(?=[A-Z])', '-', name)
+    return re.sub(r'[^a-z0-9]+', '-', snake.lower()).strip('-') or 'api'
+
+class OpenApiImporter:
+    """Turns one spec into flow files"""
+
+    def __init__(self, spec: Dict[str, Any], source: str, prefix: str = ''):
+        self.spec = spec
+        self.source = source
+        self.prefix = '/' + prefix.strip('/') if prefix.strip('/') else ''
+        self.swagger = str(spec.get('swagger')) == '2.0'
+        self.report = ImportReport(source, f"Swagger {spec['swagger']}" if self.swagger else f"OpenAPI {spec['openapi']}")
+        components = spec.get('components') if isinstance(spec.get('components'), dict) else {}
+        self.schemas: Dict[str, Any] = (spec.get('definitions') if self.swagger else components.get('schemas')) or {}
+        self.security_schemes: Dict[str, Any] = (spec.get('securityDefinitions') if self.swagger
+                                                 else components.get('securitySchemes')) or {}
+        self.names = {name: model_name(name) for name in self.schemas}
+        self._warned = set()
+
+    def warn(self, message: str):
+        if message not in self._warned:
+            self._warned.add(message)
+            self.report.warnings.append(message)
+
+    def resolve(self, schema: Any, depth: int = 0) -> Tuple[Dict[str, Any], Optional[str]]:
+        """A schema with local $refs followed, and the schema name it came from"""
+        name = None
+        while isinstance(schema, dict) and '$ref' in schema and depth < 20:
+            ref = str(schema['$ref'])
+            match = re.match(r'^#/(?:components/schemas|definitions)/(.+)$', ref)
+            if not match:
+                self.warn(f"Skipped the $ref '{ref}': only references inside the spec are followed")
+                return {}, None
+            name = match.group(1).replace('~1', '/').replace('~0', '~')
+            schema, depth = self.schemas.get(name, {}), depth + 1
+        return (schema if isinstance(schema, dict) else {}), name
+
+    def is_model(self, name: Optional[str]) -> bool:
+        if not name:
+            return False
+        schema = self.object_schema(self.schemas.get(name, {}))
+        return bool(schema.get('properties'))
+
+    def object_schema(self, schema: Any) -> Dict[str, Any]:
+        """Properties and required names, with allOf parts merged"""
+        schema, _ = self.resolve(schema)
+        if 'allOf' not in schema:
+            return schema
+        merged = {'type': 'object', 'properties': {}, 'required': []}
+        for part in schema.get('allOf') or []:
+            part = self.object_schema(part)
+            merged['properties'].update(part.get('properties') or {})
+            merged['required'] += list(part.get('required') or [])
+        merged['properties'].update(schema.get('properties') or {})
+        merged['required'] += list(schema.get('required') or [])
+        return merged
+
+    # Models
+
+    def models(self) -> Dict[str, Dict[str, Any]]:
+        """Model declarations by model name, for every object schema"""
+        models = {}
+        for name in sorted(self.schemas):
+            schema = self.object_schema(self.schemas[name])
+            if not schema.get('properties'):
+                kind = schema.get('type') or ('a composition' if ('oneOf' in schema or 'anyOf' in schema) else 'untyped')
+                self.warn(f"Schema '{name}' is {kind}, not an object with properties; it is not a model "
+                          f"(fields that use it become json)")
+                continue
+            required = set(schema.get('required') or [])
+            fields = [self.field_for(name, prop, schema['properties'][prop], prop in required)
+                      for prop in schema['properties'] if prop not in AUTOMATIC_FIELDS]
+            model = {'name': self.names[name], 'fields': fields}
+            if schema.get('description'):
+                model['description'] = _first_line(schema['description'])
+            models[self.names[name]] = model
+        return models
+
+    def field_for(self, schema_name: str, prop: str, schema: Any, required: bool) -> Dict[str, Any]:
+        resolved, ref = self.resolve(schema)
+        model_field: Dict[str, Any] = {'name': prop, 'type': 'json'}
+        types = resolved.get('type')
+        if isinstance(types, list):
+            # OpenAPI 3.1 writes nullable as ['string', 'null']
+            types = next((kind for kind in types if kind != 'null'), None)
+
+        if ref and self.is_model(ref):
+            model_field['description'] = f"{self.names[ref]} object"
+        elif 'oneOf' in resolved or 'anyOf' in resolved:
+            self.warn(f"{schema_name}.{prop} is oneOf/anyOf; imported as json")
+        elif 'allOf' in resolved:
+            merged = self.object_schema(resolved)
+            if not merged.get('properties'):
+                self.warn(f"{schema_name}.{prop} is allOf without properties; imported as json")
+        elif types == 'integer':
+            model_field['type'] = 'integer'
+        elif types == 'number':
+            model_field['type'] = 'float'
+        elif types == 'boolean':
+            model_field['type'] = 'boolean'
+        elif types == 'string' or (types is None and ('enum' in resolved or 'format' in resolved)):
+            if isinstance(resolved.get('enum'), list) and resolved['enum']:
+                model_field.update(type='enum', values=[str(value) for value in resolved['enum'] if value is not None])
+            elif resolved.get('format') in STRING_FORMATS:
+                model_field['type'] = STRING_FORMATS[resolved['format']]
+            elif isinstance(resolved.get('maxLength'), int) and resolved['maxLength'] > MAX_STRING_LENGTH:
+                model_field['type'] = 'text'
+            else:
+                model_field['type'] = 'string'
+                if resolved.get('format') == 'binary':
+                    self.warn(f"{schema_name}.{prop} is binary; imported as a string (use media uploads for files)")
+
+        if required:
+            model_field['required'] = True
+        if 'default' in resolved and isinstance(resolved['default'], (str, int, float, bool)):
+            model_field['default'] = resolved['default']
+        if resolved.get('description') and 'description' not in model_field:
+            model_field['description'] = _first_line(resolved['description'])
+        return model_field
+
+    # Endpoints
+
+    def endpoints(self) -> Dict[str, List[Dict[str, Any]]]:
+        """Endpoint declarations grouped by the file stem they go into"""
+        groups: Dict[str, List[Dict[str, Any]]] = {}
+        global_security = self.spec.get('security')
+        for path in sorted(self.spec.get('paths') or {}):
+            item = self.spec['paths'][path]
+            if not isinstance(item, dict):
+                continue
+            shared = item.get('parameters') or []
+            for method, operation in item.items():
+                if method in ('parameters', 'summary', 'description', 'servers') or method.startswith('x-'):
+                    continue
+                if method not in METHODS:
+                    self.warn(f"Skipped {method.upper()} {path}: flows declare GET, POST, PUT, PATCH and DELETE endpoints")
+                    continue
+                if not isinstance(operation, dict):
+                    continue
+                endpoint = self.endpoint_for(path, method, operation, shared,
+                                             operation.get('security', global_security))
+                groups.setdefault(self.group_of(path, operation), []).append(endpoint)
+        return groups
+
+    def group_of(self, path: str, operation: Dict[str, Any]) -> str:
+        tags = operation.get('tags')
+        if isinstance(tags, list) and tags:
+            return file_stem(str(tags[0]))
+        segments = [segment for segment in path.strip('/').split('/') if segment and not segment.startswith('{')]
+        if segments and segments[0] == 'api':
+            segments = segments[1:]
+        return file_stem(segments[0]) if segments else 'api'
+
+    def endpoint_for(self, path: str, method: str, operation: Dict[str, Any], shared: List[Any],
+                     security: Any) -> Dict[str, Any]:
+        endpoint: Dict[str, Any] = {'path': self.prefix + path, 'method': method.upper()}
+        if operation.get('operationId'):
+            endpoint['handler'] = str(operation['operationId'])
+        summary = operation.get('summary') or operation.get('description')
+        if summary:
+            endpoint['description'] = _first_line(summary)
+
+        parameters = [self.resolve(parameter)[0] for parameter in list(shared) + list(operation.get('parameters') or [])]
+        query = [str(parameter['name']) for parameter in parameters if parameter.get('in') == 'query' and parameter.get('name')]
+        if query:
+            endpoint['query'] = query
+        body = next((parameter.get('schema') for parameter in parameters if parameter.get('in') == 'body'), None)
+        if body is None:
+            body = self.json_schema((operation.get('requestBody') and self.resolve(operation['requestBody'])[0]) or {})
+        request_model = self.model_reference(body)
+        if request_model:
+            endpoint['request'] = request_model
+
+        responses = operation.get('responses') if isinstance(operation.get('responses'), dict) else {}
+        success = next((responses[code] for code in sorted(responses, key=str) if str(code).startswith('2')), None)
+        if success is not None:
+            success = self.resolve(success)[0]
+            response_model = self.model_reference(success.get('schema') if self.swagger else self.json_schema(success))
+            if response_model:
+                endpoint['response'] = response_model
+
+        endpoint.update(self.access_for(security, f"{method.upper()} {path}"))
+        if operation.get('deprecated'):
+            endpoint['deprecated'] = True
+        return endpoint
+
+    def json_schema(self, holder: Dict[str, Any]) -> Any:
+        """The schema of the JSON media type (or the only one) of a request body or response"""
+        content = holder.get('content') if isinstance(holder.get('content'), dict) else {}
+        for media_type, media in content.items():
+            if 'json' in media_type and isinstance(media, dict):
+                return media.get('schema')
+        if len(content) == 1:
+            return (next(iter(content.values())) or {}).get('schema')
+        return None
+
+    def model_reference(self, schema: Any) -> Optional[str]:
+        """'Pet' for a Pet body, 'Pet[]' for a list of them; None for anything else"""
+        if not isinstance(schema, dict):
+            return None
+        if schema.get('type') == 'array':
+            item = self.model_reference(schema.get('items'))
+            return f"{item}[]" if item and not item.endswith('[]') else None
+        _, ref = self.resolve(schema)
+        return self.names[ref] if self.is_model(ref) else None
+
+    def access_for(self, security: Any, label: str) -> Dict[str, Any]:
+        """'auth' or 'permissions' for the first security requirement FlashFlow can express"""
+        if not isinstance(security, list) or not security:
+            return {}
+        for requirement in security:
+            if not isinstance(requirement, dict):
+                continue
+            if not requirement:
+                # {} lists anonymous access as one of the options
+                return {}
+            for scheme_name, scopes in requirement.items():
+                scheme = self.security_schemes.get(scheme_name) or {}
+                kind = str(scheme.get('type', '')).lower()
+                if kind == 'apikey':
+                    return {'auth': 'api_key'}
+                if kind in ('http', 'basic', 'oauth2', 'openidconnect'):
+                    scopes = [str(scope) for scope in scopes or []]
+                    return {'permissions': {'role': 'user', 'scopes': scopes} if scopes else 'user'}
+                self.warn(f"{label}: security scheme '{scheme_name}' ({kind or 'unknown'}) has no FlashFlow equivalent")
+        return {}
+
+    # Files
+
+    def plan(self, existing_models: Dict[str, Tuple[str, List[Dict[str, Any]]]],
+             existing_routes: Dict[str, str], on_conflict: str = 'skip', reserved: Optional[List[str]] = None) -> Dict[str, Any]:
+        """Flow data by file name, after resolving conflicts with what the project declares
+
+        reserved names the project's own flows, which an import never writes to.
+        """
+        files: Dict[str, Any] = {}
+        reserved = set(reserved or [])
+
+        def file_name(stem: str) -> str:
+            name, count = f"{stem}.flow", 1
+            while name in reserved or name in files:
+                count += 1
+                name = f"{stem}-openapi.flow" if count == 2 else f"{stem}-openapi-{count - 1}.flow"
+            return name
+
+        renamed = {}
+        for name, model in self.models().items():
+            if name in existing_models:
+                where, fields = existing_models[name]
+                conflict = {'kind': 'model', 'name': name, 'existing': where, 'differences': field_differences(fields, model['fields'])}
+                if on_conflict == 'rename':
+                    renamed[name] = name + RENAME_SUFFIX
+                    model = dict(model, name=renamed[name])
+                    conflict['resolution'] = f"imported as {renamed[name]}"
+                else:
+                    conflict['resolution'] = 'kept the project\'s model'
+                    self.report.conflicts.append(conflict)
+                    continue
+                self.report.conflicts.append(conflict)
+            name = file_name(file_stem(model['name']))
+            files[name] = {'model': model}
+            self.report.models.append({'name': model['name'], 'file': name, 'fields': len(model['fields'])})
+
+        for stem, endpoints in sorted(self.endpoints().items()):
+            name, kept = file_name(f"{stem}-api"), []
+            for endpoint in endpoints:
+                key = f"{endpoint['method']} {route_key(endpoint['path'])}"
+                if key in existing_routes:
+                    self.report.conflicts.append({'kind': 'endpoint', 'name': f"{endpoint['method']} {endpoint['path']}",
+                                                  'existing': existing_routes[key], 'resolution': "kept the project's endpoint"})
+                    continue
+                for reference in ('request', 'response'):
+                    base = endpoint.get(reference, '').rstrip('[]')
+                    if base in renamed:
+                        endpoint[reference] = endpoint[reference].replace(base, renamed[base], 1)
+                kept.append(endpoint)
+                self.report.endpoints.append({'method': endpoint['method'], 'path': endpoint['path'], 'file': name})
+            if kept:
+                files[name] = {'endpoint': kept}
+        return files
+
+    def render(self, data: Dict[str, Any]) -> str:
+        body = yaml.safe_dump(data, sort_keys=False, allow_unicode=True, default_flow_style=False, width=120)
+        return f"# Imported by 'flashflow import openapi' from {self.source} (sha256:{_digest(body)})\n{body}"
+
+    def write(self, flows_dir: Path, files: Dict[str, Any], force: bool = False, dry_run: bool = False) -> Dict[str, str]:
+        """Write the planned files; returns the content of each by file name"""
+        rendered = {name: self.render(data) for name, data in files.items()}
+        for name, content in rendered.items():
+            path = flows_dir / name
+            if path.exists():
+                current = path.read_text(encoding='utf-8')
+                if _body(current) == _body(content):
+                    self.report.files['unchanged'].append(name)
+                    continue
+                if not imported_unedited(current) and not (force and HEADER.match(current)):
+                    self.report.files['kept'].append(name)
+                    continue
+            if not dry_run:
+                flows_dir.mkdir(parents=True, exist_ok=True)
+                path.write_text(content, encoding='utf-8')
+            self.report.files['written'].append(name)
+        return rendered
+
+def imported_unedited(content: str) -> bool:
+    """Whether content is an import nobody has changed since"""
+    match = HEADER.match(content)
+    return bool(match) and _digest(content[match.end():]) == match.group('digest')
+
+def imported_files(flows_dir: Path, source: str) -> List[str]:
+    """Flows an earlier import of source wrote; importing again replaces them rather than conflicting"""
+    names = []
+    for path in sorted(flows_dir.glob('*.flow')) if flows_dir.exists() else []:
+        try:
+            match = HEADER.match(path.read_text(encoding='utf-8'))
+        except (OSError, UnicodeDecodeError):
+            continue
+        if match and match.group('source') == source:
+            names.append(path.name)
+    return names
+
+def project_files(flows_dir: Path, imported: List[str]) -> List[str]:
+    """Flow files that are not imports of this spec"""
+    return [path.name for path in flows_dir.glob('*.flow') if path.name not in imported] if flows_dir.exists() else []
+
+def project_declarations(flows_dir: Path, skip: List[str]) -> Tuple[Dict[str, Tuple[str, List[Dict[str, Any]]]], Dict[str, str]]:
+    """Models (name -> file, fields) and endpoint routes ('GET /x/:param' -> file) declared outside skip"""
+    models, routes = {}, {}
+    for path in sorted(flows_dir.glob('*.flow')) if flows_dir.exists() else []:
+        if path.name in skip:
+            continue
+        try:
+            document = load_flow(path)
+        except (FlowParseError, OSError):
+            continue
+        for model in document.models:
+            models[model.name] = (path.name, model.fields)
+        for endpoint, _ in endpoints_of(document.data):
+            if endpoint.get('path'):
+                routes[f"{str(endpoint.get('method', 'GET')).upper()} {route_key(endpoint['path'])}"] = path.name
+    return models, routes
+
+def field_differences(existing: List[Dict[str, Any]], imported: List[Dict[str, Any]]) -> List[str]:
+    have = {str(item.get('name')): str(item.get('type', 'string')) for item in existing}
+    want = {str(item['name']): str(item['type']) for item in imported}
+    differences = [f"'{name}' only in the spec ({kind})" for name, kind in want.items() if name not in have]
+    differences += [f"'{name}' only in the project" for name in have if name not in want and name not in AUTOMATIC_FIELDS]
+    differences += [f"'{name}' is {have[name]} in the project, {kind} in the spec"
+                    for name, kind in want.items() if name in have and have[name] != kind]
+    return differences
+
+def _first_line(text: Any) -> str:
+    return str(text).strip().split('\n')[0].strip()
+
+def _body(content: str) -> str:
+    match = HEADER.match(content)
+    return content[match.end():] if match else content
+
+def _digest(content: str) -> str:
+    return hashlib.sha256(content.encode('utf-8')).hexdigest()
@@ -0,0 +1,228 @@
+"""
+FlashFlow flow diagnostics
+Collects every parse and validation error across a project in a single pass
+"""
+
+import re
+from dataclasses import dataclass, asdict
+from pathlib import Path
+from typing import Dict, Any, List, Optional
+
+from core.parser.flow_file import FlowParseError, load_flow
+from core.parser.parser import validate_endpoint
+from core.api_keys import ApiKeyError, ApiKeyRequirement
+from core.database import model_field_list
+from core.model_rules import ModelRuleError, ModelRules
+from core.permissions import Permission, PermissionDeclarationError
+
+@dataclass
+class FlowDiagnostic:
+    """A single problem found in a .flow file"""
+    file: str
+    message: str
+    line: Optional[int] = None
+    column: Optional[int] = None
+    severity: str = "error"  # error, warning
+    suggestion: Optional[str] = None
+
+    def to_dict(self) -> Dict[str, Any]:
+        return asdict(self)
+
+    def location(self) -> str:
+        """Human readable file:line:column"""
+        if self.line is None:
+            return self.file
+        if self.column is None:
+            return f"{self.file}:{self.line}"
+        return f"{self.file}:{self.line}:{self.column}"
+
+# Hints for the most common YAML mistakes in hand-written .flow files
+YAML_SUGGESTIONS = [
+    ("found character '\\t'", "Indent with spaces; tabs are not allowed in .flow files"),
+    ("mapping values are not allowed", "Check the indentation of this line, or quote values that contain ':'"),
+    ("could not find expected ':'", "Add a ':' after the key, or quote the whole value"),
+    ("found undefined alias", "Define the '&anchor' before using '*alias'"),
+    ("expected <block end>", "This line is indented differently from its siblings"),
+    ("found unexpected end of stream", "Close the open quote or bracket started above"),
+    ("did not find expected key", "List items and keys at the same level must share one indentation"),
+]
+
+def suggest_for_yaml_problem(problem: str) -> Optional[str]:
+    """Map a YAML error message to a suggested fix"""
+    for needle, suggestion in YAML_SUGGESTIONS:
+        if needle in problem:
+            return suggestion
+    return None
+
+def find_key_line(content: str, key: str) -> Optional[int]:
+    """Best-effort line number of the first occurrence of 'key:'"""
+    pattern = re.compile(rf"^\s*-?\s*{re.escape(key)}\s*:")
+    for number, line in enumerate(content.split('\n'), 1):
+        if pattern.match(line):
+            return number
+    return None
+
+def validate_flow_data(file_name: str, content: str, data: Any) -> List[FlowDiagnostic]:
+    """Structural checks on parsed flow data"""
+    diagnostics = []
+
+    def report(message: str, key: str = None, suggestion: str = None, severity: str = "error"):
+        line = find_key_line(content, key) if key else None
+        diagnostics.append(FlowDiagnostic(file_name, message, line, None, severity, suggestion))
+
+    if not isinstance(data, dict):
+        report("Top level of a .flow file must be a mapping of sections",
+               suggestion="Start the file with a section such as 'page:' or 'model:'")
+        return diagnostics
+
+    page = data.get('page')
+    if page is not None:
+        if not isinstance(page, dict):
+            report("'page' must be a mapping", 'page')
+        else:
+            path = page.get('path')
+            if path is None:
+                report("Page is missing a 'path'", 'page', "Add 'path: /your-route' to the page", "warning")
+            elif not str(path).startswith('/'):
+                report(f"Page path '{path}' must start with '/'", 'path', f"Use 'path: /{path}'")
+
+            if page.get('permissions') is not None:
+                validate_permissions(page['permissions'], f"Page '{path or '/'}'", report)
+
+            state = page.get('state')
+            if state is not None and not isinstance(state, dict):
+                report("Page 'state' must be a mapping of names to initial values", 'state', "Use e.g. 'state: {count: 0}'")
+
+            body = page.get('body')
+            if body is not None and not isinstance(body, list):
+                report("Page 'body' must be a list of components", 'body', "Prefix each component with '- '")
+            elif isinstance(body, list):
+                for index, component in enumerate(body):
+                    if isinstance(component, dict) and 'component' not in component:
+                        report(f"Body item {index + 1} has no 'component' type", 'body',
+                               "Add 'component: <type>' to the item", "warning")
+                    elif isinstance(component, dict):
+                        validate_media_component(component, index, report)
+                        validate_component_actions(component, index, report)
+
+    model = data.get('model')
+    if isinstance(model, dict) and 'name' not in model:
+        report("Model is missing a 'name'", 'model', "Add 'name: ModelName' to the model")
+    if isinstance(model, dict) and (model.get('computed') or model.get('hooks')):
+        validate_model_rules(model, report)
+
+    endpoints = data.get('endpoint')
+    if isinstance(endpoints, dict):
+        endpoints = [endpoints]
+    if isinstance(endpoints, list):
+        for endpoint in endpoints:
+            if not isinstance(endpoint, dict) or not validate_endpoint(endpoint):
+                label = endpoint.get('path', '?') if isinstance(endpoint, dict) else endpoint
+                report(f"Endpoint '{label}' needs a 'path' and a valid 'method' (GET, POST, PUT, DELETE, PATCH)",
+                       'endpoint', "Check the endpoint's path and method fields")
+            else:
+                if endpoint.get('permissions') is not None:
+                    validate_permissions(endpoint['permissions'], f"Endpoint '{endpoint['path']}'", report)
+                if endpoint.get('auth') is not None:
+                    validate_auth(endpoint['auth'], f"Endpoint '{endpoint['path']}'", report)
+
+    return diagnostics
+
+def validate_permissions(definition: Any, label: str, report):
+    """A page or endpoint 'permissions' declaration must name known roles"""
+    try:
+        Permission.from_definition(definition)
+    except PermissionDeclarationError as e:
+        report(f"{label}: {e}", 'permissions', "Use e.g. 'permissions: {role: editor, scopes: [todos:write]}'")
+
+def validate_model_rules(model: Dict[str, Any], report):
+    """A model's computed fields and hooks must use valid expressions and assign its own fields"""
+    fields = [model_field['name'] for model_field in model_field_list(model.get('fields'))]
+    try:
+        ModelRules.from_model(str(model.get('name') or 'Model'), model, fields)
+    except ModelRuleError as e:
+        report(str(e), 'computed' if 'computed field' in str(e) else 'hooks',
+               "Use e.g. 'computed: {total: quantity * price}' or 'hooks: {before_create: [{set: {status: \"'open'\"}}]}'")
+
+def validate_auth(definition: Any, label: str, report):
+    """An endpoint 'auth' declaration must be api_key with known options"""
+    try:
+        ApiKeyRequirement.from_definition(definition)
+    except ApiKeyError as e:
+        report(f"{label}: {e}", 'auth', "Use e.g. 'auth: api_key' or 'auth: {type: api_key, signed: true}'")
+
+def validate_media_component(component: Dict[str, Any], index: int, report):
+    """Checks for image, video and gallery components"""
+    component_type = str(component.get('component', '')).lower()
+    label = f"Body item {index + 1} ({component_type})"
+
+    if component_type in ('image', 'video') and not component.get('src'):
+        report(f"{label} needs a 'src'", 'src', "Point 'src' at a file in src/assets or a full URL")
+    if component_type == 'image' and 'alt' not in component:
+        report(f"{label} has no 'alt' text", 'alt', "Add 'alt: ...' describing the image (use alt: '' if decorative)", "warning")
+    if component_type == 'gallery':
+        images = component.get('images')
+        if not isinstance(images, list) or not images:
+            report(f"{label} needs a non-empty 'images' list", 'images', "List image paths under 'images:'")
+
+ACTION_KEYS = ['on_click', 'on_submit']
+ACTION_TYPES = ['set', 'call', 'navigate']
+
+def validate_component_actions(component: Dict[str, Any], index: int, report):
+    """Each on_click / on_submit entry must be exactly one of set, call or navigate"""
+    for key in ACTION_KEYS:
+        if key not in component:
+            continue
+        actions = component[key] if isinstance(component[key], list) else [component[key]]
+        for position, action in enumerate(actions, 1):
+            kinds = [kind for kind in ACTION_TYPES if isinstance(action, dict) and kind in action]
+            if len(kinds) != 1:
+                report(f"Body item {index + 1} {key} action {position} needs exactly one of {', '.join(ACTION_TYPES)}", key,
+                       "e.g. '- set: {count: \"{{ state.count + 1 }}\"}', '- call: /api/items' or '- navigate: /done'")
+            elif kinds == ['set'] and not isinstance(action['set'], dict):
+                report(f"Body item {index + 1} {key} action {position}: 'set' takes a mapping of state keys", key)
+
+def collect_diagnostics(flow_files: List[Path]) -> List[FlowDiagnostic]:
+    """Parse and validate every file, collecting all problems instead of stopping at the first"""
+    diagnostics = []
+
+    for flow_file in flow_files:
+        try:
+            content = flow_file.read_text(encoding='utf-8')
+        except OSError as e:
+            diagnostics.append(FlowDiagnostic(flow_file.name, f"Cannot read file: {str(e)}"))
+            continue
+        diagnostics.extend(diagnose_content(flow_file, content))
+
+    return diagnostics
+
+def diagnose_content(flow_file: Path, content: str) -> List[FlowDiagnostic]:
+    """Problems in content for flow_file, saved or not (the /edit page checks drafts with this)"""
+    try:
+        # Includes resolved, so a broken or missing include is reported where it is included
+        data = load_flow(flow_file, content=content).data
+    except FlowParseError as e:
+        return [FlowDiagnostic(flow_file.name, e.problem, e.line, e.column, "error", suggest_for_yaml_problem(e.problem))]
+    return validate_flow_data(flow_file.name, content, data)
+
+def group_by_file(diagnostics: List[FlowDiagnostic]) -> Dict[str, List[FlowDiagnostic]]:
+    """Group diagnostics by file, ordered by line"""
+    grouped: Dict[str, List[FlowDiagnostic]] = {}
+    for diagnostic in diagnostics:
+        grouped.setdefault(diagnostic.file, []).append(diagnostic)
+
+    for file_diagnostics in grouped.values():
+        file_diagnostics.sort(key=lambda d: (d.line or 0, d.column or 0))
+
+    return grouped
+
+def diagnostics_report(diagnostics: List[FlowDiagnostic]) -> Dict[str, Any]:
+    """JSON-serialisable summary used by the dev server"""
+    return {
+        'error_count': sum(1 for d in diagnostics if d.severity == 'error'),
+        'warning_count': sum(1 for d in diagnostics if d.severity == 'warning'),
+        'files': {
+            file_name: [d.to_dict() for d in file_diagnostics]
+            for file_name, file_diagnostics in group_by_file(diagnostics).items()
+        }
+    }
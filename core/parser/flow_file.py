@@ -0,0 +1,184 @@
+"""
+FlashFlow flow files - Reading one .flow file, the same way for every service
+
+The build, the dev server, the diagnostics and the direct renderer all read
+.flow files through load_flow(), so a file means the same thing to each of
+them:
+
+- comment lines are blanked rather than dropped, so YAML error positions
+  match the file (FlowParseError carries them)
+- 'include: header' or 'include: [header, footer]' at the top level pulls in
+  other flow files. Names are looked up next to the including file, then in
+  INCLUDE_DIRS of the project, with or without '.flow'. Included sections sit
+  under the file's own: mappings are merged key by key, and anything else the
+  file sets replaces the included value. Includes may include other files;
+  a cycle is an error.
+- the result is a FlowDocument, with typed views of its page and models next
+  to the merged data
+"""
+
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Any, Dict, List, Optional
+
+import yaml
+
+from core.database import model_field_list
+
+INCLUDE_KEY = 'include'
+# Where include names are looked up besides the including file's folder
+INCLUDE_DIRS = ('src/flows', 'src/layouts', 'src/custom')
+# libyaml's loader when PyYAML was built with it: the same results, several times faster
+SafeLoader = getattr(yaml, 'CSafeLoader', yaml.SafeLoader)
+
+class FlowParseError(ValueError):
+    """Raised when .flow content is not valid YAML, carrying the error position"""
+
+    def __init__(self, message: str, line: Optional[int] = None, column: Optional[int] = None, problem: str = ""):
+        super().__init__(message)
+        self.line = line
+        self.column = column
+        self.problem = problem or message
+
+class FlowIncludeError(FlowParseError):
+    """Raised when an include cannot be found, does not parse or forms a cycle"""
+    pass
+
+@dataclass
+class FlowPage:
+    path: Optional[str]
+    title: Optional[str]
+    body: List[Any]
+    data: Dict[str, Any]
+
+@dataclass
+class FlowModel:
+    name: str
+    fields: List[Dict[str, Any]]
+    data: Dict[str, Any]
+
+@dataclass
+class FlowDocument:
+    file: Path
+    data: Dict[str, Any]
+    # Every file pulled in, directly or through other includes, in load order
+    includes: List[Path] = field(default_factory=list)
+
+    @property
+    def page(self) -> Optional[FlowPage]:
+        page = self.data.get('page')
+        if not isinstance(page, dict):
+            return None
+        path = page.get('path')
+        body = page.get('body')
+        return FlowPage(str(path) if path is not None else None, page.get('title'),
+                        body if isinstance(body, list) else [], page)
+
+    @property
+    def route(self) -> Optional[str]:
+        page = self.page
+        return page.path if page else None
+
+    @property
+    def models(self) -> List[FlowModel]:
+        """Models declared as 'model: {name: ...}' or 'model: Name' with the fields beside it"""
+        model = self.data.get('model')
+        if isinstance(model, dict) and model.get('name'):
+            return [FlowModel(str(model['name']), model_field_list(model.get('fields')), model)]
+        if isinstance(model, str) and model:
+            return [FlowModel(model, model_field_list(self.data.get('fields')), self.data)]
+        return []
+
+def parse_flow_text(content: str) -> Any:
+    """The YAML data of .flow content, without resolving includes"""
+    # Blank out comments (lines starting with #) rather than dropping them
+    # so YAML error positions still match the original file
+    cleaned = '\n'.join('' if line.lstrip().startswith('#') else line for line in content.split('\n'))
+    try:
+        # Parse as YAML (since .flow syntax is YAML-like)
+        data = yaml.load(cleaned, Loader=SafeLoader)
+    except yaml.YAMLError as e:
+        line = column = None
+        mark = getattr(e, 'problem_mark', None)
+        if mark is not None:
+            line, column = mark.line + 1, mark.column + 1
+        problem = getattr(e, 'problem', None) or str(e)
+        raise FlowParseError(f"Failed to parse .flow file: {str(e)}", line, column, problem)
+    return {} if data is None else data
+
+def find_project_root(path: Path) -> Path:
+    """The nearest folder above path with a flashflow.json, else the file's own folder"""
+    path = Path(path).resolve()
+    for folder in path.parents:
+        if (folder / 'flashflow.json').is_file():
+            return folder
+    return path.parent
+
+def resolve_include(current: Path, name: str, root: Optional[Path] = None) -> Optional[Path]:
+    """The file an include (or layout) name refers to, or None"""
+    root = Path(root) if root else find_project_root(current)
+    candidates = [Path(current).parent / name] + [root / folder / name for folder in INCLUDE_DIRS] + [root / name]
+    for candidate in candidates:
+        for option in (candidate, candidate.with_name(candidate.name + '.flow')):
+            if option.is_file():
+                return option.resolve()
+    return None
+
+def load_flow(path: Path, root: Optional[Path] = None, content: Optional[str] = None) -> FlowDocument:
+    """Read a .flow file (or content standing in for it) and resolve its includes"""
+    path = Path(path)
+    if content is None:
+        if not path.exists():
+            raise FileNotFoundError(f"Flow file not found: {path}")
+        content = path.read_text(encoding='utf-8')
+    root = Path(root) if root else find_project_root(path)
+    includes: List[Path] = []
+    data = _resolve(path.resolve(), content, root, [path.resolve()], includes)
+    return FlowDocument(path, data, includes)
+
+def _resolve(path: Path, content: str, root: Path, chain: List[Path], includes: List[Path]) -> Any:
+    data = parse_flow_text(content)
+    if not isinstance(data, dict) or INCLUDE_KEY not in data:
+        return data
+    names = data.pop(INCLUDE_KEY)
+    names = names if isinstance(names, list) else [names]
+    line = _key_line(content, INCLUDE_KEY)
+
+    merged: Dict[str, Any] = {}
+    for name in names:
+        if not isinstance(name, str) or not name.strip():
+            raise FlowIncludeError(f"include takes a file name or a list of them, got {name!r}", line)
+        target = resolve_include(path, name.strip(), root)
+        if target is None:
+            raise FlowIncludeError(f"Included file '{name}' not found next to {path.name} or in {', '.join(INCLUDE_DIRS)}", line)
+        if target in chain:
+            cycle = ' → '.join(step.name for step in chain[chain.index(target):] + [target])
+            raise FlowIncludeError(f"Include cycle: {cycle}", line)
+        try:
+            included = _resolve(target, target.read_text(encoding='utf-8'), root, chain + [target], includes)
+        except FlowParseError as e:
+            where = f":{e.line}" if e.line else ''
+            raise FlowIncludeError(f"In included file {target.name}{where}: {e.problem}", line)
+        except OSError as e:
+            raise FlowIncludeError(f"Cannot read included file {target.name}: {str(e)}", line)
+        if not isinstance(included, dict):
+            raise FlowIncludeError(f"Included file {target.name} must be a mapping of sections", line)
+        if target not in includes:
+            includes.append(target)
+        merged = _merge(merged, included)
+    return _merge(merged, data)
+
+def _merge(base: Dict[str, Any], override: Dict[str, Any]) -> Dict[str, Any]:
+    merged = dict(base)
+    for key, value in override.items():
+        if isinstance(value, dict) and isinstance(merged.get(key), dict):
+            merged[key] = _merge(merged[key], value)
+        else:
+            merged[key] = value
+    return merged
+
+def _key_line(content: str, key: str) -> Optional[int]:
+    for number, line in enumerate(content.split('\n'), 1):
+        if line.startswith(f"{key}:"):
+            return number
+    return None
@@ -0,0 +1,352 @@
+"""
+FlashFlow flow loader - Reading a project's .flow files in parallel, and each only once
+
+FlowParser.parse_project reads every .flow file through the shared loader
+here, so the build, the dev server and the commands all get:
+
+- a cache of parsed files, keyed on each file's and its includes' size and
+  modification time. A file is parsed the first time something needs it and
+  again only after it (or something it includes) changes, so a dev server
+  request touching one page of a large app parses that page, not the app.
+  Every caller gets its own copy of the data, so merging it into an IR
+  cannot change what the next caller sees. A file's stamp is taken before it
+  is read, so one saved while it is parsed is parsed again next time; an
+  include, only known once its file is read, is not cached on when it
+  changed during the read.
+- lazy page bodies: the cache keeps each page's body pickled on its own, and
+  a document from the cache has a LazyPage, whose body is unpickled the
+  first time something reads it. Merging into the IR only reads the page's
+  path, and the default pages' check for a model's list, form or card reads
+  data_sources, kept beside the body, so the dev server's many parses for
+  models, endpoints or the theme never unpickle a body; rendering a page
+  unpickles that page's.
+- a worker pool for what is not cached yet: with PARALLEL_THRESHOLD or more
+  files to parse, they are parsed in worker processes, one per CPU up to
+  MAX_WORKERS (FLASHFLOW_PARSE_WORKERS overrides it; 1 turns the pool off).
+  Workers are spawned, not forked: the dev server loads from a background
+  thread, and a fork copies locks other threads hold.
+  A file that fails in a worker is parsed again in the caller, so the error
+  it raises is the one a serial parse would have.
+- LoadStats on how long it took, and a progress callback for long loads.
+"""
+
+import multiprocessing
+import os
+import pickle
+import threading
+import time
+from concurrent.futures import ProcessPoolExecutor, as_completed
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Any, Callable, Dict, List, Optional, Tuple
+
+from core.parser.flow_file import FlowDocument, load_flow
+
+# Below this many files to parse, starting worker processes costs more than it saves
+PARALLEL_THRESHOLD = 24
+MAX_WORKERS = 8
+# Filesystems keep modification times this coarsely at worst (FAT: 2s)
+MTIME_SLACK_NS = 2_000_000_000
+
+# (path, mtime_ns, size) of a file and each of its includes
+Stamp = Tuple[Tuple[str, int, int], ...]
+Progress = Callable[[int, int], None]
+
+@dataclass
+class LoadStats:
+    """How one load went"""
+    files: int = 0
+    parsed: int = 0
+    cached: int = 0
+    workers: int = 1
+    seconds: float = 0.0
+
+    def summary(self) -> str:
+        details = [f"{self.cached} cached"] if self.cached else []
+        if self.workers > 1:
+            details.append(f"{self.workers} workers")
+        noun = 'file' if self.files == 1 else 'files'
+        return f"Parsed {self.files} flow {noun} in {self.seconds:.2f}s" + (f" ({', '.join(details)})" if details else '')
+
+    def to_dict(self) -> Dict[str, float]:
+        return {'files': self.files, 'parsed': self.parsed, 'cached': self.cached, 'workers': self.workers,
+                'seconds': round(self.seconds, 3)}
+
+# One body is unpickled at a time; cheap next to parsing, and two readers never get different copies
+_BODY_LOCK = threading.Lock()
+
+class LazyPage(dict):
+    """A page's settings with its body unpickled the first time something reads it
+
+    Every key is there from the start, 'body' holding None in its place until
+    loaded, so reading path or title costs nothing. Reading 'body', or going
+    over the values (items(), values(), copies, JSON, pickling, comparison),
+    loads it first. Copies and pickles are plain dicts.
+    """
+
+    def __init__(self, settings: Dict[str, Any], body: bytes, sources: List[Tuple[Any, Any]] = ()):
+        super().__init__(settings)
+        self._body: Optional[bytes] = body
+        self._sources = list(sources)
+
+    @property
+    def loaded(self) -> bool:
+        return self._body is None
+
+    def _load(self):
+        if self._body is None:
+            return
+        with _BODY_LOCK:
+            if self._body is not None:
+                dict.__setitem__(self, 'body', pickle.loads(self._body))
+                self._body = None
+
+    def __getitem__(self, key):
+        if key == 'body':
+            self._load()
+        return dict.__getitem__(self, key)
+
+    def get(self, key, default=None):
+        if key == 'body':
+            self._load()
+        return dict.get(self, key, default)
+
+    def __setitem__(self, key, value):
+        if key == 'body':
+            self._body = None
+        dict.__setitem__(self, key, value)
+
+    def __delitem__(self, key):
+        if key == 'body':
+            self._body = None
+        dict.__delitem__(self, key)
+
+    def pop(self, key, *default):
+        if key == 'body':
+            self._load()
+        return dict.pop(self, key, *default)
+
+    def setdefault(self, key, default=None):
+        if key == 'body':
+            self._load()
+        return dict.setdefault(self, key, default)
+
+    def popitem(self):
+        self._load()
+        return dict.popitem(self)
+
+    def __iter__(self):
+        # Defined so dict(page) and {**page} go through __getitem__ instead of copying the placeholder
+        return dict.__iter__(self)
+
+    def items(self):
+        self._load()
+        return dict.items(self)
+
+    def values(self):
+        self._load()
+        return dict.values(self)
+
+    def copy(self) -> Dict[str, Any]:
+        return dict(self)
+
+    def __or__(self, other):
+        self._load()
+        return dict.__or__(self, other)
+
+    def __ror__(self, other):
+        self._load()
+        return dict.__ror__(self, other)
+
+    def __eq__(self, other):
+        # Two pages from the same cache entry compare without unpickling either body
+        if not (isinstance(other, LazyPage) and self._body is not None and self._body == other._body):
+            self._load()
+            if isinstance(other, LazyPage):
+                other._load()
+        return dict.__eq__(self, other)
+
+    def __ne__(self, other):
+        equal = self.__eq__(other)
+        return equal if equal is NotImplemented else not equal
+
+    __hash__ = None
+
+    def __repr__(self) -> str:
+        self._load()
+        return dict.__repr__(self)
+
+    def __reduce__(self):
+        return dict, (dict(self),)
+
+def data_sources(page: Dict[str, Any]) -> List[Tuple[Any, Any]]:
+    """(component, data_source) of each top-level component in page's body; a LazyPage answers without loading it"""
+    if isinstance(page, LazyPage) and not page.loaded:
+        return list(page._sources)
+    body = page.get('body')
+    if not isinstance(body, list):
+        return []
+    return [(component.get('component'), component.get('data_source')) for component in body if isinstance(component, dict)]
+
+def _pack(document: FlowDocument) -> bytes:
+    """document pickled with its page body pickled on its own, for _unpack to leave until it is read"""
+    page = document.data.get('page') if isinstance(document.data, dict) else None
+    body = sources = None
+    if isinstance(page, dict) and page.get('body') is not None:
+        body = pickle.dumps(page['body'], pickle.HIGHEST_PROTOCOL)
+        sources = data_sources(page)
+        document = FlowDocument(document.file, dict(document.data, page=dict(page, body=None)), document.includes)
+    return pickle.dumps((document, body, sources), pickle.HIGHEST_PROTOCOL)
+
+def _unpack(packed: bytes) -> FlowDocument:
+    document, body, sources = pickle.loads(packed)
+    if body is not None:
+        document.data['page'] = LazyPage(document.data['page'], body, sources)
+    return document
+
+def _stamp(files: List[Path]) -> Optional[Stamp]:
+    stamp = []
+    for path in files:
+        try:
+            info = path.stat()
+        except OSError:
+            return None
+        stamp.append((str(path), info.st_mtime_ns, info.st_size))
+    return tuple(stamp)
+
+def _with_includes(stamp: Optional[Stamp], includes: List[Path], read_from_ns: int) -> Optional[Stamp]:
+    """A file's stamp, taken before it was read, with its includes'; None when an include changed during the read"""
+    included = _stamp([Path(include) for include in includes])
+    if stamp is None or included is None:
+        return None
+    if any(mtime_ns >= read_from_ns - MTIME_SLACK_NS for _, mtime_ns, _ in included):
+        return None
+    return stamp + included
+
+def _load_packed(path: str, root: Optional[str]) -> bytes:
+    # Runs in the worker processes: bytes cross the process boundary, and are what the cache keeps anyway
+    return _pack(load_flow(Path(path), Path(root) if root else None))
+
+def default_workers() -> int:
+    configured = os.environ.get('FLASHFLOW_PARSE_WORKERS')
+    if configured and configured.strip().isdigit():
+        return max(1, int(configured))
+    return max(1, min(os.cpu_count() or 1, MAX_WORKERS))
+
+class FlowLoader:
+    """Parsed .flow files, cached by file stamp and parsed in a worker pool when there are many"""
+
+    def __init__(self, workers: Optional[int] = None):
+        self.workers = workers
+        self.last_stats: Optional[LoadStats] = None
+        self._cache: Dict[Path, Tuple[Stamp, bytes]] = {}
+        self._lock = threading.Lock()
+
+    def load(self, files: List[Path], root: Optional[Path] = None,
+             progress: Optional[Progress] = None) -> Tuple[List[FlowDocument], LoadStats]:
+        """The documents for files, in the order given; root is as for load_flow"""
+        # One load at a time: a second caller waits and then finds the first one's files cached
+        with self._lock:
+            return self._load([Path(path).resolve() for path in files], root, progress)
+
+    def _load(self, files: List[Path], root: Optional[Path], progress: Optional[Progress]) -> Tuple[List[FlowDocument], LoadStats]:
+        started = time.perf_counter()
+        stats = LoadStats(files=len(files))
+        pickled: Dict[Path, bytes] = {}
+        missing: List[Path] = []
+        for path in files:
+            cached = self._cached(path)
+            if cached is not None:
+                pickled[path] = cached
+            else:
+                missing.append(path)
+        stats.cached = len(pickled)
+        stats.parsed = len(missing)
+        done = len(pickled)
+        if progress and done:
+            progress(done, len(files))
+        stamps = {path: _stamp([path]) for path in missing}
+        read_from_ns = time.time_ns()
+
+        workers = self.workers or default_workers()
+        parsed: Dict[Path, FlowDocument] = {}
+        if workers > 1 and len(missing) >= PARALLEL_THRESHOLD:
+            workers = min(workers, len(missing))
+            try:
+                with ProcessPoolExecutor(max_workers=workers, mp_context=multiprocessing.get_context('spawn')) as pool:
+                    futures = {pool.submit(_load_packed, str(path), str(root) if root else None): path for path in missing}
+                    for future in as_completed(futures):
+                        path = futures[future]
+                        try:
+                            pickled[path] = future.result()
+                        except Exception:
+                            # Parsed again below, to raise its error where a serial parse would
+                            continue
+                        done += 1
+                        if progress:
+                            progress(done, len(files))
+                if any(path in pickled for path in missing):
+                    stats.workers = workers
+            except (OSError, RuntimeError):
+                # No processes to be had (sandboxes, frozen apps); fall back to this one
+                pass
+            missing = [path for path in missing if path not in pickled]
+
+        for path in missing:
+            parsed[path] = load_flow(path, root)
+            pickled[path] = _pack(parsed[path])
+            done += 1
+            if progress:
+                progress(done, len(files))
+
+        documents = []
+        for path in files:
+            document = parsed.pop(path, None) or _unpack(pickled[path])
+            if path in stamps:
+                self._store(path, _with_includes(stamps[path], document.includes, read_from_ns), pickled[path])
+            documents.append(document)
+        self._prune(files)
+        stats.seconds = time.perf_counter() - started
+        self.last_stats = stats
+        return documents, stats
+
+    def load_file(self, path: Path, root: Optional[Path] = None) -> FlowDocument:
+        """One file's document, from the cache when it is unchanged; raises what load_flow raises
+
+        Unlike load, this leaves the cache entries of the file's neighbours alone.
+        """
+        path = Path(path).resolve()
+        with self._lock:
+            cached = self._cached(path)
+            if cached is not None:
+                return _unpack(cached)
+            stamp = _stamp([path])
+            read_from_ns = time.time_ns()
+            document = load_flow(path, root)
+            self._store(path, _with_includes(stamp, document.includes, read_from_ns), _pack(document))
+            return document
+
+    def _cached(self, path: Path) -> Optional[bytes]:
+        cached = self._cache.get(path)
+        if cached and _stamp([Path(name) for name, _, _ in cached[0]]) == cached[0]:
+            return cached[1]
+        return None
+
+    def _store(self, path: Path, stamp: Optional[Stamp], pickled: bytes):
+        if stamp is not None:
+            self._cache[path] = (stamp, pickled)
+        else:
+            self._cache.pop(path, None)
+
+    def _prune(self, files: List[Path]):
+        # Files gone from a folder that was just loaded; other projects' entries stay
+        folders = {path.parent for path in files}
+        current = set(files)
+        for path in [path for path in self._cache if path.parent in folders and path not in current]:
+            del self._cache[path]
+
+    def clear(self):
+        with self._lock:
+            self._cache.clear()
+
+flow_loader = FlowLoader()
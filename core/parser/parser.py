@@ -6,71 +6,69 @@ Converts .flow syntax into FlashFlow IR (Intermediate Representation)
 import yaml
 import re
 from pathlib import Path
-from typing import Dict, Any, List
+from typing import Dict, Any, List, Optional
 from core.framework import FlashFlowIR
+from core.content import add_content_pages
+from core.parser.flow_file import FlowParseError, load_flow, parse_flow_text
+from core.parser.flow_loader import LoadStats, Progress, flow_loader
 from flashflow_cli.services.default_ui_service import default_ui_service
 
 class FlowParser:
     """Parser for .flow files"""
     
     def __init__(self):
+        # Imported here: the graph reads models and endpoints through the linter, which imports this module
+        from core.dependency_graph import DependencyGraph
         self.ir = FlashFlowIR()
+        # What each page, model and endpoint was read from, for rebuilding only what a change affects
+        self._graph = DependencyGraph()
+        # The project parse_project read, until the graph is linked against its IR
+        self._unlinked: Optional[Path] = None
+        # How the last parse_project read its .flow files
+        self.stats: Optional[LoadStats] = None
+    
+    @property
+    def graph(self):
+        """The dependency graph, linked on first use after parse_project; linking reads every page body"""
+        if self._unlinked is not None:
+            project_path, self._unlinked = self._unlinked, None
+            self._graph.link(self.ir, project_path)
+        return self._graph
     
     def parse_file(self, file_path: Path) -> Dict[str, Any]:
-        """Parse a single .flow file"""
-        
-        if not file_path.exists():
-            raise FileNotFoundError(f"Flow file not found: {file_path}")
-        
-        with open(file_path, 'r', encoding='utf-8') as f:
-            content = f.read()
-        
-        return self.parse_content(content)
+        """Parse a single .flow file, with its includes resolved (see core/parser/flow_file.py)"""
+        document = load_flow(file_path)
+        self._graph.add_document(document)
+        return document.data
     
     def parse_content(self, content: str) -> Dict[str, Any]:
-        """Parse .flow content string"""
-        
-        # Remove comments (lines starting with #)
-        lines = content.split('\n')
-        cleaned_lines = []
-        
-        for line in lines:
-            # Keep lines that don't start with # (after stripping whitespace)
-            stripped = line.lstrip()
-            if not stripped.startswith('#'):
-                cleaned_lines.append(line)
-        
-        cleaned_content = '\n'.join(cleaned_lines)
-        
-        try:
-            # Parse as YAML (since .flow syntax is YAML-like)
-            parsed_data = yaml.safe_load(cleaned_content)
-            
-            if parsed_data is None:
-                return {}
-            
-            return parsed_data
-            
-        except yaml.YAMLError as e:
-            raise ValueError(f"Failed to parse .flow file: {str(e)}")
+        """Parse .flow content string; includes are left as they are"""
+        return parse_flow_text(content)
     
-    def parse_project(self, project_path: Path) -> FlashFlowIR:
-        """Parse all .flow files in a project and return unified IR"""
+    def parse_project(self, project_path: Path, progress: Optional[Progress] = None) -> FlashFlowIR:
+        """Parse all .flow files in a project and return unified IR
+        
+        .flow files are read through the shared loader (core/parser/flow_loader.py):
+        unchanged files come from its cache and the rest are parsed in a worker pool
+        when there are many. progress(done, total) is called as files are read, and
+        self.stats says how the load went.
+        """
         
         flows_path = project_path / "src" / "flows"
         if not flows_path.exists():
             return self.ir
         
-        # Parse different types of flow files
-        flow_files = list(flows_path.glob("*.flow"))
-        liveflow_files = list(flows_path.glob("*.liveflow"))
-        jobflow_files = list(flows_path.glob("*.jobflow"))
-        testflow_files = list(flows_path.glob("*.testflow"))
+        # Parse different types of flow files; sorted, so the IR does not depend on directory order
+        flow_files = sorted(flows_path.glob("*.flow"))
+        liveflow_files = sorted(flows_path.glob("*.liveflow"))
+        jobflow_files = sorted(flows_path.glob("*.jobflow"))
+        testflow_files = sorted(flows_path.glob("*.testflow"))
         
         # Parse regular .flow files
-        for flow_file in flow_files:
-            parsed_data = self.parse_file(flow_file)
-            self._merge_into_ir(parsed_data)
+        documents, self.stats = flow_loader.load(flow_files, progress=progress)
+        for document in documents:
+            self._graph.add_document(document)
+            self._merge_into_ir(document.data)
         
         # Parse .liveflow files (real-time features)
         for liveflow_file in liveflow_files:
@@ -87,14 +85,14 @@ class FlowParser:
             parsed_data = self.parse_testflow_file(testflow_file)
             self._merge_testflow_into_ir(parsed_data)
         
-        # Parse .testflow files (tests)
-        for testflow_file in testflow_files:
-            parsed_data = self.parse_testflow_file(testflow_file)
-            self._merge_testflow_into_ir(parsed_data)
+        # Markdown pages in src/content, where no flow page has the route
+        add_content_pages(self.ir, project_path)
         
         # Generate default pages for models that don't have explicit page definitions
         default_ui_service.generate_default_pages(self.ir)
         
+        # Linked when the graph is asked for, so parses that only want models or endpoints leave page bodies unread
+        self._unlinked = project_path
         return self.ir
     
     def _merge_into_ir(self, parsed_data: Dict[str, Any]):
@@ -362,6 +360,7 @@ class FlowParser:
                 'providers': i18n_data.get('providers', ['google_translate']),
                 'fallback_language': i18n_data.get('fallback_language', 'en'),
                 'languages': i18n_data.get('languages', []),
+                'messages': i18n_data.get('messages', {}),
                 'processing': i18n_data.get('processing', {}),
                 'security': i18n_data.get('security', {}),
                 'performance': i18n_data.get('performance', {})
@@ -467,6 +466,14 @@ class FlowParser:
                     if isinstance(import_item, dict) and 'as' in import_item:
                         alias = import_item['as']
                         self.ir.lazy_imports[alias] = import_item
+        
+        # Handle request hooks (before/after middleware on routes)
+        for hook in extract_hooks(parsed_data):
+            self.ir.add_hook(hook)
+        
+        # Handle webhooks (signed notifications of model changes)
+        for webhook in extract_webhooks(parsed_data):
+            self.ir.add_webhook(webhook)
 
     def parse_liveflow_file(self, file_path: Path) -> Dict[str, Any]:
         """Parse a .liveflow file for real-time features"""
@@ -694,6 +701,28 @@ def parse_model_fields(fields: List[Dict]) -> List[Dict]:
     
     return parsed_fields
 
+def extract_hooks(parsed_data: Dict[str, Any]) -> List[Dict]:
+    """Normalize a 'hooks' section (list or name-keyed mapping) into a list of hook definitions"""
+    
+    hooks_data = parsed_data.get('hooks') if isinstance(parsed_data, dict) else None
+    if isinstance(hooks_data, dict):
+        hooks_data = [dict(hook, name=name) for name, hook in hooks_data.items() if isinstance(hook, dict)]
+    if not isinstance(hooks_data, list):
+        return []
+    
+    return [hook for hook in hooks_data if isinstance(hook, dict)]
+
+def extract_webhooks(parsed_data: Dict[str, Any]) -> List[Dict]:
+    """Normalize a 'webhooks' section (list or name-keyed mapping) into a list of webhook definitions"""
+    
+    webhooks_data = parsed_data.get('webhooks') if isinstance(parsed_data, dict) else None
+    if isinstance(webhooks_data, dict):
+        webhooks_data = [dict(webhook, name=name) for name, webhook in webhooks_data.items() if isinstance(webhook, dict)]
+    if not isinstance(webhooks_data, list):
+        return []
+    
+    return [webhook for webhook in webhooks_data if isinstance(webhook, dict)]
+
 def parse_component(component: Dict) -> Dict:
     """Parse a UI component definition"""
     
@@ -0,0 +1,165 @@
+"""
+FlashFlow permissions - Role and scope requirements on flow pages and endpoints
+
+    endpoint:
+      path: /api/todos/{id}
+      method: PUT
+      permissions:
+        role: editor             # editor or any role ranked above it
+        scopes: [todos:write]    # every listed scope
+
+    page:
+      path: /reports
+      permissions:
+        roles: [admin, editor]   # exactly one of these roles
+
+'permissions: admin' is short for 'role: admin'. Roles are ranked in ROLES
+order, so admin passes every role check; admin also holds every scope.
+"""
+
+import re
+from dataclasses import dataclass, field
+from typing import Any, Dict, List, Optional, Tuple
+
+ROLES = ['admin', 'editor', 'user']
+ALL_SCOPES = '*'
+PATH_PARAMETER = re.compile(r'\{[^/}]+\}|:[A-Za-z_][A-Za-z0-9_]*')
+
+class PermissionDeclarationError(ValueError):
+    """Raised for a malformed 'permissions' declaration"""
+    pass
+
+@dataclass
+class Principal:
+    """Who a request acts as"""
+    role: Optional[str] = None
+    scopes: List[str] = field(default_factory=list)
+    email: Optional[str] = None
+    source: str = 'anonymous'
+
+    @property
+    def authenticated(self) -> bool:
+        return self.role is not None
+
+    def has_scope(self, scope: str) -> bool:
+        return self.role == 'admin' or ALL_SCOPES in self.scopes or scope in self.scopes
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'role': self.role, 'scopes': self.scopes, 'email': self.email, 'source': self.source}
+
+@dataclass
+class Permission:
+    """Requirements a principal must meet"""
+    role: Optional[str] = None
+    roles: List[str] = field(default_factory=list)
+    scopes: List[str] = field(default_factory=list)
+
+    @classmethod
+    def from_definition(cls, definition: Any) -> 'Permission':
+        if isinstance(definition, str):
+            definition = {'role': definition}
+        if not isinstance(definition, dict):
+            raise PermissionDeclarationError("'permissions' must be a role name or a mapping with role, roles or scopes")
+
+        unknown = set(definition) - {'role', 'roles', 'scopes'}
+        if unknown:
+            raise PermissionDeclarationError(f"Unknown permissions key(s): {', '.join(sorted(unknown))}; use role, roles or scopes")
+
+        role = definition.get('role')
+        roles = _string_list(definition.get('roles'), 'roles')
+        scopes = _string_list(definition.get('scopes'), 'scopes')
+        for name in ([role] if role else []) + roles:
+            if name not in ROLES:
+                raise PermissionDeclarationError(f"Unknown role '{name}'; roles are {', '.join(ROLES)}")
+        if role and roles:
+            raise PermissionDeclarationError("Use either 'role' (minimum rank) or 'roles' (exact list), not both")
+        if not (role or roles or scopes):
+            raise PermissionDeclarationError("'permissions' needs a role, roles or scopes")
+        return cls(role=role, roles=roles, scopes=scopes)
+
+    def denial(self, principal: Principal) -> Optional[str]:
+        """Why the principal is refused, or None when it is allowed"""
+        if not principal.authenticated:
+            return "Authentication required"
+        if self.role and ROLES.index(principal.role) > ROLES.index(self.role):
+            return f"Requires role '{self.role}' or higher"
+        if self.roles and principal.role not in self.roles:
+            return f"Requires one of roles: {', '.join(self.roles)}"
+        missing = [scope for scope in self.scopes if not principal.has_scope(scope)]
+        if missing:
+            return f"Missing scope(s): {', '.join(missing)}"
+        return None
+
+    def allowed_roles(self) -> List[str]:
+        if self.role:
+            return ROLES[:ROLES.index(self.role) + 1]
+        return list(self.roles) or list(ROLES)
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {key: value for key, value in self.__dict__.items() if value}
+
+@dataclass
+class AccessRule:
+    """A permission attached to a page or endpoint path"""
+    kind: str
+    path: str
+    methods: List[str]
+    permission: Permission
+    source: str = ''
+
+    def __post_init__(self):
+        self._pattern = path_pattern(self.path)
+
+    def matches(self, method: str, path: str) -> bool:
+        if self.methods and method.upper() not in self.methods:
+            return False
+        return bool(self._pattern.match(path))
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'kind': self.kind, 'path': self.path, 'methods': self.methods,
+                'permissions': self.permission.to_dict(), 'source': self.source}
+
+def path_pattern(path: str) -> 're.Pattern':
+    """Regex for a flow path; {id} and :id parameters match one segment"""
+    pattern = ''.join(
+        '[^/]+' if PATH_PARAMETER.fullmatch(part) else re.escape(part)
+        for part in re.split(f"({PATH_PARAMETER.pattern})", path)
+    )
+    return re.compile(f"^{pattern}/?$")
+
+def collect_rules(parsed_data: Dict[str, Any], source: str) -> Tuple[List[AccessRule], List[str]]:
+    """Access rules declared by one parsed flow file, plus declaration errors"""
+    rules, errors = [], []
+    if not isinstance(parsed_data, dict):
+        return rules, errors
+
+    page = parsed_data.get('page')
+    if isinstance(page, dict) and page.get('permissions') is not None:
+        try:
+            rules.append(AccessRule('page', str(page.get('path', '/')), ['GET', 'HEAD'],
+                                    Permission.from_definition(page['permissions']), source))
+        except PermissionDeclarationError as e:
+            errors.append(f"{source}: page {page.get('path', '/')}: {e}")
+
+    endpoints = parsed_data.get('endpoint')
+    if isinstance(endpoints, dict):
+        endpoints = [endpoints]
+    for endpoint in endpoints if isinstance(endpoints, list) else []:
+        if not isinstance(endpoint, dict) or endpoint.get('permissions') is None:
+            continue
+        method = str(endpoint.get('method', '')).upper()
+        try:
+            rules.append(AccessRule('endpoint', str(endpoint.get('path', '/')), [method] if method else [],
+                                    Permission.from_definition(endpoint['permissions']), source))
+        except PermissionDeclarationError as e:
+            errors.append(f"{source}: endpoint {method} {endpoint.get('path', '?')}: {e}")
+    return rules, errors
+
+def _string_list(value: Any, name: str) -> List[str]:
+    if value is None:
+        return []
+    if isinstance(value, str):
+        return [value]
+    if isinstance(value, list) and all(isinstance(item, str) for item in value):
+        return list(value)
+    raise PermissionDeclarationError(f"'{name}' must be a list of names")
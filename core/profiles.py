@@ -0,0 +1,200 @@
+"""
+FlashFlow environment profiles - Per-environment settings from flashflow.json
+
+    "default_profile": "development",
+    "profiles": {
+        "development": {
+            "backend_url": "http://localhost:8000",
+            "features": {"new_checkout": true},
+            "keys": {"STRIPE_KEY": "env:STRIPE_TEST_KEY"}
+        },
+        "staging": {
+            "extends": "development",
+            "backend_url": "https://staging-api.example.com",
+            "env": {"LOG_LEVEL": "debug"}
+        },
+        "production": {
+            "backend_url": "https://api.example.com",
+            "features": {"new_checkout": false},
+            "keys": {"STRIPE_KEY": "env:STRIPE_LIVE_KEY", "MAPS_KEY": "file:/run/secrets/maps"}
+        }
+    }
+
+The profile is picked by --env, then FLASHFLOW_ENV, then default_profile,
+then the command's own default. 'keys' only hold references (env:NAME or
+file:path) so secrets never live in flashflow.json; they are resolved into
+the environment of build, hook and backend processes. Pages and generated
+artifacts only get the public part: name, backend_url and features.
+
+A profile's features are the project's flags (core/feature_flags.py) as
+they come out for this environment with nobody signed in, the profile's own
+true/false values taking precedence; profile.flags keeps the rules, for
+per-user rollouts.
+"""
+
+import json
+import os
+from pathlib import Path
+from typing import Any, Dict, List, Optional
+
+from core.feature_flags import FeatureFlagError, FeatureFlags
+
+PROFILE_FILE = "flashflow.env.json"
+KEY_SOURCES = ('env:', 'file:')
+PROFILE_FIELDS = ('extends', 'backend_url', 'features', 'keys', 'env')
+
+class ProfileError(Exception):
+    """Raised for an unknown profile or invalid profile settings"""
+    pass
+
+class Profile:
+    """One resolved environment profile"""
+
+    def __init__(self, name: str, backend_url: Optional[str] = None, features: Optional[Dict[str, bool]] = None,
+                 keys: Optional[Dict[str, str]] = None, env: Optional[Dict[str, str]] = None,
+                 flags: Optional[FeatureFlags] = None):
+        self.name = name
+        self.backend_url = backend_url
+        self.flags = flags or FeatureFlags({}, name, features)
+        self.features = self.flags.evaluate()
+        self.keys = keys or {}
+        self.env = env or {}
+
+    def public(self) -> Dict[str, Any]:
+        """What pages and generated artifacts may see; never the keys"""
+        return {'name': self.name, 'backend_url': self.backend_url, 'features': dict(self.features)}
+
+    def resolve_keys(self, root_path: Path, environ: Optional[Dict[str, str]] = None) -> Dict[str, str]:
+        """Key values from their references; missing ones are left out (see missing_keys)"""
+        environ = os.environ if environ is None else environ
+        values = {}
+        for name, reference in self.keys.items():
+            value = _read_reference(reference, root_path, environ)
+            if value is not None:
+                values[name] = value
+        return values
+
+    def missing_keys(self, root_path: Path, environ: Optional[Dict[str, str]] = None) -> List[str]:
+        resolved = self.resolve_keys(root_path, environ)
+        return [f"{name} ({reference})" for name, reference in self.keys.items() if name not in resolved]
+
+    def child_env(self, root_path: Path, environ: Optional[Dict[str, str]] = None) -> Dict[str, str]:
+        """Variables for processes started under this profile"""
+        env = {
+            'FLASHFLOW_ENV': self.name,
+            'FLASHFLOW_PROFILE': json.dumps(self.public()),
+        }
+        if self.backend_url:
+            env['FLASHFLOW_BACKEND_URL'] = self.backend_url
+        env.update({name: str(value) for name, value in self.env.items()})
+        env.update(self.resolve_keys(root_path, environ))
+        return env
+
+    def to_dict(self) -> Dict[str, Any]:
+        return dict(self.public(), keys=dict(self.keys), env=dict(self.env))
+
+def profile_names(project) -> List[str]:
+    return list((project.config.profiles or {}).keys())
+
+def resolve_profile_name(project, requested: Optional[str], default: str) -> str:
+    """--env, then FLASHFLOW_ENV, then default_profile from flashflow.json, then the command default"""
+    return requested or os.environ.get('FLASHFLOW_ENV') or project.config.default_profile or default
+
+def load_profile(project, requested: Optional[str] = None, default: str = 'development') -> Profile:
+    """The profile a command runs with; without "profiles" in flashflow.json any name is accepted"""
+    name = resolve_profile_name(project, requested, default)
+    profiles = project.config.profiles
+    settings: Dict[str, Any] = {}
+    if profiles:
+        if not isinstance(profiles, dict):
+            raise ProfileError("\"profiles\" in flashflow.json must be an object of profile name → settings")
+        if name not in profiles:
+            raise ProfileError(f"Unknown environment '{name}'. Profiles in flashflow.json: {', '.join(profiles)}")
+        settings = _merged(profiles, name, [])
+    try:
+        flags = FeatureFlags.from_config(project.config.features, name, settings.get('features'))
+    except FeatureFlagError as e:
+        raise ProfileError(str(e))
+    return Profile(name, settings.get('backend_url'), keys=settings.get('keys'), env=settings.get('env'), flags=flags)
+
+def write_profile(profile: Profile, directory: Path) -> Path:
+    """Write the public profile next to generated code, for apps to read at runtime"""
+    directory.mkdir(parents=True, exist_ok=True)
+    path = directory / PROFILE_FILE
+    with open(path, 'w') as f:
+        json.dump(profile.public(), f, indent=2)
+    return path
+
+def project_environ(project) -> Dict[str, str]:
+    """The process environment over the project's .env"""
+    return dict(load_dotenv(project.root_path / ".env"), **os.environ)
+
+def profile_env(project, profile: Profile) -> Dict[str, str]:
+    """A profile's variables, with key references resolved from the environment and .env"""
+    return profile.child_env(project.root_path, project_environ(project))
+
+def load_dotenv(env_file: Path) -> Dict[str, str]:
+    """Minimal KEY=value parser for the .env written by 'flashflow setup'"""
+    values: Dict[str, str] = {}
+    if not env_file.exists():
+        return values
+    for line in env_file.read_text(encoding='utf-8').splitlines():
+        line = line.strip()
+        if not line or line.startswith('#') or '=' not in line:
+            continue
+        key, value = line.split('=', 1)
+        key = key.strip()
+        if key.startswith('export '):
+            key = key[len('export '):].strip()
+        value = value.strip()
+        if len(value) >= 2 and value[0] == value[-1] and value[0] in ('"', "'"):
+            value = value[1:-1]
+        values[key] = value
+    return values
+
+def _merged(profiles: Dict[str, Any], name: str, chain: List[str]) -> Dict[str, Any]:
+    if name in chain:
+        raise ProfileError(f"Profiles extend each other in a loop: {' → '.join(chain + [name])}")
+    if name not in profiles:
+        raise ProfileError(f"Profile '{chain[-1]}' extends unknown profile '{name}'")
+    settings = profiles[name] or {}
+    _validate(name, settings)
+
+    merged: Dict[str, Any] = {}
+    if settings.get('extends'):
+        merged = _merged(profiles, settings['extends'], chain + [name])
+    for field in ('features', 'keys', 'env'):
+        merged[field] = dict(merged.get(field) or {}, **(settings.get(field) or {}))
+    if 'backend_url' in settings:
+        merged['backend_url'] = settings['backend_url']
+    return merged
+
+def _validate(name: str, settings: Any):
+    where = f"profiles.{name}"
+    if not isinstance(settings, dict):
+        raise ProfileError(f"{where} must be an object")
+    unknown = sorted(set(settings) - set(PROFILE_FIELDS))
+    if unknown:
+        raise ProfileError(f"Unknown field(s) {', '.join(unknown)} in {where}. Supported: {', '.join(PROFILE_FIELDS)}")
+    if settings.get('backend_url') is not None and not isinstance(settings['backend_url'], str):
+        raise ProfileError(f"{where}.backend_url must be a URL string")
+    for field in ('features', 'keys', 'env'):
+        if not isinstance(settings.get(field) or {}, dict):
+            raise ProfileError(f"{where}.{field} must be an object")
+    for flag, value in (settings.get('features') or {}).items():
+        if not isinstance(value, bool):
+            raise ProfileError(f"{where}.features.{flag} must be true or false")
+    for key, reference in (settings.get('keys') or {}).items():
+        if not isinstance(reference, str) or not reference.startswith(KEY_SOURCES):
+            raise ProfileError(f"{where}.keys.{key} must reference its value (env:NAME or file:path), not contain it")
+
+def _read_reference(reference: str, root_path: Path, environ: Dict[str, str]) -> Optional[str]:
+    source, _, target = reference.partition(':')
+    if source == 'env':
+        return environ.get(target)
+    path = Path(target).expanduser()
+    path = path if path.is_absolute() else Path(root_path) / path
+    try:
+        return path.read_text(encoding='utf-8').strip()
+    except OSError:
+        return None
@@ -0,0 +1,260 @@
+"""
+FlashFlow project templates - Starters for 'flashflow new --template'
+
+    flashflow new shop --template ecommerce                        built in: basic, todo, ecommerce
+    flashflow new crm --template crm                               a name from the template registry
+    flashflow new blog --template https://github.com/acme/ff-blog.git#v1.2.0
+    flashflow new app --template https://example.com/starter.tar.gz --sha256 <hash>
+    flashflow new app --template ../my-starter                     a local directory
+
+A template is a project directory with flashflow.json and/or src/flows. In
+every text file, and in file and folder names, {{project_name}},
+{{project_slug}}, {{author}} and {{year}} are replaced; other '{{ ... }}'
+expressions such as flow templates are left alone. The new project's
+flashflow.json gets its name and author, and the template's 'post_init'
+hooks run once the project is in place.
+
+The registry is a JSON index at REGISTRY_URL (FLASHFLOW_TEMPLATE_REGISTRY
+overrides it and may be a local file):
+
+    {"templates": {"crm": {"url": "https://example.com/crm-1.0.tar.gz", "sha256": "...",
+                           "description": "Contacts, deals and a pipeline board"}}}
+
+Archives are checked against their SHA-256 from --sha256 or the registry. A
+git template pinned to a full commit hash ('url#<40 hex digits>') is checked
+out at that commit and verified after the clone.
+"""
+
+import hashlib
+import json
+import os
+import re
+import shutil
+import subprocess
+import tarfile
+import time
+import urllib.request
+import zipfile
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Dict, Any, List, Optional
+
+BUILTIN_TEMPLATES = ('basic', 'todo', 'ecommerce')
+REGISTRY_URL = "https://templates.flashflow.dev/index.json"
+ARCHIVE_SUFFIXES = ('.zip', '.tar', '.tar.gz', '.tgz', '.tar.bz2', '.tar.xz')
+GIT_HOSTS = ('github.com', 'gitlab.com', 'bitbucket.org', 'codeberg.org')
+# Never copied out of a template
+SKIPPED_NAMES = {'.git', '.flashflow', 'node_modules', 'dist', '__pycache__'}
+PLACEHOLDER_PATTERN = re.compile(r"\{\{\s*(project_name|project_slug|author|year)\s*\}\}")
+COMMIT_PATTERN = re.compile(r"[0-9a-f]{40}")
+REGISTRY_NAME_PATTERN = re.compile(r"[a-z0-9][a-z0-9_.-]*(/[a-z0-9][a-z0-9_.-]*)?")
+
+class TemplateError(Exception):
+    """Raised when a template cannot be found, downloaded, verified or copied"""
+    pass
+
+@dataclass
+class TemplateSource:
+    """Where a template comes from; 'builtin' templates are written by the new command itself"""
+    kind: str  # builtin, directory, git, archive
+    location: str
+    ref: Optional[str] = None
+    sha256: Optional[str] = None
+    description: str = ''
+    # Set once the download matched its pinned SHA-256 or the clone its pinned commit
+    verified: bool = False
+
+    @property
+    def remote(self) -> bool:
+        return self.kind in ('git', 'archive')
+
+    def label(self) -> str:
+        return self.location + (f"#{self.ref}" if self.ref else '')
+
+def resolve(spec: str, sha256: Optional[str] = None, registry: Optional[str] = None) -> TemplateSource:
+    """Work out what kind of template a --template value names"""
+    sha256 = sha256.lower() if sha256 else None
+    if spec in BUILTIN_TEMPLATES:
+        return TemplateSource('builtin', spec)
+    local = Path(spec).expanduser()
+    if local.is_dir():
+        return TemplateSource('directory', str(local.resolve()))
+
+    location, _, ref = spec.partition('#')
+    if is_git_url(location):
+        return TemplateSource('git', location, ref or None)
+    if location.startswith(('http://', 'https://')):
+        if not location.split('?')[0].lower().endswith(ARCHIVE_SUFFIXES):
+            raise TemplateError(f"{location} is neither a git repository nor a .zip/.tar.gz archive")
+        return TemplateSource('archive', location, sha256=sha256)
+    if REGISTRY_NAME_PATTERN.fullmatch(spec):
+        return _from_registry(spec, sha256, registry)
+    raise TemplateError(f"Unknown template '{spec}'. Use {', '.join(BUILTIN_TEMPLATES)}, a registry name, "
+                        f"a git URL, an archive URL or a directory")
+
+def is_git_url(location: str) -> bool:
+    if location.startswith(('git@', 'git://', 'ssh://', 'git+')) or location.endswith('.git'):
+        return True
+    match = re.match(r"https?://([^/]+)/[^/]+/[^/]+/?$", location)
+    return bool(match and match.group(1).lower() in GIT_HOSTS)
+
+def registry_location(registry: Optional[str] = None) -> str:
+    return registry or os.environ.get('FLASHFLOW_TEMPLATE_REGISTRY') or REGISTRY_URL
+
+def load_registry(registry: Optional[str] = None) -> Dict[str, Dict[str, Any]]:
+    """The registry's templates by name"""
+    location = registry_location(registry)
+    try:
+        if location.startswith(('http://', 'https://')):
+            with urllib.request.urlopen(location, timeout=30) as response:
+                index = json.loads(response.read().decode('utf-8'))
+        else:
+            with open(Path(location).expanduser(), 'r') as f:
+                index = json.load(f)
+    except (OSError, ValueError) as e:
+        raise TemplateError(f"Could not read the template registry {location}: {e}")
+    templates = index.get('templates') if isinstance(index, dict) else None
+    if not isinstance(templates, dict):
+        raise TemplateError(f"The template registry {location} has no 'templates' object")
+    return templates
+
+def _from_registry(name: str, sha256: Optional[str], registry: Optional[str]) -> TemplateSource:
+    templates = load_registry(registry)
+    entry = templates.get(name)
+    if not isinstance(entry, dict) or not isinstance(entry.get('url'), str):
+        known = ', '.join(sorted(templates)) or 'none'
+        raise TemplateError(f"No template '{name}' in the registry (available: {known})")
+    url = entry['url'] + (f"#{entry['ref']}" if entry.get('ref') and '#' not in entry['url'] else '')
+    source = resolve(url, sha256 or entry.get('sha256'))
+    if source.kind not in ('git', 'archive'):
+        raise TemplateError(f"Registry template '{name}' must point at a git repository or an archive, not {url}")
+    source.description = str(entry.get('description', ''))
+    return source
+
+def fetch(source: TemplateSource, workdir: Path) -> Path:
+    """Download or locate a template; returns the directory holding its files"""
+    workdir = Path(workdir)
+    if source.kind == 'directory':
+        return template_root(Path(source.location))
+    if source.kind == 'git':
+        return template_root(_clone(source, workdir / 'template'))
+    if source.kind == 'archive':
+        return template_root(_download(source, workdir))
+    raise TemplateError(f"Built-in template '{source.location}' has no files to fetch")
+
+def _clone(source: TemplateSource, target: Path) -> Path:
+    pinned = bool(source.ref and COMMIT_PATTERN.fullmatch(source.ref))
+    args = ['git', 'clone', '--quiet']
+    if source.ref and not pinned:
+        args += ['--depth', '1', '--branch', source.ref]
+    elif not pinned:
+        args += ['--depth', '1']
+    url = source.location[len('git+'):] if source.location.startswith('git+') else source.location
+    _git(args + [url, str(target)])
+    if pinned:
+        _git(['git', '-C', str(target), 'checkout', '--quiet', source.ref])
+        head = _git(['git', '-C', str(target), 'rev-parse', 'HEAD']).strip()
+        if head != source.ref:
+            raise TemplateError(f"{source.location} is at {head} after checkout, expected {source.ref}")
+        source.verified = True
+    shutil.rmtree(target / '.git', ignore_errors=True)
+    return target
+
+def _git(args: List[str]) -> str:
+    try:
+        result = subprocess.run(args, stdout=subprocess.PIPE, stderr=subprocess.PIPE, text=True,
+                                env=dict(os.environ, GIT_TERMINAL_PROMPT='0'))
+    except FileNotFoundError:
+        raise TemplateError("git is not installed; it is needed for git templates")
+    if result.returncode != 0:
+        raise TemplateError(f"{' '.join(args[:3])} failed: {result.stderr.strip() or f'exit code {result.returncode}'}")
+    return result.stdout
+
+def _download(source: TemplateSource, workdir: Path) -> Path:
+    file_name = source.location.split('?')[0].rstrip('/').rsplit('/', 1)[-1]
+    archive = workdir / file_name
+    try:
+        with urllib.request.urlopen(source.location, timeout=60) as response, open(archive, 'wb') as f:
+            shutil.copyfileobj(response, f)
+    except OSError as e:
+        raise TemplateError(f"Downloading {source.location} failed: {e}")
+
+    digest = hashlib.sha256()
+    with open(archive, 'rb') as f:
+        for chunk in iter(lambda: f.read(1024 * 1024), b''):
+            digest.update(chunk)
+    if source.sha256 and digest.hexdigest() != source.sha256:
+        raise TemplateError(f"{file_name} has SHA-256 {digest.hexdigest()}, expected {source.sha256}; not using it")
+    source.verified = bool(source.sha256)
+    source.sha256 = digest.hexdigest()
+
+    target = workdir / 'template'
+    target.mkdir()
+    _unpack(archive, target)
+    # Archives of a repository usually wrap everything in one top-level folder
+    entries = [entry for entry in target.iterdir() if entry.name not in ('__MACOSX',)]
+    return entries[0] if len(entries) == 1 and entries[0].is_dir() else target
+
+def _unpack(archive: Path, target: Path):
+    name = archive.name.lower()
+    try:
+        if name.endswith('.zip'):
+            with zipfile.ZipFile(archive) as bundle:
+                _check_members(bundle.namelist(), target)
+                bundle.extractall(target)
+        else:
+            with tarfile.open(archive) as bundle:
+                links = [member.name for member in bundle.getmembers() if member.issym() or member.islnk()]
+                if links:
+                    raise TemplateError(f"Template archives may not contain links ({links[0]})")
+                _check_members(bundle.getnames(), target)
+                bundle.extractall(target)
+    except (tarfile.TarError, zipfile.BadZipFile) as e:
+        raise TemplateError(f"{archive.name} is not a readable archive: {e}")
+
+def _check_members(names: List[str], target: Path):
+    root = target.resolve()
+    for member in names:
+        destination = (target / member).resolve()
+        if destination != root and root not in destination.parents:
+            raise TemplateError(f"Archive entry {member} would be written outside the project")
+
+def template_root(path: Path) -> Path:
+    if not (path / 'flashflow.json').is_file() and not (path / 'src' / 'flows').is_dir():
+        raise TemplateError(f"{path.name or path} does not look like a FlashFlow template (no flashflow.json or src/flows)")
+    return path
+
+def placeholders(project_name: str, author: str) -> Dict[str, str]:
+    return {
+        'project_name': project_name,
+        'project_slug': re.sub(r"[^a-z0-9]+", '-', project_name.lower()).strip('-'),
+        'author': author,
+        'year': time.strftime('%Y')
+    }
+
+def substitute(text: str, values: Dict[str, str]) -> str:
+    return PLACEHOLDER_PATTERN.sub(lambda match: values[match.group(1)], text)
+
+def copy_template(template_dir: Path, project_path: Path, values: Dict[str, str]) -> int:
+    """Copy a template into the (existing, empty) project directory; returns the number of files"""
+    copied = 0
+    for current, dirs, files in os.walk(template_dir):
+        dirs[:] = sorted(name for name in dirs if name not in SKIPPED_NAMES)
+        relative = Path(current).relative_to(template_dir)
+        destination_dir = project_path / Path(*[substitute(part, values) for part in relative.parts])
+        destination_dir.mkdir(parents=True, exist_ok=True)
+        for name in sorted(files):
+            source = Path(current) / name
+            if source.is_symlink():
+                continue
+            destination = destination_dir / substitute(name, values)
+            data = source.read_bytes()
+            if b'\0' not in data:
+                try:
+                    data = substitute(data.decode('utf-8'), values).encode('utf-8')
+                except UnicodeDecodeError:
+                    pass
+            destination.write_bytes(data)
+            shutil.copymode(source, destination)
+            copied += 1
+    return copied
@@ -0,0 +1,173 @@
+"""
+FlashFlow proxy - Client address, scheme and host behind tunnels and reverse proxies
+
+    "proxy": {
+        "trusted": ["127.0.0.1", "::1", "10.0.0.0/8"],   # addresses and networks of the proxies
+        "https_redirect": false                          # send plain HTTP requests to https://
+    }
+
+A request from a trusted address has its X-Forwarded-For, -Proto, -Host,
+-Port and -Prefix headers applied, so the app sees the client's address and
+the URL it asked for, and builds its absolute URLs (Location and Link
+headers, redirects, integration callbacks) from them. X-Forwarded-For is
+read from the right, skipping trusted proxies; the first other address is
+the client. Headers from any other address are ignored, since a client can
+send them itself.
+
+The default trusts loopback only: 'serve --share' and a proxy on the same
+machine work out of the box. An empty list trusts nobody.
+
+With https_redirect, requests that reached the outermost proxy over plain
+HTTP are redirected to https://. Requests made straight to the server and
+dev tooling under /__ never are.
+"""
+
+import ipaddress
+import re
+from typing import Any, Dict, Iterable, List, Optional, Union
+from urllib.parse import quote
+
+DEFAULT_TRUSTED = ('127.0.0.1', '::1')
+ENVIRON_KEY = 'flashflow.proxy'
+EXEMPT_PREFIXES = ('/__',)
+FORWARDED_HOST = re.compile(r'^[A-Za-z0-9.-]+(:\d{1,5})?$|^\[[0-9A-Fa-f:.]+\](:\d{1,5})?$')
+FORWARDED_PREFIX = re.compile(r'^(/[A-Za-z0-9._~!$&\'()*+,;=:@%-]*)*$')
+
+Network = Union[ipaddress.IPv4Network, ipaddress.IPv6Network]
+
+class ProxyError(Exception):
+    """Raised for invalid proxy settings in flashflow.json"""
+    pass
+
+def _parse_address(text: str) -> Optional[Union[ipaddress.IPv4Address, ipaddress.IPv6Address]]:
+    text = text.strip()
+    # "[2001:db8::1]:443" and "203.0.113.7:51234" both occur in X-Forwarded-For
+    if text.startswith('['):
+        text = text[1:text.find(']')] if ']' in text else text
+    elif text.count(':') == 1:
+        text = text.split(':')[0]
+    try:
+        address = ipaddress.ip_address(text)
+    except ValueError:
+        return None
+    # ::ffff:127.0.0.1 is how a dual-stack socket reports an IPv4 peer
+    return address.ipv4_mapped if getattr(address, 'ipv4_mapped', None) else address
+
+class ProxyConfig:
+    """Which peers' forwarding headers to believe, and whether to insist on HTTPS"""
+
+    def __init__(self, trusted: Iterable[str] = DEFAULT_TRUSTED, https_redirect: bool = False):
+        if isinstance(trusted, str):
+            raise ProxyError("proxy.trusted must be a list of addresses or networks")
+        self.trusted: List[Network] = []
+        for entry in trusted:
+            try:
+                self.trusted.append(ipaddress.ip_network(str(entry).strip(), strict=False))
+            except ValueError:
+                raise ProxyError(f"proxy.trusted has '{entry}', which is not an address or network like 10.0.0.0/8")
+        if not isinstance(https_redirect, bool):
+            raise ProxyError("proxy.https_redirect must be true or false")
+        self.https_redirect = https_redirect
+
+    @classmethod
+    def for_project(cls, project, https_redirect: Optional[bool] = None) -> 'ProxyConfig':
+        settings = project.config.proxy or {}
+        if not isinstance(settings, dict):
+            raise ProxyError("\"proxy\" in flashflow.json must be an object")
+        unknown = sorted(set(settings) - {'trusted', 'https_redirect'})
+        if unknown:
+            raise ProxyError(f"proxy has unknown field(s) {', '.join(unknown)}; use trusted and https_redirect")
+        trusted = settings.get('trusted', list(DEFAULT_TRUSTED))
+        if not isinstance(trusted, list):
+            raise ProxyError("proxy.trusted must be a list of addresses or networks")
+        return cls(trusted, settings.get('https_redirect', False) if https_redirect is None else https_redirect)
+
+    def is_trusted(self, text: Optional[str]) -> bool:
+        address = _parse_address(text or '')
+        return address is not None and any(address in network for network in self.trusted)
+
+    def resolve(self, environ: Dict[str, Any]) -> Dict[str, Any]:
+        """What the outermost trusted proxy saw: client, scheme, host and prefix, and how many proxies were passed"""
+        peer = environ.get('REMOTE_ADDR') or ''
+        resolved = {'peer': peer, 'client': peer, 'scheme': environ.get('wsgi.url_scheme', 'http'),
+                    'host': environ.get('HTTP_HOST'), 'prefix': None, 'proxies': 0}
+        if not self.is_trusted(peer):
+            return resolved
+
+        chain = [entry.strip() for entry in environ.get('HTTP_X_FORWARDED_FOR', '').split(',') if entry.strip()]
+        proxies, client = 1, peer
+        for entry in reversed(chain):
+            client = entry
+            if not self.is_trusted(entry):
+                break
+            proxies += 1
+        resolved['client'], resolved['proxies'] = str(_parse_address(client) or peer), proxies
+
+        def forwarded(name: str) -> Optional[str]:
+            # Each proxy appends its own value; the outermost trusted one saw what the client asked for
+            values = [value.strip() for value in environ.get(name, '').split(',') if value.strip()]
+            return values[-min(proxies, len(values))] if values else None
+
+        proto = (forwarded('HTTP_X_FORWARDED_PROTO') or '').lower()
+        if proto in ('http', 'https'):
+            resolved['scheme'] = proto
+        host = forwarded('HTTP_X_FORWARDED_HOST')
+        if host and FORWARDED_HOST.match(host):
+            resolved['host'] = host
+        port = forwarded('HTTP_X_FORWARDED_PORT')
+        if port and port.isdigit() and 0 < int(port) < 65536 and resolved['host']:
+            bare = re.sub(r':\d+$', '', resolved['host'])
+            default = {'http': '80', 'https': '443'}[resolved['scheme']]
+            resolved['host'] = bare if port == default else f"{bare}:{port}"
+        prefix = forwarded('HTTP_X_FORWARDED_PREFIX')
+        if prefix and FORWARDED_PREFIX.match(prefix):
+            resolved['prefix'] = prefix.rstrip('/')
+        return resolved
+
+    def needs_redirect(self, environ: Dict[str, Any], resolved: Dict[str, Any]) -> bool:
+        if not self.https_redirect or resolved['scheme'] == 'https':
+            return False
+        if (environ.get('PATH_INFO') or '/').startswith(EXEMPT_PREFIXES):
+            return False
+        # The server itself only speaks HTTP, so there is no https:// to send a direct request to
+        return bool(resolved['proxies']) and ('HTTP_X_FORWARDED_PROTO' in environ or 'HTTP_X_FORWARDED_FOR' in environ)
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'trusted': [str(network) for network in self.trusted], 'https_redirect': self.https_redirect}
+
+def https_location(environ: Dict[str, Any], host: str) -> str:
+    path = quote(environ.get('SCRIPT_NAME', '') + (environ.get('PATH_INFO') or '/'), safe="/;=,:@!$&'()*+~%-._")
+    query = environ.get('QUERY_STRING')
+    # The redirect goes to the default HTTPS port, not the one plain HTTP came in on
+    host = re.sub(r':\d+$', '', host)
+    return f"https://{host}{path}" + (f"?{query}" if query else '')
+
+class ProxyMiddleware:
+    """WSGI middleware applying trusted forwarding headers, and the HTTPS redirect"""
+
+    def __init__(self, wsgi_app, config: ProxyConfig):
+        self.wsgi_app = wsgi_app
+        self.config = config
+
+    def __call__(self, environ, start_response):
+        resolved = self.config.resolve(environ)
+        environ[ENVIRON_KEY] = dict(resolved, original={key: environ.get(key) for key in
+                                                         ('REMOTE_ADDR', 'wsgi.url_scheme', 'HTTP_HOST', 'SCRIPT_NAME')})
+        if resolved['proxies']:
+            environ['REMOTE_ADDR'] = resolved['client']
+            environ['wsgi.url_scheme'] = resolved['scheme']
+            if resolved['host'] and resolved['host'] != environ.get('HTTP_HOST'):
+                environ['HTTP_HOST'] = resolved['host']
+                port = re.search(r':(\d+)$', resolved['host'])
+                environ['SERVER_PORT'] = port.group(1) if port else {'http': '80', 'https': '443'}[resolved['scheme']]
+            if resolved['prefix']:
+                environ['SCRIPT_NAME'] = resolved['prefix']
+
+        if self.config.needs_redirect(environ, resolved) and resolved['host']:
+            location = https_location(environ, resolved['host'])
+            method = environ.get('REQUEST_METHOD', 'GET')
+            # 308 keeps the method and body of a POST; browsers turn a 301 into a GET
+            status = '301 Moved Permanently' if method in ('GET', 'HEAD') else '308 Permanent Redirect'
+            start_response(status, [('Location', location), ('Content-Length', '0'), ('Cache-Control', 'no-store')])
+            return [b'']
+        return self.wsgi_app(environ, start_response)
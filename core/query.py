@@ -0,0 +1,154 @@
+"""
+FlashFlow list query language - Pagination, sorting and filtering for model endpoints
+
+    ?page=2&per_page=50
+    ?sort=-created_at,title                    (- for descending)
+    ?filter[status]=open                       (same as [eq])
+    ?filter[priority][gte]=3&filter[title][like]=invoice
+    ?filter[id][in]=1,2,3&filter[deleted_at][null]=true
+
+Operators: eq, ne, lt, lte, gt, gte, like (contains, case-insensitive),
+in (comma separated) and null (true/false). Unknown fields or operators are
+rejected rather than ignored so typos do not silently return everything.
+"""
+
+import re
+from dataclasses import dataclass, field
+from typing import Any, Dict, Iterable, List, Tuple
+from urllib.parse import urlencode
+
+from core.database import StorageError
+
+DEFAULT_PER_PAGE = 25
+MAX_PER_PAGE = 500
+FILTER_OPERATORS = ['eq', 'ne', 'lt', 'lte', 'gt', 'gte', 'like', 'in', 'null']
+FILTER_PARAM = re.compile(r'^filter\[([^\]]+)\](?:\[([^\]]+)\])?$')
+
+QUERY_GRAMMAR = (
+    "List endpoints accept `page` and `per_page` (max %d), `sort` as a comma separated list of fields "
+    "(prefix `-` for descending, e.g. `sort=-created_at,title`) and filters written as "
+    "`filter[field][op]=value` or `filter[field]=value` for equality. Operators: eq, ne, lt, lte, gt, gte, "
+    "like (case-insensitive contains), in (comma separated values) and null (`true` or `false`). "
+    "Responses include `total`, `page`, `per_page` and `pages`, an `X-Total-Count` header and a "
+    "`Link` header with first, prev, next and last pages." % MAX_PER_PAGE
+)
+
+class QueryError(StorageError):
+    """Raised for a malformed page, sort or filter parameter"""
+    pass
+
+@dataclass
+class Filter:
+    """One filter[field][op]=value condition"""
+    field: str
+    op: str
+    value: Any
+
+@dataclass
+class ListQuery:
+    """Parsed list parameters for one request"""
+    page: int = 1
+    per_page: int = DEFAULT_PER_PAGE
+    sort: List[Tuple[str, bool]] = field(default_factory=list)  # (field, descending)
+    filters: List[Filter] = field(default_factory=list)
+
+    @property
+    def offset(self) -> int:
+        return (self.page - 1) * self.per_page
+
+    def pages(self, total: int) -> int:
+        return max(1, -(-total // self.per_page))
+
+def parse_list_query(params: Iterable[Tuple[str, str]], columns: List[str],
+                     default_per_page: int = DEFAULT_PER_PAGE, max_per_page: int = MAX_PER_PAGE) -> ListQuery:
+    """Build a ListQuery from (name, value) pairs such as request.args.items(multi=True)"""
+    query = ListQuery(per_page=default_per_page)
+    for name, value in params:
+        if name == 'page':
+            query.page = _positive_int(name, value)
+        elif name == 'per_page':
+            query.per_page = min(_positive_int(name, value), max_per_page)
+        elif name == 'sort':
+            for item in filter(None, (part.strip() for part in value.split(','))):
+                descending = item.startswith('-')
+                sort_field = item.lstrip('-+')
+                _require_column(sort_field, columns, 'sort')
+                query.sort.append((sort_field, descending))
+        else:
+            match = FILTER_PARAM.match(name)
+            if not match:
+                continue
+            filter_field, op = match.group(1), (match.group(2) or 'eq').lower()
+            _require_column(filter_field, columns, 'filter')
+            if op not in FILTER_OPERATORS:
+                raise QueryError(f"Unknown filter operator '{op}' for '{filter_field}'; use one of {', '.join(FILTER_OPERATORS)}")
+            query.filters.append(Filter(filter_field, op, _filter_value(op, value)))
+    return query
+
+def _positive_int(name: str, value: str) -> int:
+    try:
+        number = int(value)
+    except (TypeError, ValueError):
+        raise QueryError(f"'{name}' must be a whole number")
+    if number < 1:
+        raise QueryError(f"'{name}' must be at least 1")
+    return number
+
+def _require_column(name: str, columns: List[str], usage: str):
+    if name not in columns:
+        raise QueryError(f"Cannot {usage} by unknown field '{name}'; fields are {', '.join(columns)}")
+
+def _filter_value(op: str, value: str) -> Any:
+    if op == 'in':
+        return [_scalar(part.strip()) for part in value.split(',') if part.strip()]
+    if op == 'null':
+        if value.lower() not in ('true', 'false', '1', '0'):
+            raise QueryError("filter[...][null] takes true or false")
+        return value.lower() in ('true', '1')
+    if op == 'like':
+        return value
+    return _scalar(value)
+
+def _scalar(value: str) -> Any:
+    # Booleans are stored as 0/1 by SQLite and as real booleans elsewhere; bound bools work for both
+    return {'true': True, 'false': False}.get(value.lower(), value)
+
+def link_header(base_url: str, params: Dict[str, str], query: ListQuery, total: int) -> str:
+    """RFC 8288 Link header with first/prev/next/last page URLs"""
+    last = query.pages(total)
+    pages = {'first': 1, 'last': last}
+    if query.page > 1:
+        pages['prev'] = min(query.page - 1, last)
+    if query.page < last:
+        pages['next'] = query.page + 1
+
+    links = []
+    for rel in ('first', 'prev', 'next', 'last'):
+        if rel in pages:
+            page_params = dict(params, page=str(pages[rel]), per_page=str(query.per_page))
+            links.append(f'<{base_url}?{urlencode(page_params)}>; rel="{rel}"')
+    return ', '.join(links)
+
+def openapi_list_parameters(columns: List[str]) -> List[Dict[str, Any]]:
+    """OpenAPI 3 parameters describing the grammar for one model"""
+    return [
+        {'name': 'page', 'in': 'query', 'schema': {'type': 'integer', 'minimum': 1, 'default': 1}},
+        {'name': 'per_page', 'in': 'query',
+         'schema': {'type': 'integer', 'minimum': 1, 'maximum': MAX_PER_PAGE, 'default': DEFAULT_PER_PAGE}},
+        {'name': 'sort', 'in': 'query', 'description': "Comma separated fields, '-' prefix for descending",
+         'schema': {'type': 'string', 'pattern': r'^-?(%s)(,-?(%s))*$' % ('|'.join(columns), '|'.join(columns))},
+         'example': '-created_at'},
+        {'name': 'filter', 'in': 'query', 'style': 'deepObject', 'explode': True,
+         'description': f"filter[field][op]=value with op in {', '.join(FILTER_OPERATORS)}; filter[field]=value means eq",
+         'schema': {
+             'type': 'object',
+             'properties': {
+                 column: {'oneOf': [
+                     {'type': 'string'},
+                     {'type': 'object', 'properties': {op: {'type': 'string'} for op in FILTER_OPERATORS}, 'additionalProperties': False}
+                 ]}
+                 for column in columns
+             },
+             'additionalProperties': False
+         }}
+    ]
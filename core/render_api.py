@@ -0,0 +1,163 @@
+"""
+FlashFlow render API - Limits, uploads and batches for rendering components over HTTP
+
+POST /api/render/component (cli/devserver/render_api.py) renders flow
+components to HTML with the static site's renderers. Request bodies are
+capped, and a body over its cap gets 413 before it is read:
+
+    "render_api": {
+      "max_body_kb": 1024,      # JSON requests
+      "max_upload_mb": 25,      # multipart requests, files included
+      "max_batch": 50           # components in one request
+    }
+
+A multipart request carries the JSON as its 'request' part and files as
+the other parts. Components point at a file with 'upload:<part name>' in
+any string prop ('src: upload:photo'), which becomes the URL the file is
+served at. Uploads are kept in .flashflow/render-uploads/<id>/ for
+UPLOAD_TTL seconds.
+"""
+
+import re
+import secrets
+import shutil
+import time
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Any, BinaryIO, Dict, List, Optional, Tuple
+
+DEFAULT_MAX_BODY_KB = 1024
+DEFAULT_MAX_UPLOAD_MB = 25
+DEFAULT_MAX_BATCH = 50
+UPLOAD_TTL = 3600
+UPLOAD_PREFIX = 'upload:'
+UPLOAD_ID = re.compile(r'^upl_[0-9a-f]{16}$')
+READ_CHUNK = 64 * 1024
+
+class RenderApiError(Exception):
+    """Raised for invalid render_api settings and requests"""
+    pass
+
+class BodyTooLarge(RenderApiError):
+    """Raised when a request body is over its limit"""
+
+    def __init__(self, limit: int):
+        super().__init__(f"The request body is larger than {format_size(limit)}; raise render_api limits in flashflow.json "
+                         "or send fewer components")
+        self.limit = limit
+
+class LengthRequired(RenderApiError):
+    """Raised for a multipart body sent without Content-Length, which cannot be checked before it is parsed"""
+    pass
+
+def format_size(count: int) -> str:
+    if count >= 1024 * 1024:
+        return f"{count / (1024 * 1024):g} MB"
+    return f"{count / 1024:g} KB"
+
+@dataclass
+class RenderLimits:
+    max_body: int = DEFAULT_MAX_BODY_KB * 1024
+    max_upload: int = DEFAULT_MAX_UPLOAD_MB * 1024 * 1024
+    max_batch: int = DEFAULT_MAX_BATCH
+
+    @classmethod
+    def from_config(cls, project) -> 'RenderLimits':
+        settings = getattr(project.config, 'render_api', None) or {}
+        try:
+            max_body = float(settings.get('max_body_kb', DEFAULT_MAX_BODY_KB))
+            max_upload = float(settings.get('max_upload_mb', DEFAULT_MAX_UPLOAD_MB))
+            max_batch = int(settings.get('max_batch', DEFAULT_MAX_BATCH))
+        except (TypeError, ValueError):
+            raise RenderApiError("render_api.max_body_kb, max_upload_mb and max_batch in flashflow.json must be numbers")
+        if max_body <= 0 or max_upload <= 0 or max_batch < 1:
+            raise RenderApiError("render_api limits in flashflow.json must be positive")
+        return cls(int(max_body * 1024), int(max_upload * 1024 * 1024), max_batch)
+
+def read_limited(stream: BinaryIO, limit: int) -> bytes:
+    """All of a body of unknown length, or BodyTooLarge as soon as it passes limit"""
+    chunks, total = [], 0
+    while True:
+        chunk = stream.read(min(READ_CHUNK, limit + 1 - total))
+        if not chunk:
+            return b''.join(chunks)
+        total += len(chunk)
+        if total > limit:
+            raise BodyTooLarge(limit)
+        chunks.append(chunk)
+
+def render_items(data: Any, max_batch: int) -> Tuple[List[Tuple[str, Any]], bool]:
+    """(id, component) pairs from a request, and whether it was a batch
+
+    {"component": {...}} renders one component; {"components": [...]} renders
+    each entry, which is a component or {"id": ..., "component": {...}}.
+    """
+    if not isinstance(data, dict) or ('component' in data) == ('components' in data):
+        raise RenderApiError("Send {\"component\": {...}} or {\"components\": [...]}")
+    if 'component' in data:
+        return [('0', data['component'])], False
+    components = data['components']
+    if not isinstance(components, list) or not components:
+        raise RenderApiError("'components' must be a list with at least one component")
+    if len(components) > max_batch:
+        raise RenderApiError(f"A batch takes at most {max_batch} components (render_api.max_batch), got {len(components)}")
+    items = []
+    for index, entry in enumerate(components):
+        if isinstance(entry, dict) and 'id' in entry and isinstance(entry.get('component'), dict):
+            items.append((str(entry['id']), entry['component']))
+        else:
+            items.append((str(index), entry))
+    return items, True
+
+def substitute_uploads(value: Any, urls: Dict[str, str], missing: List[str]) -> Any:
+    """value with every 'upload:<name>' string replaced by the upload's URL; unknown names are added to missing"""
+    if isinstance(value, dict):
+        return {key: substitute_uploads(item, urls, missing) for key, item in value.items()}
+    if isinstance(value, list):
+        return [substitute_uploads(item, urls, missing) for item in value]
+    if isinstance(value, str) and value.startswith(UPLOAD_PREFIX):
+        name = value[len(UPLOAD_PREFIX):]
+        if name in urls:
+            return urls[name]
+        missing.append(name)
+    return value
+
+def safe_filename(name: str) -> str:
+    """A file name without directories or characters that need quoting in a URL"""
+    name = re.sub(r'[^A-Za-z0-9._-]+', '_', Path(name.replace('\\', '/')).name).strip('._')
+    return name[:120] or 'upload'
+
+class UploadStore:
+    """Files uploaded with render requests, one directory per request"""
+
+    def __init__(self, directory: Path, ttl: float = UPLOAD_TTL):
+        self.directory = Path(directory)
+        self.ttl = ttl
+
+    def create(self) -> Tuple[str, Path]:
+        self.expire()
+        upload_id = 'upl_' + secrets.token_hex(8)
+        path = self.directory / upload_id
+        path.mkdir(parents=True)
+        return upload_id, path
+
+    def resolve(self, upload_id: str, filename: str) -> Optional[Path]:
+        if not UPLOAD_ID.match(upload_id) or safe_filename(filename) != filename:
+            return None
+        path = self.directory / upload_id / filename
+        return path if path.is_file() else None
+
+    def discard(self, upload_id: str):
+        shutil.rmtree(self.directory / upload_id, ignore_errors=True)
+
+    def expire(self, now: Optional[float] = None):
+        now = now or time.time()
+        if not self.directory.is_dir():
+            return
+        for path in self.directory.iterdir():
+            try:
+                expired = path.is_dir() and UPLOAD_ID.match(path.name) and now - path.stat().st_mtime > self.ttl
+            except OSError:
+                continue
+            if expired:
+                shutil.rmtree(path, ignore_errors=True)
@@ -0,0 +1,233 @@
+"""
+FlashFlow render context - Typed components and helpers for the HTML renderers
+
+Flow pages arrive as nested dicts and lists. compile_components() turns a
+page body into Component values before anything is rendered: the component
+type is known, children and tabs are lists of components, and every prop
+listed in PROP_TYPES has its declared kind. A prop of the wrong kind, an
+unknown component or an entry that is not a component becomes a
+RenderProblem naming where it is ('body[2].children[0]'), and the prop is
+left out so its renderer falls back to its default instead of failing.
+
+Renderers get a RenderContext for the page being rendered, with the
+helpers they share:
+
+    asset(src)     URL of an image or video as served (fingerprinted on export)
+    route(link)    a link made safe, pointing at the exported page when it is one
+    t(text)        text in the site's language, from the flows' i18n messages
+
+    i18n:
+      messages:
+        de: {Submit: Absenden, Open: Öffnen}
+"""
+
+from dataclasses import dataclass, field
+from typing import Any, Callable, Dict, FrozenSet, List, Optional, Set, Tuple
+
+from core.html_safety import escape_html, safe_url
+
+# Prop kinds of the components the HTML renderers draw; other props pass through as written
+PROP_TYPES: Dict[str, Dict[str, str]] = {
+    'header': {'content': 'text'},
+    'headline': {'text': 'text', 'level': 'int'},
+    'text': {'content': 'text'},
+    'rich_text': {'html': 'text'},
+    'markdown': {'content': 'text', 'src': 'text'},
+    'hero': {'title': 'text', 'subtitle': 'text', 'cta': 'object'},
+    'card': {'title': 'text', 'content': 'text'},
+    'features': {'items': 'list'},
+    'button': {'text': 'text', 'link': 'text'},
+    'primary_button': {'text': 'text', 'link': 'text'},
+    'input': {'label': 'text', 'value': 'text', 'disabled': 'bool'},
+    'form': {'fields': 'list', 'submit': 'text'},
+    'image': {'src': 'text', 'alt': 'text', 'width': 'int', 'height': 'int', 'sizes': 'text'},
+    'video': {'src': 'text', 'poster': 'text', 'controls': 'bool', 'autoplay': 'bool', 'muted': 'bool', 'loop': 'bool',
+              'width': 'int'},
+    'gallery': {'images': 'list', 'columns': 'int'},
+    'navbar': {'title': 'text', 'title_link': 'text', 'links': 'links', 'exclude': 'list'},
+    'sidebar': {'title': 'text', 'links': 'links', 'exclude': 'list'},
+    'tabs': {'selected': 'int'},
+    'modal': {'title': 'text', 'trigger': 'text'},
+}
+KIND_NAMES = {'text': 'text', 'int': 'an integer', 'bool': 'true or false', 'object': 'a mapping', 'list': 'a list',
+              'links': "'auto' or a list of links"}
+# Keys compile_components() reads itself rather than keeping as props
+STRUCTURE_KEYS = ('component', 'children', 'footer', 'tabs', 'feature', 'visibility')
+
+@dataclass
+class RenderProblem:
+    """Something in a page body the renderers cannot use as written"""
+    path: str
+    component: str
+    message: str
+
+    def __str__(self) -> str:
+        return f"{self.path}" + (f" ({self.component})" if self.component else '') + f": {self.message}"
+
+@dataclass
+class Tab:
+    label: str
+    children: List['Component'] = field(default_factory=list)
+
+@dataclass
+class Component:
+    """One component of a page body, with its props checked"""
+    type: str
+    props: Dict[str, Any] = field(default_factory=dict)
+    children: List['Component'] = field(default_factory=list)
+    footer: List['Component'] = field(default_factory=list)
+    tabs: List[Tab] = field(default_factory=list)
+    feature: Any = None
+    visibility: Dict[str, Any] = field(default_factory=dict)
+
+    def get(self, name: str, default: Any = None) -> Any:
+        value = self.props.get(name)
+        return default if value is None else value
+
+    def visible_on(self, platform: str) -> bool:
+        if platform in (self.visibility.get('exclude') or []):
+            return False
+        include = self.visibility.get('include') or []
+        return not include or platform in include
+
+def normalize_component(component: Any) -> Optional[Dict[str, Any]]:
+    """Components written as '- primary_button: {...}' become {'component': 'primary_button', ...}"""
+    if not isinstance(component, dict):
+        return None
+    if 'component' in component:
+        return component
+    if len(component) == 1:
+        (name, value), = component.items()
+        if isinstance(value, dict):
+            return dict(value, component=name)
+    return None
+
+def coerce_prop(kind: str, value: Any) -> Any:
+    """value as a prop of the given kind; ValueError when it cannot be one"""
+    if kind == 'text' and isinstance(value, (str, int, float)) and not isinstance(value, bool):
+        return str(value)
+    if kind == 'int' and not isinstance(value, bool):
+        if isinstance(value, int):
+            return value
+        if isinstance(value, float) and value.is_integer():
+            return int(value)
+        if isinstance(value, str):
+            try:
+                return int(value.strip())
+            except ValueError:
+                pass
+    if kind == 'bool' and isinstance(value, bool):
+        return value
+    if kind == 'object' and isinstance(value, dict):
+        return value
+    if kind == 'list' and isinstance(value, list):
+        return value
+    if kind == 'links' and (value == 'auto' or isinstance(value, list)):
+        return value
+    raise ValueError(f"must be {KIND_NAMES[kind]}, got {type(value).__name__} {value!r}")
+
+def prop_problems(component: Dict[str, Any]) -> List[Tuple[str, str]]:
+    """(prop, message) for each prop of a flow component that is not of its declared kind"""
+    kinds = PROP_TYPES.get(str(component.get('component', '')).lower(), {})
+    problems = []
+    for name, kind in kinds.items():
+        if component.get(name) is None:
+            continue
+        try:
+            coerce_prop(kind, component[name])
+        except ValueError as e:
+            problems.append((name, f"'{name}' {str(e)}"))
+    return problems
+
+def compile_components(items: Any, known: FrozenSet[str], path: str = 'body') -> Tuple[List[Component], List[RenderProblem]]:
+    """A page body (or any component list) as Components, and what was wrong with it
+
+    known holds the component types the renderer draws; an unknown component
+    with children still renders them.
+    """
+    components: List[Component] = []
+    problems: List[RenderProblem] = []
+    if items is None:
+        return components, problems
+    if not isinstance(items, list):
+        return components, [RenderProblem(path, '', f"must be a list of components, got {type(items).__name__}")]
+
+    for index, item in enumerate(items):
+        where = f"{path}[{index}]"
+        raw = normalize_component(item)
+        if raw is None:
+            problems.append(RenderProblem(where, '', "is not a component; write '- component: <type>' or '- <type>: {...}'"))
+            continue
+        component_type = str(raw.get('component', '')).lower()
+        component = Component(component_type, feature=raw.get('feature'))
+        if isinstance(raw.get('visibility'), dict):
+            component.visibility = raw['visibility']
+
+        kinds = PROP_TYPES.get(component_type, {})
+        for name, value in raw.items():
+            if name in STRUCTURE_KEYS or value is None:
+                continue
+            if name in kinds:
+                try:
+                    value = coerce_prop(kinds[name], value)
+                except ValueError as e:
+                    problems.append(RenderProblem(where, component_type, f"'{name}' {str(e)}"))
+                    continue
+            component.props[name] = value
+
+        for key in ('children', 'footer'):
+            nested, nested_problems = compile_components(raw.get(key), known, f"{where}.{key}")
+            setattr(component, key, nested)
+            problems.extend(nested_problems)
+        tabs = raw.get('tabs')
+        if tabs is not None and not isinstance(tabs, list):
+            problems.append(RenderProblem(where, component_type, "'tabs' must be a list of {label, children}"))
+        for tab_index, tab in enumerate(tabs if isinstance(tabs, list) else []):
+            if not isinstance(tab, dict):
+                problems.append(RenderProblem(f"{where}.tabs[{tab_index}]", component_type, "a tab must be {label, children}"))
+                continue
+            children, nested_problems = compile_components(tab.get('children'), known, f"{where}.tabs[{tab_index}].children")
+            component.tabs.append(Tab(str(tab.get('label', '')), children))
+            problems.extend(nested_problems)
+
+        if component_type not in known and not (component.children or component.footer or component.tabs):
+            problems.append(RenderProblem(where, component_type, f"unknown component '{component_type}'" if component_type
+                                          else "has no component type"))
+        components.append(component)
+    return components, problems
+
+@dataclass
+class RenderContext:
+    """The page being rendered and the helpers its components use"""
+    path: str
+    lang: str = 'en'
+    messages: Dict[str, str] = field(default_factory=dict)
+    pages: Set[str] = field(default_factory=set)
+    describe: Callable[[str], Dict[str, Any]] = lambda src: {'src': src}
+
+    def asset(self, src: Any, images: bool = False) -> str:
+        """The escaped URL an image or video is served at"""
+        return escape_html(safe_url(self.describe(str(src or ''))['src'], images))
+
+    def route(self, link: Any) -> str:
+        """An escaped, script-free href; '/pricing' becomes '/pricing/' when that page is on the site"""
+        link = '' if link is None else str(link)
+        if link.startswith('/') and not link.startswith('//') and not any(char in link for char in '?#'):
+            url_path = '/' if link.strip('/') == '' else '/' + link.strip('/') + '/'
+            if url_path in self.pages:
+                link = url_path
+        return escape_html(safe_url(link))
+
+    def t(self, text: Any) -> str:
+        """text translated for the site's language, or as it is"""
+        return str(self.messages.get(str(text), text))
+
+    def current(self, link: Any) -> bool:
+        return str(link or '').rstrip('/') == self.path.rstrip('/')
+
+def site_messages(ir, lang: str) -> Dict[str, str]:
+    """The 'i18n: messages' entries for a language, from the flows"""
+    i18n = getattr(ir, 'i18n', None)
+    messages = (i18n.get('messages') if isinstance(i18n, dict) else None) or {}
+    entries = messages.get(lang) if isinstance(messages, dict) else None
+    return {str(key): str(value) for key, value in entries.items()} if isinstance(entries, dict) else {}
@@ -0,0 +1,288 @@
+"""
+FlashFlow response cache - Cached GET responses of the generated API, invalidated by tag
+
+Turned on by a 'response_cache' block in flashflow.json:
+
+    "response_cache": {
+      "backend": "memory",            # or "sqlite": .flashflow/cache/responses.sqlite
+      "ttl_seconds": 60,
+      "max_entries": 1000,
+      "models": {"Product": {"ttl_seconds": 600}, "AuditLog": false}
+    }
+
+Each entry is tagged with the table it was read from. A write to that table
+(through /api/data or the admin database browser) drops every entry with its
+tag, so a list is never served stale after a create, update or delete made
+through the dev server. Writes made around it, such as 'flashflow db query',
+show up once the TTL runs out.
+
+The memory backend lives in one process. The sqlite backend is shared by
+processes, and is what API workers ('flashflow serve --api-workers') use
+whatever the setting, so every worker sees the others' invalidations.
+"""
+
+import json
+import sqlite3
+import threading
+import time
+from collections import OrderedDict
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Any, Dict, List, Optional
+
+BACKENDS = ('memory', 'sqlite')
+DEFAULT_TTL = 60.0
+DEFAULT_MAX_ENTRIES = 1000
+SQLITE_NAME = 'responses.sqlite'
+
+class ResponseCacheError(Exception):
+    """Raised for invalid response_cache settings in flashflow.json"""
+    pass
+
+@dataclass
+class CachedResponse:
+    key: str
+    status: int
+    body: bytes
+    headers: Dict[str, str]
+    tags: List[str]
+    created_at: float
+    expires_at: float
+    hits: int = 0
+
+    def ttl(self, now: Optional[float] = None) -> int:
+        return max(0, int(self.expires_at - (now or time.time())))
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'key': self.key, 'status': self.status, 'size': len(self.body), 'tags': self.tags,
+                'created_at': self.created_at, 'expires_at': self.expires_at, 'ttl': self.ttl(), 'hits': self.hits}
+
+def response_cache_settings(config) -> Optional[Dict[str, Any]]:
+    """Validated settings, or None when the cache is off"""
+    raw = getattr(config, 'response_cache', None)
+    if not raw:
+        return None
+    if not isinstance(raw, dict):
+        raise ResponseCacheError("response_cache must be an object")
+    if raw.get('enabled') is False:
+        return None
+    backend = raw.get('backend', 'memory')
+    if backend not in BACKENDS:
+        raise ResponseCacheError(f"response_cache.backend must be one of {', '.join(BACKENDS)}, got '{backend}'")
+    ttl = _seconds(raw.get('ttl_seconds', DEFAULT_TTL), 'response_cache.ttl_seconds')
+    max_entries = raw.get('max_entries', DEFAULT_MAX_ENTRIES)
+    if isinstance(max_entries, bool) or not isinstance(max_entries, int) or max_entries < 1:
+        raise ResponseCacheError(f"response_cache.max_entries must be a positive whole number, got {max_entries!r}")
+    models = raw.get('models') or {}
+    if not isinstance(models, dict):
+        raise ResponseCacheError("response_cache.models must map model names to false or {\"ttl_seconds\": ...}")
+    model_ttls: Dict[str, Optional[float]] = {}
+    for model, setting in models.items():
+        if setting is False:
+            model_ttls[model] = None
+        elif isinstance(setting, dict):
+            model_ttls[model] = _seconds(setting.get('ttl_seconds', ttl), f"response_cache.models.{model}.ttl_seconds")
+        else:
+            raise ResponseCacheError(f"response_cache.models.{model} must be false or {{\"ttl_seconds\": ...}}")
+    return {'backend': backend, 'ttl_seconds': ttl, 'max_entries': max_entries, 'models': model_ttls}
+
+def _seconds(value: Any, name: str) -> float:
+    if isinstance(value, bool) or not isinstance(value, (int, float)) or value <= 0:
+        raise ResponseCacheError(f"{name} must be a positive number of seconds, got {value!r}")
+    return float(value)
+
+class MemoryStore:
+    """Entries in this process, least recently used evicted first"""
+
+    def __init__(self, max_entries: int):
+        self.max_entries = max_entries
+        self._entries: 'OrderedDict[str, CachedResponse]' = OrderedDict()
+        self._counters = {'hits': 0, 'misses': 0, 'invalidated': 0}
+        self._lock = threading.Lock()
+
+    def get(self, key: str) -> Optional[CachedResponse]:
+        with self._lock:
+            entry = self._entries.get(key)
+            if entry is not None and entry.expires_at <= time.time():
+                del self._entries[key]
+                entry = None
+            if entry is None:
+                self._counters['misses'] += 1
+                return None
+            self._entries.move_to_end(key)
+            entry.hits += 1
+            self._counters['hits'] += 1
+            return entry
+
+    def put(self, entry: CachedResponse):
+        with self._lock:
+            self._entries[entry.key] = entry
+            self._entries.move_to_end(entry.key)
+            while len(self._entries) > self.max_entries:
+                self._entries.popitem(last=False)
+
+    def invalidate(self, tag: str) -> int:
+        with self._lock:
+            keys = [key for key, entry in self._entries.items() if tag in entry.tags]
+            for key in keys:
+                del self._entries[key]
+            self._counters['invalidated'] += len(keys)
+            return len(keys)
+
+    def delete(self, key: str) -> bool:
+        with self._lock:
+            return self._entries.pop(key, None) is not None
+
+    def clear(self) -> int:
+        with self._lock:
+            count = len(self._entries)
+            self._entries.clear()
+            self._counters['invalidated'] += count
+            return count
+
+    def entries(self) -> List[CachedResponse]:
+        now = time.time()
+        with self._lock:
+            return [entry for entry in reversed(self._entries.values()) if entry.expires_at > now]
+
+    def counters(self) -> Dict[str, int]:
+        with self._lock:
+            return dict(self._counters)
+
+class SqliteStore:
+    """Entries in a SQLite file that several processes share"""
+
+    def __init__(self, path: Path, max_entries: int):
+        self.path = Path(path)
+        self.max_entries = max_entries
+        self._local = threading.local()
+        with self._connect() as connection:
+            connection.executescript("""
+                CREATE TABLE IF NOT EXISTS entries (
+                    key TEXT PRIMARY KEY, status INTEGER, body BLOB, headers TEXT, tags TEXT,
+                    created_at REAL, expires_at REAL, hits INTEGER DEFAULT 0, used_at REAL
+                );
+                CREATE TABLE IF NOT EXISTS counters (name TEXT PRIMARY KEY, value INTEGER);
+            """)
+
+    def _connect(self) -> sqlite3.Connection:
+        connection = getattr(self._local, 'connection', None)
+        if connection is None:
+            connection = sqlite3.connect(str(self.path), timeout=5.0, isolation_level=None)
+            connection.execute('PRAGMA journal_mode=WAL')
+            self._local.connection = connection
+        return connection
+
+    def _count(self, connection: sqlite3.Connection, name: str, amount: int = 1):
+        if amount:
+            connection.execute("INSERT INTO counters (name, value) VALUES (?, ?) "
+                               "ON CONFLICT(name) DO UPDATE SET value = value + excluded.value", (name, amount))
+
+    def get(self, key: str) -> Optional[CachedResponse]:
+        connection = self._connect()
+        now = time.time()
+        row = connection.execute("SELECT key, status, body, headers, tags, created_at, expires_at, hits FROM entries "
+                                 "WHERE key = ? AND expires_at > ?", (key, now)).fetchone()
+        if row is None:
+            self._count(connection, 'misses')
+            return None
+        connection.execute("UPDATE entries SET hits = hits + 1, used_at = ? WHERE key = ?", (now, key))
+        self._count(connection, 'hits')
+        entry = _entry(row)
+        entry.hits += 1
+        return entry
+
+    def put(self, entry: CachedResponse):
+        connection = self._connect()
+        connection.execute("INSERT OR REPLACE INTO entries (key, status, body, headers, tags, created_at, expires_at, "
+                           "hits, used_at) VALUES (?, ?, ?, ?, ?, ?, ?, 0, ?)",
+                           (entry.key, entry.status, entry.body, json.dumps(entry.headers), json.dumps(entry.tags),
+                            entry.created_at, entry.expires_at, entry.created_at))
+        connection.execute("DELETE FROM entries WHERE expires_at <= ?", (time.time(),))
+        connection.execute("DELETE FROM entries WHERE key IN (SELECT key FROM entries ORDER BY used_at DESC "
+                           "LIMIT -1 OFFSET ?)", (self.max_entries,))
+
+    def invalidate(self, tag: str) -> int:
+        connection = self._connect()
+        # Tags are stored as a JSON list, so a quoted match cannot hit a longer tag
+        removed = connection.execute("DELETE FROM entries WHERE tags LIKE ? ESCAPE '\\'",
+                                     ('%' + _like_escape(json.dumps(tag)) + '%',)).rowcount
+        self._count(connection, 'invalidated', removed)
+        return removed
+
+    def delete(self, key: str) -> bool:
+        return self._connect().execute("DELETE FROM entries WHERE key = ?", (key,)).rowcount > 0
+
+    def clear(self) -> int:
+        connection = self._connect()
+        removed = connection.execute("DELETE FROM entries").rowcount
+        self._count(connection, 'invalidated', removed)
+        return removed
+
+    def entries(self) -> List[CachedResponse]:
+        rows = self._connect().execute("SELECT key, status, body, headers, tags, created_at, expires_at, hits "
+                                       "FROM entries WHERE expires_at > ? ORDER BY used_at DESC", (time.time(),))
+        return [_entry(row) for row in rows.fetchall()]
+
+    def counters(self) -> Dict[str, int]:
+        counters = {'hits': 0, 'misses': 0, 'invalidated': 0}
+        counters.update(dict(self._connect().execute("SELECT name, value FROM counters").fetchall()))
+        return counters
+
+def _entry(row) -> CachedResponse:
+    key, status, body, headers, tags, created_at, expires_at, hits = row
+    return CachedResponse(key, status, bytes(body), json.loads(headers), json.loads(tags), created_at, expires_at, hits)
+
+def _like_escape(text: str) -> str:
+    return text.replace('\\', '\\\\').replace('%', '\\%').replace('_', '\\_')
+
+class ResponseCache:
+    """TTLs per model on top of a store"""
+
+    def __init__(self, settings: Dict[str, Any], store):
+        self.settings = settings
+        self.store = store
+        self.backend = 'sqlite' if isinstance(store, SqliteStore) else 'memory'
+
+    @classmethod
+    def for_project(cls, project, shared: bool = False) -> Optional['ResponseCache']:
+        """The project's cache, or None when it is off; shared forces the sqlite backend"""
+        settings = response_cache_settings(project.config)
+        if settings is None:
+            return None
+        if shared or settings['backend'] == 'sqlite':
+            return cls(settings, SqliteStore(project.state.path('cache', SQLITE_NAME), settings['max_entries']))
+        return cls(settings, MemoryStore(settings['max_entries']))
+
+    def ttl_for(self, model: str) -> Optional[float]:
+        """Seconds a model's responses are kept, None when the model is not cached"""
+        return self.settings['models'].get(model, self.settings['ttl_seconds'])
+
+    def get(self, key: str) -> Optional[CachedResponse]:
+        return self.store.get(key)
+
+    def put(self, key: str, status: int, body: bytes, headers: Dict[str, str], tags: List[str],
+            ttl: float) -> CachedResponse:
+        now = time.time()
+        entry = CachedResponse(key, status, body, headers, list(tags), now, now + ttl)
+        self.store.put(entry)
+        return entry
+
+    def invalidate(self, tag: str) -> int:
+        return self.store.invalidate(tag)
+
+    def delete(self, key: str) -> bool:
+        return self.store.delete(key)
+
+    def clear(self) -> int:
+        return self.store.clear()
+
+    def entries(self) -> List[CachedResponse]:
+        return self.store.entries()
+
+    def stats(self) -> Dict[str, Any]:
+        counters = self.store.counters()
+        lookups = counters['hits'] + counters['misses']
+        return dict(counters, backend=self.backend, entries=len(self.entries()),
+                    hit_ratio=round(counters['hits'] / lookups, 3) if lookups else None,
+                    ttl_seconds=self.settings['ttl_seconds'], max_entries=self.settings['max_entries'])
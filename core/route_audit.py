@@ -0,0 +1,395 @@
+"""
+FlashFlow route audit - Broken internal links, unreachable pages and unrouted flows
+
+The flows are always read statically: every link target must be a page path
+(model CRUD pages and authentication routes included). A crawl additionally
+follows <a href> links through the running dev server's own pages, starting
+at '/', and requests each one.
+
+The report lists
+
+    broken links     targets with no page (static) or a 4xx/5xx answer (crawl)
+    unreachable      pages no chain of links leads to from an entry page
+    unrouted flows   files whose page has no 'path', or UI with no page at all
+
+Links under /api, /admin and /media are served by the dev server rather than
+the flows, so only a crawl checks them. 401 and 403 answers are protected
+pages, not broken ones.
+"""
+
+import json
+import re
+import urllib.error
+import urllib.request
+from collections import deque
+from dataclasses import dataclass, field
+from datetime import datetime
+from html import escape
+from html.parser import HTMLParser
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+from urllib.parse import urljoin, urlparse
+
+from core.parser.parser import FlowParser
+from core.permissions import PATH_PARAMETER
+
+# Keys whose string value is a navigation target
+LINK_KEYS = ('link', 'href', 'url', 'to', 'redirect', 'redirect_after', 'navigate', 'back')
+HREF_PATTERN = re.compile(r'''href\s*=\s*["'](/[^"']*)["']''')
+TEMPLATE_EXPRESSION = re.compile(r'\{\{.*?\}\}')
+SERVER_PREFIXES = ('/api/', '/admin/', '/media/', '/__')
+UI_KEYS = ('body', 'components', 'screens', 'layout')
+AUTH_ROUTES = {'login': '/login', 'register': '/register'}
+DEFAULT_MAX_PAGES = 500
+CRAWL_TIMEOUT = 10
+
+@dataclass
+class Route:
+    """A page path and where it comes from"""
+    path: str
+    source: str
+    entry: bool = False
+    linked_from: List[str] = field(default_factory=list)
+
+    def __post_init__(self):
+        self._pattern = route_pattern(self.path)
+
+    def matches(self, path: str) -> bool:
+        return bool(self._pattern.match(path))
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'path': self.path, 'source': self.source, 'entry': self.entry,
+                'linked_from': sorted(set(self.linked_from))}
+
+@dataclass
+class Link:
+    """A navigation target found on a page"""
+    href: str
+    found_on: str
+    source: str = ''
+    status: Optional[int] = None
+    reason: str = ''
+
+    def to_dict(self) -> Dict[str, Any]:
+        data = {'href': self.href, 'found_on': self.found_on, 'source': self.source, 'reason': self.reason}
+        if self.status is not None:
+            data['status'] = self.status
+        return data
+
+def route_pattern(path: str):
+    """Regex for a page path; {id} and :id segments match any single segment"""
+    pattern = ''.join(
+        '[^/]+' if PATH_PARAMETER.fullmatch(part) else re.escape(part)
+        for part in re.split(f"({PATH_PARAMETER.pattern})", path.rstrip('/') or '/')
+    )
+    return re.compile(f"^{pattern}/?$")
+
+def normalize_link(value: Any) -> Optional[str]:
+    """The internal path a link points at, or None for external and non-link values"""
+    if not isinstance(value, str):
+        return None
+    value = value.strip()
+    if not value.startswith('/') or value.startswith('//'):
+        return None
+    # Template expressions stand for one dynamic segment, e.g. /todos/{{ todo.id }}
+    value = TEMPLATE_EXPRESSION.sub('x', value)
+    return value.split('#', 1)[0].split('?', 1)[0] or '/'
+
+def find_links(node: Any) -> List[str]:
+    """Internal link targets anywhere inside a parsed flow structure"""
+    links = []
+    if isinstance(node, dict):
+        for key, value in node.items():
+            if key in LINK_KEYS:
+                target = normalize_link(value)
+                if target:
+                    links.append(target)
+            links.extend(find_links(value))
+    elif isinstance(node, list):
+        for item in node:
+            links.extend(find_links(item))
+    elif isinstance(node, str) and 'href' in node:
+        links.extend(filter(None, (normalize_link(href) for href in HREF_PATTERN.findall(node))))
+    return links
+
+@dataclass
+class RouteReport:
+    """Result of one audit"""
+    mode: str
+    routes: List[Route] = field(default_factory=list)
+    broken: List[Link] = field(default_factory=list)
+    unrouted: List[Dict[str, str]] = field(default_factory=list)
+    checked_links: int = 0
+    base_url: Optional[str] = None
+    truncated: bool = False
+
+    def route_for(self, path: str) -> Optional[Route]:
+        # Exact paths win over parameterized ones (/todos/create before /todos/{id})
+        exact = [route for route in self.routes if route.path.rstrip('/') == path.rstrip('/')]
+        if exact:
+            return exact[0]
+        return next((route for route in self.routes if route.matches(path)), None)
+
+    def link(self, from_path: str, target: str) -> Optional[Route]:
+        """Record a link; returns the route it lands on, if any"""
+        route = self.route_for(target)
+        if route is not None and route is not self.route_for(from_path):
+            route.linked_from.append(from_path)
+        return route
+
+    @property
+    def unreachable(self) -> List[Route]:
+        """Pages no chain of links reaches from an entry page"""
+        reached = {route.path for route in self.routes if route.entry}
+        frontier = list(reached)
+        while frontier:
+            current = frontier.pop()
+            for route in self.routes:
+                if route.path in reached:
+                    continue
+                linkers = {self.route_for(path).path for path in route.linked_from if self.route_for(path)}
+                if current in linkers:
+                    reached.add(route.path)
+                    frontier.append(route.path)
+        return [route for route in self.routes if route.path not in reached]
+
+    @property
+    def ok(self) -> bool:
+        return not self.broken
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {
+            'mode': self.mode,
+            'base_url': self.base_url,
+            'generated_at': datetime.now().isoformat(timespec='seconds'),
+            'summary': {
+                'pages': len(self.routes),
+                'links': self.checked_links,
+                'broken': len(self.broken),
+                'unreachable': len(self.unreachable),
+                'unrouted': len(self.unrouted),
+                'truncated': self.truncated
+            },
+            'broken_links': [link.to_dict() for link in self.broken],
+            'unreachable': [route.path for route in self.unreachable],
+            'unrouted_flows': self.unrouted,
+            'routes': [route.to_dict() for route in self.routes]
+        }
+
+def flow_routes(project) -> Tuple[List[Route], Dict[str, List[str]], List[Dict[str, str]]]:
+    """(page routes, links per page path, unrouted flows) from a project's flow files"""
+    parser = FlowParser()
+    routes: Dict[str, Route] = {}
+    links: Dict[str, List[str]] = {}
+    unrouted = []
+    entry_paths = {'/'}
+    declared = set()
+
+    for flow_file in sorted(project.get_flow_files()):
+        try:
+            data = parser.parse_file(flow_file)
+        except ValueError:
+            # Parse errors are reported by 'flashflow build' and /api/diagnostics
+            continue
+        if not isinstance(data, dict):
+            continue
+
+        page = data.get('page')
+        if isinstance(page, dict) and not page.get('path'):
+            unrouted.append({'file': flow_file.name, 'reason': "page has no 'path' (it would replace '/')"})
+            declared.add('/')
+        elif isinstance(page, dict):
+            path = str(page['path'])
+            declared.add(path)
+            if path in routes and routes[path].source != flow_file.name:
+                unrouted.append({'file': flow_file.name, 'reason': f"page path {path} is also defined in {routes[path].source}"})
+            else:
+                routes[path] = Route(path, flow_file.name)
+            links.setdefault(path, []).extend(find_links(page))
+        elif any(key in data for key in UI_KEYS) and 'endpoint' not in data:
+            unrouted.append({'file': flow_file.name, 'reason': "defines UI but no 'page' with a path"})
+
+        auth = data.get('authentication')
+        if isinstance(auth, dict):
+            for key, path in AUTH_ROUTES.items():
+                if key in auth:
+                    routes.setdefault(path, Route(path, f"{flow_file.name} (authentication)", entry=True))
+            entry_paths.update(find_links(auth))
+
+    # Models without pages of their own get generated CRUD pages
+    try:
+        ir = FlowParser().parse_project(project.root_path)
+    except ValueError:
+        ir = None
+    generated = [path for path in (ir.pages if ir else {}) if path not in declared and path not in routes]
+    for path in generated:
+        routes[path] = Route(path, 'generated CRUD page')
+        links.setdefault(path, []).extend(find_links(ir.pages[path]))
+        # The generated list page links to its create, view and edit pages
+        list_path = '/' + path.strip('/').split('/')[0]
+        if list_path != path and list_path in generated:
+            links.setdefault(list_path, []).append(PATH_PARAMETER.sub('x', path))
+
+    result = sorted(routes.values(), key=lambda route: route.path)
+    for route in result:
+        route.entry = route.entry or route.path in entry_paths
+    return result, links, unrouted
+
+def audit_static(project) -> RouteReport:
+    """Check flow links against flow pages without running anything"""
+    routes, links, unrouted = flow_routes(project)
+    report = RouteReport('static', routes=routes, unrouted=unrouted)
+    for page_path, targets in links.items():
+        source = report.route_for(page_path).source if report.route_for(page_path) else ''
+        for target in targets:
+            if target.startswith(SERVER_PREFIXES):
+                continue
+            report.checked_links += 1
+            if report.link(page_path, target) is None:
+                report.broken.append(Link(target, page_path, source, reason='no page has this path'))
+    return report
+
+class _LinkExtractor(HTMLParser):
+    def __init__(self):
+        super().__init__()
+        self.hrefs = []
+
+    def handle_starttag(self, tag, attrs):
+        if tag in ('a', 'area'):
+            href = dict(attrs).get('href')
+            if href:
+                self.hrefs.append(href)
+
+def fetch(url: str) -> Tuple[int, str, str]:
+    """(status, content type, body) for a GET; status 0 and the error as body when unreachable"""
+    request = urllib.request.Request(url, headers={'User-Agent': 'flashflow-route-audit', 'Accept': 'text/html,*/*'})
+    try:
+        with urllib.request.urlopen(request, timeout=CRAWL_TIMEOUT) as response:
+            content_type = response.headers.get('Content-Type', '')
+            body = response.read().decode('utf-8', 'replace') if 'html' in content_type else ''
+            return response.status, content_type, body
+    except urllib.error.HTTPError as e:
+        return e.code, '', ''
+    except (urllib.error.URLError, OSError) as e:
+        return 0, '', str(getattr(e, 'reason', e))
+
+def is_broken(status: int) -> bool:
+    return status == 0 or (status >= 400 and status not in (401, 403))
+
+def audit_crawl(project, base_url: str, max_pages: int = DEFAULT_MAX_PAGES, start: List[str] = None) -> RouteReport:
+    """The static audit plus a crawl of the running dev server's pages"""
+    base_url = base_url.rstrip('/')
+    host = urlparse(base_url).netloc
+    report = audit_static(project)
+    report.mode, report.base_url = 'crawl', base_url
+
+    queue = deque(start or ['/'])
+    responses: Dict[str, Tuple[int, str]] = {}
+    referrers: Dict[str, List[str]] = {}
+
+    while queue and len(responses) < max_pages:
+        path = queue.popleft()
+        if path in responses:
+            continue
+        status, content_type, body = fetch(base_url + path)
+        if status == 0 and not responses:
+            raise ConnectionError(f"Could not reach {base_url}: {body}; is 'flashflow serve' running?")
+        responses[path] = (status, body if status == 0 else '')
+        if status == 0 or status >= 400 or 'html' not in content_type:
+            continue
+
+        extractor = _LinkExtractor()
+        extractor.feed(body)
+        for href in extractor.hrefs:
+            url = urlparse(urljoin(base_url + path, href))
+            if url.scheme not in ('http', 'https') or url.netloc != host:
+                continue
+            target = url.path or '/'
+            report.checked_links += 1
+            referrers.setdefault(target, []).append(path)
+            if target not in responses:
+                queue.append(target)
+
+    report.truncated = bool(queue)
+    for path, (status, detail) in responses.items():
+        if is_broken(status):
+            reason = f"unreachable: {detail}" if status == 0 else f"HTTP {status}"
+            for found_on in dict.fromkeys(referrers.get(path, [])) or ['(start page)']:
+                report.broken.append(Link(path, found_on, 'dev server', status, reason))
+    return report
+
+def write_reports(report: RouteReport, directory: Path) -> Tuple[Path, Path]:
+    """Write routes.json and routes.html; returns both paths"""
+    directory.mkdir(parents=True, exist_ok=True)
+    data = report.to_dict()
+    json_path, html_path = directory / "routes.json", directory / "routes.html"
+    json_path.write_text(json.dumps(data, indent=2))
+    html_path.write_text(render_html(data))
+    return json_path, html_path
+
+def render_html(data: Dict[str, Any]) -> str:
+    """Standalone HTML version of a report"""
+    summary = data['summary']
+    broken = ''.join(
+        f"<tr><td><code>{escape(link['href'])}</code></td><td><code>{escape(link['found_on'])}</code></td>"
+        f"<td>{escape(link['source'])}</td><td>{escape(link['reason'])}</td></tr>"
+        for link in data['broken_links']
+    ) or '<tr><td colspan="4" class="muted">No broken links 🎉</td></tr>'
+    unrouted = ''.join(
+        f"<tr><td>{escape(item['file'])}</td><td>{escape(item['reason'])}</td></tr>" for item in data['unrouted_flows']
+    ) or '<tr><td colspan="2" class="muted">Every flow has a route</td></tr>'
+    unreachable = set(data['unreachable'])
+    routes = ''.join(
+        f"<tr><td><code>{escape(route['path'])}</code></td><td>{escape(route['source'])}</td>"
+        f"<td>{'entry' if route['entry'] else ('⚠️ not linked' if route['path'] in unreachable else len(route['linked_from']))}</td>"
+        "</tr>"
+        for route in data['routes']
+    )
+    target = f" · {escape(data['base_url'])}" if data.get('base_url') else ''
+    return f"""<!DOCTYPE html>
+<html>
+<head>
+    <title>Route Audit - FlashFlow</title>
+    <meta charset="utf-8">
+    <style>
+        body {{ font-family: 'Segoe UI', sans-serif; margin: 0; background: #f8f9fa; }}
+        .header {{ background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 1rem 2rem; }}
+        .container {{ max-width: 1200px; margin: 0 auto; padding: 2rem; }}
+        .panel {{ background: white; padding: 1.5rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); margin-bottom: 1.5rem; }}
+        .stats {{ display: flex; gap: 2rem; }}
+        .stats div {{ font-size: 1.6rem; font-weight: bold; }}
+        .stats small {{ display: block; font-size: 0.8rem; font-weight: normal; color: #6b7280; }}
+        table {{ width: 100%; border-collapse: collapse; font-size: 0.9rem; }}
+        th, td {{ text-align: left; padding: 0.5rem; border-bottom: 1px solid #e5e7eb; }}
+        .muted {{ color: #6b7280; }}
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>🧭 Route Audit</h1>
+        <p>{escape(data['mode'])} audit{target} · {escape(data['generated_at'])}</p>
+    </div>
+    <div class="container">
+        <div class="panel stats">
+            <div>{summary['pages']}<small>pages</small></div>
+            <div>{summary['links']}<small>links checked</small></div>
+            <div>{summary['broken']}<small>broken links</small></div>
+            <div>{summary['unreachable']}<small>unreachable pages</small></div>
+            <div>{summary['unrouted']}<small>unrouted flows</small></div>
+        </div>
+        <div class="panel">
+            <h3>Broken links</h3>
+            <table><thead><tr><th>Link</th><th>Found on</th><th>Source</th><th>Problem</th></tr></thead><tbody>{broken}</tbody></table>
+        </div>
+        <div class="panel">
+            <h3>Unrouted flows</h3>
+            <table><thead><tr><th>Flow</th><th>Problem</th></tr></thead><tbody>{unrouted}</tbody></table>
+        </div>
+        <div class="panel">
+            <h3>Pages</h3>
+            <table><thead><tr><th>Path</th><th>Source</th><th>Linked from</th></tr></thead><tbody>{routes}</tbody></table>
+        </div>
+    </div>
+</body>
+</html>
+"""
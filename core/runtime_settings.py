@@ -0,0 +1,202 @@
+"""
+FlashFlow runtime settings - What /admin/settings changes without restarting the dev server
+
+    theme      {"colors": {"primary": "#2563eb"}}               over the flows' theme colors on every page
+    cors       {"origins": ["http://localhost:3000"], "credentials": true, "max_age": 600}
+               origins is a list, "local" for pages served from this machine, or "*" for any
+    chaos      {"enabled": true}                                 chaos mode; rules stay in /admin/chaos
+    features   {"overrides": {"new_checkout": true}}             flags forced on or off
+
+Theme and CORS values are kept in .flashflow/settings.json. Chaos mode and
+flag overrides stay where /admin/chaos and /admin/features keep them, so
+those pages always agree with this one. settings.json also keeps the
+history of changes to all four sections, oldest first, and at most
+MAX_HISTORY entries:
+
+    {"values": {"theme": {...}, "cors": {...}},
+     "history": [{"ts": "2026-10-16T09:12:03Z", "actor": "admin", "section": "cors",
+                  "old": {...}, "new": {...}}]}
+
+Resetting a section puts its default back: the flows' own theme, CORS for
+pages on this machine (any port of localhost, 127.0.0.1 or [::1]) without
+credentials, chaos mode off and no flag overrides. A page on any other site
+could otherwise read the dev server's answers.
+"""
+
+import json
+import re
+import threading
+from datetime import datetime, timezone
+from pathlib import Path
+from typing import Any, Dict, Iterable, List, Optional
+
+SETTINGS_FILE = 'settings.json'
+SECTIONS = ('theme', 'cors', 'chaos', 'features')
+# Sections whose values settings.json holds; the others only have their history there
+STORED_SECTIONS = ('theme', 'cors')
+DEFAULTS = {
+    'theme': {'colors': {}},
+    'cors': {'origins': 'local', 'credentials': False, 'max_age': None},
+    'chaos': {'enabled': False},
+    'features': {'overrides': {}},
+}
+MAX_HISTORY = 200
+MAX_AGE_LIMIT = 86400
+
+COLOR_NAME = re.compile(r'^[a-z0-9][a-z0-9-]{0,39}$')
+# Values end up inside a <style> element, so only plain color syntax is accepted
+CSS_COLOR = re.compile(r'^(#[0-9a-fA-F]{3,8}|(rgb|rgba|hsl|hsla)\([0-9.,%\s/a-z]{1,60}\)|[a-zA-Z]{3,30})$')
+ORIGIN = re.compile(r'^https?://[A-Za-z0-9.-]+(:\d{1,5})?$')
+LOCAL_ORIGIN = re.compile(r'^https?://(localhost|127\.0\.0\.1|\[::1\])(:\d{1,5})?$', re.IGNORECASE)
+ORIGIN_KEYWORDS = ('*', 'local')
+
+class RuntimeSettingsError(Exception):
+    """Raised for an unknown section or a value it does not accept"""
+    pass
+
+def default(section: str) -> Dict[str, Any]:
+    return json.loads(json.dumps(DEFAULTS[section]))
+
+def _only(section: str, value: Any, keys: Iterable[str]) -> Dict[str, Any]:
+    keys = tuple(keys)
+    if not isinstance(value, dict):
+        raise RuntimeSettingsError(f"'{section}' must be an object with {', '.join(keys)}")
+    unknown = sorted(set(value) - set(keys))
+    if unknown:
+        raise RuntimeSettingsError(f"'{section}' has unknown field(s) {', '.join(unknown)}; use {', '.join(keys)}")
+    return value
+
+def validate(section: str, value: Any, known_flags: Optional[Iterable[str]] = None) -> Dict[str, Any]:
+    """The section's value with defaults filled in; raises RuntimeSettingsError"""
+    if section not in SECTIONS:
+        raise RuntimeSettingsError(f"Unknown settings section '{section}'; use {', '.join(SECTIONS)}")
+    value = _only(section, value, DEFAULTS[section])
+    result = dict(default(section), **value)
+
+    if section == 'theme':
+        colors = result['colors']
+        if not isinstance(colors, dict):
+            raise RuntimeSettingsError("theme.colors must map color names to CSS colors")
+        for name, color in colors.items():
+            if not COLOR_NAME.match(str(name)):
+                raise RuntimeSettingsError(f"Color name '{name}' must be lowercase letters, digits and '-'")
+            if not isinstance(color, str) or not CSS_COLOR.match(color.strip()):
+                raise RuntimeSettingsError(f"Color '{name}' must be a hex, rgb(), hsl() or named CSS color")
+        result['colors'] = {str(name): color.strip() for name, color in sorted(colors.items())}
+
+    elif section == 'cors':
+        origins = result['origins']
+        if origins not in ORIGIN_KEYWORDS:
+            if not isinstance(origins, list) or not all(isinstance(origin, str) and ORIGIN.match(origin) for origin in origins):
+                raise RuntimeSettingsError("cors.origins must be 'local', '*' or a list of origins like http://localhost:3000")
+            result['origins'] = sorted(set(origins))
+        if not isinstance(result['credentials'], bool):
+            raise RuntimeSettingsError("cors.credentials must be true or false")
+        if result['credentials'] and origins == '*':
+            raise RuntimeSettingsError("cors.credentials needs a list of origins; browsers refuse credentials with '*'")
+        max_age = result['max_age']
+        if max_age is not None and (isinstance(max_age, bool) or not isinstance(max_age, int)
+                                    or not 0 <= max_age <= MAX_AGE_LIMIT):
+            raise RuntimeSettingsError(f"cors.max_age must be a number of seconds up to {MAX_AGE_LIMIT}, or null")
+
+    elif section == 'chaos':
+        if not isinstance(result['enabled'], bool):
+            raise RuntimeSettingsError("chaos.enabled must be true or false")
+
+    else:
+        overrides = result['overrides']
+        if not isinstance(overrides, dict) or not all(isinstance(state, bool) for state in overrides.values()):
+            raise RuntimeSettingsError("features.overrides must map flag names to true or false")
+        if known_flags is not None:
+            unknown = sorted(set(overrides) - set(known_flags))
+            if unknown:
+                raise RuntimeSettingsError(f"Unknown feature flag(s) {', '.join(unknown)}")
+        result['overrides'] = dict(sorted(overrides.items()))
+    return result
+
+def cors_allows(cors: Dict[str, Any], origin: Optional[str]) -> bool:
+    origins = cors.get('origins')
+    if not origin:
+        return False
+    if origins in ORIGIN_KEYWORDS:
+        return origins == '*' or bool(LOCAL_ORIGIN.match(origin))
+    return origin in (origins or [])
+
+def theme_style(colors: Dict[str, str]) -> str:
+    """A <style> setting the --color-<name> variables the pages' stylesheets use"""
+    variables = ' '.join(f"--color-{name}: {value};" for name, value in sorted(colors.items()))
+    return f'<style id="flashflow-settings-theme">:root {{ {variables} }}</style>'
+
+class RuntimeSettings:
+    """The theme and CORS values set from /admin/settings, and every section's change history"""
+
+    def __init__(self, path: Path):
+        self.path = Path(path)
+        self._lock = threading.Lock()
+        # Why settings.json could not be read; it is then treated as empty and rewritten by the next change
+        self.error: Optional[str] = None
+        self._values, self._history = self._load()
+
+    @classmethod
+    def for_project(cls, project) -> 'RuntimeSettings':
+        return cls(project.state.path(SETTINGS_FILE))
+
+    def _load(self):
+        if not self.path.exists():
+            return {}, []
+        try:
+            data = json.loads(self.path.read_text(encoding='utf-8'))
+            values = {section: validate(section, value) for section, value in (data.get('values') or {}).items()
+                      if section in STORED_SECTIONS}
+            history = [entry for entry in data.get('history') or [] if isinstance(entry, dict)]
+        except (OSError, ValueError, AttributeError, RuntimeSettingsError) as e:
+            self.error = f"{SETTINGS_FILE} cannot be read: {e}"
+            return {}, []
+        return values, history[-MAX_HISTORY:]
+
+    def _save(self):
+        self.path.parent.mkdir(parents=True, exist_ok=True)
+        temporary = self.path.with_suffix('.tmp')
+        temporary.write_text(json.dumps({'values': dict(sorted(self._values.items())), 'history': self._history},
+                                        indent=2, ensure_ascii=False), encoding='utf-8')
+        temporary.replace(self.path)
+        self.error = None
+
+    def stored(self, section: str) -> Optional[Dict[str, Any]]:
+        """The value settings.json has for a stored section, None when it has the default"""
+        with self._lock:
+            value = self._values.get(section)
+            return json.loads(json.dumps(value)) if value is not None else None
+
+    def value(self, section: str) -> Dict[str, Any]:
+        stored = self.stored(section)
+        return stored if stored is not None else default(section)
+
+    def record(self, section: str, old: Any, new: Any, actor: str):
+        """Add a change of chaos mode or flag overrides, which are kept elsewhere, to the history"""
+        with self._lock:
+            self._append(section, old, new, actor)
+            self._save()
+
+    def _append(self, section: str, old: Any, new: Any, actor: str):
+        self._history.append({'ts': datetime.now(timezone.utc).strftime('%Y-%m-%dT%H:%M:%SZ'), 'actor': actor,
+                              'section': section, 'old': old, 'new': new})
+        del self._history[:-MAX_HISTORY]
+
+    def set(self, section: str, value: Dict[str, Any], actor: str) -> Dict[str, Any]:
+        """Store a theme or CORS value checked by validate(), the default removing it; returns the old value"""
+        with self._lock:
+            old = self._values.get(section, default(section))
+            if value == default(section):
+                self._values.pop(section, None)
+            else:
+                self._values[section] = value
+            self._append(section, old, value, actor)
+            self._save()
+        return old
+
+    def history(self, section: Optional[str] = None, limit: int = 50) -> List[Dict[str, Any]]:
+        """Changes, newest first"""
+        with self._lock:
+            entries = [entry for entry in reversed(self._history) if section is None or entry.get('section') == section]
+        return entries[:limit]
@@ -0,0 +1,191 @@
+"""
+FlashFlow database sandboxes - Throwaway copies of the dev database
+
+A sandbox is a copy of the SQLite dev database, taken with SQLite's backup
+API so it is consistent even while the server writes. Requests made in a
+sandbox (see cli/devserver/api_tester.py) read and write the copy, so
+destructive testing leaves the shared seed data alone. A sandbox expires
+after it has not been used for a while and its copy is deleted.
+
+Copies live in .flashflow/sandboxes/<id>.sqlite next to <id>.json with the
+sandbox's times, so every process of the dev server (API workers too) sees
+the same sandboxes. Settings in flashflow.json:
+
+    "sandboxes": {"ttl_minutes": 30, "max": 20}
+
+Sandboxes copy SQLite databases only; with Postgres or MySQL configured
+they are refused.
+"""
+
+import json
+import re
+import secrets
+import sqlite3
+import threading
+import time
+from dataclasses import asdict, dataclass
+from pathlib import Path
+from typing import Any, Dict, List, Optional
+
+from core.database import DatabaseConfig, Storage, StorageError, create_storage
+
+DEFAULT_TTL_MINUTES = 30
+DEFAULT_MAX_SANDBOXES = 20
+SANDBOX_ID = re.compile(r'^sbx_[0-9a-f]{16}$')
+# The request header naming the sandbox a request runs in
+SANDBOX_HEADER = 'X-FlashFlow-Sandbox'
+# Writing last_used on every request would be a file write per request
+TOUCH_INTERVAL = 15
+
+class SandboxError(Exception):
+    """Raised when a sandbox cannot be created or does not exist"""
+    pass
+
+@dataclass
+class Sandbox:
+    """One copy of the dev database"""
+    id: str
+    created_at: float
+    last_used: float
+    ttl: float
+    label: str = ''
+
+    @property
+    def expires_at(self) -> float:
+        return self.last_used + self.ttl
+
+    def expired(self, now: Optional[float] = None) -> bool:
+        return (now or time.time()) >= self.expires_at
+
+    def to_dict(self) -> Dict[str, Any]:
+        return dict(asdict(self), expires_at=self.expires_at)
+
+class SandboxStore:
+    """Creates, finds and discards the sandboxes of a project"""
+
+    def __init__(self, project, directory: Optional[Path] = None):
+        self.project = project
+        self.directory = Path(directory or project.state.path('sandboxes'))
+        settings = getattr(project.config, 'sandboxes', None) or {}
+        try:
+            self.ttl = max(1.0, float(settings.get('ttl_minutes', DEFAULT_TTL_MINUTES))) * 60
+            self.max_sandboxes = max(1, int(settings.get('max', DEFAULT_MAX_SANDBOXES)))
+        except (TypeError, ValueError):
+            raise SandboxError("sandboxes.ttl_minutes and sandboxes.max in flashflow.json must be numbers")
+        self._storages: Dict[str, Storage] = {}
+        self._lock = threading.Lock()
+
+    def source_path(self) -> Path:
+        """The dev database sandboxes copy"""
+        try:
+            storage = create_storage(self.project)
+        except StorageError as e:
+            raise SandboxError(str(e))
+        if storage.driver != 'sqlite':
+            raise SandboxError(f"Sandboxes copy SQLite databases; this project uses {storage.driver}")
+        return storage.sqlite_path()
+
+    def _meta_path(self, sandbox_id: str) -> Path:
+        return self.directory / f"{sandbox_id}.json"
+
+    def database_path(self, sandbox_id: str) -> Path:
+        return self.directory / f"{sandbox_id}.sqlite"
+
+    def create(self, label: str = '') -> Sandbox:
+        source_path = self.source_path()
+        self.expire()
+        if len(self.list()) >= self.max_sandboxes:
+            raise SandboxError(f"{self.max_sandboxes} sandboxes are open already (sandboxes.max); discard one first")
+        now = time.time()
+        sandbox = Sandbox('sbx_' + secrets.token_hex(8), now, now, self.ttl, str(label)[:80])
+        self.directory.mkdir(parents=True, exist_ok=True)
+        # Recorded first, so another process expiring sandboxes does not take the copy for a leftover
+        self._write(sandbox)
+        target = self.database_path(sandbox.id)
+        try:
+            destination = sqlite3.connect(str(target))
+            try:
+                if source_path.exists():
+                    source = sqlite3.connect(str(source_path))
+                    try:
+                        source.backup(destination)
+                    finally:
+                        source.close()
+            finally:
+                destination.close()
+        except sqlite3.Error as e:
+            self.discard(sandbox.id)
+            raise SandboxError(f"Cannot copy {source_path.name}: {str(e)}")
+        return sandbox
+
+    def get(self, sandbox_id: str) -> Optional[Sandbox]:
+        """The sandbox, if it exists and has not expired; expired ones are discarded"""
+        if not SANDBOX_ID.match(str(sandbox_id)):
+            return None
+        try:
+            sandbox = Sandbox(**json.loads(self._meta_path(sandbox_id).read_text(encoding='utf-8')))
+        except (OSError, ValueError, TypeError):
+            return None
+        if sandbox.expired():
+            self.discard(sandbox_id)
+            return None
+        return sandbox
+
+    def touch(self, sandbox: Sandbox) -> Sandbox:
+        """Push the expiry back; sandboxes expire when they stop being used"""
+        now = time.time()
+        if now - sandbox.last_used >= TOUCH_INTERVAL:
+            sandbox.last_used = now
+            self._write(sandbox)
+        return sandbox
+
+    def storage(self, sandbox: Sandbox) -> Storage:
+        """Storage on the sandbox's copy, one per sandbox and process"""
+        with self._lock:
+            if sandbox.id not in self._storages:
+                config = DatabaseConfig(driver='sqlite', dsn=str(self.database_path(sandbox.id)))
+                self._storages[sandbox.id] = Storage(config, self.project.root_path)
+            return self._storages[sandbox.id]
+
+    def list(self) -> List[Sandbox]:
+        sandboxes = []
+        for path in sorted(self.directory.glob('sbx_*.json')) if self.directory.exists() else []:
+            sandbox = self.get(path.stem)
+            if sandbox:
+                sandboxes.append(sandbox)
+        return sorted(sandboxes, key=lambda sandbox: sandbox.created_at)
+
+    def discard(self, sandbox_id: str) -> bool:
+        if not SANDBOX_ID.match(str(sandbox_id)):
+            return False
+        with self._lock:
+            storage = self._storages.pop(sandbox_id, None)
+        if storage:
+            storage.close()
+        existed = self._meta_path(sandbox_id).exists()
+        for path in (self._meta_path(sandbox_id), self.database_path(sandbox_id)):
+            path.unlink(missing_ok=True)
+        for suffix in ('-journal', '-wal', '-shm'):
+            path = self.database_path(sandbox_id)
+            path.with_name(path.name + suffix).unlink(missing_ok=True)
+        return existed
+
+    def expire(self) -> List[str]:
+        """Discard expired sandboxes, and copies left without their .json; returns their ids"""
+        discarded = []
+        if not self.directory.exists():
+            return discarded
+        for path in self.directory.glob('sbx_*.*'):
+            sandbox_id = path.name.split('.', 1)[0]
+            if sandbox_id in discarded or not SANDBOX_ID.match(sandbox_id):
+                continue
+            if self.get(sandbox_id) is None:
+                self.discard(sandbox_id)
+                discarded.append(sandbox_id)
+        return discarded
+
+    def _write(self, sandbox: Sandbox):
+        path = self._meta_path(sandbox.id)
+        temporary = path.with_name(path.name + '.tmp')
+        temporary.write_text(json.dumps(asdict(sandbox)), encoding='utf-8')
+        temporary.replace(path)
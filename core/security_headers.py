@@ -0,0 +1,223 @@
+"""
+FlashFlow security headers - Content-Security-Policy and friends for the dev server
+
+    "security_headers": {
+        "csp": "report-only",                 # report-only, enforce or off
+        "directives": {                       # merged over DEFAULT_DIRECTIVES; null drops one
+            "script-src": ["'self'", "https://cdn.example.com"]
+        },
+        "frame_options": "SAMEORIGIN",        # DENY, SAMEORIGIN or false
+        "referrer_policy": "strict-origin-when-cross-origin",
+        "hsts_max_age": 86400                 # only sent over HTTPS (e.g. --share); 0 disables
+    }
+
+The default policy only allows same-origin scripts, so inline scripts, eval
+and plain http:// resources (mixed content once the app is served over
+HTTPS) show up as violations. In report-only mode nothing is blocked: the
+browser posts violations to /api/csp-report and they are collected here.
+"""
+
+import base64
+import hashlib
+import re
+import threading
+import time
+from typing import Dict, Any, List, Optional, Tuple
+from urllib.parse import urlparse
+
+CSP_MODES = ['report-only', 'enforce', 'off']
+DEFAULT_MODE = 'report-only'
+FRAME_OPTIONS = ['DENY', 'SAMEORIGIN']
+REPORT_PATH = '/api/csp-report'
+REPORT_GROUP = 'flashflow-csp'
+DEFAULT_HSTS_MAX_AGE = 86400
+# Distinct violations kept; repeats only bump a counter
+MAX_VIOLATIONS = 200
+
+DEFAULT_DIRECTIVES: Dict[str, List[str]] = {
+    'default-src': ["'self'"],
+    'script-src': ["'self'"],
+    'style-src': ["'self'", "'unsafe-inline'"],
+    'img-src': ["'self'", 'data:', 'blob:', 'https:'],
+    'font-src': ["'self'", 'data:', 'https:'],
+    'connect-src': ["'self'"],
+    'media-src': ["'self'", 'blob:'],
+    'object-src': ["'none'"],
+    'base-uri': ["'self'"],
+    'form-action': ["'self'"],
+    'frame-ancestors': ["'self'"],
+}
+
+INLINE_SCRIPT = re.compile(r'<script>(.*?)</script>', re.S | re.I)
+SCRIPT_SRC = re.compile(r'<script[^>]*\bsrc="([^"]+)"', re.I)
+
+class SecurityHeadersError(Exception):
+    """Raised for invalid security_headers settings in flashflow.json"""
+    pass
+
+class SecurityHeadersConfig:
+    """Headers the dev server adds, from flashflow.json and 'serve --csp'"""
+
+    def __init__(self, mode: str = DEFAULT_MODE, directives: Optional[Dict[str, Any]] = None,
+                 frame_options: Optional[str] = 'SAMEORIGIN',
+                 referrer_policy: Optional[str] = 'strict-origin-when-cross-origin',
+                 hsts_max_age: int = DEFAULT_HSTS_MAX_AGE):
+        if mode not in CSP_MODES:
+            raise SecurityHeadersError(f"security_headers.csp must be one of {', '.join(CSP_MODES)}, got '{mode}'")
+        if frame_options is not None and frame_options not in FRAME_OPTIONS:
+            raise SecurityHeadersError(f"security_headers.frame_options must be DENY, SAMEORIGIN or false, got '{frame_options}'")
+        if not isinstance(hsts_max_age, int) or hsts_max_age < 0:
+            raise SecurityHeadersError("security_headers.hsts_max_age must be a non-negative number of seconds")
+        self.mode = mode
+        self.directives = merge_directives(DEFAULT_DIRECTIVES, directives or {})
+        self.frame_options = frame_options
+        self.referrer_policy = referrer_policy
+        self.hsts_max_age = hsts_max_age
+
+    @classmethod
+    def for_project(cls, project, mode: Optional[str] = None) -> 'SecurityHeadersConfig':
+        settings = project.config.security_headers or {}
+        if not isinstance(settings, dict):
+            raise SecurityHeadersError("\"security_headers\" in flashflow.json must be an object")
+        frame_options = settings.get('frame_options', 'SAMEORIGIN')
+        referrer_policy = settings.get('referrer_policy', 'strict-origin-when-cross-origin')
+        return cls(
+            mode=str(mode or settings.get('csp', DEFAULT_MODE)).lower(),
+            directives=settings.get('directives'),
+            frame_options=str(frame_options).upper() if frame_options else None,
+            referrer_policy=referrer_policy or None,
+            hsts_max_age=settings.get('hsts_max_age', DEFAULT_HSTS_MAX_AGE)
+        )
+
+    def policy(self, trusted_sources: Optional[List[str]] = None) -> str:
+        """The policy string, with the dev server's own scripts allowed"""
+        directives = {name: list(values) for name, values in self.directives.items()}
+        if trusted_sources and 'script-src' in directives:
+            directives['script-src'] += [source for source in trusted_sources if source not in directives['script-src']]
+        directives['report-uri'] = [REPORT_PATH]
+        directives['report-to'] = [REPORT_GROUP]
+        return '; '.join(f"{name} {' '.join(values)}".strip() for name, values in directives.items())
+
+    def csp_header(self) -> Optional[str]:
+        if self.mode == 'off':
+            return None
+        return 'Content-Security-Policy-Report-Only' if self.mode == 'report-only' else 'Content-Security-Policy'
+
+    def headers(self, secure: bool) -> Dict[str, str]:
+        """Headers for every response; CSP is added separately for pages"""
+        headers = {'X-Content-Type-Options': 'nosniff'}
+        if self.frame_options:
+            headers['X-Frame-Options'] = self.frame_options
+        if self.referrer_policy:
+            headers['Referrer-Policy'] = self.referrer_policy
+        # Browsers ignore HSTS over plain HTTP, and pinning localhost to HTTPS would break every other dev server
+        if secure and self.hsts_max_age:
+            headers['Strict-Transport-Security'] = f"max-age={self.hsts_max_age}"
+        return headers
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'csp': self.mode, 'policy': self.policy(), 'frame_options': self.frame_options,
+                'referrer_policy': self.referrer_policy, 'hsts_max_age': self.hsts_max_age}
+
+def merge_directives(defaults: Dict[str, List[str]], overrides: Dict[str, Any]) -> Dict[str, List[str]]:
+    if not isinstance(overrides, dict):
+        raise SecurityHeadersError("security_headers.directives must be an object of directive → sources")
+    merged = {name: list(values) for name, values in defaults.items()}
+    for name, value in overrides.items():
+        if value is None:
+            merged.pop(name, None)
+        elif isinstance(value, str):
+            merged[name] = value.split()
+        elif isinstance(value, list) and all(isinstance(source, str) for source in value):
+            merged[name] = list(value)
+        else:
+            raise SecurityHeadersError(f"security_headers.directives.{name} must be a string, a list of sources or null")
+    return merged
+
+def trusted_script_sources(snippets: List[str]) -> List[str]:
+    """Hashes of the inline scripts and origins of the external ones in HTML the dev server injects"""
+    sources = []
+    for snippet in snippets:
+        for body in INLINE_SCRIPT.findall(snippet):
+            digest = base64.b64encode(hashlib.sha256(body.encode('utf-8')).digest()).decode('ascii')
+            sources.append(f"'sha256-{digest}'")
+        for src in SCRIPT_SRC.findall(snippet):
+            parsed = urlparse(src)
+            if parsed.scheme and parsed.netloc:
+                sources.append(f"{parsed.scheme}://{parsed.netloc}")
+    return sources
+
+def parse_reports(payload: Any) -> List[Dict[str, Any]]:
+    """Violations from a report-uri body ({"csp-report": {...}}) or a Reporting API batch"""
+    if isinstance(payload, dict) and isinstance(payload.get('csp-report'), dict):
+        report = payload['csp-report']
+        return [_violation(report.get('document-uri'), report.get('effective-directive') or report.get('violated-directive'),
+                           report.get('blocked-uri'), report.get('source-file'), report.get('line-number'),
+                           report.get('script-sample'), report.get('disposition'))]
+    if isinstance(payload, list):
+        violations = []
+        for entry in payload:
+            if not isinstance(entry, dict) or entry.get('type') != 'csp-violation' or not isinstance(entry.get('body'), dict):
+                continue
+            body = entry['body']
+            violations.append(_violation(body.get('documentURL'), body.get('effectiveDirective'), body.get('blockedURL'),
+                                         body.get('sourceFile'), body.get('lineNumber'), body.get('sample'),
+                                         body.get('disposition')))
+        return violations
+    return []
+
+def _violation(document, directive, blocked, source, line, sample, disposition) -> Dict[str, Any]:
+    directive = (directive or 'unknown').split()[0]
+    return {
+        'page': urlparse(document).path if document else None,
+        'directive': directive,
+        'blocked': blocked or 'inline',
+        'source': source,
+        'line': line,
+        'sample': (sample or '')[:80],
+        'disposition': disposition or 'report',
+        'mixed_content': bool(blocked and str(blocked).startswith('http:'))
+    }
+
+class CspReport:
+    """Distinct CSP violations seen during this dev server session"""
+
+    def __init__(self):
+        self._lock = threading.Lock()
+        self._violations: Dict[Tuple, Dict[str, Any]] = {}
+        self.dropped = 0
+
+    def record(self, violation: Dict[str, Any]) -> bool:
+        """Store a violation; returns True the first time it is seen"""
+        key = (violation['page'], violation['directive'], violation['blocked'], violation['source'], violation['line'])
+        now = time.time()
+        with self._lock:
+            existing = self._violations.get(key)
+            if existing:
+                existing['count'] += 1
+                existing['last_seen'] = now
+                return False
+            if len(self._violations) >= MAX_VIOLATIONS:
+                self.dropped += 1
+                return False
+            self._violations[key] = dict(violation, count=1, first_seen=now, last_seen=now)
+            return True
+
+    def clear(self):
+        with self._lock:
+            self._violations.clear()
+            self.dropped = 0
+
+    def to_dict(self) -> Dict[str, Any]:
+        with self._lock:
+            violations = sorted(self._violations.values(), key=lambda v: v['last_seen'], reverse=True)
+            by_directive: Dict[str, int] = {}
+            for violation in violations:
+                by_directive[violation['directive']] = by_directive.get(violation['directive'], 0) + violation['count']
+            return {
+                'violations': violations,
+                'total': sum(v['count'] for v in violations),
+                'by_directive': by_directive,
+                'mixed_content': sum(1 for v in violations if v['mixed_content']),
+                'dropped': self.dropped
+            }
@@ -0,0 +1,137 @@
+"""
+FlashFlow service info - The /__info endpoint every FlashFlow service answers
+
+    GET /__info
+    {
+      "service": "dev-server",
+      "version": "0.1.0",
+      "commit": "1a2b3c4",
+      "started_at": 1760000000.0,
+      "uptime_seconds": 42.1,
+      "pid": 4242,
+      "config": {"project": "/home/me/shop", "port": 8000, "profile": "development", ...},
+      "capabilities": ["api-workers", "transforms", ...],
+      "info_version": 1
+    }
+
+'commit' is FLASHFLOW_COMMIT when set (packaged builds), else the HEAD of
+the FlashFlow checkout, else null. 'config' summarizes settings and never
+holds secrets. Services that cannot add a route to the server they run on
+(the Flet engine) serve /__info from an InfoServer on a port of their own,
+and name it as 'info_url' in their runtime record.
+
+The CLI asks /__info who is listening instead of assuming that whatever
+answers on a port is this project's server.
+"""
+
+import json
+import os
+import subprocess
+import threading
+import time
+import urllib.error
+import urllib.request
+from dataclasses import dataclass, field
+from functools import lru_cache
+from http.server import BaseHTTPRequestHandler, ThreadingHTTPServer
+from pathlib import Path
+from typing import Any, Callable, Dict, List, Optional
+
+INFO_PATH = '/__info'
+INFO_VERSION = 1
+INFO_TIMEOUT = 1.0
+CHECKOUT_ROOT = Path(__file__).parent.parent
+
+@lru_cache(maxsize=1)
+def source_commit() -> Optional[str]:
+    """The commit FlashFlow was built from, when it can be told"""
+    if os.environ.get('FLASHFLOW_COMMIT'):
+        return os.environ['FLASHFLOW_COMMIT']
+    try:
+        result = subprocess.run(['git', '-C', str(CHECKOUT_ROOT), 'rev-parse', '--short', 'HEAD'], stdout=subprocess.PIPE,
+                                stderr=subprocess.DEVNULL, text=True, timeout=2, env=dict(os.environ, GIT_TERMINAL_PROMPT='0'))
+    except (OSError, subprocess.TimeoutExpired):
+        return None
+    if result.returncode != 0:
+        return None
+    return result.stdout.strip() or None
+
+@dataclass
+class ServiceInfo:
+    """What a running service says about itself"""
+    service: str
+    version: str
+    config: Dict[str, Any] = field(default_factory=dict)
+    capabilities: List[str] = field(default_factory=list)
+    started_at: float = field(default_factory=time.time)
+    pid: int = field(default_factory=os.getpid)
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {
+            'service': self.service, 'version': self.version, 'commit': source_commit(),
+            'started_at': self.started_at, 'uptime_seconds': round(time.time() - self.started_at, 1), 'pid': self.pid,
+            'config': self.config, 'capabilities': sorted(set(self.capabilities)), 'info_version': INFO_VERSION,
+        }
+
+class InfoServer:
+    """/__info on a port of its own, for services whose own server takes no extra routes"""
+
+    def __init__(self, describe: Callable[[], ServiceInfo], host: str = '127.0.0.1', port: int = 0):
+        self.describe = describe
+        self.host = host
+        self.port = port
+        self._server: Optional[ThreadingHTTPServer] = None
+
+    @property
+    def url(self) -> str:
+        return f"http://{self.host}:{self.port}"
+
+    def start(self) -> 'InfoServer':
+        describe = self.describe
+
+        class Handler(BaseHTTPRequestHandler):
+            def do_GET(self):
+                if self.path.split('?')[0] != INFO_PATH:
+                    self.send_error(404)
+                    return
+                body = json.dumps(describe().to_dict()).encode('utf-8')
+                self.send_response(200)
+                self.send_header('Content-Type', 'application/json')
+                self.send_header('Content-Length', str(len(body)))
+                self.end_headers()
+                self.wfile.write(body)
+
+            def log_message(self, format, *args):
+                pass
+
+        self._server = ThreadingHTTPServer((self.host, self.port), Handler)
+        self._server.daemon_threads = True
+        self.port = self._server.server_port
+        threading.Thread(target=self._server.serve_forever, name='flashflow-info', daemon=True).start()
+        return self
+
+    def stop(self):
+        server, self._server = self._server, None
+        if server is not None:
+            server.shutdown()
+            server.server_close()
+
+def fetch_info(base_url: str, timeout: float = INFO_TIMEOUT) -> Optional[Dict[str, Any]]:
+    """A service's /__info, or None when nothing answers or it is not a FlashFlow service"""
+    try:
+        with urllib.request.urlopen(base_url.rstrip('/') + INFO_PATH, timeout=timeout) as response:
+            info = json.loads(response.read().decode('utf-8'))
+    except (OSError, ValueError):
+        # URLError and timeouts are OSErrors; ValueError covers a malformed URL or body
+        return None
+    return info if isinstance(info, dict) and isinstance(info.get('service'), str) else None
+
+def same_project(info: Optional[Dict[str, Any]], project_root: Path) -> bool:
+    """Whether a service's info says it serves the project at project_root"""
+    served = ((info or {}).get('config') or {}).get('project')
+    if not served:
+        return False
+    try:
+        return Path(served).resolve() == Path(project_root).resolve()
+    except OSError:
+        return False
@@ -0,0 +1,163 @@
+"""
+FlashFlow settings - Hosts, ports, the profile and file watching, resolved the same way by every command
+
+Each setting takes the first value it finds, in this order:
+
+    1. a command-line flag           flashflow serve --port 8001
+    2. an environment variable       FLASHFLOW_PORT=8001
+    3. flashflow.json                "serve": {"port": 8001}
+    4. the default                   8000
+
+    setting        flag         environment             flashflow.json      default
+    env            --env        FLASHFLOW_ENV           default_profile     development
+    serve.host     --host       FLASHFLOW_HOST          serve.host          localhost
+    serve.port     --port       FLASHFLOW_PORT          serve.port          8000
+    engine.port                 FLASHFLOW_ENGINE_PORT   engine.port         8012
+    preview.port   --port       FLASHFLOW_PREVIEW_PORT  preview.port        8010
+    watch.poll     --poll       FLASHFLOW_WATCH_POLL    watch.poll          auto (see core/file_watch.py)
+
+Values are checked wherever they come from, and a bad one names its source
+("FLASHFLOW_PORT='80OO' is not a port (1-65535)"). 'flashflow config show
+--resolved' prints every setting with the value it resolved to and where
+that came from.
+"""
+
+import os
+import re
+from dataclasses import dataclass
+from typing import Any, Dict, List, Mapping, Optional, Tuple
+
+from core.file_watch import parse_poll
+
+HOST = re.compile(r'^[A-Za-z0-9._:\[\]-]+$')
+
+class SettingsError(ValueError):
+    """Raised when a setting has a value of the wrong kind"""
+    pass
+
+@dataclass(frozen=True)
+class SettingSpec:
+    """A setting, where it is read from and what it must look like"""
+    key: str
+    kind: str
+    default: Any
+    env: Optional[str]
+    description: str
+    # Where it lives in flashflow.json, when not at its own key
+    config_path: Optional[Tuple[str, ...]] = None
+
+    @property
+    def path(self) -> Tuple[str, ...]:
+        return self.config_path or tuple(self.key.split('.'))
+
+SETTINGS: List[SettingSpec] = [
+    SettingSpec('env', 'string', 'development', 'FLASHFLOW_ENV', 'Environment profile commands run with',
+                ('default_profile',)),
+    SettingSpec('serve.host', 'host', 'localhost', 'FLASHFLOW_HOST', "Address 'flashflow serve' listens on"),
+    SettingSpec('serve.port', 'port', 8000, 'FLASHFLOW_PORT', "Port of 'flashflow serve'"),
+    SettingSpec('engine.port', 'port', 8012, 'FLASHFLOW_ENGINE_PORT', 'Port of the FlashFlow Engine (Flet renderer)'),
+    SettingSpec('preview.port', 'port', 8010, 'FLASHFLOW_PREVIEW_PORT', "Port of 'flashflow preview'"),
+    SettingSpec('watch.poll', 'poll', 'auto', 'FLASHFLOW_WATCH_POLL',
+                "File watching: 'auto', 'off' or a polling interval in seconds"),
+]
+SPECS: Dict[str, SettingSpec] = {spec.key: spec for spec in SETTINGS}
+
+@dataclass
+class ResolvedSetting:
+    spec: SettingSpec
+    value: Any
+    source: str
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'key': self.spec.key, 'value': self.value, 'source': self.source, 'env': self.spec.env,
+                'config': '.'.join(self.spec.path), 'default': self.spec.default, 'description': self.spec.description}
+
+class Settings:
+    """Resolved settings with typed accessors"""
+
+    def __init__(self, resolved: Dict[str, ResolvedSetting]):
+        self.resolved = resolved
+
+    def _setting(self, key: str) -> ResolvedSetting:
+        if key not in self.resolved:
+            raise KeyError(f"Unknown setting '{key}'")
+        return self.resolved[key]
+
+    def get(self, key: str) -> Any:
+        return self._setting(key).value
+
+    def source(self, key: str) -> str:
+        return self._setting(key).source
+
+    def port(self, key: str) -> int:
+        return int(self.get(key))
+
+    def host(self, key: str) -> str:
+        return str(self.get(key))
+
+    def string(self, key: str) -> str:
+        return str(self.get(key))
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {key: setting.to_dict() for key, setting in self.resolved.items()}
+
+def parse_value(spec: SettingSpec, raw: Any, origin: str) -> Any:
+    """raw as the setting's kind; origin names where it came from for the error"""
+    if spec.kind == 'port':
+        text = str(raw).strip() if not isinstance(raw, bool) else ''
+        if not re.fullmatch(r'\d{1,5}', text) or not 1 <= int(text) <= 65535:
+            raise SettingsError(f"{origin}={raw!r} is not a port (1-65535)")
+        return int(text)
+    if spec.kind == 'poll':
+        try:
+            return parse_poll(raw)
+        except ValueError as e:
+            raise SettingsError(f"{origin}={raw!r} {str(e)}")
+    text = str(raw).strip() if isinstance(raw, (str, int)) and not isinstance(raw, bool) else ''
+    if spec.kind == 'host':
+        # A bare name or address: 'localhost:8000' or 'http://localhost' belong in other settings
+        if not HOST.match(text) or text.count(':') == 1:
+            raise SettingsError(f"{origin}={raw!r} is not a host name or address (no scheme or port)")
+        return text
+    if not text:
+        raise SettingsError(f"{origin} is empty")
+    return text
+
+def _config_value(config_data: Mapping[str, Any], path: Tuple[str, ...]) -> Any:
+    value: Any = config_data
+    for part in path:
+        if not isinstance(value, Mapping) or part not in value:
+            return None
+        value = value[part]
+    return value
+
+def resolve_settings(project=None, flags: Optional[Mapping[str, Any]] = None,
+                     environ: Optional[Mapping[str, str]] = None, keys: Optional[List[str]] = None) -> Settings:
+    """Resolve keys (default: every setting); flags maps keys to flag values, None meaning not given"""
+    flags = flags or {}
+    environ = os.environ if environ is None else environ
+    config_data = _project_config(project)
+    resolved = {}
+    for key in keys or list(SPECS):
+        spec = SPECS[key]
+        config_value = _config_value(config_data, spec.path)
+        if flags.get(key) is not None:
+            setting = ResolvedSetting(spec, parse_value(spec, flags[key], f"--{key.split('.')[-1]}"), 'flag')
+        elif spec.env and environ.get(spec.env, '').strip():
+            setting = ResolvedSetting(spec, parse_value(spec, environ[spec.env], spec.env), f"env {spec.env}")
+        elif config_value is not None:
+            config_key = '.'.join(spec.path)
+            setting = ResolvedSetting(spec, parse_value(spec, config_value, f"flashflow.json {config_key}"),
+                                      f"flashflow.json {config_key}")
+        else:
+            setting = ResolvedSetting(spec, spec.default, 'default')
+        resolved[key] = setting
+    return Settings(resolved)
+
+def _project_config(project) -> Dict[str, Any]:
+    if project is None:
+        return {}
+    config = project.config
+    # Sections the dataclass knows, plus the top-level keys settings read
+    return {'default_profile': config.default_profile, 'serve': config.serve or {}, 'engine': config.engine or {},
+            'preview': config.preview or {}, 'watch': config.watch or {}}
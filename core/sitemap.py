@@ -0,0 +1,155 @@
+"""
+FlashFlow sitemap - sitemap.xml and robots.txt from the routed pages
+
+The dev server serves both at /sitemap.xml and /robots.txt, and
+'flashflow build -t static' writes them next to the pages (core/static_site.py).
+Pages tune their entry next to title and path:
+
+    page:
+      path: /pricing
+      title: Pricing
+      sitemap:
+        priority: 0.8           # 0.0 to 1.0
+        changefreq: monthly     # always, hourly, daily, weekly, monthly, yearly or never
+        paths: [/pricing/teams] # URLs to list for a page with route parameters
+      noindex: true             # <meta name="robots" content="noindex"> and no sitemap entry
+
+'sitemap: false' leaves a page out of the sitemap but keeps it crawlable.
+The same keys under 'static' (the static export's own settings) still work;
+the page-level ones win. Pages with route parameters are only listed at
+their 'paths', the 404 page never is, and pages behind a feature flag
+follow the environment's flags.
+
+URLs are absolute, so they need the site's address: the static export
+takes "static_site": {"site_url": ...} from flashflow.json, the dev server
+the address the request came in on. robots.txt allows everything except
+the "static_site": {"disallow": [...]} paths and, in the dev server, its
+own tooling, apart from the page previews and social cards that link
+previews fetch (core/social_cards.py), and names the sitemap when there is one.
+"""
+
+import re
+from dataclasses import dataclass
+from typing import Any, Dict, List, Optional, Tuple
+from xml.sax.saxutils import escape as xml_escape
+
+from core.feature_flags import feature_visible
+
+CHANGEFREQS = ('always', 'hourly', 'daily', 'weekly', 'monthly', 'yearly', 'never')
+ROUTE_PARAMETER = re.compile(r"\{[^}]+\}|:[A-Za-z_]\w*|<[^>]+>")
+SITEMAP_KEYS = ('priority', 'changefreq', 'paths')
+
+@dataclass
+class SitemapEntry:
+    """One URL in sitemap.xml"""
+    path: str
+    priority: Optional[float] = None
+    changefreq: Optional[str] = None
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'path': self.path, 'priority': self.priority, 'changefreq': self.changefreq}
+
+def url_path(route: str) -> str:
+    """A route as the URL pages are linked at: '/' or '/pricing/'"""
+    return '/' if route.strip('/') == '' else '/' + route.strip('/') + '/'
+
+def page_seo(page_data: Dict[str, Any]) -> Tuple[Dict[str, Any], List[str]]:
+    """A page's noindex, sitemap, priority, changefreq and paths, and what was wrong with them
+
+    Settings under 'static' come first and the page-level 'sitemap' and
+    'noindex' override them. A value that is wrong is reported and dropped.
+    """
+    static = page_data.get('static')
+    settings = {key: static[key] for key in ('noindex', 'sitemap', 'priority', 'changefreq', 'paths')
+                if isinstance(static, dict) and key in static}
+    problems = []
+    sitemap = page_data.get('sitemap')
+    if sitemap is not None:
+        if isinstance(sitemap, bool):
+            settings['sitemap'] = sitemap
+        elif isinstance(sitemap, dict):
+            unknown = sorted(set(sitemap) - set(SITEMAP_KEYS))
+            if unknown:
+                problems.append(f"sitemap has unknown key(s) {', '.join(unknown)}; use {', '.join(SITEMAP_KEYS)}")
+            settings.update({key: sitemap[key] for key in SITEMAP_KEYS if key in sitemap})
+            settings['sitemap'] = True
+        else:
+            problems.append("sitemap must be false or {priority, changefreq, paths}")
+    if 'noindex' in page_data:
+        settings['noindex'] = page_data['noindex']
+
+    result = {'noindex': False, 'sitemap': True, 'priority': None, 'changefreq': None, 'paths': None}
+    if not isinstance(settings.get('noindex', False), bool):
+        problems.append("noindex must be true or false")
+    else:
+        result['noindex'] = settings.get('noindex', False)
+    result['sitemap'] = settings.get('sitemap', True) is not False and not result['noindex']
+    priority = settings.get('priority')
+    if priority is not None:
+        if isinstance(priority, bool) or not isinstance(priority, (int, float)) or not 0 <= priority <= 1:
+            problems.append(f"sitemap priority must be a number from 0.0 to 1.0, got {priority!r}")
+        else:
+            result['priority'] = float(priority)
+    changefreq = settings.get('changefreq')
+    if changefreq is not None:
+        if changefreq not in CHANGEFREQS:
+            problems.append(f"sitemap changefreq must be one of {', '.join(CHANGEFREQS)}, got {changefreq!r}")
+        else:
+            result['changefreq'] = changefreq
+    paths = settings.get('paths')
+    if paths is not None:
+        if not isinstance(paths, list) or not all(isinstance(path, str) and path.startswith('/') for path in paths):
+            problems.append("sitemap paths must be a list of URLs starting with '/'")
+        else:
+            result['paths'] = paths
+    return result, problems
+
+def sitemap_entries(pages: Dict[str, Any], features: Optional[Dict[str, bool]] = None) -> Tuple[List[SitemapEntry], List[str]]:
+    """The entries for the IR's pages, and (route-prefixed) problems with their settings"""
+    entries, problems = [], []
+    for route, page_data in sorted(pages.items()):
+        if not isinstance(page_data, dict) or route == '/404':
+            continue
+        seo, page_problems = page_seo(page_data)
+        problems.extend(f"{route}: {problem}" for problem in page_problems)
+        if not seo['sitemap'] or (features is not None and not feature_visible(page_data, features)):
+            continue
+        routes = (seo['paths'] or []) if ROUTE_PARAMETER.search(route) else [route]
+        entries.extend(SitemapEntry(url_path(concrete), seo['priority'], seo['changefreq']) for concrete in routes)
+    return entries, problems
+
+def sitemap_xml(base_url: str, entries: List[SitemapEntry]) -> str:
+    base_url = base_url.rstrip('/')
+    urls = []
+    for entry in entries:
+        lines = [f"    <loc>{xml_escape(base_url + entry.path)}</loc>"]
+        if entry.changefreq:
+            lines.append(f"    <changefreq>{xml_escape(entry.changefreq)}</changefreq>")
+        if entry.priority is not None:
+            lines.append(f"    <priority>{entry.priority:.1f}</priority>")
+        urls.append("  <url>\n" + '\n'.join(lines) + "\n  </url>")
+    return ('<?xml version="1.0" encoding="UTF-8"?>\n'
+            '<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">\n' + '\n'.join(urls) + '\n</urlset>\n')
+
+def robots_txt(sitemap_url: Optional[str] = None, disallow: Optional[List[str]] = None,
+               allow: Optional[List[str]] = None) -> str:
+    """Everything allowed but the disallowed path prefixes, less the allowed ones inside them
+
+    noindex pages are not disallowed: a crawler has to fetch them to see
+    their noindex.
+    """
+    lines = ["User-agent: *"]
+    lines += [f"Allow: {path}" for path in allow or []]
+    lines += [f"Disallow: {path}" for path in disallow or []]
+    lines.append("Allow: /")
+    text = '\n'.join(lines) + '\n'
+    if sitemap_url:
+        text += f"\nSitemap: {sitemap_url}\n"
+    return text
+
+def disallowed_paths(settings: Dict[str, Any]) -> Tuple[List[str], List[str]]:
+    """static_site.disallow from flashflow.json, and what was wrong with it"""
+    disallow = settings.get('disallow') or []
+    if not isinstance(disallow, list) or not all(isinstance(path, str) and path.startswith('/') for path in disallow):
+        return [], ["static_site.disallow must be a list of paths starting with '/'"]
+    return list(disallow), []
@@ -0,0 +1,230 @@
+"""
+FlashFlow social cards - Open Graph and Twitter card meta for shared links
+
+Pages rendered the way 'flashflow build -t static' renders them (the static
+export, the dev server's /web/ build and its /preview frames) carry
+og: and twitter: meta, so a link pasted into a chat or a post shows a
+title, a description and an image. Pages tune them next to title and path:
+
+    page:
+      path: /pricing
+      title: Pricing
+      description: Plans for teams of every size
+      og:
+        title: Pricing that scales with you   # default: the page title
+        description: Start free, upgrade later  # default: the page description
+        image: pricing-card.png               # an asset in src/assets, a site path or a URL
+        image_alt: Three plans side by side
+        type: website                         # or article, product, ...
+      twitter: {card: summary_large_image, site: "@acme", creator: "@jane"}
+
+'og: false' leaves a page's social meta out. A page without an image gets a
+card: a 1200x630 PNG with the title and description on the theme's primary
+color and the project's name, written next to the stylesheet by the export
+and served at /__og/<route>.png by the dev server (/__og/index.png for /).
+Cards need Pillow; without it pages have no default image.
+
+Crawlers only follow absolute URLs. The export makes them absolute with
+"static_site": {"site_url": ...}; the dev server with the address the
+request came in on, so previews of 'serve --share' links work too.
+"""
+
+import re
+from io import BytesIO
+from typing import Any, Dict, List, Optional, Tuple
+
+from core.html_safety import escape_html, safe_url
+
+OG_KEYS = ('title', 'description', 'image', 'image_alt', 'type')
+TWITTER_KEYS = ('card', 'site', 'creator')
+TWITTER_CARDS = ('summary', 'summary_large_image')
+CARD_PATH = '/__og'
+CARD_SIZE = (1200, 630)
+CARD_PADDING = 80
+CARD_FONTS = ('DejaVuSans-Bold.ttf', 'Arial Bold.ttf', 'arialbd.ttf', 'Helvetica.ttc')
+TWITTER_HANDLE = re.compile(r"@\w{1,15}")
+
+class SocialCardError(Exception):
+    """Raised when a card image cannot be drawn"""
+    pass
+
+def page_social(page_data: Dict[str, Any]) -> Tuple[Dict[str, Any], List[str]]:
+    """A page's og and twitter settings with their defaults filled in, and what was wrong with them
+
+    A value that is wrong is reported and dropped.
+    """
+    problems = []
+    og = page_data.get('og', {})
+    twitter = page_data.get('twitter', {})
+    result = {'enabled': og is not False, 'title': str(page_data.get('title') or ''),
+              'description': str(page_data.get('description') or ''), 'image': None, 'image_alt': None,
+              'type': 'website', 'card': None, 'site': None, 'creator': None}
+    if og is False:
+        return result, problems
+    if og is None:
+        og = {}
+    if not isinstance(og, dict):
+        problems.append("og must be false or {title, description, image, image_alt, type}")
+        og = {}
+    unknown = sorted(set(og) - set(OG_KEYS))
+    if unknown:
+        problems.append(f"og has unknown key(s) {', '.join(unknown)}; use {', '.join(OG_KEYS)}")
+    for key in OG_KEYS:
+        value = og.get(key)
+        if value is None:
+            continue
+        if not isinstance(value, (str, int, float)) or isinstance(value, bool) or str(value).strip() == '':
+            problems.append(f"og {key} must be text, got {value!r}")
+        else:
+            result[key] = str(value)
+
+    if not isinstance(twitter, dict):
+        problems.append("twitter must be {card, site, creator}")
+        twitter = {}
+    unknown = sorted(set(twitter) - set(TWITTER_KEYS))
+    if unknown:
+        problems.append(f"twitter has unknown key(s) {', '.join(unknown)}; use {', '.join(TWITTER_KEYS)}")
+    card = twitter.get('card')
+    if card is not None:
+        if card not in TWITTER_CARDS:
+            problems.append(f"twitter card must be one of {', '.join(TWITTER_CARDS)}, got {card!r}")
+        else:
+            result['card'] = card
+    for key in ('site', 'creator'):
+        handle = twitter.get(key)
+        if handle is not None:
+            if not isinstance(handle, str) or not TWITTER_HANDLE.fullmatch(handle):
+                problems.append(f"twitter {key} must be an @handle, got {handle!r}")
+            else:
+                result[key] = handle
+    return result, problems
+
+def is_asset(image: Optional[str]) -> bool:
+    """Whether an og image names a file in src/assets rather than a site path or URL"""
+    return bool(image) and not image.startswith(('/', 'http://', 'https://', 'data:'))
+
+def card_path(route: str) -> str:
+    """Where the dev server serves a page's card: /__og/pricing.png, /__og/index.png for /"""
+    return f"{CARD_PATH}/{route.strip('/') or 'index'}.png"
+
+def absolute_url(url: str, base_url: str) -> str:
+    """A root-relative URL on base_url; absolute URLs, and any URL without a base, as they are"""
+    if base_url and url.startswith('/') and not url.startswith('//'):
+        return base_url.rstrip('/') + url
+    return url
+
+def social_tags(settings: Dict[str, Any], page_url: Optional[str], image_url: Optional[str], site_name: str,
+                generated: bool = False) -> List[str]:
+    """The <meta> tags for a page; image_url is the declared image resolved to a URL, or the page's card"""
+    if not settings['enabled']:
+        return []
+    tags = [('property', 'og:type', settings['type']), ('property', 'og:title', settings['title']),
+            ('property', 'og:description', settings['description']), ('property', 'og:site_name', site_name)]
+    if page_url:
+        tags.append(('property', 'og:url', page_url))
+    if image_url:
+        tags.append(('property', 'og:image', image_url))
+        if generated:
+            tags += [('property', 'og:image:width', str(CARD_SIZE[0])), ('property', 'og:image:height', str(CARD_SIZE[1]))]
+        tags.append(('property', 'og:image:alt', settings['image_alt'] or (settings['title'] if generated else '')))
+    card = settings['card'] or ('summary_large_image' if image_url else 'summary')
+    tags += [('name', 'twitter:card', card), ('name', 'twitter:title', settings['title']),
+             ('name', 'twitter:description', settings['description'])]
+    if image_url:
+        tags.append(('name', 'twitter:image', image_url))
+    tags += [('name', f"twitter:{key}", settings[key]) for key in ('site', 'creator') if settings[key]]
+    return [f'<meta {attribute}="{name}" content="{_content(name, value)}">'
+            for attribute, name, value in tags if value]
+
+def _content(name: str, value: str) -> str:
+    if name in ('og:url', 'og:image', 'twitter:image'):
+        return escape_html(safe_url(value, True))
+    return escape_html(value)
+
+def cards_available() -> bool:
+    try:
+        import PIL.ImageDraw  # noqa: F401
+    except ImportError:
+        return False
+    return True
+
+def render_card(title: str, description: str, site_name: str, colors: Dict[str, str]) -> bytes:
+    """A CARD_SIZE PNG: title and description on colors['primary'], the site's name at the bottom"""
+    try:
+        from PIL import Image, ImageColor, ImageDraw
+    except ImportError:
+        raise SocialCardError("Social preview cards need Pillow: pip install pillow")
+
+    def rgb(name: str, default: Tuple[int, int, int]) -> Tuple[int, int, int]:
+        try:
+            return ImageColor.getrgb(str(colors.get(name) or ''))[:3]
+        except ValueError:
+            return default
+
+    background = rgb('primary', (59, 130, 246))
+    # Light text on dark backgrounds and the other way round, with the description a step quieter
+    luminance = (0.299 * background[0] + 0.587 * background[1] + 0.114 * background[2]) / 255
+    text = (255, 255, 255) if luminance < 0.6 else rgb('dark', (15, 23, 42))
+    muted = tuple(round(t * 0.8 + b * 0.2) for t, b in zip(text, background))
+
+    image = Image.new('RGB', CARD_SIZE, background)
+    draw = ImageDraw.Draw(image)
+    width = CARD_SIZE[0] - 2 * CARD_PADDING
+    y = CARD_PADDING
+    title_font = _font(72)
+    for line in _wrap(draw, title or site_name, title_font, width, 3):
+        draw.text((CARD_PADDING, y), line, font=title_font, fill=text)
+        y += 86
+    if description:
+        y += 24
+        description_font = _font(34)
+        for line in _wrap(draw, description, description_font, width, 2):
+            draw.text((CARD_PADDING, y), line, font=description_font, fill=muted)
+            y += 46
+
+    footer = CARD_SIZE[1] - CARD_PADDING - 34
+    draw.rectangle((CARD_PADDING, footer - 28, CARD_PADDING + 96, footer - 22), fill=text)
+    draw.text((CARD_PADDING, footer), site_name, font=_font(30), fill=text)
+    output = BytesIO()
+    image.save(output, 'PNG', optimize=True)
+    return output.getvalue()
+
+def _font(size: int):
+    from PIL import ImageFont
+    for name in CARD_FONTS:
+        try:
+            return ImageFont.truetype(name, size)
+        except OSError:
+            continue
+    try:
+        # Pillow 10.1 and later scale their built-in font
+        return ImageFont.load_default(size=size)
+    except TypeError:
+        return ImageFont.load_default()
+
+def _wrap(draw, text: str, font, width: int, max_lines: int) -> List[str]:
+    """text in lines that fit width, the last one cut short with an ellipsis when there are more"""
+    def fits(line: str) -> bool:
+        left, _, right, _ = draw.textbbox((0, 0), line, font=font)
+        return right - left <= width
+
+    lines: List[str] = []
+    for word in ' '.join(str(text).split()).split(' '):
+        candidate = f"{lines[-1]} {word}" if lines else word
+        if lines and fits(candidate):
+            lines[-1] = candidate
+        elif fits(word) or not word:
+            lines.append(word)
+        else:
+            # A word wider than the card on its own breaks where it reaches the edge
+            lines.append('')
+            for char in word:
+                if lines[-1] and not fits(lines[-1] + char):
+                    lines.append('')
+                lines[-1] += char
+    if len(lines) > max_lines:
+        lines = lines[:max_lines]
+        while lines[-1] and not fits(lines[-1] + '…'):
+            lines[-1] = lines[-1][:-1].rstrip()
+        lines[-1] += '…'
+    return lines
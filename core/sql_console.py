@@ -0,0 +1,310 @@
+"""
+FlashFlow SQL console - The REPL behind 'flashflow db console'
+
+    flashflow db console
+    flashflow> SELECT id, title FROM todos
+          ...> WHERE done = 0;
+    flashflow> .tables
+    flashflow> .schema todos
+
+A statement runs once it ends with ';', so it can span lines. Tab
+completes SQL keywords, table names and, after a table has been mentioned,
+its columns. History is kept in .flashflow/db_history. Dot commands:
+
+    .tables            list tables
+    .schema TABLE      columns of a table
+    .mode table|json   how results are printed
+    .help              this list
+    .quit              leave (Ctrl-D does too)
+"""
+
+import json
+import re
+import time
+from pathlib import Path
+from typing import Any, Callable, Dict, List, Optional
+
+from core.database import Storage, StorageError
+
+PROMPT = 'flashflow> '
+CONTINUATION_PROMPT = '      ...> '
+MODES = ('table', 'json')
+HISTORY_LENGTH = 1000
+# Longer cells are cut in table mode; --json and '.mode json' show them whole
+MAX_CELL_WIDTH = 60
+KEYWORDS = [
+    'SELECT', 'FROM', 'WHERE', 'AND', 'OR', 'NOT', 'NULL', 'IS', 'IN', 'LIKE', 'BETWEEN', 'ORDER', 'BY', 'GROUP',
+    'HAVING', 'LIMIT', 'OFFSET', 'ASC', 'DESC', 'DISTINCT', 'AS', 'JOIN', 'LEFT', 'INNER', 'ON', 'INSERT', 'INTO',
+    'VALUES', 'UPDATE', 'SET', 'DELETE', 'COUNT', 'SUM', 'AVG', 'MIN', 'MAX', 'CREATE', 'TABLE', 'DROP', 'ALTER',
+    'INDEX', 'BEGIN', 'COMMIT', 'ROLLBACK'
+]
+DOT_COMMANDS = ['.tables', '.schema', '.mode', '.help', '.quit', '.exit']
+HELP = """\
+.tables            list tables
+.schema TABLE      columns of a table
+.mode table|json   how results are printed
+.help              this list
+.quit              leave (Ctrl-D does too)
+End a statement with ';' to run it; Ctrl-C drops a half-typed one."""
+
+def split_statements(text: str) -> List[str]:
+    """Statements separated by ';', ignoring semicolons in quotes and comments"""
+    statements, current = [], []
+    quote = None
+    i = 0
+    while i < len(text):
+        char = text[i]
+        if quote:
+            current.append(char)
+            if char == quote:
+                quote = None
+        elif char in ("'", '"', '`'):
+            quote = char
+            current.append(char)
+        elif text.startswith('--', i):
+            end = text.find('\n', i)
+            i = len(text) if end == -1 else end
+            continue
+        elif char == ';':
+            statements.append(''.join(current).strip())
+            current = []
+        else:
+            current.append(char)
+        i += 1
+    statements.append(''.join(current).strip())
+    return [statement for statement in statements if statement]
+
+def is_complete(buffer: str) -> bool:
+    """Whether the typed text ends a statement, i.e. its last non-comment character is a ';' outside quotes"""
+    stripped = buffer.strip()
+    if not stripped:
+        return False
+    quote = None
+    last = ''
+    i = 0
+    while i < len(stripped):
+        char = stripped[i]
+        if quote:
+            if char == quote:
+                quote = None
+        elif char in ("'", '"', '`'):
+            quote = char
+        elif stripped.startswith('--', i):
+            end = stripped.find('\n', i)
+            if end == -1:
+                break
+            i = end
+            continue
+        if not char.isspace():
+            last = char
+        i += 1
+    return quote is None and last == ';'
+
+def format_value(value: Any) -> str:
+    if value is None:
+        return 'NULL'
+    text = str(value).replace('\n', '\\n')
+    return text if len(text) <= MAX_CELL_WIDTH else text[:MAX_CELL_WIDTH - 1] + '…'
+
+def format_table(columns: List[str], rows: List[List[Any]]) -> str:
+    headers = [format_value(column) for column in columns]
+    cells = [[format_value(value) for value in row] for row in rows]
+    widths = [max([len(header)] + [len(row[index]) for row in cells]) for index, header in enumerate(headers)]
+    line = '+' + '+'.join('-' * (width + 2) for width in widths) + '+'
+
+    def render(values):
+        return '| ' + ' | '.join(value.ljust(width) for value, width in zip(values, widths)) + ' |'
+
+    return '\n'.join([line, render(headers), line] + [render(row) for row in cells] + [line])
+
+def result_json(result: Dict[str, Any]) -> Any:
+    """Result rows as objects, or the affected row count for statements without rows"""
+    if not result['columns']:
+        return {'rowcount': result['rowcount']}
+    return [dict(zip(result['columns'], row)) for row in result['rows']]
+
+def format_result(result: Dict[str, Any], mode: str = 'table', elapsed: Optional[float] = None) -> str:
+    timing = f" ({elapsed * 1000:.1f} ms)" if elapsed is not None else ''
+    if mode == 'json':
+        return json.dumps(result_json(result), indent=2, default=str)
+    if not result['columns']:
+        count = result['rowcount']
+        return f"OK, {count} row(s) affected{timing}" if count is not None and count >= 0 else f"OK{timing}"
+    rows = len(result['rows'])
+    return format_table(result['columns'], result['rows']) + f"\n{rows} row{'' if rows == 1 else 's'}{timing}"
+
+class SqlCompleter:
+    """readline completer for keywords, dot commands, tables and the columns of tables on the line"""
+
+    def __init__(self, storage: Storage):
+        self.storage = storage
+        self._tables: Optional[List[str]] = None
+        self._columns: Dict[str, List[str]] = {}
+        self._matches: List[str] = []
+        # Earlier lines of a statement being typed, whose tables count as mentioned too
+        self.context = ''
+
+    def refresh(self):
+        """Forget cached names, e.g. after a CREATE or DROP"""
+        self._tables = None
+        self._columns = {}
+
+    def tables(self) -> List[str]:
+        if self._tables is None:
+            try:
+                self._tables = self.storage.list_tables()
+            except StorageError:
+                self._tables = []
+        return self._tables
+
+    def columns(self, table: str) -> List[str]:
+        if table not in self._columns:
+            try:
+                self._columns[table] = [column['name'] for column in self.storage.table_columns(table)]
+            except StorageError:
+                self._columns[table] = []
+        return self._columns[table]
+
+    def candidates(self, line: str, text: str) -> List[str]:
+        if line.lstrip().startswith('.'):
+            words = line.split()
+            if len(words) <= 1 and not line.endswith(' '):
+                return [command for command in DOT_COMMANDS if command.startswith(text)]
+            if words[0] == '.schema':
+                return [table for table in self.tables() if table.startswith(text)]
+            if words[0] == '.mode':
+                return [mode for mode in MODES if mode.startswith(text)]
+            return []
+
+        tables = self.tables()
+        mentioned = [table for table in tables if re.search(rf"\b{re.escape(table)}\b", f"{self.context} {line}")]
+        if '.' in text:
+            # table.column
+            table, _, prefix = text.partition('.')
+            return [f"{table}.{column}" for column in self.columns(table) if column.startswith(prefix)] if table in tables else []
+        names = tables + [column for table in mentioned for column in self.columns(table)]
+        matches = [name for name in names if name.startswith(text)]
+        upper = text.upper()
+        keywords = [keyword if text.isupper() or not text else keyword.lower() for keyword in KEYWORDS if keyword.startswith(upper)]
+        return sorted(set(matches)) + [keyword for keyword in keywords if keyword not in matches]
+
+    def complete(self, text: str, state: int) -> Optional[str]:
+        if state == 0:
+            import readline
+            self._matches = self.candidates(readline.get_line_buffer()[:readline.get_endidx()], text)
+        return self._matches[state] if state < len(self._matches) else None
+
+class SqlConsole:
+    """Reads statements, runs them and prints their results"""
+
+    def __init__(self, storage: Storage, output: Callable[[str], None], history_path: Optional[Path] = None,
+                 mode: str = 'table'):
+        self.storage = storage
+        self.output = output
+        self.history_path = Path(history_path) if history_path else None
+        self.mode = mode
+        self.completer = SqlCompleter(storage)
+
+    def setup_readline(self) -> bool:
+        """History and tab completion; False where readline is not available (e.g. Windows without pyreadline)"""
+        try:
+            import readline
+        except ImportError:
+            return False
+        readline.set_completer(self.completer.complete)
+        readline.set_completer_delims(' \t\n(),;=<>*')
+        # libedit (macOS) spells the binding differently
+        if 'libedit' in (readline.__doc__ or ''):
+            readline.parse_and_bind('bind ^I rl_complete')
+        else:
+            readline.parse_and_bind('tab: complete')
+        readline.set_history_length(HISTORY_LENGTH)
+        if self.history_path and self.history_path.exists():
+            try:
+                readline.read_history_file(str(self.history_path))
+            except OSError:
+                pass
+        return True
+
+    def save_history(self):
+        if not self.history_path:
+            return
+        try:
+            import readline
+            self.history_path.parent.mkdir(parents=True, exist_ok=True)
+            readline.write_history_file(str(self.history_path))
+        except (ImportError, OSError):
+            pass
+
+    def run(self, read: Callable[[str], str] = input):
+        """The read-eval-print loop; returns on .quit or end of input"""
+        buffer = ''
+        while True:
+            self.completer.context = buffer
+            try:
+                line = read(CONTINUATION_PROMPT if buffer else PROMPT)
+            except EOFError:
+                self.output('')
+                break
+            except KeyboardInterrupt:
+                # Ctrl-C drops a half-typed statement, like psql and sqlite3
+                self.output('^C')
+                buffer = ''
+                continue
+
+            if not buffer and line.strip().startswith('.'):
+                if not self.command(line.strip()):
+                    break
+                continue
+            buffer = f"{buffer}\n{line}" if buffer else line
+            if is_complete(buffer):
+                for statement in split_statements(buffer):
+                    self.execute(statement)
+                buffer = ''
+            elif not buffer.strip():
+                buffer = ''
+
+    def execute(self, statement: str):
+        started = time.perf_counter()
+        try:
+            result = self.storage.run_sql(statement)
+        except StorageError as e:
+            self.output(f"❌ {str(e)}")
+            return
+        self.output(format_result(result, self.mode, time.perf_counter() - started))
+        if re.match(r"\s*(CREATE|DROP|ALTER)\b", statement, re.I):
+            self.completer.refresh()
+
+    def command(self, line: str) -> bool:
+        """Run a dot command; False means leave the console"""
+        name, _, argument = line.partition(' ')
+        argument = argument.strip()
+        if name in ('.quit', '.exit'):
+            return False
+        if name == '.help':
+            self.output(HELP)
+        elif name == '.tables':
+            try:
+                self.output('\n'.join(self.storage.list_tables()) or '(no tables)')
+            except StorageError as e:
+                self.output(f"❌ {str(e)}")
+        elif name == '.schema':
+            if not argument:
+                self.output("Usage: .schema TABLE")
+                return True
+            try:
+                columns = self.storage.table_columns(argument)
+            except StorageError as e:
+                self.output(f"❌ {str(e)}")
+                return True
+            self.output(format_table(['column', 'type', 'nullable', 'primary key'],
+                                     [[column['name'], column['type'], column['nullable'], column['primary_key']]
+                                      for column in columns]))
+        elif name == '.mode':
+            if argument not in MODES:
+                self.output(f"Usage: .mode {'|'.join(MODES)} (now {self.mode})")
+            else:
+                self.mode = argument
+        else:
+            self.output(f"Unknown command {name}; try .help")
+        return True
@@ -0,0 +1,133 @@
+"""
+FlashFlow project state - Generated files under <project>/.flashflow/
+
+Everything the CLI and dev services write at runtime (pids, ports, logs,
+caches, metrics, dev databases) goes here instead of paths relative to the
+current directory, so commands behave the same from any subdirectory.
+"""
+
+import json
+import os
+import time
+from pathlib import Path
+from typing import Dict, Any, Optional
+
+try:
+    import fcntl
+except ImportError:  # Windows
+    fcntl = None
+    import msvcrt
+
+STATE_DIR_NAME = ".flashflow"
+STATE_SUBDIRS = ['run', 'logs', 'cache', 'metrics']
+
+class StateLockError(Exception):
+    """Raised when another process already holds a project lock"""
+    pass
+
+class StateLock:
+    """Exclusive, non-blocking OS file lock; released automatically if the holder dies"""
+
+    def __init__(self, path: Path):
+        self.path = path
+        self._file = None
+
+    def acquire(self):
+        self.path.parent.mkdir(parents=True, exist_ok=True)
+        lock_file = open(self.path, 'a+')
+        try:
+            if fcntl:
+                fcntl.flock(lock_file.fileno(), fcntl.LOCK_EX | fcntl.LOCK_NB)
+            else:
+                lock_file.seek(0)
+                msvcrt.locking(lock_file.fileno(), msvcrt.LK_NBLCK, 1)
+        except OSError:
+            lock_file.close()
+            raise StateLockError(f"{self.path} is locked by another process")
+        self._file = lock_file
+
+    def release(self):
+        if self._file is None:
+            return
+        try:
+            if fcntl:
+                fcntl.flock(self._file.fileno(), fcntl.LOCK_UN)
+            else:
+                self._file.seek(0)
+                msvcrt.locking(self._file.fileno(), msvcrt.LK_UNLCK, 1)
+        except OSError:
+            pass
+        finally:
+            self._file.close()
+            self._file = None
+
+    def __enter__(self):
+        self.acquire()
+        return self
+
+    def __exit__(self, *exc):
+        self.release()
+
+class ProjectState:
+    """Paths and runtime records inside a project's .flashflow directory"""
+
+    def __init__(self, root_path: Path):
+        self.root_path = Path(root_path)
+        self.dir = self.root_path / STATE_DIR_NAME
+
+    def ensure(self) -> Path:
+        """Create the state directory layout; it ignores itself so nothing gets committed"""
+        for subdir in STATE_SUBDIRS:
+            (self.dir / subdir).mkdir(parents=True, exist_ok=True)
+        gitignore = self.dir / ".gitignore"
+        if not gitignore.exists():
+            gitignore.write_text("*\n")
+        return self.dir
+
+    def path(self, *parts: str) -> Path:
+        """Path inside the state directory, creating its parent directories"""
+        self.ensure()
+        target = self.dir.joinpath(*parts)
+        target.parent.mkdir(parents=True, exist_ok=True)
+        return target
+
+    @property
+    def run_dir(self) -> Path:
+        return self.ensure() / 'run'
+
+    @property
+    def logs_dir(self) -> Path:
+        return self.ensure() / 'logs'
+
+    @property
+    def cache_dir(self) -> Path:
+        return self.ensure() / 'cache'
+
+    @property
+    def metrics_dir(self) -> Path:
+        return self.ensure() / 'metrics'
+
+    def lock(self, name: str) -> StateLock:
+        return StateLock(self.path('run', f"{name}.lock"))
+
+    def write_runtime(self, name: str, info: Dict[str, Any]):
+        """Record a running process (pid, ports, ...) as run/<name>.json"""
+        record = {'pid': os.getpid(), 'started_at': time.time()}
+        record.update(info)
+        with open(self.path('run', f"{name}.json"), 'w') as f:
+            json.dump(record, f, indent=2)
+
+    def read_runtime(self, name: str) -> Optional[Dict[str, Any]]:
+        record_path = self.dir / 'run' / f"{name}.json"
+        if not record_path.exists():
+            return None
+        try:
+            with open(record_path, 'r') as f:
+                return json.load(f)
+        except (OSError, json.JSONDecodeError):
+            return None
+
+    def clear_runtime(self, name: str):
+        record_path = self.dir / 'run' / f"{name}.json"
+        if record_path.exists():
+            record_path.unlink()
@@ -0,0 +1,598 @@
+"""
+FlashFlow static site export - 'flashflow build -t static'
+
+Every routed flow page is pre-rendered to plain HTML in dist/static/ (no
+script, no server) together with a fingerprinted stylesheet, fingerprinted
+copies of the images and videos the pages use, sitemap.xml and robots.txt,
+so the folder can be uploaded to any static host or CDN as it is.
+
+Pages control their export with a 'static' key next to title and path:
+
+    page:
+      path: /pricing
+      title: Pricing
+      description: Plans for teams of every size     # <meta name="description">
+      static:
+        exclude: true           # leave the page out ('static: false' for short)
+        canonical: https://example.com/plans        # default: site_url + path
+        paths: [/pricing/teams, /pricing/enterprise]  # URLs to render a page with route parameters at
+      sitemap: {priority: 0.8, changefreq: monthly}  # or false; see core/sitemap.py
+      noindex: true             # ask crawlers not to index it (also keeps it out of the sitemap)
+      og: {image: pricing-card.png}  # Open Graph and Twitter card meta; see core/social_cards.py
+
+Pages with route parameters ('/orders/{id}') and no 'paths', and pages built
+on live data (lists, search, API-backed forms), are skipped and reported;
+'static: true' exports a live-data page anyway, without its data. The site's
+address comes from flashflow.json:
+
+    "static_site": {"site_url": "https://example.com", "lang": "en", "disallow": ["/drafts/"],
+                    "social_cards": true}
+
+Without site_url there are no canonical links and no sitemap, since both need
+absolute URLs. Pages without an og image get a generated card image in
+assets/og/ unless social_cards is false.
+
+Pages and components behind a 'feature' key (core/feature_flags.py) are
+exported as the build's environment has its flags, with nobody signed in.
+
+Page bodies are checked before they are rendered (core/render_context.py):
+unknown components and props of the wrong kind, such as a 'level' that is
+not a number, are reported as warnings and the prop's default is used.
+"""
+
+import hashlib
+import re
+import shutil
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Collection, Dict, Any, FrozenSet, List, Optional, Set, Tuple
+
+from core.content import ContentError, read_markdown_source
+from core.feature_flags import feature_visible
+from core.html_safety import escape_html, safe_url, sanitize_html
+from core.markdown import HIGHLIGHT_CSS, render_markdown
+from core.media import MediaLibrary, MediaError, collect_media_sources
+from core.profiles import ProfileError, load_profile
+from core.render_context import (Component, RenderContext, RenderProblem, compile_components, normalize_component,
+                                 site_messages)
+from core.social_cards import SocialCardError, absolute_url, is_asset, page_social, render_card, social_tags
+from core.sitemap import ROUTE_PARAMETER, SitemapEntry, disallowed_paths, page_seo, robots_txt, sitemap_xml, url_path
+
+ASSETS_DIR = 'assets'
+FINGERPRINT_LENGTH = 12
+# Components that show rows from the API or call it; a page using them has nothing useful to pre-render
+LIVE_COMPONENTS = {'list', 'table', 'data_table', 'search', 'chart', 'flashcore_demo', 'notification_bell',
+                   'countdown_timer', 'webrtc_stream'}
+DEFAULT_COLORS = {'primary': '#3B82F6', 'secondary': '#64748B', 'light': '#F8FAFC', 'dark': '#0F172A'}
+
+class StaticExportError(Exception):
+    """Raised when the static site cannot be written"""
+    pass
+
+@dataclass
+class StaticPage:
+    """One page to write, with its frontmatter resolved"""
+    route: str
+    data: Dict[str, Any]
+    canonical: Optional[str] = None
+    in_sitemap: bool = True
+    noindex: bool = False
+    priority: Optional[float] = None
+    changefreq: Optional[str] = None
+    # The route as the IR has it; a page with route parameters is written at several concrete routes
+    source_route: str = ''
+
+    @property
+    def output_name(self) -> str:
+        if self.route == '/404':
+            return '404.html'
+        path = self.route.strip('/')
+        return f"{path}/index.html" if path else 'index.html'
+
+    @property
+    def url_path(self) -> str:
+        return url_path(self.route)
+
+@dataclass
+class ExportReport:
+    """What an export wrote and what it left out"""
+    output: Path
+    pages: List[str] = field(default_factory=list)
+    skipped: List[Tuple[str, str]] = field(default_factory=list)
+    assets: int = 0
+    sitemap: bool = False
+    warnings: List[str] = field(default_factory=list)
+    # Pages an export of only some routes left as they were
+    unchanged: int = 0
+
+def page_settings(page_data: Dict[str, Any]) -> Dict[str, Any]:
+    settings = page_data.get('static')
+    if settings is False:
+        return {'exclude': True}
+    if settings is True:
+        return {'force': True}
+    return settings if isinstance(settings, dict) else {}
+
+def uses_live_data(components: List[Any]) -> Optional[str]:
+    """The first component that needs the API, if any"""
+    for component in components or []:
+        component = normalize_component(component)
+        if component is None:
+            continue
+        component_type = str(component.get('component', '')).lower()
+        if component_type in LIVE_COMPONENTS or component.get('data_source'):
+            return component_type
+        for key in ('children', 'footer'):
+            found = uses_live_data(component.get(key) or [])
+            if found:
+                return found
+        for tab in component.get('tabs') or []:
+            found = uses_live_data(tab.get('children') or []) if isinstance(tab, dict) else None
+            if found:
+                return found
+    return None
+
+class StaticSiteExporter:
+    """Renders the IR's pages into a static site folder"""
+
+    def __init__(self, project, ir, output_path: Optional[Path] = None, features: Optional[Dict[str, bool]] = None):
+        self.project = project
+        self.ir = ir
+        self.output_path = Path(output_path or project.dist_path / 'static')
+        self.features = features if features is not None else _default_features(project)
+        settings = getattr(project.config, 'static_site', None) or {}
+        self.site_url = str(settings.get('site_url') or '').rstrip('/')
+        self.lang = str(settings.get('lang') or 'en')
+        self.disallow, self._settings_problems = disallowed_paths(settings)
+        self.social_cards = settings.get('social_cards', True) is not False
+        # What plan() found wrong with the pages' sitemap settings, and with static_site itself
+        self.problems: List[str] = list(self._settings_problems)
+        self.messages = site_messages(ir, self.lang)
+        self.media = MediaLibrary(project.src_path / 'assets', project.state.cache_dir / 'media')
+        self._media_urls: Dict[str, Dict[str, Any]] = {}
+        self._links: List[Dict[str, Any]] = []
+        self._pages: Set[str] = set()
+        self._cards: Dict[str, str] = {}
+
+    def plan(self) -> Tuple[List[StaticPage], List[Tuple[str, str]]]:
+        """The pages to write, and (route, reason) for every page left out"""
+        pages, skipped = [], []
+        self.problems = list(self._settings_problems)
+        for route, page_data in sorted(self.ir.pages.items()):
+            if not isinstance(page_data, dict):
+                continue
+            settings = page_settings(page_data)
+            seo, problems = page_seo(page_data)
+            self.problems.extend(f"{route}: {problem}" for problem in problems + page_social(page_data)[1])
+            if settings.get('exclude'):
+                skipped.append((route, 'excluded by its static settings'))
+                continue
+            if not feature_visible(page_data, self.features):
+                skipped.append((route, f"behind feature flag(s) {page_data.get('feature')} that are off"))
+                continue
+            live = uses_live_data(page_data.get('body') or [])
+            if live and not settings.get('force'):
+                skipped.append((route, f"uses live data ({live}); set 'static: true' to export it anyway"))
+                continue
+            routes = [route]
+            if ROUTE_PARAMETER.search(route):
+                routes = seo['paths'] or []
+                if not routes:
+                    skipped.append((route, "has route parameters; list concrete URLs under 'static: {paths: [...]}'"))
+                    continue
+            for concrete in routes:
+                canonical = settings.get('canonical') if len(routes) == 1 else None
+                page = StaticPage(concrete, page_data, noindex=seo['noindex'], in_sitemap=seo['sitemap'] and concrete != '/404',
+                                  priority=seo['priority'], changefreq=seo['changefreq'], source_route=route)
+                page.canonical = canonical or (self.site_url + page.url_path if self.site_url else None)
+                pages.append(page)
+        return pages, skipped
+
+    def export(self, routes: Optional[Collection[str]] = None) -> ExportReport:
+        """Write the site; with routes (IR routes, see core/dependency_graph.py) only those pages are written
+        again into the existing export, and the ones no longer exported are removed"""
+        pages, skipped = self.plan()
+        report = ExportReport(self.output_path, skipped=skipped)
+        partial = routes is not None and self.output_path.exists()
+        if self.output_path.exists() and not partial:
+            shutil.rmtree(self.output_path)
+        assets_path = self.output_path / ASSETS_DIR
+        assets_path.mkdir(parents=True, exist_ok=True)
+
+        written = pages
+        if partial:
+            written = [page for page in pages if page.source_route in routes]
+            report.unchanged = len(pages) - len(written)
+            exported = {page.source_route for page in pages}
+            for route in set(routes) - exported:
+                self._remove_page(route)
+
+        # Fingerprinted, so an unchanged stylesheet keeps the name the unchanged pages link to
+        stylesheet = self._write_fingerprinted(assets_path, 'site', '.css', self._stylesheet().encode('utf-8'))
+        report.assets += 1
+        sources = collect_media_sources({page.route: page.data for page in written})
+        sources += [image for image in {page_social(page.data)[0]['image'] for page in written}
+                    if is_asset(image) and image not in sources]
+        for src in sorted(sources, key=sources.index):
+            try:
+                self._media_urls[src] = self._export_media(src, assets_path)
+                report.assets += 1 + len(self._media_urls[src].get('variants', []))
+            except MediaError as e:
+                report.warnings.append(str(e))
+
+        if self.social_cards:
+            report.assets += self._export_cards(written, assets_path, report)
+
+        self._links = self._nav_links(pages)
+        self._pages = {page.url_path for page in pages}
+        for page in written:
+            report.warnings.extend(f"{page.route}: {problem}" for problem in self.compile(page)[1])
+            target = self.output_path / page.output_name
+            target.parent.mkdir(parents=True, exist_ok=True)
+            target.write_text(self.render_page(page, stylesheet), encoding='utf-8')
+            report.pages.append(page.url_path if page.route != '/404' else '/404.html')
+
+        report.warnings.extend(self.problems)
+        if self.site_url:
+            (self.output_path / 'sitemap.xml').write_text(self.sitemap(pages), encoding='utf-8')
+            report.sitemap = True
+        else:
+            report.warnings.append("No static_site.site_url in flashflow.json: no sitemap.xml or canonical links")
+        robots = robots_txt(f"{self.site_url}/sitemap.xml" if self.site_url else None, self.disallow)
+        (self.output_path / 'robots.txt').write_text(robots, encoding='utf-8')
+        return report
+
+    def _remove_page(self, route: str):
+        """Delete a page a previous export wrote; pages with route parameters were written under other names"""
+        if ROUTE_PARAMETER.search(route):
+            return
+        target = self.output_path / StaticPage(route, {}).output_name
+        if target.is_file():
+            target.unlink()
+            if target.parent != self.output_path and not any(target.parent.iterdir()):
+                target.parent.rmdir()
+
+    # Assets
+
+    def _write_fingerprinted(self, directory: Path, stem: str, suffix: str, data: bytes) -> str:
+        name = f"{stem}.{hashlib.sha256(data).hexdigest()[:FINGERPRINT_LENGTH]}{suffix}"
+        (directory / name).write_bytes(data)
+        return f"/{ASSETS_DIR}/{name}"
+
+    def _export_media(self, src: str, assets_path: Path) -> Dict[str, Any]:
+        try:
+            return self.media.export(src, assets_path, url_prefix=f"/{ASSETS_DIR}")
+        except MediaError:
+            # Without Pillow there are no variants, but the original is still worth shipping
+            source = self.media.resolve(src)
+            name = (Path(src).parent / f"{source.stem}.{self.media.fingerprint(src)}{source.suffix}").as_posix()
+            (assets_path / name).parent.mkdir(parents=True, exist_ok=True)
+            shutil.copy2(source, assets_path / name)
+            return {'src': f"/{ASSETS_DIR}/{name}", 'srcset': '', 'variants': []}
+
+    def _export_cards(self, pages: List[StaticPage], assets_path: Path, report: ExportReport) -> int:
+        """Card images for the pages that declare no og image"""
+        written = 0
+        for page in pages:
+            social = page_social(page.data)[0]
+            if not social['enabled'] or social['image']:
+                continue
+            try:
+                data = self.card_png(page)
+            except SocialCardError as e:
+                report.warnings.append(str(e))
+                break
+            (assets_path / 'og').mkdir(exist_ok=True)
+            stem = re.sub(r'[^a-z0-9-]+', '-', page.route.strip('/').lower()).strip('-') or 'index'
+            self._cards[page.route] = self._write_fingerprinted(assets_path, f"og/{stem}", '.png', data)
+            written += 1
+        return written
+
+    def _colors(self) -> Dict[str, str]:
+        theme = self.ir.theme if isinstance(self.ir.theme, dict) else {}
+        return dict(DEFAULT_COLORS, **{name: str(value) for name, value in (theme.get('colors') or {}).items()
+                                       if isinstance(value, (str, int))})
+
+    def _stylesheet(self) -> str:
+        colors = self._colors()
+        variables = '\n'.join(f"  --color-{re.sub(r'[^a-z0-9-]', '-', name.lower())}: {value};"
+                              for name, value in sorted(colors.items()))
+        return f":root {{\n{variables}\n}}\n{BASE_STYLESHEET}{HIGHLIGHT_CSS}\n"
+
+    # Pages
+
+    def _nav_links(self, pages: List[StaticPage]) -> List[Dict[str, Any]]:
+        """Links for 'links: auto' navigation, ordered like the renderer orders them"""
+        links = []
+        for page in pages:
+            if page.route == '/404' or page.data.get('nav') is False or ROUTE_PARAMETER.search(page.data.get('path', page.route)):
+                continue
+            links.append({'text': page.data.get('nav_title') or page.data.get('title') or page.route,
+                          'link': page.url_path, 'order': page.data.get('nav_order', 1000)})
+        return sorted(links, key=lambda link: (link['order'], link['link'] != '/', link['link']))
+
+    def component_types(self) -> FrozenSet[str]:
+        """Component types this renderer draws, or knows to leave out"""
+        return frozenset(name[len('_render_'):] for name in dir(type(self)) if name.startswith('_render_')) | LIVE_COMPONENTS
+
+    def compile(self, page: StaticPage) -> Tuple[List[Component], List[RenderProblem]]:
+        return compile_components(page.data.get('body'), self.component_types())
+
+    def context(self, page: StaticPage) -> RenderContext:
+        return RenderContext(page.url_path, self.lang, self.messages, self._pages, self._media)
+
+    def render_page(self, page: StaticPage, stylesheet: str) -> str:
+        context = self.context(page)
+        title = str(page.data.get('title') or self.project.config.name)
+        head = [f'<meta charset="utf-8">',
+                f'<meta name="viewport" content="width=device-width, initial-scale=1">',
+                f'<title>{_escape(title)}</title>']
+        if page.data.get('description'):
+            head.append(f'<meta name="description" content="{_escape(page.data["description"])}">')
+        if page.noindex:
+            head.append('<meta name="robots" content="noindex">')
+        if page.canonical:
+            head.append(f'<link rel="canonical" href="{_url(page.canonical)}">')
+        head.extend(self.social_meta(page))
+        head.append(f'<link rel="stylesheet" href="{stylesheet}">')
+        components, _ = self.compile(page)
+        body = self.render_components(components, context)
+        return (f'<!DOCTYPE html>\n<html lang="{_escape(self.lang)}">\n<head>\n    ' + '\n    '.join(head) +
+                f'\n</head>\n<body>\n<main>\n<h1 class="page-title">{_escape(title)}</h1>\n{body}\n</main>\n</body>\n</html>\n')
+
+    def social_meta(self, page: StaticPage) -> List[str]:
+        """og: and twitter: meta; URLs stay root-relative without a site_url"""
+        social = page_social(page.data)[0]
+        social['title'] = social['title'] or str(self.project.config.name)
+        image, generated = social['image'], False
+        if is_asset(image):
+            image = self._media(image)['src']
+        elif not image:
+            image, generated = self.card_url(page), True
+        page_url = page.canonical or absolute_url(page.url_path, self.site_url)
+        return social_tags(social, page_url, absolute_url(image, self.site_url) if image else None,
+                           str(self.project.config.name), generated)
+
+    def card_url(self, page: StaticPage) -> Optional[str]:
+        """The page's generated card, when one was written"""
+        return self._cards.get(page.route)
+
+    def card_png(self, page: StaticPage) -> bytes:
+        social = page_social(page.data)[0]
+        return render_card(social['title'], social['description'], str(self.project.config.name), self._colors())
+
+    def render_components(self, components: List[Component], context: RenderContext) -> str:
+        return '\n'.join(filter(None, (self.render_component(component, context) for component in components)))
+
+    def render_component(self, component: Component, context: RenderContext) -> str:
+        if not component.visible_on('web') or not feature_visible({'feature': component.feature}, self.features):
+            return ''
+        renderer = getattr(self, f"_render_{component.type}", None)
+        if renderer is not None:
+            return renderer(component, context)
+        children = self.render_components(component.children, context)
+        if children:
+            return f'<div class="{_escape(component.type)}">\n{children}\n</div>'
+        return f"<!-- '{_escape(component.type)}' has no static rendering -->"
+
+    def _render_header(self, component: Component, context: RenderContext):
+        return f"<h2>{_escape(component.get('content', ''))}</h2>"
+
+    def _render_headline(self, component: Component, context: RenderContext):
+        level = min(max(component.get('level', 1) + 1, 2), 6)
+        return f"<h{level}>{_escape(component.get('text', ''))}</h{level}>"
+
+    def _render_text(self, component: Component, context: RenderContext):
+        return f"<p>{_escape(component.get('content', ''))}</p>"
+
+    def _render_rich_text(self, component: Component, context: RenderContext):
+        # The one place flow content is markup; only the allowlisted formatting survives
+        return f'<div class="rich-text">{sanitize_html(component.get("html", ""))}</div>'
+
+    def _render_markdown(self, component: Component, context: RenderContext):
+        # Rendered from Markdown, never passed through: see core/markdown.py
+        text = component.get('content', '')
+        if component.get('src'):
+            try:
+                text = read_markdown_source(self.project.root_path, component.get('src'))
+            except ContentError as e:
+                return f"<!-- {_escape(str(e))} -->"
+        return f'<div class="markdown">\n{render_markdown(text)}\n</div>'
+
+    def _render_hero(self, component: Component, context: RenderContext):
+        parts = [f"<h2>{_escape(component.get('title'))}</h2>" if component.get('title') else '',
+                 f"<p class=\"subtitle\">{_escape(component.get('subtitle'))}</p>" if component.get('subtitle') else '']
+        cta = component.get('cta')
+        if cta:
+            parts.append(_link_button(cta.get('text') or context.t('Get Started'), context.route(cta.get('link', '#'))))
+        return '<section class="hero">\n' + '\n'.join(filter(None, parts)) + '\n</section>'
+
+    def _render_card(self, component: Component, context: RenderContext):
+        parts = [f"<h3>{_escape(component.get('title'))}</h3>" if component.get('title') else '',
+                 f"<p>{_escape(component.get('content'))}</p>" if component.get('content') else '',
+                 self.render_components(component.children, context)]
+        return '<article class="card">\n' + '\n'.join(filter(None, parts)) + '\n</article>'
+
+    def _render_features(self, component: Component, context: RenderContext):
+        items = []
+        for item in component.get('items', []):
+            if isinstance(item, dict):
+                items.append(f"<article class=\"feature\"><h3>{_escape(item.get('title', ''))}</h3>"
+                             f"<p>{_escape(item.get('description', ''))}</p></article>")
+        return '<section class="features">\n' + '\n'.join(items) + '\n</section>'
+
+    def _render_button(self, component: Component, context: RenderContext):
+        text = component.get('text') or context.t('Button')
+        link = component.get('link')
+        if link:
+            return _link_button(text, context.route(link))
+        # Click actions need the engine; a static page can only show the button
+        return f'<button type="button" disabled>{_escape(text)}</button>'
+
+    _render_primary_button = _render_button
+
+    def _render_input(self, component: Component, context: RenderContext):
+        return (f"<label>{_escape(component.get('label', ''))} "
+                f"<input value=\"{_escape(component.get('value', ''))}\"{' disabled' if component.get('disabled') else ''}></label>")
+
+    def _render_form(self, component: Component, context: RenderContext):
+        fields = []
+        for form_field in component.get('fields', []):
+            form_field = form_field if isinstance(form_field, dict) else {'name': form_field}
+            name = form_field.get('name')
+            if not name:
+                continue
+            label = _escape(form_field.get('label', str(name).replace('_', ' ').title()))
+            if form_field.get('type') == 'textarea':
+                control = f'<textarea name="{_escape(name)}"></textarea>'
+            else:
+                input_type = 'password' if form_field.get('type') == 'password' else 'text'
+                control = f'<input type="{input_type}" name="{_escape(name)}" value="{_escape(form_field.get("value", ""))}">'
+            fields.append(f"<label>{label} {control}</label>")
+        action = component.get('action') if isinstance(component.get('action'), str) else None
+        attributes = f' method="post" action="{_url(action)}"' if action else ''
+        fields.append(f"<button type=\"submit\"{'' if action else ' disabled'}>{_escape(component.get('submit') or context.t('Submit'))}</button>")
+        return f"<form{attributes}>\n" + '\n'.join(fields) + '\n</form>'
+
+    def _media(self, src: str) -> Dict[str, Any]:
+        return self._media_urls.get(src) or {'src': src}
+
+    def _img(self, context: RenderContext, src: str, alt: str, width=None, height=None, sizes=None, css_class=None) -> str:
+        media = context.describe(src)
+        attributes = [f'src="{context.asset(src, images=True)}"', f'alt="{_escape(alt)}"', 'loading="lazy"']
+        if media.get('srcset'):
+            attributes.append(f'srcset="{_escape(media["srcset"])}"')
+            attributes.append(f'sizes="{_escape(sizes or media.get("sizes", ""))}"')
+        if width:
+            attributes.append(f'width="{int(width)}"')
+        if height:
+            attributes.append(f'height="{int(height)}"')
+        if css_class:
+            attributes.append(f'class="{_escape(css_class)}"')
+        return f"<img {' '.join(attributes)}>"
+
+    def _render_image(self, component: Component, context: RenderContext):
+        return self._img(context, component.get('src', ''), component.get('alt', ''), component.get('width'),
+                         component.get('height'), component.get('sizes'))
+
+    def _render_video(self, component: Component, context: RenderContext):
+        attributes = [f'src="{context.asset(component.get("src", ""))}"']
+        if component.get('poster'):
+            attributes.append(f'poster="{context.asset(component.get("poster"), images=True)}"')
+        for flag, default in (('controls', True), ('autoplay', False), ('muted', False), ('loop', False)):
+            if component.get(flag, default):
+                attributes.append(flag)
+        attributes.append(f'width="{component.get("width", 640)}"')
+        return f"<video {' '.join(attributes)}></video>"
+
+    def _render_gallery(self, component: Component, context: RenderContext):
+        columns = max(1, component.get('columns', 3))
+        images = []
+        for item in component.get('images', []):
+            item = item if isinstance(item, dict) else {'src': item}
+            images.append(self._img(context, item.get('src', ''), item.get('alt', ''), sizes=f"{round(100 / columns)}vw"))
+        return f'<div class="gallery" style="--columns: {columns}">\n' + '\n'.join(images) + '\n</div>'
+
+    def _links_for(self, component: Component) -> List[Dict[str, Any]]:
+        links = component.get('links', 'auto')
+        if links == 'auto':
+            exclude = [str(path).rstrip('/') for path in component.get('exclude', [])]
+            return [link for link in self._links if link['link'].rstrip('/') not in exclude]
+        result = []
+        for link in links:
+            link = link if isinstance(link, dict) else {'text': str(link), 'link': str(link)}
+            if link.get('link'):
+                result.append({'text': link.get('text', link['link']), 'link': link['link']})
+        return result
+
+    def _nav_list(self, component: Component, context: RenderContext) -> str:
+        items = []
+        for link in self._links_for(component):
+            current = ' aria-current="page"' if context.current(link['link']) else ''
+            items.append(f"<li><a href=\"{context.route(link['link'])}\"{current}>{_escape(link['text'])}</a></li>")
+        return '<ul>' + ''.join(items) + '</ul>'
+
+    def _render_navbar(self, component: Component, context: RenderContext):
+        title = component.get('title')
+        brand = f"<a class=\"brand\" href=\"{context.route(component.get('title_link', '/'))}\">{_escape(title)}</a>" if title else ''
+        return f'<nav class="navbar">{brand}{self._nav_list(component, context)}</nav>'
+
+    def _render_sidebar(self, component: Component, context: RenderContext):
+        title = f"<h2>{_escape(component.get('title'))}</h2>" if component.get('title') else ''
+        children = self.render_components(component.children, context)
+        return (f'<div class="with-sidebar">\n<aside class="sidebar">{title}<nav>{self._nav_list(component, context)}</nav></aside>\n'
+                f'<div class="sidebar-content">\n{children}\n</div>\n</div>')
+
+    def _render_tabs(self, component: Component, context: RenderContext):
+        sections = []
+        for index, tab in enumerate(component.tabs):
+            selected = index == component.get('selected', 0)
+            sections.append(f"<details class=\"tab\"{' open' if selected else ''}><summary>{_escape(tab.label)}</summary>\n"
+                            f"{self.render_components(tab.children, context)}\n</details>")
+        return '<div class="tabs">\n' + '\n'.join(sections) + '\n</div>'
+
+    def _render_modal(self, component: Component, context: RenderContext):
+        # A disclosure stands in for the dialog; it opens without any script
+        parts = [f"<h3>{_escape(component.get('title'))}</h3>" if component.get('title') else '',
+                 self.render_components(component.children, context),
+                 self.render_components(component.footer, context)]
+        summary = component.get('trigger') or component.get('title') or context.t('Open')
+        return f"<details class=\"modal\"><summary>{_escape(summary)}</summary>\n" + '\n'.join(filter(None, parts)) + '\n</details>'
+
+    # Sitemap
+
+    def sitemap(self, pages: List[StaticPage]) -> str:
+        return sitemap_xml(self.site_url, [SitemapEntry(page.url_path, page.priority, page.changefreq)
+                                           for page in pages if page.in_sitemap])
+
+def _default_features(project) -> Dict[str, bool]:
+    """The flags of the default environment, for exports that do not name one"""
+    try:
+        return load_profile(project).features
+    except ProfileError:
+        return {}
+
+def _link_button(text: Any, href: str) -> str:
+    """A link styled as a button; href is already escaped (RenderContext.route)"""
+    return f'<a class="button" href="{href}">{_escape(text)}</a>'
+
+_escape = escape_html
+
+def _url(value: Any, images: bool = False) -> str:
+    """A flow-supplied URL for href, src or action: javascript: and other script-running schemes become '#'"""
+    return escape_html(safe_url(value, images))
+
+BASE_STYLESHEET = """
+*, *::before, *::after { box-sizing: border-box; }
+body { margin: 0; font-family: system-ui, -apple-system, 'Segoe UI', sans-serif; color: var(--color-dark); background: var(--color-light); line-height: 1.6; }
+main { max-width: 1100px; margin: 0 auto; padding: 2rem 1.5rem; }
+img, video { max-width: 100%; height: auto; }
+a { color: var(--color-primary); }
+.page-title { font-size: 2rem; margin-top: 0; }
+.button, button { display: inline-block; background: var(--color-primary); color: white; border: none; padding: 0.6rem 1.2rem; border-radius: 6px; text-decoration: none; font: inherit; cursor: pointer; }
+button[disabled] { opacity: 0.6; cursor: default; }
+.hero { padding: 3rem 2rem; border-radius: 10px; background: color-mix(in srgb, var(--color-primary) 8%, white); text-align: center; margin-bottom: 2rem; }
+.hero .subtitle { font-size: 1.2rem; color: var(--color-secondary); }
+.card, .feature { background: white; padding: 1.25rem; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.08); margin-bottom: 1rem; }
+.features { display: grid; grid-template-columns: repeat(auto-fit, minmax(240px, 1fr)); gap: 1rem; }
+.gallery { display: grid; grid-template-columns: repeat(var(--columns, 3), 1fr); gap: 10px; }
+.gallery img { width: 100%; aspect-ratio: 1; object-fit: cover; border-radius: 6px; }
+.navbar { display: flex; align-items: center; gap: 1.5rem; padding: 0.75rem 0; margin-bottom: 1.5rem; border-bottom: 1px solid #e5e7eb; }
+.navbar .brand { font-weight: bold; font-size: 1.25rem; text-decoration: none; color: inherit; }
+nav ul { list-style: none; display: flex; flex-wrap: wrap; gap: 1rem; margin: 0; padding: 0; }
+nav a[aria-current] { font-weight: bold; }
+.with-sidebar { display: grid; grid-template-columns: 220px 1fr; gap: 2rem; }
+.sidebar nav ul { flex-direction: column; }
+details { background: white; border-radius: 8px; padding: 0.75rem 1rem; margin-bottom: 0.75rem; }
+summary { cursor: pointer; font-weight: 600; }
+form { display: grid; gap: 0.75rem; max-width: 480px; }
+label { display: grid; gap: 0.25rem; }
+input, textarea { padding: 0.5rem; border: 1px solid #d1d5db; border-radius: 6px; font: inherit; }
+.markdown pre { background: white; padding: 1rem; border-radius: 8px; overflow-x: auto; font-size: 0.9rem; }
+.markdown code { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; }
+.markdown :not(pre) > code { background: color-mix(in srgb, var(--color-secondary) 12%, white); padding: 0.1rem 0.3rem; border-radius: 4px; }
+.markdown blockquote { margin: 1rem 0; padding: 0.25rem 1rem; border-left: 4px solid var(--color-primary); color: var(--color-secondary); }
+.markdown table { border-collapse: collapse; margin: 1rem 0; }
+.markdown th, .markdown td { border: 1px solid #e5e7eb; padding: 0.4rem 0.75rem; }
+@media (max-width: 700px) { .with-sidebar { grid-template-columns: 1fr; } }
+"""
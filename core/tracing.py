@@ -0,0 +1,362 @@
+"""
+FlashFlow tracing - OpenTelemetry-compatible spans without extra dependencies
+
+Spans are exported over OTLP/HTTP (JSON) to any collector, or written as
+Jaeger JSON files under .flashflow/traces/ that the Jaeger UI can open.
+Configure in flashflow.json:
+
+    "tracing": {"exporter": "otlp", "endpoint": "http://localhost:4318", "sample_ratio": 1.0}
+
+or with the standard OTEL_EXPORTER_OTLP_ENDPOINT / OTEL_SERVICE_NAME variables.
+FLASHFLOW_TRACING=file|otlp|none overrides the exporter.
+
+Trace context crosses process boundaries as a W3C traceparent: the HTTP
+'traceparent' header between services and the TRACEPARENT environment
+variable for child processes (engine, Go services, hook scripts).
+"""
+
+import atexit
+import contextvars
+import json
+import logging
+import os
+import random
+import re
+import threading
+import time
+import urllib.request
+from contextlib import contextmanager
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Dict, Any, List, Optional
+
+logger = logging.getLogger(__name__)
+
+EXPORTERS = ['none', 'otlp', 'file']
+TRACEPARENT_PATTERN = re.compile(r'^00-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$')
+
+# OTLP span kinds
+SPAN_KINDS = {'internal': 1, 'server': 2, 'client': 3, 'producer': 4, 'consumer': 5}
+
+_current_span: contextvars.ContextVar = contextvars.ContextVar('flashflow_span', default=None)
+
+@dataclass
+class SpanContext:
+    trace_id: str
+    span_id: str
+    sampled: bool = True
+
+    def traceparent(self) -> str:
+        return f"00-{self.trace_id}-{self.span_id}-{'01' if self.sampled else '00'}"
+
+    @classmethod
+    def parse(cls, header: Optional[str]) -> Optional['SpanContext']:
+        match = TRACEPARENT_PATTERN.match((header or '').strip().lower())
+        if not match or match.group(1) == '0' * 32 or match.group(2) == '0' * 16:
+            return None
+        return cls(match.group(1), match.group(2), bool(int(match.group(3), 16) & 1))
+
+@dataclass
+class Span:
+    name: str
+    context: SpanContext
+    parent_id: Optional[str] = None
+    kind: str = 'internal'
+    attributes: Dict[str, Any] = field(default_factory=dict)
+    start_ns: int = field(default_factory=time.time_ns)
+    end_ns: Optional[int] = None
+    status: str = 'unset'  # unset, ok, error
+    status_message: str = ''
+    remote_parent: bool = False
+
+    def set_attribute(self, key: str, value: Any):
+        self.attributes[key] = value
+
+    def set_error(self, message: str):
+        self.status = 'error'
+        self.status_message = message
+
+    @property
+    def duration_ms(self) -> float:
+        return ((self.end_ns or time.time_ns()) - self.start_ns) / 1e6
+
+class NoopExporter:
+    def export(self, spans: List[Span], service_name: str):
+        pass
+
+    def shutdown(self):
+        pass
+
+class OTLPExporter:
+    """Batches spans and POSTs them to <endpoint>/v1/traces using the OTLP JSON encoding"""
+
+    def __init__(self, endpoint: str, headers: Dict[str, str] = None, flush_interval: float = 2.0):
+        self.url = endpoint.rstrip('/') + ('' if endpoint.rstrip('/').endswith('/v1/traces') else '/v1/traces')
+        self.headers = headers or {}
+        self._pending: List[Span] = []
+        self._service_name = 'flashflow'
+        self._lock = threading.Lock()
+        self._stop = threading.Event()
+        self._thread = threading.Thread(target=self._run, args=(flush_interval,), daemon=True)
+        self._thread.start()
+
+    def export(self, spans: List[Span], service_name: str):
+        with self._lock:
+            self._service_name = service_name
+            self._pending.extend(spans)
+
+    def _run(self, interval: float):
+        while not self._stop.wait(interval):
+            self.flush()
+
+    def flush(self):
+        with self._lock:
+            spans, self._pending = self._pending, []
+        if not spans:
+            return
+        body = json.dumps(otlp_payload(spans, self._service_name)).encode('utf-8')
+        request = urllib.request.Request(self.url, data=body, method='POST',
+                                         headers={'Content-Type': 'application/json', **self.headers})
+        try:
+            urllib.request.urlopen(request, timeout=5).close()
+        except Exception as e:
+            logger.debug(f"Dropped {len(spans)} spans, OTLP export to {self.url} failed: {e}")
+
+    def shutdown(self):
+        self._stop.set()
+        self.flush()
+
+class FileExporter:
+    """Writes each finished local trace as a Jaeger JSON file"""
+
+    def __init__(self, directory: Path):
+        self.directory = Path(directory)
+        self._traces: Dict[str, List[Span]] = {}
+        self._lock = threading.Lock()
+
+    def export(self, spans: List[Span], service_name: str):
+        with self._lock:
+            for span in spans:
+                self._traces.setdefault(span.context.trace_id, []).append(span)
+            finished = [trace_id for trace_id, trace_spans in self._traces.items()
+                        if any(s.parent_id is None or s.remote_parent for s in trace_spans)]
+            for trace_id in finished:
+                self._write(trace_id, self._traces.pop(trace_id), service_name)
+
+    def _write(self, trace_id: str, spans: List[Span], service_name: str):
+        self.directory.mkdir(parents=True, exist_ok=True)
+        # Several processes may add to one trace; each writes its own file
+        path = self.directory / f"{trace_id}-{os.getpid()}-{spans[-1].context.span_id}.json"
+        with open(path, 'w') as f:
+            json.dump(jaeger_payload(trace_id, spans, service_name), f, indent=2)
+
+    def shutdown(self):
+        with self._lock:
+            traces, self._traces = self._traces, {}
+        for trace_id, spans in traces.items():
+            self._write(trace_id, spans, 'flashflow')
+
+class Tracer:
+    """Creates spans, tracks the current one and hands finished spans to the exporter"""
+
+    def __init__(self, service_name: str = 'flashflow', exporter=None, sample_ratio: float = 1.0,
+                 env_settings: Dict[str, str] = None):
+        self.service_name = service_name
+        self.exporter = exporter or NoopExporter()
+        self.sample_ratio = sample_ratio
+        self.env_settings = env_settings or {}
+        self.enabled = not isinstance(self.exporter, NoopExporter)
+        # A parent process can hand its trace context down through the environment
+        self.process_parent = SpanContext.parse(os.environ.get('TRACEPARENT'))
+
+    @contextmanager
+    def span(self, name: str, kind: str = 'internal', attributes: Dict[str, Any] = None,
+             parent: Optional[SpanContext] = None):
+        """Run a block inside a span; exceptions mark the span as failed and are re-raised"""
+        span = self.start_span(name, kind, attributes, parent)
+        token = self.activate(span)
+        try:
+            yield span
+        except BaseException as e:
+            if not isinstance(e, (KeyboardInterrupt, SystemExit)):
+                span.set_error(f"{type(e).__name__}: {e}")
+            raise
+        finally:
+            self.deactivate(token)
+            self.end_span(span)
+
+    def start_span(self, name: str, kind: str = 'internal', attributes: Dict[str, Any] = None,
+                   parent: Optional[SpanContext] = None) -> Span:
+        current = _current_span.get()
+        remote_parent = False
+        if parent is None and current is not None:
+            parent = current.context
+        elif parent is None and self.process_parent is not None:
+            parent = self.process_parent
+            remote_parent = True
+        elif parent is not None:
+            remote_parent = True
+
+        if parent is not None:
+            context = SpanContext(parent.trace_id, _random_hex(16), parent.sampled)
+        else:
+            context = SpanContext(_random_hex(32), _random_hex(16), random.random() < self.sample_ratio)
+
+        return Span(name, context, parent.span_id if parent else None, kind, dict(attributes or {}),
+                    remote_parent=remote_parent)
+
+    def end_span(self, span: Span):
+        span.end_ns = time.time_ns()
+        if span.status == 'unset':
+            span.status = 'ok'
+        if self.enabled and span.context.sampled:
+            self.exporter.export([span], self.service_name)
+
+    def activate(self, span: Span) -> contextvars.Token:
+        """Make a span current for code that cannot use the span() context manager"""
+        return _current_span.set(span)
+
+    def deactivate(self, token: contextvars.Token):
+        _current_span.reset(token)
+
+    def current_span(self) -> Optional[Span]:
+        return _current_span.get()
+
+    def traceparent(self) -> Optional[str]:
+        """traceparent for the current span (or the inherited process parent)"""
+        current = _current_span.get()
+        if current is not None:
+            return current.context.traceparent()
+        return self.process_parent.traceparent() if self.process_parent else None
+
+    def inject_headers(self, headers: Dict[str, str]) -> Dict[str, str]:
+        traceparent = self.traceparent()
+        if self.enabled and traceparent:
+            headers['traceparent'] = traceparent
+        return headers
+
+    def inject_env(self, env: Dict[str, str]) -> Dict[str, str]:
+        """Pass tracing settings and the current trace context to a child process"""
+        if not self.enabled:
+            return env
+        env.update(self.env_settings)
+        traceparent = self.traceparent()
+        if traceparent:
+            env['TRACEPARENT'] = traceparent
+        return env
+
+    def shutdown(self):
+        self.exporter.shutdown()
+
+_tracer = Tracer()
+_tracer_lock = threading.Lock()
+
+def get_tracer() -> Tracer:
+    return _tracer
+
+def configure_tracing(project=None, service_name: str = 'flashflow') -> Tracer:
+    """Set up the process-wide tracer from flashflow.json and OTEL_* environment variables"""
+    global _tracer
+
+    settings: Dict[str, Any] = {}
+    if project is not None:
+        try:
+            settings = dict(project.config.tracing or {})
+        except FileNotFoundError:
+            settings = {}
+
+    endpoint = os.environ.get('OTEL_EXPORTER_OTLP_TRACES_ENDPOINT') or os.environ.get('OTEL_EXPORTER_OTLP_ENDPOINT') or settings.get('endpoint')
+    exporter_name = os.environ.get('FLASHFLOW_TRACING') or settings.get('exporter') or ('otlp' if endpoint else 'none')
+    if exporter_name not in EXPORTERS:
+        logger.warning(f"Unknown tracing exporter '{exporter_name}', tracing disabled. Use one of: {', '.join(EXPORTERS)}")
+        exporter_name = 'none'
+
+    env_settings = {'FLASHFLOW_TRACING': exporter_name}
+    if exporter_name == 'otlp':
+        endpoint = endpoint or 'http://localhost:4318'
+        exporter = OTLPExporter(endpoint, settings.get('headers'))
+        env_settings['OTEL_EXPORTER_OTLP_ENDPOINT'] = endpoint
+    elif exporter_name == 'file':
+        if settings.get('directory'):
+            directory = Path(settings['directory'])
+        elif os.environ.get('FLASHFLOW_TRACES_DIR'):
+            directory = Path(os.environ['FLASHFLOW_TRACES_DIR'])
+        elif project is not None:
+            directory = project.state.ensure() / "traces"
+        else:
+            directory = Path.cwd() / ".flashflow" / "traces"
+        exporter = FileExporter(directory)
+        env_settings['FLASHFLOW_TRACES_DIR'] = str(directory)
+    else:
+        exporter = NoopExporter()
+
+    with _tracer_lock:
+        _tracer.shutdown()
+        _tracer = Tracer(
+            os.environ.get('OTEL_SERVICE_NAME') or settings.get('service_name') or service_name,
+            exporter,
+            float(settings.get('sample_ratio', 1.0)),
+            env_settings
+        )
+    return _tracer
+
+@atexit.register
+def _shutdown_tracer():
+    _tracer.shutdown()
+
+def otlp_payload(spans: List[Span], service_name: str) -> Dict[str, Any]:
+    return {
+        'resourceSpans': [{
+            'resource': {'attributes': _otlp_attributes({'service.name': service_name, 'process.pid': os.getpid()})},
+            'scopeSpans': [{
+                'scope': {'name': 'flashflow'},
+                'spans': [{
+                    'traceId': span.context.trace_id,
+                    'spanId': span.context.span_id,
+                    'parentSpanId': span.parent_id or '',
+                    'name': span.name,
+                    'kind': SPAN_KINDS.get(span.kind, 1),
+                    'startTimeUnixNano': str(span.start_ns),
+                    'endTimeUnixNano': str(span.end_ns or span.start_ns),
+                    'attributes': _otlp_attributes(span.attributes),
+                    'status': {'code': 2 if span.status == 'error' else 1, 'message': span.status_message}
+                } for span in spans]
+            }]
+        }]
+    }
+
+def jaeger_payload(trace_id: str, spans: List[Span], service_name: str) -> Dict[str, Any]:
+    def tags(attributes: Dict[str, Any]) -> List[Dict[str, Any]]:
+        return [{'key': key, 'type': 'string', 'value': str(value)} for key, value in attributes.items()]
+
+    return {'data': [{
+        'traceID': trace_id,
+        'spans': [{
+            'traceID': trace_id,
+            'spanID': span.context.span_id,
+            'operationName': span.name,
+            'references': [{'refType': 'CHILD_OF', 'traceID': trace_id, 'spanID': span.parent_id}] if span.parent_id else [],
+            'startTime': span.start_ns // 1000,
+            'duration': ((span.end_ns or span.start_ns) - span.start_ns) // 1000,
+            'tags': tags({**span.attributes, 'span.kind': span.kind, 'error': span.status == 'error'}),
+            'logs': [],
+            'processID': 'p1'
+        } for span in spans],
+        'processes': {'p1': {'serviceName': service_name, 'tags': tags({'pid': os.getpid()})}}
+    }]}
+
+def _otlp_attributes(attributes: Dict[str, Any]) -> List[Dict[str, Any]]:
+    converted = []
+    for key, value in attributes.items():
+        if isinstance(value, bool):
+            converted.append({'key': key, 'value': {'boolValue': value}})
+        elif isinstance(value, int):
+            converted.append({'key': key, 'value': {'intValue': str(value)}})
+        elif isinstance(value, float):
+            converted.append({'key': key, 'value': {'doubleValue': value}})
+        else:
+            converted.append({'key': key, 'value': {'stringValue': str(value)}})
+    return converted
+
+def _random_hex(length: int) -> str:
+    return f"{random.getrandbits(length * 4):0{length}x}"
@@ -0,0 +1,170 @@
+"""
+FlashFlow transforms - Request and response rewriting rules for the dev server
+
+Rules sit in a flow's 'transforms' section or under "transforms" in
+flashflow.json, keyed by name:
+
+    transforms:
+      legacy_orders:
+        route: /api/v1/orders*            # fnmatch pattern on the path the client asked for
+        methods: [GET, POST]              # empty for every method
+        rewrite: {from: /api/v1/orders, to: /api/data/orders}   # path prefix to replace
+        request_headers: {X-Tenant: acme, Cookie: null}          # set, or remove with null
+        rename_request: {customer: customer_name}                # JSON body fields, old: new
+        rename_response: {customer_name: customer}
+        response_headers: {Deprecation: "true"}
+      payments_down:
+        route: /api/payments/*
+        stub: {status: 503, body: {error: Payments are down}, headers: {Retry-After: "30"}}
+        enabled: false                    # kept, but not applied
+
+Every enabled rule matching a request applies, in order: flashflow.json
+first, then the flows by file name. A rule with a stub answers the request
+itself, and the app never sees it. Renames apply wherever a field appears in
+the JSON body, in nested objects and lists too.
+"""
+
+import fnmatch
+import json
+from dataclasses import dataclass, field
+from typing import Any, Dict, List, Optional, Tuple
+
+HTTP_METHODS = ['GET', 'POST', 'PUT', 'PATCH', 'DELETE', 'HEAD', 'OPTIONS']
+RULE_KEYS = {'route', 'methods', 'rewrite', 'request_headers', 'response_headers', 'rename_request', 'rename_response',
+             'stub', 'enabled'}
+
+class TransformError(Exception):
+    """Raised for an invalid transform rule"""
+    pass
+
+@dataclass
+class Stub:
+    status: int
+    body: Any
+    headers: Dict[str, str] = field(default_factory=dict)
+
+@dataclass
+class TransformRule:
+    """One named rule from a flow or flashflow.json"""
+    name: str
+    route: str
+    source: str
+    methods: List[str] = field(default_factory=list)
+    rewrite: Optional[Tuple[str, str]] = None
+    request_headers: Dict[str, Optional[str]] = field(default_factory=dict)
+    response_headers: Dict[str, Optional[str]] = field(default_factory=dict)
+    rename_request: Dict[str, str] = field(default_factory=dict)
+    rename_response: Dict[str, str] = field(default_factory=dict)
+    stub: Optional[Stub] = None
+    enabled: bool = True
+    # Not persisted; requests the rule applied to since the server started
+    hits: int = 0
+
+    @classmethod
+    def from_definition(cls, name: str, definition: Any, source: str) -> 'TransformRule':
+        if not isinstance(definition, dict):
+            raise TransformError(f"transform '{name}' must be a mapping")
+        unknown = sorted(set(definition) - RULE_KEYS)
+        if unknown:
+            raise TransformError(f"transform '{name}': unknown key(s) {', '.join(unknown)}; use {', '.join(sorted(RULE_KEYS))}")
+        route = definition.get('route')
+        if not isinstance(route, str) or not route.startswith('/'):
+            raise TransformError(f"transform '{name}': 'route' must be a path pattern starting with '/', e.g. /api/v1/*")
+
+        methods = definition.get('methods') or []
+        if isinstance(methods, str):
+            methods = methods.replace(',', ' ').split()
+        methods = [str(method).upper() for method in methods]
+        unknown = [method for method in methods if method not in HTTP_METHODS]
+        if unknown:
+            raise TransformError(f"transform '{name}': unknown method(s) {', '.join(unknown)}")
+
+        rule = cls(name, route, source, methods, enabled=definition.get('enabled', True) is not False)
+        rewrite = definition.get('rewrite')
+        if rewrite is not None:
+            if not isinstance(rewrite, dict) or not str(rewrite.get('from', '')).startswith('/') \
+                    or not str(rewrite.get('to', '')).startswith('/'):
+                raise TransformError(f"transform '{name}': 'rewrite' needs 'from' and 'to' paths, e.g. "
+                                     "{from: /api/v1, to: /api/data}")
+            rule.rewrite = (str(rewrite['from']), str(rewrite['to']))
+        rule.request_headers = _headers(definition.get('request_headers'), name, 'request_headers')
+        rule.response_headers = _headers(definition.get('response_headers'), name, 'response_headers')
+        rule.rename_request = _renames(definition.get('rename_request'), name, 'rename_request')
+        rule.rename_response = _renames(definition.get('rename_response'), name, 'rename_response')
+
+        stub = definition.get('stub')
+        if stub is not None:
+            if not isinstance(stub, dict):
+                raise TransformError(f"transform '{name}': 'stub' must be a mapping with a 'status'")
+            status = stub.get('status')
+            if isinstance(status, bool) or not isinstance(status, int) or not 100 <= status <= 599:
+                raise TransformError(f"transform '{name}': 'stub.status' must be an HTTP status, got {status!r}")
+            body = stub['body'] if 'body' in stub else {'error': f"Stubbed by transform '{name}'"}
+            rule.stub = Stub(status, body, {key: value for key, value in
+                                            _headers(stub.get('headers'), name, 'stub.headers').items() if value is not None})
+        return rule
+
+    def matches(self, method: str, path: str) -> bool:
+        return self.enabled and (not self.methods or method.upper() in self.methods) and fnmatch.fnmatchcase(path, self.route)
+
+    def rewrite_path(self, path: str) -> str:
+        if self.rewrite and (path == self.rewrite[0] or path.startswith(self.rewrite[0].rstrip('/') + '/')):
+            return self.rewrite[1].rstrip('/') + path[len(self.rewrite[0].rstrip('/')):] or '/'
+        return path
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {
+            'name': self.name, 'route': self.route, 'source': self.source, 'methods': self.methods,
+            'rewrite': {'from': self.rewrite[0], 'to': self.rewrite[1]} if self.rewrite else None,
+            'request_headers': self.request_headers, 'response_headers': self.response_headers,
+            'rename_request': self.rename_request, 'rename_response': self.rename_response,
+            'stub': {'status': self.stub.status, 'body': self.stub.body, 'headers': self.stub.headers} if self.stub else None,
+            'enabled': self.enabled, 'hits': self.hits,
+        }
+
+def _headers(value: Any, name: str, key: str) -> Dict[str, Optional[str]]:
+    if value is None:
+        return {}
+    if not isinstance(value, dict):
+        raise TransformError(f"transform '{name}': '{key}' must map header names to values (null removes one)")
+    return {str(header): None if header_value is None else str(header_value) for header, header_value in value.items()}
+
+def _renames(value: Any, name: str, key: str) -> Dict[str, str]:
+    if value is None:
+        return {}
+    if not isinstance(value, dict) or not all(isinstance(new, str) and new for new in value.values()):
+        raise TransformError(f"transform '{name}': '{key}' must map old field names to new ones")
+    return {str(old): new for old, new in value.items()}
+
+def load_rules(sections: List[Tuple[Any, str]]) -> Tuple[List[TransformRule], List[str]]:
+    """Valid rules from (section, source) pairs, in order, and an error for each invalid one"""
+    rules, errors, names = [], [], set()
+    for section, source in sections:
+        if not isinstance(section, dict):
+            errors.append(f"{source}: 'transforms' must map names to rules")
+            continue
+        for name, definition in section.items():
+            try:
+                if str(name) in names:
+                    raise TransformError(f"transform '{name}' is declared twice")
+                rules.append(TransformRule.from_definition(str(name), definition, source))
+                names.add(str(name))
+            except TransformError as e:
+                errors.append(f"{source}: {str(e)}")
+    return rules, errors
+
+def rename_fields(value: Any, renames: Dict[str, str]) -> Any:
+    """value with every object key in renames replaced, at any depth"""
+    if isinstance(value, dict):
+        return {renames.get(key, key): rename_fields(item, renames) for key, item in value.items()}
+    if isinstance(value, list):
+        return [rename_fields(item, renames) for item in value]
+    return value
+
+def rename_json(body: bytes, renames: Dict[str, str]) -> Optional[bytes]:
+    """A JSON body with its fields renamed; None when it is not JSON"""
+    try:
+        data = json.loads(body.decode('utf-8'))
+    except (UnicodeDecodeError, ValueError):
+        return None
+    return json.dumps(rename_fields(data, renames)).encode('utf-8')
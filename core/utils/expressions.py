@@ -0,0 +1,205 @@
+"""
+FlashFlow safe expression evaluator
+Evaluates the small expressions allowed inside .flow files without exec/eval
+"""
+
+import ast
+import operator
+import re
+from functools import lru_cache
+from typing import Any, Dict
+
+MAX_EXPRESSION_LENGTH = 1000
+MAX_SEQUENCE_REPEAT = 10000
+# Width and precision of each %-conversion: '%05.2f' gives '05' and '2'
+FORMAT_SIZES = re.compile(r'%[-+ #0]*(\*|\d*)(?:\.(\*|\d*))?')
+
+class ExpressionError(ValueError):
+    """Raised when an expression is invalid or uses something that is not allowed"""
+    pass
+
+BINARY_OPERATORS = {
+    ast.Add: operator.add,
+    ast.Sub: operator.sub,
+    ast.Mult: operator.mul,
+    ast.Div: operator.truediv,
+    ast.FloorDiv: operator.floordiv,
+    ast.Mod: operator.mod,
+}
+
+UNARY_OPERATORS = {
+    ast.Not: operator.not_,
+    ast.USub: operator.neg,
+    ast.UAdd: operator.pos,
+}
+
+COMPARE_OPERATORS = {
+    ast.Eq: operator.eq,
+    ast.NotEq: operator.ne,
+    ast.Lt: operator.lt,
+    ast.LtE: operator.le,
+    ast.Gt: operator.gt,
+    ast.GtE: operator.ge,
+    ast.In: lambda a, b: a in b,
+    ast.NotIn: lambda a, b: a not in b,
+    ast.Is: operator.is_,
+    ast.IsNot: operator.is_not,
+}
+
+FUNCTIONS = {
+    'len': len,
+    'str': str,
+    'int': int,
+    'float': float,
+    'bool': bool,
+    'abs': abs,
+    'min': min,
+    'max': max,
+    'round': round,
+    'sum': sum,
+}
+
+# Methods that may be called on values of these types
+METHODS = {
+    str: {'lower', 'upper', 'strip', 'startswith', 'endswith', 'split', 'replace', 'title'},
+    dict: {'get', 'keys', 'values'},
+    list: {'count', 'index'},
+}
+
+@lru_cache(maxsize=512)
+def compile_expression(source: str) -> ast.Expression:
+    """Parse an expression once, rejecting anything that is not an expression"""
+    if len(source) > MAX_EXPRESSION_LENGTH:
+        raise ExpressionError(f"Expression is longer than {MAX_EXPRESSION_LENGTH} characters")
+    try:
+        return ast.parse(source.strip(), mode='eval')
+    except SyntaxError as e:
+        raise ExpressionError(f"Invalid expression '{source}': {e.msg}")
+
+def evaluate(source: str, context: Dict[str, Any]) -> Any:
+    """Evaluate an expression against a dict of names"""
+    return _eval(compile_expression(source).body, context)
+
+def _eval(node: ast.AST, context: Dict[str, Any]) -> Any:
+    if isinstance(node, ast.Constant):
+        return node.value
+
+    if isinstance(node, ast.Name):
+        if node.id in context:
+            return context[node.id]
+        if node.id in ('true', 'false', 'null'):
+            return {'true': True, 'false': False, 'null': None}[node.id]
+        raise ExpressionError(f"Unknown name '{node.id}'")
+
+    if isinstance(node, ast.Attribute):
+        # Dot access reads dict keys: request.method == request['method']
+        value = _eval(node.value, context)
+        if isinstance(value, dict):
+            return value.get(node.attr)
+        raise ExpressionError(f"Cannot read '{node.attr}' from {type(value).__name__}")
+
+    if isinstance(node, ast.Subscript):
+        value = _eval(node.value, context)
+        key = _eval(node.slice, context)
+        try:
+            return value[key]
+        except (KeyError, IndexError, TypeError):
+            return None
+
+    if isinstance(node, ast.BoolOp):
+        if isinstance(node.op, ast.And):
+            result = True
+            for value_node in node.values:
+                result = _eval(value_node, context)
+                if not result:
+                    return result
+            return result
+        result = False
+        for value_node in node.values:
+            result = _eval(value_node, context)
+            if result:
+                return result
+        return result
+
+    if isinstance(node, ast.UnaryOp) and type(node.op) in UNARY_OPERATORS:
+        return UNARY_OPERATORS[type(node.op)](_eval(node.operand, context))
+
+    if isinstance(node, ast.BinOp) and type(node.op) in BINARY_OPERATORS:
+        left = _eval(node.left, context)
+        right = _eval(node.right, context)
+        if isinstance(node.op, ast.Mult):
+            _check_repeat(left, right)
+        if isinstance(node.op, ast.Mod) and isinstance(left, str):
+            _check_format(left)
+        try:
+            return BINARY_OPERATORS[type(node.op)](left, right)
+        except (TypeError, ZeroDivisionError) as e:
+            raise ExpressionError(str(e))
+
+    if isinstance(node, ast.Compare):
+        left = _eval(node.left, context)
+        for op, comparator in zip(node.ops, node.comparators):
+            right = _eval(comparator, context)
+            try:
+                if not COMPARE_OPERATORS[type(op)](left, right):
+                    return False
+            except TypeError:
+                return False
+            left = right
+        return True
+
+    if isinstance(node, ast.IfExp):
+        return _eval(node.body, context) if _eval(node.test, context) else _eval(node.orelse, context)
+
+    if isinstance(node, (ast.List, ast.Tuple)):
+        return [_eval(element, context) for element in node.elts]
+
+    if isinstance(node, ast.Dict):
+        return {_eval(k, context): _eval(v, context) for k, v in zip(node.keys, node.values)}
+
+    if isinstance(node, ast.Call) and not node.keywords:
+        args = [_eval(arg, context) for arg in node.args]
+
+        if isinstance(node.func, ast.Name) and node.func.id in FUNCTIONS:
+            try:
+                return FUNCTIONS[node.func.id](*args)
+            except (TypeError, ValueError) as e:
+                raise ExpressionError(str(e))
+
+        if isinstance(node.func, ast.Attribute):
+            target = _eval(node.func.value, context)
+            allowed = METHODS.get(type(target), set())
+            if node.func.attr in allowed:
+                if node.func.attr == 'replace':
+                    _check_replace(target, args)
+                try:
+                    return getattr(target, node.func.attr)(*args)
+                except (TypeError, ValueError) as e:
+                    raise ExpressionError(str(e))
+
+        raise ExpressionError(f"Function call not allowed: {ast.unparse(node.func)}")
+
+    raise ExpressionError(f"Unsupported syntax: {type(node).__name__}")
+
+def _check_repeat(left: Any, right: Any):
+    """Stop 'x' * 10**9 style memory blowups"""
+    for sequence, count in ((left, right), (right, left)):
+        if isinstance(sequence, (str, list)) and isinstance(count, int) and count * max(len(sequence), 1) > MAX_SEQUENCE_REPEAT:
+            raise ExpressionError("Repeated sequence is too large")
+
+def _check_replace(text: str, args: list):
+    """Stop ('a' * 1000).replace('a', 'b' * 1000) style blowups, which grow without '*'"""
+    if len(args) < 2 or not isinstance(args[0], str) or not isinstance(args[1], str):
+        return
+    old, new = args[0], args[1]
+    count = text.count(old)
+    if len(args) > 2 and isinstance(args[2], int) and args[2] >= 0:
+        count = min(count, args[2])
+    if len(text) + count * max(0, len(new) - len(old)) > MAX_SEQUENCE_REPEAT:
+        raise ExpressionError("Replaced string is too large")
+
+def _check_format(template: str):
+    """Stop '%999999999s' % x, which pads without '*'"""
+    for size in (size for match in FORMAT_SIZES.findall(template) for size in match):
+        if size == '*' or (size and int(size) > MAX_SEQUENCE_REPEAT):
+            raise ExpressionError("Formatted string is too large")
@@ -0,0 +1,176 @@
+"""
+FlashFlow request validation - Check JSON bodies against OpenAPI schemas
+
+Schemas are the ones published in /api/data/openapi.json, so a body that
+passes here matches the contract clients are generated from. Only the OpenAPI
+subset used for flow models is understood:
+
+    type, format (date, date-time, email, uri), enum, nullable, readOnly,
+    required, properties, additionalProperties, items,
+    minLength, maxLength, minimum, maximum
+
+Every problem is reported against the field it concerns, e.g.
+
+    {"field": "due_date", "code": "format", "message": "must be a date (YYYY-MM-DD)"}
+"""
+
+import re
+from dataclasses import dataclass
+from datetime import date, datetime
+from typing import Any, Dict, List
+from urllib.parse import urlparse
+
+# Stop collecting after this many problems; the first ones are enough to fix a client
+MAX_ERRORS = 50
+
+EMAIL_PATTERN = re.compile(r'^[^@\s]+@[^@\s]+\.[^@\s]+$')
+
+TYPE_NAMES = {
+    'integer': 'an integer', 'number': 'a number', 'string': 'a string',
+    'boolean': 'true or false', 'object': 'an object', 'array': 'an array'
+}
+
+@dataclass
+class FieldError:
+    """One problem with one field of a request body"""
+    field: str
+    code: str
+    message: str
+
+    def to_dict(self) -> Dict[str, str]:
+        return {'field': self.field, 'code': self.code, 'message': self.message}
+
+class SchemaValidationError(Exception):
+    """Raised when a request body does not match its schema"""
+
+    def __init__(self, subject: str, errors: List[FieldError]):
+        self.subject = subject
+        self.errors = errors
+        count = f"{len(errors)} problem" + ("s" if len(errors) != 1 else "")
+        super().__init__(f"Invalid {subject}: {count}")
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'error': str(self), 'errors': [error.to_dict() for error in self.errors]}
+
+def validate_body(body: Any, schema: Dict[str, Any], subject: str, partial: bool = False, strict: bool = False):
+    """
+    Raise SchemaValidationError unless 'body' matches 'schema'.
+
+    'partial' skips 'required' at the top level (PATCH-style updates); 'strict'
+    rejects fields the schema does not declare and readOnly fields, even where
+    the schema itself allows additional properties.
+    """
+    errors: List[FieldError] = []
+    _check(body, schema, '', errors, partial, strict)
+    if errors:
+        raise SchemaValidationError(subject, errors[:MAX_ERRORS])
+
+def _check(value: Any, schema: Dict[str, Any], field: str, errors: List[FieldError], partial: bool, strict: bool):
+    if len(errors) >= MAX_ERRORS or not schema:
+        return
+    label = field or 'body'
+
+    if value is None:
+        if schema.get('nullable') or 'type' not in schema:
+            return
+        errors.append(FieldError(label, 'null', f"must not be null; expected {TYPE_NAMES.get(schema['type'], schema['type'])}"))
+        return
+
+    expected = schema.get('type')
+    if expected and not _is_type(value, expected):
+        errors.append(FieldError(label, 'type', f"must be {TYPE_NAMES.get(expected, expected)}, got {_json_type(value)}"))
+        return
+
+    if 'enum' in schema and value not in schema['enum']:
+        choices = ', '.join(repr(choice) for choice in schema['enum'])
+        errors.append(FieldError(label, 'enum', f"must be one of {choices}"))
+        return
+
+    if isinstance(value, str):
+        _check_string(value, schema, label, errors)
+    elif isinstance(value, (int, float)) and not isinstance(value, bool):
+        if 'minimum' in schema and value < schema['minimum']:
+            errors.append(FieldError(label, 'minimum', f"must be at least {schema['minimum']}"))
+        if 'maximum' in schema and value > schema['maximum']:
+            errors.append(FieldError(label, 'maximum', f"must be at most {schema['maximum']}"))
+    elif isinstance(value, list):
+        for index, item in enumerate(value):
+            _check(item, schema.get('items') or {}, f"{field}[{index}]", errors, False, strict)
+    elif isinstance(value, dict):
+        _check_object(value, schema, field, errors, partial, strict)
+
+def _check_object(value: Dict[str, Any], schema: Dict[str, Any], field: str, errors: List[FieldError], partial: bool, strict: bool):
+    properties = schema.get('properties') or {}
+    prefix = f"{field}." if field else ''
+
+    if not partial:
+        for name in schema.get('required', []):
+            if name not in value:
+                errors.append(FieldError(prefix + name, 'required', "is required"))
+
+    closed = strict or schema.get('additionalProperties') is False
+    for name, item in value.items():
+        if name not in properties:
+            if closed and 'properties' in schema:
+                errors.append(FieldError(prefix + name, 'unknown', "is not a field of this schema"))
+            continue
+        if properties[name].get('readOnly'):
+            if strict:
+                errors.append(FieldError(prefix + name, 'read_only', "is read-only and cannot be set"))
+            continue
+        _check(item, properties[name], prefix + name, errors, False, strict)
+
+def _check_string(value: str, schema: Dict[str, Any], field: str, errors: List[FieldError]):
+    if 'minLength' in schema and len(value) < schema['minLength']:
+        errors.append(FieldError(field, 'min_length', f"must be at least {schema['minLength']} characters"))
+    if 'maxLength' in schema and len(value) > schema['maxLength']:
+        errors.append(FieldError(field, 'max_length', f"must be at most {schema['maxLength']} characters"))
+
+    kind = schema.get('format')
+    if kind == 'date' and not _parses(date.fromisoformat, value):
+        errors.append(FieldError(field, 'format', "must be a date (YYYY-MM-DD)"))
+    elif kind == 'date-time' and not _parses(datetime.fromisoformat, value):
+        errors.append(FieldError(field, 'format', "must be an ISO 8601 date and time"))
+    elif kind == 'email' and not EMAIL_PATTERN.match(value):
+        errors.append(FieldError(field, 'format', "must be an email address"))
+    elif kind == 'uri':
+        parsed = urlparse(value)
+        if not (parsed.scheme and parsed.netloc):
+            errors.append(FieldError(field, 'format', "must be an absolute URL"))
+
+def _parses(parse, value: str) -> bool:
+    try:
+        parse(value)
+        return True
+    except ValueError:
+        return False
+
+def _is_type(value: Any, expected: str) -> bool:
+    if expected == 'integer':
+        return (isinstance(value, int) and not isinstance(value, bool)) or (isinstance(value, float) and value.is_integer())
+    if expected == 'number':
+        return isinstance(value, (int, float)) and not isinstance(value, bool)
+    if expected == 'string':
+        return isinstance(value, str)
+    if expected == 'boolean':
+        return isinstance(value, bool)
+    if expected == 'object':
+        return isinstance(value, dict)
+    if expected == 'array':
+        return isinstance(value, list)
+    return True
+
+def _json_type(value: Any) -> str:
+    if isinstance(value, bool):
+        return 'a boolean'
+    if isinstance(value, int):
+        return 'an integer'
+    if isinstance(value, float):
+        return 'a number'
+    if isinstance(value, str):
+        return 'a string'
+    if isinstance(value, list):
+        return 'an array'
+    if isinstance(value, dict):
+        return 'an object'
+    return type(value).__name__
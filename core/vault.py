@@ -0,0 +1,195 @@
+"""
+FlashFlow vault - AES-256-GCM sealing with an explicit wire format
+
+Every sealed value is a JSON envelope that any AES-GCM implementation can
+open, so generated backends and other services interoperate with it:
+
+    {
+      "v": 1,
+      "alg": "A256GCM",
+      "kid": "3f2a9c1d",              first 8 hex digits of SHA-256(key)
+      "iv": "<base64, 12 bytes>",     random for every encryption, never reused
+      "ciphertext": "<base64>",       same length as the plaintext
+      "tag": "<base64, 16 bytes>",    GCM authentication tag
+      "aad": "<base64>"               optional associated data, authenticated but not encrypted
+    }
+
+All binary fields are standard base64 with padding. Decrypting checks the
+tag over the ciphertext and the aad; a tampered, truncated or re-labelled
+envelope is refused with VaultTamperError instead of returning garbage.
+
+The key is 32 bytes, given as base64 in FLASHFLOW_VAULT_KEY. Without it the
+dev server generates one in .flashflow/vault.key, which is only meant for
+local development. IVs are random, so rotate a key before it has sealed
+2**32 values.
+"""
+
+import base64
+import binascii
+import hashlib
+import os
+from pathlib import Path
+from typing import Dict, Any, Optional, Tuple
+
+WIRE_VERSION = 1
+ALGORITHM = 'A256GCM'
+KEY_BYTES = 32
+IV_BYTES = 12
+TAG_BYTES = 16
+KEY_ENV = 'FLASHFLOW_VAULT_KEY'
+ENVELOPE_FIELDS = {'v', 'alg', 'kid', 'iv', 'ciphertext', 'tag', 'aad'}
+KEY_FILE_ATTEMPTS = 5
+
+class VaultError(ValueError):
+    """Raised for a malformed request or envelope, or a key that cannot be used"""
+    pass
+
+class VaultTamperError(VaultError):
+    """Raised when an envelope does not authenticate under the vault's key"""
+    pass
+
+class VaultUnavailableError(Exception):
+    """Raised when no AES-GCM implementation is installed"""
+    pass
+
+def b64decode(value: Any, field: str) -> bytes:
+    if not isinstance(value, str):
+        raise VaultError(f"'{field}' must be a base64 string")
+    try:
+        return base64.b64decode(value, validate=True)
+    except (binascii.Error, ValueError):
+        raise VaultError(f"'{field}' is not valid base64")
+
+def b64encode(data: bytes) -> str:
+    return base64.b64encode(data).decode('ascii')
+
+def key_id(key: bytes) -> str:
+    return hashlib.sha256(key).hexdigest()[:8]
+
+def _read_key_line(key_file: Path) -> str:
+    try:
+        return key_file.read_text().strip()
+    except FileNotFoundError:
+        return ''
+
+def _dev_key_line(key_file: Path) -> str:
+    """The key line in key_file, generated first when the file is missing or empty.
+
+    A new key is written to an owner-only temp file and linked into place, so
+    key_file never exists half-written and a key another process linked first
+    is never overwritten; the process that loses the race reads the winner's.
+    """
+    for _ in range(KEY_FILE_ATTEMPTS):
+        line = _read_key_line(key_file)
+        if line:
+            return line
+        key_file.parent.mkdir(parents=True, exist_ok=True)
+        if key_file.exists():
+            # Left empty by a writer that died; moved aside rather than deleted, in case a key lands meanwhile
+            aside = key_file.with_name(f"{key_file.name}.{os.getpid()}.{os.urandom(4).hex()}.empty")
+            try:
+                os.rename(key_file, aside)
+            except FileNotFoundError:
+                continue
+            if _read_key_line(aside):
+                try:
+                    os.link(aside, key_file)
+                except FileExistsError:
+                    pass
+            os.unlink(aside)
+            continue
+        partial = key_file.with_name(f"{key_file.name}.{os.getpid()}.{os.urandom(4).hex()}.tmp")
+        fd = os.open(str(partial), os.O_CREAT | os.O_EXCL | os.O_WRONLY, 0o600)
+        try:
+            with os.fdopen(fd, 'w') as f:
+                f.write(b64encode(os.urandom(KEY_BYTES)) + '\n')
+                f.flush()
+                os.fsync(f.fileno())
+            os.link(partial, key_file)
+        except FileExistsError:
+            pass
+        finally:
+            os.unlink(partial)
+    raise VaultError(f"Could not read or create the vault key in {key_file}")
+
+def load_key(key_file: Optional[Path] = None) -> Tuple[bytes, str]:
+    """The vault key and where it came from: FLASHFLOW_VAULT_KEY, or a generated dev key file"""
+    configured = os.environ.get(KEY_ENV)
+    if configured:
+        key = b64decode(configured.strip(), KEY_ENV)
+        source = f"env:{KEY_ENV}"
+    elif key_file is not None:
+        key_file = Path(key_file)
+        key = b64decode(_dev_key_line(key_file), str(key_file))
+        source = f"file:{key_file}"
+    else:
+        raise VaultError(f"No vault key; set {KEY_ENV} to 32 random bytes in base64")
+    if len(key) != KEY_BYTES:
+        raise VaultError(f"The vault key from {source} is {len(key)} bytes, AES-256 needs {KEY_BYTES}")
+    return key, source
+
+def _aesgcm(key: bytes):
+    try:
+        from cryptography.hazmat.primitives.ciphers.aead import AESGCM
+    except ImportError:
+        raise VaultUnavailableError("AES-GCM needs the 'cryptography' package (pip install cryptography)")
+    return AESGCM(key)
+
+class Vault:
+    """Seals and opens envelopes under one key"""
+
+    def __init__(self, key: bytes, source: str = ''):
+        if len(key) != KEY_BYTES:
+            raise VaultError(f"AES-256 needs a {KEY_BYTES}-byte key, not {len(key)} bytes")
+        self._key = key
+        self.kid = key_id(key)
+        self.source = source
+        self.sealed = 0
+
+    def encrypt(self, plaintext: bytes, aad: bytes = b'') -> Dict[str, Any]:
+        iv = os.urandom(IV_BYTES)
+        sealed = _aesgcm(self._key).encrypt(iv, plaintext, aad or None)
+        self.sealed += 1
+        envelope = {
+            'v': WIRE_VERSION,
+            'alg': ALGORITHM,
+            'kid': self.kid,
+            'iv': b64encode(iv),
+            'ciphertext': b64encode(sealed[:-TAG_BYTES]),
+            'tag': b64encode(sealed[-TAG_BYTES:])
+        }
+        if aad:
+            envelope['aad'] = b64encode(aad)
+        return envelope
+
+    def decrypt(self, envelope: Dict[str, Any]) -> bytes:
+        if not isinstance(envelope, dict):
+            raise VaultError("An envelope must be a JSON object")
+        unknown = set(envelope) - ENVELOPE_FIELDS
+        if unknown:
+            raise VaultError(f"Unknown envelope field(s): {', '.join(sorted(unknown))}")
+        if envelope.get('v') != WIRE_VERSION:
+            raise VaultError(f"Unsupported envelope version {envelope.get('v')!r}; this vault reads v{WIRE_VERSION}")
+        if envelope.get('alg') != ALGORITHM:
+            raise VaultError(f"Unsupported algorithm {envelope.get('alg')!r}; this vault uses {ALGORITHM}")
+        if 'kid' in envelope and envelope['kid'] != self.kid:
+            raise VaultError(f"Envelope was sealed with key {envelope['kid']!r}, this vault has {self.kid!r}")
+        iv = b64decode(envelope.get('iv'), 'iv')
+        ciphertext = b64decode(envelope.get('ciphertext'), 'ciphertext')
+        tag = b64decode(envelope.get('tag'), 'tag')
+        aad = b64decode(envelope['aad'], 'aad') if 'aad' in envelope else b''
+        if len(iv) != IV_BYTES:
+            raise VaultError(f"'iv' must be {IV_BYTES} bytes, not {len(iv)}")
+        if len(tag) != TAG_BYTES:
+            raise VaultError(f"'tag' must be {TAG_BYTES} bytes, not {len(tag)}")
+
+        aesgcm = _aesgcm(self._key)
+        from cryptography.exceptions import InvalidTag
+        try:
+            return aesgcm.decrypt(iv, ciphertext + tag, aad or None)
+        except InvalidTag:
+            raise VaultTamperError("Envelope failed authentication: the ciphertext, iv, tag or aad was altered")
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'version': WIRE_VERSION, 'alg': ALGORITHM, 'kid': self.kid, 'key_source': self.source,
+                'iv_bytes': IV_BYTES, 'tag_bytes': TAG_BYTES, 'sealed': self.sealed}
@@ -0,0 +1,162 @@
+"""
+FlashFlow vector collections - Vectors, their ids and metadata as JSONL or Parquet files
+
+A collection is a named vector index (see cli/devserver/vector_search.py).
+Exported, each vector is one record:
+
+    {"id": 7, "vector": [0.12, -0.4, ...], "metadata": {"lang": "en"}, "document": "text it embeds"}
+
+JSONL files hold one record per line. Parquet files have the columns id
+(int64 or string), vector (list<float32>), metadata (a JSON string, since
+metadata objects differ per record) and document (string); they need
+pyarrow. Both load into other vector databases as well as back into a
+FlashFlow server with 'flashflow vectors import'.
+
+The format follows the file name (.jsonl, .ndjson, .parquet) unless given.
+"""
+
+import io
+import json
+import math
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Any, BinaryIO, Dict, Iterable, List, Optional, Union
+
+FORMATS = ('jsonl', 'parquet')
+EXTENSIONS = {'.jsonl': 'jsonl', '.ndjson': 'jsonl', '.parquet': 'parquet'}
+CONTENT_TYPES = {'jsonl': 'application/x-ndjson', 'parquet': 'application/vnd.apache.parquet'}
+
+class VectorCollectionError(ValueError):
+    """Raised for a record or file that cannot be exported or imported"""
+    pass
+
+@dataclass
+class VectorRecord:
+    """One vector of a collection"""
+    id: Any
+    vector: List[float]
+    metadata: Optional[Dict[str, Any]] = None
+    document: Optional[str] = None
+
+    def to_dict(self) -> Dict[str, Any]:
+        data = {'id': self.id, 'vector': self.vector}
+        if self.metadata:
+            data['metadata'] = self.metadata
+        if self.document is not None:
+            data['document'] = self.document
+        return data
+
+    @classmethod
+    def from_dict(cls, data: Any, where: str) -> 'VectorRecord':
+        if not isinstance(data, dict):
+            raise VectorCollectionError(f"{where}: a record must be an object with 'id' and 'vector'")
+        unknown = set(data) - {'id', 'vector', 'metadata', 'document'}
+        if unknown:
+            raise VectorCollectionError(f"{where}: unknown field(s) {', '.join(sorted(unknown))}")
+        vector_id = data.get('id')
+        if isinstance(vector_id, bool) or not isinstance(vector_id, (int, str)) or vector_id == '':
+            raise VectorCollectionError(f"{where}: 'id' must be an integer or a non-empty string")
+        vector = data.get('vector')
+        if not isinstance(vector, list) or not vector or not all(
+                isinstance(value, (int, float)) and not isinstance(value, bool) and math.isfinite(value) for value in vector):
+            raise VectorCollectionError(f"{where}: 'vector' must be a non-empty list of finite numbers")
+        metadata = data.get('metadata')
+        if metadata is not None and not isinstance(metadata, dict):
+            raise VectorCollectionError(f"{where}: 'metadata' must be an object")
+        document = data.get('document')
+        if document is not None and not isinstance(document, str):
+            raise VectorCollectionError(f"{where}: 'document' must be a string")
+        return cls(vector_id, [float(value) for value in vector], metadata or None, document)
+
+def format_for(name: Optional[str], requested: Optional[str] = None) -> str:
+    """The requested format, else the one the file name's extension stands for"""
+    if requested:
+        if requested not in FORMATS:
+            raise VectorCollectionError(f"Unknown format '{requested}'. Use one of: {', '.join(FORMATS)}")
+        return requested
+    suffix = Path(name or '').suffix.lower()
+    if suffix not in EXTENSIONS:
+        raise VectorCollectionError(f"Cannot tell the format of '{name}'; name it .jsonl or .parquet, or pass a format")
+    return EXTENSIONS[suffix]
+
+def check_dimensions(records: List[VectorRecord], dimension: Optional[int] = None) -> Optional[int]:
+    """The records' common dimension; they must all match it (and dimension, when given)"""
+    for position, record in enumerate(records):
+        if dimension is None:
+            dimension = len(record.vector)
+        elif len(record.vector) != dimension:
+            raise VectorCollectionError(f"Record {position + 1} (id {record.id!r}) has {len(record.vector)} dimensions, "
+                                        f"expected {dimension}")
+    return dimension
+
+def write_records(records: Iterable[VectorRecord], target: Union[Path, str, BinaryIO], format: str) -> int:
+    """Write records to a path or binary stream; returns how many were written"""
+    records = list(records)
+    check_dimensions(records)
+    if isinstance(target, (str, Path)):
+        with open(target, 'wb') as stream:
+            return write_records(records, stream, format)
+    if format == 'jsonl':
+        for record in records:
+            target.write((json.dumps(record.to_dict(), ensure_ascii=False) + '\n').encode('utf-8'))
+        return len(records)
+    pa, pq = _pyarrow()
+    ids = [record.id for record in records]
+    if any(isinstance(vector_id, str) for vector_id in ids) and not all(isinstance(vector_id, str) for vector_id in ids):
+        raise VectorCollectionError("Parquet needs ids of one type; these mix integers and strings (export as JSONL)")
+    table = pa.table({
+        'id': pa.array(ids, type=pa.string() if ids and isinstance(ids[0], str) else pa.int64()),
+        'vector': pa.array([record.vector for record in records], type=pa.list_(pa.float32())),
+        'metadata': pa.array([json.dumps(record.metadata) if record.metadata else None for record in records], type=pa.string()),
+        'document': pa.array([record.document for record in records], type=pa.string()),
+    })
+    pq.write_table(table, target)
+    return len(records)
+
+def read_records(source: Union[Path, str, BinaryIO, bytes], format: str) -> List[VectorRecord]:
+    """Records from a path, binary stream or bytes; every record is checked before any is returned"""
+    if isinstance(source, (str, Path)):
+        try:
+            with open(source, 'rb') as stream:
+                return read_records(stream.read(), format)
+        except OSError as e:
+            raise VectorCollectionError(f"Cannot read {source}: {e.strerror or str(e)}")
+    data = source if isinstance(source, bytes) else source.read()
+    if format == 'jsonl':
+        records = []
+        for number, line in enumerate(data.decode('utf-8-sig', errors='replace').splitlines(), 1):
+            if not line.strip():
+                continue
+            try:
+                records.append(VectorRecord.from_dict(json.loads(line), f"Line {number}"))
+            except ValueError as e:
+                if isinstance(e, VectorCollectionError):
+                    raise
+                raise VectorCollectionError(f"Line {number}: not JSON ({str(e)})")
+    else:
+        pa, pq = _pyarrow()
+        try:
+            table = pq.read_table(io.BytesIO(data))
+        except (pa.ArrowException, OSError) as e:
+            raise VectorCollectionError(f"Not a Parquet file: {str(e)}")
+        missing = {'id', 'vector'} - set(table.column_names)
+        if missing:
+            raise VectorCollectionError(f"Parquet file has no {' or '.join(sorted(missing))} column")
+        records = []
+        for number, row in enumerate(table.to_pylist(), 1):
+            try:
+                row['metadata'] = json.loads(row['metadata']) if row.get('metadata') else None
+            except ValueError:
+                raise VectorCollectionError(f"Row {number}: 'metadata' is not JSON")
+            row = {key: value for key, value in row.items() if key in ('id', 'vector', 'metadata', 'document')}
+            records.append(VectorRecord.from_dict(row, f"Row {number}"))
+    check_dimensions(records)
+    return records
+
+def _pyarrow():
+    try:
+        import pyarrow
+        import pyarrow.parquet
+    except ImportError:
+        raise VectorCollectionError("Parquet needs pyarrow: pip install pyarrow (or use .jsonl)")
+    return pyarrow, pyarrow.parquet
@@ -0,0 +1,230 @@
+"""
+FlashFlow vector search - k-NN and radius queries with normalized scores
+
+Works on a FlashCore HNSWIndex, or on ExactIndex when FlashCore is not built:
+
+    search(index, query, SearchOptions(k=10))                     10 nearest
+    search(index, query, SearchOptions(radius=0.8))               everything within 0.8
+    search(index, query, SearchOptions(k=5, radius=0.8))          5 nearest, if within 0.8
+    search(index, query, SearchOptions(k=5, ef_search=200))       wider HNSW beam for this query
+
+Each hit carries the raw (Euclidean) distance and a similarity score in [0, 1],
+higher is better, so callers can rank or threshold without knowing the metric:
+
+    inverse     1 / (1 + distance)                         (default)
+    exp         exp(-distance)
+    minmax      1 for the nearest hit, 0 for the farthest one returned
+    radius      1 - distance / radius                      (radius queries only)
+
+search(..., cancel=token) stops with Cancelled (core/cancellation.py) when
+the token is cancelled: exact indexes check it between chunks of vectors,
+radius queries between rounds, FlashCore indexes through the binding.
+"""
+
+import math
+from contextlib import contextmanager
+from dataclasses import dataclass
+from typing import Dict, Any, Iterator, List, Optional, Sequence, Tuple
+
+from core.cancellation import CancelToken, check
+from core.flashcore_handles import FlashCoreHandle
+
+NORMALIZATIONS = ['inverse', 'exp', 'minmax', 'radius']
+DEFAULT_K = 10
+# Radius queries grow k from here until the ball is covered
+RADIUS_START_K = 32
+MAX_RADIUS_RESULTS = 10000
+# Vectors an exact search compares between looks at its cancel token
+EXACT_CHUNK = 4096
+
+class VectorSearchError(ValueError):
+    """Raised for invalid search options or an index that cannot honour them"""
+    pass
+
+@dataclass
+class SearchOptions:
+    """One query's settings"""
+    k: Optional[int] = DEFAULT_K
+    radius: Optional[float] = None
+    normalize: str = 'inverse'
+    ef_search: Optional[int] = None
+
+    def __post_init__(self):
+        if self.k is None and self.radius is None:
+            raise VectorSearchError("Give k, radius or both")
+        if self.k is not None and (isinstance(self.k, bool) or not isinstance(self.k, int) or self.k < 1):
+            raise VectorSearchError(f"k must be a positive integer, not {self.k!r}")
+        if self.radius is not None and (isinstance(self.radius, bool) or not isinstance(self.radius, (int, float)) or self.radius < 0):
+            raise VectorSearchError(f"radius must be a number >= 0, not {self.radius!r}")
+        if self.normalize not in NORMALIZATIONS:
+            raise VectorSearchError(f"Unknown normalize '{self.normalize}'. Use one of: {', '.join(NORMALIZATIONS)}")
+        if self.normalize == 'radius' and self.radius is None:
+            raise VectorSearchError("normalize 'radius' needs a radius")
+        if self.ef_search is not None and (isinstance(self.ef_search, bool) or not isinstance(self.ef_search, int) or self.ef_search < 1):
+            raise VectorSearchError(f"ef_search must be a positive integer, not {self.ef_search!r}")
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> 'SearchOptions':
+        """Options from a JSON request; k defaults to 10 unless only a radius is given"""
+        unknown = set(data) - {'k', 'radius', 'normalize', 'ef_search'}
+        if unknown:
+            raise VectorSearchError(f"Unknown search option(s): {', '.join(sorted(unknown))}")
+        k = data['k'] if 'k' in data else (None if data.get('radius') is not None else DEFAULT_K)
+        return cls(k=k, radius=data.get('radius'), normalize=data.get('normalize', 'inverse'), ef_search=data.get('ef_search'))
+
+@dataclass
+class Hit:
+    id: Any
+    distance: float
+    score: float = 0.0
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {'id': self.id, 'distance': self.distance, 'score': self.score}
+
+class ExactIndex:
+    """Brute-force index with the HNSWIndex interface, for machines without FlashCore"""
+
+    def __init__(self, dimension: int, max_elements: int = 0):
+        self.dimension = dimension
+        self.max_elements = max_elements
+        self.vectors: Dict[Any, List[float]] = {}
+
+    def add_vector(self, vector: Sequence[float], id: Any):
+        values = _as_list(vector)
+        if len(values) != self.dimension:
+            raise VectorSearchError(f"Vector has {len(values)} dimensions, the index has {self.dimension}")
+        if self.max_elements and id not in self.vectors and len(self.vectors) >= self.max_elements:
+            raise VectorSearchError(f"Index is full ({self.max_elements} vectors)")
+        self.vectors[id] = values
+
+    def search(self, query: Sequence[float], k: int, cancel: Optional[CancelToken] = None) -> List[Tuple[Any, float]]:
+        values = _as_list(query)
+        items = list(self.vectors.items())
+        distances = []
+        for start in range(0, len(items), EXACT_CHUNK):
+            check(cancel)
+            distances.extend((id, math.dist(values, vector)) for id, vector in items[start:start + EXACT_CHUNK])
+        return sorted(distances, key=lambda pair: pair[1])[:k]
+
+    def __len__(self) -> int:
+        return len(self.vectors)
+
+def search(index, query: Sequence[float], options: SearchOptions, cancel: Optional[CancelToken] = None) -> Dict[str, Any]:
+    """Run one query; returns the hits plus what was actually applied"""
+    with _ef_search(index, options) as ef_applied:
+        if options.radius is None:
+            hits = _knn(index, query, options.k, cancel)
+            truncated = False
+        else:
+            hits, truncated = _within(index, query, options.radius, options.k, cancel)
+
+    normalize(hits, options.normalize, options.radius)
+    return {
+        'results': [hit.to_dict() for hit in hits],
+        'count': len(hits),
+        'k': options.k,
+        'radius': options.radius,
+        'normalize': options.normalize,
+        'ef_search': ef_applied,
+        'truncated': truncated
+    }
+
+def normalize(hits: List[Hit], method: str, radius: Optional[float] = None):
+    """Set each hit's similarity score from its distance"""
+    if not hits:
+        return
+    if method == 'minmax':
+        nearest, farthest = hits[0].distance, hits[-1].distance
+        spread = farthest - nearest
+        for hit in hits:
+            hit.score = 1.0 if spread == 0 else 1.0 - (hit.distance - nearest) / spread
+    elif method == 'radius':
+        for hit in hits:
+            hit.score = 1.0 if radius == 0 else max(0.0, 1.0 - hit.distance / radius)
+    elif method == 'exp':
+        for hit in hits:
+            hit.score = math.exp(-hit.distance)
+    else:
+        for hit in hits:
+            hit.score = 1.0 / (1.0 + hit.distance)
+
+def index_size(index) -> Optional[int]:
+    for name in ('__len__', 'get_current_count', 'size'):
+        method = getattr(index, name, None)
+        if callable(method):
+            try:
+                return int(method())
+            except (TypeError, ValueError):
+                continue
+    return None
+
+def _knn(index, query, k: int, cancel: Optional[CancelToken] = None) -> List[Hit]:
+    size = index_size(index)
+    if size is not None:
+        k = min(k, size)
+    if k <= 0:
+        return []
+    if isinstance(index, FlashCoreHandle):
+        found = index.cancellable(cancel, 'search', query, k)
+    elif isinstance(index, ExactIndex):
+        found = index.search(query, k, cancel)
+    else:
+        found = index.search(query, k)
+        check(cancel)
+    hits = [_hit(item) for item in found]
+    return sorted(hits, key=lambda hit: hit.distance)
+
+def _within(index, query, radius: float, k: Optional[int],
+            cancel: Optional[CancelToken] = None) -> Tuple[List[Hit], bool]:
+    """Hits within 'radius', at most k of them; True when MAX_RADIUS_RESULTS cut the ball short"""
+    if k is not None:
+        return [hit for hit in _knn(index, query, k, cancel) if hit.distance <= radius], False
+
+    size = index_size(index)
+    limit = min(size, MAX_RADIUS_RESULTS) if size is not None else MAX_RADIUS_RESULTS
+    want = min(RADIUS_START_K, limit)
+    while True:
+        hits = _knn(index, query, want, cancel)
+        # Done once the farthest hit is outside the ball or the index has nothing more to give
+        if not hits or hits[-1].distance > radius or len(hits) < want or want >= limit:
+            within = [hit for hit in hits if hit.distance <= radius]
+            return within, len(within) == want and want == MAX_RADIUS_RESULTS
+        want = min(want * 2, limit)
+
+@contextmanager
+def _ef_search(index, options: SearchOptions) -> Iterator[Optional[int]]:
+    """Apply a per-query efSearch and restore the index's own value afterwards"""
+    if options.ef_search is None:
+        yield None
+        return
+
+    setter = getattr(index, 'set_ef', None) or getattr(index, 'set_ef_search', None)
+    if setter is None:
+        if isinstance(index, ExactIndex):
+            # Exact search has no beam to widen
+            yield None
+            return
+        raise VectorSearchError("This FlashCore build cannot change efSearch per query; rebuild the bindings or drop ef_search")
+
+    # HNSW returns at most efSearch candidates, so it can never be below k
+    ef = max(options.ef_search, options.k or 0)
+    getter = getattr(index, 'get_ef', None) or getattr(index, 'get_ef_search', None)
+    previous = getter() if callable(getter) else getattr(index, 'ef', None)
+    setter(ef)
+    try:
+        yield ef
+    finally:
+        if previous is not None:
+            setter(previous)
+
+def _hit(item) -> Hit:
+    """Accept the result shapes FlashCore builds return: (id, distance), dicts or objects"""
+    if isinstance(item, dict):
+        return Hit(item['id'], float(item['distance']))
+    if isinstance(item, (tuple, list)):
+        return Hit(item[0], float(item[1]))
+    return Hit(item.id, float(item.distance))
+
+def _as_list(vector: Sequence[float]) -> List[float]:
+    values = vector.tolist() if hasattr(vector, 'tolist') else list(vector)
+    return [float(value) for value in values]
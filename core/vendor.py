@@ -0,0 +1,313 @@
+"""
+FlashFlow vendoring - Pinned copies of the tools FlashFlow shells out to, for offline machines
+
+    "vendor": {
+        "python": ["flet==0.21.2", "PyYAML==6.0.1"],
+        "node": ["axe-core@4.10.2", "playwright@1.47.0"],
+        "files": {
+            "flashcore": {"url": "https://example.com/flashcore-linux-x64.tar.gz", "sha256": "..."}
+        }
+    }
+
+'python' defaults to the engine's requirements.txt and 'node' to the axe-core
+build used by 'flashflow serve --a11y'. 'flashflow vendor' downloads them once
+while online into .flashflow/vendor:
+
+    python/wheels       every wheel pip resolved, installed into python/site
+    node                npm packages (node/node_modules/.bin) and npm's cache of their tarballs
+    files/<name>        each download, unpacked when it is a .zip or .tar.* archive
+
+and writes vendor.lock.json next to flashflow.json with the exact versions
+and a SHA-256 for every artifact. Commit the lock, copy .flashflow/vendor to
+the air-gapped machine and run 'flashflow vendor --offline' there: artifacts
+are checked against the lock before anything is installed from them. Once
+installed, the engine, scripts, hooks, plugins and the a11y audit use the
+vendored tools ahead of anything on the system.
+"""
+
+import hashlib
+import json
+import os
+import shutil
+import subprocess
+import sys
+import tarfile
+import time
+import urllib.request
+import zipfile
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Dict, Any, List, Optional
+
+LOCK_FILE_NAME = "vendor.lock.json"
+LOCK_VERSION = 1
+VENDOR_DIR_NAME = "vendor"
+ENGINE_REQUIREMENTS = Path(__file__).parent.parent / "python-services" / "flet-direct-renderer" / "requirements.txt"
+DEFAULT_NODE_PACKAGES = ['axe-core@4.10.2']
+AXE_VENDOR_PATH = Path("node") / "node_modules" / "axe-core" / "axe.min.js"
+ARCHIVE_SUFFIXES = ('.zip', '.tar', '.tar.gz', '.tgz', '.tar.bz2', '.tar.xz')
+
+class VendorError(Exception):
+    """Raised when vendoring fails or vendored files do not match the lock"""
+    pass
+
+def file_sha256(path: Path) -> str:
+    digest = hashlib.sha256()
+    with open(path, 'rb') as f:
+        for chunk in iter(lambda: f.read(1024 * 1024), b''):
+            digest.update(chunk)
+    return digest.hexdigest()
+
+@dataclass
+class VendorConfig:
+    """What to vendor, from the "vendor" block of flashflow.json"""
+    python: List[str] = field(default_factory=list)
+    node: List[str] = field(default_factory=list)
+    files: Dict[str, Dict[str, str]] = field(default_factory=dict)
+
+    @classmethod
+    def from_dict(cls, data: Optional[Dict[str, Any]]) -> 'VendorConfig':
+        data = dict(data or {})
+        unknown = set(data) - {'python', 'node', 'files'}
+        if unknown:
+            raise VendorError(f"Unknown vendor setting(s): {', '.join(sorted(unknown))}")
+
+        python = data.get('python', default_python_requirements())
+        node = data.get('node', DEFAULT_NODE_PACKAGES)
+        for name, value in (('python', python), ('node', node)):
+            if not isinstance(value, list) or not all(isinstance(item, str) and item.strip() for item in value):
+                raise VendorError(f"vendor.{name} must be a list of package specifiers")
+
+        files = data.get('files') or {}
+        if not isinstance(files, dict):
+            raise VendorError("vendor.files must map a name to {url, sha256}")
+        for name, spec in files.items():
+            if not isinstance(spec, dict) or not isinstance(spec.get('url'), str):
+                raise VendorError(f"vendor.files.{name} needs a 'url'")
+            if '/' in name or '\\' in name or name in ('', '.', '..'):
+                raise VendorError(f"vendor.files.{name}: the name is used as a directory and cannot contain slashes")
+        return cls(python=list(python), node=list(node), files=dict(files))
+
+    @classmethod
+    def for_project(cls, project) -> 'VendorConfig':
+        return cls.from_dict(getattr(project.config, 'vendor', None))
+
+def default_python_requirements() -> List[str]:
+    if not ENGINE_REQUIREMENTS.exists():
+        return []
+    lines = ENGINE_REQUIREMENTS.read_text(encoding='utf-8').splitlines()
+    return [line.strip() for line in lines if line.strip() and not line.strip().startswith('#')]
+
+def vendor_dir(project) -> Path:
+    return project.state.dir / VENDOR_DIR_NAME
+
+def lock_path(project) -> Path:
+    return project.root_path / LOCK_FILE_NAME
+
+def read_lock(project) -> Optional[Dict[str, Any]]:
+    path = lock_path(project)
+    if not path.exists():
+        return None
+    try:
+        return json.loads(path.read_text(encoding='utf-8'))
+    except (OSError, json.JSONDecodeError) as e:
+        raise VendorError(f"Cannot read {LOCK_FILE_NAME}: {e}")
+
+def vendor(project, config: VendorConfig, log=print) -> Dict[str, Any]:
+    """Download and install everything in 'config', then write and return the lock"""
+    root = vendor_dir(project)
+    root.mkdir(parents=True, exist_ok=True)
+    lock: Dict[str, Any] = {'version': LOCK_VERSION, 'created_at': time.strftime('%Y-%m-%dT%H:%M:%SZ', time.gmtime())}
+
+    if config.python:
+        log(f"🐍 Downloading {len(config.python)} Python requirement(s) and their dependencies...")
+        lock['python'] = _download_python(root / "python", config.python)
+        _install_python(root / "python", lock['python'])
+    if config.node:
+        log(f"📦 Installing {len(config.node)} npm package(s)...")
+        lock['node'] = _install_node(root / "node", config.node, offline=False)
+    if config.files:
+        lock['files'] = {}
+        for name, spec in config.files.items():
+            log(f"⬇️  {name}: {spec['url']}")
+            lock['files'][name] = _download_file(root / "files", name, spec)
+
+    lock_path(project).write_text(json.dumps(lock, indent=2) + "\n", encoding='utf-8')
+    return lock
+
+def install_offline(project, log=print) -> Dict[str, Any]:
+    """Install from the artifacts already in .flashflow/vendor after checking them against the lock"""
+    lock = read_lock(project)
+    if lock is None:
+        raise VendorError(f"No {LOCK_FILE_NAME}; run 'flashflow vendor' on a machine with network access first")
+    problems = verify_artifacts(project, lock)
+    if problems:
+        raise VendorError("Vendored files do not match the lock:\n" + "\n".join(f"  {problem}" for problem in problems))
+
+    root = vendor_dir(project)
+    if lock.get('python'):
+        log("🐍 Installing Python packages from vendored wheels...")
+        _install_python(root / "python", lock['python'])
+    if lock.get('node'):
+        log("📦 Installing npm packages from the vendored cache...")
+        _install_node(root / "node", lock['node']['packages'], offline=True)
+    for name, entry in (lock.get('files') or {}).items():
+        _unpack(root / "files" / name / entry['file'], root / "files" / name)
+    return lock
+
+def verify_artifacts(project, lock: Optional[Dict[str, Any]] = None) -> List[str]:
+    """Problems with the downloaded artifacts; empty when everything matches the lock"""
+    lock = lock if lock is not None else read_lock(project)
+    if lock is None:
+        return [f"No {LOCK_FILE_NAME}"]
+    root = vendor_dir(project)
+    problems = []
+
+    for wheel in (lock.get('python') or {}).get('wheels', []):
+        problems += _check_file(root / "python" / "wheels" / wheel['file'], wheel['sha256'])
+    node = lock.get('node')
+    if node:
+        problems += _check_file(root / "node" / "package-lock.json", node['package_lock_sha256'])
+    for name, entry in (lock.get('files') or {}).items():
+        problems += _check_file(root / "files" / name / entry['file'], entry['sha256'])
+    return problems
+
+def vendor_env(project, env: Dict[str, str]) -> Dict[str, str]:
+    """Put installed vendored tools ahead of system ones in a child process environment"""
+    root = vendor_dir(project).resolve()
+    if not root.is_dir():
+        return env
+
+    def prepend(name: str, path: Path):
+        env[name] = os.pathsep.join(filter(None, [str(path), env.get(name)]))
+
+    site = root / "python" / "site"
+    if site.is_dir():
+        prepend('PYTHONPATH', site)
+        if (site / "bin").is_dir():
+            prepend('PATH', site / "bin")
+    node_bin = root / "node" / "node_modules" / ".bin"
+    if node_bin.is_dir():
+        prepend('PATH', node_bin)
+
+    files_dir = root / "files"
+    library_var = 'PATH' if os.name == 'nt' else ('DYLD_LIBRARY_PATH' if sys.platform == 'darwin' else 'LD_LIBRARY_PATH')
+    for directory in sorted(files_dir.iterdir()) if files_dir.is_dir() else []:
+        if directory.is_dir():
+            # Prebuilt libraries ship their Python bindings next to the shared library
+            prepend('PYTHONPATH', directory)
+            prepend(library_var, directory)
+            if (directory / "bin").is_dir():
+                prepend('PATH', directory / "bin")
+
+    env['FLASHFLOW_VENDOR_DIR'] = str(root)
+    return env
+
+def vendored_axe(project) -> Optional[Path]:
+    path = vendor_dir(project) / AXE_VENDOR_PATH
+    return path if path.exists() else None
+
+def _check_file(path: Path, expected: str) -> List[str]:
+    if not path.exists():
+        return [f"{path.name} is missing"]
+    actual = file_sha256(path)
+    if actual != expected:
+        return [f"{path.name} has SHA-256 {actual[:12]}…, the lock expects {expected[:12]}…"]
+    return []
+
+def _run(args: List[str], what: str, **kwargs):
+    try:
+        result = subprocess.run(args, capture_output=True, text=True, **kwargs)
+    except OSError as e:
+        raise VendorError(f"{what} failed: cannot run {args[0]} ({e})")
+    if result.returncode != 0:
+        detail = (result.stderr or result.stdout or '').strip().splitlines()[-15:]
+        raise VendorError(f"{what} failed (exit code {result.returncode}):\n" + "\n".join(detail))
+    return result
+
+def _download_python(python_dir: Path, requirements: List[str]) -> Dict[str, Any]:
+    wheels_dir = python_dir / "wheels"
+    if wheels_dir.exists():
+        shutil.rmtree(wheels_dir)
+    wheels_dir.mkdir(parents=True)
+    # Binary wheels only, so installing later never needs a compiler or the network
+    _run([sys.executable, '-m', 'pip', 'download', '--only-binary=:all:', '--dest', str(wheels_dir), *requirements],
+         "pip download")
+
+    wheels = []
+    for wheel in sorted(wheels_dir.glob("*.whl")):
+        name, version = wheel.name.split('-')[:2]
+        wheels.append({'file': wheel.name, 'name': name, 'version': version, 'sha256': file_sha256(wheel)})
+    return {'requirements': requirements, 'python': f"{sys.version_info.major}.{sys.version_info.minor}", 'wheels': wheels}
+
+def _install_python(python_dir: Path, locked: Dict[str, Any]):
+    # pip checks every wheel against the hashes in the lock
+    pinned = python_dir / "requirements.lock"
+    pinned.write_text("".join(f"{wheel['name']}=={wheel['version']} --hash=sha256:{wheel['sha256']}\n"
+                              for wheel in locked['wheels']), encoding='utf-8')
+    site = python_dir / "site"
+    if site.exists():
+        shutil.rmtree(site)
+    _run([sys.executable, '-m', 'pip', 'install', '--no-index', '--no-deps', '--find-links', str(python_dir / "wheels"),
+          '--require-hashes', '--target', str(site), '-r', str(pinned)], "pip install")
+
+def _install_node(node_dir: Path, packages: List[str], offline: bool) -> Dict[str, Any]:
+    node_dir.mkdir(parents=True, exist_ok=True)
+    package_json = node_dir / "package.json"
+    if not package_json.exists():
+        package_json.write_text(json.dumps({'name': 'flashflow-vendor', 'private': True}, indent=2) + "\n", encoding='utf-8')
+
+    npm = 'npm.cmd' if os.name == 'nt' else 'npm'
+    args = [npm, 'install', '--prefix', str(node_dir), '--cache', str(node_dir / "cache"),
+            '--no-audit', '--no-fund', '--save-exact']
+    # Offline installs reuse the locked tree; npm checks each tarball's integrity from package-lock.json
+    args += ['--offline'] if offline else []
+    _run(args + ([] if offline else packages), "npm install", cwd=str(node_dir))
+
+    package_lock = node_dir / "package-lock.json"
+    if not package_lock.exists():
+        raise VendorError("npm did not write package-lock.json")
+    tree = json.loads(package_lock.read_text(encoding='utf-8')).get('packages', {})
+    resolved = {path[len('node_modules/'):]: info.get('version') for path, info in tree.items()
+                if path.startswith('node_modules/') and '/node_modules/' not in path[len('node_modules/'):]}
+    return {'packages': packages, 'resolved': resolved, 'package_lock_sha256': file_sha256(package_lock)}
+
+def _download_file(files_dir: Path, name: str, spec: Dict[str, str]) -> Dict[str, Any]:
+    target_dir = files_dir / name
+    if target_dir.exists():
+        shutil.rmtree(target_dir)
+    target_dir.mkdir(parents=True)
+    file_name = spec['url'].rstrip('/').rsplit('/', 1)[-1].split('?')[0] or name
+    target = target_dir / file_name
+
+    try:
+        with urllib.request.urlopen(spec['url'], timeout=60) as response, open(target, 'wb') as f:
+            shutil.copyfileobj(response, f)
+    except OSError as e:
+        raise VendorError(f"Downloading {name} from {spec['url']} failed: {e}")
+
+    actual = file_sha256(target)
+    expected = (spec.get('sha256') or '').lower()
+    if expected and actual != expected:
+        target.unlink()
+        raise VendorError(f"{name}: downloaded file has SHA-256 {actual}, flashflow.json pins {expected}")
+    _unpack(target, target_dir)
+    return {'url': spec['url'], 'file': file_name, 'sha256': actual, 'pinned': bool(expected)}
+
+def _unpack(archive: Path, target_dir: Path):
+    name = archive.name.lower()
+    if name.endswith('.zip'):
+        with zipfile.ZipFile(archive) as bundle:
+            _check_members(bundle.namelist(), target_dir)
+            bundle.extractall(target_dir)
+    elif name.endswith(ARCHIVE_SUFFIXES):
+        with tarfile.open(archive) as bundle:
+            _check_members(bundle.getnames(), target_dir)
+            bundle.extractall(target_dir)
+
+def _check_members(names: List[str], target_dir: Path):
+    root = target_dir.resolve()
+    for member in names:
+        destination = (target_dir / member).resolve()
+        if destination != root and root not in destination.parents:
+            raise VendorError(f"Archive entry {member} would be written outside {target_dir}")
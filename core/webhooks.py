@@ -0,0 +1,319 @@
+"""
+FlashFlow webhooks - Signed notifications of model changes, declared in flows
+
+    webhooks:
+      order_paid:
+        event: order.updated          # <model>.<created|updated|deleted>; fnmatch patterns such as 'order.*' or '*.deleted'
+        url: https://example.com/hooks/orders
+        headers:
+          X-Api-Key: ${ORDERS_API_KEY}
+        secret: ${ORDERS_WEBHOOK_SECRET}
+        max_attempts: 5              # default 5
+        timeout: 10                  # seconds per attempt
+
+Each delivery is a JSON POST of {id, event, model, occurred_at, data} with
+X-FlashFlow-Event and X-FlashFlow-Delivery headers. With a secret it also
+carries X-FlashFlow-Signature: 't=<unix time>,v1=<hex>', the HMAC-SHA256 of
+'<t>.<body>', so receivers can check both origin and age. ${NAME} in the
+url, headers and secret is read from the environment.
+
+A delivery succeeds on any 2xx answer. Otherwise it is retried after
+BACKOFF_BASE seconds, doubling each time up to BACKOFF_MAX, until
+max_attempts. Deliveries are kept one file each in .flashflow/webhooks/ (the
+newest HISTORY_LIMIT), so several server processes can record them and the
+history survives restarts.
+"""
+
+import fnmatch
+import hashlib
+import hmac
+import json
+import logging
+import os
+import threading
+import time
+import uuid
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Dict, Any, List, Optional, Tuple
+
+import requests
+
+from core.tracing import get_tracer
+
+logger = logging.getLogger(__name__)
+
+EVENT_ACTIONS = ('created', 'updated', 'deleted')
+DEFAULT_MAX_ATTEMPTS = 5
+DEFAULT_TIMEOUT = 10.0
+BACKOFF_BASE = 5.0
+BACKOFF_MAX = 600.0
+HISTORY_LIMIT = 500
+# Receivers' answers are kept only this long per attempt
+RESPONSE_SNIPPET = 500
+USER_AGENT = 'FlashFlow-Webhooks/1.0'
+
+class WebhookError(Exception):
+    """Raised for an invalid webhook declaration or an unknown delivery"""
+    pass
+
+@dataclass
+class WebhookDeclaration:
+    """One 'webhooks:' entry from a flow file"""
+    name: str
+    event: str
+    url: str
+    headers: Dict[str, str] = field(default_factory=dict)
+    secret: Optional[str] = None
+    max_attempts: int = DEFAULT_MAX_ATTEMPTS
+    timeout: float = DEFAULT_TIMEOUT
+    source: str = ''
+
+    @classmethod
+    def from_definition(cls, definition: Dict[str, Any], source: str, index: int) -> 'WebhookDeclaration':
+        event = str(definition.get('event') or '').strip().lower()
+        model, _, action = event.rpartition('.')
+        if not model or not (action in EVENT_ACTIONS or any(char in action for char in '*?[')):
+            raise WebhookError(f"webhook 'event' must look like <model>.<{'|'.join(EVENT_ACTIONS)}>, got '{event}'")
+        url = os.path.expandvars(str(definition.get('url') or ''))
+        if not url.startswith(('http://', 'https://')):
+            raise WebhookError(f"webhook 'url' must be an http(s) URL, got '{url}'")
+        headers = definition.get('headers') or {}
+        if not isinstance(headers, dict):
+            raise WebhookError("webhook 'headers' must be a mapping")
+        max_attempts = int(definition.get('max_attempts', DEFAULT_MAX_ATTEMPTS))
+        if max_attempts < 1:
+            raise WebhookError("webhook 'max_attempts' must be at least 1")
+        secret = definition.get('secret')
+        return cls(
+            name=str(definition.get('name') or f"{source}#{index + 1}"),
+            event=event,
+            url=url,
+            headers={str(name): os.path.expandvars(str(value)) for name, value in headers.items()},
+            secret=os.path.expandvars(str(secret)) if secret else None,
+            max_attempts=max_attempts,
+            timeout=float(definition.get('timeout', DEFAULT_TIMEOUT)),
+            source=source
+        )
+
+    def matches(self, event: str) -> bool:
+        return fnmatch.fnmatchcase(event.lower(), self.event)
+
+    def to_dict(self) -> Dict[str, Any]:
+        # Header values and the secret often hold credentials
+        return {'name': self.name, 'event': self.event, 'url': self.url, 'headers': sorted(self.headers),
+                'signed': bool(self.secret), 'max_attempts': self.max_attempts, 'timeout': self.timeout,
+                'source': self.source}
+
+def load_declarations(definitions: List[Tuple[Dict[str, Any], str, int]]) -> Tuple[List[WebhookDeclaration], List[str]]:
+    """Declarations from (definition, flow file name, index) triples, and the errors of the invalid ones"""
+    webhooks, errors = [], []
+    for definition, source, index in definitions:
+        try:
+            webhooks.append(WebhookDeclaration.from_definition(definition, source, index))
+        except (WebhookError, ValueError, TypeError) as e:
+            errors.append(f"{source}: {str(e)}")
+    return webhooks, errors
+
+def sign(secret: str, timestamp: int, body: bytes) -> str:
+    digest = hmac.new(secret.encode('utf-8'), f"{timestamp}.".encode('utf-8') + body, hashlib.sha256).hexdigest()
+    return f"t={timestamp},v1={digest}"
+
+def backoff(attempts: int) -> float:
+    """Seconds to wait after the given number of failed attempts"""
+    return min(BACKOFF_BASE * 2 ** max(attempts - 1, 0), BACKOFF_MAX)
+
+def event_payload(model: str, action: str, row: Dict[str, Any]) -> Dict[str, Any]:
+    return {
+        'id': uuid.uuid4().hex,
+        'event': f"{model.lower()}.{action}",
+        'model': model,
+        'occurred_at': time.strftime('%Y-%m-%dT%H:%M:%SZ', time.gmtime()),
+        'data': row
+    }
+
+class DeliveryStore:
+    """One JSON file per delivery under .flashflow/webhooks/"""
+
+    def __init__(self, directory: Path):
+        self.directory = Path(directory)
+
+    @classmethod
+    def for_project(cls, project) -> 'DeliveryStore':
+        return cls(project.state.ensure() / 'webhooks')
+
+    def _file(self, delivery_id: str) -> Path:
+        if not delivery_id.isalnum():
+            raise WebhookError(f"Unknown delivery '{delivery_id}'")
+        return self.directory / f"{delivery_id}.json"
+
+    def save(self, delivery: Dict[str, Any]):
+        self.directory.mkdir(parents=True, exist_ok=True)
+        target = self._file(delivery['id'])
+        partial = target.with_suffix('.tmp')
+        with open(partial, 'w') as f:
+            json.dump(delivery, f)
+        partial.replace(target)
+
+    def get(self, delivery_id: str) -> Dict[str, Any]:
+        try:
+            with open(self._file(delivery_id), 'r') as f:
+                return json.load(f)
+        except (OSError, json.JSONDecodeError):
+            raise WebhookError(f"Unknown delivery '{delivery_id}'")
+
+    def list(self) -> List[Dict[str, Any]]:
+        """Every kept delivery, newest first"""
+        deliveries = []
+        for path in self.directory.glob('*.json') if self.directory.exists() else []:
+            try:
+                with open(path, 'r') as f:
+                    deliveries.append(json.load(f))
+            except (OSError, json.JSONDecodeError):
+                continue
+        deliveries.sort(key=lambda delivery: delivery.get('created_at', 0), reverse=True)
+        return deliveries
+
+    def prune(self):
+        """Drop the oldest finished deliveries beyond HISTORY_LIMIT"""
+        finished = [delivery for delivery in self.list() if delivery.get('status') in ('delivered', 'failed')]
+        for delivery in finished[HISTORY_LIMIT:]:
+            try:
+                self._file(delivery['id']).unlink()
+            except OSError:
+                pass
+
+class WebhookDispatcher:
+    """Sends deliveries from a background thread and schedules their retries"""
+
+    def __init__(self, store: DeliveryStore):
+        self.store = store
+        self._due: Dict[str, float] = {}
+        self._webhooks: Dict[str, WebhookDeclaration] = {}
+        self._condition = threading.Condition()
+        self._thread: Optional[threading.Thread] = None
+
+    def _ensure_thread(self):
+        if self._thread is None or not self._thread.is_alive():
+            self._thread = threading.Thread(target=self._run, name='webhook-dispatcher', daemon=True)
+            self._thread.start()
+
+    def enqueue(self, webhook: WebhookDeclaration, payload: Dict[str, Any], redelivery_of: Optional[str] = None) -> Dict[str, Any]:
+        """Record a delivery of the payload to one webhook and send it as soon as possible"""
+        delivery = {
+            'id': uuid.uuid4().hex[:16],
+            'webhook': webhook.name,
+            'event': payload['event'],
+            'url': webhook.url,
+            'payload': payload,
+            'status': 'pending',
+            'attempts': [],
+            'max_attempts': webhook.max_attempts,
+            'created_at': time.time(),
+            'next_attempt_at': time.time(),
+            'redelivery_of': redelivery_of,
+            'owner_pid': os.getpid()
+        }
+        self.store.save(delivery)
+        self._schedule(delivery['id'], webhook, delivery['next_attempt_at'])
+        return delivery
+
+    def _schedule(self, delivery_id: str, webhook: WebhookDeclaration, when: float):
+        with self._condition:
+            self._due[delivery_id] = when
+            self._webhooks[delivery_id] = webhook
+            self._condition.notify()
+        self._ensure_thread()
+
+    def adopt(self, webhooks: List[WebhookDeclaration]):
+        """Take over pending deliveries whose process has exited, e.g. after a restart"""
+        by_name = {webhook.name: webhook for webhook in webhooks}
+        for delivery in self.store.list():
+            if delivery.get('status') not in ('pending', 'retrying') or delivery['id'] in self._due:
+                continue
+            if _process_running(delivery.get('owner_pid')):
+                continue
+            webhook = by_name.get(delivery['webhook'])
+            if webhook is None:
+                delivery.update(status='failed', next_attempt_at=None, error='The webhook is no longer declared')
+                self.store.save(delivery)
+                continue
+            delivery['owner_pid'] = os.getpid()
+            self.store.save(delivery)
+            self._schedule(delivery['id'], webhook, delivery.get('next_attempt_at') or time.time())
+
+    def _run(self):
+        while True:
+            with self._condition:
+                now = time.time()
+                due = [delivery_id for delivery_id, when in self._due.items() if when <= now]
+                if not due:
+                    wait = min(self._due.values()) - now if self._due else None
+                    self._condition.wait(wait)
+                    continue
+                batch = [(delivery_id, self._webhooks.pop(delivery_id)) for delivery_id in due]
+                for delivery_id in due:
+                    del self._due[delivery_id]
+            for delivery_id, webhook in batch:
+                try:
+                    self._attempt(delivery_id, webhook)
+                except Exception as e:
+                    logger.warning(f"Webhook delivery {delivery_id} could not be sent: {str(e)}")
+
+    def _attempt(self, delivery_id: str, webhook: WebhookDeclaration):
+        delivery = self.store.get(delivery_id)
+        body = json.dumps(delivery['payload']).encode('utf-8')
+        headers = dict(webhook.headers)
+        headers.update({
+            'Content-Type': 'application/json',
+            'User-Agent': USER_AGENT,
+            'X-FlashFlow-Event': delivery['event'],
+            'X-FlashFlow-Delivery': delivery['id']
+        })
+        if webhook.secret:
+            headers['X-FlashFlow-Signature'] = sign(webhook.secret, int(time.time()), body)
+
+        attempt = {'at': time.time(), 'status_code': None, 'error': None, 'response': ''}
+        started = time.perf_counter()
+        with get_tracer().span(f"webhook {webhook.name}", kind='client',
+                               attributes={'flashflow.webhook.event': delivery['event'], 'url.full': webhook.url}) as span:
+            try:
+                response = requests.post(webhook.url, data=body, headers=get_tracer().inject_headers(headers),
+                                         timeout=webhook.timeout)
+                attempt['status_code'] = response.status_code
+                attempt['response'] = response.text[:RESPONSE_SNIPPET]
+                if not 200 <= response.status_code < 300:
+                    attempt['error'] = f"HTTP {response.status_code}"
+            except requests.RequestException as e:
+                attempt['error'] = str(e)
+            if attempt['error']:
+                span.set_error(attempt['error'])
+        attempt['duration_ms'] = round((time.perf_counter() - started) * 1000, 1)
+
+        delivery['attempts'].append(attempt)
+        if attempt['error'] is None:
+            delivery.update(status='delivered', next_attempt_at=None)
+        elif len(delivery['attempts']) >= delivery['max_attempts']:
+            delivery.update(status='failed', next_attempt_at=None)
+            logger.warning(f"Webhook '{webhook.name}' gave up on {delivery['event']} after "
+                           f"{len(delivery['attempts'])} attempt(s): {attempt['error']}")
+        else:
+            delivery.update(status='retrying', next_attempt_at=time.time() + backoff(len(delivery['attempts'])))
+        self.store.save(delivery)
+        if delivery['next_attempt_at']:
+            self._schedule(delivery_id, webhook, delivery['next_attempt_at'])
+        else:
+            self.store.prune()
+
+def _process_running(pid: Optional[int]) -> bool:
+    if not pid:
+        return False
+    if pid == os.getpid():
+        return True
+    try:
+        os.kill(pid, 0)
+    except ProcessLookupError:
+        return False
+    except (PermissionError, OSError):
+        return True
+    return True
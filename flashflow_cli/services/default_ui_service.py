@@ -5,6 +5,7 @@ Generates default UI components and pages when none are explicitly defined
 
 from typing import Dict, Any, List
 from ..core import FlashFlowIR
+from core.parser.flow_loader import data_sources
 
 
 class DefaultUIService:
@@ -20,22 +21,13 @@ class DefaultUIService:
             # Check if any pages already reference this model
             model_has_pages = False
             for page_path, page_def in ir.pages.items():
-                # Check if page has components that reference this model
+                # Check if page has list, form or card components that reference this model;
+                # data_sources answers for a cached page without loading its body
                 if 'body' in page_def:
-                    for component in page_def['body']:
-                        if isinstance(component, dict):
-                            # Check list components
-                            if component.get('component') == 'list' and component.get('data_source') == model_name:
-                                model_has_pages = True
-                                break
-                            # Check form components
-                            if component.get('component') == 'form' and component.get('data_source') == model_name:
-                                model_has_pages = True
-                                break
-                            # Check card components
-                            if component.get('component') == 'card' and component.get('data_source') == model_name:
-                                model_has_pages = True
-                                break
+                    for component, data_source in data_sources(page_def):
+                        if component in ('list', 'form', 'card') and data_source == model_name:
+                            model_has_pages = True
+                            break
                 
                 # Also check if page has direct model reference
                 if page_def.get('model') == model_name:
@@ -123,7 +115,7 @@ class DefaultUIService:
     def _get_default_list_fields(self, model_def: Dict[str, Any]) -> List[str]:
         """Get default fields to display in list views"""
         fields = []
-        model_fields = model_def.get('fields', {})
+        model_fields = self._field_names(model_def)
         
         # Always include ID if present
         if 'id' in model_fields:
@@ -131,7 +123,7 @@ class DefaultUIService:
         
         # Include the first few fields (up to 5) that are not ID
         field_count = 0
-        for field_name, field_def in model_fields.items():
+        for field_name in model_fields:
             if field_name != 'id' and field_count < 5:
                 fields.append(field_name)
                 field_count += 1
@@ -145,10 +137,10 @@ class DefaultUIService:
     def _get_default_form_fields(self, model_def: Dict[str, Any]) -> List[str]:
         """Get default fields to include in forms"""
         fields = []
-        model_fields = model_def.get('fields', {})
+        model_fields = self._field_names(model_def)
         
         # Include all fields except ID (which is typically auto-generated)
-        for field_name, field_def in model_fields.items():
+        for field_name in model_fields:
             if field_name != 'id':
                 fields.append(field_name)
                 
@@ -157,13 +149,20 @@ class DefaultUIService:
     def _get_default_view_fields(self, model_def: Dict[str, Any]) -> List[str]:
         """Get default fields to display in view/detail pages"""
         fields = []
-        model_fields = model_def.get('fields', {})
+        model_fields = self._field_names(model_def)
         
         # Include all fields
-        for field_name, field_def in model_fields.items():
+        for field_name in model_fields:
             fields.append(field_name)
             
         return fields
+    
+    def _field_names(self, model_def: Dict[str, Any]) -> List[str]:
+        """Field names from either a name -> definition mapping or a list of field definitions"""
+        model_fields = model_def.get('fields', {})
+        if isinstance(model_fields, dict):
+            return list(model_fields)
+        return [field['name'] for field in model_fields or [] if isinstance(field, dict) and field.get('name')]
 
 
 # Global instance
@@ -0,0 +1,180 @@
+"""
+Flow state and client actions for the FlashFlow Direct Renderer
+
+    page:
+      path: /counter
+      state:
+        count: 0
+        todos: []
+      body:
+        - component: text
+          content: "Clicked {{ state.count }} times"
+        - component: button
+          text: Add one
+          on_click:
+            - set: {count: "{{ state.count + 1 }}"}
+            - call: /api/clicks
+              method: POST
+              data: {count: "{{ state.count }}"}
+              result: last_click
+        - component: form
+          fields: [{name: title, label: Title}]
+          submit: Add todo
+          on_submit:
+            - call: /api/todos
+              method: POST
+              data: {title: "{{ form.title }}"}
+            - navigate: /todos
+
+'{{ ... }}' holds an expression (same rules as flow hooks) over 'state', 'form',
+'result', the response of the previous call, 'env', the profile, and
+'features', the flags as they are now ({{ features.new_checkout }}). A string that is exactly one
+'{{ ... }}' keeps the expression's type, so counters stay numbers.
+"""
+
+import logging
+import re
+import sys
+from pathlib import Path
+from typing import Any, Callable, Dict, List, Optional
+
+# The renderer runs as a script from python-services/; the expression evaluator lives in core/
+sys.path.insert(0, str(Path(__file__).resolve().parent.parent.parent))
+from core.utils.expressions import evaluate, ExpressionError
+
+logger = logging.getLogger(__name__)
+
+ACTION_KEYS = ('on_click', 'on_submit', 'on_change', 'on_select')
+# Nested components, resolved when they are rendered so their actions see click-time state
+NESTED_KEYS = ('children', 'tabs', 'footer')
+ACTION_TYPES = ('set', 'call', 'navigate')
+TEMPLATE_PATTERN = re.compile(r"\{\{\s*(.+?)\s*\}\}")
+
+class ActionError(Exception):
+    """Raised for a malformed action or an expression that cannot be evaluated"""
+    pass
+
+def render_template(text: str, context: Dict[str, Any]) -> Any:
+    """Fill '{{ expr }}' placeholders; a lone placeholder returns the raw value"""
+    whole = TEMPLATE_PATTERN.fullmatch(text.strip())
+    try:
+        if whole:
+            return evaluate(whole.group(1), context)
+        return TEMPLATE_PATTERN.sub(lambda match: _display(evaluate(match.group(1), context)), text)
+    except ExpressionError as e:
+        raise ActionError(f"{text}: {e}")
+
+def resolve_value(value: Any, context: Dict[str, Any]) -> Any:
+    """Render templates inside strings, lists and mappings"""
+    if isinstance(value, str):
+        return render_template(value, context) if '{{' in value else value
+    if isinstance(value, list):
+        return [resolve_value(item, context) for item in value]
+    if isinstance(value, dict):
+        return {key: resolve_value(item, context) for key, item in value.items()}
+    return value
+
+def normalize_actions(definition: Any) -> List[Dict[str, Any]]:
+    """A single action or a list of actions, each with exactly one of set/call/navigate"""
+    actions = definition if isinstance(definition, list) else [definition]
+    for action in actions:
+        kinds = [kind for kind in ACTION_TYPES if isinstance(action, dict) and kind in action]
+        if len(kinds) != 1:
+            raise ActionError(f"Each action needs exactly one of {', '.join(ACTION_TYPES)}: {action!r}")
+    return actions
+
+def _display(value: Any) -> str:
+    if value is None:
+        return ''
+    if isinstance(value, bool):
+        return 'true' if value else 'false'
+    return str(value)
+
+class ActionRuntime:
+    """Runs on_click / on_submit actions against the engine's app state"""
+
+    def __init__(self, engine, refresh: Callable[[], None], navigate: Callable[[str], None]):
+        self.engine = engine
+        self.refresh = refresh
+        self.navigate = navigate
+
+    def context(self, **extra) -> Dict[str, Any]:
+        context = {'state': dict(self.engine.app_state), 'env': self.engine.profile,
+                   'features': dict(self.engine.features), 'form': {}, 'result': None}
+        context.update(extra)
+        return context
+
+    def init_state(self, declarations: Any):
+        """Apply a page's 'state:' defaults without clobbering values set earlier in the session"""
+        if not isinstance(declarations, dict):
+            return
+        for key, value in declarations.items():
+            if key not in self.engine.app_state:
+                self.engine.set_state(key, resolve_value(value, self.context()))
+
+    def resolve_component(self, component_data: Dict[str, Any]) -> Dict[str, Any]:
+        """Component props with templates filled in; actions and nested components are left for later"""
+        context = self.context()
+        resolved = {}
+        for key, value in component_data.items():
+            if key in ACTION_KEYS or key in NESTED_KEYS:
+                resolved[key] = value
+                continue
+            try:
+                resolved[key] = resolve_value(value, context)
+            except ActionError as e:
+                logger.warning(f"Template error in {component_data.get('component', '?')}.{key}: {e}")
+                resolved[key] = value
+        return resolved
+
+    def handler(self, definition: Any, form_values: Callable[[], Dict[str, Any]] = None):
+        """Flet event handler running an action list"""
+        def on_event(e):
+            form = form_values() if form_values else {}
+            if not form and getattr(e, 'data', None) is not None:
+                form = {'value': e.data}
+            self.run(definition, form)
+        return on_event
+
+    def run(self, definition: Any, form: Optional[Dict[str, Any]] = None):
+        """Run actions in order, stopping at the first failure, then redraw the page"""
+        destination = None
+        result = None
+        try:
+            for action in normalize_actions(definition):
+                context = self.context(form=form or {}, result=result)
+                if 'set' in action:
+                    changes = action['set']
+                    if not isinstance(changes, dict):
+                        raise ActionError("'set' takes a mapping of state keys to values")
+                    for key, value in changes.items():
+                        self.engine.set_state(key, resolve_value(value, context))
+                elif 'call' in action:
+                    result = self._call(action, context)
+                    if isinstance(result, dict) and 'error' in result:
+                        logger.warning(f"Action call {action['call']} failed: {result['error']}")
+                        break
+                else:
+                    destination = str(resolve_value(action['navigate'], context))
+                    break
+        except ActionError as e:
+            logger.error(f"Action failed: {e}")
+
+        if destination:
+            self.navigate(destination)
+        else:
+            self.refresh()
+
+    def _call(self, action: Dict[str, Any], context: Dict[str, Any]) -> Any:
+        endpoint = resolve_value(action['call'], context)
+        method = str(action.get('method', 'GET')).upper()
+        data = resolve_value(action.get('data', {}), context)
+        result = self.engine._make_api_request(method, endpoint, data)
+
+        # 'result: key' stores the response, 'error: key' stores the failure message
+        failed = isinstance(result, dict) and 'error' in result
+        if failed and action.get('error'):
+            self.engine.set_state(action['error'], result['error'])
+        elif not failed and action.get('result'):
+            self.engine.set_state(action['result'], result)
+        return result
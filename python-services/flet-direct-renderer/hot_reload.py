@@ -0,0 +1,116 @@
+"""
+Hot reload for the FlashFlow Direct Renderer
+
+The renderer subscribes to the dev server's /__reload stream. When a .flow file
+changes, only that file is parsed again, and the page is redrawn only if it is
+the one on screen. Text typed into inputs and the scroll position are carried
+over to the new controls, so editing a flow does not lose the form being
+tested. Files that add, move or remove routes refresh the route table.
+"""
+
+import json
+import logging
+import threading
+from typing import Any, Callable, Dict, Iterator
+
+import flet as ft
+import requests
+
+logger = logging.getLogger(__name__)
+
+CLIENT_ID = "flet-direct-renderer"
+RECONNECT_DELAYS = [1, 2, 5, 10]
+INPUT_TYPES = (ft.TextField, ft.CupertinoTextField)
+
+class ReloadListener:
+    """Background subscriber to the dev server's reload events"""
+
+    def __init__(self, backend_url: str, on_change: Callable[[str], None]):
+        self.url = f"{backend_url.rstrip('/')}/__reload"
+        self.on_change = on_change
+        self._stop = threading.Event()
+        self._thread = threading.Thread(target=self._listen, name="flow-hot-reload", daemon=True)
+
+    def start(self):
+        self._thread.start()
+
+    def stop(self):
+        self._stop.set()
+
+    def _listen(self):
+        failures = 0
+        while not self._stop.is_set():
+            try:
+                with requests.get(self.url, params={'client': CLIENT_ID, 'page': 'engine'},
+                                  stream=True, timeout=(5, None)) as response:
+                    response.raise_for_status()
+                    failures = 0
+                    for event, data in parse_events(response.iter_lines(decode_unicode=True)):
+                        if self._stop.is_set():
+                            return
+                        if event == 'reload' and data.get('file'):
+                            self.on_change(data['file'])
+            except requests.RequestException as e:
+                # The dev server may start after the engine or restart underneath it
+                logger.debug(f"Reload stream unavailable: {e}")
+            delay = RECONNECT_DELAYS[min(failures, len(RECONNECT_DELAYS) - 1)]
+            failures += 1
+            self._stop.wait(delay)
+
+def parse_events(lines: Iterator[str]) -> Iterator[tuple]:
+    """(event, data) pairs from server-sent event lines"""
+    event, data = 'message', []
+    for line in lines:
+        if line is None:
+            continue
+        if line == '':
+            if data:
+                try:
+                    payload = json.loads('\n'.join(data))
+                except ValueError:
+                    payload = {}
+                yield event, payload if isinstance(payload, dict) else {}
+            event, data = 'message', []
+        elif line.startswith(':'):
+            continue
+        elif line.startswith('event:'):
+            event = line[6:].strip()
+        elif line.startswith('data:'):
+            data.append(line[5:].lstrip())
+
+def walk_controls(control: Any) -> Iterator[ft.Control]:
+    """Every control in a tree, depth first in display order"""
+    if control is None:
+        return
+    yield control
+    for child in getattr(control, 'controls', None) or []:
+        yield from walk_controls(child)
+    content = getattr(control, 'content', None)
+    if isinstance(content, ft.Control):
+        yield from walk_controls(content)
+
+def input_key(control: ft.Control) -> str:
+    return str(getattr(control, 'label', None) or getattr(control, 'placeholder_text', None) or '')
+
+def snapshot_inputs(root: Any) -> Dict[str, Any]:
+    """Values of every input, keyed by label and position among inputs with that label"""
+    values, seen = {}, {}
+    for control in walk_controls(root):
+        if isinstance(control, INPUT_TYPES):
+            key = input_key(control)
+            seen[key] = seen.get(key, 0) + 1
+            values[f"{key}#{seen[key]}"] = control.value
+    return values
+
+def restore_inputs(root: Any, values: Dict[str, Any]) -> int:
+    """Put snapshotted values back into matching inputs; returns how many were restored"""
+    restored, seen = 0, {}
+    for control in walk_controls(root):
+        if isinstance(control, INPUT_TYPES):
+            key = input_key(control)
+            seen[key] = seen.get(key, 0) + 1
+            slot = f"{key}#{seen[key]}"
+            if slot in values and values[slot] is not None:
+                control.value = values[slot]
+                restored += 1
+    return restored
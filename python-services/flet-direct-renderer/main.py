@@ -7,7 +7,7 @@ This replaces the HTML/CSS/JS approach with pure Python Flet components
 import os
 import sys
 import json
-import yaml
+import time
 import flet as ft
 from pathlib import Path
 from typing import Dict, Any, List, Union
@@ -19,6 +19,23 @@ import numpy as np
 
 # Import platform-adaptive components
 from components import PlatformAdaptiveComponents, AdaptiveThemeManager, create_adaptive_headline, create_adaptive_input, create_adaptive_button
+from actions import ActionRuntime
+from hot_reload import ReloadListener, snapshot_inputs, restore_inputs
+from navigation import NavigationComponents, NAVIGATION_COMPONENTS
+from search import SearchComponents, SEARCH_COMPONENTS
+from cli.core import __version__
+from core.inference import InferenceOptions
+from core.vector_search import SearchOptions, search as search_vectors
+from core.crashes import install_crash_reporter
+from core.content import ContentError, content_files, read_content_page, read_markdown_source
+from core.feature_flags import feature_visible
+from core.flashcore_handles import FlashCoreError, aes_vault, hnsw_index, onnx_runtime
+from core.framework import FlashFlowProject
+from core.html_safety import safe_url
+from core.parser.flow_file import load_flow
+from core.service_info import InfoServer, ServiceInfo
+from core.settings import SettingsError, resolve_settings
+from core.state import ProjectState
 # FlashCore integration
 try:
     import flashcore
@@ -27,6 +44,9 @@ except ImportError:
     FLASHCORE_AVAILABLE = False
     print("Warning: FlashCore not available, using fallback implementations")
 
+# Widths the dev server generates for /media images
+MEDIA_VARIANT_WIDTHS = [320, 640, 960, 1280, 1920]
+
 # Configure logging
 logging.basicConfig(level=logging.INFO)
 logger = logging.getLogger(__name__)
@@ -39,10 +59,20 @@ class FlashFlowEngine:
         self.flow_files_dir = self.project_root / "src" / "flows"
         self.page_registry = {}  # Maps routes to .flow files
         self.backend_url = backend_url  # Laravel backend URL
+        self.profile = self._load_profile()  # Public environment profile from 'flashflow serve --env'
+        self.features = dict(self.profile['features'])  # Flags as the dev server has them; see _refresh_features
+        self._features_retry_at = 0.0
         self.deployment_env = self._detect_deployment_environment()  # Auto-detect deployment environment
         self.current_platform = "desktop"  # Default platform
         self.app_state = {}  # Application state for temporary visibility controls
         self.state_listeners = {}  # Listeners for state changes
+        self.actions = ActionRuntime(self, refresh=self._refresh, navigate=self._navigate)  # on_click / on_submit
+        self.navigation = NavigationComponents(self)  # navbar, sidebar, tabs, modal
+        self.search = SearchComponents(self)  # semantic search over vector collections
+        self._current_route = "/"
+        self._scroll_offset = None
+        self._hot_reload = None  # Started with the first session
+        self.started_at = time.time()  # Reported by /__info
         
         # FlashFlow Engine with FlashCore acceleration
         self.flashcore_enabled = FLASHCORE_AVAILABLE
@@ -155,15 +185,15 @@ class FlashFlowEngine:
         """Initialize FlashCore components"""
         try:
             # Initialize vector search index (128-dimensional for embeddings, max 10000 elements)
-            self.vector_index = flashcore.HNSWIndex(128, 10000)
+            self.vector_index = hnsw_index(128, 10000)
             logger.info("Initialized FlashCore HNSW vector index")
             
             # Initialize inference runtime (will be configured with specific models as needed)
-            self.inference_runtime = flashcore.ONNXRuntime("")
+            self.inference_runtime = self._create_inference_runtime()
             logger.info("Initialized FlashCore ONNX runtime")
             
             # Initialize security vault with default key
-            self.security_vault = flashcore.AESVault("flashflow_default_key")
+            self.security_vault = aes_vault("flashflow_default_key")
             logger.info("Initialized FlashCore AES security vault")
             
         except Exception as e:
@@ -189,15 +219,17 @@ class FlashFlowEngine:
             except Exception as e:
                 logger.error(f"Failed to pre-populate vector index: {e}")
     
-    def vector_search(self, query_vector: np.ndarray, k: int = 5):
-        """Perform vector search using FlashCore"""
+    def vector_search(self, query_vector: np.ndarray, k: int = 5, radius: float = None,
+                      normalize: str = 'inverse', ef_search: int = None):
+        """Perform vector search using FlashCore; hits are {id, distance, score} (see core/vector_search.py)"""
         if not self.flashcore_enabled or not self.vector_index:
             # Fallback implementation
             logger.warning("FlashCore not available, using fallback vector search")
-            return [{"id": i, "distance": 0.0} for i in range(min(k, 3))]
+            return [{"id": i, "distance": 0.0, "score": 1.0} for i in range(min(k or 3, 3))]
         
         try:
-            return self.vector_index.search(query_vector, k)
+            options = SearchOptions(k=k, radius=radius, normalize=normalize, ef_search=ef_search)
+            return search_vectors(self.vector_index, query_vector, options)['results']
         except Exception as e:
             logger.error(f"Vector search failed: {e}")
             return []
@@ -248,30 +280,39 @@ class FlashFlowEngine:
             return
             
         for flow_file in self.flow_files_dir.glob("*.flow"):
+            self._register_flow_file(flow_file)
+        self._register_content_files()
+    
+    def _register_content_files(self):
+        """Map the routes of the Markdown pages in src/content, where no flow page has them"""
+        for content_file in content_files(self.project_root):
             try:
-                with open(flow_file, 'r') as f:
-                    content = f.read()
+                content_page = read_content_page(self.project_root, content_file)
+            except ContentError as e:
+                logger.error(f"Error reading {content_file}: {e}")
+                continue
+            if not content_page.draft and content_page.route not in self.page_registry:
+                self.page_registry[content_page.route] = content_file
+                logger.info(f"Registered route {content_page.route} -> {content_file.name}")
+    
+    def _register_flow_file(self, flow_file: Path):
+        """Map the page path declared in one .flow file to that file"""
+        try:
+            route = load_flow(flow_file, self.project_root).route
+            if route:
+                self.page_registry[route] = flow_file
+                logger.info(f"Registered route {route} -> {flow_file.name}")
                     
-                # Parse YAML content
-                flow_data = yaml.safe_load(content)
-                
-                # Extract page information
-                if isinstance(flow_data, dict) and 'page' in flow_data:
-                    page_info = flow_data['page']
-                    if isinstance(page_info, dict) and 'path' in page_info:
-                        route = page_info['path']
-                        self.page_registry[route] = flow_file
-                        logger.info(f"Registered route {route} -> {flow_file.name}")
-                        
-            except Exception as e:
-                logger.error(f"Error parsing {flow_file}: {e}")
+        except Exception as e:
+            logger.error(f"Error parsing {flow_file}: {e}")
     
     def _parse_flow_file(self, file_path: Path) -> Dict[str, Any]:
-        """Parse a .flow file and return structured data"""
+        """Parse a .flow file, with its includes, or a content page, and return structured data"""
         try:
-            with open(file_path, 'r') as f:
-                content = f.read()
-            return yaml.safe_load(content) or {}
+            if file_path.suffix == '.md':
+                return {'page': read_content_page(self.project_root, file_path).page_data(self.project_root)}
+            data = load_flow(file_path, self.project_root).data
+            return data if isinstance(data, dict) else {}
         except Exception as e:
             logger.error(f"Error parsing flow file {file_path}: {e}")
             return {}
@@ -280,6 +321,10 @@ class FlashFlowEngine:
         """Determine if a component should be rendered based on platform visibility rules"""
         visibility = component_data.get('visibility', {})
         
+        # Components behind a feature flag that is off
+        if not feature_visible(component_data, self.features):
+            return False
+        
         # If no visibility rules, render by default
         if not visibility:
             return True
@@ -358,21 +403,38 @@ class FlashFlowEngine:
         """Initialize FlashCore components"""
         try:
             # Initialize vector search index (128-dimensional for embeddings, max 10000 elements)
-            self.vector_index = flashcore.HNSWIndex(128, 10000)
+            self.vector_index = hnsw_index(128, 10000)
             logger.info("Initialized FlashCore HNSW vector index")
             
             # Initialize inference runtime (will be configured with specific models as needed)
-            self.inference_runtime = flashcore.ONNXRuntime("")
+            self.inference_runtime = self._create_inference_runtime()
             logger.info("Initialized FlashCore ONNX runtime")
             
             # Initialize security vault with default key
-            self.security_vault = flashcore.AESVault("flashflow_default_key")
+            self.security_vault = aes_vault("flashflow_default_key")
             logger.info("Initialized FlashCore AES security vault")
             
         except Exception as e:
             logger.error(f"Failed to initialize FlashCore components: {e}")
             self.flashcore_enabled = False
     
+    def _create_inference_runtime(self):
+        """FlashCore ONNX runtime with the project's thread and execution provider settings"""
+        try:
+            with open(self.project_root / "flashflow.json", 'r') as f:
+                options = InferenceOptions.from_dict(json.load(f).get('inference'))
+        except (OSError, ValueError) as e:
+            logger.warning(f"Ignoring inference settings: {e}")
+            options = InferenceOptions()
+
+        try:
+            return onnx_runtime("", **options.binding_kwargs())
+        except FlashCoreError as e:
+            if options.preference() == ['cpu']:
+                raise
+            logger.warning(f"Could not start {', '.join(options.preference()[:-1])}; using CPU: {e}")
+            return onnx_runtime("", **InferenceOptions(options.threads, options.inter_op_threads).binding_kwargs())
+    
     def _initialize_flashcore_features(self):
         """Initialize FlashCore-powered features"""
         # Pre-populate vector index with sample data for demonstration
@@ -392,15 +454,17 @@ class FlashFlowEngine:
             except Exception as e:
                 logger.error(f"Failed to pre-populate vector index: {e}")
     
-    def vector_search(self, query_vector: np.ndarray, k: int = 5):
-        """Perform vector search using FlashCore"""
+    def vector_search(self, query_vector: np.ndarray, k: int = 5, radius: float = None,
+                      normalize: str = 'inverse', ef_search: int = None):
+        """Perform vector search using FlashCore; hits are {id, distance, score} (see core/vector_search.py)"""
         if not self.flashcore_enabled or not self.vector_index:
             # Fallback implementation
             logger.warning("FlashCore not available, using fallback vector search")
-            return [{"id": i, "distance": 0.0} for i in range(min(k, 3))]
+            return [{"id": i, "distance": 0.0, "score": 1.0} for i in range(min(k or 3, 3))]
         
         try:
-            return self.vector_index.search(query_vector, k)
+            options = SearchOptions(k=k, radius=radius, normalize=normalize, ef_search=ef_search)
+            return search_vectors(self.vector_index, query_vector, options)['results']
         except Exception as e:
             logger.error(f"Vector search failed: {e}")
             return []
@@ -499,6 +563,7 @@ class FlashFlowEngine:
             return ft.Container()
         
         component_type = component_data.get('component', '').lower()
+        component_data = self.actions.resolve_component(component_data)
         
         # Store page reference for adaptive components
         page_ref = getattr(self, '_current_page', None)
@@ -539,23 +604,64 @@ class FlashFlowEngine:
         elif component_type == 'input':
             # New adaptive input component
             label = component_data.get('label', '')
-            value = component_data.get('value', '')
             disabled = component_data.get('disabled', False)
+            # 'bind: key' keeps the field and app state in sync without redrawing on every keystroke
+            bind = component_data.get('bind')
+            value = self.get_state(bind, component_data.get('value', '')) if bind else component_data.get('value', '')
+            events = {}
+            if bind:
+                events['on_change'] = lambda e: self.set_state(bind, e.control.value)
+            if component_data.get('on_submit'):
+                events['on_submit'] = self.actions.handler(component_data['on_submit'])
             if page_ref:
-                return create_adaptive_input(page_ref, label, value, disabled)
+                return create_adaptive_input(page_ref, label, value, disabled, **events)
             else:
                 # Fallback to standard TextField
                 return ft.TextField(
                     label=label,
                     value=value,
-                    disabled=disabled
+                    disabled=disabled,
+                    **events
+                )
+        elif component_type == 'form':
+            fields = {}
+            controls = []
+            for field in component_data.get('fields', []):
+                field = field if isinstance(field, dict) else {'name': field}
+                name = field.get('name')
+                if not name:
+                    continue
+                fields[name] = ft.TextField(
+                    label=field.get('label', name.replace('_', ' ').title()),
+                    value=str(field.get('value', '')),
+                    password=field.get('type') == 'password',
+                    can_reveal_password=field.get('type') == 'password',
+                    multiline=field.get('type') == 'textarea'
                 )
+                controls.append(fields[name])
+            
+            on_submit = self.actions.handler(
+                component_data.get('on_submit', []),
+                form_values=lambda: {name: control.value for name, control in fields.items()}
+            )
+            submit_text = component_data.get('submit', 'Submit')
+            if page_ref:
+                controls.append(create_adaptive_button(page_ref, submit_text, False, on_submit))
+            else:
+                controls.append(ft.ElevatedButton(submit_text, on_click=on_submit))
+            return ft.Column(controls, spacing=10, width=component_data.get('width', 480))
         elif component_type == 'button':
             # Handle API actions for buttons
             action = component_data.get('action')
             text = component_data.get('text', 'Button')
             disabled = component_data.get('disabled', False)
             
+            if component_data.get('on_click'):
+                on_click = self.actions.handler(component_data['on_click'])
+                if page_ref:
+                    return create_adaptive_button(page_ref, text, disabled, on_click)
+                return ft.ElevatedButton(text, on_click=on_click, disabled=disabled)
+            
             if page_ref:
                 # Use adaptive button component
                 if action:
@@ -620,6 +726,12 @@ class FlashFlowEngine:
             disabled = component_data.get('disabled', False)
             action = component_data.get('action')
             
+            if component_data.get('on_click'):
+                on_click = self.actions.handler(component_data['on_click'])
+                if page_ref:
+                    return create_adaptive_button(page_ref, text, disabled, on_click)
+                return ft.ElevatedButton(text, on_click=on_click, disabled=disabled)
+            
             if page_ref:
                 if action:
                     def on_click(e):
@@ -772,6 +884,83 @@ class FlashFlowEngine:
                     )
             
             return ft.Column(features_content, spacing=20)
+        elif component_type == 'image':
+            width = component_data.get('width')
+            return ft.Image(
+                src=self._media_url(component_data.get('src', ''), width),
+                semantics_label=component_data.get('alt', ''),
+                width=width,
+                height=component_data.get('height'),
+                fit=ft.ImageFit.COVER if component_data.get('height') else ft.ImageFit.CONTAIN,
+                border_radius=component_data.get('radius', 0)
+            )
+        elif component_type == 'video':
+            src = self._media_url(component_data.get('src', ''))
+            poster = component_data.get('poster')
+            width = component_data.get('width', 640)
+            height = component_data.get('height', round(width * 9 / 16))
+            if hasattr(ft, 'Video'):
+                return ft.Video(
+                    playlist=[ft.VideoMedia(src)],
+                    autoplay=component_data.get('autoplay', False),
+                    muted=component_data.get('muted', False),
+                    show_controls=component_data.get('controls', True),
+                    playlist_mode=ft.PlaylistMode.LOOP if component_data.get('loop') else ft.PlaylistMode.NONE,
+                    width=width,
+                    height=height
+                )
+            # Older Flet builds have no video control; show the poster instead
+            return ft.Container(
+                content=ft.Image(src=self._media_url(poster, width), width=width, height=height, fit=ft.ImageFit.COVER)
+                if poster else ft.Text(f"▶ {component_data.get('src', '')}"),
+                width=width,
+                height=height,
+                bgcolor=ft.colors.BLACK,
+                alignment=ft.alignment.center
+            )
+        elif component_type == 'gallery':
+            columns = max(1, int(component_data.get('columns', 3)))
+            width = component_data.get('width', 960)
+            item_width = (width - 10 * (columns - 1)) // columns
+            images = []
+            for item in component_data.get('images', []):
+                item = item if isinstance(item, dict) else {'src': item}
+                images.append(ft.Image(
+                    src=self._media_url(item.get('src', ''), item_width),
+                    semantics_label=item.get('alt', ''),
+                    width=item_width,
+                    height=item_width,
+                    fit=ft.ImageFit.COVER,
+                    border_radius=6
+                ))
+            return ft.Row(images, wrap=True, spacing=10, run_spacing=10, width=width)
+        elif component_type == 'markdown':
+            text = component_data.get('content', '')
+            if component_data.get('src'):
+                try:
+                    text = read_markdown_source(self.project_root, component_data['src'])
+                except ContentError as e:
+                    logger.error(f"markdown component: {e}")
+                    return ft.Text(str(e), color=ft.colors.RED)
+
+            def on_tap_link(e):
+                # Routes stay in the app; other links open in the browser
+                if e.data.startswith('/'):
+                    self._navigate(e.data)
+                elif page_ref and safe_url(e.data) != '#':
+                    page_ref.launch_url(e.data)
+
+            return ft.Markdown(
+                str(text or ''),
+                selectable=True,
+                extension_set=ft.MarkdownExtensionSet.GITHUB_WEB,
+                code_theme=component_data.get('code_theme', 'atom-one-light'),
+                on_tap_link=on_tap_link
+            )
+        elif component_type in NAVIGATION_COMPONENTS:
+            return self.navigation.create(component_type, component_data, platform)
+        elif component_type in SEARCH_COMPONENTS:
+            return self.search.create(component_type, component_data, platform)
         elif component_type == 'flashcore_demo':
             # FlashCore demonstration component
             title = component_data.get('title', 'FlashCore Demo')
@@ -858,13 +1047,30 @@ class FlashFlowEngine:
                 border_radius=5
             )
     
+    def _media_url(self, src: str, width: int = None) -> str:
+        """URL for a src/assets file served by the dev server, picking a fitting WebP variant"""
+        if not src or src.startswith(('http://', 'https://', '//', 'data:')):
+            return src
+        url = f"{self.backend_url.rstrip('/')}/media/{src.lstrip('/')}"
+        if width and Path(src).suffix.lower() in ('.jpg', '.jpeg', '.png', '.webp', '.gif', '.bmp', '.tif', '.tiff'):
+            # Same widths as core/media.py; the server snaps to what it generated
+            variant = next((w for w in MEDIA_VARIANT_WIDTHS if w >= width), MEDIA_VARIANT_WIDTHS[-1])
+            url += f"?w={variant}"
+        return url
+    
     def _render_page(self, flow_data: Dict[str, Any], platform: str = "desktop") -> List[ft.Control]:
         """Render a page from flow data with platform-specific and temporary visibility"""
         controls = []
         
         # Add page title
         page_info = flow_data.get('page', {})
+        self._refresh_features()
+        if isinstance(page_info, dict) and not feature_visible(page_info, self.features):
+            controls.append(ft.Text(f"🚩 This page is behind feature flag(s) {page_info.get('feature')} that are off",
+                                    color=ft.colors.GREY_700))
+            page_info = {}
         if page_info and isinstance(page_info, dict):
+            self.actions.init_state(page_info.get('state'))
             title = page_info.get('title', 'FlashFlow Page')
             controls.append(ft.Text(title, size=32, weight=ft.FontWeight.BOLD))
             
@@ -902,6 +1108,44 @@ class FlashFlowEngine:
             # Default to web for unknown platforms
             return "web"
     
+    def _refresh_features(self):
+        """Flags from the dev server's /api/features, so /admin/features switches show on the next render"""
+        if time.monotonic() < self._features_retry_at:
+            return
+        try:
+            response = requests.get(urljoin(self.backend_url, '/api/features'), timeout=1)
+            response.raise_for_status()
+            features = response.json().get('features')
+            if isinstance(features, dict):
+                self.features = features
+        except (requests.RequestException, ValueError, AttributeError):
+            # No dev server (or a backend without flags): keep the profile's and ask again later
+            self.features = dict(self.profile['features'])
+            self._features_retry_at = time.monotonic() + 30
+    
+    def _load_profile(self) -> Dict[str, Any]:
+        """The profile the CLI passed in FLASHFLOW_PROFILE; flows read it as {{ env.* }}"""
+        try:
+            profile = json.loads(os.environ.get("FLASHFLOW_PROFILE") or "{}")
+        except ValueError:
+            logger.warning("Ignoring FLASHFLOW_PROFILE: not valid JSON")
+            profile = {}
+        profile.setdefault("name", os.environ.get("FLASHFLOW_ENV", "development"))
+        profile.setdefault("backend_url", self.backend_url)
+        profile.setdefault("features", {})
+        return profile
+
+    def service_info(self, port: int) -> ServiceInfo:
+        """What /__info reports about this engine"""
+        capabilities = ['flet-web', 'hot-reload', 'navigation', 'search']
+        if self.flashcore_enabled:
+            capabilities.append('flashcore')
+        config = {
+            'project': str(self.project_root), 'port': port, 'backend_url': self.backend_url,
+            'profile': self.profile['name'], 'deployment_env': self.deployment_env, 'routes': len(self.page_registry)
+        }
+        return ServiceInfo('engine', __version__, config, capabilities, self.started_at)
+
     def main(self, page: ft.Page):
         """Main Flet application entry point"""
         page.title = "FlashFlow Direct Renderer"
@@ -926,8 +1170,62 @@ class FlashFlowEngine:
         self.current_platform = self._detect_platform(page)
         logger.info(f"Detected platform: {self.current_platform}")
         
-        # Route handling
-        route = page.route or "/"
+        # Scroll position is remembered so hot reloads can put it back
+        page.scroll = ft.ScrollMode.AUTO
+        page.on_scroll = self._track_scroll
+        
+        # Route handling; actions navigate with page.go(), which lands here again
+        page.on_route_change = lambda e: self._show_route(page, e.route)
+        self._show_route(page, page.route or "/")
+        
+        if self._hot_reload is None:
+            self._hot_reload = ReloadListener(self.backend_url, self._flow_changed)
+            self._hot_reload.start()
+    
+    def _track_scroll(self, e):
+        self._scroll_offset = e.pixels
+    
+    def _flow_changed(self, file_name: str):
+        """Re-read one changed .flow or content file and hot-swap the page if it is on screen"""
+        flow_file = self.flow_files_dir / file_name
+        
+        # The file may have changed or dropped its route
+        for route in [route for route, path in self.page_registry.items() if path.name == file_name]:
+            del self.page_registry[route]
+        if Path(file_name).suffix == '.md':
+            # Content files can sit in subfolders; their routes are cheap to map again
+            self._register_content_files()
+        elif flow_file.exists():
+            self._register_flow_file(flow_file)
+        
+        page = getattr(self, '_current_page', None)
+        current = self._flow_file_for_route(self._current_route)
+        if not page or (current is not None and current.name != file_name):
+            return
+        
+        values = snapshot_inputs(page)
+        offset = self._scroll_offset
+        self._show_route(page, self._current_route)
+        if restore_inputs(page, values):
+            page.update()
+        if offset:
+            page.scroll_to(offset=offset, duration=0)
+        logger.info(f"Hot reloaded {file_name} on {self._current_route}")
+    
+    def _refresh(self):
+        """Redraw the current route after actions changed state"""
+        page = getattr(self, '_current_page', None)
+        if page:
+            self._show_route(page, self._current_route)
+    
+    def _navigate(self, route: str):
+        page = getattr(self, '_current_page', None)
+        if page:
+            page.go(route)
+    
+    def _show_route(self, page: ft.Page, route: str):
+        """Render the flow page for a route"""
+        self._current_route = route
         logger.info(f"Navigating to route: {route}")
         
         # Handle preview routes
@@ -939,17 +1237,7 @@ class FlashFlowEngine:
             # Use the root route to show the main page with the specified platform
             route = "/"
         
-        # Find matching flow file
-        flow_file_path = None
-        if route in self.page_registry:
-            flow_file_path = self.page_registry[route]
-        elif route == "/" and "/app" in self.page_registry:
-            flow_file_path = self.page_registry["/app"]
-        else:
-            # Try to find default app.flow
-            default_flow = self.flow_files_dir / "app.flow"
-            if default_flow.exists():
-                flow_file_path = default_flow
+        flow_file_path = self._flow_file_for_route(route)
         
         if flow_file_path and flow_file_path.exists():
             # Parse and render the flow file
@@ -976,6 +1264,23 @@ class FlashFlowEngine:
             )
         
         page.update()
+        self.navigation.show_dialogs(page)
+    
+    def _flow_file_for_route(self, route: str) -> Union[Path, None]:
+        """The .flow file rendered for a route, if any"""
+        # Preview routes show the main page
+        if route.startswith("/preview/"):
+            route = "/"
+        
+        # Find matching flow file
+        if route in self.page_registry:
+            return self.page_registry[route]
+        elif route == "/" and "/app" in self.page_registry:
+            return self.page_registry["/app"]
+        
+        # Try to find default app.flow
+        default_flow = self.flow_files_dir / "app.flow"
+        return default_flow if default_flow.exists() else None
     
     def _make_api_request(self, method: str, endpoint: str, data: Dict = None) -> Dict:
         """Make API request to Laravel backend"""
@@ -1012,6 +1317,19 @@ def main():
     if len(sys.argv) > 2:
         backend_url = sys.argv[2]
     
+    reporter = None
+    if (Path(project_dir) / "flashflow.json").exists():
+        reporter = install_crash_reporter(Path(project_dir).resolve(), 'engine',
+                                          ProjectState(Path(project_dir).resolve()).logs_dir / "engine.log")
+    
+    try:
+        # FLASHFLOW_ENGINE_PORT, then engine.port in flashflow.json, then 8012
+        project = FlashFlowProject(Path(project_dir).resolve())
+        port = resolve_settings(project if project.exists() else None, keys=['engine.port']).port('engine.port')
+    except SettingsError as e:
+        logger.error(f"Failed to start FlashFlow Engine: {e}")
+        sys.exit(1)
+    
     try:
         # Create and start the FlashFlow Engine
         engine = FlashFlowEngine(project_dir, backend_url)
@@ -1019,15 +1337,25 @@ def main():
         logger.info(f"📂 Project root: {engine.project_root}")
         logger.info(f"📄 Flow files directory: {engine.flow_files_dir}")
         logger.info(f"📡 Backend URL: {engine.backend_url}")
+        logger.info(f"⚡ Listening on port {port}")
+        logger.info(f"🌍 Environment profile: {engine.profile['name']}")
         logger.info(f"🌐 Deployment Environment: {engine.deployment_env}")
         logger.info("🔗 Available routes:")
         for route, file_path in engine.page_registry.items():
             logger.info(f"   {route} -> {file_path.name}")
         
+        # Flet's server takes no extra routes, so /__info gets a port of its own; the CLI finds it in the runtime record
+        info_server = InfoServer(lambda: engine.service_info(port)).start()
+        if project.exists():
+            project.state.write_runtime('engine', dict(project.state.read_runtime('engine') or {}, port=port,
+                                                       info_url=info_server.url))
+        
         # Start the Flet app in web mode
-        ft.app(target=engine.main, view=ft.AppView.WEB_BROWSER, port=8013)
+        ft.app(target=engine.main, view=ft.AppView.WEB_BROWSER, port=port)
     except Exception as e:
         logger.error(f"Failed to start FlashFlow Engine: {e}")
+        if reporter:
+            reporter.report(e)
         sys.exit(1)
 
 if __name__ == "__main__":
@@ -0,0 +1,280 @@
+"""
+Navigation components for the FlashFlow Direct Renderer
+
+    page:
+      path: /orders
+      title: Orders
+      nav_order: 2                # position in generated links; 'nav: false' hides the page
+      body:
+        - component: navbar
+          title: Shop
+          links: auto             # every registered route, or a list of {text, link}
+        - component: sidebar
+          links: [{text: Open, link: /orders}, {text: Archive, link: /orders/archive, icon: archive}]
+          children:
+            - component: tabs
+              bind: orders_tab    # selected tab survives redraws
+              tabs:
+                - label: Recent
+                  children: [{component: text, content: "..."}]
+                - label: Returns
+                  children: [...]
+        - component: modal
+          bind: confirm_open      # open with 'set: {confirm_open: true}' or the trigger button
+          trigger: Cancel order
+          title: Cancel this order?
+          children: [{component: text, content: "This cannot be undone."}]
+          footer:
+            - {component: button, text: "Yes, cancel", on_click: [{call: /api/orders/cancel, method: POST}, {set: {confirm_open: false}}]}
+
+Everything runs as Flet events on the server, so no script is added to the
+page. Open/collapsed/selected state lives in the engine's app state, so it
+survives the redraw that follows every action and hot reload.
+"""
+
+import logging
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+
+import flet as ft
+
+from actions import ActionError, resolve_value
+from core.content import ContentError, read_content_page
+from core.parser.flow_file import FlowParseError, load_flow
+
+logger = logging.getLogger(__name__)
+
+NAVIGATION_COMPONENTS = ('navbar', 'sidebar', 'tabs', 'modal')
+SIDEBAR_WIDTH = 220
+SIDEBAR_COLLAPSED_WIDTH = 56
+
+class NavigationComponents:
+    """Builds navbar, sidebar, tabs and modal controls for the engine"""
+
+    def __init__(self, engine):
+        self.engine = engine
+        self._page_info: Dict[Path, Tuple[float, Dict[str, Any]]] = {}
+        self._dialogs: List[ft.AlertDialog] = []
+        self._shown: List[ft.AlertDialog] = []
+
+    def create(self, component_type: str, component_data: Dict[str, Any], platform: str) -> ft.Control:
+        builder = getattr(self, component_type)
+        return builder(component_data, platform)
+
+    # Routes
+
+    def routes(self, exclude: Optional[List[str]] = None) -> List[Dict[str, Any]]:
+        """Links for every registered page, ordered by nav_order then path"""
+        links = []
+        for route, flow_file in self.engine.page_registry.items():
+            # Routes with parameters have no single URL to link to
+            if ':' in route or '{' in route or route in (exclude or []):
+                continue
+            info = self._info(flow_file)
+            if info.get('nav') is False:
+                continue
+            links.append({'text': info.get('nav_title') or info.get('title') or route, 'link': route,
+                          'icon': info.get('nav_icon'), 'order': info.get('nav_order', 1000)})
+        return sorted(links, key=lambda link: (link['order'], link['link'] != '/', link['link']))
+
+    def _info(self, flow_file: Path) -> Dict[str, Any]:
+        """A page's title and nav settings, re-read only when its file changed"""
+        try:
+            mtime = flow_file.stat().st_mtime
+        except OSError:
+            return {}
+        cached = self._page_info.get(flow_file)
+        if cached and cached[0] == mtime:
+            return cached[1]
+        try:
+            if flow_file.suffix == '.md':
+                page = read_content_page(self.engine.project_root, flow_file).page_data(self.engine.project_root)
+            else:
+                flow_page = load_flow(flow_file, self.engine.project_root).page
+                page = flow_page.data if flow_page else {}
+        except (OSError, FlowParseError, ContentError) as e:
+            logger.warning(f"Could not read page info from {flow_file.name}: {e}")
+            page = {}
+        info = {key: page.get(key) for key in ('title', 'nav', 'nav_title', 'nav_icon')}
+        info['nav_order'] = page.get('nav_order', 1000)
+        self._page_info[flow_file] = (mtime, info)
+        return info
+
+    def _links(self, component_data: Dict[str, Any]) -> List[Dict[str, Any]]:
+        links = component_data.get('links', 'auto')
+        if links == 'auto':
+            return self.routes(component_data.get('exclude'))
+        result = []
+        for link in links if isinstance(links, list) else []:
+            link = link if isinstance(link, dict) else {'text': str(link), 'link': str(link)}
+            if link.get('link'):
+                result.append({'text': link.get('text', link['link']), 'link': link['link'], 'icon': link.get('icon')})
+        return result
+
+    def _is_current(self, link: str) -> bool:
+        current = self.engine._current_route
+        return current == link or (link != '/' and current.startswith(link.rstrip('/') + '/'))
+
+    # Components
+
+    def navbar(self, component_data: Dict[str, Any], platform: str) -> ft.Control:
+        links = self._links(component_data)
+        title = ft.Text(component_data.get('title', ''), size=20, weight=ft.FontWeight.BOLD)
+        if component_data.get('title_link', '/'):
+            title = ft.GestureDetector(content=title, on_tap=self._go(component_data.get('title_link', '/')))
+
+        if platform == 'mobile' or component_data.get('compact'):
+            # Small screens get one menu button instead of a row of links
+            menu = ft.PopupMenuButton(
+                icon=ft.icons.MENU,
+                items=[ft.PopupMenuItem(text=link['text'], checked=self._is_current(link['link']),
+                                        on_click=self._go(link['link'])) for link in links]
+            )
+            items = [title, ft.Container(expand=True), menu]
+        else:
+            buttons = []
+            for link in links:
+                style = ft.ButtonStyle(bgcolor=ft.colors.BLUE_50) if self._is_current(link['link']) else None
+                buttons.append(ft.TextButton(link['text'], icon=_icon(link.get('icon')), style=style,
+                                             on_click=self._go(link['link'])))
+            items = [title, ft.Container(expand=True), ft.Row(buttons, spacing=4, wrap=True)]
+
+        return ft.Container(
+            content=ft.Row(items, vertical_alignment=ft.CrossAxisAlignment.CENTER),
+            padding=ft.padding.symmetric(horizontal=16, vertical=8),
+            border=ft.border.only(bottom=ft.border.BorderSide(1, ft.colors.GREY_300)),
+            bgcolor=component_data.get('background')
+        )
+
+    def sidebar(self, component_data: Dict[str, Any], platform: str) -> ft.Control:
+        key = component_data.get('bind') or 'sidebar_collapsed'
+        collapsed = bool(self.engine.get_state(key, component_data.get('collapsed', platform == 'mobile')))
+
+        def toggle(e):
+            self.engine.set_state(key, not collapsed)
+            self.engine._refresh()
+
+        entries = [ft.IconButton(ft.icons.MENU_OPEN if not collapsed else ft.icons.MENU, on_click=toggle,
+                                 tooltip='Collapse' if not collapsed else 'Expand')]
+        if component_data.get('title') and not collapsed:
+            entries.append(ft.Text(component_data['title'], size=16, weight=ft.FontWeight.BOLD))
+        for link in self._links(component_data):
+            icon = _icon(link.get('icon')) or ft.icons.CHEVRON_RIGHT
+            entries.append(ft.ListTile(
+                leading=ft.Icon(icon),
+                title=None if collapsed else ft.Text(link['text']),
+                selected=self._is_current(link['link']),
+                tooltip=link['text'] if collapsed else None,
+                dense=True,
+                on_click=self._go(link['link'])
+            ))
+
+        panel = ft.Container(
+            content=ft.Column(entries, spacing=2),
+            width=SIDEBAR_COLLAPSED_WIDTH if collapsed else component_data.get('width', SIDEBAR_WIDTH),
+            padding=ft.padding.only(right=8),
+            border=ft.border.only(right=ft.border.BorderSide(1, ft.colors.GREY_300))
+        )
+        content = ft.Column(self._children(component_data.get('children'), platform), spacing=20, expand=True)
+        return ft.Row([panel, content], vertical_alignment=ft.CrossAxisAlignment.START, spacing=16)
+
+    def tabs(self, component_data: Dict[str, Any], platform: str) -> ft.Control:
+        tabs = [tab for tab in component_data.get('tabs', []) if isinstance(tab, dict)]
+        if not tabs:
+            return ft.Container()
+        key = component_data.get('bind')
+        selected = self.engine.get_state(key, component_data.get('selected', 0)) if key else component_data.get('selected', 0)
+        selected = selected if isinstance(selected, int) and 0 <= selected < len(tabs) else 0
+
+        def on_change(e):
+            # Flet swaps the content itself; the state only remembers the choice for the next redraw
+            if key:
+                self.engine.app_state[key] = e.control.selected_index
+
+        context = self.engine.actions.context()
+        controls = []
+        for tab in tabs:
+            try:
+                label = str(resolve_value(tab.get('label', ''), context))
+            except ActionError:
+                label = str(tab.get('label', ''))
+            controls.append(ft.Tab(
+                text=label,
+                icon=_icon(tab.get('icon')),
+                content=ft.Container(ft.Column(self._children(tab.get('children'), platform), spacing=20),
+                                     padding=ft.padding.only(top=16))
+            ))
+        return ft.Tabs(tabs=controls, selected_index=selected, on_change=on_change,
+                       animation_duration=150, height=component_data.get('height', 400))
+
+    def modal(self, component_data: Dict[str, Any], platform: str) -> ft.Control:
+        key = component_data.get('bind') or f"modal_open:{component_data.get('title', '')}"
+
+        def close(e=None):
+            self.engine.set_state(key, False)
+            self.engine._refresh()
+
+        def open_modal(e):
+            self.engine.set_state(key, True)
+            self.engine._refresh()
+
+        footer = self._children(component_data.get('footer'), platform)
+        if component_data.get('dismissible', True):
+            footer.append(ft.TextButton(component_data.get('close_text', 'Close'), on_click=close))
+        dialog = ft.AlertDialog(
+            modal=not component_data.get('dismissible', True),
+            title=ft.Text(component_data.get('title', '')),
+            content=ft.Column(self._children(component_data.get('children'), platform), tight=True, spacing=12),
+            actions=footer,
+            on_dismiss=lambda e: self.engine.set_state(key, False)
+        )
+        if self.engine.get_state(key):
+            self._dialogs.append(dialog)
+
+        trigger = component_data.get('trigger')
+        if not trigger:
+            return ft.Container()
+        return ft.OutlinedButton(trigger, on_click=open_modal)
+
+    # Dialogs are page overlays, so they are opened after the page body is drawn
+
+    def show_dialogs(self, page: ft.Page):
+        """Close the dialogs of the previous draw and open the ones this draw left open"""
+        for dialog in self._shown:
+            _close_dialog(page, dialog)
+        self._shown, self._dialogs = self._dialogs, []
+        for dialog in self._shown:
+            _open_dialog(page, dialog)
+
+    # Helpers
+
+    def _children(self, children: Any, platform: str) -> List[ft.Control]:
+        if not isinstance(children, list):
+            return []
+        return [self.engine._create_component(child, platform) for child in children if isinstance(child, dict)]
+
+    def _go(self, route: str):
+        return lambda e: self.engine._navigate(route)
+
+def _icon(name: Optional[str]) -> Optional[str]:
+    """A Flet icon from a flow name like 'archive' or 'shopping_cart'"""
+    if not name:
+        return None
+    return getattr(ft.icons, str(name).upper(), None)
+
+def _open_dialog(page: ft.Page, dialog: ft.AlertDialog):
+    if hasattr(page, 'open'):
+        page.open(dialog)
+    else:
+        page.dialog = dialog
+        dialog.open = True
+        page.update()
+
+def _close_dialog(page: ft.Page, dialog: ft.AlertDialog):
+    # Dismissal fires on_dismiss, which would reset the state of a dialog that should stay open
+    dialog.on_dismiss = None
+    if hasattr(page, 'close'):
+        page.close(dialog)
+    else:
+        dialog.open = False
+        page.update()
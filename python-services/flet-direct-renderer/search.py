@@ -0,0 +1,110 @@
+"""
+Semantic search component for the FlashFlow Direct Renderer
+
+    page:
+      path: /ask
+      body:
+        - component: search
+          collection: products      # vector index filled through /api/ai/embed with 'collection'
+          top_k: 5
+          placeholder: Describe what you are looking for
+          fields: [title, price]    # metadata shown per result, first as the title; default the stored text
+          bind: product_search      # query and results in app state, as {query, results, error}
+          on_select:                # optional; 'form' holds the chosen result
+            - set: {picked: "{{ form.title }}"}
+
+The query is embedded with the dev server's 'embeddings' model and searched
+in the collection, so results come back ranked by meaning rather than by
+matching words. Nothing runs in the browser; the search is a Flet event.
+"""
+
+import logging
+from typing import Any, Dict, List
+from urllib.parse import quote
+
+import flet as ft
+
+logger = logging.getLogger(__name__)
+
+SEARCH_COMPONENTS = ('search',)
+DEFAULT_TOP_K = 5
+
+class SearchComponents:
+    """Builds the search box and results list for the engine"""
+
+    def __init__(self, engine):
+        self.engine = engine
+
+    def create(self, component_type: str, component_data: Dict[str, Any], platform: str) -> ft.Control:
+        builder = getattr(self, component_type)
+        return builder(component_data, platform)
+
+    def search(self, component_data: Dict[str, Any], platform: str) -> ft.Control:
+        collection = component_data.get('collection')
+        if not collection:
+            return ft.Text("search needs a 'collection'", color=ft.colors.RED)
+        bind = component_data.get('bind') or f"search_{collection}"
+        state = self.engine.get_state(bind) or {}
+        fields = component_data.get('fields') or []
+        fields = [fields] if isinstance(fields, str) else list(fields)
+
+        def run(e):
+            query = (box.value or '').strip()
+            if query:
+                self.engine.set_state(bind, dict(self.query(collection, query, component_data.get('top_k', DEFAULT_TOP_K)), query=query))
+            else:
+                self.engine.set_state(bind, {})
+            self.engine.actions.refresh()
+
+        box = ft.TextField(
+            value=state.get('query', ''),
+            hint_text=component_data.get('placeholder', 'Search'),
+            prefix_icon=ft.icons.SEARCH,
+            on_submit=run,
+            expand=True
+        )
+        controls = [ft.Row([box, ft.IconButton(ft.icons.ARROW_FORWARD, tooltip='Search', on_click=run)])]
+
+        if state.get('error'):
+            controls.append(ft.Text(state['error'], color=ft.colors.RED))
+        elif state.get('query') and not state.get('results'):
+            controls.append(ft.Text(component_data.get('empty', 'No matches'), color=ft.colors.GREY))
+        for hit in state.get('results', []):
+            controls.append(self._result(hit, fields, component_data.get('on_select')))
+        return ft.Column(controls, spacing=8, width=component_data.get('width', 640))
+
+    def query(self, collection: str, text: str, top_k: Any) -> Dict[str, Any]:
+        """Embed the text, then search the collection; returns {results} or {error}"""
+        embedded = self.engine._make_api_request('POST', '/api/ai/embed', {'text': text})
+        if 'error' in embedded:
+            logger.warning(f"Embedding the search query failed: {embedded['error']}")
+            return {'error': f"Search is unavailable: {embedded['error']}", 'results': []}
+        try:
+            k = max(1, int(top_k))
+        except (TypeError, ValueError):
+            k = DEFAULT_TOP_K
+        found = self.engine._make_api_request('POST', f"/vector/indexes/{quote(str(collection), safe='')}/search",
+                                              {'vector': embedded['embeddings'][0], 'k': k})
+        if 'error' in found:
+            logger.warning(f"Searching '{collection}' failed: {found['error']}")
+            return {'error': f"Search is unavailable: {found['error']}", 'results': []}
+        return {'error': None, 'results': [self._flatten(hit) for hit in found.get('results', [])]}
+
+    def _flatten(self, hit: Dict[str, Any]) -> Dict[str, Any]:
+        """A hit's metadata next to its id, score and text, as actions see it"""
+        flat = dict(hit.get('metadata') or {})
+        flat.update(id=hit.get('id'), score=hit.get('score'), document=hit.get('document'))
+        return flat
+
+    def _result(self, hit: Dict[str, Any], fields: List[str], on_select: Any) -> ft.Control:
+        title = hit.get(fields[0]) if fields else None
+        title = title if title is not None else hit.get('document') or str(hit.get('id'))
+        details = [f"{field}: {hit[field]}" for field in fields[1:] if hit.get(field) is not None]
+        score = hit.get('score')
+        tile = ft.ListTile(
+            title=ft.Text(str(title)),
+            subtitle=ft.Text(' · '.join(details)) if details else None,
+            trailing=ft.Text(f"{score:.2f}", color=ft.colors.GREY) if isinstance(score, (int, float)) else None,
+            on_click=(lambda e: self.engine.actions.run(on_select, hit)) if on_select else None
+        )
+        return ft.Container(content=tile, border=ft.border.all(1, ft.colors.BLUE_100), border_radius=8)
@@ -0,0 +1,151 @@
+"""
+Unit tests for the HTML component templates (core/static_site.py)
+
+The previews and the static export draw components from these; one test per
+template, each with its props checked through core/render_context.py.
+
+    python test_html_templates.py
+"""
+
+import os
+import sys
+import tempfile
+import unittest
+from pathlib import Path
+from types import SimpleNamespace
+
+# The repository root, for core
+sys.path.insert(0, os.path.abspath(os.path.join(os.path.dirname(os.path.abspath(__file__)), '..', '..')))
+
+from core.framework import FlashFlowProject
+from core.render_context import compile_components
+
+class HtmlTemplateTest(unittest.TestCase):
+
+    @classmethod
+    def setUpClass(cls):
+        from core.static_site import StaticSiteExporter
+        root = Path(tempfile.mkdtemp())
+        (root / 'flashflow.json').write_text('{"name": "templates"}')
+        ir = SimpleNamespace(i18n={}, pages=[])
+        cls.exporter = StaticSiteExporter(FlashFlowProject(root), ir, features={})
+        cls.exporter._pages = {'/', '/pricing/'}
+        cls.exporter._links = [{'text': 'Home', 'link': '/'}, {'text': 'Pricing', 'link': '/pricing'}]
+
+    def render(self, *body, path='/'):
+        components, problems = compile_components(list(body), self.exporter.component_types())
+        self.problems = [str(problem) for problem in problems]
+        context = self.exporter.context(SimpleNamespace(url_path=path))
+        return self.exporter.render_components(components, context)
+
+    def test_header(self):
+        self.assertEqual(self.render({'header': {'content': 'Shop <now>'}}), '<h2>Shop &lt;now&gt;</h2>')
+
+    def test_headline_levels_stay_in_range(self):
+        self.assertEqual(self.render({'headline': {'text': 'A', 'level': 2}}), '<h3>A</h3>')
+        self.assertEqual(self.render({'headline': {'text': 'A', 'level': 9}}), '<h6>A</h6>')
+        self.assertEqual(self.render({'headline': {'text': 'A', 'level': '1'}}), '<h2>A</h2>')
+
+    def test_headline_with_a_bad_level_falls_back(self):
+        self.assertEqual(self.render({'headline': {'text': 'A', 'level': 'big'}}), '<h2>A</h2>')
+        self.assertEqual(len(self.problems), 1)
+        self.assertIn("'level' must be an integer", self.problems[0])
+
+    def test_text(self):
+        self.assertEqual(self.render({'text': {'content': 42}}), '<p>42</p>')
+
+    def test_rich_text_is_sanitized(self):
+        html = self.render({'rich_text': {'html': '<b onclick="x()">bold</b><script>alert(1)</script>'}})
+        self.assertEqual(html, '<div class="rich-text"><b>bold</b></div>')
+
+    def test_markdown(self):
+        self.assertEqual(self.render({'markdown': {'content': '**hi**'}}), '<div class="markdown">\n<p><strong>hi</strong></p>\n</div>')
+
+    def test_hero(self):
+        html = self.render({'hero': {'title': 'Fast', 'subtitle': 'Apps', 'cta': {'link': '/pricing'}}})
+        self.assertEqual(html, '<section class="hero">\n<h2>Fast</h2>\n<p class="subtitle">Apps</p>\n'
+                               '<a class="button" href="/pricing/">Get Started</a>\n</section>')
+
+    def test_card_with_children(self):
+        html = self.render({'card': {'title': 'Plan', 'children': [{'text': {'content': 'Body'}}]}})
+        self.assertEqual(html, '<article class="card">\n<h3>Plan</h3>\n<p>Body</p>\n</article>')
+
+    def test_features_skips_entries_that_are_not_mappings(self):
+        html = self.render({'features': {'items': [{'title': 'One', 'description': 'First'}, 'stray']}})
+        self.assertEqual(html, '<section class="features">\n<article class="feature"><h3>One</h3><p>First</p></article>\n</section>')
+
+    def test_button(self):
+        self.assertEqual(self.render({'button': {'text': 'Go', 'link': 'javascript:alert(1)'}}),
+                         '<a class="button" href="#">Go</a>')
+        self.assertEqual(self.render({'primary_button': {'text': 'Go'}}), '<button type="button" disabled>Go</button>')
+
+    def test_input(self):
+        self.assertEqual(self.render({'input': {'label': 'Name', 'value': '"x"', 'disabled': True}}),
+                         '<label>Name <input value="&quot;x&quot;" disabled></label>')
+
+    def test_form(self):
+        html = self.render({'form': {'fields': ['full_name', {'name': 'bio', 'type': 'textarea'},
+                                                {'name': 'pw', 'type': 'password', 'label': 'Password'}],
+                                     'action': '/api/signup'}})
+        self.assertEqual(html, '<form method="post" action="/api/signup">\n'
+                               '<label>Full Name <input type="text" name="full_name" value=""></label>\n'
+                               '<label>Bio <textarea name="bio"></textarea></label>\n'
+                               '<label>Password <input type="password" name="pw" value=""></label>\n'
+                               '<button type="submit">Submit</button>\n</form>')
+
+    def test_form_without_action_cannot_submit(self):
+        self.assertIn('<button type="submit" disabled>', self.render({'form': {'fields': ['email']}}))
+
+    def test_image(self):
+        self.assertEqual(self.render({'image': {'src': '/logo.png', 'alt': 'Logo', 'width': 120}}),
+                         '<img src="/logo.png" alt="Logo" loading="lazy" width="120">')
+
+    def test_video(self):
+        self.assertEqual(self.render({'video': {'src': '/intro.mp4', 'muted': True}}),
+                         '<video src="/intro.mp4" controls muted width="640"></video>')
+
+    def test_gallery(self):
+        html = self.render({'gallery': {'images': ['/a.png', {'src': '/b.png', 'alt': 'B'}], 'columns': 2}})
+        self.assertEqual(html, '<div class="gallery" style="--columns: 2">\n'
+                               '<img src="/a.png" alt="" loading="lazy">\n'
+                               '<img src="/b.png" alt="B" loading="lazy">\n</div>')
+
+    def test_navbar_marks_the_current_page(self):
+        html = self.render({'navbar': {'title': 'Shop'}}, path='/pricing/')
+        self.assertEqual(html, '<nav class="navbar"><a class="brand" href="/">Shop</a><ul>'
+                               '<li><a href="/">Home</a></li>'
+                               '<li><a href="/pricing/" aria-current="page">Pricing</a></li></ul></nav>')
+
+    def test_sidebar_with_its_own_links(self):
+        html = self.render({'sidebar': {'title': 'Docs', 'links': [{'text': 'Start', 'link': '/start'}],
+                                        'children': [{'text': {'content': 'Hi'}}]}})
+        self.assertEqual(html, '<div class="with-sidebar">\n<aside class="sidebar"><h2>Docs</h2><nav><ul>'
+                               '<li><a href="/start">Start</a></li></ul></nav></aside>\n'
+                               '<div class="sidebar-content">\n<p>Hi</p>\n</div>\n</div>')
+
+    def test_tabs(self):
+        html = self.render({'component': 'tabs', 'selected': 1, 'tabs': [
+            {'label': 'One', 'children': [{'text': {'content': '1'}}]},
+            {'label': 'Two', 'children': [{'text': {'content': '2'}}]}]})
+        self.assertEqual(html, '<div class="tabs">\n'
+                               '<details class="tab"><summary>One</summary>\n<p>1</p>\n</details>\n'
+                               '<details class="tab" open><summary>Two</summary>\n<p>2</p>\n</details>\n</div>')
+
+    def test_modal(self):
+        html = self.render({'modal': {'title': 'Sure?', 'trigger': 'Delete', 'footer': [{'button': {'text': 'Yes'}}]}})
+        self.assertEqual(html, '<details class="modal"><summary>Delete</summary>\n<h3>Sure?</h3>\n'
+                               '<button type="button" disabled>Yes</button>\n</details>')
+
+    def test_unknown_component(self):
+        self.assertEqual(self.render({'component': 'carousel'}), "<!-- 'carousel' has no static rendering -->")
+        self.assertEqual(self.problems, ["body[0] (carousel): unknown component 'carousel'"])
+
+    def test_translated_labels(self):
+        self.exporter.messages = {'Submit': 'Absenden'}
+        try:
+            self.assertIn('>Absenden</button>', self.render({'form': {'fields': ['email'], 'action': '/x'}}))
+        finally:
+            self.exporter.messages = {}
+
+if __name__ == '__main__':
+    unittest.main()
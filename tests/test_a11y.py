@@ -0,0 +1,43 @@
+"""
+Tests for core/a11y.py
+"""
+
+import json
+import tempfile
+import unittest
+from pathlib import Path
+
+from core.a11y import A11yError, A11yReport, audit_failures
+from core.framework import FlashFlowProject
+
+class AuditFailuresTest(unittest.TestCase):
+
+    def project(self, fail_on=None):
+        root = Path(tempfile.mkdtemp())
+        config = {'name': 'a11y-test'}
+        if fail_on:
+            config['a11y'] = {'fail_on': fail_on}
+        (root / 'flashflow.json').write_text(json.dumps(config))
+        project = FlashFlowProject(root)
+        A11yReport.for_project(project).record('/', [
+            {'id': 'color-contrast', 'impact': 'serious', 'nodes': [{'html': '<p>'}]},
+            {'id': 'region', 'impact': 'moderate', 'nodes': []},
+        ])
+        return project
+
+    def test_project_setting(self):
+        self.assertEqual([failure['id'] for failure in audit_failures(self.project())], ['color-contrast'])
+        self.assertEqual(audit_failures(self.project('critical')), [])
+
+    def test_threshold_overrides_the_setting(self):
+        failures = audit_failures(self.project('critical'), 'moderate')
+        self.assertEqual([(failure['page'], failure['id']) for failure in failures], [('/', 'color-contrast'), ('/', 'region')])
+
+    def test_unknown_level(self):
+        with self.assertRaises(A11yError):
+            audit_failures(self.project(), 'severe')
+        with self.assertRaises(A11yError):
+            audit_failures(self.project('severe'))
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,39 @@
+"""
+Tests for core/api_keys.py
+"""
+
+import tempfile
+import unittest
+from pathlib import Path
+
+from core.api_keys import ApiKeyError, ApiKeyStore, sign_request, verify_signature
+
+class HostileCredentialsTest(unittest.TestCase):
+
+    def setUp(self):
+        self.store = ApiKeyStore(Path(tempfile.mkdtemp()) / 'api_keys.db')
+        self.key, self.full = self.store.create_key('ci')
+        self.secret = self.full.partition('.')[2]
+
+    def test_non_ascii_key_is_unknown(self):
+        self.assertEqual(self.store.authenticate(self.full)['id'], self.key['id'])
+        # Header values arrive decoded as latin-1, so any byte can show up
+        self.assertIsNone(self.store.authenticate(f"{self.key['id']}.café"))
+        self.assertIsNone(self.store.authenticate(f"{self.key['id']}.ÿ" * 3))
+
+    def test_non_ascii_signature_does_not_match(self):
+        header = sign_request(self.secret, 'GET', '/api/data/orders', timestamp=1000)
+        self.assertEqual(verify_signature(self.secret, header, 'GET', '/api/data/orders', b'', now=1000)[0], 1000)
+        with self.assertRaises(ApiKeyError):
+            verify_signature(self.secret, 't=1000,v1=café', 'GET', '/api/data/orders', b'', now=1000)
+
+    def test_query_that_is_not_utf8_is_signed_as_sent(self):
+        # As the dev server decodes ?q=%FF sent raw: b'q=\xff'
+        target = '/api/data/orders?' + b'q=\xff'.decode('utf-8', 'surrogateescape')
+        header = sign_request(self.secret, 'GET', target, timestamp=1000)
+        self.assertEqual(verify_signature(self.secret, header, 'GET', target, b'', now=1000)[0], 1000)
+        with self.assertRaises(ApiKeyError):
+            verify_signature(self.secret, header, 'GET', '/api/data/orders?q=�', b'', now=1000)
+
+if __name__ == '__main__':
+    unittest.main()
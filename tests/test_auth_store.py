@@ -0,0 +1,37 @@
+"""
+Tests for cli/devserver/auth_store.py
+"""
+
+import sqlite3
+import tempfile
+import unittest
+from pathlib import Path
+
+from cli.devserver.auth_store import AuthStoreError, DevAuthStore
+
+class ScopesTest(unittest.TestCase):
+
+    def setUp(self):
+        self.path = Path(tempfile.mkdtemp()) / 'auth.db'
+
+    def test_scopes_are_stored_with_the_user(self):
+        store = DevAuthStore(self.path)
+        user = store.create_user('dev@example.com', 'secret1')
+        self.assertEqual(user['scopes'], [])
+        store.set_scopes(user['id'], ['todos:write', ' todos:read ', 'todos:write', ''])
+        self.assertEqual(store.authenticate('dev@example.com', 'secret1')['scopes'], ['todos:write', 'todos:read'])
+        with self.assertRaises(AuthStoreError):
+            store.set_scopes(user['id'], ['todos write'])
+
+    def test_older_stores_gain_the_column(self):
+        with sqlite3.connect(str(self.path)) as conn:
+            conn.execute("CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, email VARCHAR(255) NOT NULL UNIQUE, "
+                         "name VARCHAR(255) NOT NULL DEFAULT '', password_hash VARCHAR(255) NOT NULL, "
+                         "role VARCHAR(50) NOT NULL DEFAULT 'user', disabled BOOLEAN NOT NULL DEFAULT 0, "
+                         "created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)")
+        store = DevAuthStore(self.path)
+        user = store.create_user('old@example.com', 'secret1', role='admin')
+        self.assertEqual(store.get_user(user['id'])['scopes'], [])
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,67 @@
+"""
+Tests for cli/commands/bench.py: the timing loop and the regression gate
+
+The FlashCore benchmarks themselves run when the bindings are built.
+"""
+
+import unittest
+from unittest import mock
+
+from cli.commands import bench
+
+try:
+    import flashcore
+except ImportError:
+    flashcore = None
+
+class CompareToBaselineTest(unittest.TestCase):
+
+    def test_slowdown_past_the_threshold_is_a_regression(self):
+        regressions = bench.compare_to_baseline({'Search/dim=128/k=1': {'ns_per_op': 1200}},
+                                                {'Search/dim=128/k=1': {'ns_per_op': 1000}}, 15)
+        self.assertEqual(regressions, ['Search/dim=128/k=1: 1.00µs → 1.20µs (+20.0%)'])
+
+    def test_slowdown_within_the_threshold_passes(self):
+        self.assertEqual(bench.compare_to_baseline({'a': {'ns_per_op': 1100}}, {'a': {'ns_per_op': 1000}}, 15), [])
+
+    def test_speedups_pass(self):
+        self.assertEqual(bench.compare_to_baseline({'a': {'ns_per_op': 500}}, {'a': {'ns_per_op': 1000}}, 0), [])
+
+    def test_benchmarks_without_a_baseline_are_skipped(self):
+        results = {'new': {'ns_per_op': 10}, 'zero': {'ns_per_op': 10}}
+        self.assertEqual(bench.compare_to_baseline(results, {'zero': {'ns_per_op': 0}}, 15), [])
+
+class RunBenchmarkTest(unittest.TestCase):
+
+    def test_runs_until_the_minimum_time(self):
+        calls = []
+        setups = []
+
+        def setup():
+            setups.append(1)
+            return lambda: calls.append(1)
+
+        with mock.patch.object(bench, 'MIN_BENCH_SECONDS', 0.01):
+            ns_per_op, iterations = bench.run_benchmark(setup)
+        self.assertEqual(len(setups), 1)
+        # The warm-up call and every timed round, the last of which is the one reported
+        self.assertGreater(len(calls), iterations)
+        self.assertGreater(ns_per_op, 0)
+
+    def test_format_ns(self):
+        self.assertEqual([bench.format_ns(ns) for ns in (12, 1_500, 2_500_000, 3e9)], ['12ns', '1.50µs', '2.50ms', '3.00s'])
+
+@unittest.skipIf(flashcore is None, "FlashCore bindings are not built")
+class FlashCoreBenchmarksTest(unittest.TestCase):
+
+    def test_every_benchmark_runs(self):
+        benchmarks = bench.flashcore_benchmarks(flashcore)
+        self.assertEqual(len(benchmarks), 9)
+        with mock.patch.object(bench, 'MIN_BENCH_SECONDS', 0.001):
+            for name, setup in benchmarks.items():
+                with self.subTest(name):
+                    ns_per_op, iterations = bench.run_benchmark(setup)
+                    self.assertGreater(ns_per_op, 0)
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,33 @@
+"""
+Tests for core/utils/expressions.py
+"""
+
+import unittest
+
+from core.utils.expressions import ExpressionError, evaluate
+
+class SizeLimitTest(unittest.TestCase):
+
+    def test_repeat_bounded(self):
+        self.assertEqual(len(evaluate("'ab' * 10", {})), 20)
+        with self.assertRaises(ExpressionError):
+            evaluate("'a' * 1000000000", {})
+
+    def test_replace_bounded(self):
+        self.assertEqual(evaluate("name.replace('-', ' ')", {'name': 'a-b-c'}), 'a b c')
+        self.assertEqual(len(evaluate("('a' * 1000).replace('a', 'b' * 1000, 5)", {})), 5995)
+        for source in ("('a' * 1000).replace('a', 'b' * 1000).replace('b', 'c' * 1000)",
+                       "('a' * 1000).replace('a', 'b' * 1000)",
+                       "'abc'.replace('', 'x' * 5000)"):
+            with self.assertRaises(ExpressionError, msg=source):
+                evaluate(source, {})
+
+    def test_format_bounded(self):
+        self.assertEqual(evaluate("'%.2f' % price", {'price': 3.14159}), '3.14')
+        self.assertEqual(evaluate("'%d%%' % 5", {}), '5%')
+        for source in ("'%0999999999s' % 'a'", "'%.999999999f' % 1.0", "'%*s' % [999999999, 'a']"):
+            with self.assertRaises(ExpressionError, msg=source):
+                evaluate(source, {})
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,53 @@
+"""
+Golden-file tests for includes in core/parser/flow_file.py
+
+Each folder in tests/golden/includes is a project whose src/flows/page.flow
+is loaded; expected.json holds the merged data and the files included, or
+the error and its line. Run with FLASHFLOW_UPDATE_GOLDEN=1 to rewrite the
+expected files after an intended change, and review the diff.
+"""
+
+import json
+import os
+import unittest
+from pathlib import Path
+
+from core.parser.flow_file import FlowIncludeError, load_flow
+
+GOLDEN = Path(__file__).parent / 'golden' / 'includes'
+
+def outcome(project: Path):
+    try:
+        document = load_flow(project / 'src' / 'flows' / 'page.flow', project)
+    except FlowIncludeError as e:
+        return {'error': str(e), 'line': e.line}
+    return {'data': document.data, 'includes': [path.relative_to(project.resolve()).as_posix() for path in document.includes]}
+
+class FlowIncludesGoldenTest(unittest.TestCase):
+
+    def check(self, case: str):
+        project = GOLDEN / case
+        actual = outcome(project)
+        expected_file = project / 'expected.json'
+        if os.environ.get('FLASHFLOW_UPDATE_GOLDEN'):
+            expected_file.write_text(json.dumps(actual, indent=2, ensure_ascii=False) + '\n', encoding='utf-8')
+        self.assertEqual(actual, json.loads(expected_file.read_text(encoding='utf-8')))
+
+    def test_nested(self):
+        self.check('nested')
+
+    def test_cycle(self):
+        self.check('cycle')
+
+    def test_missing(self):
+        self.check('missing')
+
+    def test_broken_include(self):
+        self.check('broken_include')
+
+    def test_every_case_is_tested(self):
+        cases = {path.name for path in GOLDEN.iterdir() if path.is_dir()}
+        self.assertEqual(cases, {name[len('test_'):] for name in dir(self) if name.startswith('test_')} - {'every_case_is_tested'})
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,67 @@
+"""
+Tests for lazy page bodies in core/parser/flow_loader.py
+"""
+
+import copy
+import json
+import pickle
+import tempfile
+import unittest
+from pathlib import Path
+
+from core.parser.flow_loader import LazyPage
+from core.parser.parser import FlowParser
+
+def page_flow(i: int) -> str:
+    return f"page:\n  path: /p{i}\n  title: P{i}\n  body:\n    - component: text\n      content: hello {i}\n"
+
+class LazyPageTest(unittest.TestCase):
+
+    def setUp(self):
+        self.root = Path(tempfile.mkdtemp())
+        (self.root / 'flashflow.json').write_text('{"name": "lazy"}')
+        flows = self.root / 'src' / 'flows'
+        flows.mkdir(parents=True)
+        for i in range(3):
+            (flows / f'p{i}.flow').write_text(page_flow(i))
+        (flows / 'todo.flow').write_text("model:\n  name: Todo\n  fields:\n    - name: title\n      type: string\n")
+        (flows / 'todos.flow').write_text("page:\n  path: /todo-list\n  title: Todos\n  body:\n"
+                                          "    - component: list\n      data_source: Todo\n")
+        FlowParser().parse_project(self.root)
+
+    def cached(self):
+        parser = FlowParser()
+        return parser, parser.parse_project(self.root)
+
+    def test_cached_parse_leaves_bodies_unloaded(self):
+        parser, ir = self.cached()
+        pages = [page for page in ir.pages.values() if isinstance(page, LazyPage)]
+        self.assertEqual(len(pages), 4)
+        self.assertFalse(any(page.loaded for page in pages))
+        # Default pages were skipped for Todo without reading /todo-list's body
+        self.assertNotIn('/todos/create', ir.pages)
+        page = ir.pages['/p1']
+        self.assertEqual((page['path'], page.get('title')), ('/p1', 'P1'))
+        self.assertFalse(page.loaded)
+        self.assertEqual(page['body'], [{'component': 'text', 'content': 'hello 1'}])
+        self.assertTrue(page.loaded)
+        parser.graph
+        self.assertTrue(all(page.loaded for page in pages))
+
+    def test_behaves_as_dict(self):
+        expected = {'path': '/p2', 'title': 'P2', 'body': [{'component': 'text', 'content': 'hello 2'}]}
+        read = lambda: self.cached()[1].pages['/p2']
+        self.assertEqual(read(), read())
+        self.assertEqual(read(), expected)
+        self.assertEqual(json.loads(json.dumps(read())), expected)
+        self.assertEqual(dict(read()), expected)
+        self.assertEqual({**read()}, expected)
+        for copied in (pickle.loads(pickle.dumps(read())), copy.deepcopy(read()), read().copy()):
+            self.assertIs(type(copied), dict)
+            self.assertEqual(copied, expected)
+        page = read()
+        page['body'] = []
+        self.assertEqual(page['body'], [])
+
+if __name__ == '__main__':
+    unittest.main()
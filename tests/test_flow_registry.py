@@ -0,0 +1,62 @@
+"""
+Tests for cli/devserver/flow_registry.py
+"""
+
+import json
+import os
+import tempfile
+import unittest
+from pathlib import Path
+
+from core.framework import FlashFlowProject
+from cli.devserver.flow_registry import FlowRegistry, FlowSectionRegistry
+
+class HookNames(FlowSectionRegistry):
+
+    def _load(self, documents):
+        self.names = [hook['name'] for value, _ in self.sections(documents, 'hooks') for hook in value]
+
+class FlowRegistryTest(unittest.TestCase):
+
+    def setUp(self):
+        self.root = Path(tempfile.mkdtemp())
+        (self.root / 'flashflow.json').write_text(json.dumps({'name': 'registry-test'}))
+        (self.root / 'src' / 'flows').mkdir(parents=True)
+        (self.root / 'src' / 'layouts').mkdir(parents=True)
+        self.write('src/layouts/shared.flow', "hooks: [{name: shared}]\n")
+        self.write('src/flows/home.flow', "include: shared\npage: {path: /}\n")
+        self.hooks = HookNames(FlowRegistry(FlashFlowProject(self.root)))
+
+    def write(self, name, content):
+        path = self.root / name
+        path.write_text(content)
+        # Each write a few seconds on from the last, as a change looks on any filesystem
+        self.clock = getattr(self, 'clock', 1_000_000_000) + 5
+        os.utime(path, (self.clock, self.clock))
+
+    def test_loads_once_until_something_changes(self):
+        self.assertTrue(self.hooks.reload_if_changed())
+        self.assertEqual(self.hooks.names, ['shared'])
+        self.assertFalse(self.hooks.reload_if_changed())
+
+    def test_include_change_reloads(self):
+        self.hooks.reload_if_changed()
+        self.write('src/layouts/shared.flow', "hooks: [{name: edited}]\n")
+        self.assertTrue(self.hooks.reload_if_changed())
+        self.assertEqual(self.hooks.names, ['edited'])
+
+    def test_parse_error_keeps_last_good_version(self):
+        self.hooks.reload_if_changed()
+        self.write('src/flows/home.flow', "include: shared\npage: {path: /\n")
+        with self.assertLogs('cli.devserver.flow_registry', 'ERROR'):
+            self.hooks.reload_if_changed()
+        self.assertEqual(self.hooks.names, ['shared'])
+        self.assertIn('home.flow', self.hooks.flows.failures)
+
+        self.write('src/flows/home.flow', "page: {path: /}\n")
+        self.hooks.reload_if_changed()
+        self.assertEqual(self.hooks.names, [])
+        self.assertEqual(self.hooks.flows.failures, {})
+
+if __name__ == '__main__':
+    unittest.main()
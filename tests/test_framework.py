@@ -0,0 +1,43 @@
+"""
+Tests for core/framework.py
+"""
+
+import json
+import tempfile
+import unittest
+from dataclasses import fields
+from pathlib import Path
+
+from core.framework import FlashFlowConfig, FlashFlowProject
+
+class SaveConfigTest(unittest.TestCase):
+
+    def setUp(self):
+        self.root = Path(tempfile.mkdtemp())
+
+    def test_every_field_survives_a_save(self):
+        config = {config_field.name: {'set': config_field.name} for config_field in fields(FlashFlowConfig)}
+        config.update(name='app', version='1.2.0', description='d', author='a', dependencies=['x'],
+                      default_profile='staging')
+        (self.root / 'flashflow.json').write_text(json.dumps(config))
+
+        project = FlashFlowProject(self.root)
+        project.config.profiles['staging'] = {'env': {'DEBUG': '0'}}
+        project.save_config()
+
+        saved = json.loads((self.root / 'flashflow.json').read_text())
+        config['profiles']['staging'] = {'env': {'DEBUG': '0'}}
+        self.assertEqual(saved, config)
+
+    def test_unset_sections_stay_out(self):
+        (self.root / 'flashflow.json').write_text(json.dumps({'name': 'app'}))
+        project = FlashFlowProject(self.root)
+        project.config.security_headers = {'csp': "default-src 'self'"}
+        project.save_config()
+
+        saved = json.loads((self.root / 'flashflow.json').read_text())
+        self.assertEqual(set(saved), {'name', 'version', 'description', 'author', 'frameworks', 'dependencies',
+                                      'security_headers'})
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,108 @@
+"""
+Tests for core/html_safety.py, with the kind of content a hostile flow file would carry
+"""
+
+import unittest
+
+from core.html_safety import UNSAFE_URL, escape_html, safe_url, sanitize_html
+from core.markdown import render_markdown
+
+class EscapeTest(unittest.TestCase):
+
+    def test_markup_is_text(self):
+        self.assertEqual(escape_html('<script>alert(1)</script>'), '&lt;script&gt;alert(1)&lt;/script&gt;')
+
+    def test_attribute_breakout_stays_inside_the_quotes(self):
+        for value in ('" onmouseover="alert(1)', "' onfocus='alert(1)' autofocus '"):
+            escaped = escape_html(value)
+            self.assertNotIn('"', escaped)
+            self.assertNotIn("'", escaped)
+
+    def test_none_is_empty(self):
+        self.assertEqual(escape_html(None), '')
+
+class SafeUrlTest(unittest.TestCase):
+
+    def test_script_urls_are_refused(self):
+        for url in ('javascript:alert(1)', 'JaVaScRiPt:alert(1)', ' javascript:alert(1)', 'java\tscript:alert(1)',
+                    'java\nscript:alert(1)', 'javascript&#58;alert(1)', '&#106;avascript:alert(1)',
+                    'vbscript:msgbox(1)', 'data:text/html,<script>alert(1)</script>', 'jar:file:/x'):
+            self.assertEqual(safe_url(url), UNSAFE_URL, url)
+
+    def test_safe_urls_are_kept(self):
+        for url in ('/pricing', 'logo.png', '#faq', '?page=2', '//cdn.example.com/a.js', 'https://example.com/a?b=c',
+                    'mailto:hi@example.com', 'tel:+15550100'):
+            self.assertEqual(safe_url(url), url)
+
+    def test_colon_before_the_path_is_a_scheme(self):
+        self.assertEqual(safe_url('javascript%3Aalert(1)'), 'javascript%3Aalert(1)')
+        self.assertEqual(safe_url('x:y/z'), UNSAFE_URL)
+        self.assertEqual(safe_url('/a:b'), '/a:b')
+
+    def test_data_images_only_where_images_go(self):
+        image = 'data:image/png;base64,iVBORw0KGgo='
+        self.assertEqual(safe_url(image), UNSAFE_URL)
+        self.assertEqual(safe_url(image, images=True), image)
+        self.assertEqual(safe_url('data:image/svg+xml;base64,PHN2Zz4=', images=True), UNSAFE_URL)
+
+    def test_empty_is_unsafe(self):
+        self.assertEqual(safe_url(None), UNSAFE_URL)
+        self.assertEqual(safe_url('   '), UNSAFE_URL)
+
+class SanitizeTest(unittest.TestCase):
+
+    def test_scripts_and_styles_go_with_their_content(self):
+        self.assertEqual(sanitize_html('<p>a<script>alert(1)</script><style>p{}</style>b</p>'), '<p>ab</p>')
+
+    def test_self_closing_script_still_drops_what_follows(self):
+        self.assertEqual(sanitize_html('<script/>alert(1)</script><b>ok</b>'), '<b>ok</b>')
+
+    def test_nested_dropped_tags(self):
+        html = sanitize_html('<svg><script>alert(1)</script><svg></svg>x</svg>after')
+        self.assertEqual(html, 'after')
+
+    def test_event_handlers_are_dropped(self):
+        html = sanitize_html('<b onclick="alert(1)" title="t">x</b><img src=x onerror=alert(1)>')
+        self.assertEqual(html, '<b title="t">x</b>')
+
+    def test_link_urls_are_checked(self):
+        html = sanitize_html('<a href="javascript:alert(1)">x</a><a href="/ok">y</a>')
+        self.assertEqual(html, f'<a href="{UNSAFE_URL}" rel="nofollow noopener noreferrer">x</a>'
+                               '<a href="/ok" rel="nofollow noopener noreferrer">y</a>')
+
+    def test_attribute_values_cannot_break_out(self):
+        html = sanitize_html('<span title="&quot;><script>alert(1)</script>">x</span>')
+        self.assertEqual(html, '<span title="&quot;&gt;&lt;script&gt;alert(1)&lt;/script&gt;">x</span>')
+
+    def test_text_is_escaped_again(self):
+        self.assertEqual(sanitize_html('&lt;script&gt;'), '&lt;script&gt;')
+
+    def test_unclosed_tags_are_closed(self):
+        self.assertEqual(sanitize_html('<ul><li>one<li>two'), '<ul><li>one</li><li>two</li></ul>')
+        self.assertEqual(sanitize_html('<b><i>x</b>'), '<b><i>x</i></b>')
+
+    def test_embedded_documents_are_dropped(self):
+        self.assertEqual(sanitize_html('<iframe src="https://evil.example"></iframe><object data="x"></object>ok'), 'ok')
+
+class MarkdownTest(unittest.TestCase):
+
+    def test_link_and_image_urls(self):
+        html = render_markdown('[a](javascript:alert(1)) [b](java&#09;script:alert(1)) ![c](data:text/html,x)')
+        self.assertNotIn('javascript', html.lower())
+        self.assertNotIn('data:text', html)
+        self.assertEqual(html.count(f'="{UNSAFE_URL}"'), 3)
+
+    def test_link_title_cannot_break_out(self):
+        html = render_markdown('[x](/p "a\\" onmouseover=\\"alert(1)")')
+        self.assertNotIn('" onmouseover', html)
+
+    def test_raw_html_is_text(self):
+        html = render_markdown('<img src=x onerror=alert(1)>\n\n<a href="javascript:alert(1)">x</a>')
+        self.assertNotIn('<img', html)
+        self.assertNotIn('<a href="javascript', html)
+
+    def test_label_markup_is_text(self):
+        self.assertNotIn('<script>', render_markdown('[<script>alert(1)</script>](/p)'))
+
+if __name__ == '__main__':
+    unittest.main()
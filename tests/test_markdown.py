@@ -0,0 +1,36 @@
+"""
+Tests for core/markdown.py
+
+Run from the repository root: python -m unittest discover -s tests (or pytest tests)
+"""
+
+import unittest
+
+from core.markdown import render_markdown
+
+class InlineTest(unittest.TestCase):
+
+    def test_nul_byte_is_replaced_not_looped_on(self):
+        self.assertEqual(render_markdown('a\x00b'), '<p>a�b</p>')
+
+    def test_placeholder_lookalike_in_input_is_text(self):
+        self.assertEqual(render_markdown('a \x000\x00 b'), '<p>a �0� b</p>')
+
+    def test_nul_byte_in_code_block(self):
+        self.assertEqual(render_markdown('```\nx\x00y\n```'), '<pre><code>x�y</code></pre>')
+
+    def test_link_label_with_code(self):
+        self.assertEqual(render_markdown('[`code` here](/docs)'), '<p><a href="/docs"><code>code</code> here</a></p>')
+
+    def test_nested_markup(self):
+        html = render_markdown('**b** [x *y* `z`](/p "t") \\* ![i](a.png)')
+        self.assertEqual(html, '<p><strong>b</strong> <a href="/p" title="t">x <em>y</em> <code>z</code></a> * '
+                               '<img src="a.png" alt="i" loading="lazy"></p>')
+
+    def test_script_urls_and_raw_html(self):
+        html = render_markdown('[x](javascript:alert(1)) <script>alert(1)</script>')
+        self.assertNotIn('javascript:', html)
+        self.assertNotIn('<script>', html)
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,34 @@
+"""
+Tests for cli/devserver/origin_check.py
+"""
+
+import unittest
+
+from cli.devserver.origin_check import LOCAL_HOSTS, host_refusal
+
+class HostCheckTest(unittest.TestCase):
+
+    def setUp(self):
+        self.allowed = LOCAL_HOSTS + ('devbox.local', 'demo.loca.lt')
+
+    def test_known_hosts_answered(self):
+        for host in ('localhost:8000', 'LOCALHOST', '127.0.0.1:8000', '[::1]:8000', 'localhost.:8000',
+                     'devbox.local:8000', 'demo.loca.lt'):
+            self.assertIsNone(host_refusal(host, self.allowed), host)
+
+    def test_ip_addresses_answered(self):
+        # Rebinding needs a name; a page served from an address is the dev server's own
+        for host in ('192.168.1.20:8000', '10.0.0.5', '[fe80::1]:8000'):
+            self.assertIsNone(host_refusal(host, self.allowed), host)
+
+    def test_rebound_names_refused(self):
+        for host in ('attacker.example:8000', 'localhost.attacker.example', 'demo.loca.lt.attacker.example',
+                     '127.0.0.1.nip.io:8000', '', None):
+            self.assertIsNotNone(host_refusal(host, self.allowed), host)
+
+    def test_bind_host_counts(self):
+        self.assertIsNotNone(host_refusal('myapp.test:8000', LOCAL_HOSTS))
+        self.assertIsNone(host_refusal('myapp.test:8000', LOCAL_HOSTS + ('myapp.test',)))
+
+if __name__ == '__main__':
+    unittest.main()
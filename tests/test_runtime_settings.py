@@ -0,0 +1,32 @@
+"""
+Tests for core/runtime_settings.py
+"""
+
+import unittest
+
+from core.runtime_settings import RuntimeSettingsError, cors_allows, default, validate
+
+class CorsTest(unittest.TestCase):
+
+    def test_default_allows_only_this_machine(self):
+        cors = default('cors')
+        self.assertEqual(cors['origins'], 'local')
+        for origin in ('http://localhost:3000', 'http://127.0.0.1:5173', 'http://[::1]:8080', 'https://localhost'):
+            self.assertTrue(cors_allows(cors, origin), origin)
+        for origin in ('https://example.com', 'http://localhost.example.com', 'null', None):
+            self.assertFalse(cors_allows(cors, origin), origin)
+
+    def test_origin_lists_and_any(self):
+        self.assertTrue(cors_allows(validate('cors', {'origins': '*'}), 'https://example.com'))
+        listed = validate('cors', {'origins': ['https://app.example.com']})
+        self.assertTrue(cors_allows(listed, 'https://app.example.com'))
+        self.assertFalse(cors_allows(listed, 'http://localhost:3000'))
+
+    def test_invalid_origins(self):
+        with self.assertRaises(RuntimeSettingsError):
+            validate('cors', {'origins': 'everywhere'})
+        with self.assertRaises(RuntimeSettingsError):
+            validate('cors', {'origins': '*', 'credentials': True})
+
+if __name__ == '__main__':
+    unittest.main()
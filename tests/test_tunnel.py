@@ -0,0 +1,60 @@
+"""
+Tests for cli/utils/tunnel.py
+"""
+
+import unittest
+
+from cli.utils.tunnel import SHARED_PATHS, LocalTunnel
+
+class ShareGateTest(unittest.TestCase):
+
+    def setUp(self):
+        self.tunnel = LocalTunnel(3000, shared=SHARED_PATHS, token='secret')
+
+    def status(self, target, headers=None):
+        refusal, _ = self.tunnel.gate(target, headers or {})
+        return refusal[0] if refusal else 200
+
+    def test_token_required(self):
+        self.assertEqual(self.status('/'), 401)
+        self.assertEqual(self.status('/?flashflow_share=wrong'), 401)
+        self.assertEqual(self.status('/?flashflow_share=secret'), 200)
+        self.assertEqual(self.status('/preview', {'cookie': 'a=1; flashflow_share=secret'}), 200)
+        self.assertEqual(self.status('/api/data/todos', {'x-flashflow-share': 'secret'}), 200)
+        self.assertEqual(self.status('/__reload'), 401)
+
+    def test_cookie_set_only_from_link(self):
+        self.assertTrue(self.tunnel.gate('/?flashflow_share=secret', {})[1])
+        self.assertFalse(self.tunnel.gate('/', {'cookie': 'flashflow_share=secret'})[1])
+
+    def test_tooling_blocked_however_spelled(self):
+        cookie = {'cookie': 'flashflow_share=secret'}
+        for target in ('/admin', '/admin/settings', '/%61dmin', '//admin', '/x/../admin', '/api\\edit/files/a',
+                       '/__restart', '/__og/../__build'):
+            self.assertEqual(self.status(target, cookie), 403, target)
+        self.assertEqual(self.status('/__reload', cookie), 200)
+
+    def test_only_the_app_is_shared(self):
+        cookie = {'cookie': 'flashflow_share=secret'}
+        for target in ('/', '/preview', '/preview/page/orders', '/web/', '/web/docs/start', '/media/logo.png',
+                       '/api/data', '/api/data/todos/3', '/android', '/__reload?client=x'):
+            self.assertEqual(self.status(target, cookie), 200, target)
+        for target in ('/vector/indexes', '/vector/indexes/docs', '/vector/indexes/docs/import', '/api/ai/chat',
+                       '/api/ai/models', '/inference/devices', '/inference/run', '/api/render/uploads',
+                       '/api/render/uploads/a.png', '/api/data-export', '/desktop/api/tray', '/webhooks',
+                       '/api/vault/seal', '/branch/main/'):
+            self.assertEqual(self.status(target, cookie), 403, target)
+
+    def test_debugger_never_forwarded(self):
+        cookie = {'cookie': 'flashflow_share=secret'}
+        for target in ('/?__debugger__=yes&cmd=resource&f=style.css', '/page?__debugger__=yes&cmd=printpin&s=x',
+                       '/robots.txt?%5F%5Fdebugger%5F%5F=yes', '/api/data/todos?a=1&__debugger__'):
+            self.assertEqual(self.status(target, cookie), 403, target)
+        self.assertEqual(self.status('/preview?debugger=yes', cookie), 200)
+
+    def test_social_cards_public(self):
+        self.assertEqual(self.status('/__og/index.png'), 200)
+        self.assertEqual(self.status('/robots.txt'), 200)
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,55 @@
+"""
+Tests for core/vault.py
+"""
+
+import os
+import stat
+import tempfile
+import threading
+import unittest
+from pathlib import Path
+from unittest import mock
+
+from core.vault import KEY_BYTES, KEY_ENV, load_key
+
+@mock.patch.dict(os.environ, {KEY_ENV: ''})
+class DevKeyFileTest(unittest.TestCase):
+
+    def setUp(self):
+        self.key_file = Path(tempfile.mkdtemp()) / '.flashflow' / 'vault.key'
+
+    def test_generated_owner_only_and_reused(self):
+        key, source = load_key(self.key_file)
+        self.assertEqual(len(key), KEY_BYTES)
+        self.assertEqual(source, f"file:{self.key_file}")
+        self.assertEqual(stat.S_IMODE(self.key_file.stat().st_mode), 0o600)
+        self.assertEqual(load_key(self.key_file)[0], key)
+        self.assertEqual(sorted(p.name for p in self.key_file.parent.iterdir()), ['vault.key'])
+
+    def test_empty_file_is_missing(self):
+        self.key_file.parent.mkdir(parents=True)
+        self.key_file.write_text('\n')
+        key, _ = load_key(self.key_file)
+        self.assertEqual(len(key), KEY_BYTES)
+        self.assertEqual(load_key(self.key_file)[0], key)
+        self.assertEqual(sorted(p.name for p in self.key_file.parent.iterdir()), ['vault.key'])
+
+    def test_concurrent_loaders_share_one_key(self):
+        keys, errors = [], []
+        start = threading.Barrier(8)
+        def load():
+            start.wait()
+            try:
+                keys.append(load_key(self.key_file)[0])
+            except Exception as e:
+                errors.append(e)
+        threads = [threading.Thread(target=load) for _ in range(8)]
+        for thread in threads:
+            thread.start()
+        for thread in threads:
+            thread.join()
+        self.assertEqual(errors, [])
+        self.assertEqual(len(set(keys)), 1)
+
+if __name__ == '__main__':
+    unittest.main()